@@ -0,0 +1,6 @@
+"""Conformance fixture: source code."""
+
+
+def conformance_marker(value):
+    # The comment marker sits on line five.
+    return value * 2
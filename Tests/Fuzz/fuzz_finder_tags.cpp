@@ -0,0 +1,22 @@
+// Fuzz target for FinderTags::parse, which decodes the
+// com.apple.metadata:_kMDItemUserTags attribute (a binary or XML plist)
+// of every file the scanner visits.
+
+#include "core/fs/finder_tags.h"
+
+#include <QByteArray>
+
+#include <cstddef>
+#include <cstdint>
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    const QByteArray plist(reinterpret_cast<const char*>(data), static_cast<qsizetype>(size));
+    const auto tags = bs::FinderTags::parse(plist);
+    if (tags.has_value()) {
+        for (const QString& entry : tags.value()) {
+            bs::FinderTags::entryName(entry);
+        }
+    }
+    return 0;
+}
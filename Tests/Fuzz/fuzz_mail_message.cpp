@@ -0,0 +1,24 @@
+// Fuzz target for MailSource: an .emlx file, and the same bytes as a bare
+// RFC 5322 message, whose MIME parts and encoded words are decoded by hand.
+
+#include "core/indexing/mail_source.h"
+
+#include <cstddef>
+#include <cstdint>
+
+namespace {
+
+constexpr size_t kMaxMessageBytes = 1024 * 1024;
+
+} // namespace
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    if (size > kMaxMessageBytes) {
+        return 0;
+    }
+    const QByteArray bytes(reinterpret_cast<const char*>(data), static_cast<qsizetype>(size));
+    bs::MailSource::parseEmlx(bytes);
+    bs::MailSource::parseMessage(bytes);
+    return 0;
+}
@@ -0,0 +1,14 @@
+// Fuzz target for MessagesSource::attributedBodyText: a
+// message.attributedBody blob in NSArchiver's typedstream format.
+
+#include "core/indexing/messages_source.h"
+
+#include <cstddef>
+#include <cstdint>
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    bs::MessagesSource::attributedBodyText(
+        QByteArray(reinterpret_cast<const char*>(data), static_cast<qsizetype>(size)));
+    return 0;
+}
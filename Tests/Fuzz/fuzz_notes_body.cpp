@@ -0,0 +1,14 @@
+// Fuzz target for NotesSource::noteText: a ZICNOTEDATA.ZDATA blob, a
+// gzipped protobuf, decoded by hand.
+
+#include "core/indexing/notes_source.h"
+
+#include <cstddef>
+#include <cstdint>
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    bs::NotesSource::noteText(
+        QByteArray(reinterpret_cast<const char*>(data), static_cast<qsizetype>(size)));
+    return 0;
+}
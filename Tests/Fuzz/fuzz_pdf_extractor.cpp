@@ -0,0 +1,47 @@
+// Fuzz target for PdfExtractor. The extractor reads a path, so each input
+// is written to one scratch file first; without Poppler the extractor
+// returns UnsupportedFormat and this only exercises the file checks.
+
+#include "core/extraction/pdf_extractor.h"
+
+#include <QDir>
+#include <QFile>
+#include <QTemporaryDir>
+
+#include <cstddef>
+#include <cstdint>
+#include <cstdlib>
+
+namespace {
+
+constexpr size_t kMaxPdfBytes = 1024 * 1024;
+
+const QString& scratchPath()
+{
+    static QTemporaryDir dir;
+    static const QString path = QDir(dir.path()).filePath(QStringLiteral("input.pdf"));
+    if (!dir.isValid()) {
+        std::abort();
+    }
+    return path;
+}
+
+} // namespace
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    if (size > kMaxPdfBytes) {
+        return 0;
+    }
+    QFile file(scratchPath());
+    if (!file.open(QIODevice::WriteOnly | QIODevice::Truncate)
+        || file.write(reinterpret_cast<const char*>(data), static_cast<qint64>(size))
+               != static_cast<qint64>(size)) {
+        std::abort();
+    }
+    file.close();
+
+    bs::PdfExtractor extractor;
+    extractor.extract(scratchPath());
+    return 0;
+}
@@ -0,0 +1,41 @@
+// Fuzz target for the query language, run the way QueryService::search
+// parses a query: instant answers on the raw text, then normalization, the
+// natural-language pass and QueryParser.
+
+#include "core/query/instant_answers.h"
+#include "core/query/natural_language_query.h"
+#include "core/query/query_normalizer.h"
+#include "core/query/query_parser.h"
+
+#include <QTimeZone>
+
+#include <cstddef>
+#include <cstdint>
+
+namespace {
+
+// Typed queries are short; longer inputs only slow the fuzzer down.
+constexpr size_t kMaxQueryBytes = 4096;
+
+// A fixed clock, so a crash on a relative date replays.
+const QDateTime kNow(QDate(2026, 10, 14), QTime(15, 30), QTimeZone::UTC);
+
+} // namespace
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    if (size > kMaxQueryBytes) {
+        return 0;
+    }
+    const QString raw = QString::fromUtf8(reinterpret_cast<const char*>(data),
+                                          static_cast<qsizetype>(size));
+    bs::InstantAnswers::evaluate(raw);
+
+    QString query = bs::QueryNormalizer::normalize(raw).normalized;
+    const auto interpretation = bs::NaturalLanguageQuery::interpret(query, kNow);
+    if (interpretation.has_value()) {
+        query = interpretation->query;
+    }
+    bs::QueryParser::parse(query, kNow);
+    return 0;
+}
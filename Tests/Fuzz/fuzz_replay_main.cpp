@@ -0,0 +1,81 @@
+// fuzz_replay_main -- runs a fuzz target over saved inputs without libFuzzer.
+//
+// With BETTERSPOTLIGHT_ENABLE_FUZZING the targets link libFuzzer's own
+// main; otherwise (AppleClang ships no libFuzzer) they link this one, so
+// the checked-in corpora in Tests/Fixtures/fuzz run as ordinary tests:
+//
+//   fuzz-query-parser -runs=0 Tests/Fixtures/fuzz/query_parser
+//
+// Arguments are files or directories of files. Flags (arguments starting
+// with '-') are libFuzzer's and are ignored, so both builds take the same
+// command line. A crash is the failure; nothing else is checked.
+
+#include <QByteArray>
+#include <QDir>
+#include <QDirIterator>
+#include <QFile>
+#include <QFileInfo>
+#include <QStringList>
+
+#include <cstdint>
+#include <cstdio>
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size);
+
+namespace {
+
+bool runFile(const QString& path)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        std::fprintf(stderr, "Cannot read %s\n", qPrintable(path));
+        return false;
+    }
+    const QByteArray input = file.readAll();
+    std::fprintf(stderr, "Running %s (%lld bytes)\n", qPrintable(path),
+                 static_cast<long long>(input.size()));
+    LLVMFuzzerTestOneInput(reinterpret_cast<const uint8_t*>(input.constData()),
+                           static_cast<size_t>(input.size()));
+    return true;
+}
+
+} // namespace
+
+int main(int argc, char* argv[])
+{
+    QStringList paths;
+    for (int i = 1; i < argc; ++i) {
+        const QString arg = QString::fromLocal8Bit(argv[i]);
+        if (arg.startsWith(QLatin1Char('-'))) {
+            continue;
+        }
+        const QFileInfo info(arg);
+        if (info.isDir()) {
+            QStringList files;
+            QDirIterator it(arg, QDir::Files | QDir::Hidden, QDirIterator::Subdirectories);
+            while (it.hasNext()) {
+                files.append(it.next());
+            }
+            files.sort();
+            paths.append(files);
+        } else if (info.isFile()) {
+            paths.append(arg);
+        } else {
+            std::fprintf(stderr, "No such input: %s\n", qPrintable(arg));
+            return 1;
+        }
+    }
+    if (paths.isEmpty()) {
+        std::fprintf(stderr, "usage: %s [-flags] <input file or corpus directory>...\n", argv[0]);
+        return 2;
+    }
+
+    int failed = 0;
+    for (const QString& path : paths) {
+        if (!runFile(path)) {
+            ++failed;
+        }
+    }
+    std::fprintf(stderr, "Ran %lld inputs\n", static_cast<long long>(paths.size() - failed));
+    return failed == 0 ? 0 : 1;
+}
@@ -0,0 +1,30 @@
+// Fuzz target for SpotlightPredicate, which translates the mdfind-style
+// `predicate` of a search request (`bsctl search --predicate`).
+
+#include "core/query/spotlight_predicate.h"
+
+#include <QTimeZone>
+
+#include <cstddef>
+#include <cstdint>
+
+namespace {
+
+constexpr size_t kMaxPredicateBytes = 8192;
+
+// A fixed clock for $time.today(...) and friends, so a crash replays.
+const QDateTime kNow(QDate(2026, 10, 14), QTime(15, 30), QTimeZone::UTC);
+
+} // namespace
+
+extern "C" int LLVMFuzzerTestOneInput(const uint8_t* data, size_t size)
+{
+    if (size > kMaxPredicateBytes) {
+        return 0;
+    }
+    const QString predicate = QString::fromUtf8(reinterpret_cast<const char*>(data),
+                                                static_cast<qsizetype>(size));
+    QString error;
+    bs::SpotlightPredicate::translate(predicate, kNow, &error);
+    return 0;
+}
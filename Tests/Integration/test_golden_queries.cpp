@@ -0,0 +1,141 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "golden_queries.h"
+#include "ipc_test_utils.h"
+#include "service_process_harness.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QSaveFile>
+#include <QTemporaryDir>
+
+#ifndef BS_GOLDEN_SUITE_PATH
+#define BS_GOLDEN_SUITE_PATH ""
+#endif
+
+namespace {
+
+// Results requested beyond a case's depth, so the diff can say where a
+// displaced file went.
+constexpr int kExtraResults = 10;
+
+QString resolveSuitePath()
+{
+    const QString fromEnv = qEnvironmentVariable("BS_GOLDEN_SUITE");
+    if (!fromEnv.isEmpty()) {
+        return fromEnv;
+    }
+    return QString::fromUtf8(BS_GOLDEN_SUITE_PATH);
+}
+
+bool envFlagEnabled(const QString& raw)
+{
+    const QString normalized = raw.trimmed().toLower();
+    return normalized == QLatin1String("1")
+        || normalized == QLatin1String("true")
+        || normalized == QLatin1String("yes")
+        || normalized == QLatin1String("on");
+}
+
+} // namespace
+
+class TestGoldenQueries : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testGoldenQueriesViaIpc();
+};
+
+void TestGoldenQueries::testGoldenQueriesViaIpc()
+{
+    const QString suitePath = resolveSuitePath();
+    QVERIFY2(QFileInfo::exists(suitePath),
+             qPrintable(QStringLiteral("Golden-query suite not found: %1").arg(suitePath)));
+    QString error;
+    auto suite = bs::test::GoldenQueries::loadSuite(suitePath, &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+
+    const QString fixtureRoot = QDir::cleanPath(
+        QDir(QFileInfo(suitePath).absolutePath())
+            .filePath(QStringLiteral("../Fixtures/") + suite->fixtureId));
+    QVERIFY2(QFileInfo(fixtureRoot).isDir(),
+             qPrintable(QStringLiteral("Fixture not found: %1").arg(fixtureRoot)));
+
+    QTemporaryDir tempHome;
+    QVERIFY(tempHome.isValid());
+    const QString dataDir = QDir(tempHome.path())
+                                .filePath(QStringLiteral("Library/Application Support/betterspotlight"));
+    QVERIFY(QDir().mkpath(dataDir));
+    {
+        auto store = bs::SQLiteStore::open(QDir(dataDir).filePath(QStringLiteral("index.db")));
+        QVERIFY2(store.has_value(), "Failed to open the fixture store");
+        const int seeded = bs::test::GoldenQueries::seedFixtureHome(
+            *store, fixtureRoot, QDir(tempHome.path()).filePath(QStringLiteral("Documents")),
+            &error);
+        QVERIFY2(seeded > 0, qPrintable(error));
+    }
+
+    bs::test::ServiceProcessHarness harness(QStringLiteral("query"),
+                                            QStringLiteral("betterspotlight-query"));
+    bs::test::ServiceLaunchConfig launch;
+    launch.homeDir = tempHome.path();
+    launch.dataDir = dataDir;
+    launch.requestDefaultTimeoutMs = 8000;
+    QVERIFY2(harness.start(launch), "Failed to start query service");
+
+    const bool update = envFlagEnabled(qEnvironmentVariable("BS_GOLDEN_UPDATE"));
+    QStringList failures;
+    for (bs::test::GoldenCase& goldenCase : suite->cases) {
+        QJsonObject params;
+        params[QStringLiteral("query")] = goldenCase.query;
+        params[QStringLiteral("limit")] = goldenCase.depth() + kExtraResults;
+        params[QStringLiteral("queryMode")] = goldenCase.mode;
+        const QJsonObject response = harness.request(QStringLiteral("search"), params);
+        QVERIFY2(bs::test::isResponse(response),
+                 qPrintable(QStringLiteral("%1: search failed: %2")
+                                .arg(goldenCase.id,
+                                     QString::fromUtf8(QJsonDocument(response).toJson(
+                                         QJsonDocument::Compact)))));
+
+        QStringList actual;
+        const QJsonArray results =
+            bs::test::resultPayload(response).value(QStringLiteral("results")).toArray();
+        for (const QJsonValue& result : results) {
+            actual.append(QFileInfo(result.toObject().value(QStringLiteral("path")).toString())
+                              .fileName());
+        }
+        const QString diff = bs::test::GoldenQueries::diff(goldenCase, actual);
+        if (diff.isEmpty()) {
+            continue;
+        }
+        failures.append(diff);
+        if (update) {
+            goldenCase = bs::test::GoldenQueries::updated(goldenCase, actual);
+        }
+    }
+
+    if (update && !failures.isEmpty()) {
+        QSaveFile file(suitePath);
+        QVERIFY(file.open(QIODevice::WriteOnly));
+        file.write(QJsonDocument(bs::test::GoldenQueries::suiteToJson(*suite))
+                       .toJson(QJsonDocument::Indented));
+        QVERIFY(file.commit());
+        qInfo().noquote() << failures.join(QLatin1Char('\n'));
+        QSKIP(qPrintable(QStringLiteral("Rewrote %1 case(s) in %2; review the diff")
+                             .arg(failures.size())
+                             .arg(suitePath)));
+    }
+    QVERIFY2(failures.isEmpty(),
+             qPrintable(QStringLiteral("%1 of %2 golden queries changed "
+                                       "(BS_GOLDEN_UPDATE=1 accepts the new ranking):\n%3")
+                            .arg(failures.size())
+                            .arg(suite->cases.size())
+                            .arg(failures.join(QLatin1Char('\n')))));
+}
+
+QTEST_MAIN(TestGoldenQueries)
+#include "test_golden_queries.moc"
@@ -0,0 +1,84 @@
+#include <QtTest/QtTest>
+
+#include "mutation_corpus.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QTemporaryDir>
+
+namespace {
+
+using bs::IndexDiff;
+using bs::IndexDiffResult;
+using bs::test::MutationHarness;
+using bs::test::MutationScript;
+
+constexpr int kShownDocuments = 20;
+
+QString scriptsDir()
+{
+#ifdef BETTERSPOTLIGHT_SOURCE_DIR
+    return QDir(QString::fromUtf8(BETTERSPOTLIGHT_SOURCE_DIR))
+        .filePath(QStringLiteral("Tests/Fixtures/mutation_scripts"));
+#else
+    return QString();
+#endif
+}
+
+} // namespace
+
+// Each script in Tests/Fixtures/mutation_scripts runs against a live
+// pipeline, whose index must end up as a fresh full index of the final tree
+// does. Set BETTERSPOTLIGHT_TEST_SECOND_VOLUME to a directory on another
+// device for moves that cross a real volume boundary.
+class TestIncrementalConvergence : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testLiveIndexConverges_data();
+    void testLiveIndexConverges();
+};
+
+void TestIncrementalConvergence::testLiveIndexConverges_data()
+{
+    QTest::addColumn<QString>("scriptPath");
+
+    const QString dir = scriptsDir();
+    if (dir.isEmpty()) {
+        QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+    }
+    const QDir scripts(dir);
+    for (const QString& file : scripts.entryList({QStringLiteral("*.json")}, QDir::Files)) {
+        QTest::newRow(qPrintable(QFileInfo(file).completeBaseName())) << scripts.filePath(file);
+    }
+}
+
+void TestIncrementalConvergence::testLiveIndexConverges()
+{
+    QFETCH(QString, scriptPath);
+
+    QString error;
+    const std::optional<MutationScript> script = MutationHarness::loadScript(scriptPath, &error);
+    QVERIFY2(script.has_value(), qPrintable(error));
+
+    QTemporaryDir workDir;
+    QVERIFY(workDir.isValid());
+    MutationHarness harness(workDir.path());
+    const std::optional<IndexDiffResult> diff = harness.converge(*script, &error);
+    QVERIFY2(diff.has_value(), qPrintable(error));
+
+    if (!diff->identical()) {
+        // Keep both indexes for `bspot debug diff-index`.
+        workDir.setAutoRemove(false);
+        qWarning().noquote() << "fresh index (-) against live index (+), kept in"
+                             << workDir.path() << '\n'
+                             << IndexDiff::renderText(*diff, kShownDocuments);
+    }
+    if (!script->knownIssue.isEmpty()) {
+        QEXPECT_FAIL("", qPrintable(script->knownIssue), Continue);
+    }
+    QVERIFY(diff->identical());
+}
+
+QTEST_MAIN(TestIncrementalConvergence)
+#include "test_incremental_convergence.moc"
@@ -0,0 +1,242 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "service_process_harness.h"
+
+#include <QDir>
+#include <QHostAddress>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QList>
+#include <QPair>
+#include <QTcpServer>
+#include <QTcpSocket>
+#include <QTemporaryDir>
+
+#include <memory>
+
+namespace {
+
+using HeaderList = QList<QPair<QByteArray, QByteArray>>;
+
+struct HttpReply {
+    int status = 0;
+    QByteArray reason;
+    QString message;
+};
+
+quint16 freeLoopbackPort()
+{
+    QTcpServer probe;
+    if (!probe.listen(QHostAddress::LocalHost, 0)) {
+        return 0;
+    }
+    const quint16 port = probe.serverPort();
+    probe.close();
+    return port;
+}
+
+HttpReply parseReply(const QByteArray& raw)
+{
+    HttpReply reply;
+    const qsizetype headerEnd = raw.indexOf("\r\n\r\n");
+    if (headerEnd < 0) {
+        return reply;
+    }
+    const QList<QByteArray> statusLine = raw.left(raw.indexOf("\r\n")).split(' ');
+    if (statusLine.size() >= 2) {
+        reply.status = statusLine.at(1).toInt();
+        reply.reason = statusLine.mid(2).join(' ');
+    }
+    const QJsonObject body = QJsonDocument::fromJson(raw.mid(headerEnd + 4)).object();
+    reply.message = body.value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString();
+    return reply;
+}
+
+} // namespace
+
+class TestQueryServiceHttp : public QObject {
+    Q_OBJECT
+
+private slots:
+    void initTestCase();
+    void cleanupTestCase();
+    void testActionsRequireJsonContentType();
+    void testActionsRequireBearerToken();
+    void testRepeatActionRequiresJsonAndBearerToken();
+    void testLoopbackWithoutTokensNeedsNoAuthorization();
+    void testHostMustNameLoopbackAndPort();
+    void testOriginIsRefused();
+
+private:
+    QByteArray loopbackHost() const;
+    HttpReply fetch(const QByteArray& method, const QByteArray& path,
+                    const HeaderList& headers, const QByteArray& body = {}) const;
+
+    QTemporaryDir m_home;
+    quint16 m_port = 0;
+    std::unique_ptr<bs::test::ServiceProcessHarness> m_harness;
+};
+
+QByteArray TestQueryServiceHttp::loopbackHost() const
+{
+    return "127.0.0.1:" + QByteArray::number(m_port);
+}
+
+HttpReply TestQueryServiceHttp::fetch(const QByteArray& method, const QByteArray& path,
+                                      const HeaderList& headers, const QByteArray& body) const
+{
+    QByteArray request = method + ' ' + path + " HTTP/1.1\r\n";
+    for (const auto& [name, value] : headers) {
+        request += name + ": " + value + "\r\n";
+    }
+    request += "Content-Length: " + QByteArray::number(body.size()) + "\r\n\r\n" + body;
+
+    QTcpSocket socket;
+    socket.connectToHost(QHostAddress::LocalHost, m_port);
+    QByteArray raw;
+    if (!socket.waitForConnected(3000)) {
+        return {};
+    }
+    socket.write(request);
+    QObject::connect(&socket, &QTcpSocket::readyRead,
+                     [&socket, &raw]() { raw += socket.readAll(); });
+    QTest::qWaitFor([&socket]() { return socket.state() == QAbstractSocket::UnconnectedState; },
+                    5000);
+    raw += socket.readAll();
+    return parseReply(raw);
+}
+
+void TestQueryServiceHttp::initTestCase()
+{
+    QVERIFY(m_home.isValid());
+    const QString dataDir =
+        QDir(m_home.path()).filePath(QStringLiteral("Library/Application Support/betterspotlight"));
+    QVERIFY(QDir().mkpath(dataDir));
+    QVERIFY(bs::SQLiteStore::open(QDir(dataDir).filePath(QStringLiteral("index.db"))).has_value());
+
+    m_port = freeLoopbackPort();
+    QVERIFY(m_port != 0);
+
+    bs::test::ServiceLaunchConfig launch;
+    launch.homeDir = m_home.path();
+    launch.dataDir = dataDir;
+    launch.env[QStringLiteral("BETTERSPOTLIGHT_HTTP_PORT")] = QString::number(m_port);
+    launch.env[QStringLiteral("BETTERSPOTLIGHT_ADMIN_TOKEN")] = QString();
+    m_harness = std::make_unique<bs::test::ServiceProcessHarness>(
+        QStringLiteral("query"), QStringLiteral("betterspotlight-query"));
+    QVERIFY2(m_harness->start(launch), "Failed to start query service");
+
+    // The REST bridge listens once the service is up; wait until it answers.
+    QTRY_VERIFY_WITH_TIMEOUT(
+        fetch("GET", "/v1/stats", {{"Host", loopbackHost()}}).status != 0, 5000);
+}
+
+void TestQueryServiceHttp::cleanupTestCase()
+{
+    if (m_harness) {
+        m_harness->stop();
+    }
+}
+
+void TestQueryServiceHttp::testActionsRequireJsonContentType()
+{
+    const QByteArray body = R"({"itemId":1,"action":"open"})";
+    HttpReply reply = fetch("POST", "/v1/actions",
+                            {{"Host", loopbackHost()}, {"Content-Type", "text/plain"},
+                             {"Authorization", "Bearer bogus"}},
+                            body);
+    QCOMPARE(reply.status, 415);
+    QCOMPARE(reply.reason, QByteArrayLiteral("Unsupported Media Type"));
+    QCOMPARE(reply.message, QStringLiteral("Content-Type must be application/json"));
+
+    // A form post is the other body a page can send without a preflight.
+    reply = fetch("POST", "/v1/actions",
+                  {{"Host", loopbackHost()},
+                   {"Content-Type", "application/x-www-form-urlencoded"}},
+                  body);
+    QCOMPARE(reply.status, 415);
+}
+
+void TestQueryServiceHttp::testActionsRequireBearerToken()
+{
+    const QByteArray body = R"({"itemId":1,"action":"open"})";
+    // No API token has been issued, so other loopback routes accept this
+    // request; actions still want a bearer token.
+    HttpReply reply = fetch("POST", "/v1/actions",
+                            {{"Host", loopbackHost()},
+                             {"Content-Type", "application/json; charset=utf-8"}},
+                            body);
+    QCOMPARE(reply.status, 401);
+    QCOMPARE(reply.message, QStringLiteral("Actions need a bearer token"));
+
+    reply = fetch("POST", "/v1/actions",
+                  {{"Host", loopbackHost()}, {"Content-Type", "application/json"},
+                   {"Authorization", "Bearer bogus"}},
+                  body);
+    QCOMPARE(reply.status, 401);
+    QCOMPARE(reply.message, QStringLiteral("Invalid bearer token"));
+}
+
+void TestQueryServiceHttp::testRepeatActionRequiresJsonAndBearerToken()
+{
+    HttpReply reply = fetch("POST", "/v1/actions/repeat",
+                            {{"Host", loopbackHost()}, {"Content-Type", "text/plain"},
+                             {"Authorization", "Bearer bogus"}});
+    QCOMPARE(reply.status, 415);
+    QCOMPARE(reply.message, QStringLiteral("Content-Type must be application/json"));
+
+    reply = fetch("POST", "/v1/actions/repeat",
+                  {{"Host", loopbackHost()}, {"Content-Type", "application/json"}}, "{}");
+    QCOMPARE(reply.status, 401);
+    QCOMPARE(reply.message, QStringLiteral("Actions need a bearer token"));
+
+    reply = fetch("POST", "/v1/actions/repeat",
+                  {{"Host", loopbackHost()}, {"Content-Type", "application/json"},
+                   {"Authorization", "Bearer bogus"}},
+                  "{}");
+    QCOMPARE(reply.status, 401);
+    QCOMPARE(reply.message, QStringLiteral("Invalid bearer token"));
+}
+
+void TestQueryServiceHttp::testLoopbackWithoutTokensNeedsNoAuthorization()
+{
+    const HttpReply reply = fetch("GET", "/v1/stats", {{"Host", loopbackHost()}});
+    QCOMPARE(reply.status, 200);
+}
+
+void TestQueryServiceHttp::testHostMustNameLoopbackAndPort()
+{
+    const QByteArray port = QByteArray::number(m_port);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "localhost:" + port}}).status, 200);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "LocalHost:" + port}}).status, 200);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "[::1]:" + port}}).status, 200);
+
+    // A DNS-rebound page reaches the same socket under its own name.
+    HttpReply reply = fetch("GET", "/v1/stats", {{"Host", "evil.example:" + port}});
+    QCOMPARE(reply.status, 403);
+    QCOMPARE(reply.message, QStringLiteral("Host is not a loopback address"));
+
+    // Without a port the Host means 80, which is not where we listen.
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "127.0.0.1"}}).status, 403);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "[::1]"}}).status, 403);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "127.0.0.1:1" + port}}).status, 403);
+    QCOMPARE(fetch("GET", "/v1/stats", {{"Host", "[::1]x:" + port}}).status, 403);
+    QCOMPARE(fetch("GET", "/v1/stats", {}).status, 403);
+}
+
+void TestQueryServiceHttp::testOriginIsRefused()
+{
+    const QByteArray origin = "http://" + loopbackHost();
+    for (const QByteArray& value : {origin, QByteArrayLiteral("null"),
+                                    QByteArrayLiteral("https://evil.example")}) {
+        const HttpReply reply =
+            fetch("GET", "/v1/stats", {{"Host", loopbackHost()}, {"Origin", value}});
+        QCOMPARE(reply.status, 403);
+        QCOMPARE(reply.message, QStringLiteral("Cross-origin requests are not accepted"));
+    }
+}
+
+QTEST_MAIN(TestQueryServiceHttp)
+#include "test_query_service_http.moc"
@@ -19,6 +19,7 @@
 #include <optional>
 #include <vector>
 
+#include <sqlite3.h>
 #include <unistd.h>
 
 namespace {
@@ -64,6 +65,7 @@ class TestQueryServiceIpcExtensions : public QObject {
 
 private slots:
     void testExtendedIpcBranches();
+    void testHistoryRecordedAfterDeadlineExceeded();
 };
 
 void TestQueryServiceIpcExtensions::testExtendedIpcBranches()
@@ -310,6 +312,22 @@ void TestQueryServiceIpcExtensions::testExtendedIpcBranches()
         }
     }
 
+    {
+        // A private search answered from the cache is still marked private.
+        QJsonObject params;
+        params[QStringLiteral("query")] = QStringLiteral("remediation notes");
+        const QJsonObject first = harness.request(QStringLiteral("search"), params, 5000);
+        QVERIFY(bs::test::isResponse(first));
+        QVERIFY(!bs::test::resultPayload(first).contains(QStringLiteral("private")));
+
+        params[QStringLiteral("private")] = true;
+        const QJsonObject second = harness.request(QStringLiteral("search"), params, 5000);
+        QVERIFY(bs::test::isResponse(second));
+        const QJsonObject result = bs::test::resultPayload(second);
+        QVERIFY(result.value(QStringLiteral("cached")).toBool(false));
+        QVERIFY(result.value(QStringLiteral("private")).toBool(false));
+    }
+
     {
         queueRoots = QJsonArray();
         for (int i = 0; i < 40; ++i) {
@@ -456,6 +474,29 @@ void TestQueryServiceIpcExtensions::testExtendedIpcBranches()
         QCOMPARE(bs::test::errorPayload(response).value(QStringLiteral("code")).toInt(),
                  static_cast<int>(bs::IpcErrorCode::InvalidParams));
     }
+    {
+        // Private feedback is acknowledged without touching frecency.
+        QJsonObject frequencyParams;
+        frequencyParams[QStringLiteral("itemId")] = static_cast<qint64>(seededId.value());
+        const int openCountBefore = bs::test::resultPayload(
+            harness.request(QStringLiteral("getFrequency"), frequencyParams))
+            .value(QStringLiteral("openCount")).toInt();
+
+        QJsonObject params = frequencyParams;
+        params[QStringLiteral("action")] = QStringLiteral("opened");
+        params[QStringLiteral("query")] = QStringLiteral("private marker");
+        params[QStringLiteral("private")] = true;
+        const QJsonObject response = harness.request(QStringLiteral("recordFeedback"), params);
+        QVERIFY(bs::test::isResponse(response));
+        const QJsonObject result = bs::test::resultPayload(response);
+        QVERIFY(!result.value(QStringLiteral("recorded")).toBool(true));
+        QVERIFY(result.value(QStringLiteral("private")).toBool(false));
+
+        QCOMPARE(bs::test::resultPayload(
+                     harness.request(QStringLiteral("getFrequency"), frequencyParams))
+                     .value(QStringLiteral("openCount")).toInt(),
+                 openCountBefore);
+    }
     {
         QJsonObject params;
         params[QStringLiteral("itemId")] = static_cast<qint64>(seededId.value());
@@ -483,9 +524,80 @@ void TestQueryServiceIpcExtensions::testExtendedIpcBranches()
         QVERIFY(result.value(QStringLiteral("frequencyTier")).toInt() >= 1);
     }
 
+    {
+        const QJsonObject response = harness.request(QStringLiteral("getMetrics"));
+        QVERIFY(bs::test::isResponse(response));
+        const QString text =
+            bs::test::resultPayload(response).value(QStringLiteral("text")).toString();
+        QVERIFY(text.contains(QStringLiteral("# TYPE betterspotlight_index_items gauge\n")));
+        QVERIFY(text.contains(QStringLiteral("betterspotlight_indexer_up 1\n")));
+        QVERIFY(text.contains(
+            QStringLiteral("betterspotlight_indexer_queue_items{state=\"pending\"} %1\n")
+                .arg(queuePending)));
+        QVERIFY(text.contains(QStringLiteral(
+            "betterspotlight_extraction_failures{kind=\"text\",expected=\"false\"} 1\n")));
+        QVERIFY(text.contains(QStringLiteral(
+            "betterspotlight_extraction_failures{kind=\"text\",expected=\"true\"} 1\n")));
+        QVERIFY(text.contains(QStringLiteral(
+            "betterspotlight_request_duration_seconds_count{method=\"getAnswerSnippet\"}")));
+        QVERIFY(!text.contains(seededPath));
+    }
+
     fakeInference.close();
     fakeIndexer.close();
 }
 
+void TestQueryServiceIpcExtensions::testHistoryRecordedAfterDeadlineExceeded()
+{
+    QTemporaryDir tempHome;
+    QVERIFY(tempHome.isValid());
+    const QString dataDir =
+        QDir(tempHome.path()).filePath(QStringLiteral("Library/Application Support/betterspotlight"));
+    QVERIFY(QDir().mkpath(dataDir));
+
+    {
+        auto storeOpt = bs::SQLiteStore::open(QDir(dataDir).filePath(QStringLiteral("index.db")));
+        QVERIFY(storeOpt.has_value());
+        const QString docPath = QDir(tempHome.path()).filePath(QStringLiteral("Docs/budget.md"));
+        QVERIFY(seedItem(storeOpt.value(), docPath, QStringLiteral("quarterly budget review"),
+                         /*size=*/64, /*modifiedAtSecs=*/300.0).has_value());
+        // Expired entries give the first recording's retention sweep enough
+        // work that a leftover deadline would interrupt it.
+        const char* sql = R"(
+            WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2000)
+            INSERT INTO query_history (
+                query_normalized, query, hit_count, last_result_count, first_used_at, last_used_at
+            )
+            SELECT 'expired ' || i, 'expired ' || i, 1, 0,
+                   '2000-01-01 00:00:00', '2000-01-01 00:00:00'
+            FROM n;
+        )";
+        QCOMPARE(sqlite3_exec(storeOpt->rawDb(), sql, nullptr, nullptr, nullptr), SQLITE_OK);
+    }
+
+    bs::test::ServiceProcessHarness harness(
+        QStringLiteral("query"), QStringLiteral("betterspotlight-query"));
+    bs::test::ServiceLaunchConfig launch;
+    launch.homeDir = tempHome.path();
+    launch.dataDir = dataDir;
+    QVERIFY2(harness.start(launch), "Failed to start query service");
+
+    QJsonObject params;
+    params[QStringLiteral("query")] = QStringLiteral("quarterly budget");
+    params[QStringLiteral("deadlineMs")] = QDateTime::currentMSecsSinceEpoch() - 1000;
+    const QJsonObject search = harness.request(QStringLiteral("search"), params, 5000);
+    QVERIFY(bs::test::isResponse(search));
+    QVERIFY(bs::test::resultPayload(search).value(QStringLiteral("deadlineExceeded")).toBool());
+
+    const QJsonObject history = harness.request(QStringLiteral("getQueryHistory"));
+    QVERIFY(bs::test::isResponse(history));
+    const QJsonObject result = bs::test::resultPayload(history);
+    QCOMPARE(result.value(QStringLiteral("total")).toInt(), 1);
+    const QJsonArray entries = result.value(QStringLiteral("entries")).toArray();
+    QCOMPARE(entries.size(), 1);
+    QCOMPARE(entries.first().toObject().value(QStringLiteral("query")).toString(),
+             QStringLiteral("quarterly budget"));
+}
+
 QTEST_MAIN(TestQueryServiceIpcExtensions)
 #include "test_query_service_ipc_extensions.moc"
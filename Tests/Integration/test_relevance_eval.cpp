@@ -0,0 +1,128 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "golden_queries.h"
+#include "ipc_test_utils.h"
+#include "relevance_eval.h"
+#include "service_process_harness.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QSaveFile>
+#include <QTemporaryDir>
+
+#ifndef BS_RELEVANCE_JUDGMENTS_PATH
+#define BS_RELEVANCE_JUDGMENTS_PATH ""
+#endif
+
+namespace {
+
+QString resolveJudgmentsPath()
+{
+    const QString fromEnv = qEnvironmentVariable("BS_RELEVANCE_JUDGMENTS");
+    if (!fromEnv.isEmpty()) {
+        return fromEnv;
+    }
+    return QString::fromUtf8(BS_RELEVANCE_JUDGMENTS_PATH);
+}
+
+} // namespace
+
+class TestRelevanceEval : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRankerConfigurationsViaIpc();
+};
+
+void TestRelevanceEval::testRankerConfigurationsViaIpc()
+{
+    const QString judgmentsPath = resolveJudgmentsPath();
+    QVERIFY2(QFileInfo::exists(judgmentsPath),
+             qPrintable(QStringLiteral("Judgments not found: %1").arg(judgmentsPath)));
+    QString error;
+    const auto set = bs::test::RelevanceEval::loadJudgments(judgmentsPath, &error);
+    QVERIFY2(set.has_value(), qPrintable(error));
+
+    const QString fixtureRoot = QDir::cleanPath(
+        QDir(QFileInfo(judgmentsPath).absolutePath())
+            .filePath(QStringLiteral("../Fixtures/") + set->fixtureId));
+    QVERIFY2(QFileInfo(fixtureRoot).isDir(),
+             qPrintable(QStringLiteral("Fixture not found: %1").arg(fixtureRoot)));
+
+    std::vector<bs::test::ConfigScore> scores;
+    for (const bs::test::RankerConfig& config : set->configs) {
+        // A fresh home per configuration: some settings (the BM25 weights)
+        // are applied when the store opens, and none may leak into the next.
+        QTemporaryDir tempHome;
+        QVERIFY(tempHome.isValid());
+        const QString dataDir = QDir(tempHome.path())
+                                    .filePath(QStringLiteral("Library/Application Support/betterspotlight"));
+        QVERIFY(QDir().mkpath(dataDir));
+        {
+            auto store = bs::SQLiteStore::open(QDir(dataDir).filePath(QStringLiteral("index.db")));
+            QVERIFY2(store.has_value(), "Failed to open the fixture store");
+            QVERIFY2(bs::test::GoldenQueries::seedFixtureHome(
+                         *store, fixtureRoot,
+                         QDir(tempHome.path()).filePath(QStringLiteral("Documents")), &error)
+                         > 0,
+                     qPrintable(error));
+            for (auto it = config.settings.cbegin(); it != config.settings.cend(); ++it) {
+                QVERIFY(store->setSetting(it.key(), it.value()));
+            }
+        }
+
+        bs::test::ServiceProcessHarness harness(QStringLiteral("query"),
+                                                QStringLiteral("betterspotlight-query"));
+        bs::test::ServiceLaunchConfig launch;
+        launch.homeDir = tempHome.path();
+        launch.dataDir = dataDir;
+        launch.requestDefaultTimeoutMs = 8000;
+        QVERIFY2(harness.start(launch),
+                 qPrintable(QStringLiteral("Failed to start query service for %1").arg(config.name)));
+
+        std::vector<bs::test::QueryScore> queries;
+        for (const bs::test::RelevanceJudgment& judgment : set->judgments) {
+            QJsonObject params;
+            params[QStringLiteral("query")] = judgment.query;
+            params[QStringLiteral("limit")] = set->cutoff;
+            params[QStringLiteral("queryMode")] = judgment.mode;
+            params[QStringLiteral("noCache")] = true;
+            const QJsonObject response = harness.request(QStringLiteral("search"), params);
+            QVERIFY2(bs::test::isResponse(response),
+                     qPrintable(QStringLiteral("%1/%2: search failed").arg(config.name, judgment.id)));
+
+            QStringList ranked;
+            const QJsonArray results =
+                bs::test::resultPayload(response).value(QStringLiteral("results")).toArray();
+            for (const QJsonValue& result : results) {
+                ranked.append(QFileInfo(result.toObject().value(QStringLiteral("path")).toString())
+                                  .fileName());
+            }
+            queries.push_back(bs::test::RelevanceEval::score(judgment, ranked, set->cutoff));
+        }
+        harness.stop();
+        scores.push_back(bs::test::RelevanceEval::aggregate(config.name, std::move(queries)));
+    }
+
+    qInfo().noquote() << bs::test::RelevanceEval::formatReport(scores, set->cutoff);
+
+    const QString reportPath = qEnvironmentVariable("BS_RELEVANCE_EVAL_REPORT_PATH").trimmed();
+    if (!reportPath.isEmpty()) {
+        QJsonObject report = bs::test::RelevanceEval::reportToJson(scores, set->cutoff);
+        report[QStringLiteral("judgmentsPath")] = judgmentsPath;
+        report[QStringLiteral("timestampUtc")] =
+            QDateTime::currentDateTimeUtc().toString(Qt::ISODate);
+        QSaveFile file(reportPath);
+        QVERIFY(file.open(QIODevice::WriteOnly));
+        file.write(QJsonDocument(report).toJson(QJsonDocument::Indented));
+        QVERIFY(file.commit());
+    }
+}
+
+QTEST_MAIN(TestRelevanceEval)
+#include "test_relevance_eval.moc"
@@ -0,0 +1,608 @@
+#include "corpus_generator.h"
+
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+#include <QSet>
+
+#include <algorithm>
+#include <random>
+
+namespace bs::test {
+
+namespace {
+
+struct KindName {
+    CorpusEntryKind kind;
+    const char* name;
+};
+
+constexpr KindName kKindNames[] = {
+    {CorpusEntryKind::Text, "text"},
+    {CorpusEntryKind::Markdown, "markdown"},
+    {CorpusEntryKind::Html, "html"},
+    {CorpusEntryKind::Source, "source"},
+    {CorpusEntryKind::Csv, "csv"},
+    {CorpusEntryKind::Json, "json"},
+    {CorpusEntryKind::Binary, "binary"},
+    {CorpusEntryKind::Directory, "directory"},
+    {CorpusEntryKind::Symlink, "symlink"},
+    {CorpusEntryKind::EdgeNames, "edge-names"},
+};
+
+// The header a Binary entry starts with, by its "magic" name.
+QByteArray magicBytes(const QString& magic)
+{
+    if (magic == QLatin1String("png")) return QByteArray("\x89PNG\r\n\x1a\n", 8);
+    if (magic == QLatin1String("jpeg")) return QByteArray("\xff\xd8\xff\xe0", 4);
+    if (magic == QLatin1String("pdf")) return QByteArrayLiteral("%PDF-1.4\n");
+    if (magic == QLatin1String("zip")) return QByteArray("PK\x03\x04", 4);
+    if (magic == QLatin1String("macho")) return QByteArray("\xcf\xfa\xed\xfe", 4);
+    return {};
+}
+
+bool isKnownMagic(const QString& magic)
+{
+    return magic.isEmpty() || !magicBytes(magic).isEmpty();
+}
+
+// The language family a Source entry's extension selects; empty for none.
+QString languageOf(const QString& path)
+{
+    const QString suffix = QFileInfo(path).suffix().toLower();
+    static const QSet<QString> cFamily = {QStringLiteral("c"), QStringLiteral("h"),
+                                          QStringLiteral("cc"), QStringLiteral("cpp"),
+                                          QStringLiteral("hpp"), QStringLiteral("mm")};
+    if (cFamily.contains(suffix)) return QStringLiteral("c");
+    static const QSet<QString> known = {QStringLiteral("py"), QStringLiteral("js"),
+                                        QStringLiteral("ts"), QStringLiteral("swift"),
+                                        QStringLiteral("go"), QStringLiteral("rs"),
+                                        QStringLiteral("java"), QStringLiteral("kt"),
+                                        QStringLiteral("rb"), QStringLiteral("sh")};
+    return known.contains(suffix) ? suffix : QString();
+}
+
+// Relative, inside the root, no empty or dot components.
+bool isContainedPath(const QString& path)
+{
+    if (path.isEmpty() || path.startsWith(QLatin1Char('/'))) {
+        return false;
+    }
+    for (const QString& part : path.split(QLatin1Char('/'))) {
+        if (part.isEmpty() || part == QLatin1String(".") || part == QLatin1String("..")) {
+            return false;
+        }
+    }
+    return true;
+}
+
+QString expandPath(const QString& path, int n, int count)
+{
+    const int width = static_cast<int>(QString::number(count).size());
+    return QString(path).replace(QLatin1String("{n}"),
+                                 QStringLiteral("%1").arg(n, width, 10, QLatin1Char('0')));
+}
+
+// FNV-1a: unlike qHash, the same on every run.
+quint32 stableHash(const QByteArray& bytes)
+{
+    quint32 hash = 2166136261u;
+    for (const char byte : bytes) {
+        hash ^= static_cast<quint8>(byte);
+        hash *= 16777619u;
+    }
+    return hash;
+}
+
+// Filler prose over a fixed vocabulary. Only raw engine output is used,
+// never a std distribution, whose results differ between standard
+// libraries.
+class TextGenerator {
+public:
+    TextGenerator(quint32 seed, QStringList keywords)
+        : m_rng(seed)
+        , m_keywords(std::move(keywords))
+    {
+    }
+
+    int written() const { return m_written; }
+    quint32 next(quint32 bound) { return bound == 0 ? 0 : static_cast<quint32>(m_rng()) % bound; }
+
+    QString word()
+    {
+        static const char* const kVocabulary[] = {
+            "project",  "budget",   "meeting",  "summary",  "report",   "design",
+            "review",   "schedule", "invoice",  "travel",   "research", "analysis",
+            "customer", "release",  "draft",    "proposal", "contract", "launch",
+            "metrics",  "roadmap",  "feedback", "team",     "quarter",  "update",
+            "server",   "database", "network",  "request",  "response", "cache",
+            "index",    "search",   "query",    "result",   "document", "folder",
+            "archive",  "backup",   "storage",  "memory",   "thread",   "kernel",
+            "garden",   "recipe",   "kitchen",  "holiday",  "weekend",  "family",
+            "ocean",    "mountain", "river",    "forest",   "city",     "morning",
+            "evening",  "winter",   "summer",   "coffee",   "letter",   "notebook",
+            "camera",   "guitar",   "library",  "station",
+        };
+        constexpr quint32 kSize = sizeof(kVocabulary) / sizeof(kVocabulary[0]);
+        ++m_written;
+        return QLatin1String(kVocabulary[next(kSize)]);
+    }
+
+    // The first sentence carries every keyword.
+    QString sentence()
+    {
+        QStringList words;
+        const int length = 6 + static_cast<int>(next(9));
+        for (int i = 0; i < length; ++i) {
+            words.append(word());
+        }
+        for (const QString& keyword : std::as_const(m_keywords)) {
+            words.insert(static_cast<qsizetype>(next(static_cast<quint32>(words.size() + 1))),
+                         keyword);
+            ++m_written;
+        }
+        m_keywords.clear();
+        QString text = words.join(QLatin1Char(' '));
+        text[0] = text[0].toUpper();
+        return text + QLatin1Char('.');
+    }
+
+    QString paragraph()
+    {
+        QStringList sentences;
+        const int length = 3 + static_cast<int>(next(4));
+        for (int i = 0; i < length; ++i) {
+            sentences.append(sentence());
+        }
+        return sentences.join(QLatin1Char(' '));
+    }
+
+    QString title(int words)
+    {
+        QStringList parts;
+        for (int i = 0; i < words; ++i) {
+            QString part = word();
+            part[0] = part[0].toUpper();
+            parts.append(part);
+        }
+        return parts.join(QLatin1Char(' '));
+    }
+
+    QString identifier(bool camelCase)
+    {
+        const QString first = word();
+        QString second = word();
+        if (!camelCase) {
+            return first + QLatin1Char('_') + second;
+        }
+        second[0] = second[0].toUpper();
+        return first + second;
+    }
+
+private:
+    std::mt19937 m_rng;
+    QStringList m_keywords;
+    int m_written = 0;
+};
+
+QString titleFromPath(const QString& path)
+{
+    QString stem = QFileInfo(path).completeBaseName();
+    stem.replace(QLatin1Char('-'), QLatin1Char(' ')).replace(QLatin1Char('_'), QLatin1Char(' '));
+    if (!stem.isEmpty()) {
+        stem[0] = stem[0].toUpper();
+    }
+    return stem;
+}
+
+QString plainText(TextGenerator& generator, int words)
+{
+    QStringList paragraphs;
+    do {
+        paragraphs.append(generator.paragraph());
+    } while (generator.written() < words);
+    return paragraphs.join(QStringLiteral("\n\n")) + QLatin1Char('\n');
+}
+
+QString markdownText(TextGenerator& generator, const QString& path, int words)
+{
+    QString text = QStringLiteral("# %1\n\n%2\n").arg(titleFromPath(path), generator.paragraph());
+    while (generator.written() < words) {
+        // One call a statement: argument order is unspecified.
+        const QString heading = generator.title(2);
+        text += QStringLiteral("\n## %1\n\n%2\n").arg(heading, generator.paragraph());
+        if (generator.next(3) == 0) {
+            text += QLatin1Char('\n');
+            for (int i = 0; i < 3; ++i) {
+                text += QStringLiteral("- %1\n").arg(generator.sentence());
+            }
+        }
+    }
+    return text;
+}
+
+QString htmlText(TextGenerator& generator, const QString& path, int words)
+{
+    const QString title = titleFromPath(path);
+    QString body;
+    do {
+        body += QStringLiteral("<p>%1</p>\n").arg(generator.paragraph());
+    } while (generator.written() < words);
+    return QStringLiteral("<!DOCTYPE html>\n<html>\n<head><title>%1</title></head>\n<body>\n"
+                          "<h1>%1</h1>\n%2</body>\n</html>\n")
+        .arg(title, body);
+}
+
+QString sourceText(TextGenerator& generator, const QString& language, int words)
+{
+    const bool hashComments = language == QLatin1String("py") || language == QLatin1String("rb")
+                              || language == QLatin1String("sh");
+    const QString comment = hashComments ? QStringLiteral("# ") : QStringLiteral("// ");
+    const bool camelCase = language != QLatin1String("c") && language != QLatin1String("py")
+                           && language != QLatin1String("rs") && language != QLatin1String("rb")
+                           && language != QLatin1String("sh");
+
+    QString text;
+    if (language == QLatin1String("sh")) {
+        text += QStringLiteral("#!/bin/sh\n");
+    } else if (language == QLatin1String("go")) {
+        text += QStringLiteral("package main\n");
+    } else if (language == QLatin1String("c")) {
+        text += QStringLiteral("#include <stdint.h>\n");
+    }
+    text += QLatin1Char('\n') + comment + generator.sentence() + QLatin1Char('\n');
+    while (generator.written() < words) {
+        const QString name = generator.identifier(camelCase);
+        const QString factor = QString::number(2 + generator.next(97));
+        text += QLatin1Char('\n') + comment + generator.sentence() + QLatin1Char('\n');
+        if (language == QLatin1String("c")) {
+            text += QStringLiteral("int %1(int value)\n{\n    return value * %2;\n}\n").arg(name, factor);
+        } else if (language == QLatin1String("py")) {
+            text += QStringLiteral("def %1(value):\n    return value * %2\n").arg(name, factor);
+        } else if (language == QLatin1String("js") || language == QLatin1String("ts")) {
+            text += QStringLiteral("export function %1(value) {\n  return value * %2;\n}\n")
+                        .arg(name, factor);
+        } else if (language == QLatin1String("swift")) {
+            text += QStringLiteral("func %1(_ value: Int) -> Int {\n    return value * %2\n}\n")
+                        .arg(name, factor);
+        } else if (language == QLatin1String("go")) {
+            text += QStringLiteral("func %1(value int) int {\n\treturn value * %2\n}\n").arg(name, factor);
+        } else if (language == QLatin1String("rs")) {
+            text += QStringLiteral("fn %1(value: i64) -> i64 {\n    value * %2\n}\n").arg(name, factor);
+        } else if (language == QLatin1String("java")) {
+            text += QStringLiteral("static int %1(int value) {\n    return value * %2;\n}\n")
+                        .arg(name, factor);
+        } else if (language == QLatin1String("kt")) {
+            text += QStringLiteral("fun %1(value: Int): Int = value * %2\n").arg(name, factor);
+        } else if (language == QLatin1String("rb")) {
+            text += QStringLiteral("def %1(value)\n  value * %2\nend\n").arg(name, factor);
+        } else {
+            text += QStringLiteral("%1() {\n  echo $(( $1 * %2 ))\n}\n").arg(name, factor);
+        }
+    }
+    return text;
+}
+
+QString csvText(TextGenerator& generator, int words)
+{
+    QString text = QStringLiteral("id,name,category,amount,date,note\n");
+    int row = 0;
+    do {
+        ++row;
+        const QString name = generator.title(2);
+        const QString category = generator.word();
+        text += QStringLiteral("%1,%2,%3,%4.%5,2026-%6-%7,\"%8\"\n")
+                    .arg(row)
+                    .arg(name, category)
+                    .arg(generator.next(10000))
+                    .arg(generator.next(100), 2, 10, QLatin1Char('0'))
+                    .arg(1 + generator.next(12), 2, 10, QLatin1Char('0'))
+                    .arg(1 + generator.next(28), 2, 10, QLatin1Char('0'))
+                    .arg(generator.sentence());
+    } while (generator.written() < words);
+    return text;
+}
+
+QString jsonText(TextGenerator& generator, int words)
+{
+    QJsonObject object;
+    object[QStringLiteral("title")] = generator.sentence();
+    QJsonArray items;
+    do {
+        QJsonObject item;
+        item[QStringLiteral("id")] = static_cast<int>(items.size()) + 1;
+        item[QStringLiteral("name")] = generator.identifier(true);
+        item[QStringLiteral("note")] = generator.sentence();
+        items.append(item);
+    } while (generator.written() < words);
+    object[QStringLiteral("items")] = items;
+    return QString::fromUtf8(QJsonDocument(object).toJson(QJsonDocument::Indented));
+}
+
+QByteArray binaryBytes(std::mt19937& rng, const QString& magic, int64_t size)
+{
+    QByteArray bytes = magicBytes(magic);
+    bytes.reserve(static_cast<qsizetype>(size));
+    while (bytes.size() < size) {
+        bytes.append(static_cast<char>(rng() & 0xff));
+    }
+    return bytes;
+}
+
+QByteArray contentOf(const CorpusEntry& entry, const QString& path, quint32 seed)
+{
+    if (entry.kind == CorpusEntryKind::Binary) {
+        std::mt19937 rng(seed);
+        return binaryBytes(rng, entry.magic, entry.size);
+    }
+    if (!entry.text.isEmpty()) {
+        return entry.text.toUtf8();
+    }
+    TextGenerator generator(seed, entry.keywords);
+    switch (entry.kind) {
+    case CorpusEntryKind::Markdown:
+        return markdownText(generator, path, entry.words).toUtf8();
+    case CorpusEntryKind::Html:
+        return htmlText(generator, path, entry.words).toUtf8();
+    case CorpusEntryKind::Source:
+        return sourceText(generator, languageOf(path), entry.words).toUtf8();
+    case CorpusEntryKind::Csv:
+        return csvText(generator, entry.words).toUtf8();
+    case CorpusEntryKind::Json:
+        return jsonText(generator, entry.words).toUtf8();
+    default:
+        return plainText(generator, entry.words).toUtf8();
+    }
+}
+
+quint32 pathSeed(const QString& relative, quint32 specSeed)
+{
+    return stableHash(relative.toUtf8()) ^ (specSeed * 0x9e3779b9u);
+}
+
+bool writeFile(const QString& path, const QByteArray& content, const QDateTime& modifiedAt)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::WriteOnly | QIODevice::NewOnly)
+        || file.write(content) != content.size() || !file.flush()) {
+        return false;
+    }
+    return !modifiedAt.isValid()
+           || file.setFileTime(modifiedAt, QFileDevice::FileModificationTime);
+}
+
+bool existsOrLinks(const QString& path)
+{
+    const QFileInfo info(path);
+    return info.exists() || info.isSymLink();
+}
+
+} // namespace
+
+std::optional<CorpusSpec> CorpusGenerator::parseSpec(const QByteArray& json, QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("A corpus spec is a JSON object: %1").arg(parseError.errorString());
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    CorpusSpec spec;
+    spec.name = object.value(QStringLiteral("name")).toString();
+    spec.seed = static_cast<quint32>(object.value(QStringLiteral("seed")).toInteger(1));
+
+    const QJsonArray entries = object.value(QStringLiteral("entries")).toArray();
+    for (qsizetype i = 0; i < entries.size(); ++i) {
+        const QJsonObject json = entries.at(i).toObject();
+        const auto fail = [error, i](const QString& message) {
+            *error = QStringLiteral("Entry %1: %2").arg(i + 1).arg(message);
+            return std::nullopt;
+        };
+
+        CorpusEntry entry;
+        const QString kind = json.value(QStringLiteral("kind")).toString();
+        const auto known = std::find_if(std::begin(kKindNames), std::end(kKindNames),
+                                        [&kind](const KindName& k) {
+                                            return kind == QLatin1String(k.name);
+                                        });
+        if (known == std::end(kKindNames)) {
+            return fail(QStringLiteral("unknown kind '%1'").arg(kind));
+        }
+        entry.kind = known->kind;
+        entry.path = json.value(QStringLiteral("path")).toString();
+        if (!isContainedPath(entry.path)) {
+            return fail(QStringLiteral("'%1' is not a relative path inside the corpus")
+                            .arg(entry.path));
+        }
+        entry.count = json.value(QStringLiteral("count")).toInt(1);
+        if (entry.count < 1 || entry.count > kMaxCount) {
+            return fail(QStringLiteral("count must be 1 to %1").arg(kMaxCount));
+        }
+        if (entry.count > 1 && !entry.path.contains(QLatin1String("{n}"))) {
+            return fail(QStringLiteral("a count needs {n} in the path"));
+        }
+        entry.text = json.value(QStringLiteral("text")).toString();
+        for (const QJsonValue& keyword : json.value(QStringLiteral("keywords")).toArray()) {
+            entry.keywords.append(keyword.toString());
+        }
+        entry.words = json.value(QStringLiteral("words")).toInt(entry.words);
+        if (entry.words < 1 || entry.words > kMaxWords) {
+            return fail(QStringLiteral("words must be 1 to %1").arg(kMaxWords));
+        }
+        entry.magic = json.value(QStringLiteral("magic")).toString();
+        if (!isKnownMagic(entry.magic)) {
+            return fail(QStringLiteral("unknown magic '%1'").arg(entry.magic));
+        }
+        entry.size = json.value(QStringLiteral("size")).toInteger(entry.size);
+        if (entry.kind == CorpusEntryKind::Binary
+            && (entry.size < magicBytes(entry.magic).size() || entry.size > kMaxBinaryBytes)) {
+            return fail(QStringLiteral("size must fit the magic and be at most %1 bytes")
+                            .arg(kMaxBinaryBytes));
+        }
+        entry.target = json.value(QStringLiteral("target")).toString();
+        if (entry.kind == CorpusEntryKind::Symlink && entry.target.isEmpty()) {
+            return fail(QStringLiteral("a symlink needs a target"));
+        }
+        if (entry.kind == CorpusEntryKind::Source && languageOf(entry.path).isEmpty()) {
+            return fail(QStringLiteral("no source language for '%1'").arg(entry.path));
+        }
+        const QString modifiedAt = json.value(QStringLiteral("modifiedAt")).toString();
+        if (!modifiedAt.isEmpty()) {
+            entry.modifiedAt = QDateTime::fromString(modifiedAt, Qt::ISODate);
+            if (!entry.modifiedAt.isValid()) {
+                return fail(QStringLiteral("modifiedAt '%1' is not an ISO 8601 date")
+                                .arg(modifiedAt));
+            }
+        }
+        spec.entries.push_back(std::move(entry));
+    }
+    return spec;
+}
+
+std::optional<CorpusSpec> CorpusGenerator::loadSpec(const QString& path, QString* error)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("Cannot read %1").arg(path);
+        return std::nullopt;
+    }
+    return parseSpec(file.readAll(), error);
+}
+
+std::optional<CorpusReport> CorpusGenerator::generate(const CorpusSpec& spec, const QString& root,
+                                                      QString* error)
+{
+    const QDir rootDir(root);
+    if (!QDir().mkpath(root)) {
+        *error = QStringLiteral("Cannot create %1").arg(root);
+        return std::nullopt;
+    }
+
+    CorpusReport report;
+    QSet<QString> directories;
+    // Creates the directories of `relative` up to the root, counting them.
+    const auto makeDirectory = [&](const QString& relative) {
+        QStringList missing;
+        for (QString dir = relative; !dir.isEmpty() && dir != QLatin1String(".");
+             dir = QFileInfo(dir).path()) {
+            if (!directories.contains(dir) && !QFileInfo(rootDir.filePath(dir)).isDir()) {
+                missing.prepend(dir);
+            }
+        }
+        if (!rootDir.mkpath(relative)) {
+            return false;
+        }
+        for (const QString& dir : missing) {
+            directories.insert(dir);
+            report.paths.append(dir);
+            ++report.directories;
+        }
+        return true;
+    };
+    const auto writeOne = [&](const QString& relative, const QByteArray& content,
+                              const QDateTime& modifiedAt) {
+        if (!writeFile(rootDir.filePath(relative), content, modifiedAt)) {
+            *error = QStringLiteral("Cannot write %1").arg(rootDir.filePath(relative));
+            return false;
+        }
+        report.paths.append(relative);
+        ++report.files;
+        report.bytes += content.size();
+        return true;
+    };
+
+    for (const CorpusEntry& entry : spec.entries) {
+        for (int n = 1; n <= entry.count; ++n) {
+            const QString relative = expandPath(entry.path, n, entry.count);
+            const QString absolute = rootDir.filePath(relative);
+            // A directory an earlier entry's files already made is fine.
+            if (existsOrLinks(absolute)
+                && !(entry.kind == CorpusEntryKind::Directory && QFileInfo(absolute).isDir())) {
+                *error = QStringLiteral("%1 already exists").arg(absolute);
+                return std::nullopt;
+            }
+            const QString parent = QFileInfo(relative).path();
+            if (parent != QLatin1String(".") && !makeDirectory(parent)) {
+                *error = QStringLiteral("Cannot create %1").arg(rootDir.filePath(parent));
+                return std::nullopt;
+            }
+            const quint32 seed = pathSeed(relative, spec.seed);
+
+            switch (entry.kind) {
+            case CorpusEntryKind::Directory:
+                if (!makeDirectory(relative)) {
+                    *error = QStringLiteral("Cannot create %1").arg(absolute);
+                    return std::nullopt;
+                }
+                break;
+            case CorpusEntryKind::Symlink:
+                if (!QFile::link(entry.target, absolute)) {
+                    *error = QStringLiteral("Cannot link %1").arg(absolute);
+                    return std::nullopt;
+                }
+                report.paths.append(relative);
+                ++report.symlinks;
+                break;
+            case CorpusEntryKind::EdgeNames:
+                if (!makeDirectory(relative)) {
+                    *error = QStringLiteral("Cannot create %1").arg(absolute);
+                    return std::nullopt;
+                }
+                for (const QString& name : edgeCaseNames()) {
+                    const QString file = relative + QLatin1Char('/') + name;
+                    TextGenerator generator(stableHash(file.toUtf8()) ^ seed, entry.keywords);
+                    const QByteArray content =
+                        (QStringLiteral("Edge case file: %1\n\n").arg(name)
+                         + plainText(generator, std::min(entry.words, 40)))
+                            .toUtf8();
+                    if (!writeOne(file, content, entry.modifiedAt)) {
+                        return std::nullopt;
+                    }
+                }
+                break;
+            default:
+                if (!writeOne(relative, content(entry, relative, spec.seed), entry.modifiedAt)) {
+                    return std::nullopt;
+                }
+                break;
+            }
+        }
+    }
+    return report;
+}
+
+QByteArray CorpusGenerator::content(const CorpusEntry& entry, const QString& relative,
+                                    quint32 specSeed)
+{
+    return contentOf(entry, relative, pathSeed(relative, specSeed));
+}
+
+QStringList CorpusGenerator::edgeCaseNames()
+{
+    // 255 bytes, the longest name APFS allows.
+    const QString longName = QStringLiteral("long-") + QString(246, QLatin1Char('n'))
+                             + QStringLiteral(".txt");
+    return {
+        QStringLiteral("file with spaces.txt"),
+        QStringLiteral("MiXeDcAsE.Txt"),
+        QStringLiteral("UPPERCASE.TXT"),
+        QString::fromUtf8("caf\xc3\xa9 r\xc3\xa9sum\xc3\xa9.txt"),
+        QString::fromUtf8("\xe6\x97\xa5\xe6\x9c\xac\xe8\xaa\x9e\xe3\x81\xae\xe3\x83\xa1\xe3\x83\xa2.txt"),
+        QString::fromUtf8("emoji \xf0\x9f\x93\x84 notes.md"),
+        QStringLiteral(".hidden-notes.txt"),
+        QStringLiteral("-leading-dash.txt"),
+        QStringLiteral("semi;colon & ampersand.txt"),
+        QStringLiteral("quote's \"double\".txt"),
+        QStringLiteral("#hash %percent.txt"),
+        QStringLiteral("no_extension"),
+        QStringLiteral("multiple.dots.in.name.txt"),
+        longName,
+    };
+}
+
+} // namespace bs::test
@@ -0,0 +1,98 @@
+#pragma once
+
+#include <QByteArray>
+#include <QDateTime>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs::test {
+
+enum class CorpusEntryKind {
+    Text,
+    Markdown,
+    Html,
+    Source,     // code in the language of its extension
+    Csv,
+    Json,
+    Binary,     // random bytes after an optional magic header
+    Directory,  // an empty directory
+    Symlink,
+    EdgeNames,  // a directory of CorpusGenerator::edgeCaseNames() files
+};
+
+// One line of a corpus spec: a file, or `count` files when the path holds
+// "{n}" (numbered from 1, zero-padded to the width of `count`).
+struct CorpusEntry {
+    CorpusEntryKind kind = CorpusEntryKind::Text;
+    QString path;           // relative to the corpus root
+    int count = 1;
+    QString text;           // the content verbatim; generated when empty
+    QStringList keywords;   // planted in generated text, for queries to find
+    int words = 120;        // length of generated text
+    int64_t size = 4096;    // Binary, magic included
+    QString magic;          // Binary: "png", "jpeg", "pdf", "zip", "macho"
+    QString target;         // Symlink, as stored in the link; may dangle
+    QDateTime modifiedAt;   // invalid leaves the time of writing
+};
+
+struct CorpusSpec {
+    QString name;
+    quint32 seed = 1;
+    std::vector<CorpusEntry> entries;
+};
+
+struct CorpusReport {
+    int files = 0;
+    int directories = 0;
+    int symlinks = 0;
+    int64_t bytes = 0;
+    QStringList paths;      // relative, in the order written
+};
+
+// CorpusGenerator -- builds fixture trees like Tests/Fixtures/standard_home_v1
+// from a declarative spec, for tests (and the `corpusgen` tool) that need
+// many realistic files without committing them.
+//
+// Generated content is a function of the spec's seed and each file's path
+// alone, so a spec builds the same bytes on every run and machine, and
+// adding an entry does not change the others.
+//
+//   {"name": "edge-home", "seed": 7, "entries": [
+//     {"kind": "markdown", "path": "Documents/notes-{n}.md", "count": 20,
+//      "keywords": ["quarterly"], "modifiedAt": "2026-01-15T09:30:00Z"},
+//     {"kind": "source", "path": "Projects/app/src/main.cpp"},
+//     {"kind": "binary", "path": "Downloads/photo.png", "magic": "png", "size": 2048},
+//     {"kind": "symlink", "path": "Desktop/notes", "target": "../Documents"},
+//     {"kind": "edge-names", "path": "Edge Cases"}]}
+class CorpusGenerator {
+public:
+    static constexpr int kMaxCount = 10000;
+    static constexpr int kMaxWords = 100000;
+    static constexpr int64_t kMaxBinaryBytes = 64 * 1024 * 1024;
+
+    // Nullopt with *error for JSON that is not a spec: an unknown kind or
+    // magic, a path that is absolute or climbs out with "..", a count
+    // without "{n}", a symlink without a target.
+    static std::optional<CorpusSpec> parseSpec(const QByteArray& json, QString* error);
+    static std::optional<CorpusSpec> loadSpec(const QString& path, QString* error);
+
+    // Write `spec` under `root`, creating it. Nothing is overwritten: an
+    // entry that already exists is an error.
+    static std::optional<CorpusReport> generate(const CorpusSpec& spec, const QString& root,
+                                                QString* error);
+
+    // The bytes `generate` writes for a file of `entry` at `relative`, for
+    // generators that lay out their own trees.
+    static QByteArray content(const CorpusEntry& entry, const QString& relative, quint32 specSeed);
+
+    // The file names an EdgeNames entry creates: spaces, mixed case,
+    // accents, CJK, emoji, a leading dot or dash, shell metacharacters, no
+    // extension, several dots, and a 255-byte name.
+    static QStringList edgeCaseNames();
+};
+
+} // namespace bs::test
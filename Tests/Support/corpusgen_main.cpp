@@ -0,0 +1,175 @@
+// corpusgen -- writes a fixture tree from a corpus spec (corpus_generator.h),
+// a large synthetic home for benchmarks (synthetic_home.h), or the
+// standard_home_v2 fixture home (fixture_home.h).
+//
+//   corpusgen Tests/Fixtures/corpus_specs/extractor_edge_cases.json /tmp/edge-home
+//   corpusgen --synthetic-home --files 1000000 /tmp/big-home
+//   corpusgen --standard-home-v2 /tmp/home-v2
+
+#include "corpus_generator.h"
+#include "fixture_home.h"
+#include "synthetic_home.h"
+
+#include <QCommandLineParser>
+#include <QCoreApplication>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QSaveFile>
+#include <QTextStream>
+
+int main(int argc, char* argv[])
+{
+    QCoreApplication app(argc, argv);
+    QCoreApplication::setApplicationName(QStringLiteral("corpusgen"));
+
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Build a fixture tree from a declarative corpus spec, with "
+                       "--synthetic-home a large home directory drawn from a model of a real "
+                       "one, or with --standard-home-v2 the fixture home of the integration "
+                       "tests. The same input writes the same bytes on every run.\n"
+                       "Exit status: 0 written, 1 the spec is invalid or a file could not be "
+                       "written, 2 usage error."));
+    parser.addHelpOption();
+    parser.addPositionalArgument(QStringLiteral("spec"),
+                                 QStringLiteral("The corpus spec (JSON); not with "
+                                                "--synthetic-home or --standard-home-v2."));
+    parser.addPositionalArgument(QStringLiteral("output"),
+                                 QStringLiteral("The directory to write; it must be empty or "
+                                                "missing."));
+    const QCommandLineOption forceOption(
+        QStringLiteral("force"), QStringLiteral("Delete the output directory's contents first."));
+    parser.addOption(forceOption);
+    const QCommandLineOption syntheticOption(
+        QStringLiteral("synthetic-home"),
+        QStringLiteral("Write a synthetic home for indexing and query benchmarks instead of a "
+                       "spec's files."));
+    const QCommandLineOption filesOption(
+        QStringLiteral("files"), QStringLiteral("Files in the synthetic home (default 1000000)."),
+        QStringLiteral("n"), QStringLiteral("1000000"));
+    const QCommandLineOption seedOption(
+        QStringLiteral("seed"), QStringLiteral("Seed of the synthetic home (default 1)."),
+        QStringLiteral("n"), QStringLiteral("1"));
+    const QCommandLineOption maxBytesOption(
+        QStringLiteral("max-file-bytes"),
+        QStringLiteral("Cap on each synthetic file's size (default 8192)."), QStringLiteral("bytes"),
+        QStringLiteral("8192"));
+    const QCommandLineOption manifestOption(
+        QStringLiteral("manifest"),
+        QStringLiteral("Where to write the synthetic home's counts and planted query tokens "
+                       "(default: <output>.manifest.json)."),
+        QStringLiteral("path"));
+    const QCommandLineOption standardHomeOption(
+        QStringLiteral("standard-home-v2"),
+        QStringLiteral("Write standard_home_v2, with its timestamps and extended attributes, "
+                       "instead of a spec's files."));
+    parser.addOptions({syntheticOption, filesOption, seedOption, maxBytesOption, manifestOption,
+                       standardHomeOption});
+    parser.process(app);
+
+    QTextStream out(stdout);
+    QTextStream err(stderr);
+    const QStringList args = parser.positionalArguments();
+    const bool synthetic = parser.isSet(syntheticOption);
+    const bool standardHome = parser.isSet(standardHomeOption);
+    if (synthetic && standardHome) {
+        err << "corpusgen: --synthetic-home and --standard-home-v2 are exclusive" << Qt::endl;
+        return 2;
+    }
+    if (args.size() != (synthetic || standardHome ? 1 : 2)) {
+        err << "corpusgen: expected "
+            << (synthetic || standardHome ? "an output directory"
+                                          : "a spec and an output directory")
+            << Qt::endl;
+        return 2;
+    }
+    bool filesOk = false;
+    bool seedOk = false;
+    bool maxBytesOk = false;
+    const qint64 files = parser.value(filesOption).toLongLong(&filesOk);
+    const uint seed = parser.value(seedOption).toUInt(&seedOk);
+    const qint64 maxFileBytes = parser.value(maxBytesOption).toLongLong(&maxBytesOk);
+    if (synthetic && (!filesOk || !seedOk || !maxBytesOk || maxFileBytes < 1)) {
+        err << "corpusgen: --files, --seed and --max-file-bytes take positive numbers" << Qt::endl;
+        return 2;
+    }
+    QString error;
+    std::optional<bs::test::CorpusSpec> spec;
+    if (!synthetic && !standardHome) {
+        spec = bs::test::CorpusGenerator::loadSpec(args.at(0), &error);
+        if (!spec.has_value()) {
+            err << "corpusgen: " << error << Qt::endl;
+            return 1;
+        }
+    }
+    const QString output = QDir::cleanPath(QFileInfo(args.constLast()).absoluteFilePath());
+    QDir outputDir(output);
+    if (outputDir.exists()
+        && !outputDir.isEmpty(QDir::AllEntries | QDir::NoDotAndDotDot | QDir::Hidden
+                              | QDir::System)) {
+        if (!parser.isSet(forceOption)) {
+            err << "corpusgen: " << output << " is not empty; --force replaces it" << Qt::endl;
+            return 2;
+        }
+        if (!outputDir.removeRecursively()) {
+            err << "corpusgen: cannot empty " << output << Qt::endl;
+            return 1;
+        }
+    }
+
+    if (synthetic) {
+        bs::test::SyntheticHomeProfile profile = bs::test::SyntheticHome::defaultProfile();
+        profile.seed = seed;
+        profile.maxFileBytes = maxFileBytes;
+        const auto report = bs::test::SyntheticHome::generate(
+            profile, files, output, &error, [&](int64_t written) {
+                if (written % 100000 == 0) {
+                    out << "  " << written << " / " << files << " files" << Qt::endl;
+                }
+            });
+        if (!report.has_value()) {
+            err << "corpusgen: " << error << Qt::endl;
+            return 1;
+        }
+        const QString manifestPath = parser.isSet(manifestOption)
+                                         ? parser.value(manifestOption)
+                                         : output + QStringLiteral(".manifest.json");
+        QSaveFile manifest(manifestPath);
+        if (!manifest.open(QIODevice::WriteOnly)
+            || manifest.write(QJsonDocument(bs::test::SyntheticHome::reportToJson(*report, profile))
+                                  .toJson(QJsonDocument::Indented))
+                   < 0
+            || !manifest.commit()) {
+            err << "corpusgen: cannot write " << manifestPath << Qt::endl;
+            return 1;
+        }
+        out << "Wrote " << report->files << " file(s) in " << report->directories
+            << " directories, " << report->bytes << " bytes (" << report->profileBytes
+            << " modeled), to " << output << "; manifest " << manifestPath << Qt::endl;
+        return 0;
+    }
+
+    if (standardHome) {
+        const auto report =
+            bs::test::FixtureHomeBuilder::standardHomeV2().build(output, &error);
+        if (!report.has_value()) {
+            err << "corpusgen: " << error << Qt::endl;
+            return 1;
+        }
+        out << "Wrote " << report->corpus.files << " file(s) from " << report->moduleFiles.size()
+            << " module(s), " << report->corpus.bytes << " bytes and " << report->xattrs
+            << " extended attribute(s), to " << output << Qt::endl;
+        return 0;
+    }
+
+    const auto report = bs::test::CorpusGenerator::generate(*spec, output, &error);
+    if (!report.has_value()) {
+        err << "corpusgen: " << error << Qt::endl;
+        return 1;
+    }
+    out << "Wrote " << report->files << " file(s), " << report->directories << " director"
+        << (report->directories == 1 ? "y" : "ies") << " and " << report->symlinks
+        << " symlink(s), " << report->bytes << " bytes, to " << output << Qt::endl;
+    return 0;
+}
@@ -0,0 +1,575 @@
+#include "extractor_conformance.h"
+
+#include "core/shared/resource_usage.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QDir>
+#include <QDirIterator>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+#include <QMap>
+#include <QSet>
+#include <QTemporaryDir>
+
+#include <algorithm>
+
+namespace bs::test {
+
+namespace {
+
+// durationMs is read from the extractor's own timer; allow for the two
+// clocks ticking apart.
+constexpr int64_t kDurationSlackMs = 5;
+constexpr qsizetype kQuotedChars = 40;
+
+struct StatusName {
+    ExtractionResult::Status status;
+    const char* name;
+};
+
+constexpr StatusName kStatusNames[] = {
+    {ExtractionResult::Status::Success, "success"},
+    {ExtractionResult::Status::Timeout, "timeout"},
+    {ExtractionResult::Status::CorruptedFile, "corrupted"},
+    {ExtractionResult::Status::UnsupportedFormat, "unsupported"},
+    {ExtractionResult::Status::SizeExceeded, "size_exceeded"},
+    {ExtractionResult::Status::Inaccessible, "inaccessible"},
+    {ExtractionResult::Status::Unknown, "unknown"},
+    {ExtractionResult::Status::Cancelled, "cancelled"},
+    {ExtractionResult::Status::Crashed, "crashed"},
+};
+
+QStringList stringList(const QJsonValue& value)
+{
+    QStringList out;
+    for (const QJsonValue& entry : value.toArray()) {
+        out.append(entry.toString());
+    }
+    return out;
+}
+
+// "text", shortened and with line breaks shown, for failure messages.
+QString quoted(const QString& text)
+{
+    QString shown = text.left(kQuotedChars);
+    shown.replace(QLatin1Char('\n'), QStringLiteral("\\n"));
+    shown.replace(QLatin1Char('\r'), QStringLiteral("\\r"));
+    if (text.size() > kQuotedChars) {
+        shown += QStringLiteral("...");
+    }
+    return QLatin1Char('"') + shown + QLatin1Char('"');
+}
+
+ConformanceFailure failure(const QString& check, const QString& message)
+{
+    return ConformanceFailure{check, QString(), message};
+}
+
+// Line (from 1) of `content` holding `text`, 0 when none does.
+int lineOf(const QString& content, const QString& text)
+{
+    const QStringList lines = content.split(QLatin1Char('\n'));
+    for (qsizetype i = 0; i < lines.size(); ++i) {
+        if (lines.at(i).contains(text)) {
+            return static_cast<int>(i) + 1;
+        }
+    }
+    return 0;
+}
+
+QString describe(const ExtractionResult& result)
+{
+    const QString name = ExtractorConformance::statusName(result.status);
+    return result.errorMessage.has_value() && !result.errorMessage->isEmpty()
+               ? QStringLiteral("%1 (%2)").arg(name, result.errorMessage.value())
+               : name;
+}
+
+// Size, modification time and a hash of every file under `root`, so the
+// run can tell whether an extractor wrote to its input.
+QMap<QString, QString> snapshot(const QString& root)
+{
+    QMap<QString, QString> files;
+    QDirIterator it(root, QDir::Files | QDir::Dirs | QDir::Hidden | QDir::NoDotAndDotDot,
+                    QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QString path = it.next();
+        const QFileInfo info(path);
+        QString state = info.isDir() ? QStringLiteral("dir")
+                                     : QStringLiteral("%1 %2").arg(info.size()).arg(
+                                           info.lastModified().toMSecsSinceEpoch());
+        QFile file(path);
+        if (info.isFile() && file.open(QIODevice::ReadOnly)) {
+            QCryptographicHash hash(QCryptographicHash::Sha1);
+            hash.addData(&file);
+            state += QLatin1Char(' ') + QString::fromLatin1(hash.result().toHex());
+        }
+        files.insert(QDir(root).relativeFilePath(path), state);
+    }
+    return files;
+}
+
+// The files a missing, directory or unreadable path must be reported for.
+void checkRobustness(FileExtractor& extractor, const ConformanceSuite& suite,
+                     ConformanceReport* report)
+{
+    const auto sample = std::find_if(suite.files.cbegin(), suite.files.cend(),
+                                     [](const ConformanceExpectation& file) {
+                                         return file.status == ExtractionResult::Status::Success;
+                                     });
+    if (sample == suite.files.cend()) {
+        return;
+    }
+    const QString samplePath = QDir(suite.directory).filePath(sample->path);
+    const QString suffix = QFileInfo(samplePath).suffix();
+    const QString dot = suffix.isEmpty() ? QString() : QLatin1Char('.') + suffix;
+
+    QTemporaryDir scratch;
+    if (!scratch.isValid()) {
+        report->failures.push_back(
+            ConformanceFailure{QStringLiteral("robustness"), QString(),
+                               QStringLiteral("Cannot create a scratch directory")});
+        return;
+    }
+    const QDir dir(scratch.path());
+    std::vector<std::pair<QString, QString>> cases = {
+        {QStringLiteral("a missing file"), dir.filePath(QStringLiteral("absent") + dot)},
+        {QStringLiteral("a directory"), dir.filePath(QStringLiteral("folder") + dot)},
+    };
+    dir.mkdir(QStringLiteral("folder") + dot);
+
+    // Root reads through any permission bits; the case is skipped there.
+    const QString unreadable = dir.filePath(QStringLiteral("unreadable") + dot);
+    if (QFile::copy(samplePath, unreadable)
+        && QFile::setPermissions(unreadable, QFileDevice::Permissions())) {
+        QFile probe(unreadable);
+        if (!probe.open(QIODevice::ReadOnly)) {
+            cases.emplace_back(QStringLiteral("an unreadable file"), unreadable);
+        }
+    }
+
+    for (const auto& [what, path] : cases) {
+        QElapsedTimer timer;
+        timer.start();
+        const ExtractionResult result = extractor.extract(path);
+        const int64_t wallMs = timer.elapsed();
+        ++report->extractions;
+        for (ConformanceFailure& fieldFailure : ExtractorConformance::checkFields(result, wallMs)) {
+            fieldFailure.message = what + QStringLiteral(": ") + fieldFailure.message;
+            report->failures.push_back(std::move(fieldFailure));
+        }
+        if (result.status != ExtractionResult::Status::Inaccessible) {
+            report->failures.push_back(ConformanceFailure{
+                QStringLiteral("robustness"), QString(),
+                QStringLiteral("%1 must be inaccessible, got %2").arg(what, describe(result))});
+        }
+    }
+    QFile::setPermissions(unreadable, QFileDevice::ReadOwner | QFileDevice::WriteOwner);
+}
+
+} // namespace
+
+QString ExtractorConformance::statusName(ExtractionResult::Status status)
+{
+    for (const StatusName& entry : kStatusNames) {
+        if (entry.status == status) {
+            return QString::fromLatin1(entry.name);
+        }
+    }
+    return QStringLiteral("unknown");
+}
+
+std::optional<ExtractionResult::Status> ExtractorConformance::statusFromName(const QString& name)
+{
+    for (const StatusName& entry : kStatusNames) {
+        if (name == QLatin1String(entry.name)) {
+            return entry.status;
+        }
+    }
+    return std::nullopt;
+}
+
+std::optional<ConformanceSuite> ExtractorConformance::parseSuite(const QByteArray& json,
+                                                                 const QString& directory,
+                                                                 QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("A conformance manifest is a JSON object: %1")
+                     .arg(parseError.errorString());
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    const int version = object.value(QStringLiteral("version")).toInt(kFormatVersion);
+    if (version < 1 || version > kFormatVersion) {
+        *error = QStringLiteral("Conformance manifest version %1 is not supported (up to %2)")
+                     .arg(version)
+                     .arg(kFormatVersion);
+        return std::nullopt;
+    }
+
+    ConformanceSuite suite;
+    suite.directory = directory;
+    const QJsonObject limits = object.value(QStringLiteral("limits")).toObject();
+    suite.limits.maxDurationMs =
+        limits.value(QStringLiteral("maxDurationMs")).toInt(suite.limits.maxDurationMs);
+    suite.limits.maxContentChars = static_cast<qsizetype>(
+        limits.value(QStringLiteral("maxContentChars"))
+            .toInteger(static_cast<qint64>(suite.limits.maxContentChars)));
+    if (limits.contains(QStringLiteral("maxRssGrowthMb"))) {
+        suite.limits.maxRssGrowthBytes =
+            limits.value(QStringLiteral("maxRssGrowthMb")).toInteger() * 1024 * 1024;
+    }
+    suite.limits.determinismRuns =
+        limits.value(QStringLiteral("determinismRuns")).toInt(suite.limits.determinismRuns);
+    if (suite.limits.maxDurationMs < 1 || suite.limits.maxContentChars < 0
+        || suite.limits.maxRssGrowthBytes < 0 || suite.limits.determinismRuns < 1) {
+        *error = QStringLiteral("Limits must be positive, with at least one run per file");
+        return std::nullopt;
+    }
+
+    const QDir root(directory);
+    QSet<QString> paths;
+    for (const QJsonValue& value : object.value(QStringLiteral("files")).toArray()) {
+        const QJsonObject json = value.toObject();
+        ConformanceExpectation file;
+        file.path = QDir::cleanPath(json.value(QStringLiteral("path")).toString());
+        if (file.path.isEmpty() || file.path == QLatin1String(".")
+            || QDir::isAbsolutePath(file.path) || file.path == QLatin1String("..")
+            || file.path.startsWith(QLatin1String("../"))
+            || paths.contains(file.path)) {
+            *error = QStringLiteral("File \"%1\" needs a unique path inside the fixture directory")
+                         .arg(file.path);
+            return std::nullopt;
+        }
+        if (!QFileInfo(root.filePath(file.path)).isFile()) {
+            *error = QStringLiteral("%1 is not in %2").arg(file.path, directory);
+            return std::nullopt;
+        }
+        const QString statusText = json.value(QStringLiteral("status")).toString(
+            QStringLiteral("success"));
+        const auto status = statusFromName(statusText);
+        if (!status.has_value()) {
+            *error = QStringLiteral("%1: unknown status \"%2\"").arg(file.path, statusText);
+            return std::nullopt;
+        }
+        file.status = status.value();
+        file.contains = stringList(json.value(QStringLiteral("contains")));
+        file.excludes = stringList(json.value(QStringLiteral("excludes")));
+        file.ordered = stringList(json.value(QStringLiteral("ordered")));
+        for (const QJsonValue& positionValue : json.value(QStringLiteral("positions")).toArray()) {
+            const QJsonObject positionJson = positionValue.toObject();
+            ConformancePosition position;
+            position.text = positionJson.value(QStringLiteral("text")).toString();
+            position.offset = positionJson.value(QStringLiteral("offset")).toInt(-1);
+            position.line = positionJson.value(QStringLiteral("line")).toInt(0);
+            if (position.text.isEmpty() || (position.offset < 0 && position.line < 1)) {
+                *error = QStringLiteral("%1: a position needs text and an offset or a line")
+                             .arg(file.path);
+                return std::nullopt;
+            }
+            file.positions.push_back(std::move(position));
+        }
+        const bool expectsText = !file.contains.isEmpty() || !file.excludes.isEmpty()
+                                 || !file.ordered.isEmpty() || !file.positions.empty();
+        if (expectsText && file.status != ExtractionResult::Status::Success) {
+            *error = QStringLiteral("%1: only a successful extraction has text to check")
+                         .arg(file.path);
+            return std::nullopt;
+        }
+        paths.insert(file.path);
+        suite.files.push_back(std::move(file));
+    }
+    if (suite.files.empty()) {
+        *error = QStringLiteral("The manifest lists no files");
+        return std::nullopt;
+    }
+    return suite;
+}
+
+std::optional<ConformanceSuite> ExtractorConformance::loadSuite(const QString& directory,
+                                                                QString* error)
+{
+    QFile file(QDir(directory).filePath(QString::fromLatin1(kManifestName)));
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("Cannot read %1").arg(file.fileName());
+        return std::nullopt;
+    }
+    return parseSuite(file.readAll(), directory, error);
+}
+
+std::vector<ConformanceFailure> ExtractorConformance::checkFields(const ExtractionResult& result,
+                                                                  int64_t wallMs)
+{
+    std::vector<ConformanceFailure> failures;
+    const QString fields = QStringLiteral("fields");
+    if (result.status == ExtractionResult::Status::Success) {
+        if (!result.content.has_value()) {
+            failures.push_back(failure(fields, QStringLiteral("success without content")));
+        }
+        if (result.errorMessage.has_value()) {
+            failures.push_back(failure(fields, QStringLiteral("success with an errorMessage: %1")
+                                                   .arg(result.errorMessage.value())));
+        }
+    } else {
+        if (result.content.has_value()) {
+            failures.push_back(failure(fields, QStringLiteral("%1 with content")
+                                                   .arg(statusName(result.status))));
+        }
+        if (!result.errorMessage.has_value() || result.errorMessage->trimmed().isEmpty()) {
+            failures.push_back(failure(fields, QStringLiteral("%1 without an errorMessage")
+                                                   .arg(statusName(result.status))));
+        }
+    }
+    if (result.status == ExtractionResult::Status::Cancelled
+        || result.status == ExtractionResult::Status::Crashed) {
+        failures.push_back(failure(
+            fields, QStringLiteral("%1 is reported by ExtractionManager and the sandbox, "
+                                   "not by an extractor")
+                        .arg(statusName(result.status))));
+    }
+    if (result.durationMs < 0 || result.durationMs > wallMs + kDurationSlackMs) {
+        failures.push_back(failure(fields, QStringLiteral("durationMs %1 is not within the "
+                                                          "%2 ms call")
+                                               .arg(result.durationMs)
+                                               .arg(wallMs)));
+    }
+    if (result.redactedSecrets != 0) {
+        failures.push_back(failure(
+            fields, QStringLiteral("redactedSecrets is %1; redaction is ExtractionManager's")
+                        .arg(result.redactedSecrets)));
+    }
+    return failures;
+}
+
+std::vector<ConformanceFailure> ExtractorConformance::checkContent(
+    const ConformanceExpectation& expected, const ExtractionResult& result)
+{
+    std::vector<ConformanceFailure> failures;
+    if (result.status != expected.status) {
+        failures.push_back(failure(QStringLiteral("status"),
+                                   QStringLiteral("want %1, got %2")
+                                       .arg(statusName(expected.status), describe(result))));
+        return failures;
+    }
+    if (result.status != ExtractionResult::Status::Success) {
+        return failures;
+    }
+    const QString content = result.content.value_or(QString());
+
+    const QString contentCheck = QStringLiteral("content");
+    for (const QString& text : expected.contains) {
+        if (!content.contains(text)) {
+            failures.push_back(
+                failure(contentCheck, QStringLiteral("%1 is missing").arg(quoted(text))));
+        }
+    }
+    for (const QString& text : expected.excludes) {
+        if (content.contains(text)) {
+            failures.push_back(
+                failure(contentCheck, QStringLiteral("%1 should not appear").arg(quoted(text))));
+        }
+    }
+
+    const QString positionCheck = QStringLiteral("position");
+    qsizetype from = 0;
+    for (qsizetype i = 0; i < expected.ordered.size(); ++i) {
+        const QString& text = expected.ordered.at(i);
+        const qsizetype at = content.indexOf(text, from);
+        if (at < 0) {
+            failures.push_back(failure(
+                positionCheck,
+                !content.contains(text)
+                    ? QStringLiteral("%1 is missing").arg(quoted(text))
+                    : QStringLiteral("%1 does not follow %2")
+                          .arg(quoted(text), quoted(expected.ordered.at(i - 1)))));
+            break;
+        }
+        from = at + text.size();
+    }
+
+    for (const ConformancePosition& position : expected.positions) {
+        const QString text = quoted(position.text);
+        if (position.offset >= 0
+            && content.mid(position.offset, position.text.size()) != position.text) {
+            const qsizetype found = content.indexOf(position.text);
+            const QString want = QString::number(position.offset);
+            failures.push_back(failure(
+                positionCheck,
+                found < 0 ? QStringLiteral("%1: want offset %2, not found").arg(text, want)
+                          : QStringLiteral("%1: want offset %2, found at %3")
+                                .arg(text, want, QString::number(found))));
+        }
+        if (position.line >= 1) {
+            const QStringList lines = content.split(QLatin1Char('\n'));
+            if (position.line > lines.size()
+                || !lines.at(position.line - 1).contains(position.text)) {
+                const int found = lineOf(content, position.text);
+                const QString want = QString::number(position.line);
+                failures.push_back(failure(
+                    positionCheck,
+                    found == 0 ? QStringLiteral("%1: want line %2, not found").arg(text, want)
+                               : QStringLiteral("%1: want line %2, found on line %3")
+                                     .arg(text, want, QString::number(found))));
+            }
+        }
+    }
+    return failures;
+}
+
+ConformanceReport ExtractorConformance::run(FileExtractor& extractor,
+                                            const ConformanceSuite& suite)
+{
+    ConformanceReport report;
+    const QDir root(suite.directory);
+    const QMap<QString, QString> before = snapshot(suite.directory);
+
+    // A warm-up extraction first, so state loaded once per process (fonts,
+    // the log file, caches) is not taken for growth or a leak.
+    extractor.extract(root.filePath(suite.files.front().path));
+    const ResourceSample start = ResourceMonitor::sampleProcess(0);
+
+    for (const ConformanceExpectation& expected : suite.files) {
+        ++report.files;
+        const QString path = root.filePath(expected.path);
+        auto fail = [&](ConformanceFailure entry) {
+            entry.path = expected.path;
+            report.failures.push_back(std::move(entry));
+        };
+
+        const QString suffix = QFileInfo(path).suffix().toLower();
+        if (expected.status != ExtractionResult::Status::UnsupportedFormat
+            && !extractor.supports(suffix)) {
+            fail(failure(QStringLiteral("supports"),
+                         QStringLiteral("supports(\"%1\") is false").arg(suffix)));
+        }
+
+        std::optional<ExtractionResult> first;
+        for (int run = 1; run <= suite.limits.determinismRuns; ++run) {
+            QElapsedTimer timer;
+            timer.start();
+            const ExtractionResult result = extractor.extract(path);
+            const int64_t wallMs = timer.elapsed();
+            ++report.extractions;
+            report.slowestMs = std::max(report.slowestMs, static_cast<int>(wallMs));
+            const ResourceSample sample = ResourceMonitor::sampleProcess(0);
+            if (sample.rssBytes.has_value() && start.rssBytes.has_value()) {
+                report.rssGrowthBytes = std::max(report.rssGrowthBytes,
+                                                 sample.rssBytes.value() - start.rssBytes.value());
+            }
+
+            if (wallMs > suite.limits.maxDurationMs) {
+                fail(failure(QStringLiteral("limits"),
+                             QStringLiteral("run %1 took %2 ms, over the %3 ms limit")
+                                 .arg(run)
+                                 .arg(wallMs)
+                                 .arg(suite.limits.maxDurationMs)));
+            }
+            if (result.content.has_value()
+                && result.content->size() > suite.limits.maxContentChars) {
+                fail(failure(QStringLiteral("limits"),
+                             QStringLiteral("%1 characters of text, over the %2 limit")
+                                 .arg(result.content->size())
+                                 .arg(suite.limits.maxContentChars)));
+            }
+
+            if (!first.has_value()) {
+                for (ConformanceFailure& entry : checkFields(result, wallMs)) {
+                    fail(std::move(entry));
+                }
+                for (ConformanceFailure& entry : checkContent(expected, result)) {
+                    fail(std::move(entry));
+                }
+                first = result;
+                continue;
+            }
+            if (result.status != first->status) {
+                fail(failure(QStringLiteral("determinism"),
+                             QStringLiteral("run %1 is %2, the first was %3")
+                                 .arg(QString::number(run), describe(result),
+                                      describe(first.value()))));
+                break;
+            }
+            const QString text = result.content.value_or(QString());
+            const QString firstText = first->content.value_or(QString());
+            if (text != firstText) {
+                qsizetype at = 0;
+                while (at < text.size() && at < firstText.size()
+                       && text.at(at) == firstText.at(at)) {
+                    ++at;
+                }
+                fail(failure(QStringLiteral("determinism"),
+                             QStringLiteral("run %1's text differs from the first run's at "
+                                            "offset %2")
+                                 .arg(run)
+                                 .arg(at)));
+                break;
+            }
+        }
+    }
+
+    checkRobustness(extractor, suite, &report);
+
+    if (report.rssGrowthBytes > suite.limits.maxRssGrowthBytes) {
+        report.failures.push_back(ConformanceFailure{
+            QStringLiteral("limits"), QString(),
+            QStringLiteral("resident memory grew by %1 MB, over the %2 MB limit")
+                .arg(report.rssGrowthBytes / (1024 * 1024))
+                .arg(suite.limits.maxRssGrowthBytes / (1024 * 1024))});
+    }
+    const ResourceSample end = ResourceMonitor::sampleProcess(0);
+    if (start.openFds.has_value() && end.openFds.has_value()
+        && end.openFds.value() > start.openFds.value()) {
+        report.failures.push_back(ConformanceFailure{
+            QStringLiteral("limits"), QString(),
+            QStringLiteral("%1 file descriptors left open").arg(end.openFds.value()
+                                                                - start.openFds.value())});
+    }
+
+    const QMap<QString, QString> after = snapshot(suite.directory);
+    for (auto it = after.cbegin(); it != after.cend(); ++it) {
+        if (!before.contains(it.key())) {
+            report.failures.push_back(ConformanceFailure{QStringLiteral("side effects"), it.key(),
+                                                         QStringLiteral("created in the fixture "
+                                                                        "directory")});
+        } else if (before.value(it.key()) != it.value()) {
+            report.failures.push_back(ConformanceFailure{QStringLiteral("side effects"), it.key(),
+                                                         QStringLiteral("modified")});
+        }
+    }
+    for (auto it = before.cbegin(); it != before.cend(); ++it) {
+        if (!after.contains(it.key())) {
+            report.failures.push_back(ConformanceFailure{QStringLiteral("side effects"), it.key(),
+                                                         QStringLiteral("removed")});
+        }
+    }
+    return report;
+}
+
+QString ExtractorConformance::formatReport(const ConformanceReport& report)
+{
+    QString out = QStringLiteral("Extractor conformance: %1 files, %2 extractions, slowest %3 ms")
+                      .arg(report.files)
+                      .arg(report.extractions)
+                      .arg(report.slowestMs);
+    if (report.rssGrowthBytes > 0) {
+        out += QStringLiteral(", resident memory +%1 KB").arg(report.rssGrowthBytes / 1024);
+    }
+    out += report.passed() ? QStringLiteral(": passed\n")
+                           : QStringLiteral(": %1 failures\n").arg(report.failures.size());
+    for (const ConformanceFailure& entry : report.failures) {
+        out += entry.path.isEmpty()
+                   ? QStringLiteral("  [%1] %2\n").arg(entry.check, entry.message)
+                   : QStringLiteral("  [%1] %2: %3\n").arg(entry.check, entry.path, entry.message);
+    }
+    return out;
+}
+
+} // namespace bs::test
@@ -0,0 +1,111 @@
+#pragma once
+
+#include "core/extraction/extractor.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs::test {
+
+// Where a piece of the extracted text must sit: at a QString offset of the
+// content, on a line (from 1), or both.
+struct ConformancePosition {
+    QString text;
+    int offset = -1;
+    int line = 0;
+};
+
+// What one fixture file must extract to.
+struct ConformanceExpectation {
+    QString path;  // relative to the fixture directory
+    ExtractionResult::Status status = ExtractionResult::Status::Success;
+    QStringList contains;
+    QStringList excludes;
+    QStringList ordered;  // appear in this order
+    std::vector<ConformancePosition> positions;
+};
+
+struct ConformanceLimits {
+    int maxDurationMs = 5000;  // per extraction, wall clock
+    qsizetype maxContentChars = 10 * 1024 * 1024;
+    int64_t maxRssGrowthBytes = 512LL * 1024 * 1024;  // after any extraction, over the start
+    int determinismRuns = 3;   // extractions per file
+};
+
+// A fixture directory and its conformance.json.
+struct ConformanceSuite {
+    QString directory;
+    ConformanceLimits limits;
+    std::vector<ConformanceExpectation> files;
+};
+
+struct ConformanceFailure {
+    QString check;  // "fields", "status", "content", "position", "determinism",
+                    // "limits", "supports", "robustness", "side effects"
+    QString path;   // relative to the fixture directory; empty for the run
+    QString message;
+};
+
+struct ConformanceReport {
+    int files = 0;
+    int extractions = 0;
+    int slowestMs = 0;
+    int64_t rssGrowthBytes = 0;  // 0 where RSS cannot be read
+    std::vector<ConformanceFailure> failures;
+
+    bool passed() const { return failures.empty(); }
+};
+
+// ExtractorConformance -- checks that a FileExtractor keeps the contract
+// ExtractionManager relies on, so a new extractor (ours or a third party's)
+// can be validated by pointing it at a fixture directory:
+//
+//   - fields: content only on Success, an errorMessage on every failure,
+//     a durationMs within the call, and the statuses reserved for the
+//     manager and the sandbox (Cancelled, Crashed) left alone;
+//   - status, content and positions as the manifest lists them, with
+//     offsets and lines counted in the extracted text;
+//   - determinism: every run over a file gives the same status and text;
+//   - limits: time per extraction, text length, resident memory growth
+//     (macOS only) and file descriptors left open;
+//   - robustness: a missing file, a directory and an unreadable file are
+//     Inaccessible, and the fixture directory is left as it was.
+//
+// From a test:
+//
+//   const auto suite = ExtractorConformance::loadSuite(dir, &error);
+//   const ConformanceReport report = ExtractorConformance::run(extractor, *suite);
+//   QVERIFY2(report.passed(), qPrintable(ExtractorConformance::formatReport(report)));
+class ExtractorConformance {
+public:
+    static constexpr const char* kManifestName = "conformance.json";
+    static constexpr int kFormatVersion = 1;
+
+    // `directory`/conformance.json. Nullopt with *error when it is
+    // malformed or lists a file the directory does not hold.
+    static std::optional<ConformanceSuite> loadSuite(const QString& directory, QString* error);
+    static std::optional<ConformanceSuite> parseSuite(const QByteArray& json,
+                                                      const QString& directory, QString* error);
+
+    static ConformanceReport run(FileExtractor& extractor, const ConformanceSuite& suite);
+
+    // The checks of one result; the failures carry no path.
+    static std::vector<ConformanceFailure> checkFields(const ExtractionResult& result,
+                                                       int64_t wallMs);
+    static std::vector<ConformanceFailure> checkContent(const ConformanceExpectation& expected,
+                                                        const ExtractionResult& result);
+
+    // "success", "corrupted", ...: the names of SandboxedExtraction's wire
+    // format, which the manifest uses too.
+    static QString statusName(ExtractionResult::Status status);
+    static std::optional<ExtractionResult::Status> statusFromName(const QString& name);
+
+    static QString formatReport(const ConformanceReport& report);
+};
+
+} // namespace bs::test
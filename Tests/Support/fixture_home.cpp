@@ -0,0 +1,562 @@
+#include "fixture_home.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QLocale>
+#include <QSet>
+#include <QTimeZone>
+
+#include <iterator>
+#include <utility>
+
+#include <sys/xattr.h>
+
+namespace bs::test {
+
+namespace {
+
+constexpr char kPlistHeader[] =
+    "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"
+    "<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" "
+    "\"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n"
+    "<plist version=\"1.0\">\n";
+
+QString plistString(const QString& value)
+{
+    return QStringLiteral("<string>%1</string>").arg(value.toHtmlEscaped());
+}
+
+// What a language's project holds besides the README and the .git
+// directory: sources, the manifest, and the tree its tooling writes.
+struct ProjectLayout {
+    const char* language;
+    QStringList sources;            // "{name}" is the project's name
+    const char* manifest;
+    const char* manifestText;
+    QStringList generated;          // written by the toolchain; excluded by PathRules
+};
+
+const ProjectLayout* projectLayout(const QString& language)
+{
+    static const ProjectLayout layouts[] = {
+        {"cpp",
+         {QStringLiteral("src/main.cpp"), QStringLiteral("src/{name}.cpp"),
+          QStringLiteral("include/{name}.h")},
+         "CMakeLists.txt",
+         "cmake_minimum_required(VERSION 3.24)\nproject({name} CXX)\n"
+         "add_executable({name} src/main.cpp src/{name}.cpp)\n",
+         {QStringLiteral("build/{name}"), QStringLiteral("build/CMakeCache.txt")}},
+        {"py",
+         {QStringLiteral("{name}/__init__.py"), QStringLiteral("{name}/cli.py"),
+          QStringLiteral("tests/test_cli.py")},
+         "pyproject.toml",
+         "[project]\nname = \"{name}\"\nversion = \"0.4.0\"\nrequires-python = \">=3.11\"\n",
+         {QStringLiteral("{name}/__pycache__/cli.cpython-312.pyc"),
+          QStringLiteral(".venv/lib/python3.12/site-packages/requests/__init__.py")}},
+        {"ts",
+         {QStringLiteral("src/index.ts"), QStringLiteral("src/server.ts"),
+          QStringLiteral("test/server.spec.ts")},
+         "package.json",
+         "{\n  \"name\": \"{name}\",\n  \"version\": \"1.2.0\",\n"
+         "  \"scripts\": {\"build\": \"tsc\", \"test\": \"vitest\"}\n}\n",
+         {QStringLiteral("node_modules/left-pad/index.js"),
+          QStringLiteral("node_modules/left-pad/package.json"), QStringLiteral("dist/index.js")}},
+        {"rs",
+         {QStringLiteral("src/main.rs"), QStringLiteral("src/lib.rs"),
+          QStringLiteral("tests/integration.rs")},
+         "Cargo.toml",
+         "[package]\nname = \"{name}\"\nversion = \"0.1.0\"\nedition = \"2021\"\n",
+         {QStringLiteral("target/debug/{name}"), QStringLiteral("target/debug/{name}.d")}},
+        {"go",
+         {QStringLiteral("main.go"), QStringLiteral("internal/server/server.go"),
+          QStringLiteral("internal/server/server_test.go")},
+         "go.mod",
+         "module example.com/{name}\n\ngo 1.22\n",
+         {QStringLiteral("vendor/github.com/pkg/errors/errors.go")}},
+    };
+    for (const ProjectLayout& layout : layouts) {
+        if (language == QLatin1String(layout.language)) {
+            return &layout;
+        }
+    }
+    return nullptr;
+}
+
+// The kind CorpusGenerator writes a generated file of a project as.
+CorpusEntryKind generatedKind(const QString& path, QString* magic)
+{
+    const QString suffix = QFileInfo(path).suffix();
+    if (suffix == QLatin1String("pyc")) {
+        return CorpusEntryKind::Binary;
+    }
+    if (suffix.isEmpty()) {
+        *magic = QStringLiteral("macho");  // a linked executable
+        return CorpusEntryKind::Binary;
+    }
+    if (suffix == QLatin1String("json")) {
+        return CorpusEntryKind::Json;
+    }
+    if (suffix == QLatin1String("js") || suffix == QLatin1String("py")
+        || suffix == QLatin1String("go")) {
+        return CorpusEntryKind::Source;
+    }
+    return CorpusEntryKind::Text;
+}
+
+// A UUID-shaped identifier that is the same on every run.
+QString fixtureUuid(quint32 seed, int n)
+{
+    return QStringLiteral("%1-%2-4%3-8%4-%5")
+        .arg(seed, 8, 16, QLatin1Char('0'))
+        .arg(n & 0xffff, 4, 16, QLatin1Char('0'))
+        .arg(n % 0x1000, 3, 16, QLatin1Char('0'))
+        .arg(n % 0x1000, 3, 16, QLatin1Char('0'))
+        .arg(static_cast<qulonglong>(n) * 0x9e3779b1u % 0x1000000000000ull, 12, 16,
+             QLatin1Char('0'))
+        .toUpper();
+}
+
+// RFC 5322, as Mail writes it.
+QString mailDate(const QDateTime& time)
+{
+    return QLocale::c().toString(time.toUTC(), QStringLiteral("ddd, dd MMM yyyy HH:mm:ss"))
+           + QStringLiteral(" +0000");
+}
+
+struct MailTemplate {
+    const char* from;
+    const char* subject;
+};
+
+constexpr MailTemplate kMailTemplates[] = {
+    {"Ada Lovelace <ada@example.com>", "Quarterly planning offsite"},
+    {"Billing <billing@utility.example.net>", "Your January statement is ready"},
+    {"Grace Hopper <grace@example.org>", "Re: compiler review notes"},
+    {"Travel Desk <trips@airline.example.com>", "Booking confirmation LIS-2026"},
+    {"Charles Babbage <charles@example.com>", "Engine parts shipment delayed"},
+    {"Newsletter <news@weekly.example.io>", "This week in distributed systems"},
+    {"Hedy Lamarr <hedy@example.org>", "Frequency hopping patent draft"},
+    {"Landlord <office@apartments.example.com>", "Lease renewal for 2026"},
+};
+
+bool hasFiles(const QString& root)
+{
+    const QDir dir(root);
+    return dir.exists()
+           && !dir.isEmpty(QDir::AllEntries | QDir::NoDotAndDotDot | QDir::Hidden | QDir::System);
+}
+
+bool setAttribute(const QString& path, const QString& name, const QByteArray& value)
+{
+    const QByteArray pathUtf8 = path.toUtf8();
+    return setxattr(pathUtf8.constData(), name.toUtf8().constData(), value.constData(),
+                    static_cast<size_t>(value.size()), 0, XATTR_NOFOLLOW)
+           == 0;
+}
+
+} // namespace
+
+QDateTime FixtureHomeBuilder::defaultReference()
+{
+    return QDateTime(QDate(2026, 1, 1), QTime(0, 0), QTimeZone::UTC);
+}
+
+FixtureHomeBuilder::FixtureHomeBuilder(quint32 seed, QDateTime reference)
+    : m_seed(seed)
+    , m_reference(std::move(reference))
+{
+}
+
+FixtureHomeBuilder FixtureHomeBuilder::standardHomeV2()
+{
+    FixtureHomeBuilder builder;
+    builder.documents()
+        .devProject(QStringLiteral("orbit"), QStringLiteral("ts"))
+        .devProject(QStringLiteral("ledger"), QStringLiteral("py"))
+        .devProject(QStringLiteral("quasar"), QStringLiteral("rs"))
+        .photoLibrary(9)
+        .mailArchive(16)
+        .cloudPlaceholders();
+    return builder;
+}
+
+QDateTime FixtureHomeBuilder::ago(int days, int seconds) const
+{
+    return m_reference.addDays(-days).addSecs(-seconds);
+}
+
+FixtureFile& FixtureHomeBuilder::add(const QString& module, CorpusEntryKind kind,
+                                     const QString& path, const QDateTime& modifiedAt)
+{
+    FixtureFile file;
+    file.module = module;
+    file.entry.kind = kind;
+    file.entry.path = path;
+    file.entry.modifiedAt = modifiedAt;
+    m_files.push_back(std::move(file));
+    return m_files.back();
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::devProject(const QString& name, const QString& language)
+{
+    const QString module = QStringLiteral("devProject:%1").arg(name);
+    const ProjectLayout* layout = projectLayout(language);
+    if (name.isEmpty() || name.contains(QLatin1Char('/')) || !layout) {
+        m_errors.append(QStringLiteral("no project '%1' in language '%2'").arg(name, language));
+        return *this;
+    }
+    const QString base = QStringLiteral("Developer/") + name + QLatin1Char('/');
+    const auto named = [&name](QString text) {
+        return text.replace(QLatin1String("{name}"), name);
+    };
+
+    FixtureFile& readme = add(module, CorpusEntryKind::Markdown, base + QStringLiteral("README.md"),
+                              ago(30));
+    readme.entry.keywords = {name, QStringLiteral("getting started")};
+    readme.finderTags = {QStringLiteral("Work\n4")};
+
+    int age = 1;
+    for (const QString& source : layout->sources) {
+        FixtureFile& file = add(module, CorpusEntryKind::Source, base + named(source), ago(age++));
+        file.entry.keywords = {name};
+        file.entry.words = 200;
+    }
+    add(module, CorpusEntryKind::Text, base + QLatin1String(layout->manifest), ago(age))
+        .entry.text = named(QString::fromLatin1(layout->manifestText));
+    add(module, CorpusEntryKind::Text, base + QStringLiteral(".gitignore"), ago(30)).entry.text =
+        QStringLiteral(".DS_Store\nnode_modules/\nbuild/\ntarget/\n__pycache__/\n.venv/\ndist/\n");
+
+    add(module, CorpusEntryKind::Text, base + QStringLiteral(".git/HEAD"), ago(1)).entry.text =
+        QStringLiteral("ref: refs/heads/main\n");
+    add(module, CorpusEntryKind::Text, base + QStringLiteral(".git/config"), ago(30)).entry.text =
+        QStringLiteral("[core]\n\trepositoryformatversion = 0\n\tbare = false\n"
+                       "[remote \"origin\"]\n\turl = git@github.com:example/%1.git\n")
+            .arg(name);
+    add(module, CorpusEntryKind::Binary, base + QStringLiteral(".git/index"), ago(1)).entry.size =
+        1024;
+    add(module, CorpusEntryKind::Binary,
+        base + QStringLiteral(".git/objects/4b/825dc642cb6eb9a060e54bf8d69288fbee4904"), ago(1))
+        .entry.size = 512;
+    add(module, CorpusEntryKind::Text, base + QStringLiteral(".git/refs/heads/main"), ago(1))
+        .entry.text = QStringLiteral("4b825dc642cb6eb9a060e54bf8d69288fbee4904\n");
+
+    for (const QString& generated : layout->generated) {
+        const QString path = base + named(generated);
+        QString magic;
+        FixtureFile& file = add(module, generatedKind(path, &magic), path, ago(90));
+        file.entry.magic = magic;
+        file.entry.size = 16 * 1024;
+        file.entry.words = 80;
+    }
+    return *this;
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::photoLibrary(int photos)
+{
+    const QString module = QStringLiteral("photoLibrary");
+    if (photos < 1 || photos > 9999) {
+        m_errors.append(QStringLiteral("a photo library holds 1 to 9999 photos"));
+        return *this;
+    }
+    const QString bundle = QStringLiteral("Pictures/Photos Library.photoslibrary/");
+    for (int n = 1; n <= photos; ++n) {
+        // The oldest first, as a camera numbers them.
+        const QDateTime taken = ago(7 * (photos - n + 1), -10 * 3600);
+        FixtureFile& photo = add(module, CorpusEntryKind::Binary,
+                                 QStringLiteral("Pictures/Camera Roll/IMG_%1.jpg")
+                                     .arg(n, 4, 10, QLatin1Char('0')),
+                                 taken);
+        photo.entry.magic = QStringLiteral("jpeg");
+        photo.entry.size = 8192;
+        if (n % 3 == 0) {
+            photo.finderTags = {QStringLiteral("Vacation\n5")};
+        }
+        if (n == 1) {
+            photo.finderComment = QStringLiteral("Lake Tahoe, first morning of the trip");
+        }
+
+        const QString uuid = fixtureUuid(m_seed, n);
+        FixtureFile& original = add(module, CorpusEntryKind::Binary,
+                                    bundle + QStringLiteral("originals/%1/%2.jpeg")
+                                                 .arg(uuid.left(1), uuid),
+                                    taken);
+        original.entry.magic = QStringLiteral("jpeg");
+        original.entry.size = 8192;
+    }
+    add(module, CorpusEntryKind::Binary, bundle + QStringLiteral("database/Photos.sqlite"), ago(0))
+        .entry.size = 32 * 1024;
+    add(module, CorpusEntryKind::Text, bundle + QStringLiteral("database/DataModelVersion.plist"),
+        ago(300))
+        .entry.text = QString::fromLatin1(kPlistHeader)
+                      + QStringLiteral("<dict><key>DatabaseVersion</key><integer>17000</integer>"
+                                       "</dict>\n</plist>\n");
+    return *this;
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::mailArchive(int messages)
+{
+    const QString module = QStringLiteral("mailArchive");
+    if (messages < 1 || messages > 9999) {
+        m_errors.append(QStringLiteral("a mail archive holds 1 to 9999 messages"));
+        return *this;
+    }
+    const QString account = QStringLiteral("Library/Mail/V10/") + fixtureUuid(m_seed, 0xa11c);
+    const QString inbox = account + QStringLiteral("/INBOX.mbox/") + fixtureUuid(m_seed, 1)
+                          + QStringLiteral("/Data/Messages/");
+    const QString archive = account + QStringLiteral("/Archive.mbox/") + fixtureUuid(m_seed, 2)
+                            + QStringLiteral("/Data/Messages/");
+    constexpr int templates = int(std::size(kMailTemplates));
+
+    for (int n = 1; n <= messages; ++n) {
+        const MailTemplate& mail = kMailTemplates[(n - 1) % templates];
+        // Every fourth message filed away, the newest last.
+        const QString path = (n % 4 == 0 ? archive : inbox) + QString::number(1000 + n)
+                             + QStringLiteral(".emlx");
+        const QDateTime sent = ago(messages - n + 1, -(9 * 3600 + 30 * 60 + n * 60));
+
+        CorpusEntry body;
+        body.words = 90;
+        const QString message =
+            QStringLiteral("Message-ID: <%1.fixture@mail.example.com>\r\n"
+                           "From: %2\r\n"
+                           "To: Rex Example <rex@example.com>\r\n"
+                           "Subject: %3\r\n"
+                           "Date: %4\r\n"
+                           "MIME-Version: 1.0\r\n"
+                           "Content-Type: text/plain; charset=utf-8\r\n"
+                           "\r\n"
+                           "%5\r\n")
+                .arg(QString::number(1000 + n), QLatin1String(mail.from),
+                     QLatin1String(mail.subject), mailDate(sent),
+                     QString::fromUtf8(CorpusGenerator::content(body, path, m_seed)));
+        const QByteArray bytes = message.toUtf8();
+        add(module, CorpusEntryKind::Text, path, sent).entry.text =
+            QString::number(bytes.size()) + QLatin1Char('\n') + message
+            + QString::fromLatin1(kPlistHeader)
+            + QStringLiteral("<dict><key>flags</key><integer>8590195713</integer></dict>\n"
+                             "</plist>\n");
+    }
+    // Mail's own databases, which MailSource skips.
+    add(module, CorpusEntryKind::Binary,
+        QStringLiteral("Library/Mail/V10/MailData/Envelope Index"), ago(0))
+        .entry.size = 16 * 1024;
+    return *this;
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::cloudPlaceholders()
+{
+    const QString module = QStringLiteral("cloudPlaceholders");
+    const QString drive = QStringLiteral("Library/Mobile Documents/com~apple~CloudDocs/");
+    // The stub iCloud leaves for an evicted file: its name and size.
+    const auto placeholder = [&](const QString& folder, const QString& name, int64_t size,
+                                 int days) {
+        add(module, CorpusEntryKind::Text,
+            drive + folder + QStringLiteral("/.") + name + QStringLiteral(".icloud"), ago(days))
+            .entry.text = QString::fromLatin1(kPlistHeader) + QStringLiteral("<dict>\n")
+                          + QStringLiteral("<key>NSURLNameKey</key>") + plistString(name)
+                          + QStringLiteral("\n<key>NSURLFileSizeKey</key><integer>%1</integer>\n")
+                                .arg(size)
+                          + QStringLiteral("<key>NSURLFileResourceTypeKey</key>")
+                          + plistString(QStringLiteral("NSURLFileResourceTypeRegular"))
+                          + QStringLiteral("\n</dict>\n</plist>\n");
+    };
+    const auto folderAttributes = [&](const QString& folder) {
+        add(module, CorpusEntryKind::Text,
+            drive + folder + QStringLiteral("/.icloud_folder_attributes.plist"), ago(10))
+            .entry.text = QString::fromLatin1(kPlistHeader)
+                          + QStringLiteral("<dict><key>evictable</key><true/></dict>\n</plist>\n");
+    };
+
+    FixtureFile& lease = add(module, CorpusEntryKind::Binary,
+                             drive + QStringLiteral("Documents/lease-agreement-2025.pdf"),
+                             ago(40));
+    lease.entry.magic = QStringLiteral("pdf");
+    placeholder(QStringLiteral("Documents"), QStringLiteral("tax-return-2024.pdf"), 482133, 200);
+    folderAttributes(QStringLiteral("Documents"));
+
+    FixtureFile& list = add(module, CorpusEntryKind::Markdown,
+                            drive + QStringLiteral("Family/shopping-list.md"), ago(2));
+    list.entry.keywords = {QStringLiteral("groceries")};
+    placeholder(QStringLiteral("Family"), QStringLiteral("beach-panorama.heic"), 6291456, 150);
+    folderAttributes(QStringLiteral("Family"));
+
+    // An app's iCloud container, next to the Drive's.
+    FixtureFile& note = add(module, CorpusEntryKind::Markdown,
+                            QStringLiteral("Library/Mobile Documents/iCloud~md~obsidian/"
+                                           "Documents/Vault/daily-note.md"),
+                            ago(1));
+    note.entry.keywords = {QStringLiteral("standup")};
+
+    const QString oneDrive = QStringLiteral("OneDrive/Reports/");
+    add(module, CorpusEntryKind::Text, oneDrive + QStringLiteral("OneDrive_folder_placeholder.ini"),
+        ago(30))
+        .entry.text = QStringLiteral("[.ShellClassInfo]\r\nIconResource=OneDrive.dll,0\r\n");
+    FixtureFile& report = add(module, CorpusEntryKind::Binary,
+                              oneDrive + QStringLiteral("annual-report-2025.docx"), ago(30));
+    report.entry.magic = QStringLiteral("zip");
+    return *this;
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::documents()
+{
+    const QString module = QStringLiteral("documents");
+
+    FixtureFile& budget = add(module, CorpusEntryKind::Csv,
+                              QStringLiteral("Documents/budget-2026.csv"), ago(5));
+    budget.entry.keywords = {QStringLiteral("Revenue"), QStringLiteral("Expenses")};
+    budget.finderTags = {QStringLiteral("Finance\n2")};
+
+    FixtureFile& notes = add(module, CorpusEntryKind::Markdown,
+                             QStringLiteral("Documents/meeting-notes-jan.md"), ago(14));
+    notes.entry.keywords = {QStringLiteral("action items")};
+    notes.finderTags = {QStringLiteral("Work\n4"), QStringLiteral("Important\n6")};
+    notes.finderComment = QStringLiteral("Follow up with the marketing team");
+
+    FixtureFile& itinerary = add(module, CorpusEntryKind::Text,
+                                 QStringLiteral("Documents/travel-itinerary.txt"), ago(60));
+    itinerary.entry.keywords = {QStringLiteral("itinerary"), QStringLiteral("Lisbon")};
+    itinerary.finderComment = QStringLiteral("Confirmation 9Q4X, window seat");
+
+    // Years old, for recency to sink.
+    add(module, CorpusEntryKind::Text, QStringLiteral("Documents/Archive/annual-report-2019.txt"),
+        ago(2200))
+        .entry.keywords = {QStringLiteral("annual report")};
+
+    FixtureFile& todo = add(module, CorpusEntryKind::Markdown,
+                            QStringLiteral("Desktop/todo-list.md"), ago(0, 3600));
+    todo.entry.keywords = {QStringLiteral("remember to")};
+    todo.finderTags = {QStringLiteral("Important\n6")};
+    FixtureFile& screenshot = add(module, CorpusEntryKind::Binary,
+                                  QStringLiteral("Desktop/Screenshot 2025-12-31 at 18.04.12.png"),
+                                  ago(1));
+    screenshot.entry.magic = QStringLiteral("png");
+
+    // Downloads, as Safari leaves them.
+    const auto download = [&](CorpusEntryKind kind, const QString& name, const QString& magic,
+                              const QString& url, const QString& page, int days) -> FixtureFile& {
+        FixtureFile& file = add(module, kind, QStringLiteral("Downloads/") + name, ago(days));
+        file.entry.magic = magic;
+        file.whereFroms = {url, page};
+        file.xattrs.insert(QString::fromLatin1(kQuarantineXattr),
+                           QStringLiteral("0083;%1;Safari;")
+                               .arg(file.entry.modifiedAt.toSecsSinceEpoch(), 8, 16,
+                                    QLatin1Char('0'))
+                               .toLatin1());
+        return file;
+    };
+    download(CorpusEntryKind::Binary, QStringLiteral("invoice-january-2026.pdf"),
+             QStringLiteral("pdf"), QStringLiteral("https://billing.example.com/invoices/2026-01.pdf"),
+             QStringLiteral("https://billing.example.com/account"), 3);
+    download(CorpusEntryKind::Binary, QStringLiteral("setup-installer.dmg"), QString(),
+             QStringLiteral("https://downloads.example.org/setup-4.2.dmg"),
+             QStringLiteral("https://example.org/download"), 20)
+        .entry.size = 64 * 1024;
+    download(CorpusEntryKind::Html, QStringLiteral("random-article.html"), QString(),
+             QStringLiteral("https://blog.example.net/posts/consensus.html"),
+             QStringLiteral("https://blog.example.net/"), 8)
+        .entry.keywords = {QStringLiteral("consensus")};
+    return *this;
+}
+
+FixtureHomeBuilder& FixtureHomeBuilder::file(FixtureFile file)
+{
+    if (file.module.isEmpty()) {
+        file.module = QStringLiteral("custom");
+    }
+    m_files.push_back(std::move(file));
+    return *this;
+}
+
+std::optional<FixtureHomeReport> FixtureHomeBuilder::build(const QString& root,
+                                                           QString* error) const
+{
+    if (!m_errors.isEmpty()) {
+        *error = m_errors.constFirst();
+        return std::nullopt;
+    }
+    if (hasFiles(root)) {
+        *error = QStringLiteral("%1 is not empty").arg(root);
+        return std::nullopt;
+    }
+
+    CorpusSpec spec;
+    spec.name = QStringLiteral("fixture-home");
+    spec.seed = m_seed;
+    FixtureHomeReport report;
+    QSet<QString> paths;
+    for (const FixtureFile& file : m_files) {
+        const QString& path = file.entry.path;
+        const QString clean = QDir::cleanPath(path);
+        if (path.isEmpty() || QDir::isAbsolutePath(path) || clean != path
+            || clean == QLatin1String("..") || clean.startsWith(QLatin1String("../"))) {
+            *error = QStringLiteral("'%1' is not a relative path inside the home").arg(path);
+            return std::nullopt;
+        }
+        if (path.contains(QLatin1String("{n}")) || file.entry.count != 1) {
+            *error = QStringLiteral("%1 names more than one file").arg(path);
+            return std::nullopt;
+        }
+        if (paths.contains(path)) {
+            *error = QStringLiteral("%1 is added twice").arg(path);
+            return std::nullopt;
+        }
+        paths.insert(path);
+        spec.entries.push_back(file.entry);
+        report.moduleFiles[file.module].append(path);
+    }
+
+    const auto corpus = CorpusGenerator::generate(spec, root, error);
+    if (!corpus.has_value()) {
+        return std::nullopt;
+    }
+    report.corpus = *corpus;
+
+    // Attributes last: setting one leaves the modification time alone.
+    const QDir rootDir(root);
+    for (const FixtureFile& file : m_files) {
+        QMap<QString, QByteArray> attributes = file.xattrs;
+        if (!file.finderTags.isEmpty()) {
+            attributes.insert(QString::fromLatin1(kFinderTagsXattr),
+                              finderTagsPlist(file.finderTags));
+        }
+        if (!file.finderComment.isEmpty()) {
+            attributes.insert(QString::fromLatin1(kFinderCommentXattr),
+                              stringPlist(file.finderComment));
+        }
+        if (!file.whereFroms.isEmpty()) {
+            attributes.insert(QString::fromLatin1(kWhereFromsXattr),
+                              stringArrayPlist(file.whereFroms));
+        }
+        const QString absolute = rootDir.filePath(file.entry.path);
+        for (auto it = attributes.cbegin(); it != attributes.cend(); ++it) {
+            if (!setAttribute(absolute, it.key(), it.value())) {
+                *error = QStringLiteral("Cannot set %1 on %2").arg(it.key(), absolute);
+                return std::nullopt;
+            }
+            ++report.xattrs;
+        }
+    }
+    return report;
+}
+
+QByteArray FixtureHomeBuilder::finderTagsPlist(const QStringList& tags)
+{
+    return stringArrayPlist(tags);
+}
+
+QByteArray FixtureHomeBuilder::stringPlist(const QString& value)
+{
+    return (QString::fromLatin1(kPlistHeader) + plistString(value) + QStringLiteral("\n</plist>\n"))
+        .toUtf8();
+}
+
+QByteArray FixtureHomeBuilder::stringArrayPlist(const QStringList& values)
+{
+    QString plist = QString::fromLatin1(kPlistHeader) + QStringLiteral("<array>\n");
+    for (const QString& value : values) {
+        plist += QStringLiteral("\t") + plistString(value) + QLatin1Char('\n');
+    }
+    return (plist + QStringLiteral("</array>\n</plist>\n")).toUtf8();
+}
+
+} // namespace bs::test
@@ -0,0 +1,128 @@
+#pragma once
+
+#include "corpus_generator.h"
+
+#include <QByteArray>
+#include <QDateTime>
+#include <QMap>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs::test {
+
+// One file (or directory, or link) of a fixture home, with the metadata a
+// CorpusEntry does not carry. The entry's path names one file: no "{n}".
+struct FixtureFile {
+    QString module;                 // the module that added it
+    CorpusEntry entry;
+    QStringList finderTags;         // "Name" or "Name\nColor", as Finder stores them
+    QString finderComment;
+    QStringList whereFroms;         // kMDItemWhereFroms: the download URL, then the page
+    QMap<QString, QByteArray> xattrs; // any other attribute, raw
+};
+
+struct FixtureHomeReport {
+    CorpusReport corpus;
+    int xattrs = 0;                 // attributes written
+    QMap<QString, QStringList> moduleFiles; // module -> relative paths it added
+};
+
+// FixtureHomeBuilder -- assembles a fixture home from modules, each a
+// scenario an integration test can target: a developer's projects with
+// their dependency and build trees, a photo library, a Mail.app archive,
+// iCloud and OneDrive placeholders, and the everyday Documents/Downloads.
+//
+// Every time is an offset from the builder's reference time, so a
+// "modified three days ago" file is three days older than the reference on
+// every run, and tests that rank or filter by recency can pin the clock to
+// it. Tags, Finder comments and download origins are written as the
+// extended attributes Finder and Safari write (XML plists, which the
+// readers accept like the binary ones).
+//
+//   const auto report = FixtureHomeBuilder()
+//                           .devProject(QStringLiteral("orbit"), QStringLiteral("ts"))
+//                           .mailArchive(12)
+//                           .cloudPlaceholders()
+//                           .build(home.path(), &error);
+//
+// Contents come from CorpusGenerator, so the same modules, seed and
+// reference write the same bytes; adding a module does not change the files
+// of the others.
+class FixtureHomeBuilder {
+public:
+    static constexpr const char* kFinderTagsXattr = "com.apple.metadata:_kMDItemUserTags";
+    static constexpr const char* kFinderCommentXattr = "com.apple.metadata:kMDItemFinderComment";
+    static constexpr const char* kWhereFromsXattr = "com.apple.metadata:kMDItemWhereFroms";
+    static constexpr const char* kQuarantineXattr = "com.apple.quarantine";
+
+    // 2026-01-01T00:00:00Z, the reference of the standard homes.
+    static QDateTime defaultReference();
+
+    explicit FixtureHomeBuilder(quint32 seed = 1, QDateTime reference = defaultReference());
+
+    // standard_home_v2: every module below, with the scenarios the
+    // integration tests use.
+    static FixtureHomeBuilder standardHomeV2();
+
+    quint32 seed() const { return m_seed; }
+    const QDateTime& reference() const { return m_reference; }
+    const std::vector<FixtureFile>& files() const { return m_files; }
+
+    // The reference time minus `days` (and `seconds`).
+    QDateTime ago(int days, int seconds = 0) const;
+
+    // Developer/<name>: a README, sources in `language` ("cpp", "py", "ts",
+    // "rs" or "go"), a .git directory, and the dependency or build tree the
+    // language leaves (node_modules, __pycache__, target/debug, build),
+    // which the indexer's default exclusions skip. Sources are days old,
+    // the README a month, the dependencies a season.
+    FixtureHomeBuilder& devProject(const QString& name, const QString& language);
+
+    // Pictures: `photos` camera-roll JPEGs a week apart, every third one
+    // tagged Vacation, and a Photos Library.photoslibrary bundle.
+    FixtureHomeBuilder& photoLibrary(int photos);
+
+    // Library/Mail/V10: `messages` .emlx messages a day apart in an INBOX
+    // and an Archive mailbox, laid out as MailSource reads them, each file
+    // dated as its Date header.
+    FixtureHomeBuilder& mailArchive(int messages);
+
+    // Library/Mobile Documents and OneDrive: downloaded files next to
+    // ".name.icloud" stubs of evicted ones, the folders' attribute plists,
+    // and a OneDrive folder placeholder; PathRules treats all but the
+    // downloaded files as cloud artifacts.
+    FixtureHomeBuilder& cloudPlaceholders();
+
+    // Documents, Desktop and Downloads: tagged and commented documents, and
+    // downloads carrying their origin URLs and a quarantine flag.
+    FixtureHomeBuilder& documents();
+
+    // Anything a module does not cover.
+    FixtureHomeBuilder& file(FixtureFile file);
+
+    // Write the home under `root`, which must be empty or missing. Nullopt
+    // with *error for a module given invalid arguments, an invalid file (a
+    // "{n}" path, two files at one path), or one that cannot be written or
+    // given its attributes.
+    std::optional<FixtureHomeReport> build(const QString& root, QString* error) const;
+
+    // The Finder tags attribute for `tags`, as an XML plist.
+    static QByteArray finderTagsPlist(const QStringList& tags);
+    // An XML plist of one string, or of an array of them.
+    static QByteArray stringPlist(const QString& value);
+    static QByteArray stringArrayPlist(const QStringList& values);
+
+private:
+    FixtureFile& add(const QString& module, CorpusEntryKind kind, const QString& path,
+                     const QDateTime& modifiedAt);
+
+    quint32 m_seed;
+    QDateTime m_reference;
+    std::vector<FixtureFile> m_files;
+    QStringList m_errors;  // reported by build()
+};
+
+} // namespace bs::test
@@ -0,0 +1,86 @@
+#include "fsevent_replay.h"
+
+#include <optional>
+
+namespace bs::test {
+
+FsEventReplayResult FsEventReplayer::replay(const FsEventRecording& recording,
+                                            const FileMonitor::ChangeCallback& deliver,
+                                            int debounceMs)
+{
+    FsEventReplayResult result;
+    const FileMonitor::ErrorCallback onError = [&result](const QString& error) {
+        result.errors.append(error);
+    };
+
+    // As in FileMonitorMacOS::handleEvents: the first buffered batch arms a
+    // single timer, and every callback before it fires joins the delivery.
+    std::vector<WorkItem> pending;
+    std::optional<int64_t> flushAt;
+    const auto flush = [&]() {
+        FsEventDelivery delivery;
+        delivery.atMs = *flushAt;
+        delivery.items = std::move(pending);
+        delivery.lastEventId = result.lastEventId;
+        pending.clear();
+        flushAt.reset();
+        if (deliver) {
+            deliver(delivery.items);
+        }
+        result.deliveries.push_back(std::move(delivery));
+    };
+
+    for (const FsEventBatch& batch : recording.batches) {
+        if (flushAt.has_value() && batch.offsetMs >= *flushAt) {
+            flush();
+        }
+        std::vector<WorkItem> items = FileMonitorMacOS::translateEvents(batch.events, onError);
+        if (!batch.events.empty()) {
+            result.lastEventId = batch.events.back().eventId;
+        }
+        if (items.empty()) {
+            continue;
+        }
+        pending.insert(pending.end(), std::make_move_iterator(items.begin()),
+                       std::make_move_iterator(items.end()));
+        if (!flushAt.has_value()) {
+            flushAt = batch.offsetMs + debounceMs;
+        }
+    }
+    if (flushAt.has_value()) {
+        flush();
+    }
+    return result;
+}
+
+QStringList FsEventReplayer::describe(const FsEventReplayResult& result)
+{
+    QStringList lines;
+    for (const FsEventDelivery& delivery : result.deliveries) {
+        if (!lines.isEmpty()) {
+            lines.append(QStringLiteral("--"));
+        }
+        for (const WorkItem& item : delivery.items) {
+            lines.append(typeName(item.type) + QLatin1Char(' ')
+                         + QString::fromStdString(item.filePath));
+        }
+    }
+    return lines;
+}
+
+QString FsEventReplayer::typeName(WorkItem::Type type)
+{
+    switch (type) {
+    case WorkItem::Type::Delete:
+        return QStringLiteral("delete");
+    case WorkItem::Type::ModifiedContent:
+        return QStringLiteral("modified");
+    case WorkItem::Type::NewFile:
+        return QStringLiteral("new");
+    case WorkItem::Type::RescanDirectory:
+        return QStringLiteral("rescan");
+    }
+    return QStringLiteral("unknown");
+}
+
+} // namespace bs::test
@@ -0,0 +1,46 @@
+#pragma once
+
+#include "core/fs/file_monitor_macos.h"
+#include "core/fs/fsevent_recording.h"
+
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <vector>
+
+namespace bs::test {
+
+// One delivery the monitor would have made: the batch
+// Pipeline::onFileSystemEvents receives.
+struct FsEventDelivery {
+    int64_t atMs = 0;          // when the debounce timer fired, recording time
+    std::vector<WorkItem> items;
+    uint64_t lastEventId = 0;  // the monitor's lastEventId() at that point
+};
+
+struct FsEventReplayResult {
+    std::vector<FsEventDelivery> deliveries;
+    QStringList errors;        // what the error callback was told, in order
+    uint64_t lastEventId = 0;
+};
+
+// FsEventReplayer -- plays an FSEvents recording (FsEventRecorder) back
+// through FileMonitorMacOS's own translation and debounce, on the
+// recording's clock instead of a real one: no stream, no sleeping, and the
+// same deliveries on every run. A coalescing or rename-tracking bug seen
+// live is reproduced by recording it once and replaying the file in a test.
+class FsEventReplayer {
+public:
+    // Deliveries are handed to `deliver` as they fall due, when given.
+    static FsEventReplayResult replay(const FsEventRecording& recording,
+                                      const FileMonitor::ChangeCallback& deliver = {},
+                                      int debounceMs = FileMonitorMacOS::kDebounceMs);
+
+    // "<type> <path>" per delivered item, with "--" between deliveries, for
+    // comparing against an expected listing.
+    static QStringList describe(const FsEventReplayResult& result);
+    static QString typeName(WorkItem::Type type);
+};
+
+} // namespace bs::test
@@ -0,0 +1,104 @@
+// fseventrec -- records the FSEvents stream of one or more directories to a
+// file that FsEventReplayer (fsevent_replay.h) plays back in tests, and
+// prints the WorkItems the monitor delivered while recording.
+//
+//   fseventrec --output /tmp/rename.ndjson --seconds 20 ~/Documents
+
+#include "core/fs/file_monitor_macos.h"
+#include "fsevent_replay.h"
+
+#include <QCommandLineParser>
+#include <QCoreApplication>
+#include <QDir>
+#include <QFileInfo>
+#include <QMetaObject>
+#include <QTextStream>
+#include <QTimer>
+
+int main(int argc, char* argv[])
+{
+    QCoreApplication app(argc, argv);
+    QCoreApplication::setApplicationName(QStringLiteral("fseventrec"));
+
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Record the raw FSEvents stream of the given directories for replay in "
+                       "watcher tests. Make the changes to reproduce while it runs.\n"
+                       "Exit status: 0 recorded, 1 the stream or the output could not be "
+                       "opened, 2 usage error."));
+    parser.addHelpOption();
+    parser.addPositionalArgument(QStringLiteral("roots"),
+                                 QStringLiteral("Directories to watch."),
+                                 QStringLiteral("<root>..."));
+    const QCommandLineOption outputOption(
+        QStringLiteral("output"), QStringLiteral("The recording to write."),
+        QStringLiteral("path"));
+    const QCommandLineOption secondsOption(
+        QStringLiteral("seconds"), QStringLiteral("How long to record (default 30)."),
+        QStringLiteral("n"), QStringLiteral("30"));
+    parser.addOptions({outputOption, secondsOption});
+    parser.process(app);
+
+    QTextStream out(stdout);
+    QTextStream err(stderr);
+    const QStringList args = parser.positionalArguments();
+    bool secondsOk = false;
+    const int seconds = parser.value(secondsOption).toInt(&secondsOk);
+    if (args.isEmpty() || !parser.isSet(outputOption) || !secondsOk || seconds <= 0) {
+        err << "usage: fseventrec --output <path> [--seconds n] <root>..." << Qt::endl;
+        return 2;
+    }
+
+    std::vector<std::string> roots;
+    for (const QString& arg : args) {
+        const QFileInfo info(arg);
+        if (!info.isDir()) {
+            err << "fseventrec: " << arg << " is not a directory" << Qt::endl;
+            return 2;
+        }
+        roots.push_back(QDir::cleanPath(info.absoluteFilePath()).toStdString());
+    }
+
+    const QString output = parser.value(outputOption);
+    bs::FileMonitorMacOS monitor;
+    monitor.setRecordingPath(output);
+    monitor.setErrorCallback([&err](const QString& error) {
+        err << "fseventrec: " << error << Qt::endl;
+    });
+    // Deliveries arrive on the monitor's queue; print them on the main thread.
+    const bool started = monitor.start(roots, [&app, &out](const std::vector<bs::WorkItem>& items) {
+        QStringList lines;
+        for (const bs::WorkItem& item : items) {
+            lines.append(bs::test::FsEventReplayer::typeName(item.type) + QLatin1Char(' ')
+                         + QString::fromStdString(item.filePath));
+        }
+        QMetaObject::invokeMethod(&app, [&out, lines] {
+            for (const QString& line : lines) {
+                out << line << Qt::endl;
+            }
+            out << "--" << Qt::endl;
+        });
+    });
+    if (!started) {
+        err << "fseventrec: cannot watch " << args.join(QStringLiteral(", ")) << Qt::endl;
+        return 1;
+    }
+
+    QTimer::singleShot(seconds * 1000, &app, &QCoreApplication::quit);
+    app.exec();
+    monitor.stop();
+
+    QString error;
+    const std::optional<bs::FsEventRecording> recording = bs::FsEventRecorder::load(output, &error);
+    if (!recording.has_value()) {
+        err << "fseventrec: " << error << Qt::endl;
+        return 1;
+    }
+    size_t events = 0;
+    for (const bs::FsEventBatch& batch : recording->batches) {
+        events += batch.events.size();
+    }
+    err << "fseventrec: recorded " << events << " events in " << recording->batches.size()
+        << " callbacks to " << output << Qt::endl;
+    return 0;
+}
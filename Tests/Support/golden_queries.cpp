@@ -0,0 +1,347 @@
+#include "golden_queries.h"
+
+#include "core/index/sqlite_store.h"
+#include "core/shared/chunk.h"
+#include "core/shared/types.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QDirIterator>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs::test {
+
+namespace {
+
+constexpr qint64 kSeedTextBytes = 8192;
+
+QStringList stringList(const QJsonValue& value)
+{
+    QStringList out;
+    for (const QJsonValue& entry : value.toArray()) {
+        out.append(entry.toString());
+    }
+    return out;
+}
+
+QJsonArray jsonArray(const QStringList& values)
+{
+    QJsonArray out;
+    for (const QString& value : values) {
+        out.append(value);
+    }
+    return out;
+}
+
+// Rank (1-based) of `name` in `ranked`, 0 when absent. File names compare
+// case-insensitively, as APFS does.
+int rankOf(const QStringList& ranked, const QString& name)
+{
+    for (int i = 0; i < ranked.size(); ++i) {
+        if (ranked.at(i).compare(name, Qt::CaseInsensitive) == 0) {
+            return i + 1;
+        }
+    }
+    return 0;
+}
+
+bool sameName(const QString& a, const QString& b)
+{
+    return a.compare(b, Qt::CaseInsensitive) == 0;
+}
+
+QString tokenizedName(const QString& fileName)
+{
+    QString out = fileName;
+    out.replace(QLatin1Char('-'), QLatin1Char(' '));
+    out.replace(QLatin1Char('_'), QLatin1Char(' '));
+    out.replace(QLatin1Char('.'), QLatin1Char(' '));
+    return out.simplified().toLower();
+}
+
+// The name twice over (whole and without extension) and the first bytes
+// of text, so a name-only binary still matches on its name.
+QString seedContent(const QFileInfo& info)
+{
+    QString content = tokenizedName(info.fileName()) + QLatin1Char(' ')
+                      + tokenizedName(info.completeBaseName());
+    QFile file(info.filePath());
+    if (!file.open(QIODevice::ReadOnly)) {
+        return content;
+    }
+    const QByteArray bytes = file.read(kSeedTextBytes);
+    if (bytes.contains('\0')) {
+        return content;
+    }
+    const QString text = QString::fromUtf8(bytes).simplified();
+    return text.isEmpty() ? content : content + QLatin1Char('\n') + text;
+}
+
+// The extension mapping the relevance fixture test uses, so both gates
+// rank the same index.
+ItemKind seedKind(const QString& suffix)
+{
+    static const QSet<QString> code = {QStringLiteral("cpp"), QStringLiteral("h"),
+                                       QStringLiteral("py"), QStringLiteral("ts"),
+                                       QStringLiteral("js"), QStringLiteral("go"),
+                                       QStringLiteral("rs")};
+    static const QSet<QString> images = {QStringLiteral("png"), QStringLiteral("jpg"),
+                                         QStringLiteral("jpeg"), QStringLiteral("webp")};
+    static const QSet<QString> binaries = {QStringLiteral("mp3"), QStringLiteral("mp4"),
+                                           QStringLiteral("mov")};
+    if (suffix == QLatin1String("pdf")) return ItemKind::Pdf;
+    if (suffix == QLatin1String("md") || suffix == QLatin1String("markdown")) {
+        return ItemKind::Markdown;
+    }
+    if (images.contains(suffix)) return ItemKind::Image;
+    if (binaries.contains(suffix)) return ItemKind::Binary;
+    if (code.contains(suffix)) return ItemKind::Code;
+    return ItemKind::Text;
+}
+
+} // namespace
+
+std::optional<GoldenSuite> GoldenQueries::parseSuite(const QByteArray& json, QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("A golden-query suite is a JSON object: %1")
+                     .arg(parseError.errorString());
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    const int version = object.value(QStringLiteral("version")).toInt(kFormatVersion);
+    if (version < 1 || version > kFormatVersion) {
+        *error = QStringLiteral("Suite version %1 is not supported (up to %2)")
+                     .arg(version)
+                     .arg(kFormatVersion);
+        return std::nullopt;
+    }
+
+    GoldenSuite suite;
+    suite.fixtureId = object.value(QStringLiteral("fixtureId")).toString();
+    if (suite.fixtureId.isEmpty()) {
+        *error = QStringLiteral("The suite names no fixtureId");
+        return std::nullopt;
+    }
+    QSet<QString> ids;
+    const QJsonArray cases = object.value(QStringLiteral("cases")).toArray();
+    for (qsizetype i = 0; i < cases.size(); ++i) {
+        const QJsonObject json = cases.at(i).toObject();
+        GoldenCase goldenCase;
+        goldenCase.id = json.value(QStringLiteral("id")).toString();
+        goldenCase.query = json.value(QStringLiteral("query")).toString();
+        goldenCase.mode = json.value(QStringLiteral("mode")).toString(goldenCase.mode);
+        goldenCase.expected = stringList(json.value(QStringLiteral("expected")));
+        goldenCase.ordered = json.value(QStringLiteral("ordered")).toBool(true);
+        goldenCase.topN = json.value(QStringLiteral("topN")).toInt(0);
+        goldenCase.excluded = stringList(json.value(QStringLiteral("excluded")));
+        goldenCase.notes = json.value(QStringLiteral("notes")).toString();
+
+        const QString label = goldenCase.id.isEmpty() ? QStringLiteral("#%1").arg(i + 1)
+                                                      : goldenCase.id;
+        if (goldenCase.id.isEmpty() || goldenCase.query.isEmpty()) {
+            *error = QStringLiteral("Case %1 needs an id and a query").arg(label);
+            return std::nullopt;
+        }
+        if (ids.contains(goldenCase.id)) {
+            *error = QStringLiteral("Case %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (goldenCase.expected.isEmpty() && goldenCase.excluded.isEmpty()) {
+            *error = QStringLiteral("Case %1 expects nothing").arg(label);
+            return std::nullopt;
+        }
+        if (goldenCase.topN < 0
+            || (goldenCase.topN > 0 && goldenCase.topN < goldenCase.expected.size())) {
+            *error = QStringLiteral("Case %1: topN must cover every expected result").arg(label);
+            return std::nullopt;
+        }
+        if (goldenCase.depth() == 0) {
+            *error = QStringLiteral("Case %1: excluded results need a topN").arg(label);
+            return std::nullopt;
+        }
+        ids.insert(goldenCase.id);
+        suite.cases.push_back(std::move(goldenCase));
+    }
+    return suite;
+}
+
+std::optional<GoldenSuite> GoldenQueries::loadSuite(const QString& path, QString* error)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("Cannot read %1").arg(path);
+        return std::nullopt;
+    }
+    return parseSuite(file.readAll(), error);
+}
+
+QJsonObject GoldenQueries::suiteToJson(const GoldenSuite& suite)
+{
+    QJsonArray cases;
+    for (const GoldenCase& goldenCase : suite.cases) {
+        // Defaults are left out, so the table stays as terse as written.
+        QJsonObject json;
+        json[QStringLiteral("id")] = goldenCase.id;
+        json[QStringLiteral("query")] = goldenCase.query;
+        if (goldenCase.mode != QLatin1String("auto")) {
+            json[QStringLiteral("mode")] = goldenCase.mode;
+        }
+        json[QStringLiteral("expected")] = jsonArray(goldenCase.expected);
+        if (!goldenCase.ordered) {
+            json[QStringLiteral("ordered")] = false;
+        }
+        if (goldenCase.topN > 0) {
+            json[QStringLiteral("topN")] = goldenCase.topN;
+        }
+        if (!goldenCase.excluded.isEmpty()) {
+            json[QStringLiteral("excluded")] = jsonArray(goldenCase.excluded);
+        }
+        if (!goldenCase.notes.isEmpty()) {
+            json[QStringLiteral("notes")] = goldenCase.notes;
+        }
+        cases.append(json);
+    }
+    QJsonObject json;
+    json[QStringLiteral("version")] = kFormatVersion;
+    json[QStringLiteral("fixtureId")] = suite.fixtureId;
+    json[QStringLiteral("cases")] = cases;
+    return json;
+}
+
+QString GoldenQueries::diff(const GoldenCase& goldenCase, const QStringList& actual)
+{
+    const int depth = goldenCase.depth();
+    const QStringList top = actual.mid(0, depth);
+
+    QStringList problems;
+    QSet<int> badRows;
+    for (int i = 0; i < goldenCase.expected.size(); ++i) {
+        const QString& name = goldenCase.expected.at(i);
+        const int rank = rankOf(actual, name);
+        const bool inPlace = goldenCase.ordered ? (rank == i + 1) : (rank > 0 && rank <= depth);
+        if (inPlace) {
+            continue;
+        }
+        badRows.insert(i);
+        const QString where = goldenCase.ordered ? QStringLiteral("at %1").arg(i + 1)
+                                                 : QStringLiteral("in the top %1").arg(depth);
+        if (rank == 0) {
+            problems.append(QStringLiteral("%1: expected %2, not returned").arg(name, where));
+        } else {
+            problems.append(QStringLiteral("%1: expected %2, ranked %3")
+                                .arg(name, where, QString::number(rank)));
+        }
+    }
+    for (const QString& name : goldenCase.excluded) {
+        const int rank = rankOf(top, name);
+        if (rank > 0) {
+            badRows.insert(rank - 1);
+            problems.append(QStringLiteral("%1: excluded, ranked %2").arg(name, QString::number(rank)));
+        }
+    }
+    if (problems.isEmpty()) {
+        return QString();
+    }
+    if (!goldenCase.ordered) {
+        // Any order passes, so a row is only wrong for a name that should
+        // not be in the top at all.
+        badRows.clear();
+        for (int i = 0; i < top.size(); ++i) {
+            const bool expected = std::any_of(goldenCase.expected.begin(),
+                                              goldenCase.expected.end(),
+                                              [&](const QString& name) {
+                                                  return sameName(name, top.at(i));
+                                              });
+            if (!expected) {
+                badRows.insert(i);
+            }
+        }
+    }
+
+    int width = static_cast<int>(QStringLiteral("expected").size());
+    for (const QString& name : goldenCase.expected) {
+        width = std::max(width, static_cast<int>(name.size()));
+    }
+    QString out = QStringLiteral("%1 \"%2\" (%3, %4 top %5)\n")
+                      .arg(goldenCase.id, goldenCase.query, goldenCase.mode,
+                           goldenCase.ordered ? QStringLiteral("ordered")
+                                              : QStringLiteral("any order"),
+                           QString::number(depth));
+    out += QStringLiteral("  rank  %1  actual\n").arg(QStringLiteral("expected"), -width);
+    for (int i = 0; i < depth; ++i) {
+        const QString expected = i < goldenCase.expected.size() ? goldenCase.expected.at(i)
+                                                                : QString();
+        const QString shown = i < top.size() ? top.at(i) : QStringLiteral("-");
+        const bool bad = badRows.contains(i)
+                         || (goldenCase.ordered && !expected.isEmpty() && !sameName(expected, shown));
+        // One multi-arg call so a '%' in a file name is not substituted.
+        out += QStringLiteral("%1 %2  %3  %4\n")
+                   .arg(bad ? QStringLiteral("!") : QStringLiteral(" "),
+                        QStringLiteral("%1").arg(i + 1, 4), expected.leftJustified(width), shown);
+    }
+    for (const QString& problem : problems) {
+        out += QStringLiteral("  %1\n").arg(problem);
+    }
+    return out;
+}
+
+GoldenCase GoldenQueries::updated(const GoldenCase& goldenCase, const QStringList& actual)
+{
+    GoldenCase result = goldenCase;
+    if (!goldenCase.expected.isEmpty()) {
+        result.expected = actual.mid(0, goldenCase.expected.size());
+    }
+    const QStringList top = actual.mid(0, goldenCase.depth());
+    result.excluded.removeIf([&](const QString& name) { return rankOf(top, name) > 0; });
+    return result;
+}
+
+int GoldenQueries::seedFixtureHome(SQLiteStore& store, const QString& fixtureRoot,
+                                   const QString& targetRoot, QString* error)
+{
+    const double seededAt = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    int seeded = 0;
+    QDirIterator it(fixtureRoot, QDir::Files, QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QFileInfo source(it.next());
+        const QString targetPath =
+            QDir(targetRoot).filePath(QDir(fixtureRoot).relativeFilePath(source.filePath()));
+        if (!QDir().mkpath(QFileInfo(targetPath).path())) {
+            *error = QStringLiteral("Cannot create %1").arg(QFileInfo(targetPath).path());
+            return -1;
+        }
+        const QString suffix = source.suffix().toLower();
+        const auto itemId = store.upsertItem(
+            targetPath, source.fileName(), suffix.isEmpty() ? QString() : QLatin1Char('.') + suffix,
+            seedKind(suffix), std::max<int64_t>(1, source.size()), seededAt, seededAt, QString(),
+            QStringLiteral("normal"), QFileInfo(targetPath).path());
+        if (!itemId.has_value()) {
+            *error = QStringLiteral("Cannot index %1").arg(targetPath);
+            return -1;
+        }
+        Chunk chunk;
+        chunk.chunkId = computeChunkId(targetPath, 0);
+        chunk.filePath = targetPath;
+        chunk.chunkIndex = 0;
+        chunk.content = seedContent(source);
+        chunk.byteOffset = 0;
+        if (!store.insertChunks(*itemId, source.fileName(), targetPath, {chunk})) {
+            *error = QStringLiteral("Cannot index the text of %1").arg(targetPath);
+            return -1;
+        }
+        ++seeded;
+    }
+    return seeded;
+}
+
+} // namespace bs::test
@@ -0,0 +1,69 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+class SQLiteStore;
+}
+
+namespace bs::test {
+
+// One row of a golden-query table: a query and the ranked file names it
+// must return.
+struct GoldenCase {
+    QString id;
+    QString query;
+    QString mode = QStringLiteral("auto");  // search queryMode
+    QStringList expected;   // file names, best first
+    bool ordered = true;    // false: the top results hold `expected` in any order
+    int topN = 0;           // how many results are compared; 0 is expected.size()
+    QStringList excluded;   // file names that must not appear in the top topN
+    QString notes;
+
+    int depth() const { return topN > 0 ? topN : static_cast<int>(expected.size()); }
+};
+
+struct GoldenSuite {
+    QString fixtureId;      // a directory under Tests/Fixtures
+    std::vector<GoldenCase> cases;
+};
+
+// GoldenQueries -- ranking regression tables. A suite loads a fixture home,
+// fixes the expected ranked result set of each query, and fails with a
+// side-by-side diff of expected against actual ranks, so a ranking or
+// parser change cannot reorder expected results unnoticed. When a change
+// is meant to move results, BS_GOLDEN_UPDATE=1 rewrites the table from
+// what the service returns, for review in the diff.
+class GoldenQueries {
+public:
+    static constexpr int kFormatVersion = 1;
+
+    // Nullopt with *error for a newer version, a case without an id,
+    // query or expected result, or an id used twice.
+    static std::optional<GoldenSuite> parseSuite(const QByteArray& json, QString* error);
+    static std::optional<GoldenSuite> loadSuite(const QString& path, QString* error);
+    static QJsonObject suiteToJson(const GoldenSuite& suite);
+
+    // Empty when `actual` (ranked file names) satisfies `goldenCase`;
+    // otherwise a table of expected against actual rank by rank, with the
+    // rows that differ marked, and where missing and excluded names were.
+    static QString diff(const GoldenCase& goldenCase, const QStringList& actual);
+    // `goldenCase` expecting what `actual` ranked, for BS_GOLDEN_UPDATE;
+    // excluded names that now rank in the top are dropped.
+    static GoldenCase updated(const GoldenCase& goldenCase, const QStringList& actual);
+
+    // Index every file under `fixtureRoot` into `store` as if it lived
+    // under `targetRoot`: one chunk of its name and first 8 KB of text,
+    // all with the same timestamps so recency cannot reorder them. Returns
+    // the number of files, or -1 with *error.
+    static int seedFixtureHome(SQLiteStore& store, const QString& fixtureRoot,
+                               const QString& targetRoot, QString* error);
+};
+
+} // namespace bs::test
@@ -0,0 +1,447 @@
+#include "mutation_corpus.h"
+
+#include "core/extraction/extraction_manager.h"
+#include "core/fs/path_rules.h"
+#include "core/index/sqlite_store.h"
+#include "core/indexing/pipeline.h"
+
+#include <QDir>
+#include <QDirIterator>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QTemporaryDir>
+#include <QTest>
+
+#include <cstdio>
+#include <sys/stat.h>
+
+namespace bs::test {
+
+namespace {
+
+constexpr int kPollMs = 25;
+// Long enough for the FSEvents latency plus the monitor's debounce, so a
+// quiet pipeline has been handed everything the steps caused.
+constexpr int kSettleQuietMs = 2500;
+constexpr int kCrawlQuietMs = 500;
+constexpr int kSettleTimeoutMs = 60000;
+
+PipelineRuntimeConfig harnessConfig()
+{
+    PipelineRuntimeConfig config;
+    config.batchCommitSize = 16;
+    config.batchCommitIntervalMs = 20;
+    return config;
+}
+
+const QMap<QString, Mutation::Op>& opNames()
+{
+    static const QMap<QString, Mutation::Op> names = {
+        {QStringLiteral("create"), Mutation::Op::Create},
+        {QStringLiteral("append"), Mutation::Op::Append},
+        {QStringLiteral("mkdir"), Mutation::Op::Mkdir},
+        {QStringLiteral("rename"), Mutation::Op::Rename},
+        {QStringLiteral("move"), Mutation::Op::Move},
+        {QStringLiteral("chmod"), Mutation::Op::Chmod},
+        {QStringLiteral("delete"), Mutation::Op::Delete},
+    };
+    return names;
+}
+
+// "home/..." or "external/...", with no way out of the volume.
+bool isVolumePath(const QString& path)
+{
+    const QString volume = path.section(QLatin1Char('/'), 0, 0);
+    const QString rest = path.section(QLatin1Char('/'), 1);
+    if ((volume != QLatin1String(MutationHarness::kHomeVolume)
+         && volume != QLatin1String(MutationHarness::kExternalVolume))
+        || rest.isEmpty()) {
+        return false;
+    }
+    return !rest.split(QLatin1Char('/')).contains(QStringLiteral(".."));
+}
+
+bool writeFile(const QString& path, const QByteArray& bytes, QIODevice::OpenMode mode,
+               QString* error)
+{
+    if (!QDir().mkpath(QFileInfo(path).absolutePath())) {
+        *error = QStringLiteral("cannot create the directory of %1").arg(path);
+        return false;
+    }
+    QFile file(path);
+    if (!file.open(mode) || file.write(bytes) != bytes.size()) {
+        *error = QStringLiteral("cannot write %1: %2").arg(path, file.errorString());
+        return false;
+    }
+    return true;
+}
+
+// A file copied as mv(1) copies it across volumes: content, permissions
+// and modification time.
+bool copyFile(const QString& from, const QString& to, QString* error)
+{
+    const QFileInfo info(from);
+    QFile source(from);
+    if (!source.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("cannot read %1: %2").arg(from, source.errorString());
+        return false;
+    }
+    if (!writeFile(to, source.readAll(), QIODevice::WriteOnly | QIODevice::Truncate, error)) {
+        return false;
+    }
+    QFile target(to);
+    if (!target.open(QIODevice::ReadWrite)
+        || !target.setFileTime(info.lastModified(), QFileDevice::FileModificationTime)) {
+        *error = QStringLiteral("cannot set the modification time of %1").arg(to);
+        return false;
+    }
+    target.close();
+    QFile::setPermissions(to, info.permissions());
+    return true;
+}
+
+bool copyTree(const QString& from, const QString& to, QString* error)
+{
+    if (QFileInfo(from).isFile()) {
+        return copyFile(from, to, error);
+    }
+    if (!QDir().mkpath(to)) {
+        *error = QStringLiteral("cannot create %1").arg(to);
+        return false;
+    }
+    const QDir source(from);
+    QDirIterator it(from, QDir::AllEntries | QDir::NoDotAndDotDot | QDir::Hidden,
+                    QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QString path = it.next();
+        const QString target = QDir(to).filePath(source.relativeFilePath(path));
+        if (it.fileInfo().isDir()) {
+            if (!QDir().mkpath(target)) {
+                *error = QStringLiteral("cannot create %1").arg(target);
+                return false;
+            }
+        } else if (!copyFile(path, target, error)) {
+            return false;
+        }
+    }
+    return true;
+}
+
+bool removePath(const QString& path, QString* error)
+{
+    const QFileInfo info(path);
+    const bool removed = info.isDir() && !info.isSymLink() ? QDir(path).removeRecursively()
+                                                           : QFile::remove(path);
+    if (!removed) {
+        *error = QStringLiteral("cannot delete %1").arg(path);
+    }
+    return removed;
+}
+
+// Until nothing is queued or in flight and the processed count has held
+// still for `quietMs`; with `crawl`, also until every root is scanned.
+bool waitUntilSettled(const Pipeline& pipeline, bool crawl, int quietMs)
+{
+    QElapsedTimer timer;
+    timer.start();
+    QElapsedTimer quiet;
+    quiet.start();
+    int lastProcessed = -1;
+    while (timer.elapsed() < kSettleTimeoutMs) {
+        const QueueStats stats = pipeline.queueStatus();
+        const bool busy = stats.depth > 0 || stats.preparing > 0 || stats.writing > 0
+                          || (crawl && !pipeline.progressSnapshot().crawlComplete);
+        const int processed = pipeline.processedCount();
+        if (busy || processed != lastProcessed) {
+            lastProcessed = processed;
+            quiet.restart();
+        } else if (quiet.elapsed() >= quietMs) {
+            return true;
+        }
+        QTest::qWait(kPollMs);
+    }
+    return false;
+}
+
+} // namespace
+
+MutationHarness::MutationHarness(const QString& workDir)
+    : m_workDir(workDir)
+    , m_homeRoot(QDir(workDir).filePath(QLatin1String(kHomeVolume)))
+    , m_externalRoot(QDir(workDir).filePath(QLatin1String(kExternalVolume)))
+{
+    const QString secondVolume = qEnvironmentVariable(kEnvSecondVolume).trimmed();
+    if (!secondVolume.isEmpty()) {
+        m_secondVolume = std::make_unique<QTemporaryDir>(
+            QDir(secondVolume).filePath(QStringLiteral("bs-mutation-XXXXXX")));
+        if (m_secondVolume->isValid()) {
+            m_externalRoot =
+                QDir(m_secondVolume->path()).filePath(QLatin1String(kExternalVolume));
+        }
+    }
+}
+
+MutationHarness::~MutationHarness() = default;
+
+std::optional<MutationScript> MutationHarness::loadScript(const QString& path, QString* error)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("cannot read %1: %2").arg(path, file.errorString());
+        return std::nullopt;
+    }
+    return parseScript(file.readAll(), error);
+}
+
+std::optional<MutationScript> MutationHarness::parseScript(const QByteArray& json, QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (!document.isObject()) {
+        *error = QStringLiteral("not a JSON object: %1").arg(parseError.errorString());
+        return std::nullopt;
+    }
+    const QJsonObject root = document.object();
+
+    MutationScript script;
+    script.id = root.value(QStringLiteral("id")).toString();
+    script.description = root.value(QStringLiteral("description")).toString();
+    script.stepDelayMs = root.value(QStringLiteral("stepDelayMs")).toInt(0);
+    script.knownIssue = root.value(QStringLiteral("knownIssue")).toString();
+    if (script.id.isEmpty()) {
+        *error = QStringLiteral("a script needs an id");
+        return std::nullopt;
+    }
+
+    const QJsonObject tree = root.value(QStringLiteral("tree")).toObject();
+    for (auto it = tree.constBegin(); it != tree.constEnd(); ++it) {
+        if (!isVolumePath(it.key())) {
+            *error = QStringLiteral("tree: %1 is not under home/ or external/").arg(it.key());
+            return std::nullopt;
+        }
+        script.tree.insert(it.key(), it.value().toString().toUtf8());
+    }
+
+    const QJsonArray steps = root.value(QStringLiteral("steps")).toArray();
+    for (qsizetype i = 0; i < steps.size(); ++i) {
+        const QJsonObject step = steps.at(i).toObject();
+        const QString where = QStringLiteral("step %1").arg(i + 1);
+        const QString opName = step.value(QStringLiteral("op")).toString();
+        if (!opNames().contains(opName)) {
+            *error = QStringLiteral("%1: unknown op \"%2\"").arg(where, opName);
+            return std::nullopt;
+        }
+
+        Mutation mutation;
+        mutation.op = opNames().value(opName);
+        mutation.path = step.value(QStringLiteral("path")).toString();
+        mutation.to = step.value(QStringLiteral("to")).toString();
+        mutation.content = step.value(QStringLiteral("content")).toString().toUtf8();
+        if (!isVolumePath(mutation.path)) {
+            *error = QStringLiteral("%1: %2 is not under home/ or external/")
+                         .arg(where, mutation.path);
+            return std::nullopt;
+        }
+        switch (mutation.op) {
+        case Mutation::Op::Rename:
+        case Mutation::Op::Move:
+            if (!isVolumePath(mutation.to)) {
+                *error = QStringLiteral("%1: %2 needs a \"to\" under home/ or external/")
+                             .arg(where, opName);
+                return std::nullopt;
+            }
+            if (mutation.op == Mutation::Op::Rename
+                && mutation.to.section(QLatin1Char('/'), 0, 0)
+                       != mutation.path.section(QLatin1Char('/'), 0, 0)) {
+                *error = QStringLiteral("%1: a rename stays on its volume; use move")
+                             .arg(where);
+                return std::nullopt;
+            }
+            break;
+        case Mutation::Op::Chmod: {
+            bool ok = false;
+            mutation.mode = step.value(QStringLiteral("mode")).toString().toInt(&ok, 8);
+            if (!ok || mutation.mode < 0 || mutation.mode > 07777) {
+                *error = QStringLiteral("%1: chmod needs an octal \"mode\" such as \"0444\"")
+                             .arg(where);
+                return std::nullopt;
+            }
+            break;
+        }
+        case Mutation::Op::Create:
+        case Mutation::Op::Append:
+        case Mutation::Op::Mkdir:
+        case Mutation::Op::Delete:
+            break;
+        }
+        script.steps.push_back(std::move(mutation));
+    }
+    return script;
+}
+
+std::vector<std::string> MutationHarness::roots() const
+{
+    return {m_homeRoot.toStdString(), m_externalRoot.toStdString()};
+}
+
+QString MutationHarness::resolve(const QString& scriptPath) const
+{
+    const QString volume = scriptPath.section(QLatin1Char('/'), 0, 0);
+    const QString rest = scriptPath.section(QLatin1Char('/'), 1);
+    return QDir(volume == QLatin1String(kExternalVolume) ? m_externalRoot : m_homeRoot)
+        .filePath(rest);
+}
+
+bool MutationHarness::writeTree(const MutationScript& script, QString* error)
+{
+    for (const QString& root : {m_homeRoot, m_externalRoot}) {
+        if (QFileInfo::exists(root) && !QDir(root).removeRecursively()) {
+            *error = QStringLiteral("cannot empty %1").arg(root);
+            return false;
+        }
+        if (!QDir().mkpath(root)) {
+            *error = QStringLiteral("cannot create %1").arg(root);
+            return false;
+        }
+    }
+    for (auto it = script.tree.cbegin(); it != script.tree.cend(); ++it) {
+        if (!writeFile(resolve(it.key()), it.value(), QIODevice::WriteOnly, error)) {
+            return false;
+        }
+    }
+    return true;
+}
+
+bool MutationHarness::apply(const Mutation& mutation, QString* error)
+{
+    const QString path = resolve(mutation.path);
+    const QString to = resolve(mutation.to);
+    switch (mutation.op) {
+    case Mutation::Op::Create:
+        return writeFile(path, mutation.content, QIODevice::WriteOnly | QIODevice::Truncate,
+                         error);
+    case Mutation::Op::Append:
+        if (!QFileInfo(path).isFile()) {
+            *error = QStringLiteral("cannot append to %1: no such file").arg(path);
+            return false;
+        }
+        return writeFile(path, mutation.content, QIODevice::Append, error);
+    case Mutation::Op::Mkdir:
+        if (!QDir().mkpath(path)) {
+            *error = QStringLiteral("cannot create %1").arg(path);
+            return false;
+        }
+        return true;
+    case Mutation::Op::Rename:
+        if (!QDir().mkpath(QFileInfo(to).absolutePath())
+            || ::rename(QFile::encodeName(path).constData(),
+                        QFile::encodeName(to).constData()) != 0) {
+            *error = QStringLiteral("cannot rename %1 to %2").arg(path, to);
+            return false;
+        }
+        return true;
+    case Mutation::Op::Move:
+        if (!QFileInfo::exists(path)) {
+            *error = QStringLiteral("cannot move %1: no such file").arg(path);
+            return false;
+        }
+        return copyTree(path, to, error) && removePath(path, error);
+    case Mutation::Op::Chmod:
+        if (::chmod(QFile::encodeName(path).constData(), static_cast<mode_t>(mutation.mode))
+            != 0) {
+            *error = QStringLiteral("cannot chmod %1").arg(path);
+            return false;
+        }
+        return true;
+    case Mutation::Op::Delete:
+        return removePath(path, error);
+    }
+    return false;
+}
+
+std::optional<IndexDiffResult> MutationHarness::converge(const MutationScript& script,
+                                                         QString* error)
+{
+    if (!QDir().mkpath(m_workDir) || !writeTree(script, error)) {
+        return std::nullopt;
+    }
+    QFile::remove(liveIndexPath());
+    QFile::remove(freshIndexPath());
+
+    // The live index: a full index, then the steps as the watcher reports them.
+    std::optional<IndexSnapshot> live;
+    {
+        std::optional<SQLiteStore> store = SQLiteStore::open(liveIndexPath());
+        if (!store.has_value()) {
+            *error = QStringLiteral("cannot create %1").arg(liveIndexPath());
+            return std::nullopt;
+        }
+        ExtractionManager extractor;
+        PathRules rules;
+        rules.setExplicitIncludeRoots(roots());
+        Pipeline pipeline(*store, extractor, rules, harnessConfig());
+        pipeline.start(roots());
+        if (!waitUntilSettled(pipeline, true, kCrawlQuietMs)) {
+            *error = QStringLiteral("the first full index did not finish");
+            pipeline.stop();
+            return std::nullopt;
+        }
+        for (size_t i = 0; i < script.steps.size(); ++i) {
+            if (i > 0 && script.stepDelayMs > 0) {
+                QTest::qWait(script.stepDelayMs);
+            }
+            if (!apply(script.steps[i], error)) {
+                *error = QStringLiteral("step %1: %2").arg(QString::number(i + 1), *error);
+                pipeline.stop();
+                return std::nullopt;
+            }
+        }
+        if (!waitUntilSettled(pipeline, false, kSettleQuietMs)) {
+            *error = QStringLiteral("the live index did not settle after the steps");
+            pipeline.stop();
+            return std::nullopt;
+        }
+        pipeline.stop();
+        live = IndexDiff::read(*store);
+    }
+
+    // The fresh index: the final tree, indexed from scratch.
+    std::optional<IndexSnapshot> fresh;
+    {
+        std::optional<SQLiteStore> store = SQLiteStore::open(freshIndexPath());
+        if (!store.has_value()) {
+            *error = QStringLiteral("cannot create %1").arg(freshIndexPath());
+            return std::nullopt;
+        }
+        ExtractionManager extractor;
+        PathRules rules;
+        rules.setExplicitIncludeRoots(roots());
+        Pipeline pipeline(*store, extractor, rules, harnessConfig());
+        pipeline.start(roots());
+        const bool settled = waitUntilSettled(pipeline, true, kCrawlQuietMs);
+        pipeline.stop();
+        if (!settled) {
+            *error = QStringLiteral("the fresh index did not finish");
+            return std::nullopt;
+        }
+        fresh = IndexDiff::read(*store);
+    }
+
+    return IndexDiff::diff(*fresh, *live);
+}
+
+QString MutationHarness::liveIndexPath() const
+{
+    return QDir(m_workDir).filePath(QStringLiteral("live.db"));
+}
+
+QString MutationHarness::freshIndexPath() const
+{
+    return QDir(m_workDir).filePath(QStringLiteral("fresh.db"));
+}
+
+} // namespace bs::test
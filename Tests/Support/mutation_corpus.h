@@ -0,0 +1,99 @@
+#pragma once
+
+#include "cli/index_diff.h"
+
+#include <QByteArray>
+#include <QMap>
+#include <QString>
+
+#include <memory>
+#include <optional>
+#include <string>
+#include <vector>
+
+class QTemporaryDir;
+
+namespace bs::test {
+
+// One scripted change to the fixture tree. Paths start with the volume they
+// are on, "home/" or "external/", followed by a path relative to it.
+struct Mutation {
+    enum class Op { Create, Append, Mkdir, Rename, Move, Chmod, Delete };
+
+    Op op = Op::Create;
+    QString path;
+    QString to;           // Rename, Move
+    QByteArray content;   // Create, Append
+    int mode = 0;         // Chmod, e.g. 0444
+};
+
+struct MutationScript {
+    QString id;
+    QString description;
+    QMap<QString, QByteArray> tree;  // the files present before the first step
+    std::vector<Mutation> steps;
+    int stepDelayMs = 0;    // between steps; 0 lets the watcher see one burst
+    QString knownIssue;     // set while the live index is known not to converge
+};
+
+// MutationHarness -- checks that incremental indexing ends where a full
+// index does. It writes a script's tree, indexes it with a Pipeline and
+// keeps that pipeline watching while the steps run, waits for the live
+// index to settle, then indexes the resulting tree from scratch with a
+// second pipeline and compares the two with IndexDiff. Both indexes are
+// left in the work directory, for `bspot debug diff-index` when a run
+// fails.
+//
+// The two volumes are directories of the work directory, unless
+// BETTERSPOTLIGHT_TEST_SECOND_VOLUME names a directory on another device
+// (e.g. a mounted disk image), which then holds "external". A move is done
+// as mv(1) does one across volumes, copy then delete, either way.
+//
+// Not part of betterspotlight-test-support: it needs src/cli/index_diff.cpp,
+// which a test adds to its own sources.
+class MutationHarness {
+public:
+    static constexpr const char* kEnvSecondVolume = "BETTERSPOTLIGHT_TEST_SECOND_VOLUME";
+    static constexpr const char* kHomeVolume = "home";
+    static constexpr const char* kExternalVolume = "external";
+
+    explicit MutationHarness(const QString& workDir);
+    ~MutationHarness();
+
+    MutationHarness(const MutationHarness&) = delete;
+    MutationHarness& operator=(const MutationHarness&) = delete;
+
+    // A script from its JSON file or text. Nullopt with *error when it is
+    // malformed: an unknown op, a path outside the volumes, or a step
+    // missing what its op needs.
+    static std::optional<MutationScript> loadScript(const QString& path, QString* error);
+    static std::optional<MutationScript> parseScript(const QByteArray& json, QString* error);
+
+    // The watched roots: both volumes' directories.
+    std::vector<std::string> roots() const;
+    // "home/Documents/a.txt" as an absolute path.
+    QString resolve(const QString& scriptPath) const;
+
+    // Write the script's tree under empty volumes.
+    bool writeTree(const MutationScript& script, QString* error);
+    // Apply one step to the tree. False with *error when it failed.
+    bool apply(const Mutation& mutation, QString* error);
+
+    // The whole check described above: the fresh index is the first
+    // snapshot and the live one the second, so "added" documents are ones
+    // the live index kept or invented and "removed" ones it missed.
+    // Nullopt with *error when the tree could not be written or changed, or
+    // an index did not settle in time.
+    std::optional<IndexDiffResult> converge(const MutationScript& script, QString* error);
+
+    QString liveIndexPath() const;
+    QString freshIndexPath() const;
+
+private:
+    QString m_workDir;
+    QString m_homeRoot;
+    QString m_externalRoot;
+    std::unique_ptr<QTemporaryDir> m_secondVolume;
+};
+
+} // namespace bs::test
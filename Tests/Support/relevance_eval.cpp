@@ -0,0 +1,318 @@
+#include "relevance_eval.h"
+
+#include <QFile>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QSet>
+
+#include <algorithm>
+#include <cmath>
+#include <functional>
+
+namespace bs::test {
+
+namespace {
+
+constexpr int kMaxChangesShown = 5;
+constexpr double kChangeEpsilon = 1e-4;
+
+double gain(int grade)
+{
+    return std::pow(2.0, grade) - 1.0;
+}
+
+double discount(int position)
+{
+    return std::log2(static_cast<double>(position) + 2.0);
+}
+
+// Grades keyed by lower-cased file name, as APFS compares them.
+QHash<QString, int> foldedGrades(const QHash<QString, int>& grades)
+{
+    QHash<QString, int> folded;
+    for (auto it = grades.cbegin(); it != grades.cend(); ++it) {
+        folded.insert(it.key().toLower(), it.value());
+    }
+    return folded;
+}
+
+// Settings are stored as text; JSON booleans and numbers become what the
+// store would hold.
+QString settingText(const QJsonValue& value)
+{
+    if (value.isBool()) {
+        return value.toBool() ? QStringLiteral("1") : QStringLiteral("0");
+    }
+    if (value.isDouble()) {
+        return QString::number(value.toDouble());
+    }
+    return value.toString();
+}
+
+QString signedValue(double value)
+{
+    return (value >= 0.0 ? QStringLiteral("+") : QString()) + QString::number(value, 'f', 4);
+}
+
+} // namespace
+
+std::optional<JudgmentSet> RelevanceEval::parseJudgments(const QByteArray& json, QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("A judgment set is a JSON object: %1")
+                     .arg(parseError.errorString());
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    const int version = object.value(QStringLiteral("version")).toInt(kFormatVersion);
+    if (version < 1 || version > kFormatVersion) {
+        *error = QStringLiteral("Judgment set version %1 is not supported (up to %2)")
+                     .arg(version)
+                     .arg(kFormatVersion);
+        return std::nullopt;
+    }
+
+    JudgmentSet set;
+    set.fixtureId = object.value(QStringLiteral("fixtureId")).toString();
+    set.cutoff = object.value(QStringLiteral("cutoff")).toInt(set.cutoff);
+    if (set.fixtureId.isEmpty() || set.cutoff < 1) {
+        *error = QStringLiteral("A judgment set needs a fixtureId and a cutoff of at least 1");
+        return std::nullopt;
+    }
+
+    QSet<QString> names;
+    for (const QJsonValue& value : object.value(QStringLiteral("configurations")).toArray()) {
+        const QJsonObject json = value.toObject();
+        RankerConfig config;
+        config.name = json.value(QStringLiteral("name")).toString();
+        if (config.name.isEmpty() || names.contains(config.name)) {
+            *error = QStringLiteral("Configuration \"%1\" is unnamed or listed twice")
+                         .arg(config.name);
+            return std::nullopt;
+        }
+        const QJsonObject settings = json.value(QStringLiteral("settings")).toObject();
+        for (auto it = settings.begin(); it != settings.end(); ++it) {
+            config.settings.insert(it.key(), settingText(it.value()));
+        }
+        names.insert(config.name);
+        set.configs.push_back(std::move(config));
+    }
+    if (set.configs.empty()) {
+        set.configs.push_back(RankerConfig{QStringLiteral("default"), {}});
+    }
+
+    QSet<QString> ids;
+    for (const QJsonValue& value : object.value(QStringLiteral("judgments")).toArray()) {
+        const QJsonObject json = value.toObject();
+        RelevanceJudgment judgment;
+        judgment.id = json.value(QStringLiteral("id")).toString();
+        judgment.query = json.value(QStringLiteral("query")).toString();
+        judgment.mode = json.value(QStringLiteral("mode")).toString(judgment.mode);
+        if (judgment.id.isEmpty() || judgment.query.isEmpty() || ids.contains(judgment.id)) {
+            *error = QStringLiteral("Judgment \"%1\" needs a unique id and a query")
+                         .arg(judgment.id);
+            return std::nullopt;
+        }
+        bool anyRelevant = false;
+        const QJsonObject relevant = json.value(QStringLiteral("relevant")).toObject();
+        for (auto it = relevant.begin(); it != relevant.end(); ++it) {
+            const int grade = it.value().toInt(-1);
+            if (grade < 0 || grade > kMaxGrade) {
+                *error = QStringLiteral("Judgment %1: grade of %2 must be 0 to %3")
+                             .arg(judgment.id, it.key())
+                             .arg(kMaxGrade);
+                return std::nullopt;
+            }
+            anyRelevant = anyRelevant || grade > 0;
+            judgment.grades.insert(it.key(), grade);
+        }
+        if (!anyRelevant) {
+            *error = QStringLiteral("Judgment %1 has no relevant file").arg(judgment.id);
+            return std::nullopt;
+        }
+        ids.insert(judgment.id);
+        set.judgments.push_back(std::move(judgment));
+    }
+    if (set.judgments.empty()) {
+        *error = QStringLiteral("The judgment set has no judgments");
+        return std::nullopt;
+    }
+    return set;
+}
+
+std::optional<JudgmentSet> RelevanceEval::loadJudgments(const QString& path, QString* error)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("Cannot read %1").arg(path);
+        return std::nullopt;
+    }
+    return parseJudgments(file.readAll(), error);
+}
+
+double RelevanceEval::ndcgAt(const QStringList& ranked, const QHash<QString, int>& grades, int k)
+{
+    const QHash<QString, int> folded = foldedGrades(grades);
+    double dcg = 0.0;
+    QSet<QString> seen;
+    int position = 0;
+    for (const QString& name : ranked) {
+        if (position >= k) {
+            break;
+        }
+        const QString key = name.toLower();
+        if (seen.contains(key)) {
+            continue;
+        }
+        seen.insert(key);
+        dcg += gain(folded.value(key, 0)) / discount(position);
+        ++position;
+    }
+
+    std::vector<int> ideal(folded.cbegin(), folded.cend());
+    std::sort(ideal.begin(), ideal.end(), std::greater<int>());
+    double idcg = 0.0;
+    for (int i = 0; i < std::min<int>(k, static_cast<int>(ideal.size())); ++i) {
+        idcg += gain(ideal[static_cast<size_t>(i)]) / discount(i);
+    }
+    return idcg > 0.0 ? dcg / idcg : 0.0;
+}
+
+double RelevanceEval::reciprocalRank(const QStringList& ranked, const QHash<QString, int>& grades,
+                                     int k)
+{
+    const QHash<QString, int> folded = foldedGrades(grades);
+    for (int i = 0; i < std::min<int>(k, static_cast<int>(ranked.size())); ++i) {
+        if (folded.value(ranked.at(i).toLower(), 0) > 0) {
+            return 1.0 / static_cast<double>(i + 1);
+        }
+    }
+    return 0.0;
+}
+
+QueryScore RelevanceEval::score(const RelevanceJudgment& judgment, const QStringList& ranked, int k)
+{
+    QueryScore result;
+    result.id = judgment.id;
+    result.ndcg = ndcgAt(ranked, judgment.grades, k);
+    result.reciprocalRank = reciprocalRank(ranked, judgment.grades, k);
+    result.ranked = ranked.mid(0, k);
+    return result;
+}
+
+ConfigScore RelevanceEval::aggregate(const QString& name, std::vector<QueryScore> queries)
+{
+    ConfigScore result;
+    result.name = name;
+    for (const QueryScore& query : queries) {
+        result.ndcg += query.ndcg;
+        result.mrr += query.reciprocalRank;
+    }
+    if (!queries.empty()) {
+        result.ndcg /= static_cast<double>(queries.size());
+        result.mrr /= static_cast<double>(queries.size());
+    }
+    result.queries = std::move(queries);
+    return result;
+}
+
+QString RelevanceEval::formatReport(const std::vector<ConfigScore>& scores, int k)
+{
+    if (scores.empty()) {
+        return QString();
+    }
+    const ConfigScore& baseline = scores.front();
+    int width = static_cast<int>(QStringLiteral("config").size());
+    for (const ConfigScore& config : scores) {
+        width = std::max(width, static_cast<int>(config.name.size()));
+    }
+    const QString ndcgLabel = QStringLiteral("NDCG@%1").arg(k);
+
+    QString out = QStringLiteral("%1 and MRR over %2 judged queries\n")
+                      .arg(ndcgLabel)
+                      .arg(baseline.queries.size());
+    out += QStringLiteral("%1  %2  %3  %4  %5\n")
+               .arg(QStringLiteral("config").leftJustified(width), ndcgLabel.rightJustified(8),
+                    QStringLiteral("delta").rightJustified(8), QStringLiteral("MRR").rightJustified(8),
+                    QStringLiteral("delta").rightJustified(8));
+    for (const ConfigScore& config : scores) {
+        const bool isBaseline = &config == &baseline;
+        out += QStringLiteral("%1  %2  %3  %4  %5\n")
+                   .arg(config.name.leftJustified(width),
+                        QString::number(config.ndcg, 'f', 4).rightJustified(8),
+                        (isBaseline ? QStringLiteral("-") : signedValue(config.ndcg - baseline.ndcg))
+                            .rightJustified(8),
+                        QString::number(config.mrr, 'f', 4).rightJustified(8),
+                        (isBaseline ? QStringLiteral("-") : signedValue(config.mrr - baseline.mrr))
+                            .rightJustified(8));
+    }
+
+    QHash<QString, double> baselineNdcg;
+    for (const QueryScore& query : baseline.queries) {
+        baselineNdcg.insert(query.id, query.ndcg);
+    }
+    for (size_t i = 1; i < scores.size(); ++i) {
+        std::vector<const QueryScore*> changed;
+        for (const QueryScore& query : scores[i].queries) {
+            if (std::abs(query.ndcg - baselineNdcg.value(query.id, query.ndcg)) > kChangeEpsilon) {
+                changed.push_back(&query);
+            }
+        }
+        if (changed.empty()) {
+            continue;
+        }
+        std::sort(changed.begin(), changed.end(), [&](const QueryScore* a, const QueryScore* b) {
+            return std::abs(a->ndcg - baselineNdcg.value(a->id))
+                   > std::abs(b->ndcg - baselineNdcg.value(b->id));
+        });
+        out += QStringLiteral("\n%1: %2 of %3 queries moved from %4\n")
+                   .arg(scores[i].name)
+                   .arg(changed.size())
+                   .arg(scores[i].queries.size())
+                   .arg(baseline.name);
+        for (size_t j = 0; j < std::min<size_t>(changed.size(), kMaxChangesShown); ++j) {
+            const QueryScore& query = *changed[j];
+            const double before = baselineNdcg.value(query.id);
+            out += QStringLiteral("  %1  %2 -> %3  (%4)\n")
+                       .arg(query.id, QString::number(before, 'f', 4),
+                            QString::number(query.ndcg, 'f', 4), signedValue(query.ndcg - before));
+        }
+    }
+    return out;
+}
+
+QJsonObject RelevanceEval::reportToJson(const std::vector<ConfigScore>& scores, int k)
+{
+    QJsonArray configs;
+    for (const ConfigScore& config : scores) {
+        QJsonArray queries;
+        for (const QueryScore& query : config.queries) {
+            QJsonObject json;
+            json[QStringLiteral("id")] = query.id;
+            json[QStringLiteral("ndcg")] = query.ndcg;
+            json[QStringLiteral("reciprocalRank")] = query.reciprocalRank;
+            json[QStringLiteral("ranked")] = QJsonArray::fromStringList(query.ranked);
+            queries.append(json);
+        }
+        QJsonObject json;
+        json[QStringLiteral("name")] = config.name;
+        json[QStringLiteral("ndcg")] = config.ndcg;
+        json[QStringLiteral("mrr")] = config.mrr;
+        if (!scores.empty() && &config != &scores.front()) {
+            json[QStringLiteral("ndcgDelta")] = config.ndcg - scores.front().ndcg;
+            json[QStringLiteral("mrrDelta")] = config.mrr - scores.front().mrr;
+        }
+        json[QStringLiteral("queries")] = queries;
+        configs.append(json);
+    }
+    QJsonObject json;
+    json[QStringLiteral("cutoff")] = k;
+    json[QStringLiteral("baseline")] = scores.empty() ? QString() : scores.front().name;
+    json[QStringLiteral("configurations")] = configs;
+    return json;
+}
+
+} // namespace bs::test
@@ -0,0 +1,84 @@
+#pragma once
+
+#include <QByteArray>
+#include <QHash>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs::test {
+
+// One labeled query: the graded relevance of the files it should find.
+// Grades run 0 (irrelevant) to 3 (the answer); unlisted files are 0.
+struct RelevanceJudgment {
+    QString id;
+    QString query;
+    QString mode = QStringLiteral("auto");  // search queryMode
+    QHash<QString, int> grades;             // file name -> grade
+};
+
+// A ranker configuration: store settings written before the query service
+// starts (the keys QueryService reads per search, e.g.
+// "rerankerCascadeEnabled"). No settings is the shipped default.
+struct RankerConfig {
+    QString name;
+    QHash<QString, QString> settings;
+};
+
+struct JudgmentSet {
+    QString fixtureId;
+    int cutoff = 10;                        // k of NDCG@k
+    std::vector<RankerConfig> configs;      // the first is the baseline
+    std::vector<RelevanceJudgment> judgments;
+};
+
+struct QueryScore {
+    QString id;
+    double ndcg = 0.0;
+    double reciprocalRank = 0.0;
+    QStringList ranked;
+};
+
+struct ConfigScore {
+    QString name;
+    double ndcg = 0.0;                      // mean over judgments
+    double mrr = 0.0;
+    std::vector<QueryScore> queries;
+};
+
+// RelevanceEval -- offline ranking metrics over a judged fixture corpus. A
+// judgment set labels queries with graded relevant files and names the
+// ranker configurations to compare; the relevance-eval test runs every
+// configuration through the query service and reports NDCG@k and MRR for
+// each, against the first, so a ranking change comes with numbers.
+class RelevanceEval {
+public:
+    static constexpr int kFormatVersion = 1;
+    static constexpr int kMaxGrade = 3;
+
+    // Nullopt with *error for a newer version, a judgment without an id,
+    // a query or a relevant file, a grade out of range, or a duplicate
+    // judgment or configuration name.
+    static std::optional<JudgmentSet> parseJudgments(const QByteArray& json, QString* error);
+    static std::optional<JudgmentSet> loadJudgments(const QString& path, QString* error);
+
+    // NDCG@k with exponential gain (2^grade - 1) and a log2 discount. File
+    // names compare case-insensitively; a name ranked twice counts once.
+    static double ndcgAt(const QStringList& ranked, const QHash<QString, int>& grades, int k);
+    // 1/rank of the first file with a grade above 0 in the top k, else 0.
+    static double reciprocalRank(const QStringList& ranked, const QHash<QString, int>& grades,
+                                 int k);
+    static QueryScore score(const RelevanceJudgment& judgment, const QStringList& ranked,
+                            int k);
+    static ConfigScore aggregate(const QString& name, std::vector<QueryScore> queries);
+
+    // A fixed-width table, one row per configuration with its deltas from
+    // the first, then the queries whose NDCG moved most from the baseline.
+    static QString formatReport(const std::vector<ConfigScore>& scores, int k);
+    static QJsonObject reportToJson(const std::vector<ConfigScore>& scores, int k);
+};
+
+} // namespace bs::test
@@ -0,0 +1,342 @@
+#include "synthetic_home.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QTimeZone>
+
+#include <algorithm>
+#include <random>
+
+namespace bs::test {
+
+namespace {
+
+constexpr int64_t kProgressEvery = 10000;
+constexpr int kBytesPerWord = 7;
+
+// Size as a multiple of typicalBytes, in eighths: most files near the
+// typical size, a long tail of large ones.
+struct SizeFactor {
+    int eighths;
+    int weight;
+};
+constexpr SizeFactor kSizeFactors[] = {
+    {1, 15}, {2, 20}, {4, 20}, {8, 20}, {16, 13}, {32, 8}, {128, 4},
+};
+
+// Days since the file was last modified: a recent working set, then an
+// older archive.
+struct AgeBucket {
+    int maxDays;
+    int weight;
+};
+constexpr AgeBucket kAgeBuckets[] = {
+    {7, 10}, {30, 20}, {365, 30}, {4 * 365, 30}, {10 * 365, 10},
+};
+
+const char* const kDirectoryNames[] = {
+    "src",     "lib",     "assets",  "cache",   "archive", "notes",  "build",
+    "images",  "drafts",  "shared",  "vendor",  "tests",   "old",    "data",
+    "exports", "reports", "backups", "scripts", "config",  "2024",   "2025",
+};
+const char* const kFileStems[] = {
+    "budget",  "meeting", "notes",   "report",  "draft",   "invoice", "index",
+    "config",  "utils",   "main",    "handler", "photo",   "scan",    "export",
+    "summary", "plan",    "review",  "backup",  "session", "track",   "client",
+};
+
+// Raw engine output only, as in CorpusGenerator: std distributions differ
+// between standard libraries.
+quint32 draw(std::mt19937& rng, quint32 bound)
+{
+    return bound == 0 ? 0 : static_cast<quint32>(rng()) % bound;
+}
+
+template <typename T, typename Weight>
+const T& pick(std::mt19937& rng, const T* begin, const T* end, Weight weightOf)
+{
+    quint32 total = 0;
+    for (const T* it = begin; it != end; ++it) {
+        total += static_cast<quint32>(weightOf(*it));
+    }
+    quint32 target = draw(rng, total);
+    for (const T* it = begin; it != end; ++it) {
+        const auto weight = static_cast<quint32>(weightOf(*it));
+        if (target < weight) {
+            return *it;
+        }
+        target -= weight;
+    }
+    return *(end - 1);
+}
+
+template <typename T, typename Weight>
+const T& pick(std::mt19937& rng, const std::vector<T>& items, Weight weightOf)
+{
+    return pick(rng, items.data(), items.data() + items.size(), weightOf);
+}
+
+template <typename T, size_t N>
+const T& pick(std::mt19937& rng, const T (&items)[N])
+{
+    return pick(rng, items, items + N, [](const T& item) { return item.weight; });
+}
+
+template <size_t N>
+QLatin1String name(std::mt19937& rng, const char* const (&names)[N])
+{
+    return QLatin1String(names[draw(rng, static_cast<quint32>(N))]);
+}
+
+bool isText(CorpusEntryKind kind)
+{
+    return kind != CorpusEntryKind::Binary;
+}
+
+SyntheticFileType type(const char* suffix, CorpusEntryKind kind, int weight, int64_t typicalBytes,
+                       const char* magic = "")
+{
+    return SyntheticFileType{QLatin1String(suffix), kind, QLatin1String(magic), weight,
+                             typicalBytes};
+}
+
+} // namespace
+
+SyntheticHomeProfile SyntheticHome::defaultProfile()
+{
+    using K = CorpusEntryKind;
+    SyntheticHomeProfile profile;
+    profile.roots = {
+        {QStringLiteral("Library"), 320, 9,
+         {type("plist", K::Text, 30, 2048), type("json", K::Json, 20, 4096),
+          type("log", K::Text, 10, 16384), type("db", K::Binary, 5, 262144),
+          type("", K::Binary, 25, 8192), type("png", K::Binary, 10, 12288, "png")}},
+        {QStringLiteral("Developer"), 280, 12,
+         {type("js", K::Source, 30, 6144), type("ts", K::Source, 15, 5120),
+          type("py", K::Source, 10, 4096), type("h", K::Source, 10, 3072),
+          type("cpp", K::Source, 8, 8192), type("json", K::Json, 12, 3072),
+          type("md", K::Markdown, 6, 4096), type("txt", K::Text, 3, 2048),
+          type("o", K::Binary, 6, 40960, "macho")}},
+        {QStringLiteral("Documents"), 110, 6,
+         {type("pdf", K::Binary, 25, 307200, "pdf"), type("docx", K::Binary, 15, 61440, "zip"),
+          type("xlsx", K::Binary, 8, 40960, "zip"), type("md", K::Markdown, 20, 4096),
+          type("txt", K::Text, 15, 3072), type("csv", K::Csv, 7, 20480),
+          type("html", K::Html, 10, 30720)}},
+        {QStringLiteral("Pictures"), 120, 5,
+         {type("jpg", K::Binary, 60, 2097152, "jpeg"), type("png", K::Binary, 15, 1048576, "png"),
+          type("heic", K::Binary, 25, 1572864)}},
+        {QStringLiteral("Downloads"), 60, 3,
+         {type("pdf", K::Binary, 30, 524288, "pdf"), type("zip", K::Binary, 20, 2097152, "zip"),
+          type("dmg", K::Binary, 5, 10485760), type("jpg", K::Binary, 20, 819200, "jpeg"),
+          type("html", K::Html, 15, 61440), type("csv", K::Csv, 10, 40960)}},
+        {QStringLiteral("Music"), 50, 4,
+         {type("mp3", K::Binary, 60, 6291456), type("m4a", K::Binary, 40, 5242880)}},
+        {QStringLiteral("Desktop"), 30, 3,
+         {type("png", K::Binary, 40, 1258291, "png"), type("txt", K::Text, 25, 1024),
+          type("md", K::Markdown, 20, 2048), type("pdf", K::Binary, 15, 204800, "pdf")}},
+        {QStringLiteral(".config"), 20, 4,
+         {type("yml", K::Text, 35, 1024), type("toml", K::Text, 25, 1024),
+          type("lua", K::Text, 20, 2048), type("json", K::Json, 20, 1024)}},
+        {QStringLiteral("Movies"), 10, 3, {type("mov", K::Binary, 80, 52428800),
+                                           type("mp4", K::Binary, 20, 31457280)}},
+    };
+    return profile;
+}
+
+std::vector<std::pair<QString, int>> SyntheticHome::plantedTokens()
+{
+    return {
+        {QStringLiteral("bsbenchrare"), 100000},
+        {QStringLiteral("bsbenchuncommon"), 10000},
+        {QStringLiteral("bsbenchmedium"), 1000},
+        {QStringLiteral("bsbenchcommon"), 100},
+    };
+}
+
+std::optional<SyntheticHomeReport> SyntheticHome::generate(
+    const SyntheticHomeProfile& profile, int64_t files, const QString& root, QString* error,
+    const std::function<void(int64_t)>& progress)
+{
+    if (files < 1 || files > kMaxFiles || profile.roots.empty() || profile.filesPerDirectory < 1) {
+        *error = QStringLiteral("A synthetic home needs 1 to %1 files and at least one root")
+                     .arg(kMaxFiles);
+        return std::nullopt;
+    }
+    for (const SyntheticHomeRoot& homeRoot : profile.roots) {
+        if (homeRoot.name.isEmpty() || homeRoot.types.empty() || homeRoot.maxDepth < 1) {
+            *error = QStringLiteral("Root \"%1\" needs a name, a depth and file types")
+                         .arg(homeRoot.name);
+            return std::nullopt;
+        }
+    }
+    const QDir rootDir(root);
+    if (rootDir.exists()
+        && !rootDir.isEmpty(QDir::AllEntries | QDir::NoDotAndDotDot | QDir::Hidden
+                            | QDir::System)) {
+        *error = QStringLiteral("%1 is not empty").arg(root);
+        return std::nullopt;
+    }
+    if (!QDir().mkpath(root)) {
+        *error = QStringLiteral("Cannot create %1").arg(root);
+        return std::nullopt;
+    }
+
+    std::mt19937 rng(profile.seed);
+    SyntheticHomeReport report;
+
+    // Directories first, as a random recursive tree per root: each new one
+    // hangs off a directory of its root already made, which gives the
+    // shallow-and-wide top and occasional deep chains of a real home.
+    struct Directory {
+        QString relative;
+        int depth;
+    };
+    std::vector<Directory> directories;
+    std::vector<std::vector<size_t>> byRoot(profile.roots.size());
+    const auto addDirectory = [&](size_t rootIndex, const QString& relative, int depth) {
+        if (!rootDir.mkdir(relative)) {
+            *error = QStringLiteral("Cannot create %1").arg(rootDir.filePath(relative));
+            return false;
+        }
+        byRoot[rootIndex].push_back(directories.size());
+        directories.push_back(Directory{relative, depth});
+        ++report.directories;
+        return true;
+    };
+    for (size_t r = 0; r < profile.roots.size(); ++r) {
+        if (!addDirectory(r, profile.roots[r].name, 1)) {
+            return std::nullopt;
+        }
+    }
+    const int64_t directoryCount =
+        std::max<int64_t>(static_cast<int64_t>(profile.roots.size()),
+                          files / profile.filesPerDirectory);
+    while (static_cast<int64_t>(directories.size()) < directoryCount) {
+        const SyntheticHomeRoot& homeRoot =
+            pick(rng, profile.roots, [](const SyntheticHomeRoot& r) { return r.weight; });
+        const auto rootIndex = static_cast<size_t>(&homeRoot - profile.roots.data());
+        const std::vector<size_t>& candidates = byRoot[rootIndex];
+        size_t parent = candidates[draw(rng, static_cast<quint32>(candidates.size()))];
+        if (directories[parent].depth >= homeRoot.maxDepth) {
+            parent = candidates.front();
+        }
+        const QString relative = QStringLiteral("%1/%2-%3").arg(
+            directories[parent].relative, name(rng, kDirectoryNames),
+            QString::number(directories.size(), 36));
+        if (!addDirectory(rootIndex, relative, directories[parent].depth + 1)) {
+            return std::nullopt;
+        }
+    }
+
+    const QDateTime reference(QDate(2026, 1, 1), QTime(0, 0), QTimeZone::UTC);
+    const auto tokens = plantedTokens();
+    for (int64_t i = 0; i < files; ++i) {
+        const SyntheticHomeRoot& homeRoot =
+            pick(rng, profile.roots, [](const SyntheticHomeRoot& r) { return r.weight; });
+        const auto rootIndex = static_cast<size_t>(&homeRoot - profile.roots.data());
+        const std::vector<size_t>& candidates = byRoot[rootIndex];
+        // The product of two uniform draws favors the earlier, shallower
+        // directories, so file counts per directory are skewed as well.
+        const auto span = static_cast<quint64>(candidates.size());
+        const quint64 first = draw(rng, 65536);
+        const quint64 second = draw(rng, 65536);
+        const quint64 skewed = (first * second * span) >> 32;
+        const Directory& directory = directories[candidates[std::min<quint64>(skewed, span - 1)]];
+
+        const SyntheticFileType& fileType = pick(
+            rng, homeRoot.types, [](const SyntheticFileType& t) { return t.weight; });
+        const SizeFactor& factor = pick(rng, kSizeFactors);
+        const int64_t modeled = std::max<int64_t>(
+            1, fileType.typicalBytes * factor.eighths / 8 * (50 + draw(rng, 101)) / 100);
+        const int64_t capped = std::min(modeled, profile.maxFileBytes);
+
+        // One draw a statement: argument order is unspecified.
+        const QLatin1String stem = name(rng, kFileStems);
+        const QLatin1String qualifier = name(rng, kFileStems);
+        QString fileName = QStringLiteral("%1-%2-%3").arg(stem, qualifier, QString::number(i));
+        if (!fileType.suffix.isEmpty()) {
+            fileName += QLatin1Char('.') + fileType.suffix;
+        }
+        const QString relative = directory.relative + QLatin1Char('/') + fileName;
+
+        CorpusEntry entry;
+        entry.kind = fileType.kind;
+        entry.magic = fileType.magic;
+        entry.size = capped;
+        entry.words = static_cast<int>(std::clamp<int64_t>(capped / kBytesPerWord, 20,
+                                                           CorpusGenerator::kMaxWords));
+        if (isText(fileType.kind)) {
+            for (const auto& [token, oneIn] : tokens) {
+                if (draw(rng, static_cast<quint32>(oneIn)) == 0) {
+                    entry.keywords.append(token);
+                    ++report.tokenFiles[token];
+                }
+            }
+        }
+        const QByteArray content = CorpusGenerator::content(entry, relative, profile.seed);
+
+        const AgeBucket& age = pick(rng, kAgeBuckets);
+        const qint64 days = draw(rng, static_cast<quint32>(age.maxDays));
+        const qint64 seconds = draw(rng, 86400);
+        const QDateTime modifiedAt = reference.addSecs(-(days * 86400 + seconds));
+        QFile file(rootDir.filePath(relative));
+        if (!file.open(QIODevice::WriteOnly | QIODevice::NewOnly)
+            || file.write(content) != content.size() || !file.flush()
+            || !file.setFileTime(modifiedAt, QFileDevice::FileModificationTime)) {
+            *error = QStringLiteral("Cannot write %1").arg(file.fileName());
+            return std::nullopt;
+        }
+
+        ++report.files;
+        report.bytes += content.size();
+        report.profileBytes += modeled;
+        ++report.byExtension[fileType.suffix];
+        if (report.byDepth.size() <= static_cast<size_t>(directory.depth)) {
+            report.byDepth.resize(static_cast<size_t>(directory.depth) + 1, 0);
+        }
+        ++report.byDepth[static_cast<size_t>(directory.depth)];
+        if (progress && report.files % kProgressEvery == 0) {
+            progress(report.files);
+        }
+    }
+    return report;
+}
+
+QJsonObject SyntheticHome::reportToJson(const SyntheticHomeReport& report,
+                                        const SyntheticHomeProfile& profile)
+{
+    QJsonObject byExtension;
+    for (auto it = report.byExtension.cbegin(); it != report.byExtension.cend(); ++it) {
+        byExtension[it.key().isEmpty() ? QStringLiteral("(none)") : it.key()] =
+            static_cast<qint64>(it.value());
+    }
+    QJsonArray byDepth;
+    for (const int64_t count : report.byDepth) {
+        byDepth.append(static_cast<qint64>(count));
+    }
+    QJsonArray tokens;
+    for (const auto& [token, oneIn] : plantedTokens()) {
+        QJsonObject json;
+        json[QStringLiteral("token")] = token;
+        json[QStringLiteral("oneIn")] = oneIn;
+        json[QStringLiteral("files")] = static_cast<qint64>(report.tokenFiles.value(token));
+        tokens.append(json);
+    }
+    QJsonObject json;
+    json[QStringLiteral("seed")] = static_cast<qint64>(profile.seed);
+    json[QStringLiteral("filesPerDirectory")] = profile.filesPerDirectory;
+    json[QStringLiteral("maxFileBytes")] = static_cast<qint64>(profile.maxFileBytes);
+    json[QStringLiteral("files")] = static_cast<qint64>(report.files);
+    json[QStringLiteral("directories")] = static_cast<qint64>(report.directories);
+    json[QStringLiteral("bytes")] = static_cast<qint64>(report.bytes);
+    json[QStringLiteral("profileBytes")] = static_cast<qint64>(report.profileBytes);
+    json[QStringLiteral("byExtension")] = byExtension;
+    json[QStringLiteral("byDepth")] = byDepth;
+    json[QStringLiteral("plantedTokens")] = tokens;
+    return json;
+}
+
+} // namespace bs::test
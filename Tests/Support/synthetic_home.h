@@ -0,0 +1,86 @@
+#pragma once
+
+#include "corpus_generator.h"
+
+#include <QJsonObject>
+#include <QMap>
+#include <QString>
+
+#include <cstdint>
+#include <functional>
+#include <optional>
+#include <utility>
+#include <vector>
+
+namespace bs::test {
+
+// A file type in a root's mix. Sizes scatter around `typicalBytes` with a
+// long tail (a few files 16x larger), as on a real disk.
+struct SyntheticFileType {
+    QString suffix;                 // without the dot; empty for none
+    CorpusEntryKind kind = CorpusEntryKind::Text;
+    QString magic;                  // Binary header, see CorpusEntry::magic
+    int weight = 1;
+    int64_t typicalBytes = 4096;
+};
+
+// A top-level directory of the home, its share of the files, how deep its
+// tree may grow, and what it holds.
+struct SyntheticHomeRoot {
+    QString name;
+    int weight = 1;
+    int maxDepth = 4;
+    std::vector<SyntheticFileType> types;
+};
+
+struct SyntheticHomeProfile {
+    std::vector<SyntheticHomeRoot> roots;
+    int filesPerDirectory = 12;     // mean; directories are files / this
+    int64_t maxFileBytes = 8 * 1024; // modeled sizes are capped to this on disk
+    quint32 seed = 1;
+};
+
+struct SyntheticHomeReport {
+    int64_t files = 0;
+    int64_t directories = 0;
+    int64_t bytes = 0;              // written
+    int64_t profileBytes = 0;       // the modeled sizes before the cap
+    QMap<QString, int64_t> byExtension;
+    std::vector<int64_t> byDepth;   // files per directory depth, the root's children at 1
+    QMap<QString, int64_t> tokenFiles; // planted token -> text files holding it
+};
+
+// SyntheticHome -- a large home directory for benchmarks. Where
+// CorpusGenerator writes each listed file, this draws a whole tree from a
+// profile: how files split across ~/Library, ~/Developer, ~/Documents and
+// the rest, each root's extension mix and depth, and the sizes of each
+// type. Text files get their content from CorpusGenerator, and some carry
+// planted tokens of known frequency, so a query benchmark can search a
+// rare, a medium and a common term with known hit counts.
+//
+// The same profile, seed and file count write the same tree. Sizes are
+// capped at maxFileBytes so a million files fit a CI runner's disk (about
+// 5 GB at the default); profileBytes keeps what the model asked for.
+class SyntheticHome {
+public:
+    static constexpr int64_t kMaxFiles = 10'000'000;
+
+    // A macOS home of a developer: most files are small and sit in
+    // dependency trees and ~/Library; media has few files but most bytes.
+    static SyntheticHomeProfile defaultProfile();
+
+    // Planted tokens and the share of text files holding each ("one in N").
+    static std::vector<std::pair<QString, int>> plantedTokens();
+
+    // Write `files` files under `root`, which must be empty or missing.
+    // `progress`, when set, is called with the files written so far every
+    // 10,000 files.
+    static std::optional<SyntheticHomeReport> generate(
+        const SyntheticHomeProfile& profile, int64_t files, const QString& root, QString* error,
+        const std::function<void(int64_t)>& progress = {});
+
+    static QJsonObject reportToJson(const SyntheticHomeReport& report,
+                                    const SyntheticHomeProfile& profile);
+};
+
+} // namespace bs::test
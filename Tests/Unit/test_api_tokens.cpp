@@ -0,0 +1,205 @@
+#include <QtTest/QtTest>
+
+#include "core/ipc/api_tokens.h"
+
+#include <QFile>
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+namespace {
+
+bs::ApiTokenScope makeScope(const QStringList& capabilities, const QStringList& roots = {})
+{
+    bs::ApiTokenScope scope;
+    scope.capabilities = capabilities;
+    scope.roots = roots;
+    return scope;
+}
+
+QJsonObject entry(const QString& path)
+{
+    QJsonObject json;
+    json[QStringLiteral("path")] = path;
+    json[QStringLiteral("snippet")] = QStringLiteral("matched text");
+    json[QStringLiteral("highlights")] = QJsonArray{QJsonObject()};
+    return json;
+}
+
+} // namespace
+
+class TestApiTokens : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testCreateVerifyRevoke();
+    void testFileStoresOnlyHashOwnerOnly();
+    void testRejectsInvalidScope();
+    void testCoversPathRespectsBoundaries();
+    void testFilterResultDropsOutsideRootsAndContent();
+    void testFilterResultHidesDocumentOutsideRoots();
+    void testNarrowSearchKeepsIncludePathsInsideRoots();
+};
+
+void TestApiTokens::testCreateVerifyRevoke()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::ApiTokenStore store(dir.filePath(QStringLiteral("api-tokens.json")));
+    QVERIFY(store.isEmpty());
+
+    bs::ApiToken created;
+    QString error;
+    const auto secret = store.create(QStringLiteral("vscode"),
+                                     makeScope({QStringLiteral("search")}), &created, &error);
+    QVERIFY2(secret.has_value(), qPrintable(error));
+    QVERIFY(secret->startsWith(QLatin1String(bs::ApiTokenStore::kTokenPrefix)));
+    QCOMPARE(created.id.size(), qsizetype(12));
+    QCOMPARE(created.name, QStringLiteral("vscode"));
+    QVERIFY(!store.isEmpty());
+
+    const auto verified = store.verify(secret->toLatin1());
+    QVERIFY(verified.has_value());
+    QCOMPARE(verified->id, created.id);
+    QCOMPARE(verified->scope.capabilities, QStringList{QStringLiteral("search")});
+    QVERIFY(!store.verify(secret->toLatin1() + "x").has_value());
+    QVERIFY(!store.verify(QByteArray()).has_value());
+    QVERIFY(store.find(created.id).has_value());
+
+    QVERIFY(store.revoke(created.id));
+    QVERIFY(!store.verify(secret->toLatin1()).has_value());
+    QVERIFY(!store.find(created.id).has_value());
+    QVERIFY(!store.revoke(created.id));
+    QVERIFY(store.isEmpty());
+}
+
+void TestApiTokens::testFileStoresOnlyHashOwnerOnly()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("api-tokens.json"));
+    bs::ApiTokenStore store(path);
+
+    QString error;
+    const auto secret = store.create(QStringLiteral("script"),
+                                     makeScope({QStringLiteral("search")}), nullptr, &error);
+    QVERIFY2(secret.has_value(), qPrintable(error));
+
+    QFile file(path);
+    QVERIFY(file.open(QIODevice::ReadOnly));
+    QVERIFY(!file.readAll().contains(secret->toLatin1()));
+
+    const QFileDevice::Permissions permissions = QFile::permissions(path);
+    QVERIFY(permissions.testFlag(QFileDevice::ReadOwner));
+    QVERIFY(!permissions.testFlag(QFileDevice::ReadGroup));
+    QVERIFY(!permissions.testFlag(QFileDevice::ReadOther));
+
+    // Another store on the same file sees the token: revocation is shared.
+    bs::ApiTokenStore other(path);
+    QVERIFY(other.verify(secret->toLatin1()).has_value());
+}
+
+void TestApiTokens::testRejectsInvalidScope()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::ApiTokenStore store(dir.filePath(QStringLiteral("api-tokens.json")));
+    QString error;
+
+    QVERIFY(!store.create(QStringLiteral("x"), makeScope({}), nullptr, &error).has_value());
+    QVERIFY(!store.create(QStringLiteral("x"), makeScope({QStringLiteral("admin")}), nullptr,
+                          &error).has_value());
+    QVERIFY(error.contains(QStringLiteral("admin")));
+    QVERIFY(!store.create(QStringLiteral("x"),
+                          makeScope({QStringLiteral("search")}, {QStringLiteral("relative/dir")}),
+                          nullptr, &error).has_value());
+    QVERIFY(!store.create(QStringLiteral(" "), makeScope({QStringLiteral("search")}), nullptr,
+                          &error).has_value());
+    QVERIFY(store.isEmpty());
+}
+
+void TestApiTokens::testCoversPathRespectsBoundaries()
+{
+    const bs::ApiTokenScope scope =
+        makeScope({QStringLiteral("search")}, {QStringLiteral("/Users/alice/src")});
+    QVERIFY(scope.coversPath(QStringLiteral("/Users/alice/src")));
+    QVERIFY(scope.coversPath(QStringLiteral("/Users/alice/src/app/main.cpp")));
+    QVERIFY(!scope.coversPath(QStringLiteral("/Users/alice/src-private/notes.md")));
+    QVERIFY(!scope.coversPath(QStringLiteral("/Users/alice/src/../Documents/tax.pdf")));
+    QVERIFY(makeScope({QStringLiteral("search")}).coversPath(QStringLiteral("/anything")));
+}
+
+void TestApiTokens::testFilterResultDropsOutsideRootsAndContent()
+{
+    const bs::ApiTokenScope scope =
+        makeScope({QStringLiteral("search")}, {QStringLiteral("/Users/alice/src")});
+    QJsonObject result;
+    result[QStringLiteral("results")] = QJsonArray{
+        entry(QStringLiteral("/Users/alice/src/a.cpp")),
+        entry(QStringLiteral("/Users/alice/Documents/tax.pdf")),
+    };
+    result[QStringLiteral("totalMatches")] = 2;
+    result[QStringLiteral("facets")] = QJsonObject();
+    result[QStringLiteral("debugInfo")] = QJsonObject();
+
+    const auto filtered = scope.filterResult(result);
+    QVERIFY(filtered.has_value());
+    const QJsonArray results = filtered->value(QStringLiteral("results")).toArray();
+    QCOMPARE(results.size(), qsizetype(1));
+    const QJsonObject kept = results.first().toObject();
+    QCOMPARE(kept.value(QStringLiteral("path")).toString(), QStringLiteral("/Users/alice/src/a.cpp"));
+    QVERIFY(!kept.contains(QStringLiteral("snippet")));
+    QVERIFY(!kept.contains(QStringLiteral("highlights")));
+    QVERIFY(!filtered->contains(QStringLiteral("totalMatches")));
+    QVERIFY(!filtered->contains(QStringLiteral("facets")));
+    QVERIFY(!filtered->contains(QStringLiteral("debugInfo")));
+
+    const auto withContent =
+        makeScope({QStringLiteral("search"), QStringLiteral("content")}).filterResult(result);
+    QVERIFY(withContent.has_value());
+    QCOMPARE(withContent->value(QStringLiteral("results")).toArray().size(), qsizetype(2));
+    QVERIFY(withContent->value(QStringLiteral("results")).toArray().first().toObject()
+                .contains(QStringLiteral("snippet")));
+    QVERIFY(withContent->contains(QStringLiteral("totalMatches")));
+}
+
+void TestApiTokens::testFilterResultHidesDocumentOutsideRoots()
+{
+    const bs::ApiTokenScope scope =
+        makeScope({QStringLiteral("search")}, {QStringLiteral("/Users/alice/src")});
+    QJsonObject document;
+    document[QStringLiteral("path")] = QStringLiteral("/Users/alice/Documents/tax.pdf");
+    QVERIFY(!scope.filterResult(document).has_value());
+
+    document[QStringLiteral("path")] = QStringLiteral("/Users/alice/src/a.cpp");
+    document[QStringLiteral("content")] = QStringLiteral("int main() {}");
+    const auto filtered = scope.filterResult(document);
+    QVERIFY(filtered.has_value());
+    QVERIFY(!filtered->contains(QStringLiteral("content")));
+}
+
+void TestApiTokens::testNarrowSearchKeepsIncludePathsInsideRoots()
+{
+    const bs::ApiTokenScope scope =
+        makeScope({QStringLiteral("search")}, {QStringLiteral("/Users/alice/src")});
+
+    QJsonObject params;
+    params[QStringLiteral("query")] = QStringLiteral("main");
+    QJsonObject narrowed = scope.narrowSearch(params);
+    QCOMPARE(narrowed.value(QStringLiteral("filters")).toObject()
+                 .value(QStringLiteral("includePaths")).toArray(),
+             QJsonArray{QStringLiteral("/Users/alice/src")});
+
+    QJsonObject filters;
+    filters[QStringLiteral("includePaths")] = QJsonArray{
+        QStringLiteral("/Users/alice/src/app"), QStringLiteral("/Users/alice/Documents")};
+    params[QStringLiteral("filters")] = filters;
+    narrowed = scope.narrowSearch(params);
+    QCOMPARE(narrowed.value(QStringLiteral("filters")).toObject()
+                 .value(QStringLiteral("includePaths")).toArray(),
+             QJsonArray{QStringLiteral("/Users/alice/src/app")});
+
+    QCOMPARE(makeScope({QStringLiteral("search")}).narrowSearch(params), params);
+}
+
+QTEST_MAIN(TestApiTokens)
+#include "test_api_tokens.moc"
@@ -0,0 +1,163 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/app_catalog.h"
+
+#include <QDir>
+#include <QTemporaryDir>
+
+namespace {
+
+constexpr qint64 kNow = 1'790'000'000;
+
+bs::AppEntry app(const QString& name, const QString& bundleId,
+                 const QStringList& alternateNames = {})
+{
+    bs::AppEntry entry;
+    entry.name = name;
+    entry.bundleId = bundleId;
+    entry.path = QStringLiteral("/Applications/%1.app").arg(name);
+    entry.alternateNames = alternateNames;
+    return entry;
+}
+
+} // namespace
+
+class TestAppCatalog : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testNameScore();
+    void testMatchOrdering();
+    void testAlternateNames();
+    void testFrecencyReorders();
+    void testFind();
+    void testLaunchesJsonRoundTrip();
+    void testScan();
+};
+
+void TestAppCatalog::testNameScore()
+{
+    QCOMPARE(bs::AppCatalog::nameScore(QStringLiteral("safari"), QStringLiteral("Safari")), 1.0);
+    const double prefix =
+        bs::AppCatalog::nameScore(QStringLiteral("saf"), QStringLiteral("Safari"));
+    const double word =
+        bs::AppCatalog::nameScore(QStringLiteral("code"), QStringLiteral("Visual Studio Code"));
+    const double initials =
+        bs::AppCatalog::nameScore(QStringLiteral("vsc"), QStringLiteral("Visual Studio Code"));
+    const double inside =
+        bs::AppCatalog::nameScore(QStringLiteral("fari"), QStringLiteral("Safari"));
+    QVERIFY(prefix < 1.0);
+    QVERIFY(prefix > word);
+    QVERIFY(word > initials);
+    QVERIFY(initials > inside);
+    QVERIFY(inside > 0.0);
+    // CamelCase counts as words.
+    QVERIFY(bs::AppCatalog::nameScore(QStringLiteral("band"), QStringLiteral("GarageBand")) > 0.6);
+    QCOMPARE(bs::AppCatalog::nameScore(QStringLiteral("fa"), QStringLiteral("Safari")), 0.0);
+    QCOMPARE(bs::AppCatalog::nameScore(QStringLiteral("xcode"), QStringLiteral("Safari")), 0.0);
+    QCOMPARE(bs::AppCatalog::nameScore(QString(), QStringLiteral("Safari")), 0.0);
+}
+
+void TestAppCatalog::testMatchOrdering()
+{
+    bs::AppCatalog catalog;
+    catalog.setEntries({app(QStringLiteral("MailMate"), QStringLiteral("com.freron.MailMate")),
+                        app(QStringLiteral("Mail"), QStringLiteral("com.apple.mail")),
+                        app(QStringLiteral("Maps"), QStringLiteral("com.apple.Maps"))});
+    const auto matches = catalog.match(QStringLiteral("mai"), 5, kNow);
+    QCOMPARE(matches.size(), size_t(2));
+    QCOMPARE(matches.at(0).entry.name, QStringLiteral("Mail"));
+    QCOMPARE(matches.at(1).entry.name, QStringLiteral("MailMate"));
+
+    QCOMPARE(catalog.match(QStringLiteral("ma"), 1, kNow).size(), size_t(1));
+    QVERIFY(catalog.match(QStringLiteral("   "), 5, kNow).empty());
+}
+
+void TestAppCatalog::testAlternateNames()
+{
+    bs::AppCatalog catalog;
+    catalog.setEntries({app(QStringLiteral("Visual Studio Code"),
+                            QStringLiteral("com.microsoft.VSCode"),
+                            {QStringLiteral("Code"), QStringLiteral("VSCode")})});
+    const auto matches = catalog.match(QStringLiteral("vscode"), 3, kNow);
+    QCOMPARE(matches.size(), size_t(1));
+    QCOMPARE(matches.at(0).matchedName, QStringLiteral("VSCode"));
+    QVERIFY(matches.at(0).score < 1.0);
+}
+
+void TestAppCatalog::testFrecencyReorders()
+{
+    bs::AppCatalog catalog;
+    const bs::AppEntry terminal = app(QStringLiteral("Terminal"), QStringLiteral("com.apple.Terminal"));
+    const bs::AppEntry termius = app(QStringLiteral("Termius"), QStringLiteral("com.termius-dmg.mac"));
+    catalog.setEntries({terminal, termius});
+    QCOMPARE(catalog.match(QStringLiteral("term"), 2, kNow).at(0).entry.name,
+             QStringLiteral("Termius"));
+
+    for (int i = 0; i < 5; ++i) {
+        catalog.recordLaunch(terminal, kNow - 3600);
+    }
+    const auto matches = catalog.match(QStringLiteral("term"), 2, kNow);
+    QCOMPARE(matches.at(0).entry.name, QStringLiteral("Terminal"));
+    QCOMPARE(matches.at(0).launchCount, 5);
+
+    bs::AppCatalog::Launch stale;
+    stale.count = 20;
+    stale.lastLaunchedAt = kNow - 14 * 86400;
+    QVERIFY(qAbs(bs::AppCatalog::frecency(stale, kNow) - 0.5) < 1e-9);
+    QCOMPARE(bs::AppCatalog::frecency(bs::AppCatalog::Launch(), kNow), 0.0);
+}
+
+void TestAppCatalog::testFind()
+{
+    bs::AppCatalog catalog;
+    catalog.setEntries({app(QStringLiteral("Safari"), QStringLiteral("com.apple.Safari"))});
+    QVERIFY(catalog.find(QStringLiteral("/Applications/Safari.app/")) != nullptr);
+    QVERIFY(catalog.find(QStringLiteral("com.apple.safari")) != nullptr);
+    QVERIFY(catalog.find(QStringLiteral("/Applications/Other.app")) == nullptr);
+    QVERIFY(catalog.find(QStringLiteral("/etc/passwd")) == nullptr);
+}
+
+void TestAppCatalog::testLaunchesJsonRoundTrip()
+{
+    bs::AppCatalog catalog;
+    const bs::AppEntry safari = app(QStringLiteral("Safari"), QStringLiteral("com.apple.Safari"));
+    catalog.recordLaunch(safari, kNow);
+    catalog.recordLaunch(safari, kNow + 10);
+    const QJsonObject json = catalog.launchesToJson();
+    QCOMPARE(json.value(QStringLiteral("com.apple.Safari")).toObject()
+                 .value(QStringLiteral("count")).toInt(),
+             2);
+
+    bs::AppCatalog restored;
+    QJsonObject withJunk = json;
+    withJunk[QStringLiteral("com.example.none")] = QJsonObject{{QStringLiteral("count"), 0}};
+    restored.setLaunches(withJunk);
+    QCOMPARE(restored.launch(safari).count, 2);
+    QCOMPARE(restored.launch(safari).lastLaunchedAt, kNow + 10);
+    QCOMPARE(restored.launchesToJson().size(), 1);
+}
+
+void TestAppCatalog::testScan()
+{
+    QTemporaryDir root;
+    QVERIFY(root.isValid());
+    QDir dir(root.path());
+    QVERIFY(dir.mkpath(QStringLiteral("Notes.app/Contents")));
+    QVERIFY(dir.mkpath(QStringLiteral("Utilities/Console.app")));
+    QVERIFY(dir.mkpath(QStringLiteral("Vendor/Deep/Hidden.app")));
+    QVERIFY(dir.mkpath(QStringLiteral("Notes.app/Contents/Helper.app")));
+    QVERIFY(dir.mkpath(QStringLiteral("Folder")));
+
+    // The same root twice lists each app once.
+    const auto entries = bs::AppCatalog::scan({root.path(), root.path()});
+    QStringList names;
+    for (const bs::AppEntry& entry : entries) {
+        names.append(entry.name);
+    }
+    names.sort();
+    QCOMPARE(names, QStringList({QStringLiteral("Console"), QStringLiteral("Notes")}));
+}
+
+QTEST_MAIN(TestAppCatalog)
+#include "test_app_catalog.moc"
@@ -0,0 +1,168 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/audit_log.h"
+
+#include <QDir>
+#include <QFile>
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+class TestAuditLog : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDisabledByDefault();
+    void testAppendAndRead();
+    void testRotation();
+    void testPurge();
+    void testSummarizeParams();
+};
+
+void TestAuditLog::testDisabledByDefault()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+    const QString directory = QDir(tempDir.path()).filePath(QStringLiteral("audit"));
+
+    bs::AuditLog log(directory, QStringLiteral("query"));
+    QVERIFY(!log.isEnabled());
+    QVERIFY(!log.append(QStringLiteral("query"), QStringLiteral("search"), {}));
+    QVERIFY(!QFile::exists(log.filePath()));
+
+    QVERIFY(bs::AuditLog::setEnabled(directory, true));
+    QVERIFY(log.isEnabled());
+    QVERIFY(log.append(QStringLiteral("query"), QStringLiteral("search"), {}));
+    QVERIFY(bs::AuditLog::setEnabled(directory, false));
+    QVERIFY(!log.append(QStringLiteral("query"), QStringLiteral("search"), {}));
+
+    // Turning the log off keeps what was recorded.
+    QCOMPARE(bs::AuditLog::read(directory, {}).size(), static_cast<size_t>(1));
+}
+
+void TestAuditLog::testAppendAndRead()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+    const QString directory = tempDir.path();
+    QVERIFY(bs::AuditLog::setEnabled(directory, true));
+
+    bs::AuditLog query(directory, QStringLiteral("query"));
+    bs::AuditLog indexer(directory, QStringLiteral("indexer"));
+    QJsonObject details;
+    details[QStringLiteral("query")] = QStringLiteral("budget");
+    QVERIFY(query.append(QStringLiteral("query"), QStringLiteral("search"), details));
+    QTest::qWait(5);
+    QVERIFY(indexer.append(QStringLiteral("admin"), QStringLiteral("rebuildAll"), {}, false));
+    QTest::qWait(5);
+    QVERIFY(query.append(QStringLiteral("admin"), QStringLiteral("purgePrivacyPaths"), {}));
+
+    const auto all = bs::AuditLog::read(directory, {});
+    QCOMPARE(all.size(), static_cast<size_t>(3));
+    QCOMPARE(all.front().value(QStringLiteral("method")).toString(),
+             QStringLiteral("purgePrivacyPaths"));
+    QCOMPARE(all.back().value(QStringLiteral("details")).toObject()
+                 .value(QStringLiteral("query")).toString(),
+             QStringLiteral("budget"));
+    QVERIFY(!all.at(1).value(QStringLiteral("ok")).toBool());
+    QCOMPARE(all.at(1).value(QStringLiteral("service")).toString(), QStringLiteral("indexer"));
+
+    bs::AuditLogQuery adminOnly;
+    adminOnly.kinds = {QStringLiteral("admin")};
+    QCOMPARE(bs::AuditLog::read(directory, adminOnly).size(), static_cast<size_t>(2));
+
+    bs::AuditLogQuery fromIndexer;
+    fromIndexer.services = {QStringLiteral("indexer")};
+    QCOMPARE(bs::AuditLog::read(directory, fromIndexer).size(), static_cast<size_t>(1));
+
+    bs::AuditLogQuery newest;
+    newest.limit = 1;
+    QCOMPARE(bs::AuditLog::read(directory, newest).front().value(QStringLiteral("method")),
+             QJsonValue(QStringLiteral("purgePrivacyPaths")));
+
+    bs::AuditLogQuery afterLast;
+    afterLast.since = all.front().value(QStringLiteral("ts")).toDouble() + 1.0;
+    QVERIFY(bs::AuditLog::read(directory, afterLast).empty());
+}
+
+void TestAuditLog::testRotation()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+    const QString directory = tempDir.path();
+    QVERIFY(bs::AuditLog::setEnabled(directory, true));
+
+    // Entries are ~350 bytes, so two fit in each 1 KiB file.
+    bs::AuditLog log(directory, QStringLiteral("query"), 1024, 3);
+    QJsonObject details;
+    details[QStringLiteral("query")] = QString(250, QLatin1Char('x'));
+    for (int i = 0; i < 30; ++i) {
+        QVERIFY(log.append(QStringLiteral("query"), QStringLiteral("search"), details));
+    }
+
+    QDir dir(directory);
+    QVERIFY(dir.exists(QStringLiteral("query.jsonl")));
+    QVERIFY(dir.exists(QStringLiteral("query.1.jsonl")));
+    QVERIFY(dir.exists(QStringLiteral("query.2.jsonl")));
+    QVERIFY(!dir.exists(QStringLiteral("query.3.jsonl")));
+    for (const QString& name : dir.entryList({QStringLiteral("*.jsonl")})) {
+        QVERIFY(QFileInfo(dir.filePath(name)).size() <= 1024);
+    }
+    const size_t kept = bs::AuditLog::read(directory, {}).size();
+    QVERIFY(kept > 0 && kept < 30);
+}
+
+void TestAuditLog::testPurge()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+    const QString directory = tempDir.path();
+    QVERIFY(bs::AuditLog::setEnabled(directory, true));
+
+    bs::AuditLog log(directory, QStringLiteral("query"));
+    QVERIFY(log.append(QStringLiteral("query"), QStringLiteral("search"), {}));
+    QTest::qWait(20);
+    const double cutoff = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    QTest::qWait(20);
+    QVERIFY(log.append(QStringLiteral("query"), QStringLiteral("suggest"), {}));
+
+    QCOMPARE(bs::AuditLog::purge(directory, cutoff), static_cast<int64_t>(1));
+    const auto left = bs::AuditLog::read(directory, {});
+    QCOMPARE(left.size(), static_cast<size_t>(1));
+    QCOMPARE(left.front().value(QStringLiteral("method")).toString(), QStringLiteral("suggest"));
+
+    QCOMPARE(bs::AuditLog::purge(directory), static_cast<int64_t>(1));
+    QVERIFY(bs::AuditLog::read(directory, {}).empty());
+    QVERIFY(!QFile::exists(log.filePath()));
+    QVERIFY(log.isEnabled());
+}
+
+void TestAuditLog::testSummarizeParams()
+{
+    QJsonArray paths;
+    for (int i = 0; i < 25; ++i) {
+        paths.append(QStringLiteral("/Users/test/%1").arg(i));
+    }
+    QJsonObject nested;
+    nested[QStringLiteral("apiKey")] = QStringLiteral("sk-live-123");
+    QJsonObject params;
+    params[QStringLiteral("paths")] = paths;
+    params[QStringLiteral("token")] = QStringLiteral("abc");
+    params[QStringLiteral("query")] = QString(300, QLatin1Char('q'));
+    params[QStringLiteral("limit")] = 20;
+    params[QStringLiteral("options")] = nested;
+
+    const QJsonObject summary = bs::AuditLog::summarizeParams(params);
+    const QJsonArray loggedPaths = summary.value(QStringLiteral("paths")).toArray();
+    QCOMPARE(loggedPaths.size(), static_cast<qsizetype>(21));
+    QCOMPARE(loggedPaths.last().toString(), QStringLiteral("(+5 more)"));
+    QCOMPARE(summary.value(QStringLiteral("token")).toString(), QStringLiteral("[redacted]"));
+    QCOMPARE(summary.value(QStringLiteral("query")).toString().size(),
+             static_cast<qsizetype>(259));
+    QCOMPARE(summary.value(QStringLiteral("limit")).toInt(), 20);
+    QCOMPARE(summary.value(QStringLiteral("options")).toObject()
+                 .value(QStringLiteral("apiKey")).toString(),
+             QStringLiteral("[redacted]"));
+}
+
+QTEST_MAIN(TestAuditLog)
+#include "test_audit_log.moc"
@@ -0,0 +1,128 @@
+#include <QtTest/QtTest>
+
+#include "app/automation_url.h"
+
+#include <QDir>
+#include <QUrlQuery>
+
+class TestAutomationUrl : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testSearchAndOpen();
+    void testXCallbackForm();
+    void testReindexPath();
+    void testRejectsInvalidRequests();
+    void testCallbacksOnlyIntoShortcuts();
+    void testCallbackAppendsParams();
+};
+
+void TestAutomationUrl::testSearchAndOpen()
+{
+    QString error;
+    auto request = bs::AutomationUrl::parse(
+        QUrl(QStringLiteral("betterspotlight://search?q=budget%202026&limit=5")), &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->command, bs::AutomationCommand::Search);
+    QCOMPARE(request->query, QStringLiteral("budget 2026"));
+    QCOMPARE(request->limit, 5);
+    QVERIFY(request->successUrl.isEmpty());
+
+    request = bs::AutomationUrl::parse(
+        QUrl(QStringLiteral("BetterSpotlight://Open?q=report&action=reveal")), &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->command, bs::AutomationCommand::Open);
+    QCOMPARE(request->action, QStringLiteral("reveal"));
+
+    request = bs::AutomationUrl::parse(QUrl(QStringLiteral("betterspotlight://open?q=report")),
+                                       &error);
+    QVERIFY(request.has_value());
+    QCOMPARE(request->action, QStringLiteral("open"));
+}
+
+void TestAutomationUrl::testXCallbackForm()
+{
+    QString error;
+    const auto request = bs::AutomationUrl::parse(
+        QUrl(QStringLiteral("betterspotlight://x-callback-url/search?q=notes"
+                            "&x-success=shortcuts%3A%2F%2Fx-callback-url%2Fok"
+                            "&x-error=shortcuts%3A%2F%2Fx-callback-url%2Ferror")),
+        &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->command, bs::AutomationCommand::Search);
+    QCOMPARE(request->successUrl, QUrl(QStringLiteral("shortcuts://x-callback-url/ok")));
+    QCOMPARE(request->errorUrl, QUrl(QStringLiteral("shortcuts://x-callback-url/error")));
+}
+
+void TestAutomationUrl::testReindexPath()
+{
+    QString error;
+    auto request = bs::AutomationUrl::parse(
+        QUrl(QStringLiteral("betterspotlight://reindex?path=/Users/test/Projects/../Docs/")),
+        &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->command, bs::AutomationCommand::Reindex);
+    QCOMPARE(request->path, QStringLiteral("/Users/test/Docs"));
+
+    request = bs::AutomationUrl::parse(
+        QUrl(QStringLiteral("betterspotlight://reindex?path=~/Notes")), &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->path, QDir::homePath() + QStringLiteral("/Notes"));
+}
+
+void TestAutomationUrl::testRejectsInvalidRequests()
+{
+    const QStringList invalid = {
+        QStringLiteral("https://search?q=budget"),
+        QStringLiteral("betterspotlight://search"),
+        QStringLiteral("betterspotlight://search?q=%20"),
+        QStringLiteral("betterspotlight://search?q=a&limit=0"),
+        QStringLiteral("betterspotlight://search?q=a&limit=51"),
+        QStringLiteral("betterspotlight://search?q=a&action=reveal"),
+        QStringLiteral("betterspotlight://open?q=a&action=delete"),
+        QStringLiteral("betterspotlight://reindex?path=relative/dir"),
+        QStringLiteral("betterspotlight://reindex"),
+        QStringLiteral("betterspotlight://rebuild"),
+    };
+    for (const QString& url : invalid) {
+        QString error;
+        QVERIFY2(!bs::AutomationUrl::parse(QUrl(url), &error).has_value(), qPrintable(url));
+        QVERIFY2(!error.isEmpty(), qPrintable(url));
+    }
+}
+
+void TestAutomationUrl::testCallbacksOnlyIntoShortcuts()
+{
+    QVERIFY(bs::AutomationUrl::isAllowedCallback(
+        QUrl(QStringLiteral("shortcuts://x-callback-url/done"))));
+    QVERIFY(!bs::AutomationUrl::isAllowedCallback(QUrl(QStringLiteral("https://example.com/"))));
+    QVERIFY(!bs::AutomationUrl::isAllowedCallback(QUrl(QStringLiteral("file:///tmp/x"))));
+    QVERIFY(!bs::AutomationUrl::isAllowedCallback(QUrl()));
+
+    QString error;
+    QVERIFY(!bs::AutomationUrl::parse(
+                 QUrl(QStringLiteral("betterspotlight://search?q=a"
+                                     "&x-success=https%3A%2F%2Fexample.com%2Fcollect")),
+                 &error)
+                 .has_value());
+    QVERIFY(error.contains(QStringLiteral("x-success")));
+}
+
+void TestAutomationUrl::testCallbackAppendsParams()
+{
+    const QUrl url = bs::AutomationUrl::callback(
+        QUrl(QStringLiteral("shortcuts://x-callback-url/ok?id=7")),
+        {{QStringLiteral("path"), QStringLiteral("/Users/test/a&b=c.txt")},
+         {QStringLiteral("paths"), QStringLiteral("/x\n/y")}});
+    QCOMPARE(url.scheme(), QStringLiteral("shortcuts"));
+
+    const QUrlQuery query(url);
+    QCOMPARE(query.queryItemValue(QStringLiteral("id")), QStringLiteral("7"));
+    QCOMPARE(query.queryItemValue(QStringLiteral("path"), QUrl::FullyDecoded),
+             QStringLiteral("/Users/test/a&b=c.txt"));
+    QCOMPARE(query.queryItemValue(QStringLiteral("paths"), QUrl::FullyDecoded),
+             QStringLiteral("/x\n/y"));
+}
+
+QTEST_MAIN(TestAutomationUrl)
+#include "test_automation_url.moc"
@@ -0,0 +1,120 @@
+#include <QtTest/QtTest>
+
+#include "cli/bench.h"
+
+#include <QJsonObject>
+
+class TestBench : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testCorpusIsDeterministic();
+    void testCorpusKindsAndMarkers();
+    void testWorkload();
+    void testSummarizePercentiles();
+    void testRenderAndJson();
+};
+
+void TestBench::testCorpusIsDeterministic()
+{
+    const bs::BenchCorpusFile first = bs::Bench::corpusFile(42);
+    const bs::BenchCorpusFile again = bs::Bench::corpusFile(42);
+    QCOMPARE(first.relativePath, again.relativePath);
+    QCOMPARE(first.contents, again.contents);
+
+    QVERIFY(bs::Bench::corpusFile(42, 7).contents != first.contents);
+    QVERIFY(bs::Bench::corpusFile(46).contents != first.contents);
+}
+
+void TestBench::testCorpusKindsAndMarkers()
+{
+    QCOMPARE(bs::Bench::corpusFile(0).relativePath, QStringLiteral("d000/report_0.txt"));
+    QCOMPARE(bs::Bench::corpusFile(101).relativePath, QStringLiteral("d001/notes_101.md"));
+    QCOMPARE(bs::Bench::corpusFile(202).relativePath, QStringLiteral("d002/module_202.py"));
+    QCOMPARE(bs::Bench::corpusFile(1203).relativePath, QStringLiteral("d012/engine_1203.cpp"));
+
+    for (int i = 0; i < 8; ++i) {
+        const bs::BenchCorpusFile file = bs::Bench::corpusFile(i);
+        QVERIFY(!file.contents.isEmpty());
+        QVERIFY(file.contents.contains("bsbench" + QByteArray::number(i) + '.')
+                || file.contents.contains("bsbench" + QByteArray::number(i) + '"')
+                || file.contents.contains("bsbench" + QByteArray::number(i) + '\n'));
+    }
+}
+
+void TestBench::testWorkload()
+{
+    const QStringList workload = bs::Bench::queryWorkload(1000);
+    QCOMPARE(workload, bs::Bench::queryWorkload(1000));
+    QCOMPARE(workload.size(), 35);
+    QVERIFY(workload.contains(QStringLiteral("report")));
+    QVERIFY(workload.contains(QStringLiteral("bugdet")));
+    QCOMPARE(workload.last(), QStringLiteral("zzqx nonexistent"));
+
+    // Lookups only name files that exist in a corpus of this size.
+    for (const QString& query : workload) {
+        if (query.startsWith(QLatin1String("report_"))) {
+            const int index = query.mid(7).toInt();
+            QVERIFY(index < 1000);
+            QCOMPARE(index % 4, 0);
+        } else if (query.startsWith(QLatin1String("bsbench"))) {
+            QVERIFY(query.mid(7).toInt() < 1000);
+        }
+    }
+}
+
+void TestBench::testSummarizePercentiles()
+{
+    QCOMPARE(bs::Bench::summarize({}).count, 0);
+
+    std::vector<double> samples;
+    for (int i = 100; i >= 1; --i) {
+        samples.push_back(static_cast<double>(i));
+    }
+    const bs::BenchLatency latency = bs::Bench::summarize(samples);
+    QCOMPARE(latency.count, 100);
+    QCOMPARE(latency.p50Ms, 50.0);
+    QCOMPARE(latency.p95Ms, 95.0);
+    QCOMPARE(latency.p99Ms, 99.0);
+    QCOMPARE(latency.maxMs, 100.0);
+
+    const bs::BenchLatency single = bs::Bench::summarize({3.5});
+    QCOMPARE(single.p50Ms, 3.5);
+    QCOMPARE(single.p99Ms, 3.5);
+}
+
+void TestBench::testRenderAndJson()
+{
+    bs::BenchReport report;
+    report.version = QStringLiteral("0.1.0");
+    report.files = 2000;
+    report.corpusBytes = 4 * 1024 * 1024;
+    report.indexMs = 8000;
+    report.indexedItems = 2021;
+    report.rounds = 5;
+    report.latency = bs::Bench::summarize({1.0, 2.0, 3.0, 40.0});
+    report.emptyQueries = 5;
+    report.indexBytes = 10 * 1024 * 1024;
+
+    QCOMPARE(report.filesPerSecond(), 250.0);
+    QCOMPARE(bs::Bench::render(report),
+             QStringLiteral("BetterSpotlight benchmark (bspot 0.1.0)\n\n"
+                            "Corpus:     2000 files, 4.0 MiB\n"
+                            "Indexing:   8.0 s, 250.0 files/s (2021 indexed, 0 failed)\n"
+                            "Queries:    4 over 5 round(s), 5 with no results\n"
+                            "Latency:    p50 2.00 ms, p95 40.0 ms, p99 40.0 ms, max 40.0 ms\n"
+                            "Index size: 10.0 MiB (2.5x corpus)\n"));
+
+    const QJsonObject json = bs::Bench::toJson(report);
+    QCOMPARE(json.value(QStringLiteral("indexing")).toObject()
+                 .value(QStringLiteral("filesPerSec")).toDouble(),
+             250.0);
+    QCOMPARE(json.value(QStringLiteral("queries")).toObject()
+                 .value(QStringLiteral("latency")).toObject()
+                 .value(QStringLiteral("p50Ms")).toDouble(),
+             2.0);
+    QCOMPARE(json.value(QStringLiteral("indexBytes")).toInteger(), 10LL * 1024 * 1024);
+}
+
+QTEST_MAIN(TestBench)
+#include "test_bench.moc"
@@ -0,0 +1,159 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/calendar_source.h"
+
+namespace {
+
+constexpr double kStart = 1790000000.0;
+
+bs::CalendarEvent standup()
+{
+    bs::CalendarEvent event;
+    event.eventIdentifier = QStringLiteral("A1B2C3D4-0000-4000-8000-000000000001:9F8E7D6C");
+    event.title = QStringLiteral("Team Standup");
+    event.location = QStringLiteral("Room 4");
+    event.notes = QStringLiteral("Standup notes in ~/Documents/standup.md, and see\n"
+                                 "file:///Users/ada/Plans/Q4%20plan.key.");
+    event.calendarName = QStringLiteral("Work");
+    event.organizer = QStringLiteral("Ada Lovelace");
+    event.attendees = {QStringLiteral("Ada Lovelace"), QStringLiteral("charles@example.com")};
+    event.start = kStart;
+    event.end = kStart + 900.0;
+    return event;
+}
+
+QStringList attributeValues(const bs::DonatedItem& item, const QString& name)
+{
+    QStringList values;
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == name) {
+            values.append(attribute.value);
+        }
+    }
+    return values;
+}
+
+} // namespace
+
+class TestCalendarSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testItem();
+    void testRecurringOccurrencesAreSeparateItems();
+    void testHashFollowsTheEvent();
+    void testSkipsUntitledAndDuplicateEvents();
+    void testStaleIdentifiers();
+    void testLinkedDocuments();
+    void testEventUrl();
+};
+
+void TestCalendarSource::testItem()
+{
+    const auto items = bs::CalendarSource::items({standup()});
+    QCOMPARE(items.size(), size_t(1));
+    const bs::DonatedItem& item = items.front();
+    QCOMPARE(item.name, QStringLiteral("Team Standup"));
+    QCOMPARE(item.parentPath, QStringLiteral("donated://com.apple.iCal"));
+    QCOMPARE(bs::SpotlightDonation::uniqueIdentifier(item.path), standup().eventIdentifier);
+    QCOMPARE(item.modifiedAt, kStart);
+    QVERIFY(item.textContent.contains(QStringLiteral("Standup notes")));
+    QVERIFY(item.textContent.contains(QStringLiteral("charles@example.com")));
+
+    const QDateTime start = QDateTime::fromSecsSinceEpoch(static_cast<qint64>(kStart));
+    const QString when = start.toString(QStringLiteral("yyyy-MM-dd HH:mm")) + QLatin1Char('-')
+                         + start.addSecs(900).toString(QStringLiteral("HH:mm"));
+    QCOMPARE(attributeValues(item, QStringLiteral("contentDescription")),
+             QStringList({when + QStringLiteral(" · Work · Room 4")}));
+    QCOMPARE(attributeValues(item, QStringLiteral("startDate")),
+             QStringList({QStringLiteral("1790000000")}));
+    QCOMPARE(attributeValues(item, QStringLiteral("endDate")),
+             QStringList({QStringLiteral("1790000900")}));
+    // The organizer is also an attendee; listed once.
+    QCOMPARE(attributeValues(item, QStringLiteral("attendees")),
+             QStringList({QStringLiteral("Ada Lovelace"), QStringLiteral("charles@example.com")}));
+    QCOMPARE(attributeValues(item, QStringLiteral("linkedDocument")).size(), 2);
+    QCOMPARE(attributeValues(item, QStringLiteral("contentType")),
+             QStringList({QStringLiteral("public.calendar-event")}));
+}
+
+void TestCalendarSource::testRecurringOccurrencesAreSeparateItems()
+{
+    bs::CalendarEvent monday = standup();
+    monday.recurring = true;
+    bs::CalendarEvent tuesday = monday;
+    tuesday.start += 86400.0;
+    tuesday.end += 86400.0;
+    QCOMPARE(monday.identifier(), monday.eventIdentifier + QStringLiteral("@1790000000"));
+
+    const auto items = bs::CalendarSource::items({monday, tuesday});
+    QCOMPARE(items.size(), size_t(2));
+    QVERIFY(items.at(0).path != items.at(1).path);
+    QCOMPARE(bs::SpotlightDonation::uniqueIdentifier(items.at(1).path), tuesday.identifier());
+}
+
+void TestCalendarSource::testHashFollowsTheEvent()
+{
+    const QString first = bs::CalendarSource::items({standup()}).front().contentHash;
+    QCOMPARE(bs::CalendarSource::items({standup()}).front().contentHash, first);
+
+    bs::CalendarEvent moved = standup();
+    moved.start += 1800.0;
+    moved.end += 1800.0;
+    QVERIFY(bs::CalendarSource::items({moved}).front().contentHash != first);
+
+    bs::CalendarEvent renamed = standup();
+    renamed.notes = QStringLiteral("Cancelled");
+    QVERIFY(bs::CalendarSource::items({renamed}).front().contentHash != first);
+}
+
+void TestCalendarSource::testSkipsUntitledAndDuplicateEvents()
+{
+    bs::CalendarEvent untitled = standup();
+    untitled.eventIdentifier = QStringLiteral("untitled");
+    untitled.title = QStringLiteral("  ");
+    bs::CalendarEvent noIdentifier = standup();
+    noIdentifier.eventIdentifier.clear();
+    const auto items = bs::CalendarSource::items({standup(), untitled, noIdentifier, standup()});
+    QCOMPARE(items.size(), size_t(1));
+}
+
+void TestCalendarSource::testStaleIdentifiers()
+{
+    bs::CalendarEvent untitled = standup();
+    untitled.eventIdentifier = QStringLiteral("untitled");
+    untitled.title.clear();
+    const QStringList stale = bs::CalendarSource::staleIdentifiers(
+        {standup().identifier(), QStringLiteral("gone"), untitled.identifier()},
+        {standup(), untitled});
+    QCOMPARE(stale, QStringList({QStringLiteral("gone"), QStringLiteral("untitled")}));
+}
+
+void TestCalendarSource::testLinkedDocuments()
+{
+    QCOMPARE(bs::CalendarSource::linkedDocuments(standup(), QStringLiteral("/Users/ada")),
+             QStringList({QStringLiteral("/Users/ada/Plans/Q4 plan.key"),
+                          QStringLiteral("/Users/ada/Documents/standup.md")}));
+
+    bs::CalendarEvent web = standup();
+    web.notes = QStringLiteral("Join https://meet.example.com/abc/def and/or dial in.");
+    web.url = QStringLiteral("https://example.com/agenda");
+    QVERIFY(bs::CalendarSource::linkedDocuments(web, QStringLiteral("/Users/ada")).isEmpty());
+
+    bs::CalendarEvent dropped = standup();
+    dropped.notes.clear();
+    dropped.url = QStringLiteral("file:///Users/ada/Desktop/agenda.pdf");
+    QCOMPARE(bs::CalendarSource::linkedDocuments(dropped, QStringLiteral("/Users/ada")),
+             QStringList({QStringLiteral("/Users/ada/Desktop/agenda.pdf")}));
+}
+
+void TestCalendarSource::testEventUrl()
+{
+    QCOMPARE(bs::CalendarSource::eventUrl(standup()),
+             QStringLiteral("ical://ekevent/20260921T141320Z/"
+                            "A1B2C3D4-0000-4000-8000-000000000001:9F8E7D6C"
+                            "?method=show&options=more"));
+}
+
+QTEST_MAIN(TestCalendarSource)
+#include "test_calendar_source.moc"
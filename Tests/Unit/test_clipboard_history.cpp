@@ -0,0 +1,150 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/clipboard_history.h"
+
+class TestClipboardHistory : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRecord();
+    void testCaps();
+    void testSearch();
+    void testExcludedFormats();
+    void testTitleAndIdentifier();
+    void testSerialize();
+    void testSeal();
+};
+
+void TestClipboardHistory::testRecord()
+{
+    bs::ClipboardHistory history(QStringLiteral("/tmp/unused-clipboard-history.bin"));
+    QVERIFY(history.record(QStringLiteral("first"), 1000.0));
+    QVERIFY(history.record(QStringLiteral("second"), 1001.0));
+    QVERIFY(!history.record(QStringLiteral("   \n"), 1002.0));
+
+    // Copying a text again moves it to the top rather than repeating it.
+    QVERIFY(history.record(QStringLiteral("first"), 1003.0));
+    QCOMPARE(history.entries().size(), size_t(2));
+    QCOMPARE(history.entries().front().text, QStringLiteral("first"));
+    QCOMPARE(history.entries().front().copiedAt, 1003.0);
+    QCOMPARE(history.entries().back().text, QStringLiteral("second"));
+}
+
+void TestClipboardHistory::testCaps()
+{
+    bs::ClipboardHistory history(QStringLiteral("/tmp/unused-clipboard-history.bin"));
+    QVERIFY(!history.record(QString(bs::ClipboardHistory::kMaxEntryBytes + 1, QLatin1Char('a')),
+                            1000.0));
+    QVERIFY(history.record(QString(bs::ClipboardHistory::kMaxEntryBytes, QLatin1Char('a')),
+                           1000.0));
+
+    for (int i = 0; i < bs::ClipboardHistory::kMaxEntries + 10; ++i) {
+        history.record(QStringLiteral("copy %1").arg(i), 2000.0 + i);
+    }
+    QCOMPARE(history.entries().size(), size_t(bs::ClipboardHistory::kMaxEntries));
+    QCOMPARE(history.entries().front().text,
+             QStringLiteral("copy %1").arg(bs::ClipboardHistory::kMaxEntries + 9));
+
+    // Copies older than the retention window go as new ones come in.
+    const double later = 2000.0 + bs::ClipboardHistory::kRetentionDays * 86400.0 + 600.0;
+    QVERIFY(history.record(QStringLiteral("much later"), later));
+    QCOMPARE(history.entries().size(), size_t(1));
+}
+
+void TestClipboardHistory::testSearch()
+{
+    bs::ClipboardHistory history(QStringLiteral("/tmp/unused-clipboard-history.bin"));
+    history.record(QStringLiteral("3f2b8c1e-9a4d-4e5f-b6a7-0c1d2e3f4a5b"), 1000.0);
+    history.record(QStringLiteral("ssh deploy@build.example.com"), 1001.0);
+    history.record(QStringLiteral("Deploy notes for Friday"), 1002.0);
+
+    auto matches = history.search(QStringLiteral("3F2B8C1E"), 10);
+    QCOMPARE(matches.size(), size_t(1));
+    QCOMPARE(matches.front().copiedAt, 1000.0);
+
+    // Every word must match; newest first.
+    matches = history.search(QStringLiteral("deploy"), 10);
+    QCOMPARE(matches.size(), size_t(2));
+    QCOMPARE(matches.front().text, QStringLiteral("Deploy notes for Friday"));
+    QCOMPARE(history.search(QStringLiteral("deploy example"), 10).size(), size_t(1));
+    QCOMPARE(history.search(QStringLiteral("deploy"), 1).size(), size_t(1));
+    QVERIFY(history.search(QStringLiteral("  "), 10).empty());
+    QVERIFY(history.search(QStringLiteral("missing"), 10).empty());
+}
+
+void TestClipboardHistory::testExcludedFormats()
+{
+    QVERIFY(bs::ClipboardHistory::isExcludedFormat(
+        {QStringLiteral("text/plain"), QStringLiteral("org.nspasteboard.ConcealedType")}));
+    QVERIFY(bs::ClipboardHistory::isExcludedFormat(
+        {QStringLiteral("application/x-qt-mime-type-name;value=\"org.nspasteboard.TransientType\"")}));
+    QVERIFY(bs::ClipboardHistory::isExcludedFormat(
+        {QStringLiteral("org.nspasteboard.AutoGeneratedType")}));
+    QVERIFY(!bs::ClipboardHistory::isExcludedFormat(
+        {QStringLiteral("text/plain"), QStringLiteral("text/html")}));
+    QVERIFY(!bs::ClipboardHistory::isExcludedFormat({}));
+}
+
+void TestClipboardHistory::testTitleAndIdentifier()
+{
+    bs::ClipboardEntry entry;
+    entry.text = QStringLiteral("\n  SELECT *\tFROM items\nWHERE id = 1");
+    QCOMPARE(bs::ClipboardHistory::title(entry), QStringLiteral("SELECT * FROM items"));
+    entry.text = QString(300, QLatin1Char('x'));
+    QCOMPARE(bs::ClipboardHistory::title(entry).size(),
+             qsizetype(bs::ClipboardHistory::kMaxNameChars));
+
+    bs::ClipboardEntry same;
+    same.text = entry.text;
+    same.copiedAt = 99.0;
+    QCOMPARE(bs::ClipboardHistory::identifier(entry), bs::ClipboardHistory::identifier(same));
+    same.text.append(QLatin1Char('y'));
+    QVERIFY(bs::ClipboardHistory::identifier(entry) != bs::ClipboardHistory::identifier(same));
+}
+
+void TestClipboardHistory::testSerialize()
+{
+    const std::vector<bs::ClipboardEntry> entries = {
+        {QStringLiteral("line one\nline two"), 1778307200.0},
+        {QStringLiteral("café"), 1778307100.0},
+    };
+    const auto parsed =
+        bs::ClipboardHistory::deserialize(bs::ClipboardHistory::serialize(entries));
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->size(), size_t(2));
+    QCOMPARE(parsed->at(0).text, QStringLiteral("line one\nline two"));
+    QCOMPARE(parsed->at(1).text, QStringLiteral("café"));
+    QCOMPARE(parsed->at(1).copiedAt, 1778307100.0);
+
+    QVERIFY(!bs::ClipboardHistory::deserialize(QByteArrayLiteral("not json")).has_value());
+    QVERIFY(!bs::ClipboardHistory::deserialize(QByteArrayLiteral("{\"version\":2}")).has_value());
+}
+
+void TestClipboardHistory::testSeal()
+{
+    const QByteArray key(32, '\x2a');
+    const QByteArray plaintext = QByteArrayLiteral("3f2b8c1e-9a4d-4e5f-b6a7-0c1d2e3f4a5b");
+    QString error;
+    QVERIFY(!bs::ClipboardHistory::seal(plaintext, QByteArray(16, '\x2a'), &error).has_value());
+
+    const auto sealed = bs::ClipboardHistory::seal(plaintext, key, &error);
+    if (!sealed) {
+        QSKIP("No AES on this platform");
+    }
+    QVERIFY(!sealed->contains(plaintext));
+    const auto opened = bs::ClipboardHistory::unseal(*sealed, key, &error);
+    QVERIFY2(opened.has_value(), qPrintable(error));
+    QCOMPARE(opened.value(), plaintext);
+
+    // A fresh IV each time.
+    QVERIFY(bs::ClipboardHistory::seal(plaintext, key, &error).value() != *sealed);
+
+    QByteArray tampered = *sealed;
+    tampered[tampered.size() / 2] = static_cast<char>(tampered.at(tampered.size() / 2) ^ 0x01);
+    QVERIFY(!bs::ClipboardHistory::unseal(tampered, key, &error).has_value());
+    QVERIFY(!bs::ClipboardHistory::unseal(*sealed, QByteArray(32, '\x2b'), &error).has_value());
+    QVERIFY(!bs::ClipboardHistory::unseal(QByteArrayLiteral("BSC1"), key, &error).has_value());
+}
+
+QTEST_MAIN(TestClipboardHistory)
+#include "test_clipboard_history.moc"
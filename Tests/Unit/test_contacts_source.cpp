@@ -0,0 +1,117 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/contacts_source.h"
+
+namespace {
+
+constexpr double kNow = 1790000000.0;
+
+bs::ContactRecord ada()
+{
+    bs::ContactRecord record;
+    record.identifier = QStringLiteral("410FE041-5C4E-48DA-B4DE-04C15EA3DBAC:ABPerson");
+    record.givenName = QStringLiteral("Ada");
+    record.familyName = QStringLiteral("Lovelace");
+    record.organization = QStringLiteral("Analytical Engines");
+    record.jobTitle = QStringLiteral("Engineer");
+    record.emails = {QStringLiteral("ada@example.com")};
+    record.phones = {QStringLiteral("+1 (555) 010-9999")};
+    return record;
+}
+
+QStringList attributeValues(const bs::DonatedItem& item, const QString& name)
+{
+    QStringList values;
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == name) {
+            values.append(attribute.value);
+        }
+    }
+    return values;
+}
+
+} // namespace
+
+class TestContactsSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDisplayName();
+    void testItem();
+    void testDatesKeepHashStable();
+    void testSkipsEmptyAndDuplicateCards();
+    void testStaleIdentifiers();
+};
+
+void TestContactsSource::testDisplayName()
+{
+    QCOMPARE(ada().displayName(), QStringLiteral("Ada Lovelace"));
+
+    bs::ContactRecord company;
+    company.organization = QStringLiteral("  Acme  ");
+    QCOMPARE(company.displayName(), QStringLiteral("Acme"));
+
+    bs::ContactRecord emailOnly;
+    emailOnly.emails = {QStringLiteral("someone@example.com")};
+    QCOMPARE(emailOnly.displayName(), QStringLiteral("someone@example.com"));
+    QVERIFY(bs::ContactRecord().displayName().isEmpty());
+}
+
+void TestContactsSource::testItem()
+{
+    const auto items = bs::ContactsSource::items({ada()}, {}, kNow);
+    QCOMPARE(items.size(), size_t(1));
+    const bs::DonatedItem& item = items.front();
+    QCOMPARE(item.name, QStringLiteral("Ada Lovelace"));
+    QCOMPARE(item.parentPath, QStringLiteral("donated://com.apple.Contacts"));
+    QCOMPARE(bs::SpotlightDonation::uniqueIdentifier(item.path), ada().identifier);
+    QCOMPARE(item.modifiedAt, kNow);
+    QVERIFY(item.textContent.contains(QStringLiteral("Engineer at Analytical Engines")));
+    QVERIFY(item.textContent.contains(QStringLiteral("ada@example.com")));
+    // Digits alone find the card too.
+    QVERIFY(attributeValues(item, QStringLiteral("keywords")).contains(QStringLiteral("15550109999")));
+    QCOMPARE(attributeValues(item, QStringLiteral("url")),
+             QStringList({QStringLiteral(
+                 "addressbook://410FE041-5C4E-48DA-B4DE-04C15EA3DBAC:ABPerson")}));
+    QCOMPARE(attributeValues(item, QStringLiteral("contentType")),
+             QStringList({QStringLiteral("public.vcard")}));
+}
+
+void TestContactsSource::testDatesKeepHashStable()
+{
+    const bs::DonatedItem first = bs::ContactsSource::items({ada()}, {}, kNow).front();
+    QHash<QString, double> indexed;
+    indexed.insert(ada().identifier, first.modifiedAt);
+    const bs::DonatedItem again = bs::ContactsSource::items({ada()}, indexed, kNow + 3600).front();
+    QCOMPARE(again.modifiedAt, first.modifiedAt);
+    QCOMPARE(again.contentHash, first.contentHash);
+
+    bs::ContactRecord edited = ada();
+    edited.jobTitle = QStringLiteral("Mathematician");
+    QVERIFY(bs::ContactsSource::items({edited}, indexed, kNow + 3600).front().contentHash
+            != first.contentHash);
+}
+
+void TestContactsSource::testSkipsEmptyAndDuplicateCards()
+{
+    bs::ContactRecord nameless;
+    nameless.identifier = QStringLiteral("empty:ABPerson");
+    bs::ContactRecord noIdentifier = ada();
+    noIdentifier.identifier.clear();
+    const auto items =
+        bs::ContactsSource::items({ada(), nameless, noIdentifier, ada()}, {}, kNow);
+    QCOMPARE(items.size(), size_t(1));
+}
+
+void TestContactsSource::testStaleIdentifiers()
+{
+    bs::ContactRecord nameless;
+    nameless.identifier = QStringLiteral("empty:ABPerson");
+    const QStringList stale = bs::ContactsSource::staleIdentifiers(
+        {ada().identifier, QStringLiteral("gone:ABPerson"), nameless.identifier},
+        {ada(), nameless});
+    QCOMPARE(stale, QStringList({QStringLiteral("gone:ABPerson"), nameless.identifier}));
+}
+
+QTEST_MAIN(TestContactsSource)
+#include "test_contacts_source.moc"
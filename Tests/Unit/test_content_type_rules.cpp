@@ -0,0 +1,136 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/content_type_rules.h"
+
+#include <QJsonArray>
+#include <QJsonObject>
+
+namespace {
+
+QJsonObject rule(const QString& root, const QStringList& allow, const QStringList& deny = {})
+{
+    QJsonObject json;
+    json[QStringLiteral("root")] = root;
+    json[QStringLiteral("allow")] = QJsonArray::fromStringList(allow);
+    json[QStringLiteral("deny")] = QJsonArray::fromStringList(deny);
+    return json;
+}
+
+bs::ContentTypeRules parse(const QJsonArray& json)
+{
+    QString error;
+    const auto rules = bs::ContentTypeRules::fromJson(json, &error);
+    if (!rules.has_value()) {
+        qWarning("%s", qPrintable(error));
+        return {};
+    }
+    return rules.value();
+}
+
+} // namespace
+
+class TestContentTypeRules : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testEmptyRulesAllowEverything();
+    void testAllowListPerRoot();
+    void testDenyWinsAndNamesOnly();
+    void testMostSpecificRootDecides();
+    void testMimeAndExtensionPatterns();
+    void testRejectsInvalidRules();
+    void testJsonRoundTrip();
+};
+
+void TestContentTypeRules::testEmptyRulesAllowEverything()
+{
+    const bs::ContentTypeRules rules = parse({});
+    QVERIFY(rules.isEmpty());
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/photo.jpg"), bs::ItemKind::Image));
+}
+
+void TestContentTypeRules::testAllowListPerRoot()
+{
+    const bs::ContentTypeRules rules = parse(
+        {rule(QStringLiteral("/Users/a/Downloads"), {QStringLiteral("document"), QStringLiteral("code")})});
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Downloads/report.pdf"), bs::ItemKind::Pdf));
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Downloads/notes.md"), bs::ItemKind::Markdown));
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Downloads/main.cpp"), bs::ItemKind::Code));
+    QVERIFY(!rules.allowsContent(QStringLiteral("/Users/a/Downloads/scan.png"), bs::ItemKind::Image));
+    // Other roots, and look-alike siblings, are not covered.
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Pictures/scan.png"), bs::ItemKind::Image));
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Downloads-old/scan.png"),
+                                bs::ItemKind::Image));
+}
+
+void TestContentTypeRules::testDenyWinsAndNamesOnly()
+{
+    const bs::ContentTypeRules rules = parse({
+        rule(QStringLiteral("/Users/a/Code"), {QStringLiteral("code")}, {QStringLiteral(".min.js")}),
+        rule(QStringLiteral("/Volumes/Archive"), {}, {QStringLiteral("*")}),
+    });
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Code/app.js"), bs::ItemKind::Code));
+    QVERIFY(!rules.allowsContent(QStringLiteral("/Users/a/Code/vendor.MIN.js"), bs::ItemKind::Code));
+    QVERIFY(!rules.allowsContent(QStringLiteral("/Volumes/Archive/notes.txt"), bs::ItemKind::Text));
+}
+
+void TestContentTypeRules::testMostSpecificRootDecides()
+{
+    const bs::ContentTypeRules rules = parse({
+        rule(QStringLiteral("*"), {}, {QStringLiteral("image")}),
+        rule(QStringLiteral("/Users/a/Scans"), {}),
+    });
+    QVERIFY(!rules.allowsContent(QStringLiteral("/Users/a/Desktop/shot.png"), bs::ItemKind::Image));
+    // An empty rule lifts the broader one for its subtree.
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Scans/page1.png"), bs::ItemKind::Image));
+}
+
+void TestContentTypeRules::testMimeAndExtensionPatterns()
+{
+    const bs::ContentTypeRules rules = parse({
+        rule(QStringLiteral("/Users/a/Docs"),
+             {QStringLiteral("application/pdf"), QStringLiteral("text/*"), QStringLiteral(".PSD")}),
+    });
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Docs/paper.pdf"), bs::ItemKind::Pdf));
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Docs/readme.txt"), bs::ItemKind::Text));
+    // text/x-c++src inherits text/plain, so text/* covers source too.
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Docs/main.cpp"), bs::ItemKind::Code));
+    QVERIFY(rules.allowsContent(QStringLiteral("/Users/a/Docs/cover.psd"), bs::ItemKind::Image));
+    QVERIFY(!rules.allowsContent(QStringLiteral("/Users/a/Docs/photo.jpg"), bs::ItemKind::Image));
+}
+
+void TestContentTypeRules::testRejectsInvalidRules()
+{
+    QString error;
+    QVERIFY(!bs::ContentTypeRules::fromJson({rule(QStringLiteral("relative/dir"), {})}, &error)
+                 .has_value());
+    QVERIFY(error.contains(QStringLiteral("not absolute")));
+    QVERIFY(!bs::ContentTypeRules::fromJson(
+                 {rule(QStringLiteral("/Users/a"), {QStringLiteral("spreadsheets")})}, &error)
+                 .has_value());
+    QVERIFY(error.contains(QStringLiteral("spreadsheets")));
+    QVERIFY(!bs::ContentTypeRules::fromJson({rule(QStringLiteral("/Users/a"), {}),
+                                             rule(QStringLiteral("/Users/a/"), {})},
+                                            &error)
+                 .has_value());
+}
+
+void TestContentTypeRules::testJsonRoundTrip()
+{
+    const bs::ContentTypeRules rules = parse({
+        rule(QStringLiteral("*"), {}, {QStringLiteral("image")}),
+        rule(QStringLiteral("/Users/a/Code"), {QStringLiteral("Code")}),
+    });
+    const QJsonArray json = rules.toJson();
+    QCOMPARE(json.size(), qsizetype(2));
+    // Most specific first, names lowercased.
+    QCOMPARE(json.at(0).toObject().value(QStringLiteral("root")).toString(),
+             QStringLiteral("/Users/a/Code"));
+    QCOMPARE(json.at(0).toObject().value(QStringLiteral("allow")).toArray(),
+             QJsonArray{QStringLiteral("code")});
+    QCOMPARE(json.at(1).toObject().value(QStringLiteral("root")).toString(), QStringLiteral("*"));
+    QCOMPARE(parse(json).toJson(), json);
+}
+
+QTEST_MAIN(TestContentTypeRules)
+#include "test_content_type_rules.moc"
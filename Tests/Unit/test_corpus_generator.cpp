@@ -0,0 +1,210 @@
+#include <QtTest/QtTest>
+
+#include "corpus_generator.h"
+
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QTemporaryDir>
+
+namespace {
+
+QByteArray readAll(const QString& path)
+{
+    QFile file(path);
+    return file.open(QIODevice::ReadOnly) ? file.readAll() : QByteArray();
+}
+
+QByteArray smallSpec()
+{
+    return QByteArrayLiteral(R"({
+        "name": "small", "seed": 7,
+        "entries": [
+            {"kind": "markdown", "path": "Documents/notes-{n}.md", "count": 12,
+             "keywords": ["zephyrine"], "modifiedAt": "2026-01-15T09:30:00Z"},
+            {"kind": "text", "path": "Documents/fixed.txt", "text": "exactly this"},
+            {"kind": "source", "path": "Projects/app/main.cpp", "words": 200},
+            {"kind": "csv", "path": "Documents/table.csv"},
+            {"kind": "json", "path": "Projects/app/config.json"},
+            {"kind": "binary", "path": "Desktop/shot.png", "magic": "png", "size": 1000},
+            {"kind": "directory", "path": "Projects/app/build"},
+            {"kind": "symlink", "path": "Desktop/docs", "target": "../Documents"},
+            {"kind": "symlink", "path": "Desktop/dangling", "target": "missing.txt"},
+            {"kind": "edge-names", "path": "Edge"}
+        ]})");
+}
+
+} // namespace
+
+class TestCorpusGenerator : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseSpec();
+    void testParseSpecRejectsInvalid();
+    void testGenerate();
+    void testGenerateIsDeterministic();
+    void testGenerateRefusesExisting();
+    void testCheckedInSpecs();
+};
+
+void TestCorpusGenerator::testParseSpec()
+{
+    QString error;
+    const auto spec = bs::test::CorpusGenerator::parseSpec(smallSpec(), &error);
+    QVERIFY2(spec.has_value(), qPrintable(error));
+    QCOMPARE(spec->name, QStringLiteral("small"));
+    QCOMPARE(spec->seed, quint32(7));
+    QCOMPARE(spec->entries.size(), size_t(10));
+    QCOMPARE(spec->entries[0].kind, bs::test::CorpusEntryKind::Markdown);
+    QCOMPARE(spec->entries[0].count, 12);
+    QCOMPARE(spec->entries[0].keywords, QStringList({QStringLiteral("zephyrine")}));
+    QCOMPARE(spec->entries[0].modifiedAt.toSecsSinceEpoch(), qint64(1768469400));
+    QCOMPARE(spec->entries[5].size, int64_t(1000));
+    QCOMPARE(spec->entries[7].target, QStringLiteral("../Documents"));
+}
+
+void TestCorpusGenerator::testParseSpecRejectsInvalid()
+{
+    const std::vector<QByteArray> invalid = {
+        QByteArrayLiteral("[]"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "spreadsheet", "path": "a.xls"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "text", "path": "/etc/passwd"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "text", "path": "a/../../b.txt"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "text", "path": "a.txt", "count": 3}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "text", "path": "a-{n}.txt", "count": 0}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "symlink", "path": "link"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "source", "path": "notes.docx"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "binary", "path": "a.bin", "magic": "gif"}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "binary", "path": "a.png", "magic": "png",
+                                            "size": 4}]})"),
+        QByteArrayLiteral(R"({"entries": [{"kind": "text", "path": "a.txt",
+                                            "modifiedAt": "last tuesday"}]})"),
+    };
+    for (const QByteArray& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::test::CorpusGenerator::parseSpec(json, &error).has_value(),
+                 json.constData());
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestCorpusGenerator::testGenerate()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    const auto spec = bs::test::CorpusGenerator::parseSpec(smallSpec(), &error);
+    QVERIFY(spec.has_value());
+    const QString root = dir.filePath(QStringLiteral("home"));
+    const auto report = bs::test::CorpusGenerator::generate(*spec, root, &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+
+    const int edgeFiles = static_cast<int>(bs::test::CorpusGenerator::edgeCaseNames().size());
+    QCOMPARE(report->files, 12 + 5 + edgeFiles);
+    QCOMPARE(report->symlinks, 2);
+    // Documents, Projects, Projects/app, Projects/app/build, Desktop, Edge.
+    QCOMPARE(report->directories, 6);
+    QVERIFY(report->paths.contains(QStringLiteral("Documents/notes-01.md")));
+    QVERIFY(report->paths.contains(QStringLiteral("Documents/notes-12.md")));
+
+    const QDir home(root);
+    const QByteArray notes = readAll(home.filePath(QStringLiteral("Documents/notes-03.md")));
+    QVERIFY(notes.startsWith("# Notes 03\n"));
+    QVERIFY(notes.contains("zephyrine"));
+    QCOMPARE(QFileInfo(home.filePath(QStringLiteral("Documents/notes-03.md")))
+                 .lastModified()
+                 .toSecsSinceEpoch(),
+             qint64(1768469400));
+    QCOMPARE(readAll(home.filePath(QStringLiteral("Documents/fixed.txt"))),
+             QByteArrayLiteral("exactly this"));
+    QVERIFY(readAll(home.filePath(QStringLiteral("Projects/app/main.cpp"))).contains("int "));
+    QVERIFY(readAll(home.filePath(QStringLiteral("Documents/table.csv")))
+                .startsWith("id,name,category,amount,date,note\n"));
+
+    const QByteArray png = readAll(home.filePath(QStringLiteral("Desktop/shot.png")));
+    QCOMPARE(png.size(), qsizetype(1000));
+    QVERIFY(png.startsWith("\x89PNG"));
+    QVERIFY(QFileInfo(home.filePath(QStringLiteral("Projects/app/build"))).isDir());
+
+    const QFileInfo link(home.filePath(QStringLiteral("Desktop/docs")));
+    QVERIFY(link.isSymLink());
+    QVERIFY(link.isDir());
+    const QFileInfo dangling(home.filePath(QStringLiteral("Desktop/dangling")));
+    QVERIFY(dangling.isSymLink());
+    QVERIFY(!dangling.exists());
+
+    for (const QString& name : bs::test::CorpusGenerator::edgeCaseNames()) {
+        QVERIFY2(QFileInfo::exists(home.filePath(QStringLiteral("Edge/") + name)),
+                 qPrintable(name));
+    }
+}
+
+void TestCorpusGenerator::testGenerateIsDeterministic()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    auto spec = bs::test::CorpusGenerator::parseSpec(smallSpec(), &error);
+    QVERIFY(spec.has_value());
+    QVERIFY(bs::test::CorpusGenerator::generate(*spec, dir.filePath(QStringLiteral("a")), &error));
+    QVERIFY(bs::test::CorpusGenerator::generate(*spec, dir.filePath(QStringLiteral("b")), &error));
+    for (const QString& path : {QStringLiteral("Documents/notes-07.md"),
+                                QStringLiteral("Projects/app/main.cpp"),
+                                QStringLiteral("Desktop/shot.png")}) {
+        QCOMPARE(readAll(QDir(dir.filePath(QStringLiteral("a"))).filePath(path)),
+                 readAll(QDir(dir.filePath(QStringLiteral("b"))).filePath(path)));
+    }
+
+    // Another seed, other text; an added entry leaves the others alone.
+    spec->seed = 8;
+    QVERIFY(bs::test::CorpusGenerator::generate(*spec, dir.filePath(QStringLiteral("c")), &error));
+    QVERIFY(readAll(dir.filePath(QStringLiteral("a/Documents/notes-07.md")))
+            != readAll(dir.filePath(QStringLiteral("c/Documents/notes-07.md"))));
+    spec->seed = 7;
+    bs::test::CorpusEntry extra;
+    extra.path = QStringLiteral("Documents/extra.txt");
+    spec->entries.insert(spec->entries.begin(), extra);
+    QVERIFY(bs::test::CorpusGenerator::generate(*spec, dir.filePath(QStringLiteral("d")), &error));
+    QCOMPARE(readAll(dir.filePath(QStringLiteral("a/Documents/notes-07.md"))),
+             readAll(dir.filePath(QStringLiteral("d/Documents/notes-07.md"))));
+}
+
+void TestCorpusGenerator::testGenerateRefusesExisting()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QFile existing(dir.filePath(QStringLiteral("notes.txt")));
+    QVERIFY(existing.open(QIODevice::WriteOnly));
+    existing.write("keep me");
+    existing.close();
+
+    bs::test::CorpusSpec spec;
+    bs::test::CorpusEntry entry;
+    entry.path = QStringLiteral("notes.txt");
+    spec.entries.push_back(entry);
+    QString error;
+    QVERIFY(!bs::test::CorpusGenerator::generate(spec, dir.path(), &error).has_value());
+    QVERIFY(error.contains(QStringLiteral("already exists")));
+    QCOMPARE(readAll(dir.filePath(QStringLiteral("notes.txt"))), QByteArrayLiteral("keep me"));
+}
+
+void TestCorpusGenerator::testCheckedInSpecs()
+{
+#ifdef BETTERSPOTLIGHT_SOURCE_DIR
+    const QDir specs(QDir(QString::fromUtf8(BETTERSPOTLIGHT_SOURCE_DIR))
+                         .filePath(QStringLiteral("Tests/Fixtures/corpus_specs")));
+    const QStringList files = specs.entryList({QStringLiteral("*.json")}, QDir::Files);
+    QVERIFY(!files.isEmpty());
+    for (const QString& file : files) {
+        QString error;
+        QVERIFY2(bs::test::CorpusGenerator::loadSpec(specs.filePath(file), &error).has_value(),
+                 qPrintable(file + QStringLiteral(": ") + error));
+    }
+#else
+    QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+#endif
+}
+
+QTEST_MAIN(TestCorpusGenerator)
+#include "test_corpus_generator.moc"
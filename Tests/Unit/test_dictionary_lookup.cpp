@@ -0,0 +1,100 @@
+#include <QtTest/QtTest>
+
+#include "core/query/dictionary_lookup.h"
+
+class TestDictionaryLookup : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDefinableQueries();
+    void testParseOxfordEntry();
+    void testParseNumberedSenses();
+    void testParseWithoutPronunciation();
+    void testSummaryIsTruncated();
+    void testJson();
+};
+
+void TestDictionaryLookup::testDefinableQueries()
+{
+    QVERIFY(bs::DictionaryLookup::isDefinableQuery(QStringLiteral("serendipity")));
+    QVERIFY(bs::DictionaryLookup::isDefinableQuery(QStringLiteral("well-being")));
+    QVERIFY(bs::DictionaryLookup::isDefinableQuery(QStringLiteral("o'clock")));
+    QVERIFY(bs::DictionaryLookup::isDefinableQuery(QStringLiteral("café")));
+    QVERIFY(!bs::DictionaryLookup::isDefinableQuery(QStringLiteral("a")));
+    QVERIFY(!bs::DictionaryLookup::isDefinableQuery(QStringLiteral("two words")));
+    QVERIFY(!bs::DictionaryLookup::isDefinableQuery(QStringLiteral("report2024")));
+    QVERIFY(!bs::DictionaryLookup::isDefinableQuery(QStringLiteral("main.cpp")));
+    QVERIFY(!bs::DictionaryLookup::isDefinableQuery(QString()));
+}
+
+void TestDictionaryLookup::testParseOxfordEntry()
+{
+    const auto definition = bs::DictionaryLookup::parse(
+        QStringLiteral("serendipity"),
+        QStringLiteral("serendipity | ˌserənˈdipədē | noun the occurrence and development of "
+                       "events by chance in a happy or beneficial way: a fortunate stroke of "
+                       "serendipity. ORIGIN 1750s: coined by Horace Walpole."));
+    QVERIFY(definition.has_value());
+    QCOMPARE(definition->word, QStringLiteral("serendipity"));
+    QCOMPARE(definition->pronunciation, QStringLiteral("ˌserənˈdipədē"));
+    QCOMPARE(definition->partOfSpeech, QStringLiteral("noun"));
+    QCOMPARE(definition->summary,
+             QStringLiteral("the occurrence and development of events by chance in a happy or "
+                            "beneficial way"));
+}
+
+void TestDictionaryLookup::testParseNumberedSenses()
+{
+    const auto definition = bs::DictionaryLookup::parse(
+        QStringLiteral("run"),
+        QStringLiteral("run | rən | verb 1 move at a speed faster than a walk, never having both "
+                       "feet on the ground at the same time. 2 pass or cause to pass quickly. "
+                       "▶noun an act or spell of running."));
+    QVERIFY(definition.has_value());
+    QCOMPARE(definition->partOfSpeech, QStringLiteral("verb"));
+    QCOMPARE(definition->summary,
+             QStringLiteral("move at a speed faster than a walk, never having both feet on the "
+                            "ground at the same time."));
+}
+
+void TestDictionaryLookup::testParseWithoutPronunciation()
+{
+    const auto definition = bs::DictionaryLookup::parse(
+        QStringLiteral("thesaurus"),
+        QStringLiteral("Thesaurus noun a book that lists words in groups of synonyms."));
+    QVERIFY(definition.has_value());
+    QCOMPARE(definition->word, QStringLiteral("Thesaurus"));
+    QVERIFY(definition->pronunciation.isEmpty());
+    QCOMPARE(definition->summary,
+             QStringLiteral("a book that lists words in groups of synonyms."));
+
+    QVERIFY(!bs::DictionaryLookup::parse(QStringLiteral("x"), QStringLiteral("  ")).has_value());
+}
+
+void TestDictionaryLookup::testSummaryIsTruncated()
+{
+    QString body;
+    for (int i = 0; i < 100; ++i) {
+        body += QStringLiteral("word ");
+    }
+    const auto definition = bs::DictionaryLookup::parse(
+        QStringLiteral("long"), QStringLiteral("long | lông | adjective ") + body);
+    QVERIFY(definition.has_value());
+    QVERIFY(definition->summary.size() <= bs::DictionaryLookup::kMaxSummaryLength + 1);
+    QVERIFY(definition->summary.endsWith(QChar(0x2026)));
+}
+
+void TestDictionaryLookup::testJson()
+{
+    bs::DictionaryDefinition definition;
+    definition.word = QStringLiteral("café au lait");
+    definition.summary = QStringLiteral("coffee with milk.");
+    const QJsonObject json = definition.toJson();
+    QCOMPARE(json.value(QStringLiteral("url")).toString(),
+             QStringLiteral("dict://caf%C3%A9%20au%20lait"));
+    QVERIFY(!json.contains(QStringLiteral("pronunciation")));
+    QVERIFY(!json.contains(QStringLiteral("partOfSpeech")));
+}
+
+QTEST_MAIN(TestDictionaryLookup)
+#include "test_dictionary_lookup.moc"
@@ -0,0 +1,228 @@
+#include <QtTest/QtTest>
+
+#include "cli/doctor.h"
+
+#include <QDir>
+#include <QFile>
+#include <QJsonDocument>
+#include <QTemporaryDir>
+
+namespace {
+
+using Status = bs::DoctorCheck::Status;
+
+QJsonObject makeDiagnostics(bool fda, bool indexing, bool watcherRunning, qint64 watcherErrors)
+{
+    QJsonObject watcher;
+    watcher[QStringLiteral("running")] = watcherRunning;
+    watcher[QStringLiteral("errorCount")] = watcherErrors;
+    watcher[QStringLiteral("lastError")] = QStringLiteral("FSEvents: kernel dropped events");
+
+    QJsonObject diagnostics;
+    diagnostics[QStringLiteral("fullDiskAccess")] = fda;
+    diagnostics[QStringLiteral("indexing")] = indexing;
+    diagnostics[QStringLiteral("roots")] = QJsonArray{QStringLiteral("/Users/test")};
+    diagnostics[QStringLiteral("watcher")] = watcher;
+    return diagnostics;
+}
+
+bool writeFile(const QString& path, const QByteArray& contents)
+{
+    QFile file(path);
+    return file.open(QIODevice::WriteOnly) && file.write(contents) == contents.size();
+}
+
+} // namespace
+
+class TestDoctor : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testServiceChecks();
+    void testFullDiskAccess();
+    void testWatcher();
+    void testIntegrity();
+    void testDiskSpaceThresholds();
+    void testStaleFiles();
+    void testRenderAndExitCode();
+};
+
+void TestDoctor::testServiceChecks()
+{
+    bs::Doctor::ServiceProbe probe;
+    probe.name = QStringLiteral("query");
+    probe.socketExists = true;
+    probe.reachable = true;
+    QCOMPARE(bs::Doctor::evaluateService(probe, true).status, Status::Ok);
+
+    probe.reachable = false;
+    probe.error = QStringLiteral("query service did not answer 'ping'");
+    const bs::DoctorCheck hung = bs::Doctor::evaluateService(probe, true);
+    QCOMPARE(hung.status, Status::Failed);
+    QCOMPARE(hung.id, QStringLiteral("service-query"));
+    QVERIFY(hung.detail.contains(probe.error));
+    QVERIFY(!hung.fix.isEmpty());
+
+    probe.name = QStringLiteral("inference");
+    probe.socketExists = false;
+    const bs::DoctorCheck optional = bs::Doctor::evaluateService(probe, false);
+    QCOMPARE(optional.status, Status::Warning);
+    QVERIFY(optional.fix.contains(QStringLiteral("bspot service install")));
+}
+
+void TestDoctor::testFullDiskAccess()
+{
+    QCOMPARE(bs::Doctor::evaluateFullDiskAccess(makeDiagnostics(true, true, true, 0), false).status,
+             Status::Ok);
+
+    const bs::DoctorCheck denied =
+        bs::Doctor::evaluateFullDiskAccess(makeDiagnostics(false, true, true, 0), true);
+    QCOMPARE(denied.status, Status::Failed);
+    QVERIFY(denied.fix.contains(QStringLiteral("Full Disk Access")));
+
+    QJsonObject folder;
+    folder[QStringLiteral("path")] = QStringLiteral("/Users/test/Documents");
+    folder[QStringLiteral("reason")] = QStringLiteral("privacy_denied");
+    QJsonObject access;
+    access[QStringLiteral("protectedFolders")] = QJsonArray{folder};
+    access[QStringLiteral("remediation")] = QStringLiteral("Open System Settings");
+    QJsonObject diagnostics = makeDiagnostics(true, true, true, 0);
+    diagnostics[QStringLiteral("access")] = access;
+    const bs::DoctorCheck skipped = bs::Doctor::evaluateFullDiskAccess(diagnostics, false);
+    QCOMPARE(skipped.status, Status::Warning);
+    QVERIFY(skipped.detail.contains(QStringLiteral("/Users/test/Documents")));
+    QCOMPARE(skipped.fix, QStringLiteral("Open System Settings"));
+
+    // An unreadable root fails even with the grant.
+    folder[QStringLiteral("path")] = QStringLiteral("/Volumes/Archive");
+    folder[QStringLiteral("reason")] = QStringLiteral("missing");
+    access[QStringLiteral("unreadableRoots")] = QJsonArray{folder};
+    diagnostics[QStringLiteral("access")] = access;
+    const bs::DoctorCheck unreadable = bs::Doctor::evaluateFullDiskAccess(diagnostics, false);
+    QCOMPARE(unreadable.status, Status::Failed);
+    QVERIFY(unreadable.detail.contains(QStringLiteral("/Volumes/Archive")));
+
+    // Without the indexer, the terminal's own grant proves nothing.
+    const bs::DoctorCheck unknown = bs::Doctor::evaluateFullDiskAccess(std::nullopt, true);
+    QCOMPARE(unknown.status, Status::Warning);
+    QVERIFY(unknown.detail.contains(QStringLiteral("this terminal has access")));
+}
+
+void TestDoctor::testWatcher()
+{
+    QCOMPARE(bs::Doctor::evaluateWatcher(std::nullopt).status, Status::Failed);
+    QCOMPARE(bs::Doctor::evaluateWatcher(makeDiagnostics(true, false, false, 0)).status,
+             Status::Warning);
+    QCOMPARE(bs::Doctor::evaluateWatcher(makeDiagnostics(true, true, false, 0)).status,
+             Status::Failed);
+
+    const bs::DoctorCheck dropped =
+        bs::Doctor::evaluateWatcher(makeDiagnostics(true, true, true, 3));
+    QCOMPARE(dropped.status, Status::Warning);
+    QVERIFY(dropped.detail.contains(QStringLiteral("3 dropped-event")));
+    QVERIFY(dropped.detail.contains(QStringLiteral("kernel dropped events")));
+
+    const bs::DoctorCheck healthy =
+        bs::Doctor::evaluateWatcher(makeDiagnostics(true, true, true, 0));
+    QCOMPARE(healthy.status, Status::Ok);
+    QCOMPARE(healthy.detail, QStringLiteral("running on 1 root(s)"));
+}
+
+void TestDoctor::testIntegrity()
+{
+    const QString dbPath = QStringLiteral("/Users/test/Library/Application Support/index.db");
+    QCOMPARE(bs::Doctor::evaluateIntegrity(dbPath, false, {}, {}).status, Status::Warning);
+    QCOMPARE(bs::Doctor::evaluateIntegrity(dbPath, true, {}, QStringLiteral("ok")).status,
+             Status::Ok);
+
+    const bs::DoctorCheck corrupt = bs::Doctor::evaluateIntegrity(
+        dbPath, true, {}, QStringLiteral("*** in database main ***\nPage 12: btreeInitPage() returns error code 11"));
+    QCOMPARE(corrupt.status, Status::Failed);
+    // Paths with spaces are quoted so the fix can be pasted into a shell.
+    QVERIFY(corrupt.fix.contains(QStringLiteral("'%1'").arg(dbPath)));
+
+    QCOMPARE(bs::Doctor::evaluateIntegrity(dbPath, true, QStringLiteral("file is not a database"),
+                                           {}).status,
+             Status::Failed);
+}
+
+void TestDoctor::testDiskSpaceThresholds()
+{
+    const qint64 gib = 1LL << 30;
+    QCOMPARE(bs::Doctor::evaluateDiskSpace(QStringLiteral("/"), 20 * gib, 500 * gib).status,
+             Status::Ok);
+    QCOMPARE(bs::Doctor::evaluateDiskSpace(QStringLiteral("/"), 2 * gib, 500 * gib).status,
+             Status::Warning);
+    const bs::DoctorCheck full = bs::Doctor::evaluateDiskSpace(QStringLiteral("/"), gib / 2,
+                                                               500 * gib);
+    QCOMPARE(full.status, Status::Failed);
+    QCOMPARE(full.detail, QStringLiteral("0.5 GiB free of 500.0 GiB on the index volume"));
+    QCOMPARE(bs::Doctor::evaluateDiskSpace(QStringLiteral("/"), -1, -1).status, Status::Warning);
+}
+
+void TestDoctor::testStaleFiles()
+{
+    QTemporaryDir root;
+    QVERIFY(root.isValid());
+    const auto alive = [](qint64 pid) { return pid == 100; };
+
+    QCOMPARE(bs::Doctor::evaluateStaleFiles(root.path(), {}, alive).status, Status::Ok);
+
+    QDir dir(root.path());
+    QVERIFY(dir.mkdir(QStringLiteral("live-instance")));
+    QVERIFY(writeFile(dir.filePath(QStringLiteral("live-instance/instance.json")),
+                      QJsonDocument(QJsonObject{{QStringLiteral("app_pid"), 100}}).toJson()));
+    QVERIFY(dir.mkdir(QStringLiteral("dead-instance")));
+    QVERIFY(writeFile(dir.filePath(QStringLiteral("dead-instance/instance.json")),
+                      QJsonDocument(QJsonObject{{QStringLiteral("app_pid"), 200}}).toJson()));
+    QVERIFY(dir.mkdir(QStringLiteral("unrelated")));
+    // QLockFile format: pid, app name, host name.
+    QVERIFY(writeFile(dir.filePath(QStringLiteral("app.lock")),
+                      QByteArrayLiteral("200\nbetterspotlight\n") + QSysInfo::machineHostName().toUtf8()
+                          + "\n"));
+
+    const QString socket = dir.filePath(QStringLiteral("query.sock"));
+    const bs::DoctorCheck check = bs::Doctor::evaluateStaleFiles(root.path(), {socket}, alive);
+    QCOMPARE(check.status, Status::Warning);
+    QVERIFY(check.detail.startsWith(QStringLiteral("3 left over")));
+    QVERIFY(check.detail.contains(dir.filePath(QStringLiteral("app.lock"))));
+    QVERIFY(check.detail.contains(dir.filePath(QStringLiteral("dead-instance"))));
+    QVERIFY(check.detail.contains(socket));
+    QVERIFY(!check.detail.contains(QStringLiteral("live-instance")));
+    QVERIFY(check.fix.contains(QStringLiteral("rm -rf")));
+}
+
+void TestDoctor::testRenderAndExitCode()
+{
+    bs::DoctorCheck ok;
+    ok.title = QStringLiteral("Disk space");
+    ok.detail = QStringLiteral("plenty");
+    ok.fix = QStringLiteral("ignored while ok");
+
+    bs::DoctorCheck warn;
+    warn.title = QStringLiteral("Stale locks");
+    warn.status = Status::Warning;
+    warn.detail = QStringLiteral("1 left over");
+    warn.fix = QStringLiteral("rm -rf /tmp/x");
+
+    QCOMPARE(bs::Doctor::exitCode({ok, warn}), 0);
+    const QString rendered = bs::Doctor::render({ok, warn});
+    QCOMPARE(rendered,
+             QStringLiteral("[ok  ] Disk space: plenty\n"
+                            "[warn] Stale locks: 1 left over\n"
+                            "       fix: rm -rf /tmp/x\n"
+                            "\n0 failed, 1 warning(s).\n"));
+    QVERIFY(bs::Doctor::render({ok}).endsWith(QStringLiteral("All checks passed.\n")));
+
+    bs::DoctorCheck failed = warn;
+    failed.status = Status::Failed;
+    QCOMPARE(bs::Doctor::exitCode({ok, failed}), 1);
+    const QJsonArray json = bs::Doctor::toJson({ok, failed});
+    QCOMPARE(json.at(1).toObject().value(QStringLiteral("status")).toString(),
+             QStringLiteral("fail"));
+    QCOMPARE(json.at(0).toObject().value(QStringLiteral("fix")).toString(),
+             QStringLiteral("ignored while ok"));
+}
+
+QTEST_MAIN(TestDoctor)
+#include "test_doctor.moc"
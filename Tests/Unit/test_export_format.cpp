@@ -0,0 +1,100 @@
+#include <QtTest/QtTest>
+
+#include "cli/export_format.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace {
+
+QJsonObject makeItem(qint64 itemId, const QString& path)
+{
+    QJsonObject item;
+    item[QStringLiteral("itemId")] = itemId;
+    item[QStringLiteral("path")] = path;
+    item[QStringLiteral("name")] = path.section(QLatin1Char('/'), -1);
+    item[QStringLiteral("kind")] = QStringLiteral("text");
+    item[QStringLiteral("size")] = 2048;
+    item[QStringLiteral("created")] = QStringLiteral("2026-01-02T03:04:05Z");
+    item[QStringLiteral("modified")] = QStringLiteral("2026-02-03T04:05:06Z");
+    item[QStringLiteral("indexed")] = QJsonValue(QJsonValue::Null);
+    item[QStringLiteral("matchedFields")] =
+        QJsonArray{QStringLiteral("name"), QStringLiteral("content")};
+    return item;
+}
+
+} // namespace
+
+class TestExportFormat : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParse();
+    void testCsv();
+    void testJsonArrayIsValid();
+    void testNdjson();
+};
+
+void TestExportFormat::testParse()
+{
+    QCOMPARE(bs::ExportFormat::parse(QStringLiteral("CSV")).value(), bs::ExportFileFormat::Csv);
+    QCOMPARE(bs::ExportFormat::parse(QStringLiteral("jsonl")).value(), bs::ExportFileFormat::Ndjson);
+    QVERIFY(!bs::ExportFormat::parse(QStringLiteral("xml")).has_value());
+    QCOMPARE(bs::ExportFormat::fromFileName(QStringLiteral("/tmp/hits.json")).value(),
+             bs::ExportFileFormat::Json);
+    QVERIFY(!bs::ExportFormat::fromFileName(QStringLiteral("/tmp/hits")).has_value());
+}
+
+void TestExportFormat::testCsv()
+{
+    QCOMPARE(bs::ExportFormat::begin(bs::ExportFileFormat::Csv),
+             QByteArray("itemId,path,name,kind,size,created,modified,indexed,matchedFields\r\n"));
+    QCOMPARE(bs::ExportFormat::record(bs::ExportFileFormat::Csv,
+                                      makeItem(7, QStringLiteral("/docs/plan.md")), true),
+             QByteArray("7,/docs/plan.md,plan.md,text,2048,2026-01-02T03:04:05Z,"
+                        "2026-02-03T04:05:06Z,,name;content\r\n"));
+
+    QCOMPARE(bs::ExportFormat::csvField(QStringLiteral("plain")), QStringLiteral("plain"));
+    QCOMPARE(bs::ExportFormat::csvField(QStringLiteral("a,b")), QStringLiteral("\"a,b\""));
+    QCOMPARE(bs::ExportFormat::csvField(QStringLiteral("say \"hi\"")),
+             QStringLiteral("\"say \"\"hi\"\"\""));
+    QCOMPARE(bs::ExportFormat::csvField(QStringLiteral("two\nlines")),
+             QStringLiteral("\"two\nlines\""));
+    QVERIFY(bs::ExportFormat::end(bs::ExportFileFormat::Csv, false).isEmpty());
+}
+
+void TestExportFormat::testJsonArrayIsValid()
+{
+    for (const int count : {0, 1, 3}) {
+        QByteArray out = bs::ExportFormat::begin(bs::ExportFileFormat::Json);
+        for (int i = 0; i < count; ++i) {
+            out += bs::ExportFormat::record(bs::ExportFileFormat::Json,
+                                            makeItem(i, QStringLiteral("/docs/%1").arg(i)),
+                                            i == 0);
+        }
+        out += bs::ExportFormat::end(bs::ExportFileFormat::Json, count == 0);
+
+        QJsonParseError error;
+        const QJsonDocument document = QJsonDocument::fromJson(out, &error);
+        QCOMPARE(error.error, QJsonParseError::NoError);
+        QVERIFY(document.isArray());
+        QCOMPARE(document.array().size(), count);
+        if (count == 3) {
+            QCOMPARE(document.array().at(2).toObject().value(QStringLiteral("itemId")).toInt(), 2);
+        }
+    }
+}
+
+void TestExportFormat::testNdjson()
+{
+    const QByteArray line = bs::ExportFormat::record(
+        bs::ExportFileFormat::Ndjson, makeItem(1, QStringLiteral("/docs/a.txt")), false);
+    QVERIFY(line.endsWith('\n'));
+    QCOMPARE(line.count('\n'), 1);
+    QCOMPARE(QJsonDocument::fromJson(line).object().value(QStringLiteral("path")).toString(),
+             QStringLiteral("/docs/a.txt"));
+    QVERIFY(bs::ExportFormat::begin(bs::ExportFileFormat::Ndjson).isEmpty());
+}
+
+QTEST_MAIN(TestExportFormat)
+#include "test_export_format.moc"
@@ -0,0 +1,119 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/extended_attributes.h"
+
+#include <QFile>
+#include <QTemporaryDir>
+
+#include <sys/xattr.h>
+
+class TestExtendedAttributes : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDecodeText();
+    void testDecodePlist();
+    void testParseNameList();
+    void testReadConfiguredXattrs();
+    void testReadFinderComment();
+};
+
+void TestExtendedAttributes::testDecodeText()
+{
+    QCOMPARE(bs::ExtendedAttributes::decode(QByteArrayLiteral("batch-42")),
+             QStringList({QStringLiteral("batch-42")}));
+    QCOMPARE(bs::ExtendedAttributes::decode(QByteArray("reviewed\0", 9)),
+             QStringList({QStringLiteral("reviewed")}));
+    QVERIFY(bs::ExtendedAttributes::decode(QByteArray("\xff\xfe\x01", 3)).isEmpty());
+    QVERIFY(bs::ExtendedAttributes::decode(QByteArray("a\0b", 3)).isEmpty());
+    QVERIFY(bs::ExtendedAttributes::decode(QByteArray()).isEmpty());
+}
+
+void TestExtendedAttributes::testDecodePlist()
+{
+    const QByteArray array = R"(<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<array>
+    <string>https://downloads.example.org/tool.zip</string>
+    <integer>7</integer>
+    <string>https://example.org/</string>
+</array>
+</plist>
+)";
+    QCOMPARE(bs::ExtendedAttributes::decode(array),
+             QStringList({QStringLiteral("https://downloads.example.org/tool.zip"),
+                          QStringLiteral("https://example.org/")}));
+
+    const QByteArray string = R"(<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><string>Scanned at front desk</string></plist>
+)";
+    QCOMPARE(bs::ExtendedAttributes::decode(string),
+             QStringList({QStringLiteral("Scanned at front desk")}));
+}
+
+void TestExtendedAttributes::testParseNameList()
+{
+    QCOMPARE(bs::ExtendedAttributes::parseNameList(
+                 QStringLiteral(R"([" com.example.batch ", "com.example.batch", "", 4, "user.project"])")),
+             QStringList({QStringLiteral("com.example.batch"), QStringLiteral("user.project")}));
+    QVERIFY(bs::ExtendedAttributes::parseNameList(QStringLiteral("com.example.batch")).isEmpty());
+    QVERIFY(bs::ExtendedAttributes::parseNameList(QStringLiteral("{}")).isEmpty());
+}
+
+void TestExtendedAttributes::testReadConfiguredXattrs()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.path() + QStringLiteral("/stamped.txt");
+    QFile file(path);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.write("body");
+    file.close();
+
+    const QByteArray pathUtf8 = path.toUtf8();
+    const QByteArray value("  batch-42 ");
+    QCOMPARE(setxattr(pathUtf8.constData(), "com.example.batch", value.constData(),
+                      static_cast<size_t>(value.size()), 0, 0), 0);
+    const QByteArray blob("\x00\x01\x02\x03", 4);
+    QCOMPARE(setxattr(pathUtf8.constData(), "com.example.blob", blob.constData(),
+                      static_cast<size_t>(blob.size()), 0, 0), 0);
+
+    const auto attributes = bs::ExtendedAttributes::read(
+        path, {QStringLiteral("com.example.batch"), QStringLiteral("com.example.blob"),
+               QStringLiteral("com.example.absent")});
+    QCOMPARE(attributes.size(), size_t(1));
+    QCOMPARE(attributes[0].name, QStringLiteral("com.example.batch"));
+    QCOMPARE(attributes[0].value, QStringLiteral("batch-42"));
+    QVERIFY(attributes[0].searchable);
+
+    QVERIFY(bs::ExtendedAttributes::read(path, {}).empty());
+}
+
+void TestExtendedAttributes::testReadFinderComment()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.path() + QStringLiteral("/annotated.pdf");
+    QFile file(path);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.write("%PDF");
+    file.close();
+
+    QVERIFY(bs::ExtendedAttributes::readFinderComment(path).empty());
+
+    const QByteArray plist = R"(<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><string>Signed copy from the 2019 closing</string></plist>
+)";
+    const QByteArray pathUtf8 = path.toUtf8();
+    QCOMPARE(setxattr(pathUtf8.constData(), bs::ExtendedAttributes::kFinderCommentXattr,
+                      plist.constData(), static_cast<size_t>(plist.size()), 0, 0), 0);
+
+    const auto comment = bs::ExtendedAttributes::readFinderComment(path);
+    QCOMPARE(comment.size(), size_t(1));
+    QCOMPARE(comment[0].name, QString::fromLatin1(bs::ExtendedAttributes::kFinderCommentAttribute));
+    QCOMPARE(comment[0].value, QStringLiteral("Signed copy from the 2019 closing"));
+    QVERIFY(comment[0].searchable);
+}
+
+QTEST_MAIN(TestExtendedAttributes)
+#include "test_extended_attributes.moc"
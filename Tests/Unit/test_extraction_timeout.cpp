@@ -11,6 +11,7 @@ private slots:
     void testExtractionCompletesWithinTimeout();
     void testCancelledExtractionReturnsImmediately();
     void testMaxFileSizeEnforcement();
+    void testExtractorStats();
 };
 
 void TestExtractionTimeout::testDeadlineConstant()
@@ -82,5 +83,39 @@ void TestExtractionTimeout::testMaxFileSizeEnforcement()
     QCOMPARE(result.status, bs::ExtractionResult::Status::SizeExceeded);
 }
 
+void TestExtractionTimeout::testExtractorStats()
+{
+    bs::ExtractionManager mgr;
+
+    auto stats = mgr.extractorStats();
+    QCOMPARE(static_cast<int>(stats.size()), bs::ExtractionManager::extractorNames().size());
+    QCOMPARE(stats.at(0).extractor, QStringLiteral("text"));
+    QCOMPARE(stats.at(0).extractions, uint64_t(0));
+
+    QTemporaryFile small;
+    QVERIFY(small.open());
+    small.write("Hello world, this is a test file for extraction.\n");
+    small.flush();
+    QCOMPARE(mgr.extract(small.fileName(), bs::ItemKind::Text).status,
+             bs::ExtractionResult::Status::Success);
+    QCOMPARE(mgr.extract(small.fileName(), bs::ItemKind::Code).status,
+             bs::ExtractionResult::Status::Success);
+
+    // Rejected before any extractor runs: not counted.
+    mgr.setMaxFileSizeBytes(10);
+    QCOMPARE(mgr.extract(small.fileName(), bs::ItemKind::Text).status,
+             bs::ExtractionResult::Status::SizeExceeded);
+    mgr.extract(QStringLiteral("/nonexistent/file.pdf"), bs::ItemKind::Pdf);
+    mgr.extract(small.fileName(), bs::ItemKind::Directory);
+
+    stats = mgr.extractorStats();
+    QCOMPARE(stats.at(0).extractions, uint64_t(2));
+    QCOMPARE(stats.at(0).failures, uint64_t(0));
+    QVERIFY(stats.at(0).maxMs <= stats.at(0).totalMs);
+    QCOMPARE(stats.at(1).extractor, QStringLiteral("pdf"));
+    QCOMPARE(stats.at(1).extractions, uint64_t(0));
+    QCOMPARE(stats.at(2).extractions, uint64_t(0));
+}
+
 QTEST_MAIN(TestExtractionTimeout)
 #include "test_extraction_timeout.moc"
@@ -0,0 +1,296 @@
+#include <QtTest/QtTest>
+
+#include "core/extraction/pdf_extractor.h"
+#include "core/extraction/text_extractor.h"
+#include "extractor_conformance.h"
+
+#include <QDir>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QFileInfo>
+#include <QTemporaryDir>
+
+namespace {
+
+using bs::test::ConformanceReport;
+using bs::test::ExtractorConformance;
+
+QString fixtureDir(const QString& name)
+{
+#ifdef BETTERSPOTLIGHT_SOURCE_DIR
+    return QDir(QString::fromUtf8(BETTERSPOTLIGHT_SOURCE_DIR))
+        .filePath(QStringLiteral("Tests/Fixtures/extractor_conformance/") + name);
+#else
+    Q_UNUSED(name);
+    return QString();
+#endif
+}
+
+bool writeFile(const QString& path, const QByteArray& bytes)
+{
+    QFile file(path);
+    return file.open(QIODevice::WriteOnly) && file.write(bytes) == bytes.size();
+}
+
+// Reads files as Latin-1 text, with one way of breaking the contract
+// switched on at a time.
+class FaultyExtractor : public bs::FileExtractor {
+public:
+    enum class Fault { None, Nondeterministic, ShiftedText, ContentOnFailure, WritesNextToInput,
+                       FoundMissingFile };
+
+    explicit FaultyExtractor(Fault fault) : m_fault(fault) {}
+
+    bs::ExtractionResult extract(const QString& filePath) override
+    {
+        QElapsedTimer timer;
+        timer.start();
+        bs::ExtractionResult result;
+        QFile file(filePath);
+        if (!QFileInfo(filePath).isFile() || !file.open(QIODevice::ReadOnly)) {
+            result.status = bs::ExtractionResult::Status::Inaccessible;
+            result.errorMessage = QStringLiteral("cannot open");
+            if (m_fault == Fault::FoundMissingFile) {
+                result.status = bs::ExtractionResult::Status::Success;
+                result.content = QString();
+                result.errorMessage.reset();
+            }
+            return result;
+        }
+        QString text = QString::fromLatin1(file.readAll());
+        if (m_fault == Fault::Nondeterministic) {
+            text += QString::number(++m_calls);
+        } else if (m_fault == Fault::ShiftedText) {
+            text.prepend(QStringLiteral("header\n"));
+        } else if (m_fault == Fault::WritesNextToInput) {
+            writeFile(filePath + QStringLiteral(".cache"), QByteArrayLiteral("cache"));
+        }
+        if (text.startsWith(QLatin1String("%PDF-"))) {
+            result.status = bs::ExtractionResult::Status::CorruptedFile;
+            result.errorMessage = QStringLiteral("not text");
+            if (m_fault == Fault::ContentOnFailure) {
+                result.content = text;
+            }
+        } else {
+            result.status = bs::ExtractionResult::Status::Success;
+            result.content = text;
+        }
+        result.durationMs = static_cast<int>(timer.elapsed());
+        return result;
+    }
+
+    bool supports(const QString& extension) const override
+    {
+        return extension == QLatin1String("txt") || extension == QLatin1String("pdf");
+    }
+
+private:
+    Fault m_fault;
+    int m_calls = 0;
+};
+
+// A fixture of two files: text with a known layout and a "PDF" the faulty
+// extractor rejects.
+bool writeFaultFixture(const QString& dir)
+{
+    const QByteArray manifest = QByteArrayLiteral(
+        "{\"version\": 1, \"limits\": {\"determinismRuns\": 2}, \"files\": ["
+        " {\"path\": \"notes.txt\", \"contains\": [\"second\"], \"ordered\": [\"first\", \"second\"],"
+        "  \"positions\": [{\"text\": \"first\", \"offset\": 0, \"line\": 1},"
+        "                 {\"text\": \"second\", \"line\": 2}]},"
+        " {\"path\": \"broken.pdf\", \"status\": \"corrupted\"}]}");
+    return writeFile(QDir(dir).filePath(QStringLiteral("notes.txt")),
+                     QByteArrayLiteral("first line\nsecond line\n"))
+           && writeFile(QDir(dir).filePath(QStringLiteral("broken.pdf")),
+                        QByteArrayLiteral("%PDF-1.4 cut"))
+           && writeFile(QDir(dir).filePath(QString::fromLatin1(ExtractorConformance::kManifestName)),
+                        manifest);
+}
+
+QStringList failedChecks(const ConformanceReport& report)
+{
+    QStringList checks;
+    for (const bs::test::ConformanceFailure& failure : report.failures) {
+        if (!checks.contains(failure.check)) {
+            checks.append(failure.check);
+        }
+    }
+    return checks;
+}
+
+} // namespace
+
+class TestExtractorConformance : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testTextExtractorConforms();
+    void testPdfExtractorConforms();
+    void testFaultsAreCaught_data();
+    void testFaultsAreCaught();
+    void testCheckFields();
+    void testPositionMessages();
+    void testRejectsInvalidManifest();
+};
+
+void TestExtractorConformance::testTextExtractorConforms()
+{
+    QString error;
+    const auto suite = ExtractorConformance::loadSuite(fixtureDir(QStringLiteral("text")), &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+
+    bs::TextExtractor extractor;
+    const ConformanceReport report = ExtractorConformance::run(extractor, *suite);
+    QVERIFY2(report.passed(), qPrintable(ExtractorConformance::formatReport(report)));
+    QCOMPARE(report.files, static_cast<int>(suite->files.size()));
+    QVERIFY(report.extractions >= report.files * suite->limits.determinismRuns);
+}
+
+void TestExtractorConformance::testPdfExtractorConforms()
+{
+    QString error;
+    const auto suite = ExtractorConformance::loadSuite(fixtureDir(QStringLiteral("pdf")), &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+
+    bs::PdfExtractor extractor;
+    const auto probe = extractor.extract(QDir(suite->directory).filePath(suite->files.front().path));
+    if (probe.status == bs::ExtractionResult::Status::UnsupportedFormat) {
+        QSKIP("Built without Poppler");
+    }
+    const ConformanceReport report = ExtractorConformance::run(extractor, *suite);
+    QVERIFY2(report.passed(), qPrintable(ExtractorConformance::formatReport(report)));
+}
+
+void TestExtractorConformance::testFaultsAreCaught_data()
+{
+    QTest::addColumn<int>("fault");
+    QTest::addColumn<QString>("check");
+
+    using Fault = FaultyExtractor::Fault;
+    QTest::newRow("nondeterministic") << static_cast<int>(Fault::Nondeterministic)
+                                      << QStringLiteral("determinism");
+    QTest::newRow("shifted text") << static_cast<int>(Fault::ShiftedText)
+                                  << QStringLiteral("position");
+    QTest::newRow("content on failure") << static_cast<int>(Fault::ContentOnFailure)
+                                        << QStringLiteral("fields");
+    QTest::newRow("writes next to input") << static_cast<int>(Fault::WritesNextToInput)
+                                          << QStringLiteral("side effects");
+    QTest::newRow("finds a missing file") << static_cast<int>(Fault::FoundMissingFile)
+                                          << QStringLiteral("robustness");
+}
+
+void TestExtractorConformance::testFaultsAreCaught()
+{
+    QFETCH(int, fault);
+    QFETCH(QString, check);
+
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QVERIFY(writeFaultFixture(dir.path()));
+    QString error;
+    const auto suite = ExtractorConformance::loadSuite(dir.path(), &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+
+    FaultyExtractor sound(FaultyExtractor::Fault::None);
+    const ConformanceReport baseline = ExtractorConformance::run(sound, *suite);
+    QVERIFY2(baseline.passed(), qPrintable(ExtractorConformance::formatReport(baseline)));
+
+    FaultyExtractor faulty(static_cast<FaultyExtractor::Fault>(fault));
+    const ConformanceReport report = ExtractorConformance::run(faulty, *suite);
+    QVERIFY(!report.passed());
+    QVERIFY2(failedChecks(report).contains(check),
+             qPrintable(ExtractorConformance::formatReport(report)));
+}
+
+void TestExtractorConformance::testCheckFields()
+{
+    bs::ExtractionResult result;
+    result.status = bs::ExtractionResult::Status::Success;
+    result.content = QStringLiteral("text");
+    result.durationMs = 3;
+    QVERIFY(ExtractorConformance::checkFields(result, 10).empty());
+
+    // A duration longer than the call, and no content on success.
+    result.content.reset();
+    result.durationMs = 50;
+    QCOMPARE(ExtractorConformance::checkFields(result, 10).size(), size_t(2));
+
+    bs::ExtractionResult failed;
+    failed.status = bs::ExtractionResult::Status::CorruptedFile;
+    QCOMPARE(ExtractorConformance::checkFields(failed, 0).size(), size_t(1));
+    failed.errorMessage = QStringLiteral("bad xref");
+    QVERIFY(ExtractorConformance::checkFields(failed, 0).empty());
+
+    // Reserved for ExtractionManager and the sandbox.
+    failed.status = bs::ExtractionResult::Status::Crashed;
+    QCOMPARE(ExtractorConformance::checkFields(failed, 0).size(), size_t(1));
+    failed.status = bs::ExtractionResult::Status::CorruptedFile;
+    failed.redactedSecrets = 2;
+    QCOMPARE(ExtractorConformance::checkFields(failed, 0).size(), size_t(1));
+}
+
+void TestExtractorConformance::testPositionMessages()
+{
+    bs::test::ConformanceExpectation expected;
+    expected.path = QStringLiteral("a.txt");
+    expected.ordered = {QStringLiteral("beta"), QStringLiteral("alpha")};
+    expected.positions.push_back({QStringLiteral("beta"), 0, 1});
+    expected.positions.push_back({QStringLiteral("gamma"), -1, 2});
+
+    bs::ExtractionResult result;
+    result.status = bs::ExtractionResult::Status::Success;
+    result.content = QStringLiteral("alpha\nbeta 100%\n");
+    const auto failures = ExtractorConformance::checkContent(expected, result);
+    QCOMPARE(failures.size(), size_t(4));
+    QCOMPARE(failures[0].message, QStringLiteral("\"alpha\" does not follow \"beta\""));
+    QCOMPARE(failures[1].message, QStringLiteral("\"beta\": want offset 0, found at 6"));
+    QCOMPARE(failures[2].message, QStringLiteral("\"beta\": want line 1, found on line 2"));
+    QCOMPARE(failures[3].message, QStringLiteral("\"gamma\": want line 2, not found"));
+
+    expected.ordered.clear();
+    expected.positions.clear();
+    result.status = bs::ExtractionResult::Status::Timeout;
+    result.content.reset();
+    result.errorMessage = QStringLiteral("30 s");
+    const auto status = ExtractorConformance::checkContent(expected, result);
+    QCOMPARE(status.size(), size_t(1));
+    QCOMPARE(status[0].check, QStringLiteral("status"));
+    QCOMPARE(status[0].message, QStringLiteral("want success, got timeout (30 s)"));
+}
+
+void TestExtractorConformance::testRejectsInvalidManifest()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QVERIFY(writeFile(QDir(dir.path()).filePath(QStringLiteral("a.txt")), QByteArrayLiteral("a")));
+    QString error;
+
+    const QList<QByteArray> invalid = {
+        QByteArrayLiteral("[]"),
+        QByteArrayLiteral("{\"version\": 9, \"files\": [{\"path\": \"a.txt\"}]}"),
+        QByteArrayLiteral("{\"files\": []}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"missing.txt\"}]}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"../a.txt\"}]}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"a.txt\"}, {\"path\": \"./a.txt\"}]}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"a.txt\", \"status\": \"fine\"}]}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"a.txt\", \"positions\": [{\"text\": \"a\"}]}]}"),
+        QByteArrayLiteral("{\"files\": [{\"path\": \"a.txt\", \"status\": \"corrupted\","
+                          " \"contains\": [\"a\"]}]}"),
+        QByteArrayLiteral("{\"limits\": {\"determinismRuns\": 0}, \"files\": [{\"path\": \"a.txt\"}]}"),
+    };
+    for (const QByteArray& json : invalid) {
+        error.clear();
+        QVERIFY2(!ExtractorConformance::parseSuite(json, dir.path(), &error).has_value(),
+                 json.constData());
+        QVERIFY(!error.isEmpty());
+    }
+
+    const auto suite = ExtractorConformance::parseSuite(
+        QByteArrayLiteral("{\"files\": [{\"path\": \"a.txt\"}]}"), dir.path(), &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+    QCOMPARE(suite->files.front().status, bs::ExtractionResult::Status::Success);
+    QCOMPARE(suite->limits.determinismRuns, 3);
+}
+
+QTEST_MAIN(TestExtractorConformance)
+#include "test_extractor_conformance.moc"
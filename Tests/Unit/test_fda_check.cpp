@@ -0,0 +1,122 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/fda_check.h"
+
+#include <QDir>
+#include <QFile>
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+#include <unistd.h>
+
+class TestFdaCheck : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testProbeFolderClassifiesFailures();
+    void testMissingRootIsReported();
+    void testProtectedFoldersOnlyInsideReadableRoots();
+    void testReportJson();
+};
+
+void TestFdaCheck::testProbeFolderClassifiesFailures()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QCOMPARE(bs::FdaCheck::probeFolder(dir.path()), bs::FolderAccess::Readable);
+    QCOMPARE(bs::FdaCheck::probeFolder(dir.filePath(QStringLiteral("absent"))),
+             bs::FolderAccess::Missing);
+
+    const QString file = dir.filePath(QStringLiteral("plain.txt"));
+    QFile handle(file);
+    QVERIFY(handle.open(QIODevice::WriteOnly));
+    handle.close();
+    QCOMPARE(bs::FdaCheck::probeFolder(file), bs::FolderAccess::Missing);
+
+    if (geteuid() == 0) {
+        QSKIP("root ignores directory mode bits");
+    }
+    const QString locked = dir.filePath(QStringLiteral("locked"));
+    QVERIFY(QDir().mkpath(locked));
+    QVERIFY(QFile::setPermissions(locked, QFileDevice::Permissions()));
+    QCOMPARE(bs::FdaCheck::probeFolder(locked), bs::FolderAccess::PermissionDenied);
+    QFile::setPermissions(locked, QFileDevice::ReadOwner | QFileDevice::WriteOwner
+                                      | QFileDevice::ExeOwner);
+}
+
+void TestFdaCheck::testMissingRootIsReported()
+{
+    QTemporaryDir home;
+    QVERIFY(home.isValid());
+    const QString missing = home.filePath(QStringLiteral("Volumes/Archive"));
+
+    const bs::FileAccessReport report =
+        bs::FdaCheck::probeRoots({home.path(), missing + QStringLiteral("/")}, home.path());
+    QVERIFY(!report.fullDiskAccess);
+    QCOMPARE(report.unreadableRoots.size(), size_t(1));
+    QCOMPARE(report.unreadableRoots.front().path, missing);
+    QCOMPARE(report.unreadableRoots.front().reason, bs::FolderAccess::Missing);
+    // Absent protected folders are not blocked ones.
+    QVERIFY(report.protectedFolders.empty());
+    QVERIFY(report.blocksIndexing());
+}
+
+void TestFdaCheck::testProtectedFoldersOnlyInsideReadableRoots()
+{
+    if (geteuid() == 0) {
+        QSKIP("root ignores directory mode bits");
+    }
+    QTemporaryDir home;
+    QVERIFY(home.isValid());
+    const QString documents = home.filePath(QStringLiteral("Documents"));
+    QVERIFY(QDir().mkpath(documents));
+    QVERIFY(QDir().mkpath(home.filePath(QStringLiteral("Downloads"))));
+    QVERIFY(QDir().mkpath(home.filePath(QStringLiteral("Code"))));
+    QVERIFY(QFile::setPermissions(documents, QFileDevice::Permissions()));
+
+    const bs::FileAccessReport whole = bs::FdaCheck::probeRoots({home.path()}, home.path());
+    QVERIFY(whole.unreadableRoots.empty());
+    QCOMPARE(whole.protectedFolders.size(), size_t(1));
+    QCOMPARE(whole.protectedFolders.front().path, documents);
+    QCOMPARE(whole.protectedFolders.front().reason, bs::FolderAccess::PermissionDenied);
+
+    // Documents is not under ~/Code, so it does not matter there.
+    const bs::FileAccessReport code =
+        bs::FdaCheck::probeRoots({home.filePath(QStringLiteral("Code"))}, home.path());
+    QVERIFY(!code.blocksIndexing());
+
+    QFile::setPermissions(documents, QFileDevice::ReadOwner | QFileDevice::WriteOwner
+                                         | QFileDevice::ExeOwner);
+}
+
+void TestFdaCheck::testReportJson()
+{
+    bs::FileAccessReport report;
+    report.fullDiskAccess = false;
+    report.unreadableRoots.push_back({QStringLiteral("/Volumes/Archive"),
+                                      bs::FolderAccess::Missing});
+    report.protectedFolders.push_back({QStringLiteral("/Users/test/Documents"),
+                                       bs::FolderAccess::PrivacyDenied});
+
+    const QJsonObject json = report.toJson();
+    QCOMPARE(json.value(QStringLiteral("fullDiskAccess")).toBool(true), false);
+    const QJsonObject root = json.value(QStringLiteral("unreadableRoots")).toArray()
+                                 .first().toObject();
+    QCOMPARE(root.value(QStringLiteral("path")).toString(), QStringLiteral("/Volumes/Archive"));
+    QCOMPARE(root.value(QStringLiteral("reason")).toString(), QStringLiteral("missing"));
+    QCOMPARE(json.value(QStringLiteral("protectedFolders")).toArray().first().toObject()
+                 .value(QStringLiteral("reason")).toString(),
+             QStringLiteral("privacy_denied"));
+
+    const QStringList steps =
+        json.value(QStringLiteral("remediation")).toString().split(QLatin1Char('\n'));
+    QCOMPARE(steps.size(), qsizetype(2));
+    QVERIFY(steps.at(0).contains(QStringLiteral("Full Disk Access")));
+    QVERIFY(steps.at(1).contains(QStringLiteral("Reconnect")));
+
+    QVERIFY(bs::FileAccessReport().toJson().value(QStringLiteral("remediation"))
+                .toString().isEmpty());
+}
+
+QTEST_MAIN(TestFdaCheck)
+#include "test_fda_check.moc"
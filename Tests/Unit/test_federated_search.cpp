@@ -0,0 +1,272 @@
+#include <QtTest/QtTest>
+
+#include "core/ipc/http_server.h"
+#include "core/query/federated_search.h"
+
+#include <QHostAddress>
+#include <QJsonDocument>
+#include <QTcpServer>
+
+namespace {
+
+QJsonObject row(int64_t itemId, const QString& path)
+{
+    QJsonObject object;
+    object[QStringLiteral("itemId")] = static_cast<qint64>(itemId);
+    object[QStringLiteral("path")] = path;
+    object[QStringLiteral("name")] = path.mid(path.lastIndexOf(QLatin1Char('/')) + 1);
+    return object;
+}
+
+QJsonObject searchResult(const QJsonArray& rows, qint64 totalMatches)
+{
+    QJsonObject result;
+    result[QStringLiteral("results")] = rows;
+    result[QStringLiteral("totalMatches")] = totalMatches;
+    result[QStringLiteral("queryTime")] = 12;
+    return result;
+}
+
+bs::FederatedSource answered(const QString& name, const QJsonObject& result)
+{
+    bs::FederatedSource source;
+    source.name = name;
+    source.reply.json = result;
+    source.reply.elapsedMs = 40;
+    return source;
+}
+
+} // namespace
+
+class TestFederatedSearch : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseIndexes();
+    void testParseIndexesRejectsInvalid();
+    void testRemoteParams();
+    void testMergeInterleavesByRank();
+    void testMergeLabelsRemoteRows();
+    void testMergeDropsReplicasOfLiveResults();
+    void testMergeReportsFailedSources();
+    void testFetchAllPostsConcurrently();
+    void testFetchAllTimesOut();
+};
+
+void TestFederatedSearch::testParseIndexes()
+{
+    QString error;
+    auto none = bs::FederatedSearch::parseIndexes(QString(), &error);
+    QVERIFY(none.has_value());
+    QVERIFY(none->empty());
+
+    const QString json = QStringLiteral(
+        R"([{"name": "desktop", "url": "https://desktop.local:8765", "token": "bst_a",
+             "fingerprint": "AB:CD"},
+            {"name": "nas", "url": "http://127.0.0.1:9000", "token": "bst_b", "timeoutMs": 50},
+            {"name": "laptop", "url": "https://laptop.local", "token": "bst_c",
+             "timeoutMs": 60000}])");
+    auto indexes = bs::FederatedSearch::parseIndexes(json, &error);
+    QVERIFY2(indexes.has_value(), qPrintable(error));
+    QCOMPARE(indexes->size(), size_t(3));
+    QCOMPARE(indexes->at(0).name, QStringLiteral("desktop"));
+    QCOMPARE(indexes->at(0).endpoint.token, QByteArray("bst_a"));
+    QCOMPARE(indexes->at(0).endpoint.fingerprint, QStringLiteral("AB:CD"));
+    QCOMPARE(indexes->at(0).timeoutMs, bs::FederatedSearch::kDefaultTimeoutMs);
+    QCOMPARE(indexes->at(1).timeoutMs, bs::FederatedSearch::kMinTimeoutMs);
+    QCOMPARE(indexes->at(2).timeoutMs, bs::FederatedSearch::kMaxTimeoutMs);
+}
+
+void TestFederatedSearch::testParseIndexesRejectsInvalid()
+{
+    const QStringList invalid = {
+        QStringLiteral("{}"),
+        QStringLiteral(R"([{"name": "desk top", "url": "https://a.local", "token": "t"}])"),
+        QStringLiteral(R"([{"name": "local", "url": "https://a.local", "token": "t"}])"),
+        QStringLiteral(R"([{"name": "a", "url": "https://a.local", "token": "t"},
+                           {"name": "A", "url": "https://b.local", "token": "t"}])"),
+        QStringLiteral(R"([{"name": "a", "url": "http://a.local", "token": "t"}])"),
+        QStringLiteral(R"([{"name": "a", "url": "https://a.local"}])"),
+    };
+    for (const QString& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::FederatedSearch::parseIndexes(json, &error).has_value(), qPrintable(json));
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestFederatedSearch::testRemoteParams()
+{
+    QJsonObject params;
+    params[QStringLiteral("query")] = QStringLiteral("budget");
+    params[QStringLiteral("limit")] = 500;
+    params[QStringLiteral("scope")] = QStringLiteral("clienta");
+    params[QStringLiteral("context")] = QJsonObject{{QStringLiteral("cwdPath"), QStringLiteral("/tmp")}};
+    params[QStringLiteral("deadlineMs")] = 1790000000000;
+    params[QStringLiteral("private")] = true;
+
+    const QJsonObject remote = bs::FederatedSearch::remoteParams(params);
+    QCOMPARE(remote.value(QStringLiteral("query")).toString(), QStringLiteral("budget"));
+    QCOMPARE(remote.value(QStringLiteral("limit")).toInt(), 200);
+    QCOMPARE(remote.value(QStringLiteral("federated")).toBool(true), false);
+    QVERIFY(remote.value(QStringLiteral("private")).toBool());
+    QVERIFY(!remote.contains(QStringLiteral("scope")));
+    QVERIFY(!remote.contains(QStringLiteral("context")));
+    QVERIFY(!remote.contains(QStringLiteral("deadlineMs")));
+    QCOMPARE(bs::FederatedSearch::limitOf(QJsonObject()), 20);
+}
+
+void TestFederatedSearch::testMergeInterleavesByRank()
+{
+    const QJsonObject local = searchResult(
+        {row(1, QStringLiteral("/Users/a/one.txt")), row(2, QStringLiteral("/Users/a/two.txt")),
+         row(3, QStringLiteral("/Users/a/three.txt"))}, 3);
+    const QJsonObject remote = searchResult(
+        {row(10, QStringLiteral("/Users/b/ten.txt")), row(11, QStringLiteral("/Users/b/eleven.txt"))}, 7);
+
+    const QJsonObject merged = bs::FederatedSearch::merge(
+        local, 12, {answered(QStringLiteral("desktop"), remote)}, 4);
+    const QJsonArray results = merged.value(QStringLiteral("results")).toArray();
+    QCOMPARE(results.size(), 4);
+    QCOMPARE(results.at(0).toObject().value(QStringLiteral("itemId")).toInteger(), 1);
+    QCOMPARE(results.at(1).toObject().value(QStringLiteral("sourceItemId")).toInteger(), 10);
+    QCOMPARE(results.at(2).toObject().value(QStringLiteral("itemId")).toInteger(), 2);
+    QCOMPARE(results.at(3).toObject().value(QStringLiteral("sourceItemId")).toInteger(), 11);
+    QCOMPARE(merged.value(QStringLiteral("totalMatches")).toInteger(), 10);
+    QCOMPARE(merged.value(QStringLiteral("queryTime")).toInt(), 12);
+}
+
+void TestFederatedSearch::testMergeLabelsRemoteRows()
+{
+    const QJsonObject remote = searchResult(
+        {row(10, QStringLiteral("/Users/b/Documents/plan.md")),
+         row(11, QStringLiteral("donated://com.apple.mail/42"))}, 2);
+    const QJsonObject merged = bs::FederatedSearch::merge(
+        searchResult({row(1, QStringLiteral("/Users/a/one.txt"))}, 1), 5,
+        {answered(QStringLiteral("desktop"), remote)}, 20);
+    const QJsonArray results = merged.value(QStringLiteral("results")).toArray();
+    QCOMPARE(results.size(), 2);
+
+    const QJsonObject localRow = results.at(0).toObject();
+    QCOMPARE(localRow.value(QStringLiteral("source")).toString(), QStringLiteral("local"));
+    QVERIFY(!localRow.contains(QStringLiteral("peer")));
+
+    const QJsonObject remoteRow = results.at(1).toObject();
+    QCOMPARE(remoteRow.value(QStringLiteral("source")).toString(), QStringLiteral("desktop"));
+    QCOMPARE(remoteRow.value(QStringLiteral("itemId")).toInteger(), 0);
+    QCOMPARE(remoteRow.value(QStringLiteral("peer")).toString(), QStringLiteral("desktop"));
+    QCOMPARE(remoteRow.value(QStringLiteral("peerPath")).toString(),
+             QStringLiteral("/Users/b/Documents/plan.md"));
+    QCOMPARE(remoteRow.value(QStringLiteral("contentDescription")).toString(),
+             QStringLiteral("desktop · /Users/b/Documents"));
+}
+
+void TestFederatedSearch::testMergeDropsReplicasOfLiveResults()
+{
+    QJsonObject replica = row(5, QStringLiteral("donated://com.betterspotlight.peer.desktop/10"));
+    replica[QStringLiteral("peer")] = QStringLiteral("desktop");
+    replica[QStringLiteral("peerPath")] = QStringLiteral("/Users/b/plan.md");
+    QJsonObject otherReplica = row(6, QStringLiteral("donated://com.betterspotlight.peer.desktop/12"));
+    otherReplica[QStringLiteral("peer")] = QStringLiteral("desktop");
+    otherReplica[QStringLiteral("peerPath")] = QStringLiteral("/Users/b/old.md");
+
+    const QJsonObject merged = bs::FederatedSearch::merge(
+        searchResult({replica, otherReplica}, 2), 5,
+        {answered(QStringLiteral("Desktop"),
+                  searchResult({row(10, QStringLiteral("/Users/b/plan.md"))}, 1))}, 20);
+    const QJsonArray results = merged.value(QStringLiteral("results")).toArray();
+    QCOMPARE(results.size(), 2);
+    QCOMPARE(results.at(0).toObject().value(QStringLiteral("itemId")).toInteger(), 6);
+    QCOMPARE(results.at(1).toObject().value(QStringLiteral("sourceItemId")).toInteger(), 10);
+}
+
+void TestFederatedSearch::testMergeReportsFailedSources()
+{
+    bs::FederatedSource slow;
+    slow.name = QStringLiteral("nas");
+    slow.reply.timedOut = true;
+    slow.reply.elapsedMs = 800;
+    slow.reply.error = QStringLiteral("nas.local did not answer within 800 ms");
+    bs::FederatedSource denied;
+    denied.name = QStringLiteral("laptop");
+    denied.reply.error = QStringLiteral("laptop.local answered HTTP 401: Invalid token");
+
+    const QJsonObject merged = bs::FederatedSearch::merge(
+        searchResult({row(1, QStringLiteral("/Users/a/one.txt"))}, 1), 9, {slow, denied}, 20);
+    QCOMPARE(merged.value(QStringLiteral("results")).toArray().size(), 1);
+    QCOMPARE(merged.value(QStringLiteral("totalMatches")).toInteger(), 1);
+
+    const QJsonArray sources = merged.value(QStringLiteral("sources")).toArray();
+    QCOMPARE(sources.size(), 3);
+    QCOMPARE(sources.at(0).toObject().value(QStringLiteral("name")).toString(), QStringLiteral("local"));
+    QCOMPARE(sources.at(0).toObject().value(QStringLiteral("status")).toString(), QStringLiteral("ok"));
+    QCOMPARE(sources.at(0).toObject().value(QStringLiteral("elapsedMs")).toInteger(), 9);
+    QCOMPARE(sources.at(1).toObject().value(QStringLiteral("status")).toString(),
+             QStringLiteral("timeout"));
+    QCOMPARE(sources.at(2).toObject().value(QStringLiteral("status")).toString(),
+             QStringLiteral("error"));
+    QVERIFY(sources.at(2).toObject().value(QStringLiteral("error")).toString().contains(
+        QStringLiteral("401")));
+}
+
+void TestFederatedSearch::testFetchAllPostsConcurrently()
+{
+    bs::HttpServer server;
+    int searches = 0;
+    server.route(QStringLiteral("POST"), QStringLiteral("/v1/search"),
+                 [&searches](const bs::HttpRequest& request) {
+        ++searches;
+        const QJsonObject params = QJsonDocument::fromJson(request.body).object();
+        return bs::HttpResponse::json(
+            200, searchResult({row(searches, QStringLiteral("/Users/b/%1.txt")
+                                                  .arg(params.value(QStringLiteral("query")).toString()))},
+                              1));
+    });
+    QVERIFY(server.listen(QHostAddress::LocalHost, 0));
+
+    bs::PeerRequest request;
+    request.peer.baseUrl = QUrl(QStringLiteral("http://127.0.0.1:%1").arg(server.serverPort()));
+    request.peer.token = QByteArrayLiteral("bst_abc");
+    request.path = QStringLiteral("/v1/search");
+    request.body = QByteArrayLiteral(R"({"query": "plan"})");
+    request.timeoutMs = 5000;
+    bs::PeerRequest refused = request;
+    refused.peer.baseUrl = QUrl(QStringLiteral("http://desktop.local:8765"));
+
+    const std::vector<bs::PeerReply> replies = bs::PeerClient::fetchAll({request, refused, request});
+    QCOMPARE(replies.size(), size_t(3));
+    QVERIFY2(replies[0].json.has_value(), qPrintable(replies[0].error));
+    QVERIFY(replies[2].json.has_value());
+    QVERIFY(!replies[1].json.has_value());
+    QVERIFY(!replies[1].error.isEmpty());
+    QCOMPARE(searches, 2);
+    const QJsonObject first =
+        replies[0].json->value(QStringLiteral("results")).toArray().at(0).toObject();
+    QVERIFY(first.value(QStringLiteral("path")).toString().endsWith(QStringLiteral("/plan.txt")));
+}
+
+void TestFederatedSearch::testFetchAllTimesOut()
+{
+    // Accepts connections (the OS backlog does) and never answers.
+    QTcpServer silent;
+    QVERIFY(silent.listen(QHostAddress::LocalHost, 0));
+
+    bs::PeerRequest request;
+    request.peer.baseUrl = QUrl(QStringLiteral("http://127.0.0.1:%1").arg(silent.serverPort()));
+    request.peer.token = QByteArrayLiteral("bst_abc");
+    request.path = QStringLiteral("/v1/search");
+    request.body = QByteArrayLiteral(R"({"query": "plan"})");
+    request.timeoutMs = 200;
+
+    QElapsedTimer timer;
+    timer.start();
+    const std::vector<bs::PeerReply> replies = bs::PeerClient::fetchAll({request});
+    QVERIFY(timer.elapsed() < 5000);
+    QCOMPARE(replies.size(), size_t(1));
+    QVERIFY(!replies[0].json.has_value());
+    QVERIFY(replies[0].timedOut);
+    QVERIFY(replies[0].elapsedMs >= 200);
+}
+
+QTEST_MAIN(TestFederatedSearch)
+#include "test_federated_search.moc"
@@ -0,0 +1,75 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/finder_tags.h"
+
+#include <QFile>
+#include <QTemporaryDir>
+
+class TestFinderTags : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testEntryName();
+    void testParsePlist();
+    void testParseRejectsNonArray();
+    void testReadUntaggedAndMissing();
+};
+
+void TestFinderTags::testEntryName()
+{
+    QCOMPARE(bs::FinderTags::entryName(QStringLiteral("Red\n6")), QStringLiteral("Red"));
+    QCOMPARE(bs::FinderTags::entryName(QStringLiteral("Needs Review")),
+             QStringLiteral("Needs Review"));
+    QCOMPARE(bs::FinderTags::entryName(QStringLiteral("  urgent \n0")), QStringLiteral("urgent"));
+}
+
+void TestFinderTags::testParsePlist()
+{
+    const QByteArray xml = R"(<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<array>
+    <string>Red
+6</string>
+    <string>Needs Review</string>
+    <string>red
+6</string>
+    <string>
+0</string>
+</array>
+</plist>
+)";
+    const auto tags = bs::FinderTags::parse(xml);
+    QVERIFY(tags.has_value());
+    QCOMPARE(tags.value(), QStringList({QStringLiteral("Red"), QStringLiteral("Needs Review")}));
+}
+
+void TestFinderTags::testParseRejectsNonArray()
+{
+    const QByteArray xml = R"(<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><string>Red</string></plist>
+)";
+    QVERIFY(!bs::FinderTags::parse(xml).has_value());
+    QVERIFY(!bs::FinderTags::parse(QByteArrayLiteral("not a plist")).has_value());
+    QVERIFY(bs::FinderTags::parse(QByteArray()).value().isEmpty());
+}
+
+void TestFinderTags::testReadUntaggedAndMissing()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.path() + QStringLiteral("/untagged.txt");
+    QFile file(path);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.write("plain");
+    file.close();
+
+    const auto tags = bs::FinderTags::read(path);
+    QVERIFY(tags.has_value());
+    QVERIFY(tags->isEmpty());
+
+    QVERIFY(!bs::FinderTags::read(dir.path() + QStringLiteral("/missing.txt")).has_value());
+}
+
+QTEST_MAIN(TestFinderTags)
+#include "test_finder_tags.moc"
@@ -0,0 +1,311 @@
+#include <QtTest/QtTest>
+
+#include "fixture_home.h"
+#include "core/fs/extended_attributes.h"
+#include "core/fs/finder_tags.h"
+#include "core/fs/path_rules.h"
+#include "core/indexing/mail_source.h"
+
+#include <QDir>
+#include <QDirIterator>
+#include <QFile>
+#include <QFileInfo>
+#include <QTemporaryDir>
+#include <QTimeZone>
+
+namespace {
+
+// Relative path -> content and modification time of every file under `root`.
+QMap<QString, QPair<QByteArray, qint64>> readTree(const QString& root)
+{
+    QMap<QString, QPair<QByteArray, qint64>> tree;
+    QDirIterator it(root, QDir::Files | QDir::Hidden, QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QString path = it.next();
+        QFile file(path);
+        if (file.open(QIODevice::ReadOnly)) {
+            tree.insert(QDir(root).relativeFilePath(path),
+                        {file.readAll(), QFileInfo(path).lastModified().toSecsSinceEpoch()});
+        }
+    }
+    return tree;
+}
+
+qint64 modifiedSecs(const QString& path)
+{
+    return QFileInfo(path).lastModified().toSecsSinceEpoch();
+}
+
+} // namespace
+
+class TestFixtureHome : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testStandardHomeV2();
+    void testTimestampsFollowReference();
+    void testExtendedAttributes();
+    void testMailArchiveReadsAsMail();
+    void testDevProjectExclusions();
+    void testCloudPlaceholders();
+    void testBuildIsDeterministic();
+    void testRejectsInvalid();
+};
+
+void TestFixtureHome::testStandardHomeV2()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    const auto builder = bs::test::FixtureHomeBuilder::standardHomeV2();
+    const auto report = builder.build(dir.path(), &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+
+    QCOMPARE(report->corpus.files, int(builder.files().size()));
+    const QStringList modules = report->moduleFiles.keys();
+    for (const char* module : {"documents", "devProject:orbit", "devProject:ledger",
+                               "devProject:quasar", "photoLibrary", "mailArchive",
+                               "cloudPlaceholders"}) {
+        QVERIFY2(modules.contains(QLatin1String(module)), module);
+    }
+    for (const QStringList& paths : report->moduleFiles) {
+        for (const QString& path : paths) {
+            QVERIFY2(QFileInfo::exists(dir.filePath(path)), qPrintable(path));
+        }
+    }
+    QVERIFY(report->xattrs > 10);
+}
+
+void TestFixtureHome::testTimestampsFollowReference()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QDateTime reference(QDate(2030, 6, 1), QTime(12, 0), QTimeZone::UTC);
+    bs::test::FixtureHomeBuilder builder(1, reference);
+    builder.documents().photoLibrary(4);
+    QString error;
+    QVERIFY2(builder.build(dir.path(), &error), qPrintable(error));
+
+    QCOMPARE(builder.ago(14), reference.addDays(-14));
+    QCOMPARE(modifiedSecs(dir.filePath(QStringLiteral("Documents/meeting-notes-jan.md"))),
+             reference.addDays(-14).toSecsSinceEpoch());
+    QCOMPARE(modifiedSecs(dir.filePath(QStringLiteral("Desktop/todo-list.md"))),
+             reference.addSecs(-3600).toSecsSinceEpoch());
+    // Camera-roll photos are a week apart, the last the newest.
+    const qint64 first = modifiedSecs(dir.filePath(QStringLiteral("Pictures/Camera Roll/IMG_0001.jpg")));
+    const qint64 last = modifiedSecs(dir.filePath(QStringLiteral("Pictures/Camera Roll/IMG_0004.jpg")));
+    QCOMPARE(last - first, qint64(3 * 7 * 86400));
+    QVERIFY(last < reference.toSecsSinceEpoch());
+    for (const bs::test::FixtureFile& file : builder.files()) {
+        if (file.entry.kind != bs::test::CorpusEntryKind::Directory) {
+            QCOMPARE(modifiedSecs(dir.filePath(file.entry.path)),
+                     file.entry.modifiedAt.toSecsSinceEpoch());
+        }
+    }
+}
+
+void TestFixtureHome::testExtendedAttributes()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::FixtureHomeBuilder builder;
+    builder.documents();
+    bs::test::FixtureFile custom;
+    custom.entry.path = QStringLiteral("Documents/stamped.txt");
+    custom.entry.text = QStringLiteral("stamped\n");
+    custom.entry.modifiedAt = builder.ago(2);
+    custom.xattrs.insert(QStringLiteral("com.example.batch"), QByteArrayLiteral("batch-42"));
+    builder.file(custom);
+    QString error;
+    const auto report = builder.build(dir.path(), &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+    QCOMPARE(report->moduleFiles.value(QStringLiteral("custom")),
+             QStringList({QStringLiteral("Documents/stamped.txt")}));
+
+    const QString notes = dir.filePath(QStringLiteral("Documents/meeting-notes-jan.md"));
+    QCOMPARE(bs::FinderTags::read(notes),
+             std::optional<QStringList>(QStringList({QStringLiteral("Work"),
+                                                     QStringLiteral("Important")})));
+    const auto comment = bs::ExtendedAttributes::readFinderComment(notes);
+    QCOMPARE(comment.size(), size_t(1));
+    QCOMPARE(comment[0].value, QStringLiteral("Follow up with the marketing team"));
+    QCOMPARE(bs::FinderTags::read(dir.filePath(QStringLiteral("Documents/travel-itinerary.txt"))),
+             std::optional<QStringList>(QStringList()));
+
+    const QString invoice = dir.filePath(QStringLiteral("Downloads/invoice-january-2026.pdf"));
+    const auto origins = bs::ExtendedAttributes::read(
+        invoice, {QString::fromLatin1(bs::test::FixtureHomeBuilder::kWhereFromsXattr),
+                  QString::fromLatin1(bs::test::FixtureHomeBuilder::kQuarantineXattr)});
+    QCOMPARE(origins.size(), size_t(2));
+    QVERIFY(origins[0].value.contains(QStringLiteral("https://billing.example.com/invoices/2026-01.pdf")));
+    QVERIFY(origins[1].value.startsWith(QStringLiteral("0083;")));
+    QVERIFY(origins[1].value.endsWith(QStringLiteral(";Safari;")));
+
+    const auto batch = bs::ExtendedAttributes::read(dir.filePath(custom.entry.path),
+                                                    {QStringLiteral("com.example.batch")});
+    QCOMPARE(batch.size(), size_t(1));
+    QCOMPARE(batch[0].value, QStringLiteral("batch-42"));
+    // Setting the attributes left the times alone.
+    QCOMPARE(modifiedSecs(invoice), builder.ago(3).toSecsSinceEpoch());
+}
+
+void TestFixtureHome::testMailArchiveReadsAsMail()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::FixtureHomeBuilder builder;
+    builder.mailArchive(9);
+    QString error;
+    QVERIFY2(builder.build(dir.path(), &error), qPrintable(error));
+
+    const QString store = bs::MailSource::storePath(dir.path());
+    QVERIFY(store.endsWith(QStringLiteral("/Library/Mail/V10")));
+    const auto mailboxes = bs::MailSource::mailboxes(store);
+    QCOMPARE(mailboxes.size(), size_t(2));
+    QCOMPARE(mailboxes[0].name, QStringLiteral("Archive"));
+    QCOMPARE(mailboxes[1].name, QStringLiteral("INBOX"));
+
+    int messages = 0;
+    for (const bs::MailMailbox& mailbox : mailboxes) {
+        for (const QString& path : bs::MailSource::messageFiles(mailbox)) {
+            QFile file(path);
+            QVERIFY(file.open(QIODevice::ReadOnly));
+            const auto message = bs::MailSource::parseEmlx(file.readAll());
+            QVERIFY2(message.has_value(), qPrintable(path));
+            QVERIFY(!message->subject.isEmpty());
+            QVERIFY(!message->body.isEmpty());
+            QCOMPARE(message->to, QStringList({QStringLiteral("Rex Example <rex@example.com>")}));
+            QCOMPARE(qint64(message->date), modifiedSecs(path));
+            ++messages;
+        }
+    }
+    QCOMPARE(messages, 9);
+    QCOMPARE(bs::MailSource::messageFiles(mailboxes[0]).size(), qsizetype(2));
+}
+
+void TestFixtureHome::testDevProjectExclusions()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::FixtureHomeBuilder builder;
+    builder.devProject(QStringLiteral("orbit"), QStringLiteral("ts"))
+        .devProject(QStringLiteral("quasar"), QStringLiteral("rs"))
+        .devProject(QStringLiteral("engine"), QStringLiteral("cpp"));
+    QString error;
+    QVERIFY2(builder.build(dir.path(), &error), qPrintable(error));
+
+    const bs::PathRules rules;
+    const auto validate = [&](const QString& relative) {
+        QVERIFY2(QFileInfo::exists(dir.filePath(relative)), qPrintable(relative));
+        return rules.validate(dir.filePath(relative).toStdString());
+    };
+    QCOMPARE(validate(QStringLiteral("Developer/orbit/src/server.ts")), bs::ValidationResult::Include);
+    QCOMPARE(validate(QStringLiteral("Developer/orbit/README.md")), bs::ValidationResult::Include);
+    QCOMPARE(validate(QStringLiteral("Developer/orbit/node_modules/left-pad/index.js")),
+             bs::ValidationResult::Exclude);
+    QCOMPARE(validate(QStringLiteral("Developer/orbit/dist/index.js")), bs::ValidationResult::Exclude);
+    QCOMPARE(validate(QStringLiteral("Developer/quasar/target/debug/quasar")),
+             bs::ValidationResult::Exclude);
+    QCOMPARE(validate(QStringLiteral("Developer/engine/build/engine")), bs::ValidationResult::Exclude);
+    QCOMPARE(validate(QStringLiteral("Developer/engine/.git/index")), bs::ValidationResult::Exclude);
+
+    QFile source(dir.filePath(QStringLiteral("Developer/quasar/src/main.rs")));
+    QVERIFY(source.open(QIODevice::ReadOnly));
+    QVERIFY(source.readAll().contains("quasar"));
+}
+
+void TestFixtureHome::testCloudPlaceholders()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::FixtureHomeBuilder builder;
+    builder.cloudPlaceholders();
+    QString error;
+    const auto report = builder.build(dir.path(), &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+
+    const bs::PathRules rules;
+    int artifacts = 0;
+    for (const QString& relative : report->moduleFiles.value(QStringLiteral("cloudPlaceholders"))) {
+        const std::string path = dir.filePath(relative).toStdString();
+        QVERIFY2(rules.isCloudFolder(path), qPrintable(relative));
+        const QString name = QFileInfo(relative).fileName();
+        const bool stub = name.endsWith(QLatin1String(".icloud"))
+                          || name == QLatin1String(".icloud_folder_attributes.plist")
+                          || name == QLatin1String("OneDrive_folder_placeholder.ini");
+        QCOMPARE(rules.isCloudArtifact(path), stub);
+        artifacts += stub ? 1 : 0;
+    }
+    QCOMPARE(artifacts, 5);
+
+    QFile stub(dir.filePath(QStringLiteral(
+        "Library/Mobile Documents/com~apple~CloudDocs/Documents/.tax-return-2024.pdf.icloud")));
+    QVERIFY(stub.open(QIODevice::ReadOnly));
+    QVERIFY(stub.readAll().contains("<string>tax-return-2024.pdf</string>"));
+}
+
+void TestFixtureHome::testBuildIsDeterministic()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    const auto builder = bs::test::FixtureHomeBuilder::standardHomeV2();
+    QVERIFY(builder.build(dir.filePath(QStringLiteral("a")), &error));
+    QVERIFY(builder.build(dir.filePath(QStringLiteral("b")), &error));
+    QCOMPARE(readTree(dir.filePath(QStringLiteral("a"))), readTree(dir.filePath(QStringLiteral("b"))));
+
+    // A module added to the mix leaves the others' files as they were.
+    bs::test::FixtureHomeBuilder smaller;
+    smaller.documents();
+    QVERIFY(smaller.build(dir.filePath(QStringLiteral("c")), &error));
+    const auto full = readTree(dir.filePath(QStringLiteral("a")));
+    const auto documents = readTree(dir.filePath(QStringLiteral("c")));
+    for (auto it = documents.cbegin(); it != documents.cend(); ++it) {
+        QCOMPARE(full.value(it.key()), it.value());
+    }
+
+    bs::test::FixtureHomeBuilder reseeded(2);
+    reseeded.documents();
+    QVERIFY(reseeded.build(dir.filePath(QStringLiteral("d")), &error));
+    QVERIFY(readTree(dir.filePath(QStringLiteral("d"))) != documents);
+}
+
+void TestFixtureHome::testRejectsInvalid()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+
+    bs::test::FixtureHomeBuilder unknown;
+    unknown.devProject(QStringLiteral("orbit"), QStringLiteral("cobol"));
+    QVERIFY(!unknown.build(dir.filePath(QStringLiteral("a")), &error));
+    QVERIFY(error.contains(QStringLiteral("cobol")));
+
+    bs::test::FixtureHomeBuilder empty;
+    QVERIFY(!empty.photoLibrary(0).build(dir.filePath(QStringLiteral("b")), &error));
+
+    bs::test::FixtureHomeBuilder twice;
+    twice.documents().documents();
+    QVERIFY(!twice.build(dir.filePath(QStringLiteral("c")), &error));
+    QVERIFY(error.contains(QStringLiteral("twice")));
+
+    bs::test::FixtureFile numbered;
+    numbered.entry.path = QStringLiteral("Documents/notes-{n}.md");
+    bs::test::FixtureHomeBuilder counted;
+    QVERIFY(!counted.file(numbered).build(dir.filePath(QStringLiteral("d")), &error));
+
+    bs::test::FixtureFile climbing;
+    climbing.entry.path = QStringLiteral("../outside.txt");
+    bs::test::FixtureHomeBuilder outside;
+    QVERIFY(!outside.file(climbing).build(dir.filePath(QStringLiteral("e")), &error));
+    QVERIFY(!QFileInfo::exists(dir.filePath(QStringLiteral("outside.txt"))));
+
+    bs::test::FixtureHomeBuilder documents;
+    documents.documents();
+    QVERIFY(documents.build(dir.filePath(QStringLiteral("f")), &error));
+    QVERIFY(!documents.build(dir.filePath(QStringLiteral("f")), &error));
+    QVERIFY(error.contains(QStringLiteral("not empty")));
+}
+
+QTEST_MAIN(TestFixtureHome)
+#include "test_fixture_home.moc"
@@ -0,0 +1,265 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/file_monitor_macos.h"
+#include "core/fs/fsevent_recording.h"
+#include "fsevent_replay.h"
+
+#include <QDir>
+#include <QFile>
+#include <QTemporaryDir>
+
+namespace {
+
+using bs::FsEventRecord;
+using bs::FsEventRecorder;
+using bs::FsEventRecording;
+using bs::WorkItem;
+using bs::test::FsEventReplayer;
+using bs::test::FsEventReplayResult;
+
+QString fixturePath(const QString& name)
+{
+#ifdef BETTERSPOTLIGHT_SOURCE_DIR
+    return QDir(QString::fromUtf8(BETTERSPOTLIGHT_SOURCE_DIR))
+        .filePath(QStringLiteral("Tests/Fixtures/fsevents/") + name);
+#else
+    Q_UNUSED(name);
+    return QString();
+#endif
+}
+
+FsEventRecord event(uint64_t id, uint32_t flags, const char* path, bool exists,
+                    bool isDirectory = false)
+{
+    FsEventRecord record;
+    record.eventId = id;
+    record.flags = flags;
+    record.path = path;
+    record.exists = exists;
+    record.isDirectory = isDirectory;
+    if (exists) {
+        record.modTime = 1760000000;
+        record.size = 42;
+    }
+    return record;
+}
+
+} // namespace
+
+class TestFsEventReplay : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRecorderRoundTrip();
+    void testParseRejectsMalformedRecordings();
+    void testTranslateEvents();
+    void testBurstIsCoalesced();
+    void testReplayIsDeterministic();
+    void testRenameWithinRoot();
+};
+
+void TestFsEventReplay::testRecorderRoundTrip()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("stream.ndjson"));
+
+    FsEventRecorder recorder;
+    recorder.append({event(1, kFSEventStreamEventFlagItemCreated, "/ignored", true)});
+    QString error;
+    QVERIFY2(recorder.open(path, {"/Users/test", "/Volumes/Work"}, &error), qPrintable(error));
+    QVERIFY(recorder.isOpen());
+    recorder.append({event(7, kFSEventStreamEventFlagItemCreated | kFSEventStreamEventFlagItemIsFile,
+                           "/Users/test/a.txt", true)});
+    recorder.append({});
+    recorder.append({event(8, kFSEventStreamEventFlagItemRemoved, "/Users/test/a.txt", false),
+                     event(9, kFSEventStreamEventFlagItemIsDir, "/Users/test/dir", true, true)});
+    recorder.close();
+    QVERIFY(!recorder.isOpen());
+
+    const std::optional<FsEventRecording> recording = FsEventRecorder::load(path, &error);
+    QVERIFY2(recording.has_value(), qPrintable(error));
+    QCOMPARE(recording->roots, (std::vector<std::string>{"/Users/test", "/Volumes/Work"}));
+    // Nothing before open(), and no line for an empty callback.
+    QCOMPARE(recording->batches.size(), size_t(2));
+    QVERIFY(recording->batches[0].offsetMs <= recording->batches[1].offsetMs);
+
+    const FsEventRecord& created = recording->batches[0].events.at(0);
+    QCOMPARE(created.eventId, uint64_t(7));
+    QCOMPARE(created.flags, uint32_t(kFSEventStreamEventFlagItemCreated
+                                     | kFSEventStreamEventFlagItemIsFile));
+    QCOMPARE(created.path, std::string("/Users/test/a.txt"));
+    QVERIFY(created.exists);
+    QCOMPARE(created.modTime, uint64_t(1760000000));
+    QCOMPARE(created.size, uint64_t(42));
+
+    QCOMPARE(recording->batches[1].events.size(), size_t(2));
+    QVERIFY(!recording->batches[1].events[0].exists);
+    QVERIFY(recording->batches[1].events[1].isDirectory);
+}
+
+void TestFsEventReplay::testParseRejectsMalformedRecordings()
+{
+    const QByteArray header =
+        R"({"format":"betterspotlight-fsevents","version":1,"roots":["/Users/test"]})" "\n";
+    QString error;
+
+    QVERIFY(!FsEventRecorder::parse(QByteArray(), &error).has_value());
+    QCOMPARE(error, QStringLiteral("empty recording"));
+
+    QVERIFY(!FsEventRecorder::parse(
+                 R"({"format":"betterspotlight-fsevents","version":2,"roots":[]})", &error)
+                 .has_value());
+    QCOMPARE(error, QStringLiteral("not a version 1 FSEvents recording"));
+
+    QVERIFY(!FsEventRecorder::parse(header + "{\"t\":0,", &error).has_value());
+    QVERIFY2(error.startsWith(QStringLiteral("line 2: ")), qPrintable(error));
+
+    QVERIFY(!FsEventRecorder::parse(header + R"({"t":0,"events":[{"id":1,"flags":256}]})",
+                                    &error)
+                 .has_value());
+    QCOMPARE(error, QStringLiteral("line 2: an event needs a path and flags"));
+
+    QVERIFY(!FsEventRecorder::parse(header
+                                        + R"({"t":50,"events":[]})" "\n"
+                                        + R"({"t":10,"events":[]})",
+                                    &error)
+                 .has_value());
+    QCOMPARE(error, QStringLiteral("line 3: events go back in time"));
+
+    // A header alone is an empty recording, not an error.
+    const std::optional<FsEventRecording> recording = FsEventRecorder::parse(header, &error);
+    QVERIFY2(recording.has_value(), qPrintable(error));
+    QVERIFY(recording->batches.empty());
+}
+
+void TestFsEventReplay::testTranslateEvents()
+{
+    QStringList errors;
+    const auto onError = [&errors](const QString& error) { errors.append(error); };
+    const uint32_t fileFlag = kFSEventStreamEventFlagItemIsFile;
+
+    const std::vector<WorkItem> items = bs::FileMonitorMacOS::translateEvents(
+        {
+            event(1, kFSEventStreamEventFlagHistoryDone, "/Users/test", true, true),
+            event(2, kFSEventStreamEventFlagItemCreated | fileFlag, "/Users/test/new.txt", true),
+            event(3, kFSEventStreamEventFlagItemCreated | fileFlag, "/Users/test/gone.txt", false),
+            event(4, kFSEventStreamEventFlagItemXattrMod | fileFlag, "/Users/test/tagged.txt",
+                  true),
+            event(5, kFSEventStreamEventFlagItemCreated | kFSEventStreamEventFlagItemIsDir,
+                  "/Users/test/dir", true, true),
+            event(6, kFSEventStreamEventFlagMount, "/Volumes/Stick", true, true),
+            event(7, kFSEventStreamEventFlagRootChanged, "/Users/test", false),
+            event(8, kFSEventStreamEventFlagKernelDropped, "/Users/test/Documents", true, true),
+        },
+        onError);
+
+    QCOMPARE(items.size(), size_t(6));
+    QCOMPARE(items[0].type, WorkItem::Type::NewFile);
+    QCOMPARE(items[0].filePath, std::string("/Users/test/new.txt"));
+    QCOMPARE(items[0].knownModTime.value_or(0), uint64_t(1760000000));
+    QCOMPARE(items[0].knownSize.value_or(0), uint64_t(42));
+    // Gone by the time it was stat'ed.
+    QCOMPARE(items[1].type, WorkItem::Type::Delete);
+    QVERIFY(!items[1].knownSize.has_value());
+    QCOMPARE(items[2].type, WorkItem::Type::ModifiedContent);
+    QCOMPARE(items[3].type, WorkItem::Type::RescanDirectory);
+    QCOMPARE(items[4].type, WorkItem::Type::RescanDirectory);
+    QCOMPARE(items[4].filePath, std::string("/Users/test"));
+    // Dropped events are reported, and the directory still rescanned.
+    QCOMPARE(items[5].type, WorkItem::Type::RescanDirectory);
+    QCOMPARE(errors, QStringList{QStringLiteral("FSEvents: kernel dropped events")});
+
+    QVERIFY(bs::FileMonitorMacOS::translateEvents({}, onError).empty());
+}
+
+void TestFsEventReplay::testBurstIsCoalesced()
+{
+    const QString path = fixturePath(QStringLiteral("burst_coalescing.ndjson"));
+    if (path.isEmpty()) {
+        QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+    }
+    QString error;
+    const std::optional<FsEventRecording> recording = FsEventRecorder::load(path, &error);
+    QVERIFY2(recording.has_value(), qPrintable(error));
+
+    const FsEventReplayResult result = FsEventReplayer::replay(*recording);
+
+    // The timer is armed by the first buffered event, not reset by later
+    // ones: the three writes to draft.txt inside 500 ms go out together,
+    // and the directory created 5 ms after the timer fired waits for the
+    // next delivery.
+    QCOMPARE(FsEventReplayer::describe(result),
+             (QStringList{
+                 QStringLiteral("new /Users/test/Documents/draft.txt"),
+                 QStringLiteral("modified /Users/test/Documents/draft.txt"),
+                 QStringLiteral("new /Users/test/Documents/draft.txt"),
+                 QStringLiteral("--"),
+                 QStringLiteral("rescan /Users/test/Documents/drafts"),
+                 QStringLiteral("--"),
+                 QStringLiteral("rescan /Users/test/Projects"),
+             }));
+    QCOMPARE(result.deliveries.size(), size_t(3));
+    QCOMPARE(result.deliveries[0].atMs, int64_t(510));
+    QCOMPARE(result.deliveries[0].lastEventId, uint64_t(103));
+    QCOMPARE(result.deliveries[0].items.back().knownSize.value_or(0), uint64_t(1024));
+    QCOMPARE(result.deliveries[1].atMs, int64_t(1015));
+    QCOMPARE(result.deliveries[1].lastEventId, uint64_t(104));
+    QCOMPARE(result.deliveries[2].atMs, int64_t(2500));
+    QCOMPARE(result.lastEventId, uint64_t(105));
+    QCOMPARE(result.errors,
+             QStringList{QStringLiteral("FSEvents: must rescan subdirs at /Users/test/Projects")});
+}
+
+void TestFsEventReplay::testReplayIsDeterministic()
+{
+    const QString path = fixturePath(QStringLiteral("burst_coalescing.ndjson"));
+    if (path.isEmpty()) {
+        QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+    }
+    QString error;
+    const std::optional<FsEventRecording> recording = FsEventRecorder::load(path, &error);
+    QVERIFY2(recording.has_value(), qPrintable(error));
+
+    std::vector<size_t> delivered;
+    const FsEventReplayResult first = FsEventReplayer::replay(
+        *recording, [&delivered](const std::vector<WorkItem>& items) {
+            delivered.push_back(items.size());
+        });
+    QCOMPARE(delivered, (std::vector<size_t>{3, 1, 1}));
+    QCOMPARE(FsEventReplayer::describe(FsEventReplayer::replay(*recording)),
+             FsEventReplayer::describe(first));
+
+    // A longer debounce folds the first two deliveries together.
+    const FsEventReplayResult slow = FsEventReplayer::replay(*recording, {}, 1000);
+    QCOMPARE(slow.deliveries.size(), size_t(2));
+    QCOMPARE(slow.deliveries[0].items.size(), size_t(4));
+    QCOMPARE(slow.deliveries[0].atMs, int64_t(1010));
+}
+
+void TestFsEventReplay::testRenameWithinRoot()
+{
+    const QString path = fixturePath(QStringLiteral("rename_within_root.ndjson"));
+    if (path.isEmpty()) {
+        QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+    }
+    QString error;
+    const std::optional<FsEventRecording> recording = FsEventRecorder::load(path, &error);
+    QVERIFY2(recording.has_value(), qPrintable(error));
+
+    // `mv a.txt b.txt`: FSEvents flags both sides Renamed, and only a stat
+    // tells them apart.
+    const FsEventReplayResult result = FsEventReplayer::replay(*recording);
+    QCOMPARE(result.deliveries.size(), size_t(1));
+    const std::vector<WorkItem>& items = result.deliveries[0].items;
+    QCOMPARE(items.size(), size_t(2));
+    QCOMPARE(items[0].type, WorkItem::Type::Delete);
+    QCOMPARE(items[0].filePath, std::string("/Users/test/Documents/a.txt"));
+    QCOMPARE(items[1].filePath, std::string("/Users/test/Documents/b.txt"));
+    QEXPECT_FAIL("", "the destination of a rename is classified before it is stat'ed, "
+                     "so it is reported as a delete", Continue);
+    QCOMPARE(items[1].type, WorkItem::Type::NewFile);
+}
+
+QTEST_MAIN(TestFsEventReplay)
+#include "test_fsevent_replay.moc"
@@ -0,0 +1,183 @@
+#include <QtTest/QtTest>
+
+#include "golden_queries.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace {
+
+bs::test::GoldenCase orderedCase()
+{
+    bs::test::GoldenCase goldenCase;
+    goldenCase.id = QStringLiteral("G-1");
+    goldenCase.query = QStringLiteral("budget");
+    goldenCase.expected = {QStringLiteral("budget-2026.xlsx"), QStringLiteral("budget-2025.xlsx")};
+    goldenCase.topN = 3;
+    goldenCase.excluded = {QStringLiteral("cover-letter.md")};
+    return goldenCase;
+}
+
+} // namespace
+
+class TestGoldenQueryTable : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseSuite();
+    void testParseSuiteRejectsInvalid();
+    void testSuiteRoundTrip();
+    void testDiffOrdered();
+    void testDiffUnordered();
+    void testDiffExcluded();
+    void testUpdated();
+};
+
+void TestGoldenQueryTable::testParseSuite()
+{
+    QString error;
+    const auto suite = bs::test::GoldenQueries::parseSuite(QByteArrayLiteral(R"({
+        "version": 1, "fixtureId": "standard_home_v1",
+        "cases": [
+            {"id": "A", "query": "budget", "expected": ["budget-2026.xlsx"]},
+            {"id": "B", "query": "guide", "mode": "strict", "ordered": false, "topN": 4,
+             "expected": ["a.md", "b.md"], "excluded": ["c.md"], "notes": "two guides"}
+        ]})"), &error);
+    QVERIFY2(suite.has_value(), qPrintable(error));
+    QCOMPARE(suite->fixtureId, QStringLiteral("standard_home_v1"));
+    QCOMPARE(suite->cases.size(), size_t(2));
+    QCOMPARE(suite->cases[0].mode, QStringLiteral("auto"));
+    QVERIFY(suite->cases[0].ordered);
+    QCOMPARE(suite->cases[0].depth(), 1);
+    QCOMPARE(suite->cases[1].mode, QStringLiteral("strict"));
+    QVERIFY(!suite->cases[1].ordered);
+    QCOMPARE(suite->cases[1].depth(), 4);
+    QCOMPARE(suite->cases[1].excluded, QStringList({QStringLiteral("c.md")}));
+}
+
+void TestGoldenQueryTable::testParseSuiteRejectsInvalid()
+{
+    const std::vector<QByteArray> invalid = {
+        QByteArrayLiteral("[]"),
+        QByteArrayLiteral(R"({"cases": []})"),
+        QByteArrayLiteral(R"({"version": 2, "fixtureId": "f", "cases": []})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cases": [{"query": "q", "expected": ["a"]}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cases": [{"id": "A", "query": "q"}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cases": [
+            {"id": "A", "query": "q", "expected": ["a"]},
+            {"id": "A", "query": "r", "expected": ["b"]}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cases": [
+            {"id": "A", "query": "q", "expected": ["a", "b"], "topN": 1}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cases": [
+            {"id": "A", "query": "q", "excluded": ["a"]}]})"),
+    };
+    for (const QByteArray& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::test::GoldenQueries::parseSuite(json, &error).has_value(), json.constData());
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestGoldenQueryTable::testSuiteRoundTrip()
+{
+    bs::test::GoldenSuite suite;
+    suite.fixtureId = QStringLiteral("standard_home_v1");
+    suite.cases.push_back(orderedCase());
+    bs::test::GoldenCase plain;
+    plain.id = QStringLiteral("G-2");
+    plain.query = QStringLiteral("travel");
+    plain.expected = {QStringLiteral("travel-itinerary.txt")};
+    suite.cases.push_back(plain);
+
+    const QJsonObject json = bs::test::GoldenQueries::suiteToJson(suite);
+    const QJsonObject plainJson = json.value(QStringLiteral("cases")).toArray().at(1).toObject();
+    QVERIFY(!plainJson.contains(QStringLiteral("mode")));
+    QVERIFY(!plainJson.contains(QStringLiteral("topN")));
+    QVERIFY(!plainJson.contains(QStringLiteral("excluded")));
+
+    QString error;
+    const auto parsed = bs::test::GoldenQueries::parseSuite(QJsonDocument(json).toJson(), &error);
+    QVERIFY2(parsed.has_value(), qPrintable(error));
+    QCOMPARE(parsed->cases.size(), size_t(2));
+    QCOMPARE(parsed->cases[0].expected, orderedCase().expected);
+    QCOMPARE(parsed->cases[0].topN, 3);
+    QCOMPARE(parsed->cases[0].excluded, orderedCase().excluded);
+    QCOMPARE(parsed->cases[1].expected, plain.expected);
+}
+
+void TestGoldenQueryTable::testDiffOrdered()
+{
+    const bs::test::GoldenCase goldenCase = orderedCase();
+    QVERIFY(bs::test::GoldenQueries::diff(
+                goldenCase, {QStringLiteral("budget-2026.xlsx"), QStringLiteral("Budget-2025.xlsx"),
+                             QStringLiteral("notes.md")})
+                .isEmpty());
+
+    const QString diff = bs::test::GoldenQueries::diff(
+        goldenCase, {QStringLiteral("budget-2025.xlsx"), QStringLiteral("budget-2026.xlsx"),
+                     QStringLiteral("notes.md")});
+    QVERIFY(diff.startsWith(QStringLiteral("G-1 \"budget\" (auto, ordered top 3)\n")));
+    QVERIFY2(diff.contains(QStringLiteral("!    1  budget-2026.xlsx  budget-2025.xlsx\n")),
+             qPrintable(diff));
+    QVERIFY2(diff.contains(QStringLiteral("!    2  budget-2025.xlsx  budget-2026.xlsx\n")),
+             qPrintable(diff));
+    QVERIFY2(diff.contains(QStringLiteral("     3                    notes.md\n")), qPrintable(diff));
+    QVERIFY(diff.contains(QStringLiteral("budget-2026.xlsx: expected at 1, ranked 2")));
+    QVERIFY(diff.contains(QStringLiteral("budget-2025.xlsx: expected at 2, ranked 1")));
+
+    const QString missing = bs::test::GoldenQueries::diff(goldenCase,
+                                                          {QStringLiteral("budget-2026.xlsx")});
+    QVERIFY(missing.contains(QStringLiteral("budget-2025.xlsx: expected at 2, not returned")));
+    QVERIFY(missing.contains(QStringLiteral("!    2  budget-2025.xlsx  -\n")));
+}
+
+void TestGoldenQueryTable::testDiffUnordered()
+{
+    bs::test::GoldenCase goldenCase;
+    goldenCase.id = QStringLiteral("G-3");
+    goldenCase.query = QStringLiteral("guide");
+    goldenCase.expected = {QStringLiteral("a-guide.md"), QStringLiteral("b-guide.md")};
+    goldenCase.ordered = false;
+    QVERIFY(bs::test::GoldenQueries::diff(
+                goldenCase, {QStringLiteral("b-guide.md"), QStringLiteral("a-guide.md")})
+                .isEmpty());
+
+    const QString diff = bs::test::GoldenQueries::diff(
+        goldenCase, {QStringLiteral("b-guide.md"), QStringLiteral("notes.md"),
+                     QStringLiteral("a-guide.md")});
+    QVERIFY(diff.startsWith(QStringLiteral("G-3 \"guide\" (auto, any order top 2)\n")));
+    QVERIFY2(diff.contains(QStringLiteral("     1  a-guide.md  b-guide.md\n")), qPrintable(diff));
+    QVERIFY2(diff.contains(QStringLiteral("!    2  b-guide.md  notes.md\n")), qPrintable(diff));
+    QVERIFY(diff.contains(QStringLiteral("a-guide.md: expected in the top 2, ranked 3")));
+}
+
+void TestGoldenQueryTable::testDiffExcluded()
+{
+    const QString diff = bs::test::GoldenQueries::diff(
+        orderedCase(), {QStringLiteral("budget-2026.xlsx"), QStringLiteral("budget-2025.xlsx"),
+                        QStringLiteral("cover-letter.md")});
+    QVERIFY2(diff.contains(QStringLiteral("!    3                    cover-letter.md\n")),
+             qPrintable(diff));
+    QVERIFY(diff.contains(QStringLiteral("cover-letter.md: excluded, ranked 3")));
+
+    // Below the compared depth it may appear.
+    QVERIFY(bs::test::GoldenQueries::diff(
+                orderedCase(), {QStringLiteral("budget-2026.xlsx"), QStringLiteral("budget-2025.xlsx"),
+                                QStringLiteral("notes.md"), QStringLiteral("cover-letter.md")})
+                .isEmpty());
+}
+
+void TestGoldenQueryTable::testUpdated()
+{
+    const QStringList actual = {QStringLiteral("budget-2025.xlsx"), QStringLiteral("cover-letter.md"),
+                                QStringLiteral("budget-2026.xlsx")};
+    const bs::test::GoldenCase updated = bs::test::GoldenQueries::updated(orderedCase(), actual);
+    QCOMPARE(updated.expected,
+             QStringList({QStringLiteral("budget-2025.xlsx"), QStringLiteral("cover-letter.md")}));
+    QVERIFY(updated.excluded.isEmpty());
+    QCOMPARE(updated.topN, 3);
+    QVERIFY(bs::test::GoldenQueries::diff(updated, actual).isEmpty());
+}
+
+QTEST_MAIN(TestGoldenQueryTable)
+#include "test_golden_query_table.moc"
@@ -88,6 +88,21 @@ void TestHealthAggregatorActor::testOverallStatePrecedence()
              QStringLiteral("rebuilding"));
     QCOMPARE(reason, QStringLiteral("rebuilding"));
 
+    QJsonObject unreadableRoot;
+    unreadableRoot[QStringLiteral("path")] = QStringLiteral("/Volumes/Archive");
+    unreadableRoot[QStringLiteral("reason")] = QStringLiteral("missing");
+    QJsonObject fileAccess;
+    fileAccess[QStringLiteral("unreadableRoots")] = QJsonArray{unreadableRoot};
+    QJsonObject unreadableHealth;
+    unreadableHealth[QStringLiteral("fileAccess")] = fileAccess;
+    QCOMPARE(bs::HealthAggregatorActor::computeOverallState(
+                 services,
+                 unreadableHealth,
+                 0,
+                 &reason),
+             QStringLiteral("degraded"));
+    QCOMPARE(reason, QStringLiteral("roots_unreadable"));
+
     QCOMPARE(bs::HealthAggregatorActor::computeOverallState(
                  services,
                  QJsonObject{},
@@ -0,0 +1,264 @@
+#include <QtTest/QtTest>
+
+#include "cli/index_diff.h"
+#include "core/index/sqlite_store.h"
+#include "core/shared/chunk.h"
+
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+namespace {
+
+struct Document {
+    QString path;
+    QString text;
+    int64_t size = 512;
+    QStringList tags;
+    std::vector<bs::ItemAttribute> attributes;
+};
+
+// An index of `documents`, closed so its WAL is checkpointed.
+void writeIndex(const QString& dbPath, const std::vector<Document>& documents)
+{
+    auto store = bs::SQLiteStore::open(dbPath);
+    QVERIFY(store.has_value());
+    for (const Document& document : documents) {
+        const QString name = document.path.mid(document.path.lastIndexOf(QLatin1Char('/')) + 1);
+        auto id = store->upsertItem(document.path, name, QStringLiteral("md"),
+                                    bs::ItemKind::Markdown, document.size, 1.0, 1790000000.0);
+        QVERIFY(id.has_value());
+        bs::Chunk chunk;
+        chunk.chunkId = bs::computeChunkId(document.path, 0);
+        chunk.filePath = document.path;
+        chunk.content = document.text;
+        QVERIFY(store->insertChunks(*id, name, document.path, {chunk}));
+        if (!document.tags.isEmpty()) {
+            QVERIFY(store->replaceItemTags(*id, QLatin1String(bs::SQLiteStore::kFinderTagSource),
+                                           document.tags));
+        }
+        if (!document.attributes.empty()) {
+            QVERIFY(store->replaceItemAttributes(*id, name, document.path, document.attributes));
+        }
+    }
+}
+
+const bs::IndexFieldChange* fieldChange(const bs::IndexDocumentChange& change, const char* field)
+{
+    for (const bs::IndexFieldChange& fieldChange : change.fields) {
+        if (fieldChange.field == QLatin1String(field)) {
+            return &fieldChange;
+        }
+    }
+    return nullptr;
+}
+
+} // namespace
+
+class TestIndexDiff : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDiffSnapshots();
+    void testIdenticalSnapshots();
+    void testIgnoredFields();
+    void testLoadUnderPath();
+    void testLoadRejectsNonIndexes();
+    void testRenderText();
+    void testJson();
+};
+
+void TestIndexDiff::testDiffSnapshots()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString before = dir.filePath(QStringLiteral("before.db"));
+    const QString after = dir.filePath(QStringLiteral("after.db"));
+    writeIndex(before, {
+        {QStringLiteral("/home/notes/alpha.md"), QStringLiteral("alpha stays the same")},
+        {QStringLiteral("/home/notes/beta.md"), QStringLiteral("beta before the edit"), 512,
+         {QStringLiteral("Work")}, {{QStringLiteral("finderComment"), QStringLiteral("draft")}}},
+        {QStringLiteral("/home/notes/gamma.md"), QStringLiteral("gamma is deleted")},
+    });
+    writeIndex(after, {
+        {QStringLiteral("/home/notes/alpha.md"), QStringLiteral("alpha stays the same")},
+        {QStringLiteral("/home/notes/beta.md"), QStringLiteral("beta after the edit, longer"), 640,
+         {QStringLiteral("Work"), QStringLiteral("Important")},
+         {{QStringLiteral("finderComment"), QStringLiteral("final")}}},
+        {QStringLiteral("/home/notes/delta.md"), QStringLiteral("delta is new")},
+    });
+
+    QString error;
+    const auto a = bs::IndexDiff::load(before, {}, &error);
+    QVERIFY2(a.has_value(), qPrintable(error));
+    const auto b = bs::IndexDiff::load(after, {}, &error);
+    QVERIFY2(b.has_value(), qPrintable(error));
+    QCOMPARE(a->documents.size(), qsizetype(3));
+    QCOMPARE(a->source, before);
+
+    const bs::IndexDiffResult result = bs::IndexDiff::diff(*a, *b);
+    QVERIFY(!result.identical());
+    QCOMPARE(result.added, QStringList({QStringLiteral("/home/notes/delta.md")}));
+    QCOMPARE(result.removed, QStringList({QStringLiteral("/home/notes/gamma.md")}));
+    QCOMPARE(result.unchanged, int64_t(1));
+    QCOMPARE(result.changed.size(), size_t(1));
+
+    const bs::IndexDocumentChange& change = result.changed.front();
+    QCOMPARE(change.path, QStringLiteral("/home/notes/beta.md"));
+    const bs::IndexFieldChange* size = fieldChange(change, "size");
+    QVERIFY(size);
+    QCOMPARE(size->before, QStringLiteral("512"));
+    QCOMPARE(size->after, QStringLiteral("640"));
+    const bs::IndexFieldChange* content = fieldChange(change, bs::IndexDiff::kContentField);
+    QVERIFY(content);
+    QVERIFY(content->before.startsWith(QStringLiteral("20 chars, sha256 ")));
+    QVERIFY(content->after.startsWith(QStringLiteral("27 chars, sha256 ")));
+    const bs::IndexFieldChange* tags = fieldChange(change, "tags");
+    QVERIFY(tags);
+    QCOMPARE(tags->before, QStringLiteral("Work"));
+    QCOMPARE(tags->after, QStringLiteral("Important, Work"));
+    const bs::IndexFieldChange* comment = fieldChange(change, "attribute:finderComment");
+    QVERIFY(comment);
+    QCOMPARE(comment->before, QStringLiteral("draft"));
+    QCOMPARE(comment->after, QStringLiteral("final"));
+    QVERIFY(!fieldChange(change, "name"));
+    QVERIFY(!fieldChange(change, "indexedAt"));
+}
+
+void TestIndexDiff::testIdenticalSnapshots()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("index.db"));
+    writeIndex(path, {{QStringLiteral("/home/a.md"), QStringLiteral("same text")},
+                      {QStringLiteral("/home/b.md"), QStringLiteral("other text")}});
+    QString error;
+    const auto snapshot = bs::IndexDiff::load(path, {}, &error);
+    QVERIFY2(snapshot.has_value(), qPrintable(error));
+
+    const bs::IndexDiffResult result = bs::IndexDiff::diff(*snapshot, *snapshot);
+    QVERIFY(result.identical());
+    QCOMPARE(result.unchanged, int64_t(2));
+}
+
+void TestIndexDiff::testIgnoredFields()
+{
+    bs::IndexSnapshot before;
+    before.documents.insert(QStringLiteral("/home/a.md"),
+                            {{QStringLiteral("name"), QStringLiteral("a.md")},
+                             {QStringLiteral("indexedAt"), QStringLiteral("2026-01-01T00:00:00.000Z")},
+                             {QStringLiteral("attribute:wherefrom"), QStringLiteral("https://a")}});
+    bs::IndexSnapshot after = before;
+    after.documents[QStringLiteral("/home/a.md")][QStringLiteral("indexedAt")] =
+        QStringLiteral("2026-02-01T00:00:00.000Z");
+
+    // indexedAt by default.
+    QVERIFY(bs::IndexDiff::diff(before, after).identical());
+    const bs::IndexDiffResult all = bs::IndexDiff::diff(before, after, {});
+    QCOMPARE(all.changed.size(), size_t(1));
+    QCOMPARE(all.changed.front().fields.front().field, QStringLiteral("indexedAt"));
+
+    // A field only one side has is a change, and a trailing * ignores a prefix.
+    after.documents[QStringLiteral("/home/a.md")].remove(QStringLiteral("attribute:wherefrom"));
+    after.documents[QStringLiteral("/home/a.md")][QStringLiteral("attribute:batch")] =
+        QStringLiteral("42");
+    const bs::IndexDiffResult attributes = bs::IndexDiff::diff(before, after);
+    QCOMPARE(attributes.changed.size(), size_t(1));
+    QCOMPARE(attributes.changed.front().fields.size(), size_t(2));
+    QCOMPARE(attributes.changed.front().fields[0].field, QStringLiteral("attribute:batch"));
+    QCOMPARE(attributes.changed.front().fields[0].before, QString());
+    QCOMPARE(attributes.changed.front().fields[1].after, QString());
+    QVERIFY(bs::IndexDiff::diff(before, after,
+                                {QStringLiteral("indexedAt"), QStringLiteral("attribute:*")})
+                .identical());
+}
+
+void TestIndexDiff::testLoadUnderPath()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("index.db"));
+    writeIndex(path, {{QStringLiteral("/home/projects/app/main.md"), QStringLiteral("inside")},
+                      {QStringLiteral("/home/projects/application.md"), QStringLiteral("sibling")},
+                      {QStringLiteral("/home/notes/todo.md"), QStringLiteral("outside")}});
+    QString error;
+    const auto snapshot = bs::IndexDiff::load(path, QStringLiteral("/home/projects/app"), &error);
+    QVERIFY2(snapshot.has_value(), qPrintable(error));
+    QCOMPARE(snapshot->documents.keys(), QStringList({QStringLiteral("/home/projects/app/main.md")}));
+}
+
+void TestIndexDiff::testLoadRejectsNonIndexes()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    QVERIFY(!bs::IndexDiff::load(dir.filePath(QStringLiteral("missing.db")), {}, &error));
+    QVERIFY(error.contains(QStringLiteral("does not exist")));
+
+    const QString text = dir.filePath(QStringLiteral("notes.txt"));
+    QFile file(text);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.write("not an index\n");
+    file.close();
+    QVERIFY(!bs::IndexDiff::load(text, {}, &error));
+    QVERIFY(error.contains(QStringLiteral("not an index")));
+}
+
+void TestIndexDiff::testRenderText()
+{
+    bs::IndexDiffResult result;
+    result.added = {QStringLiteral("/home/new-1.md"), QStringLiteral("/home/new-2.md"),
+                    QStringLiteral("/home/new-3.md")};
+    result.removed = {QStringLiteral("/home/old.md")};
+    result.changed.push_back({QStringLiteral("/home/edited.md"),
+                              {{QStringLiteral("size"), QStringLiteral("10"), QStringLiteral("12")},
+                               {QStringLiteral("tags"), QString(), QStringLiteral("Red")},
+                               {QStringLiteral("attribute:comment"),
+                                QStringLiteral("line one\nline two ") + QString(100, QLatin1Char('x')),
+                                QString()}}});
+    result.unchanged = 7;
+
+    const QString text = bs::IndexDiff::renderText(result, 2);
+    const QStringList lines = text.split(QLatin1Char('\n'), Qt::SkipEmptyParts);
+    QCOMPARE(lines.at(0), QStringLiteral("3 added, 1 removed, 1 changed, 7 unchanged"));
+    QCOMPARE(lines.at(1), QStringLiteral("+ /home/new-1.md"));
+    QCOMPARE(lines.at(2), QStringLiteral("+ /home/new-2.md"));
+    QCOMPARE(lines.at(3), QStringLiteral("  ... and 1 more"));
+    QCOMPARE(lines.at(4), QStringLiteral("- /home/old.md"));
+    QCOMPARE(lines.at(5), QStringLiteral("~ /home/edited.md"));
+    QCOMPARE(lines.at(6), QStringLiteral("    size: 10 -> 12"));
+    QCOMPARE(lines.at(7), QStringLiteral("    tags: (none) -> Red"));
+    QVERIFY(lines.at(8).startsWith(QStringLiteral("    attribute:comment: line one line two xxx")));
+    QVERIFY(lines.at(8).endsWith(QStringLiteral("... -> (none)")));
+    QCOMPARE(lines.size(), qsizetype(9));
+
+    QCOMPARE(bs::IndexDiff::renderText({}, 50),
+             QStringLiteral("0 added, 0 removed, 0 changed, 0 unchanged\n"));
+}
+
+void TestIndexDiff::testJson()
+{
+    bs::IndexDiffResult result;
+    result.removed = {QStringLiteral("/home/old.md")};
+    result.changed.push_back(
+        {QStringLiteral("/home/edited.md"),
+         {{QStringLiteral("size"), QStringLiteral("10"), QStringLiteral("12")}}});
+    result.unchanged = 3;
+
+    const QJsonObject json = bs::IndexDiff::toJson(result);
+    QCOMPARE(json.value(QStringLiteral("identical")).toBool(true), false);
+    QVERIFY(json.value(QStringLiteral("added")).toArray().isEmpty());
+    QCOMPARE(json.value(QStringLiteral("removed")).toArray().first().toString(),
+             QStringLiteral("/home/old.md"));
+    QCOMPARE(json.value(QStringLiteral("unchanged")).toInteger(), qint64(3));
+    const QJsonObject changed = json.value(QStringLiteral("changed")).toArray().first().toObject();
+    QCOMPARE(changed.value(QStringLiteral("path")).toString(), QStringLiteral("/home/edited.md"));
+    const QJsonObject size = changed.value(QStringLiteral("fields")).toArray().first().toObject();
+    QCOMPARE(size.value(QStringLiteral("field")).toString(), QStringLiteral("size"));
+    QCOMPARE(size.value(QStringLiteral("before")).toString(), QStringLiteral("10"));
+    QCOMPARE(size.value(QStringLiteral("after")).toString(), QStringLiteral("12"));
+
+    QVERIFY(bs::IndexDiff::toJson({}).value(QStringLiteral("identical")).toBool());
+}
+
+QTEST_MAIN(TestIndexDiff)
+#include "test_index_diff.moc"
@@ -9,8 +9,12 @@
 #include <QDir>
 #include <QFile>
 #include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonObject>
 #include <QTemporaryDir>
 
+#include <sqlite3.h>
+
 namespace {
 
 bool writeTextFile(const QString& path, const QByteArray& payload)
@@ -35,6 +39,8 @@ private slots:
     void testExcludeAndDeleteLifecycle();
     void testMetadataOnlyRescanAndSkipBranches();
     void testNonExtractableAndExtractionFailurePaths();
+    void testPrivacyPurgeRemovesSubtree();
+    void testContentTypeRuleDropsStoredContent();
 };
 
 void TestIndexer::testExcludeAndDeleteLifecycle()
@@ -237,5 +243,113 @@ void TestIndexer::testNonExtractableAndExtractionFailurePaths()
     QCOMPARE(manualResult.status, bs::IndexResult::Status::ExtractionFailed);
 }
 
+void TestIndexer::testPrivacyPurgeRemovesSubtree()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+
+    auto storeOpt = bs::SQLiteStore::open(QDir(tempDir.path()).filePath(QStringLiteral("index.db")));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    bs::ExtractionManager extractor;
+    bs::PathRules pathRules;
+    bs::Chunker chunker;
+    bs::Indexer indexer(store, extractor, pathRules, chunker);
+
+    const QString privateDir = QDir(tempDir.path()).filePath(QStringLiteral("Medical"));
+    QVERIFY(QDir().mkpath(privateDir + QStringLiteral("/2026")));
+    const QString scanPath = QDir(privateDir).filePath(QStringLiteral("2026/scan.txt"));
+    const QString notesPath = QDir(privateDir).filePath(QStringLiteral("notes.txt"));
+    const QString latePath = QDir(privateDir).filePath(QStringLiteral("late.txt"));
+    const QString publicPath = QDir(tempDir.path()).filePath(QStringLiteral("MedicalBills.txt"));
+    QVERIFY(writeTextFile(scanPath, QByteArrayLiteral("biopsy results zephyrine")));
+    QVERIFY(writeTextFile(notesPath, QByteArrayLiteral("allergy notes zephyrine")));
+    QVERIFY(writeTextFile(latePath, QByteArrayLiteral("late arrival zephyrine")));
+    QVERIFY(writeTextFile(publicPath, QByteArrayLiteral("invoice zephyrine")));
+
+    for (const QString& path : {scanPath, notesPath, publicPath}) {
+        bs::WorkItem work;
+        work.type = bs::WorkItem::Type::NewFile;
+        work.filePath = path.toStdString();
+        QCOMPARE(indexer.processWorkItem(work).status, bs::IndexResult::Status::Indexed);
+    }
+    const QString eventSql = QStringLiteral(
+        "INSERT INTO behavior_events_v1 (event_id, timestamp, source, event_type, item_path, "
+        "created_at) VALUES ('e1', 1, 'test', 'open', '%1', 1)").arg(scanPath);
+    QCOMPARE(sqlite3_exec(store.rawDb(), eventSql.toUtf8().constData(), nullptr, nullptr,
+                          nullptr),
+             SQLITE_OK);
+
+    // Prepared before the folder became private, written after.
+    bs::WorkItem lateWork;
+    lateWork.type = bs::WorkItem::Type::NewFile;
+    lateWork.filePath = latePath.toStdString();
+    const bs::PreparedWork latePrepared = indexer.prepareWorkItem(lateWork);
+    QCOMPARE(latePrepared.validation, bs::ValidationResult::Include);
+
+    const bs::SQLiteStore::PathFootprint before = store.footprintUnderPath(privateDir);
+    QCOMPARE(before.items, static_cast<int64_t>(2));
+    QVERIFY(before.ftsRows > 0);
+    QCOMPARE(before.behaviorEvents, static_cast<int64_t>(1));
+
+    pathRules.setPrivacyExclusions({privateDir.toStdString()});
+    QVERIFY(store.setSecureDelete(true));
+    QCOMPARE(indexer.purgeSubtree(privateDir), static_cast<size_t>(before.items));
+    QVERIFY(store.setSecureDelete(false));
+    QCOMPARE(indexer.applyPreparedWork(latePrepared).status, bs::IndexResult::Status::Excluded);
+    QVERIFY(store.checkpointWal());
+
+    QVERIFY(store.footprintUnderPath(privateDir).empty());
+    QVERIFY(!store.getItemByPath(latePath).has_value());
+    const auto hits = store.searchFts5(QStringLiteral("zephyrine"));
+    QCOMPARE(hits.size(), static_cast<size_t>(1));
+    QCOMPARE(store.getItemById(hits.front().fileId)->path, publicPath);
+}
+
+void TestIndexer::testContentTypeRuleDropsStoredContent()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+
+    const QString dbPath = QDir(tempDir.path()).filePath(QStringLiteral("index.db"));
+    auto storeOpt = bs::SQLiteStore::open(dbPath);
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    bs::ExtractionManager extractor;
+    bs::PathRules pathRules;
+    bs::Chunker chunker;
+    bs::Indexer indexer(store, extractor, pathRules, chunker);
+
+    const QString notesPath = QDir(tempDir.path()).filePath(QStringLiteral("notes.txt"));
+    QVERIFY(writeTextFile(notesPath, QByteArrayLiteral("quarterly revenue projections")));
+    bs::WorkItem work;
+    work.type = bs::WorkItem::Type::NewFile;
+    work.filePath = notesPath.toStdString();
+    QCOMPARE(indexer.processWorkItem(work).status, bs::IndexResult::Status::Indexed);
+    QVERIFY(!store.getItemByPath(notesPath)->contentHash.isEmpty());
+    QVERIFY(!store.searchFts5(QStringLiteral("revenue"), 10).empty());
+
+    QJsonObject rule;
+    rule[QStringLiteral("root")] = tempDir.path();
+    rule[QStringLiteral("deny")] = QJsonArray{QStringLiteral("text")};
+    QString error;
+    const auto rules = bs::ContentTypeRules::fromJson(QJsonArray{rule}, &error);
+    QVERIFY2(rules.has_value(), qPrintable(error));
+    indexer.setContentTypeRules(rules.value());
+
+    const bs::PreparedWork prepared = indexer.prepareWorkItem(work);
+    QVERIFY(prepared.contentExcluded);
+    QVERIFY(prepared.chunks.empty());
+    QCOMPARE(indexer.applyPreparedWork(prepared).status, bs::IndexResult::Status::MetadataOnly);
+
+    // The name stays searchable; the earlier content does not.
+    const auto item = store.getItemByPath(notesPath);
+    QVERIFY(item.has_value());
+    QVERIFY(item->contentHash.isEmpty());
+    QVERIFY(store.searchFts5(QStringLiteral("revenue"), 10).empty());
+}
+
 QTEST_MAIN(TestIndexer)
 #include "test_indexer.moc"
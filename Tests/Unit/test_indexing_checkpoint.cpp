@@ -0,0 +1,123 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/indexing_checkpoint.h"
+
+#include <QFile>
+#include <QTemporaryDir>
+
+namespace {
+
+bs::WorkItem makeItem(bs::WorkItem::Type type, const std::string& path)
+{
+    bs::WorkItem item;
+    item.type = type;
+    item.filePath = path;
+    return item;
+}
+
+bs::IndexingCheckpoint makeCheckpoint()
+{
+    bs::IndexingCheckpoint checkpoint;
+    checkpoint.roots = {"/Users/test/Documents", "/Users/test/Projects"};
+    checkpoint.unscannedRoots = {"/Users/test/Projects"};
+    checkpoint.pendingItems.push_back(
+        makeItem(bs::WorkItem::Type::NewFile, "/Users/test/Documents/a.txt"));
+    bs::WorkItem rebuildItem =
+        makeItem(bs::WorkItem::Type::ModifiedContent, "/Users/test/Documents/b.md");
+    rebuildItem.rebuildLane = true;
+    rebuildItem.retryCount = 2;
+    checkpoint.pendingItems.push_back(rebuildItem);
+    checkpoint.pendingItems.push_back(
+        makeItem(bs::WorkItem::Type::Delete, "/Users/test/Documents/gone.pdf"));
+    // FSEvents ids exceed 2^53; must survive JSON without precision loss.
+    checkpoint.lastEventId = 18446744073709551000ULL;
+    checkpoint.savedAtMs = 1760000000000;
+    return checkpoint;
+}
+
+} // namespace
+
+class TestIndexingCheckpoint : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testJsonRoundTrip();
+    void testMatchesRootsIgnoresOrder();
+    void testRejectsOtherFormatVersion();
+    void testSaveAndLoad();
+    void testLoadMissingOrCorruptFile();
+};
+
+void TestIndexingCheckpoint::testJsonRoundTrip()
+{
+    const bs::IndexingCheckpoint original = makeCheckpoint();
+    const auto restored = bs::IndexingCheckpoint::fromJson(original.toJson());
+    QVERIFY(restored.has_value());
+
+    QCOMPARE(restored->roots, original.roots);
+    QCOMPARE(restored->unscannedRoots, original.unscannedRoots);
+    QCOMPARE(restored->pendingItems.size(), original.pendingItems.size());
+    for (size_t i = 0; i < original.pendingItems.size(); ++i) {
+        QCOMPARE(restored->pendingItems[i].type, original.pendingItems[i].type);
+        QCOMPARE(restored->pendingItems[i].filePath, original.pendingItems[i].filePath);
+        QCOMPARE(restored->pendingItems[i].rebuildLane, original.pendingItems[i].rebuildLane);
+        QCOMPARE(restored->pendingItems[i].retryCount, original.pendingItems[i].retryCount);
+    }
+    QVERIFY(restored->lastEventId.has_value());
+    QCOMPARE(restored->lastEventId.value(), original.lastEventId.value());
+    QCOMPARE(restored->savedAtMs, original.savedAtMs);
+
+    bs::IndexingCheckpoint noHistory = original;
+    noHistory.lastEventId.reset();
+    const auto restoredNoHistory = bs::IndexingCheckpoint::fromJson(noHistory.toJson());
+    QVERIFY(restoredNoHistory.has_value());
+    QVERIFY(!restoredNoHistory->lastEventId.has_value());
+}
+
+void TestIndexingCheckpoint::testMatchesRootsIgnoresOrder()
+{
+    const bs::IndexingCheckpoint checkpoint = makeCheckpoint();
+    QVERIFY(checkpoint.matchesRoots({"/Users/test/Projects", "/Users/test/Documents"}));
+    QVERIFY(!checkpoint.matchesRoots({"/Users/test/Documents"}));
+    QVERIFY(!checkpoint.matchesRoots(
+        {"/Users/test/Documents", "/Users/test/Projects", "/Users/test/Desktop"}));
+}
+
+void TestIndexingCheckpoint::testRejectsOtherFormatVersion()
+{
+    QJsonObject json = makeCheckpoint().toJson();
+    json[QStringLiteral("version")] = bs::IndexingCheckpoint::kFormatVersion + 1;
+    QVERIFY(!bs::IndexingCheckpoint::fromJson(json).has_value());
+}
+
+void TestIndexingCheckpoint::testSaveAndLoad()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("indexer-checkpoint.json"));
+
+    const bs::IndexingCheckpoint original = makeCheckpoint();
+    QVERIFY(original.save(path));
+
+    const auto loaded = bs::IndexingCheckpoint::load(path);
+    QVERIFY(loaded.has_value());
+    QCOMPARE(loaded->pendingItems.size(), original.pendingItems.size());
+    QCOMPARE(loaded->lastEventId.value(), original.lastEventId.value());
+}
+
+void TestIndexingCheckpoint::testLoadMissingOrCorruptFile()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("indexer-checkpoint.json"));
+    QVERIFY(!bs::IndexingCheckpoint::load(path).has_value());
+
+    QFile file(path);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.write("{\"version\": 1, \"roots\": [");
+    file.close();
+    QVERIFY(!bs::IndexingCheckpoint::load(path).has_value());
+}
+
+QTEST_MAIN(TestIndexingCheckpoint)
+#include "test_indexing_checkpoint.moc"
@@ -0,0 +1,91 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/indexing_events.h"
+
+namespace {
+
+constexpr int64_t kNowMs = 1760000000000;
+
+} // namespace
+
+class TestIndexingEvents : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testSequenceAndFields();
+    void testSinceAndLimit();
+    void testCapacityTruncates();
+    void testSinceFromBeforeRestart();
+};
+
+void TestIndexingEvents::testSequenceAndFields()
+{
+    bs::IndexingEventLog log;
+    const QJsonObject first = log.append(QStringLiteral("rootStarted"),
+                                         {{QStringLiteral("root"), QStringLiteral("/Users/test")}},
+                                         kNowMs);
+    QCOMPARE(first.value(QStringLiteral("seq")).toInteger(), qint64(1));
+    QCOMPARE(first.value(QStringLiteral("event")).toString(), QStringLiteral("rootStarted"));
+    QCOMPARE(first.value(QStringLiteral("root")).toString(), QStringLiteral("/Users/test"));
+    QCOMPARE(first.value(QStringLiteral("timestampMs")).toInteger(), qint64(kNowMs));
+
+    log.append(QStringLiteral("rootScanned"), {}, kNowMs + 5);
+    QCOMPARE(log.lastSeq(), int64_t(2));
+}
+
+void TestIndexingEvents::testSinceAndLimit()
+{
+    bs::IndexingEventLog log;
+    QCOMPARE(log.since(0, 10).lastSeq, int64_t(0));
+    for (int i = 0; i < 5; ++i) {
+        log.append(QStringLiteral("progress"), {}, kNowMs + i);
+    }
+
+    bs::IndexingEventLog::Page page = log.since(0, 3);
+    QCOMPARE(page.events.size(), qsizetype(3));
+    QCOMPARE(page.events.at(0).toObject().value(QStringLiteral("seq")).toInteger(), qint64(1));
+    QCOMPARE(page.lastSeq, int64_t(3));
+    QVERIFY(!page.truncated);
+
+    page = log.since(page.lastSeq, 10);
+    QCOMPARE(page.events.size(), qsizetype(2));
+    QCOMPARE(page.lastSeq, int64_t(5));
+
+    // Caught up: nothing new, same position.
+    page = log.since(5, 10);
+    QVERIFY(page.events.isEmpty());
+    QCOMPARE(page.lastSeq, int64_t(5));
+    QVERIFY(!page.truncated);
+}
+
+void TestIndexingEvents::testCapacityTruncates()
+{
+    bs::IndexingEventLog log;
+    const int total = static_cast<int>(bs::IndexingEventLog::kCapacity) + 10;
+    for (int i = 0; i < total; ++i) {
+        log.append(QStringLiteral("fileFailed"), {}, kNowMs);
+    }
+    const bs::IndexingEventLog::Page page = log.since(0, total);
+    QVERIFY(page.truncated);
+    QCOMPARE(page.events.size(), qsizetype(bs::IndexingEventLog::kCapacity));
+    QCOMPARE(page.events.at(0).toObject().value(QStringLiteral("seq")).toInteger(), qint64(11));
+    QCOMPARE(page.lastSeq, int64_t(total));
+
+    QVERIFY(!log.since(10, 1).truncated);
+}
+
+void TestIndexingEvents::testSinceFromBeforeRestart()
+{
+    bs::IndexingEventLog log;
+    log.append(QStringLiteral("rootStarted"), {}, kNowMs);
+    log.append(QStringLiteral("rootScanned"), {}, kNowMs);
+
+    // A client still holding seq 900 from the previous indexer process.
+    const bs::IndexingEventLog::Page page = log.since(900, 10);
+    QVERIFY(page.truncated);
+    QCOMPARE(page.events.size(), qsizetype(2));
+    QCOMPARE(page.lastSeq, int64_t(2));
+}
+
+QTEST_MAIN(TestIndexingEvents)
+#include "test_indexing_events.moc"
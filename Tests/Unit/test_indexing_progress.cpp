@@ -0,0 +1,198 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/indexing_progress.h"
+
+namespace {
+
+constexpr int64_t kStartMs = 1760000000000;
+
+} // namespace
+
+class TestIndexingProgress : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testPerRootPercentages();
+    void testNestedRootsCreditMostSpecific();
+    void testLiveItemsCountTowardThroughputOnly();
+    void testEtaWaitsForScanCompletion();
+    void testDrainedPipelineCompletesCrawl();
+    void testCompletedRootsReportedOnce();
+    void testThroughputWindowSlides();
+    void testJsonShape();
+};
+
+void TestIndexingProgress::testPerRootPercentages()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents/", "/Users/test/Code"}, kStartMs);
+    for (int i = 0; i < 4; ++i) {
+        tracker.recordDiscovered("/Users/test/Documents/doc" + std::to_string(i) + ".txt");
+    }
+    tracker.recordDiscovered("/Users/test/Code/main.cpp");
+    tracker.recordDiscovered("/Users/test/Codex/ignored.txt");
+    tracker.recordProcessed("/Users/test/Documents/doc0.txt", true, kStartMs + 100);
+
+    const bs::IndexingProgress progress = tracker.snapshot(kStartMs + 1000, false);
+    QCOMPARE(progress.roots.size(), size_t(2));
+    QCOMPARE(progress.roots[0].root, std::string("/Users/test/Documents"));
+    QCOMPARE(progress.roots[0].discovered, size_t(4));
+    QCOMPARE(progress.roots[0].processed, size_t(1));
+    QCOMPARE(progress.roots[0].percent, 25.0);
+    QCOMPARE(progress.roots[1].discovered, size_t(1));
+    QCOMPARE(progress.roots[1].percent, 0.0);
+    QCOMPARE(progress.discovered, size_t(5));
+    QCOMPARE(progress.processed, size_t(1));
+    QVERIFY(!progress.crawlComplete);
+}
+
+void TestIndexingProgress::testNestedRootsCreditMostSpecific()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test", "/Users/test/Projects"}, kStartMs);
+    tracker.recordDiscovered("/Users/test/Projects/app/main.cpp");
+    tracker.recordDiscovered("/Users/test/notes.md");
+    tracker.recordProcessed("/Users/test/Projects/app/main.cpp", true, kStartMs + 10);
+
+    const bs::IndexingProgress progress = tracker.snapshot(kStartMs + 1000, false);
+    QCOMPARE(progress.roots[0].discovered, size_t(1));
+    QCOMPARE(progress.roots[0].processed, size_t(0));
+    QCOMPARE(progress.roots[1].discovered, size_t(1));
+    QCOMPARE(progress.roots[1].processed, size_t(1));
+    QCOMPARE(progress.roots[1].percent, 100.0);
+}
+
+void TestIndexingProgress::testLiveItemsCountTowardThroughputOnly()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents"}, kStartMs);
+    tracker.recordDiscovered("/Users/test/Documents/a.txt");
+    tracker.recordProcessed("/Users/test/Documents/b.txt", false, kStartMs + 500);
+    tracker.recordProcessed("/Users/test/Documents/c.txt", false, kStartMs + 600);
+
+    const bs::IndexingProgress progress = tracker.snapshot(kStartMs + 2000, false);
+    QCOMPARE(progress.roots[0].processed, size_t(0));
+    QCOMPARE(progress.throughputWindowMs, int64_t(2000));
+    QCOMPARE(progress.itemsPerSecond, 1.0);
+}
+
+void TestIndexingProgress::testEtaWaitsForScanCompletion()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents"}, kStartMs);
+    for (int i = 0; i < 30; ++i) {
+        tracker.recordDiscovered("/Users/test/Documents/f" + std::to_string(i));
+    }
+    for (int i = 0; i < 10; ++i) {
+        tracker.recordProcessed("/Users/test/Documents/f" + std::to_string(i), true,
+                                kStartMs + 100 * i);
+    }
+
+    bs::IndexingProgress progress = tracker.snapshot(kStartMs + 5000, false);
+    QVERIFY(!progress.etaSeconds.has_value());
+
+    tracker.markScanComplete("/Users/test/Documents/");
+    progress = tracker.snapshot(kStartMs + 5000, false);
+    QVERIFY(progress.etaSeconds.has_value());
+    // 10 items in 5s = 2/s; 20 remaining.
+    QCOMPARE(progress.itemsPerSecond, 2.0);
+    QCOMPARE(progress.etaSeconds.value(), int64_t(10));
+}
+
+void TestIndexingProgress::testDrainedPipelineCompletesCrawl()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents", "/Users/test/Empty"}, kStartMs);
+    tracker.recordDiscovered("/Users/test/Documents/a.txt");
+    tracker.recordDiscovered("/Users/test/Documents/a.txt");
+    tracker.recordProcessed("/Users/test/Documents/a.txt", true, kStartMs + 10);
+    tracker.markScanComplete("/Users/test/Documents");
+
+    // Scan of the second root has not run yet: draining is not enough.
+    QVERIFY(!tracker.snapshot(kStartMs + 1000, true).crawlComplete);
+
+    tracker.markScanComplete("/Users/test/Empty");
+    QVERIFY(!tracker.snapshot(kStartMs + 1000, false).crawlComplete);
+
+    // The duplicate was coalesced, so processed stays short of discovered.
+    const bs::IndexingProgress progress = tracker.snapshot(kStartMs + 1000, true);
+    QVERIFY(progress.crawlComplete);
+    QCOMPARE(progress.roots[0].percent, 100.0);
+    QCOMPARE(progress.roots[1].percent, 100.0);
+    QCOMPARE(progress.etaSeconds.value_or(-1), int64_t(0));
+}
+
+void TestIndexingProgress::testCompletedRootsReportedOnce()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents", "/Users/test/Empty", "/Users/test/Code"}, kStartMs);
+    tracker.recordDiscovered("/Users/test/Documents/a.txt");
+    tracker.recordDiscovered("/Users/test/Documents/b.txt");
+    tracker.recordDiscovered("/Users/test/Code/main.cpp");
+    tracker.recordDiscovered("/Users/test/Code/main.cpp");
+    QCOMPARE(tracker.markScanComplete("/Users/test/Documents/"), size_t(2));
+    QCOMPARE(tracker.markScanComplete("/Users/test/Empty"), size_t(0));
+
+    // Nothing written yet: only the empty root is done.
+    std::vector<bs::RootProgress> completed = tracker.takeCompletedRoots(false);
+    QCOMPARE(completed.size(), size_t(1));
+    QCOMPARE(completed[0].root, std::string("/Users/test/Empty"));
+
+    tracker.recordProcessed("/Users/test/Documents/a.txt", true, kStartMs + 10);
+    QVERIFY(tracker.takeCompletedRoots(false).empty());
+    tracker.recordProcessed("/Users/test/Documents/b.txt", true, kStartMs + 20);
+    completed = tracker.takeCompletedRoots(false);
+    QCOMPARE(completed.size(), size_t(1));
+    QCOMPARE(completed[0].root, std::string("/Users/test/Documents"));
+    QCOMPARE(completed[0].discovered, size_t(2));
+    QVERIFY(tracker.takeCompletedRoots(false).empty());
+
+    // The duplicate was coalesced; the drained pipeline finishes the root,
+    // but not before its scan has.
+    tracker.recordProcessed("/Users/test/Code/main.cpp", true, kStartMs + 30);
+    QVERIFY(tracker.takeCompletedRoots(true).empty());
+    tracker.markScanComplete("/Users/test/Code");
+    QVERIFY(tracker.takeCompletedRoots(false).empty());
+    completed = tracker.takeCompletedRoots(true);
+    QCOMPARE(completed.size(), size_t(1));
+    QCOMPARE(completed[0].root, std::string("/Users/test/Code"));
+}
+
+void TestIndexingProgress::testThroughputWindowSlides()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/r"}, kStartMs);
+    for (int i = 0; i < 60; ++i) {
+        tracker.recordProcessed("/r/early", false, kStartMs + 100);
+    }
+    tracker.recordProcessed("/r/late", false, kStartMs + 40000);
+
+    const bs::IndexingProgress progress = tracker.snapshot(kStartMs + 40000, false);
+    QCOMPARE(progress.throughputWindowMs, bs::IndexingProgressTracker::kThroughputWindowMs);
+    QVERIFY(progress.itemsPerSecond < 0.1);
+}
+
+void TestIndexingProgress::testJsonShape()
+{
+    bs::IndexingProgressTracker tracker;
+    tracker.reset({"/Users/test/Documents"}, kStartMs);
+    tracker.recordDiscovered("/Users/test/Documents/a.txt");
+    tracker.recordDiscovered("/Users/test/Documents/b.txt");
+    tracker.recordDiscovered("/Users/test/Documents/c.txt");
+    tracker.recordProcessed("/Users/test/Documents/a.txt", true, kStartMs + 10);
+
+    const QJsonObject json = tracker.snapshot(kStartMs + 1000, false).toJson();
+    QVERIFY(json.value(QStringLiteral("etaSeconds")).isNull());
+    QCOMPARE(json.value(QStringLiteral("crawlComplete")).toBool(true), false);
+    const QJsonObject root =
+        json.value(QStringLiteral("roots")).toArray().at(0).toObject();
+    QCOMPARE(root.value(QStringLiteral("path")).toString(),
+             QStringLiteral("/Users/test/Documents"));
+    QCOMPARE(root.value(QStringLiteral("percent")).toDouble(), 33.3);
+    QCOMPARE(json.value(QStringLiteral("throughput")).toObject()
+                 .value(QStringLiteral("itemsPerSec")).toDouble(),
+             1.0);
+}
+
+QTEST_MAIN(TestIndexingProgress)
+#include "test_indexing_progress.moc"
@@ -0,0 +1,110 @@
+#include <QtTest/QtTest>
+
+#include "core/query/instant_answers.h"
+
+namespace {
+
+QString valueOf(const QString& query)
+{
+    const auto answer = bs::InstantAnswers::evaluate(query);
+    return answer.has_value() ? answer->value : QString();
+}
+
+} // namespace
+
+class TestInstantAnswers : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testArithmetic();
+    void testPercentAndFunctions();
+    void testRejectsNonExpressions();
+    void testConversions();
+    void testConversionJson();
+    void testFormatNumber();
+};
+
+void TestInstantAnswers::testArithmetic()
+{
+    const auto answer = bs::InstantAnswers::evaluate(QStringLiteral("1499*1.21"));
+    QVERIFY(answer.has_value());
+    QCOMPARE(answer->kind, bs::InstantAnswer::Kind::Calculation);
+    QCOMPARE(answer->value, QStringLiteral("1813.79"));
+    QCOMPARE(answer->display, QStringLiteral("1499*1.21 = 1813.79"));
+
+    QCOMPARE(valueOf(QStringLiteral("(3+4)^2")), QStringLiteral("49"));
+    QCOMPARE(valueOf(QStringLiteral("2^3^2")), QStringLiteral("512"));
+    QCOMPARE(valueOf(QStringLiteral("2 ** 10")), QStringLiteral("1024"));
+    QCOMPARE(valueOf(QStringLiteral("-5 + 2")), QStringLiteral("-3"));
+    QCOMPARE(valueOf(QStringLiteral("3 x 4")), QStringLiteral("12"));
+    QCOMPARE(valueOf(QStringLiteral("1,499 * 2")), QStringLiteral("2998"));
+    QCOMPARE(valueOf(QStringLiteral("10 - 3")), QStringLiteral("7"));
+}
+
+void TestInstantAnswers::testPercentAndFunctions()
+{
+    QCOMPARE(valueOf(QStringLiteral("15% of 80")), QStringLiteral("12"));
+    QCOMPARE(valueOf(QStringLiteral("200 * 15%")), QStringLiteral("30"));
+    QCOMPARE(valueOf(QStringLiteral("sqrt(2)")), QStringLiteral("1.414214"));
+    QCOMPARE(valueOf(QStringLiteral("2 * pi")), QStringLiteral("6.283185"));
+    QCOMPARE(valueOf(QStringLiteral("round(2.6)")), QStringLiteral("3"));
+}
+
+void TestInstantAnswers::testRejectsNonExpressions()
+{
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("42")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("-5")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("pi")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("quarterly report")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("2026-10-14")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("10/14/2026")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("v1.2.3 release")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("10 / 0")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("(1 + 2")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("notes in march")).has_value());
+    QVERIFY(!bs::InstantAnswers::evaluate(QStringLiteral("5 km to kg")).has_value());
+}
+
+void TestInstantAnswers::testConversions()
+{
+    const auto answer = bs::InstantAnswers::evaluate(QStringLiteral("72 f in c"));
+    QVERIFY(answer.has_value());
+    QCOMPARE(answer->kind, bs::InstantAnswer::Kind::Conversion);
+    QCOMPARE(answer->value, QStringLiteral("22.222222"));
+    QCOMPARE(answer->unit, QStringLiteral("°C"));
+    QCOMPARE(answer->display, QStringLiteral("72 °F = 22.222222 °C"));
+
+    QCOMPARE(valueOf(QStringLiteral("100 celsius to kelvin")), QStringLiteral("373.15"));
+    QCOMPARE(valueOf(QStringLiteral("5 km to mi")), QStringLiteral("3.106856"));
+    QCOMPARE(valueOf(QStringLiteral("12 in in cm")), QStringLiteral("30.48"));
+    QCOMPARE(valueOf(QStringLiteral("2 gb in mib")), QStringLiteral("1907.348633"));
+    QCOMPARE(valueOf(QStringLiteral("1.5 hours in minutes")), QStringLiteral("90"));
+    QCOMPARE(valueOf(QStringLiteral("10 Lbs to KG")), QStringLiteral("4.535924"));
+}
+
+void TestInstantAnswers::testConversionJson()
+{
+    const auto answer = bs::InstantAnswers::evaluate(QStringLiteral("1 mi in km"));
+    QVERIFY(answer.has_value());
+    const QJsonObject json = answer->toJson();
+    QCOMPARE(json.value(QStringLiteral("kind")).toString(), QStringLiteral("conversion"));
+    QCOMPARE(json.value(QStringLiteral("value")).toString(), QStringLiteral("1.609344"));
+    QCOMPARE(json.value(QStringLiteral("unit")).toString(), QStringLiteral("km"));
+    QCOMPARE(json.value(QStringLiteral("number")).toDouble(), 1.609344);
+
+    const QJsonObject calculation =
+        bs::InstantAnswers::evaluate(QStringLiteral("2 + 2")).value().toJson();
+    QCOMPARE(calculation.value(QStringLiteral("kind")).toString(), QStringLiteral("calculation"));
+    QVERIFY(!calculation.contains(QStringLiteral("unit")));
+}
+
+void TestInstantAnswers::testFormatNumber()
+{
+    QCOMPARE(bs::InstantAnswers::formatNumber(2.5), QStringLiteral("2.5"));
+    QCOMPARE(bs::InstantAnswers::formatNumber(-0.0), QStringLiteral("0"));
+    QCOMPARE(bs::InstantAnswers::formatNumber(1.0 / 3.0), QStringLiteral("0.333333"));
+    QCOMPARE(bs::InstantAnswers::formatNumber(1e20), QStringLiteral("1e+20"));
+}
+
+QTEST_MAIN(TestInstantAnswers)
+#include "test_instant_answers.moc"
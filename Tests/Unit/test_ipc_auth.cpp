@@ -0,0 +1,302 @@
+#include <QtTest/QtTest>
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/message.h"
+#include "core/ipc/socket_server.h"
+
+#include <QDir>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QLocalServer>
+#include <QLocalSocket>
+#include <QRandomGenerator>
+#include <QTemporaryDir>
+
+#include <optional>
+
+namespace {
+
+class ScopedEnvVar {
+public:
+    ScopedEnvVar(const char* key, const QByteArray& value)
+        : m_key(key)
+        , m_hadOriginal(qEnvironmentVariableIsSet(key))
+        , m_original(qgetenv(key))
+    {
+        qputenv(m_key, value);
+    }
+
+    ~ScopedEnvVar()
+    {
+        if (m_hadOriginal) {
+            qputenv(m_key, m_original);
+        } else {
+            qunsetenv(m_key);
+        }
+    }
+
+private:
+    const char* m_key;
+    bool m_hadOriginal = false;
+    QByteArray m_original;
+};
+
+QString makeShortSocketPath(const QString& tag)
+{
+    const QString token = QString::number(QRandomGenerator::global()->generate(), 16);
+    return QDir(QDir::tempPath()).filePath(
+        QStringLiteral("bs-%1-%2.sock").arg(tag.left(6), token.left(8)));
+}
+
+// SocketClient::sendRequest blocks without pumping events, so drive the
+// in-process server with a raw socket instead.
+std::optional<QJsonObject> roundTrip(QLocalSocket& socket, const QJsonObject& request)
+{
+    socket.write(bs::IpcMessage::encode(request));
+    socket.flush();
+
+    QByteArray buffer;
+    QElapsedTimer timer;
+    timer.start();
+    while (timer.elapsed() < 3000) {
+        QCoreApplication::processEvents(QEventLoop::AllEvents, 10);
+        if (socket.bytesAvailable() > 0 || socket.waitForReadyRead(10)) {
+            buffer.append(socket.readAll());
+        }
+        if (auto decoded = bs::IpcMessage::decode(buffer)) {
+            return decoded->json;
+        }
+        if (socket.state() != QLocalSocket::ConnectedState) {
+            break;
+        }
+    }
+    return std::nullopt;
+}
+
+int errorCode(const QJsonObject& message)
+{
+    return message.value(QStringLiteral("error")).toObject().value(QStringLiteral("code")).toInt();
+}
+
+} // namespace
+
+class TestIpcAuth : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testGeneratedTokensAreUniqueHex();
+    void testTokensEqual();
+    void testTokenFileIsOwnerOnly();
+    void testPeerCredentialsReportCurrentUser();
+    void testAdminMethodsRequireToken();
+    void testWithoutTokenAllClientsAreAdmin();
+    void testScopedTokenInjectsAuth();
+};
+
+void TestIpcAuth::testGeneratedTokensAreUniqueHex()
+{
+    const QByteArray first = bs::IpcAuth::generateToken();
+    const QByteArray second = bs::IpcAuth::generateToken();
+    QCOMPARE(first.size(), 64);
+    QVERIFY(first != second);
+    QVERIFY(!QByteArray::fromHex(first).isEmpty());
+}
+
+void TestIpcAuth::testTokensEqual()
+{
+    QVERIFY(bs::IpcAuth::tokensEqual("abc123", "abc123"));
+    QVERIFY(!bs::IpcAuth::tokensEqual("abc123", "abc124"));
+    QVERIFY(!bs::IpcAuth::tokensEqual("abc123", "abc12"));
+    QVERIFY(!bs::IpcAuth::tokensEqual(QByteArray(), QByteArray()));
+    QVERIFY(!bs::IpcAuth::tokensEqual("abc123", QByteArray()));
+}
+
+void TestIpcAuth::testTokenFileIsOwnerOnly()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    ScopedEnvVar runtimeDir("BETTERSPOTLIGHT_RUNTIME_DIR", dir.path().toUtf8());
+
+    const QByteArray token = bs::IpcAuth::generateToken();
+    QVERIFY(bs::IpcAuth::writeTokenFile(token));
+    QCOMPARE(bs::IpcAuth::readTokenFile(), token);
+
+    const QFileDevice::Permissions permissions =
+        QFile::permissions(bs::IpcAuth::tokenFilePath());
+    QVERIFY(permissions.testFlag(QFileDevice::ReadOwner));
+    QVERIFY(!permissions.testFlag(QFileDevice::ReadGroup));
+    QVERIFY(!permissions.testFlag(QFileDevice::ReadOther));
+
+    bs::IpcAuth::removeTokenFile();
+    QVERIFY(!QFile::exists(bs::IpcAuth::tokenFilePath()));
+    QVERIFY(bs::IpcAuth::readTokenFile().isEmpty());
+}
+
+void TestIpcAuth::testPeerCredentialsReportCurrentUser()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("peer"));
+    QLocalServer server;
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket client;
+    client.connectToServer(socketPath);
+    if (!client.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+    QVERIFY(server.waitForNewConnection(3000));
+    QLocalSocket* serverSide = server.nextPendingConnection();
+    QVERIFY(serverSide != nullptr);
+
+    const auto creds = bs::IpcAuth::peerCredentials(serverSide->socketDescriptor());
+    QVERIFY(creds.has_value());
+    QCOMPARE(creds->uid, bs::IpcAuth::currentUid());
+
+    QVERIFY(!bs::IpcAuth::peerCredentials(-1).has_value());
+    server.close();
+}
+
+void TestIpcAuth::testAdminMethodsRequireToken()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("admin"));
+    const QByteArray token = bs::IpcAuth::generateToken();
+
+    bs::SocketServer server;
+    server.setAdminToken(token);
+    server.setAdminMethodPredicate([](const QString& method) {
+        return method == QLatin1String("rebuildAll");
+    });
+    server.setRequestHandler([](const QJsonObject& request) {
+        const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+        return bs::IpcMessage::makeResponse(id, QJsonObject{{QStringLiteral("ok"), true}});
+    });
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket client;
+    client.connectToServer(socketPath);
+    if (!client.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+
+    auto response = roundTrip(client, bs::IpcMessage::makeRequest(1, QStringLiteral("search"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("response"));
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(2, QStringLiteral("rebuildAll"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("error"));
+    QCOMPARE(errorCode(*response), static_cast<int>(bs::IpcErrorCode::PermissionDenied));
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(
+        3, QStringLiteral("authenticate"), QJsonObject{{QStringLiteral("token"), QStringLiteral("wrong")}}));
+    QVERIFY(response.has_value());
+    QCOMPARE(errorCode(*response), static_cast<int>(bs::IpcErrorCode::PermissionDenied));
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(
+        4, QStringLiteral("authenticate"),
+        QJsonObject{{QStringLiteral("token"), QString::fromLatin1(token)}}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("response"));
+    QCOMPARE(response->value(QStringLiteral("result")).toObject()
+                 .value(QStringLiteral("role")).toString(),
+             QStringLiteral("admin"));
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(5, QStringLiteral("rebuildAll"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("response"));
+
+    client.disconnectFromServer();
+    server.close();
+}
+
+void TestIpcAuth::testWithoutTokenAllClientsAreAdmin()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("legacy"));
+
+    bs::SocketServer server;
+    server.setAdminMethodPredicate([](const QString& method) {
+        return method == QLatin1String("rebuildAll");
+    });
+    server.setRequestHandler([](const QJsonObject& request) {
+        const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+        return bs::IpcMessage::makeResponse(id, QJsonObject());
+    });
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket client;
+    client.connectToServer(socketPath);
+    if (!client.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+
+    const auto response =
+        roundTrip(client, bs::IpcMessage::makeRequest(1, QStringLiteral("rebuildAll"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("response"));
+
+    client.disconnectFromServer();
+    server.close();
+}
+
+void TestIpcAuth::testScopedTokenInjectsAuth()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("scoped"));
+    const QByteArray token = bs::IpcAuth::generateToken();
+    const QJsonObject scope{{QStringLiteral("tokenId"), QStringLiteral("abc123")}};
+
+    bs::SocketServer server;
+    server.setAdminToken(token);
+    server.setAdminMethodPredicate([](const QString& method) {
+        return method == QLatin1String("rebuildAll");
+    });
+    server.setScopedTokenVerifier([&](const QByteArray& provided) -> std::optional<QJsonObject> {
+        if (provided == "bst_plugin") {
+            return scope;
+        }
+        return std::nullopt;
+    });
+    // Echo what reached the handler so the test can see the injected field.
+    server.setRequestHandler([](const QJsonObject& request) {
+        const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+        return bs::IpcMessage::makeResponse(
+            id, QJsonObject{{QStringLiteral("auth"), request.value(QStringLiteral("auth"))}});
+    });
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket client;
+    client.connectToServer(socketPath);
+    if (!client.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+
+    // A client cannot claim a scope on its own.
+    QJsonObject forged = bs::IpcMessage::makeRequest(1, QStringLiteral("search"), {});
+    forged[QStringLiteral("auth")] = scope;
+    auto response = roundTrip(client, forged);
+    QVERIFY(response.has_value());
+    QVERIFY(response->value(QStringLiteral("result")).toObject()
+                .value(QStringLiteral("auth")).isUndefined());
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(
+        2, QStringLiteral("authenticate"),
+        QJsonObject{{QStringLiteral("token"), QStringLiteral("bst_plugin")}}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("result")).toObject()
+                 .value(QStringLiteral("role")).toString(),
+             QStringLiteral("scoped"));
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(3, QStringLiteral("search"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("result")).toObject()
+                 .value(QStringLiteral("auth")).toObject(),
+             scope);
+
+    response = roundTrip(client, bs::IpcMessage::makeRequest(4, QStringLiteral("rebuildAll"), {}));
+    QVERIFY(response.has_value());
+    QCOMPARE(errorCode(*response), static_cast<int>(bs::IpcErrorCode::PermissionDenied));
+
+    client.disconnectFromServer();
+    server.close();
+}
+
+QTEST_MAIN(TestIpcAuth)
+#include "test_ipc_auth.moc"
@@ -0,0 +1,85 @@
+#include <QtTest/QtTest>
+
+#include "cli/launch_agent.h"
+
+class TestLaunchAgent : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRenderPlistRestartsOnlyAfterCrash();
+    void testRenderPlistEscapesValues();
+    void testParseLaunchctlPrintRunningJob();
+    void testParseLaunchctlPrintEmptyOutputMeansNotLoaded();
+};
+
+void TestLaunchAgent::testRenderPlistRestartsOnlyAfterCrash()
+{
+    bs::LaunchAgentConfig config;
+    config.label = bs::LaunchAgent::defaultLabel();
+    config.programPath = QStringLiteral("/Applications/BetterSpotlight.app/Contents/MacOS/betterspotlight");
+    config.stdoutPath = QStringLiteral("/tmp/bs/agent.log");
+    config.throttleIntervalSecs = 15;
+
+    const QString plist = QString::fromUtf8(bs::LaunchAgent::renderPlist(config));
+    QVERIFY(plist.contains(QStringLiteral("<string>com.betterspotlight.agent</string>")));
+    QVERIFY(plist.contains(QStringLiteral("<key>RunAtLoad</key>\n    <true/>")));
+    QVERIFY(plist.contains(QStringLiteral(
+        "<key>SuccessfulExit</key>\n        <false/>")));
+    QVERIFY(plist.contains(QStringLiteral("<integer>15</integer>")));
+    QVERIFY(plist.contains(QStringLiteral("<key>StandardOutPath</key>\n    <string>/tmp/bs/agent.log</string>")));
+    QVERIFY(!plist.contains(QStringLiteral("StandardErrorPath")));
+}
+
+void TestLaunchAgent::testRenderPlistEscapesValues()
+{
+    bs::LaunchAgentConfig config;
+    config.label = QStringLiteral("com.example.agent");
+    config.programPath = QStringLiteral("/Users/a&b/Apps/<bs>");
+    config.arguments = {QStringLiteral("--flag=\"x\"")};
+
+    const QString plist = QString::fromUtf8(bs::LaunchAgent::renderPlist(config));
+    QVERIFY(plist.contains(QStringLiteral("<string>/Users/a&amp;b/Apps/&lt;bs&gt;</string>")));
+    QVERIFY(plist.contains(QStringLiteral("<string>--flag=&quot;x&quot;</string>")));
+}
+
+void TestLaunchAgent::testParseLaunchctlPrintRunningJob()
+{
+    const QString output = QStringLiteral(
+        "gui/501/com.betterspotlight.agent = {\n"
+        "\tactive count = 1\n"
+        "\tpath = /Users/alice/Library/LaunchAgents/com.betterspotlight.agent.plist\n"
+        "\tstate = running\n"
+        "\n"
+        "\tprogram = /Applications/BetterSpotlight.app/Contents/MacOS/betterspotlight\n"
+        "\truns = 3\n"
+        "\tpid = 4242\n"
+        "\tlast exit code = 1\n"
+        "\n"
+        "\tendpoints = {\n"
+        "\t\tstate = inactive\n"
+        "\t}\n"
+        "}\n");
+
+    const bs::LaunchAgentStatus status = bs::LaunchAgent::parseLaunchctlPrint(output);
+    QVERIFY(status.loaded);
+    QCOMPARE(status.state, QStringLiteral("running"));
+    QCOMPARE(status.pid, qint64(4242));
+    QCOMPARE(status.runs, 3);
+    QVERIFY(status.lastExitCode.has_value());
+    QCOMPARE(status.lastExitCode.value(), 1);
+}
+
+void TestLaunchAgent::testParseLaunchctlPrintEmptyOutputMeansNotLoaded()
+{
+    const bs::LaunchAgentStatus status = bs::LaunchAgent::parseLaunchctlPrint(QString());
+    QVERIFY(!status.loaded);
+    QVERIFY(status.state.isEmpty());
+
+    const bs::LaunchAgentStatus neverExited = bs::LaunchAgent::parseLaunchctlPrint(
+        QStringLiteral("\tstate = running\n\tlast exit code = (never exited)\n"));
+    QVERIFY(neverExited.loaded);
+    QVERIFY(!neverExited.lastExitCode.has_value());
+}
+
+QTEST_MAIN(TestLaunchAgent)
+#include "test_launch_agent.moc"
@@ -0,0 +1,200 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/logging.h"
+
+#include <QDir>
+#include <QFile>
+#include <QJsonDocument>
+#include <QTemporaryDir>
+
+class TestLogging : public QObject {
+    Q_OBJECT
+
+private slots:
+    void cleanup();
+
+    void testConsoleRecord();
+    void testJsonRecord();
+    void testParseNames();
+    void testOptionsFromEnvironment();
+    void testModuleOverrides();
+    void testRotation();
+};
+
+void TestLogging::cleanup()
+{
+    qInstallMessageHandler(nullptr);
+    bs::Logging::Options options;
+    options.defaultLevel = bs::Logging::Level::Debug;
+    bs::Logging::install(QStringLiteral("test"), options);
+    qInstallMessageHandler(nullptr);
+}
+
+void TestLogging::testConsoleRecord()
+{
+    // 2026-03-10T00:26:40.123Z
+    const qint64 ts = 1773102400123LL;
+    QCOMPARE(QString::fromUtf8(bs::Logging::formatRecord(
+                 bs::Logging::Format::Console, ts, QtWarningMsg, QStringLiteral("indexer"),
+                 QStringLiteral("bs.extraction"), QStringLiteral("PDF extraction timed out"))),
+             QStringLiteral("2026-03-10T00:26:40.123Z WARN  [indexer] bs.extraction: "
+                            "PDF extraction timed out"));
+    QVERIFY(bs::Logging::formatRecord(bs::Logging::Format::Console, ts, QtDebugMsg,
+                                      QStringLiteral("query"), QStringLiteral("bs.ipc"),
+                                      QStringLiteral("x"), "4bf92f3577b34da6a3ce929d0e0e4736")
+                .endsWith(" trace=4bf92f3577b34da6a3ce929d0e0e4736"));
+}
+
+void TestLogging::testJsonRecord()
+{
+    const QByteArray line = bs::Logging::formatRecord(
+        bs::Logging::Format::Json, 1773102400123LL, QtCriticalMsg, QStringLiteral("query"),
+        QStringLiteral("bs.vector"), QStringLiteral("load failed: \"meta\"\nsecond line"),
+        "4bf92f3577b34da6a3ce929d0e0e4736");
+    QVERIFY(!line.contains('\n'));
+
+    const QJsonObject record = QJsonDocument::fromJson(line).object();
+    QCOMPARE(record.value(QStringLiteral("ts")).toDouble(), 1773102400.123);
+    QCOMPARE(record.value(QStringLiteral("level")).toString(), QStringLiteral("critical"));
+    QCOMPARE(record.value(QStringLiteral("service")).toString(), QStringLiteral("query"));
+    QCOMPARE(record.value(QStringLiteral("module")).toString(), QStringLiteral("bs.vector"));
+    QCOMPARE(record.value(QStringLiteral("msg")).toString(),
+             QStringLiteral("load failed: \"meta\"\nsecond line"));
+    QCOMPARE(record.value(QStringLiteral("traceId")).toString(),
+             QStringLiteral("4bf92f3577b34da6a3ce929d0e0e4736"));
+
+    const QJsonObject untraced = QJsonDocument::fromJson(bs::Logging::formatRecord(
+        bs::Logging::Format::Json, 0, QtInfoMsg, QStringLiteral("query"),
+        QStringLiteral("bs.ipc"), QStringLiteral("x"))).object();
+    QVERIFY(!untraced.contains(QStringLiteral("traceId")));
+}
+
+void TestLogging::testParseNames()
+{
+    QCOMPARE(bs::Logging::moduleName(QStringLiteral("extraction")).value_or(QString()),
+             QStringLiteral("bs.extraction"));
+    QCOMPARE(bs::Logging::moduleName(QStringLiteral("BS.Index")).value_or(QString()),
+             QStringLiteral("bs.index"));
+    QVERIFY(!bs::Logging::moduleName(QStringLiteral("qt.network")).has_value());
+    QVERIFY(!bs::Logging::moduleName(QStringLiteral("extractor")).has_value());
+
+    QVERIFY(bs::Logging::parseLevel(QStringLiteral("WARN")) == bs::Logging::Level::Warning);
+    QVERIFY(bs::Logging::parseLevel(QStringLiteral("error")) == bs::Logging::Level::Critical);
+    QVERIFY(!bs::Logging::parseLevel(QStringLiteral("verbose")).has_value());
+    QCOMPARE(bs::Logging::levelName(bs::Logging::Level::Debug), QStringLiteral("debug"));
+}
+
+void TestLogging::testOptionsFromEnvironment()
+{
+    qputenv("BETTERSPOTLIGHT_LOG_FORMAT", "json");
+    qputenv("BETTERSPOTLIGHT_LOG_LEVEL", "warning");
+    qputenv("BETTERSPOTLIGHT_LOG_MODULES", "extraction=debug, bs.ipc=error,bogus=debug,fs");
+    qputenv("BETTERSPOTLIGHT_LOG_DIR", "-");
+    qputenv("BETTERSPOTLIGHT_LOG_MAX_BYTES", "100");
+    qputenv("BETTERSPOTLIGHT_LOG_MAX_FILES", "3");
+
+    QStringList warnings;
+    const bs::Logging::Options options = bs::Logging::optionsFromEnvironment(&warnings);
+    QVERIFY(options.format == bs::Logging::Format::Json);
+    QVERIFY(options.defaultLevel == bs::Logging::Level::Warning);
+    QCOMPARE(options.moduleLevels.size(), 2);
+    QVERIFY(options.moduleLevels.value(QStringLiteral("bs.extraction"))
+            == bs::Logging::Level::Debug);
+    QVERIFY(options.moduleLevels.value(QStringLiteral("bs.ipc")) == bs::Logging::Level::Critical);
+    QVERIFY(options.directory.isEmpty());
+    QCOMPARE(options.maxFileBytes, bs::Logging::kDefaultMaxFileBytes);
+    QCOMPARE(options.maxFiles, 3);
+    // bogus=debug, fs and the tiny max bytes.
+    QCOMPARE(warnings.size(), 3);
+
+    qunsetenv("BETTERSPOTLIGHT_LOG_DIR");
+    qputenv("BETTERSPOTLIGHT_DATA_DIR", "/tmp/bs-logging-test");
+    QCOMPARE(bs::Logging::optionsFromEnvironment().directory,
+             QStringLiteral("/tmp/bs-logging-test/logs"));
+
+    for (const char* name : {"BETTERSPOTLIGHT_LOG_FORMAT", "BETTERSPOTLIGHT_LOG_LEVEL",
+                             "BETTERSPOTLIGHT_LOG_MODULES", "BETTERSPOTLIGHT_LOG_MAX_BYTES",
+                             "BETTERSPOTLIGHT_LOG_MAX_FILES", "BETTERSPOTLIGHT_DATA_DIR"}) {
+        qunsetenv(name);
+    }
+}
+
+void TestLogging::testModuleOverrides()
+{
+    bs::Logging::Options options;
+    options.defaultLevel = bs::Logging::Level::Warning;
+    options.moduleLevels.insert(QStringLiteral("bs.extraction"), bs::Logging::Level::Debug);
+    bs::Logging::install(QStringLiteral("test"), options);
+
+    QVERIFY(bsExtraction().isDebugEnabled());
+    QVERIFY(!bsIndex().isInfoEnabled());
+    QVERIFY(bsIndex().isWarningEnabled());
+    QVERIFY(bsIndex().isCriticalEnabled());
+
+    // Runtime changes reach categories that already exist.
+    QVERIFY(bs::Logging::setModuleLevel(QStringLiteral("index"), bs::Logging::Level::Info));
+    QVERIFY(bsIndex().isInfoEnabled());
+    QVERIFY(!bsIndex().isDebugEnabled());
+    QVERIFY(bs::Logging::setModuleLevel(QStringLiteral("extraction"), std::nullopt));
+    QVERIFY(!bsExtraction().isDebugEnabled());
+    QVERIFY(!bsExtraction().isInfoEnabled());
+    QVERIFY(!bs::Logging::setModuleLevel(QStringLiteral("nope"), bs::Logging::Level::Debug));
+
+    bs::Logging::setDefaultLevel(bs::Logging::Level::Critical);
+    QVERIFY(!bsFs().isWarningEnabled());
+    QVERIFY(bsIndex().isInfoEnabled());  // still overridden
+
+    const QJsonObject described = bs::Logging::describe();
+    QCOMPARE(described.value(QStringLiteral("default")).toString(), QStringLiteral("critical"));
+    QVERIFY(described.value(QStringLiteral("file")).isNull());
+    const QJsonObject index = described.value(QStringLiteral("modules")).toObject()
+        .value(QStringLiteral("bs.index")).toObject();
+    QCOMPARE(index.value(QStringLiteral("level")).toString(), QStringLiteral("info"));
+    QVERIFY(index.value(QStringLiteral("override")).toBool());
+    const QJsonObject fs = described.value(QStringLiteral("modules")).toObject()
+        .value(QStringLiteral("bs.fs")).toObject();
+    QCOMPARE(fs.value(QStringLiteral("level")).toString(), QStringLiteral("critical"));
+    QVERIFY(!fs.value(QStringLiteral("override")).toBool());
+}
+
+void TestLogging::testRotation()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+
+    bs::Logging::Options options;
+    options.format = bs::Logging::Format::Json;
+    options.defaultLevel = bs::Logging::Level::Info;
+    options.directory = dir.path();
+    options.maxFileBytes = 400;
+    options.maxFiles = 3;
+    bs::Logging::install(QStringLiteral("indexer"), options);
+    QCOMPARE(bs::Logging::describe().value(QStringLiteral("file")).toString(),
+             QDir(dir.path()).filePath(QStringLiteral("indexer.log")));
+
+    for (int i = 0; i < 40; ++i) {
+        qCInfo(bsIndex, "message %d with some padding to fill the file", i);
+    }
+    qCDebug(bsIndex, "filtered out");
+    qInstallMessageHandler(nullptr);
+
+    const QDir logDir(dir.path());
+    QVERIFY(logDir.exists(QStringLiteral("indexer.log")));
+    QVERIFY(logDir.exists(QStringLiteral("indexer.1.log")));
+    QVERIFY(logDir.exists(QStringLiteral("indexer.2.log")));
+    QVERIFY(!logDir.exists(QStringLiteral("indexer.3.log")));
+
+    QFile current(logDir.filePath(QStringLiteral("indexer.log")));
+    QVERIFY(current.open(QIODevice::ReadOnly));
+    const QByteArray contents = current.readAll();
+    QVERIFY(contents.size() <= 400);
+    const QList<QByteArray> lines = contents.trimmed().split('\n');
+    const QJsonObject last = QJsonDocument::fromJson(lines.last()).object();
+    QCOMPARE(last.value(QStringLiteral("msg")).toString(),
+             QStringLiteral("message 39 with some padding to fill the file"));
+    QCOMPARE(last.value(QStringLiteral("service")).toString(), QStringLiteral("indexer"));
+    QVERIFY(!contents.contains("filtered out"));
+}
+
+QTEST_MAIN(TestLogging)
+#include "test_logging.moc"
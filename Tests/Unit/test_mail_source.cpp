@@ -0,0 +1,236 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/mail_source.h"
+
+#include <QTemporaryDir>
+
+namespace {
+
+QByteArray emlx(const QByteArray& message)
+{
+    return QByteArray::number(message.size()) + '\n' + message
+           + QByteArrayLiteral("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<plist version=\"1.0\">"
+                               "<dict><key>flags</key><integer>8590195713</integer></dict></plist>\n");
+}
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+bool touch(const QDir& root, const QString& path)
+{
+    if (!root.mkpath(QFileInfo(root.filePath(path)).path())) {
+        return false;
+    }
+    QFile file(root.filePath(path));
+    return file.open(QIODevice::WriteOnly);
+}
+
+} // namespace
+
+class TestMailSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseEmlx();
+    void testEncodedWords();
+    void testMultipartPrefersPlainText();
+    void testHtmlOnlyBody();
+    void testItem();
+    void testStoreLayout();
+    void testAccounts();
+};
+
+void TestMailSource::testParseEmlx()
+{
+    const QByteArray message =
+        "Message-ID: <1234@mail.example.com>\r\n"
+        "From: Ada Lovelace <ada@example.com>\r\n"
+        "To: Bob <bob@example.com>, \"Babbage, Charles\" <charles@example.com>\r\n"
+        "Cc: grace@example.com\r\n"
+        "Subject: Quarterly\r\n"
+        " plan\r\n"
+        "Date: Tue, 14 Oct 2025 09:30:00 +0200 (CEST)\r\n"
+        "\r\n"
+        "Numbers are in.\r\n";
+    const auto parsed = bs::MailSource::parseEmlx(emlx(message));
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->messageId, QStringLiteral("1234@mail.example.com"));
+    QCOMPARE(parsed->subject, QStringLiteral("Quarterly plan"));
+    QCOMPARE(parsed->from, QStringLiteral("Ada Lovelace <ada@example.com>"));
+    QCOMPARE(parsed->to, QStringList({QStringLiteral("Bob <bob@example.com>"),
+                                      QStringLiteral("\"Babbage, Charles\" <charles@example.com>"),
+                                      QStringLiteral("grace@example.com")}));
+    QCOMPARE(parsed->date, 1760427000.0);
+    // The trailing plist is not part of the message.
+    QCOMPARE(parsed->body, QStringLiteral("Numbers are in."));
+
+    QVERIFY(!bs::MailSource::parseEmlx(QByteArrayLiteral("not a message")).has_value());
+}
+
+void TestMailSource::testEncodedWords()
+{
+    const QByteArray message =
+        "From: =?utf-8?q?Andr=C3=A9?= <andre@example.com>\n"
+        "Subject: =?UTF-8?B?UXVhcnRlcmx5?= =?UTF-8?Q?_r=C3=A9view?=\n"
+        "To: =?ISO-8859-1?Q?Ren=E9e?= <renee@example.com>\n"
+        "\n"
+        "Body\n";
+    const auto parsed = bs::MailSource::parseMessage(message);
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->from, QStringLiteral("André <andre@example.com>"));
+    QCOMPARE(parsed->subject, QStringLiteral("Quarterly réview"));
+    QCOMPARE(parsed->to, QStringList{QStringLiteral("Renée <renee@example.com>")});
+}
+
+void TestMailSource::testMultipartPrefersPlainText()
+{
+    const QByteArray message =
+        "From: ada@example.com\n"
+        "Subject: Plan\n"
+        "Content-Type: multipart/mixed; boundary=\"outer\"\n"
+        "\n"
+        "This is a multi-part message in MIME format.\n"
+        "--outer\n"
+        "Content-Type: multipart/alternative; boundary=inner\n"
+        "\n"
+        "--inner\n"
+        "Content-Type: text/plain; charset=utf-8\n"
+        "Content-Transfer-Encoding: base64\n"
+        "\n"
+        "SGVsbG8gQWRhLApUaGUgcGxhbiBpcyBhdHRhY2hlZC4K\n"
+        "--inner\n"
+        "Content-Type: text/html; charset=utf-8\n"
+        "\n"
+        "<p>Hello Ada, <b>HTML</b></p>\n"
+        "--inner--\n"
+        "--outer\n"
+        "Content-Type: application/pdf; name=plan.pdf\n"
+        "Content-Disposition: attachment; filename=plan.pdf\n"
+        "Content-Transfer-Encoding: base64\n"
+        "\n"
+        "JVBERi0xLjQK\n"
+        "--outer--\n";
+    const auto parsed = bs::MailSource::parseMessage(message);
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->body, QStringLiteral("Hello Ada,\nThe plan is attached."));
+}
+
+void TestMailSource::testHtmlOnlyBody()
+{
+    const QByteArray message =
+        "From: ada@example.com\n"
+        "Subject: Menu\n"
+        "Content-Type: text/html; charset=\"utf-8\"\n"
+        "Content-Transfer-Encoding: quoted-printable\n"
+        "\n"
+        "<html><head><style>p { color: red; }</style></head><body><p>Caf=C3=A9 &amp; ca=\n"
+        "ke&#33;</p></body></html>\n";
+    const auto parsed = bs::MailSource::parseMessage(message);
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->body, QStringLiteral("Café & cake!"));
+}
+
+void TestMailSource::testItem()
+{
+    bs::MailMessage message;
+    message.messageId = QStringLiteral("1234@mail.example.com");
+    message.subject = QStringLiteral("Quarterly plan");
+    message.from = QStringLiteral("Ada Lovelace <ada@example.com>");
+    message.to = {QStringLiteral("bob@example.com")};
+    message.date = 1760427000.0;
+    message.body = QStringLiteral("Numbers are in.");
+    bs::MailMailbox mailbox;
+    mailbox.accountId = QStringLiteral("IMAP-ada@imap.example.com");
+    mailbox.name = QStringLiteral("INBOX");
+
+    const QString identifier = bs::MailSource::identifier(
+        mailbox, QStringLiteral("/x/INBOX.mbox/UUID/Data/4/Messages/4242.emlx"));
+    QCOMPARE(identifier, QStringLiteral("IMAP-ada@imap.example.com/INBOX/4242"));
+    QCOMPARE(bs::MailSource::identifier(mailbox, QStringLiteral("/x/4242.partial.emlx")),
+             identifier);
+
+    const bs::DonatedItem item = bs::MailSource::item(message, mailbox, identifier);
+    QCOMPARE(item.path, QStringLiteral("donated://com.apple.mail/") + identifier);
+    QCOMPARE(item.name, QStringLiteral("Quarterly plan"));
+    QCOMPARE(item.modifiedAt, 1760427000.0);
+    QVERIFY(item.textContent.contains(QStringLiteral("bob@example.com")));
+    QVERIFY(item.textContent.contains(QStringLiteral("Numbers are in.")));
+    QCOMPARE(attributeValue(item, "authors"), message.from);
+    QCOMPARE(attributeValue(item, "recipients"), QStringLiteral("bob@example.com"));
+    QCOMPARE(attributeValue(item, "mailbox"), QStringLiteral("INBOX"));
+    QCOMPARE(attributeValue(item, "account"), mailbox.accountId);
+    QCOMPARE(attributeValue(item, "contentDescription"), QStringLiteral("Ada Lovelace · INBOX"));
+    QCOMPARE(attributeValue(item, "url"),
+             QStringLiteral("message://%3C1234@mail.example.com%3E"));
+    QVERIFY(!item.contentHash.isEmpty());
+
+    message.subject.clear();
+    QCOMPARE(bs::MailSource::item(message, mailbox, identifier).name,
+             QStringLiteral("(No Subject)"));
+}
+
+void TestMailSource::testStoreLayout()
+{
+    QTemporaryDir home;
+    QVERIFY(home.isValid());
+    const QDir root(home.path());
+    const QString account = QStringLiteral("Library/Mail/V10/IMAP-ada@imap.example.com");
+    QVERIFY(root.mkpath(QStringLiteral("Library/Mail/V9")));
+    QVERIFY(root.mkpath(QStringLiteral("Library/Mail/V10/MailData/Signatures")));
+    QVERIFY(touch(root, account + QStringLiteral("/INBOX.mbox/AB12/Data/1/Messages/1.emlx")));
+    QVERIFY(touch(root, account + QStringLiteral("/INBOX.mbox/AB12/Data/2/Messages/2.partial.emlx")));
+    QVERIFY(touch(root, account + QStringLiteral("/INBOX.mbox/Info.plist")));
+    QVERIFY(touch(root, account + QStringLiteral("/Archive.mbox/2026.mbox/CD34/Data/Messages/3.emlx")));
+
+    const QString store = bs::MailSource::storePath(home.path());
+    QCOMPARE(store, root.filePath(QStringLiteral("Library/Mail/V10")));
+
+    const auto mailboxes = bs::MailSource::mailboxes(store);
+    QCOMPARE(mailboxes.size(), size_t(3));
+    QCOMPARE(mailboxes.at(0).name, QStringLiteral("Archive"));
+    QCOMPARE(mailboxes.at(1).name, QStringLiteral("Archive/2026"));
+    QCOMPARE(mailboxes.at(2).name, QStringLiteral("INBOX"));
+    QCOMPARE(mailboxes.at(2).accountId, QStringLiteral("IMAP-ada@imap.example.com"));
+
+    // A nested mailbox's messages are not its parent's.
+    QVERIFY(bs::MailSource::messageFiles(mailboxes.at(0)).isEmpty());
+    QCOMPARE(bs::MailSource::messageFiles(mailboxes.at(1)).size(), 1);
+    const QStringList inbox = bs::MailSource::messageFiles(mailboxes.at(2));
+    QCOMPARE(inbox.size(), 2);
+    QVERIFY(inbox.at(0).endsWith(QStringLiteral("/1.emlx")));
+    QVERIFY(inbox.at(1).endsWith(QStringLiteral("/2.partial.emlx")));
+
+    QVERIFY(bs::MailSource::storePath(root.filePath(QStringLiteral("nowhere"))).isEmpty());
+}
+
+void TestMailSource::testAccounts()
+{
+    QCOMPARE(bs::MailSource::accountAddress(QStringLiteral("IMAP-ada@imap.example.com")),
+             QStringLiteral("ada@imap.example.com"));
+    QCOMPARE(bs::MailSource::accountAddress(QStringLiteral("POP-ada@example.com@pop.example.com")),
+             QStringLiteral("ada@example.com"));
+    QVERIFY(bs::MailSource::accountAddress(
+                QStringLiteral("5A1C0F3E-8D2B-4E77-9C1A-2B6F0D9E4A11")).isEmpty());
+
+    QCOMPARE(bs::MailSource::address(QStringLiteral("Ada <Ada@Example.com>")),
+             QStringLiteral("ada@example.com"));
+
+    const QStringList excluded = {QStringLiteral("work@example.com"),
+                                  QStringLiteral("5a1c0f3e-8d2b-4e77-9c1a-2b6f0d9e4a11")};
+    QVERIFY(bs::MailSource::isExcluded(QStringLiteral("5A1C0F3E-8D2B-4E77-9C1A-2B6F0D9E4A11"),
+                                       {}, excluded));
+    QVERIFY(bs::MailSource::isExcluded(QStringLiteral("IMAP-work@imap.example.com"),
+                                       {QStringLiteral("Work@Example.com")}, excluded));
+    QVERIFY(!bs::MailSource::isExcluded(QStringLiteral("IMAP-ada@imap.example.com"),
+                                        {QStringLiteral("ada@imap.example.com")}, excluded));
+}
+
+QTEST_MAIN(TestMailSource)
+#include "test_mail_source.moc"
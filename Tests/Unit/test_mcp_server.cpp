@@ -0,0 +1,212 @@
+#include <QtTest/QtTest>
+
+#include "cli/mcp_server.h"
+#include "core/ipc/message.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+
+#include <vector>
+
+namespace {
+
+struct RecordedCall {
+    QString method;
+    QJsonObject params;
+};
+
+QJsonObject makeCall(int id, const QString& method, const QJsonObject& params = {})
+{
+    QJsonObject message;
+    message[QStringLiteral("jsonrpc")] = QStringLiteral("2.0");
+    message[QStringLiteral("id")] = id;
+    message[QStringLiteral("method")] = method;
+    message[QStringLiteral("params")] = params;
+    return message;
+}
+
+QJsonObject toolCall(int id, const QString& name, const QJsonObject& arguments)
+{
+    QJsonObject params;
+    params[QStringLiteral("name")] = name;
+    params[QStringLiteral("arguments")] = arguments;
+    return makeCall(id, QStringLiteral("tools/call"), params);
+}
+
+} // namespace
+
+class TestMcpServer : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testInitializeAndToolsList();
+    void testNotificationsGetNoResponse();
+    void testSearchToolMapsToIpcSearch();
+    void testFetchDocumentRequestsContent();
+    void testServiceErrorsAreReportedInBand();
+    void testProtocolErrors();
+};
+
+void TestMcpServer::testInitializeAndToolsList()
+{
+    bs::McpServer server([](const QString&, const QJsonObject&, QString*) {
+        return std::optional<QJsonObject>();
+    });
+
+    QJsonObject initParams;
+    initParams[QStringLiteral("protocolVersion")] = QStringLiteral("2025-03-26");
+    const auto init = server.handleMessage(makeCall(1, QStringLiteral("initialize"), initParams));
+    QVERIFY(init.has_value());
+    const QJsonObject initResult = init->value(QStringLiteral("result")).toObject();
+    QCOMPARE(initResult.value(QStringLiteral("protocolVersion")).toString(),
+             QStringLiteral("2025-03-26"));
+    QVERIFY(initResult.value(QStringLiteral("capabilities")).toObject()
+                .contains(QStringLiteral("tools")));
+
+    // A revision we do not speak, or none, gets ours back.
+    for (const QString& offered : {QStringLiteral("2025-06-18"), QString()}) {
+        QJsonObject otherParams;
+        if (!offered.isEmpty()) {
+            otherParams[QStringLiteral("protocolVersion")] = offered;
+        }
+        const auto other =
+            server.handleMessage(makeCall(5, QStringLiteral("initialize"), otherParams));
+        QVERIFY(other.has_value());
+        QCOMPARE(other->value(QStringLiteral("result")).toObject()
+                     .value(QStringLiteral("protocolVersion")).toString(),
+                 QString::fromLatin1(bs::McpServer::kProtocolVersion));
+    }
+
+    const auto list = server.handleMessage(makeCall(2, QStringLiteral("tools/list")));
+    QVERIFY(list.has_value());
+    QStringList names;
+    for (const QJsonValue& tool : list->value(QStringLiteral("result")).toObject()
+                                      .value(QStringLiteral("tools")).toArray()) {
+        names.append(tool.toObject().value(QStringLiteral("name")).toString());
+    }
+    QCOMPARE(names, (QStringList{QStringLiteral("search"), QStringLiteral("fetch_document"),
+                                 QStringLiteral("similar_documents")}));
+}
+
+void TestMcpServer::testNotificationsGetNoResponse()
+{
+    bs::McpServer server([](const QString&, const QJsonObject&, QString*) {
+        return std::optional<QJsonObject>();
+    });
+    QJsonObject notification;
+    notification[QStringLiteral("jsonrpc")] = QStringLiteral("2.0");
+    notification[QStringLiteral("method")] = QStringLiteral("notifications/initialized");
+    QVERIFY(!server.handleMessage(notification).has_value());
+    QVERIFY(!server.handleLine("   \n").has_value());
+}
+
+void TestMcpServer::testSearchToolMapsToIpcSearch()
+{
+    std::vector<RecordedCall> calls;
+    bs::McpServer server([&calls](const QString& method, const QJsonObject& params, QString*) {
+        calls.push_back({method, params});
+        QJsonObject hit;
+        hit[QStringLiteral("itemId")] = 7;
+        hit[QStringLiteral("name")] = QStringLiteral("report.md");
+        hit[QStringLiteral("path")] = QStringLiteral("/docs/report.md");
+        hit[QStringLiteral("bm25Raw")] = -3.2;
+        QJsonObject result;
+        result[QStringLiteral("results")] = QJsonArray{hit};
+        return std::optional<QJsonObject>(bs::IpcMessage::makeResponse(1, result));
+    });
+
+    QJsonObject arguments;
+    arguments[QStringLiteral("query")] = QStringLiteral("quarterly report");
+    arguments[QStringLiteral("limit")] = 500;
+    const auto response = server.handleMessage(toolCall(3, QStringLiteral("search"), arguments));
+    QVERIFY(response.has_value());
+
+    QCOMPARE(static_cast<int>(calls.size()), 1);
+    QCOMPARE(calls.front().method, QStringLiteral("search"));
+    QCOMPARE(calls.front().params.value(QStringLiteral("limit")).toInt(), 50);
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    QCOMPARE(result.value(QStringLiteral("isError")).toBool(), false);
+    QVERIFY(!result.contains(QStringLiteral("structuredContent")));
+    const QJsonObject content =
+        result.value(QStringLiteral("content")).toArray().first().toObject();
+    QCOMPARE(content.value(QStringLiteral("type")).toString(), QStringLiteral("text"));
+    const QJsonObject hit =
+        QJsonDocument::fromJson(content.value(QStringLiteral("text")).toString().toUtf8())
+            .object().value(QStringLiteral("results")).toArray().first().toObject();
+    QCOMPARE(hit.value(QStringLiteral("path")).toString(), QStringLiteral("/docs/report.md"));
+    QVERIFY(!hit.contains(QStringLiteral("bm25Raw")));
+}
+
+void TestMcpServer::testFetchDocumentRequestsContent()
+{
+    std::vector<RecordedCall> calls;
+    bs::McpServer server([&calls](const QString& method, const QJsonObject& params, QString*) {
+        calls.push_back({method, params});
+        QJsonObject result;
+        result[QStringLiteral("content")] = QStringLiteral("hello");
+        return std::optional<QJsonObject>(bs::IpcMessage::makeResponse(1, result));
+    });
+
+    QJsonObject arguments;
+    arguments[QStringLiteral("path")] = QStringLiteral("/docs/report.md");
+    QVERIFY(server.handleMessage(toolCall(4, QStringLiteral("fetch_document"), arguments))
+                .has_value());
+
+    QCOMPARE(static_cast<int>(calls.size()), 1);
+    QCOMPARE(calls.front().method, QStringLiteral("getDocument"));
+    QCOMPARE(calls.front().params.value(QStringLiteral("path")).toString(),
+             QStringLiteral("/docs/report.md"));
+    QCOMPARE(calls.front().params.value(QStringLiteral("includeContent")).toBool(), true);
+
+    // Missing selector never reaches the service.
+    const auto missing = server.handleMessage(
+        toolCall(5, QStringLiteral("similar_documents"), QJsonObject()));
+    QVERIFY(missing.has_value());
+    QCOMPARE(missing->value(QStringLiteral("result")).toObject()
+                 .value(QStringLiteral("isError")).toBool(), true);
+    QCOMPARE(static_cast<int>(calls.size()), 1);
+}
+
+void TestMcpServer::testServiceErrorsAreReportedInBand()
+{
+    bs::McpServer server([](const QString&, const QJsonObject&, QString* error) {
+        *error = QStringLiteral("query service is not running");
+        return std::optional<QJsonObject>();
+    });
+    QJsonObject arguments;
+    arguments[QStringLiteral("itemId")] = 9;
+    const auto response =
+        server.handleMessage(toolCall(6, QStringLiteral("similar_documents"), arguments));
+    QVERIFY(response.has_value());
+    QVERIFY(!response->contains(QStringLiteral("error")));
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    QCOMPARE(result.value(QStringLiteral("isError")).toBool(), true);
+    QVERIFY(result.value(QStringLiteral("content")).toArray().first().toObject()
+                .value(QStringLiteral("text")).toString().contains(QStringLiteral("not running")));
+}
+
+void TestMcpServer::testProtocolErrors()
+{
+    bs::McpServer server([](const QString&, const QJsonObject&, QString*) {
+        return std::optional<QJsonObject>();
+    });
+
+    const auto parseError = server.handleLine("{not json");
+    QVERIFY(parseError.has_value());
+    QCOMPARE(parseError->value(QStringLiteral("error")).toObject()
+                 .value(QStringLiteral("code")).toInt(), -32700);
+
+    const auto unknownMethod = server.handleMessage(makeCall(7, QStringLiteral("resources/list")));
+    QCOMPARE(unknownMethod->value(QStringLiteral("error")).toObject()
+                 .value(QStringLiteral("code")).toInt(), -32601);
+
+    const auto unknownTool = server.handleMessage(
+        toolCall(8, QStringLiteral("delete_everything"), QJsonObject()));
+    QCOMPARE(unknownTool->value(QStringLiteral("error")).toObject()
+                 .value(QStringLiteral("code")).toInt(), -32602);
+    QCOMPARE(unknownTool->value(QStringLiteral("id")).toInt(), 8);
+}
+
+QTEST_MAIN(TestMcpServer)
+#include "test_mcp_server.moc"
@@ -0,0 +1,193 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/messages_source.h"
+
+#include <QTemporaryDir>
+
+#include <sqlite3.h>
+
+namespace {
+
+// A typedstream NSAttributedString whose string is "On my way", with its
+// class chain in front and an attribute run after it.
+const char* kOnMyWayHex =
+    "040b73747265616d747970656481e803840140848484124e5341747472696275746564537472696e67008484"
+    "084e534f626a656374008592848484084e53537472696e67019484012b094f6e206d79207761798684026949"
+    "010992";
+
+constexpr double kAppleEpochOffset = 978307200.0;
+
+bool createStore(const QString& path)
+{
+    const QByteArray sql = QByteArrayLiteral(
+        "CREATE TABLE handle (ROWID INTEGER PRIMARY KEY, id TEXT);"
+        "CREATE TABLE chat (ROWID INTEGER PRIMARY KEY, guid TEXT, chat_identifier TEXT,"
+        " display_name TEXT);"
+        "CREATE TABLE message (ROWID INTEGER PRIMARY KEY, guid TEXT, text TEXT,"
+        " attributedBody BLOB, handle_id INTEGER, date INTEGER, is_from_me INTEGER,"
+        " associated_message_type INTEGER, item_type INTEGER);"
+        "CREATE TABLE chat_message_join (chat_id INTEGER, message_id INTEGER);"
+        "INSERT INTO handle VALUES (1, '+15551234567'), (2, 'ada@example.com');"
+        "INSERT INTO chat VALUES (1, 'iMessage;-;+15551234567', '+15551234567', ''),"
+        " (2, 'iMessage;+;chat42', 'chat42', 'Family');"
+        "INSERT INTO message VALUES"
+        " (1, 'GUID-1', 'Meet at 12 Baker St', NULL, 1, 800000000000000000, 0, 0, 0),"
+        " (2, 'GUID-2', NULL, X'") + QByteArray(kOnMyWayHex) + QByteArrayLiteral(
+        "', 0, 800000100000000000, 1, 0, 0),"
+        " (3, 'GUID-3', 'Loved \"Meet at 12 Baker St\"', NULL, 1, 800000200000000000, 0, 2000, 0),"
+        " (4, 'GUID-4', 'Dinner at 7?', NULL, 2, 700000000, 0, 0, 0),"
+        " (5, 'GUID-5', NULL, NULL, 2, 700000100, 0, 0, 1);"
+        "INSERT INTO chat_message_join VALUES (1, 1), (1, 2), (1, 3), (2, 4), (2, 5);");
+
+    sqlite3* db = nullptr;
+    if (sqlite3_open(path.toUtf8().constData(), &db) != SQLITE_OK) {
+        sqlite3_close(db);
+        return false;
+    }
+    const bool ok = sqlite3_exec(db, sql.constData(), nullptr, nullptr, nullptr) == SQLITE_OK;
+    sqlite3_close(db);
+    return ok;
+}
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+} // namespace
+
+class TestMessagesSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testAttributedBodyText();
+    void testReadMessages();
+    void testItems();
+    void testStaleIdentifiers();
+    void testLocalOnly();
+};
+
+void TestMessagesSource::testAttributedBodyText()
+{
+    const auto text = bs::MessagesSource::attributedBodyText(QByteArray::fromHex(kOnMyWayHex));
+    QVERIFY(text.has_value());
+    QCOMPARE(text.value(), QStringLiteral("On my way"));
+
+    // Longer strings carry a 0x81 marker and a little-endian 16-bit length.
+    const QByteArray body(300, 'a');
+    const QByteArray data = QByteArrayLiteral("NSString\x01\x94\x84\x01+\x81\x2c\x01") + body
+                            + QByteArrayLiteral("\x86\x84");
+    QCOMPARE(bs::MessagesSource::attributedBodyText(data).value_or(QString()),
+             QString::fromLatin1(body));
+
+    QVERIFY(!bs::MessagesSource::attributedBodyText(QByteArray()).has_value());
+    QVERIFY(!bs::MessagesSource::attributedBodyText(QByteArrayLiteral("NSString\x01+\x7f")).has_value());
+}
+
+void TestMessagesSource::testReadMessages()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("chat.db"));
+    QVERIFY(createStore(path));
+
+    QString error;
+    const auto messages = bs::MessagesSource::readMessages(path, 10, &error);
+    QVERIFY2(messages.has_value(), qPrintable(error));
+    // The tapback and the group event are left out; newest first.
+    QCOMPARE(messages->size(), size_t(3));
+
+    const bs::ChatMessage& sent = messages->at(0);
+    QCOMPARE(sent.guid, QStringLiteral("GUID-2"));
+    QCOMPARE(sent.text, QStringLiteral("On my way"));
+    QVERIFY(sent.fromMe);
+    QVERIFY(sent.sender.isEmpty());
+    QCOMPARE(sent.date, 800000100.0 + kAppleEpochOffset);
+    QCOMPARE(sent.conversation, QStringLiteral("iMessage;-;+15551234567"));
+    QCOMPARE(sent.conversationName, QStringLiteral("+15551234567"));
+
+    const bs::ChatMessage& received = messages->at(1);
+    QCOMPARE(received.guid, QStringLiteral("GUID-1"));
+    QCOMPARE(received.text, QStringLiteral("Meet at 12 Baker St"));
+    QCOMPARE(received.sender, QStringLiteral("+15551234567"));
+    QVERIFY(!received.fromMe);
+
+    // Seconds, as before High Sierra, rather than nanoseconds.
+    const bs::ChatMessage& group = messages->at(2);
+    QCOMPARE(group.date, 700000000.0 + kAppleEpochOffset);
+    QCOMPARE(group.sender, QStringLiteral("ada@example.com"));
+    QCOMPARE(group.conversationName, QStringLiteral("Family"));
+
+    const auto newest = bs::MessagesSource::readMessages(path, 1, &error);
+    QVERIFY(newest.has_value());
+    QCOMPARE(newest->size(), size_t(1));
+    QCOMPARE(newest->front().guid, QStringLiteral("GUID-2"));
+
+    QVERIFY(!bs::MessagesSource::readMessages(dir.filePath(QStringLiteral("missing.db")), 10,
+                                              &error).has_value());
+    QVERIFY(!error.isEmpty());
+}
+
+void TestMessagesSource::testItems()
+{
+    bs::ChatMessage message;
+    message.guid = QStringLiteral("GUID-4");
+    message.text = QStringLiteral("Dinner at 7?\nAt 221B Baker Street");
+    message.sender = QStringLiteral("ada@example.com");
+    message.date = 1778307200.0;
+    message.conversation = QStringLiteral("iMessage;+;chat42");
+    message.conversationName = QStringLiteral("Family");
+    bs::ChatMessage attachmentOnly;
+    attachmentOnly.guid = QStringLiteral("GUID-6");
+
+    const auto items = bs::MessagesSource::items({message, attachmentOnly, message});
+    QCOMPARE(items.size(), size_t(1));
+    const bs::DonatedItem& item = items.front();
+    QCOMPARE(item.path, QStringLiteral("donated://com.apple.MobileSMS/GUID-4"));
+    QCOMPARE(item.name, QStringLiteral("Dinner at 7?"));
+    QVERIFY(item.textContent.contains(QStringLiteral("221B Baker Street")));
+    QVERIFY(item.textContent.contains(QStringLiteral("ada@example.com")));
+    QVERIFY(item.textContent.contains(QStringLiteral("Family")));
+    QCOMPARE(item.modifiedAt, 1778307200.0);
+    QCOMPARE(attributeValue(item, "authors"), QStringLiteral("ada@example.com"));
+    QCOMPARE(attributeValue(item, "conversation"), QStringLiteral("iMessage;+;chat42"));
+    QCOMPARE(attributeValue(item, "conversationName"), QStringLiteral("Family"));
+    QCOMPARE(attributeValue(item, "contentDescription"), QStringLiteral("ada@example.com · Family"));
+    QCOMPARE(attributeValue(item, "url"), QStringLiteral("sms://open?message-guid=GUID-4"));
+    QVERIFY(!item.contentHash.isEmpty());
+
+    // One-to-one: the conversation is the sender, so it is not repeated.
+    message.conversationName = message.sender;
+    QCOMPARE(attributeValue(bs::MessagesSource::items({message}).front(), "contentDescription"),
+             QStringLiteral("ada@example.com"));
+    message.fromMe = true;
+    message.sender.clear();
+    QCOMPARE(attributeValue(bs::MessagesSource::items({message}).front(), "contentDescription"),
+             QStringLiteral("Me · ada@example.com"));
+}
+
+void TestMessagesSource::testStaleIdentifiers()
+{
+    bs::ChatMessage message;
+    message.guid = QStringLiteral("GUID-1");
+    message.text = QStringLiteral("Hi");
+    const QStringList stale = bs::MessagesSource::staleIdentifiers(
+        {QStringLiteral("GUID-1"), QStringLiteral("GUID-9")}, {message});
+    QCOMPARE(stale, QStringList{QStringLiteral("GUID-9")});
+}
+
+void TestMessagesSource::testLocalOnly()
+{
+    QVERIFY(bs::MessagesSource::isLocalOnly(QStringLiteral("donated://com.apple.MobileSMS/GUID-1")));
+    QVERIFY(!bs::MessagesSource::isLocalOnly(QStringLiteral("donated://com.apple.Notes/NOTE-1")));
+    QVERIFY(!bs::MessagesSource::isLocalOnly(QStringLiteral("/Users/ada/com.apple.MobileSMS")));
+    QVERIFY(!bs::MessagesSource::isLocalOnly(QString()));
+}
+
+QTEST_MAIN(TestMessagesSource)
+#include "test_messages_source.moc"
@@ -0,0 +1,244 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "core/indexing/metadata_merge.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QTemporaryDir>
+
+namespace {
+
+bs::MetadataValue value(const QJsonValue& json, double changedAt)
+{
+    bs::MetadataValue metadataValue;
+    metadataValue.value = json;
+    metadataValue.changedAt = changedAt;
+    return metadataValue;
+}
+
+QJsonArray tags(const QStringList& names)
+{
+    QJsonArray json;
+    for (const QString& name : names) {
+        json.append(name);
+    }
+    return json;
+}
+
+QByteArray canonical(const bs::DocumentMetadata& metadata)
+{
+    return QJsonDocument(bs::MetadataMerge::toJson(metadata)).toJson(QJsonDocument::Compact);
+}
+
+bs::DocumentMetadata laptopMetadata()
+{
+    bs::DocumentMetadata metadata;
+    metadata.insert(QLatin1String(bs::MetadataMerge::kTagsField),
+                    value(tags({QStringLiteral("Blue"), QStringLiteral("Work")}), 100.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kPinnedField), value(true, 300.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kOpenCountField), value(4, 250.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kLastOpenedAtField), value(250.0, 250.0));
+    return metadata;
+}
+
+bs::DocumentMetadata desktopMetadata()
+{
+    bs::DocumentMetadata metadata;
+    metadata.insert(QLatin1String(bs::MetadataMerge::kTagsField),
+                    value(tags({QStringLiteral("Red")}), 200.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kPinnedField), value(false, 400.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kOpenCountField), value(9, 380.0));
+    metadata.insert(QLatin1String(bs::MetadataMerge::kLastOpenedAtField), value(380.0, 380.0));
+    return metadata;
+}
+
+} // namespace
+
+class TestMetadataMerge : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParsePolicies();
+    void testParsePoliciesRejectsInvalid();
+    void testMergeValue();
+    void testMergeConverges();
+    void testJsonRoundTrip();
+    void testApply();
+};
+
+void TestMetadataMerge::testParsePolicies()
+{
+    QString error;
+    const auto defaults = bs::MetadataMerge::parsePolicies(QString(), &error);
+    QVERIFY(defaults.has_value());
+    QCOMPARE(defaults->value(QStringLiteral("tags")), bs::MergePolicy::Union);
+    QCOMPARE(defaults->value(QStringLiteral("pinned")), bs::MergePolicy::LastWriterWins);
+    QCOMPARE(defaults->value(QStringLiteral("openCount")), bs::MergePolicy::Max);
+    QCOMPARE(defaults->value(QStringLiteral("lastOpenedAt")), bs::MergePolicy::Max);
+
+    const auto parsed = bs::MetadataMerge::parsePolicies(
+        QStringLiteral(R"({"tags": "last-writer-wins", "pinned": "max"})"), &error);
+    QVERIFY2(parsed.has_value(), qPrintable(error));
+    QCOMPARE(parsed->value(QStringLiteral("tags")), bs::MergePolicy::LastWriterWins);
+    QCOMPARE(parsed->value(QStringLiteral("pinned")), bs::MergePolicy::Max);
+    QCOMPARE(parsed->value(QStringLiteral("openCount")), bs::MergePolicy::Max);
+
+    for (const bs::MergePolicy policy :
+         {bs::MergePolicy::LastWriterWins, bs::MergePolicy::Max, bs::MergePolicy::Union}) {
+        QCOMPARE(bs::MetadataMerge::policyFromName(bs::MetadataMerge::policyName(policy)),
+                 std::optional<bs::MergePolicy>(policy));
+    }
+}
+
+void TestMetadataMerge::testParsePoliciesRejectsInvalid()
+{
+    const QStringList invalid = {
+        QStringLiteral("[]"),
+        QStringLiteral(R"({"color": "union"})"),
+        QStringLiteral(R"({"tags": "newest"})"),
+        QStringLiteral(R"({"tags": "max"})"),
+        QStringLiteral(R"({"pinned": "union"})"),
+    };
+    for (const QString& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::MetadataMerge::parsePolicies(json, &error).has_value(), qPrintable(json));
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestMetadataMerge::testMergeValue()
+{
+    using bs::MergePolicy;
+    using bs::MetadataMerge;
+
+    // The later change wins, whichever side it is on.
+    const bs::MetadataValue pinned = value(true, 100.0);
+    const bs::MetadataValue unpinned = value(false, 200.0);
+    QCOMPARE(MetadataMerge::mergeValue(MergePolicy::LastWriterWins, pinned, unpinned).value,
+             QJsonValue(false));
+    QCOMPARE(MetadataMerge::mergeValue(MergePolicy::LastWriterWins, unpinned, pinned).value,
+             QJsonValue(false));
+    QCOMPARE(MetadataMerge::mergeValue(MergePolicy::LastWriterWins, pinned, unpinned).changedAt,
+             200.0);
+
+    // A tie is broken on the value, not the side.
+    const bs::MetadataValue tiedA = value(true, 100.0);
+    const bs::MetadataValue tiedB = value(false, 100.0);
+    QCOMPARE(MetadataMerge::mergeValue(MergePolicy::LastWriterWins, tiedA, tiedB).value,
+             MetadataMerge::mergeValue(MergePolicy::LastWriterWins, tiedB, tiedA).value);
+
+    // Max keeps the greater value and the later change time.
+    const bs::MetadataValue often = value(12, 100.0);
+    const bs::MetadataValue recent = value(3, 500.0);
+    const bs::MetadataValue opened = MetadataMerge::mergeValue(MergePolicy::Max, recent, often);
+    QCOMPARE(opened.value.toInteger(), qint64(12));
+    QCOMPARE(opened.changedAt, 500.0);
+    QCOMPARE(MetadataMerge::mergeValue(MergePolicy::Max, tiedB, tiedA).value, QJsonValue(true));
+
+    // Union is sorted and without duplicates.
+    const bs::MetadataValue merged = MetadataMerge::mergeValue(
+        MergePolicy::Union, value(tags({QStringLiteral("Work"), QStringLiteral("Blue")}), 1.0),
+        value(tags({QStringLiteral("Blue"), QStringLiteral("Red")}), 2.0));
+    QCOMPARE(merged.value.toArray(),
+             tags({QStringLiteral("Blue"), QStringLiteral("Red"), QStringLiteral("Work")}));
+}
+
+void TestMetadataMerge::testMergeConverges()
+{
+    const bs::MetadataMerge::Policies policies = bs::MetadataMerge::defaultPolicies();
+    const bs::DocumentMetadata laptop = laptopMetadata();
+    const bs::DocumentMetadata desktop = desktopMetadata();
+
+    const bs::DocumentMetadata onLaptop = bs::MetadataMerge::merge(laptop, desktop, policies);
+    const bs::DocumentMetadata onDesktop = bs::MetadataMerge::merge(desktop, laptop, policies);
+    QCOMPARE(canonical(onLaptop), canonical(onDesktop));
+    QCOMPARE(canonical(bs::MetadataMerge::merge(onLaptop, desktop, policies)), canonical(onLaptop));
+    QCOMPARE(canonical(bs::MetadataMerge::merge(onLaptop, onLaptop, policies)), canonical(onLaptop));
+
+    QCOMPARE(onLaptop.value(QStringLiteral("tags")).value.toArray(),
+             tags({QStringLiteral("Blue"), QStringLiteral("Red"), QStringLiteral("Work")}));
+    QCOMPARE(onLaptop.value(QStringLiteral("pinned")).value, QJsonValue(false));
+    QCOMPARE(onLaptop.value(QStringLiteral("openCount")).value.toInteger(), qint64(9));
+    QCOMPARE(onLaptop.value(QStringLiteral("lastOpenedAt")).value.toDouble(), 380.0);
+
+    // A field only one side has is kept.
+    bs::DocumentMetadata untagged = desktop;
+    untagged.remove(QStringLiteral("tags"));
+    QCOMPARE(bs::MetadataMerge::merge(untagged, laptop, policies).value(QStringLiteral("tags")).value,
+             laptop.value(QStringLiteral("tags")).value);
+}
+
+void TestMetadataMerge::testJsonRoundTrip()
+{
+    const bs::DocumentMetadata metadata = laptopMetadata();
+    QCOMPARE(canonical(bs::MetadataMerge::fromJson(bs::MetadataMerge::toJson(metadata))),
+             canonical(metadata));
+
+    const QJsonObject json = QJsonDocument::fromJson(QByteArrayLiteral(R"({
+        "tags": {"value": ["Work", "Blue", "Work"], "changedAt": 10},
+        "pinned": {"value": "yes", "changedAt": 10},
+        "openCount": {"value": -1},
+        "color": {"value": "red"}
+    })")).object();
+    const bs::DocumentMetadata parsed = bs::MetadataMerge::fromJson(json);
+    QCOMPARE(parsed.keys(), QStringList({QStringLiteral("tags")}));
+    QCOMPARE(parsed.value(QStringLiteral("tags")).value.toArray(),
+             tags({QStringLiteral("Blue"), QStringLiteral("Work")}));
+    QCOMPARE(parsed.value(QStringLiteral("tags")).changedAt, 10.0);
+}
+
+void TestMetadataMerge::testApply()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto store = bs::SQLiteStore::open(dir.filePath(QStringLiteral("index.db")));
+    QVERIFY(store.has_value());
+    const auto id = store->upsertItem(QStringLiteral("/Users/alice/Plans.md"),
+                                      QStringLiteral("Plans.md"), QStringLiteral(".md"),
+                                      bs::ItemKind::Markdown, 100, 1.0, 2.0);
+    QVERIFY(id.has_value());
+    QVERIFY(store->replaceItemTags(*id, QLatin1String(bs::SQLiteStore::kFinderTagSource),
+                                   {QStringLiteral("Blue")}));
+    QVERIFY(store->setItemPinned(*id, true, 100.0));
+    QVERIFY(store->setFrequency(*id, 3, 400.0));
+
+    const auto signalsOf = [&store, &id]() {
+        return store->getItemSignalsInIdRange(*id, *id).at(*id);
+    };
+    QCOMPARE(signalsOf().pinChangedAt, 100.0);
+
+    bs::DocumentMetadata remote;
+    remote.insert(QStringLiteral("tags"), value(tags({QStringLiteral("Red")}), 50.0));
+    remote.insert(QStringLiteral("pinned"), value(false, 200.0));
+    remote.insert(QStringLiteral("openCount"), value(9, 500.0));
+    remote.insert(QStringLiteral("lastOpenedAt"), value(500.0, 500.0));
+    const bs::MetadataMerge::Policies policies = bs::MetadataMerge::defaultPolicies();
+    QVERIFY(bs::MetadataMerge::apply(*store, *id, 10.0, remote, policies));
+
+    bs::SQLiteStore::ItemSignals merged = signalsOf();
+    QCOMPARE(merged.tags, QStringList({QStringLiteral("Blue"), QStringLiteral("Red")}));
+    QCOMPARE(merged.syncedTags, QStringList({QStringLiteral("Red")}));
+    QVERIFY(!merged.pinned);
+    QCOMPARE(merged.pinChangedAt, 200.0);
+    QCOMPARE(merged.openCount, 9);
+    QCOMPARE(merged.lastOpenedAt, 500.0);
+
+    // Applying the same signals again changes nothing.
+    QVERIFY(!bs::MetadataMerge::apply(*store, *id, 10.0, remote, policies));
+
+    // Under last-writer-wins a later tag list replaces the merged tags, but
+    // not the file's own.
+    bs::MetadataMerge::Policies lastWriter = policies;
+    lastWriter.insert(QStringLiteral("tags"), bs::MergePolicy::LastWriterWins);
+    bs::DocumentMetadata retagged;
+    retagged.insert(QStringLiteral("tags"), value(tags({QStringLiteral("Green")}), 900.0));
+    QVERIFY(bs::MetadataMerge::apply(*store, *id, 10.0, retagged, lastWriter));
+    merged = signalsOf();
+    QCOMPARE(merged.syncedTags, QStringList({QStringLiteral("Green")}));
+    QVERIFY(merged.tags.contains(QStringLiteral("Blue")));
+    QVERIFY(!merged.tags.contains(QStringLiteral("Red")));
+}
+
+QTEST_MAIN(TestMetadataMerge)
+#include "test_metadata_merge.moc"
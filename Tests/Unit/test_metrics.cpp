@@ -0,0 +1,141 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/metrics.h"
+
+#include <cmath>
+
+class TestMetrics : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testHistogramBuckets();
+    void testWindowedQuantiles();
+    void testWindowSlides();
+    void testGaugeAndCounterText();
+    void testHistogramText();
+    void testEscaping();
+    void testFormatValue();
+};
+
+void TestMetrics::testHistogramBuckets()
+{
+    bs::LatencyHistogram histogram({0.01, 0.1, 1.0});
+    histogram.observe(0.004);
+    histogram.observe(0.01);   // on a bound counts in that bucket
+    histogram.observe(0.05);
+    histogram.observe(3.0);    // beyond the last bound: +Inf only
+
+    QCOMPARE(histogram.cumulativeCounts(), (std::vector<uint64_t>{2, 3, 3}));
+    QCOMPARE(histogram.count(), uint64_t(4));
+    QVERIFY(qFuzzyCompare(histogram.sum(), 3.064));
+    QCOMPARE(bs::LatencyHistogram().bounds(), bs::LatencyHistogram::defaultBounds());
+}
+
+void TestMetrics::testWindowedQuantiles()
+{
+    const qint64 now = 1'000'000;
+    bs::WindowedLatency latency(10, 60);
+    for (int i = 0; i < 98; ++i) {
+        latency.observe(0.010, false, i < 30, now);
+    }
+    latency.observe(1.0, true, false, now);
+    latency.observe(1.0, false, false, now);
+
+    const auto summary = latency.summarize(60, now);
+    QCOMPARE(summary.count, uint64_t(100));
+    QCOMPARE(summary.errors, uint64_t(1));
+    QCOMPARE(summary.hits, uint64_t(30));
+    // Quantiles come from 20%-wide buckets.
+    QVERIFY(std::abs(summary.p50Ms - 10.0) <= 1.0);
+    QVERIFY(std::abs(summary.p95Ms - 10.0) <= 1.0);
+    QVERIFY(summary.p99Ms >= 900.0 && summary.p99Ms <= 1000.0);
+    QVERIFY(qFuzzyCompare(summary.maxMs, 1000.0));
+
+    QCOMPARE(bs::WindowedLatency().summarize(60, now).count, uint64_t(0));
+}
+
+void TestMetrics::testWindowSlides()
+{
+    const qint64 start = 1'000'000;
+    bs::WindowedLatency latency(10, 60);
+    latency.observe(0.010, false, false, start);
+    latency.observe(0.100, false, false, start + 30'000);
+
+    QCOMPARE(latency.summarize(10, start + 30'000).count, uint64_t(1));
+    QCOMPARE(latency.summarize(60, start + 30'000).count, uint64_t(2));
+    // A minute on, the first slot has left the window.
+    QCOMPARE(latency.summarize(60, start + 61'000).count, uint64_t(1));
+    // Longer windows are cut to the 60 s kept.
+    QCOMPARE(latency.summarize(900, start + 61'000).count, uint64_t(1));
+
+    // Reusing the first slot's place in the ring starts it empty.
+    latency.observe(0.200, false, false, start + 70'000);
+    const auto summary = latency.summarize(60, start + 70'000);
+    QCOMPARE(summary.count, uint64_t(2));
+    QVERIFY(qFuzzyCompare(summary.maxMs, 200.0));
+
+    QCOMPARE(latency.summarize(60, start + 600'000).count, uint64_t(0));
+}
+
+void TestMetrics::testGaugeAndCounterText()
+{
+    bs::PrometheusText out;
+    out.gauge(QStringLiteral("bs_queue_items"), QStringLiteral("Queued items."), 3,
+              {{QStringLiteral("state"), QStringLiteral("pending")}});
+    out.gauge(QStringLiteral("bs_queue_items"), QStringLiteral("Queued items."), 1,
+              {{QStringLiteral("state"), QStringLiteral("writing")}});
+    out.counter(QStringLiteral("bs_processed_total"), QStringLiteral("Processed."), 12421);
+
+    QCOMPARE(QString::fromUtf8(out.text()),
+             QStringLiteral("# HELP bs_queue_items Queued items.\n"
+                            "# TYPE bs_queue_items gauge\n"
+                            "bs_queue_items{state=\"pending\"} 3\n"
+                            "bs_queue_items{state=\"writing\"} 1\n"
+                            "# HELP bs_processed_total Processed.\n"
+                            "# TYPE bs_processed_total counter\n"
+                            "bs_processed_total 12421\n"));
+}
+
+void TestMetrics::testHistogramText()
+{
+    bs::LatencyHistogram histogram({0.05, 0.5});
+    histogram.observe(0.02);
+    histogram.observe(0.8);
+
+    bs::PrometheusText out;
+    out.histogram(QStringLiteral("bs_request_duration_seconds"), QStringLiteral("Latency."),
+                  histogram, {{QStringLiteral("method"), QStringLiteral("search")}});
+
+    QCOMPARE(QString::fromUtf8(out.text()),
+             QStringLiteral("# HELP bs_request_duration_seconds Latency.\n"
+                            "# TYPE bs_request_duration_seconds histogram\n"
+                            "bs_request_duration_seconds_bucket{method=\"search\",le=\"0.05\"} 1\n"
+                            "bs_request_duration_seconds_bucket{method=\"search\",le=\"0.5\"} 1\n"
+                            "bs_request_duration_seconds_bucket{method=\"search\",le=\"+Inf\"} 2\n"
+                            "bs_request_duration_seconds_sum{method=\"search\"} 0.82\n"
+                            "bs_request_duration_seconds_count{method=\"search\"} 2\n"));
+}
+
+void TestMetrics::testEscaping()
+{
+    bs::PrometheusText out;
+    out.gauge(QStringLiteral("bs_x"), QStringLiteral("Back\\slash\nnewline"), 1,
+              {{QStringLiteral("kind"), QStringLiteral("a\"b\\c\nd")}});
+    QCOMPARE(QString::fromUtf8(out.text()),
+             QStringLiteral("# HELP bs_x Back\\\\slash\\nnewline\n"
+                            "# TYPE bs_x gauge\n"
+                            "bs_x{kind=\"a\\\"b\\\\c\\nd\"} 1\n"));
+}
+
+void TestMetrics::testFormatValue()
+{
+    QCOMPARE(bs::PrometheusText::formatValue(0), QStringLiteral("0"));
+    QCOMPARE(bs::PrometheusText::formatValue(48234496), QStringLiteral("48234496"));
+    QCOMPARE(bs::PrometheusText::formatValue(-1), QStringLiteral("-1"));
+    QCOMPARE(bs::PrometheusText::formatValue(0.25), QStringLiteral("0.25"));
+    QCOMPARE(bs::PrometheusText::formatValue(qInf()), QStringLiteral("+Inf"));
+    QCOMPARE(bs::PrometheusText::formatValue(qQNaN()), QStringLiteral("NaN"));
+}
+
+QTEST_MAIN(TestMetrics)
+#include "test_metrics.moc"
@@ -10,6 +10,9 @@ class TestMigration : public QObject {
 private slots:
     void testCurrentVersionMissingSettingsDefaultsToZero();
     void testApplyMigrationsUpToV4();
+    void testApplyMigrationsUpToV5();
+    void testApplyMigrationsUpToV6();
+    void testApplyMigrationsUpToV7();
     void testRejectsDowngrade();
     void testRejectsUnsupportedTargetVersion();
 };
@@ -194,7 +197,7 @@ void TestMigration::testApplyMigrationsUpToV4()
     sqlite3_close(db);
 }
 
-void TestMigration::testRejectsDowngrade()
+void TestMigration::testApplyMigrationsUpToV5()
 {
     sqlite3* db = nullptr;
     QCOMPARE(sqlite3_open(":memory:", &db), SQLITE_OK);
@@ -202,13 +205,113 @@ void TestMigration::testRejectsDowngrade()
 
     QCOMPARE(sqlite3_exec(db,
                           "CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);"
-                          "INSERT INTO settings (key, value) VALUES ('schema_version', '5');",
+                          "INSERT INTO settings (key, value) VALUES ('schema_version', '4');",
                           nullptr, nullptr, nullptr),
              SQLITE_OK);
 
-    QVERIFY(!bs::applyMigrations(db, 4));
+    QVERIFY(bs::applyMigrations(db, 5));
     QCOMPARE(bs::currentSchemaVersion(db), 5);
 
+    sqlite3_stmt* stmt = nullptr;
+    QCOMPARE(sqlite3_prepare_v2(
+                 db,
+                 "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='item_attributes';",
+                 -1, &stmt, nullptr),
+             SQLITE_OK);
+    QCOMPARE(sqlite3_step(stmt), SQLITE_ROW);
+    QVERIFY(sqlite3_column_int(stmt, 0) == 1);
+    sqlite3_finalize(stmt);
+
+    sqlite3_close(db);
+}
+
+void TestMigration::testApplyMigrationsUpToV6()
+{
+    sqlite3* db = nullptr;
+    QCOMPARE(sqlite3_open(":memory:", &db), SQLITE_OK);
+    QVERIFY(db != nullptr);
+
+    QCOMPARE(sqlite3_exec(db,
+                          "CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);"
+                          "INSERT INTO settings (key, value) VALUES ('schema_version', '5');",
+                          nullptr, nullptr, nullptr),
+             SQLITE_OK);
+
+    QVERIFY(bs::applyMigrations(db, 6));
+    QCOMPARE(bs::currentSchemaVersion(db), 6);
+
+    sqlite3_stmt* stmt = nullptr;
+    QCOMPARE(sqlite3_prepare_v2(
+                 db,
+                 "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='query_history';",
+                 -1, &stmt, nullptr),
+             SQLITE_OK);
+    QCOMPARE(sqlite3_step(stmt), SQLITE_ROW);
+    QVERIFY(sqlite3_column_int(stmt, 0) == 1);
+    sqlite3_finalize(stmt);
+
+    QCOMPARE(sqlite3_prepare_v2(
+                 db,
+                 "SELECT value FROM settings WHERE key='queryHistoryRetentionDays';",
+                 -1, &stmt, nullptr),
+             SQLITE_OK);
+    QCOMPARE(sqlite3_step(stmt), SQLITE_ROW);
+    QCOMPARE(QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0))),
+             QStringLiteral("90"));
+    sqlite3_finalize(stmt);
+
+    sqlite3_close(db);
+}
+
+void TestMigration::testApplyMigrationsUpToV7()
+{
+    sqlite3* db = nullptr;
+    QCOMPARE(sqlite3_open(":memory:", &db), SQLITE_OK);
+    QVERIFY(db != nullptr);
+
+    QCOMPARE(sqlite3_exec(db,
+                          "CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);"
+                          "CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, path TEXT);"
+                          "INSERT INTO items (path) VALUES ('/Users/me/a.txt'), ('/Users/me/b.txt');"
+                          "INSERT INTO settings (key, value) VALUES ('schema_version', '6');",
+                          nullptr, nullptr, nullptr),
+             SQLITE_OK);
+
+    QVERIFY(bs::applyMigrations(db, 7));
+    QCOMPARE(bs::currentSchemaVersion(db), 7);
+
+    // Deleting an item leaves its id, and only its id, behind.
+    QCOMPARE(sqlite3_exec(db, "DELETE FROM items WHERE path = '/Users/me/b.txt';", nullptr,
+                          nullptr, nullptr),
+             SQLITE_OK);
+    sqlite3_stmt* stmt = nullptr;
+    QCOMPARE(sqlite3_prepare_v2(db, "SELECT item_id, deleted_at FROM item_tombstones;", -1, &stmt,
+                                nullptr),
+             SQLITE_OK);
+    QCOMPARE(sqlite3_step(stmt), SQLITE_ROW);
+    QCOMPARE(sqlite3_column_int64(stmt, 0), sqlite3_int64(2));
+    QVERIFY(sqlite3_column_double(stmt, 1) > 1.7e9);
+    QCOMPARE(sqlite3_step(stmt), SQLITE_DONE);
+    sqlite3_finalize(stmt);
+
+    sqlite3_close(db);
+}
+
+void TestMigration::testRejectsDowngrade()
+{
+    sqlite3* db = nullptr;
+    QCOMPARE(sqlite3_open(":memory:", &db), SQLITE_OK);
+    QVERIFY(db != nullptr);
+
+    QCOMPARE(sqlite3_exec(db,
+                          "CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);"
+                          "INSERT INTO settings (key, value) VALUES ('schema_version', '8');",
+                          nullptr, nullptr, nullptr),
+             SQLITE_OK);
+
+    QVERIFY(!bs::applyMigrations(db, 7));
+    QCOMPARE(bs::currentSchemaVersion(db), 8);
+
     sqlite3_close(db);
 }
 
@@ -220,12 +323,13 @@ void TestMigration::testRejectsUnsupportedTargetVersion()
 
     QCOMPARE(sqlite3_exec(db,
                           "CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);"
+                          "CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, path TEXT);"
                           "INSERT INTO settings (key, value) VALUES ('schema_version', '1');",
                           nullptr, nullptr, nullptr),
              SQLITE_OK);
 
-    QVERIFY(!bs::applyMigrations(db, 5));
-    QCOMPARE(bs::currentSchemaVersion(db), 4);
+    QVERIFY(!bs::applyMigrations(db, 8));
+    QCOMPARE(bs::currentSchemaVersion(db), 7);
 
     sqlite3_close(db);
 }
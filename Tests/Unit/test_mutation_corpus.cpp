@@ -0,0 +1,229 @@
+#include <QtTest/QtTest>
+
+#include "mutation_corpus.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QTemporaryDir>
+#include <QTimeZone>
+
+namespace {
+
+using bs::test::Mutation;
+using bs::test::MutationHarness;
+using bs::test::MutationScript;
+
+QByteArray readFile(const QString& path)
+{
+    QFile file(path);
+    return file.open(QIODevice::ReadOnly) ? file.readAll() : QByteArray();
+}
+
+Mutation step(Mutation::Op op, const QString& path, const QString& to = {},
+              const QByteArray& content = {})
+{
+    Mutation mutation;
+    mutation.op = op;
+    mutation.path = path;
+    mutation.to = to;
+    mutation.content = content;
+    return mutation;
+}
+
+} // namespace
+
+class TestMutationCorpus : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseScript();
+    void testParseRejectsInvalidSteps_data();
+    void testParseRejectsInvalidSteps();
+    void testApplySteps();
+    void testMoveKeepsModificationTime();
+    void testFixtureScriptsParse();
+};
+
+void TestMutationCorpus::testParseScript()
+{
+    QString error;
+    const std::optional<MutationScript> script = MutationHarness::parseScript(R"({
+        "id": "sample", "stepDelayMs": 50, "knownIssue": "not yet",
+        "tree": {"home/a.txt": "alpha", "external/b/c.txt": "gamma"},
+        "steps": [
+            {"op": "append", "path": "home/a.txt", "content": " beta"},
+            {"op": "move", "path": "home/a.txt", "to": "external/a.txt"},
+            {"op": "chmod", "path": "external/b/c.txt", "mode": "0640"}
+        ]})", &error);
+    QVERIFY2(script.has_value(), qPrintable(error));
+    QCOMPARE(script->id, QStringLiteral("sample"));
+    QCOMPARE(script->stepDelayMs, 50);
+    QCOMPARE(script->knownIssue, QStringLiteral("not yet"));
+    QCOMPARE(script->tree.value(QStringLiteral("external/b/c.txt")), QByteArray("gamma"));
+    QCOMPARE(script->steps.size(), size_t(3));
+    QCOMPARE(script->steps[0].op, Mutation::Op::Append);
+    QCOMPARE(script->steps[0].content, QByteArray(" beta"));
+    QCOMPARE(script->steps[1].to, QStringLiteral("external/a.txt"));
+    QCOMPARE(script->steps[2].mode, 0640);
+}
+
+void TestMutationCorpus::testParseRejectsInvalidSteps_data()
+{
+    QTest::addColumn<QByteArray>("json");
+    QTest::addColumn<QString>("error");
+
+    QTest::newRow("no id") << QByteArray(R"({"steps": []})")
+                           << QStringLiteral("a script needs an id");
+    QTest::newRow("tree outside the volumes")
+        << QByteArray(R"({"id": "x", "tree": {"tmp/a.txt": ""}})")
+        << QStringLiteral("tree: tmp/a.txt is not under home/ or external/");
+    QTest::newRow("unknown op")
+        << QByteArray(R"({"id": "x", "steps": [{"op": "truncate", "path": "home/a"}]})")
+        << QStringLiteral("step 1: unknown op \"truncate\"");
+    QTest::newRow("escaping path")
+        << QByteArray(R"({"id": "x", "steps": [{"op": "delete", "path": "home/../../etc"}]})")
+        << QStringLiteral("step 1: home/../../etc is not under home/ or external/");
+    QTest::newRow("rename without to")
+        << QByteArray(R"({"id": "x", "steps": [{"op": "rename", "path": "home/a"}]})")
+        << QStringLiteral("step 1: rename needs a \"to\" under home/ or external/");
+    QTest::newRow("rename across volumes")
+        << QByteArray(R"({"id": "x", "steps": [
+               {"op": "create", "path": "home/a"},
+               {"op": "rename", "path": "home/a", "to": "external/a"}]})")
+        << QStringLiteral("step 2: a rename stays on its volume; use move");
+    QTest::newRow("chmod without an octal mode")
+        << QByteArray(R"({"id": "x", "steps": [{"op": "chmod", "path": "home/a", "mode": "rw"}]})")
+        << QStringLiteral("step 1: chmod needs an octal \"mode\" such as \"0444\"");
+}
+
+void TestMutationCorpus::testParseRejectsInvalidSteps()
+{
+    QFETCH(QByteArray, json);
+    QFETCH(QString, error);
+
+    QString actual;
+    QVERIFY(!MutationHarness::parseScript(json, &actual).has_value());
+    QCOMPARE(actual, error);
+}
+
+void TestMutationCorpus::testApplySteps()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    MutationHarness harness(dir.path());
+
+    MutationScript script;
+    script.id = QStringLiteral("apply");
+    script.tree.insert(QStringLiteral("home/notes.txt"), "one\n");
+    script.tree.insert(QStringLiteral("home/folder/inner.txt"), "inner\n");
+    QString error;
+    QVERIFY2(harness.writeTree(script, &error), qPrintable(error));
+    QCOMPARE(harness.roots().size(), size_t(2));
+    QVERIFY(QFileInfo(QString::fromStdString(harness.roots()[1])).isDir());
+
+    const QString notes = harness.resolve(QStringLiteral("home/notes.txt"));
+    QCOMPARE(notes, QDir(dir.path()).filePath(QStringLiteral("home/notes.txt")));
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Append, QStringLiteral("home/notes.txt"), {},
+                                "two\n"),
+                           &error),
+             qPrintable(error));
+    QCOMPARE(readFile(notes), QByteArray("one\ntwo\n"));
+    QVERIFY(!harness.apply(step(Mutation::Op::Append, QStringLiteral("home/missing.txt"), {},
+                                "x"),
+                           &error));
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Create, QStringLiteral("home/deep/new.txt"), {},
+                                "new\n"),
+                           &error),
+             qPrintable(error));
+    QCOMPARE(readFile(harness.resolve(QStringLiteral("home/deep/new.txt"))), QByteArray("new\n"));
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Rename, QStringLiteral("home/notes.txt"),
+                                QStringLiteral("home/renamed/notes.txt")),
+                           &error),
+             qPrintable(error));
+    QVERIFY(!QFileInfo::exists(notes));
+    QCOMPARE(readFile(harness.resolve(QStringLiteral("home/renamed/notes.txt"))),
+             QByteArray("one\ntwo\n"));
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Move, QStringLiteral("home/folder"),
+                                QStringLiteral("external/folder")),
+                           &error),
+             qPrintable(error));
+    QVERIFY(!QFileInfo::exists(harness.resolve(QStringLiteral("home/folder"))));
+    QCOMPARE(readFile(harness.resolve(QStringLiteral("external/folder/inner.txt"))),
+             QByteArray("inner\n"));
+
+    Mutation chmod = step(Mutation::Op::Chmod, QStringLiteral("external/folder/inner.txt"));
+    chmod.mode = 0444;
+    QVERIFY2(harness.apply(chmod, &error), qPrintable(error));
+    QVERIFY(!(QFileInfo(harness.resolve(chmod.path)).permissions() & QFileDevice::WriteOwner));
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Mkdir, QStringLiteral("home/empty/dir")), &error),
+             qPrintable(error));
+    QVERIFY(QFileInfo(harness.resolve(QStringLiteral("home/empty/dir"))).isDir());
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Delete, QStringLiteral("external/folder")), &error),
+             qPrintable(error));
+    QVERIFY(!QFileInfo::exists(harness.resolve(QStringLiteral("external/folder"))));
+    QVERIFY(!harness.apply(step(Mutation::Op::Delete, QStringLiteral("external/folder")), &error));
+
+    // Writing the tree again starts from empty volumes.
+    QVERIFY2(harness.writeTree(script, &error), qPrintable(error));
+    QVERIFY(!QFileInfo::exists(harness.resolve(QStringLiteral("home/deep/new.txt"))));
+    QCOMPARE(readFile(notes), QByteArray("one\n"));
+}
+
+void TestMutationCorpus::testMoveKeepsModificationTime()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    MutationHarness harness(dir.path());
+
+    MutationScript script;
+    script.id = QStringLiteral("mtime");
+    script.tree.insert(QStringLiteral("home/old.txt"), "old\n");
+    QString error;
+    QVERIFY2(harness.writeTree(script, &error), qPrintable(error));
+
+    const QString source = harness.resolve(QStringLiteral("home/old.txt"));
+    const QDateTime past = QDateTime::fromSecsSinceEpoch(1600000000, QTimeZone::UTC);
+    {
+        QFile file(source);
+        QVERIFY(file.open(QIODevice::ReadWrite));
+        QVERIFY(file.setFileTime(past, QFileDevice::FileModificationTime));
+    }
+
+    QVERIFY2(harness.apply(step(Mutation::Op::Move, QStringLiteral("home/old.txt"),
+                                QStringLiteral("external/old.txt")),
+                           &error),
+             qPrintable(error));
+    QCOMPARE(QFileInfo(harness.resolve(QStringLiteral("external/old.txt"))).lastModified(),
+             past.toLocalTime());
+}
+
+void TestMutationCorpus::testFixtureScriptsParse()
+{
+#ifdef BETTERSPOTLIGHT_SOURCE_DIR
+    const QDir scripts(QDir(QString::fromUtf8(BETTERSPOTLIGHT_SOURCE_DIR))
+                           .filePath(QStringLiteral("Tests/Fixtures/mutation_scripts")));
+    const QStringList files = scripts.entryList({QStringLiteral("*.json")}, QDir::Files);
+    QVERIFY(!files.isEmpty());
+    for (const QString& file : files) {
+        QString error;
+        const std::optional<MutationScript> script =
+            MutationHarness::loadScript(scripts.filePath(file), &error);
+        QVERIFY2(script.has_value(), qPrintable(file + QStringLiteral(": ") + error));
+        QCOMPARE(script->id + QStringLiteral(".json"), file);
+        QVERIFY2(!script->steps.empty(), qPrintable(file));
+    }
+#else
+    QSKIP("BETTERSPOTLIGHT_SOURCE_DIR is not defined");
+#endif
+}
+
+QTEST_MAIN(TestMutationCorpus)
+#include "test_mutation_corpus.moc"
@@ -0,0 +1,156 @@
+#include <QtTest/QtTest>
+
+#include "core/query/natural_language_query.h"
+#include "core/query/relative_dates.h"
+
+#include <QTimeZone>
+
+namespace {
+
+// Wednesday 2026-10-14.
+const QDateTime kNow(QDate(2026, 10, 14), QTime(15, 30), QTimeZone::UTC);
+
+double midnight(int year, int month, int day)
+{
+    return static_cast<double>(
+        QDateTime(QDate(year, month, day), QTime(0, 0), QTimeZone::UTC).toSecsSinceEpoch());
+}
+
+} // namespace
+
+class TestNaturalLanguageQuery : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRelativeDatePhrases();
+    void testDescribe();
+    void testScreenshotsFromLastTuesday();
+    void testCreationVerbAndMonth();
+    void testSinceIsOpenEnded();
+    void testKeepsRemainingWords();
+    void testBarePhraseNeedsKind();
+    void testLeavesPlainQueriesAlone();
+};
+
+void TestNaturalLanguageQuery::testRelativeDatePhrases()
+{
+    auto bounds = bs::RelativeDates::resolve(QStringLiteral("last tuesday"), kNow);
+    QVERIFY(bounds.has_value());
+    QCOMPARE(bounds->after.value(), midnight(2026, 10, 13));
+
+    // Today is Wednesday: "wednesday" is last week's.
+    bounds = bs::RelativeDates::resolve(QStringLiteral("wednesday"), kNow);
+    QCOMPARE(bounds->after.value(), midnight(2026, 10, 7));
+
+    // November has not come yet this year.
+    bounds = bs::RelativeDates::resolve(QStringLiteral("november"), kNow);
+    QCOMPARE(bounds->after.value(), midnight(2025, 11, 1));
+    QVERIFY(bounds->before.value() < midnight(2025, 12, 1));
+
+    bounds = bs::RelativeDates::resolve(QStringLiteral("march 2024"), kNow);
+    QCOMPARE(bounds->after.value(), midnight(2024, 3, 1));
+
+    bounds = bs::RelativeDates::resolve(QStringLiteral("3 days ago"), kNow);
+    QCOMPARE(bounds->after.value(), midnight(2026, 10, 11));
+    QVERIFY(bounds->before.value() < midnight(2026, 10, 12));
+
+    bounds = bs::RelativeDates::resolve(QStringLiteral("2 weeks ago"), kNow);
+    QCOMPARE(bounds->after.value(), midnight(2026, 9, 28));
+
+    QVERIFY(!bs::RelativeDates::resolve(QStringLiteral("someday"), kNow).has_value());
+    QVERIFY(!bs::RelativeDates::resolve(QStringLiteral("2025-13"), kNow).has_value());
+}
+
+void TestNaturalLanguageQuery::testDescribe()
+{
+    const QTimeZone utc = QTimeZone::UTC;
+    QCOMPARE(bs::RelativeDates::describe(
+                 bs::RelativeDates::resolve(QStringLiteral("yesterday"), kNow).value(), utc),
+             QStringLiteral("2026-10-13"));
+    QCOMPARE(bs::RelativeDates::describe(
+                 bs::RelativeDates::resolve(QStringLiteral("last week"), kNow).value(), utc),
+             QStringLiteral("2026-10-05 to 2026-10-11"));
+    QCOMPARE(bs::RelativeDates::describe(
+                 bs::RelativeDates::resolve(QStringLiteral("this month"), kNow).value(), utc),
+             QStringLiteral("since 2026-10-01"));
+}
+
+void TestNaturalLanguageQuery::testScreenshotsFromLastTuesday()
+{
+    const auto interpretation =
+        bs::NaturalLanguageQuery::interpret(QStringLiteral("screenshots from last tuesday"), kNow);
+    QVERIFY(interpretation.has_value());
+    QCOMPARE(interpretation->kind, QStringLiteral("screenshots"));
+    QVERIFY(std::find(interpretation->fileTypes.begin(), interpretation->fileTypes.end(),
+                      QStringLiteral("png"))
+            != interpretation->fileTypes.end());
+    QCOMPARE(interpretation->query, QStringLiteral("screenshot"));
+    QVERIFY(!interpretation->created);
+    QCOMPARE(interpretation->dates.after.value(), midnight(2026, 10, 13));
+    QCOMPARE(interpretation->datePhrase, QStringLiteral("from last tuesday"));
+    QCOMPARE(interpretation->summary, QStringLiteral("screenshots modified 2026-10-13"));
+
+    const QJsonObject json = interpretation->toJson();
+    QCOMPARE(json.value(QStringLiteral("dateField")).toString(), QStringLiteral("modified"));
+    QVERIFY(json.contains(QStringLiteral("before")));
+}
+
+void TestNaturalLanguageQuery::testCreationVerbAndMonth()
+{
+    const auto interpretation =
+        bs::NaturalLanguageQuery::interpret(QStringLiteral("show me photos taken in march"), kNow);
+    QVERIFY(interpretation.has_value());
+    QCOMPARE(interpretation->kind, QStringLiteral("images"));
+    QVERIFY(interpretation->created);
+    QVERIFY(interpretation->query.isEmpty());
+    QCOMPARE(interpretation->dates.after.value(), midnight(2026, 3, 1));
+    QCOMPARE(interpretation->summary,
+             QStringLiteral("images created 2026-03-01 to 2026-03-31"));
+}
+
+void TestNaturalLanguageQuery::testSinceIsOpenEnded()
+{
+    const auto interpretation =
+        bs::NaturalLanguageQuery::interpret(QStringLiteral("invoices since last month"), kNow);
+    QVERIFY(interpretation.has_value());
+    QCOMPARE(interpretation->dates.after.value(), midnight(2026, 9, 1));
+    QVERIFY(!interpretation->dates.before.has_value());
+    QCOMPARE(interpretation->query, QStringLiteral("invoices"));
+}
+
+void TestNaturalLanguageQuery::testKeepsRemainingWords()
+{
+    const auto interpretation = bs::NaturalLanguageQuery::interpret(
+        QStringLiteral("budget pdfs edited 3 days ago"), kNow);
+    QVERIFY(interpretation.has_value());
+    QCOMPARE(interpretation->query, QStringLiteral("budget"));
+    QCOMPARE(interpretation->fileTypes, std::vector<QString>({QStringLiteral("pdf")}));
+    QCOMPARE(interpretation->summary,
+             QStringLiteral("pdfs matching \"budget\" modified 2026-10-11"));
+}
+
+void TestNaturalLanguageQuery::testBarePhraseNeedsKind()
+{
+    QVERIFY(bs::NaturalLanguageQuery::interpret(QStringLiteral("screenshots yesterday"), kNow)
+                .has_value());
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(QStringLiteral("notes yesterday"), kNow)
+                 .has_value());
+    // A bare year is more likely part of a name.
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(QStringLiteral("pdfs 2025 budget"), kNow)
+                 .has_value());
+}
+
+void TestNaturalLanguageQuery::testLeavesPlainQueriesAlone()
+{
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(QStringLiteral("quarterly report"), kNow)
+                 .has_value());
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(QStringLiteral("notes on the project"), kNow)
+                 .has_value());
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(QStringLiteral("photos"), kNow).has_value());
+    QVERIFY(!bs::NaturalLanguageQuery::interpret(
+                 QStringLiteral("photos from yesterday modified:today"), kNow)
+                 .has_value());
+}
+
+QTEST_MAIN(TestNaturalLanguageQuery)
+#include "test_natural_language_query.moc"
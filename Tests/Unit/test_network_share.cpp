@@ -0,0 +1,198 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/network_share.h"
+
+#include <QTemporaryDir>
+#include <QTimeZone>
+
+namespace {
+
+bs::FileMetadata scanned(const std::string& path, uint64_t size, double modifiedAt)
+{
+    bs::FileMetadata meta;
+    meta.filePath = path;
+    meta.fileSize = size;
+    meta.modifiedAt = modifiedAt;
+    return meta;
+}
+
+bs::SQLiteStore::ItemRow indexed(const QString& path, int64_t size, double modifiedAt,
+                                 const QString& kind = QStringLiteral("text"))
+{
+    bs::SQLiteStore::ItemRow row;
+    row.path = path;
+    row.kind = kind;
+    row.size = size;
+    row.modifiedAt = modifiedAt;
+    return row;
+}
+
+} // namespace
+
+class TestNetworkShare : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseShares();
+    void testParseSharesRejectsInvalid();
+    void testThrottle();
+    void testThrottleUnlimited();
+    void testSchedule();
+    void testRetryDelay();
+    void testLocalFolderIsNotMounted();
+    void testDiff();
+};
+
+void TestNetworkShare::testParseShares()
+{
+    QString error;
+    auto none = bs::NetworkShares::parseShares(QString(), &error);
+    QVERIFY(none.has_value());
+    QVERIFY(none->empty());
+
+    const QString json = QStringLiteral(
+        R"([{"name": "nas", "path": "/Volumes/Projects/", "maxOpsPerSecond": 50,
+             "maxBytesPerSecond": 1048576, "intervalMinutes": 120, "hours": "22-6"},
+            {"name": "media", "path": "/Volumes/Media", "maxBytesPerSecond": 0}])");
+    auto shares = bs::NetworkShares::parseShares(json, &error);
+    QVERIFY2(shares.has_value(), qPrintable(error));
+    QCOMPARE(shares->size(), size_t(2));
+    QCOMPARE(shares->at(0).path, QStringLiteral("/Volumes/Projects"));
+    QCOMPARE(shares->at(0).maxOpsPerSecond, 50);
+    QCOMPARE(shares->at(0).maxBytesPerSecond, 1048576);
+    QCOMPARE(shares->at(0).schedule.intervalMinutes, 120);
+    QCOMPARE(shares->at(0).schedule.startHour, 22);
+    QCOMPARE(shares->at(0).schedule.endHour, 6);
+    QCOMPARE(shares->at(1).maxOpsPerSecond, bs::NetworkShares::kDefaultMaxOpsPerSecond);
+    QCOMPARE(shares->at(1).maxBytesPerSecond, 0);
+    QCOMPARE(shares->at(1).schedule.intervalMinutes, bs::NetworkShares::kDefaultIntervalMinutes);
+    QCOMPARE(shares->at(1).schedule.startHour, -1);
+}
+
+void TestNetworkShare::testParseSharesRejectsInvalid()
+{
+    const QStringList invalid = {
+        QStringLiteral("{}"),
+        QStringLiteral(R"([{"name": "my nas", "path": "/Volumes/Projects"}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "Volumes/Projects"}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "/Volumes/Projects"},
+                           {"name": "A", "path": "/Volumes/Media"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "/Volumes/Projects"},
+                           {"name": "b", "path": "/Volumes/Projects/2026"}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/Volumes/Projects", "maxOpsPerSecond": -1}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/Volumes/Projects", "intervalMinutes": 1}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/Volumes/Projects", "hours": "nights"}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/Volumes/Projects", "hours": "6-6"}])"),
+        QStringLiteral(R"([{"name": "nas", "path": "/Volumes/Projects", "hours": "25-6"}])"),
+    };
+    for (const QString& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::NetworkShares::parseShares(json, &error).has_value(), qPrintable(json));
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestNetworkShare::testThrottle()
+{
+    // 10 ops and 1,000 bytes a second.
+    bs::ShareThrottle throttle(10, 1000);
+    const int64_t start = 1790000000000;
+    // A second's budget passes at once after an idle spell...
+    for (int i = 0; i < 10; ++i) {
+        QCOMPARE(throttle.reserve(1, 0, start), 0);
+    }
+    // ...then each op waits its 100 ms.
+    QCOMPARE(throttle.reserve(1, 0, start), 100);
+    QCOMPARE(throttle.reserve(1, 0, start), 200);
+    // Bytes are booked on their own budget: 3,000 bytes are 3 s, less the
+    // second that was idle.
+    QCOMPARE(throttle.reserve(0, 3000, start), 2000);
+    // Whichever budget is further behind sets the wait.
+    QCOMPARE(throttle.reserve(1, 100, start), 2100);
+    // Time passing pays the debt back.
+    QCOMPARE(throttle.reserve(1, 0, start + 10000), 0);
+}
+
+void TestNetworkShare::testThrottleUnlimited()
+{
+    bs::ShareThrottle throttle(0, 0);
+    for (int i = 0; i < 1000; ++i) {
+        QCOMPARE(throttle.reserve(1, 1 << 20, 0), 0);
+    }
+}
+
+void TestNetworkShare::testSchedule()
+{
+    bs::ShareSchedule nights;
+    nights.intervalMinutes = 60;
+    nights.startHour = 22;
+    nights.endHour = 6;
+    QVERIFY(bs::NetworkShares::isInWindow(nights, QTime(23, 30)));
+    QVERIFY(bs::NetworkShares::isInWindow(nights, QTime(0, 0)));
+    QVERIFY(bs::NetworkShares::isInWindow(nights, QTime(5, 59)));
+    QVERIFY(!bs::NetworkShares::isInWindow(nights, QTime(6, 0)));
+    QVERIFY(!bs::NetworkShares::isInWindow(nights, QTime(12, 0)));
+
+    bs::ShareSchedule office;
+    office.startHour = 9;
+    office.endHour = 17;
+    QVERIFY(bs::NetworkShares::isInWindow(office, QTime(9, 0)));
+    QVERIFY(!bs::NetworkShares::isInWindow(office, QTime(17, 0)));
+    QVERIFY(bs::NetworkShares::isInWindow(bs::ShareSchedule{}, QTime(3, 0)));
+
+    const QDateTime night(QDate(2026, 10, 14), QTime(23, 0), QTimeZone::LocalTime);
+    const int64_t nowMs = night.toMSecsSinceEpoch();
+    QVERIFY(bs::NetworkShares::isDue(nights, 0, night));
+    QVERIFY(bs::NetworkShares::isDue(nights, nowMs - 61 * 60 * 1000, night));
+    QVERIFY(!bs::NetworkShares::isDue(nights, nowMs - 30 * 60 * 1000, night));
+    const QDateTime noon(QDate(2026, 10, 14), QTime(12, 0), QTimeZone::LocalTime);
+    QVERIFY(!bs::NetworkShares::isDue(nights, 0, noon));
+}
+
+void TestNetworkShare::testRetryDelay()
+{
+    QCOMPARE(bs::NetworkShares::retryDelayMs(0), 0);
+    QCOMPARE(bs::NetworkShares::retryDelayMs(1), bs::NetworkShares::kRetryBaseMs);
+    QCOMPARE(bs::NetworkShares::retryDelayMs(3), 4 * bs::NetworkShares::kRetryBaseMs);
+    QCOMPARE(bs::NetworkShares::retryDelayMs(7), bs::NetworkShares::kRetryMaxMs);
+    QCOMPARE(bs::NetworkShares::retryDelayMs(1000), bs::NetworkShares::kRetryMaxMs);
+}
+
+void TestNetworkShare::testLocalFolderIsNotMounted()
+{
+    // What an unmounted share's leftover folder looks like: a local one.
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QVERIFY(!bs::NetworkShares::isMounted(dir.path()));
+    QVERIFY(!bs::NetworkShares::isMounted(dir.filePath(QStringLiteral("missing"))));
+}
+
+void TestNetworkShare::testDiff()
+{
+    const std::vector<bs::FileMetadata> files = {
+        scanned("/Volumes/Projects/a.txt", 10, 1790000000.0),
+        scanned("/Volumes/Projects/b.txt", 20, 1790000000.0),
+        scanned("/Volumes/Projects/c.txt", 30, 1790000000.0),
+        scanned("/Volumes/Projects/new.txt", 40, 1790000000.0),
+    };
+    const std::vector<bs::SQLiteStore::ItemRow> rows = {
+        // stat() has sub-second mtimes; the scan whole seconds.
+        indexed(QStringLiteral("/Volumes/Projects/a.txt"), 10, 1790000000.4),
+        indexed(QStringLiteral("/Volumes/Projects/b.txt"), 21, 1790000000.0),
+        indexed(QStringLiteral("/Volumes/Projects/c.txt"), 30, 1790000100.0),
+        indexed(QStringLiteral("/Volumes/Projects/gone.txt"), 5, 1790000000.0),
+        indexed(QStringLiteral("/Volumes/Projects/folder"), 0, 1790000000.0,
+                QStringLiteral("directory")),
+    };
+    const bs::ShareChanges changes = bs::NetworkShares::diff(files, rows);
+    QCOMPARE(changes.unchanged, size_t(1));
+    QCOMPARE(changes.changed.size(), size_t(3));
+    QCOMPARE(changes.changed[0].filePath, std::string("/Volumes/Projects/b.txt"));
+    QCOMPARE(changes.changed[1].filePath, std::string("/Volumes/Projects/c.txt"));
+    QCOMPARE(changes.changed[2].filePath, std::string("/Volumes/Projects/new.txt"));
+    QCOMPARE(changes.removed, std::vector<std::string>({"/Volumes/Projects/gone.txt"}));
+}
+
+QTEST_MAIN(TestNetworkShare)
+#include "test_network_share.moc"
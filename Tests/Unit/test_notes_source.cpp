@@ -0,0 +1,160 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/notes_source.h"
+
+#include <QTemporaryDir>
+
+#include <sqlite3.h>
+
+namespace {
+
+// gzip of a NoteStoreProto whose note text is "Groceries\nMilk, eggs￼ and
+// bread", with a varint and an attribute run around it to skip.
+const char* kGroceriesHex =
+    "1f8b0800000000000203e36010d2166090521752742fca4f4e2dca4c2de6f2cdccc9d651484d4f2f7e"
+    "bf7f8f42625e8a4252516a628a1613072300b6a4c29a2f000000";
+
+constexpr double kCoreDataEpochOffset = 978307200.0;
+
+bool createStore(const QString& path)
+{
+    const QByteArray groceries = QByteArray(kGroceriesHex);
+    const QByteArray sql = QByteArrayLiteral(
+        "CREATE TABLE ZICCLOUDSYNCINGOBJECT (Z_PK INTEGER PRIMARY KEY, ZIDENTIFIER TEXT,"
+        " ZTITLE1 TEXT, ZTITLE2 TEXT, ZNAME TEXT, ZCREATIONDATE3 REAL,"
+        " ZMODIFICATIONDATE1 REAL, ZNOTEDATA INTEGER, ZFOLDER INTEGER, ZOWNER INTEGER,"
+        " ZFOLDERTYPE INTEGER, ZMARKEDFORDELETION INTEGER, ZISPASSWORDPROTECTED INTEGER);"
+        "CREATE TABLE ZICNOTEDATA (Z_PK INTEGER PRIMARY KEY, ZNOTE INTEGER, ZDATA BLOB);"
+        "INSERT INTO ZICCLOUDSYNCINGOBJECT (Z_PK, ZIDENTIFIER, ZNAME) VALUES (1, 'ACCOUNT-1', 'iCloud');"
+        "INSERT INTO ZICCLOUDSYNCINGOBJECT (Z_PK, ZIDENTIFIER, ZTITLE2, ZOWNER, ZFOLDERTYPE)"
+        " VALUES (2, 'FOLDER-1', 'Notes', 1, 0), (3, 'FOLDER-2', 'Recently Deleted', 1, 1);"
+        "INSERT INTO ZICCLOUDSYNCINGOBJECT (Z_PK, ZIDENTIFIER, ZTITLE1, ZCREATIONDATE3,"
+        " ZMODIFICATIONDATE1, ZNOTEDATA, ZFOLDER, ZMARKEDFORDELETION, ZISPASSWORDPROTECTED) VALUES"
+        " (4, 'NOTE-1', 'Groceries', 800000000, 810000000, 1, 2, 0, 0),"
+        " (5, 'NOTE-2', 'Passwords', 800000000, 800000000, 2, 2, 0, 1),"
+        " (6, 'NOTE-3', 'Old list', 800000000, 800000000, 3, 3, 0, 0),"
+        " (7, 'NOTE-4', 'Gone', 800000000, 800000000, 4, 2, 1, 0);"
+        "INSERT INTO ZICNOTEDATA (Z_PK, ZNOTE, ZDATA) VALUES"
+        " (1, 4, X'") + groceries + QByteArrayLiteral("'), (2, 5, X'00'),"
+        " (3, 6, X'") + groceries + QByteArrayLiteral("'), (4, 7, X'") + groceries
+        + QByteArrayLiteral("');");
+
+    sqlite3* db = nullptr;
+    if (sqlite3_open(path.toUtf8().constData(), &db) != SQLITE_OK) {
+        sqlite3_close(db);
+        return false;
+    }
+    const bool ok = sqlite3_exec(db, sql.constData(), nullptr, nullptr, nullptr) == SQLITE_OK;
+    sqlite3_close(db);
+    return ok;
+}
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+} // namespace
+
+class TestNotesSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testNoteText();
+    void testReadNotes();
+    void testItems();
+    void testStaleIdentifiers();
+};
+
+void TestNotesSource::testNoteText()
+{
+    const auto text = bs::NotesSource::noteText(QByteArray::fromHex(kGroceriesHex));
+    QVERIFY(text.has_value());
+    QCOMPARE(text.value(), QStringLiteral("Groceries\nMilk, eggs and bread"));
+
+    QVERIFY(!bs::NotesSource::noteText(QByteArray()).has_value());
+    QVERIFY(!bs::NotesSource::noteText(QByteArrayLiteral("not gzip")).has_value());
+}
+
+void TestNotesSource::testReadNotes()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString path = dir.filePath(QStringLiteral("NoteStore.sqlite"));
+    QVERIFY(createStore(path));
+
+    QString error;
+    const auto notes = bs::NotesSource::readNotes(path, &error);
+    QVERIFY2(notes.has_value(), qPrintable(error));
+    // Recently Deleted and notes marked for deletion are left out.
+    QCOMPARE(notes->size(), size_t(2));
+
+    const bs::AppleNote& groceries = notes->at(0);
+    QCOMPARE(groceries.identifier, QStringLiteral("NOTE-1"));
+    QCOMPARE(groceries.title, QStringLiteral("Groceries"));
+    QCOMPARE(groceries.body, QStringLiteral("Groceries\nMilk, eggs and bread"));
+    QCOMPARE(groceries.folder, QStringLiteral("Notes"));
+    QCOMPARE(groceries.account, QStringLiteral("iCloud"));
+    QCOMPARE(groceries.accountIdentifier, QStringLiteral("ACCOUNT-1"));
+    QCOMPARE(groceries.createdAt, 800000000.0 + kCoreDataEpochOffset);
+    QCOMPARE(groceries.modifiedAt, 810000000.0 + kCoreDataEpochOffset);
+    QVERIFY(!groceries.locked);
+
+    const bs::AppleNote& locked = notes->at(1);
+    QCOMPARE(locked.title, QStringLiteral("Passwords"));
+    QVERIFY(locked.locked);
+    QVERIFY(locked.body.isEmpty());
+
+    QVERIFY(!bs::NotesSource::readNotes(dir.filePath(QStringLiteral("missing.sqlite")), &error)
+                 .has_value());
+    QVERIFY(!error.isEmpty());
+}
+
+void TestNotesSource::testItems()
+{
+    bs::AppleNote note;
+    note.identifier = QStringLiteral("NOTE-1");
+    note.title = QStringLiteral("Groceries");
+    note.body = QStringLiteral("Groceries\nMilk, eggs and bread");
+    note.folder = QStringLiteral("Notes");
+    note.account = QStringLiteral("iCloud");
+    note.accountIdentifier = QStringLiteral("ACCOUNT-1");
+    note.createdAt = 1778307200.0;
+    note.modifiedAt = 1788307200.0;
+    bs::AppleNote untitled;
+    untitled.identifier = QStringLiteral("NOTE-5");
+
+    const auto items = bs::NotesSource::items({note, untitled, note});
+    QCOMPARE(items.size(), size_t(1));
+    const bs::DonatedItem& item = items.front();
+    QCOMPARE(item.path, QStringLiteral("donated://com.apple.Notes/NOTE-1"));
+    QCOMPARE(item.name, QStringLiteral("Groceries"));
+    QCOMPARE(item.textContent, QStringLiteral("Groceries\nMilk, eggs and bread"));
+    QCOMPARE(item.modifiedAt, 1788307200.0);
+    QCOMPARE(attributeValue(item, "folder"), QStringLiteral("Notes"));
+    QCOMPARE(attributeValue(item, "contentDescription"), QStringLiteral("Notes · iCloud"));
+    QCOMPARE(attributeValue(item, "url"),
+             QStringLiteral("applenotes:note/NOTE-1?ownerIdentifier=ACCOUNT-1"));
+    QVERIFY(!item.contentHash.isEmpty());
+
+    note.accountIdentifier.clear();
+    QCOMPARE(bs::NotesSource::noteUrl(note), QStringLiteral("applenotes:note/NOTE-1"));
+}
+
+void TestNotesSource::testStaleIdentifiers()
+{
+    bs::AppleNote note;
+    note.identifier = QStringLiteral("NOTE-1");
+    note.title = QStringLiteral("Groceries");
+    const QStringList stale = bs::NotesSource::staleIdentifiers(
+        {QStringLiteral("NOTE-1"), QStringLiteral("NOTE-9")}, {note});
+    QCOMPARE(stale, QStringList{QStringLiteral("NOTE-9")});
+}
+
+QTEST_MAIN(TestNotesSource)
+#include "test_notes_source.moc"
@@ -69,6 +69,10 @@ private slots:
     void testReloadBsignoreMissingFileClearsPatterns();
     void testLoadBsignoreMissingFileMarksNotLoaded();
 
+    // ── Privacy exclusions ───────────────────────────────────────
+    void testPrivacyExclusionExcludesSubtree();
+    void testPrivacyExclusionSharedAcrossCopies();
+
     // ── Edge cases ───────────────────────────────────────────────
     void testEmptyPath();
     void testUnicodeFilename();
@@ -461,6 +465,42 @@ void TestPathRules::testLoadBsignoreMissingFileMarksNotLoaded()
     QCOMPARE(rules.bsignorePatternCount(), static_cast<size_t>(0));
 }
 
+// ── Privacy exclusions ───────────────────────────────────────────
+
+void TestPathRules::testPrivacyExclusionExcludesSubtree()
+{
+    bs::PathRules rules;
+    rules.setPrivacyExclusions({"/Users/me/Documents/Medical/", ""});
+    QCOMPARE(rules.privacyExclusions(),
+             std::vector<std::string>({"/Users/me/Documents/Medical"}));
+
+    QCOMPARE(rules.validate("/Users/me/Documents/Medical"), bs::ValidationResult::Exclude);
+    QCOMPARE(rules.validate("/Users/me/Documents/Medical/"), bs::ValidationResult::Exclude);
+    QCOMPARE(rules.validate("/Users/me/Documents/Medical/2026/scan.pdf"),
+             bs::ValidationResult::Exclude);
+    // A sibling sharing the prefix is not inside the folder.
+    QCOMPARE(rules.validate("/Users/me/Documents/MedicalBills.pdf"),
+             bs::ValidationResult::Include);
+
+    // Privacy beats an explicit include root and the sensitive-path rules.
+    rules.setExplicitIncludeRoots({"/Users/me/.ssh"});
+    rules.setPrivacyExclusions({"/Users/me/.ssh"});
+    QCOMPARE(rules.validate("/Users/me/.ssh/config"), bs::ValidationResult::Exclude);
+
+    rules.setPrivacyExclusions({});
+    QCOMPARE(rules.validate("/Users/me/Documents/Medical/2026/scan.pdf"),
+             bs::ValidationResult::Include);
+}
+
+void TestPathRules::testPrivacyExclusionSharedAcrossCopies()
+{
+    bs::PathRules rules;
+    const bs::PathRules copy = rules;
+    rules.setPrivacyExclusions({"/Users/me/Private"});
+    QVERIFY(copy.isPrivacyExcluded("/Users/me/Private/notes.md"));
+    QVERIFY(!copy.isPrivacyExcluded("/Users/me/Public/notes.md"));
+}
+
 // ── Edge cases ───────────────────────────────────────────────────
 
 void TestPathRules::testEmptyPath()
@@ -0,0 +1,357 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/peer_sync.h"
+#include "core/ipc/http_server.h"
+#include "core/ipc/peer_client.h"
+
+#include <QHostAddress>
+#include <QJsonDocument>
+
+namespace {
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+bs::SyncedItem syncedItem(int64_t id, const QString& path)
+{
+    bs::SyncedItem item;
+    item.id = id;
+    item.path = path;
+    item.name = path.mid(path.lastIndexOf(QLatin1Char('/')) + 1);
+    item.kind = QStringLiteral("text");
+    item.size = 120;
+    item.createdAt = 1790000000.0;
+    item.modifiedAt = 1790003600.0;
+    item.indexedAt = 1790007200.0;
+    item.contentHash = QStringLiteral("hash-%1").arg(id);
+    return item;
+}
+
+} // namespace
+
+class TestPeerSync : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParsePeers();
+    void testParsePeersRejectsInvalid();
+    void testPeerPaths();
+    void testSegments();
+    void testChangedAndRemovedSegments();
+    void testJsonRoundTrip();
+    void testToDonatedItem();
+    void testStaleIdentifiers();
+    void testCursorToken();
+    void testSegmentDelta();
+    void testSegmentDeltaJson();
+    void testClientRefusesPlainHttpOffLoopback();
+    void testClientFetchesJson();
+};
+
+void TestPeerSync::testParsePeers()
+{
+    QString error;
+    const auto peers = bs::PeerSync::parsePeers(
+        QStringLiteral(R"([{"name": "desktop", "url": "https://desktop.local:8443",
+                            "token": "bst_abc", "fingerprint": "AA:BB"},
+                           {"name": "loop", "url": "http://127.0.0.1:9000", "token": "bst_def"}])"),
+        &error);
+    QVERIFY2(peers.has_value(), qPrintable(error));
+    QCOMPARE(peers->size(), size_t(2));
+    QCOMPARE(peers->at(0).name, QStringLiteral("desktop"));
+    QCOMPARE(peers->at(0).url, QUrl(QStringLiteral("https://desktop.local:8443")));
+    QCOMPARE(peers->at(0).token, QStringLiteral("bst_abc"));
+    QCOMPARE(peers->at(0).fingerprint, QStringLiteral("AA:BB"));
+    QVERIFY(peers->at(1).fingerprint.isEmpty());
+
+    const auto none = bs::PeerSync::parsePeers(QString(), &error);
+    QVERIFY(none.has_value());
+    QVERIFY(none->empty());
+}
+
+void TestPeerSync::testParsePeersRejectsInvalid()
+{
+    QString error;
+    QVERIFY(!bs::PeerSync::parsePeers(QStringLiteral("{}"), &error).has_value());
+    // Plain http off loopback would send the token in the clear.
+    QVERIFY(!bs::PeerSync::parsePeers(
+        QStringLiteral(R"([{"name": "desktop", "url": "http://desktop.local", "token": "t"}])"),
+        &error).has_value());
+    QVERIFY(error.contains(QStringLiteral("desktop")));
+    QVERIFY(!bs::PeerSync::parsePeers(
+        QStringLiteral(R"([{"name": "my desk", "url": "https://d.local", "token": "t"}])"),
+        &error).has_value());
+    QVERIFY(!bs::PeerSync::parsePeers(
+        QStringLiteral(R"([{"name": "desktop", "url": "https://d.local"}])"), &error).has_value());
+    QVERIFY(!bs::PeerSync::parsePeers(
+        QStringLiteral(R"([{"name": "desktop", "url": "https://a.local", "token": "t"},
+                           {"name": "Desktop", "url": "https://b.local", "token": "u"}])"),
+        &error).has_value());
+}
+
+void TestPeerSync::testPeerPaths()
+{
+    QCOMPARE(bs::PeerSync::bundleIdentifier(QStringLiteral("Desktop")),
+             QStringLiteral("com.betterspotlight.peer.desktop"));
+    const QString replica = bs::SpotlightDonation::itemPath(
+        bs::PeerSync::bundleIdentifier(QStringLiteral("desktop")), QStringLiteral("42"));
+    QVERIFY(bs::PeerSync::isPeerPath(replica));
+    QCOMPARE(bs::PeerSync::peerName(replica), QStringLiteral("desktop"));
+
+    const QString message =
+        bs::SpotlightDonation::itemPath(QStringLiteral("com.apple.MobileSMS"), QStringLiteral("1"));
+    QVERIFY(!bs::PeerSync::isPeerPath(message));
+    QVERIFY(!bs::PeerSync::isPeerPath(QStringLiteral("/Users/alice/a.txt")));
+
+    // Neither donations nor replicas are published again.
+    QVERIFY(bs::PeerSync::isSyncable(QStringLiteral("/Volumes/Archive/a.txt")));
+    QVERIFY(!bs::PeerSync::isSyncable(message));
+    QVERIFY(!bs::PeerSync::isSyncable(replica));
+}
+
+void TestPeerSync::testSegments()
+{
+    const std::vector<bs::SyncedItem> items = {
+        syncedItem(300, QStringLiteral("/a/c.txt")),
+        syncedItem(1, QStringLiteral("/a/a.txt")),
+        syncedItem(255, QStringLiteral("/a/b.txt")),
+    };
+    const auto segments = bs::PeerSync::segments(items);
+    QCOMPARE(segments.size(), size_t(2));
+    QCOMPARE(segments.at(0).first, int64_t(0));
+    QCOMPARE(segments.at(0).count, 2);
+    QCOMPARE(segments.at(1).first, int64_t(256));
+    QCOMPARE(segments.at(1).count, 1);
+    QVERIFY(!segments.at(0).digest.isEmpty());
+
+    // Order does not matter; a reindexed entry does.
+    const auto reordered = bs::PeerSync::segments({items.at(2), items.at(1), items.at(0)});
+    QCOMPARE(reordered.at(0).digest, segments.at(0).digest);
+    std::vector<bs::SyncedItem> changed = items;
+    changed.at(1).indexedAt += 60.0;
+    const auto afterReindex = bs::PeerSync::segments(changed);
+    QVERIFY(afterReindex.at(0).digest != segments.at(0).digest);
+    QCOMPARE(afterReindex.at(1).digest, segments.at(1).digest);
+
+    // So does a change to the user's signals on an entry.
+    changed = items;
+    changed.at(0).metadata.insert(QLatin1String(bs::MetadataMerge::kPinnedField),
+                                  bs::MetadataValue{true, 1790010000.0});
+    const auto afterPin = bs::PeerSync::segments(changed);
+    QCOMPARE(afterPin.at(0).digest, segments.at(0).digest);
+    QVERIFY(afterPin.at(1).digest != segments.at(1).digest);
+    const auto roundTripped = bs::PeerSync::itemFromJson(bs::PeerSync::itemToJson(changed.at(0)));
+    QVERIFY(roundTripped.has_value());
+    QCOMPARE(bs::PeerSync::segments({*roundTripped}).at(0).digest, afterPin.at(1).digest);
+}
+
+void TestPeerSync::testChangedAndRemovedSegments()
+{
+    bs::PeerSync::SegmentDigests known;
+    known.insert(0, QStringLiteral("d0"));
+    known.insert(256, QStringLiteral("d1"));
+    known.insert(512, QStringLiteral("d2"));
+
+    std::vector<bs::SyncSegment> remote(3);
+    remote[0] = {0, 10, QStringLiteral("d0")};
+    remote[1] = {256, 10, QStringLiteral("d1-new")};
+    remote[2] = {768, 3, QStringLiteral("d3")};
+
+    QCOMPARE(bs::PeerSync::changedSegments(known, remote), (std::vector<int64_t>{256, 768}));
+    QCOMPARE(bs::PeerSync::removedSegments(known, remote), (std::vector<int64_t>{512}));
+}
+
+void TestPeerSync::testJsonRoundTrip()
+{
+    bs::SyncedItem item = syncedItem(7, QStringLiteral("/Volumes/Archive/report.pdf"));
+    item.kind = QStringLiteral("pdf");
+    item.text = QStringLiteral("Quarterly revenue");
+    const auto parsed = bs::PeerSync::itemFromJson(bs::PeerSync::itemToJson(item));
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->id, int64_t(7));
+    QCOMPARE(parsed->path, item.path);
+    QCOMPARE(parsed->kind, QStringLiteral("pdf"));
+    QCOMPARE(parsed->modifiedAt, item.modifiedAt);
+    QCOMPARE(parsed->text, item.text);
+
+    QJsonObject relative = bs::PeerSync::itemToJson(item);
+    relative[QStringLiteral("path")] = QStringLiteral("donated://com.apple.MobileSMS/1");
+    QVERIFY(!bs::PeerSync::itemFromJson(relative).has_value());
+
+    const auto segments = bs::PeerSync::segments({item});
+    QJsonArray json = bs::PeerSync::segmentsToJson(segments);
+    // Off the segment grid: dropped.
+    json.append(QJsonObject{{QStringLiteral("first"), 100},
+                            {QStringLiteral("count"), 1},
+                            {QStringLiteral("digest"), QStringLiteral("x")}});
+    const auto back = bs::PeerSync::segmentsFromJson(json);
+    QCOMPARE(back.size(), size_t(1));
+    QCOMPARE(back.front().digest, segments.front().digest);
+
+    bs::PeerSync::SegmentDigests digests;
+    digests.insert(0, QStringLiteral("a"));
+    digests.insert(512, QStringLiteral("b"));
+    QCOMPARE(bs::PeerSync::digestsFromJson(bs::PeerSync::digestsToJson(digests)), digests);
+}
+
+void TestPeerSync::testToDonatedItem()
+{
+    bs::SyncedItem item = syncedItem(42, QStringLiteral("/Volumes/Archive/2019/budget.xlsx"));
+    item.kind = QStringLiteral("spreadsheet");
+    item.text = QStringLiteral("Travel 1200");
+    const bs::DonatedItem donated = bs::PeerSync::toDonatedItem(QStringLiteral("desktop"), item);
+
+    QCOMPARE(donated.path, QStringLiteral("donated://com.betterspotlight.peer.desktop/42"));
+    QCOMPARE(donated.parentPath, QStringLiteral("donated://com.betterspotlight.peer.desktop"));
+    QCOMPARE(donated.name, QStringLiteral("budget.xlsx"));
+    QVERIFY(donated.textContent.contains(QStringLiteral("Travel 1200")));
+    QVERIFY(donated.textContent.contains(item.path));
+    QCOMPARE(donated.modifiedAt, item.modifiedAt);
+    QCOMPARE(attributeValue(donated, bs::PeerSync::kPeerAttribute), QStringLiteral("desktop"));
+    QCOMPARE(attributeValue(donated, bs::PeerSync::kPeerPathAttribute), item.path);
+    QCOMPARE(attributeValue(donated, bs::PeerSync::kPeerKindAttribute),
+             QStringLiteral("spreadsheet"));
+    QCOMPARE(attributeValue(donated, bs::SpotlightDonation::kDescriptionAttribute),
+             QStringLiteral("desktop · /Volumes/Archive/2019"));
+    QVERIFY(!donated.contentHash.isEmpty());
+
+    item.text = QStringLiteral("Travel 1400");
+    QVERIFY(bs::PeerSync::toDonatedItem(QStringLiteral("desktop"), item).contentHash
+            != donated.contentHash);
+}
+
+void TestPeerSync::testStaleIdentifiers()
+{
+    const QStringList indexed = {QStringLiteral("1"), QStringLiteral("2"),
+                                 QStringLiteral("300"), QStringLiteral("bogus")};
+    const std::vector<bs::SyncedItem> items = {syncedItem(2, QStringLiteral("/a/b.txt"))};
+    QCOMPARE(bs::PeerSync::staleIdentifiers(indexed, 0, items), QStringList{QStringLiteral("1")});
+    QCOMPARE(bs::PeerSync::staleIdentifiers(indexed, 256, {}), QStringList{QStringLiteral("300")});
+}
+
+void TestPeerSync::testCursorToken()
+{
+    bs::SyncCursor cursor;
+    cursor.indexedSince = 1790000000.0;
+    cursor.tombstoneSeq = 42;
+    const QString token = bs::PeerSync::cursorToken(cursor);
+    const auto parsed = bs::PeerSync::cursorFromToken(token);
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->indexedSince, 1790000000.0);
+    QCOMPARE(parsed->tombstoneSeq, int64_t(42));
+
+    for (const char* invalid : {"", "c1-1790000000", "c2-1-2", "c1-x-2", "c1--1-2"}) {
+        QVERIFY2(!bs::PeerSync::cursorFromToken(QString::fromLatin1(invalid)).has_value(),
+                 invalid);
+    }
+}
+
+void TestPeerSync::testSegmentDelta()
+{
+    bs::SyncedItem old = syncedItem(257, QStringLiteral("/a/old.txt"));
+    bs::SyncedItem fresh = syncedItem(300, QStringLiteral("/a/fresh.txt"));
+    fresh.indexedAt = old.indexedAt + 600.0;
+    const std::vector<bs::SyncedItem> items = {old, fresh, syncedItem(900, QStringLiteral("/b.txt"))};
+
+    QCOMPARE(bs::PeerSync::touchedSegments({300, 257, 900}, {2, 260}),
+             std::vector<int64_t>({0, 256, 768}));
+
+    const bs::SegmentDelta delta =
+        bs::PeerSync::segmentDelta(256, items, fresh.indexedAt, {2, 260, 260, 258});
+    QCOMPARE(delta.items.size(), size_t(1));
+    QCOMPARE(delta.items[0].id, int64_t(300));
+    QCOMPARE(delta.tombstones, std::vector<int64_t>({258, 260}));
+    // The digest covers the whole segment, not only what changed.
+    QCOMPARE(delta.segment.count, 2);
+    QCOMPARE(delta.segment.digest, bs::PeerSync::segments({old, fresh}).front().digest);
+
+    const bs::SegmentDelta emptied = bs::PeerSync::segmentDelta(512, items, 0.0, {513});
+    QCOMPARE(emptied.segment.first, int64_t(512));
+    QCOMPARE(emptied.segment.count, 0);
+    QVERIFY(emptied.segment.digest.isEmpty());
+    QCOMPARE(emptied.tombstones, std::vector<int64_t>({513}));
+}
+
+void TestPeerSync::testSegmentDeltaJson()
+{
+    bs::SyncedItem fresh = syncedItem(300, QStringLiteral("/a/fresh.txt"));
+    fresh.text = QStringLiteral("quarterly numbers");
+    const bs::SegmentDelta delta = bs::PeerSync::segmentDelta(256, {fresh}, 0.0, {260});
+
+    const auto parsed = bs::PeerSync::segmentDeltaFromJson(bs::PeerSync::segmentDeltaToJson(delta));
+    QVERIFY(parsed.has_value());
+    QCOMPARE(parsed->segment.first, int64_t(256));
+    QCOMPARE(parsed->segment.digest, delta.segment.digest);
+    QCOMPARE(parsed->items.size(), size_t(1));
+    QCOMPARE(parsed->items[0].text, QStringLiteral("quarterly numbers"));
+    QCOMPARE(parsed->tombstones, std::vector<int64_t>({260}));
+
+    QJsonObject offGrid = bs::PeerSync::segmentDeltaToJson(delta);
+    offGrid[QStringLiteral("first")] = 100;
+    QVERIFY(!bs::PeerSync::segmentDeltaFromJson(offGrid).has_value());
+    QJsonObject foreign = bs::PeerSync::segmentDeltaToJson(delta);
+    foreign[QStringLiteral("tombstones")] = QJsonArray{5};
+    QVERIFY(!bs::PeerSync::segmentDeltaFromJson(foreign).has_value());
+    QJsonObject noDigest = bs::PeerSync::segmentDeltaToJson(delta);
+    noDigest[QStringLiteral("digest")] = QString();
+    QVERIFY(!bs::PeerSync::segmentDeltaFromJson(noDigest).has_value());
+}
+
+void TestPeerSync::testClientRefusesPlainHttpOffLoopback()
+{
+    QVERIFY(bs::PeerClient::isAllowedUrl(QUrl(QStringLiteral("https://desktop.local:8443"))));
+    QVERIFY(bs::PeerClient::isAllowedUrl(QUrl(QStringLiteral("http://127.0.0.1:9000"))));
+    QVERIFY(!bs::PeerClient::isAllowedUrl(QUrl(QStringLiteral("http://desktop.local:8443"))));
+    QVERIFY(!bs::PeerClient::isAllowedUrl(QUrl(QStringLiteral("ftp://desktop.local"))));
+
+    bs::PeerEndpoint peer;
+    peer.baseUrl = QUrl(QStringLiteral("http://desktop.local:8443"));
+    peer.token = QByteArrayLiteral("bst_abc");
+    QString error;
+    QVERIFY(!bs::PeerClient::getJson(peer, QStringLiteral("/v1/sync/segments"), &error, 1000)
+                 .has_value());
+    QVERIFY(!error.isEmpty());
+}
+
+void TestPeerSync::testClientFetchesJson()
+{
+    bs::HttpServer server;
+    QByteArray authorization;
+    server.route(QStringLiteral("GET"), QStringLiteral("/v1/sync/segments"),
+                 [&authorization](const bs::HttpRequest& request) {
+        authorization = request.header(QStringLiteral("Authorization")).toUtf8();
+        QJsonObject result;
+        result[QStringLiteral("segmentSpan")] = static_cast<qint64>(bs::PeerSync::kSegmentSpan);
+        return bs::HttpResponse::json(200, result);
+    });
+    server.route(QStringLiteral("GET"), QStringLiteral("/v1/denied"),
+                 [](const bs::HttpRequest&) {
+        return bs::HttpResponse::error(401, QStringLiteral("Invalid token"));
+    });
+    QVERIFY(server.listen(QHostAddress::LocalHost, 0));
+
+    bs::PeerEndpoint peer;
+    peer.baseUrl = QUrl(QStringLiteral("http://127.0.0.1:%1/").arg(server.serverPort()));
+    peer.token = QByteArrayLiteral("bst_abc");
+    QString error;
+    const auto reply =
+        bs::PeerClient::getJson(peer, QStringLiteral("/v1/sync/segments"), &error, 5000);
+    QVERIFY2(reply.has_value(), qPrintable(error));
+    QCOMPARE(reply->value(QStringLiteral("segmentSpan")).toInteger(), bs::PeerSync::kSegmentSpan);
+    QCOMPARE(authorization, QByteArray("Bearer bst_abc"));
+
+    QVERIFY(!bs::PeerClient::getJson(peer, QStringLiteral("/v1/denied"), &error, 5000)
+                 .has_value());
+    QVERIFY(error.contains(QStringLiteral("401")));
+    QVERIFY(error.contains(QStringLiteral("Invalid token")));
+}
+
+QTEST_MAIN(TestPeerSync)
+#include "test_peer_sync.moc"
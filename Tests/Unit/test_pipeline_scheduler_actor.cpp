@@ -24,6 +24,7 @@ private slots:
     void testLaneCapsAndDropReasons();
     void testLiveLaneDispatchBias();
     void testShutdownUnblocksBlockingDequeue();
+    void testTakeAllDrainsLiveLaneFirst();
 };
 
 void TestPipelineSchedulerActor::testLaneCapsAndDropReasons()
@@ -107,5 +108,21 @@ void TestPipelineSchedulerActor::testShutdownUnblocksBlockingDequeue()
     QVERIFY(!result.has_value());
 }
 
+void TestPipelineSchedulerActor::testTakeAllDrainsLiveLaneFirst()
+{
+    bs::PipelineSchedulerActor actor;
+    QVERIFY(actor.enqueue(makeItem("/tmp/rebuild-a"), bs::PipelineLane::Rebuild));
+    QVERIFY(actor.enqueue(makeItem("/tmp/live-a"), bs::PipelineLane::Live));
+    QVERIFY(actor.enqueue(makeItem("/tmp/live-b"), bs::PipelineLane::Live));
+
+    const auto items = actor.takeAll();
+    QCOMPARE(items.size(), static_cast<size_t>(3));
+    QCOMPARE(items[0].item.filePath, std::string("/tmp/live-a"));
+    QCOMPARE(items[1].item.filePath, std::string("/tmp/live-b"));
+    QCOMPARE(items[2].item.filePath, std::string("/tmp/rebuild-a"));
+    QCOMPARE(items[2].lane, bs::PipelineLane::Rebuild);
+    QVERIFY(!actor.tryDequeue().has_value());
+}
+
 QTEST_MAIN(TestPipelineSchedulerActor)
 #include "test_pipeline_scheduler_actor.moc"
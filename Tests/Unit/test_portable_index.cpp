@@ -0,0 +1,204 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "core/indexing/portable_index.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QTemporaryDir>
+#include <QTimeZone>
+
+namespace {
+
+bs::SyncedItem syncedItem(int64_t id, const QString& path, const QString& text)
+{
+    bs::SyncedItem item;
+    item.id = id;
+    item.path = path;
+    item.name = path.mid(path.lastIndexOf(QLatin1Char('/')) + 1);
+    item.kind = QStringLiteral("markdown");
+    item.size = 1200;
+    item.createdAt = 1780000000.0;
+    item.modifiedAt = 1790000000.0;
+    item.indexedAt = 1790000100.0;
+    item.contentHash = QStringLiteral("h%1").arg(id);
+    item.text = text;
+    return item;
+}
+
+std::vector<bs::SyncedItem> segmentItems()
+{
+    return {syncedItem(257, QStringLiteral("/Users/alice/Handbook/Onboarding.md"),
+                       QStringLiteral("Laptop setup and first week checklist")),
+            syncedItem(300, QStringLiteral("/Users/alice/Handbook/Travel.md"),
+                       QStringLiteral("Booking flights and expense reports"))};
+}
+
+bs::PortableManifest manifest()
+{
+    bs::PortableManifest manifest;
+    manifest.formatVersion = bs::PortableIndex::kFormatVersion;
+    manifest.schemaVersion = 6;
+    manifest.source = QStringLiteral("alice-mbp");
+    manifest.createdAt = QDateTime::fromSecsSinceEpoch(1790000000, QTimeZone::UTC);
+    manifest.segments = bs::PeerSync::segments(segmentItems());
+    manifest.itemCount = 2;
+    return manifest;
+}
+
+QByteArray manifestWith(const char* key, const QJsonValue& value)
+{
+    QJsonObject json = bs::PortableIndex::manifestToJson(manifest());
+    json[QLatin1String(key)] = value;
+    return QJsonDocument(json).toJson();
+}
+
+} // namespace
+
+class TestPortableIndex : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testSegmentFile();
+    void testManifestRoundTrip();
+    void testManifestRejectsInvalid();
+    void testSchemaListsEntryFields();
+    void testSegmentRoundTrip();
+    void testSegmentDetectsDamage();
+    void testImportItem();
+};
+
+void TestPortableIndex::testSegmentFile()
+{
+    QCOMPARE(bs::PortableIndex::segmentFile(0), QStringLiteral("segments/000000000000.ndjson"));
+    QCOMPARE(bs::PortableIndex::segmentFile(512), QStringLiteral("segments/000000000512.ndjson"));
+}
+
+void TestPortableIndex::testManifestRoundTrip()
+{
+    const QJsonObject json = bs::PortableIndex::manifestToJson(manifest());
+    QCOMPARE(json.value(QStringLiteral("format")).toString(),
+             QLatin1String(bs::PortableIndex::kFormat));
+    QCOMPARE(json.value(QStringLiteral("segments")).toArray().at(0).toObject()
+                 .value(QStringLiteral("file")).toString(),
+             QStringLiteral("segments/000000000256.ndjson"));
+
+    QString error;
+    const auto parsed = bs::PortableIndex::manifestFromJson(QJsonDocument(json).toJson(), &error);
+    QVERIFY2(parsed.has_value(), qPrintable(error));
+    QCOMPARE(parsed->formatVersion, bs::PortableIndex::kFormatVersion);
+    QCOMPARE(parsed->schemaVersion, 6);
+    QCOMPARE(parsed->source, QStringLiteral("alice-mbp"));
+    QCOMPARE(parsed->createdAt.toSecsSinceEpoch(), qint64(1790000000));
+    QCOMPARE(parsed->itemCount, int64_t(2));
+    QCOMPARE(parsed->segments.size(), size_t(1));
+    QCOMPARE(parsed->segments[0].first, int64_t(256));
+    QCOMPARE(parsed->segments[0].count, 2);
+    QCOMPARE(parsed->segments[0].digest, manifest().segments[0].digest);
+}
+
+void TestPortableIndex::testManifestRejectsInvalid()
+{
+    const std::vector<QByteArray> invalid = {
+        QByteArrayLiteral("[]"),
+        manifestWith("format", QStringLiteral("something-else")),
+        manifestWith("formatVersion", bs::PortableIndex::kFormatVersion + 1),
+        manifestWith("segmentSpan", 1024),
+        manifestWith("itemCount", 3),
+        manifestWith("segments", QJsonArray{QJsonObject{{QStringLiteral("first"), 100},
+                                                        {QStringLiteral("count"), 2},
+                                                        {QStringLiteral("digest"), QStringLiteral("ab")}}}),
+    };
+    for (const QByteArray& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::PortableIndex::manifestFromJson(json, &error).has_value(), json.constData());
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestPortableIndex::testSchemaListsEntryFields()
+{
+    const QJsonObject schema = bs::PortableIndex::schemaJson();
+    QCOMPARE(schema.value(QStringLiteral("formatVersion")).toInt(), bs::PortableIndex::kFormatVersion);
+    QStringList required;
+    QStringList names;
+    for (const QJsonValue& value : schema.value(QStringLiteral("entry")).toArray()) {
+        const QJsonObject field = value.toObject();
+        names.append(field.value(QStringLiteral("name")).toString());
+        if (field.value(QStringLiteral("required")).toBool()) {
+            required.append(field.value(QStringLiteral("name")).toString());
+        }
+    }
+    QCOMPARE(required, QStringList({QStringLiteral("id"), QStringLiteral("path")}));
+    // Every field an exported entry carries is described.
+    for (const QString& key : bs::PeerSync::itemToJson(segmentItems()[0]).keys()) {
+        QVERIFY2(names.contains(key), qPrintable(key));
+    }
+}
+
+void TestPortableIndex::testSegmentRoundTrip()
+{
+    const std::vector<bs::SyncedItem> items = segmentItems();
+    const QByteArray ndjson = bs::PortableIndex::segmentToNdjson(items);
+    QCOMPARE(ndjson.count('\n'), 2);
+
+    QString error;
+    const auto parsed =
+        bs::PortableIndex::segmentFromNdjson(ndjson, manifest().segments[0], &error);
+    QVERIFY2(parsed.has_value(), qPrintable(error));
+    QCOMPARE(parsed->size(), size_t(2));
+    QCOMPARE(parsed->at(1).path, items[1].path);
+    QCOMPARE(parsed->at(1).text, items[1].text);
+}
+
+void TestPortableIndex::testSegmentDetectsDamage()
+{
+    const bs::SyncSegment segment = manifest().segments[0];
+    std::vector<bs::SyncedItem> items = segmentItems();
+    QString error;
+
+    // A missing entry.
+    QVERIFY(!bs::PortableIndex::segmentFromNdjson(
+                 bs::PortableIndex::segmentToNdjson({items[0]}), segment, &error)
+                 .has_value());
+    // An entry changed after export.
+    items[1].modifiedAt += 60.0;
+    QVERIFY(!bs::PortableIndex::segmentFromNdjson(bs::PortableIndex::segmentToNdjson(items),
+                                                  segment, &error)
+                 .has_value());
+    // An entry from another segment.
+    items = segmentItems();
+    items[1].id = 900;
+    QVERIFY(!bs::PortableIndex::segmentFromNdjson(bs::PortableIndex::segmentToNdjson(items),
+                                                  segment, &error)
+                 .has_value());
+    QVERIFY(error.contains(QStringLiteral("line 2")));
+    QVERIFY(!bs::PortableIndex::segmentFromNdjson(QByteArrayLiteral("{not json\n"), segment, &error)
+                 .has_value());
+}
+
+void TestPortableIndex::testImportItem()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto store = bs::SQLiteStore::open(dir.filePath(QStringLiteral("index.db")));
+    QVERIFY(store.has_value());
+
+    for (const bs::SyncedItem& item : segmentItems()) {
+        QVERIFY(bs::PortableIndex::importItem(*store, item));
+    }
+    bs::SyncedItem folder = syncedItem(301, QStringLiteral("/Users/alice/Handbook"), QString());
+    folder.kind = QStringLiteral("directory");
+    QVERIFY(bs::PortableIndex::importItem(*store, folder));
+
+    const auto onboarding = store->getItemByPath(QStringLiteral("/Users/alice/Handbook/Onboarding.md"));
+    QVERIFY(onboarding.has_value());
+    QCOMPARE(onboarding->size, int64_t(1200));
+    QCOMPARE(onboarding->modifiedAt, 1790000000.0);
+    QCOMPARE(onboarding->contentHash, QStringLiteral("h257"));
+    QCOMPARE(store->searchFts5(QStringLiteral("checklist")).size(), size_t(1));
+    QVERIFY(store->getItemByPath(QStringLiteral("/Users/alice/Handbook")).has_value());
+}
+
+QTEST_MAIN(TestPortableIndex)
+#include "test_portable_index.moc"
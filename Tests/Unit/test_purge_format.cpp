@@ -0,0 +1,130 @@
+#include <QtTest/QtTest>
+
+#include "cli/purge_format.h"
+
+namespace {
+
+const QString kPath = QStringLiteral("/Users/test/Secret");
+
+QJsonObject makeIndex(qint64 items, qint64 pending)
+{
+    QJsonObject remaining;
+    remaining[QStringLiteral("items")] = items;
+    remaining[QStringLiteral("ftsRows")] = items;
+    remaining[QStringLiteral("behaviorEvents")] = 0;
+
+    QJsonObject index;
+    index[QStringLiteral("path")] = kPath;
+    index[QStringLiteral("remaining")] = remaining;
+    index[QStringLiteral("pendingCompactionDeletes")] = pending;
+    index[QStringLiteral("freePages")] = 0;
+    index[QStringLiteral("verified")] = items == 0 && pending == 0;
+    return index;
+}
+
+QJsonObject makeVectors(qint64 remaining, bool saved = true)
+{
+    QJsonObject vectors;
+    vectors[QStringLiteral("remainingVectors")] = remaining;
+    vectors[QStringLiteral("vectorsSaved")] = saved;
+    return vectors;
+}
+
+} // namespace
+
+class TestPurgeFormat : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testVerified();
+    void testPendingCompactionSuggestsPurge();
+    void testRemainingItemsSuggestDeleting();
+    void testQueryServiceNotReached();
+    void testPurgedHeader();
+};
+
+void TestPurgeFormat::testVerified()
+{
+    const QJsonObject index = makeIndex(0, 0);
+    QVERIFY(bs::PurgeFormat::verified(index, makeVectors(0)));
+    QVERIFY(!bs::PurgeFormat::verified(index, makeVectors(2)));
+    QVERIFY(!bs::PurgeFormat::verified(index, makeVectors(0, /*saved=*/false)));
+
+    const QString out = bs::PurgeFormat::render(index, makeVectors(0), false);
+    QVERIFY(out.contains(QStringLiteral("  Index rows:          none\n")));
+    QVERIFY(out.contains(QStringLiteral("  Awaiting compaction: none\n")));
+    QVERIFY(out.contains(QStringLiteral("  Embeddings:          none\n")));
+    QVERIFY(out.endsWith(QStringLiteral("Verified: nothing remains for /Users/test/Secret\n")));
+    QVERIFY(!out.contains(QStringLiteral("Purged")));
+}
+
+void TestPurgeFormat::testPendingCompactionSuggestsPurge()
+{
+    const QJsonObject index = makeIndex(0, 3);
+    QVERIFY(!bs::PurgeFormat::verified(index, makeVectors(0)));
+
+    const QString out = bs::PurgeFormat::render(index, makeVectors(0), false);
+    QVERIFY(out.contains(QStringLiteral("  Awaiting compaction: 3 deleted item(s)\n")));
+    QVERIFY(out.contains(QStringLiteral("Not verified: data for /Users/test/Secret may remain\n")));
+    QVERIFY(out.endsWith(
+        QStringLiteral("Fix: run 'bspot purge /Users/test/Secret' to compact now\n")));
+}
+
+void TestPurgeFormat::testRemainingItemsSuggestDeleting()
+{
+    QJsonObject index = makeIndex(2, 0);
+    QJsonObject remaining = index.value(QStringLiteral("remaining")).toObject();
+    remaining[QStringLiteral("behaviorEvents")] = -1;
+    index[QStringLiteral("remaining")] = remaining;
+
+    const QString out = bs::PurgeFormat::render(index, makeVectors(1), false);
+    QVERIFY(out.contains(QStringLiteral(
+        "  Index rows:          2 item(s), 2 search row(s), unknown behavior event(s)\n")));
+    QVERIFY(out.contains(QStringLiteral("  Embeddings:          1 orphaned vector(s)\n")));
+    QVERIFY(out.endsWith(QStringLiteral(
+        "Fix: delete or exclude /Users/test/Secret, then run 'bspot purge /Users/test/Secret'\n")));
+}
+
+void TestPurgeFormat::testQueryServiceNotReached()
+{
+    const QJsonObject index = makeIndex(0, 0);
+    QVERIFY(!bs::PurgeFormat::verified(index, QJsonObject()));
+
+    const QString out = bs::PurgeFormat::render(index, QJsonObject(), false);
+    QVERIFY(out.contains(
+        QStringLiteral("  Embeddings:          not checked (query service not reached)\n")));
+    QVERIFY(out.contains(QStringLiteral("Not verified")));
+    QVERIFY(!out.contains(QStringLiteral("Fix:")));
+
+    const QString unsaved = bs::PurgeFormat::render(index, makeVectors(0, false), false);
+    QVERIFY(unsaved.contains(QStringLiteral("  Embeddings:          erased in memory but not saved\n")));
+}
+
+void TestPurgeFormat::testPurgedHeader()
+{
+    QJsonObject compaction;
+    compaction[QStringLiteral("complete")] = true;
+    compaction[QStringLiteral("bytesBefore")] = 3 * 1024 * 1024;
+    compaction[QStringLiteral("bytesAfter")] = 512 * 1024;
+
+    QJsonObject index = makeIndex(0, 0);
+    index[QStringLiteral("deletedItems")] = 4;
+    index[QStringLiteral("compaction")] = compaction;
+
+    const QString out = bs::PurgeFormat::render(index, makeVectors(0), true);
+    QVERIFY(out.startsWith(QStringLiteral("Purged 4 item(s) under /Users/test/Secret\n"
+                                          "Compacted the index: 3.0 MB -> 512.0 KB\n")));
+    QVERIFY(out.contains(QStringLiteral("Verified:")));
+
+    compaction[QStringLiteral("complete")] = false;
+    index[QStringLiteral("compaction")] = compaction;
+    index[QStringLiteral("pendingCompactionDeletes")] = 4;
+    index[QStringLiteral("verified")] = false;
+    const QString failed = bs::PurgeFormat::render(index, makeVectors(0), true);
+    QVERIFY(failed.contains(QStringLiteral("Compaction did not finish; see the indexer log\n")));
+    // The purge already tried to compact, so there is no "run purge" hint.
+    QVERIFY(!failed.contains(QStringLiteral("Fix:")));
+}
+
+QTEST_MAIN(TestPurgeFormat)
+#include "test_purge_format.moc"
@@ -0,0 +1,100 @@
+#include <QtTest/QtTest>
+
+#include "core/query/query_abbreviations.h"
+
+class TestQueryAbbreviations : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testExpandsWholeWordsOnly();
+    void testLeavesFieldFiltersAlone();
+    void testExpansionIsNotExpandedAgain();
+    void testRejectsInvalidTerms();
+    void testEntryLimit();
+    void testJsonRoundTripSkipsInvalid();
+};
+
+void TestQueryAbbreviations::testExpandsWholeWordsOnly()
+{
+    bs::QueryAbbreviations abbreviations;
+    QVERIFY(abbreviations.set(QStringLiteral("Inv"), QStringLiteral("invoice"), nullptr));
+    QVERIFY(abbreviations.set(QStringLiteral("k8s"), QStringLiteral("  kubernetes   cluster "),
+                              nullptr));
+    QCOMPARE(abbreviations.entries().value(QStringLiteral("inv")), QStringLiteral("invoice"));
+
+    QStringList applied;
+    QCOMPARE(abbreviations.expand(QStringLiteral("inv 2025 k8s INV"), &applied),
+             QStringLiteral("invoice 2025 kubernetes cluster invoice"));
+    QCOMPARE(applied, QStringList({QStringLiteral("inv"), QStringLiteral("k8s")}));
+
+    applied.clear();
+    QCOMPARE(abbreviations.expand(QStringLiteral("invoices inv-2025"), &applied),
+             QStringLiteral("invoices inv-2025"));
+    QVERIFY(applied.isEmpty());
+}
+
+void TestQueryAbbreviations::testLeavesFieldFiltersAlone()
+{
+    bs::QueryAbbreviations abbreviations;
+    QVERIFY(abbreviations.set(QStringLiteral("pdf"), QStringLiteral("portable document"), nullptr));
+    QCOMPARE(abbreviations.expand(QStringLiteral("kind:pdf report")),
+             QStringLiteral("kind:pdf report"));
+}
+
+void TestQueryAbbreviations::testExpansionIsNotExpandedAgain()
+{
+    bs::QueryAbbreviations abbreviations;
+    QVERIFY(abbreviations.set(QStringLiteral("js"), QStringLiteral("js javascript"), nullptr));
+    QVERIFY(abbreviations.set(QStringLiteral("ts"), QStringLiteral("js"), nullptr));
+    QCOMPARE(abbreviations.expand(QStringLiteral("js ts")), QStringLiteral("js javascript js"));
+}
+
+void TestQueryAbbreviations::testRejectsInvalidTerms()
+{
+    bs::QueryAbbreviations abbreviations;
+    QString error;
+    QVERIFY(!abbreviations.set(QStringLiteral("two words"), QStringLiteral("x"), &error));
+    QVERIFY(!error.isEmpty());
+    QVERIFY(!abbreviations.set(QStringLiteral("kind:pdf"), QStringLiteral("x"), nullptr));
+    QVERIFY(!abbreviations.set(QString(33, QLatin1Char('a')), QStringLiteral("x"), nullptr));
+    QVERIFY(!abbreviations.set(QStringLiteral("inv"), QStringLiteral("   "), nullptr));
+    QVERIFY(!abbreviations.set(QStringLiteral("inv"), QString(201, QLatin1Char('x')), nullptr));
+    QVERIFY(abbreviations.set(QStringLiteral("c++"), QStringLiteral("cpp"), nullptr));
+    QVERIFY(abbreviations.remove(QStringLiteral("C++")));
+    QVERIFY(!abbreviations.remove(QStringLiteral("c++")));
+    QVERIFY(abbreviations.isEmpty());
+}
+
+void TestQueryAbbreviations::testEntryLimit()
+{
+    bs::QueryAbbreviations abbreviations;
+    for (int i = 0; i < bs::QueryAbbreviations::kMaxEntries; ++i) {
+        QVERIFY(abbreviations.set(QStringLiteral("t%1").arg(i), QStringLiteral("x"), nullptr));
+    }
+    QString error;
+    QVERIFY(!abbreviations.set(QStringLiteral("extra"), QStringLiteral("x"), &error));
+    QVERIFY(!error.isEmpty());
+    // Replacing an existing term still works when full.
+    QVERIFY(abbreviations.set(QStringLiteral("t0"), QStringLiteral("y"), nullptr));
+    QCOMPARE(abbreviations.size(), bs::QueryAbbreviations::kMaxEntries);
+}
+
+void TestQueryAbbreviations::testJsonRoundTripSkipsInvalid()
+{
+    QJsonObject json;
+    json[QStringLiteral("inv")] = QStringLiteral("invoice");
+    json[QStringLiteral("bad term")] = QStringLiteral("x");
+    json[QStringLiteral("num")] = 3;
+
+    int skipped = 0;
+    const bs::QueryAbbreviations abbreviations = bs::QueryAbbreviations::fromJson(json, &skipped);
+    QCOMPARE(skipped, 2);
+    QCOMPARE(abbreviations.size(), 1);
+
+    const QJsonObject out = abbreviations.toJson();
+    QCOMPARE(out.size(), 1);
+    QCOMPARE(out.value(QStringLiteral("inv")).toString(), QStringLiteral("invoice"));
+}
+
+QTEST_MAIN(TestQueryAbbreviations)
+#include "test_query_abbreviations.moc"
@@ -0,0 +1,177 @@
+#include <QtTest/QtTest>
+#include "core/feedback/query_history.h"
+
+#include <sqlite3.h>
+
+class TestQueryHistory : public QObject {
+    Q_OBJECT
+
+private slots:
+    void initTestCase();
+    void cleanupTestCase();
+    void cleanup();
+
+    void testRecordCountsHits();
+    void testBlankQueryIgnored();
+    void testSearchContainsAndPrefix();
+    void testSearchEscapesWildcards();
+    void testRecentAndFrequentOrder();
+    void testRemove();
+    void testPurgeBefore();
+    void testCleanupDropsExpired();
+
+private:
+    sqlite3* m_db = nullptr;
+};
+
+void TestQueryHistory::initTestCase()
+{
+    const int rc = sqlite3_open(":memory:", &m_db);
+    QCOMPARE(rc, SQLITE_OK);
+
+    const char* sql = R"(
+        CREATE TABLE IF NOT EXISTS query_history (
+            query_normalized TEXT PRIMARY KEY,
+            query TEXT NOT NULL,
+            hit_count INTEGER NOT NULL DEFAULT 1,
+            last_result_count INTEGER NOT NULL DEFAULT 0,
+            first_used_at TEXT NOT NULL,
+            last_used_at TEXT NOT NULL
+        );
+    )";
+    QCOMPARE(sqlite3_exec(m_db, sql, nullptr, nullptr, nullptr), SQLITE_OK);
+}
+
+void TestQueryHistory::cleanupTestCase()
+{
+    if (m_db) {
+        sqlite3_close(m_db);
+        m_db = nullptr;
+    }
+}
+
+void TestQueryHistory::cleanup()
+{
+    QCOMPARE(sqlite3_exec(m_db, "DELETE FROM query_history;", nullptr, nullptr, nullptr),
+             SQLITE_OK);
+}
+
+void TestQueryHistory::testRecordCountsHits()
+{
+    bs::QueryHistory history(m_db);
+    const QDateTime first = QDateTime::currentDateTimeUtc().addDays(-2);
+    QVERIFY(history.record(QStringLiteral("Quarterly  Report"), 4, first));
+    QVERIFY(history.record(QStringLiteral("quarterly report"), 7));
+
+    QCOMPARE(history.count(), int64_t(1));
+    const auto entries = history.search(QString(), 10, bs::QueryHistory::Order::Recent);
+    QCOMPARE(entries.size(), 1);
+    QCOMPARE(entries[0].query, QStringLiteral("quarterly report"));
+    QCOMPARE(entries[0].normalizedQuery, QStringLiteral("quarterly report"));
+    QCOMPARE(entries[0].hitCount, 2);
+    QCOMPARE(entries[0].lastResultCount, 7);
+    QVERIFY(entries[0].firstUsedAt < entries[0].lastUsedAt);
+}
+
+void TestQueryHistory::testBlankQueryIgnored()
+{
+    bs::QueryHistory history(m_db);
+    QVERIFY(!history.record(QStringLiteral("   "), 0));
+    QCOMPARE(history.count(), int64_t(0));
+}
+
+void TestQueryHistory::testSearchContainsAndPrefix()
+{
+    bs::QueryHistory history(m_db);
+    QVERIFY(history.record(QStringLiteral("budget 2026"), 3));
+    QVERIFY(history.record(QStringLiteral("travel budget"), 1));
+    QVERIFY(history.record(QStringLiteral("invoice"), 2));
+
+    const auto contains = history.search(QStringLiteral("Budget"), 10,
+                                         bs::QueryHistory::Order::Recent);
+    QCOMPARE(contains.size(), 2);
+
+    const auto prefix = history.search(QStringLiteral("budget"), 10,
+                                       bs::QueryHistory::Order::Recent, true);
+    QCOMPARE(prefix.size(), 1);
+    QCOMPARE(prefix[0].query, QStringLiteral("budget 2026"));
+}
+
+void TestQueryHistory::testSearchEscapesWildcards()
+{
+    bs::QueryHistory history(m_db);
+    QVERIFY(history.record(QStringLiteral("100% done"), 1));
+    QVERIFY(history.record(QStringLiteral("1000 done"), 1));
+    QVERIFY(history.record(QStringLiteral("file_name"), 1));
+    QVERIFY(history.record(QStringLiteral("filename"), 1));
+
+    const auto percent = history.search(QStringLiteral("100%"), 10,
+                                        bs::QueryHistory::Order::Recent);
+    QCOMPARE(percent.size(), 1);
+    QCOMPARE(percent[0].query, QStringLiteral("100% done"));
+
+    const auto underscore = history.search(QStringLiteral("file_"), 10,
+                                           bs::QueryHistory::Order::Recent);
+    QCOMPARE(underscore.size(), 1);
+    QCOMPARE(underscore[0].query, QStringLiteral("file_name"));
+}
+
+void TestQueryHistory::testRecentAndFrequentOrder()
+{
+    bs::QueryHistory history(m_db);
+    const QDateTime now = QDateTime::currentDateTimeUtc();
+    for (int i = 0; i < 5; ++i) {
+        QVERIFY(history.record(QStringLiteral("often"), 1, now.addSecs(-3600 - i)));
+    }
+    QVERIFY(history.record(QStringLiteral("latest"), 1, now));
+
+    const auto recent = history.search(QString(), 10, bs::QueryHistory::Order::Recent);
+    QCOMPARE(recent.size(), 2);
+    QCOMPARE(recent[0].query, QStringLiteral("latest"));
+
+    const auto frequent = history.search(QString(), 10, bs::QueryHistory::Order::Frequent);
+    QCOMPARE(frequent.size(), 2);
+    QCOMPARE(frequent[0].query, QStringLiteral("often"));
+
+    QCOMPARE(history.search(QString(), 1, bs::QueryHistory::Order::Recent).size(), 1);
+}
+
+void TestQueryHistory::testRemove()
+{
+    bs::QueryHistory history(m_db);
+    QVERIFY(history.record(QStringLiteral("keep me"), 1));
+    QVERIFY(history.record(QStringLiteral("forget me"), 1));
+
+    QCOMPARE(history.remove(QStringLiteral("Forget  Me")), int64_t(1));
+    QCOMPARE(history.remove(QStringLiteral("not there")), int64_t(0));
+    QCOMPARE(history.count(), int64_t(1));
+}
+
+void TestQueryHistory::testPurgeBefore()
+{
+    bs::QueryHistory history(m_db);
+    const QDateTime now = QDateTime::currentDateTimeUtc();
+    QVERIFY(history.record(QStringLiteral("old"), 1, now.addDays(-40)));
+    QVERIFY(history.record(QStringLiteral("new"), 1, now));
+
+    QCOMPARE(history.purge(now.addDays(-30)), int64_t(1));
+    QCOMPARE(history.count(), int64_t(1));
+    QCOMPARE(history.purge(), int64_t(1));
+    QCOMPARE(history.count(), int64_t(0));
+}
+
+void TestQueryHistory::testCleanupDropsExpired()
+{
+    bs::QueryHistory history(m_db);
+    const QDateTime now = QDateTime::currentDateTimeUtc();
+    QVERIFY(history.record(QStringLiteral("expired"), 1, now.addDays(-100)));
+    QVERIFY(history.record(QStringLiteral("recent"), 1, now.addDays(-10)));
+
+    QVERIFY(history.cleanup(90));
+    const auto entries = history.search(QString(), 10, bs::QueryHistory::Order::Recent);
+    QCOMPARE(entries.size(), 1);
+    QCOMPARE(entries[0].query, QStringLiteral("recent"));
+}
+
+QTEST_MAIN(TestQueryHistory)
+#include "test_query_history.moc"
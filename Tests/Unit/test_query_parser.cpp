@@ -0,0 +1,205 @@
+#include <QtTest/QtTest>
+
+#include "core/query/query_parser.h"
+
+#include <QTimeZone>
+
+namespace {
+
+// Wednesday; the week started on Monday 2026-10-12.
+const QDateTime kNow(QDate(2026, 10, 14), QTime(15, 30), QTimeZone::UTC);
+
+double midnight(int year, int month, int day)
+{
+    return static_cast<double>(
+        QDateTime(QDate(year, month, day), QTime(0, 0), QTimeZone::UTC).toSecsSinceEpoch());
+}
+
+double lastMillisecondBefore(int year, int month, int day)
+{
+    return midnight(year, month, day) - 0.001;
+}
+
+} // namespace
+
+class TestQueryParser : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testTrailingTypeHint();
+    void testModifiedToday();
+    void testCalendarPeriods();
+    void testQuotedPhraseAfterNormalization();
+    void testRollingSpans();
+    void testIsoDates();
+    void testCreatedAndIntersection();
+    void testUnreadableDate();
+    void testDueDates();
+    void testKinds();
+};
+
+void TestQueryParser::testTrailingTypeHint()
+{
+    const bs::ParsedQuery parsed = bs::QueryParser::parse(QStringLiteral("report pdf"), kNow);
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("report"));
+    QVERIFY(parsed.hasTypeHint);
+    QVERIFY(!parsed.hasDateFilter);
+}
+
+void TestQueryParser::testModifiedToday()
+{
+    const bs::ParsedQuery parsed =
+        bs::QueryParser::parse(QStringLiteral("budget modified:today"), kNow);
+    QVERIFY(parsed.hasDateFilter);
+    QVERIFY(parsed.dateError.isEmpty());
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("budget"));
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 10, 14));
+    QVERIFY(!parsed.filters.modifiedBefore.has_value());
+}
+
+void TestQueryParser::testCalendarPeriods()
+{
+    bs::ParsedQuery parsed = bs::QueryParser::parse(QStringLiteral("modified:yesterday"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 10, 13));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2026, 10, 14));
+    QVERIFY(parsed.cleanedQuery.isEmpty());
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:this week"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 10, 12));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:last month"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 9, 1));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2026, 10, 1));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:this year"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 1, 1));
+}
+
+void TestQueryParser::testQuotedPhraseAfterNormalization()
+{
+    // The normalizer drops the quotes of `modified:"last week" notes`.
+    const bs::ParsedQuery parsed =
+        bs::QueryParser::parse(QStringLiteral("modified:last week notes"), kNow);
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("notes"));
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 10, 5));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2026, 10, 12));
+
+    // Quotes that survive are ignored too.
+    const bs::ParsedQuery quoted =
+        bs::QueryParser::parse(QStringLiteral("modified:\"last week\" notes"), kNow);
+    QCOMPARE(quoted.filters.modifiedAfter, parsed.filters.modifiedAfter);
+    QCOMPARE(quoted.cleanedQuery, QStringLiteral("notes"));
+}
+
+void TestQueryParser::testRollingSpans()
+{
+    bs::ParsedQuery parsed = bs::QueryParser::parse(QStringLiteral("modified:past 3 days"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(),
+             static_cast<double>(kNow.addDays(-3).toSecsSinceEpoch()));
+    QVERIFY(!parsed.filters.modifiedBefore.has_value());
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:last 2 weeks"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(),
+             static_cast<double>(kNow.addDays(-14).toSecsSinceEpoch()));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:past week"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(),
+             static_cast<double>(kNow.addDays(-7).toSecsSinceEpoch()));
+}
+
+void TestQueryParser::testIsoDates()
+{
+    bs::ParsedQuery parsed = bs::QueryParser::parse(QStringLiteral("modified:2025-03-14"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2025, 3, 14));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2025, 3, 15));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:2025"), kNow);
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2025, 1, 1));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2026, 1, 1));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("modified:2025-13"), kNow);
+    QVERIFY(!parsed.dateError.isEmpty());
+}
+
+void TestQueryParser::testCreatedAndIntersection()
+{
+    const bs::ParsedQuery parsed = bs::QueryParser::parse(
+        QStringLiteral("created:this month modified:2026 modified:last week invoice pdf"), kNow);
+    QCOMPARE(parsed.filters.createdAfter.value(), midnight(2026, 10, 1));
+    QVERIFY(!parsed.filters.createdBefore.has_value());
+    QCOMPARE(parsed.filters.modifiedAfter.value(), midnight(2026, 10, 5));
+    QCOMPARE(parsed.filters.modifiedBefore.value(), lastMillisecondBefore(2026, 10, 12));
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("invoice"));
+    QVERIFY(parsed.hasTypeHint);
+}
+
+void TestQueryParser::testUnreadableDate()
+{
+    const bs::ParsedQuery parsed =
+        bs::QueryParser::parse(QStringLiteral("modified:someday report"), kNow);
+    QVERIFY(parsed.hasDateFilter);
+    QVERIFY(parsed.dateError.contains(QStringLiteral("someday")));
+
+    // A bare prefix is left as text.
+    const bs::ParsedQuery bare = bs::QueryParser::parse(QStringLiteral("modified: report"), kNow);
+    QVERIFY(!bare.hasDateFilter);
+    QCOMPARE(bare.cleanedQuery, QStringLiteral("modified: report"));
+}
+
+void TestQueryParser::testDueDates()
+{
+    // Unlike modified:, due:today ends with the day.
+    bs::ParsedQuery parsed = bs::QueryParser::parse(QStringLiteral("due:today"), kNow);
+    QVERIFY(parsed.hasDateFilter);
+    QCOMPARE(parsed.filters.dueAfter.value(), midnight(2026, 10, 14));
+    QCOMPARE(parsed.filters.dueBefore.value(), lastMillisecondBefore(2026, 10, 15));
+    QVERIFY(!parsed.filters.modifiedAfter.has_value());
+    QVERIFY(parsed.filters.hasFilters());
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:tomorrow milk"), kNow);
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("milk"));
+    QCOMPARE(parsed.filters.dueAfter.value(), midnight(2026, 10, 15));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:next week"), kNow);
+    QCOMPARE(parsed.filters.dueAfter.value(), midnight(2026, 10, 19));
+    QCOMPARE(parsed.filters.dueBefore.value(), lastMillisecondBefore(2026, 10, 26));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:this week"), kNow);
+    QCOMPARE(parsed.filters.dueBefore.value(), lastMillisecondBefore(2026, 10, 19));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:next 3 days"), kNow);
+    QCOMPARE(parsed.filters.dueAfter.value(), static_cast<double>(kNow.toSecsSinceEpoch()));
+    QCOMPARE(parsed.filters.dueBefore.value(),
+             static_cast<double>(kNow.addDays(3).toSecsSinceEpoch()));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:overdue"), kNow);
+    QVERIFY(!parsed.filters.dueAfter.has_value());
+    QVERIFY(parsed.filters.dueBefore.value() < static_cast<double>(kNow.toSecsSinceEpoch()));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:2026-11-02"), kNow);
+    QCOMPARE(parsed.filters.dueAfter.value(), midnight(2026, 11, 2));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("due:someday"), kNow);
+    QVERIFY(parsed.dateError.contains(QStringLiteral("due:")));
+}
+
+void TestQueryParser::testKinds()
+{
+    bs::ParsedQuery parsed =
+        bs::QueryParser::parse(QStringLiteral("due:today kind:reminder"), kNow);
+    QVERIFY(parsed.hasKindFilter);
+    QCOMPARE(parsed.kinds, QStringList{QStringLiteral("reminder")});
+    QVERIFY(parsed.cleanedQuery.isEmpty());
+    QCOMPARE(parsed.filters.dueAfter.value(), midnight(2026, 10, 14));
+
+    parsed = bs::QueryParser::parse(QStringLiteral("kind:Mail invoice kind:note kind:mail"), kNow);
+    QCOMPARE(parsed.kinds, (QStringList{QStringLiteral("mail"), QStringLiteral("note")}));
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("invoice"));
+
+    const bs::ParsedQuery bare = bs::QueryParser::parse(QStringLiteral("kind: report"), kNow);
+    QVERIFY(!bare.hasKindFilter);
+    QCOMPARE(bare.cleanedQuery, QStringLiteral("kind: report"));
+}
+
+QTEST_MAIN(TestQueryParser)
+#include "test_query_parser.moc"
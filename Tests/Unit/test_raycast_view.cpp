@@ -0,0 +1,153 @@
+#include <QtTest/QtTest>
+
+#include "core/query/raycast_view.h"
+
+#include <QFile>
+#include <QJsonDocument>
+
+namespace {
+
+QJsonObject makeHit(qint64 itemId, const QString& path, const QString& kind)
+{
+    QJsonObject metadata;
+    metadata[QStringLiteral("modificationDate")] = QStringLiteral("2026-03-04T05:06:00Z");
+
+    QJsonObject hit;
+    hit[QStringLiteral("itemId")] = itemId;
+    hit[QStringLiteral("path")] = path;
+    hit[QStringLiteral("name")] = path.section(QLatin1Char('/'), -1);
+    hit[QStringLiteral("kind")] = kind;
+    hit[QStringLiteral("metadata")] = metadata;
+    return hit;
+}
+
+QStringList sectionIds(const QJsonArray& sections)
+{
+    QStringList ids;
+    for (const QJsonValue& value : sections) {
+        ids.append(value.toObject().value(QStringLiteral("id")).toString());
+    }
+    return ids;
+}
+
+} // namespace
+
+class TestRaycastView : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testSectionsGroupByKindInRankOrder();
+    void testItemFieldsAndActions();
+    void testSectionIdsMatchSchema();
+};
+
+void TestRaycastView::testSectionsGroupByKindInRankOrder()
+{
+    QVERIFY(bs::RaycastView::sections(QJsonArray(), QStringLiteral("q"), QString()).isEmpty());
+
+    QJsonArray results;
+    results.append(makeHit(1, QStringLiteral("/Users/test/budget.pdf"), QStringLiteral("pdf")));
+    results.append(makeHit(2, QStringLiteral("/Users/test/budget.md"), QStringLiteral("markdown")));
+    results.append(makeHit(3, QStringLiteral("/Users/test/old-budget.pdf"), QStringLiteral("pdf")));
+    results.append(makeHit(4, QStringLiteral("/Users/test/budget.bin"), QStringLiteral("binary")));
+    results.append(makeHit(5, QStringLiteral("/Users/test/budget.dat"), QStringLiteral("unknown")));
+
+    const QJsonArray sections =
+        bs::RaycastView::sections(results, QStringLiteral("budget"), QStringLiteral("/Users/test"));
+    QCOMPARE(sectionIds(sections),
+             QStringList({QStringLiteral("topHit"), QStringLiteral("markdown"),
+                          QStringLiteral("pdf"), QStringLiteral("other")}));
+
+    const QJsonObject topHit = sections.at(0).toObject();
+    QCOMPARE(topHit.value(QStringLiteral("title")).toString(), QStringLiteral("Top Hit"));
+    QCOMPARE(topHit.value(QStringLiteral("items")).toArray().size(), 1);
+    QCOMPARE(topHit.value(QStringLiteral("items")).toArray().at(0).toObject()
+                 .value(QStringLiteral("itemId")).toInteger(),
+             1);
+
+    const QJsonObject pdf = sections.at(2).toObject();
+    QCOMPARE(pdf.value(QStringLiteral("title")).toString(), QStringLiteral("PDF Documents"));
+    QCOMPARE(pdf.value(QStringLiteral("subtitle")).toString(), QStringLiteral("1"));
+    QCOMPARE(pdf.value(QStringLiteral("items")).toArray().at(0).toObject()
+                 .value(QStringLiteral("itemId")).toInteger(),
+             3);
+    QCOMPARE(sections.at(3).toObject().value(QStringLiteral("items")).toArray().size(), 2);
+}
+
+void TestRaycastView::testItemFieldsAndActions()
+{
+    QJsonObject hit = makeHit(42, QStringLiteral("/Users/test/Docs/report.pdf"),
+                              QStringLiteral("pdf"));
+    hit[QStringLiteral("tags")] = QJsonArray{QStringLiteral("Work")};
+    hit[QStringLiteral("snippet")] = QStringLiteral("quarterly\n  numbers");
+
+    const QJsonObject item = bs::RaycastView::item(hit, QStringLiteral("report"), 3,
+                                                   QStringLiteral("/Users/test"));
+    QCOMPARE(item.value(QStringLiteral("id")).toString(), QStringLiteral("42"));
+    QCOMPARE(item.value(QStringLiteral("title")).toString(), QStringLiteral("report.pdf"));
+    QCOMPARE(item.value(QStringLiteral("subtitle")).toString(), QStringLiteral("~/Docs"));
+    QCOMPARE(item.value(QStringLiteral("icon")).toObject().value(QStringLiteral("fileIcon"))
+                 .toString(),
+             QStringLiteral("/Users/test/Docs/report.pdf"));
+    QCOMPARE(item.value(QStringLiteral("thumbnail")).toString(),
+             QStringLiteral("/v1/documents/42/thumbnail"));
+    QCOMPARE(item.value(QStringLiteral("snippet")).toString(),
+             QStringLiteral("quarterly numbers"));
+
+    const QJsonArray accessories = item.value(QStringLiteral("accessories")).toArray();
+    QCOMPARE(accessories.size(), 2);
+    QCOMPARE(accessories.at(0).toObject().value(QStringLiteral("tag")).toString(),
+             QStringLiteral("Work"));
+    QCOMPARE(accessories.at(1).toObject().value(QStringLiteral("date")).toString(),
+             QStringLiteral("2026-03-04T05:06:00Z"));
+
+    const QJsonArray actions = item.value(QStringLiteral("actions")).toArray();
+    QCOMPARE(actions.size(), 4);
+    QStringList ids;
+    for (const QJsonValue& value : actions) {
+        ids.append(value.toObject().value(QStringLiteral("id")).toString());
+    }
+    QCOMPARE(ids, QStringList({QStringLiteral("open"), QStringLiteral("reveal"),
+                               QStringLiteral("quicklook"), QStringLiteral("copyPath")}));
+    QVERIFY(!actions.at(0).toObject().contains(QStringLiteral("shortcut")));
+
+    const QJsonObject request =
+        actions.at(1).toObject().value(QStringLiteral("request")).toObject();
+    QCOMPARE(request.value(QStringLiteral("method")).toString(), QStringLiteral("POST"));
+    QCOMPARE(request.value(QStringLiteral("path")).toString(), QStringLiteral("/v1/actions"));
+    const QJsonObject body = request.value(QStringLiteral("body")).toObject();
+    QCOMPARE(body.value(QStringLiteral("action")).toString(), QStringLiteral("reveal"));
+    QCOMPARE(body.value(QStringLiteral("itemId")).toInteger(), 42);
+    QCOMPARE(body.value(QStringLiteral("query")).toString(), QStringLiteral("report"));
+    QCOMPARE(body.value(QStringLiteral("position")).toInt(), 3);
+}
+
+void TestRaycastView::testSectionIdsMatchSchema()
+{
+    QFile file(QStringLiteral(BETTERSPOTLIGHT_SOURCE_DIR)
+               + QStringLiteral("/docs/foundation/schemas/raycast-search.schema.json"));
+    QVERIFY(file.open(QIODevice::ReadOnly));
+    const QJsonObject schema = QJsonDocument::fromJson(file.readAll()).object();
+    const QJsonArray ids = schema.value(QStringLiteral("$defs")).toObject()
+                               .value(QStringLiteral("section")).toObject()
+                               .value(QStringLiteral("properties")).toObject()
+                               .value(QStringLiteral("id")).toObject()
+                               .value(QStringLiteral("enum")).toArray();
+    QVERIFY(!ids.isEmpty());
+
+    QStringList schemaIds;
+    for (const QJsonValue& id : ids) {
+        schemaIds.append(id.toString());
+    }
+    for (const QString& kind : {QStringLiteral("directory"), QStringLiteral("text"),
+                                QStringLiteral("code"), QStringLiteral("markdown"),
+                                QStringLiteral("pdf"), QStringLiteral("image"),
+                                QStringLiteral("archive"), QStringLiteral("binary"),
+                                QStringLiteral("unknown")}) {
+        QVERIFY2(schemaIds.contains(bs::RaycastView::sectionId(kind)), qPrintable(kind));
+    }
+    QVERIFY(schemaIds.contains(QStringLiteral("topHit")));
+}
+
+QTEST_MAIN(TestRaycastView)
+#include "test_raycast_view.moc"
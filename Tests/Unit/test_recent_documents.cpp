@@ -0,0 +1,87 @@
+#include <QtTest/QtTest>
+#include "core/fs/recent_documents.h"
+
+class TestRecentDocuments : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testEntryScoreDecaysWithAge();
+    void testEntryScoreFallsOffWithRank();
+    void testBestScoreAcrossLists();
+    void testUnknownPathScoresZero();
+    void testUpdateReplacesEntries();
+};
+
+namespace {
+
+constexpr qint64 kNow = 1760000000;
+
+bs::RecentDocuments::Entry makeEntry(const QString& path, int rank, qint64 ageSecs,
+                                     const QString& app = QStringLiteral("com.apple.iWork.Pages"))
+{
+    bs::RecentDocuments::Entry entry;
+    entry.path = path;
+    entry.appBundleId = app;
+    entry.rank = rank;
+    entry.listModifiedAt = kNow - ageSecs;
+    return entry;
+}
+
+} // namespace
+
+void TestRecentDocuments::testEntryScoreDecaysWithAge()
+{
+    const QString path = QStringLiteral("/Users/me/Documents/plan.pages");
+    QCOMPARE(bs::RecentDocuments::entryScore(makeEntry(path, 0, 0), kNow), 1.0);
+
+    const double halfLife = bs::RecentDocuments::entryScore(
+        makeEntry(path, 0, static_cast<qint64>(bs::RecentDocuments::kHalfLifeDays * 86400)), kNow);
+    QVERIFY(qAbs(halfLife - 0.5) < 1e-9);
+
+    // A list changed "in the future" (clock skew) counts as just now.
+    QCOMPARE(bs::RecentDocuments::entryScore(makeEntry(path, 0, -3600), kNow), 1.0);
+}
+
+void TestRecentDocuments::testEntryScoreFallsOffWithRank()
+{
+    const QString path = QStringLiteral("/Users/me/Documents/plan.pages");
+    const double top = bs::RecentDocuments::entryScore(makeEntry(path, 0, 0), kNow);
+    const double fourth = bs::RecentDocuments::entryScore(makeEntry(path, 4, 0), kNow);
+    QVERIFY(qAbs(fourth - 0.5) < 1e-9);
+    QVERIFY(top > fourth);
+    QCOMPARE(bs::RecentDocuments::entryScore(makeEntry(QString(), 0, 0), kNow), 0.0);
+}
+
+void TestRecentDocuments::testBestScoreAcrossLists()
+{
+    const QString path = QStringLiteral("/Users/me/Code/main.cpp");
+    bs::RecentDocuments recent;
+    recent.update({makeEntry(path, 6, 86400, QStringLiteral("com.apple.dt.Xcode")),
+                   makeEntry(path, 0, 0, QString())},
+                  kNow);
+    QCOMPARE(recent.size(), 1);
+    QCOMPARE(recent.score(path), 1.0);
+    // Paths are compared cleaned.
+    QCOMPARE(recent.score(QStringLiteral("/Users/me/Code/./main.cpp")), 1.0);
+}
+
+void TestRecentDocuments::testUnknownPathScoresZero()
+{
+    bs::RecentDocuments recent;
+    QCOMPARE(recent.score(QStringLiteral("/tmp/anything")), 0.0);
+    recent.update({makeEntry(QStringLiteral("/tmp/a.txt"), 0, 0)}, kNow);
+    QCOMPARE(recent.score(QStringLiteral("/tmp/b.txt")), 0.0);
+}
+
+void TestRecentDocuments::testUpdateReplacesEntries()
+{
+    bs::RecentDocuments recent;
+    recent.update({makeEntry(QStringLiteral("/tmp/a.txt"), 0, 0)}, kNow);
+    recent.update({makeEntry(QStringLiteral("/tmp/b.txt"), 0, 0)}, kNow);
+    QCOMPARE(recent.score(QStringLiteral("/tmp/a.txt")), 0.0);
+    QVERIFY(recent.score(QStringLiteral("/tmp/b.txt")) > 0.0);
+    QCOMPARE(recent.size(), 1);
+}
+
+QTEST_MAIN(TestRecentDocuments)
+#include "test_recent_documents.moc"
@@ -0,0 +1,173 @@
+#include <QtTest/QtTest>
+
+#include "relevance_eval.h"
+
+#include <QJsonArray>
+
+#include <cmath>
+
+namespace {
+
+const QHash<QString, int> kGrades = {
+    {QStringLiteral("answer.md"), 3},
+    {QStringLiteral("related.md"), 1},
+};
+
+} // namespace
+
+class TestRelevanceMetrics : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseJudgments();
+    void testParseJudgmentsRejectsInvalid();
+    void testNdcg();
+    void testReciprocalRank();
+    void testAggregateAndReport();
+};
+
+void TestRelevanceMetrics::testParseJudgments()
+{
+    QString error;
+    const auto set = bs::test::RelevanceEval::parseJudgments(QByteArrayLiteral(R"({
+        "version": 1, "fixtureId": "standard_home_v1", "cutoff": 5,
+        "configurations": [
+            {"name": "default"},
+            {"name": "no_reranker", "settings": {"rerankerCascadeEnabled": false,
+                                                 "bm25WeightName": 20, "mode": "x"}}
+        ],
+        "judgments": [
+            {"id": "J-1", "query": "budget", "relevant": {"budget-2026.xlsx": 3, "notes.md": 0}}
+        ]})"), &error);
+    QVERIFY2(set.has_value(), qPrintable(error));
+    QCOMPARE(set->cutoff, 5);
+    QCOMPARE(set->configs.size(), size_t(2));
+    QVERIFY(set->configs[0].settings.isEmpty());
+    QCOMPARE(set->configs[1].settings.value(QStringLiteral("rerankerCascadeEnabled")),
+             QStringLiteral("0"));
+    QCOMPARE(set->configs[1].settings.value(QStringLiteral("bm25WeightName")), QStringLiteral("20"));
+    QCOMPARE(set->configs[1].settings.value(QStringLiteral("mode")), QStringLiteral("x"));
+    QCOMPARE(set->judgments.size(), size_t(1));
+    QCOMPARE(set->judgments[0].mode, QStringLiteral("auto"));
+    QCOMPARE(set->judgments[0].grades.value(QStringLiteral("budget-2026.xlsx")), 3);
+
+    // No configurations compares the default alone.
+    const auto bare = bs::test::RelevanceEval::parseJudgments(QByteArrayLiteral(R"({
+        "fixtureId": "f", "judgments": [{"id": "J-1", "query": "q", "relevant": {"a": 1}}]})"),
+        &error);
+    QVERIFY2(bare.has_value(), qPrintable(error));
+    QCOMPARE(bare->configs.size(), size_t(1));
+    QCOMPARE(bare->configs[0].name, QStringLiteral("default"));
+    QCOMPARE(bare->cutoff, 10);
+}
+
+void TestRelevanceMetrics::testParseJudgmentsRejectsInvalid()
+{
+    const std::vector<QByteArray> invalid = {
+        QByteArrayLiteral("[]"),
+        QByteArrayLiteral(R"({"version": 2, "fixtureId": "f",
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 1}}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "cutoff": 0,
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 1}}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "judgments": []})"),
+        QByteArrayLiteral(R"({"fixtureId": "f",
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 0}}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f",
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 4}}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f",
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 1}},
+                          {"id": "J", "query": "r", "relevant": {"b": 1}}]})"),
+        QByteArrayLiteral(R"({"fixtureId": "f", "configurations": [{"name": "a"}, {"name": "a"}],
+            "judgments": [{"id": "J", "query": "q", "relevant": {"a": 1}}]})"),
+    };
+    for (const QByteArray& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::test::RelevanceEval::parseJudgments(json, &error).has_value(),
+                 json.constData());
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestRelevanceMetrics::testNdcg()
+{
+    using bs::test::RelevanceEval;
+    QCOMPARE(RelevanceEval::ndcgAt({QStringLiteral("answer.md"), QStringLiteral("related.md")},
+                                   kGrades, 10),
+             1.0);
+    QCOMPARE(RelevanceEval::ndcgAt({QStringLiteral("other.md")}, kGrades, 10), 0.0);
+
+    // Swapped: DCG = 1 + 7/log2(3), IDCG = 7 + 1/log2(3).
+    const double swapped = RelevanceEval::ndcgAt(
+        {QStringLiteral("related.md"), QStringLiteral("answer.md")}, kGrades, 10);
+    const double expected = (1.0 + 7.0 / std::log2(3.0)) / (7.0 + 1.0 / std::log2(3.0));
+    QVERIFY(std::abs(swapped - expected) < 1e-9);
+
+    // Past the cutoff counts for nothing; case folds; a repeat counts once.
+    QCOMPARE(RelevanceEval::ndcgAt({QStringLiteral("other.md"), QStringLiteral("answer.md")},
+                                   kGrades, 1),
+             0.0);
+    QCOMPARE(RelevanceEval::ndcgAt({QStringLiteral("Answer.MD"), QStringLiteral("related.md")},
+                                   kGrades, 10),
+             1.0);
+    QCOMPARE(RelevanceEval::ndcgAt({QStringLiteral("answer.md"), QStringLiteral("answer.md"),
+                                    QStringLiteral("related.md")},
+                                   kGrades, 2),
+             1.0);
+}
+
+void TestRelevanceMetrics::testReciprocalRank()
+{
+    using bs::test::RelevanceEval;
+    QCOMPARE(RelevanceEval::reciprocalRank({QStringLiteral("answer.md")}, kGrades, 10), 1.0);
+    QCOMPARE(RelevanceEval::reciprocalRank(
+                 {QStringLiteral("x"), QStringLiteral("y"), QStringLiteral("related.md")}, kGrades,
+                 10),
+             1.0 / 3.0);
+    QCOMPARE(RelevanceEval::reciprocalRank({QStringLiteral("x"), QStringLiteral("answer.md")},
+                                           kGrades, 1),
+             0.0);
+    QCOMPARE(RelevanceEval::reciprocalRank({}, kGrades, 10), 0.0);
+}
+
+void TestRelevanceMetrics::testAggregateAndReport()
+{
+    using bs::test::RelevanceEval;
+    bs::test::RelevanceJudgment first;
+    first.id = QStringLiteral("J-1");
+    first.grades = kGrades;
+    bs::test::RelevanceJudgment second;
+    second.id = QStringLiteral("J-2");
+    second.grades = {{QStringLiteral("b.md"), 2}};
+
+    const auto baseline = RelevanceEval::aggregate(
+        QStringLiteral("default"),
+        {RelevanceEval::score(first, {QStringLiteral("answer.md")}, 10),
+         RelevanceEval::score(second, {QStringLiteral("b.md")}, 10)});
+    QCOMPARE(baseline.mrr, 1.0);
+    const auto worse = RelevanceEval::aggregate(
+        QStringLiteral("no_reranker"),
+        {RelevanceEval::score(first, {QStringLiteral("answer.md")}, 10),
+         RelevanceEval::score(second, {QStringLiteral("a.md"), QStringLiteral("b.md")}, 10)});
+    QCOMPARE(worse.mrr, 0.75);
+    QVERIFY(worse.ndcg < baseline.ndcg);
+
+    const QString report = RelevanceEval::formatReport({baseline, worse}, 10);
+    QVERIFY2(report.startsWith(QStringLiteral("NDCG@10 and MRR over 2 judged queries\n")),
+             qPrintable(report));
+    QVERIFY2(report.contains(QStringLiteral("no_reranker    0.8155   -0.1845    0.7500   -0.2500")),
+             qPrintable(report));
+    QVERIFY2(report.contains(QStringLiteral("no_reranker: 1 of 2 queries moved from default")),
+             qPrintable(report));
+    QVERIFY2(report.contains(QStringLiteral("  J-2  1.0000 -> 0.6309  (-0.3691)")),
+             qPrintable(report));
+
+    const QJsonObject json = RelevanceEval::reportToJson({baseline, worse}, 10);
+    QCOMPARE(json.value(QStringLiteral("baseline")).toString(), QStringLiteral("default"));
+    const QJsonArray configs = json.value(QStringLiteral("configurations")).toArray();
+    QCOMPARE(configs.size(), qsizetype(2));
+    QVERIFY(!configs.at(0).toObject().contains(QStringLiteral("ndcgDelta")));
+    QCOMPARE(configs.at(1).toObject().value(QStringLiteral("mrrDelta")).toDouble(), -0.25);
+}
+
+QTEST_MAIN(TestRelevanceMetrics)
+#include "test_relevance_metrics.moc"
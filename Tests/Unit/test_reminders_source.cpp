@@ -0,0 +1,118 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/reminders_source.h"
+
+#include <QDateTime>
+
+namespace {
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+bs::Reminder groceries()
+{
+    bs::Reminder reminder;
+    reminder.identifier = QStringLiteral("REMINDER-1");
+    reminder.title = QStringLiteral("Buy milk");
+    reminder.notes = QStringLiteral("Oat, not almond");
+    reminder.listName = QStringLiteral("Groceries");
+    reminder.dueDate = static_cast<double>(
+        QDateTime(QDate(2026, 10, 14), QTime(17, 0)).toSecsSinceEpoch());
+    reminder.createdAt = 1790000000.0;
+    reminder.modifiedAt = 1790003600.0;
+    reminder.priority = 1;
+    return reminder;
+}
+
+} // namespace
+
+class TestRemindersSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testItems();
+    void testCompletedAndUndated();
+    void testStaleIdentifiers();
+    void testReminderUrl();
+};
+
+void TestRemindersSource::testItems()
+{
+    const bs::Reminder reminder = groceries();
+    bs::Reminder untitled;
+    untitled.identifier = QStringLiteral("REMINDER-2");
+
+    const auto items = bs::RemindersSource::items({reminder, untitled, reminder});
+    QCOMPARE(items.size(), size_t(1));
+    const bs::DonatedItem& item = items.front();
+    QCOMPARE(item.path, QStringLiteral("donated://com.apple.reminders/REMINDER-1"));
+    QCOMPARE(item.name, QStringLiteral("Buy milk"));
+    QVERIFY(item.textContent.contains(QStringLiteral("Oat, not almond")));
+    QCOMPARE(item.createdAt, 1790000000.0);
+    QCOMPARE(item.modifiedAt, 1790003600.0);
+    QCOMPARE(attributeValue(item, "list"), QStringLiteral("Groceries"));
+    QCOMPARE(attributeValue(item, "dueDate"),
+             QString::number(static_cast<qint64>(reminder.dueDate)));
+    QCOMPARE(attributeValue(item, "completed"), QStringLiteral("0"));
+    QCOMPARE(attributeValue(item, "priority"), QStringLiteral("1"));
+    QCOMPARE(attributeValue(item, "contentDescription"),
+             QStringLiteral("Due 2026-10-14 17:00 · Groceries"));
+    QVERIFY(!item.contentHash.isEmpty());
+
+    // Ticking it off changes the item.
+    bs::Reminder done = reminder;
+    done.completed = true;
+    QVERIFY(bs::RemindersSource::items({done}).front().contentHash != item.contentHash);
+}
+
+void TestRemindersSource::testCompletedAndUndated()
+{
+    bs::Reminder reminder = groceries();
+    reminder.allDay = true;
+    QCOMPARE(attributeValue(bs::RemindersSource::items({reminder}).front(), "contentDescription"),
+             QStringLiteral("Due 2026-10-14 · Groceries"));
+
+    reminder.completed = true;
+    const bs::DonatedItem completed = bs::RemindersSource::items({reminder}).front();
+    QCOMPARE(attributeValue(completed, "contentDescription"),
+             QStringLiteral("Completed · Groceries"));
+    QCOMPARE(attributeValue(completed, "completed"), QStringLiteral("1"));
+
+    // No due date: no dueDate attribute for due: to match.
+    reminder = groceries();
+    reminder.dueDate = 0.0;
+    reminder.priority = 0;
+    const bs::DonatedItem undated = bs::RemindersSource::items({reminder}).front();
+    QVERIFY(attributeValue(undated, "dueDate").isEmpty());
+    QVERIFY(attributeValue(undated, "priority").isEmpty());
+    QCOMPARE(attributeValue(undated, "contentDescription"), QStringLiteral("Groceries"));
+}
+
+void TestRemindersSource::testStaleIdentifiers()
+{
+    const QStringList stale = bs::RemindersSource::staleIdentifiers(
+        {QStringLiteral("REMINDER-1"), QStringLiteral("REMINDER-9")}, {groceries()});
+    QCOMPARE(stale, QStringList{QStringLiteral("REMINDER-9")});
+}
+
+void TestRemindersSource::testReminderUrl()
+{
+    bs::Reminder reminder = groceries();
+    QCOMPARE(bs::RemindersSource::reminderUrl(reminder),
+             QStringLiteral("x-apple-reminderkit://REMCDReminder/REMINDER-1"));
+    reminder.identifier = QStringLiteral("A B/C");
+    QCOMPARE(bs::RemindersSource::reminderUrl(reminder),
+             QStringLiteral("x-apple-reminderkit://REMCDReminder/A%20B%2FC"));
+    QCOMPARE(bs::RemindersSource::accessToString(bs::RemindersSource::Access::NotDetermined),
+             QStringLiteral("notDetermined"));
+}
+
+QTEST_MAIN(TestRemindersSource)
+#include "test_reminders_source.moc"
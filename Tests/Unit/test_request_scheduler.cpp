@@ -0,0 +1,236 @@
+#include <QtTest/QtTest>
+
+#include "core/ipc/message.h"
+#include "core/ipc/request_scheduler.h"
+#include "core/ipc/socket_server.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QElapsedTimer>
+#include <QLocalSocket>
+#include <QRandomGenerator>
+
+#include <algorithm>
+#include <optional>
+
+namespace {
+
+QString makeShortSocketPath(const QString& tag)
+{
+    const QString token = QString::number(QRandomGenerator::global()->generate(), 16);
+    return QDir(QDir::tempPath()).filePath(
+        QStringLiteral("bs-%1-%2.sock").arg(tag.left(6), token.left(8)));
+}
+
+QJsonObject makeRequest(uint64_t id, qint64 deadlineMs = 0)
+{
+    QJsonObject params;
+    if (deadlineMs > 0) {
+        params[QStringLiteral("deadlineMs")] = deadlineMs;
+    }
+    return bs::IpcMessage::makeRequest(id, QStringLiteral("search"), params);
+}
+
+uint64_t idOf(const QJsonObject& message)
+{
+    return static_cast<uint64_t>(message.value(QStringLiteral("id")).toInteger());
+}
+
+// Pump the event loop until one full message can be decoded from `socket`.
+std::optional<QJsonObject> readMessage(QLocalSocket& socket, QByteArray& buffer)
+{
+    QElapsedTimer timer;
+    timer.start();
+    while (timer.elapsed() < 3000) {
+        if (auto decoded = bs::IpcMessage::decode(buffer)) {
+            buffer.remove(0, decoded->bytesConsumed);
+            return decoded->json;
+        }
+        QCoreApplication::processEvents(QEventLoop::AllEvents, 10);
+        if (socket.bytesAvailable() > 0 || socket.waitForReadyRead(10)) {
+            buffer.append(socket.readAll());
+        }
+    }
+    return std::nullopt;
+}
+
+} // namespace
+
+class TestRequestScheduler : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRoundRobinAcrossClients();
+    void testPerClientCap();
+    void testExpiredDeadline();
+    void testRemoveClient();
+    void testServerInterleavesClients();
+    void testServerRejectsExpiredRequest();
+};
+
+void TestRequestScheduler::testRoundRobinAcrossClients()
+{
+    bs::RequestScheduler scheduler;
+    int a = 0;
+    int b = 0;
+    for (uint64_t id = 1; id <= 3; ++id) {
+        QVERIFY(scheduler.enqueue(&a, makeRequest(id), 0));
+    }
+    QVERIFY(scheduler.enqueue(&b, makeRequest(100), 0));
+
+    // a1, b100, a2, a3: b is not starved behind a's burst, a keeps its order.
+    const std::vector<std::pair<const void*, uint64_t>> expected = {
+        {&a, 1}, {&b, 100}, {&a, 2}, {&a, 3}};
+    for (const auto& [client, id] : expected) {
+        auto pending = scheduler.next(0);
+        QVERIFY(pending.has_value());
+        QCOMPARE(pending->client, client);
+        QCOMPARE(idOf(pending->message), id);
+        QVERIFY(!pending->expired);
+    }
+    QVERIFY(!scheduler.next(0).has_value());
+    QVERIFY(scheduler.isEmpty());
+    QCOMPARE(scheduler.stats().dispatched, static_cast<uint64_t>(4));
+}
+
+void TestRequestScheduler::testPerClientCap()
+{
+    bs::RequestScheduler scheduler;
+    int a = 0;
+    int b = 0;
+    for (size_t i = 0; i < bs::RequestScheduler::kMaxPendingPerClient; ++i) {
+        QVERIFY(scheduler.enqueue(&a, makeRequest(i + 1), 0));
+    }
+    QVERIFY(!scheduler.enqueue(&a, makeRequest(999), 0));
+    QVERIFY(scheduler.enqueue(&b, makeRequest(1000), 0));
+
+    const bs::RequestSchedulerStats stats = scheduler.stats();
+    QCOMPARE(stats.rejected, static_cast<uint64_t>(1));
+    QCOMPARE(stats.pending, bs::RequestScheduler::kMaxPendingPerClient + 1);
+    QCOMPARE(stats.clients, static_cast<size_t>(2));
+}
+
+void TestRequestScheduler::testExpiredDeadline()
+{
+    bs::RequestScheduler scheduler;
+    int a = 0;
+    QVERIFY(scheduler.enqueue(&a, makeRequest(1, 1500), 1000));
+    QVERIFY(scheduler.enqueue(&a, makeRequest(2, 5000), 1000));
+    QVERIFY(scheduler.enqueue(&a, makeRequest(3), 1000));
+
+    auto first = scheduler.next(2000);
+    QVERIFY(first.has_value());
+    QVERIFY(first->expired);
+    QCOMPARE(first->deadlineAtMs, static_cast<int64_t>(1500));
+
+    auto second = scheduler.next(2000);
+    QVERIFY(second.has_value());
+    QVERIFY(!second->expired);
+
+    auto third = scheduler.next(2000);
+    QVERIFY(third.has_value());
+    QVERIFY(!third->expired);
+
+    const bs::RequestSchedulerStats stats = scheduler.stats();
+    QCOMPARE(stats.expired, static_cast<uint64_t>(1));
+    QCOMPARE(stats.maxQueueDelayMs, static_cast<int64_t>(1000));
+}
+
+void TestRequestScheduler::testRemoveClient()
+{
+    bs::RequestScheduler scheduler;
+    int a = 0;
+    int b = 0;
+    QVERIFY(scheduler.enqueue(&a, makeRequest(1), 0));
+    QVERIFY(scheduler.enqueue(&a, makeRequest(2), 0));
+    QVERIFY(scheduler.enqueue(&b, makeRequest(3), 0));
+
+    scheduler.removeClient(&a);
+    QCOMPARE(scheduler.stats().pending, static_cast<size_t>(1));
+    auto pending = scheduler.next(0);
+    QVERIFY(pending.has_value());
+    QCOMPARE(pending->client, static_cast<const void*>(&b));
+    QVERIFY(!scheduler.next(0).has_value());
+}
+
+void TestRequestScheduler::testServerInterleavesClients()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("fair"));
+    bs::SocketServer server;
+    server.setFairScheduling(true);
+    std::vector<uint64_t> handled;
+    server.setRequestHandler([&handled](const QJsonObject& request) {
+        handled.push_back(idOf(request));
+        return bs::IpcMessage::makeResponse(idOf(request), QJsonObject());
+    });
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket bulk;
+    QLocalSocket interactive;
+    bulk.connectToServer(socketPath);
+    interactive.connectToServer(socketPath);
+    if (!bulk.waitForConnected(3000) || !interactive.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+    // The bulk client pipelines a burst before the interactive one sends.
+    QByteArray burst;
+    for (uint64_t id = 1; id <= 8; ++id) {
+        burst.append(bs::IpcMessage::encode(makeRequest(id)));
+    }
+    bulk.write(burst);
+    bulk.flush();
+    interactive.write(bs::IpcMessage::encode(makeRequest(100)));
+    interactive.flush();
+
+    QByteArray buffer;
+    const auto response = readMessage(interactive, buffer);
+    QVERIFY(response.has_value());
+    QCOMPARE(idOf(*response), static_cast<uint64_t>(100));
+
+    QTRY_COMPARE(handled.size(), static_cast<size_t>(9));
+    const auto position = std::find(handled.begin(), handled.end(), 100) - handled.begin();
+    QVERIFY2(position < 8, "Interactive request should not wait for the whole burst");
+
+    bulk.disconnectFromServer();
+    interactive.disconnectFromServer();
+    server.close();
+}
+
+void TestRequestScheduler::testServerRejectsExpiredRequest()
+{
+    const QString socketPath = makeShortSocketPath(QStringLiteral("dline"));
+    bs::SocketServer server;
+    server.setFairScheduling(true);
+    int handledCount = 0;
+    server.setRequestHandler([&handledCount](const QJsonObject& request) {
+        ++handledCount;
+        return bs::IpcMessage::makeResponse(idOf(request), QJsonObject());
+    });
+    QVERIFY(server.listen(socketPath));
+
+    QLocalSocket client;
+    client.connectToServer(socketPath);
+    if (!client.waitForConnected(3000)) {
+        QSKIP("Could not connect to local socket (platform limitation)");
+    }
+
+    client.write(bs::IpcMessage::encode(
+        makeRequest(7, QDateTime::currentMSecsSinceEpoch() - 1)));
+    client.flush();
+
+    QByteArray buffer;
+    const auto response = readMessage(client, buffer);
+    QVERIFY(response.has_value());
+    QCOMPARE(response->value(QStringLiteral("type")).toString(), QStringLiteral("error"));
+    QCOMPARE(response->value(QStringLiteral("error")).toObject()
+                 .value(QStringLiteral("code")).toInt(),
+             static_cast<int>(bs::IpcErrorCode::Timeout));
+    QCOMPARE(handledCount, 0);
+    QCOMPARE(server.schedulingStats().expired, static_cast<uint64_t>(1));
+
+    client.disconnectFromServer();
+    server.close();
+}
+
+QTEST_MAIN(TestRequestScheduler)
+#include "test_request_scheduler.moc"
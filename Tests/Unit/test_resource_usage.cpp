@@ -0,0 +1,115 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/resource_usage.h"
+
+#include <fcntl.h>
+#include <unistd.h>
+
+namespace {
+
+bs::ResourceSample sampleAt(int64_t atMs, double cpuSeconds, int64_t readBytes = 0,
+                            int64_t writeBytes = 0)
+{
+    bs::ResourceSample sample;
+    sample.atMs = atMs;
+    sample.cpuSeconds = cpuSeconds;
+    sample.diskReadBytes = readBytes;
+    sample.diskWriteBytes = writeBytes;
+    return sample;
+}
+
+} // namespace
+
+class TestResourceUsage : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testFirstSampleHasNoRates();
+    void testRatesOverWindow();
+    void testWindowSlides();
+    void testJsonLeavesUnknownsNull();
+    void testSampleProcess();
+};
+
+void TestResourceUsage::testFirstSampleHasNoRates()
+{
+    bs::ResourceMonitor monitor;
+    const bs::ResourceUsage usage = monitor.record(sampleAt(1000, 5.0));
+    QVERIFY(!usage.cpuPercent.has_value());
+    QVERIFY(!usage.diskReadBytesPerSec.has_value());
+    QCOMPARE(usage.windowMs, int64_t(0));
+}
+
+void TestResourceUsage::testRatesOverWindow()
+{
+    bs::ResourceMonitor monitor;
+    monitor.record(sampleAt(0, 10.0, 0, 0));
+    // 0.5 s of CPU in 2 s is a quarter of a core.
+    const bs::ResourceUsage usage = monitor.record(sampleAt(2000, 10.5, 4'000'000, 1'000'000));
+    QCOMPARE(usage.windowMs, int64_t(2000));
+    QVERIFY(usage.cpuPercent.has_value());
+    QCOMPARE(*usage.cpuPercent, 25.0);
+    QCOMPARE(*usage.diskReadBytesPerSec, 2'000'000.0);
+    QCOMPARE(*usage.diskWriteBytesPerSec, 500'000.0);
+}
+
+void TestResourceUsage::testWindowSlides()
+{
+    bs::ResourceMonitor monitor;
+    // Ten busy seconds, then a quiet stretch polled every second.
+    monitor.record(sampleAt(0, 0.0));
+    monitor.record(sampleAt(10'000, 10.0));
+    double cpu = 10.0;
+    for (int64_t at = 11'000; at <= 20'000; at += 1000) {
+        cpu += 0.1;
+        monitor.record(sampleAt(at, cpu));
+    }
+    const bs::ResourceUsage usage = monitor.record(sampleAt(21'000, cpu + 0.1));
+    // The busy stretch has left the window; only the quiet one is measured.
+    QVERIFY(usage.windowMs >= bs::ResourceMonitor::kWindowMs);
+    QVERIFY(usage.windowMs < bs::ResourceMonitor::kWindowMs + 2000);
+    QVERIFY(*usage.cpuPercent > 9.0);
+    QVERIFY(*usage.cpuPercent < 11.0);
+
+    // Calls closer together than kMinSpacingMs do not crowd out older
+    // samples, so a fast poller still sees about a window's rate.
+    const bs::ResourceUsage burst = monitor.record(sampleAt(21'100, cpu + 0.1));
+    QVERIFY(burst.windowMs >= bs::ResourceMonitor::kWindowMs);
+}
+
+void TestResourceUsage::testJsonLeavesUnknownsNull()
+{
+    bs::ResourceUsage usage;
+    usage.current.cpuSeconds = 1.26;
+    usage.current.openFds = 12;
+    usage.cpuPercent = 12.345;
+    const QJsonObject json = usage.toJson();
+    QCOMPARE(json.value(QStringLiteral("cpuPercent")).toDouble(), 12.3);
+    QCOMPARE(json.value(QStringLiteral("cpuSeconds")).toDouble(), 1.3);
+    QCOMPARE(json.value(QStringLiteral("openFds")).toInt(), 12);
+    QVERIFY(json.value(QStringLiteral("rssBytes")).isNull());
+    QVERIFY(json.value(QStringLiteral("diskReadBytesPerSec")).isNull());
+    QVERIFY(json.value(QStringLiteral("diskWriteBytes")).isNull());
+}
+
+void TestResourceUsage::testSampleProcess()
+{
+    const bs::ResourceSample before = bs::ResourceMonitor::sampleProcess(0);
+    QVERIFY(before.openFds.has_value());
+    QVERIFY(before.cpuSeconds >= 0.0);
+
+    const int fd = ::open("/dev/null", O_RDONLY);
+    QVERIFY(fd >= 0);
+    const bs::ResourceSample during = bs::ResourceMonitor::sampleProcess(1);
+    ::close(fd);
+    QCOMPARE(*during.openFds, *before.openFds + 1);
+    QVERIFY(during.cpuSeconds >= before.cpuSeconds);
+#if defined(__APPLE__)
+    QVERIFY(during.rssBytes.has_value());
+    QVERIFY(*during.rssBytes > 0);
+    QVERIFY(during.diskReadBytes.has_value());
+#endif
+}
+
+QTEST_MAIN(TestResourceUsage)
+#include "test_resource_usage.moc"
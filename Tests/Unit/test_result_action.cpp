@@ -0,0 +1,68 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/result_action.h"
+
+class TestResultAction : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testNamesRoundTrip();
+    void testAliases();
+    void testCommands();
+    void testSelectionSignal();
+};
+
+void TestResultAction::testNamesRoundTrip()
+{
+    for (const bs::ResultAction action : {bs::ResultAction::Open, bs::ResultAction::Reveal,
+                                          bs::ResultAction::QuickLook,
+                                          bs::ResultAction::CopyPath}) {
+        QCOMPARE(bs::resultActionFromString(bs::resultActionToString(action)), action);
+    }
+    QCOMPARE(bs::resultActionToString(bs::ResultAction::CopyPath), QStringLiteral("copyPath"));
+}
+
+void TestResultAction::testAliases()
+{
+    QCOMPARE(bs::resultActionFromString(QStringLiteral("copy-path")), bs::ResultAction::CopyPath);
+    QCOMPARE(bs::resultActionFromString(QStringLiteral("copy_path")), bs::ResultAction::CopyPath);
+    QCOMPARE(bs::resultActionFromString(QStringLiteral(" Quick-Look ")),
+             bs::ResultAction::QuickLook);
+    QVERIFY(!bs::resultActionFromString(QStringLiteral("delete")).has_value());
+    QVERIFY(!bs::resultActionFromString(QString()).has_value());
+}
+
+void TestResultAction::testCommands()
+{
+    const QString path = QStringLiteral("/Users/test/My Report.pdf");
+
+    const bs::ResultActionCommand open = bs::resultActionCommand(bs::ResultAction::Open, path);
+    QCOMPARE(open.program, QStringLiteral("/usr/bin/open"));
+    QCOMPARE(open.arguments, QStringList{path});
+    QVERIFY(open.input.isEmpty());
+
+    const bs::ResultActionCommand reveal = bs::resultActionCommand(bs::ResultAction::Reveal, path);
+    QCOMPARE(reveal.arguments, (QStringList{QStringLiteral("-R"), path}));
+
+    const bs::ResultActionCommand preview =
+        bs::resultActionCommand(bs::ResultAction::QuickLook, path);
+    QCOMPARE(preview.program, QStringLiteral("/usr/bin/qlmanage"));
+    QCOMPARE(preview.arguments, (QStringList{QStringLiteral("-p"), path}));
+
+    // The path goes through stdin, never the argument list.
+    const bs::ResultActionCommand copy = bs::resultActionCommand(bs::ResultAction::CopyPath, path);
+    QCOMPARE(copy.program, QStringLiteral("/usr/bin/pbcopy"));
+    QVERIFY(copy.arguments.isEmpty());
+    QCOMPARE(copy.input, path.toUtf8());
+}
+
+void TestResultAction::testSelectionSignal()
+{
+    QVERIFY(bs::resultActionCountsAsSelection(bs::ResultAction::Open));
+    QVERIFY(bs::resultActionCountsAsSelection(bs::ResultAction::Reveal));
+    QVERIFY(bs::resultActionCountsAsSelection(bs::ResultAction::CopyPath));
+    QVERIFY(!bs::resultActionCountsAsSelection(bs::ResultAction::QuickLook));
+}
+
+QTEST_MAIN(TestResultAction)
+#include "test_result_action.moc"
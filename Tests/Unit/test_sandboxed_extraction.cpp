@@ -0,0 +1,196 @@
+#include <QtTest/QtTest>
+
+#include "core/extraction/extraction_manager.h"
+#include "core/extraction/sandboxed_extraction.h"
+
+#include <QFile>
+#include <QTemporaryDir>
+
+namespace {
+
+// Writes an executable shell script standing in for the helper.
+QString writeFakeHelper(const QTemporaryDir& dir, const QString& name, const QByteArray& body)
+{
+    const QString path = dir.filePath(name);
+    QFile file(path);
+    if (!file.open(QIODevice::WriteOnly)) {
+        return {};
+    }
+    file.write("#!/bin/sh\n" + body + '\n');
+    file.close();
+    file.setPermissions(QFile::ReadOwner | QFile::WriteOwner | QFile::ExeOwner);
+    return path;
+}
+
+QString writePdf(const QTemporaryDir& dir)
+{
+    const QString path = dir.filePath(QStringLiteral("sample.pdf"));
+    QFile file(path);
+    if (file.open(QIODevice::WriteOnly)) {
+        file.write("%PDF-1.4\n%%EOF\n");
+    }
+    return path;
+}
+
+} // namespace
+
+class TestSandboxedExtraction : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testEncodeDecodeRoundTrip();
+    void testHelperArgumentsRoundTrip();
+    void testParseRejectsMalformedArguments();
+    void testRunDecodesHelperOutput();
+    void testRunReportsCrash();
+    void testRunKillsHungHelper();
+    void testRunReportsHelperFailure();
+    void testManagerRoutesPdfThroughHelper();
+};
+
+void TestSandboxedExtraction::testEncodeDecodeRoundTrip()
+{
+    bs::ExtractionResult result;
+    result.status = bs::ExtractionResult::Status::CorruptedFile;
+    result.content = QStringLiteral("partial \"text\"\n");
+    result.errorMessage = QStringLiteral("bad xref");
+    result.durationMs = 42;
+
+    const auto decoded =
+        bs::SandboxedExtraction::decodeResult(bs::SandboxedExtraction::encodeResult(result));
+    QVERIFY(decoded.has_value());
+    QCOMPARE(decoded->status, result.status);
+    QCOMPARE(decoded->content, result.content);
+    QCOMPARE(decoded->errorMessage, result.errorMessage);
+    QCOMPARE(decoded->durationMs, 42);
+
+    QVERIFY(!bs::SandboxedExtraction::decodeResult("not json").has_value());
+    QVERIFY(!bs::SandboxedExtraction::decodeResult(R"({"status":"exploded"})").has_value());
+}
+
+void TestSandboxedExtraction::testHelperArgumentsRoundTrip()
+{
+    bs::SandboxLimits limits;
+    limits.memoryLimitMb = 512;
+    limits.cpuLimitSecs = 12;
+    const QString path = QStringLiteral("/Users/test/--kind odd.pdf");
+    const QStringList args =
+        bs::SandboxedExtraction::helperArguments(path, bs::ItemKind::Pdf, limits);
+
+    QString error;
+    const auto request = bs::SandboxedExtraction::parseHelperArguments(args, &error);
+    QVERIFY2(request.has_value(), qPrintable(error));
+    QCOMPARE(request->filePath, path);
+    QCOMPARE(request->kind, bs::ItemKind::Pdf);
+    QCOMPARE(request->limits.memoryLimitMb, 512);
+    QCOMPARE(request->limits.cpuLimitSecs, 12);
+}
+
+void TestSandboxedExtraction::testParseRejectsMalformedArguments()
+{
+    QString error;
+    QVERIFY(!bs::SandboxedExtraction::parseHelperArguments(
+        {QStringLiteral("--kind"), QStringLiteral("text"), QStringLiteral("--"),
+         QStringLiteral("/tmp/a.txt")}, &error).has_value());
+    QVERIFY(!bs::SandboxedExtraction::parseHelperArguments(
+        {QStringLiteral("--kind"), QStringLiteral("pdf"), QStringLiteral("--"),
+         QStringLiteral("relative.pdf")}, &error).has_value());
+    QVERIFY(!bs::SandboxedExtraction::parseHelperArguments(
+        {QStringLiteral("--kind"), QStringLiteral("pdf"), QStringLiteral("--memory-mb"),
+         QStringLiteral("lots"), QStringLiteral("--"), QStringLiteral("/tmp/a.pdf")},
+        &error).has_value());
+    QVERIFY(!bs::SandboxedExtraction::parseHelperArguments(
+        {QStringLiteral("--kind")}, &error).has_value());
+    QVERIFY(!error.isEmpty());
+}
+
+void TestSandboxedExtraction::testRunDecodesHelperOutput()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString helper = writeFakeHelper(
+        dir, QStringLiteral("ok-helper"),
+        R"(printf '%s' '{"status":"success","content":"hello from helper","durationMs":1}')");
+
+    const bs::ExtractionResult result = bs::SandboxedExtraction::run(
+        helper, writePdf(dir), bs::ItemKind::Pdf, bs::SandboxLimits{});
+    QCOMPARE(result.status, bs::ExtractionResult::Status::Success);
+    QCOMPARE(result.content.value_or(QString()), QStringLiteral("hello from helper"));
+}
+
+void TestSandboxedExtraction::testRunReportsCrash()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString helper =
+        writeFakeHelper(dir, QStringLiteral("crash-helper"), "kill -SEGV $$");
+
+    const bs::ExtractionResult result = bs::SandboxedExtraction::run(
+        helper, writePdf(dir), bs::ItemKind::Pdf, bs::SandboxLimits{});
+    QCOMPARE(result.status, bs::ExtractionResult::Status::Crashed);
+    QVERIFY(result.errorMessage.has_value());
+}
+
+void TestSandboxedExtraction::testRunKillsHungHelper()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString helper = writeFakeHelper(dir, QStringLiteral("hung-helper"), "exec sleep 30");
+
+    bs::SandboxLimits limits;
+    limits.timeoutMs = 200;
+    QElapsedTimer timer;
+    timer.start();
+    const bs::ExtractionResult result =
+        bs::SandboxedExtraction::run(helper, writePdf(dir), bs::ItemKind::Pdf, limits);
+    QCOMPARE(result.status, bs::ExtractionResult::Status::Timeout);
+    QVERIFY(timer.elapsed() < 5000);
+}
+
+void TestSandboxedExtraction::testRunReportsHelperFailure()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString helper = writeFakeHelper(
+        dir, QStringLiteral("usage-helper"), "echo 'helper: bad arguments' >&2; exit 2");
+
+    const bs::ExtractionResult result = bs::SandboxedExtraction::run(
+        helper, writePdf(dir), bs::ItemKind::Pdf, bs::SandboxLimits{});
+    QCOMPARE(result.status, bs::ExtractionResult::Status::Unknown);
+    QVERIFY(result.errorMessage.value_or(QString()).contains(QStringLiteral("bad arguments")));
+
+    const bs::ExtractionResult missing = bs::SandboxedExtraction::run(
+        dir.filePath(QStringLiteral("no-such-helper")), writePdf(dir), bs::ItemKind::Pdf,
+        bs::SandboxLimits{});
+    QCOMPARE(missing.status, bs::ExtractionResult::Status::Unknown);
+}
+
+void TestSandboxedExtraction::testManagerRoutesPdfThroughHelper()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    // Counts its own launches so the test can see the manager ran it once.
+    const QString counter = dir.filePath(QStringLiteral("launches"));
+    const QString helper = writeFakeHelper(
+        dir, QStringLiteral("crash-helper"),
+        QStringLiteral("echo x >> '%1'; kill -SEGV $$").arg(counter).toUtf8());
+
+    bs::ExtractionManager mgr;
+    mgr.setSandboxedExtraction(true, helper);
+    QVERIFY(mgr.isSandboxed());
+    const bs::ExtractionResult result = mgr.extract(writePdf(dir), bs::ItemKind::Pdf);
+    QCOMPARE(result.status, bs::ExtractionResult::Status::Crashed);
+    QCOMPARE(mgr.helperCrashCount(), 1);
+
+    QFile launches(counter);
+    QVERIFY(launches.open(QIODevice::ReadOnly));
+    QCOMPARE(launches.readAll().count('x'), static_cast<qsizetype>(1));
+
+    // Without a helper binary the manager keeps extracting in-process.
+    bs::ExtractionManager fallback;
+    fallback.setSandboxedExtraction(true, QString());
+    QVERIFY(!fallback.isSandboxed());
+}
+
+QTEST_MAIN(TestSandboxedExtraction)
+#include "test_sandboxed_extraction.moc"
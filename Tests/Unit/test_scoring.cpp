@@ -53,6 +53,15 @@ private slots:
     void testPinnedBoostApplied();
     void testNotPinnedNoBoost();
 
+    // ── Action boost ─────────────────────────────────────────────
+    void testActionBoostSaturatesAtThreeActions();
+    void testActionBoostDecaysWithAge();
+    void testActionBoostZeroActions();
+
+    // ── Event time boost ─────────────────────────────────────────
+    void testEventTimeBoostFullWhileOn();
+    void testEventTimeBoostDecaysBothWays();
+
     // ── Junk penalty ─────────────────────────────────────────────
     void testJunkPenaltyNodeModules();
     void testJunkPenaltyPycache();
@@ -68,6 +77,9 @@ private slots:
 
     // ── Context signals ──────────────────────────────────────────
     void testCwdProximityBoost();
+    void testAppContextBoostPreviewPrefersPdf();
+    void testAppContextBoostIgnoresBundleIdCase();
+    void testAppContextBoostUnknownAppNoBoost();
 };
 
 // ── Match type ordering ──────────────────────────────────────────
@@ -259,6 +271,58 @@ void TestScoring::testNotPinnedNoBoost()
     QCOMPARE(boost, 0.0);
 }
 
+// ── Action boost ─────────────────────────────────────────────────
+
+void TestScoring::testActionBoostSaturatesAtThreeActions()
+{
+    bs::Scorer scorer;
+    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    const double weight = static_cast<double>(scorer.weights().actionBoostWeight);
+    QVERIFY(std::abs(scorer.computeActionBoost(1, 0, now) - weight / 3.0) < 0.01);
+    QVERIFY(std::abs(scorer.computeActionBoost(2, 1, now) - weight) < 0.01);
+    QVERIFY(std::abs(scorer.computeActionBoost(5, 4, now) - weight) < 0.01);
+}
+
+void TestScoring::testActionBoostDecaysWithAge()
+{
+    bs::Scorer scorer;
+    const double twoWeeksAgo =
+        static_cast<double>(QDateTime::currentSecsSinceEpoch()) - 14.0 * 86400.0;
+    const double weight = static_cast<double>(scorer.weights().actionBoostWeight);
+    QVERIFY(std::abs(scorer.computeActionBoost(0, 3, twoWeeksAgo) - weight / 2.0) < 0.01);
+}
+
+void TestScoring::testActionBoostZeroActions()
+{
+    bs::Scorer scorer;
+    QCOMPARE(scorer.computeActionBoost(0, 0, 0.0), 0.0);
+}
+
+// ── Event time boost ─────────────────────────────────────────────
+
+void TestScoring::testEventTimeBoostFullWhileOn()
+{
+    bs::Scorer scorer;
+    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    QCOMPARE(scorer.computeEventTimeBoost(now - 600.0, now + 600.0),
+             static_cast<double>(scorer.weights().eventTimeWeight));
+}
+
+void TestScoring::testEventTimeBoostDecaysBothWays()
+{
+    bs::Scorer scorer;
+    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    const double weight = static_cast<double>(scorer.weights().eventTimeWeight);
+    const double inThreeDays = now + 3.0 * 86400.0;
+    QVERIFY(std::abs(scorer.computeEventTimeBoost(inThreeDays, inThreeDays + 3600.0)
+                     - weight / 2.0) < 0.01);
+    const double weekAgo = now - 7.0 * 86400.0;
+    QVERIFY(std::abs(scorer.computeEventTimeBoost(weekAgo - 3600.0, weekAgo) - weight / 2.0)
+            < 0.01);
+    // Unlike recency, the far future gets next to nothing.
+    QVERIFY(scorer.computeEventTimeBoost(now + 60.0 * 86400.0, now + 60.0 * 86400.0) < 0.01);
+}
+
 // ── Junk penalty ─────────────────────────────────────────────────
 
 void TestScoring::testJunkPenaltyNodeModules()
@@ -378,5 +442,38 @@ void TestScoring::testCwdProximityBoost()
     QCOMPARE(s2.contextBoost, 0.0);
 }
 
+void TestScoring::testAppContextBoostPreviewPrefersPdf()
+{
+    bs::Scorer scorer;
+    bs::QueryContext ctx;
+    ctx.frontmostAppBundleId = QStringLiteral("com.apple.Preview");
+
+    auto pdf = makeResult(1, "/Users/me/Documents/lease.pdf", "lease.pdf",
+                          bs::MatchType::ContainsName);
+    auto code = makeResult(2, "/Users/me/project/lease.cpp", "lease.cpp",
+                           bs::MatchType::ContainsName);
+    QCOMPARE(scorer.computeScore(pdf, ctx).contextBoost,
+             static_cast<double>(scorer.weights().appContextBoostWeight));
+    QCOMPARE(scorer.computeScore(code, ctx).contextBoost, 0.0);
+}
+
+void TestScoring::testAppContextBoostIgnoresBundleIdCase()
+{
+    bs::ContextSignals contextSignals;
+    const QString path = QStringLiteral("/Users/me/project/main.cpp");
+    QCOMPARE(contextSignals.appContextBoost(path, QStringLiteral("com.microsoft.vscode"), 15), 15.0);
+    QCOMPARE(contextSignals.appContextBoost(path, QStringLiteral("COM.MICROSOFT.VSCODE"), 15), 15.0);
+    QCOMPARE(contextSignals.appContextBoost(path, QStringLiteral("com.microsoft.VSCode"), 0), 0.0);
+}
+
+void TestScoring::testAppContextBoostUnknownAppNoBoost()
+{
+    // Not in the table and not installed, so nothing is declared either.
+    bs::ContextSignals contextSignals;
+    QCOMPARE(contextSignals.appContextBoost(QStringLiteral("/tmp/a.pdf"),
+                                            QStringLiteral("com.example.NotInstalled"), 15),
+             0.0);
+}
+
 QTEST_MAIN(TestScoring)
 #include "test_scoring.moc"
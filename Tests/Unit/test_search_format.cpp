@@ -0,0 +1,234 @@
+#include <QtTest/QtTest>
+
+#include "cli/search_format.h"
+
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QTimeZone>
+
+namespace {
+
+QJsonObject makeHit(const QString& path, double score, qint64 size)
+{
+    QJsonObject metadata;
+    metadata[QStringLiteral("fileSize")] = size;
+    metadata[QStringLiteral("modificationDate")] = QStringLiteral("2026-03-04T05:06:00Z");
+
+    QJsonObject hit;
+    hit[QStringLiteral("itemId")] = 42;
+    hit[QStringLiteral("path")] = path;
+    hit[QStringLiteral("name")] = QFileInfo(path).fileName();
+    hit[QStringLiteral("kind")] = QStringLiteral("pdf");
+    hit[QStringLiteral("matchType")] = QStringLiteral("contentMatch");
+    hit[QStringLiteral("score")] = score;
+    hit[QStringLiteral("snippet")] = QStringLiteral("first line\nsecond   line");
+    hit[QStringLiteral("metadata")] = metadata;
+    return hit;
+}
+
+} // namespace
+
+class TestSearchFormat : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseRelativeTime();
+    void testParseAbsoluteTime();
+    void testParseSize();
+    void testColumnValues();
+    void testRenderColumnsAligns();
+    void testRenderNdjson();
+    void testRenderAlfred();
+    void testRenderAlfredEmpty();
+    void testRenderAlfredInstantAnswer();
+    void testRenderAlfredDefinition();
+};
+
+void TestSearchFormat::testParseRelativeTime()
+{
+    const qint64 now = 1'800'000'000;
+    QCOMPARE(bs::SearchFormat::parseTime(QStringLiteral("30m"), now).value(), now - 1800.0);
+    QCOMPARE(bs::SearchFormat::parseTime(QStringLiteral("12h"), now).value(), now - 43200.0);
+    QCOMPARE(bs::SearchFormat::parseTime(QStringLiteral("7d"), now).value(), now - 604800.0);
+    QCOMPARE(bs::SearchFormat::parseTime(QStringLiteral("2W"), now).value(), now - 1209600.0);
+    QVERIFY(!bs::SearchFormat::parseTime(QStringLiteral("7y"), now).has_value());
+    QVERIFY(!bs::SearchFormat::parseTime(QStringLiteral("yesterday"), now).has_value());
+}
+
+void TestSearchFormat::testParseAbsoluteTime()
+{
+    const auto dateTime =
+        bs::SearchFormat::parseTime(QStringLiteral("2026-01-31T09:00:00Z"), 0);
+    QVERIFY(dateTime.has_value());
+    QCOMPARE(dateTime.value(), 1769850000.0);
+
+    const auto date = bs::SearchFormat::parseTime(QStringLiteral("2026-01-31"), 0);
+    QVERIFY(date.has_value());
+    QCOMPARE(date.value(),
+             static_cast<double>(QDate(2026, 1, 31).startOfDay().toSecsSinceEpoch()));
+}
+
+void TestSearchFormat::testParseSize()
+{
+    QCOMPARE(bs::SearchFormat::parseSize(QStringLiteral("512")).value(), 512);
+    QCOMPARE(bs::SearchFormat::parseSize(QStringLiteral("10k")).value(), 10 * 1024);
+    QCOMPARE(bs::SearchFormat::parseSize(QStringLiteral("5M")).value(), 5LL * 1024 * 1024);
+    QCOMPARE(bs::SearchFormat::parseSize(QStringLiteral("1GB")).value(), 1LL << 30);
+    QVERIFY(!bs::SearchFormat::parseSize(QStringLiteral("-1")).has_value());
+    QVERIFY(!bs::SearchFormat::parseSize(QStringLiteral("big")).has_value());
+    QVERIFY(!bs::SearchFormat::parseSize(QStringLiteral("99999999999999T")).has_value());
+}
+
+void TestSearchFormat::testColumnValues()
+{
+    const QJsonObject hit = makeHit(QStringLiteral("/Users/test/Report Q1.pdf"), 12.345, 1536);
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("score")), QStringLiteral("12.3"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("size")), QStringLiteral("1.5K"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("name")),
+             QStringLiteral("Report Q1.pdf"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("itemId")), QStringLiteral("42"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("match")),
+             QStringLiteral("contentMatch"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("snippet")),
+             QStringLiteral("first line second line"));
+    QCOMPARE(bs::SearchFormat::columnValue(hit, QStringLiteral("modified")),
+             QDateTime(QDate(2026, 3, 4), QTime(5, 6), QTimeZone::UTC)
+                 .toLocalTime().toString(QStringLiteral("yyyy-MM-dd HH:mm")));
+    QVERIFY(bs::SearchFormat::columnValue(QJsonObject(), QStringLiteral("size")).isEmpty());
+}
+
+void TestSearchFormat::testRenderColumnsAligns()
+{
+    QJsonArray results;
+    results.append(makeHit(QStringLiteral("/a/short.pdf"), 100.0, 10));
+    results.append(makeHit(QStringLiteral("/a/much longer name.pdf"), 9.5, 10));
+
+    const QString output = bs::SearchFormat::renderColumns(
+        results, {QStringLiteral("score"), QStringLiteral("path")}, true);
+    const QStringList lines = output.split(QLatin1Char('\n'), Qt::SkipEmptyParts);
+    QCOMPARE(lines.size(), 3);
+    QCOMPARE(lines.at(0), QStringLiteral("SCORE  PATH"));
+    QCOMPARE(lines.at(1), QStringLiteral("100.0  /a/short.pdf"));
+    QCOMPARE(lines.at(2), QStringLiteral("9.5    /a/much longer name.pdf"));
+
+    const QString noHeader = bs::SearchFormat::renderColumns(
+        results, {QStringLiteral("path")}, false);
+    QCOMPARE(noHeader, QStringLiteral("/a/short.pdf\n/a/much longer name.pdf\n"));
+}
+
+void TestSearchFormat::testRenderNdjson()
+{
+    QJsonArray results;
+    results.append(makeHit(QStringLiteral("/a/one.pdf"), 1.0, 1));
+    results.append(makeHit(QStringLiteral("/a/two.pdf"), 2.0, 2));
+
+    const QStringList lines = bs::SearchFormat::renderNdjson(results)
+                                  .split(QLatin1Char('\n'), Qt::SkipEmptyParts);
+    QCOMPARE(lines.size(), 2);
+    for (int i = 0; i < lines.size(); ++i) {
+        const QJsonDocument doc = QJsonDocument::fromJson(lines.at(i).toUtf8());
+        QVERIFY(doc.isObject());
+        QCOMPARE(doc.object().value(QStringLiteral("path")).toString(),
+                 results.at(i).toObject().value(QStringLiteral("path")).toString());
+    }
+}
+
+void TestSearchFormat::testRenderAlfred()
+{
+    QJsonArray results;
+    results.append(makeHit(QStringLiteral("/Users/test/Report Q1.pdf"), 12.0, 10));
+    results.append(makeHit(QStringLiteral("/Volumes/Shared/notes.pdf"), 3.0, 10));
+
+    const QString output = bs::SearchFormat::renderAlfred(
+        results, QStringLiteral("report"), QStringLiteral("/Users/test"));
+    QVERIFY(output.endsWith(QLatin1Char('\n')));
+    const QJsonObject document = QJsonDocument::fromJson(output.toUtf8()).object();
+    QVERIFY(document.value(QStringLiteral("skipknowledge")).toBool());
+    const QJsonArray items = document.value(QStringLiteral("items")).toArray();
+    QCOMPARE(items.size(), 2);
+
+    const QJsonObject first = items.at(0).toObject();
+    QCOMPARE(first.value(QStringLiteral("title")).toString(), QStringLiteral("Report Q1.pdf"));
+    QCOMPARE(first.value(QStringLiteral("subtitle")).toString(),
+             QStringLiteral("~/Report Q1.pdf"));
+    QCOMPARE(first.value(QStringLiteral("arg")).toString(),
+             QStringLiteral("/Users/test/Report Q1.pdf"));
+    QCOMPARE(first.value(QStringLiteral("type")).toString(), QStringLiteral("file"));
+    QCOMPARE(first.value(QStringLiteral("icon")).toObject().value(QStringLiteral("type")).toString(),
+             QStringLiteral("fileicon"));
+    QCOMPARE(first.value(QStringLiteral("variables")).toObject()
+                 .value(QStringLiteral("action")).toString(),
+             QStringLiteral("open"));
+    QCOMPARE(first.value(QStringLiteral("variables")).toObject()
+                 .value(QStringLiteral("itemId")).toString(),
+             QStringLiteral("42"));
+    const QJsonObject mods = first.value(QStringLiteral("mods")).toObject();
+    QCOMPARE(mods.value(QStringLiteral("cmd")).toObject().value(QStringLiteral("variables"))
+                 .toObject().value(QStringLiteral("action")).toString(),
+             QStringLiteral("reveal"));
+    QCOMPARE(mods.value(QStringLiteral("alt")).toObject().value(QStringLiteral("variables"))
+                 .toObject().value(QStringLiteral("action")).toString(),
+             QStringLiteral("copyPath"));
+    QCOMPARE(first.value(QStringLiteral("text")).toObject()
+                 .value(QStringLiteral("largetype")).toString(),
+             QStringLiteral("first line second line"));
+
+    QCOMPARE(items.at(1).toObject().value(QStringLiteral("subtitle")).toString(),
+             QStringLiteral("/Volumes/Shared/notes.pdf"));
+}
+
+void TestSearchFormat::testRenderAlfredEmpty()
+{
+    const QJsonObject document = QJsonDocument::fromJson(
+        bs::SearchFormat::renderAlfred(QJsonArray(), QStringLiteral("zzz"),
+                                       QStringLiteral("/Users/test")).toUtf8()).object();
+    const QJsonArray items = document.value(QStringLiteral("items")).toArray();
+    QCOMPARE(items.size(), 1);
+    QCOMPARE(items.at(0).toObject().value(QStringLiteral("title")).toString(),
+             QStringLiteral("No results"));
+    QCOMPARE(items.at(0).toObject().value(QStringLiteral("valid")).toBool(true), false);
+}
+
+void TestSearchFormat::testRenderAlfredInstantAnswer()
+{
+    QJsonObject answer;
+    answer[QStringLiteral("value")] = QStringLiteral("22.222222");
+    answer[QStringLiteral("unit")] = QStringLiteral("°C");
+    answer[QStringLiteral("display")] = QStringLiteral("72 °F = 22.222222 °C");
+
+    const QJsonObject document = QJsonDocument::fromJson(
+        bs::SearchFormat::renderAlfred(QJsonArray(), QStringLiteral("72 f in c"),
+                                       QStringLiteral("/Users/test"), answer).toUtf8()).object();
+    const QJsonArray items = document.value(QStringLiteral("items")).toArray();
+    QCOMPARE(items.size(), 1);
+    const QJsonObject item = items.at(0).toObject();
+    QCOMPARE(item.value(QStringLiteral("title")).toString(), QStringLiteral("22.222222 °C"));
+    QCOMPARE(item.value(QStringLiteral("arg")).toString(), QStringLiteral("22.222222"));
+    QCOMPARE(item.value(QStringLiteral("variables")).toObject()
+                 .value(QStringLiteral("action")).toString(),
+             QStringLiteral("copy"));
+}
+
+void TestSearchFormat::testRenderAlfredDefinition()
+{
+    QJsonObject definition;
+    definition[QStringLiteral("word")] = QStringLiteral("serendipity");
+    definition[QStringLiteral("partOfSpeech")] = QStringLiteral("noun");
+    definition[QStringLiteral("summary")] = QStringLiteral("happy chance");
+    definition[QStringLiteral("url")] = QStringLiteral("dict://serendipity");
+
+    QJsonArray results;
+    results.append(makeHit(QStringLiteral("/Users/test/serendipity.txt"), 4.0, 10));
+    const QJsonObject document = QJsonDocument::fromJson(
+        bs::SearchFormat::renderAlfred(results, QStringLiteral("serendipity"),
+                                       QStringLiteral("/Users/test"), QJsonObject(), definition)
+            .toUtf8()).object();
+    const QJsonArray items = document.value(QStringLiteral("items")).toArray();
+    QCOMPARE(items.size(), 2);
+    const QJsonObject item = items.at(1).toObject();
+    QCOMPARE(item.value(QStringLiteral("title")).toString(), QStringLiteral("serendipity (noun)"));
+    QCOMPARE(item.value(QStringLiteral("arg")).toString(), QStringLiteral("dict://serendipity"));
+}
+
+QTEST_MAIN(TestSearchFormat)
+#include "test_search_format.moc"
@@ -0,0 +1,169 @@
+#include <QtTest/QtTest>
+
+#include "core/query/query_parser.h"
+#include "core/query/search_scopes.h"
+
+namespace {
+
+bs::SearchScope makeScope()
+{
+    QJsonObject json;
+    json[QStringLiteral("name")] = QStringLiteral("Client A");
+    json[QStringLiteral("roots")] =
+        QJsonArray{QStringLiteral("/Users/a/Clients/A/"), QStringLiteral("/Users/a/Mail/A")};
+    json[QStringLiteral("excludePaths")] = QJsonArray{QStringLiteral("/Users/a/Clients/A/old")};
+    json[QStringLiteral("fileTypes")] = QJsonArray{QStringLiteral(".PDF"), QStringLiteral("docx")};
+    json[QStringLiteral("tags")] = QJsonArray{QStringLiteral("Client")};
+    QString error;
+    const auto scope = bs::SearchScope::fromJson(json, &error);
+    Q_ASSERT(scope.has_value());
+    return scope.value();
+}
+
+} // namespace
+
+class TestSearchScopes : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testIdForName();
+    void testFromJsonNormalizes();
+    void testFromJsonRejectsInvalid();
+    void testNarrowWithoutIncludePaths();
+    void testNarrowKeepsPathsInsideRoots();
+    void testNarrowDisjointPathsFails();
+    void testNarrowKeepsExplicitFileTypes();
+    void testSetReplacesAndLimits();
+    void testJsonRoundTripSkipsInvalid();
+    void testParserExtractsScope();
+};
+
+void TestSearchScopes::testIdForName()
+{
+    QCOMPARE(bs::SearchScope::idForName(QStringLiteral("Client A")), QStringLiteral("clienta"));
+    QCOMPARE(bs::SearchScope::idForName(QStringLiteral("my-work_2")), QStringLiteral("my-work_2"));
+    QCOMPARE(bs::SearchScope::idForName(QStringLiteral("!!")), QString());
+}
+
+void TestSearchScopes::testFromJsonNormalizes()
+{
+    const bs::SearchScope scope = makeScope();
+    QCOMPARE(scope.id, QStringLiteral("clienta"));
+    QCOMPARE(scope.roots.first(), QStringLiteral("/Users/a/Clients/A"));
+    QCOMPARE(scope.fileTypes, QStringList({QStringLiteral("pdf"), QStringLiteral("docx")}));
+    QVERIFY(scope.coversPath(QStringLiteral("/Users/a/Mail/A/inbox")));
+    QVERIFY(!scope.coversPath(QStringLiteral("/Users/a/Mail/AB")));
+}
+
+void TestSearchScopes::testFromJsonRejectsInvalid()
+{
+    QString error;
+    QJsonObject json;
+    json[QStringLiteral("name")] = QStringLiteral("Work");
+    QVERIFY(!bs::SearchScope::fromJson(json, &error).has_value());  // no roots
+    QVERIFY(!error.isEmpty());
+
+    json[QStringLiteral("roots")] = QJsonArray{QStringLiteral("relative/path")};
+    QVERIFY(!bs::SearchScope::fromJson(json, &error).has_value());
+
+    json[QStringLiteral("roots")] = QJsonArray{QStringLiteral("/Users/a/Work")};
+    json[QStringLiteral("excludePaths")] = QJsonArray{QStringLiteral("old")};
+    QVERIFY(!bs::SearchScope::fromJson(json, &error).has_value());
+
+    json.remove(QStringLiteral("excludePaths"));
+    json[QStringLiteral("name")] = QStringLiteral("   ");
+    QVERIFY(!bs::SearchScope::fromJson(json, &error).has_value());
+
+    json[QStringLiteral("name")] = QStringLiteral("Work");
+    QVERIFY(bs::SearchScope::fromJson(json, &error).has_value());
+}
+
+void TestSearchScopes::testNarrowWithoutIncludePaths()
+{
+    bs::SearchOptions options;
+    options.tags = {QStringLiteral("client")};
+    QVERIFY(makeScope().narrow(options));
+    QCOMPARE(options.includePaths.size(), size_t(2));
+    QCOMPARE(options.excludePaths,
+             std::vector<QString>({QStringLiteral("/Users/a/Clients/A/old")}));
+    QCOMPARE(options.fileTypes.size(), size_t(2));
+    QCOMPARE(options.tags.size(), size_t(1));  // case-insensitive duplicate
+}
+
+void TestSearchScopes::testNarrowKeepsPathsInsideRoots()
+{
+    bs::SearchOptions options;
+    options.includePaths = {QStringLiteral("/Users/a/Clients/A/2025"), QStringLiteral("/Users/a"),
+                            QStringLiteral("/tmp")};
+    QVERIFY(makeScope().narrow(options));
+    QCOMPARE(options.includePaths,
+             std::vector<QString>({QStringLiteral("/Users/a/Clients/A/2025"),
+                                   QStringLiteral("/Users/a/Clients/A"),
+                                   QStringLiteral("/Users/a/Mail/A")}));
+}
+
+void TestSearchScopes::testNarrowDisjointPathsFails()
+{
+    bs::SearchOptions options;
+    options.includePaths = {QStringLiteral("/Users/a/Clients/B")};
+    QVERIFY(!makeScope().narrow(options));
+    QCOMPARE(options.includePaths, std::vector<QString>({QStringLiteral("/Users/a/Clients/B")}));
+    QVERIFY(options.excludePaths.empty());
+}
+
+void TestSearchScopes::testNarrowKeepsExplicitFileTypes()
+{
+    bs::SearchOptions options;
+    options.fileTypes = {QStringLiteral("xlsx")};
+    QVERIFY(makeScope().narrow(options));
+    QCOMPARE(options.fileTypes, std::vector<QString>({QStringLiteral("xlsx")}));
+}
+
+void TestSearchScopes::testSetReplacesAndLimits()
+{
+    bs::SearchScopes scopes;
+    bs::SearchScope scope = makeScope();
+    QVERIFY(scopes.set(scope, nullptr));
+    scope.roots = {QStringLiteral("/Users/a/Other")};
+    QVERIFY(scopes.set(scope, nullptr));
+    QCOMPARE(scopes.scopes().size(), size_t(1));
+    QCOMPARE(scopes.find(QStringLiteral("ClientA"))->roots.first(), QStringLiteral("/Users/a/Other"));
+
+    for (int i = 1; i < bs::SearchScopes::kMaxScopes; ++i) {
+        scope.id = QStringLiteral("s%1").arg(i);
+        QVERIFY(scopes.set(scope, nullptr));
+    }
+    QString error;
+    scope.id = QStringLiteral("extra");
+    QVERIFY(!scopes.set(scope, &error));
+    QVERIFY(!error.isEmpty());
+
+    QVERIFY(scopes.remove(QStringLiteral("clienta")));
+    QVERIFY(!scopes.remove(QStringLiteral("clienta")));
+    QVERIFY(!scopes.find(QStringLiteral("clienta")).has_value());
+}
+
+void TestSearchScopes::testJsonRoundTripSkipsInvalid()
+{
+    bs::SearchScopes scopes;
+    QVERIFY(scopes.set(makeScope(), nullptr));
+    QJsonArray json = scopes.toJson();
+    json.append(QJsonObject{{QStringLiteral("name"), QStringLiteral("No roots")}});
+
+    int skipped = 0;
+    const bs::SearchScopes restored = bs::SearchScopes::fromJson(json, &skipped);
+    QCOMPARE(skipped, 1);
+    QCOMPARE(restored.scopes().size(), size_t(1));
+    QCOMPARE(restored.scopes().front().toJson(), makeScope().toJson());
+}
+
+void TestSearchScopes::testParserExtractsScope()
+{
+    const bs::ParsedQuery parsed =
+        bs::QueryParser::parse(QStringLiteral("scope:work contract scope:ClientA"));
+    QCOMPARE(parsed.scope, QStringLiteral("clienta"));
+    QCOMPARE(parsed.cleanedQuery, QStringLiteral("contract"));
+}
+
+QTEST_MAIN(TestSearchScopes)
+#include "test_search_scopes.moc"
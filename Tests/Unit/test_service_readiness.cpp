@@ -0,0 +1,121 @@
+#include <QtTest/QtTest>
+
+#include "core/shared/service_readiness.h"
+
+class TestServiceReadiness : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testClosedIndexReportsOpeningIndex();
+    void testFirstCrawlReportsInitialCrawl();
+    void testCompletedIndexReportsServing();
+    void testStalledQueueIsDegradedEvenDuringInitialCrawl();
+    void testPausedQueueIsNotStalled();
+    void testUnreachableIndexerIsDegraded();
+    void testStateStrings();
+};
+
+namespace {
+
+bs::ReadinessInputs servingInputs()
+{
+    bs::ReadinessInputs inputs;
+    inputs.indexOpen = true;
+    inputs.indexerReachable = true;
+    inputs.initialIndexCompleted = true;
+    inputs.indexedItems = 100;
+    return inputs;
+}
+
+} // namespace
+
+void TestServiceReadiness::testClosedIndexReportsOpeningIndex()
+{
+    const bs::ReadinessReport report = bs::evaluateReadiness(bs::ReadinessInputs{});
+    QCOMPARE(report.state, bs::ReadinessState::OpeningIndex);
+    QVERIFY(!report.acceptingQueries);
+}
+
+void TestServiceReadiness::testFirstCrawlReportsInitialCrawl()
+{
+    bs::ReadinessInputs inputs = servingInputs();
+    inputs.initialIndexCompleted = false;
+    inputs.indexerActive = true;
+    inputs.msSinceIndexerProgress = 500;
+    bs::ReadinessReport report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::InitialCrawl);
+    QCOMPARE(report.reason, QStringLiteral("initial_crawl"));
+    QVERIFY(report.acceptingQueries);
+
+    // Nothing indexed and nothing queued yet still counts as the first crawl.
+    inputs.indexerActive = false;
+    inputs.indexedItems = 0;
+    report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::InitialCrawl);
+}
+
+void TestServiceReadiness::testCompletedIndexReportsServing()
+{
+    bs::ReadinessInputs inputs = servingInputs();
+    bs::ReadinessReport report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::Serving);
+    QCOMPARE(report.reason, QStringLiteral("idle"));
+
+    inputs.indexerActive = true;
+    report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::Serving);
+    QCOMPARE(report.reason, QStringLiteral("incremental_indexing"));
+
+    inputs.criticalFailures = 2;
+    report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::Degraded);
+    QCOMPARE(report.reason, QStringLiteral("critical_failures"));
+}
+
+void TestServiceReadiness::testStalledQueueIsDegradedEvenDuringInitialCrawl()
+{
+    bs::ReadinessInputs inputs = servingInputs();
+    inputs.initialIndexCompleted = false;
+    inputs.indexerActive = true;
+    inputs.stallThresholdMs = 1000;
+    inputs.msSinceIndexerProgress = 1000;
+    const bs::ReadinessReport report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::Degraded);
+    QCOMPARE(report.reason, QStringLiteral("indexer_stalled"));
+}
+
+void TestServiceReadiness::testPausedQueueIsNotStalled()
+{
+    bs::ReadinessInputs inputs = servingInputs();
+    inputs.initialIndexCompleted = false;
+    inputs.indexerActive = true;
+    inputs.indexerPaused = true;
+    inputs.stallThresholdMs = 1000;
+    inputs.msSinceIndexerProgress = 60000;
+    const bs::ReadinessReport report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::InitialCrawl);
+    QCOMPARE(report.reason, QStringLiteral("initial_crawl_paused"));
+}
+
+void TestServiceReadiness::testUnreachableIndexerIsDegraded()
+{
+    bs::ReadinessInputs inputs = servingInputs();
+    inputs.indexerReachable = false;
+    const bs::ReadinessReport report = bs::evaluateReadiness(inputs);
+    QCOMPARE(report.state, bs::ReadinessState::Degraded);
+    QCOMPARE(report.reason, QStringLiteral("indexer_unavailable"));
+    QVERIFY(report.acceptingQueries);
+}
+
+void TestServiceReadiness::testStateStrings()
+{
+    QCOMPARE(bs::readinessStateToString(bs::ReadinessState::OpeningIndex),
+             QStringLiteral("opening_index"));
+    QCOMPARE(bs::readinessStateToString(bs::ReadinessState::InitialCrawl),
+             QStringLiteral("initial_crawl"));
+    QCOMPARE(bs::readinessStateToString(bs::ReadinessState::Serving), QStringLiteral("serving"));
+    QCOMPARE(bs::readinessStateToString(bs::ReadinessState::Degraded), QStringLiteral("degraded"));
+}
+
+QTEST_MAIN(TestServiceReadiness)
+#include "test_service_readiness.moc"
@@ -0,0 +1,84 @@
+#include <QtTest/QtTest>
+
+#include "core/query/settings_panes.h"
+
+#include <QSet>
+
+class TestSettingsPanes : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testTableIsWellFormed();
+    void testNameMatch();
+    void testKeywordMatch();
+    void testPrivacyAnchors();
+    void testLimitsAndShortQueries();
+    void testFind();
+};
+
+void TestSettingsPanes::testTableIsWellFormed()
+{
+    QSet<QString> ids;
+    for (const bs::SettingsPane& pane : bs::SettingsPanes::all()) {
+        QVERIFY2(!ids.contains(pane.id), qPrintable(pane.id));
+        ids.insert(pane.id);
+        QVERIFY(!pane.name.isEmpty());
+        QVERIFY(pane.url.startsWith(QStringLiteral("x-apple.systempreferences:com.apple.")));
+    }
+    QVERIFY(ids.size() > 30);
+}
+
+void TestSettingsPanes::testNameMatch()
+{
+    const auto matches = bs::SettingsPanes::match(QStringLiteral("bluetooth"), 3);
+    QVERIFY(!matches.empty());
+    QCOMPARE(matches.at(0).pane.id, QStringLiteral("bluetooth"));
+    QCOMPARE(matches.at(0).score, 1.0);
+    QVERIFY(matches.at(0).matchedKeyword.isEmpty());
+
+    const auto displays = bs::SettingsPanes::match(QStringLiteral("displays"), 3);
+    QCOMPARE(displays.at(0).pane.url,
+             QStringLiteral("x-apple.systempreferences:com.apple.Displays-Settings.extension"));
+}
+
+void TestSettingsPanes::testKeywordMatch()
+{
+    const auto matches = bs::SettingsPanes::match(QStringLiteral("night shift"), 3);
+    QVERIFY(!matches.empty());
+    QCOMPARE(matches.at(0).pane.id, QStringLiteral("displays"));
+    QCOMPARE(matches.at(0).matchedKeyword, QStringLiteral("night shift"));
+    QVERIFY(matches.at(0).score < 1.0);
+
+    QCOMPARE(bs::SettingsPanes::match(QStringLiteral("Dark Mode"), 1).at(0).pane.id,
+             QStringLiteral("appearance"));
+}
+
+void TestSettingsPanes::testPrivacyAnchors()
+{
+    const auto matches = bs::SettingsPanes::match(QStringLiteral("full disk access"), 1);
+    QCOMPARE(matches.size(), size_t(1));
+    QCOMPARE(matches.at(0).pane.url,
+             QStringLiteral("x-apple.systempreferences:com.apple.preference.security"
+                            "?Privacy_AllFiles"));
+}
+
+void TestSettingsPanes::testLimitsAndShortQueries()
+{
+    QCOMPARE(bs::SettingsPanes::match(QStringLiteral("screen"), 2).size(), size_t(2));
+    QVERIFY(bs::SettingsPanes::match(QStringLiteral("screen"), 0).empty());
+    QVERIFY(bs::SettingsPanes::match(QStringLiteral("b"), 3).empty());
+    QVERIFY(bs::SettingsPanes::match(QStringLiteral("  "), 3).empty());
+    QVERIFY(bs::SettingsPanes::match(QStringLiteral("quarterly report"), 3).empty());
+}
+
+void TestSettingsPanes::testFind()
+{
+    const bs::SettingsPane* pane = bs::SettingsPanes::find(QStringLiteral("wifi"));
+    QVERIFY(pane != nullptr);
+    QCOMPARE(pane->name, QStringLiteral("Wi-Fi"));
+    QCOMPARE(pane->toJson().value(QStringLiteral("kind")).toString(), QStringLiteral("settings"));
+    QVERIFY(bs::SettingsPanes::find(QStringLiteral("nope")) == nullptr);
+}
+
+QTEST_MAIN(TestSettingsPanes)
+#include "test_settings_panes.moc"
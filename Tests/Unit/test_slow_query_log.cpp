@@ -0,0 +1,92 @@
+#include <QtTest/QtTest>
+#include "core/query/slow_query_log.h"
+
+#include <thread>
+
+class TestSlowQueryLog : public QObject {
+    Q_OBJECT
+
+private:
+    bs::SlowQueryLog::Entry makeEntry(const QString& query, double elapsedMs) const
+    {
+        bs::SlowQueryLog::Entry entry;
+        entry.timestamp = 1773102400.0;
+        entry.query = query;
+        entry.elapsedMs = elapsedMs;
+        entry.plan[QStringLiteral("queryMode")] = QStringLiteral("auto");
+        return entry;
+    }
+
+private slots:
+    void testBelowThresholdIsNotKept()
+    {
+        bs::SlowQueryLog log(bs::SlowQueryLogConfig{100, 10});
+        QVERIFY(!log.isSlow(99.9));
+        QVERIFY(log.isSlow(100.0));
+        QVERIFY(!log.record(makeEntry(QStringLiteral("fast"), 20.0)));
+        QVERIFY(log.record(makeEntry(QStringLiteral("slow"), 150.0)));
+        QCOMPARE(static_cast<int>(log.entries(10).size()), 1);
+        QCOMPARE(log.recordedCount(), uint64_t(1));
+    }
+
+    void testNewestFirstAndBounded()
+    {
+        bs::SlowQueryLog log(bs::SlowQueryLogConfig{10, 3});
+        for (int i = 0; i < 5; ++i) {
+            QVERIFY(log.record(makeEntry(QStringLiteral("q%1").arg(i), 50.0)));
+        }
+        const auto entries = log.entries(10);
+        QCOMPARE(static_cast<int>(entries.size()), 3);
+        QCOMPARE(entries.at(0).query, QStringLiteral("q4"));
+        QCOMPARE(entries.at(2).query, QStringLiteral("q2"));
+        QCOMPARE(static_cast<int>(log.entries(2).size()), 2);
+        QCOMPARE(log.recordedCount(), uint64_t(5));
+
+        const QJsonObject json = entries.at(0).toJson();
+        QCOMPARE(json.value(QStringLiteral("query")).toString(), QStringLiteral("q4"));
+        QCOMPARE(json.value(QStringLiteral("elapsedMs")).toDouble(), 50.0);
+        QCOMPARE(json.value(QStringLiteral("plan")).toObject()
+                     .value(QStringLiteral("queryMode")).toString(),
+                 QStringLiteral("auto"));
+
+        log.clear();
+        QVERIFY(log.entries(10).empty());
+        QCOMPARE(log.recordedCount(), uint64_t(0));
+    }
+
+    void testZeroThresholdDisables()
+    {
+        bs::SlowQueryLog log;
+        log.setThresholdMs(0);
+        QVERIFY(!log.isSlow(60000.0));
+        QVERIFY(!log.record(makeEntry(QStringLiteral("slow"), 60000.0)));
+        log.setThresholdMs(-5);
+        QCOMPARE(log.thresholdMs(), 0);
+    }
+
+    void testPhaseTimerKeepsOrder()
+    {
+        bs::SearchPhaseTimer timer;
+        timer.mark(QStringLiteral("parse"));
+        std::this_thread::sleep_for(std::chrono::milliseconds(5));
+        timer.mark(QStringLiteral("lexical"));
+        timer.mark(QStringLiteral("finish"));
+
+        const QJsonArray phases = timer.toJson();
+        QCOMPARE(phases.size(), 3);
+        QCOMPARE(phases.at(0).toObject().value(QStringLiteral("phase")).toString(),
+                 QStringLiteral("parse"));
+        QCOMPARE(phases.at(1).toObject().value(QStringLiteral("phase")).toString(),
+                 QStringLiteral("lexical"));
+        QVERIFY(phases.at(1).toObject().value(QStringLiteral("ms")).toDouble() >= 4.0);
+
+        double sum = 0.0;
+        for (const QJsonValue& phase : phases) {
+            sum += phase.toObject().value(QStringLiteral("ms")).toDouble();
+        }
+        QVERIFY(sum <= timer.elapsedMs());
+    }
+};
+
+QTEST_MAIN(TestSlowQueryLog)
+#include "test_slow_query_log.moc"
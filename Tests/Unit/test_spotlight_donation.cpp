@@ -0,0 +1,233 @@
+#include <QtTest/QtTest>
+
+#include "core/extraction/extraction_manager.h"
+#include "core/fs/path_rules.h"
+#include "core/index/sqlite_store.h"
+#include "core/indexing/chunker.h"
+#include "core/indexing/indexer.h"
+#include "core/indexing/spotlight_donation.h"
+
+#include <QDir>
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+namespace {
+
+constexpr double kNow = 1773100000.0;
+
+QJsonObject note(const QString& uniqueIdentifier, const QString& domain,
+                 const QString& title, const QString& text)
+{
+    QJsonObject attributeSet;
+    attributeSet[QStringLiteral("title")] = title;
+    attributeSet[QStringLiteral("textContent")] = text;
+    attributeSet[QStringLiteral("keywords")] = QJsonArray({QStringLiteral("errands")});
+    attributeSet[QStringLiteral("url")] = QStringLiteral("notes://open/") + uniqueIdentifier;
+
+    QJsonObject item;
+    item[QStringLiteral("uniqueIdentifier")] = uniqueIdentifier;
+    item[QStringLiteral("domainIdentifier")] = domain;
+    item[QStringLiteral("attributeSet")] = attributeSet;
+    return item;
+}
+
+QJsonObject donation(const QJsonArray& items)
+{
+    QJsonObject params;
+    params[QStringLiteral("bundleIdentifier")] = QStringLiteral("com.example.notes");
+    params[QStringLiteral("items")] = items;
+    return params;
+}
+
+std::vector<bs::DonatedItem> parseOk(const QJsonObject& params)
+{
+    QString error;
+    auto items = bs::SpotlightDonation::parseItems(params, kNow, &error);
+    if (!items.has_value()) {
+        qWarning() << "parseItems failed:" << error;
+        return {};
+    }
+    return items.value();
+}
+
+QString attribute(const bs::DonatedItem& item, const QString& name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == name) {
+            return attribute.value;
+        }
+    }
+    return {};
+}
+
+} // namespace
+
+class TestSpotlightDonation : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseItem();
+    void testRejectsInvalidPayloads();
+    void testParseRemoval();
+    void testDonateUpdateAndRemove();
+};
+
+void TestSpotlightDonation::testParseItem()
+{
+    QJsonObject item = note(QStringLiteral("list/42"), QStringLiteral("lists.home"),
+                            QStringLiteral("Groceries"), QStringLiteral("oat milk, lemons"));
+    QJsonObject set = item.value(QStringLiteral("attributeSet")).toObject();
+    set[QStringLiteral("displayName")] = QStringLiteral("Weekend groceries");
+    set[QStringLiteral("contentModificationDate")] = QStringLiteral("2026-03-01T10:00:00Z");
+    item[QStringLiteral("attributeSet")] = set;
+
+    const std::vector<bs::DonatedItem> items = parseOk(donation({item}));
+    QCOMPARE(items.size(), static_cast<size_t>(1));
+    const bs::DonatedItem& parsed = items.front();
+    QCOMPARE(parsed.path, QStringLiteral("donated://com.example.notes/list%2F42"));
+    QCOMPARE(parsed.parentPath, QStringLiteral("donated://com.example.notes"));
+    QCOMPARE(parsed.name, QStringLiteral("Weekend groceries"));
+    QCOMPARE(parsed.textContent, QStringLiteral("oat milk, lemons"));
+    QCOMPARE(attribute(parsed, QStringLiteral("title")), QStringLiteral("Groceries"));
+    QCOMPARE(attribute(parsed, QStringLiteral("keywords")), QStringLiteral("errands"));
+    QCOMPARE(attribute(parsed, QStringLiteral("domainIdentifier")), QStringLiteral("lists.home"));
+    QCOMPARE(attribute(parsed, QStringLiteral("url")), QStringLiteral("notes://open/list/42"));
+    QCOMPARE(parsed.modifiedAt, 1772359200.0);
+    QCOMPARE(parsed.createdAt, parsed.modifiedAt);
+    QVERIFY(!parsed.contentHash.isEmpty());
+
+    QVERIFY(bs::SpotlightDonation::isDonatedPath(parsed.path));
+    QVERIFY(!bs::SpotlightDonation::isDonatedPath(QStringLiteral("/Users/test/notes.txt")));
+    QCOMPARE(bs::SpotlightDonation::bundleIdentifier(parsed.path),
+             QStringLiteral("com.example.notes"));
+    QCOMPARE(bs::SpotlightDonation::uniqueIdentifier(parsed.path), QStringLiteral("list/42"));
+    QVERIFY(bs::SpotlightDonation::uniqueIdentifier(parsed.parentPath).isEmpty());
+
+    // Without a modification date the donation is dated now.
+    const auto undated = parseOk(donation({note(QStringLiteral("1"), QString(),
+                                                QStringLiteral("Todo"), QString())}));
+    QCOMPARE(undated.front().modifiedAt, kNow);
+}
+
+void TestSpotlightDonation::testRejectsInvalidPayloads()
+{
+    QJsonObject missingTitle = note(QStringLiteral("1"), QString(), QString(), QStringLiteral("x"));
+    QJsonObject badUrl = note(QStringLiteral("1"), QString(), QStringLiteral("t"), QString());
+    QJsonObject set = badUrl.value(QStringLiteral("attributeSet")).toObject();
+    set[QStringLiteral("url")] = QStringLiteral("no scheme here");
+    badUrl[QStringLiteral("attributeSet")] = set;
+    QJsonObject badDate = note(QStringLiteral("1"), QString(), QStringLiteral("t"), QString());
+    set = badDate.value(QStringLiteral("attributeSet")).toObject();
+    set[QStringLiteral("contentCreationDate")] = QStringLiteral("last tuesday");
+    badDate[QStringLiteral("attributeSet")] = set;
+    const QJsonObject ok = note(QStringLiteral("1"), QString(), QStringLiteral("t"), QString());
+
+    QJsonObject noBundle = donation({ok});
+    noBundle.remove(QStringLiteral("bundleIdentifier"));
+    QJsonObject badBundle = donation({ok});
+    badBundle[QStringLiteral("bundleIdentifier")] = QStringLiteral("com.example/../x");
+
+    const QList<QJsonObject> invalid = {
+        noBundle,
+        badBundle,
+        donation({}),
+        donation({note(QString(), QString(), QStringLiteral("t"), QString())}),
+        donation({missingTitle}),
+        donation({badUrl}),
+        donation({badDate}),
+        donation({ok, ok}),
+    };
+    for (const QJsonObject& params : invalid) {
+        QString error;
+        QVERIFY(!bs::SpotlightDonation::parseItems(params, kNow, &error).has_value());
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestSpotlightDonation::testParseRemoval()
+{
+    QJsonObject params;
+    params[QStringLiteral("bundleIdentifier")] = QStringLiteral("com.example.notes");
+    QString error;
+    QVERIFY(!bs::SpotlightDonation::parseRemoval(params, &error).has_value());
+
+    params[QStringLiteral("domainIdentifiers")] = QJsonArray({QStringLiteral("lists")});
+    const auto removal = bs::SpotlightDonation::parseRemoval(params, &error);
+    QVERIFY(removal.has_value());
+    QCOMPARE(removal->domainIdentifiers, QStringList({QStringLiteral("lists")}));
+    QVERIFY(!removal->all);
+
+    params[QStringLiteral("all")] = true;
+    QVERIFY(!bs::SpotlightDonation::parseRemoval(params, &error).has_value());
+
+    QVERIFY(bs::SpotlightDonation::isInDomain(QStringLiteral("lists.home"),
+                                              QStringLiteral("lists")));
+    QVERIFY(bs::SpotlightDonation::isInDomain(QStringLiteral("lists"), QStringLiteral("lists")));
+    QVERIFY(!bs::SpotlightDonation::isInDomain(QStringLiteral("listsold"),
+                                               QStringLiteral("lists")));
+}
+
+void TestSpotlightDonation::testDonateUpdateAndRemove()
+{
+    QTemporaryDir tempDir;
+    QVERIFY(tempDir.isValid());
+    auto storeOpt =
+        bs::SQLiteStore::open(QDir(tempDir.path()).filePath(QStringLiteral("index.db")));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    bs::ExtractionManager extractor;
+    bs::PathRules pathRules;
+    bs::Chunker chunker;
+    bs::Indexer indexer(store, extractor, pathRules, chunker);
+
+    const auto items = parseOk(donation({
+        note(QStringLiteral("1"), QStringLiteral("lists.home"), QStringLiteral("Groceries"),
+             QStringLiteral("oat milk and lemons")),
+        note(QStringLiteral("2"), QStringLiteral("journal"), QStringLiteral("Trip"),
+             QStringLiteral("lisbon itinerary")),
+    }));
+    QCOMPARE(items.size(), static_cast<size_t>(2));
+    for (const bs::DonatedItem& item : items) {
+        QCOMPARE(indexer.applyDonation(item).status, bs::IndexResult::Status::Indexed);
+    }
+    QCOMPARE(indexer.applyDonation(items.front()).status, bs::IndexResult::Status::Skipped);
+
+    const auto hits = store.searchFts5(QStringLiteral("lemons"));
+    QCOMPARE(hits.size(), static_cast<size_t>(1));
+    const auto groceries = store.getItemById(hits.front().fileId);
+    QVERIFY(groceries.has_value());
+    QCOMPARE(groceries->path, items.front().path);
+    QCOMPARE(store.getItemAttributeValue(groceries->id, QStringLiteral("url")),
+             QStringLiteral("notes://open/1"));
+    QVERIFY(!store.searchFts5(QStringLiteral("errands")).empty());
+
+    // A changed donation replaces the old text.
+    const auto updated = parseOk(donation({note(QStringLiteral("1"), QStringLiteral("lists.home"),
+                                                QStringLiteral("Groceries"),
+                                                QStringLiteral("coffee beans"))}));
+    QCOMPARE(indexer.applyDonation(updated.front()).status, bs::IndexResult::Status::Indexed);
+    QVERIFY(store.searchFts5(QStringLiteral("lemons")).empty());
+    QCOMPARE(store.searchFts5(QStringLiteral("coffee")).size(), static_cast<size_t>(1));
+
+    bs::DonationRemoval byDomain;
+    byDomain.bundleIdentifier = QStringLiteral("com.example.notes");
+    byDomain.domainIdentifiers = {QStringLiteral("lists")};
+    QCOMPARE(indexer.applyDonationRemoval(byDomain), static_cast<size_t>(1));
+    QVERIFY(!store.getItemByPath(items.front().path).has_value());
+    QVERIFY(store.searchFts5(QStringLiteral("coffee")).empty());
+
+    bs::DonationRemoval otherApp;
+    otherApp.bundleIdentifier = QStringLiteral("com.example.other");
+    otherApp.all = true;
+    QCOMPARE(indexer.applyDonationRemoval(otherApp), static_cast<size_t>(0));
+
+    bs::DonationRemoval byIdentifier;
+    byIdentifier.bundleIdentifier = QStringLiteral("com.example.notes");
+    byIdentifier.uniqueIdentifiers = {QStringLiteral("2"), QStringLiteral("missing")};
+    QCOMPARE(indexer.applyDonationRemoval(byIdentifier), static_cast<size_t>(1));
+    QCOMPARE(store.itemCount(), static_cast<int64_t>(0));
+}
+
+QTEST_MAIN(TestSpotlightDonation)
+#include "test_spotlight_donation.moc"
@@ -0,0 +1,60 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QSet>
+
+class TestSpotlightMetadata : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testFieldNames();
+    void testNormalize();
+    void testReadMissingPath();
+};
+
+void TestSpotlightMetadata::testFieldNames()
+{
+    QSet<QString> names;
+    for (const bs::SpotlightMetadata::Field& field : bs::SpotlightMetadata::fields()) {
+        QVERIFY(field.mdKey.startsWith(QStringLiteral("kMDItem")));
+        // Stored name is the key without its prefix, lower camel case.
+        const QString stripped = field.mdKey.mid(7);
+        QCOMPARE(field.name, stripped.left(1).toLower() + stripped.mid(1));
+        names.insert(field.name);
+    }
+    QVERIFY(names.contains(QStringLiteral("whereFroms")));
+    QVERIFY(names.contains(QStringLiteral("authors")));
+    QVERIFY(names.contains(QStringLiteral("contentType")));
+}
+
+void TestSpotlightMetadata::testNormalize()
+{
+    std::vector<bs::ItemAttribute> raw = {
+        {QStringLiteral("whereFroms"), QStringLiteral("  https://example.com/a.zip \n"), true},
+        {QStringLiteral("whereFroms"), QStringLiteral("https://example.com/a.zip"), true},
+        {QStringLiteral("whereFroms"), QStringLiteral("   "), true},
+        {QStringLiteral("authors"), QStringLiteral("https://example.com/a.zip"), true},
+        {QStringLiteral("title"), QString(2100, QLatin1Char('t')), true},
+    };
+    for (int i = 0; i < 20; ++i) {
+        raw.push_back({QStringLiteral("keywords"), QStringLiteral("k%1").arg(i), true});
+    }
+
+    const std::vector<bs::ItemAttribute> out = bs::SpotlightMetadata::normalize(raw);
+    QCOMPARE(out.size(), size_t(3 + 16));
+    QCOMPARE(out[0].value, QStringLiteral("https://example.com/a.zip"));
+    // The same value under another name is a different attribute.
+    QCOMPARE(out[1].name, QStringLiteral("authors"));
+    QCOMPARE(out[2].value.size(), 2048);
+    QCOMPARE(out.back().value, QStringLiteral("k15"));
+}
+
+void TestSpotlightMetadata::testReadMissingPath()
+{
+    QVERIFY(!bs::SpotlightMetadata::read(QStringLiteral("/nonexistent/bs-spotlight-probe.txt"))
+                 .has_value());
+}
+
+QTEST_MAIN(TestSpotlightMetadata)
+#include "test_spotlight_metadata.moc"
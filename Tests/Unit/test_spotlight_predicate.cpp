@@ -0,0 +1,192 @@
+#include <QtTest/QtTest>
+
+#include "core/query/spotlight_predicate.h"
+
+#include <QJsonArray>
+
+namespace {
+
+QDateTime fixedNow()
+{
+    return QDateTime(QDate(2026, 3, 10), QTime(15, 30, 0), QTimeZone::UTC);
+}
+
+double utcEpoch(int year, int month, int day)
+{
+    return static_cast<double>(
+        QDateTime(QDate(year, month, day), QTime(0, 0, 0), QTimeZone::UTC).toSecsSinceEpoch());
+}
+
+bs::SpotlightQuery translateOk(const QString& predicate)
+{
+    QString error;
+    const auto query = bs::SpotlightPredicate::translate(predicate, fixedNow(), &error);
+    if (!query.has_value()) {
+        qWarning() << "translate failed:" << predicate << error;
+        return {QStringLiteral("<failed>"), {}};
+    }
+    return query.value();
+}
+
+} // namespace
+
+class TestSpotlightPredicate : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testTextTypeAndDate();
+    void testFinderStyleOr();
+    void testContentTypeUnionAndIntersection();
+    void testNamePatternsBecomeFileTypes();
+    void testTagsPathsAndSizes();
+    void testTimeFunctions();
+    void testFilterOnlyPredicate();
+    void testRejectsUntranslatable();
+};
+
+void TestSpotlightPredicate::testTextTypeAndDate()
+{
+    const bs::SpotlightQuery query = translateOk(
+        QStringLiteral("kMDItemContentType == \"com.adobe.pdf\" && "
+                       "kMDItemTextContent == \"budget*\"cd AND "
+                       "kMDItemFSContentChangeDate >= $time.today(-7)"));
+    QCOMPARE(query.text, QStringLiteral("budget"));
+    QCOMPARE(query.filters.value(QStringLiteral("fileTypes")).toArray(),
+             QJsonArray({QStringLiteral("pdf")}));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedAfter")).toDouble(),
+             utcEpoch(2026, 3, 3));
+    QVERIFY(!query.filters.contains(QStringLiteral("modifiedBefore")));
+}
+
+void TestSpotlightPredicate::testFinderStyleOr()
+{
+    const bs::SpotlightQuery query = translateOk(
+        QStringLiteral("(kMDItemDisplayName == \"quarterly report*\"cdw || "
+                       "kMDItemTextContent == \"Quarterly Report*\"cdw)"));
+    QCOMPARE(query.text, QStringLiteral("quarterly report"));
+    QVERIFY(query.filters.isEmpty());
+
+    QCOMPARE(translateOk(QStringLiteral("* == \"notes\" && kMDItemAuthors LIKE[cd] \"alice\""))
+                 .text,
+             QStringLiteral("notes alice"));
+}
+
+void TestSpotlightPredicate::testContentTypeUnionAndIntersection()
+{
+    bs::SpotlightQuery query = translateOk(
+        QStringLiteral("kMDItemContentType == 'com.adobe.pdf' || "
+                       "kMDItemContentType == 'net.daringfireball.markdown'"));
+    QCOMPARE(query.filters.value(QStringLiteral("fileTypes")).toArray(),
+             QJsonArray({QStringLiteral("pdf"), QStringLiteral("md"),
+                         QStringLiteral("markdown")}));
+
+    query = translateOk(QStringLiteral("kMDItemContentTypeTree == \"public.image\" && "
+                                       "kMDItemContentType == \"public.jpeg\""));
+    QCOMPARE(query.filters.value(QStringLiteral("fileTypes")).toArray(),
+             QJsonArray({QStringLiteral("jpg"), QStringLiteral("jpeg")}));
+
+    // public.item matches everything, so it adds no filter.
+    query = translateOk(QStringLiteral("kMDItemContentTypeTree == \"public.item\""));
+    QVERIFY(query.filters.isEmpty());
+}
+
+void TestSpotlightPredicate::testNamePatternsBecomeFileTypes()
+{
+    bs::SpotlightQuery query = translateOk(QStringLiteral("kMDItemFSName == \"*.csv\"c"));
+    QVERIFY(query.text.isEmpty());
+    QCOMPARE(query.filters.value(QStringLiteral("fileTypes")).toArray(),
+             QJsonArray({QStringLiteral("csv")}));
+
+    query = translateOk(QStringLiteral("kMDItemDisplayName ENDSWITH \".TXT\""));
+    QCOMPARE(query.filters.value(QStringLiteral("fileTypes")).toArray(),
+             QJsonArray({QStringLiteral("txt")}));
+}
+
+void TestSpotlightPredicate::testTagsPathsAndSizes()
+{
+    const bs::SpotlightQuery query = translateOk(
+        QStringLiteral("kMDItemUserTags == \"Work\" && kMDItemPath == \"/Users/test/*\" && "
+                       "kMDItemPath BEGINSWITH \"/Users/test/Docs/\" && "
+                       "!(kMDItemPath == \"/Users/test/Docs/Archive/*\") && "
+                       "kMDItemFSSize > 1000 && kMDItemFSSize <= 5000000"));
+    QCOMPARE(query.filters.value(QStringLiteral("tags")).toArray(),
+             QJsonArray({QStringLiteral("Work")}));
+    QCOMPARE(query.filters.value(QStringLiteral("includePaths")).toArray(),
+             QJsonArray({QStringLiteral("/Users/test/Docs")}));
+    QCOMPARE(query.filters.value(QStringLiteral("excludePaths")).toArray(),
+             QJsonArray({QStringLiteral("/Users/test/Docs/Archive")}));
+    QCOMPARE(query.filters.value(QStringLiteral("minSize")).toInteger(), 1001);
+    QCOMPARE(query.filters.value(QStringLiteral("maxSize")).toInteger(), 5000000);
+
+    const bs::SpotlightQuery range =
+        translateOk(QStringLiteral("InRange(kMDItemLogicalSize, 10, 20)"));
+    QCOMPARE(range.filters.value(QStringLiteral("minSize")).toInteger(), 10);
+    QCOMPARE(range.filters.value(QStringLiteral("maxSize")).toInteger(), 20);
+}
+
+void TestSpotlightPredicate::testTimeFunctions()
+{
+    bs::SpotlightQuery query = translateOk(QStringLiteral(
+        "InRange(kMDItemContentModificationDate, $time.iso(2026-01-01T00:00:00Z), "
+        "$time.this_month)"));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedAfter")).toDouble(),
+             utcEpoch(2026, 1, 1));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedBefore")).toDouble(),
+             utcEpoch(2026, 3, 1));
+
+    query = translateOk(QStringLiteral("kMDItemFSContentChangeDate > $time.now(-3600) && "
+                                       "kMDItemFSContentChangeDate < $time.yesterday(1)"));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedAfter")).toDouble(),
+             static_cast<double>(fixedNow().toSecsSinceEpoch() - 3600));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedBefore")).toDouble(),
+             utcEpoch(2026, 3, 10));
+
+    query = translateOk(QStringLiteral("kMDItemFSContentChangeDate >= $time.this_year(-1)"));
+    QCOMPARE(query.filters.value(QStringLiteral("modifiedAfter")).toDouble(),
+             utcEpoch(2025, 1, 1));
+}
+
+void TestSpotlightPredicate::testFilterOnlyPredicate()
+{
+    const bs::SpotlightQuery query =
+        translateOk(QStringLiteral("kMDItemDisplayName == \"*\" && kMDItemUserTags == \"Red\""));
+    QVERIFY(query.text.isEmpty());
+    QCOMPARE(query.filters.keys(), QStringList({QStringLiteral("tags")}));
+}
+
+void TestSpotlightPredicate::testRejectsUntranslatable()
+{
+    const QStringList invalid = {
+        QString(),
+        QStringLiteral("kMDItemLastUsedDate > $time.today(-1)"),
+        QStringLiteral("kMDItemContentType == \"com.example.unknown\""),
+        QStringLiteral("kMDItemTextContent == \"a\" || kMDItemTextContent == \"b\""),
+        QStringLiteral("kMDItemTextContent == \"a\" || kMDItemUserTags == \"Red\""),
+        QStringLiteral("!(kMDItemTextContent == \"secret\")"),
+        QStringLiteral("kMDItemTextContent != \"secret\""),
+        QStringLiteral("kMDItemContentType == \"com.adobe.pdf\" && "
+                       "kMDItemContentType == \"public.png\""),
+        QStringLiteral("kMDItemPath == \"/a/*\" && kMDItemPath == \"/b/*\""),
+        QStringLiteral("kMDItemPath == \"relative/*\""),
+        QStringLiteral("kMDItemPath == \"/a/*/b\""),
+        QStringLiteral("kMDItemUserTags == \"*\""),
+        QStringLiteral("kMDItemFSContentChangeDate == $time.today"),
+        QStringLiteral("kMDItemFSContentChangeDate > 12345"),
+        QStringLiteral("kMDItemFSContentChangeDate > $time.fortnight"),
+        QStringLiteral("kMDItemFSSize > \"big\""),
+        QStringLiteral("kMDItemTextContent == \"unterminated"),
+        QStringLiteral("(kMDItemTextContent == \"a\""),
+        QStringLiteral("kMDItemTextContent \"a\""),
+        QStringLiteral("kMDItemTextContent == \"a\" &&"),
+        QStringLiteral("kMDItemTextContent == \"a\" # comment"),
+    };
+    for (const QString& predicate : invalid) {
+        QString error;
+        QVERIFY2(!bs::SpotlightPredicate::translate(predicate, fixedNow(), &error).has_value(),
+                 qPrintable(predicate));
+        QVERIFY2(!error.isEmpty(), qPrintable(predicate));
+    }
+}
+
+QTEST_MAIN(TestSpotlightPredicate)
+#include "test_spotlight_predicate.moc"
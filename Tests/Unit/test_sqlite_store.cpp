@@ -26,6 +26,7 @@ private slots:
     void testBm25FileNameBoost();
     void testBm25WeightsCanBeApplied();
     void testDeleteAll();
+    void testTombstones();
 };
 
 void TestSQLiteStore::testOpenCreatesDatabase()
@@ -481,5 +482,42 @@ void TestSQLiteStore::testDeleteAll()
     QVERIFY(store->searchFts5(QStringLiteral("xyzzy123")).empty());
 }
 
+void TestSQLiteStore::testTombstones()
+{
+    QTemporaryDir dir;
+    const QString dbPath = dir.path() + "/test.db";
+    auto store = bs::SQLiteStore::open(dbPath);
+    QVERIFY(store.has_value());
+    QCOMPARE(store->lastTombstoneSeq(), int64_t(0));
+
+    auto keep = store->upsertItem(QStringLiteral("/test/keep.txt"), QStringLiteral("keep.txt"),
+                                  QStringLiteral("txt"), bs::ItemKind::Text, 10, 1.0, 2.0);
+    auto gone = store->upsertItem(QStringLiteral("/test/gone.txt"), QStringLiteral("gone.txt"),
+                                  QStringLiteral("txt"), bs::ItemKind::Text, 10, 1.0, 2.0);
+    QVERIFY(keep.has_value() && gone.has_value());
+    QCOMPARE(store->getItemIdsIndexedSince(0.0), std::vector<int64_t>({*keep, *gone}));
+    QVERIFY(store->getItemIdsIndexedSince(4.0e9).empty());
+
+    QVERIFY(store->deleteItemByPath(QStringLiteral("/test/gone.txt")));
+    const auto tombstones = store->getTombstonesAfter(0, 10);
+    QCOMPARE(tombstones.size(), size_t(1));
+    QCOMPARE(tombstones[0].itemId, *gone);
+    QCOMPARE(store->lastTombstoneSeq(), tombstones[0].seq);
+    QVERIFY(store->getTombstonesAfter(tombstones[0].seq, 10).empty());
+
+    // Nothing is old enough yet; pruning everything keeps the sequence.
+    QCOMPARE(store->pruneTombstones(1.0), int64_t(0));
+    QCOMPARE(store->prunedTombstoneSeq(), int64_t(0));
+    QCOMPARE(store->pruneTombstones(4.0e9), int64_t(1));
+    QCOMPARE(store->prunedTombstoneSeq(), tombstones[0].seq);
+    QCOMPARE(store->lastTombstoneSeq(), tombstones[0].seq);
+    QVERIFY(store->getTombstonesAfter(0, 10).empty());
+
+    // A rebuild prunes what it deleted.
+    QVERIFY(store->deleteAll());
+    QVERIFY(store->getTombstonesAfter(0, 10).empty());
+    QCOMPARE(store->prunedTombstoneSeq(), tombstones[0].seq + 1);
+}
+
 QTEST_MAIN(TestSQLiteStore)
 #include "test_sqlite_store.moc"
@@ -4,9 +4,12 @@
 #include "core/shared/chunk.h"
 #include "core/shared/search_options.h"
 
+#include <QDateTime>
 #include <QFileInfo>
 #include <QTemporaryDir>
 
+#include <cstdlib>
+#include <limits>
 #include <optional>
 #include <vector>
 
@@ -56,6 +59,19 @@ private slots:
     void testFilteredFts5SearchOptions();
     void testFilteredNameSearchOptions();
     void testFeedbackAggregationBatchAndMaintenance();
+    void testRecentActionsAndCounts();
+    void testSuggestByNamePrefixOrdersByOpenCount();
+    void testCompletePathsPagesOpenedFirst();
+    void testExportMatchesPagesWholeItems();
+    void testGetItemsUnderPathStaysInSubtree();
+    void testGetItemsInIdRange();
+    void testGetItemContentTruncates();
+    void testItemAttributesAreSearchableAndSurviveRechunking();
+    void testFinderTagsFilterAndCount();
+    void testCompactClearsPendingDeletes();
+    void testMetricsCounts();
+    void testBreakdownDirectory();
+    void testSizeBreakdown();
 };
 
 void TestSQLiteStoreExtended::testFilteredFts5SearchOptions()
@@ -234,5 +250,564 @@ void TestSQLiteStoreExtended::testFeedbackAggregationBatchAndMaintenance()
     QVERIFY(store.vacuum());
 }
 
+void TestSQLiteStoreExtended::testRecentActionsAndCounts()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/actions.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const auto idA = insertTextFixture(store, QStringLiteral("/workspace/docs/a.md"),
+                                       QStringLiteral("alpha"), 100, 200.0);
+    const auto idB = insertTextFixture(store, QStringLiteral("/workspace/docs/b.md"),
+                                       QStringLiteral("beta"), 100, 200.0);
+    QVERIFY(idA.has_value());
+    QVERIFY(idB.has_value());
+
+    QVERIFY(store.recordFeedback(idA.value(), QStringLiteral("open"), QStringLiteral("alpha"), 0));
+    QVERIFY(store.recordFeedback(idA.value(), QStringLiteral("reveal"), QStringLiteral("alpha"), 1));
+    QVERIFY(store.recordFeedback(idB.value(), QStringLiteral("copyPath"), QStringLiteral("beta"), 2));
+    QVERIFY(store.recordFeedback(idA.value(), QStringLiteral("copyPath"), QStringLiteral("al"), 0));
+
+    // Same-second rows keep insertion order, newest first.
+    const auto recent = store.getRecentActions(10);
+    QCOMPARE(static_cast<int>(recent.size()), 4);
+    QCOMPARE(recent.at(0).action, QStringLiteral("copyPath"));
+    QCOMPARE(recent.at(0).itemId, idA.value());
+    QCOMPARE(recent.at(0).query, QStringLiteral("al"));
+    QCOMPARE(recent.at(0).path, QStringLiteral("/workspace/docs/a.md"));
+    QCOMPARE(recent.at(0).name, QStringLiteral("a.md"));
+    QCOMPARE(recent.at(3).action, QStringLiteral("open"));
+    QVERIFY(recent.at(0).id > recent.at(1).id);
+
+    QCOMPARE(static_cast<int>(store.getRecentActions(2).size()), 2);
+    const auto copies = store.getRecentActions(10, {QStringLiteral("copyPath")});
+    QCOMPARE(static_cast<int>(copies.size()), 2);
+    const auto onB = store.getRecentActions(10, {}, idB.value());
+    QCOMPARE(static_cast<int>(onB.size()), 1);
+    QCOMPARE(onB.front().position, 2);
+
+    const auto single = store.getAction(recent.at(1).id);
+    QVERIFY(single.has_value());
+    QCOMPARE(single->action, QStringLiteral("copyPath"));
+    QCOMPARE(single->itemId, idB.value());
+    QVERIFY(!store.getAction(999999).has_value());
+
+    const auto counts = store.getActionCountsBatch({idA.value(), idB.value()}, 0.0);
+    QCOMPARE(counts.at(idA.value()).reveals, 1);
+    QCOMPARE(counts.at(idA.value()).copies, 1);
+    QCOMPARE(counts.at(idB.value()).reveals, 0);
+    QCOMPARE(counts.at(idB.value()).copies, 1);
+    QVERIFY(counts.at(idA.value()).lastActedAt > 0.0);
+
+    // Nothing after the cutoff.
+    const double future = static_cast<double>(QDateTime::currentSecsSinceEpoch()) + 3600.0;
+    QVERIFY(store.getActionCountsBatch({idA.value()}, future).empty());
+
+    // Actions on items that left the index are not listed.
+    QVERIFY(store.deleteItemByPath(QStringLiteral("/workspace/docs/b.md")));
+    QCOMPARE(static_cast<int>(store.getRecentActions(10).size()), 2);
+}
+
+void TestSQLiteStoreExtended::testSuggestByNamePrefixOrdersByOpenCount()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString dbPath = dir.path() + QStringLiteral("/suggest.db");
+
+    auto storeOpt = bs::SQLiteStore::open(dbPath);
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const auto shortId = insertTextFixture(
+        store, QStringLiteral("/workspace/docs/invoice.md"),
+        QStringLiteral("invoice"), /*size=*/100, /*modifiedAt=*/200.0);
+    const auto openedId = insertTextFixture(
+        store, QStringLiteral("/workspace/docs/invoice-2026-march.md"),
+        QStringLiteral("invoice march"), /*size=*/100, /*modifiedAt=*/200.0);
+    const auto literalId = insertTextFixture(
+        store, QStringLiteral("/workspace/docs/in_progress.md"),
+        QStringLiteral("in progress"), /*size=*/100, /*modifiedAt=*/200.0);
+    const auto unrelatedId = insertTextFixture(
+        store, QStringLiteral("/workspace/docs/receipt.md"),
+        QStringLiteral("receipt"), /*size=*/100, /*modifiedAt=*/200.0);
+    QVERIFY(shortId.has_value());
+    QVERIFY(openedId.has_value());
+    QVERIFY(literalId.has_value());
+    QVERIFY(unrelatedId.has_value());
+
+    QVERIFY(store.incrementFrequency(openedId.value()));
+
+    const auto hits = store.suggestByNamePrefix(QStringLiteral("INV"), 10);
+    QCOMPARE(static_cast<int>(hits.size()), 2);
+    QCOMPARE(hits.at(0).fileId, openedId.value());
+    QCOMPARE(hits.at(1).fileId, shortId.value());
+
+    // '_' must match literally rather than as a LIKE wildcard.
+    const auto literalHits = store.suggestByNamePrefix(QStringLiteral("in_"), 10);
+    QCOMPARE(static_cast<int>(literalHits.size()), 1);
+    QCOMPARE(literalHits.front().fileId, literalId.value());
+
+    QVERIFY(store.suggestByNamePrefix(QStringLiteral("   "), 10).empty());
+}
+
+void TestSQLiteStoreExtended::testCompletePathsPagesOpenedFirst()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/complete.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const QStringList paths = {
+        QStringLiteral("/workspace/docs/a.md"),
+        QStringLiteral("/workspace/docs/b.md"),
+        QStringLiteral("/workspace/docs/c.md"),
+        QStringLiteral("/workspace/docs-old/d.md"),
+        QStringLiteral("/workspace/src/core/e.cpp"),
+    };
+    std::vector<int64_t> ids;
+    for (const QString& path : paths) {
+        const auto id = insertTextFixture(store, path, QStringLiteral("x"), /*size=*/1,
+                                          /*modifiedAt=*/200.0);
+        QVERIFY(id.has_value());
+        ids.push_back(id.value());
+    }
+    QVERIFY(store.incrementFrequency(ids.at(2)));
+
+    // Page through "/workspace/docs" two at a time: the opened c.md leads,
+    // the rest follow in byte order ('-' sorts before '/') without
+    // repeating it.
+    QStringList walked;
+    bs::SQLiteStore::PathCompletionCursor cursor;
+    for (int page = 0; page < 10; ++page) {
+        const auto result = store.completePaths(QStringLiteral("/workspace/docs"), 2, cursor);
+        for (const auto& candidate : result.candidates) {
+            walked.append(candidate.path);
+        }
+        if (!result.next.has_value()) {
+            break;
+        }
+        cursor = result.next.value();
+    }
+    QCOMPARE(walked, (QStringList{paths.at(2), paths.at(3), paths.at(0), paths.at(1)}));
+
+    const auto first = store.completePaths(QStringLiteral("/workspace/docs/"), 10);
+    QCOMPARE(static_cast<int>(first.candidates.size()), 3);
+    QCOMPARE(first.candidates.front().openCount, 1);
+    QVERIFY(!first.next.has_value());
+
+    // Fragment with a slash matches at a component boundary, any depth.
+    const auto fragment = store.completePaths(QStringLiteral("SRC/co"), 10);
+    QCOMPARE(static_cast<int>(fragment.candidates.size()), 1);
+    QCOMPARE(fragment.candidates.front().path, paths.at(4));
+
+    // No slash: name prefix.
+    const auto byName = store.completePaths(QStringLiteral("d."), 10);
+    QCOMPARE(static_cast<int>(byName.candidates.size()), 1);
+    QCOMPARE(byName.candidates.front().path, paths.at(3));
+
+    QCOMPARE(static_cast<int>(store.completePaths(QString(), 100).candidates.size()),
+             paths.size());
+}
+
+void TestSQLiteStoreExtended::testExportMatchesPagesWholeItems()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/export.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const auto contentId = insertTextFixture(store, QStringLiteral("/workspace/a.md"),
+                                             QStringLiteral("ledgerzeta totals"), 10, 200.0);
+    const auto nameId = insertTextFixture(store, QStringLiteral("/workspace/ledgerzeta-notes.md"),
+                                          QStringLiteral("unrelated"), 10, 200.0);
+    const auto oldId = insertTextFixture(store, QStringLiteral("/workspace/old.md"),
+                                         QStringLiteral("ledgerzeta archive"), 10, 50.0);
+    QVERIFY(insertTextFixture(store, QStringLiteral("/workspace/miss.md"),
+                              QStringLiteral("nothing here"), 10, 200.0).has_value());
+    QVERIFY(contentId.has_value());
+    QVERIFY(nameId.has_value());
+    QVERIFY(oldId.has_value());
+
+    // Three matching chunks for one item must still come out as one row.
+    const QString multiPath = QStringLiteral("/workspace/multi.txt");
+    const auto multiId = store.upsertItem(multiPath, QStringLiteral("multi.txt"),
+                                          QStringLiteral("txt"), bs::ItemKind::Text, 30,
+                                          190.0, 200.0, QString(), QStringLiteral("normal"),
+                                          QStringLiteral("/workspace"));
+    QVERIFY(multiId.has_value());
+    std::vector<bs::Chunk> chunks;
+    for (int i = 0; i < 3; ++i) {
+        bs::Chunk chunk;
+        chunk.chunkId = bs::computeChunkId(multiPath, i);
+        chunk.filePath = multiPath;
+        chunk.chunkIndex = i;
+        chunk.content = QStringLiteral("ledgerzeta part %1").arg(i);
+        chunk.byteOffset = i * 10;
+        chunks.push_back(chunk);
+    }
+    QVERIFY(store.insertChunks(multiId.value(), QStringLiteral("multi.txt"), multiPath, chunks));
+
+    QList<int64_t> walked;
+    int64_t after = 0;
+    for (int page = 0; page < 10; ++page) {
+        const auto result = store.exportMatches(QStringLiteral("ledgerzeta"), false, {}, 2, after);
+        QVERIFY(static_cast<int>(result.rows.size()) <= 2);
+        for (const auto& row : result.rows) {
+            walked.append(row.fileId);
+            if (row.fileId == nameId.value()) {
+                QVERIFY(row.matchedFields.contains(QStringLiteral("name")));
+                QVERIFY(!row.matchedFields.contains(QStringLiteral("content")));
+            }
+            if (row.fileId == contentId.value()) {
+                QCOMPARE(row.matchedFields, QStringList{QStringLiteral("content")});
+                QCOMPARE(row.createdAt, 190.0);
+            }
+        }
+        if (!result.nextAfterRowid.has_value()) {
+            break;
+        }
+        after = result.nextAfterRowid.value();
+    }
+    QCOMPARE(walked, (QList<int64_t>{contentId.value(), nameId.value(), oldId.value(),
+                                     multiId.value()}));
+
+    bs::SearchOptions recent;
+    recent.modifiedAfter = 100.0;
+    const auto filtered = store.exportMatches(QStringLiteral("ledgerzeta"), false, recent, 100);
+    QCOMPARE(static_cast<int>(filtered.rows.size()), 3);
+    QVERIFY(!filtered.nextAfterRowid.has_value());
+}
+
+void TestSQLiteStoreExtended::testGetItemsUnderPathStaysInSubtree()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/subtree.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    for (const char* path : {"/work/foo", "/work/foo/a.md", "/work/foo/deep/b.md",
+                             "/work/foo-old/c.md", "/work/foo.md", "/work/fo/d.md"}) {
+        QVERIFY(insertTextFixture(store, QString::fromLatin1(path), QStringLiteral("x"), 1,
+                                  100.0).has_value());
+    }
+
+    QStringList paths;
+    for (const auto& row : store.getItemsUnderPath(QStringLiteral("/work/foo/"))) {
+        paths.append(row.path);
+    }
+    QCOMPARE(paths, (QStringList{QStringLiteral("/work/foo"), QStringLiteral("/work/foo/a.md"),
+                                 QStringLiteral("/work/foo/deep/b.md")}));
+
+    QCOMPARE(store.getItemsUnderPath(QStringLiteral("/work/foo/deep/b.md")).size(), size_t(1));
+    QCOMPARE(store.getItemsUnderPath(QStringLiteral("/")).size(), size_t(6));
+    QVERIFY(store.getItemsUnderPath(QStringLiteral("/elsewhere")).empty());
+}
+
+void TestSQLiteStoreExtended::testGetItemsInIdRange()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/range.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    QList<int64_t> ids;
+    for (const char* path : {"/r/c.md", "/r/a.md", "/r/b.md", "/r/d.md"}) {
+        const auto id = insertTextFixture(store, QString::fromLatin1(path), QStringLiteral("x"),
+                                          1, 100.0);
+        QVERIFY(id.has_value());
+        ids.append(id.value());
+    }
+
+    QList<int64_t> found;
+    QStringList paths;
+    for (const auto& row : store.getItemsInIdRange(ids.at(1), ids.at(2))) {
+        found.append(row.id);
+        paths.append(row.path);
+    }
+    QCOMPARE(found, (QList<int64_t>{ids.at(1), ids.at(2)}));
+    QCOMPARE(paths, (QStringList{QStringLiteral("/r/a.md"), QStringLiteral("/r/b.md")}));
+    QCOMPARE(store.getItemsInIdRange(0, std::numeric_limits<int64_t>::max()).size(), size_t(4));
+    QVERIFY(store.getItemsInIdRange(ids.at(3) + 1, ids.at(3) + 100).empty());
+}
+
+void TestSQLiteStoreExtended::testGetItemContentTruncates()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/content.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const auto itemId = insertTextFixture(
+        store, QStringLiteral("/workspace/docs/notes.md"),
+        QStringLiteral("alpha beta gamma"), /*size=*/16, /*modifiedAt=*/200.0);
+    QVERIFY(itemId.has_value());
+
+    bool truncated = true;
+    QCOMPARE(store.getItemContent(itemId.value(), 1000, &truncated),
+             QStringLiteral("alpha beta gamma"));
+    QVERIFY(!truncated);
+
+    QCOMPARE(store.getItemContent(itemId.value(), 5, &truncated), QStringLiteral("alpha"));
+    QVERIFY(truncated);
+
+    QVERIFY(store.getItemContent(itemId.value() + 999, 1000).isEmpty());
+}
+
+void TestSQLiteStoreExtended::testItemAttributesAreSearchableAndSurviveRechunking()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/attributes.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const QString path = QStringLiteral("/workspace/downloads/tool.txt");
+    const auto itemId = insertTextFixture(store, path, QStringLiteral("alpha"), 5, 100.0);
+    QVERIFY(itemId.has_value());
+
+    const std::vector<bs::ItemAttribute> attributes = {
+        {QStringLiteral("contentType"), QStringLiteral("public.plain-text"), false},
+        {QStringLiteral("whereFroms"), QStringLiteral("https://quillmirror.example.org/tool.txt"), true},
+        {QStringLiteral("whereFroms"), QStringLiteral("https://quillmirror.example.org/"), true},
+    };
+    QVERIFY(store.replaceItemAttributes(itemId.value(), QStringLiteral("tool.txt"), path,
+                                        attributes));
+    QVERIFY(store.getItemAttributes(itemId.value()) == attributes);
+    QCOMPARE(store.getItemAttributeValue(itemId.value(), QStringLiteral("whereFroms")),
+             QStringLiteral("https://quillmirror.example.org/tool.txt"));
+    QVERIFY(store.getItemAttributeValue(itemId.value(), QStringLiteral("finderComment")).isEmpty());
+
+    auto hits = store.searchFts5(QStringLiteral("quillmirror"));
+    QCOMPARE(hits.size(), size_t(1));
+    QCOMPARE(hits.front().fileId, itemId.value());
+    QCOMPARE(hits.front().chunkId, QString::fromLatin1(bs::SQLiteStore::kAttributesChunkId));
+    QVERIFY(store.searchFts5(QStringLiteral("plain")).empty());
+
+    // Re-extracting the content keeps the attributes row.
+    bs::Chunk chunk;
+    chunk.chunkId = bs::computeChunkId(path, 0);
+    chunk.filePath = path;
+    chunk.content = QStringLiteral("omega");
+    QVERIFY(store.insertChunks(itemId.value(), QStringLiteral("tool.txt"), path, {chunk}));
+    QCOMPARE(store.searchFts5(QStringLiteral("quillmirror")).size(), size_t(1));
+    QVERIFY(store.searchFts5(QStringLiteral("alpha")).empty());
+
+    QVERIFY(store.replaceItemAttributes(itemId.value(), QStringLiteral("tool.txt"), path, {}));
+    QVERIFY(store.getItemAttributes(itemId.value()).empty());
+    QVERIFY(store.searchFts5(QStringLiteral("quillmirror")).empty());
+    QCOMPARE(store.searchFts5(QStringLiteral("omega")).size(), size_t(1));
+}
+
+void TestSQLiteStoreExtended::testFinderTagsFilterAndCount()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/tags.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const auto bothId = insertTextFixture(store, QStringLiteral("/workspace/both.md"),
+                                          QStringLiteral("budget plan"), 10, 300.0);
+    const auto redId = insertTextFixture(store, QStringLiteral("/workspace/red.md"),
+                                         QStringLiteral("budget draft"), 10, 200.0);
+    const auto plainId = insertTextFixture(store, QStringLiteral("/workspace/plain.md"),
+                                           QStringLiteral("budget notes"), 10, 100.0);
+    QVERIFY(bothId.has_value());
+    QVERIFY(redId.has_value());
+    QVERIFY(plainId.has_value());
+
+    const QString finder = QString::fromLatin1(bs::SQLiteStore::kFinderTagSource);
+    QVERIFY(store.replaceItemTags(bothId.value(), finder,
+                                  {QStringLiteral("Urgent"), QStringLiteral("Red")}));
+    QVERIFY(store.replaceItemTags(redId.value(), finder, {QStringLiteral("Red")}));
+    QVERIFY(store.replaceItemTags(redId.value(), QStringLiteral("user"),
+                                  {QStringLiteral("draft")}));
+    QCOMPARE(store.getItemTags(bothId.value()),
+             QStringList({QStringLiteral("Urgent"), QStringLiteral("Red")}));
+
+    bs::SearchOptions options;
+    options.tags = {QStringLiteral("red"), QStringLiteral("urgent")};
+    auto joined = store.searchFts5Joined(QStringLiteral("budget"), 10, false, options);
+    QCOMPARE(joined.size(), size_t(1));
+    QCOMPARE(joined.front().fileId, bothId.value());
+    const auto filtered = store.searchFts5(QStringLiteral("budget"), 10, false, options);
+    QCOMPARE(filtered.size(), size_t(1));
+    QCOMPARE(filtered.front().fileId, bothId.value());
+
+    options.tags = {QStringLiteral("red")};
+    const auto listed = store.listFilteredItems(options, 10);
+    QCOMPARE(listed.size(), size_t(2));
+    QCOMPARE(listed[0].id, bothId.value());   // most recently modified first
+    QCOMPARE(listed[1].id, redId.value());
+
+    const auto counts = store.countTags({bothId.value(), redId.value(), plainId.value()});
+    QCOMPARE(counts.size(), size_t(3));
+    QCOMPARE(counts[0].tag, QStringLiteral("Red"));
+    QCOMPARE(counts[0].count, 2);
+    QCOMPARE(counts[1].tag, QStringLiteral("Urgent"));
+    QCOMPARE(counts[2].tag, QStringLiteral("draft"));
+
+    // Clearing Finder tags leaves tags from other sources alone.
+    QVERIFY(store.replaceItemTags(redId.value(), finder, {}));
+    QCOMPARE(store.getItemTags(redId.value()), QStringList({QStringLiteral("draft")}));
+    QCOMPARE(store.listFilteredItems(options, 10).size(), size_t(1));
+}
+
+void TestSQLiteStoreExtended::testCompactClearsPendingDeletes()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/compact.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const QString path = QStringLiteral("/workspace/private/secret.md");
+    QVERIFY(insertTextFixture(store, path, QStringLiteral("zanzibarcode secret"),
+                              /*size=*/100, /*modifiedAt=*/200.0).has_value());
+    QVERIFY(insertTextFixture(store, QStringLiteral("/workspace/docs/keep.md"),
+                              QStringLiteral("keep"), /*size=*/100,
+                              /*modifiedAt=*/200.0).has_value());
+    QCOMPARE(store.pendingCompactionDeletes(), int64_t(0));
+
+    QVERIFY(store.deleteItemByPath(path));
+    QCOMPARE(store.pendingCompactionDeletes(), int64_t(1));
+    // Deleting a path that is not indexed changes nothing to compact.
+    QVERIFY(store.deleteItemByPath(QStringLiteral("/workspace/private/missing.md")));
+    QCOMPARE(store.pendingCompactionDeletes(), int64_t(1));
+    QVERIFY(store.footprintUnderPath(QStringLiteral("/workspace/private")).empty());
+
+    const auto summary = store.compact();
+    QVERIFY(summary.complete());
+    QCOMPARE(summary.compactedDeletes, int64_t(1));
+    QCOMPARE(summary.freePages, int64_t(0));
+    QVERIFY(summary.bytesAfter > 0);
+    QCOMPARE(store.pendingCompactionDeletes(), int64_t(0));
+    QVERIFY(store.getSetting(QStringLiteral("last_vacuum_at")).has_value());
+    QVERIFY(store.searchFts5(QStringLiteral("zanzibarcode"), 10).empty());
+    QCOMPARE(static_cast<int>(store.searchFts5(QStringLiteral("keep"), 10).size()), 1);
+}
+
+void TestSQLiteStoreExtended::testMetricsCounts()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/metrics.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    QCOMPARE(store.ftsSegmentCount(), 0);
+
+    std::vector<int64_t> ids;
+    for (int i = 0; i < 3; ++i) {
+        const auto id = insertTextFixture(
+            store, QStringLiteral("/workspace/docs/note-%1.md").arg(i),
+            QStringLiteral("segment body %1").arg(i), /*size=*/100, /*modifiedAt=*/200.0);
+        QVERIFY(id.has_value());
+        ids.push_back(id.value());
+    }
+    QVERIFY(store.ftsSegmentCount() >= 1);
+    QVERIFY(store.optimizeFts5());
+    QCOMPARE(store.ftsSegmentCount(), 1);
+
+    QVERIFY(store.recordFailure(ids.at(0), QStringLiteral("extraction"),
+                                QStringLiteral("File is not readable")));
+    QVERIFY(store.recordFailure(ids.at(1), QStringLiteral("extraction"),
+                                QStringLiteral("Chunker produced no output")));
+    QVERIFY(store.recordFailure(ids.at(2), QStringLiteral("extraction"),
+                                QStringLiteral("Chunker produced no output")));
+    QVERIFY(store.recordFailure(ids.at(2), QStringLiteral("embedding"),
+                                QStringLiteral("Model unavailable")));
+
+    const auto failures = store.extractionFailureCounts();
+    QCOMPARE(static_cast<int>(failures.size()), 2);
+    QCOMPARE(failures.at(0).kind, QStringLiteral("text"));
+    QVERIFY(!failures.at(0).expected);
+    QCOMPARE(failures.at(0).count, int64_t(2));
+    QVERIFY(failures.at(1).expected);
+    QCOMPARE(failures.at(1).count, int64_t(1));
+}
+
+void TestSQLiteStoreExtended::testBreakdownDirectory()
+{
+    const QStringList roots = {QStringLiteral("/Users/me"), QStringLiteral("/Users/me/Code/")};
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/Users/me/Documents/a/b.txt"),
+                                                 roots),
+             QStringLiteral("/Users/me/Documents"));
+    // The longest root wins.
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/Users/me/Code/app/main.cpp"),
+                                                 roots),
+             QStringLiteral("/Users/me/Code/app"));
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/Users/me/notes.md"), roots),
+             QStringLiteral("/Users/me"));
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/Users/meta/x.txt"), roots),
+             QStringLiteral("/Users/meta"));
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/Volumes/ext/a/b.txt"), {}),
+             QStringLiteral("/Volumes/ext/a"));
+    QCOMPARE(bs::SQLiteStore::breakdownDirectory(QStringLiteral("/etc/hosts"),
+                                                 {QStringLiteral("/")}),
+             QStringLiteral("/etc"));
+}
+
+void TestSQLiteStoreExtended::testSizeBreakdown()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    auto storeOpt = bs::SQLiteStore::open(dir.path() + QStringLiteral("/breakdown.db"));
+    QVERIFY(storeOpt.has_value());
+    bs::SQLiteStore store = std::move(storeOpt.value());
+
+    const QString big = QStringLiteral("lorem ipsum dolor ").repeated(200);
+    for (int i = 0; i < 3; ++i) {
+        QVERIFY(insertTextFixture(store, QStringLiteral("/workspace/docs/guide-%1.md").arg(i),
+                                  big, /*size=*/4000, /*modifiedAt=*/200.0)
+                    .has_value());
+    }
+    QVERIFY(insertTextFixture(store, QStringLiteral("/workspace/src/main.cpp"),
+                              QStringLiteral("int main() {}"), /*size=*/20, /*modifiedAt=*/200.0)
+                .has_value());
+    QVERIFY(insertTextFixture(store, QStringLiteral("/workspace/src/util.cpp"),
+                              QStringLiteral("void util() {}"), /*size=*/20, /*modifiedAt=*/200.0)
+                .has_value());
+
+    const auto breakdown = store.sizeBreakdown({QStringLiteral("/workspace")}, /*maxBuckets=*/1);
+    QVERIFY(breakdown.measured);
+    QVERIFY(breakdown.totalBytes > 0);
+    QVERIFY(breakdown.fileBytes > 0);
+    QVERIFY(breakdown.fileBytes <= breakdown.totalBytes);
+
+    QStringList tables;
+    for (const auto& table : breakdown.tables) {
+        tables.append(table.name);
+    }
+    QVERIFY(tables.contains(QStringLiteral("search_index")));
+    QVERIFY(!tables.contains(QStringLiteral("search_index_data")));
+
+    QCOMPARE(static_cast<int>(breakdown.fields.size()), 3);
+    QCOMPARE(breakdown.fields.front().name, QStringLiteral("content"));
+    int64_t fieldBytes = 0;
+    for (const auto& field : breakdown.fields) {
+        fieldBytes += field.bytes;
+    }
+    QVERIFY(std::abs(fieldBytes - breakdown.fileBytes) <= 2);
+
+    // One bucket, then the rest in "(other)".
+    QCOMPARE(static_cast<int>(breakdown.fileTypes.size()), 2);
+    QCOMPARE(breakdown.fileTypes.at(0).name, QStringLiteral("md"));
+    QCOMPARE(breakdown.fileTypes.at(0).items, int64_t(3));
+    QCOMPARE(breakdown.fileTypes.at(1).name, QStringLiteral("(other)"));
+    QCOMPARE(breakdown.fileTypes.at(1).items, int64_t(2));
+    QVERIFY(breakdown.fileTypes.at(0).bytes > breakdown.fileTypes.at(1).bytes);
+
+    QCOMPARE(breakdown.directories.at(0).name, QStringLiteral("/workspace/docs"));
+    QCOMPARE(breakdown.directories.at(0).items, int64_t(3));
+}
+
 QTEST_MAIN(TestSQLiteStoreExtended)
 #include "test_sqlite_store_extended.moc"
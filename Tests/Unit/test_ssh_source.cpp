@@ -0,0 +1,236 @@
+#include <QtTest/QtTest>
+
+#include "core/indexing/ssh_source.h"
+
+#include <QTemporaryDir>
+
+namespace {
+
+QString attributeValue(const bs::DonatedItem& item, const char* name)
+{
+    for (const bs::ItemAttribute& attribute : item.attributes) {
+        if (attribute.name == QLatin1String(name)) {
+            return attribute.value;
+        }
+    }
+    return QString();
+}
+
+bs::SshRemote remote()
+{
+    bs::SshRemote remote;
+    remote.name = QStringLiteral("devbox");
+    remote.host = QStringLiteral("me@dev.example.com");
+    remote.root = QStringLiteral("/srv/app");
+    remote.maxFileBytes = bs::SshSource::kDefaultMaxFileBytes;
+    return remote;
+}
+
+bs::RemoteFile remoteFile(const QString& path, int64_t size)
+{
+    bs::RemoteFile file;
+    file.path = path;
+    file.size = size;
+    file.modifiedAt = 1790003600.25;
+    return file;
+}
+
+} // namespace
+
+class TestSshSource : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseRemotes();
+    void testParseRemotesRejectsInvalid();
+    void testShellQuote();
+    void testCommands();
+    void testSshArguments();
+    void testParseListing();
+    void testParseListingTruncates();
+    void testIsFetchable();
+    void testUnchanged();
+    void testToDonatedItem();
+};
+
+void TestSshSource::testParseRemotes()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString key = dir.filePath(QStringLiteral("id_ed25519"));
+    QFile file(key);
+    QVERIFY(file.open(QIODevice::WriteOnly));
+    file.close();
+
+    QString error;
+    auto none = bs::SshSource::parseRemotes(QString(), &error);
+    QVERIFY(none.has_value());
+    QVERIFY(none->empty());
+
+    const QString json = QStringLiteral(
+        R"([{"name": "devbox", "host": "me@dev.example.com", "root": "/srv/app/", "port": 2222,
+             "identityFile": "%1"},
+            {"name": "build", "host": "build", "root": "/home/ci//work", "maxFileBytes": 0}])")
+                             .arg(key);
+    auto remotes = bs::SshSource::parseRemotes(json, &error);
+    QVERIFY2(remotes.has_value(), qPrintable(error));
+    QCOMPARE(remotes->size(), size_t(2));
+    QCOMPARE(remotes->at(0).host, QStringLiteral("me@dev.example.com"));
+    QCOMPARE(remotes->at(0).root, QStringLiteral("/srv/app"));
+    QCOMPARE(remotes->at(0).port, 2222);
+    QCOMPARE(remotes->at(0).identityFile, key);
+    QCOMPARE(remotes->at(1).port, 0);
+    QCOMPARE(remotes->at(1).root, QStringLiteral("/home/ci/work"));
+    QCOMPARE(remotes->at(1).maxFileBytes, bs::SshSource::kDefaultMaxFileBytes);
+}
+
+void TestSshSource::testParseRemotesRejectsInvalid()
+{
+    const QStringList invalid = {
+        QStringLiteral("{}"),
+        QStringLiteral(R"([{"name": "dev box", "host": "dev", "root": "/srv"}])"),
+        QStringLiteral(R"([{"name": "a", "host": "dev", "root": "/srv"},
+                           {"name": "A", "host": "dev", "root": "/srv"}])"),
+        QStringLiteral(R"([{"name": "a", "host": "-oProxyCommand=touch /tmp/x", "root": "/srv"}])"),
+        QStringLiteral(R"([{"name": "a", "host": "dev; rm -rf /", "root": "/srv"}])"),
+        QStringLiteral(R"([{"name": "a", "host": "dev", "root": "srv"}])"),
+        QStringLiteral(R"([{"name": "a", "host": "dev", "root": "/srv", "port": 70000}])"),
+        QStringLiteral(R"([{"name": "a", "host": "dev", "root": "/srv",
+                           "identityFile": "/nonexistent/id_rsa"}])"),
+    };
+    for (const QString& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::SshSource::parseRemotes(json, &error).has_value(), qPrintable(json));
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestSshSource::testShellQuote()
+{
+    QCOMPARE(bs::SshSource::shellQuote(QStringLiteral("/srv/app")), QStringLiteral("'/srv/app'"));
+    QCOMPARE(bs::SshSource::shellQuote(QStringLiteral("it's $HOME")),
+             QStringLiteral("'it'\\''s $HOME'"));
+}
+
+void TestSshSource::testCommands()
+{
+    const QString list = bs::SshSource::listCommand(QStringLiteral("/srv/my app"));
+    QVERIFY(list.startsWith(QStringLiteral("cd -- '/srv/my app' || exit 3;")));
+    QVERIFY(list.contains(QStringLiteral("-printf '%s %T@ %P\\0'")));
+    QVERIFY(list.contains(QStringLiteral("-name node_modules")));
+    QCOMPARE(bs::SshSource::fetchCommand(QStringLiteral("/srv/app/a'b.txt")),
+             QStringLiteral("cat -- '/srv/app/a'\\''b.txt'"));
+}
+
+void TestSshSource::testSshArguments()
+{
+    bs::SshRemote withPort = remote();
+    withPort.port = 2222;
+    withPort.identityFile = QStringLiteral("/Users/me/.ssh/id_ed25519");
+    const QStringList arguments = bs::SshSource::sshArguments(
+        withPort, QStringLiteral("/tmp/bs-ssh-abc/c0"), QStringLiteral("true"));
+    QVERIFY(arguments.contains(QStringLiteral("BatchMode=yes")));
+    QVERIFY(arguments.contains(QStringLiteral("ControlPath=/tmp/bs-ssh-abc/c0")));
+    QCOMPARE(arguments.at(arguments.indexOf(QStringLiteral("-p")) + 1), QStringLiteral("2222"));
+    QCOMPARE(arguments.at(arguments.indexOf(QStringLiteral("-i")) + 1),
+             QStringLiteral("/Users/me/.ssh/id_ed25519"));
+    // The destination follows "--", then the command as one argument.
+    QCOMPARE(arguments.mid(arguments.size() - 3),
+             QStringList({QStringLiteral("--"), QStringLiteral("me@dev.example.com"),
+                          QStringLiteral("true")}));
+
+    const QStringList plain = bs::SshSource::sshArguments(remote(), QString(), QStringLiteral("true"));
+    QVERIFY(!plain.contains(QStringLiteral("-p")));
+    QVERIFY(!plain.contains(QStringLiteral("ControlMaster=auto")));
+}
+
+void TestSshSource::testParseListing()
+{
+    QByteArray output;
+    output += QByteArrayLiteral("120 1790003600.2500000000 README.md");
+    output += '\0';
+    output += QByteArrayLiteral("4096 1790000000.0000000000 config/app settings.yml");
+    output += '\0';
+    output += QByteArrayLiteral("garbage");
+    output += '\0';
+    output += QByteArrayLiteral("10 1790000000.0 ../escape.txt");
+    output += '\0';
+    output += QByteArrayLiteral("10 1790000000.0 /etc/passwd");
+    output += '\0';
+
+    bool truncated = true;
+    const std::vector<bs::RemoteFile> files =
+        bs::SshSource::parseListing(output, QStringLiteral("/srv/app"), &truncated);
+    QVERIFY(!truncated);
+    QCOMPARE(files.size(), size_t(2));
+    QCOMPARE(files[0].path, QStringLiteral("/srv/app/README.md"));
+    QCOMPARE(files[0].size, 120);
+    QCOMPARE(files[0].modifiedAt, 1790003600.25);
+    QCOMPARE(files[1].path, QStringLiteral("/srv/app/config/app settings.yml"));
+
+    const std::vector<bs::RemoteFile> atRoot =
+        bs::SshSource::parseListing(QByteArrayLiteral("1 1.0 etc/hosts"), QStringLiteral("/"),
+                                    &truncated);
+    QCOMPARE(atRoot.size(), size_t(1));
+    QCOMPARE(atRoot[0].path, QStringLiteral("/etc/hosts"));
+}
+
+void TestSshSource::testParseListingTruncates()
+{
+    QByteArray output;
+    for (int i = 0; i < bs::SshSource::kMaxFiles + 5; ++i) {
+        output += QByteArray("1 1.0 f") + QByteArray::number(i);
+        output += '\0';
+    }
+    bool truncated = false;
+    const std::vector<bs::RemoteFile> files =
+        bs::SshSource::parseListing(output, QStringLiteral("/srv/app"), &truncated);
+    QVERIFY(truncated);
+    QCOMPARE(static_cast<int>(files.size()), bs::SshSource::kMaxFiles);
+}
+
+void TestSshSource::testIsFetchable()
+{
+    const bs::SshRemote source = remote();
+    QVERIFY(bs::SshSource::isFetchable(source, remoteFile(QStringLiteral("/srv/app/main.go"), 900)));
+    QVERIFY(bs::SshSource::isFetchable(source, remoteFile(QStringLiteral("/srv/app/NOTES.md"), 900)));
+    QVERIFY(bs::SshSource::isFetchable(source, remoteFile(QStringLiteral("/srv/app/spec.pdf"), 900)));
+    QVERIFY(!bs::SshSource::isFetchable(source, remoteFile(QStringLiteral("/srv/app/logo.png"), 900)));
+    QVERIFY(!bs::SshSource::isFetchable(source, remoteFile(QStringLiteral("/srv/app/empty.txt"), 0)));
+    QVERIFY(!bs::SshSource::isFetchable(
+        source, remoteFile(QStringLiteral("/srv/app/huge.log.txt"), source.maxFileBytes + 1)));
+}
+
+void TestSshSource::testUnchanged()
+{
+    const bs::RemoteFile file = remoteFile(QStringLiteral("/srv/app/main.go"), 900);
+    QVERIFY(bs::SshSource::isUnchanged(file, 900, 1790003600.25));
+    QVERIFY(!bs::SshSource::isUnchanged(file, 901, 1790003600.25));
+    QVERIFY(!bs::SshSource::isUnchanged(file, 900, 1790003601.25));
+}
+
+void TestSshSource::testToDonatedItem()
+{
+    const bs::DonatedItem item = bs::SshSource::toDonatedItem(
+        remote(), remoteFile(QStringLiteral("/srv/app/config/app.yml"), 300),
+        QStringLiteral("listen: 8080"));
+    QVERIFY(bs::SshSource::isSshPath(item.path));
+    QCOMPARE(bs::SpotlightDonation::bundleIdentifier(item.path),
+             QStringLiteral("com.betterspotlight.ssh.devbox"));
+    QCOMPARE(bs::SpotlightDonation::uniqueIdentifier(item.path), QStringLiteral("config/app.yml"));
+    QCOMPARE(item.name, QStringLiteral("app.yml"));
+    QVERIFY(item.textContent.contains(QStringLiteral("listen: 8080")));
+    QVERIFY(item.textContent.contains(QStringLiteral("/srv/app/config/app.yml")));
+    QCOMPARE(item.size, 300);
+    QCOMPARE(item.modifiedAt, 1790003600.25);
+    QCOMPARE(attributeValue(item, bs::SshSource::kRemoteAttribute), QStringLiteral("devbox"));
+    QCOMPARE(attributeValue(item, bs::SshSource::kRemotePathAttribute),
+             QStringLiteral("me@dev.example.com:/srv/app/config/app.yml"));
+    QCOMPARE(attributeValue(item, bs::SpotlightDonation::kDescriptionAttribute),
+             QStringLiteral("devbox · /srv/app/config"));
+    QVERIFY(!item.contentHash.isEmpty());
+    QVERIFY(!bs::SshSource::isSshPath(QStringLiteral("/srv/app/config/app.yml")));
+}
+
+QTEST_MAIN(TestSshSource)
+#include "test_ssh_source.moc"
@@ -0,0 +1,199 @@
+#include <QtTest/QtTest>
+
+#include "cli/status_format.h"
+
+#include <QJsonArray>
+
+namespace {
+
+QJsonObject makeRoot(const QString& path, qint64 discovered, qint64 processed,
+                     bool scanComplete, double percent)
+{
+    QJsonObject root;
+    root[QStringLiteral("path")] = path;
+    root[QStringLiteral("discovered")] = discovered;
+    root[QStringLiteral("processed")] = processed;
+    root[QStringLiteral("scanComplete")] = scanComplete;
+    root[QStringLiteral("percent")] = percent;
+    return root;
+}
+
+QJsonObject makeProgress(const QString& state, const QJsonValue& eta)
+{
+    QJsonObject throughput;
+    throughput[QStringLiteral("itemsPerSec")] = 85.26;
+    throughput[QStringLiteral("windowMs")] = 30000;
+
+    QJsonObject progress;
+    progress[QStringLiteral("state")] = state;
+    progress[QStringLiteral("indexedItems")] = 12421;
+    progress[QStringLiteral("pending")] = 579;
+    progress[QStringLiteral("processing")] = 2;
+    progress[QStringLiteral("throughput")] = throughput;
+    progress[QStringLiteral("etaSeconds")] = eta;
+    progress[QStringLiteral("roots")] = QJsonArray{
+        makeRoot(QStringLiteral("/Users/test/Documents"), 12000, 12000, true, 100.0),
+        makeRoot(QStringLiteral("/Users/test/Code"), 1000, 421, false, 42.1),
+    };
+    return progress;
+}
+
+} // namespace
+
+class TestStatusFormat : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testFormatDuration();
+    void testRenderCrawl();
+    void testRenderEtaStates();
+    void testRenderStopped();
+    void testRenderUnreadableFolders();
+    void testRenderExtractors();
+    void testRenderResources();
+};
+
+void TestStatusFormat::testFormatDuration()
+{
+    QCOMPARE(bs::StatusFormat::formatDuration(-5), QStringLiteral("0s"));
+    QCOMPARE(bs::StatusFormat::formatDuration(45), QStringLiteral("45s"));
+    QCOMPARE(bs::StatusFormat::formatDuration(250), QStringLiteral("4m 10s"));
+    QCOMPARE(bs::StatusFormat::formatDuration(7500), QStringLiteral("2h 05m"));
+    QCOMPARE(bs::StatusFormat::formatDuration(273600), QStringLiteral("3d 4h"));
+}
+
+void TestStatusFormat::testRenderCrawl()
+{
+    const QString out = bs::StatusFormat::render(
+        makeProgress(QStringLiteral("initial_crawl"), QJsonValue(QJsonValue::Null)));
+    const QStringList lines = out.split(QLatin1Char('\n'));
+    QCOMPARE(lines.value(0), QStringLiteral("State:      initial crawl"));
+    QCOMPARE(lines.value(1), QStringLiteral("Indexed:    12421 documents"));
+    QCOMPARE(lines.value(2), QStringLiteral("Pending:    579 queued, 2 in progress"));
+    QCOMPARE(lines.value(3), QStringLiteral("Throughput: 85.3 items/s"));
+    QCOMPARE(lines.value(4), QStringLiteral("ETA:        unknown until the scan finishes"));
+    QCOMPARE(lines.value(6), QStringLiteral("Roots:"));
+    QCOMPARE(lines.value(7), QStringLiteral("  100.0%  /Users/test/Documents  (12000/12000)"));
+    QCOMPARE(lines.value(8), QStringLiteral("   42.1%  /Users/test/Code  (421/1000, scanning)"));
+}
+
+void TestStatusFormat::testRenderEtaStates()
+{
+    QVERIFY(bs::StatusFormat::render(makeProgress(QStringLiteral("initial_crawl"), 250))
+                .contains(QStringLiteral("ETA:        4m 10s\n")));
+    QVERIFY(bs::StatusFormat::render(makeProgress(QStringLiteral("idle"), 0))
+                .contains(QStringLiteral("ETA:        -\n")));
+    QVERIFY(bs::StatusFormat::render(makeProgress(QStringLiteral("idle"), 0))
+                .contains(QStringLiteral("State:      up to date\n")));
+}
+
+void TestStatusFormat::testRenderStopped()
+{
+    QJsonObject progress;
+    progress[QStringLiteral("state")] = QStringLiteral("stopped");
+    progress[QStringLiteral("indexedItems")] = 10;
+    progress[QStringLiteral("pending")] = 0;
+    progress[QStringLiteral("processing")] = 0;
+    progress[QStringLiteral("roots")] = QJsonArray();
+
+    const QString out = bs::StatusFormat::render(progress);
+    QVERIFY(out.startsWith(QStringLiteral("State:      not indexing\n")));
+    QVERIFY(!out.contains(QStringLiteral("Throughput")));
+    QVERIFY(!out.contains(QStringLiteral("Roots:")));
+}
+
+void TestStatusFormat::testRenderUnreadableFolders()
+{
+    QJsonObject root;
+    root[QStringLiteral("path")] = QStringLiteral("/Volumes/Archive");
+    root[QStringLiteral("reason")] = QStringLiteral("missing");
+    QJsonObject folder;
+    folder[QStringLiteral("path")] = QStringLiteral("/Users/test/Documents");
+    folder[QStringLiteral("reason")] = QStringLiteral("privacy_denied");
+
+    QJsonObject access;
+    access[QStringLiteral("fullDiskAccess")] = false;
+    access[QStringLiteral("unreadableRoots")] = QJsonArray{root};
+    access[QStringLiteral("protectedFolders")] = QJsonArray{folder};
+    access[QStringLiteral("remediation")] = QStringLiteral("Grant access.\nReconnect the volume.");
+
+    QJsonObject progress = makeProgress(QStringLiteral("idle"), 0);
+    progress[QStringLiteral("access")] = access;
+    const QString out = bs::StatusFormat::render(progress);
+    QVERIFY(out.contains(QStringLiteral("\nNot readable:\n"
+                                        "  /Volumes/Archive  (missing, root skipped)\n"
+                                        "  /Users/test/Documents  (blocked by macOS privacy, "
+                                        "folder skipped)\n"
+                                        "Fix: Grant access.\n"
+                                        "     Reconnect the volume.\n")));
+
+    // Nothing blocked: no section at all.
+    access[QStringLiteral("unreadableRoots")] = QJsonArray();
+    access[QStringLiteral("protectedFolders")] = QJsonArray();
+    access[QStringLiteral("remediation")] = QString();
+    progress[QStringLiteral("access")] = access;
+    QVERIFY(!bs::StatusFormat::render(progress).contains(QStringLiteral("Not readable")));
+}
+
+void TestStatusFormat::testRenderExtractors()
+{
+    const auto extractor = [](const QString& name, qint64 extractions, qint64 failures,
+                              qint64 timeouts, qint64 totalMs, double timeShare) {
+        QJsonObject json;
+        json[QStringLiteral("extractor")] = name;
+        json[QStringLiteral("extractions")] = extractions;
+        json[QStringLiteral("failures")] = failures;
+        json[QStringLiteral("timeouts")] = timeouts;
+        json[QStringLiteral("totalMs")] = totalMs;
+        json[QStringLiteral("timeShare")] = timeShare;
+        return json;
+    };
+
+    QJsonObject progress = makeProgress(QStringLiteral("idle"), 0);
+    progress[QStringLiteral("extractors")] = QJsonArray{
+        extractor(QStringLiteral("text"), 9000, 0, 0, 170000, 0.19),
+        extractor(QStringLiteral("pdf"), 1204, 3, 1, 750000, 0.81),
+        extractor(QStringLiteral("ocr"), 0, 0, 0, 0, 0.0),
+    };
+    const QString out = bs::StatusFormat::render(progress);
+    QVERIFY(out.contains(QStringLiteral("\nExtractors:\n"
+                                        "  pdf   1204 files, 12m 30s, 81% of extraction time, "
+                                        "3 failed (1 timed out)\n"
+                                        "  text  9000 files, 2m 50s, 19% of extraction time\n")));
+    QVERIFY(!out.contains(QStringLiteral("  ocr")));
+
+    // Nothing extracted yet: no section.
+    progress[QStringLiteral("extractors")] = QJsonArray{extractor(QStringLiteral("pdf"), 0, 0, 0,
+                                                                  0, 0.0)};
+    QVERIFY(!bs::StatusFormat::render(progress).contains(QStringLiteral("Extractors:")));
+}
+
+void TestStatusFormat::testRenderResources()
+{
+    QJsonObject resources;
+    resources[QStringLiteral("cpuPercent")] = 12.34;
+    resources[QStringLiteral("rssBytes")] = qint64(182) * 1024 * 1024 + 5000;
+    resources[QStringLiteral("openFds")] = 42;
+    resources[QStringLiteral("diskReadBytesPerSec")] = 3.1 * 1024 * 1024;
+    resources[QStringLiteral("diskWriteBytesPerSec")] = 80.0 * 1024;
+
+    QJsonObject progress = makeProgress(QStringLiteral("initial_crawl"), QJsonValue::Null);
+    progress[QStringLiteral("resources")] = resources;
+    QVERIFY(bs::StatusFormat::render(progress).contains(
+        QStringLiteral("Indexer:    12.3% CPU, 182 MB memory, 42 open files, "
+                       "disk 3.1 MB/s read, 80 KB/s write\n")));
+
+    // No rate yet and no macOS counters: only what is known.
+    resources[QStringLiteral("cpuPercent")] = QJsonValue::Null;
+    resources[QStringLiteral("rssBytes")] = QJsonValue::Null;
+    resources[QStringLiteral("diskReadBytesPerSec")] = QJsonValue::Null;
+    progress[QStringLiteral("resources")] = resources;
+    QVERIFY(bs::StatusFormat::render(progress).contains(
+        QStringLiteral("Indexer:    42 open files\n")));
+
+    QVERIFY(!bs::StatusFormat::render(makeProgress(QStringLiteral("idle"), 0))
+                 .contains(QStringLiteral("Indexer:")));
+}
+
+QTEST_MAIN(TestStatusFormat)
+#include "test_status_format.moc"
@@ -0,0 +1,174 @@
+#include <QtTest/QtTest>
+
+#include "synthetic_home.h"
+
+#include <QDir>
+#include <QDirIterator>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+#include <cmath>
+
+namespace {
+
+// Relative path -> content of every file under `root`.
+QMap<QString, QByteArray> readTree(const QString& root)
+{
+    QMap<QString, QByteArray> tree;
+    QDirIterator it(root, QDir::Files | QDir::Hidden, QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QString path = it.next();
+        QFile file(path);
+        if (file.open(QIODevice::ReadOnly)) {
+            tree.insert(QDir(root).relativeFilePath(path), file.readAll());
+        }
+    }
+    return tree;
+}
+
+} // namespace
+
+class TestSyntheticHome : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testGenerateFollowsProfile();
+    void testGenerateIsDeterministic();
+    void testPlantedTokens();
+    void testDepthLimit();
+    void testRejectsInvalid();
+};
+
+void TestSyntheticHome::testGenerateFollowsProfile()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const bs::test::SyntheticHomeProfile profile = bs::test::SyntheticHome::defaultProfile();
+    QString error;
+    int64_t lastProgress = 0;
+    const auto report = bs::test::SyntheticHome::generate(
+        profile, 20000, dir.filePath(QStringLiteral("home")), &error,
+        [&](int64_t written) { lastProgress = written; });
+    QVERIFY2(report.has_value(), qPrintable(error));
+    QCOMPARE(report->files, int64_t(20000));
+    QCOMPARE(lastProgress, int64_t(20000));
+    QCOMPARE(report->directories, int64_t(20000 / profile.filesPerDirectory));
+
+    const QMap<QString, QByteArray> tree = readTree(dir.filePath(QStringLiteral("home")));
+    QCOMPARE(tree.size(), qsizetype(20000));
+    int64_t bytes = 0;
+    QMap<QString, int> byRoot;
+    for (auto it = tree.cbegin(); it != tree.cend(); ++it) {
+        bytes += it.value().size();
+        ++byRoot[it.key().section(QLatin1Char('/'), 0, 0)];
+        if (it.key().endsWith(QLatin1String(".jpg"))) {
+            QVERIFY(it.value().startsWith("\xff\xd8\xff"));
+            QCOMPARE(it.value().size(), qsizetype(profile.maxFileBytes));
+        }
+    }
+    QCOMPARE(bytes, report->bytes);
+    QVERIFY(report->profileBytes > report->bytes);
+
+    // Shares follow the root weights (per mille) to within a few points.
+    for (const bs::test::SyntheticHomeRoot& root : profile.roots) {
+        const double share = byRoot.value(root.name) / 20000.0 * 1000.0;
+        QVERIFY2(std::abs(share - root.weight) < 15.0 + root.weight * 0.1,
+                 qPrintable(QStringLiteral("%1: %2 per mille").arg(root.name).arg(share)));
+    }
+    QVERIFY(report->byExtension.value(QStringLiteral("js")) > report->byExtension.value(QStringLiteral("mov")));
+
+    int64_t byDepth = 0;
+    for (const int64_t count : report->byDepth) {
+        byDepth += count;
+    }
+    QCOMPARE(byDepth, report->files);
+    QVERIFY(report->byDepth.size() > 4);
+
+    const QJsonObject json = bs::test::SyntheticHome::reportToJson(*report, profile);
+    QCOMPARE(json.value(QStringLiteral("files")).toInteger(), qint64(20000));
+    QCOMPARE(json.value(QStringLiteral("plantedTokens")).toArray().size(),
+             qsizetype(bs::test::SyntheticHome::plantedTokens().size()));
+}
+
+void TestSyntheticHome::testGenerateIsDeterministic()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::SyntheticHomeProfile profile = bs::test::SyntheticHome::defaultProfile();
+    QString error;
+    QVERIFY(bs::test::SyntheticHome::generate(profile, 500, dir.filePath(QStringLiteral("a")), &error));
+    QVERIFY(bs::test::SyntheticHome::generate(profile, 500, dir.filePath(QStringLiteral("b")), &error));
+    QCOMPARE(readTree(dir.filePath(QStringLiteral("a"))), readTree(dir.filePath(QStringLiteral("b"))));
+
+    profile.seed = 2;
+    QVERIFY(bs::test::SyntheticHome::generate(profile, 500, dir.filePath(QStringLiteral("c")), &error));
+    QVERIFY(readTree(dir.filePath(QStringLiteral("a"))).keys()
+            != readTree(dir.filePath(QStringLiteral("c"))).keys());
+}
+
+void TestSyntheticHome::testPlantedTokens()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    const auto report = bs::test::SyntheticHome::generate(
+        bs::test::SyntheticHome::defaultProfile(), 5000, dir.filePath(QStringLiteral("home")),
+        &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+    const QMap<QString, QByteArray> tree = readTree(dir.filePath(QStringLiteral("home")));
+    for (const auto& [token, oneIn] : bs::test::SyntheticHome::plantedTokens()) {
+        int64_t holding = 0;
+        for (const QByteArray& content : tree) {
+            holding += content.contains(token.toUtf8()) ? 1 : 0;
+        }
+        QCOMPARE(holding, report->tokenFiles.value(token));
+        if (oneIn <= 100) {
+            QVERIFY(holding > 0);
+        }
+    }
+}
+
+void TestSyntheticHome::testDepthLimit()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::test::SyntheticHomeProfile profile;
+    profile.filesPerDirectory = 2;
+    bs::test::SyntheticHomeRoot root;
+    root.name = QStringLiteral("Flat");
+    root.maxDepth = 2;
+    root.types = {bs::test::SyntheticFileType{QStringLiteral("txt"),
+                                              bs::test::CorpusEntryKind::Text, {}, 1, 512}};
+    profile.roots = {root};
+    QString error;
+    const auto report = bs::test::SyntheticHome::generate(
+        profile, 300, dir.filePath(QStringLiteral("home")), &error);
+    QVERIFY2(report.has_value(), qPrintable(error));
+    QVERIFY(report->byDepth.size() <= 3);
+    for (const QString& path : readTree(dir.filePath(QStringLiteral("home"))).keys()) {
+        QVERIFY2(path.count(QLatin1Char('/')) <= 2, qPrintable(path));
+        QVERIFY(path.endsWith(QLatin1String(".txt")));
+    }
+}
+
+void TestSyntheticHome::testRejectsInvalid()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    QString error;
+    const bs::test::SyntheticHomeProfile profile = bs::test::SyntheticHome::defaultProfile();
+    QVERIFY(!bs::test::SyntheticHome::generate(profile, 0, dir.filePath(QStringLiteral("a")), &error));
+    QVERIFY(!bs::test::SyntheticHome::generate(bs::test::SyntheticHomeProfile{}, 10,
+                                               dir.filePath(QStringLiteral("b")), &error));
+
+    QFile existing(dir.filePath(QStringLiteral("keep.txt")));
+    QVERIFY(existing.open(QIODevice::WriteOnly));
+    existing.close();
+    QVERIFY(!bs::test::SyntheticHome::generate(profile, 10, dir.path(), &error));
+    QVERIFY(error.contains(QStringLiteral("not empty")));
+}
+
+QTEST_MAIN(TestSyntheticHome)
+#include "test_synthetic_home.moc"
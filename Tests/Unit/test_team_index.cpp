@@ -0,0 +1,189 @@
+#include <QtTest/QtTest>
+
+#include "core/index/sqlite_store.h"
+#include "core/query/federated_search.h"
+#include "core/query/team_index.h"
+#include "core/shared/chunk.h"
+
+#include <QJsonArray>
+#include <QTemporaryDir>
+
+namespace {
+
+bs::TeamIndex handbook()
+{
+    bs::TeamIndex index;
+    index.name = QStringLiteral("handbook");
+    index.path = QStringLiteral("/Volumes/Team/handbook/index.db");
+    index.publishedRoot = QStringLiteral("/Users/alice/Handbook");
+    index.localRoot = QStringLiteral("/Volumes/Team/handbook");
+    return index;
+}
+
+// A published index with one document, closed so its WAL is checkpointed.
+void publish(const QString& dbPath, const QString& path, const QString& text)
+{
+    auto store = bs::SQLiteStore::open(dbPath);
+    QVERIFY(store.has_value());
+    const QString name = path.mid(path.lastIndexOf(QLatin1Char('/')) + 1);
+    auto id = store->upsertItem(path, name, QStringLiteral("md"), bs::ItemKind::Markdown, 512, 1.0,
+                                1790000000.0);
+    QVERIFY(id.has_value());
+    bs::Chunk chunk;
+    chunk.chunkId = bs::computeChunkId(path, 0);
+    chunk.filePath = path;
+    chunk.content = text;
+    QVERIFY(store->insertChunks(*id, name, path, {chunk}));
+}
+
+} // namespace
+
+class TestTeamIndex : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testParseIndexes();
+    void testParseIndexesRejectsInvalid();
+    void testLocalPath();
+    void testOpenReadOnlyRefusesWrites();
+    void testSearch();
+    void testMergeLabelsTeamRows();
+};
+
+void TestTeamIndex::testParseIndexes()
+{
+    QString error;
+    auto none = bs::TeamIndexes::parseIndexes(QString(), &error);
+    QVERIFY(none.has_value());
+    QVERIFY(none->empty());
+
+    auto indexes = bs::TeamIndexes::parseIndexes(
+        QStringLiteral(R"([{"name": "handbook", "path": "/Volumes/Team/handbook//index.db",
+                            "publishedRoot": "/Users/alice/Handbook/", "localRoot": "/Volumes/Team/handbook"},
+                           {"name": "specs", "path": "/Volumes/Specs/index.db"}])"),
+        &error);
+    QVERIFY2(indexes.has_value(), qPrintable(error));
+    QCOMPARE(indexes->size(), size_t(2));
+    QCOMPARE(indexes->at(0).path, QStringLiteral("/Volumes/Team/handbook/index.db"));
+    QCOMPARE(indexes->at(0).publishedRoot, QStringLiteral("/Users/alice/Handbook"));
+    QVERIFY(indexes->at(1).publishedRoot.isEmpty());
+    QVERIFY(indexes->at(1).localRoot.isEmpty());
+}
+
+void TestTeamIndex::testParseIndexesRejectsInvalid()
+{
+    const QStringList invalid = {
+        QStringLiteral("{}"),
+        QStringLiteral(R"([{"name": "team docs", "path": "/Volumes/a.db"}])"),
+        QStringLiteral(R"([{"name": "local", "path": "/Volumes/a.db"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "/Volumes/a.db"}, {"name": "A", "path": "/b.db"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "a.db"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "/a.db", "publishedRoot": "/Users/alice"}])"),
+        QStringLiteral(R"([{"name": "a", "path": "/a.db", "publishedRoot": "/Users/alice",
+                           "localRoot": "Team"}])"),
+    };
+    for (const QString& json : invalid) {
+        QString error;
+        QVERIFY2(!bs::TeamIndexes::parseIndexes(json, &error).has_value(), qPrintable(json));
+        QVERIFY(!error.isEmpty());
+    }
+}
+
+void TestTeamIndex::testLocalPath()
+{
+    const bs::TeamIndex index = handbook();
+    QCOMPARE(bs::TeamIndexes::localPath(index, QStringLiteral("/Users/alice/Handbook/Onboarding.md")),
+             QStringLiteral("/Volumes/Team/handbook/Onboarding.md"));
+    QCOMPARE(bs::TeamIndexes::localPath(index, QStringLiteral("/Users/alice/Handbook")),
+             QStringLiteral("/Volumes/Team/handbook"));
+    // Only whole path components match.
+    QCOMPARE(bs::TeamIndexes::localPath(index, QStringLiteral("/Users/alice/Handbook2/a.md")),
+             QStringLiteral("/Users/alice/Handbook2/a.md"));
+
+    bs::TeamIndex unmapped = index;
+    unmapped.publishedRoot.clear();
+    unmapped.localRoot.clear();
+    QCOMPARE(bs::TeamIndexes::localPath(unmapped, QStringLiteral("/Users/alice/Handbook/a.md")),
+             QStringLiteral("/Users/alice/Handbook/a.md"));
+}
+
+void TestTeamIndex::testOpenReadOnlyRefusesWrites()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString dbPath = dir.filePath(QStringLiteral("index.db"));
+    QVERIFY(!bs::SQLiteStore::openReadOnly(dbPath).has_value());
+    QVERIFY(!QFile::exists(dbPath));
+
+    publish(dbPath, QStringLiteral("/Users/alice/Handbook/Onboarding.md"),
+            QStringLiteral("Laptop setup and first week checklist"));
+    auto store = bs::SQLiteStore::openReadOnly(dbPath);
+    QVERIFY(store.has_value());
+    QVERIFY(!store->setSetting(QStringLiteral("bm25WeightName"), QStringLiteral("1")));
+    store->deleteItemByPath(QStringLiteral("/Users/alice/Handbook/Onboarding.md"));
+    QCOMPARE(store->searchFts5(QStringLiteral("checklist")).size(), size_t(1));
+}
+
+void TestTeamIndex::testSearch()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    const QString dbPath = dir.filePath(QStringLiteral("index.db"));
+    publish(dbPath, QStringLiteral("/Users/alice/Handbook/Onboarding.md"),
+            QStringLiteral("Laptop setup and first week checklist"));
+    auto store = bs::SQLiteStore::openReadOnly(dbPath);
+    QVERIFY(store.has_value());
+
+    const QJsonObject result =
+        bs::TeamIndexes::search(*store, handbook(), QStringLiteral("checklist"), 20);
+    const QJsonArray rows = result.value(QStringLiteral("results")).toArray();
+    QCOMPARE(rows.size(), 1);
+    const QJsonObject row = rows.at(0).toObject();
+    QCOMPARE(row.value(QStringLiteral("path")).toString(),
+             QStringLiteral("/Volumes/Team/handbook/Onboarding.md"));
+    QCOMPARE(row.value(QStringLiteral("name")).toString(), QStringLiteral("Onboarding.md"));
+    QCOMPARE(row.value(QStringLiteral("matchType")).toString(), QStringLiteral("contentMatch"));
+    QVERIFY(!row.value(QStringLiteral("snippet")).toString().contains(QStringLiteral("<b>")));
+    QCOMPARE(result.value(QStringLiteral("totalMatches")).toInt(), 1);
+
+    QVERIFY(bs::TeamIndexes::search(*store, handbook(), QStringLiteral("payroll"), 20)
+                .value(QStringLiteral("results"))
+                .toArray()
+                .isEmpty());
+}
+
+void TestTeamIndex::testMergeLabelsTeamRows()
+{
+    QJsonObject localRow;
+    localRow[QStringLiteral("itemId")] = 7;
+    localRow[QStringLiteral("path")] = QStringLiteral("/Users/me/notes.md");
+    QJsonObject local;
+    local[QStringLiteral("results")] = QJsonArray{localRow};
+    local[QStringLiteral("totalMatches")] = 1;
+
+    QJsonObject teamRow;
+    teamRow[QStringLiteral("itemId")] = 42;
+    teamRow[QStringLiteral("path")] = QStringLiteral("/Volumes/Team/handbook/Onboarding.md");
+    QJsonObject teamResult;
+    teamResult[QStringLiteral("results")] = QJsonArray{teamRow};
+    teamResult[QStringLiteral("totalMatches")] = 1;
+    bs::PeerReply reply;
+    reply.json = teamResult;
+
+    const QJsonObject merged =
+        bs::FederatedSearch::merge(local, 3, {{QStringLiteral("handbook"), reply, true}}, 20);
+    const QJsonArray rows = merged.value(QStringLiteral("results")).toArray();
+    QCOMPARE(rows.size(), 2);
+    const QJsonObject team = rows.at(1).toObject();
+    QCOMPARE(team.value(QStringLiteral("itemId")).toInt(), 0);
+    QCOMPARE(team.value(QStringLiteral("sourceItemId")).toInt(), 42);
+    QCOMPARE(team.value(QStringLiteral("team")).toString(), QStringLiteral("handbook"));
+    QCOMPARE(team.value(QStringLiteral("source")).toString(), QStringLiteral("handbook"));
+    QVERIFY(!team.contains(QStringLiteral("peer")));
+    QCOMPARE(team.value(QStringLiteral("contentDescription")).toString(),
+             QStringLiteral("handbook · /Volumes/Team/handbook"));
+    QCOMPARE(merged.value(QStringLiteral("totalMatches")).toInt(), 2);
+}
+
+QTEST_MAIN(TestTeamIndex)
+#include "test_team_index.moc"
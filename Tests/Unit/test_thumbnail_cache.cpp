@@ -0,0 +1,104 @@
+#include <QtTest/QtTest>
+
+#include "core/fs/thumbnail_cache.h"
+
+#include <QDateTime>
+#include <QFile>
+#include <QTemporaryDir>
+
+class TestThumbnailCache : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testNormalizePixelSize();
+    void testCacheKeyTracksVersion();
+    void testStoreAndLookup();
+    void testPruneRemovesLeastRecentlyUsed();
+    void testClearRemovesAllEntries();
+};
+
+void TestThumbnailCache::testNormalizePixelSize()
+{
+    QCOMPARE(bs::ThumbnailCache::normalizePixelSize(256), 256);
+    QCOMPARE(bs::ThumbnailCache::normalizePixelSize(0), bs::ThumbnailCache::kMinPixelSize);
+    QCOMPARE(bs::ThumbnailCache::normalizePixelSize(100000), bs::ThumbnailCache::kMaxPixelSize);
+}
+
+void TestThumbnailCache::testCacheKeyTracksVersion()
+{
+    const QString path = QStringLiteral("/Users/alice/Documents/report.pdf");
+    const QString key = bs::ThumbnailCache::cacheKey(path, 1700000000.5, 256);
+    QCOMPARE(key, bs::ThumbnailCache::cacheKey(path, 1700000000.5, 256));
+    QCOMPARE(key.size(), 40);
+    QVERIFY(key != bs::ThumbnailCache::cacheKey(path, 1700000001.5, 256));
+    QVERIFY(key != bs::ThumbnailCache::cacheKey(path, 1700000000.5, 128));
+    QVERIFY(key != bs::ThumbnailCache::cacheKey(path + QStringLiteral(".bak"), 1700000000.5, 256));
+}
+
+void TestThumbnailCache::testStoreAndLookup()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::ThumbnailCache cache(dir.path() + QStringLiteral("/thumbnails"));
+
+    const QString key = bs::ThumbnailCache::cacheKey(QStringLiteral("/tmp/a.png"), 1.0, 64);
+    QVERIFY(!cache.lookup(key).has_value());
+
+    const QByteArray png("\x89PNG\r\n\x1a\nfake", 12);
+    QVERIFY(cache.store(key, png));
+    QVERIFY(cache.entryPath(key).startsWith(cache.rootDir() + QLatin1Char('/') + key.left(2)));
+    QVERIFY(QFile::exists(cache.entryPath(key)));
+    QCOMPARE(cache.lookup(key).value(), png);
+}
+
+void TestThumbnailCache::testPruneRemovesLeastRecentlyUsed()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::ThumbnailCache cache(dir.path(), 25);
+
+    const QByteArray png(10, 'x');
+    const QString oldest = bs::ThumbnailCache::cacheKey(QStringLiteral("/a"), 1.0, 64);
+    const QString middle = bs::ThumbnailCache::cacheKey(QStringLiteral("/b"), 1.0, 64);
+    const QString newest = bs::ThumbnailCache::cacheKey(QStringLiteral("/c"), 1.0, 64);
+    QVERIFY(cache.store(oldest, png));
+    QVERIFY(cache.store(middle, png));
+    QVERIFY(cache.store(newest, png));
+
+    const QDateTime now = QDateTime::currentDateTimeUtc();
+    const auto age = [&cache](const QString& key, const QDateTime& when) {
+        QFile file(cache.entryPath(key));
+        QVERIFY(file.open(QIODevice::ReadWrite));
+        QVERIFY(file.setFileTime(when, QFileDevice::FileModificationTime));
+    };
+    age(oldest, now.addSecs(-300));
+    age(middle, now.addSecs(-200));
+    age(newest, now.addSecs(-100));
+
+    QCOMPARE(cache.prune(), 1);
+    QVERIFY(!cache.lookup(oldest).has_value());
+    QVERIFY(cache.lookup(middle).has_value());
+    QVERIFY(cache.lookup(newest).has_value());
+    QCOMPARE(cache.prune(), 0);
+}
+
+void TestThumbnailCache::testClearRemovesAllEntries()
+{
+    QTemporaryDir dir;
+    QVERIFY(dir.isValid());
+    bs::ThumbnailCache cache(dir.path());
+
+    const QByteArray png(10, 'x');
+    const QString first = bs::ThumbnailCache::cacheKey(QStringLiteral("/a"), 1.0, 64);
+    const QString second = bs::ThumbnailCache::cacheKey(QStringLiteral("/b"), 1.0, 64);
+    QVERIFY(cache.store(first, png));
+    QVERIFY(cache.store(second, png));
+
+    QCOMPARE(cache.clear(), 2);
+    QVERIFY(!cache.lookup(first).has_value());
+    QVERIFY(!cache.lookup(second).has_value());
+    QCOMPARE(cache.clear(), 0);
+}
+
+QTEST_MAIN(TestThumbnailCache)
+#include "test_thumbnail_cache.moc"
@@ -0,0 +1,277 @@
+#include <QtTest/QtTest>
+
+#include "core/ipc/otlp_exporter.h"
+#include "core/shared/tracing.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+
+class TestTracing : public QObject {
+    Q_OBJECT
+
+private slots:
+    void init();
+    void cleanup();
+
+    void testTraceparentRoundTrip();
+    void testRejectsMalformedTraceparent();
+    void testNestedSpans();
+    void testRemoteParent();
+    void testUnsampledTraceRecordsNothing();
+    void testDisabledIsNoop();
+    void testPendingSpan();
+    void testBufferIsBounded();
+    void testOtlpJson();
+    void testEndpointFromEnvironment();
+};
+
+void TestTracing::init()
+{
+    bs::Tracer::instance().configure(QStringLiteral("betterspotlight-test"), 1.0);
+}
+
+void TestTracing::cleanup()
+{
+    bs::Tracer::instance().disable();
+    bs::Tracer::setCurrent({});
+}
+
+void TestTracing::testTraceparentRoundTrip()
+{
+    const QByteArray header("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01");
+    const bs::TraceContext context = bs::TraceContext::fromTraceparent(header);
+    QVERIFY(context.isValid());
+    QCOMPARE(context.traceId, QByteArray("4bf92f3577b34da6a3ce929d0e0e4736"));
+    QCOMPARE(context.spanId, QByteArray("00f067aa0ba902b7"));
+    QVERIFY(context.sampled);
+    QCOMPARE(context.toTraceparent(), header);
+
+    const bs::TraceContext unsampled = bs::TraceContext::fromTraceparent(
+        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00");
+    QVERIFY(unsampled.isValid());
+    QVERIFY(!unsampled.sampled);
+}
+
+void TestTracing::testRejectsMalformedTraceparent()
+{
+    QVERIFY(!bs::TraceContext::fromTraceparent("").isValid());
+    QVERIFY(!bs::TraceContext::fromTraceparent(
+                 "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").isValid());
+    QVERIFY(!bs::TraceContext::fromTraceparent(
+                 "00-00000000000000000000000000000000-00f067aa0ba902b7-01").isValid());
+    QVERIFY(!bs::TraceContext::fromTraceparent(
+                 "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01").isValid());
+    QVERIFY(!bs::TraceContext::fromTraceparent(
+                 "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01").isValid());
+    QVERIFY(!bs::TraceContext::fromTraceparent("00-4bf92f35-00f067aa0ba902b7-01").isValid());
+}
+
+void TestTracing::testNestedSpans()
+{
+    bs::TraceContext rootContext;
+    bs::TraceContext childContext;
+    {
+        bs::Span root(QStringLiteral("query.search"), bs::Span::Kind::Server);
+        rootContext = root.context();
+        QVERIFY(root.isRecording());
+        QCOMPARE(bs::Tracer::current().spanId, rootContext.spanId);
+        {
+            bs::Span child(QStringLiteral("search.parse"));
+            child.setAttribute(QStringLiteral("tokens"), 3);
+            childContext = child.context();
+        }
+        QCOMPARE(bs::Tracer::current().spanId, rootContext.spanId);
+    }
+    QVERIFY(!bs::Tracer::current().isValid());
+
+    const auto spans = bs::Tracer::instance().takeFinished(10);
+    QCOMPARE(spans.size(), size_t(2));
+    // Children end, and are recorded, first.
+    QCOMPARE(spans[0].name, QStringLiteral("search.parse"));
+    QCOMPARE(spans[0].traceId, rootContext.traceId);
+    QCOMPARE(spans[0].parentSpanId, rootContext.spanId);
+    QCOMPARE(spans[0].spanId, childContext.spanId);
+    QCOMPARE(spans[0].attributes.value(QStringLiteral("tokens")).toInt(), 3);
+    QCOMPARE(spans[1].name, QStringLiteral("query.search"));
+    QVERIFY(spans[1].parentSpanId.isEmpty());
+    QCOMPARE(spans[1].kind, 2);
+    QVERIFY(spans[1].startNs <= spans[0].startNs);
+    QVERIFY(spans[1].endNs >= spans[0].endNs);
+}
+
+void TestTracing::testRemoteParent()
+{
+    const bs::TraceContext remote = bs::TraceContext::fromTraceparent(
+        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01");
+    {
+        const bs::ScopedTraceContext scope(remote);
+        bs::Span span(QStringLiteral("indexer.getQueueStatus"), bs::Span::Kind::Server);
+        span.setError(QStringLiteral("NOT_FOUND"));
+    }
+    QVERIFY(!bs::Tracer::current().isValid());
+
+    const auto spans = bs::Tracer::instance().takeFinished(10);
+    QCOMPARE(spans.size(), size_t(1));
+    QCOMPARE(spans[0].traceId, remote.traceId);
+    QCOMPARE(spans[0].parentSpanId, remote.spanId);
+    QVERIFY(spans[0].error);
+    QCOMPARE(spans[0].statusMessage, QStringLiteral("NOT_FOUND"));
+}
+
+void TestTracing::testUnsampledTraceRecordsNothing()
+{
+    bs::Tracer::instance().configure(QStringLiteral("betterspotlight-test"), 0.0);
+    {
+        bs::Span root(QStringLiteral("query.search"));
+        QVERIFY(!root.isRecording());
+        // Still propagated, so downstream services skip the trace too.
+        QVERIFY(root.context().isValid());
+        QVERIFY(!root.context().sampled);
+        bs::Span child(QStringLiteral("search.parse"));
+        QVERIFY(!child.isRecording());
+        QCOMPARE(child.context().traceId, root.context().traceId);
+    }
+    QCOMPARE(bs::Tracer::instance().bufferedCount(), size_t(0));
+}
+
+void TestTracing::testDisabledIsNoop()
+{
+    bs::Tracer::instance().disable();
+    {
+        bs::Span span(QStringLiteral("query.search"));
+        QVERIFY(!span.isRecording());
+        QVERIFY(!span.context().isValid());
+        QVERIFY(!bs::Tracer::current().isValid());
+    }
+    QVERIFY(!bs::PendingSpan::startTrace().isActive());
+    QCOMPARE(bs::Tracer::instance().bufferedCount(), size_t(0));
+}
+
+void TestTracing::testPendingSpan()
+{
+    const bs::PendingSpan item = bs::PendingSpan::startTrace();
+    QVERIFY(item.isActive());
+    {
+        // As a prep worker would, on another thread than the one that enqueued.
+        bs::Span extract(QStringLiteral("index.extract"), item.context);
+    }
+    item.finish(QStringLiteral("index.item"), {{QStringLiteral("lane"), QStringLiteral("live")}});
+
+    const auto spans = bs::Tracer::instance().takeFinished(10);
+    QCOMPARE(spans.size(), size_t(2));
+    QCOMPARE(spans[0].parentSpanId, item.context.spanId);
+    QCOMPARE(spans[1].name, QStringLiteral("index.item"));
+    QCOMPARE(spans[1].spanId, item.context.spanId);
+    QVERIFY(spans[1].parentSpanId.isEmpty());
+    QCOMPARE(spans[1].startNs, item.startNs);
+    QCOMPARE(spans[1].attributes.value(QStringLiteral("lane")).toString(),
+             QStringLiteral("live"));
+}
+
+void TestTracing::testBufferIsBounded()
+{
+    const uint64_t droppedBefore = bs::Tracer::instance().droppedCount();
+    for (size_t i = 0; i < bs::Tracer::kMaxBufferedSpans + 5; ++i) {
+        bs::Span span(QStringLiteral("s"));
+    }
+    QCOMPARE(bs::Tracer::instance().bufferedCount(), bs::Tracer::kMaxBufferedSpans);
+    QCOMPARE(bs::Tracer::instance().droppedCount() - droppedBefore, uint64_t(5));
+    QCOMPARE(bs::Tracer::instance().takeFinished(100).size(), size_t(100));
+    QCOMPARE(bs::Tracer::instance().bufferedCount(), bs::Tracer::kMaxBufferedSpans - 100);
+}
+
+void TestTracing::testOtlpJson()
+{
+    bs::FinishedSpan span;
+    span.traceId = "4bf92f3577b34da6a3ce929d0e0e4736";
+    span.spanId = "00f067aa0ba902b7";
+    span.parentSpanId = "1111111111111111";
+    span.name = QStringLiteral("search.semantic");
+    span.startNs = 1773100000000000000LL;
+    span.endNs = 1773100000800000000LL;
+    span.attributes[QStringLiteral("candidates")] = 42;
+    span.attributes[QStringLiteral("cached")] = false;
+    span.attributes[QStringLiteral("score")] = 0.5;
+    span.attributes[QStringLiteral("lane")] = QStringLiteral("live");
+    span.error = true;
+    span.statusMessage = QStringLiteral("TIMEOUT");
+
+    const QJsonObject body = QJsonDocument::fromJson(
+        bs::Tracer::encodeOtlpJson(QStringLiteral("betterspotlight-query"), {span})).object();
+    const QJsonObject resourceSpans = body.value(QStringLiteral("resourceSpans")).toArray()[0].toObject();
+    const QJsonObject serviceAttribute = resourceSpans.value(QStringLiteral("resource")).toObject()
+        .value(QStringLiteral("attributes")).toArray()[0].toObject();
+    QCOMPARE(serviceAttribute.value(QStringLiteral("key")).toString(),
+             QStringLiteral("service.name"));
+    QCOMPARE(serviceAttribute.value(QStringLiteral("value")).toObject()
+                 .value(QStringLiteral("stringValue")).toString(),
+             QStringLiteral("betterspotlight-query"));
+
+    const QJsonObject scopeSpans = resourceSpans.value(QStringLiteral("scopeSpans")).toArray()[0].toObject();
+    QCOMPARE(scopeSpans.value(QStringLiteral("scope")).toObject()
+                 .value(QStringLiteral("name")).toString(),
+             QStringLiteral("betterspotlight"));
+    const QJsonObject out = scopeSpans.value(QStringLiteral("spans")).toArray()[0].toObject();
+    QCOMPARE(out.value(QStringLiteral("traceId")).toString(),
+             QStringLiteral("4bf92f3577b34da6a3ce929d0e0e4736"));
+    QCOMPARE(out.value(QStringLiteral("parentSpanId")).toString(),
+             QStringLiteral("1111111111111111"));
+    QCOMPARE(out.value(QStringLiteral("kind")).toInt(), 1);
+    // 64-bit nanoseconds travel as strings in OTLP JSON.
+    QCOMPARE(out.value(QStringLiteral("startTimeUnixNano")).toString(),
+             QStringLiteral("1773100000000000000"));
+    QCOMPARE(out.value(QStringLiteral("endTimeUnixNano")).toString(),
+             QStringLiteral("1773100000800000000"));
+    QCOMPARE(out.value(QStringLiteral("status")).toObject().value(QStringLiteral("code")).toInt(), 2);
+
+    QHash<QString, QJsonObject> attributes;
+    for (const QJsonValue& value : out.value(QStringLiteral("attributes")).toArray()) {
+        attributes.insert(value.toObject().value(QStringLiteral("key")).toString(),
+                          value.toObject().value(QStringLiteral("value")).toObject());
+    }
+    QCOMPARE(attributes.value(QStringLiteral("candidates")).value(QStringLiteral("intValue")).toString(),
+             QStringLiteral("42"));
+    QCOMPARE(attributes.value(QStringLiteral("cached")).value(QStringLiteral("boolValue")).toBool(true),
+             false);
+    QCOMPARE(attributes.value(QStringLiteral("score")).value(QStringLiteral("doubleValue")).toDouble(),
+             0.5);
+    QCOMPARE(attributes.value(QStringLiteral("lane")).value(QStringLiteral("stringValue")).toString(),
+             QStringLiteral("live"));
+}
+
+void TestTracing::testEndpointFromEnvironment()
+{
+    qunsetenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT");
+    qunsetenv("OTEL_EXPORTER_OTLP_ENDPOINT");
+    qunsetenv("OTEL_SDK_DISABLED");
+    QVERIFY(bs::OtlpExporter::endpointFromEnvironment().isEmpty());
+
+    qputenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318/");
+    QCOMPARE(bs::OtlpExporter::endpointFromEnvironment(),
+             QUrl(QStringLiteral("http://localhost:4318/v1/traces")));
+
+    qputenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://collector:4318/custom");
+    QCOMPARE(bs::OtlpExporter::endpointFromEnvironment(),
+             QUrl(QStringLiteral("http://collector:4318/custom")));
+
+    qputenv("OTEL_SDK_DISABLED", "true");
+    QVERIFY(bs::OtlpExporter::endpointFromEnvironment().isEmpty());
+    qunsetenv("OTEL_SDK_DISABLED");
+
+    qputenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "grpc://localhost:4317");
+    QVERIFY(bs::OtlpExporter::endpointFromEnvironment().isEmpty());
+
+    qputenv("OTEL_TRACES_SAMPLER_ARG", "0.25");
+    QCOMPARE(bs::OtlpExporter::sampleRatioFromEnvironment(), 0.25);
+    qputenv("OTEL_TRACES_SAMPLER_ARG", "7");
+    QCOMPARE(bs::OtlpExporter::sampleRatioFromEnvironment(), 1.0);
+    qputenv("OTEL_TRACES_SAMPLER_ARG", "often");
+    QCOMPARE(bs::OtlpExporter::sampleRatioFromEnvironment(), 1.0);
+
+    qunsetenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT");
+    qunsetenv("OTEL_EXPORTER_OTLP_ENDPOINT");
+    qunsetenv("OTEL_TRACES_SAMPLER_ARG");
+}
+
+QTEST_MAIN(TestTracing)
+#include "test_tracing.moc"
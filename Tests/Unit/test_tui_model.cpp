@@ -0,0 +1,183 @@
+#include <QtTest/QtTest>
+
+#include "cli/tui_model.h"
+
+namespace {
+
+using Key = bs::TuiKeyEvent::Key;
+
+bs::TuiKeyEvent keyEvent(Key key, const QString& text = QString())
+{
+    bs::TuiKeyEvent event;
+    event.key = key;
+    event.text = text;
+    return event;
+}
+
+QJsonObject makeHit(const QString& path, const QString& snippet = QString(),
+                    int highlightOffset = -1, int highlightLength = 0)
+{
+    QJsonObject hit;
+    hit[QStringLiteral("path")] = path;
+    hit[QStringLiteral("name")] = path.section(QLatin1Char('/'), -1);
+    hit[QStringLiteral("kind")] = QStringLiteral("text");
+    hit[QStringLiteral("snippet")] = snippet;
+    if (highlightOffset >= 0) {
+        QJsonObject highlight;
+        highlight[QStringLiteral("offset")] = highlightOffset;
+        highlight[QStringLiteral("length")] = highlightLength;
+        hit[QStringLiteral("highlights")] = QJsonArray{highlight};
+    }
+    return hit;
+}
+
+} // namespace
+
+class TestTuiModel : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testDecodesKeysAndText();
+    void testBuffersPartialSequences();
+    void testLoneEscapeFlushes();
+    void testQueryEditingRequestsSearch();
+    void testSelectionAndActions();
+    void testSelectionFollowsPathAcrossRefresh();
+    void testHighlightSnippet();
+    void testRenderFitsTerminal();
+};
+
+void TestTuiModel::testDecodesKeysAndText()
+{
+    bs::TuiKeyDecoder decoder;
+    const auto events = decoder.feed(QByteArray("ab\x1b[A\x1b[B\r\x7f\x0f\x19\x15\x03", 14));
+    QCOMPARE(events.size(), static_cast<size_t>(9));
+    QCOMPARE(events[0].key, Key::Text);
+    QCOMPARE(events[0].text, QStringLiteral("ab"));
+    QCOMPARE(events[1].key, Key::Up);
+    QCOMPARE(events[2].key, Key::Down);
+    QCOMPARE(events[3].key, Key::Enter);
+    QCOMPARE(events[4].key, Key::Backspace);
+    QCOMPARE(events[5].key, Key::Reveal);
+    QCOMPARE(events[6].key, Key::CopyPath);
+    QCOMPARE(events[7].key, Key::ClearLine);
+    QCOMPARE(events[8].key, Key::Interrupt);
+}
+
+void TestTuiModel::testBuffersPartialSequences()
+{
+    bs::TuiKeyDecoder decoder;
+    QVERIFY(decoder.feed(QByteArray("\x1b[", 2)).empty());
+    QVERIFY(decoder.hasPending());
+    auto events = decoder.feed(QByteArray("6~", 2));
+    QCOMPARE(events.size(), static_cast<size_t>(1));
+    QCOMPARE(events[0].key, Key::PageDown);
+
+    // "é" split across reads.
+    const QByteArray accented = QStringLiteral("é").toUtf8();
+    QVERIFY(decoder.feed(accented.left(1)).empty());
+    events = decoder.feed(accented.mid(1));
+    QCOMPARE(events.size(), static_cast<size_t>(1));
+    QCOMPARE(events[0].text, QStringLiteral("é"));
+}
+
+void TestTuiModel::testLoneEscapeFlushes()
+{
+    bs::TuiKeyDecoder decoder;
+    QVERIFY(decoder.feed(QByteArray("\x1b", 1)).empty());
+    const auto events = decoder.flushPendingEscape();
+    QCOMPARE(events.size(), static_cast<size_t>(1));
+    QCOMPARE(events[0].key, Key::Escape);
+    QVERIFY(!decoder.hasPending());
+}
+
+void TestTuiModel::testQueryEditingRequestsSearch()
+{
+    bs::TuiModel model;
+    QCOMPARE(model.handleKey(keyEvent(Key::Text, QStringLiteral("hello world"))),
+             bs::TuiModel::Action::Search);
+    QCOMPARE(model.handleKey(keyEvent(Key::DeleteWord)), bs::TuiModel::Action::Search);
+    QCOMPARE(model.query(), QStringLiteral("hello "));
+    QCOMPARE(model.handleKey(keyEvent(Key::Backspace)), bs::TuiModel::Action::Search);
+    QCOMPARE(model.query(), QStringLiteral("hello"));
+    QCOMPARE(model.handleKey(keyEvent(Key::ClearLine)), bs::TuiModel::Action::Search);
+    QVERIFY(model.query().isEmpty());
+    QCOMPARE(model.handleKey(keyEvent(Key::Backspace)), bs::TuiModel::Action::None);
+    QCOMPARE(model.handleKey(keyEvent(Key::Escape)), bs::TuiModel::Action::Quit);
+}
+
+void TestTuiModel::testSelectionAndActions()
+{
+    bs::TuiModel model;
+    QCOMPARE(model.handleKey(keyEvent(Key::Enter)), bs::TuiModel::Action::None);
+
+    model.setResults(QJsonArray{makeHit(QStringLiteral("/a/one.txt")),
+                                makeHit(QStringLiteral("/a/two.txt")),
+                                makeHit(QStringLiteral("/a/three.txt"))},
+                     5, false);
+    QCOMPARE(model.selectedIndex(), 0);
+    model.handleKey(keyEvent(Key::Down));
+    model.handleKey(keyEvent(Key::Down));
+    model.handleKey(keyEvent(Key::Down));
+    QCOMPARE(model.selectedIndex(), 2);
+    model.handleKey(keyEvent(Key::PageUp));
+    QCOMPARE(model.selectedIndex(), 0);
+
+    QCOMPARE(model.handleKey(keyEvent(Key::Enter)), bs::TuiModel::Action::Open);
+    QCOMPARE(model.handleKey(keyEvent(Key::Reveal)), bs::TuiModel::Action::Reveal);
+    QCOMPARE(model.handleKey(keyEvent(Key::CopyPath)), bs::TuiModel::Action::CopyPath);
+    QCOMPARE(model.selectedResult().value(QStringLiteral("path")).toString(),
+             QStringLiteral("/a/one.txt"));
+}
+
+void TestTuiModel::testSelectionFollowsPathAcrossRefresh()
+{
+    bs::TuiModel model;
+    model.setResults(QJsonArray{makeHit(QStringLiteral("/a/one.txt")),
+                                makeHit(QStringLiteral("/a/two.txt"))},
+                     5, false);
+    model.handleKey(keyEvent(Key::Down));
+    model.setResults(QJsonArray{makeHit(QStringLiteral("/a/zero.txt")),
+                                makeHit(QStringLiteral("/a/one.txt")),
+                                makeHit(QStringLiteral("/a/two.txt"))},
+                     5, false);
+    QCOMPARE(model.selectedIndex(), 2);
+
+    model.setResults(QJsonArray{makeHit(QStringLiteral("/a/other.txt"))}, 5, false);
+    QCOMPARE(model.selectedIndex(), 0);
+}
+
+void TestTuiModel::testHighlightSnippet()
+{
+    const QJsonObject hit =
+        makeHit(QStringLiteral("/a/notes.txt"), QStringLiteral("the quarterly\nreport"), 4, 9);
+    QCOMPARE(bs::TuiModel::highlightSnippet(hit, QStringLiteral("["), QStringLiteral("]")),
+             QStringLiteral("the [quarterly] report"));
+
+    const QJsonObject outOfRange =
+        makeHit(QStringLiteral("/a/notes.txt"), QStringLiteral("short"), 3, 50);
+    QCOMPARE(bs::TuiModel::highlightSnippet(outOfRange, QStringLiteral("["), QStringLiteral("]")),
+             QStringLiteral("sho[rt]"));
+}
+
+void TestTuiModel::testRenderFitsTerminal()
+{
+    bs::TuiModel model;
+    model.handleKey(keyEvent(Key::Text, QStringLiteral("report")));
+    QJsonArray results;
+    for (int i = 0; i < 40; ++i) {
+        results.append(makeHit(QStringLiteral("/Users/test/Documents/report-%1.txt").arg(i),
+                               QStringLiteral("quarterly report body"), 10, 6));
+    }
+    model.setResults(results, 12, false);
+
+    const QString frame = model.render(60, 20);
+    QVERIFY(frame.startsWith(QStringLiteral("\x1b[H")));
+    QCOMPARE(frame.count(QStringLiteral("\r\n")), 19);
+    QVERIFY(frame.contains(QStringLiteral("40 results")));
+    QVERIFY(frame.contains(QStringLiteral("\x1b[1;33mreport\x1b[0m")));
+    QVERIFY(frame.endsWith(QStringLiteral("\x1b[1;9H")));
+}
+
+QTEST_MAIN(TestTuiModel)
+#include "test_tui_model.moc"
@@ -18,6 +18,7 @@ private slots:
     void testAddAndSearch();
     void testAddMultipleVectors();
     void testDeleteVector();
+    void testEraseVector();
     void testSearchEmptyIndex();
     void testSearchKParameter();
     void testTotalElements();
@@ -162,6 +163,36 @@ void TestVectorIndex::testDeleteVector()
     QCOMPARE(index.deletedElements(), 1);
 }
 
+void TestVectorIndex::testEraseVector()
+{
+    bs::VectorIndex::IndexMetadata meta;
+    meta.dimensions = kTestDimensions;
+    meta.modelId = "unit-test-model";
+    meta.generationId = "v1";
+    bs::VectorIndex index(meta);
+    QVERIFY(index.create());
+
+    std::vector<uint64_t> labels;
+    for (int i = 0; i < 3; ++i) {
+        const std::vector<float> vec = makeVector(i);
+        labels.push_back(index.addVector(vec.data()));
+    }
+
+    QVERIFY(index.deleteVector(labels[0]));
+    QCOMPARE(index.unerasedDeletedLabels(), std::vector<uint64_t>({labels[0]}));
+    QVERIFY(index.eraseVector(labels[0]));  // already deleted: only zeroed
+    QVERIFY(index.eraseVector(labels[1]));
+    QVERIFY(!index.eraseVector(labels[2] + 100));
+    QCOMPARE(index.deletedElements(), 2);
+    QCOMPARE(index.liveLabels(), std::vector<uint64_t>({labels[2]}));
+    QVERIFY(index.unerasedDeletedLabels().empty());
+
+    const std::vector<float> query = makeVector(1);
+    for (const bs::VectorIndex::KnnResult& hit : index.search(query.data(), 3)) {
+        QVERIFY(hit.label != labels[1]);
+    }
+}
+
 void TestVectorIndex::testSearchEmptyIndex()
 {
     bs::VectorIndex::IndexMetadata meta;
@@ -0,0 +1,128 @@
+#include <QtTest/QtTest>
+
+#include "cli/watch_session.h"
+
+#include <QJsonArray>
+#include <QJsonObject>
+
+#include <vector>
+
+namespace {
+
+QJsonObject hit(qint64 itemId)
+{
+    QJsonObject json;
+    json[QStringLiteral("itemId")] = itemId;
+    json[QStringLiteral("path")] = QStringLiteral("/docs/%1.md").arg(itemId);
+    return json;
+}
+
+QJsonObject snapshot(qint64 subscriptionId, const QJsonArray& results)
+{
+    QJsonObject json;
+    json[QStringLiteral("subscriptionId")] = subscriptionId;
+    json[QStringLiteral("results")] = results;
+    return json;
+}
+
+QJsonObject update(qint64 subscriptionId, const QJsonArray& added)
+{
+    QJsonObject json;
+    json[QStringLiteral("subscriptionId")] = subscriptionId;
+    json[QStringLiteral("added")] = added;
+    return json;
+}
+
+QList<qint64> itemIds(const std::vector<QJsonArray>& printed)
+{
+    QList<qint64> ids;
+    for (const QJsonArray& batch : printed) {
+        for (const QJsonValue& value : batch) {
+            ids.append(value.toObject().value(QStringLiteral("itemId")).toInteger());
+        }
+    }
+    return ids;
+}
+
+} // namespace
+
+class TestWatchSession : public QObject {
+    Q_OBJECT
+
+private slots:
+    void testRepeatedItemPrintsOnce();
+    void testOtherSubscriptionsAreIgnored();
+    void testUnsubscribesOnExit();
+    void testNoUnsubscribeWithoutSubscription();
+};
+
+void TestWatchSession::testRepeatedItemPrintsOnce()
+{
+    std::vector<QJsonArray> printed;
+    bs::WatchSession session([&printed](const QJsonArray& results) { printed.push_back(results); },
+                             {});
+    session.start(snapshot(4, QJsonArray{hit(1), hit(2)}));
+    session.handleNotification(QStringLiteral("liveQueryUpdated"),
+                               update(4, QJsonArray{hit(2), hit(3)}));
+    // Dropped out and came back: already on screen.
+    session.handleNotification(QStringLiteral("liveQueryUpdated"), update(4, QJsonArray{hit(1)}));
+
+    QCOMPARE(printed.size(), size_t(2));
+    QCOMPARE(itemIds(printed), (QList<qint64>{1, 2, 3}));
+}
+
+void TestWatchSession::testOtherSubscriptionsAreIgnored()
+{
+    std::vector<QJsonArray> printed;
+    bs::WatchSession session([&printed](const QJsonArray& results) { printed.push_back(results); },
+                             {});
+    // Before the snapshot arrives nothing is ours yet.
+    session.handleNotification(QStringLiteral("liveQueryUpdated"), update(0, QJsonArray{hit(9)}));
+    session.start(snapshot(4, {}));
+    session.handleNotification(QStringLiteral("liveQueryUpdated"), update(5, QJsonArray{hit(7)}));
+    session.handleNotification(QStringLiteral("indexUpdated"), update(4, QJsonArray{hit(8)}));
+    session.handleNotification(QStringLiteral("liveQueryUpdated"), update(4, QJsonArray{hit(6)}));
+
+    QCOMPARE(itemIds(printed), (QList<qint64>{6}));
+}
+
+void TestWatchSession::testUnsubscribesOnExit()
+{
+    QList<qint64> unsubscribed;
+    std::vector<QJsonArray> printed;
+    {
+        bs::WatchSession session(
+            [&printed](const QJsonArray& results) { printed.push_back(results); },
+            [&unsubscribed](qint64 subscriptionId) { unsubscribed.append(subscriptionId); });
+        session.start(snapshot(4, QJsonArray{hit(1)}));
+        session.finish();
+        QCOMPARE(unsubscribed, (QList<qint64>{4}));
+
+        // Finished: updates no longer print and the scope exit is a no-op.
+        session.handleNotification(QStringLiteral("liveQueryUpdated"),
+                                   update(4, QJsonArray{hit(2)}));
+    }
+    QCOMPARE(unsubscribed, (QList<qint64>{4}));
+    QCOMPARE(itemIds(printed), (QList<qint64>{1}));
+
+    // An early return still unsubscribes.
+    {
+        bs::WatchSession session(
+            {}, [&unsubscribed](qint64 subscriptionId) { unsubscribed.append(subscriptionId); });
+        session.start(snapshot(11, {}));
+    }
+    QCOMPARE(unsubscribed, (QList<qint64>{4, 11}));
+}
+
+void TestWatchSession::testNoUnsubscribeWithoutSubscription()
+{
+    int unsubscribes = 0;
+    {
+        bs::WatchSession session({}, [&unsubscribes](qint64) { ++unsubscribes; });
+        session.finish();
+    }
+    QCOMPARE(unsubscribes, 0);
+}
+
+QTEST_MAIN(TestWatchSession)
+#include "test_watch_session.moc"
@@ -3,6 +3,10 @@
 #
 # Usage: ./benchmark_indexing.sh [FILE_COUNT]
 #
+# BS_BENCHMARK_CORPUS=synthetic indexes a synthetic home from corpusgen
+# (see Tests/Support/synthetic_home.h) instead of uniform text files, e.g.
+#   BS_BENCHMARK_CORPUS=synthetic ./benchmark_indexing.sh 1000000
+#
 # Reports:
 #   - Total indexing elapsed time
 #   - Indexing throughput (files/sec)
@@ -17,6 +21,9 @@ ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/../.." && pwd)"
 BUILD_DIR="${BS_BENCHMARK_BUILD_DIR:-${ROOT_DIR}/build}"
 INDEXER_BIN="${BS_INDEXER_BIN:-${BUILD_DIR}/src/services/indexer/betterspotlight-indexer}"
 QUERY_BIN="${BS_QUERY_BIN:-${BUILD_DIR}/src/services/query/betterspotlight-query}"
+CORPUSGEN_BIN="${BS_CORPUSGEN_BIN:-${BUILD_DIR}/Tests/corpusgen}"
+CORPUS="${BS_BENCHMARK_CORPUS:-uniform}"
+TIMEOUT_S="${BS_BENCHMARK_TIMEOUT_S:-300}"
 
 trap "rm -rf '$TEMP_DIR'" EXIT
 
@@ -29,17 +36,31 @@ if [[ ! -x "$INDEXER_BIN" || ! -x "$QUERY_BIN" ]]; then
   exit 1
 fi
 
-echo "Creating deterministic benchmark corpus..."
-for i in $(seq 1 "$FILE_COUNT"); do
-  cat >"$TEMP_DIR/file_${i}.txt" <<TXT
+if [[ "$CORPUS" == "synthetic" ]]; then
+  if [[ ! -x "$CORPUSGEN_BIN" ]]; then
+    echo "Error: corpusgen not found at $CORPUSGEN_BIN. Build the tests first."
+    exit 1
+  fi
+  echo "Generating synthetic home corpus..."
+  "$CORPUSGEN_BIN" --synthetic-home --files "$FILE_COUNT" \
+    --manifest "$TEMP_DIR/manifest.json" "$TEMP_DIR/home"
+  CORPUS_ROOT="$TEMP_DIR/home"
+  QUERY_TOKEN="bsbenchmedium"
+else
+  echo "Creating deterministic benchmark corpus..."
+  CORPUS_ROOT="$TEMP_DIR"
+  QUERY_TOKEN="benchmark_token"
+  for i in $(seq 1 "$FILE_COUNT"); do
+    cat >"$TEMP_DIR/file_${i}.txt" <<TXT
 benchmark_token file_$i
 This file is used for deterministic indexing throughput measurement.
 $(printf "alpha beta gamma delta epsilon %.0s" $(seq 1 80))
 TXT
-done
+  done
+fi
 echo "✓ Created $FILE_COUNT files"
 
-python3 - "$TEMP_DIR" "$INDEXER_BIN" "$QUERY_BIN" "$FILE_COUNT" <<'PY'
+python3 - "$CORPUS_ROOT" "$INDEXER_BIN" "$QUERY_BIN" "$FILE_COUNT" "$QUERY_TOKEN" "$TIMEOUT_S" <<'PY'
 import json
 import os
 import socket
@@ -51,6 +72,7 @@ import threading
 import time
 
 root, indexer_bin, query_bin, expected = sys.argv[1], sys.argv[2], sys.argv[3], int(sys.argv[4])
+query_token, timeout_s = sys.argv[5], float(sys.argv[6])
 uid = os.getuid()
 indexer_sock = f"/tmp/betterspotlight-{uid}/indexer.sock"
 query_sock = f"/tmp/betterspotlight-{uid}/query.sock"
@@ -176,7 +198,7 @@ sampler.start()
 while True:
     s0 = time.time()
     try:
-        sresp = rpc(query_sock, "search", {"query": "benchmark_token", "limit": 20},
+        sresp = rpc(query_sock, "search", {"query": query_token, "limit": 20},
                     rid=next_id(), timeout=10.0)
         if sresp.get("type") != "error":
             search_latencies_ms.append((time.time() - s0) * 1000.0)
@@ -190,7 +212,7 @@ while True:
     if pending == 0 and processing == 0 and (time.time() - start) > 0.5:
         break
 
-    if time.time() - start > 300:
+    if time.time() - start > timeout_s:
         print("WARN: benchmark timeout reached", file=sys.stderr)
         break
 
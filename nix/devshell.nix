@@ -16,6 +16,7 @@ pkgs.mkShell {
     poppler
     tesseract
     leptonica
+    zlib
     onnxruntime
   ];
 
@@ -135,6 +135,15 @@ function ensure_helpers_in_bundle() {
         cp -f "$src" "$dst"
         chmod +x "$dst"
     done
+
+    # PDF/OCR extraction runs in this per-file sandboxed helper.
+    local extract_helper="$BUILD_DIR/src/services/extractor/betterspotlight-extract-helper"
+    if [[ ! -x "$extract_helper" ]]; then
+        echo "Error: helper binary missing: $extract_helper" >&2
+        exit 1
+    fi
+    cp -f "$extract_helper" "$helpers_dir/betterspotlight-extract-helper"
+    chmod +x "$helpers_dir/betterspotlight-extract-helper"
 }
 
 function verify_bundle_contents() {
@@ -198,6 +207,7 @@ function maybe_run_macdeployqt() {
     for helper_exec in \
         "$APP_STAGE_PATH/Contents/Helpers/betterspotlight-indexer" \
         "$APP_STAGE_PATH/Contents/Helpers/betterspotlight-extractor" \
+        "$APP_STAGE_PATH/Contents/Helpers/betterspotlight-extract-helper" \
         "$APP_STAGE_PATH/Contents/Helpers/betterspotlight-query" \
         "$APP_STAGE_PATH/Contents/Helpers/betterspotlight-inference"; do
         if [[ -x "$helper_exec" ]]; then
@@ -0,0 +1,255 @@
+#include "automation_controller.h"
+
+#include "service_manager.h"
+#include "status_bar_bridge.h"
+#include "core/ipc/service_base.h"
+#include "core/shared/logging.h"
+
+#include <QDesktopServices>
+#include <QFileInfo>
+#include <QFileOpenEvent>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 1000;
+constexpr int kRequestTimeoutMs = 10000;
+
+// x-error codes, mirroring the HTTP API's status mapping.
+constexpr int kErrorRefused = 403;
+constexpr int kErrorNotFound = 404;
+constexpr int kErrorUnavailable = 503;
+
+// A web page can fire betterspotlight://open, so the URL route never
+// launches anything that runs code; Finder, Quick Look and copy still work.
+bool launchesCode(const QString& path)
+{
+    static const QStringList kLaunchSuffixes = {
+        QStringLiteral("app"), QStringLiteral("command"), QStringLiteral("tool"),
+        QStringLiteral("terminal"), QStringLiteral("workflow"), QStringLiteral("scpt"),
+        QStringLiteral("applescript"), QStringLiteral("pkg"), QStringLiteral("mpkg"),
+        QStringLiteral("jar"), QStringLiteral("sh"), QStringLiteral("webloc"),
+        QStringLiteral("inetloc"), QStringLiteral("fileloc"),
+    };
+    const QFileInfo info(path);
+    return (info.isFile() && info.isExecutable()) || info.isBundle()
+           || kLaunchSuffixes.contains(info.suffix().toLower());
+}
+
+} // namespace
+
+AutomationController::AutomationController(ServiceManager* serviceManager,
+                                           StatusBarBridge* statusBar, QObject* parent)
+    : QObject(parent)
+    , m_serviceManager(serviceManager)
+    , m_statusBar(statusBar)
+{
+    connect(m_serviceManager, &ServiceManager::allServicesReady,
+            this, &AutomationController::flushPending);
+}
+
+AutomationController::~AutomationController() = default;
+
+bool AutomationController::eventFilter(QObject* watched, QEvent* event)
+{
+    if (event->type() == QEvent::FileOpen
+        && handleUrl(static_cast<QFileOpenEvent*>(event)->url())) {
+        return true;
+    }
+    return QObject::eventFilter(watched, event);
+}
+
+bool AutomationController::handleUrl(const QUrl& url)
+{
+    if (url.scheme().compare(QLatin1String(AutomationUrl::kScheme), Qt::CaseInsensitive) != 0) {
+        return false;
+    }
+
+    QString error;
+    const std::optional<AutomationRequest> request = AutomationUrl::parse(url, &error);
+    if (!request.has_value()) {
+        LOG_WARN(bsCore, "Automation URL rejected: %s", qUtf8Printable(error));
+        return true;
+    }
+
+    // Showing the panel needs no services; everything else waits for them.
+    const bool interactive = request->command == AutomationCommand::Search
+                             && request->successUrl.isEmpty();
+    if (!interactive && !m_serviceManager->isReady()) {
+        m_pending.push_back(*request);
+        return true;
+    }
+    run(*request);
+    return true;
+}
+
+void AutomationController::flushPending()
+{
+    std::vector<AutomationRequest> pending;
+    pending.swap(m_pending);
+    for (const AutomationRequest& request : pending) {
+        run(request);
+    }
+}
+
+void AutomationController::run(const AutomationRequest& request)
+{
+    switch (request.command) {
+    case AutomationCommand::Search:
+        runSearch(request);
+        break;
+    case AutomationCommand::Open:
+        runOpen(request);
+        break;
+    case AutomationCommand::Reindex:
+        runReindex(request);
+        break;
+    }
+}
+
+void AutomationController::runSearch(const AutomationRequest& request)
+{
+    if (request.successUrl.isEmpty()) {
+        emit m_statusBar->showSearchWithQueryRequested(request.query);
+        return;
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("query")] = request.query;
+    params[QStringLiteral("limit")] = request.limit;
+    QString error;
+    const auto result = queryRequest(QStringLiteral("search"), params, &error);
+    if (!result.has_value()) {
+        fail(request, kErrorUnavailable, error);
+        return;
+    }
+
+    QJsonArray results;
+    QStringList paths;
+    for (const QJsonValue& value : result->value(QStringLiteral("results")).toArray()) {
+        const QJsonObject hit = value.toObject();
+        QJsonObject entry;
+        entry[QStringLiteral("itemId")] = hit.value(QStringLiteral("itemId"));
+        entry[QStringLiteral("name")] = hit.value(QStringLiteral("name"));
+        entry[QStringLiteral("path")] = hit.value(QStringLiteral("path"));
+        entry[QStringLiteral("kind")] = hit.value(QStringLiteral("kind"));
+        results.append(entry);
+        paths.append(hit.value(QStringLiteral("path")).toString());
+    }
+    succeed(request, {
+        {QStringLiteral("count"), QString::number(results.size())},
+        {QStringLiteral("top"), paths.value(0)},
+        {QStringLiteral("paths"), paths.join(QLatin1Char('\n'))},
+        {QStringLiteral("results"),
+         QString::fromUtf8(QJsonDocument(results).toJson(QJsonDocument::Compact))},
+    });
+}
+
+void AutomationController::runOpen(const AutomationRequest& request)
+{
+    QJsonObject params;
+    params[QStringLiteral("query")] = request.query;
+    params[QStringLiteral("limit")] = 1;
+    QString error;
+    const auto result = queryRequest(QStringLiteral("search"), params, &error);
+    if (!result.has_value()) {
+        fail(request, kErrorUnavailable, error);
+        return;
+    }
+    const QJsonObject top =
+        result->value(QStringLiteral("results")).toArray().at(0).toObject();
+    if (top.isEmpty()) {
+        fail(request, kErrorNotFound,
+             QStringLiteral("No results for '%1'").arg(request.query));
+        return;
+    }
+    const QString path = top.value(QStringLiteral("path")).toString();
+    if (request.action == QLatin1String("open") && launchesCode(path)) {
+        LOG_WARN(bsCore, "Automation open refused for launchable item '%s'",
+                 qUtf8Printable(path));
+        fail(request, kErrorRefused,
+             QStringLiteral("Refusing to launch %1; use action=reveal").arg(path));
+        return;
+    }
+
+    QJsonObject actionParams;
+    actionParams[QStringLiteral("action")] = request.action;
+    actionParams[QStringLiteral("itemId")] = top.value(QStringLiteral("itemId"));
+    actionParams[QStringLiteral("query")] = request.query;
+    actionParams[QStringLiteral("position")] = 0;
+    if (!queryRequest(QStringLiteral("performAction"), actionParams, &error).has_value()) {
+        fail(request, kErrorUnavailable, error);
+        return;
+    }
+    succeed(request, {
+        {QStringLiteral("action"), request.action},
+        {QStringLiteral("name"), top.value(QStringLiteral("name")).toString()},
+        {QStringLiteral("path"), path},
+    });
+}
+
+void AutomationController::runReindex(const AutomationRequest& request)
+{
+    if (!QFileInfo::exists(request.path)) {
+        fail(request, kErrorNotFound,
+             QStringLiteral("Path does not exist: %1").arg(request.path));
+        return;
+    }
+    if (!m_serviceManager->reindexPath(request.path)) {
+        fail(request, kErrorUnavailable,
+             QStringLiteral("Indexer did not accept the reindex request"));
+        return;
+    }
+    succeed(request, {{QStringLiteral("path"), request.path}});
+}
+
+std::optional<QJsonObject> AutomationController::queryRequest(const QString& method,
+                                                              const QJsonObject& params,
+                                                              QString* error)
+{
+    if (!m_queryClient) {
+        m_queryClient = std::make_unique<SocketClient>(this);
+    }
+    if (!m_queryClient->isConnected()
+        && !m_queryClient->connectToServer(ServiceBase::socketPath(QStringLiteral("query")),
+                                           kConnectTimeoutMs)) {
+        *error = QStringLiteral("Query service is not running");
+        return std::nullopt;
+    }
+    const auto response = m_queryClient->sendRequest(method, params, kRequestTimeoutMs);
+    if (!response.has_value()) {
+        m_queryClient->disconnect();
+        *error = QStringLiteral("Query service is not responding");
+        return std::nullopt;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        *error = response->value(QStringLiteral("error")).toObject()
+                     .value(QStringLiteral("message")).toString();
+        return std::nullopt;
+    }
+    return response->value(QStringLiteral("result")).toObject();
+}
+
+void AutomationController::succeed(const AutomationRequest& request,
+                                   const QList<QPair<QString, QString>>& params)
+{
+    if (!request.successUrl.isEmpty()) {
+        QDesktopServices::openUrl(AutomationUrl::callback(request.successUrl, params));
+    }
+}
+
+void AutomationController::fail(const AutomationRequest& request, int code,
+                                const QString& message)
+{
+    LOG_WARN(bsCore, "Automation request failed: %s", qUtf8Printable(message));
+    if (!request.errorUrl.isEmpty()) {
+        QDesktopServices::openUrl(AutomationUrl::callback(
+            request.errorUrl, {{QStringLiteral("errorCode"), QString::number(code)},
+                               {QStringLiteral("errorMessage"), message}}));
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,55 @@
+#pragma once
+
+#include "automation_url.h"
+#include "core/ipc/socket_client.h"
+
+#include <QJsonObject>
+#include <QObject>
+
+#include <memory>
+#include <vector>
+
+namespace bs {
+
+class ServiceManager;
+class StatusBarBridge;
+
+// Handles betterspotlight:// URLs delivered to the app (QFileOpenEvent) for
+// Shortcuts and AppleScript. Requests that need the services wait until
+// ServiceManager reports them ready, so a URL can cold-launch the app.
+class AutomationController : public QObject {
+    Q_OBJECT
+
+public:
+    AutomationController(ServiceManager* serviceManager, StatusBarBridge* statusBar,
+                         QObject* parent = nullptr);
+    ~AutomationController() override;
+
+    // Returns false for URLs of other schemes.
+    bool handleUrl(const QUrl& url);
+
+protected:
+    bool eventFilter(QObject* watched, QEvent* event) override;
+
+private:
+    void run(const AutomationRequest& request);
+    void runSearch(const AutomationRequest& request);
+    void runOpen(const AutomationRequest& request);
+    void runReindex(const AutomationRequest& request);
+    void flushPending();
+
+    // Query service request; nullopt with *error set on failure.
+    std::optional<QJsonObject> queryRequest(const QString& method, const QJsonObject& params,
+                                            QString* error);
+
+    void succeed(const AutomationRequest& request,
+                 const QList<QPair<QString, QString>>& params = {});
+    void fail(const AutomationRequest& request, int code, const QString& message);
+
+    ServiceManager* m_serviceManager = nullptr;
+    StatusBarBridge* m_statusBar = nullptr;
+    std::unique_ptr<SocketClient> m_queryClient;
+    std::vector<AutomationRequest> m_pending;
+};
+
+} // namespace bs
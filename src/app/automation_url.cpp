@@ -0,0 +1,118 @@
+#include "automation_url.h"
+
+#include <QDir>
+#include <QUrlQuery>
+
+namespace bs {
+
+namespace {
+
+const QStringList& allowedActions()
+{
+    static const QStringList kActions = {
+        QStringLiteral("open"), QStringLiteral("reveal"), QStringLiteral("quicklook"),
+        QStringLiteral("copyPath"),
+    };
+    return kActions;
+}
+
+std::optional<AutomationRequest> fail(QString* error, const QString& message)
+{
+    if (error) {
+        *error = message;
+    }
+    return std::nullopt;
+}
+
+} // namespace
+
+std::optional<AutomationRequest> AutomationUrl::parse(const QUrl& url, QString* error)
+{
+    if (url.scheme().compare(QLatin1String(kScheme), Qt::CaseInsensitive) != 0) {
+        return fail(error, QStringLiteral("Not a %1:// URL").arg(QLatin1String(kScheme)));
+    }
+
+    QString command = url.host().toLower();
+    if (command == QLatin1String("x-callback-url")) {
+        command = url.path().mid(1).toLower();
+    }
+
+    const QUrlQuery query(url);
+    const auto value = [&query](const QString& key) {
+        return query.queryItemValue(key, QUrl::FullyDecoded).trimmed();
+    };
+
+    AutomationRequest request;
+    for (const auto& [key, target] : {std::pair{QStringLiteral("x-success"), &request.successUrl},
+                                      std::pair{QStringLiteral("x-error"), &request.errorUrl}}) {
+        if (!query.hasQueryItem(key)) {
+            continue;
+        }
+        const QUrl callbackUrl(value(key), QUrl::StrictMode);
+        if (!isAllowedCallback(callbackUrl)) {
+            return fail(error, QStringLiteral("'%1' must be a shortcuts:// URL").arg(key));
+        }
+        *target = callbackUrl;
+    }
+
+    if (command == QLatin1String("search") || command == QLatin1String("open")) {
+        request.command = command == QLatin1String("search") ? AutomationCommand::Search
+                                                              : AutomationCommand::Open;
+        request.query = value(QStringLiteral("q"));
+        if (request.query.isEmpty()) {
+            return fail(error, QStringLiteral("Missing 'q'"));
+        }
+        if (query.hasQueryItem(QStringLiteral("limit"))) {
+            bool ok = false;
+            request.limit = value(QStringLiteral("limit")).toInt(&ok);
+            if (!ok || request.limit < 1 || request.limit > kMaxLimit) {
+                return fail(error,
+                            QStringLiteral("'limit' must be between 1 and %1").arg(kMaxLimit));
+            }
+        }
+        if (query.hasQueryItem(QStringLiteral("action"))) {
+            request.action = value(QStringLiteral("action"));
+            if (request.command != AutomationCommand::Open
+                || !allowedActions().contains(request.action)) {
+                return fail(error, QStringLiteral("'action' must be open, reveal, quicklook or "
+                                                  "copyPath, on open only"));
+            }
+        }
+        return request;
+    }
+
+    if (command == QLatin1String("reindex")) {
+        request.command = AutomationCommand::Reindex;
+        QString path = value(QStringLiteral("path"));
+        if (path == QLatin1String("~") || path.startsWith(QStringLiteral("~/"))) {
+            path = QDir::homePath() + path.mid(1);
+        }
+        if (!QDir::isAbsolutePath(path)) {
+            return fail(error, QStringLiteral("'path' must be an absolute path"));
+        }
+        request.path = QDir::cleanPath(path);
+        return request;
+    }
+
+    return fail(error, QStringLiteral("Unknown command '%1' (expected search, open or reindex)")
+                           .arg(command));
+}
+
+bool AutomationUrl::isAllowedCallback(const QUrl& url)
+{
+    return url.isValid() && url.scheme().compare(QLatin1String("shortcuts"),
+                                                 Qt::CaseInsensitive) == 0;
+}
+
+QUrl AutomationUrl::callback(const QUrl& base, const QList<QPair<QString, QString>>& params)
+{
+    QUrl out = base;
+    QUrlQuery query(base);
+    for (const auto& [key, value] : params) {
+        query.addQueryItem(key, QString::fromLatin1(QUrl::toPercentEncoding(value)));
+    }
+    out.setQuery(query);
+    return out;
+}
+
+} // namespace bs
@@ -0,0 +1,53 @@
+#pragma once
+
+#include <QList>
+#include <QPair>
+#include <QString>
+#include <QUrl>
+
+#include <optional>
+
+namespace bs {
+
+enum class AutomationCommand {
+    Search,   // show the panel with a query, or return results to x-success
+    Open,     // run a result action on the top hit
+    Reindex,  // re-extract a file or folder
+};
+
+struct AutomationRequest {
+    AutomationCommand command = AutomationCommand::Search;
+    QString query;
+    QString action = QStringLiteral("open");  // Open: open, reveal, quicklook, copyPath
+    QString path;                             // Reindex
+    int limit = 10;                           // Search with x-success
+    QUrl successUrl;
+    QUrl errorUrl;
+};
+
+// AutomationUrl — the betterspotlight:// URL scheme used by Shortcuts,
+// AppleScript (`open location`) and other automation tools:
+//
+//   betterspotlight://search?q=budget[&limit=5]
+//   betterspotlight://open?q=budget[&action=reveal]
+//   betterspotlight://reindex?path=/Users/me/Projects
+//
+// The x-callback-url form (betterspotlight://x-callback-url/search?...) is
+// accepted too. Any web page can fire a custom scheme, so x-success/x-error
+// may only call back into Shortcuts: results never leave for a browser.
+class AutomationUrl {
+public:
+    static constexpr const char* kScheme = "betterspotlight";
+    static constexpr int kMaxLimit = 50;
+
+    // Nullopt with *error set when the URL is not a valid request.
+    static std::optional<AutomationRequest> parse(const QUrl& url, QString* error);
+
+    // Callback schemes allowed for x-success and x-error.
+    static bool isAllowedCallback(const QUrl& url);
+
+    // base with params appended to its query (x-callback-url style).
+    static QUrl callback(const QUrl& base, const QList<QPair<QString, QString>>& params);
+};
+
+} // namespace bs
@@ -363,6 +363,12 @@ QString HealthAggregatorActor::computeOverallState(const QJsonArray& services,
         if (reason) *reason = QStringLiteral("component_degraded");
         return QStringLiteral("degraded");
     }
+    // A root the indexer cannot list indexes as empty rather than failing.
+    if (!mergedHealth.value(QStringLiteral("fileAccess")).toObject()
+             .value(QStringLiteral("unreadableRoots")).toArray().isEmpty()) {
+        if (reason) *reason = QStringLiteral("roots_unreadable");
+        return QStringLiteral("degraded");
+    }
     if (rebuilding) {
         if (reason) *reason = QStringLiteral("rebuilding");
         return QStringLiteral("rebuilding");
@@ -504,6 +510,8 @@ void HealthAggregatorActor::buildAndPublishSnapshot(const QJsonObject& queryHeal
         mergedHealth[QStringLiteral("queueSource")] = QStringLiteral("indexer_rpc");
         mergedHealth[QStringLiteral("pipelineBulkhead")] =
             indexerQueue.value(QStringLiteral("bulkhead")).toObject();
+        mergedHealth[QStringLiteral("fileAccess")] =
+            indexerQueue.value(QStringLiteral("access")).toObject();
     } else if (!mergedHealth.contains(QStringLiteral("queueSource"))) {
         mergedHealth[QStringLiteral("queueSource")] = QStringLiteral("unavailable");
     }
@@ -634,6 +642,9 @@ void HealthAggregatorActor::buildAndPublishSnapshot(const QJsonObject& queryHeal
         compat = mergedHealth;
     }
     compat[QStringLiteral("supervisorServices")] = m_managedServices;
+    if (mergedHealth.contains(QStringLiteral("fileAccess"))) {
+        compat[QStringLiteral("fileAccess")] = mergedHealth.value(QStringLiteral("fileAccess"));
+    }
     snapshot.compatibility = compat;
 
     const QJsonArray recentErrors = mergedHealth.value(QStringLiteral("recentErrors")).toArray();
@@ -1,3 +1,4 @@
+#include "automation_controller.h"
 #include "hotkey_manager.h"
 #include "onboarding_controller.h"
 #include "search_controller.h"
@@ -181,6 +182,7 @@ int main(int argc, char* argv[])
     app.setOrganizationName(QStringLiteral("BetterSpotlight"));
     app.setOrganizationDomain(QStringLiteral("com.betterspotlight"));
     app.setWindowIcon(QIcon(QStringLiteral(":/icons/app_icon_master.png")));
+    bs::Logging::install(QStringLiteral("app"));
 
     // Always run from a writable model cache to keep the bundle lean.
     if (!qEnvironmentVariableIsSet("BETTERSPOTLIGHT_MODELS_DIR")) {
@@ -265,6 +267,11 @@ int main(int argc, char* argv[])
                      &app, syncClipboardSignalsFromSettings);
     QObject::connect(&settingsController, &bs::SettingsController::enableInteractionTrackingChanged,
                      &app, syncClipboardSignalsFromSettings);
+    searchController.setClipboardHistoryEnabled(settingsController.indexClipboard());
+    QObject::connect(&settingsController, &bs::SettingsController::indexClipboardChanged,
+                     &app, [&]() {
+        searchController.setClipboardHistoryEnabled(settingsController.indexClipboard());
+    });
 
     QObject::connect(&systemInteractionCollector,
                      &bs::SystemInteractionCollector::behaviorEventCaptured,
@@ -337,6 +344,26 @@ int main(int argc, char* argv[])
                      &serviceManager, &bs::ServiceManager::clearExtractionCache);
     QObject::connect(&settingsController, &bs::SettingsController::reindexFolderRequested,
                      &serviceManager, &bs::ServiceManager::reindexPath);
+    QObject::connect(&settingsController, &bs::SettingsController::privacyExclusionAdded,
+                     &serviceManager, &bs::ServiceManager::addPrivacyExclusion);
+    QObject::connect(&settingsController, &bs::SettingsController::privacyExclusionRemoved,
+                     &serviceManager, &bs::ServiceManager::removePrivacyExclusion);
+    QObject::connect(&settingsController, &bs::SettingsController::indexContactsChanged,
+                     &serviceManager, &bs::ServiceManager::syncContacts);
+    QObject::connect(&settingsController, &bs::SettingsController::indexCalendarChanged,
+                     &serviceManager, &bs::ServiceManager::syncCalendar);
+    QObject::connect(&settingsController, &bs::SettingsController::indexBrowserHistoryChanged,
+                     &serviceManager, &bs::ServiceManager::syncBrowsers);
+    QObject::connect(&settingsController, &bs::SettingsController::indexMailChanged,
+                     &serviceManager, &bs::ServiceManager::syncMail);
+    QObject::connect(&settingsController, &bs::SettingsController::mailExcludedAccountsChanged,
+                     &serviceManager, &bs::ServiceManager::syncMail);
+    QObject::connect(&settingsController, &bs::SettingsController::indexNotesChanged,
+                     &serviceManager, &bs::ServiceManager::syncNotes);
+    QObject::connect(&settingsController, &bs::SettingsController::indexMessagesChanged,
+                     &serviceManager, &bs::ServiceManager::syncMessages);
+    QObject::connect(&settingsController, &bs::SettingsController::indexRemindersChanged,
+                     &serviceManager, &bs::ServiceManager::syncReminders);
     QObject::connect(&settingsController, &bs::SettingsController::checkForUpdatesChanged,
                      &app, [&]() {
         updateManager.setAutomaticallyChecks(settingsController.checkForUpdates());
@@ -383,6 +410,10 @@ int main(int argc, char* argv[])
     bs::StatusBarBridge statusBarBridge;
     engine.rootContext()->setContextProperty(QStringLiteral("statusBar"), &statusBarBridge);
 
+    // betterspotlight:// URLs (Shortcuts, AppleScript) arrive as FileOpen events.
+    bs::AutomationController automationController(&serviceManager, &statusBarBridge);
+    app.installEventFilter(&automationController);
+
     // Load Main.qml from embedded resources
     engine.load(QUrl(QStringLiteral("qrc:/BetterSpotlight/Main.qml")));
 
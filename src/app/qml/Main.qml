@@ -46,6 +46,10 @@ QtObject {
             searchPanel.showAndActivate()
         }
 
+        function onShowSearchWithQueryRequested(query) {
+            searchPanel.showWithQuery(query)
+        }
+
         function onShowSettingsRequested() {
             settingsPanel.show()
             settingsPanel.raise()
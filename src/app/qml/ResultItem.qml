@@ -175,6 +175,16 @@ Rectangle {
         case "audio":     return "\uD83C\uDFB5"  // music
         case "archive":   return "\uD83D\uDCE6"  // package
         case "code":      return "\u2699"         // gear
+        case "application": return "\uD83D\uDE80"  // rocket
+        case "settings":  return "\uD83D\uDD27"  // wrench
+        case "contact":   return "\uD83D\uDC64"  // bust
+        case "event":     return "\uD83D\uDCC5"  // calendar
+        case "webpage":   return "\uD83C\uDF10"  // globe
+        case "mail":      return "\u2709"         // envelope
+        case "note":      return "\uD83D\uDDD2"  // spiral notepad
+        case "message":   return "\uD83D\uDCAC"  // speech balloon
+        case "reminder":  return "\u2611"         // ballot box with check
+        case "clipboard": return "\uD83D\uDCCB"  // clipboard
         default:          return "\uD83D\uDCC4"  // generic document
         }
     }
@@ -188,6 +198,16 @@ Rectangle {
         case "audio":     return "#F3E5F5"
         case "archive":   return "#FFF8E1"
         case "code":      return "#E0F2F1"
+        case "application": return "#E8EAF6"
+        case "settings":  return "#ECEFF1"
+        case "contact":   return "#FCE4EC"
+        case "event":     return "#FBE9E7"
+        case "webpage":   return "#E1F5FE"
+        case "mail":      return "#E0F7FA"
+        case "note":      return "#FFFDE7"
+        case "message":   return "#E8F5E9"
+        case "reminder":  return "#FFF3E0"
+        case "clipboard": return "#EDE7F6"
         default:          return "#F5F5F5"
         }
     }
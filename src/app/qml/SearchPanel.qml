@@ -47,6 +47,15 @@ Window {
     readonly property int containerPadding: 8
     readonly property int implicitContentHeight: {
         var h = containerPadding * 2 + searchField.height
+        if (instantAnswerRow.visible) {
+            h += instantAnswerRow.implicitHeight + instantAnswerRow.Layout.topMargin
+        }
+        if (definitionRow.visible) {
+            h += definitionRow.implicitHeight + definitionRow.Layout.topMargin
+        }
+        if (interpretationRow.visible) {
+            h += interpretationRow.implicitHeight + interpretationRow.Layout.topMargin
+        }
         if (resultsList.count > 0) {
             h += resultsDivider.height
             h += computedResultsHeight()
@@ -75,6 +84,13 @@ Window {
         Qt.callLater(function() { _dismissEnabled = true })
     }
 
+    // Used by betterspotlight://search; the text change runs the query.
+    function showWithQuery(text) {
+        showAndActivate()
+        searchField.text = text
+        searchField.cursorPosition = text.length
+    }
+
     function dismiss() {
         _dismissEnabled = false
         visible = false
@@ -178,6 +194,147 @@ Window {
                 }
             }
 
+            // Calculator and unit conversion answers ("72 °F = 22.222222 °C"),
+            // shown ahead of file hits.
+            RowLayout {
+                id: instantAnswerRow
+                Layout.fillWidth: true
+                Layout.leftMargin: 14
+                Layout.rightMargin: 14
+                Layout.topMargin: 4
+                spacing: 8
+                visible: searchController ? searchController.instantAnswer.length > 0 : false
+
+                Text {
+                    Layout.fillWidth: true
+                    text: searchController ? searchController.instantAnswer : ""
+                    font.pixelSize: 18
+                    font.weight: Font.Medium
+                    color: "#1A1A1A"
+                    elide: Text.ElideRight
+                }
+
+                Text {
+                    text: qsTr("Copy")
+                    font.pixelSize: 12
+                    color: "#2A6FDB"
+
+                    MouseArea {
+                        anchors.fill: parent
+                        cursorShape: Qt.PointingHandCursor
+                        onClicked: {
+                            if (searchController) {
+                                searchController.copyInstantAnswer()
+                            }
+                        }
+                    }
+                }
+            }
+
+            // Dictionary entry for a lone word with few file hits.
+            ColumnLayout {
+                id: definitionRow
+                Layout.fillWidth: true
+                Layout.leftMargin: 14
+                Layout.rightMargin: 14
+                Layout.topMargin: 4
+                spacing: 2
+                visible: searchController && searchController.definition
+                         ? (searchController.definition.summary || "").length > 0 : false
+
+                RowLayout {
+                    Layout.fillWidth: true
+                    spacing: 8
+
+                    Text {
+                        text: searchController && searchController.definition
+                              ? (searchController.definition.word || "") : ""
+                        font.pixelSize: 15
+                        font.weight: Font.Medium
+                        color: "#1A1A1A"
+                    }
+
+                    Text {
+                        Layout.fillWidth: true
+                        text: {
+                            if (!searchController || !searchController.definition) return ""
+                            var d = searchController.definition
+                            var parts = []
+                            if (d.pronunciation) parts.push("| " + d.pronunciation + " |")
+                            if (d.partOfSpeech) parts.push(d.partOfSpeech)
+                            return parts.join(" ")
+                        }
+                        font.pixelSize: 12
+                        font.italic: true
+                        color: "#666666"
+                        elide: Text.ElideRight
+                    }
+
+                    Text {
+                        text: qsTr("Open in Dictionary")
+                        font.pixelSize: 12
+                        color: "#2A6FDB"
+
+                        MouseArea {
+                            anchors.fill: parent
+                            cursorShape: Qt.PointingHandCursor
+                            onClicked: {
+                                if (searchController) {
+                                    searchController.openDefinition()
+                                }
+                            }
+                        }
+                    }
+                }
+
+                Text {
+                    Layout.fillWidth: true
+                    text: searchController && searchController.definition
+                          ? (searchController.definition.summary || "") : ""
+                    font.pixelSize: 12
+                    color: "#333333"
+                    wrapMode: Text.WordWrap
+                    maximumLineCount: 3
+                    elide: Text.ElideRight
+                }
+            }
+
+            // How the query was read ("screenshots modified 2026-10-13"),
+            // with a way back to matching the words as typed.
+            RowLayout {
+                id: interpretationRow
+                Layout.fillWidth: true
+                Layout.leftMargin: 14
+                Layout.rightMargin: 14
+                Layout.topMargin: 2
+                spacing: 8
+                visible: searchController ? searchController.interpretation.length > 0 : false
+
+                Text {
+                    Layout.fillWidth: true
+                    text: searchController ? qsTr("Showing %1").arg(searchController.interpretation) : ""
+                    font.pixelSize: 12
+                    color: "#666666"
+                    elide: Text.ElideRight
+                }
+
+                Text {
+                    text: qsTr("Match words instead")
+                    font.pixelSize: 12
+                    color: "#2A6FDB"
+
+                    MouseArea {
+                        anchors.fill: parent
+                        cursorShape: Qt.PointingHandCursor
+                        onClicked: {
+                            if (searchController) {
+                                searchController.searchLiterally()
+                            }
+                        }
+                    }
+                }
+            }
+
             // Divider between search field and results
             Rectangle {
                 id: resultsDivider
@@ -989,6 +989,223 @@ Window {
                             }
                         }
 
+                        GroupBox {
+                            Layout.fillWidth: true
+                            title: qsTr("Other Sources")
+
+                            ColumnLayout {
+                                anchors.fill: parent
+                                spacing: 12
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Contacts"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search names, emails, phone numbers and companies from Contacts; a person's card shows up next to the files that mention them. macOS asks for access the first time. Turning this off removes the cards from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexContacts : false
+                                        onToggled: { if (settingsController) settingsController.indexContacts = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Calendar"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search event titles, attendees and notes from the last six months and the next three; events closest to today rank first, and documents an event's notes link to rank higher. macOS asks for access the first time. Turning this off removes the events from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexCalendar : false
+                                        onToggled: { if (settingsController) settingsController.indexCalendar = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Browser History"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search the titles and addresses of pages visited in the last 90 days and of bookmarks, from Safari, Chrome, Brave, Edge, Vivaldi and Arc. A page open in several browsers shows up once. Safari needs Full Disk Access. Turning this off removes the pages from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexBrowserHistory : false
+                                        onToggled: { if (settingsController) settingsController.indexBrowserHistory = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Mail"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search the sender, recipients, subject and text of messages downloaded by Mail, newest first, and narrow results by mailbox. Needs Full Disk Access. Turning this off removes the messages from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexMail : false
+                                        onToggled: { if (settingsController) settingsController.indexMail = checked }
+                                    }
+                                }
+
+                                Repeater {
+                                    model: settingsController && settingsController.indexMail
+                                           ? settingsController.mailExcludedAccounts : []
+
+                                    delegate: RowLayout {
+                                        required property int index
+                                        required property string modelData
+                                        spacing: 8
+                                        Layout.fillWidth: true
+                                        Rectangle { width: 6; height: 6; radius: 3; color: "#C62828" }
+                                        Label { text: modelData; font.pixelSize: 12; font.family: "Menlo"; color: "#1A1A1A"; Layout.fillWidth: true }
+                                        Button {
+                                            text: qsTr("Remove")
+                                            font.pixelSize: 11
+                                            palette.buttonText: "#C62828"
+                                            onClicked: {
+                                                if (settingsController) {
+                                                    var accounts = settingsController.mailExcludedAccounts
+                                                    accounts.splice(index, 1)
+                                                    settingsController.mailExcludedAccounts = accounts
+                                                }
+                                            }
+                                        }
+                                    }
+                                }
+
+                                RowLayout {
+                                    visible: settingsController ? settingsController.indexMail : false
+                                    spacing: 8
+                                    Layout.fillWidth: true
+
+                                    TextField {
+                                        id: newMailExcludedAccountField
+                                        Layout.fillWidth: true
+                                        placeholderText: qsTr("Leave out an account, e.g. work@example.com")
+                                        font.pixelSize: 12
+                                        font.family: "Menlo"
+                                    }
+                                    Button {
+                                        text: qsTr("Exclude")
+                                        enabled: newMailExcludedAccountField.text.trim().length > 0
+                                        onClicked: {
+                                            if (settingsController) {
+                                                var accounts = settingsController.mailExcludedAccounts
+                                                var account = newMailExcludedAccountField.text.trim()
+                                                if (accounts.indexOf(account) === -1) {
+                                                    accounts.push(account)
+                                                    settingsController.mailExcludedAccounts = accounts
+                                                }
+                                                newMailExcludedAccountField.text = ""
+                                            }
+                                        }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Notes"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search the titles and text of notes in Apple Notes; opening one opens it in Notes. Locked notes are found by title only, and Recently Deleted is left out. Needs Full Disk Access. Turning this off removes the notes from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexNotes : false
+                                        onToggled: { if (settingsController) settingsController.indexNotes = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Messages"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search the text, senders and conversations of your most recent iMessage and SMS messages; results show one match per conversation and open in Messages. Messages stay in this app: they are never returned over the HTTP API or to API tokens. Needs Full Disk Access. Turning this off removes the messages from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexMessages : false
+                                        onToggled: { if (settingsController) settingsController.indexMessages = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Index Reminders"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Search the titles, notes and lists of your open reminders and those completed in the last 30 days; type due:today kind:reminder to list what is due. macOS asks for access the first time. Turning this off removes the reminders from the index.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexReminders : false
+                                        onToggled: { if (settingsController) settingsController.indexReminders = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Clipboard History"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Remember the last 500 texts you copy, for 30 days, and show matches in search; choosing one copies it again. Kept encrypted on this Mac only, never in the index. Passwords marked concealed, images and files are skipped. Turning this off deletes the history.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.indexClipboard : false
+                                        onToggled: { if (settingsController) settingsController.indexClipboard = checked }
+                                    }
+                                }
+                            }
+                        }
+
                         GroupBox {
                             Layout.fillWidth: true
                             title: qsTr("Limits")
@@ -1271,6 +1488,27 @@ Window {
 
                                 Rectangle { Layout.fillWidth: true; height: 1; color: "#C0C0C0" }
 
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Recent documents from other apps"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Boost files that appear in other apps' Open Recent lists. The lists are read locally and never stored.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.recentDocumentsSignalEnabled : true
+                                        onToggled: { if (settingsController) settingsController.recentDocumentsSignalEnabled = checked }
+                                    }
+                                }
+
+                                Rectangle { Layout.fillWidth: true; height: 1; color: "#C0C0C0" }
+
                                 RowLayout {
                                     spacing: 12
                                     Layout.fillWidth: true
@@ -1292,6 +1530,34 @@ Window {
                                         }
                                     }
                                 }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Query history"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Past searches suggested as you type. Private searches are never kept; Off clears the history.")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+
+                                    ComboBox {
+                                        model: [ qsTr("Off"), qsTr("30 days"), qsTr("90 days"), qsTr("1 year") ]
+                                        property var dayValues: [0, 30, 90, 365]
+                                        currentIndex: {
+                                            if (!settingsController) return 2
+                                            var idx = dayValues.indexOf(settingsController.queryHistoryRetentionDays)
+                                            return idx >= 0 ? idx : 2
+                                        }
+                                        onActivated: function(index) {
+                                            if (settingsController) settingsController.queryHistoryRetentionDays = dayValues[index]
+                                        }
+                                    }
+                                }
                             }
                         }
 
@@ -1364,6 +1630,144 @@ Window {
                             }
                         }
 
+                        GroupBox {
+                            Layout.fillWidth: true
+                            title: qsTr("Secrets in Files")
+
+                            ColumnLayout {
+                                anchors.fill: parent
+                                spacing: 8
+
+                                Label {
+                                    text: qsTr("API keys, private keys and passwords found in file content are kept out of the index. Takes effect the next time indexing starts; rebuild the index to redact content already indexed.")
+                                    font.pixelSize: 13; color: "#666666"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    Label {
+                                        text: qsTr("Detected secrets")
+                                        font.pixelSize: 13
+                                        color: "#1A1A1A"
+                                        Layout.fillWidth: true
+                                    }
+                                    ComboBox {
+                                        model: [qsTr("Store hashed"), qsTr("Leave out"), qsTr("Index as-is")]
+                                        property var modeValues: ["hash", "skip", "off"]
+                                        currentIndex: {
+                                            if (!settingsController) return 0
+                                            var idx = modeValues.indexOf(settingsController.secretRedaction)
+                                            return idx >= 0 ? idx : 0
+                                        }
+                                        onActivated: function(index) {
+                                            if (settingsController) settingsController.secretRedaction = modeValues[index]
+                                        }
+                                    }
+                                }
+                            }
+                        }
+
+                        GroupBox {
+                            Layout.fillWidth: true
+                            title: qsTr("Audit Log")
+
+                            ColumnLayout {
+                                anchors.fill: parent
+                                spacing: 8
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    ColumnLayout {
+                                        spacing: 2
+                                        Layout.fillWidth: true
+                                        Label { text: qsTr("Record queries and admin operations"); font.pixelSize: 13; color: "#1A1A1A" }
+                                        Label {
+                                            text: qsTr("Keeps a local, append-only log for shared or managed Macs, in %1. Review it with 'bspot audit'.").arg(settingsController ? settingsController.auditLogDirectory : "")
+                                            font.pixelSize: 11; color: "#999999"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                        }
+                                    }
+                                    Switch {
+                                        checked: settingsController ? settingsController.auditLogEnabled : false
+                                        onToggled: { if (settingsController) settingsController.auditLogEnabled = checked }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 12
+                                    Layout.fillWidth: true
+
+                                    Button {
+                                        text: qsTr("Clear Audit Log")
+                                        palette.buttonText: "#C62828"
+                                        onClicked: clearAuditLogDialog.open()
+                                    }
+
+                                    Item { Layout.fillWidth: true }
+                                }
+                            }
+                        }
+
+                        GroupBox {
+                            Layout.fillWidth: true
+                            title: qsTr("Privacy Folders")
+
+                            ColumnLayout {
+                                anchors.fill: parent
+                                spacing: 8
+
+                                Label {
+                                    text: qsTr("Nothing inside these folders is indexed. Adding a folder immediately deletes everything already indexed from it, including embeddings and cached previews.")
+                                    font.pixelSize: 13; color: "#666666"; wrapMode: Text.WordWrap; Layout.fillWidth: true
+                                }
+
+                                Repeater {
+                                    model: settingsController ? settingsController.privacyExclusions : []
+
+                                    delegate: RowLayout {
+                                        required property string modelData
+                                        spacing: 8
+                                        Layout.fillWidth: true
+                                        Rectangle { width: 6; height: 6; radius: 3; color: "#1A1A1A" }
+                                        Label { text: modelData; font.pixelSize: 12; font.family: "Menlo"; color: "#1A1A1A"; Layout.fillWidth: true }
+                                        Button {
+                                            text: qsTr("Remove")
+                                            font.pixelSize: 11
+                                            onClicked: {
+                                                if (settingsController) settingsController.removePrivacyExclusion(modelData)
+                                            }
+                                        }
+                                    }
+                                }
+
+                                RowLayout {
+                                    spacing: 8
+                                    Layout.fillWidth: true
+
+                                    TextField {
+                                        id: newPrivacyFolderField
+                                        Layout.fillWidth: true
+                                        placeholderText: qsTr("e.g. ~/Documents/Medical")
+                                        font.pixelSize: 12
+                                        font.family: "Menlo"
+                                    }
+                                    Button {
+                                        text: qsTr("Add")
+                                        enabled: newPrivacyFolderField.text.trim().length > 0
+                                        onClicked: {
+                                            if (settingsController
+                                                    && settingsController.addPrivacyExclusion(newPrivacyFolderField.text.trim())) {
+                                                newPrivacyFolderField.text = ""
+                                            }
+                                        }
+                                    }
+                                }
+                            }
+                        }
+
                         GroupBox {
                             Layout.fillWidth: true
                             title: qsTr("Data Management")
@@ -1651,6 +2055,65 @@ Window {
                             }
                         }
 
+                        // Roots or protected folders the indexer cannot read
+                        Rectangle {
+                            id: fileAccessBanner
+                            property var access: healthTab.healthData["fileAccess"] || ({})
+                            property var blocked: {
+                                var out = []
+                                var roots = fileAccessBanner.access["unreadableRoots"] || []
+                                var folders = fileAccessBanner.access["protectedFolders"] || []
+                                for (var i = 0; i < roots.length; ++i) out.push(roots[i])
+                                for (var j = 0; j < folders.length; ++j) out.push(folders[j])
+                                return out
+                            }
+                            property bool privacyDenied: {
+                                for (var i = 0; i < blocked.length; ++i) {
+                                    if (String(blocked[i].reason || "") === "privacy_denied") return true
+                                }
+                                return false
+                            }
+                            visible: healthTab.loaded && blocked.length > 0
+                            Layout.fillWidth: true
+                            implicitHeight: fileAccessColumn.implicitHeight + 24
+                            radius: 6
+                            color: "#FFF8E1"
+                            border.width: 1; border.color: "#F57F17"
+
+                            ColumnLayout {
+                                id: fileAccessColumn
+                                anchors.fill: parent
+                                anchors.margins: 12
+                                spacing: 6
+
+                                Label {
+                                    text: qsTr("Some folders cannot be read and are not being indexed")
+                                    font.pixelSize: 13; font.weight: Font.DemiBold; color: "#1A1A1A"
+                                    Layout.fillWidth: true
+                                }
+                                Repeater {
+                                    model: fileAccessBanner.blocked
+                                    delegate: Label {
+                                        required property var modelData
+                                        text: String(modelData.path || "") + "  (" + String(modelData.reason || "") + ")"
+                                        font.pixelSize: 12; color: "#666666"
+                                        elide: Text.ElideMiddle
+                                        Layout.fillWidth: true
+                                    }
+                                }
+                                Label {
+                                    text: String(fileAccessBanner.access["remediation"] || "")
+                                    font.pixelSize: 12; color: "#1A1A1A"; wrapMode: Text.WordWrap
+                                    Layout.fillWidth: true
+                                }
+                                Button {
+                                    visible: fileAccessBanner.privacyDenied
+                                    text: qsTr("Open Full Disk Access Settings")
+                                    onClicked: onboardingControllerObj.openFdaSystemSettings()
+                                }
+                            }
+                        }
+
                         GroupBox {
                             Layout.fillWidth: true
                             title: qsTr("Index Statistics")
@@ -2911,6 +3374,14 @@ Window {
 
     // ---- Dialogs ----
 
+    MessageDialog {
+        id: clearAuditLogDialog
+        title: qsTr("Clear Audit Log")
+        text: qsTr("Delete every recorded query and admin operation? The clearing itself stays recorded while the log is on. This action cannot be undone.")
+        buttons: MessageDialog.Ok | MessageDialog.Cancel
+        onAccepted: { if (settingsController) settingsController.clearAuditLog() }
+    }
+
     MessageDialog {
         id: clearFeedbackDialog
         title: qsTr("Clear Feedback Data")
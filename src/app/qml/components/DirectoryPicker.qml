@@ -6,10 +6,27 @@ import QtQuick.Dialogs
 Item {
     id: root
 
-    // model: QVariantList of objects { path: string, mode: string }
+    // model: QVariantList of objects { path: string, mode: string,
+    //                                   contentTypes?: { allow: [], deny: [] } }
     // mode values: "index_embed", "index_only", "skip"
     property var model: []
 
+    // Content presets; anything else set in settings.json shows as Custom.
+    readonly property var contentPresets: [
+        null,
+        { allow: ["document", "code"], deny: [] },
+        { allow: [], deny: ["*"] }
+    ]
+
+    function contentPresetIndex(contentTypes) {
+        if (!contentTypes) return 0
+        var key = JSON.stringify({ allow: contentTypes.allow || [], deny: contentTypes.deny || [] })
+        for (var i = 1; i < contentPresets.length; ++i) {
+            if (JSON.stringify(contentPresets[i]) === key) return i
+        }
+        return contentPresets.length
+    }
+
     implicitHeight: mainLayout.implicitHeight
 
     ColumnLayout {
@@ -69,6 +86,37 @@ Item {
                         }
                     }
 
+                    ComboBox {
+                        id: contentCombo
+                        Layout.preferredWidth: 150
+                        ToolTip.visible: hovered
+                        ToolTip.text: qsTr("Which files get their content indexed. Names are always indexed.")
+                        model: [
+                            qsTr("All Content"),
+                            qsTr("Documents + Code"),
+                            qsTr("Names Only"),
+                            qsTr("Custom")
+                        ]
+                        currentIndex: root.contentPresetIndex(modelData.contentTypes)
+                        onActivated: function(comboIndex) {
+                            if (comboIndex >= root.contentPresets.length) {
+                                currentIndex = Qt.binding(function() {
+                                    return root.contentPresetIndex(modelData.contentTypes)
+                                })
+                                return
+                            }
+                            var newModel = root.model.slice()
+                            var entry = Object.assign({}, newModel[index])
+                            if (root.contentPresets[comboIndex] === null) {
+                                delete entry.contentTypes
+                            } else {
+                                entry.contentTypes = root.contentPresets[comboIndex]
+                            }
+                            newModel[index] = entry
+                            root.model = newModel
+                        }
+                    }
+
                     Button {
                         text: "\u2212"
                         font.pixelSize: 16
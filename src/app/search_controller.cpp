@@ -8,12 +8,16 @@
 #include <QDateTime>
 #include <QDesktopServices>
 #include <QDir>
+#include <QFile>
+#include <QFileInfo>
 #include <QGuiApplication>
 #include <QCryptographicHash>
 #include <QJsonArray>
 #include <QJsonObject>
+#include <QMimeData>
 #include <QProcess>
 #include <QRegularExpression>
+#include <QStandardPaths>
 #include <QUrl>
 #include <QUuid>
 
@@ -66,6 +70,7 @@ SearchController::SearchController(QObject* parent)
                 this, [this](QClipboard::Mode mode) {
             if (mode == QClipboard::Clipboard) {
                 handleClipboardChanged();
+                recordClipboardHistory();
             }
         });
     }
@@ -122,6 +127,32 @@ void SearchController::setClipboardSignalsEnabled(bool enabled)
     handleClipboardChanged();
 }
 
+void SearchController::setClipboardHistoryEnabled(bool enabled)
+{
+    if ((m_clipboardHistory != nullptr) == enabled) {
+        return;
+    }
+
+    if (!enabled) {
+        // Off means forgotten: the file and the key it was sealed under go.
+        const bool hadHistory = QFile::exists(clipboardHistoryPath());
+        ClipboardHistory(clipboardHistoryPath()).clear();
+        if (hadHistory) {
+            ClipboardHistory::removeStorageKey();
+        }
+        m_clipboardHistory.reset();
+        return;
+    }
+
+    m_clipboardHistory = std::make_unique<ClipboardHistory>(clipboardHistoryPath());
+    QString error;
+    if (!m_clipboardHistory->load(&error)) {
+        // Unreadable history is started over rather than kept off.
+        LOG_WARN(bsCore, "SearchController: clipboard history not loaded: %s",
+                 qPrintable(error));
+    }
+}
+
 void SearchController::recordBehaviorEvent(const QJsonObject& event)
 {
     QJsonObject payload = event;
@@ -215,9 +246,23 @@ void SearchController::setQuery(const QString& query)
         m_results.clear();
         m_resultRows.clear();
         m_selectedIndex = -1;
+        m_literalQuery.clear();
         emit resultsChanged();
         emit resultRowsChanged();
         emit selectedIndexChanged();
+        if (!m_interpretation.isEmpty()) {
+            m_interpretation.clear();
+            emit interpretationChanged();
+        }
+        if (!m_instantAnswer.isEmpty()) {
+            m_instantAnswer.clear();
+            m_instantAnswerValue.clear();
+            emit instantAnswerChanged();
+        }
+        if (!m_definition.isEmpty()) {
+            m_definition.clear();
+            emit definitionChanged();
+        }
         m_debounceTimer.stop();
         return;
     }
@@ -284,20 +329,69 @@ void SearchController::openResult(int index)
         return;
     }
 
+    const QVariantMap selected = m_results.at(resultIndex).toMap();
+    if (selected.value(QStringLiteral("kind")).toString() == QLatin1String("application")) {
+        launchApplication(path);
+        return;
+    }
+    // A copied text opens by being copied again; it stays out of the
+    // service's interaction history.
+    if (selected.value(QStringLiteral("kind")).toString() == QLatin1String("clipboard")) {
+        if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+            clipboard->setText(selected.value(QStringLiteral("text")).toString());
+        }
+        return;
+    }
+    // A file indexed on another Mac opens here only when the same path is
+    // mounted; otherwise its path there is copied to find it by.
+    const QString peerPath = selected.value(QStringLiteral("peerPath")).toString();
+    if (!peerPath.isEmpty()) {
+        if (QFileInfo::exists(peerPath)) {
+            QDesktopServices::openUrl(QUrl::fromLocalFile(peerPath));
+        } else if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+            clipboard->setText(peerPath);
+        }
+        recordResultFeedback(resultIndex, QStringLiteral("open"));
+        return;
+    }
+    // A file from a team index opens when its volume is mounted; it is in
+    // no index here, so nothing about the open is recorded.
+    if (selected.contains(QStringLiteral("team"))) {
+        if (QFileInfo::exists(path)) {
+            QDesktopServices::openUrl(QUrl::fromLocalFile(path));
+        } else if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+            clipboard->setText(path);
+        }
+        return;
+    }
+    // A file on a server opens nowhere here; "host:/path" is copied for
+    // scp or an editor's remote open.
+    const QString remotePath = selected.value(QStringLiteral("remotePath")).toString();
+    if (!remotePath.isEmpty()) {
+        if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+            clipboard->setText(remotePath);
+        }
+        recordResultFeedback(resultIndex, QStringLiteral("open"));
+        return;
+    }
+    if (selected.value(QStringLiteral("kind")).toString() == QLatin1String("settings")) {
+        LOG_INFO(bsCore, "SearchController: opening settings pane '%s'", qPrintable(path));
+        QDesktopServices::openUrl(QUrl(path));
+        return;
+    }
+
     LOG_INFO(bsCore, "SearchController: opening '%s'", qPrintable(path));
-    QDesktopServices::openUrl(QUrl::fromLocalFile(path));
+    // Items donated by apps have no file; they carry the URL to open.
+    const QString donatedUrl =
+        m_results.at(resultIndex).toMap().value(QStringLiteral("url")).toString();
+    QDesktopServices::openUrl(donatedUrl.isEmpty() ? QUrl::fromLocalFile(path)
+                                                   : QUrl(donatedUrl));
+
+    recordResultFeedback(resultIndex, QStringLiteral("open"));
 
-    // Record feedback via IPC (fire and forget)
     SocketClient* client = ensureQueryClient(250);
     if (client && client->isConnected()) {
-        QJsonObject params;
         QVariantMap item = m_results.at(resultIndex).toMap();
-        params[QStringLiteral("itemId")] = item.value(QStringLiteral("itemId")).toLongLong();
-        params[QStringLiteral("action")] = QStringLiteral("open");
-        params[QStringLiteral("query")] = m_query;
-        params[QStringLiteral("position")] = resultIndex;
-        client->sendNotification(QStringLiteral("recordFeedback"), params);
-
         QJsonObject interactionParams;
         interactionParams[QStringLiteral("query")] = m_query;
         interactionParams[QStringLiteral("selectedItemId")] =
@@ -344,27 +438,69 @@ void SearchController::openResult(int index)
 
 void SearchController::revealInFinder(int index)
 {
-    QString path = pathForResult(resultIndexForRow(index));
+    const int resultIndex = resultIndexForRow(index);
+    QString path = pathForResult(resultIndex);
     if (path.isEmpty()) {
         return;
     }
 
     LOG_INFO(bsCore, "SearchController: revealing '%s' in Finder", qPrintable(path));
     QProcess::startDetached(QStringLiteral("open"), {QStringLiteral("-R"), path});
+    recordResultFeedback(resultIndex, QStringLiteral("reveal"));
 }
 
 void SearchController::copyPath(int index)
 {
-    QString path = pathForResult(resultIndexForRow(index));
+    const int resultIndex = resultIndexForRow(index);
+    QString path = pathForResult(resultIndex);
     if (path.isEmpty()) {
         return;
     }
 
+    const QVariantMap selected = m_results.at(resultIndex).toMap();
+    if (selected.value(QStringLiteral("kind")).toString() == QLatin1String("clipboard")) {
+        if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+            clipboard->setText(selected.value(QStringLiteral("text")).toString());
+        }
+        return;
+    }
+
+    // The path on the other Mac, not the replica's donated:// one.
+    if (!selected.value(QStringLiteral("peerPath")).toString().isEmpty()) {
+        path = selected.value(QStringLiteral("peerPath")).toString();
+    }
+    if (!selected.value(QStringLiteral("remotePath")).toString().isEmpty()) {
+        path = selected.value(QStringLiteral("remotePath")).toString();
+    }
+
     LOG_INFO(bsCore, "SearchController: copying path '%s'", qPrintable(path));
     QClipboard* clipboard = QGuiApplication::clipboard();
     if (clipboard) {
         clipboard->setText(path);
     }
+    recordResultFeedback(resultIndex, QStringLiteral("copyPath"));
+}
+
+// Fire and forget: the query service keeps the action history and counts
+// the action toward ranking.
+void SearchController::recordResultFeedback(int resultIndex, const QString& action)
+{
+    SocketClient* client = ensureQueryClient(250);
+    if (!client || !client->isConnected()) {
+        return;
+    }
+    // A federated result lives in another index and has no item here.
+    const qint64 itemId =
+        m_results.at(resultIndex).toMap().value(QStringLiteral("itemId")).toLongLong();
+    if (itemId <= 0) {
+        return;
+    }
+    QJsonObject params;
+    params[QStringLiteral("itemId")] = itemId;
+    params[QStringLiteral("action")] = action;
+    params[QStringLiteral("query")] = m_query;
+    params[QStringLiteral("position")] = resultIndex;
+    client->sendNotification(QStringLiteral("recordFeedback"), params);
 }
 
 QVariantMap SearchController::requestAnswerSnippet(int index)
@@ -471,12 +607,64 @@ void SearchController::clearResults()
     m_results.clear();
     m_resultRows.clear();
     m_selectedIndex = -1;
+    m_interpretation.clear();
+    m_literalQuery.clear();
+    m_instantAnswer.clear();
+    m_instantAnswerValue.clear();
+    m_definition.clear();
     m_debounceTimer.stop();
 
     emit queryChanged();
     emit resultsChanged();
     emit resultRowsChanged();
     emit selectedIndexChanged();
+    emit interpretationChanged();
+    emit instantAnswerChanged();
+    emit definitionChanged();
+}
+
+QString SearchController::interpretation() const
+{
+    return m_interpretation;
+}
+
+QString SearchController::instantAnswer() const
+{
+    return m_instantAnswer;
+}
+
+QVariantMap SearchController::definition() const
+{
+    return m_definition;
+}
+
+void SearchController::openDefinition()
+{
+    const QString url = m_definition.value(QStringLiteral("url")).toString();
+    if (!url.isEmpty()) {
+        QDesktopServices::openUrl(QUrl(url));
+    }
+}
+
+void SearchController::copyInstantAnswer()
+{
+    if (m_instantAnswerValue.isEmpty()) {
+        return;
+    }
+    if (QClipboard* clipboard = QGuiApplication::clipboard()) {
+        clipboard->setText(m_instantAnswerValue);
+    }
+}
+
+void SearchController::searchLiterally()
+{
+    const QString trimmedQuery = m_query.trimmed();
+    if (trimmedQuery.isEmpty() || m_literalQuery == trimmedQuery) {
+        return;
+    }
+    m_literalQuery = trimmedQuery;
+    m_debounceTimer.stop();
+    executeSearch();
 }
 
 void SearchController::moveSelection(int delta)
@@ -644,6 +832,9 @@ void SearchController::executeSearch()
     QJsonObject params;
     params[QStringLiteral("query")] = trimmedQuery;
     params[QStringLiteral("limit")] = 20;
+    if (trimmedQuery == m_literalQuery) {
+        params[QStringLiteral("naturalLanguage")] = false;
+    }
     QJsonObject context;
     m_lastContextEventId = QUuid::createUuid().toString(QUuid::WithoutBraces);
     QByteArray digestSeed = trimmedQuery.toUtf8();
@@ -736,9 +927,68 @@ void SearchController::parseSearchResponse(const QJsonObject& response)
 
     QJsonObject result = response.value(QStringLiteral("result")).toObject();
     QJsonArray resultsArray = result.value(QStringLiteral("results")).toArray();
-
+    const QString interpretation = result.value(QStringLiteral("interpretation")).toObject()
+                                       .value(QStringLiteral("summary")).toString();
+    if (interpretation != m_interpretation) {
+        m_interpretation = interpretation;
+        emit interpretationChanged();
+    }
+    const QJsonObject instantAnswer = result.value(QStringLiteral("instantAnswer")).toObject();
+    const QString instantAnswerDisplay = instantAnswer.value(QStringLiteral("display")).toString();
+    m_instantAnswerValue = instantAnswer.value(QStringLiteral("value")).toString();
+    if (instantAnswerDisplay != m_instantAnswer) {
+        m_instantAnswer = instantAnswerDisplay;
+        emit instantAnswerChanged();
+    }
+    const QVariantMap definition =
+        result.value(QStringLiteral("definition")).toObject().toVariantMap();
+    if (definition != m_definition) {
+        m_definition = definition;
+        emit definitionChanged();
+    }
+
+    const QJsonArray applicationsArray = result.value(QStringLiteral("applications")).toArray();
+    const QJsonArray settingsArray = result.value(QStringLiteral("settings")).toArray();
     QVariantList newResults;
-    newResults.reserve(resultsArray.size());
+    newResults.reserve(applicationsArray.size() + settingsArray.size() + resultsArray.size());
+
+    // Matching apps come first so Return on "saf" launches Safari.
+    for (const auto& val : applicationsArray) {
+        const QJsonObject obj = val.toObject();
+        QVariantMap item;
+        item[QStringLiteral("itemId")] = 0;
+        item[QStringLiteral("path")] = obj.value(QStringLiteral("path")).toString();
+        item[QStringLiteral("name")] = obj.value(QStringLiteral("name")).toString();
+        item[QStringLiteral("kind")] = QStringLiteral("application");
+        item[QStringLiteral("bundleId")] = obj.value(QStringLiteral("bundleId")).toString();
+        item[QStringLiteral("score")] = obj.value(QStringLiteral("score")).toDouble();
+        item[QStringLiteral("frequency")] = 0;
+        item[QStringLiteral("contentAvailable")] = false;
+        item[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+        item[QStringLiteral("answerStatus")] = QStringLiteral("idle");
+        const QString path = item.value(QStringLiteral("path")).toString();
+        const int lastSlash = static_cast<int>(path.lastIndexOf(QLatin1Char('/')));
+        item[QStringLiteral("parentPath")] = (lastSlash > 0) ? path.left(lastSlash) : path;
+        newResults.append(item);
+    }
+
+    // Settings panes have no file; the deep link stands in for the path.
+    for (const auto& val : settingsArray) {
+        const QJsonObject obj = val.toObject();
+        QVariantMap item;
+        item[QStringLiteral("itemId")] = 0;
+        item[QStringLiteral("path")] = obj.value(QStringLiteral("url")).toString();
+        item[QStringLiteral("url")] = obj.value(QStringLiteral("url")).toString();
+        item[QStringLiteral("name")] = obj.value(QStringLiteral("name")).toString();
+        item[QStringLiteral("kind")] = QStringLiteral("settings");
+        item[QStringLiteral("score")] = obj.value(QStringLiteral("score")).toDouble();
+        item[QStringLiteral("frequency")] = 0;
+        item[QStringLiteral("contentAvailable")] = false;
+        item[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+        item[QStringLiteral("answerStatus")] = QStringLiteral("idle");
+        item[QStringLiteral("parentPath")] = QStringLiteral("System Settings");
+        newResults.append(item);
+    }
 
     for (const auto& val : resultsArray) {
         QJsonObject obj = val.toObject();
@@ -749,6 +999,18 @@ void SearchController::parseSearchResponse(const QJsonObject& response)
         item[QStringLiteral("path")] = obj.value(QStringLiteral("path")).toString();
         item[QStringLiteral("name")] = obj.value(QStringLiteral("name")).toString();
         item[QStringLiteral("kind")] = obj.value(QStringLiteral("kind")).toString();
+        item[QStringLiteral("url")] = obj.value(QStringLiteral("url")).toString();
+        if (obj.contains(QStringLiteral("peer"))) {
+            item[QStringLiteral("peer")] = obj.value(QStringLiteral("peer")).toString();
+            item[QStringLiteral("peerPath")] = obj.value(QStringLiteral("peerPath")).toString();
+        }
+        if (obj.contains(QStringLiteral("team"))) {
+            item[QStringLiteral("team")] = obj.value(QStringLiteral("team")).toString();
+        }
+        if (obj.contains(QStringLiteral("remote"))) {
+            item[QStringLiteral("remote")] = obj.value(QStringLiteral("remote")).toString();
+            item[QStringLiteral("remotePath")] = obj.value(QStringLiteral("remotePath")).toString();
+        }
         item[QStringLiteral("matchType")] = obj.value(QStringLiteral("matchType")).toString();
         item[QStringLiteral("score")] = obj.value(QStringLiteral("score")).toDouble();
         item[QStringLiteral("snippet")] = obj.value(QStringLiteral("snippet")).toString();
@@ -769,10 +1031,65 @@ void SearchController::parseSearchResponse(const QJsonObject& response)
         QString path = item.value(QStringLiteral("path")).toString();
         int lastSlash = path.lastIndexOf(QLatin1Char('/'));
         item[QStringLiteral("parentPath")] = (lastSlash > 0) ? path.left(lastSlash) : path;
+        if (item.value(QStringLiteral("kind")).toString() == QLatin1String("contact")) {
+            // The role line ("Engineer at Acme") says more than donated://.
+            const QString role = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] = role.isEmpty() ? QStringLiteral("Contacts") : role;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("event")) {
+            // "2026-10-14 09:30-10:00 · Work" rather than donated://.
+            const QString when = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] = when.isEmpty() ? QStringLiteral("Calendar") : when;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("webpage")) {
+            // "example.com · Safari, Chrome"; the URL itself is the subtitle
+            // otherwise.
+            const QString seen = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] =
+                seen.isEmpty() ? obj.value(QStringLiteral("url")).toString() : seen;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("mail")) {
+            // "Ada Lovelace · INBOX" rather than donated://.
+            const QString from = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] = from.isEmpty() ? QStringLiteral("Mail") : from;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("note")) {
+            // "Recipes · iCloud" rather than donated://.
+            const QString folder = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] = folder.isEmpty() ? QStringLiteral("Notes") : folder;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("message")) {
+            // "Ada · Family · 4 matches": the conversation's other matches
+            // are folded into this row.
+            QString who = obj.value(QStringLiteral("contentDescription")).toString();
+            if (who.isEmpty()) {
+                who = QStringLiteral("Messages");
+            }
+            const int matches = obj.value(QStringLiteral("conversationMatches")).toInt(1);
+            item[QStringLiteral("parentPath")] =
+                matches > 1 ? QStringLiteral("%1 · %2 matches").arg(who).arg(matches) : who;
+        } else if (item.value(QStringLiteral("kind")).toString() == QLatin1String("reminder")) {
+            // "Due 2026-10-14 17:00 · Work" rather than donated://.
+            const QString due = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] = due.isEmpty() ? QStringLiteral("Reminders") : due;
+        }
+        if (item.contains(QStringLiteral("peer"))) {
+            // "desktop · /Volumes/Archive/2019" rather than donated://.
+            const QString where = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] =
+                where.isEmpty() ? item.value(QStringLiteral("peer")).toString() : where;
+        } else if (item.contains(QStringLiteral("team"))) {
+            // "docs · /Volumes/Team/Handbook": whose index it came from.
+            const QString where = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] =
+                where.isEmpty() ? item.value(QStringLiteral("team")).toString() : where;
+        } else if (item.contains(QStringLiteral("remote"))) {
+            // "devbox · /srv/app/config" rather than donated://.
+            const QString where = obj.value(QStringLiteral("contentDescription")).toString();
+            item[QStringLiteral("parentPath")] =
+                where.isEmpty() ? item.value(QStringLiteral("remote")).toString() : where;
+        }
 
         newResults.append(item);
     }
 
+    appendClipboardMatches(newResults);
+
     m_results = std::move(newResults);
     rebuildResultRows();
     m_selectedIndex = firstSelectableRow();
@@ -786,6 +1103,19 @@ void SearchController::parseSearchResponse(const QJsonObject& response)
 
 void SearchController::rebuildResultRows()
 {
+    QVariantList applicationRows;
+    QVariantList settingsRows;
+    QVariantList contactRows;
+    QVariantList eventRows;
+    QVariantList webPageRows;
+    QVariantList mailRows;
+    QVariantList noteRows;
+    QVariantList messageRows;
+    QVariantList reminderRows;
+    QVariantList clipboardRows;
+    QVariantList peerRows;
+    QVariantList remoteRows;
+    QVariantList teamRows;
     QVariantList recentRows;
     QVariantList folderRows;
     QVariantList fileRows;
@@ -799,7 +1129,33 @@ void SearchController::rebuildResultRows()
 
         const QString kind = item.value(QStringLiteral("kind")).toString();
         const int frequency = item.value(QStringLiteral("frequency")).toInt();
-        if (frequency > 0) {
+        if (kind == QLatin1String("application")) {
+            applicationRows.append(row);
+        } else if (kind == QLatin1String("settings")) {
+            settingsRows.append(row);
+        } else if (kind == QLatin1String("contact")) {
+            contactRows.append(row);
+        } else if (kind == QLatin1String("event")) {
+            eventRows.append(row);
+        } else if (kind == QLatin1String("webpage")) {
+            webPageRows.append(row);
+        } else if (kind == QLatin1String("mail")) {
+            mailRows.append(row);
+        } else if (kind == QLatin1String("note")) {
+            noteRows.append(row);
+        } else if (kind == QLatin1String("message")) {
+            messageRows.append(row);
+        } else if (kind == QLatin1String("reminder")) {
+            reminderRows.append(row);
+        } else if (kind == QLatin1String("clipboard")) {
+            clipboardRows.append(row);
+        } else if (item.contains(QStringLiteral("peer"))) {
+            peerRows.append(row);
+        } else if (item.contains(QStringLiteral("remote"))) {
+            remoteRows.append(row);
+        } else if (item.contains(QStringLiteral("team"))) {
+            teamRows.append(row);
+        } else if (frequency > 0) {
             recentRows.append(row);
         } else if (kind == QLatin1String("directory")) {
             folderRows.append(row);
@@ -822,6 +1178,19 @@ void SearchController::rebuildResultRows()
         }
     };
 
+    appendGroup(QStringLiteral("Applications"), applicationRows);
+    appendGroup(QStringLiteral("System Settings"), settingsRows);
+    appendGroup(QStringLiteral("Contacts"), contactRows);
+    appendGroup(QStringLiteral("Events"), eventRows);
+    appendGroup(QStringLiteral("Web Pages"), webPageRows);
+    appendGroup(QStringLiteral("Mail"), mailRows);
+    appendGroup(QStringLiteral("Notes"), noteRows);
+    appendGroup(QStringLiteral("Messages"), messageRows);
+    appendGroup(QStringLiteral("Reminders"), reminderRows);
+    appendGroup(QStringLiteral("Clipboard"), clipboardRows);
+    appendGroup(QStringLiteral("Other Macs"), peerRows);
+    appendGroup(QStringLiteral("Servers"), remoteRows);
+    appendGroup(QStringLiteral("Team"), teamRows);
     appendGroup(QStringLiteral("Recently Opened"), recentRows);
     appendGroup(QStringLiteral("Folders"), folderRows);
     appendGroup(QStringLiteral("Files"), fileRows);
@@ -866,6 +1235,24 @@ int SearchController::nextSelectableRow(int fromIndex, int delta) const
     return -1;
 }
 
+void SearchController::launchApplication(const QString& path)
+{
+    LOG_INFO(bsCore, "SearchController: launching '%s'", qPrintable(path));
+    // The service launches it and counts the launch for ordering.
+    SocketClient* client = ensureQueryClient(250);
+    if (client && client->isConnected()) {
+        QJsonObject params;
+        params[QStringLiteral("path")] = path;
+        params[QStringLiteral("query")] = m_query;
+        const auto response = client->sendRequest(QStringLiteral("launchApp"), params, 1000);
+        if (response.has_value()
+            && response->value(QStringLiteral("type")).toString() != QLatin1String("error")) {
+            return;
+        }
+    }
+    QDesktopServices::openUrl(QUrl::fromLocalFile(path));
+}
+
 QString SearchController::pathForResult(int index) const
 {
     if (index < 0 || index >= m_results.size()) {
@@ -891,6 +1278,63 @@ void SearchController::handleClipboardChanged()
     updateClipboardSignalsFromText(clipboard->text(QClipboard::Clipboard));
 }
 
+void SearchController::recordClipboardHistory()
+{
+    if (!m_clipboardHistory) {
+        return;
+    }
+    const QClipboard* clipboard = QGuiApplication::clipboard();
+    const QMimeData* mime = clipboard ? clipboard->mimeData(QClipboard::Clipboard) : nullptr;
+    // Text only: a copied file's name or an image's caption is not kept,
+    // nor what a password manager marks concealed.
+    if (!mime || !mime->hasText() || mime->hasImage() || mime->hasUrls()
+        || ClipboardHistory::isExcludedFormat(mime->formats())) {
+        return;
+    }
+    if (!m_clipboardHistory->record(mime->text(),
+                                    static_cast<double>(QDateTime::currentSecsSinceEpoch()))) {
+        return;
+    }
+    QString error;
+    if (!m_clipboardHistory->save(&error)) {
+        LOG_WARN(bsCore, "SearchController: clipboard history not saved: %s", qPrintable(error));
+    }
+}
+
+void SearchController::appendClipboardMatches(QVariantList& results) const
+{
+    if (!m_clipboardHistory) {
+        return;
+    }
+    const auto matches = m_clipboardHistory->search(m_query.trimmed(), kMaxClipboardResults);
+    for (const ClipboardEntry& entry : matches) {
+        QVariantMap item;
+        item[QStringLiteral("itemId")] = 0;
+        item[QStringLiteral("path")] =
+            QStringLiteral("clipboard://") + ClipboardHistory::identifier(entry);
+        item[QStringLiteral("name")] = ClipboardHistory::title(entry);
+        item[QStringLiteral("kind")] = QStringLiteral("clipboard");
+        item[QStringLiteral("text")] = entry.text;
+        item[QStringLiteral("snippet")] = entry.text.left(200);
+        item[QStringLiteral("score")] = 0.0;
+        item[QStringLiteral("frequency")] = 0;
+        item[QStringLiteral("contentAvailable")] = false;
+        item[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+        item[QStringLiteral("answerStatus")] = QStringLiteral("idle");
+        item[QStringLiteral("parentPath")] =
+            QStringLiteral("Copied ")
+            + QDateTime::fromSecsSinceEpoch(static_cast<qint64>(entry.copiedAt))
+                  .toString(QStringLiteral("yyyy-MM-dd HH:mm"));
+        results.append(item);
+    }
+}
+
+QString SearchController::clipboardHistoryPath()
+{
+    return QStandardPaths::writableLocation(QStandardPaths::AppDataLocation)
+           + QStringLiteral("/clipboard-history.bin");
+}
+
 void SearchController::clearClipboardSignals()
 {
     m_clipboardBasenameSignal.reset();
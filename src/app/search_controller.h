@@ -1,5 +1,6 @@
 #pragma once
 
+#include "core/indexing/clipboard_history.h"
 #include "core/ipc/socket_client.h"
 
 #include <QObject>
@@ -24,6 +25,15 @@ class SearchController : public QObject {
     Q_PROPERTY(QVariantList resultRows READ resultRows NOTIFY resultRowsChanged)
     Q_PROPERTY(bool isSearching READ isSearching NOTIFY isSearchingChanged)
     Q_PROPERTY(int selectedIndex READ selectedIndex WRITE setSelectedIndex NOTIFY selectedIndexChanged)
+    // How the service read the query ("screenshots modified 2026-10-13");
+    // empty when it matched the words as typed.
+    Q_PROPERTY(QString interpretation READ interpretation NOTIFY interpretationChanged)
+    // Calculator or unit conversion answer ("72 °F = 22.222222 °C"); empty
+    // for ordinary queries.
+    Q_PROPERTY(QString instantAnswer READ instantAnswer NOTIFY instantAnswerChanged)
+    // Dictionary entry for a lone word that matched little on disk: word,
+    // pronunciation, partOfSpeech, summary, url. Empty otherwise.
+    Q_PROPERTY(QVariantMap definition READ definition NOTIFY definitionChanged)
 
 public:
     explicit SearchController(QObject* parent = nullptr);
@@ -33,6 +43,8 @@ public:
     void setSupervisor(Supervisor* supervisor);
     void setServiceManager(ServiceManager* serviceManager);
     void setClipboardSignalsEnabled(bool enabled);
+    // Keeps and searches the clipboard history; turning it off deletes it.
+    void setClipboardHistoryEnabled(bool enabled);
     void recordBehaviorEvent(const QJsonObject& event);
 
     QString query() const;
@@ -45,11 +57,21 @@ public:
     int selectedIndex() const;
     void setSelectedIndex(int index);
 
+    QString interpretation() const;
+    QString instantAnswer() const;
+    QVariantMap definition() const;
+
     Q_INVOKABLE void openResult(int index);
     Q_INVOKABLE void revealInFinder(int index);
     Q_INVOKABLE void copyPath(int index);
     Q_INVOKABLE QVariantMap requestAnswerSnippet(int index);
     Q_INVOKABLE void clearResults();
+    // Re-runs the current query matching its words as typed.
+    Q_INVOKABLE void searchLiterally();
+    // Copies the instant answer's value, without the expression or unit.
+    Q_INVOKABLE void copyInstantAnswer();
+    // Opens the definition's entry in Dictionary.app.
+    Q_INVOKABLE void openDefinition();
     Q_INVOKABLE void moveSelection(int delta);
 
     Q_INVOKABLE QVariantMap getHealthSync();
@@ -60,6 +82,9 @@ signals:
     void resultRowsChanged();
     void isSearchingChanged();
     void selectedIndexChanged();
+    void interpretationChanged();
+    void instantAnswerChanged();
+    void definitionChanged();
 
 private slots:
     void executeSearch();
@@ -76,9 +101,14 @@ private:
     int firstSelectableRow() const;
     int nextSelectableRow(int fromIndex, int delta) const;
     QString pathForResult(int index) const;
+    void launchApplication(const QString& path);
+    void recordResultFeedback(int resultIndex, const QString& action);
     void handleClipboardChanged();
     void clearClipboardSignals();
     void updateClipboardSignalsFromText(const QString& text);
+    void recordClipboardHistory();
+    void appendClipboardMatches(QVariantList& results) const;
+    static QString clipboardHistoryPath();
 
     Supervisor* m_supervisor = nullptr;
     ServiceManager* m_serviceManager = nullptr;
@@ -90,12 +120,18 @@ private:
     QVariantList m_resultRows;
     bool m_isSearching = false;
     int m_selectedIndex = -1;
+    QString m_interpretation;
+    QString m_literalQuery;  // searched with naturalLanguage off
+    QString m_instantAnswer;
+    QString m_instantAnswerValue;
+    QVariantMap m_definition;
     QVariantMap m_lastHealthSnapshot;
     qint64 m_lastHealthSnapshotTimeMs = 0;
     bool m_clipboardSignalsEnabled = false;
     std::optional<QString> m_clipboardBasenameSignal;
     std::optional<QString> m_clipboardDirnameSignal;
     std::optional<QString> m_clipboardExtensionSignal;
+    std::unique_ptr<ClipboardHistory> m_clipboardHistory;  // null while off
     QString m_lastFrontmostAppBundleId;
     QString m_lastSystemEventId;
     QString m_lastSystemActivityDigest;
@@ -105,6 +141,7 @@ private:
     QTimer m_debounceTimer;
     static constexpr int kDebounceMs = 100;
     static constexpr int kSearchTimeoutMs = 10000;
+    static constexpr int kMaxClipboardResults = 5;
 };
 
 } // namespace bs
@@ -373,6 +373,11 @@ QVariantMap ServiceManager::healthSnapshot() const
     return m_latestHealthSnapshot.toVariantMap();
 }
 
+QVariantMap ServiceManager::indexerResources() const
+{
+    return m_indexerResources.toVariantMap();
+}
+
 Supervisor* ServiceManager::supervisor() const
 {
     return nullptr;
@@ -692,12 +697,29 @@ void ServiceManager::startIndexing()
 {
     QJsonObject params;
     params[QStringLiteral("roots")] = loadIndexRoots();
+    params[QStringLiteral("privacyExclusions")] = loadPrivacyExclusions();
 
     LOG_INFO(bsCore, "ServiceManager: sending startIndexing (%d root(s))",
              static_cast<int>(params.value(QStringLiteral("roots")).toArray().size()));
-    if (sendIndexerRequest(QStringLiteral("startIndexing"), params)) {
-        m_indexingActive = true;
-        updateTrayState();
+    const ServiceRequestResult request =
+        sendServiceRequestSync(QStringLiteral("indexer"), QStringLiteral("startIndexing"), params);
+    if (!request.ok) {
+        LOG_ERROR(bsCore, "ServiceManager: indexer 'startIndexing' failed: %s",
+                  qPrintable(request.error));
+        emit serviceError(QStringLiteral("indexer"), request.error);
+        return;
+    }
+    m_indexingActive = true;
+    updateTrayState();
+
+    // Folders added to privacyExclusions while the indexer was down are
+    // purged at start; sweep what the query service still holds for them.
+    const QJsonObject result = request.response.value(QStringLiteral("result")).toObject();
+    if (result.value(QStringLiteral("privacyPurgedItems")).toInteger() > 0) {
+        QJsonObject sweepParams;
+        sweepParams[QStringLiteral("paths")] = params.value(QStringLiteral("privacyExclusions"));
+        sendServiceRequest(QStringLiteral("query"), QStringLiteral("purgePrivacyPaths"),
+                           sweepParams);
     }
 }
 
@@ -777,6 +799,41 @@ bool ServiceManager::clearExtractionCache()
     return sendServiceRequest(QStringLiteral("extractor"), QStringLiteral("clearExtractionCache"));
 }
 
+bool ServiceManager::syncContacts()
+{
+    return sendIndexerRequest(QStringLiteral("syncContacts"));
+}
+
+bool ServiceManager::syncCalendar()
+{
+    return sendIndexerRequest(QStringLiteral("syncCalendar"));
+}
+
+bool ServiceManager::syncBrowsers()
+{
+    return sendIndexerRequest(QStringLiteral("syncBrowsers"));
+}
+
+bool ServiceManager::syncMail()
+{
+    return sendIndexerRequest(QStringLiteral("syncMail"));
+}
+
+bool ServiceManager::syncNotes()
+{
+    return sendIndexerRequest(QStringLiteral("syncNotes"));
+}
+
+bool ServiceManager::syncMessages()
+{
+    return sendIndexerRequest(QStringLiteral("syncMessages"));
+}
+
+bool ServiceManager::syncReminders()
+{
+    return sendIndexerRequest(QStringLiteral("syncReminders"));
+}
+
 bool ServiceManager::reindexPath(const QString& path)
 {
     QString normalizedPath = path;
@@ -793,6 +850,63 @@ bool ServiceManager::reindexPath(const QString& path)
     return false;
 }
 
+bool ServiceManager::addPrivacyExclusion(const QString& path)
+{
+    QJsonObject params;
+    params[QStringLiteral("path")] = path;
+    // The indexer deletes the folder's items before replying, which can take
+    // a while for a large folder.
+    const ServiceRequestResult purge =
+        sendServiceRequestSync(QStringLiteral("indexer"),
+                               QStringLiteral("addPrivacyExclusion"),
+                               params,
+                               120000);
+    if (!purge.ok) {
+        LOG_ERROR(bsCore, "ServiceManager: indexer 'addPrivacyExclusion' failed: %s",
+                  qPrintable(purge.error));
+        emit serviceError(QStringLiteral("indexer"), purge.error);
+        return false;
+    }
+    const QJsonObject purged = purge.response.value(QStringLiteral("result")).toObject();
+
+    // Embeddings, cached results and thumbnails live in the query service,
+    // extracted text in the extractor's cache.
+    QJsonObject sweepParams;
+    sweepParams[QStringLiteral("paths")] = QJsonArray{path};
+    const ServiceRequestResult sweep =
+        sendServiceRequestSync(QStringLiteral("query"),
+                               QStringLiteral("purgePrivacyPaths"),
+                               sweepParams,
+                               60000);
+    if (!sweep.ok) {
+        LOG_ERROR(bsCore, "ServiceManager: query 'purgePrivacyPaths' failed: %s",
+                  qPrintable(sweep.error));
+        emit serviceError(QStringLiteral("query"), sweep.error);
+    }
+    const bool cacheCleared = clearExtractionCache();
+
+    const bool verified = purged.value(QStringLiteral("verified")).toBool()
+        && sweep.response.value(QStringLiteral("result")).toObject()
+               .value(QStringLiteral("verified")).toBool()
+        && cacheCleared;
+    LOG_INFO(bsCore, "ServiceManager: privacy folder %s purged (%lld item(s), verified=%d)",
+             qUtf8Printable(path),
+             static_cast<long long>(purged.value(QStringLiteral("deletedItems")).toInteger()),
+             verified ? 1 : 0);
+    return verified;
+}
+
+bool ServiceManager::removePrivacyExclusion(const QString& path)
+{
+    QJsonObject params;
+    params[QStringLiteral("path")] = path;
+    if (!sendIndexerRequest(QStringLiteral("removePrivacyExclusion"), params)) {
+        return false;
+    }
+    // Nothing under the folder is indexed any more; bring it back.
+    return reindexPath(path);
+}
+
 bool ServiceManager::downloadModels(const QStringList& roles, bool includeExisting)
 {
     {
@@ -1088,6 +1202,11 @@ QJsonArray ServiceManager::loadIndexRoots() const
     return roots;
 }
 
+QJsonArray ServiceManager::loadPrivacyExclusions() const
+{
+    return readAppSettings().value(QStringLiteral("privacyExclusions")).toArray();
+}
+
 QJsonArray ServiceManager::loadEmbeddingRoots() const
 {
     const QJsonObject settings = readAppSettings();
@@ -1276,6 +1395,20 @@ void ServiceManager::refreshIndexerQueueStatus()
         }
     }
 
+    // The indexer's compaction cannot reach embeddings and cached results;
+    // sweep those once it reports a new one.
+    const qint64 compactedAtMs = result.value(QStringLiteral("compactedAtMs")).toInteger();
+    if (compactedAtMs > 0 && compactedAtMs != m_lastQueueCompactedAtMs) {
+        m_lastQueueCompactedAtMs = compactedAtMs;
+        sendServiceRequest(QStringLiteral("query"), QStringLiteral("purgeOrphans"), {});
+    }
+
+    const QJsonObject resources = result.value(QStringLiteral("resources")).toObject();
+    if (resources != m_indexerResources) {
+        m_indexerResources = resources;
+        emit indexerResourcesChanged();
+    }
+
     if (m_indexingActive == active) {
         return;
     }
@@ -31,6 +31,8 @@ class ServiceManager : public QObject {
     Q_PROPERTY(QString modelDownloadStatus READ modelDownloadStatus NOTIFY modelDownloadStateChanged)
     Q_PROPERTY(bool modelDownloadHasError READ modelDownloadHasError NOTIFY modelDownloadStateChanged)
     Q_PROPERTY(QVariantMap healthSnapshot READ healthSnapshot NOTIFY healthSnapshotChanged)
+    // The indexer's getResourceUsage() result, refreshed with the queue status.
+    Q_PROPERTY(QVariantMap indexerResources READ indexerResources NOTIFY indexerResourcesChanged)
 
 public:
     enum class TrayState {
@@ -53,6 +55,7 @@ public:
     QString modelDownloadStatus() const;
     bool modelDownloadHasError() const;
     QVariantMap healthSnapshot() const;
+    QVariantMap indexerResources() const;
 
     // Legacy accessor retained for compatibility.
     Supervisor* supervisor() const;
@@ -65,7 +68,16 @@ public:
     Q_INVOKABLE bool rebuildAll();
     Q_INVOKABLE bool rebuildVectorIndex();
     Q_INVOKABLE bool clearExtractionCache();
+    Q_INVOKABLE bool syncContacts();
+    Q_INVOKABLE bool syncCalendar();
+    Q_INVOKABLE bool syncBrowsers();
+    Q_INVOKABLE bool syncMail();
+    Q_INVOKABLE bool syncNotes();
+    Q_INVOKABLE bool syncMessages();
+    Q_INVOKABLE bool syncReminders();
     Q_INVOKABLE bool reindexPath(const QString& path);
+    Q_INVOKABLE bool addPrivacyExclusion(const QString& path);
+    Q_INVOKABLE bool removePrivacyExclusion(const QString& path);
     Q_INVOKABLE bool downloadModels(const QStringList& roles, bool includeExisting = false);
     Q_INVOKABLE QVariantList serviceDiagnostics() const;
     Q_INVOKABLE void triggerInitialIndexing();
@@ -79,6 +91,7 @@ signals:
     void trayStateChanged();
     void modelDownloadStateChanged();
     void healthSnapshotChanged();
+    void indexerResourcesChanged();
     void healthSnapshotUpdated(const QJsonObject& snapshot);
 
 private slots:
@@ -119,6 +132,7 @@ private:
     bool sendIndexerRequest(const QString& method, const QJsonObject& params = {});
     QJsonArray loadIndexRoots() const;
     QJsonArray loadEmbeddingRoots() const;
+    QJsonArray loadPrivacyExclusions() const;
 
     void startControlPlaneThread();
     void stopControlPlaneThread();
@@ -146,6 +160,8 @@ private:
     QTimer m_indexingStatusTimer;
     bool m_lastQueueRebuildRunning = false;
     qint64 m_lastQueueRebuildFinishedAtMs = 0;
+    qint64 m_lastQueueCompactedAtMs = 0;
+    QJsonObject m_indexerResources;
     bool m_pendingPostRebuildVectorRefresh = false;
     int m_pendingPostRebuildVectorRefreshAttempts = 0;
     bool m_started = false;
@@ -1,5 +1,6 @@
 #include "settings_controller.h"
 #include "platform_integration.h"
+#include "core/shared/audit_log.h"
 
 #include <QDateTime>
 #include <QDir>
@@ -10,6 +11,7 @@
 #include <QSaveFile>
 #include <QStandardPaths>
 #include <QTimer>
+#include <QUrl>
 #include <QVariantMap>
 
 #include <sqlite3.h>
@@ -87,6 +89,63 @@ QJsonArray stringListToJsonArray(const QStringList& values)
     return out;
 }
 
+// Folder dialogs hand back file:// URLs and people type ~/...; empty for
+// anything that is still not absolute.
+QString normalizedFolderPath(const QString& folderPath)
+{
+    QString path = folderPath.trimmed();
+    if (path.startsWith(QStringLiteral("file://"))) {
+        path = QUrl(path).toLocalFile();
+    } else if (path == QStringLiteral("~") || path.startsWith(QStringLiteral("~/"))) {
+        path = QDir::homePath() + path.mid(1);
+    }
+    if (!QDir::isAbsolutePath(path)) {
+        return {};
+    }
+    return QDir::cleanPath(path);
+}
+
+// s3Sources entries without accessKeyId/secretAccessKey: an entry names a
+// profile of the AWS credentials file instead (S3Source), so neither
+// settings.json nor the index's settings table holds a secret.
+QJsonArray s3SourcesWithoutKeys(const QJsonArray& sources)
+{
+    QJsonArray out;
+    for (const QJsonValue& value : sources) {
+        QJsonObject source = value.toObject();
+        source.remove(QStringLiteral("accessKeyId"));
+        source.remove(QStringLiteral("secretAccessKey"));
+        out.append(source);
+    }
+    return out;
+}
+
+// `contentTypes: {allow, deny}` on indexRoots entries, plus a top-level one
+// for every root, in the indexer's `content_type_rules` shape.
+QJsonArray contentTypeRulesFromSettings(const QJsonObject& settings)
+{
+    QJsonArray rules;
+    const auto append = [&rules](const QString& root, const QJsonObject& types) {
+        if (types.isEmpty()) {
+            return;
+        }
+        QJsonObject rule;
+        rule[QStringLiteral("root")] = root;
+        rule[QStringLiteral("allow")] = types.value(QStringLiteral("allow")).toArray();
+        rule[QStringLiteral("deny")] = types.value(QStringLiteral("deny")).toArray();
+        rules.append(rule);
+    };
+    append(QStringLiteral("*"), settings.value(QStringLiteral("contentTypes")).toObject());
+    for (const QJsonValue& value : settings.value(QStringLiteral("indexRoots")).toArray()) {
+        const QJsonObject root = value.toObject();
+        const QString path = normalizedFolderPath(root.value(QStringLiteral("path")).toString());
+        if (!path.isEmpty()) {
+            append(path, root.value(QStringLiteral("contentTypes")).toObject());
+        }
+    }
+    return rules;
+}
+
 QVariantList jsonArrayToVariantList(const QJsonArray& arr)
 {
     QVariantList out;
@@ -106,6 +165,10 @@ QJsonArray variantListToJsonArray(const QVariantList& values)
         QJsonObject obj;
         obj[QStringLiteral("path")] = map.value(QStringLiteral("path")).toString();
         obj[QStringLiteral("mode")] = map.value(QStringLiteral("mode"), QStringLiteral("index_embed")).toString();
+        if (map.contains(QStringLiteral("contentTypes"))) {
+            obj[QStringLiteral("contentTypes")] =
+                QJsonObject::fromVariantMap(map.value(QStringLiteral("contentTypes")).toMap());
+        }
         out.append(obj);
     }
 
@@ -235,6 +298,8 @@ void syncRuntimeSettingsToDb(const QJsonObject& settings)
                   boolToSqlValue(settings.value(QStringLiteral("qaSnippetEnabled")).toBool(true)));
     upsertSetting(db, QStringLiteral("personalizedLtrEnabled"),
                   boolToSqlValue(settings.value(QStringLiteral("personalizedLtrEnabled")).toBool(true)));
+    upsertSetting(db, QStringLiteral("recentDocumentsSignalEnabled"),
+                  boolToSqlValue(settings.value(QStringLiteral("recentDocumentsSignalEnabled")).toBool(true)));
     upsertSetting(db, QStringLiteral("behaviorStreamEnabled"),
                   boolToSqlValue(settings.value(QStringLiteral("behaviorStreamEnabled")).toBool(false)));
     upsertSetting(db, QStringLiteral("learningEnabled"),
@@ -321,6 +386,9 @@ void syncRuntimeSettingsToDb(const QJsonObject& settings)
                       60, settings.value(QStringLiteral("onlineRankerMaxTrainingBatchSize")).toInt(1200))));
     upsertSetting(db, QStringLiteral("behaviorRawRetentionDays"),
                   QString::number(settings.value(QStringLiteral("behaviorRawRetentionDays")).toInt(30)));
+    upsertSetting(db, QStringLiteral("queryHistoryRetentionDays"),
+                  QString::number(std::max(
+                      0, settings.value(QStringLiteral("queryHistoryRetentionDays")).toInt(90))));
     upsertSetting(db, QStringLiteral("semanticBudgetMs"),
                   QString::number(settings.value(QStringLiteral("semanticBudgetMs")).toInt(70)));
     upsertSetting(db, QStringLiteral("rerankBudgetMs"),
@@ -329,6 +397,63 @@ void syncRuntimeSettingsToDb(const QJsonObject& settings)
                   QString::number(settings.value(QStringLiteral("maxFileSizeMB")).toInt(50) * 1024 * 1024));
     upsertSetting(db, QStringLiteral("extraction_timeout_ms"),
                   QString::number(settings.value(QStringLiteral("extractionTimeoutMs")).toInt(30000)));
+    upsertSetting(db, QStringLiteral("extraction_sandbox"),
+                  settings.value(QStringLiteral("extractionSandbox")).toBool(true)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_contacts"),
+                  settings.value(QStringLiteral("indexContacts")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_calendar"),
+                  settings.value(QStringLiteral("indexCalendar")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_browser_history"),
+                  settings.value(QStringLiteral("indexBrowserHistory")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_mail"),
+                  settings.value(QStringLiteral("indexMail")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("mail_excluded_accounts"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("mailExcludedAccounts")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("index_notes"),
+                  settings.value(QStringLiteral("indexNotes")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_messages"),
+                  settings.value(QStringLiteral("indexMessages")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("index_reminders"),
+                  settings.value(QStringLiteral("indexReminders")).toBool(false)
+                      ? QStringLiteral("1") : QStringLiteral("0"));
+    upsertSetting(db, QStringLiteral("secret_redaction"),
+                  settings.value(QStringLiteral("secretRedaction")).toString(QStringLiteral("hash")));
+    upsertSetting(db, QStringLiteral("indexed_xattrs"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("indexedXattrs")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("sync_peers"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("syncPeers")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("sync_merge_policies"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("syncMergePolicies")).toObject())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("federated_indexes"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("federatedIndexes")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("team_indexes"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("teamIndexes")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("ssh_sources"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("sshSources")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("s3_sources"),
+                  QString::fromUtf8(QJsonDocument(s3SourcesWithoutKeys(
+                                            settings.value(QStringLiteral("s3Sources")).toArray()))
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("network_shares"),
+                  QString::fromUtf8(QJsonDocument(settings.value(QStringLiteral("networkShares")).toArray())
+                                        .toJson(QJsonDocument::Compact)));
+    upsertSetting(db, QStringLiteral("content_type_rules"),
+                  QString::fromUtf8(QJsonDocument(contentTypeRulesFromSettings(settings))
+                                        .toJson(QJsonDocument::Compact)));
     sqlite3_close(db);
 }
 
@@ -392,6 +517,51 @@ bool SettingsController::enableOcr() const
     return m_settings.value(QStringLiteral("enableOcr")).toBool(false);
 }
 
+bool SettingsController::indexContacts() const
+{
+    return m_settings.value(QStringLiteral("indexContacts")).toBool(false);
+}
+
+bool SettingsController::indexCalendar() const
+{
+    return m_settings.value(QStringLiteral("indexCalendar")).toBool(false);
+}
+
+bool SettingsController::indexBrowserHistory() const
+{
+    return m_settings.value(QStringLiteral("indexBrowserHistory")).toBool(false);
+}
+
+bool SettingsController::indexMail() const
+{
+    return m_settings.value(QStringLiteral("indexMail")).toBool(false);
+}
+
+QStringList SettingsController::mailExcludedAccounts() const
+{
+    return jsonArrayToStringList(m_settings.value(QStringLiteral("mailExcludedAccounts")).toArray());
+}
+
+bool SettingsController::indexNotes() const
+{
+    return m_settings.value(QStringLiteral("indexNotes")).toBool(false);
+}
+
+bool SettingsController::indexMessages() const
+{
+    return m_settings.value(QStringLiteral("indexMessages")).toBool(false);
+}
+
+bool SettingsController::indexReminders() const
+{
+    return m_settings.value(QStringLiteral("indexReminders")).toBool(false);
+}
+
+bool SettingsController::indexClipboard() const
+{
+    return m_settings.value(QStringLiteral("indexClipboard")).toBool(false);
+}
+
 bool SettingsController::embeddingEnabled() const
 {
     return m_settings.value(QStringLiteral("embeddingEnabled")).toBool(false);
@@ -537,16 +707,46 @@ bool SettingsController::clipboardSignalEnabled() const
     return m_settings.value(QStringLiteral("clipboardSignalEnabled")).toBool(false);
 }
 
+bool SettingsController::recentDocumentsSignalEnabled() const
+{
+    return m_settings.value(QStringLiteral("recentDocumentsSignalEnabled")).toBool(true);
+}
+
 int SettingsController::feedbackRetentionDays() const
 {
     return m_settings.value(QStringLiteral("feedbackRetentionDays")).toInt(90);
 }
 
+int SettingsController::queryHistoryRetentionDays() const
+{
+    return m_settings.value(QStringLiteral("queryHistoryRetentionDays")).toInt(90);
+}
+
 QStringList SettingsController::sensitivePaths() const
 {
     return jsonArrayToStringList(m_settings.value(QStringLiteral("sensitivePaths")).toArray());
 }
 
+QStringList SettingsController::privacyExclusions() const
+{
+    return jsonArrayToStringList(m_settings.value(QStringLiteral("privacyExclusions")).toArray());
+}
+
+QString SettingsController::secretRedaction() const
+{
+    return m_settings.value(QStringLiteral("secretRedaction")).toString(QStringLiteral("hash"));
+}
+
+bool SettingsController::auditLogEnabled() const
+{
+    return m_settings.value(QStringLiteral("auditLogEnabled")).toBool(false);
+}
+
+QString SettingsController::auditLogDirectory() const
+{
+    return AuditLog::defaultDirectory();
+}
+
 QString SettingsController::theme() const
 {
     return m_settings.value(QStringLiteral("theme")).toString(QStringLiteral("system"));
@@ -737,6 +937,105 @@ void SettingsController::setEnableOcr(bool enabled)
     emit settingsChanged(QStringLiteral("enableOcr"));
 }
 
+void SettingsController::setIndexContacts(bool enabled)
+{
+    if (indexContacts() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexContacts")] = enabled;
+    saveSettings();
+    emit indexContactsChanged();
+    emit settingsChanged(QStringLiteral("indexContacts"));
+}
+
+void SettingsController::setIndexCalendar(bool enabled)
+{
+    if (indexCalendar() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexCalendar")] = enabled;
+    saveSettings();
+    emit indexCalendarChanged();
+    emit settingsChanged(QStringLiteral("indexCalendar"));
+}
+
+void SettingsController::setIndexBrowserHistory(bool enabled)
+{
+    if (indexBrowserHistory() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexBrowserHistory")] = enabled;
+    saveSettings();
+    emit indexBrowserHistoryChanged();
+    emit settingsChanged(QStringLiteral("indexBrowserHistory"));
+}
+
+void SettingsController::setIndexMail(bool enabled)
+{
+    if (indexMail() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexMail")] = enabled;
+    saveSettings();
+    emit indexMailChanged();
+    emit settingsChanged(QStringLiteral("indexMail"));
+}
+
+void SettingsController::setMailExcludedAccounts(const QStringList& accounts)
+{
+    if (mailExcludedAccounts() == accounts) {
+        return;
+    }
+    m_settings[QStringLiteral("mailExcludedAccounts")] = stringListToJsonArray(accounts);
+    saveSettings();
+    emit mailExcludedAccountsChanged();
+    emit settingsChanged(QStringLiteral("mailExcludedAccounts"));
+}
+
+void SettingsController::setIndexNotes(bool enabled)
+{
+    if (indexNotes() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexNotes")] = enabled;
+    saveSettings();
+    emit indexNotesChanged();
+    emit settingsChanged(QStringLiteral("indexNotes"));
+}
+
+void SettingsController::setIndexMessages(bool enabled)
+{
+    if (indexMessages() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexMessages")] = enabled;
+    saveSettings();
+    emit indexMessagesChanged();
+    emit settingsChanged(QStringLiteral("indexMessages"));
+}
+
+void SettingsController::setIndexReminders(bool enabled)
+{
+    if (indexReminders() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexReminders")] = enabled;
+    saveSettings();
+    emit indexRemindersChanged();
+    emit settingsChanged(QStringLiteral("indexReminders"));
+}
+
+void SettingsController::setIndexClipboard(bool enabled)
+{
+    if (indexClipboard() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("indexClipboard")] = enabled;
+    saveSettings();
+    emit indexClipboardChanged();
+    emit settingsChanged(QStringLiteral("indexClipboard"));
+}
+
 void SettingsController::setEmbeddingEnabled(bool enabled)
 {
     if (embeddingEnabled() == enabled) {
@@ -1068,6 +1367,17 @@ void SettingsController::setClipboardSignalEnabled(bool enabled)
     emit settingsChanged(QStringLiteral("clipboardSignalEnabled"));
 }
 
+void SettingsController::setRecentDocumentsSignalEnabled(bool enabled)
+{
+    if (recentDocumentsSignalEnabled() == enabled) {
+        return;
+    }
+    m_settings[QStringLiteral("recentDocumentsSignalEnabled")] = enabled;
+    saveSettings();
+    emit recentDocumentsSignalEnabledChanged();
+    emit settingsChanged(QStringLiteral("recentDocumentsSignalEnabled"));
+}
+
 void SettingsController::setFeedbackRetentionDays(int days)
 {
     const int clamped = std::clamp(days, 7, 365);
@@ -1080,6 +1390,18 @@ void SettingsController::setFeedbackRetentionDays(int days)
     emit settingsChanged(QStringLiteral("feedbackRetentionDays"));
 }
 
+void SettingsController::setQueryHistoryRetentionDays(int days)
+{
+    const int clamped = days <= 0 ? 0 : std::clamp(days, 7, 3650);
+    if (queryHistoryRetentionDays() == clamped) {
+        return;
+    }
+    m_settings[QStringLiteral("queryHistoryRetentionDays")] = clamped;
+    saveSettings();
+    emit queryHistoryRetentionDaysChanged();
+    emit settingsChanged(QStringLiteral("queryHistoryRetentionDays"));
+}
+
 void SettingsController::setSensitivePaths(const QStringList& paths)
 {
     if (sensitivePaths() == paths) {
@@ -1091,6 +1413,44 @@ void SettingsController::setSensitivePaths(const QStringList& paths)
     emit settingsChanged(QStringLiteral("sensitivePaths"));
 }
 
+void SettingsController::setSecretRedaction(const QString& mode)
+{
+    static const QStringList kModes = {
+        QStringLiteral("hash"), QStringLiteral("skip"), QStringLiteral("off"),
+    };
+    if (!kModes.contains(mode) || secretRedaction() == mode) {
+        return;
+    }
+    m_settings[QStringLiteral("secretRedaction")] = mode;
+    saveSettings();
+    emit secretRedactionChanged();
+    emit settingsChanged(QStringLiteral("secretRedaction"));
+}
+
+void SettingsController::setAuditLogEnabled(bool enabled)
+{
+    if (auditLogEnabled() == enabled) {
+        return;
+    }
+    // Switching the log off is itself recorded, so log before the marker goes.
+    AuditLog appLog(AuditLog::defaultDirectory(), QStringLiteral("app"));
+    QJsonObject details;
+    details[QStringLiteral("enabled")] = enabled;
+    if (!enabled) {
+        appLog.append(QStringLiteral("admin"), QStringLiteral("setAuditLogEnabled"), details);
+    }
+    if (!AuditLog::setEnabled(AuditLog::defaultDirectory(), enabled)) {
+        return;
+    }
+    if (enabled) {
+        appLog.append(QStringLiteral("admin"), QStringLiteral("setAuditLogEnabled"), details);
+    }
+    m_settings[QStringLiteral("auditLogEnabled")] = enabled;
+    saveSettings();
+    emit auditLogEnabledChanged();
+    emit settingsChanged(QStringLiteral("auditLogEnabled"));
+}
+
 void SettingsController::setTheme(const QString& value)
 {
     if (theme() == value) {
@@ -1125,6 +1485,7 @@ void SettingsController::clearFeedbackData()
         sqlite3_exec(db, "DELETE FROM behavior_events_v1", nullptr, nullptr, nullptr);
         sqlite3_exec(db, "DELETE FROM training_examples_v1", nullptr, nullptr, nullptr);
         sqlite3_exec(db, "DELETE FROM replay_reservoir_v1", nullptr, nullptr, nullptr);
+        sqlite3_exec(db, "DELETE FROM query_history", nullptr, nullptr, nullptr);
         sqlite3_close(db);
     }
 
@@ -1133,6 +1494,16 @@ void SettingsController::clearFeedbackData()
     emit feedbackDataCleared();
 }
 
+int SettingsController::clearAuditLog()
+{
+    const int64_t removed = AuditLog::purge(AuditLog::defaultDirectory());
+    QJsonObject details;
+    details[QStringLiteral("removedEntries")] = static_cast<qint64>(removed);
+    AuditLog(AuditLog::defaultDirectory(), QStringLiteral("app"))
+        .append(QStringLiteral("admin"), QStringLiteral("purgeAuditLog"), details);
+    return static_cast<int>(removed);
+}
+
 void SettingsController::exportData()
 {
     const QString downloads = QStandardPaths::writableLocation(QStandardPaths::DownloadLocation);
@@ -1223,6 +1594,37 @@ void SettingsController::reindexFolder(const QString& folderPath)
     emit reindexFolderRequested(folderPath);
 }
 
+bool SettingsController::addPrivacyExclusion(const QString& folderPath)
+{
+    const QString path = normalizedFolderPath(folderPath);
+    QStringList paths = privacyExclusions();
+    if (path.isEmpty() || paths.contains(path)) {
+        return false;
+    }
+    paths.append(path);
+    m_settings[QStringLiteral("privacyExclusions")] = stringListToJsonArray(paths);
+    saveSettings();
+    emit privacyExclusionsChanged();
+    emit settingsChanged(QStringLiteral("privacyExclusions"));
+    emit privacyExclusionAdded(path);
+    return true;
+}
+
+bool SettingsController::removePrivacyExclusion(const QString& folderPath)
+{
+    const QString path = normalizedFolderPath(folderPath);
+    QStringList paths = privacyExclusions();
+    if (path.isEmpty() || !paths.removeOne(path)) {
+        return false;
+    }
+    m_settings[QStringLiteral("privacyExclusions")] = stringListToJsonArray(paths);
+    saveSettings();
+    emit privacyExclusionsChanged();
+    emit settingsChanged(QStringLiteral("privacyExclusions"));
+    emit privacyExclusionRemoved(path);
+    return true;
+}
+
 bool SettingsController::setRuntimeSetting(const QString& key, const QString& value)
 {
     const QString normalizedKey = key.trimmed();
@@ -1313,6 +1715,15 @@ void SettingsController::loadSettings()
     ensureDefault(m_settings, QStringLiteral("indexRoots"), defaultIndexRoots());
     ensureDefault(m_settings, QStringLiteral("enablePdf"), true);
     ensureDefault(m_settings, QStringLiteral("enableOcr"), false);
+    ensureDefault(m_settings, QStringLiteral("indexContacts"), false);
+    ensureDefault(m_settings, QStringLiteral("indexCalendar"), false);
+    ensureDefault(m_settings, QStringLiteral("indexBrowserHistory"), false);
+    ensureDefault(m_settings, QStringLiteral("indexMail"), false);
+    ensureDefault(m_settings, QStringLiteral("mailExcludedAccounts"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("indexNotes"), false);
+    ensureDefault(m_settings, QStringLiteral("indexMessages"), false);
+    ensureDefault(m_settings, QStringLiteral("indexReminders"), false);
+    ensureDefault(m_settings, QStringLiteral("indexClipboard"), false);
     ensureDefault(m_settings, QStringLiteral("embeddingEnabled"), true);
     ensureDefault(m_settings, QStringLiteral("inferenceServiceEnabled"), true);
     ensureDefault(m_settings, QStringLiteral("inferenceEmbedOffloadEnabled"), true);
@@ -1361,13 +1772,30 @@ void SettingsController::loadSettings()
     ensureDefault(m_settings, QStringLiteral("rerankBudgetMs"), 120);
     ensureDefault(m_settings, QStringLiteral("maxFileSizeMB"), 50);
     ensureDefault(m_settings, QStringLiteral("extractionTimeoutMs"), 30000);
+    ensureDefault(m_settings, QStringLiteral("extractionSandbox"), true);
     ensureDefault(m_settings, QStringLiteral("userPatterns"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("indexedXattrs"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("syncPeers"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("syncMergePolicies"), QJsonObject{});
+    ensureDefault(m_settings, QStringLiteral("federatedIndexes"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("teamIndexes"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("sshSources"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("s3Sources"), QJsonArray{});
+    // Keys written by earlier versions are dropped, not kept in plain text.
+    m_settings[QStringLiteral("s3Sources")] =
+        s3SourcesWithoutKeys(m_settings.value(QStringLiteral("s3Sources")).toArray());
+    ensureDefault(m_settings, QStringLiteral("networkShares"), QJsonArray{});
     ensureDefault(m_settings, QStringLiteral("enableFeedbackLogging"), true);
     ensureDefault(m_settings, QStringLiteral("enableInteractionTracking"), true);
     ensureDefault(m_settings, QStringLiteral("clipboardSignalEnabled"), false);
+    ensureDefault(m_settings, QStringLiteral("recentDocumentsSignalEnabled"), true);
     ensureDefault(m_settings, QStringLiteral("feedbackRetentionDays"), 90);
+    ensureDefault(m_settings, QStringLiteral("queryHistoryRetentionDays"), 90);
     ensureDefault(m_settings, QStringLiteral("theme"), QStringLiteral("system"));
     ensureDefault(m_settings, QStringLiteral("language"), QStringLiteral("en"));
+    ensureDefault(m_settings, QStringLiteral("privacyExclusions"), QJsonArray{});
+    ensureDefault(m_settings, QStringLiteral("secretRedaction"), QStringLiteral("hash"));
+    ensureDefault(m_settings, QStringLiteral("auditLogEnabled"), false);
     ensureDefault(m_settings, QStringLiteral("sensitivePaths"), QJsonArray{
         home + QStringLiteral("/.ssh"),
         home + QStringLiteral("/.gnupg"),
@@ -1376,6 +1804,8 @@ void SettingsController::loadSettings()
         home + QStringLiteral("/Library/Preferences"),
     });
 
+    // The services only see the marker file; keep it in step with settings.
+    AuditLog::setEnabled(AuditLog::defaultDirectory(), auditLogEnabled());
     saveSettings();
 }
 
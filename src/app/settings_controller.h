@@ -22,6 +22,15 @@ class SettingsController : public QObject {
     Q_PROPERTY(QVariantList indexRoots READ indexRoots WRITE setIndexRoots NOTIFY indexRootsChanged)
     Q_PROPERTY(bool enablePdf READ enablePdf WRITE setEnablePdf NOTIFY enablePdfChanged)
     Q_PROPERTY(bool enableOcr READ enableOcr WRITE setEnableOcr NOTIFY enableOcrChanged)
+    Q_PROPERTY(bool indexContacts READ indexContacts WRITE setIndexContacts NOTIFY indexContactsChanged)
+    Q_PROPERTY(bool indexCalendar READ indexCalendar WRITE setIndexCalendar NOTIFY indexCalendarChanged)
+    Q_PROPERTY(bool indexBrowserHistory READ indexBrowserHistory WRITE setIndexBrowserHistory NOTIFY indexBrowserHistoryChanged)
+    Q_PROPERTY(bool indexMail READ indexMail WRITE setIndexMail NOTIFY indexMailChanged)
+    Q_PROPERTY(bool indexNotes READ indexNotes WRITE setIndexNotes NOTIFY indexNotesChanged)
+    Q_PROPERTY(bool indexMessages READ indexMessages WRITE setIndexMessages NOTIFY indexMessagesChanged)
+    Q_PROPERTY(bool indexReminders READ indexReminders WRITE setIndexReminders NOTIFY indexRemindersChanged)
+    Q_PROPERTY(bool indexClipboard READ indexClipboard WRITE setIndexClipboard NOTIFY indexClipboardChanged)
+    Q_PROPERTY(QStringList mailExcludedAccounts READ mailExcludedAccounts WRITE setMailExcludedAccounts NOTIFY mailExcludedAccountsChanged)
     Q_PROPERTY(bool embeddingEnabled READ embeddingEnabled WRITE setEmbeddingEnabled NOTIFY embeddingEnabledChanged)
     Q_PROPERTY(bool inferenceServiceEnabled READ inferenceServiceEnabled WRITE setInferenceServiceEnabled NOTIFY inferenceServiceEnabledChanged)
     Q_PROPERTY(bool inferenceEmbedOffloadEnabled READ inferenceEmbedOffloadEnabled WRITE setInferenceEmbedOffloadEnabled NOTIFY inferenceEmbedOffloadEnabledChanged)
@@ -51,8 +60,14 @@ class SettingsController : public QObject {
     Q_PROPERTY(bool enableFeedbackLogging READ enableFeedbackLogging WRITE setEnableFeedbackLogging NOTIFY enableFeedbackLoggingChanged)
     Q_PROPERTY(bool enableInteractionTracking READ enableInteractionTracking WRITE setEnableInteractionTracking NOTIFY enableInteractionTrackingChanged)
     Q_PROPERTY(bool clipboardSignalEnabled READ clipboardSignalEnabled WRITE setClipboardSignalEnabled NOTIFY clipboardSignalEnabledChanged)
+    Q_PROPERTY(bool recentDocumentsSignalEnabled READ recentDocumentsSignalEnabled WRITE setRecentDocumentsSignalEnabled NOTIFY recentDocumentsSignalEnabledChanged)
     Q_PROPERTY(int feedbackRetentionDays READ feedbackRetentionDays WRITE setFeedbackRetentionDays NOTIFY feedbackRetentionDaysChanged)
+    Q_PROPERTY(int queryHistoryRetentionDays READ queryHistoryRetentionDays WRITE setQueryHistoryRetentionDays NOTIFY queryHistoryRetentionDaysChanged)
     Q_PROPERTY(QStringList sensitivePaths READ sensitivePaths WRITE setSensitivePaths NOTIFY sensitivePathsChanged)
+    Q_PROPERTY(QStringList privacyExclusions READ privacyExclusions NOTIFY privacyExclusionsChanged)
+    Q_PROPERTY(QString secretRedaction READ secretRedaction WRITE setSecretRedaction NOTIFY secretRedactionChanged)
+    Q_PROPERTY(bool auditLogEnabled READ auditLogEnabled WRITE setAuditLogEnabled NOTIFY auditLogEnabledChanged)
+    Q_PROPERTY(QString auditLogDirectory READ auditLogDirectory CONSTANT)
     Q_PROPERTY(QString theme READ theme WRITE setTheme NOTIFY themeChanged)
     Q_PROPERTY(QString language READ language WRITE setLanguage NOTIFY languageChanged)
     Q_PROPERTY(QString platformStatusMessage READ platformStatusMessage NOTIFY platformStatusChanged)
@@ -71,6 +86,17 @@ public:
     QVariantList indexRoots() const;
     bool enablePdf() const;
     bool enableOcr() const;
+    bool indexContacts() const;
+    bool indexCalendar() const;
+    bool indexBrowserHistory() const;
+    bool indexMail() const;
+    QStringList mailExcludedAccounts() const;
+    bool indexNotes() const;
+    bool indexMessages() const;
+    bool indexReminders() const;
+    // App-only: the clipboard history is kept and searched by the app, so
+    // this is not mirrored into index.db.
+    bool indexClipboard() const;
     bool embeddingEnabled() const;
     bool inferenceServiceEnabled() const;
     bool inferenceEmbedOffloadEnabled() const;
@@ -100,8 +126,14 @@ public:
     bool enableFeedbackLogging() const;
     bool enableInteractionTracking() const;
     bool clipboardSignalEnabled() const;
+    bool recentDocumentsSignalEnabled() const;
     int feedbackRetentionDays() const;
+    int queryHistoryRetentionDays() const;
     QStringList sensitivePaths() const;
+    QStringList privacyExclusions() const;
+    QString secretRedaction() const;
+    bool auditLogEnabled() const;
+    QString auditLogDirectory() const;
     QString theme() const;
     QString language() const;
     QString platformStatusMessage() const;
@@ -118,6 +150,15 @@ public:
     void setIndexRoots(const QVariantList& roots);
     void setEnablePdf(bool enabled);
     void setEnableOcr(bool enabled);
+    void setIndexContacts(bool enabled);
+    void setIndexCalendar(bool enabled);
+    void setIndexBrowserHistory(bool enabled);
+    void setIndexMail(bool enabled);
+    void setMailExcludedAccounts(const QStringList& accounts);
+    void setIndexNotes(bool enabled);
+    void setIndexMessages(bool enabled);
+    void setIndexReminders(bool enabled);
+    void setIndexClipboard(bool enabled);
     void setEmbeddingEnabled(bool enabled);
     void setInferenceServiceEnabled(bool enabled);
     void setInferenceEmbedOffloadEnabled(bool enabled);
@@ -147,12 +188,18 @@ public:
     void setEnableFeedbackLogging(bool enabled);
     void setEnableInteractionTracking(bool enabled);
     void setClipboardSignalEnabled(bool enabled);
+    void setRecentDocumentsSignalEnabled(bool enabled);
     void setFeedbackRetentionDays(int days);
+    // 0 turns query history off (and clears it).
+    void setQueryHistoryRetentionDays(int days);
     void setSensitivePaths(const QStringList& paths);
+    void setSecretRedaction(const QString& mode);
+    void setAuditLogEnabled(bool enabled);
     void setTheme(const QString& theme);
     void setLanguage(const QString& language);
 
     Q_INVOKABLE void clearFeedbackData();
+    Q_INVOKABLE int clearAuditLog();
     Q_INVOKABLE void exportData();
     Q_INVOKABLE void pauseIndexing();
     Q_INVOKABLE void resumeIndexing();
@@ -160,6 +207,10 @@ public:
     Q_INVOKABLE void rebuildVectorIndex();
     Q_INVOKABLE void clearExtractionCache();
     Q_INVOKABLE void reindexFolder(const QString& folderPath);
+    // Privacy folders are never indexed; adding one purges what is already
+    // indexed under it.
+    Q_INVOKABLE bool addPrivacyExclusion(const QString& folderPath);
+    Q_INVOKABLE bool removePrivacyExclusion(const QString& folderPath);
     Q_INVOKABLE bool setRuntimeSetting(const QString& key, const QString& value);
     Q_INVOKABLE bool removeRuntimeSetting(const QString& key);
 
@@ -172,6 +223,15 @@ signals:
     void indexRootsChanged();
     void enablePdfChanged();
     void enableOcrChanged();
+    void indexContactsChanged();
+    void indexCalendarChanged();
+    void indexBrowserHistoryChanged();
+    void indexMailChanged();
+    void mailExcludedAccountsChanged();
+    void indexNotesChanged();
+    void indexMessagesChanged();
+    void indexRemindersChanged();
+    void indexClipboardChanged();
     void embeddingEnabledChanged();
     void inferenceServiceEnabledChanged();
     void inferenceEmbedOffloadEnabledChanged();
@@ -201,8 +261,13 @@ signals:
     void enableFeedbackLoggingChanged();
     void enableInteractionTrackingChanged();
     void clipboardSignalEnabledChanged();
+    void recentDocumentsSignalEnabledChanged();
     void feedbackRetentionDaysChanged();
+    void queryHistoryRetentionDaysChanged();
     void sensitivePathsChanged();
+    void privacyExclusionsChanged();
+    void secretRedactionChanged();
+    void auditLogEnabledChanged();
     void themeChanged();
     void languageChanged();
     void platformStatusChanged();
@@ -214,6 +279,8 @@ signals:
     void rebuildVectorIndexRequested();
     void clearExtractionCacheRequested();
     void reindexFolderRequested(const QString& folderPath);
+    void privacyExclusionAdded(const QString& folderPath);
+    void privacyExclusionRemoved(const QString& folderPath);
 
 private:
     void loadSettings();
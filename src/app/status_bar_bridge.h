@@ -1,6 +1,7 @@
 #pragma once
 
 #include <QObject>
+#include <QString>
 
 namespace bs {
 
@@ -14,6 +15,7 @@ public:
 
 signals:
     void showSearchRequested();
+    void showSearchWithQueryRequested(const QString& query);
     void showSettingsRequested();
     void showIndexHealthRequested();
 };
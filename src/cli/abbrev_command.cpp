@@ -0,0 +1,122 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QJsonDocument>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+
+} // namespace
+
+int runAbbrevCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("List, define or remove search abbreviations. A search for a term "
+                       "runs as its expansion, e.g. 'inv 2025' as 'invoice 2025'.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    parser.addPositionalArgument(
+        QStringLiteral("term"),
+        QStringLiteral("The abbreviation, one word. Alone, lists the abbreviations starting with it."),
+        QStringLiteral("[term]"));
+    parser.addPositionalArgument(QStringLiteral("expansion"),
+                                 QStringLiteral("What it stands for; defines or replaces it."),
+                                 QStringLiteral("[expansion...]"));
+    const QCommandLineOption removeOption(QStringLiteral("remove"),
+                                          QStringLiteral("Remove the abbreviation given as term."));
+    const QCommandLineOption clearOption(QStringLiteral("clear"),
+                                         QStringLiteral("Remove every abbreviation."));
+    const QCommandLineOption jsonOption(QStringLiteral("json"),
+                                        QStringLiteral("Print the raw result."));
+    parser.addOptions({removeOption, clearOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot abbrev"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot abbrev: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const QStringList positional = parser.positionalArguments();
+
+    QString method;
+    QJsonObject params;
+    if (parser.isSet(clearOption)) {
+        if (!positional.isEmpty() || parser.isSet(removeOption)) {
+            return usageError(QStringLiteral("--clear takes no term"));
+        }
+        method = QStringLiteral("removeAbbreviation");
+        params[QStringLiteral("all")] = true;
+    } else if (parser.isSet(removeOption)) {
+        if (positional.size() != 1) {
+            return usageError(QStringLiteral("--remove expects exactly one term"));
+        }
+        method = QStringLiteral("removeAbbreviation");
+        params[QStringLiteral("term")] = positional.first();
+    } else if (positional.size() >= 2) {
+        method = QStringLiteral("setAbbreviation");
+        params[QStringLiteral("term")] = positional.first();
+        params[QStringLiteral("expansion")] = positional.mid(1).join(QLatin1Char(' '));
+    } else {
+        method = QStringLiteral("getAbbreviations");
+    }
+
+    // Defining and removing are admin methods: they change every client's
+    // searches.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot abbrev: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot abbrev: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    if (method == QLatin1String("setAbbreviation")) {
+        cliOut() << result.value(QStringLiteral("term")).toString() << " -> "
+                 << result.value(QStringLiteral("expansion")).toString() << Qt::endl;
+        return kCliExitOk;
+    }
+    if (method == QLatin1String("removeAbbreviation")) {
+        cliOut() << "Removed " << result.value(QStringLiteral("removed")).toInt()
+                 << " abbreviations" << Qt::endl;
+        return kCliExitOk;
+    }
+
+    const QJsonObject abbreviations = result.value(QStringLiteral("abbreviations")).toObject();
+    const QString filter = positional.isEmpty() ? QString() : positional.first().toLower();
+    int width = 0;
+    for (const QString& term : abbreviations.keys()) {
+        width = std::max(width, static_cast<int>(term.size()));
+    }
+    for (auto it = abbreviations.constBegin(); it != abbreviations.constEnd(); ++it) {
+        if (!filter.isEmpty() && !it.key().startsWith(filter)) {
+            continue;
+        }
+        cliOut() << it.key().leftJustified(width) << "  " << it.value().toString() << '\n';
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+} // namespace bs
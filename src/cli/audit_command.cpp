@@ -0,0 +1,159 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+constexpr int kDefaultLimit = 50;
+
+QString renderEntry(const QJsonObject& entry)
+{
+    const QDateTime at = QDateTime::fromMSecsSinceEpoch(
+        static_cast<qint64>(entry.value(QStringLiteral("ts")).toDouble() * 1000.0));
+    QString line = QStringLiteral("%1  %2  %3  %4  %5")
+        .arg(at.toString(QStringLiteral("yyyy-MM-dd HH:mm:ss")),
+             entry.value(QStringLiteral("service")).toString().leftJustified(8),
+             entry.value(QStringLiteral("kind")).toString().leftJustified(5),
+             entry.value(QStringLiteral("ok")).toBool() ? QStringLiteral("ok  ")
+                                                        : QStringLiteral("FAIL"),
+             entry.value(QStringLiteral("method")).toString());
+    const QJsonObject details = entry.value(QStringLiteral("details")).toObject();
+    if (!details.isEmpty()) {
+        line += QStringLiteral("  ")
+                + QString::fromUtf8(QJsonDocument(details).toJson(QJsonDocument::Compact));
+    }
+    return line;
+}
+
+} // namespace
+
+int runAuditCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Review or purge the local audit log of queries and admin operations. "
+                       "The log is off unless turned on in Settings > Privacy.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    const QCommandLineOption sinceOption(
+        QStringLiteral("since"),
+        QStringLiteral("Only entries newer than this, e.g. 90m, 24h, 7d."),
+        QStringLiteral("duration"));
+    const QCommandLineOption kindOption(
+        QStringLiteral("kind"), QStringLiteral("Only 'query' or 'admin' entries."),
+        QStringLiteral("kind"));
+    const QCommandLineOption serviceOption(
+        QStringLiteral("service"),
+        QStringLiteral("Only entries from this service (app, indexer, query, ...). Repeatable."),
+        QStringLiteral("name"));
+    const QCommandLineOption limitOption(
+        QStringLiteral("limit"),
+        QStringLiteral("At most this many entries, newest first (default %1).").arg(kDefaultLimit),
+        QStringLiteral("n"), QString::number(kDefaultLimit));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw getAuditLog result."));
+    const QCommandLineOption purgeOption(
+        QStringLiteral("purge"), QStringLiteral("Delete entries instead of listing them."));
+    const QCommandLineOption olderThanOption(
+        QStringLiteral("older-than"),
+        QStringLiteral("With --purge, only delete entries older than this, e.g. 30d."),
+        QStringLiteral("duration"));
+    parser.addOptions({sinceOption, kindOption, serviceOption, limitOption, jsonOption,
+                       purgeOption, olderThanOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot audit"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot audit: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const double now = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+
+    QString method;
+    QJsonObject params;
+    if (parser.isSet(purgeOption)) {
+        method = QStringLiteral("purgeAuditLog");
+        if (parser.isSet(olderThanOption)) {
+            const auto age = parseCliDuration(parser.value(olderThanOption));
+            if (!age.has_value()) {
+                return usageError(QStringLiteral("--older-than expects a duration like 30d"));
+            }
+            params[QStringLiteral("before")] = now - age.value();
+        }
+    } else {
+        if (parser.isSet(olderThanOption)) {
+            return usageError(QStringLiteral("--older-than needs --purge"));
+        }
+        method = QStringLiteral("getAuditLog");
+        if (parser.isSet(sinceOption)) {
+            const auto age = parseCliDuration(parser.value(sinceOption));
+            if (!age.has_value()) {
+                return usageError(QStringLiteral("--since expects a duration like 24h"));
+            }
+            params[QStringLiteral("since")] = now - age.value();
+        }
+        if (parser.isSet(kindOption)) {
+            params[QStringLiteral("kinds")] = QJsonArray({parser.value(kindOption)});
+        }
+        if (parser.isSet(serviceOption)) {
+            params[QStringLiteral("services")] =
+                QJsonArray::fromStringList(parser.values(serviceOption));
+        }
+        bool ok = false;
+        params[QStringLiteral("limit")] = parser.value(limitOption).toInt(&ok);
+        if (!ok) {
+            return usageError(QStringLiteral("--limit must be a number"));
+        }
+    }
+
+    // Both methods are admin-only: the log holds every client's queries.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot audit: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot audit: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    if (parser.isSet(purgeOption)) {
+        cliOut() << "Removed " << result.value(QStringLiteral("removedEntries")).toInteger()
+                 << " audit log entries" << Qt::endl;
+        return kCliExitOk;
+    }
+
+    if (!result.value(QStringLiteral("enabled")).toBool()) {
+        cliErr() << "bspot audit: the audit log is off; turn it on in Settings > Privacy"
+                 << Qt::endl;
+    }
+    const QJsonArray entries = result.value(QStringLiteral("entries")).toArray();
+    // Oldest first on screen, like a log file.
+    for (qsizetype i = entries.size() - 1; i >= 0; --i) {
+        cliOut() << renderEntry(entries.at(i).toObject()) << '\n';
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,278 @@
+#include "cli/bench.h"
+
+#include <QJsonArray>
+
+#include <algorithm>
+#include <cmath>
+#include <iterator>
+#include <random>
+
+namespace bs {
+
+namespace {
+
+// Ordered roughly by how often they are drawn: the pick is skewed toward
+// the front so the corpus has a Zipf-like mix of common and rare terms.
+const char* const kVocabulary[] = {
+    "report",    "project",   "meeting",   "budget",    "review",    "client",
+    "update",    "design",    "release",   "schedule",  "invoice",   "summary",
+    "quarterly", "planning",  "customer",  "contract",  "roadmap",   "feedback",
+    "analysis",  "proposal",  "deadline",  "migration", "database",  "network",
+    "security",  "overview",  "estimate",  "milestone", "incident",  "handbook",
+    "research",  "template",  "forecast",  "quota",     "pipeline",  "latency",
+    "dashboard", "archive",   "onboarding","inventory", "compliance","renewal",
+    "prototype", "workshop",  "benchmark", "retention", "telemetry", "escalation",
+    "warehouse", "procurement","sandbox",  "throughput","manifest",  "gazetteer",
+    "palimpsest","zeitgeist", "quokka",    "xylograph",
+};
+constexpr int kVocabularySize = static_cast<int>(std::size(kVocabulary));
+
+// std::mt19937's output sequence is fixed by the standard; the library
+// distributions are not, so they are avoided to keep corpora identical
+// across platforms and toolchains. Draws are made one per statement:
+// argument evaluation order is unspecified.
+class BenchRandom {
+public:
+    explicit BenchRandom(uint32_t seed) : m_engine(seed) {}
+
+    int below(int bound) { return static_cast<int>(m_engine() % static_cast<uint32_t>(bound)); }
+
+    QString word()
+    {
+        const double u = static_cast<double>(m_engine()) / 4294967296.0;
+        return QString::fromLatin1(kVocabulary[static_cast<int>(u * u * kVocabularySize)]);
+    }
+
+private:
+    std::mt19937 m_engine;
+};
+
+uint32_t fileSeed(uint32_t seed, int index)
+{
+    return seed ^ (static_cast<uint32_t>(index) * 2654435761u);
+}
+
+QString sentence(BenchRandom& random)
+{
+    QStringList words;
+    const int count = 6 + random.below(9);
+    for (int i = 0; i < count; ++i) {
+        words.append(random.word());
+    }
+    QString text = words.join(QLatin1Char(' '));
+    text[0] = text[0].toUpper();
+    return text + QLatin1Char('.');
+}
+
+QString formatBytes(qint64 bytes)
+{
+    if (bytes < 1024 * 1024) {
+        return QStringLiteral("%1 KiB").arg(static_cast<double>(bytes) / 1024.0, 0, 'f', 1);
+    }
+    return QStringLiteral("%1 MiB").arg(static_cast<double>(bytes) / (1024.0 * 1024.0), 0, 'f', 1);
+}
+
+QString formatMs(double ms)
+{
+    return QStringLiteral("%1 ms").arg(ms, 0, 'f', ms < 10.0 ? 2 : 1);
+}
+
+// Swap two inner letters: "budget" -> "bugdet".
+QString typo(const QString& word)
+{
+    QString out = word;
+    const int at = static_cast<int>(out.size()) / 2;
+    std::swap(out[at - 1], out[at]);
+    return out;
+}
+
+} // namespace
+
+double BenchReport::filesPerSecond() const
+{
+    return indexMs > 0 ? static_cast<double>(files) * 1000.0 / static_cast<double>(indexMs) : 0.0;
+}
+
+BenchCorpusFile Bench::corpusFile(int index, uint32_t seed)
+{
+    BenchRandom random(fileSeed(seed, index));
+    const QString dir = QStringLiteral("d%1/").arg(index / 100, 3, 10, QLatin1Char('0'));
+    const QString marker = QStringLiteral("bsbench%1").arg(index);
+
+    BenchCorpusFile file;
+    QString text;
+    switch (index % 4) {
+    case 0: {
+        file.relativePath = dir + QStringLiteral("report_%1.txt").arg(index);
+        const int paragraphs = 2 + random.below(4);
+        for (int p = 0; p < paragraphs; ++p) {
+            const int sentences = 4 + random.below(8);
+            for (int s = 0; s < sentences; ++s) {
+                text += sentence(random) + QLatin1Char(' ');
+            }
+            text += QStringLiteral("\n\n");
+        }
+        text += QStringLiteral("Reference %1.\n").arg(marker);
+        break;
+    }
+    case 1: {
+        file.relativePath = dir + QStringLiteral("notes_%1.md").arg(index);
+        text = QStringLiteral("# %1 notes %2\n\n").arg(random.word(), QString::number(index));
+        const int bullets = 5 + random.below(15);
+        for (int b = 0; b < bullets; ++b) {
+            text += QStringLiteral("- %1\n").arg(sentence(random));
+        }
+        text += QStringLiteral("\nTag: %1\n").arg(marker);
+        break;
+    }
+    case 2: {
+        file.relativePath = dir + QStringLiteral("module_%1.py").arg(index);
+        text = QStringLiteral("\"\"\"%1\"\"\"\n\n").arg(sentence(random));
+        const int functions = 2 + random.below(6);
+        for (int f = 0; f < functions; ++f) {
+            const QString verb = random.word();
+            const QString noun = random.word();
+            const QString left = random.word();
+            const QString right = random.word() + QStringLiteral("_count");
+            const QString comment = sentence(random);
+            text += QStringLiteral("def %1_%2(%3, %4):\n    # %5\n    return %3 + %4\n\n")
+                        .arg(verb, noun, left, right, comment);
+        }
+        text += QStringLiteral("MARKER = \"%1\"\n").arg(marker);
+        break;
+    }
+    default: {
+        file.relativePath = dir + QStringLiteral("engine_%1.cpp").arg(index);
+        text = QStringLiteral("// %1\n#include <vector>\n\n").arg(sentence(random));
+        const int functions = 2 + random.below(6);
+        for (int f = 0; f < functions; ++f) {
+            const QString comment = sentence(random);
+            const QString verb = random.word();
+            const QString noun = random.word();
+            const QString parameter = random.word();
+            const int factor = 1 + random.below(97);
+            text += QStringLiteral("// %1\nint %2_%3(int %4) { return %4 * %5; }\n\n")
+                        .arg(comment, verb, noun, parameter)
+                        .arg(factor);
+        }
+        text += QStringLiteral("static const char* kMarker = \"%1\";\n").arg(marker);
+        break;
+    }
+    }
+    file.contents = text.toUtf8();
+    return file;
+}
+
+QStringList Bench::queryWorkload(int files, uint32_t seed)
+{
+    BenchRandom random(seed);
+    QStringList queries;
+    for (int i = 0; i < 8; ++i) {
+        queries.append(QString::fromLatin1(kVocabulary[i]));
+    }
+    for (int i = kVocabularySize - 4; i < kVocabularySize; ++i) {
+        queries.append(QString::fromLatin1(kVocabulary[i]));
+    }
+    for (int i = 0; i < 8; ++i) {
+        const QString first = random.word();
+        queries.append(first + QLatin1Char(' ') + random.word());
+    }
+    const int count = std::max(1, files);
+    for (int i = 0; i < 6; ++i) {
+        // Report files sit at multiples of four.
+        const int index = random.below(std::max(1, (count + 3) / 4)) * 4;
+        queries.append(QStringLiteral("report_%1").arg(index));
+    }
+    for (int i = 0; i < 6; ++i) {
+        queries.append(QStringLiteral("bsbench%1").arg(random.below(count)));
+    }
+    queries.append(typo(QString::fromLatin1(kVocabulary[3])));
+    queries.append(typo(QString::fromLatin1(kVocabulary[17])));
+    queries.append(QStringLiteral("zzqx nonexistent"));
+    return queries;
+}
+
+BenchLatency Bench::summarize(std::vector<double> samplesMs)
+{
+    BenchLatency latency;
+    latency.count = static_cast<int>(samplesMs.size());
+    if (samplesMs.empty()) {
+        return latency;
+    }
+    std::sort(samplesMs.begin(), samplesMs.end());
+    const auto rank = [&samplesMs](double percent) {
+        const size_t n = samplesMs.size();
+        const size_t r = static_cast<size_t>(std::ceil(percent / 100.0 * static_cast<double>(n)));
+        return samplesMs[std::clamp<size_t>(r, 1, n) - 1];
+    };
+    latency.p50Ms = rank(50.0);
+    latency.p95Ms = rank(95.0);
+    latency.p99Ms = rank(99.0);
+    latency.maxMs = samplesMs.back();
+    return latency;
+}
+
+QString Bench::render(const BenchReport& report)
+{
+    QString out = QStringLiteral("BetterSpotlight benchmark (bspot %1)\n\n").arg(report.version);
+    out += QStringLiteral("Corpus:     %1 files, %2\n")
+               .arg(report.files)
+               .arg(formatBytes(report.corpusBytes));
+    out += QStringLiteral("Indexing:   %1 s, %2 files/s (%3 indexed, %4 failed)\n")
+               .arg(static_cast<double>(report.indexMs) / 1000.0, 0, 'f', 1)
+               .arg(report.filesPerSecond(), 0, 'f', 1)
+               .arg(report.indexedItems)
+               .arg(report.failedItems);
+    out += QStringLiteral("Queries:    %1 over %2 round(s), %3 with no results\n")
+               .arg(report.latency.count)
+               .arg(report.rounds)
+               .arg(report.emptyQueries);
+    out += QStringLiteral("Latency:    p50 %1, p95 %2, p99 %3, max %4\n")
+               .arg(formatMs(report.latency.p50Ms), formatMs(report.latency.p95Ms),
+                    formatMs(report.latency.p99Ms), formatMs(report.latency.maxMs));
+    out += QStringLiteral("Index size: %1").arg(formatBytes(report.indexBytes));
+    if (report.corpusBytes > 0) {
+        out += QStringLiteral(" (%1x corpus)")
+                   .arg(static_cast<double>(report.indexBytes)
+                            / static_cast<double>(report.corpusBytes),
+                        0, 'f', 1);
+    }
+    out += QLatin1Char('\n');
+    return out;
+}
+
+QJsonObject Bench::toJson(const BenchReport& report)
+{
+    QJsonObject corpus;
+    corpus[QStringLiteral("files")] = report.files;
+    corpus[QStringLiteral("bytes")] = report.corpusBytes;
+
+    QJsonObject indexing;
+    indexing[QStringLiteral("elapsedMs")] = report.indexMs;
+    indexing[QStringLiteral("indexed")] = report.indexedItems;
+    indexing[QStringLiteral("failed")] = report.failedItems;
+    indexing[QStringLiteral("filesPerSec")] = std::round(report.filesPerSecond() * 10.0) / 10.0;
+
+    const auto roundMs = [](double ms) { return std::round(ms * 100.0) / 100.0; };
+    QJsonObject latency;
+    latency[QStringLiteral("p50Ms")] = roundMs(report.latency.p50Ms);
+    latency[QStringLiteral("p95Ms")] = roundMs(report.latency.p95Ms);
+    latency[QStringLiteral("p99Ms")] = roundMs(report.latency.p99Ms);
+    latency[QStringLiteral("maxMs")] = roundMs(report.latency.maxMs);
+
+    QJsonObject queries;
+    queries[QStringLiteral("count")] = report.latency.count;
+    queries[QStringLiteral("rounds")] = report.rounds;
+    queries[QStringLiteral("empty")] = report.emptyQueries;
+    queries[QStringLiteral("latency")] = latency;
+
+    QJsonObject json;
+    json[QStringLiteral("version")] = report.version;
+    json[QStringLiteral("corpus")] = corpus;
+    json[QStringLiteral("indexing")] = indexing;
+    json[QStringLiteral("queries")] = queries;
+    json[QStringLiteral("indexBytes")] = report.indexBytes;
+    return json;
+}
+
+} // namespace bs
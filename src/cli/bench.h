@@ -0,0 +1,66 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <vector>
+
+namespace bs {
+
+struct BenchCorpusFile {
+    QString relativePath;
+    QByteArray contents;
+};
+
+struct BenchLatency {
+    int count = 0;
+    double p50Ms = 0.0;
+    double p95Ms = 0.0;
+    double p99Ms = 0.0;
+    double maxMs = 0.0;
+};
+
+struct BenchReport {
+    QString version;
+    int files = 0;
+    qint64 corpusBytes = 0;
+    qint64 indexMs = 0;
+    qint64 indexedItems = 0;
+    qint64 failedItems = 0;
+    int rounds = 0;
+    BenchLatency latency;
+    int emptyQueries = 0;       // workload queries that returned nothing
+    qint64 indexBytes = 0;      // index.db plus WAL/SHM and vector files
+
+    double filesPerSecond() const;
+};
+
+// Bench — corpus, workload and reporting behind `bspot bench`.
+//
+// Everything here is deterministic for a given file count and seed so runs
+// on different releases index and query the same data. Spawning the
+// services and timing them lives in bench_command.cpp.
+class Bench {
+public:
+    static constexpr uint32_t kDefaultSeed = 20260101;
+
+    // File `index` of the corpus: prose (.txt, .md) and code (.py, .cpp)
+    // in 100-file directories, each carrying a unique "bsbench<index>"
+    // marker so exact lookups have exactly one hit.
+    static BenchCorpusFile corpusFile(int index, uint32_t seed = kDefaultSeed);
+
+    // Query mix for a corpus of `files` files: common and rare terms,
+    // two-term queries, file-name lookups, markers, a typo and a miss.
+    static QStringList queryWorkload(int files, uint32_t seed = kDefaultSeed);
+
+    // Nearest-rank percentiles; `samplesMs` need not be sorted.
+    static BenchLatency summarize(std::vector<double> samplesMs);
+
+    static QString render(const BenchReport& report);
+    static QJsonObject toJson(const BenchReport& report);
+};
+
+} // namespace bs
@@ -0,0 +1,363 @@
+#include "cli/bench.h"
+#include "cli/cli_common.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QCoreApplication>
+#include <QDir>
+#include <QDirIterator>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QProcess>
+#include <QTemporaryDir>
+#include <QThread>
+
+#include <memory>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultFiles = 2000;
+constexpr int kDefaultRounds = 5;
+constexpr int kDefaultIndexTimeoutSecs = 600;
+constexpr int kStartupTimeoutMs = 20000;
+constexpr int kRequestTimeoutMs = 30000;
+constexpr int kProgressPollMs = 100;
+
+// Release layout: every binary in Contents/Helpers; build tree:
+// build/src/cli/bspot next to build/src/services/<name>/.
+QString locateServiceBinary(const QString& name, const QString& binDir)
+{
+    const QString binary = QStringLiteral("betterspotlight-") + name;
+    const QString cliDir = QCoreApplication::applicationDirPath();
+    QStringList candidates;
+    if (!binDir.isEmpty()) {
+        candidates.append(QDir(binDir).filePath(binary));
+    }
+    candidates.append(QDir(cliDir).filePath(binary));
+    candidates.append(cliDir + QStringLiteral("/../services/") + name + QLatin1Char('/') + binary);
+    for (const QString& candidate : candidates) {
+        const QFileInfo info(candidate);
+        if (info.exists() && info.isExecutable()) {
+            return info.canonicalFilePath();
+        }
+    }
+    return {};
+}
+
+// A private indexer/query pair: own data dir and sockets, no admin token,
+// so it never touches the user's index or running services.
+class BenchServices {
+public:
+    ~BenchServices() { stop(); }
+
+    bool start(const QString& name, const QString& binary, const QString& logDir,
+               QString* error)
+    {
+        auto process = std::make_unique<QProcess>();
+        process->setProgram(binary);
+        process->setProcessEnvironment(QProcessEnvironment::systemEnvironment());
+        process->setProcessChannelMode(QProcess::MergedChannels);
+        process->setStandardOutputFile(QDir(logDir).filePath(name + QStringLiteral(".log")));
+        process->start();
+        if (!process->waitForStarted(5000)) {
+            *error = QStringLiteral("could not start %1").arg(binary);
+            return false;
+        }
+
+        auto client = std::make_unique<SocketClient>();
+        QElapsedTimer waited;
+        waited.start();
+        while (!client->connectToServer(ServiceBase::socketPath(name), 200)) {
+            if (waited.elapsed() > kStartupTimeoutMs
+                || process->state() == QProcess::NotRunning) {
+                *error = QStringLiteral("%1 service did not come up (see %2)")
+                             .arg(name, QDir(logDir).filePath(name + QStringLiteral(".log")));
+                return false;
+            }
+            QThread::msleep(50);
+        }
+        m_services.push_back({name, std::move(process), std::move(client)});
+        return true;
+    }
+
+    SocketClient* client(const QString& name)
+    {
+        for (const Service& service : m_services) {
+            if (service.name == name) {
+                return service.client.get();
+            }
+        }
+        return nullptr;
+    }
+
+    // Services checkpoint the WAL on shutdown, so measure sizes after this.
+    void stop()
+    {
+        for (Service& service : m_services) {
+            if (service.client->isConnected()) {
+                service.client->sendRequest(QStringLiteral("shutdown"), {}, 2000);
+            }
+        }
+        for (Service& service : m_services) {
+            if (!service.process->waitForFinished(10000)) {
+                service.process->terminate();
+                if (!service.process->waitForFinished(3000)) {
+                    service.process->kill();
+                    service.process->waitForFinished(1000);
+                }
+            }
+        }
+        m_services.clear();
+    }
+
+private:
+    struct Service {
+        QString name;
+        std::unique_ptr<QProcess> process;
+        std::unique_ptr<SocketClient> client;
+    };
+    std::vector<Service> m_services;
+};
+
+qint64 directorySize(const QString& path)
+{
+    qint64 total = 0;
+    QDirIterator it(path, QDir::Files | QDir::Hidden, QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        it.next();
+        total += it.fileInfo().size();
+    }
+    return total;
+}
+
+std::optional<QJsonObject> requestResult(SocketClient* client, const QString& method,
+                                         const QJsonObject& params, QString* error)
+{
+    const auto response = client->sendRequest(method, params, kRequestTimeoutMs);
+    if (!response.has_value()) {
+        *error = QStringLiteral("no answer to '%1'").arg(method);
+        return std::nullopt;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        *error = QStringLiteral("'%1' failed: %2")
+                     .arg(method, response->value(QStringLiteral("error")).toObject()
+                                      .value(QStringLiteral("message")).toString());
+        return std::nullopt;
+    }
+    return response->value(QStringLiteral("result")).toObject();
+}
+
+} // namespace
+
+int runBenchCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Index a generated corpus with private indexer and query services, "
+                       "replay a query workload, and report indexing throughput, query "
+                       "latency and index size. Does not touch your index.\n"
+                       "Exit status: 0 done, 1 benchmark failed, 2 usage error, "
+                       "3 service binaries not found."));
+    const QCommandLineOption filesOption(
+        QStringLiteral("files"),
+        QStringLiteral("Corpus size in files (10-200000, default %1).").arg(kDefaultFiles),
+        QStringLiteral("n"), QString::number(kDefaultFiles));
+    const QCommandLineOption roundsOption(
+        QStringLiteral("rounds"),
+        QStringLiteral("Times to replay the query workload (1-100, default %1).")
+            .arg(kDefaultRounds),
+        QStringLiteral("n"), QString::number(kDefaultRounds));
+    const QCommandLineOption seedOption(
+        QStringLiteral("seed"),
+        QStringLiteral("Corpus and workload seed (default %1).").arg(Bench::kDefaultSeed),
+        QStringLiteral("n"), QString::number(Bench::kDefaultSeed));
+    const QCommandLineOption indexTimeoutOption(
+        QStringLiteral("index-timeout"),
+        QStringLiteral("Give up if indexing takes longer, in seconds (default %1).")
+            .arg(kDefaultIndexTimeoutSecs),
+        QStringLiteral("secs"), QString::number(kDefaultIndexTimeoutSecs));
+    const QCommandLineOption binDirOption(
+        QStringLiteral("bin-dir"),
+        QStringLiteral("Directory holding betterspotlight-indexer and betterspotlight-query."),
+        QStringLiteral("dir"));
+    const QCommandLineOption keepOption(
+        QStringLiteral("keep"),
+        QStringLiteral("Keep the corpus, index and service logs, and print where."));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the report as a JSON document."));
+    parser.addOptions({filesOption, roundsOption, seedOption, indexTimeoutOption, binDirOption,
+                       keepOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot bench"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot bench: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const auto failure = [](const QString& message) {
+        cliErr() << "bspot bench: " << message << Qt::endl;
+        return kCliExitFailure;
+    };
+
+    bool ok = false;
+    const int files = parser.value(filesOption).toInt(&ok);
+    if (!ok || files < 10 || files > 200000) {
+        return usageError(QStringLiteral("--files must be between 10 and 200000"));
+    }
+    const int rounds = parser.value(roundsOption).toInt(&ok);
+    if (!ok || rounds < 1 || rounds > 100) {
+        return usageError(QStringLiteral("--rounds must be between 1 and 100"));
+    }
+    const uint32_t seed = parser.value(seedOption).toUInt(&ok);
+    if (!ok) {
+        return usageError(QStringLiteral("--seed must be a non-negative integer"));
+    }
+    const int indexTimeoutSecs = parser.value(indexTimeoutOption).toInt(&ok);
+    if (!ok || indexTimeoutSecs < 1) {
+        return usageError(QStringLiteral("--index-timeout must be a positive number of seconds"));
+    }
+
+    const QString binDir = parser.value(binDirOption);
+    const QString indexerBinary = locateServiceBinary(QStringLiteral("indexer"), binDir);
+    const QString queryBinary = locateServiceBinary(QStringLiteral("query"), binDir);
+    if (indexerBinary.isEmpty() || queryBinary.isEmpty()) {
+        cliErr() << "bspot bench: betterspotlight-indexer/-query not found next to bspot; "
+                    "pass --bin-dir"
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    QTemporaryDir workDir(QDir::tempPath() + QStringLiteral("/bspot-bench-XXXXXX"));
+    if (!workDir.isValid()) {
+        return failure(QStringLiteral("could not create a temporary directory"));
+    }
+    workDir.setAutoRemove(!parser.isSet(keepOption));
+    const QDir work(workDir.path());
+    const QString corpusDir = work.filePath(QStringLiteral("corpus"));
+    const QString dataDir = work.filePath(QStringLiteral("data"));
+    const QString runDir = work.filePath(QStringLiteral("run"));
+    const QString logDir = work.filePath(QStringLiteral("logs"));
+    for (const QString& dir : {corpusDir, dataDir, runDir, logDir}) {
+        if (!QDir().mkpath(dir)) {
+            return failure(QStringLiteral("could not create %1").arg(dir));
+        }
+    }
+
+    // Children inherit this; our own socketPath() lookups follow it too.
+    qputenv("BETTERSPOTLIGHT_DATA_DIR", dataDir.toUtf8());
+    qputenv("BETTERSPOTLIGHT_RUNTIME_DIR", runDir.toUtf8());
+    for (const char* name : {"BETTERSPOTLIGHT_SOCKET_DIR", "BETTERSPOTLIGHT_PID_DIR",
+                             "BETTERSPOTLIGHT_ADMIN_TOKEN", "BETTERSPOTLIGHT_HTTP_PORT"}) {
+        qunsetenv(name);
+    }
+
+    BenchReport report;
+    report.version = QCoreApplication::applicationVersion();
+    report.files = files;
+    report.rounds = rounds;
+
+    cliErr() << "Generating " << files << " files..." << Qt::endl;
+    for (int i = 0; i < files; ++i) {
+        const BenchCorpusFile corpusFile = Bench::corpusFile(i, seed);
+        const QString path = QDir(corpusDir).filePath(corpusFile.relativePath);
+        QDir().mkpath(QFileInfo(path).absolutePath());
+        QFile out(path);
+        if (!out.open(QIODevice::WriteOnly) || out.write(corpusFile.contents) < 0) {
+            return failure(QStringLiteral("could not write %1").arg(path));
+        }
+        report.corpusBytes += corpusFile.contents.size();
+    }
+
+    BenchServices services;
+    QString error;
+    if (!services.start(QStringLiteral("indexer"), indexerBinary, logDir, &error)
+        || !services.start(QStringLiteral("query"), queryBinary, logDir, &error)) {
+        return failure(error);
+    }
+    SocketClient* indexer = services.client(QStringLiteral("indexer"));
+    SocketClient* query = services.client(QStringLiteral("query"));
+
+    cliErr() << "Indexing..." << Qt::endl;
+    QElapsedTimer indexTimer;
+    indexTimer.start();
+    if (!requestResult(indexer, QStringLiteral("startIndexing"),
+                       QJsonObject{{QStringLiteral("roots"), QJsonArray{corpusDir}}}, &error)) {
+        return failure(error);
+    }
+    while (true) {
+        const auto progress =
+            requestResult(indexer, QStringLiteral("getIndexingProgress"), {}, &error);
+        if (!progress.has_value()) {
+            return failure(error);
+        }
+        if (progress->value(QStringLiteral("state")).toString() == QLatin1String("idle")) {
+            report.indexMs = indexTimer.elapsed();
+            report.indexedItems = progress->value(QStringLiteral("indexedItems")).toInteger();
+            report.failedItems = progress->value(QStringLiteral("failed")).toInteger();
+            break;
+        }
+        if (indexTimer.elapsed() > static_cast<qint64>(indexTimeoutSecs) * 1000) {
+            return failure(QStringLiteral("indexing did not finish within %1 s")
+                               .arg(indexTimeoutSecs));
+        }
+        QThread::msleep(kProgressPollMs);
+    }
+
+    // One unrecorded pass loads models and warms SQLite's page cache, so
+    // the numbers describe steady-state queries rather than startup.
+    const QStringList workload = Bench::queryWorkload(files, seed);
+    cliErr() << "Replaying " << workload.size() << " queries x " << rounds << "..." << Qt::endl;
+    std::vector<double> samplesMs;
+    samplesMs.reserve(static_cast<size_t>(workload.size() * rounds));
+    for (int round = 0; round <= rounds; ++round) {
+        for (const QString& text : workload) {
+            QJsonObject params;
+            params[QStringLiteral("query")] = text;
+            params[QStringLiteral("limit")] = 20;
+            params[QStringLiteral("noCache")] = true;
+            QElapsedTimer queryTimer;
+            queryTimer.start();
+            const auto result = requestResult(query, QStringLiteral("search"), params, &error);
+            const double elapsedMs = static_cast<double>(queryTimer.nsecsElapsed()) / 1.0e6;
+            if (!result.has_value()) {
+                return failure(error);
+            }
+            if (round == 0) {
+                continue;
+            }
+            samplesMs.push_back(elapsedMs);
+            if (result->value(QStringLiteral("results")).toArray().isEmpty()) {
+                ++report.emptyQueries;
+            }
+        }
+    }
+    report.latency = Bench::summarize(std::move(samplesMs));
+
+    services.stop();
+    report.indexBytes = directorySize(dataDir);
+
+    if (parser.isSet(jsonOption)) {
+        QJsonObject json = Bench::toJson(report);
+        if (parser.isSet(keepOption)) {
+            json[QStringLiteral("workDir")] = workDir.path();
+        }
+        cliOut() << QJsonDocument(json).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else {
+        cliOut() << Bench::render(report) << Qt::flush;
+        if (parser.isSet(keepOption)) {
+            cliOut() << "\nKept corpus, index and logs in " << workDir.path() << Qt::endl;
+        }
+    }
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,76 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QRegularExpression>
+
+#include <cstdio>
+
+namespace bs {
+
+QTextStream& cliOut()
+{
+    static QTextStream stream(stdout);
+    return stream;
+}
+
+QTextStream& cliErr()
+{
+    static QTextStream stream(stderr);
+    return stream;
+}
+
+std::optional<double> parseCliDuration(const QString& text)
+{
+    static const QRegularExpression kPattern(QStringLiteral("^(\\d+)([smhd])$"));
+    const QRegularExpressionMatch match = kPattern.match(text.trimmed().toLower());
+    if (!match.hasMatch()) {
+        return std::nullopt;
+    }
+    const double amount = match.captured(1).toDouble();
+    switch (match.captured(2).at(0).toLatin1()) {
+    case 's': return amount;
+    case 'm': return amount * 60.0;
+    case 'h': return amount * 3600.0;
+    default:  return amount * 86400.0;
+    }
+}
+
+std::optional<int> parseCliArguments(QCommandLineParser& parser,
+                                     const QString& commandName,
+                                     const QStringList& args)
+{
+    const QCommandLineOption helpOption = parser.addHelpOption();
+    if (!parser.parse(QStringList{commandName} + args)) {
+        cliErr() << commandName << ": " << parser.errorText() << Qt::endl;
+        return kCliExitUsage;
+    }
+    if (parser.isSet(helpOption)) {
+        cliOut() << parser.helpText() << Qt::flush;
+        return kCliExitOk;
+    }
+    return std::nullopt;
+}
+
+std::optional<QJsonObject> callService(const QString& serviceName,
+                                       const QString& method,
+                                       const QJsonObject& params,
+                                       int timeoutMs,
+                                       QString* error)
+{
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(serviceName), timeoutMs)) {
+        if (error) {
+            *error = QStringLiteral("%1 service is not running").arg(serviceName);
+        }
+        return std::nullopt;
+    }
+    auto response = client.sendRequest(method, params, timeoutMs);
+    if (!response.has_value() && error) {
+        *error = QStringLiteral("%1 service did not answer '%2'").arg(serviceName, method);
+    }
+    return response;
+}
+
+} // namespace bs
@@ -0,0 +1,62 @@
+#pragma once
+
+#include <QCommandLineParser>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+#include <QTextStream>
+
+#include <optional>
+
+namespace bs {
+
+// Shared plumbing for `bspot` subcommands.
+
+constexpr int kCliExitOk = 0;
+constexpr int kCliExitFailure = 1;
+constexpr int kCliExitUsage = 2;
+constexpr int kCliExitUnavailable = 3;  // service not running or returned an error
+
+QTextStream& cliOut();
+QTextStream& cliErr();
+
+// Parse subcommand arguments. `commandName` becomes argv[0] in help text.
+// Returns an exit code when parsing failed or --help was handled.
+std::optional<int> parseCliArguments(QCommandLineParser& parser,
+                                     const QString& commandName,
+                                     const QStringList& args);
+
+// "90m", "24h", "7d" -> seconds. Nullopt when unreadable.
+std::optional<double> parseCliDuration(const QString& text);
+
+// Send one request to a running service. Returns the IPC envelope (response
+// or error), or nullopt with *error set when the service is unreachable.
+std::optional<QJsonObject> callService(const QString& serviceName,
+                                       const QString& method,
+                                       const QJsonObject& params,
+                                       int timeoutMs,
+                                       QString* error);
+
+// Subcommand entry points. `args` excludes the subcommand name itself.
+int runServiceCommand(const QStringList& args);
+int runMcpCommand(const QStringList& args);
+int runSearchCommand(const QStringList& args);
+int runExportCommand(const QStringList& args);
+int runImportCommand(const QStringList& args);
+int runStatusCommand(const QStringList& args);
+int runStatsCommand(const QStringList& args);
+int runReindexCommand(const QStringList& args);
+int runPurgeCommand(const QStringList& args);
+int runAuditCommand(const QStringList& args);
+int runHistoryCommand(const QStringList& args);
+int runAbbrevCommand(const QStringList& args);
+int runScopeCommand(const QStringList& args);
+int runLogCommand(const QStringList& args);
+int runDebugCommand(const QStringList& args);
+int runTokenCommand(const QStringList& args);
+int runDoctorCommand(const QStringList& args);
+int runTuiCommand(const QStringList& args);
+int runCompleteCommand(const QStringList& args);
+int runBenchCommand(const QStringList& args);
+
+} // namespace bs
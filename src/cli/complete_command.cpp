@@ -0,0 +1,170 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDir>
+#include <QJsonArray>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 1000;
+constexpr int kDefaultTimeoutMs = 5000;
+constexpr int kPageSize = 1000;
+
+// Maps what the user typed to an indexed (absolute) prefix and back, so
+// completions come out in the form the shell handed us: "~/..." stays
+// "~/...", "./src" and "../x" stay relative to the working directory.
+struct PrefixForm {
+    QString query;              // sent to completePaths
+    bool underHome = false;     // print as "~/..."
+    bool relativeToCwd = false; // print relative to the working directory
+    bool dotSlash = false;      // ... with a leading "./"
+
+    static PrefixForm fromArgument(const QString& argument)
+    {
+        PrefixForm form;
+        QString absolute;
+        const bool home = argument == QLatin1String("~") || argument.startsWith(QLatin1String("~/"));
+        const bool relative = argument == QLatin1String(".") || argument == QLatin1String("..")
+            || argument.startsWith(QLatin1String("./")) || argument.startsWith(QLatin1String("../"));
+        if (home) {
+            absolute = QDir::homePath() + argument.mid(1);
+            form.underHome = true;
+        } else if (relative) {
+            absolute = QDir::current().absoluteFilePath(argument);
+            form.relativeToCwd = true;
+            form.dotSlash = argument == QLatin1String(".") || argument.startsWith(QLatin1String("./"));
+        } else {
+            form.query = argument;
+            return form;
+        }
+
+        // cleanPath drops the trailing slash that separates "docs/" (inside
+        // the folder) from "docs" (also docs-old, docs.md, ...).
+        form.query = QDir::cleanPath(absolute);
+        if ((argument.endsWith(QLatin1Char('/')) || argument.endsWith(QLatin1String("/.")))
+            && !form.query.endsWith(QLatin1Char('/'))) {
+            form.query += QLatin1Char('/');
+        }
+        return form;
+    }
+
+    QString display(const QString& path) const
+    {
+        if (relativeToCwd) {
+            const QString relative = QDir::current().relativeFilePath(path);
+            return dotSlash && !relative.startsWith(QLatin1String(".."))
+                ? QStringLiteral("./") + relative
+                : relative;
+        }
+        const QString home = QDir::homePath();
+        if (underHome && path.startsWith(home + QLatin1Char('/'))) {
+            return QLatin1Char('~') + path.mid(home.size());
+        }
+        return path;
+    }
+};
+
+} // namespace
+
+int runCompleteCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Stream indexed paths matching a prefix, most-opened first, for shell "
+                       "completion and fzf. A prefix starting with '/', '~' or '.' matches the "
+                       "path; one containing '/' matches a path fragment; anything else "
+                       "matches file names. An empty prefix lists the whole index.\n"
+                       "Exit status: 0 candidates printed, 1 no candidates, 2 usage error, "
+                       "3 service unavailable or failed."));
+    const QCommandLineOption pathsOption(
+        QStringLiteral("paths"), QStringLiteral("Complete paths starting with <prefix>."),
+        QStringLiteral("prefix"));
+    const QCommandLineOption limitOption(
+        {QStringLiteral("n"), QStringLiteral("limit")},
+        QStringLiteral("Stop after this many candidates (default: all)."), QStringLiteral("n"),
+        QStringLiteral("0"));
+    const QCommandLineOption print0Option(
+        {QStringLiteral("0"), QStringLiteral("print0")},
+        QStringLiteral("NUL-terminate candidates (for fzf --read0 or xargs -0)."));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Per-page timeout in milliseconds (default %1).").arg(kDefaultTimeoutMs),
+        QStringLiteral("ms"), QString::number(kDefaultTimeoutMs));
+    parser.addOptions({pathsOption, limitOption, print0Option, timeoutOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot complete"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot complete: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+
+    if (!parser.isSet(pathsOption)) {
+        return usageError(QStringLiteral("missing --paths <prefix>"));
+    }
+    bool ok = false;
+    const int limit = parser.value(limitOption).toInt(&ok);
+    if (!ok || limit < 0) {
+        return usageError(QStringLiteral("--limit must be a non-negative number"));
+    }
+    const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutMs < 1) {
+        return usageError(QStringLiteral("--timeout must be a positive number of milliseconds"));
+    }
+
+    const PrefixForm form = PrefixForm::fromArgument(parser.value(pathsOption));
+    const QChar terminator = parser.isSet(print0Option) ? QChar(0) : QChar(QLatin1Char('\n'));
+
+    // One connection for every page; a completion feed can be long.
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("query")),
+                                kConnectTimeoutMs)) {
+        cliErr() << "bspot complete: query service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    int printed = 0;
+    QJsonValue cursor(QJsonValue::Null);
+    do {
+        QJsonObject params;
+        params[QStringLiteral("prefix")] = form.query;
+        params[QStringLiteral("limit")] =
+            limit > 0 ? std::min(kPageSize, limit - printed) : kPageSize;
+        params[QStringLiteral("cursor")] = cursor;
+
+        const auto response =
+            client.sendRequest(QStringLiteral("completePaths"), params, timeoutMs);
+        if (!response.has_value()) {
+            cliErr() << "bspot complete: query service did not answer" << Qt::endl;
+            return kCliExitUnavailable;
+        }
+        if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            cliErr() << "bspot complete: "
+                     << response->value(QStringLiteral("error")).toObject()
+                            .value(QStringLiteral("message")).toString()
+                     << Qt::endl;
+            return kCliExitUnavailable;
+        }
+
+        const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+        for (const QJsonValue& value : result.value(QStringLiteral("candidates")).toArray()) {
+            cliOut() << form.display(value.toObject().value(QStringLiteral("path")).toString())
+                     << terminator;
+            ++printed;
+        }
+        // Flush per page so fzf starts filtering before the feed ends.
+        cliOut() << Qt::flush;
+        cursor = result.value(QStringLiteral("nextCursor"));
+    } while (cursor.isObject() && (limit == 0 || printed < limit));
+
+    return printed > 0 ? kCliExitOk : kCliExitFailure;
+}
+
+} // namespace bs
@@ -0,0 +1,460 @@
+#include "cli/cli_common.h"
+#include "cli/index_diff.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+#include "core/shared/crash_report.h"
+#include "core/shared/logging.h"
+
+#include <QCoreApplication>
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QProcess>
+#include <QStandardPaths>
+#include <QTemporaryDir>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+constexpr int kDefaultLimit = 20;
+constexpr int kDefaultCrashReports = 5;
+constexpr int kStatusTimeoutMs = 2000;
+constexpr qint64 kLogTailBytes = 1024 * 1024;
+constexpr int kDefaultDiffLimit = 50;
+
+int callQuery(const QString& command, const QString& method, const QJsonObject& params,
+              QJsonObject* result)
+{
+    // Slow query entries carry query text, so both methods are admin-only.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << command << ": " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << command << ": "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    *result = response->value(QStringLiteral("result")).toObject();
+    return kCliExitOk;
+}
+
+// Three lines per search: when, how long and the query; time per phase;
+// what the plan decided.
+QString renderSlowQuery(const QJsonObject& entry)
+{
+    const QDateTime at = QDateTime::fromMSecsSinceEpoch(
+        static_cast<qint64>(entry.value(QStringLiteral("timestamp")).toDouble() * 1000.0));
+    QString out = QStringLiteral("%1  %2 ms  \"%3\"\n")
+        .arg(at.toString(QStringLiteral("yyyy-MM-dd HH:mm:ss")))
+        .arg(entry.value(QStringLiteral("elapsedMs")).toDouble(), 0, 'f', 0)
+        .arg(entry.value(QStringLiteral("query")).toString());
+
+    QStringList phases;
+    for (const QJsonValue& value : entry.value(QStringLiteral("phases")).toArray()) {
+        const QJsonObject phase = value.toObject();
+        phases.append(QStringLiteral("%1 %2")
+                          .arg(phase.value(QStringLiteral("phase")).toString())
+                          .arg(phase.value(QStringLiteral("ms")).toDouble(), 0, 'f', 1));
+    }
+    out += QStringLiteral("  phases: ") + phases.join(QStringLiteral(", ")) + QLatin1Char('\n');
+
+    const QJsonObject plan = entry.value(QStringLiteral("plan")).toObject();
+    out += QStringLiteral("  plan:   %1 mode, %2, lexical %3 strict / %4 relaxed, "
+                          "semantic %5, rerank %6, %7 of %8 matches")
+               .arg(plan.value(QStringLiteral("queryMode")).toString(),
+                    plan.value(QStringLiteral("queryClass")).toString())
+               .arg(plan.value(QStringLiteral("lexicalStrictHits")).toInt())
+               .arg(plan.value(QStringLiteral("lexicalRelaxedHits")).toInt())
+               .arg(plan.value(QStringLiteral("semanticCandidates")).toInt())
+               .arg(plan.value(QStringLiteral("rerankDepth")).toInt())
+               .arg(plan.value(QStringLiteral("results")).toInt())
+               .arg(plan.value(QStringLiteral("totalMatches")).toInt());
+    if (plan.value(QStringLiteral("deadlineExceeded")).toBool()) {
+        out += QStringLiteral(", deadline exceeded");
+    }
+    return out + QLatin1Char('\n');
+}
+
+int debugSlowLog(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Show searches that took longer than the slow query threshold "
+                       "(setting slowQueryThresholdMs, default 250; 0 turns it off), with "
+                       "their per-phase timings and plan. Kept in memory by the query "
+                       "service, so the list starts empty after a restart; private searches "
+                       "are never kept.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    const QCommandLineOption limitOption(
+        QStringLiteral("limit"),
+        QStringLiteral("At most this many searches, newest first (default %1).")
+            .arg(kDefaultLimit),
+        QStringLiteral("n"), QString::number(kDefaultLimit));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw getSlowQueries result."));
+    const QCommandLineOption clearOption(
+        QStringLiteral("clear"), QStringLiteral("Empty the log instead of listing it."));
+    parser.addOptions({limitOption, jsonOption, clearOption});
+    if (const auto exitCode =
+            parseCliArguments(parser, QStringLiteral("bspot debug slowlog"), args)) {
+        return exitCode.value();
+    }
+
+    const QString command = QStringLiteral("bspot debug slowlog");
+    QJsonObject result;
+    if (parser.isSet(clearOption)) {
+        if (const int exitCode = callQuery(command, QStringLiteral("clearSlowQueries"), {},
+                                           &result);
+            exitCode != kCliExitOk) {
+            return exitCode;
+        }
+        cliOut() << "Removed " << result.value(QStringLiteral("removedEntries")).toInteger()
+                 << " slow queries" << Qt::endl;
+        return kCliExitOk;
+    }
+
+    bool ok = false;
+    const int limit = parser.value(limitOption).toInt(&ok);
+    if (!ok || limit < 1) {
+        cliErr() << command << ": --limit must be a positive number" << Qt::endl;
+        return kCliExitUsage;
+    }
+    QJsonObject params;
+    params[QStringLiteral("limit")] = limit;
+    if (const int exitCode = callQuery(command, QStringLiteral("getSlowQueries"), params,
+                                       &result);
+        exitCode != kCliExitOk) {
+        return exitCode;
+    }
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+
+    const int thresholdMs = result.value(QStringLiteral("thresholdMs")).toInt();
+    if (thresholdMs <= 0) {
+        cliErr() << command << ": the slow query log is off (slowQueryThresholdMs is 0)"
+                 << Qt::endl;
+    }
+    const QJsonArray entries = result.value(QStringLiteral("entries")).toArray();
+    if (entries.isEmpty()) {
+        cliOut() << "No searches over " << thresholdMs << " ms since the query service started"
+                 << Qt::endl;
+        return kCliExitOk;
+    }
+    // Oldest first on screen, like a log file.
+    for (qsizetype i = entries.size() - 1; i >= 0; --i) {
+        cliOut() << renderSlowQuery(entries.at(i).toObject());
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+bool writeFile(const QString& path, const QByteArray& contents)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::WriteOnly | QIODevice::Truncate)) {
+        return false;
+    }
+    file.setPermissions(QFile::ReadOwner | QFile::WriteOwner);
+    return file.write(contents) == contents.size();
+}
+
+// The last `bytes` of a log, from the first whole line.
+QByteArray logTail(const QString& path, qint64 bytes)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        return {};
+    }
+    if (file.size() <= bytes) {
+        return file.readAll();
+    }
+    file.seek(file.size() - bytes);
+    QByteArray tail = file.readAll();
+    const qsizetype newline = tail.indexOf('\n');
+    return newline >= 0 ? tail.mid(newline + 1) : tail;
+}
+
+// What the services say right now; the error instead when one is down.
+QJsonObject liveStatus()
+{
+    QJsonObject status;
+    const auto probe = [&status](const QString& service, const QString& method) {
+        QString error;
+        const auto response = callService(service, method, {}, kStatusTimeoutMs, &error);
+        QJsonObject entry;
+        if (!response.has_value()) {
+            entry[QStringLiteral("error")] = error;
+        } else if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            entry[QStringLiteral("error")] = response->value(QStringLiteral("error")).toObject()
+                                                 .value(QStringLiteral("message")).toString();
+        } else {
+            entry[QStringLiteral("result")] = response->value(QStringLiteral("result")).toObject();
+        }
+        status[service + QLatin1Char('.') + method] = entry;
+    };
+    probe(QStringLiteral("indexer"), QStringLiteral("getQueueStatus"));
+    probe(QStringLiteral("query"), QStringLiteral("getHealth"));
+    probe(QStringLiteral("query"), QStringLiteral("getReadiness"));
+    return status;
+}
+
+int debugReport(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Package what a bug report needs into one .tar.gz: the newest crash "
+                       "reports the services wrote, the tail of each service log, the "
+                       "settings and BETTERSPOTLIGHT_*/OTEL_* environment with secrets "
+                       "stripped, and the live service status. Logs and crash reports hold "
+                       "file paths; look the bundle over before sharing it.\n"
+                       "Exit status: 0 ok, 1 the bundle could not be written, 2 usage "
+                       "error."));
+    const QCommandLineOption outputOption(
+        QStringLiteral("output"),
+        QStringLiteral("Write the bundle here (default ./betterspotlight-report-<time>.tar.gz)."),
+        QStringLiteral("file"));
+    const QCommandLineOption crashesOption(
+        QStringLiteral("crashes"),
+        QStringLiteral("Include at most this many crash reports, newest first (default %1).")
+            .arg(kDefaultCrashReports),
+        QStringLiteral("n"), QString::number(kDefaultCrashReports));
+    parser.addOptions({outputOption, crashesOption});
+    if (const auto exitCode =
+            parseCliArguments(parser, QStringLiteral("bspot debug report"), args)) {
+        return exitCode.value();
+    }
+
+    const QString command = QStringLiteral("bspot debug report");
+    bool ok = false;
+    const int maxCrashes = parser.value(crashesOption).toInt(&ok);
+    if (!ok || maxCrashes < 0) {
+        cliErr() << command << ": --crashes must be 0 or more" << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    const QDateTime now = QDateTime::currentDateTime();
+    const QString bundleName =
+        QStringLiteral("betterspotlight-report-") + now.toString(QStringLiteral("yyyyMMdd-HHmmss"));
+    const QString output = QFileInfo(parser.isSet(outputOption)
+                                         ? parser.value(outputOption)
+                                         : bundleName + QStringLiteral(".tar.gz"))
+                               .absoluteFilePath();
+
+    QTemporaryDir staging;
+    if (!staging.isValid()) {
+        cliErr() << command << ": cannot create a staging directory" << Qt::endl;
+        return kCliExitFailure;
+    }
+    const QDir bundle(QDir(staging.path()).filePath(bundleName));
+    QDir().mkpath(bundle.filePath(QStringLiteral("crashes")));
+    QDir().mkpath(bundle.filePath(QStringLiteral("logs")));
+    QStringList manifest;
+
+    const std::vector<CrashReport::Report> crashes = CrashReport::list();
+    int includedCrashes = 0;
+    for (const CrashReport::Report& crash : crashes) {
+        if (includedCrashes >= maxCrashes) {
+            break;
+        }
+        const QString name = QFileInfo(crash.path).fileName();
+        if (QFile::copy(crash.path, bundle.filePath(QStringLiteral("crashes/") + name))) {
+            manifest.append(QStringLiteral("crashes/%1  %2 signal %3 at %4")
+                                .arg(name, crash.service)
+                                .arg(crash.signal)
+                                .arg(QDateTime::fromSecsSinceEpoch(crash.crashedAt)
+                                         .toString(Qt::ISODate)));
+            ++includedCrashes;
+        }
+    }
+
+    const QString logDirectory = Logging::optionsFromEnvironment().directory;
+    if (!logDirectory.isEmpty()) {
+        for (const QFileInfo& log :
+             QDir(logDirectory).entryInfoList({QStringLiteral("*.log")}, QDir::Files,
+                                              QDir::Name)) {
+            if (writeFile(bundle.filePath(QStringLiteral("logs/") + log.fileName()),
+                          logTail(log.absoluteFilePath(), kLogTailBytes))) {
+                manifest.append(QStringLiteral("logs/") + log.fileName());
+            }
+        }
+    }
+
+    // The app's settings.json sits in its AppDataLocation, which is not
+    // this process's.
+    const QString settingsPath =
+        QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+        + QStringLiteral("/BetterSpotlight/BetterSpotlight/settings.json");
+    QFile settingsFile(settingsPath);
+    if (settingsFile.open(QIODevice::ReadOnly)) {
+        const QJsonObject settings = QJsonDocument::fromJson(settingsFile.readAll()).object();
+        writeFile(bundle.filePath(QStringLiteral("settings.json")),
+                  QJsonDocument(CrashReport::stripSecrets(settings))
+                      .toJson(QJsonDocument::Indented));
+        manifest.append(QStringLiteral("settings.json  secrets stripped"));
+    }
+    writeFile(bundle.filePath(QStringLiteral("environment.json")),
+              QJsonDocument(CrashReport::environmentConfig()).toJson(QJsonDocument::Indented));
+    manifest.append(QStringLiteral("environment.json  secrets stripped"));
+    writeFile(bundle.filePath(QStringLiteral("status.json")),
+              QJsonDocument(liveStatus()).toJson(QJsonDocument::Indented));
+    manifest.append(QStringLiteral("status.json  live service status"));
+
+    const QString readme =
+        QStringLiteral("BetterSpotlight bug report bundle\n"
+                       "created: %1\nbspot: %2\n\n")
+            .arg(now.toString(Qt::ISODate), QCoreApplication::applicationVersion())
+        + manifest.join(QLatin1Char('\n')) + QLatin1Char('\n');
+    writeFile(bundle.filePath(QStringLiteral("README.txt")), readme.toUtf8());
+
+    QProcess tar;
+    tar.start(QStringLiteral("tar"),
+              {QStringLiteral("-czf"), output, QStringLiteral("-C"), staging.path(), bundleName});
+    if (!tar.waitForFinished(60000) || tar.exitStatus() != QProcess::NormalExit
+        || tar.exitCode() != 0) {
+        cliErr() << command << ": tar failed: "
+                 << QString::fromLocal8Bit(tar.readAllStandardError()).trimmed() << Qt::endl;
+        return kCliExitFailure;
+    }
+    QFile::setPermissions(output, QFile::ReadOwner | QFile::WriteOwner);
+
+    cliOut() << "Wrote " << output << Qt::endl
+             << "  " << includedCrashes << " of " << static_cast<qint64>(crashes.size())
+             << " crash reports, "
+             << manifest.filter(QStringLiteral("logs/")).size() << " logs, settings and status"
+             << Qt::endl
+             << "Logs and crash reports hold file paths; look it over before attaching it."
+             << Qt::endl;
+    return kCliExitOk;
+}
+
+int debugDiffIndex(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Compare two index snapshots (copies of index.db, e.g. made with "
+                       "sqlite3 index.db \".backup before.db\" before a reindex) document by "
+                       "document: what was added, removed, and which fields of the rest "
+                       "changed. Documents are matched by path; when each was indexed is not "
+                       "compared unless asked for.\n"
+                       "Exit status: 0 identical, 1 the snapshots differ, 2 usage error or a "
+                       "snapshot could not be read."));
+    parser.addPositionalArgument(QStringLiteral("snapshotA"),
+                                 QStringLiteral("The index before."));
+    parser.addPositionalArgument(QStringLiteral("snapshotB"),
+                                 QStringLiteral("The index after."));
+    const QCommandLineOption underOption(
+        QStringLiteral("under"), QStringLiteral("Only documents at or below this path."),
+        QStringLiteral("path"));
+    const QCommandLineOption ignoreOption(
+        QStringLiteral("ignore"),
+        QStringLiteral("Leave this field out; repeatable, a trailing * matches a prefix "
+                       "(\"attribute:*\")."),
+        QStringLiteral("field"));
+    const QCommandLineOption indexedAtOption(
+        QStringLiteral("indexed-at"), QStringLiteral("Also compare when each was indexed."));
+    const QCommandLineOption limitOption(
+        QStringLiteral("limit"),
+        QStringLiteral("List at most this many added, removed and changed documents each "
+                       "(default %1).")
+            .arg(kDefaultDiffLimit),
+        QStringLiteral("n"), QString::number(kDefaultDiffLimit));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print every difference as JSON."));
+    parser.addOptions({underOption, ignoreOption, indexedAtOption, limitOption, jsonOption});
+    if (const auto exitCode =
+            parseCliArguments(parser, QStringLiteral("bspot debug diff-index"), args)) {
+        return exitCode.value();
+    }
+
+    const QString command = QStringLiteral("bspot debug diff-index");
+    const QStringList snapshots = parser.positionalArguments();
+    if (snapshots.size() != 2) {
+        cliErr() << command << ": expected two index snapshots" << Qt::endl;
+        return kCliExitUsage;
+    }
+    bool ok = false;
+    const int limit = parser.value(limitOption).toInt(&ok);
+    if (!ok || limit < 0) {
+        cliErr() << command << ": --limit must be 0 or more" << Qt::endl;
+        return kCliExitUsage;
+    }
+    QStringList ignored = parser.values(ignoreOption);
+    if (!parser.isSet(indexedAtOption)) {
+        ignored.append(IndexDiff::defaultIgnoredFields());
+    }
+    const QString under = parser.isSet(underOption)
+                              ? QDir::cleanPath(QFileInfo(parser.value(underOption))
+                                                    .absoluteFilePath())
+                              : QString();
+
+    QString error;
+    const auto before = IndexDiff::load(QFileInfo(snapshots.at(0)).absoluteFilePath(), under,
+                                        &error);
+    const auto after = before.has_value()
+                           ? IndexDiff::load(QFileInfo(snapshots.at(1)).absoluteFilePath(),
+                                             under, &error)
+                           : std::nullopt;
+    if (!after.has_value()) {
+        cliErr() << command << ": " << error << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    const IndexDiffResult result = IndexDiff::diff(*before, *after, ignored);
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(IndexDiff::toJson(result)).toJson(QJsonDocument::Indented)
+                 << Qt::flush;
+    } else {
+        cliOut() << IndexDiff::renderText(result, limit) << Qt::flush;
+    }
+    return result.identical() ? kCliExitOk : kCliExitFailure;
+}
+
+void printDebugUsage(QTextStream& stream)
+{
+    stream << "Usage: bspot debug <slowlog|report|diff-index> [options]\n"
+              "\n"
+              "  slowlog     Searches over the latency threshold, with phase timings and plan\n"
+              "  report      Package crash reports, logs and settings for a bug report\n"
+              "  diff-index  Documents and fields that differ between two index snapshots\n"
+           << Qt::flush;
+}
+
+} // namespace
+
+int runDebugCommand(const QStringList& args)
+{
+    if (args.isEmpty()) {
+        printDebugUsage(cliErr());
+        return kCliExitUsage;
+    }
+    const QString action = args.first();
+    const QStringList rest = args.mid(1);
+    if (action == QLatin1String("slowlog")) return debugSlowLog(rest);
+    if (action == QLatin1String("report"))  return debugReport(rest);
+    if (action == QLatin1String("diff-index")) return debugDiffIndex(rest);
+    if (action == QLatin1String("--help") || action == QLatin1String("-h")) {
+        printDebugUsage(cliOut());
+        return kCliExitOk;
+    }
+    cliErr() << "bspot debug: unknown action '" << action << "'" << Qt::endl;
+    printDebugUsage(cliErr());
+    return kCliExitUsage;
+}
+
+} // namespace bs
@@ -0,0 +1,326 @@
+#include "cli/doctor.h"
+
+#include <QDir>
+#include <QFile>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QLockFile>
+#include <QRegularExpression>
+
+namespace bs {
+
+namespace {
+
+DoctorCheck makeCheck(const QString& id, const QString& title, DoctorCheck::Status status,
+                      const QString& detail, const QString& fix = QString())
+{
+    DoctorCheck check;
+    check.id = id;
+    check.title = title;
+    check.status = status;
+    check.detail = detail;
+    check.fix = fix;
+    return check;
+}
+
+QString gib(qint64 bytes)
+{
+    return QStringLiteral("%1 GiB").arg(static_cast<double>(bytes) / (1LL << 30), 0, 'f', 1);
+}
+
+QString shellQuote(const QString& path)
+{
+    if (!path.contains(QRegularExpression(QStringLiteral(R"([^\w@%+=:,./-])")))) {
+        return path;
+    }
+    QString quoted = path;
+    quoted.replace(QLatin1Char('\''), QStringLiteral("'\\''"));
+    return QLatin1Char('\'') + quoted + QLatin1Char('\'');
+}
+
+const QString kStartFix = QStringLiteral(
+    "Start BetterSpotlight, or run 'bspot service install' to keep it running at login.");
+
+} // namespace
+
+DoctorCheck Doctor::evaluateService(const ServiceProbe& probe, bool required)
+{
+    const QString id = QStringLiteral("service-") + probe.name;
+    const QString title = QStringLiteral("%1 service").arg(probe.name);
+    if (probe.reachable) {
+        return makeCheck(id, title, DoctorCheck::Status::Ok, QStringLiteral("responding"));
+    }
+
+    const DoctorCheck::Status status =
+        required ? DoctorCheck::Status::Failed : DoctorCheck::Status::Warning;
+    if (!probe.socketExists) {
+        return makeCheck(id, title, status, QStringLiteral("not running (no socket)"),
+                         kStartFix);
+    }
+    QString detail = QStringLiteral("socket exists but the service does not answer");
+    if (!probe.error.isEmpty()) {
+        detail += QStringLiteral(" (%1)").arg(probe.error);
+    }
+    return makeCheck(id, title, status, detail,
+                     QStringLiteral("Quit and reopen BetterSpotlight. If it persists, check "
+                                    "~/Library/Logs/BetterSpotlight for crash loops."));
+}
+
+DoctorCheck Doctor::evaluateFullDiskAccess(const std::optional<QJsonObject>& diagnostics,
+                                           bool localGranted)
+{
+    const QString id = QStringLiteral("full-disk-access");
+    const QString title = QStringLiteral("Full Disk Access");
+    const QString fix = QStringLiteral(
+        "Open System Settings > Privacy & Security > Full Disk Access, enable "
+        "BetterSpotlight, then restart it.");
+
+    if (diagnostics.has_value()) {
+        // A root the indexer cannot list is the worse problem: it indexes
+        // nothing at all, with or without the FDA grant.
+        const QJsonObject access = diagnostics->value(QStringLiteral("access")).toObject();
+        QStringList unreadable;
+        for (const QJsonValue& value : access.value(QStringLiteral("unreadableRoots")).toArray()) {
+            unreadable.append(value.toObject().value(QStringLiteral("path")).toString());
+        }
+        QStringList skipped;
+        for (const QJsonValue& value : access.value(QStringLiteral("protectedFolders")).toArray()) {
+            skipped.append(value.toObject().value(QStringLiteral("path")).toString());
+        }
+        const QString remediation = access.value(QStringLiteral("remediation")).toString();
+        if (!unreadable.isEmpty()) {
+            return makeCheck(id, title, DoctorCheck::Status::Failed,
+                             QStringLiteral("the indexer cannot read %1").arg(
+                                 unreadable.join(QStringLiteral(", "))),
+                             remediation.isEmpty() ? fix : remediation);
+        }
+
+        if (diagnostics->value(QStringLiteral("fullDiskAccess")).toBool()) {
+            if (!skipped.isEmpty()) {
+                return makeCheck(id, title, DoctorCheck::Status::Warning,
+                                 QStringLiteral("granted, but %1 cannot be read and is skipped")
+                                     .arg(skipped.join(QStringLiteral(", "))),
+                                 remediation);
+            }
+            return makeCheck(id, title, DoctorCheck::Status::Ok,
+                             QStringLiteral("granted to the indexer"));
+        }
+        QString detail = QStringLiteral("not granted to the indexer; Mail, Messages and other "
+                                        "protected folders are skipped");
+        if (!skipped.isEmpty()) {
+            detail += QStringLiteral(", including %1").arg(skipped.join(QStringLiteral(", ")));
+        }
+        return makeCheck(id, title, DoctorCheck::Status::Failed, detail, fix);
+    }
+
+    // Only this terminal's grant is observable; it says nothing about the app.
+    return makeCheck(id, title, DoctorCheck::Status::Warning,
+                     localGranted
+                         ? QStringLiteral("indexer unreachable; this terminal has access")
+                         : QStringLiteral("indexer unreachable; this terminal has no access"),
+                     QStringLiteral("Start BetterSpotlight and re-run 'bspot doctor' to check "
+                                    "the indexer itself."));
+}
+
+DoctorCheck Doctor::evaluateWatcher(const std::optional<QJsonObject>& diagnostics)
+{
+    const QString id = QStringLiteral("watcher");
+    const QString title = QStringLiteral("File watcher");
+    if (!diagnostics.has_value()) {
+        return makeCheck(id, title, DoctorCheck::Status::Failed,
+                         QStringLiteral("unknown (indexer unreachable)"), kStartFix);
+    }
+    if (!diagnostics->value(QStringLiteral("indexing")).toBool()) {
+        return makeCheck(id, title, DoctorCheck::Status::Warning,
+                         QStringLiteral("indexing has not been started"),
+                         QStringLiteral("Finish onboarding in the app, or choose folders to "
+                                        "index in Settings."));
+    }
+
+    const QJsonObject watcher = diagnostics->value(QStringLiteral("watcher")).toObject();
+    if (!watcher.value(QStringLiteral("running")).toBool()) {
+        return makeCheck(id, title, DoctorCheck::Status::Failed,
+                         QStringLiteral("not running; changes are not picked up until the next "
+                                        "full scan"),
+                         QStringLiteral("Restart BetterSpotlight. Check that the indexed folders "
+                                        "still exist."));
+    }
+    const qint64 errors = watcher.value(QStringLiteral("errorCount")).toInteger();
+    if (errors > 0) {
+        return makeCheck(id, title, DoctorCheck::Status::Warning,
+                         QStringLiteral("running; %1 dropped-event notice(s), last: %2")
+                             .arg(errors)
+                             .arg(watcher.value(QStringLiteral("lastError")).toString()),
+                         QStringLiteral("If results look stale, use Settings > Index Health > "
+                                        "Reindex Folder on the affected folder."));
+    }
+    const int roots = diagnostics->value(QStringLiteral("roots")).toArray().size();
+    return makeCheck(id, title, DoctorCheck::Status::Ok,
+                     QStringLiteral("running on %1 root(s)").arg(roots));
+}
+
+DoctorCheck Doctor::evaluateIntegrity(const QString& dbPath, bool exists,
+                                      const QString& openError, const QString& quickCheck)
+{
+    const QString id = QStringLiteral("index-integrity");
+    const QString title = QStringLiteral("Index integrity");
+    const QString rebuildFix = QStringLiteral(
+        "Use Settings > Index Health > Rebuild All. If the app will not start, quit "
+        "BetterSpotlight and move %1 aside.").arg(shellQuote(dbPath));
+
+    if (!exists) {
+        return makeCheck(id, title, DoctorCheck::Status::Warning,
+                         QStringLiteral("no index at %1").arg(dbPath),
+                         QStringLiteral("Nothing is indexed yet. Start BetterSpotlight and "
+                                        "finish onboarding."));
+    }
+    if (!openError.isEmpty()) {
+        return makeCheck(id, title, DoctorCheck::Status::Failed,
+                         QStringLiteral("cannot open %1: %2").arg(dbPath, openError),
+                         rebuildFix);
+    }
+    if (quickCheck != QLatin1String("ok")) {
+        return makeCheck(id, title, DoctorCheck::Status::Failed,
+                         QStringLiteral("quick_check reported: %1").arg(quickCheck), rebuildFix);
+    }
+    return makeCheck(id, title, DoctorCheck::Status::Ok, QStringLiteral("%1 ok").arg(dbPath));
+}
+
+DoctorCheck Doctor::evaluateDiskSpace(const QString& path, qint64 availableBytes,
+                                      qint64 totalBytes)
+{
+    const QString id = QStringLiteral("disk-space");
+    const QString title = QStringLiteral("Disk space");
+    if (availableBytes < 0 || totalBytes <= 0) {
+        return makeCheck(id, title, DoctorCheck::Status::Warning,
+                         QStringLiteral("could not read free space for %1").arg(path));
+    }
+
+    const QString detail =
+        QStringLiteral("%1 free of %2 on the index volume").arg(gib(availableBytes), gib(totalBytes));
+    const QString fix = QStringLiteral(
+        "Free up space; SQLite needs room for its WAL and the index stops writing when the "
+        "disk is full.");
+    if (availableBytes < kDiskFailBytes) {
+        return makeCheck(id, title, DoctorCheck::Status::Failed, detail, fix);
+    }
+    if (availableBytes < kDiskWarnBytes) {
+        return makeCheck(id, title, DoctorCheck::Status::Warning, detail, fix);
+    }
+    return makeCheck(id, title, DoctorCheck::Status::Ok, detail);
+}
+
+DoctorCheck Doctor::evaluateStaleFiles(const QString& runtimeRoot,
+                                       const QStringList& unreachableSockets,
+                                       const std::function<bool(qint64)>& processIsAlive)
+{
+    QStringList stale;
+
+    const QString lockPath = QDir(runtimeRoot).filePath(QStringLiteral("app.lock"));
+    if (QFile::exists(lockPath)) {
+        qint64 ownerPid = 0;
+        QString ownerHost;
+        QString ownerApp;
+        QLockFile lock(lockPath);
+        if (!lock.getLockInfo(&ownerPid, &ownerHost, &ownerApp) || !processIsAlive(ownerPid)) {
+            stale.append(lockPath);
+        }
+    }
+
+    const QFileInfoList instances =
+        QDir(runtimeRoot).entryInfoList(QDir::Dirs | QDir::NoDotAndDotDot, QDir::Name);
+    for (const QFileInfo& entry : instances) {
+        QFile metadataFile(QDir(entry.absoluteFilePath()).filePath(QStringLiteral("instance.json")));
+        if (!metadataFile.open(QIODevice::ReadOnly)) {
+            continue;
+        }
+        const QJsonObject metadata = QJsonDocument::fromJson(metadataFile.readAll()).object();
+        if (!processIsAlive(metadata.value(QStringLiteral("app_pid")).toInteger())) {
+            stale.append(entry.absoluteFilePath());
+        }
+    }
+
+    for (const QString& socket : unreachableSockets) {
+        if (!stale.contains(socket)) {
+            stale.append(socket);
+        }
+    }
+
+    const QString id = QStringLiteral("stale-locks");
+    const QString title = QStringLiteral("Stale locks");
+    if (stale.isEmpty()) {
+        return makeCheck(id, title, DoctorCheck::Status::Ok, QStringLiteral("none"));
+    }
+
+    QStringList quoted;
+    for (const QString& path : stale) {
+        quoted.append(shellQuote(path));
+    }
+    return makeCheck(id, title, DoctorCheck::Status::Warning,
+                     QStringLiteral("%1 left over from a crashed run: %2")
+                         .arg(stale.size())
+                         .arg(stale.join(QStringLiteral(", "))),
+                     QStringLiteral("Quit BetterSpotlight, then: rm -rf %1")
+                         .arg(quoted.join(QLatin1Char(' '))));
+}
+
+QString Doctor::statusLabel(DoctorCheck::Status status)
+{
+    switch (status) {
+    case DoctorCheck::Status::Ok:      return QStringLiteral("ok");
+    case DoctorCheck::Status::Warning: return QStringLiteral("warn");
+    case DoctorCheck::Status::Failed:  return QStringLiteral("fail");
+    }
+    return QStringLiteral("fail");
+}
+
+QString Doctor::render(const QList<DoctorCheck>& checks)
+{
+    QString out;
+    int failed = 0;
+    int warnings = 0;
+    for (const DoctorCheck& check : checks) {
+        out += QStringLiteral("[%1] %2: %3\n")
+                   .arg(statusLabel(check.status).leftJustified(4), check.title, check.detail);
+        if (!check.fix.isEmpty() && check.status != DoctorCheck::Status::Ok) {
+            out += QStringLiteral("       fix: %1\n").arg(check.fix);
+        }
+        failed += check.status == DoctorCheck::Status::Failed ? 1 : 0;
+        warnings += check.status == DoctorCheck::Status::Warning ? 1 : 0;
+    }
+    if (failed == 0 && warnings == 0) {
+        out += QStringLiteral("\nAll checks passed.\n");
+    } else {
+        out += QStringLiteral("\n%1 failed, %2 warning(s).\n").arg(failed).arg(warnings);
+    }
+    return out;
+}
+
+QJsonArray Doctor::toJson(const QList<DoctorCheck>& checks)
+{
+    QJsonArray array;
+    for (const DoctorCheck& check : checks) {
+        QJsonObject entry;
+        entry[QStringLiteral("id")] = check.id;
+        entry[QStringLiteral("title")] = check.title;
+        entry[QStringLiteral("status")] = statusLabel(check.status);
+        entry[QStringLiteral("detail")] = check.detail;
+        if (!check.fix.isEmpty()) {
+            entry[QStringLiteral("fix")] = check.fix;
+        }
+        array.append(entry);
+    }
+    return array;
+}
+
+int Doctor::exitCode(const QList<DoctorCheck>& checks)
+{
+    for (const DoctorCheck& check : checks) {
+        if (check.status == DoctorCheck::Status::Failed) {
+            return 1;
+        }
+    }
+    return 0;
+}
+
+} // namespace bs
@@ -0,0 +1,75 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QList>
+#include <QString>
+
+#include <functional>
+#include <optional>
+
+namespace bs {
+
+struct DoctorCheck {
+    enum class Status { Ok, Warning, Failed };
+
+    QString id;        // stable key for --json, e.g. "disk-space"
+    QString title;
+    Status status = Status::Ok;
+    QString detail;
+    QString fix;       // empty when there is nothing to do
+};
+
+// Doctor — evaluation and rendering behind `bspot doctor`.
+//
+// Probing (sockets, SQLite, statfs) lives in doctor_command.cpp; the
+// functions here turn probe results into checks with fixes, so the wording
+// and thresholds can be unit tested.
+class Doctor {
+public:
+    static constexpr qint64 kDiskFailBytes = 1LL << 30;      // 1 GiB
+    static constexpr qint64 kDiskWarnBytes = 5LL << 30;      // 5 GiB
+
+    // Outcome of pinging one service socket.
+    struct ServiceProbe {
+        QString name;
+        bool socketExists = false;
+        bool reachable = false;
+        QString error;
+    };
+
+    static DoctorCheck evaluateService(const ServiceProbe& probe, bool required);
+
+    // `diagnostics` is the indexer's getDiagnostics result; without it the
+    // check falls back to this process, which only speaks for the terminal.
+    // Its `access` report fails the check for unreadable roots and warns
+    // for skipped protected folders even when FDA is granted.
+    static DoctorCheck evaluateFullDiskAccess(const std::optional<QJsonObject>& diagnostics,
+                                              bool localGranted);
+    static DoctorCheck evaluateWatcher(const std::optional<QJsonObject>& diagnostics);
+
+    // `quickCheck` is the first row of PRAGMA quick_check ("ok" when
+    // healthy); `openError` is set when the database could not be opened.
+    static DoctorCheck evaluateIntegrity(const QString& dbPath, bool exists,
+                                         const QString& openError,
+                                         const QString& quickCheck);
+
+    static DoctorCheck evaluateDiskSpace(const QString& path, qint64 availableBytes,
+                                         qint64 totalBytes);
+
+    // Leftovers of crashed runs under the runtime root: app.lock held by a
+    // dead pid, instance directories whose app_pid is gone, and socket
+    // files nothing listens on (`unreachableSockets`).
+    static DoctorCheck evaluateStaleFiles(const QString& runtimeRoot,
+                                          const QStringList& unreachableSockets,
+                                          const std::function<bool(qint64)>& processIsAlive);
+
+    static QString statusLabel(DoctorCheck::Status status);
+    static QString render(const QList<DoctorCheck>& checks);
+    static QJsonArray toJson(const QList<DoctorCheck>& checks);
+
+    // 0 when nothing failed (warnings allowed), 1 otherwise.
+    static int exitCode(const QList<DoctorCheck>& checks);
+};
+
+} // namespace bs
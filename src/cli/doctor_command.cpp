@@ -0,0 +1,195 @@
+#include "cli/cli_common.h"
+#include "cli/doctor.h"
+#include "core/ipc/service_base.h"
+#include "core/shared/fda_check.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QStandardPaths>
+#include <QStorageInfo>
+
+#include <sqlite3.h>
+#include <signal.h>
+
+#include <cerrno>
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 1500;
+
+// Same resolution as IndexerService/QueryService.
+QString indexDataDirectory()
+{
+    const QString envDataDir = qEnvironmentVariable("BETTERSPOTLIGHT_DATA_DIR").trimmed();
+    if (!envDataDir.isEmpty()) {
+        return QDir::cleanPath(envDataDir);
+    }
+    return QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+        + QStringLiteral("/betterspotlight");
+}
+
+bool processIsAlive(qint64 pid)
+{
+    // EPERM: the pid exists but belongs to someone else.
+    return pid > 0 && (::kill(static_cast<pid_t>(pid), 0) == 0 || errno == EPERM);
+}
+
+Doctor::ServiceProbe probeService(const QString& name, int timeoutMs)
+{
+    Doctor::ServiceProbe probe;
+    probe.name = name;
+    probe.socketExists = QFileInfo::exists(ServiceBase::socketPath(name));
+    if (!probe.socketExists) {
+        return probe;
+    }
+    QString error;
+    const auto response = callService(name, QStringLiteral("ping"), {}, timeoutMs, &error);
+    probe.reachable = response.has_value()
+        && response->value(QStringLiteral("type")).toString() == QLatin1String("response");
+    if (!probe.reachable) {
+        probe.error = response.has_value()
+            ? response->value(QStringLiteral("error")).toObject()
+                  .value(QStringLiteral("message")).toString()
+            : error;
+    }
+    return probe;
+}
+
+std::optional<QJsonObject> indexerDiagnostics(int timeoutMs)
+{
+    QString error;
+    const auto response =
+        callService(QStringLiteral("indexer"), QStringLiteral("getDiagnostics"), {}, timeoutMs,
+                    &error);
+    if (!response.has_value()
+        || response->value(QStringLiteral("type")).toString() != QLatin1String("response")) {
+        return std::nullopt;
+    }
+    return response->value(QStringLiteral("result")).toObject();
+}
+
+// Read-only, so it is safe next to a running indexer (WAL readers do not
+// block the writer) and never creates a database that was not there.
+DoctorCheck checkIntegrity(const QString& dbPath)
+{
+    if (!QFileInfo::exists(dbPath)) {
+        return Doctor::evaluateIntegrity(dbPath, false, {}, {});
+    }
+
+    sqlite3* db = nullptr;
+    const int rc = sqlite3_open_v2(dbPath.toUtf8().constData(), &db, SQLITE_OPEN_READONLY,
+                                   nullptr);
+    if (rc != SQLITE_OK) {
+        const QString openError = QString::fromUtf8(db ? sqlite3_errmsg(db) : sqlite3_errstr(rc));
+        sqlite3_close(db);
+        return Doctor::evaluateIntegrity(dbPath, true, openError, {});
+    }
+    sqlite3_busy_timeout(db, 2000);
+
+    QString quickCheck;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(db, "PRAGMA quick_check(1)", -1, &stmt, nullptr) == SQLITE_OK) {
+        if (sqlite3_step(stmt) == SQLITE_ROW) {
+            quickCheck = QString::fromUtf8(
+                reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        } else {
+            quickCheck = QString::fromUtf8(sqlite3_errmsg(db));
+        }
+    } else {
+        quickCheck = QString::fromUtf8(sqlite3_errmsg(db));
+    }
+    sqlite3_finalize(stmt);
+    sqlite3_close(db);
+    return Doctor::evaluateIntegrity(dbPath, true, {}, quickCheck);
+}
+
+} // namespace
+
+int runDoctorCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Check services, Full Disk Access, the file watcher, index integrity, "
+                       "disk space and stale locks, and print fixes.\n"
+                       "Exit status: 0 no failures (warnings allowed), 1 a check failed, "
+                       "2 usage error."));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the checks as a JSON document."));
+    const QCommandLineOption skipIntegrityOption(
+        QStringLiteral("skip-integrity"),
+        QStringLiteral("Skip the SQLite quick_check (slow on very large indexes)."));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Per-service timeout in milliseconds (default %1).").arg(kDefaultTimeoutMs),
+        QStringLiteral("ms"), QString::number(kDefaultTimeoutMs));
+    parser.addOptions({jsonOption, skipIntegrityOption, timeoutOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot doctor"), args)) {
+        return exitCode.value();
+    }
+
+    bool ok = false;
+    const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutMs < 1) {
+        cliErr() << "bspot doctor: --timeout must be a positive number of milliseconds"
+                 << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    QList<DoctorCheck> checks;
+    QStringList unreachableSockets;
+    // Inference only backs semantic search; lexical search works without it.
+    for (const auto& [name, required] : {std::pair{QStringLiteral("indexer"), true},
+                                         std::pair{QStringLiteral("extractor"), true},
+                                         std::pair{QStringLiteral("query"), true},
+                                         std::pair{QStringLiteral("inference"), false}}) {
+        const Doctor::ServiceProbe probe = probeService(name, timeoutMs);
+        if (probe.socketExists && !probe.reachable) {
+            unreachableSockets.append(ServiceBase::socketPath(name));
+        }
+        checks.append(Doctor::evaluateService(probe, required));
+    }
+
+    const bool indexerUp = checks.first().status == DoctorCheck::Status::Ok;
+    const std::optional<QJsonObject> diagnostics =
+        indexerUp ? indexerDiagnostics(timeoutMs) : std::nullopt;
+    checks.append(Doctor::evaluateFullDiskAccess(
+        diagnostics, diagnostics.has_value() ? false : FdaCheck::hasFullDiskAccess()));
+    checks.append(Doctor::evaluateWatcher(diagnostics));
+
+    const QString dataDir = indexDataDirectory();
+    const QString dbPath = dataDir + QStringLiteral("/index.db");
+    if (!parser.isSet(skipIntegrityOption)) {
+        checks.append(checkIntegrity(dbPath));
+    }
+
+    // Measure the volume the index lives on, even before it exists.
+    QString volumePath = dataDir;
+    while (!QFileInfo::exists(volumePath) && volumePath != QDir::rootPath()) {
+        volumePath = QFileInfo(volumePath).absolutePath();
+    }
+    const QStorageInfo storage(volumePath);
+    checks.append(Doctor::evaluateDiskSpace(
+        volumePath, storage.isValid() ? storage.bytesAvailable() : -1,
+        storage.isValid() ? storage.bytesTotal() : -1));
+
+    checks.append(Doctor::evaluateStaleFiles(ServiceBase::runtimeDirectory(),
+                                             unreachableSockets, processIsAlive));
+
+    if (parser.isSet(jsonOption)) {
+        QJsonObject report;
+        report[QStringLiteral("checks")] = Doctor::toJson(checks);
+        report[QStringLiteral("dataDir")] = dataDir;
+        report[QStringLiteral("socketDir")] = ServiceBase::socketDirectory();
+        report[QStringLiteral("ok")] = Doctor::exitCode(checks) == 0;
+        cliOut() << QJsonDocument(report).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else {
+        cliOut() << Doctor::render(checks) << Qt::flush;
+    }
+    return Doctor::exitCode(checks);
+}
+
+} // namespace bs
@@ -0,0 +1,349 @@
+#include "cli/cli_common.h"
+#include "cli/export_format.h"
+#include "cli/search_filters.h"
+
+#include "core/index/schema.h"
+#include "core/indexing/portable_index.h"
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QProcess>
+#include <QSaveFile>
+#include <QSysInfo>
+#include <QTemporaryDir>
+
+#include <memory>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 1000;
+constexpr int kDefaultTimeoutMs = 30000;
+constexpr int kPageSize = 1000;
+constexpr int kTarTimeoutMs = 10 * 60 * 1000;
+
+// The error message of an IPC error envelope, or empty for a response.
+QString errorOf(const QJsonObject& envelope)
+{
+    if (envelope.value(QStringLiteral("type")).toString() != QLatin1String("error")) {
+        return QString();
+    }
+    return envelope.value(QStringLiteral("error")).toObject().value(QStringLiteral("message")).toString();
+}
+
+bool writeFile(const QString& path, const QByteArray& bytes)
+{
+    QSaveFile file(path);
+    return file.open(QIODevice::WriteOnly) && file.write(bytes) == bytes.size() && file.commit();
+}
+
+// `bspot export --portable`: every segment the query service publishes for
+// PeerSync, written as a PortableIndex archive.
+int exportPortable(const QString& outputPath, int timeoutMs)
+{
+    const QString command = QStringLiteral("bspot export");
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("query")),
+                                kConnectTimeoutMs)) {
+        cliErr() << command << ": query service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    const auto request = [&](const QString& method, const QJsonObject& params,
+                             QJsonObject* result) {
+        const auto response = client.sendRequest(method, params, timeoutMs);
+        if (!response.has_value()) {
+            cliErr() << command << ": query service did not answer" << Qt::endl;
+            return false;
+        }
+        const QString error = errorOf(*response);
+        if (!error.isEmpty()) {
+            cliErr() << command << ": " << error << Qt::endl;
+            return false;
+        }
+        *result = response->value(QStringLiteral("result")).toObject();
+        return true;
+    };
+
+    QJsonObject listing;
+    if (!request(QStringLiteral("getSyncSegments"), {}, &listing)) {
+        return kCliExitUnavailable;
+    }
+
+    QTemporaryDir staging;
+    if (!staging.isValid()) {
+        cliErr() << command << ": cannot create a staging directory" << Qt::endl;
+        return kCliExitFailure;
+    }
+    const QDir root(QDir(staging.path()).filePath(QLatin1String(PortableIndex::kRootDirectory)));
+    QDir().mkpath(root.filePath(QStringLiteral("segments")));
+
+    PortableManifest manifest;
+    manifest.formatVersion = PortableIndex::kFormatVersion;
+    manifest.schemaVersion = kCurrentSchemaVersion;
+    manifest.source = QSysInfo::machineHostName();
+    manifest.createdAt = QDateTime::currentDateTimeUtc();
+    for (const SyncSegment& listed :
+         PeerSync::segmentsFromJson(listing.value(QStringLiteral("segments")).toArray())) {
+        QJsonObject params;
+        params[QStringLiteral("first")] = static_cast<qint64>(listed.first);
+        QJsonObject page;
+        if (!request(QStringLiteral("getSyncSegment"), params, &page)) {
+            return kCliExitUnavailable;
+        }
+        std::vector<SyncedItem> items;
+        for (const QJsonValue& value : page.value(QStringLiteral("items")).toArray()) {
+            if (const auto item = PeerSync::itemFromJson(value.toObject())) {
+                items.push_back(*item);
+            }
+        }
+        // The index may have moved on since the listing; the archive holds
+        // what was read.
+        const std::vector<SyncSegment> segments = PeerSync::segments(items);
+        if (segments.empty()) {
+            continue;
+        }
+        if (!writeFile(root.filePath(PortableIndex::segmentFile(listed.first)),
+                       PortableIndex::segmentToNdjson(items))) {
+            cliErr() << command << ": cannot write to " << staging.path() << Qt::endl;
+            return kCliExitFailure;
+        }
+        manifest.segments.push_back(segments.front());
+        manifest.itemCount += segments.front().count;
+    }
+
+    if (!writeFile(root.filePath(QLatin1String(PortableIndex::kManifestFile)),
+                   QJsonDocument(PortableIndex::manifestToJson(manifest))
+                       .toJson(QJsonDocument::Indented))
+        || !writeFile(root.filePath(QLatin1String(PortableIndex::kSchemaFile)),
+                      QJsonDocument(PortableIndex::schemaJson()).toJson(QJsonDocument::Indented))) {
+        cliErr() << command << ": cannot write to " << staging.path() << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    // Built in the staging directory and moved into place, so a failed
+    // export never leaves a partial archive at the target.
+    const QString archive = QDir(staging.path()).filePath(QStringLiteral("index.bsindex"));
+    QProcess tar;
+    tar.start(QStringLiteral("tar"), {QStringLiteral("-czf"), archive, QStringLiteral("-C"),
+                                      staging.path(), QLatin1String(PortableIndex::kRootDirectory)});
+    if (!tar.waitForFinished(kTarTimeoutMs) || tar.exitStatus() != QProcess::NormalExit
+        || tar.exitCode() != 0) {
+        cliErr() << command << ": tar failed: "
+                 << QString::fromLocal8Bit(tar.readAllStandardError()).trimmed() << Qt::endl;
+        return kCliExitFailure;
+    }
+    QFile::setPermissions(archive, QFile::ReadOwner | QFile::WriteOwner);
+    QFile::remove(outputPath);
+    if (!QFile::rename(archive, outputPath) && !QFile::copy(archive, outputPath)) {
+        cliErr() << command << ": cannot write " << outputPath << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    cliErr() << "Exported " << manifest.itemCount << " item(s) in "
+             << static_cast<qint64>(manifest.segments.size()) << " segment(s) to " << outputPath
+             << Qt::endl;
+    return manifest.itemCount > 0 ? kCliExitOk : kCliExitFailure;
+}
+
+} // namespace
+
+int runExportCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Write the metadata of every document matching a query (path, size, "
+                       "dates, matched fields) to a CSV, JSON or NDJSON file. Unlike search, "
+                       "results are not ranked or capped; they are streamed in index order. "
+                       "With --portable, write the whole index as an archive for 'bspot "
+                       "import' instead.\n"
+                       "Exit status: 0 items exported, 1 no matches, 2 usage error, "
+                       "3 service unavailable or failed."));
+    parser.addPositionalArgument(QStringLiteral("query"),
+                                 QStringLiteral("Search text (not with --portable)."),
+                                 QStringLiteral("<query...>"));
+    const QCommandLineOption outputOption(
+        {QStringLiteral("o"), QStringLiteral("output")},
+        QStringLiteral("Write to this file instead of standard output. It only appears once "
+                       "the export is complete."),
+        QStringLiteral("file"));
+    const QCommandLineOption formatOption(
+        {QStringLiteral("f"), QStringLiteral("format")},
+        QStringLiteral("csv, json or ndjson (default: from the --output extension, else "
+                       "ndjson)."),
+        QStringLiteral("format"));
+    const QCommandLineOption modeOption(
+        QStringLiteral("mode"),
+        QStringLiteral("Query mode: strict (all terms, default) or relaxed (any term)."),
+        QStringLiteral("mode"), QStringLiteral("strict"));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Per-page timeout in milliseconds (default %1).").arg(kDefaultTimeoutMs),
+        QStringLiteral("ms"), QString::number(kDefaultTimeoutMs));
+    const QCommandLineOption portableOption(
+        QStringLiteral("portable"),
+        QStringLiteral("Export the whole index instead, as a portable archive (%1) that "
+                       "'bspot import' turns into an index on another Mac. Entries and at "
+                       "most %2 characters of text per file; no file contents.")
+            .arg(QLatin1String(PortableIndex::kFileExtension))
+            .arg(PeerSync::kMaxTextChars));
+    const SearchFilterOptions filterOptions;
+    parser.addOptions({outputOption, formatOption, portableOption});
+    filterOptions.addTo(parser);
+    parser.addOptions({modeOption, timeoutOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot export"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot export: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+
+    const QString query = parser.positionalArguments().join(QLatin1Char(' ')).trimmed();
+    if (parser.isSet(portableOption)) {
+        QString filterError;
+        const std::optional<QJsonObject> filters = filterOptions.filters(parser, &filterError);
+        if (!query.isEmpty() || parser.isSet(formatOption) || parser.isSet(modeOption)
+            || !filters.has_value() || !filters->isEmpty()) {
+            return usageError(QStringLiteral("--portable exports everything; it takes no "
+                                             "query, filters, --format or --mode"));
+        }
+        const QString outputPath = parser.value(outputOption);
+        if (outputPath.isEmpty() || outputPath == QLatin1String("-")) {
+            return usageError(QStringLiteral("--portable needs --output <file>"));
+        }
+        bool ok = false;
+        const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+        if (!ok || timeoutMs < 1) {
+            return usageError(QStringLiteral("--timeout must be a positive number of milliseconds"));
+        }
+        return exportPortable(QFileInfo(outputPath).absoluteFilePath(), timeoutMs);
+    }
+    if (query.isEmpty()) {
+        return usageError(QStringLiteral("missing query"));
+    }
+    const QString outputPath = parser.value(outputOption);
+    const bool toStdout = outputPath.isEmpty() || outputPath == QLatin1String("-");
+
+    std::optional<ExportFileFormat> format;
+    if (parser.isSet(formatOption)) {
+        format = ExportFormat::parse(parser.value(formatOption));
+        if (!format.has_value()) {
+            return usageError(QStringLiteral("--format must be csv, json or ndjson"));
+        }
+    } else {
+        format = toStdout ? std::nullopt : ExportFormat::fromFileName(outputPath);
+        if (!format.has_value()) {
+            format = ExportFileFormat::Ndjson;
+        }
+    }
+    const QString mode = parser.value(modeOption).toLower();
+    if (mode != QLatin1String("strict") && mode != QLatin1String("relaxed")) {
+        return usageError(QStringLiteral("--mode must be strict or relaxed"));
+    }
+    bool ok = false;
+    const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutMs < 1) {
+        return usageError(QStringLiteral("--timeout must be a positive number of milliseconds"));
+    }
+    QString filterError;
+    const std::optional<QJsonObject> filters = filterOptions.filters(parser, &filterError);
+    if (!filters.has_value()) {
+        return usageError(filterError);
+    }
+
+    // QSaveFile writes next to the target and renames on commit, so a
+    // failed or interrupted export never leaves a truncated file behind.
+    std::unique_ptr<QIODevice> out;
+    if (toStdout) {
+        auto stdoutFile = std::make_unique<QFile>();
+        if (!stdoutFile->open(stdout, QIODevice::WriteOnly)) {
+            cliErr() << "bspot export: cannot write to standard output" << Qt::endl;
+            return kCliExitFailure;
+        }
+        out = std::move(stdoutFile);
+    } else {
+        auto saveFile = std::make_unique<QSaveFile>(outputPath);
+        if (!saveFile->open(QIODevice::WriteOnly)) {
+            cliErr() << "bspot export: cannot write " << outputPath << ": "
+                     << saveFile->errorString() << Qt::endl;
+            return kCliExitFailure;
+        }
+        out = std::move(saveFile);
+    }
+    const auto write = [&out](const QByteArray& bytes) {
+        return bytes.isEmpty() || out->write(bytes) == bytes.size();
+    };
+
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("query")),
+                                kConnectTimeoutMs)) {
+        cliErr() << "bspot export: query service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    qint64 exported = 0;
+    bool writeFailed = !write(ExportFormat::begin(*format));
+    QJsonValue cursor(QJsonValue::Null);
+    while (!writeFailed) {
+        QJsonObject params;
+        params[QStringLiteral("query")] = query;
+        params[QStringLiteral("queryMode")] = mode;
+        params[QStringLiteral("limit")] = kPageSize;
+        params[QStringLiteral("cursor")] = cursor;
+        if (!filters->isEmpty()) {
+            params[QStringLiteral("filters")] = filters.value();
+        }
+
+        const auto response =
+            client.sendRequest(QStringLiteral("exportMatches"), params, timeoutMs);
+        if (!response.has_value()) {
+            cliErr() << "bspot export: query service did not answer" << Qt::endl;
+            return kCliExitUnavailable;
+        }
+        if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            cliErr() << "bspot export: "
+                     << response->value(QStringLiteral("error")).toObject()
+                            .value(QStringLiteral("message")).toString()
+                     << Qt::endl;
+            return kCliExitUnavailable;
+        }
+
+        const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+        for (const QJsonValue& value : result.value(QStringLiteral("items")).toArray()) {
+            if (!write(ExportFormat::record(*format, value.toObject(), exported == 0))) {
+                writeFailed = true;
+                break;
+            }
+            ++exported;
+        }
+        cursor = result.value(QStringLiteral("nextCursor"));
+        if (!cursor.isObject()) {
+            break;
+        }
+    }
+    writeFailed = writeFailed || !write(ExportFormat::end(*format, exported == 0));
+
+    if (auto* saveFile = qobject_cast<QSaveFile*>(out.get())) {
+        if (writeFailed || !saveFile->commit()) {
+            cliErr() << "bspot export: cannot write " << outputPath << ": "
+                     << saveFile->errorString() << Qt::endl;
+            return kCliExitFailure;
+        }
+        cliErr() << "Exported " << exported << " item(s) to " << outputPath << Qt::endl;
+    } else if (writeFailed) {
+        cliErr() << "bspot export: write to standard output failed" << Qt::endl;
+        return kCliExitFailure;
+    } else {
+        out->close();
+    }
+    return exported > 0 ? kCliExitOk : kCliExitFailure;
+}
+
+} // namespace bs
@@ -0,0 +1,107 @@
+#include "cli/export_format.h"
+
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+QString csvValue(const QJsonObject& item, const QString& column)
+{
+    const QJsonValue value = item.value(column);
+    if (column == QLatin1String("matchedFields")) {
+        QStringList fields;
+        for (const QJsonValue& field : value.toArray()) {
+            fields.append(field.toString());
+        }
+        return fields.join(QLatin1Char(';'));
+    }
+    if (value.isDouble()) {
+        return QString::number(value.toInteger());
+    }
+    return value.toString();
+}
+
+} // namespace
+
+QStringList ExportFormat::columns()
+{
+    return {QStringLiteral("itemId"),  QStringLiteral("path"),     QStringLiteral("name"),
+            QStringLiteral("kind"),    QStringLiteral("size"),     QStringLiteral("created"),
+            QStringLiteral("modified"), QStringLiteral("indexed"), QStringLiteral("matchedFields")};
+}
+
+std::optional<ExportFileFormat> ExportFormat::parse(const QString& name)
+{
+    const QString lowered = name.trimmed().toLower();
+    if (lowered == QLatin1String("csv")) {
+        return ExportFileFormat::Csv;
+    }
+    if (lowered == QLatin1String("json")) {
+        return ExportFileFormat::Json;
+    }
+    if (lowered == QLatin1String("ndjson") || lowered == QLatin1String("jsonl")) {
+        return ExportFileFormat::Ndjson;
+    }
+    return std::nullopt;
+}
+
+std::optional<ExportFileFormat> ExportFormat::fromFileName(const QString& fileName)
+{
+    return parse(QFileInfo(fileName).suffix());
+}
+
+QByteArray ExportFormat::begin(ExportFileFormat format)
+{
+    switch (format) {
+    case ExportFileFormat::Csv:
+        return columns().join(QLatin1Char(',')).toUtf8() + "\r\n";
+    case ExportFileFormat::Json:
+        return "[";
+    case ExportFileFormat::Ndjson:
+        return {};
+    }
+    return {};
+}
+
+QByteArray ExportFormat::record(ExportFileFormat format, const QJsonObject& item, bool first)
+{
+    switch (format) {
+    case ExportFileFormat::Csv: {
+        QStringList cells;
+        for (const QString& column : columns()) {
+            cells.append(csvField(csvValue(item, column)));
+        }
+        return cells.join(QLatin1Char(',')).toUtf8() + "\r\n";
+    }
+    case ExportFileFormat::Json:
+        return (first ? QByteArray("\n  ") : QByteArray(",\n  "))
+            + QJsonDocument(item).toJson(QJsonDocument::Compact);
+    case ExportFileFormat::Ndjson:
+        return QJsonDocument(item).toJson(QJsonDocument::Compact) + '\n';
+    }
+    return {};
+}
+
+QByteArray ExportFormat::end(ExportFileFormat format, bool empty)
+{
+    if (format == ExportFileFormat::Json) {
+        return empty ? QByteArray("]\n") : QByteArray("\n]\n");
+    }
+    return {};
+}
+
+QString ExportFormat::csvField(const QString& value)
+{
+    if (!value.contains(QLatin1Char(',')) && !value.contains(QLatin1Char('"'))
+        && !value.contains(QLatin1Char('\n')) && !value.contains(QLatin1Char('\r'))) {
+        return value;
+    }
+    QString quoted = value;
+    quoted.replace(QLatin1Char('"'), QStringLiteral("\"\""));
+    return QLatin1Char('"') + quoted + QLatin1Char('"');
+}
+
+} // namespace bs
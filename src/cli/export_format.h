@@ -0,0 +1,37 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+enum class ExportFileFormat { Csv, Json, Ndjson };
+
+// ExportFormat — record encoding behind `bspot export`. Output is produced
+// piecewise (begin, one record per item, end) so an export is written as
+// the pages arrive instead of being assembled in memory.
+class ExportFormat {
+public:
+    // Fields of each record, in CSV column order.
+    static QStringList columns();
+
+    // "csv", "json", "ndjson" (or "jsonl"), case-insensitive.
+    static std::optional<ExportFileFormat> parse(const QString& name);
+    // From the extension of an output file name.
+    static std::optional<ExportFileFormat> fromFileName(const QString& fileName);
+
+    static QByteArray begin(ExportFileFormat format);
+    // `item` is one exportMatches item; `first` is true for the first
+    // record written.
+    static QByteArray record(ExportFileFormat format, const QJsonObject& item, bool first);
+    static QByteArray end(ExportFileFormat format, bool empty);
+
+    // RFC 4180 quoting: only fields holding a comma, quote or line break.
+    static QString csvField(const QString& value);
+};
+
+} // namespace bs
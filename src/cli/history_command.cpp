@@ -0,0 +1,144 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+constexpr int kDefaultLimit = 20;
+
+// "2026-10-12 10:14     6  quarterly report 2025" in local time.
+QString renderEntry(const QJsonObject& entry)
+{
+    const QDateTime lastUsed = QDateTime::fromString(
+        entry.value(QStringLiteral("lastUsedAt")).toString(), Qt::ISODate);
+    return QStringLiteral("%1  %2  %3")
+        .arg(lastUsed.toLocalTime().toString(QStringLiteral("yyyy-MM-dd HH:mm")),
+             QString::number(entry.value(QStringLiteral("hitCount")).toInt()).rightJustified(4),
+             entry.value(QStringLiteral("query")).toString());
+}
+
+} // namespace
+
+int runHistoryCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("List, search or purge the history of past searches. Private "
+                       "searches are never recorded.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    parser.addPositionalArgument(
+        QStringLiteral("text"),
+        QStringLiteral("Only queries containing this. With --purge, the query to delete."),
+        QStringLiteral("[text]"));
+    const QCommandLineOption frequentOption(
+        QStringLiteral("frequent"),
+        QStringLiteral("Most used first instead of most recent first."));
+    const QCommandLineOption limitOption(
+        QStringLiteral("limit"),
+        QStringLiteral("At most this many queries (default %1).").arg(kDefaultLimit),
+        QStringLiteral("n"), QString::number(kDefaultLimit));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw getQueryHistory result."));
+    const QCommandLineOption purgeOption(
+        QStringLiteral("purge"),
+        QStringLiteral("Delete the query given as text, or the whole history."));
+    const QCommandLineOption olderThanOption(
+        QStringLiteral("older-than"),
+        QStringLiteral("With --purge, only delete queries last used longer ago than this, "
+                       "e.g. 30d."),
+        QStringLiteral("duration"));
+    parser.addOptions({frequentOption, limitOption, jsonOption, purgeOption, olderThanOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot history"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot history: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const QStringList positional = parser.positionalArguments();
+    const QString text = positional.join(QLatin1Char(' ')).trimmed();
+
+    QString method;
+    QJsonObject params;
+    if (parser.isSet(purgeOption)) {
+        method = QStringLiteral("purgeQueryHistory");
+        if (parser.isSet(olderThanOption)) {
+            if (!text.isEmpty()) {
+                return usageError(QStringLiteral("--older-than cannot be combined with a query"));
+            }
+            const auto age = parseCliDuration(parser.value(olderThanOption));
+            if (!age.has_value()) {
+                return usageError(QStringLiteral("--older-than expects a duration like 30d"));
+            }
+            params[QStringLiteral("before")] =
+                static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0 - age.value();
+        } else if (!text.isEmpty()) {
+            params[QStringLiteral("query")] = text;
+        }
+    } else {
+        if (parser.isSet(olderThanOption)) {
+            return usageError(QStringLiteral("--older-than needs --purge"));
+        }
+        method = QStringLiteral("getQueryHistory");
+        if (!text.isEmpty()) {
+            params[QStringLiteral("query")] = text;
+        }
+        params[QStringLiteral("order")] = parser.isSet(frequentOption) ? QStringLiteral("frequent")
+                                                                       : QStringLiteral("recent");
+        bool ok = false;
+        params[QStringLiteral("limit")] = parser.value(limitOption).toInt(&ok);
+        if (!ok) {
+            return usageError(QStringLiteral("--limit must be a number"));
+        }
+    }
+
+    // Both methods are admin-only: the history holds every client's queries.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot history: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot history: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    if (parser.isSet(purgeOption)) {
+        cliOut() << "Removed " << result.value(QStringLiteral("removedEntries")).toInteger()
+                 << " queries from the history" << Qt::endl;
+        return kCliExitOk;
+    }
+
+    if (!result.value(QStringLiteral("enabled")).toBool()) {
+        cliErr() << "bspot history: query history is off; set a retention in Settings > Privacy"
+                 << Qt::endl;
+    }
+    for (const QJsonValue& entry : result.value(QStringLiteral("entries")).toArray()) {
+        cliOut() << renderEntry(entry.toObject()) << '\n';
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,183 @@
+#include "cli/cli_common.h"
+
+#include "core/index/sqlite_store.h"
+#include "core/indexing/portable_index.h"
+
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QProcess>
+#include <QTemporaryDir>
+
+#include <sqlite3.h>
+
+namespace bs {
+
+namespace {
+
+constexpr int kTarTimeoutMs = 10 * 60 * 1000;
+
+// At most `maxBytes` of `path`; nullopt when it cannot be read or is larger.
+std::optional<QByteArray> readFile(const QString& path, qint64 maxBytes)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly) || file.size() > maxBytes) {
+        return std::nullopt;
+    }
+    return file.readAll();
+}
+
+void removeDatabase(const QString& path)
+{
+    for (const char* suffix : {"", "-wal", "-shm", "-journal"}) {
+        QFile::remove(path + QLatin1String(suffix));
+    }
+}
+
+} // namespace
+
+int runImportCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Build an index file from a portable archive written by 'bspot export "
+                       "--portable'. Every segment is checked against the archive's manifest "
+                       "first. The result can be listed under teamIndexes in settings.json "
+                       "to search it next to this Mac's index, or, with the services "
+                       "stopped, replace index.db to move an index here.\n"
+                       "Exit status: 0 imported, 1 the archive is damaged or the index could "
+                       "not be written, 2 usage error."));
+    parser.addPositionalArgument(QStringLiteral("archive"),
+                                 QStringLiteral("A %1 file.").arg(QLatin1String(PortableIndex::kFileExtension)),
+                                 QStringLiteral("<archive>"));
+    const QCommandLineOption outputOption(
+        {QStringLiteral("o"), QStringLiteral("output")},
+        QStringLiteral("The index file to create. It only appears once the import is "
+                       "complete."),
+        QStringLiteral("file"));
+    const QCommandLineOption forceOption(QStringLiteral("force"),
+                                         QStringLiteral("Replace the output file if it exists."));
+    parser.addOptions({outputOption, forceOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot import"), args)) {
+        return exitCode.value();
+    }
+
+    const QString command = QStringLiteral("bspot import");
+    const auto usageError = [&command](const QString& message) {
+        cliErr() << command << ": " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const auto failure = [&command](const QString& message) {
+        cliErr() << command << ": " << message << Qt::endl;
+        return kCliExitFailure;
+    };
+
+    if (parser.positionalArguments().size() != 1) {
+        return usageError(QStringLiteral("expected one archive"));
+    }
+    const QString archivePath = QFileInfo(parser.positionalArguments().first()).absoluteFilePath();
+    if (!QFileInfo(archivePath).isFile()) {
+        return usageError(QStringLiteral("%1 does not exist").arg(archivePath));
+    }
+    if (!parser.isSet(outputOption)) {
+        return usageError(QStringLiteral("missing --output <file>"));
+    }
+    const QString outputPath = QFileInfo(parser.value(outputOption)).absoluteFilePath();
+    if (QFileInfo::exists(outputPath) && !parser.isSet(forceOption)) {
+        return usageError(QStringLiteral("%1 exists; --force replaces it").arg(outputPath));
+    }
+
+    QTemporaryDir staging;
+    if (!staging.isValid()) {
+        return failure(QStringLiteral("cannot create a staging directory"));
+    }
+    // Only the archive's own directory is unpacked; tar refuses absolute
+    // and ".." member names.
+    QProcess tar;
+    tar.start(QStringLiteral("tar"), {QStringLiteral("-xzf"), archivePath, QStringLiteral("-C"),
+                                      staging.path(), QLatin1String(PortableIndex::kRootDirectory)});
+    if (!tar.waitForFinished(kTarTimeoutMs) || tar.exitStatus() != QProcess::NormalExit
+        || tar.exitCode() != 0) {
+        return failure(QStringLiteral("%1 is not a portable index archive: %2")
+                           .arg(archivePath,
+                                QString::fromLocal8Bit(tar.readAllStandardError()).trimmed()));
+    }
+    const QDir root(QDir(staging.path()).filePath(QLatin1String(PortableIndex::kRootDirectory)));
+
+    const std::optional<QByteArray> manifestJson =
+        readFile(root.filePath(QLatin1String(PortableIndex::kManifestFile)),
+                 PortableIndex::kMaxManifestBytes);
+    if (!manifestJson.has_value()) {
+        return failure(QStringLiteral("the archive has no readable manifest"));
+    }
+    QString error;
+    const std::optional<PortableManifest> manifest =
+        PortableIndex::manifestFromJson(*manifestJson, &error);
+    if (!manifest.has_value()) {
+        return failure(error);
+    }
+
+    // Built next to the target and renamed, so a failed import never
+    // leaves a partial index behind.
+    const QString partialPath = outputPath + QStringLiteral(".importing");
+    removeDatabase(partialPath);
+    int64_t imported = 0;
+    {
+        std::optional<SQLiteStore> store = SQLiteStore::open(partialPath);
+        if (!store.has_value()) {
+            return failure(QStringLiteral("cannot create %1").arg(partialPath));
+        }
+        for (const SyncSegment& segment : manifest->segments) {
+            const std::optional<QByteArray> ndjson =
+                readFile(root.filePath(PortableIndex::segmentFile(segment.first)),
+                         PortableIndex::kMaxSegmentBytes);
+            const std::optional<std::vector<SyncedItem>> items =
+                ndjson.has_value()
+                    ? PortableIndex::segmentFromNdjson(*ndjson, segment, &error)
+                    : std::nullopt;
+            if (!items.has_value()) {
+                store.reset();
+                removeDatabase(partialPath);
+                return failure(ndjson.has_value()
+                                   ? error
+                                   : QStringLiteral("segment %1 is missing").arg(segment.first));
+            }
+            store->beginTransaction();
+            for (const SyncedItem& item : *items) {
+                if (!PortableIndex::importItem(*store, item)) {
+                    store->rollbackTransaction();
+                    store.reset();
+                    removeDatabase(partialPath);
+                    return failure(QStringLiteral("cannot write %1 to the index").arg(item.path));
+                }
+            }
+            store->commitTransaction();
+            imported += static_cast<int64_t>(items->size());
+        }
+        // One file, no WAL beside it: what a read-only reader on a shared
+        // volume can open.
+        sqlite3_exec(store->rawDb(), "PRAGMA journal_mode = DELETE", nullptr, nullptr, nullptr);
+    }
+
+    if (QFileInfo::exists(outputPath)) {
+        removeDatabase(outputPath);
+    }
+    if (!QFile::rename(partialPath, outputPath)) {
+        removeDatabase(partialPath);
+        return failure(QStringLiteral("cannot write %1").arg(outputPath));
+    }
+
+    cliOut() << "Imported " << imported << " item(s)";
+    if (!manifest->source.isEmpty()) {
+        cliOut() << " from " << manifest->source;
+    }
+    if (manifest->createdAt.isValid()) {
+        cliOut() << " (exported " << manifest->createdAt.toLocalTime().toString(Qt::ISODate) << ")";
+    }
+    cliOut() << " to " << outputPath << Qt::endl
+             << "Search it next to this index by listing it under teamIndexes in settings.json."
+             << Qt::endl;
+    return kCliExitOk;
+}
+
+} // namespace bs
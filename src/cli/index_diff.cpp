@@ -0,0 +1,230 @@
+#include "cli/index_diff.h"
+
+#include "core/index/sqlite_store.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QTimeZone>
+
+#include <algorithm>
+#include <limits>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxShownChars = 80;
+
+// Seconds since the epoch as UTC ISO 8601; empty for unset.
+QString formatTime(double secs)
+{
+    if (secs <= 0.0) {
+        return {};
+    }
+    return QDateTime::fromMSecsSinceEpoch(qRound64(secs * 1000.0), QTimeZone::UTC)
+        .toString(Qt::ISODateWithMs);
+}
+
+void setField(IndexSnapshotDocument& document, const QString& field, const QString& value)
+{
+    if (!value.isEmpty()) {
+        document.insert(field, value);
+    }
+}
+
+bool isIgnored(const QString& field, const QStringList& ignoredFields)
+{
+    for (const QString& ignored : ignoredFields) {
+        if (ignored.endsWith(QLatin1Char('*')) ? field.startsWith(ignored.chopped(1))
+                                               : field == ignored) {
+            return true;
+        }
+    }
+    return false;
+}
+
+QString shown(const QString& value)
+{
+    if (value.isEmpty()) {
+        return QStringLiteral("(none)");
+    }
+    QString line = value;
+    line.replace(QLatin1Char('\n'), QLatin1Char(' '));
+    return line.size() > kMaxShownChars ? line.left(kMaxShownChars - 3) + QStringLiteral("...")
+                                        : line;
+}
+
+// "  ... and 12 more" when `total` passes `limit`.
+QString moreLine(qsizetype total, int limit)
+{
+    return total > limit ? QStringLiteral("  ... and %1 more\n").arg(total - limit) : QString();
+}
+
+} // namespace
+
+QStringList IndexDiff::defaultIgnoredFields()
+{
+    return {QStringLiteral("indexedAt")};
+}
+
+IndexSnapshot IndexDiff::read(SQLiteStore& store, const QString& under)
+{
+    IndexSnapshot snapshot;
+    const std::vector<SQLiteStore::ItemRow> rows =
+        under.isEmpty() ? store.getItemsInIdRange(0, std::numeric_limits<int64_t>::max())
+                        : store.getItemsUnderPath(under);
+    for (const SQLiteStore::ItemRow& row : rows) {
+        IndexSnapshotDocument document;
+        setField(document, QStringLiteral("name"), row.name);
+        setField(document, QStringLiteral("kind"), row.kind);
+        document.insert(QStringLiteral("size"), QString::number(row.size));
+        setField(document, QStringLiteral("createdAt"), formatTime(row.createdAt));
+        setField(document, QStringLiteral("modifiedAt"), formatTime(row.modifiedAt));
+        setField(document, QStringLiteral("indexedAt"), formatTime(row.indexedAt));
+        setField(document, QStringLiteral("contentHash"), row.contentHash);
+        document.insert(QStringLiteral("pinned"),
+                        row.isPinned ? QStringLiteral("true") : QStringLiteral("false"));
+
+        if (const auto availability = store.getItemAvailability(row.id)) {
+            setField(document, QStringLiteral("availability"), availability->availabilityStatus);
+            setField(document, QStringLiteral("extractionError"),
+                     availability->lastExtractionError);
+        }
+
+        bool truncated = false;
+        const QString content = store.getItemContent(row.id, kMaxContentChars, &truncated);
+        if (!content.isEmpty()) {
+            const QByteArray digest =
+                QCryptographicHash::hash(content.toUtf8(), QCryptographicHash::Sha256).toHex();
+            document.insert(QLatin1String(kContentField),
+                            QStringLiteral("%1 chars, sha256 %2%3")
+                                .arg(QString::number(content.size()),
+                                     QString::fromLatin1(digest.left(16)),
+                                     truncated ? QStringLiteral(", truncated") : QString()));
+        }
+
+        QStringList tags = store.getItemTags(row.id);
+        tags.sort(Qt::CaseInsensitive);
+        setField(document, QStringLiteral("tags"), tags.join(QStringLiteral(", ")));
+
+        QMap<QString, QStringList> attributes;
+        for (const ItemAttribute& attribute : store.getItemAttributes(row.id)) {
+            attributes[QLatin1String(kAttributePrefix) + attribute.name].append(attribute.value);
+        }
+        for (auto it = attributes.cbegin(); it != attributes.cend(); ++it) {
+            QStringList values = it.value();
+            values.sort();
+            document.insert(it.key(), values.join(QStringLiteral("; ")));
+        }
+        snapshot.documents.insert(row.path, std::move(document));
+    }
+    return snapshot;
+}
+
+std::optional<IndexSnapshot> IndexDiff::load(const QString& dbPath, const QString& under,
+                                             QString* error)
+{
+    if (!QFileInfo(dbPath).isFile()) {
+        *error = QStringLiteral("%1 does not exist").arg(dbPath);
+        return std::nullopt;
+    }
+    std::optional<SQLiteStore> store = SQLiteStore::openReadOnly(dbPath);
+    if (!store.has_value()) {
+        *error = QStringLiteral("%1 is not an index of this schema version").arg(dbPath);
+        return std::nullopt;
+    }
+    IndexSnapshot snapshot = read(*store, under);
+    snapshot.source = dbPath;
+    return snapshot;
+}
+
+IndexDiffResult IndexDiff::diff(const IndexSnapshot& before, const IndexSnapshot& after,
+                                const QStringList& ignoredFields)
+{
+    IndexDiffResult result;
+    for (auto it = before.documents.cbegin(); it != before.documents.cend(); ++it) {
+        const auto other = after.documents.constFind(it.key());
+        if (other == after.documents.cend()) {
+            result.removed.append(it.key());
+            continue;
+        }
+        // Both documents' fields, in name order.
+        QStringList fields = it.value().keys() + other.value().keys();
+        fields.removeDuplicates();
+        std::sort(fields.begin(), fields.end());
+
+        IndexDocumentChange change;
+        change.path = it.key();
+        for (const QString& field : fields) {
+            const QString was = it.value().value(field);
+            const QString is = other.value().value(field);
+            if (was != is && !isIgnored(field, ignoredFields)) {
+                change.fields.push_back({field, was, is});
+            }
+        }
+        if (change.fields.empty()) {
+            ++result.unchanged;
+        } else {
+            result.changed.push_back(std::move(change));
+        }
+    }
+    for (auto it = after.documents.cbegin(); it != after.documents.cend(); ++it) {
+        if (!before.documents.contains(it.key())) {
+            result.added.append(it.key());
+        }
+    }
+    return result;
+}
+
+QString IndexDiff::renderText(const IndexDiffResult& result, int limit)
+{
+    QString out = QStringLiteral("%1 added, %2 removed, %3 changed, %4 unchanged\n")
+                      .arg(result.added.size())
+                      .arg(result.removed.size())
+                      .arg(static_cast<qint64>(result.changed.size()))
+                      .arg(result.unchanged);
+    for (qsizetype i = 0; i < result.added.size() && i < limit; ++i) {
+        out += QStringLiteral("+ ") + result.added.at(i) + QLatin1Char('\n');
+    }
+    out += moreLine(result.added.size(), limit);
+    for (qsizetype i = 0; i < result.removed.size() && i < limit; ++i) {
+        out += QStringLiteral("- ") + result.removed.at(i) + QLatin1Char('\n');
+    }
+    out += moreLine(result.removed.size(), limit);
+    for (size_t i = 0; i < result.changed.size() && i < static_cast<size_t>(limit); ++i) {
+        const IndexDocumentChange& change = result.changed[i];
+        out += QStringLiteral("~ ") + change.path + QLatin1Char('\n');
+        for (const IndexFieldChange& field : change.fields) {
+            out += QStringLiteral("    %1: %2 -> %3\n")
+                       .arg(field.field, shown(field.before), shown(field.after));
+        }
+    }
+    out += moreLine(static_cast<qsizetype>(result.changed.size()), limit);
+    return out;
+}
+
+QJsonObject IndexDiff::toJson(const IndexDiffResult& result)
+{
+    QJsonArray changed;
+    for (const IndexDocumentChange& change : result.changed) {
+        QJsonArray fields;
+        for (const IndexFieldChange& field : change.fields) {
+            fields.append(QJsonObject{{QStringLiteral("field"), field.field},
+                                      {QStringLiteral("before"), field.before},
+                                      {QStringLiteral("after"), field.after}});
+        }
+        changed.append(QJsonObject{{QStringLiteral("path"), change.path},
+                                   {QStringLiteral("fields"), fields}});
+    }
+    return {
+        {QStringLiteral("identical"), result.identical()},
+        {QStringLiteral("added"), QJsonArray::fromStringList(result.added)},
+        {QStringLiteral("removed"), QJsonArray::fromStringList(result.removed)},
+        {QStringLiteral("changed"), changed},
+        {QStringLiteral("unchanged"), static_cast<qint64>(result.unchanged)},
+    };
+}
+
+} // namespace bs
@@ -0,0 +1,85 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QMap>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+class SQLiteStore;
+
+// One indexed document as `bspot debug diff-index` compares it: each field
+// rendered as text. Extracted text is kept as its length and a digest.
+using IndexSnapshotDocument = QMap<QString, QString>;
+
+// Every document of one index, by path.
+struct IndexSnapshot {
+    QString source;
+    QMap<QString, IndexSnapshotDocument> documents;
+};
+
+struct IndexFieldChange {
+    QString field;
+    QString before;  // empty when the field was not set
+    QString after;
+};
+
+struct IndexDocumentChange {
+    QString path;
+    std::vector<IndexFieldChange> fields;
+};
+
+struct IndexDiffResult {
+    QStringList added;      // only in the second snapshot
+    QStringList removed;    // only in the first
+    std::vector<IndexDocumentChange> changed;
+    int64_t unchanged = 0;
+
+    bool identical() const { return added.isEmpty() && removed.isEmpty() && changed.empty(); }
+};
+
+// IndexDiff -- compares two index snapshots (copies of index.db) document
+// by document: what a reindex added, dropped and changed, down to the field
+// that changed. Documents are matched by path, since item ids are not
+// stable across indexes. No service is involved, so it can be unit tested
+// and run against indexes of another Mac.
+class IndexDiff {
+public:
+    // Fields compared: the item row's, the availability of its content,
+    // the extracted text, tags and every stored attribute as
+    // "attribute:<name>".
+    static constexpr const char* kContentField = "content";
+    static constexpr const char* kAttributePrefix = "attribute:";
+    // Extracted text past this is not compared.
+    static constexpr int kMaxContentChars = 16 * 1024 * 1024;
+
+    // Fields left out unless asked for: when a file was indexed changes on
+    // every reindex.
+    static QStringList defaultIgnoredFields();
+
+    // The documents of `store` at or below `under` (every document when
+    // empty).
+    static IndexSnapshot read(SQLiteStore& store, const QString& under = {});
+    // The same from an index file, opened read-only. Nullopt with *error
+    // when it is missing, not an index or of another schema version.
+    static std::optional<IndexSnapshot> load(const QString& dbPath, const QString& under,
+                                             QString* error);
+
+    // Documents in both, neither or one of the snapshots, in path order,
+    // leaving out `ignoredFields`.
+    static IndexDiffResult diff(const IndexSnapshot& before, const IndexSnapshot& after,
+                                const QStringList& ignoredFields = defaultIgnoredFields());
+
+    // A summary line, then "+ path", "- path" and "~ path" with one
+    // "    field: before -> after" line per changed field. At most `limit`
+    // documents of each kind are listed; the counts cover all of them.
+    static QString renderText(const IndexDiffResult& result, int limit);
+    static QJsonObject toJson(const IndexDiffResult& result);
+};
+
+} // namespace bs
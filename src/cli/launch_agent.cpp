@@ -0,0 +1,141 @@
+#include "cli/launch_agent.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QRegularExpression>
+
+namespace bs {
+
+namespace {
+
+QByteArray xmlEscaped(const QString& value)
+{
+    return value.toHtmlEscaped().toUtf8();
+}
+
+void appendStringKey(QByteArray& out, const char* key, const QString& value)
+{
+    out += "    <key>";
+    out += key;
+    out += "</key>\n    <string>" + xmlEscaped(value) + "</string>\n";
+}
+
+} // namespace
+
+QString LaunchAgent::defaultLabel()
+{
+    return QStringLiteral("com.betterspotlight.agent");
+}
+
+QString LaunchAgent::plistPath(const QString& label)
+{
+    return QDir::homePath() + QStringLiteral("/Library/LaunchAgents/") + label
+        + QStringLiteral(".plist");
+}
+
+QString LaunchAgent::logDirectory()
+{
+    return QDir::homePath() + QStringLiteral("/Library/Logs/BetterSpotlight");
+}
+
+QString LaunchAgent::locateAppExecutable(const QString& cliExecutablePath)
+{
+    const QString cliDir = QFileInfo(cliExecutablePath).absolutePath();
+    const QStringList candidates = {
+        // Release layout: BetterSpotlight.app/Contents/Helpers/bspot
+        cliDir + QStringLiteral("/../MacOS/betterspotlight"),
+        // CMake build tree: build/src/cli/bspot
+        cliDir + QStringLiteral("/../app/betterspotlight.app/Contents/MacOS/betterspotlight"),
+        QStringLiteral("/Applications/BetterSpotlight.app/Contents/MacOS/betterspotlight"),
+        QDir::homePath()
+            + QStringLiteral("/Applications/BetterSpotlight.app/Contents/MacOS/betterspotlight"),
+    };
+    for (const QString& candidate : candidates) {
+        const QFileInfo info(candidate);
+        if (info.exists() && info.isExecutable()) {
+            return info.canonicalFilePath();
+        }
+    }
+    return {};
+}
+
+QByteArray LaunchAgent::renderPlist(const LaunchAgentConfig& config)
+{
+    QByteArray out;
+    out += "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"
+           "<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" "
+           "\"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n"
+           "<plist version=\"1.0\">\n"
+           "<dict>\n";
+    appendStringKey(out, "Label", config.label);
+
+    out += "    <key>ProgramArguments</key>\n    <array>\n";
+    out += "        <string>" + xmlEscaped(config.programPath) + "</string>\n";
+    for (const QString& argument : config.arguments) {
+        out += "        <string>" + xmlEscaped(argument) + "</string>\n";
+    }
+    out += "    </array>\n";
+
+    out += "    <key>RunAtLoad</key>\n    <true/>\n";
+    out += "    <key>KeepAlive</key>\n    <dict>\n"
+           "        <key>SuccessfulExit</key>\n        <false/>\n"
+           "    </dict>\n";
+    out += "    <key>ThrottleInterval</key>\n    <integer>"
+        + QByteArray::number(config.throttleIntervalSecs) + "</integer>\n";
+    out += "    <key>ProcessType</key>\n    <string>Interactive</string>\n";
+    if (!config.stdoutPath.isEmpty()) {
+        appendStringKey(out, "StandardOutPath", config.stdoutPath);
+    }
+    if (!config.stderrPath.isEmpty()) {
+        appendStringKey(out, "StandardErrorPath", config.stderrPath);
+    }
+
+    out += "</dict>\n</plist>\n";
+    return out;
+}
+
+LaunchAgentStatus LaunchAgent::parseLaunchctlPrint(const QString& output)
+{
+    LaunchAgentStatus status;
+    if (output.trimmed().isEmpty()) {
+        return status;
+    }
+    status.loaded = true;
+
+    // Nested sections repeat keys like "state"; the job's own values come first.
+    static const QRegularExpression lineRegex(
+        QStringLiteral(R"(^\s*([a-z ]+?)\s*=\s*(.*?)\s*$)"));
+    bool sawState = false;
+    bool sawPid = false;
+    bool sawRuns = false;
+    bool sawExit = false;
+    const QStringList lines = output.split(QLatin1Char('\n'));
+    for (const QString& line : lines) {
+        const QRegularExpressionMatch match = lineRegex.match(line);
+        if (!match.hasMatch()) {
+            continue;
+        }
+        const QString key = match.captured(1);
+        const QString value = match.captured(2);
+        if (key == QLatin1String("state") && !sawState) {
+            status.state = value;
+            sawState = true;
+        } else if (key == QLatin1String("pid") && !sawPid) {
+            status.pid = value.toLongLong();
+            sawPid = true;
+        } else if (key == QLatin1String("runs") && !sawRuns) {
+            status.runs = value.toInt();
+            sawRuns = true;
+        } else if (key == QLatin1String("last exit code") && !sawExit) {
+            bool ok = false;
+            const int code = value.toInt(&ok);
+            if (ok) {
+                status.lastExitCode = code;
+            }
+            sawExit = true;
+        }
+    }
+    return status;
+}
+
+} // namespace bs
@@ -0,0 +1,51 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// LaunchAgent — per-user launchd job that keeps BetterSpotlight (and with it
+// the supervised indexer/extractor/query services) running across logins and
+// crashes. Pure helpers live here so they can be unit tested; `bspot service`
+// drives launchctl on top of them.
+
+struct LaunchAgentConfig {
+    QString label;
+    QString programPath;      // BetterSpotlight.app/Contents/MacOS/betterspotlight
+    QStringList arguments;    // extra argv after programPath
+    QString stdoutPath;
+    QString stderrPath;
+    int throttleIntervalSecs = 10;
+};
+
+struct LaunchAgentStatus {
+    bool loaded = false;
+    QString state;            // launchd job state, e.g. "running", "not running"
+    qint64 pid = 0;
+    int runs = 0;
+    std::optional<int> lastExitCode;
+};
+
+class LaunchAgent {
+public:
+    static QString defaultLabel();
+    static QString plistPath(const QString& label);
+    static QString logDirectory();
+
+    // Resolve the app executable relative to the CLI binary (bundle Helpers/
+    // or a build tree), falling back to /Applications.
+    static QString locateAppExecutable(const QString& cliExecutablePath);
+
+    // RunAtLoad + KeepAlive{SuccessfulExit=false}: launchd restarts the app
+    // after a crash but leaves it stopped after a clean quit.
+    static QByteArray renderPlist(const LaunchAgentConfig& config);
+
+    // Parse `launchctl print gui/<uid>/<label>` output.
+    static LaunchAgentStatus parseLaunchctlPrint(const QString& output);
+};
+
+} // namespace bs
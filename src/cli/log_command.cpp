@@ -0,0 +1,153 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+#include "core/shared/logging.h"
+
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kTimeoutMs = 3000;
+
+const QStringList kServices = {
+    QStringLiteral("indexer"),
+    QStringLiteral("extractor"),
+    QStringLiteral("query"),
+    QStringLiteral("inference"),
+};
+
+QString renderLevels(const QString& service, const QJsonObject& levels)
+{
+    const QJsonValue file = levels.value(QStringLiteral("file"));
+    QString out = QStringLiteral("%1: %2 (%3, %4)\n")
+        .arg(service, levels.value(QStringLiteral("default")).toString(),
+             levels.value(QStringLiteral("format")).toString(),
+             file.isString() ? file.toString() : QStringLiteral("stderr only"));
+    const QJsonObject modules = levels.value(QStringLiteral("modules")).toObject();
+    for (auto it = modules.begin(); it != modules.end(); ++it) {
+        const QJsonObject module = it.value().toObject();
+        if (module.value(QStringLiteral("override")).toBool()) {
+            out += QStringLiteral("  %1 = %2\n")
+                       .arg(it.key(), module.value(QStringLiteral("level")).toString());
+        }
+    }
+    return out;
+}
+
+} // namespace
+
+int runLogCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Show or change service log levels while they run. With no arguments, "
+                       "print each service's default level and module overrides. "
+                       "'bspot log extraction debug' turns one module up; "
+                       "'bspot log extraction default' drops the override; "
+                       "'bspot log default warning' sets the default. Changes last until "
+                       "the service restarts; BETTERSPOTLIGHT_LOG_MODULES sets them at start.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 no service reached or a change "
+                       "failed."));
+    parser.addPositionalArgument(QStringLiteral("module level"),
+                                 QStringLiteral("Module (index, extraction, ipc, ...) or "
+                                                "'default', then debug, info, warning, "
+                                                "critical, or 'default' to drop an override."),
+                                 QStringLiteral("[<module> <level>]"));
+    const QCommandLineOption serviceOption(
+        QStringLiteral("service"),
+        QStringLiteral("Only this service (%1). Repeatable; default all.")
+            .arg(kServices.join(QStringLiteral(", "))),
+        QStringLiteral("name"));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw getLogLevels results."));
+    parser.addOptions({serviceOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot log"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot log: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+
+    QStringList services = parser.values(serviceOption);
+    for (const QString& service : services) {
+        if (!kServices.contains(service)) {
+            return usageError(QStringLiteral("unknown service '%1'").arg(service));
+        }
+    }
+    if (services.isEmpty()) {
+        services = kServices;
+    }
+
+    const QStringList positional = parser.positionalArguments();
+    if (positional.size() == 1 || positional.size() > 2) {
+        return usageError(QStringLiteral("expected <module> <level>, or nothing to show levels"));
+    }
+
+    QString method = QStringLiteral("getLogLevels");
+    QJsonObject params;
+    if (positional.size() == 2) {
+        const QString module = positional.at(0);
+        const QString level = positional.at(1);
+        const bool isDefault = module.compare(QLatin1String("default"), Qt::CaseInsensitive) == 0;
+        if (!isDefault && !Logging::moduleName(module).has_value()) {
+            return usageError(QStringLiteral("unknown module '%1'").arg(module));
+        }
+        const bool reset = level.compare(QLatin1String("default"), Qt::CaseInsensitive) == 0;
+        if (!Logging::parseLevel(level).has_value() && !(reset && !isDefault)) {
+            return usageError(QStringLiteral("unknown level '%1'").arg(level));
+        }
+        method = QStringLiteral("setLogLevel");
+        if (!isDefault) {
+            params[QStringLiteral("module")] = Logging::moduleName(module).value();
+        }
+        params[QStringLiteral("level")] = level.toLower();
+        // setLogLevel is admin-only, like every other state change.
+        SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    }
+
+    int reached = 0;
+    bool failed = false;
+    QJsonObject rawResults;
+    for (const QString& service : services) {
+        QString error;
+        const auto response = callService(service, method, params, kTimeoutMs, &error);
+        if (!response.has_value()) {
+            // Services that are not running (inference often is not) are skipped.
+            if (parser.isSet(serviceOption)) {
+                cliErr() << "bspot log: " << error << Qt::endl;
+            }
+            continue;
+        }
+        ++reached;
+        if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            cliErr() << "bspot log: " << service << ": "
+                     << response->value(QStringLiteral("error")).toObject()
+                            .value(QStringLiteral("message")).toString()
+                     << Qt::endl;
+            failed = true;
+            continue;
+        }
+        const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+        rawResults[service] = result;
+        if (!parser.isSet(jsonOption)) {
+            cliOut() << renderLevels(service, result);
+        }
+    }
+
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(rawResults).toJson(QJsonDocument::Indented);
+    }
+    cliOut().flush();
+    if (reached == 0) {
+        cliErr() << "bspot log: no service is running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    return failed ? kCliExitUnavailable : kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,85 @@
+#include "cli/cli_common.h"
+
+#include <QCoreApplication>
+
+namespace {
+
+void printUsage(QTextStream& stream)
+{
+    stream << "Usage: bspot <command> [options]\n"
+              "\n"
+              "Commands:\n"
+              "  search     Search the index (columns, --json or --ndjson)\n"
+              "  export     Write every match to a CSV, JSON or NDJSON file\n"
+              "  import     Build an index file from a portable export\n"
+              "  tui        Interactive search in the terminal\n"
+              "  status     Indexing progress, throughput and ETA\n"
+              "  stats      Index size by table, field, file type and directory\n"
+              "  reindex    Re-extract a file or folder without a full rebuild\n"
+              "  purge      Remove and verify what is left of a deleted or excluded path\n"
+              "  audit      Review or purge the opt-in query and admin audit log\n"
+              "  history    List, search or purge past searches\n"
+              "  abbrev     List, define or remove search abbreviations\n"
+              "  scope      List, define or remove named search scopes\n"
+              "  log        Show or change service log levels per module\n"
+              "  debug      Diagnostics: slow searches, crash report bundles, index diffs\n"
+              "  token      Issue, list or revoke scoped API tokens\n"
+              "  doctor     Diagnose common setup problems and print fixes\n"
+              "  complete   Stream matching paths for shell completion and fzf\n"
+              "  bench      Benchmark indexing and queries on a generated corpus\n"
+              "  service    Manage the BetterSpotlight LaunchAgent\n"
+              "  mcp        Serve search as MCP tools over stdio\n"
+              "\n"
+              "Run 'bspot <command> --help' for command options.\n"
+           << Qt::flush;
+}
+
+} // namespace
+
+int main(int argc, char* argv[])
+{
+    QCoreApplication app(argc, argv);
+    app.setApplicationName(QStringLiteral("bspot"));
+    app.setApplicationVersion(QStringLiteral("0.1.0"));
+
+    QStringList args = app.arguments().mid(1);
+    if (args.isEmpty()) {
+        printUsage(bs::cliErr());
+        return bs::kCliExitUsage;
+    }
+
+    const QString command = args.takeFirst();
+    if (command == QLatin1String("search"))  return bs::runSearchCommand(args);
+    if (command == QLatin1String("export"))  return bs::runExportCommand(args);
+    if (command == QLatin1String("import"))  return bs::runImportCommand(args);
+    if (command == QLatin1String("tui"))     return bs::runTuiCommand(args);
+    if (command == QLatin1String("status"))  return bs::runStatusCommand(args);
+    if (command == QLatin1String("stats"))   return bs::runStatsCommand(args);
+    if (command == QLatin1String("reindex")) return bs::runReindexCommand(args);
+    if (command == QLatin1String("purge"))   return bs::runPurgeCommand(args);
+    if (command == QLatin1String("audit"))   return bs::runAuditCommand(args);
+    if (command == QLatin1String("history")) return bs::runHistoryCommand(args);
+    if (command == QLatin1String("abbrev"))  return bs::runAbbrevCommand(args);
+    if (command == QLatin1String("scope"))   return bs::runScopeCommand(args);
+    if (command == QLatin1String("log"))     return bs::runLogCommand(args);
+    if (command == QLatin1String("debug"))   return bs::runDebugCommand(args);
+    if (command == QLatin1String("token"))   return bs::runTokenCommand(args);
+    if (command == QLatin1String("doctor"))  return bs::runDoctorCommand(args);
+    if (command == QLatin1String("complete")) return bs::runCompleteCommand(args);
+    if (command == QLatin1String("bench"))   return bs::runBenchCommand(args);
+    if (command == QLatin1String("service")) return bs::runServiceCommand(args);
+    if (command == QLatin1String("mcp"))     return bs::runMcpCommand(args);
+
+    if (command == QLatin1String("--help") || command == QLatin1String("-h")
+        || command == QLatin1String("help")) {
+        printUsage(bs::cliOut());
+        return bs::kCliExitOk;
+    }
+    if (command == QLatin1String("--version")) {
+        bs::cliOut() << "bspot " << app.applicationVersion() << Qt::endl;
+        return bs::kCliExitOk;
+    }
+    bs::cliErr() << "bspot: unknown command '" << command << "'\n";
+    printUsage(bs::cliErr());
+    return bs::kCliExitUsage;
+}
@@ -0,0 +1,72 @@
+#include "cli/cli_common.h"
+#include "cli/mcp_server.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QFile>
+#include <QJsonDocument>
+
+#include <cstdio>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 2000;
+constexpr int kToolTimeoutMs = 30000;
+
+} // namespace
+
+int runMcpCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Serve BetterSpotlight search as Model Context Protocol tools over "
+                       "stdio (newline-delimited JSON-RPC). Logs go to stderr."));
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot mcp"), args)) {
+        return exitCode.value();
+    }
+
+    // One connection for the session; re-dial once if the service restarted.
+    SocketClient client;
+    const QString socketPath = ServiceBase::socketPath(QStringLiteral("query"));
+    McpServer server([&client, &socketPath](const QString& method, const QJsonObject& params,
+                                            QString* error) -> std::optional<QJsonObject> {
+        for (int attempt = 0; attempt < 2; ++attempt) {
+            if (!client.isConnected() && !client.connectToServer(socketPath, kConnectTimeoutMs)) {
+                continue;
+            }
+            if (auto response = client.sendRequest(method, params, kToolTimeoutMs)) {
+                return response;
+            }
+            client.disconnect();
+        }
+        *error = QStringLiteral("BetterSpotlight query service is not running");
+        return std::nullopt;
+    });
+
+    QFile input;
+    QFile output;
+    if (!input.open(stdin, QIODevice::ReadOnly) || !output.open(stdout, QIODevice::WriteOnly)) {
+        cliErr() << "bspot mcp: failed to open stdio" << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    while (true) {
+        const QByteArray line = input.readLine();
+        if (line.isEmpty() && input.atEnd()) {
+            break;
+        }
+        const auto response = server.handleLine(line);
+        if (!response.has_value()) {
+            continue;
+        }
+        output.write(QJsonDocument(response.value()).toJson(QJsonDocument::Compact));
+        output.write("\n");
+        output.flush();
+    }
+    return kCliExitOk;
+}
+
+} // namespace bs
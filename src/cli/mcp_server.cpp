@@ -0,0 +1,312 @@
+#include "cli/mcp_server.h"
+
+#include <QCoreApplication>
+#include <QJsonDocument>
+#include <QJsonParseError>
+
+#include <algorithm>
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+constexpr int kParseError = -32700;
+constexpr int kInvalidRequest = -32600;
+constexpr int kMethodNotFound = -32601;
+constexpr int kInvalidParams = -32602;
+
+// Revisions whose tools/list and tools/call shapes match what we send:
+// text content only, no structuredContent (added in 2025-06-18).
+bool isSupportedProtocolVersion(const QString& version)
+{
+    return version == QLatin1String(McpServer::kProtocolVersion)
+        || version == QLatin1String("2025-03-26");
+}
+
+QJsonObject rpcResult(const QJsonValue& id, const QJsonObject& result)
+{
+    QJsonObject response;
+    response[QStringLiteral("jsonrpc")] = QStringLiteral("2.0");
+    response[QStringLiteral("id")] = id;
+    response[QStringLiteral("result")] = result;
+    return response;
+}
+
+QJsonObject rpcError(const QJsonValue& id, int code, const QString& message)
+{
+    QJsonObject error;
+    error[QStringLiteral("code")] = code;
+    error[QStringLiteral("message")] = message;
+
+    QJsonObject response;
+    response[QStringLiteral("jsonrpc")] = QStringLiteral("2.0");
+    response[QStringLiteral("id")] = id;
+    response[QStringLiteral("error")] = error;
+    return response;
+}
+
+// MCP reports tool failures in-band so the model can see and react to them.
+QJsonObject toolText(const QString& text, bool isError)
+{
+    QJsonObject content;
+    content[QStringLiteral("type")] = QStringLiteral("text");
+    content[QStringLiteral("text")] = text;
+
+    QJsonObject result;
+    result[QStringLiteral("content")] = QJsonArray{content};
+    result[QStringLiteral("isError")] = isError;
+    return result;
+}
+
+QJsonObject toolJson(const QJsonObject& payload)
+{
+    return toolText(
+        QString::fromUtf8(QJsonDocument(payload).toJson(QJsonDocument::Indented)), false);
+}
+
+QJsonObject schemaProperty(const QString& type, const QString& description)
+{
+    QJsonObject property;
+    property[QStringLiteral("type")] = type;
+    property[QStringLiteral("description")] = description;
+    return property;
+}
+
+QJsonObject toolDefinition(const QString& name, const QString& description,
+                           const QJsonObject& properties, const QJsonArray& required)
+{
+    QJsonObject schema;
+    schema[QStringLiteral("type")] = QStringLiteral("object");
+    schema[QStringLiteral("properties")] = properties;
+    if (!required.isEmpty()) {
+        schema[QStringLiteral("required")] = required;
+    }
+
+    QJsonObject tool;
+    tool[QStringLiteral("name")] = name;
+    tool[QStringLiteral("description")] = description;
+    tool[QStringLiteral("inputSchema")] = schema;
+    return tool;
+}
+
+// Copy itemId/path from tool arguments into IPC params.
+bool copyDocumentSelector(const QJsonObject& arguments, QJsonObject& params)
+{
+    if (arguments.contains(QStringLiteral("itemId"))) {
+        params[QStringLiteral("itemId")] = arguments.value(QStringLiteral("itemId")).toInteger();
+        return true;
+    }
+    const QString path = arguments.value(QStringLiteral("path")).toString();
+    if (!path.isEmpty()) {
+        params[QStringLiteral("path")] = path;
+        return true;
+    }
+    return false;
+}
+
+} // namespace
+
+McpServer::McpServer(ServiceCall serviceCall)
+    : m_serviceCall(std::move(serviceCall))
+{
+}
+
+QJsonArray McpServer::toolDefinitions()
+{
+    const QJsonObject itemIdProperty =
+        schemaProperty(QStringLiteral("integer"), QStringLiteral("Document id from a search result."));
+    const QJsonObject pathProperty =
+        schemaProperty(QStringLiteral("string"), QStringLiteral("Absolute file path."));
+
+    QJsonArray tools;
+    tools.append(toolDefinition(
+        QStringLiteral("search"),
+        QStringLiteral("Search the local BetterSpotlight index of files by name and content. "
+                       "Returns ranked documents with snippets."),
+        QJsonObject{
+            {QStringLiteral("query"),
+             schemaProperty(QStringLiteral("string"), QStringLiteral("Search text."))},
+            {QStringLiteral("limit"),
+             schemaProperty(QStringLiteral("integer"),
+                            QStringLiteral("Maximum results (1-50, default 10)."))},
+        },
+        QJsonArray{QStringLiteral("query")}));
+    tools.append(toolDefinition(
+        QStringLiteral("fetch_document"),
+        QStringLiteral("Fetch metadata and extracted text of an indexed document by itemId or path."),
+        QJsonObject{
+            {QStringLiteral("itemId"), itemIdProperty},
+            {QStringLiteral("path"), pathProperty},
+            {QStringLiteral("maxChars"),
+             schemaProperty(QStringLiteral("integer"),
+                            QStringLiteral("Maximum characters of text (default 20000)."))},
+        },
+        QJsonArray{}));
+    tools.append(toolDefinition(
+        QStringLiteral("similar_documents"),
+        QStringLiteral("Find documents semantically similar to an indexed document. "
+                       "Requires the semantic index."),
+        QJsonObject{
+            {QStringLiteral("itemId"), itemIdProperty},
+            {QStringLiteral("path"), pathProperty},
+            {QStringLiteral("limit"),
+             schemaProperty(QStringLiteral("integer"),
+                            QStringLiteral("Maximum results (1-50, default 10)."))},
+        },
+        QJsonArray{}));
+    return tools;
+}
+
+std::optional<QJsonObject> McpServer::handleLine(const QByteArray& line)
+{
+    if (line.trimmed().isEmpty()) {
+        return std::nullopt;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument doc = QJsonDocument::fromJson(line, &parseError);
+    if (parseError.error != QJsonParseError::NoError) {
+        return rpcError(QJsonValue::Null, kParseError, parseError.errorString());
+    }
+    if (!doc.isObject()) {
+        // Batches were removed from MCP; only single messages are accepted.
+        return rpcError(QJsonValue::Null, kInvalidRequest,
+                        QStringLiteral("Expected a JSON-RPC object"));
+    }
+    return handleMessage(doc.object());
+}
+
+std::optional<QJsonObject> McpServer::handleMessage(const QJsonObject& message)
+{
+    const QString method = message.value(QStringLiteral("method")).toString();
+    const bool isNotification = !message.contains(QStringLiteral("id"));
+    const QJsonValue id = message.value(QStringLiteral("id"));
+    const QJsonObject params = message.value(QStringLiteral("params")).toObject();
+
+    if (isNotification) {
+        // notifications/initialized, notifications/cancelled: nothing to do.
+        return std::nullopt;
+    }
+    if (method.isEmpty()) {
+        return rpcError(id, kInvalidRequest, QStringLiteral("Missing method"));
+    }
+
+    if (method == QLatin1String("initialize")) return handleInitialize(id, params);
+    if (method == QLatin1String("ping"))       return rpcResult(id, QJsonObject());
+    if (method == QLatin1String("tools/list")) {
+        QJsonObject result;
+        result[QStringLiteral("tools")] = toolDefinitions();
+        return rpcResult(id, result);
+    }
+    if (method == QLatin1String("tools/call")) return handleToolCall(id, params);
+
+    return rpcError(id, kMethodNotFound, QStringLiteral("Unknown method: %1").arg(method));
+}
+
+QJsonObject McpServer::handleInitialize(const QJsonValue& id, const QJsonObject& params)
+{
+    // Echo the client's version when we speak it; otherwise offer ours and
+    // let the client decide whether to disconnect.
+    QString protocolVersion = params.value(QStringLiteral("protocolVersion")).toString();
+    if (!isSupportedProtocolVersion(protocolVersion)) {
+        protocolVersion = QString::fromLatin1(kProtocolVersion);
+    }
+
+    QJsonObject capabilities;
+    capabilities[QStringLiteral("tools")] = QJsonObject();
+
+    QJsonObject serverInfo;
+    serverInfo[QStringLiteral("name")] = QStringLiteral("betterspotlight");
+    serverInfo[QStringLiteral("version")] = QCoreApplication::applicationVersion();
+
+    QJsonObject result;
+    result[QStringLiteral("protocolVersion")] = protocolVersion;
+    result[QStringLiteral("capabilities")] = capabilities;
+    result[QStringLiteral("serverInfo")] = serverInfo;
+    return rpcResult(id, result);
+}
+
+QJsonObject McpServer::handleToolCall(const QJsonValue& id, const QJsonObject& params)
+{
+    const QString name = params.value(QStringLiteral("name")).toString();
+    bool known = false;
+    for (const QJsonValue& tool : toolDefinitions()) {
+        if (tool.toObject().value(QStringLiteral("name")).toString() == name) {
+            known = true;
+            break;
+        }
+    }
+    if (!known) {
+        return rpcError(id, kInvalidParams, QStringLiteral("Unknown tool: %1").arg(name));
+    }
+    return rpcResult(id, callTool(name, params.value(QStringLiteral("arguments")).toObject()));
+}
+
+QJsonObject McpServer::callTool(const QString& name, const QJsonObject& arguments)
+{
+    QString ipcMethod;
+    QJsonObject ipcParams;
+    if (name == QLatin1String("search")) {
+        const QString query = arguments.value(QStringLiteral("query")).toString().trimmed();
+        if (query.isEmpty()) {
+            return toolText(QStringLiteral("'query' is required"), true);
+        }
+        ipcMethod = QStringLiteral("search");
+        ipcParams[QStringLiteral("query")] = query;
+        ipcParams[QStringLiteral("limit")] =
+            std::clamp(arguments.value(QStringLiteral("limit")).toInt(10), 1, 50);
+    } else {
+        if (!copyDocumentSelector(arguments, ipcParams)) {
+            return toolText(QStringLiteral("'itemId' or 'path' is required"), true);
+        }
+        if (name == QLatin1String("fetch_document")) {
+            ipcMethod = QStringLiteral("getDocument");
+            ipcParams[QStringLiteral("includeContent")] = true;
+            ipcParams[QStringLiteral("maxContentChars")] =
+                std::clamp(arguments.value(QStringLiteral("maxChars")).toInt(20000), 1, 200000);
+        } else {
+            ipcMethod = QStringLiteral("findSimilar");
+            ipcParams[QStringLiteral("limit")] =
+                std::clamp(arguments.value(QStringLiteral("limit")).toInt(10), 1, 50);
+        }
+    }
+
+    QString transportError;
+    const auto response = m_serviceCall(ipcMethod, ipcParams, &transportError);
+    if (!response.has_value()) {
+        return toolText(transportError.isEmpty()
+                            ? QStringLiteral("BetterSpotlight query service is unavailable")
+                            : transportError,
+                        true);
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return toolText(response->value(QStringLiteral("error")).toObject()
+                            .value(QStringLiteral("message")).toString(),
+                        true);
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (name != QLatin1String("search")) {
+        return toolJson(result);
+    }
+
+    // Trim ranking internals; agents need identity, location and a snippet.
+    QJsonArray documents;
+    for (const QJsonValue& value : result.value(QStringLiteral("results")).toArray()) {
+        const QJsonObject hit = value.toObject();
+        QJsonObject document;
+        for (const char* key : {"itemId", "name", "path", "kind", "snippet", "score"}) {
+            const QString field = QString::fromLatin1(key);
+            if (hit.contains(field)) {
+                document[field] = hit.value(field);
+            }
+        }
+        documents.append(document);
+    }
+    QJsonObject payload;
+    payload[QStringLiteral("query")] = ipcParams.value(QStringLiteral("query"));
+    payload[QStringLiteral("results")] = documents;
+    return toolJson(payload);
+}
+
+} // namespace bs
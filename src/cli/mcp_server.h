@@ -0,0 +1,45 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <functional>
+#include <optional>
+
+namespace bs {
+
+// McpServer — Model Context Protocol (JSON-RPC 2.0) front for QueryService.
+//
+// Exposes `search`, `fetch_document` and `similar_documents` as MCP tools.
+// Transport-agnostic: `bspot mcp` feeds it newline-delimited messages from
+// stdin; tests drive handleMessage() directly with a fake service call.
+class McpServer {
+public:
+    static constexpr const char* kProtocolVersion = "2024-11-05";
+
+    // Send one request to QueryService. Returns the IPC envelope (response
+    // or error), or nullopt with *error set when the service is unreachable.
+    using ServiceCall = std::function<std::optional<QJsonObject>(
+        const QString& method, const QJsonObject& params, QString* error)>;
+
+    explicit McpServer(ServiceCall serviceCall);
+
+    // Handle one JSON-RPC message. Returns the response to write, or nullopt
+    // for notifications.
+    std::optional<QJsonObject> handleMessage(const QJsonObject& message);
+
+    // Handle one raw line; parse failures produce a JSON-RPC parse error.
+    std::optional<QJsonObject> handleLine(const QByteArray& line);
+
+    static QJsonArray toolDefinitions();
+
+private:
+    QJsonObject handleInitialize(const QJsonValue& id, const QJsonObject& params);
+    QJsonObject handleToolCall(const QJsonValue& id, const QJsonObject& params);
+    QJsonObject callTool(const QString& name, const QJsonObject& arguments);
+
+    ServiceCall m_serviceCall;
+};
+
+} // namespace bs
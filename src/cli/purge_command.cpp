@@ -0,0 +1,123 @@
+#include "cli/cli_common.h"
+#include "cli/purge_format.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDir>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kVerifyTimeoutMs = 10000;
+// purgePath compacts the whole index; VACUUM of a large one takes minutes.
+constexpr int kPurgeTimeoutMs = 10 * 60 * 1000;
+
+QString absolutePath(const QString& argument)
+{
+    if (argument == QLatin1String("~") || argument.startsWith(QLatin1String("~/"))) {
+        return QDir::cleanPath(QDir::homePath() + argument.mid(1));
+    }
+    return QDir::cleanPath(QDir::current().absoluteFilePath(argument));
+}
+
+// The result object, or nullopt with *error set.
+std::optional<QJsonObject> callForResult(const QString& service, const QString& method,
+                                         const QJsonObject& params, int timeoutMs,
+                                         QString* error)
+{
+    const auto response = callService(service, method, params, timeoutMs, error);
+    if (!response.has_value()) {
+        return std::nullopt;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        *error = response->value(QStringLiteral("error")).toObject()
+                     .value(QStringLiteral("message")).toString();
+        return std::nullopt;
+    }
+    return response->value(QStringLiteral("result")).toObject();
+}
+
+} // namespace
+
+int runPurgeCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Remove what the index still holds for a deleted or excluded file or "
+                       "folder, compact the index so it is overwritten on disk, and check "
+                       "that nothing remains. With --verify, only check.\n"
+                       "Exit status: 0 nothing remains, 1 something remains, 2 usage error, "
+                       "3 service unavailable or failed."));
+    parser.addPositionalArgument(QStringLiteral("path"), QStringLiteral("File or folder."),
+                                 QStringLiteral("<path>"));
+    const QCommandLineOption verifyOption(
+        QStringLiteral("verify"),
+        QStringLiteral("Only check that nothing remains; delete and compact nothing."));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw service results."));
+    parser.addOptions({verifyOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot purge"), args)) {
+        return exitCode.value();
+    }
+    if (parser.positionalArguments().size() != 1) {
+        cliErr() << "bspot purge: expected exactly one <path>" << Qt::endl;
+        return kCliExitUsage;
+    }
+    const QString path = absolutePath(parser.positionalArguments().first());
+    const bool verifyOnly = parser.isSet(verifyOption);
+
+    // purgePath, purgePrivacyPaths and purgeOrphans are admin methods.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+
+    QJsonObject params;
+    params[QStringLiteral("path")] = path;
+    QString error;
+    const auto index = verifyOnly
+        ? callForResult(QStringLiteral("indexer"), QStringLiteral("verifyPurged"), params,
+                        kVerifyTimeoutMs, &error)
+        : callForResult(QStringLiteral("indexer"), QStringLiteral("purgePath"), params,
+                        kPurgeTimeoutMs, &error);
+    if (!index.has_value()) {
+        cliErr() << "bspot purge: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    // Embeddings, cached results and thumbnails live in the query service.
+    QJsonObject sweepParams;
+    QString sweepMethod;
+    if (verifyOnly) {
+        sweepMethod = QStringLiteral("purgeOrphans");
+        sweepParams[QStringLiteral("dryRun")] = true;
+    } else {
+        sweepMethod = QStringLiteral("purgePrivacyPaths");
+        sweepParams[QStringLiteral("paths")] = QJsonArray{path};
+    }
+    QString sweepError;
+    const auto vectors = callForResult(QStringLiteral("query"), sweepMethod, sweepParams,
+                                       kVerifyTimeoutMs, &sweepError);
+
+    if (parser.isSet(jsonOption)) {
+        QJsonObject output;
+        output[QStringLiteral("index")] = index.value();
+        output[QStringLiteral("vectors")] = vectors.value_or(QJsonObject());
+        output[QStringLiteral("verified")] =
+            PurgeFormat::verified(index.value(), vectors.value_or(QJsonObject()));
+        cliOut() << QJsonDocument(output).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else {
+        cliOut() << PurgeFormat::render(index.value(), vectors.value_or(QJsonObject()),
+                                        !verifyOnly)
+                 << Qt::flush;
+    }
+
+    if (!vectors.has_value()) {
+        cliErr() << "bspot purge: " << sweepError << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    return PurgeFormat::verified(index.value(), vectors.value()) ? kCliExitOk : kCliExitFailure;
+}
+
+} // namespace bs
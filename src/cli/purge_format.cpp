@@ -0,0 +1,100 @@
+#include "cli/purge_format.h"
+
+#include <QStringList>
+
+namespace bs {
+
+namespace {
+
+QString formatBytes(qint64 bytes)
+{
+    if (bytes < 1024 * 1024) {
+        return QStringLiteral("%1 KB").arg(QString::number(static_cast<double>(bytes) / 1024.0,
+                                                           'f', 1));
+    }
+    return QStringLiteral("%1 MB").arg(
+        QString::number(static_cast<double>(bytes) / (1024.0 * 1024.0), 'f', 1));
+}
+
+QString rowsLine(const QJsonObject& remaining)
+{
+    QStringList parts;
+    const auto add = [&](const char* key, const char* label) {
+        const qint64 count = remaining.value(QLatin1String(key)).toInteger();
+        if (count != 0) {
+            // -1: the count itself failed, which is not "none".
+            parts.append(count < 0 ? QStringLiteral("unknown %1").arg(QLatin1String(label))
+                                   : QStringLiteral("%1 %2").arg(count).arg(QLatin1String(label)));
+        }
+    };
+    add("items", "item(s)");
+    add("ftsRows", "search row(s)");
+    add("behaviorEvents", "behavior event(s)");
+    return parts.isEmpty() ? QStringLiteral("none") : parts.join(QStringLiteral(", "));
+}
+
+} // namespace
+
+bool PurgeFormat::verified(const QJsonObject& index, const QJsonObject& vectors)
+{
+    return index.value(QStringLiteral("verified")).toBool()
+        && vectors.contains(QStringLiteral("remainingVectors"))
+        && vectors.value(QStringLiteral("remainingVectors")).toInteger() == 0
+        && vectors.value(QStringLiteral("vectorsSaved")).toBool(true);
+}
+
+QString PurgeFormat::render(const QJsonObject& index, const QJsonObject& vectors, bool purged)
+{
+    const QString path = index.value(QStringLiteral("path")).toString();
+    QString out;
+
+    if (purged) {
+        const QJsonObject compaction = index.value(QStringLiteral("compaction")).toObject();
+        out += QStringLiteral("Purged %1 item(s) under %2\n")
+                   .arg(index.value(QStringLiteral("deletedItems")).toInteger())
+                   .arg(path);
+        if (compaction.value(QStringLiteral("complete")).toBool()) {
+            out += QStringLiteral("Compacted the index: %1 -> %2\n")
+                       .arg(formatBytes(compaction.value(QStringLiteral("bytesBefore")).toInteger()),
+                            formatBytes(compaction.value(QStringLiteral("bytesAfter")).toInteger()));
+        } else {
+            out += QStringLiteral("Compaction did not finish; see the indexer log\n");
+        }
+    }
+
+    out += QStringLiteral("%1\n").arg(path);
+    out += QStringLiteral("  Index rows:          %1\n")
+               .arg(rowsLine(index.value(QStringLiteral("remaining")).toObject()));
+
+    const qint64 pending = index.value(QStringLiteral("pendingCompactionDeletes")).toInteger();
+    out += QStringLiteral("  Awaiting compaction: %1\n")
+               .arg(pending == 0 ? QStringLiteral("none")
+                                 : QStringLiteral("%1 deleted item(s)").arg(pending));
+
+    QString embeddings;
+    if (!vectors.contains(QStringLiteral("remainingVectors"))) {
+        embeddings = QStringLiteral("not checked (query service not reached)");
+    } else if (!vectors.value(QStringLiteral("vectorsSaved")).toBool(true)) {
+        embeddings = QStringLiteral("erased in memory but not saved");
+    } else {
+        const qint64 remaining = vectors.value(QStringLiteral("remainingVectors")).toInteger();
+        embeddings = remaining == 0 ? QStringLiteral("none")
+                                    : QStringLiteral("%1 orphaned vector(s)").arg(remaining);
+    }
+    out += QStringLiteral("  Embeddings:          %1\n").arg(embeddings);
+
+    if (verified(index, vectors)) {
+        out += QStringLiteral("Verified: nothing remains for %1\n").arg(path);
+        return out;
+    }
+    out += QStringLiteral("Not verified: data for %1 may remain\n").arg(path);
+    const QJsonObject remaining = index.value(QStringLiteral("remaining")).toObject();
+    if (remaining.value(QStringLiteral("items")).toInteger() != 0) {
+        out += QStringLiteral("Fix: delete or exclude %1, then run 'bspot purge %1'\n").arg(path);
+    } else if (!purged && pending > 0) {
+        out += QStringLiteral("Fix: run 'bspot purge %1' to compact now\n").arg(path);
+    }
+    return out;
+}
+
+} // namespace bs
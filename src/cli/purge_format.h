@@ -0,0 +1,24 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QString>
+
+namespace bs {
+
+// PurgeFormat — renders `bspot purge` results: the indexer's `purgePath` or
+// `verifyPurged` result and the query service's vector sweep
+// (`purgePrivacyPaths` or a dry-run `purgeOrphans`). No I/O, so it can be
+// unit tested.
+class PurgeFormat {
+public:
+    // Nothing left: no rows under the path, no deletions awaiting
+    // compaction and no orphaned embeddings. An empty `vectors` (query
+    // service not reached) never verifies.
+    static bool verified(const QJsonObject& index, const QJsonObject& vectors);
+
+    // One line per check, then the verdict. `purged` adds what the purge
+    // itself removed and how the compaction went.
+    static QString render(const QJsonObject& index, const QJsonObject& vectors, bool purged);
+};
+
+} // namespace bs
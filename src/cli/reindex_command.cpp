@@ -0,0 +1,190 @@
+#include "cli/cli_common.h"
+#include "cli/search_filters.h"
+
+#include "core/extraction/extraction_manager.h"
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDir>
+#include <QEventLoop>
+#include <QFileInfo>
+#include <QHash>
+#include <QJsonArray>
+#include <QTimer>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 1000;
+constexpr int kRequestTimeoutMs = 10000;
+constexpr int kDefaultWaitSecs = 600;
+
+QString absolutePath(const QString& argument)
+{
+    if (argument == QLatin1String("~") || argument.startsWith(QLatin1String("~/"))) {
+        return QDir::cleanPath(QDir::homePath() + argument.mid(1));
+    }
+    return QDir::cleanPath(QDir::current().absoluteFilePath(argument));
+}
+
+QString errorMessage(const QJsonObject& envelope)
+{
+    return envelope.value(QStringLiteral("error")).toObject()
+        .value(QStringLiteral("message")).toString();
+}
+
+} // namespace
+
+int runReindexCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Re-extract one file, or everything under a folder, without rebuilding "
+                       "the whole index. Entries under the folder that were deleted or are now "
+                       "excluded are removed. Waits until the folder has been scanned and "
+                       "queued; 'bspot status' follows the extraction itself.\n"
+                       "Exit status: 0 queued, 1 nothing matched, 2 usage error, "
+                       "3 service unavailable or failed."));
+    const QStringList knownExtractors = ExtractionManager::extractorNames();
+    parser.addPositionalArgument(QStringLiteral("path"), QStringLiteral("File or folder."),
+                                 QStringLiteral("<path>"));
+    const QCommandLineOption extractorOption(
+        {QStringLiteral("e"), QStringLiteral("extractor")},
+        QStringLiteral("Only files handled by this extractor: %1. Repeatable or "
+                       "comma-separated.").arg(knownExtractors.join(QStringLiteral(", "))),
+        QStringLiteral("name"));
+    const QCommandLineOption noWaitOption(
+        QStringLiteral("no-wait"),
+        QStringLiteral("Return once the indexer accepts a folder, before it is scanned."));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Seconds to wait for a folder scan (default %1).").arg(kDefaultWaitSecs),
+        QStringLiteral("secs"), QString::number(kDefaultWaitSecs));
+    parser.addOptions({extractorOption, noWaitOption, timeoutOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot reindex"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot reindex: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+
+    if (parser.positionalArguments().size() != 1) {
+        return usageError(QStringLiteral("expected exactly one <path>"));
+    }
+    const QString path = absolutePath(parser.positionalArguments().first());
+    const QFileInfo info(path);
+    if (!info.exists()) {
+        return usageError(QStringLiteral("%1: no such file or directory").arg(path));
+    }
+
+    QJsonArray extractors;
+    for (const QString& name : SearchFilterOptions::splitValues(parser.values(extractorOption))) {
+        const QString normalized = name.toLower();
+        if (!knownExtractors.contains(normalized)) {
+            return usageError(QStringLiteral("unknown extractor '%1' (expected %2)")
+                                  .arg(name, knownExtractors.join(QStringLiteral(", "))));
+        }
+        if (!extractors.contains(normalized)) {
+            extractors.append(normalized);
+        }
+    }
+    bool ok = false;
+    const int timeoutSecs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutSecs < 1) {
+        return usageError(QStringLiteral("--timeout must be a positive number of seconds"));
+    }
+
+    // reindexPath is an admin method; the app leaves its session token
+    // where same-user tools can read it.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+
+    bool serviceLost = false;  // outlives the client, whose teardown emits disconnected()
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("indexer")),
+                                kConnectTimeoutMs)) {
+        cliErr() << "bspot reindex: indexer service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    // Listen before asking: a small folder can finish before the response
+    // has been read, and every client hears every completion.
+    QHash<qint64, QJsonObject> completions;
+    qint64 reindexId = 0;
+    QEventLoop loop;
+    client.setNotificationHandler([&](const QString& method, const QJsonObject& params) {
+        if (method == QLatin1String("reindexPathComplete")) {
+            const qint64 id = params.value(QStringLiteral("reindexId")).toInteger();
+            completions.insert(id, params);
+            if (id == reindexId) {
+                loop.quit();
+            }
+        }
+    });
+    QObject::connect(&client, &SocketClient::disconnected, &loop, [&]() {
+        serviceLost = true;
+        loop.quit();
+    });
+
+    QJsonObject params;
+    params[QStringLiteral("path")] = path;
+    if (!extractors.isEmpty()) {
+        params[QStringLiteral("extractors")] = extractors;
+    }
+    const auto response = client.sendRequest(QStringLiteral("reindexPath"), params,
+                                             kRequestTimeoutMs);
+    if (!response.has_value()) {
+        cliErr() << "bspot reindex: indexer service did not answer" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot reindex: " << errorMessage(response.value()) << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (!result.value(QStringLiteral("scanning")).toBool()) {
+        const qint64 queued = result.value(QStringLiteral("queuedFiles")).toInteger();
+        if (queued == 0) {
+            cliOut() << "Nothing to reindex: " << path
+                     << " is not handled by the selected extractor(s)" << Qt::endl;
+            return kCliExitFailure;
+        }
+        cliOut() << "Queued " << path << " for re-extraction" << Qt::endl;
+        return kCliExitOk;
+    }
+    if (parser.isSet(noWaitOption)) {
+        cliOut() << "Reindex of " << path << " started" << Qt::endl;
+        return kCliExitOk;
+    }
+
+    reindexId = result.value(QStringLiteral("reindexId")).toInteger();
+    QTimer::singleShot(timeoutSecs * 1000, &loop, &QEventLoop::quit);
+    if (!completions.contains(reindexId)) {
+        loop.exec();
+    }
+    if (serviceLost && !completions.contains(reindexId)) {
+        cliErr() << "bspot reindex: lost connection to the indexer service" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (!completions.contains(reindexId)) {
+        cliErr() << "bspot reindex: " << path << " is still being scanned after "
+                 << timeoutSecs << " s; it continues in the background" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject completion = completions.value(reindexId);
+    const qint64 queued = completion.value(QStringLiteral("queuedFiles")).toInteger();
+    const qint64 removed = completion.value(QStringLiteral("deletedEntries")).toInteger();
+    cliOut() << "Queued " << queued << " file(s) under " << path << " for re-extraction";
+    if (removed > 0) {
+        cliOut() << ", removing " << removed << " stale entr" << (removed == 1 ? "y" : "ies");
+    }
+    cliOut() << Qt::endl;
+    return queued + removed > 0 ? kCliExitOk : kCliExitFailure;
+}
+
+} // namespace bs
@@ -0,0 +1,164 @@
+#include "cli/cli_common.h"
+#include "cli/search_filters.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+
+QString joinArray(const QJsonValue& value)
+{
+    QStringList parts;
+    for (const QJsonValue& entry : value.toArray()) {
+        parts.append(entry.toString());
+    }
+    return parts.join(QStringLiteral(", "));
+}
+
+// "clienta  Client A\n  roots: ...\n  types: pdf, docx"
+void printScope(const QJsonObject& scope)
+{
+    cliOut() << scope.value(QStringLiteral("id")).toString() << "  "
+             << scope.value(QStringLiteral("name")).toString() << '\n'
+             << "  roots: " << joinArray(scope.value(QStringLiteral("roots"))) << '\n';
+    if (!scope.value(QStringLiteral("excludePaths")).toArray().isEmpty()) {
+        cliOut() << "  exclude: " << joinArray(scope.value(QStringLiteral("excludePaths")))
+                 << '\n';
+    }
+    if (!scope.value(QStringLiteral("fileTypes")).toArray().isEmpty()) {
+        cliOut() << "  types: " << joinArray(scope.value(QStringLiteral("fileTypes"))) << '\n';
+    }
+    if (!scope.value(QStringLiteral("tags")).toArray().isEmpty()) {
+        cliOut() << "  tags: " << joinArray(scope.value(QStringLiteral("tags"))) << '\n';
+    }
+}
+
+QJsonArray absolutePaths(const QStringList& paths)
+{
+    QJsonArray out;
+    for (const QString& path : SearchFilterOptions::splitValues(paths)) {
+        out.append(QDir::cleanPath(QFileInfo(path).absoluteFilePath()));
+    }
+    return out;
+}
+
+} // namespace
+
+int runScopeCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("List, define or remove named search scopes: folders plus filters a "
+                       "search selects with scope:<id> or bspot search --scope.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    parser.addPositionalArgument(
+        QStringLiteral("name"),
+        QStringLiteral("With --root, the scope to define (its id is the name lowercased, "
+                       "letters and digits only); with --remove, the id to remove."),
+        QStringLiteral("[name]"));
+    const QCommandLineOption rootOption(
+        QStringLiteral("root"), QStringLiteral("A folder in the scope; repeatable."),
+        QStringLiteral("path"));
+    const QCommandLineOption excludeOption(
+        QStringLiteral("exclude"), QStringLiteral("A folder inside the roots to leave out; "
+                                                  "repeatable."),
+        QStringLiteral("path"));
+    const QCommandLineOption typeOption(
+        QStringLiteral("type"), QStringLiteral("Only these extensions when a search names "
+                                               "none; repeatable."),
+        QStringLiteral("ext"));
+    const QCommandLineOption tagOption(
+        QStringLiteral("tag"), QStringLiteral("Only items with this Finder tag; repeatable."),
+        QStringLiteral("tag"));
+    const QCommandLineOption removeOption(QStringLiteral("remove"),
+                                          QStringLiteral("Remove the scope given as name."));
+    const QCommandLineOption jsonOption(QStringLiteral("json"),
+                                        QStringLiteral("Print the raw result."));
+    parser.addOptions({rootOption, excludeOption, typeOption, tagOption, removeOption,
+                       jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot scope"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot scope: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+    const QString name = parser.positionalArguments().join(QLatin1Char(' ')).trimmed();
+    const bool defining = parser.isSet(rootOption);
+
+    QString method;
+    QJsonObject params;
+    if (parser.isSet(removeOption)) {
+        if (name.isEmpty() || defining) {
+            return usageError(QStringLiteral("--remove expects a scope id and no --root"));
+        }
+        method = QStringLiteral("removeSearchScope");
+        params[QStringLiteral("id")] = name;
+    } else if (defining) {
+        if (name.isEmpty()) {
+            return usageError(QStringLiteral("a new scope needs a name"));
+        }
+        method = QStringLiteral("setSearchScope");
+        params[QStringLiteral("name")] = name;
+        params[QStringLiteral("roots")] = absolutePaths(parser.values(rootOption));
+        params[QStringLiteral("excludePaths")] = absolutePaths(parser.values(excludeOption));
+        params[QStringLiteral("fileTypes")] = QJsonArray::fromStringList(
+            SearchFilterOptions::splitValues(parser.values(typeOption)));
+        params[QStringLiteral("tags")] = QJsonArray::fromStringList(parser.values(tagOption));
+    } else {
+        if (parser.isSet(excludeOption) || parser.isSet(typeOption) || parser.isSet(tagOption)) {
+            return usageError(QStringLiteral("--exclude, --type and --tag need --root"));
+        }
+        method = QStringLiteral("listSearchScopes");
+    }
+
+    // Defining and removing are admin methods: they change every client's
+    // searches.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot scope: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot scope: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    if (method == QLatin1String("removeSearchScope")) {
+        cliOut() << "Removed scope " << name << Qt::endl;
+        return kCliExitOk;
+    }
+    if (method == QLatin1String("setSearchScope")) {
+        printScope(result);
+    } else {
+        for (const QJsonValue& scope : result.value(QStringLiteral("scopes")).toArray()) {
+            printScope(scope.toObject());
+        }
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,406 @@
+#include "cli/cli_common.h"
+#include "cli/search_filters.h"
+#include "cli/search_format.h"
+#include "cli/watch_session.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QCoreApplication>
+#include <QDateTime>
+#include <QDir>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QSocketNotifier>
+
+#include <fcntl.h>
+#include <signal.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#include <algorithm>
+#include <functional>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 10000;
+constexpr int kDefaultWatchLimit = 200;
+
+int g_watchSignalPipe[2] = {-1, -1};
+
+void onWatchSignal(int)
+{
+    // Async-signal-safe; a full pipe just means a stop is already pending.
+    const char byte = 1;
+    (void)::write(g_watchSignalPipe[1], &byte, 1);
+}
+
+// `--watch`: subscribe, print the snapshot, then print each new match from
+// liveQueryUpdated until a signal (Ctrl-C, SIGTERM, a closed pipe) or the
+// service goes away. Unsubscribes on the way out so the service's live
+// query slots are not leaked.
+int watchQuery(const QJsonObject& params, int timeoutMs,
+               const std::function<void(const QJsonArray&)>& print)
+{
+    bool serviceLost = false;  // outlives the client, whose teardown emits disconnected()
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("query")), timeoutMs)) {
+        cliErr() << "bspot search: query service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    // Declared after the client so it unsubscribes while still connected.
+    WatchSession session(print, [&client](qint64 subscriptionId) {
+        if (!client.isConnected()) {
+            return;
+        }
+        QJsonObject unsubscribe;
+        unsubscribe[QStringLiteral("subscriptionId")] = subscriptionId;
+        client.sendRequest(QStringLiteral("unsubscribeQuery"), unsubscribe, 1000);
+    });
+    client.setNotificationHandler([&session](const QString& method, const QJsonObject& update) {
+        session.handleNotification(method, update);
+    });
+
+    const auto response = client.sendRequest(QStringLiteral("subscribeQuery"), params, timeoutMs);
+    if (!response.has_value()) {
+        cliErr() << "bspot search: query service did not answer 'subscribeQuery'" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot search: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    session.start(response->value(QStringLiteral("result")).toObject());
+
+    if (::socketpair(AF_UNIX, SOCK_STREAM, 0, g_watchSignalPipe) != 0) {
+        cliErr() << "bspot search: failed to set up signal handling" << Qt::endl;
+        return kCliExitFailure;
+    }
+    ::fcntl(g_watchSignalPipe[1], F_SETFL, O_NONBLOCK);
+    QSocketNotifier signalNotifier(g_watchSignalPipe[0], QSocketNotifier::Read);
+    QObject::connect(&signalNotifier, &QSocketNotifier::activated, []() {
+        QCoreApplication::quit();
+    });
+    struct sigaction action {};
+    action.sa_handler = onWatchSignal;
+    sigemptyset(&action.sa_mask);
+    action.sa_flags = SA_RESTART;
+    for (const int signalNumber : {SIGINT, SIGTERM, SIGHUP, SIGPIPE}) {
+        ::sigaction(signalNumber, &action, nullptr);
+    }
+
+    QObject::connect(&client, &SocketClient::disconnected, [&serviceLost]() {
+        serviceLost = true;
+        QCoreApplication::quit();
+    });
+    QCoreApplication::exec();
+
+    if (serviceLost) {
+        cliErr() << "bspot search: lost connection to the query service" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    session.finish();
+    return kCliExitOk;
+}
+
+} // namespace
+
+int runSearchCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Search the BetterSpotlight index.\n"
+                       "Exit status: 0 results found (or --watch stopped by a signal), "
+                       "1 no results, 2 usage error, 3 service unavailable or failed."));
+    parser.addPositionalArgument(QStringLiteral("query"), QStringLiteral("Search text."),
+                                 QStringLiteral("<query...>"));
+    const QCommandLineOption limitOption(
+        {QStringLiteral("n"), QStringLiteral("limit")},
+        QStringLiteral("Maximum results (1-200, default 20; %1 with --watch).")
+            .arg(kDefaultWatchLimit),
+        QStringLiteral("n"), QStringLiteral("20"));
+    const QCommandLineOption modeOption(
+        QStringLiteral("mode"),
+        QStringLiteral("Query mode: auto, strict (all terms) or relaxed (any term)."),
+        QStringLiteral("mode"), QStringLiteral("auto"));
+    const QCommandLineOption predicateOption(
+        QStringLiteral("predicate"),
+        QStringLiteral("Spotlight predicate (as for mdfind), e.g. "
+                       "'kMDItemContentType == \"com.adobe.pdf\"'; the query is optional."),
+        QStringLiteral("predicate"));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the full result as one JSON document."));
+    const QCommandLineOption ndjsonOption(
+        QStringLiteral("ndjson"), QStringLiteral("Print one JSON object per result line."));
+    const QCommandLineOption print0Option(
+        {QStringLiteral("0"), QStringLiteral("print0")},
+        QStringLiteral("Print NUL-terminated paths only (for xargs -0)."));
+    const QCommandLineOption formatOption(
+        QStringLiteral("format"),
+        QStringLiteral("Print for a launcher: alfred (Alfred script-filter JSON)."),
+        QStringLiteral("format"));
+    const QCommandLineOption columnsOption(
+        {QStringLiteral("c"), QStringLiteral("columns")},
+        QStringLiteral("Columns to print: %1 (default %2).")
+            .arg(SearchFormat::columnNames().join(QLatin1Char(',')),
+                 SearchFormat::defaultColumns().join(QLatin1Char(','))),
+        QStringLiteral("list"));
+    const QCommandLineOption noHeaderOption(
+        QStringLiteral("no-header"), QStringLiteral("Omit the column header line."));
+    const QCommandLineOption watchOption(
+        QStringLiteral("watch"),
+        QStringLiteral("Keep running and print new matches as they are indexed "
+                       "(Ctrl-C to stop)."));
+    const QCommandLineOption appOption(
+        QStringLiteral("app"),
+        QStringLiteral("Rank files this app opens higher, as if it were frontmost "
+                       "(bundle ID, e.g. com.apple.Preview)."),
+        QStringLiteral("bundle-id"));
+    const QCommandLineOption scopeOption(
+        QStringLiteral("scope"),
+        QStringLiteral("Only search this named scope (see bspot scope); same as scope:<id> "
+                       "in the query."),
+        QStringLiteral("id"));
+    const QCommandLineOption literalOption(
+        QStringLiteral("literal"),
+        QStringLiteral("Match the words as typed; don't read phrases like \"screenshots from "
+                       "last tuesday\" as filters."));
+    const QCommandLineOption privateOption(
+        QStringLiteral("private"),
+        QStringLiteral("Don't let this search influence ranking or autocomplete later."));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Give up after this many milliseconds (default %1).").arg(kDefaultTimeoutMs),
+        QStringLiteral("ms"), QString::number(kDefaultTimeoutMs));
+    const SearchFilterOptions filterOptions;
+    parser.addOption(limitOption);
+    filterOptions.addTo(parser);
+    parser.addOptions({modeOption, predicateOption, jsonOption, ndjsonOption, print0Option, formatOption,
+                       columnsOption, noHeaderOption, watchOption, appOption, scopeOption,
+                       literalOption, privateOption, timeoutOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot search"), args)) {
+        return exitCode.value();
+    }
+
+    const auto usageError = [](const QString& message) {
+        cliErr() << "bspot search: " << message << Qt::endl;
+        return kCliExitUsage;
+    };
+
+    const QString query = parser.positionalArguments().join(QLatin1Char(' ')).trimmed();
+    const bool hasPredicate = parser.isSet(predicateOption);
+    if (query.isEmpty() && !hasPredicate) {
+        // Alfred runs the script filter as soon as the keyword is typed.
+        if (parser.value(formatOption).toLower() == QLatin1String("alfred")) {
+            cliOut() << SearchFormat::renderAlfredMessage(
+                            QStringLiteral("Search BetterSpotlight"),
+                            QStringLiteral("Type a file name or words from its content"))
+                     << Qt::flush;
+            return kCliExitOk;
+        }
+        return usageError(QStringLiteral("missing query"));
+    }
+    const int outputModes = (parser.isSet(jsonOption) ? 1 : 0)
+        + (parser.isSet(ndjsonOption) ? 1 : 0) + (parser.isSet(print0Option) ? 1 : 0)
+        + (parser.isSet(formatOption) ? 1 : 0);
+    if (outputModes > 1) {
+        return usageError(
+            QStringLiteral("--json, --ndjson, --print0 and --format are mutually exclusive"));
+    }
+    const bool alfred = parser.isSet(formatOption);
+    if (alfred && parser.value(formatOption).toLower() != QLatin1String("alfred")) {
+        return usageError(QStringLiteral("--format must be alfred"));
+    }
+    const bool watch = parser.isSet(watchOption);
+    if (watch && parser.isSet(jsonOption)) {
+        return usageError(QStringLiteral("--watch streams results; use --ndjson instead of --json"));
+    }
+    if (watch && alfred) {
+        return usageError(QStringLiteral("--watch cannot be combined with --format alfred"));
+    }
+    if (watch && hasPredicate) {
+        return usageError(QStringLiteral("--watch cannot be combined with --predicate"));
+    }
+
+    // A live query only reports changes inside its top `limit` results, so
+    // watching defaults to a deeper window than a one-shot search.
+    bool ok = true;
+    const int limit = watch && !parser.isSet(limitOption)
+        ? kDefaultWatchLimit
+        : parser.value(limitOption).toInt(&ok);
+    if (!ok || limit < 1 || limit > 200) {
+        return usageError(QStringLiteral("--limit must be between 1 and 200"));
+    }
+    const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutMs < 1) {
+        return usageError(QStringLiteral("--timeout must be a positive number of milliseconds"));
+    }
+    const QString mode = parser.value(modeOption).toLower();
+    if (mode != QLatin1String("auto") && mode != QLatin1String("strict")
+        && mode != QLatin1String("relaxed")) {
+        return usageError(QStringLiteral("--mode must be auto, strict or relaxed"));
+    }
+
+    QStringList columns = SearchFormat::defaultColumns();
+    if (parser.isSet(columnsOption)) {
+        columns = SearchFilterOptions::splitValues({parser.value(columnsOption)});
+        const QStringList known = SearchFormat::columnNames();
+        for (const QString& column : columns) {
+            if (!known.contains(column)) {
+                return usageError(QStringLiteral("unknown column '%1'").arg(column));
+            }
+        }
+        if (columns.isEmpty()) {
+            return usageError(QStringLiteral("--columns is empty"));
+        }
+    }
+
+    QString filterError;
+    const std::optional<QJsonObject> filters = filterOptions.filters(parser, &filterError);
+    if (!filters.has_value()) {
+        return usageError(filterError);
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("query")] = query;
+    params[QStringLiteral("limit")] = limit;
+    params[QStringLiteral("queryMode")] = mode;
+    if (hasPredicate) {
+        params[QStringLiteral("predicate")] = parser.value(predicateOption);
+    }
+    if (!filters->isEmpty()) {
+        params[QStringLiteral("filters")] = filters.value();
+    }
+    if (parser.isSet(appOption)) {
+        const QString bundleId = parser.value(appOption).trimmed();
+        if (bundleId.isEmpty()) {
+            return usageError(QStringLiteral("--app needs a bundle ID"));
+        }
+        QJsonObject context;
+        context[QStringLiteral("frontmostAppBundleId")] = bundleId;
+        params[QStringLiteral("context")] = context;
+    }
+    if (parser.isSet(scopeOption)) {
+        const QString scope = parser.value(scopeOption).trimmed();
+        if (scope.isEmpty()) {
+            return usageError(QStringLiteral("--scope needs a scope id"));
+        }
+        params[QStringLiteral("scope")] = scope;
+    }
+    if (parser.isSet(literalOption)) {
+        params[QStringLiteral("naturalLanguage")] = false;
+    }
+    if (parser.isSet(privateOption)) {
+        params[QStringLiteral("private")] = true;
+    }
+
+    if (watch) {
+        bool headerPending = !parser.isSet(noHeaderOption);
+        return watchQuery(params, timeoutMs, [&](const QJsonArray& results) {
+            if (parser.isSet(ndjsonOption)) {
+                cliOut() << SearchFormat::renderNdjson(results);
+            } else if (parser.isSet(print0Option)) {
+                for (const QJsonValue& value : results) {
+                    cliOut() << value.toObject().value(QStringLiteral("path")).toString()
+                             << QChar(0);
+                }
+            } else {
+                cliOut() << SearchFormat::renderColumns(results, columns, headerPending);
+                headerPending = false;
+            }
+            cliOut() << Qt::flush;
+        });
+    }
+
+    // Leave headroom for the reply to make it back before the socket timeout.
+    params[QStringLiteral("deadlineMs")] =
+        QDateTime::currentMSecsSinceEpoch() + std::max(1, timeoutMs - 250);
+
+    // Alfred shows stdout only, so failures are also rendered as a row.
+    const auto reportFailure = [alfred](const QString& message) {
+        cliErr() << "bspot search: " << message << Qt::endl;
+        if (alfred) {
+            cliOut() << SearchFormat::renderAlfredMessage(
+                            QStringLiteral("BetterSpotlight search failed"), message)
+                     << Qt::flush;
+        }
+        return kCliExitUnavailable;
+    };
+
+    QString error;
+    const auto response =
+        callService(QStringLiteral("query"), QStringLiteral("search"), params, timeoutMs, &error);
+    if (!response.has_value()) {
+        return reportFailure(error);
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return reportFailure(response->value(QStringLiteral("error")).toObject()
+                                 .value(QStringLiteral("message")).toString());
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    const QJsonArray results = result.value(QStringLiteral("results")).toArray();
+    if (result.value(QStringLiteral("deadlineExceeded")).toBool()) {
+        cliErr() << "bspot search: timed out; results may be incomplete" << Qt::endl;
+    }
+
+    if (parser.isSet(jsonOption)) {
+        QJsonObject document = result;
+        document[QStringLiteral("query")] = query;
+        cliOut() << QJsonDocument(document).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else if (parser.isSet(ndjsonOption)) {
+        cliOut() << SearchFormat::renderNdjson(results) << Qt::flush;
+    } else if (parser.isSet(print0Option)) {
+        for (const QJsonValue& value : results) {
+            cliOut() << value.toObject().value(QStringLiteral("path")).toString() << QChar(0);
+        }
+        cliOut() << Qt::flush;
+    } else if (alfred) {
+        cliOut() << SearchFormat::renderAlfred(
+                        results, query, QDir::homePath(),
+                        result.value(QStringLiteral("instantAnswer")).toObject(),
+                        result.value(QStringLiteral("definition")).toObject(),
+                        result.value(QStringLiteral("applications")).toArray(),
+                        result.value(QStringLiteral("settings")).toArray())
+                 << Qt::flush;
+    } else {
+        // On stderr, so pipes only ever see result rows.
+        const QJsonObject interpretation = result.value(QStringLiteral("interpretation")).toObject();
+        if (!interpretation.isEmpty()) {
+            cliErr() << "Showing " << interpretation.value(QStringLiteral("summary")).toString()
+                     << " (--literal to match the words)" << Qt::endl;
+        }
+        // The answer is the output here, so it goes to stdout.
+        const QJsonObject instantAnswer = result.value(QStringLiteral("instantAnswer")).toObject();
+        if (!instantAnswer.isEmpty()) {
+            cliOut() << instantAnswer.value(QStringLiteral("display")).toString() << Qt::endl;
+        }
+        if (!results.isEmpty()) {
+            cliOut() << SearchFormat::renderColumns(results, columns,
+                                                    !parser.isSet(noHeaderOption))
+                     << Qt::flush;
+        }
+        // Supplementary like the interpretation; file rows stay parseable.
+        const QJsonObject definition = result.value(QStringLiteral("definition")).toObject();
+        if (!definition.isEmpty()) {
+            const QString partOfSpeech =
+                definition.value(QStringLiteral("partOfSpeech")).toString();
+            cliErr() << definition.value(QStringLiteral("word")).toString()
+                     << (partOfSpeech.isEmpty() ? QString()
+                                                : QStringLiteral(" (%1)").arg(partOfSpeech))
+                     << ": " << definition.value(QStringLiteral("summary")).toString()
+                     << Qt::endl;
+        }
+    }
+
+    const bool answered = result.contains(QStringLiteral("instantAnswer"));
+    return results.isEmpty() && !answered ? kCliExitFailure : kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,113 @@
+#include "cli/search_filters.h"
+#include "cli/search_format.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+QJsonArray absolutePaths(const QStringList& values)
+{
+    QJsonArray paths;
+    for (const QString& value : values) {
+        QString path = value;
+        if (path == QLatin1String("~") || path.startsWith(QLatin1String("~/"))) {
+            path.replace(0, 1, QDir::homePath());
+        }
+        paths.append(QDir::cleanPath(QFileInfo(path).absoluteFilePath()));
+    }
+    return paths;
+}
+
+} // namespace
+
+SearchFilterOptions::SearchFilterOptions()
+    : m_type({QStringLiteral("t"), QStringLiteral("type")},
+             QStringLiteral("Only these file types/extensions (repeatable or comma-separated)."),
+             QStringLiteral("ext"))
+    , m_in(QStringLiteral("in"), QStringLiteral("Only results under this directory (repeatable)."),
+           QStringLiteral("path"))
+    , m_exclude(QStringLiteral("exclude"),
+                QStringLiteral("Skip results under this directory (repeatable)."),
+                QStringLiteral("path"))
+    , m_after(QStringLiteral("after"),
+              QStringLiteral("Modified after a date (2026-01-31) or age (30m, 12h, 7d, 2w)."),
+              QStringLiteral("when"))
+    , m_before(QStringLiteral("before"), QStringLiteral("Modified before a date or age."),
+               QStringLiteral("when"))
+    , m_minSize(QStringLiteral("min-size"),
+                QStringLiteral("Minimum file size (512, 10k, 5M, 1G)."), QStringLiteral("size"))
+    , m_maxSize(QStringLiteral("max-size"), QStringLiteral("Maximum file size."),
+                QStringLiteral("size"))
+{
+}
+
+void SearchFilterOptions::addTo(QCommandLineParser& parser) const
+{
+    parser.addOptions({m_type, m_in, m_exclude, m_after, m_before, m_minSize, m_maxSize});
+}
+
+std::optional<QJsonObject> SearchFilterOptions::filters(const QCommandLineParser& parser,
+                                                        QString* error) const
+{
+    QJsonObject filters;
+    const QStringList types = splitValues(parser.values(m_type));
+    if (!types.isEmpty()) {
+        QJsonArray fileTypes;
+        for (const QString& type : types) {
+            fileTypes.append(type.startsWith(QLatin1Char('.')) ? type.mid(1) : type);
+        }
+        filters[QStringLiteral("fileTypes")] = fileTypes;
+    }
+    if (parser.isSet(m_in)) {
+        filters[QStringLiteral("includePaths")] = absolutePaths(parser.values(m_in));
+    }
+    if (parser.isSet(m_exclude)) {
+        filters[QStringLiteral("excludePaths")] = absolutePaths(parser.values(m_exclude));
+    }
+    const qint64 nowSecs = QDateTime::currentSecsSinceEpoch();
+    for (const auto& [option, key] : {std::pair{&m_after, "modifiedAfter"},
+                                      std::pair{&m_before, "modifiedBefore"}}) {
+        if (!parser.isSet(*option)) {
+            continue;
+        }
+        const auto epoch = SearchFormat::parseTime(parser.value(*option), nowSecs);
+        if (!epoch.has_value()) {
+            *error = QStringLiteral("invalid date or age '%1'").arg(parser.value(*option));
+            return std::nullopt;
+        }
+        filters[QLatin1String(key)] = epoch.value();
+    }
+    for (const auto& [option, key] : {std::pair{&m_minSize, "minSize"},
+                                      std::pair{&m_maxSize, "maxSize"}}) {
+        if (!parser.isSet(*option)) {
+            continue;
+        }
+        const auto bytes = SearchFormat::parseSize(parser.value(*option));
+        if (!bytes.has_value()) {
+            *error = QStringLiteral("invalid size '%1'").arg(parser.value(*option));
+            return std::nullopt;
+        }
+        filters[QLatin1String(key)] = static_cast<double>(bytes.value());
+    }
+    return filters;
+}
+
+QStringList SearchFilterOptions::splitValues(const QStringList& values)
+{
+    QStringList out;
+    for (const QString& value : values) {
+        for (const QString& part : value.split(QLatin1Char(','), Qt::SkipEmptyParts)) {
+            out.append(part.trimmed());
+        }
+    }
+    return out;
+}
+
+} // namespace bs
@@ -0,0 +1,39 @@
+#pragma once
+
+#include <QCommandLineOption>
+#include <QCommandLineParser>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// SearchFilterOptions — the filter flags shared by `bspot search` and
+// `bspot export` (-t, --in, --exclude, --after, --before, --min-size,
+// --max-size) and their translation into a `filters` params object.
+class SearchFilterOptions {
+public:
+    SearchFilterOptions();
+
+    void addTo(QCommandLineParser& parser) const;
+
+    // The `filters` object for the flags given (empty when none were), or
+    // nullopt with *error set for a date or size that does not parse.
+    std::optional<QJsonObject> filters(const QCommandLineParser& parser, QString* error) const;
+
+    // Repeatable flags also accept comma-separated lists: -t pdf,md -t txt.
+    static QStringList splitValues(const QStringList& values);
+
+private:
+    QCommandLineOption m_type;
+    QCommandLineOption m_in;
+    QCommandLineOption m_exclude;
+    QCommandLineOption m_after;
+    QCommandLineOption m_before;
+    QCommandLineOption m_minSize;
+    QCommandLineOption m_maxSize;
+};
+
+} // namespace bs
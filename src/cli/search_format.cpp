@@ -0,0 +1,372 @@
+#include "cli/search_format.h"
+
+#include <QDateTime>
+#include <QJsonDocument>
+#include <QRegularExpression>
+
+#include <algorithm>
+#include <limits>
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+QString humanSize(qint64 bytes)
+{
+    static const char* kUnits[] = {"B", "K", "M", "G", "T"};
+    double value = static_cast<double>(bytes);
+    int unit = 0;
+    while (value >= 1024.0 && unit < 4) {
+        value /= 1024.0;
+        ++unit;
+    }
+    if (unit == 0) {
+        return QStringLiteral("%1B").arg(bytes);
+    }
+    return QStringLiteral("%1%2").arg(value, 0, 'f', value < 10.0 ? 1 : 0)
+        .arg(QLatin1String(kUnits[unit]));
+}
+
+QString abbreviateHome(const QString& path, const QString& homeDir)
+{
+    if (!homeDir.isEmpty()
+        && (path == homeDir || path.startsWith(homeDir + QLatin1Char('/')))) {
+        return QLatin1Char('~') + path.mid(homeDir.size());
+    }
+    return path;
+}
+
+QJsonObject alfredMod(const QString& path, const QString& subtitle, const QString& action)
+{
+    QJsonObject variables;
+    variables[QStringLiteral("action")] = action;
+
+    QJsonObject mod;
+    mod[QStringLiteral("valid")] = true;
+    mod[QStringLiteral("arg")] = path;
+    mod[QStringLiteral("subtitle")] = subtitle;
+    mod[QStringLiteral("variables")] = variables;
+    return mod;
+}
+
+QString alfredDocument(const QJsonArray& items)
+{
+    QJsonObject document;
+    // Keep BetterSpotlight's ranking instead of Alfred's own usage order.
+    document[QStringLiteral("skipknowledge")] = true;
+    document[QStringLiteral("items")] = items;
+    return QString::fromUtf8(QJsonDocument(document).toJson(QJsonDocument::Compact))
+        + QLatin1Char('\n');
+}
+
+} // namespace
+
+QStringList SearchFormat::columnNames()
+{
+    return {QStringLiteral("score"), QStringLiteral("kind"), QStringLiteral("modified"),
+            QStringLiteral("size"), QStringLiteral("name"), QStringLiteral("path"),
+            QStringLiteral("itemId"), QStringLiteral("match"), QStringLiteral("snippet")};
+}
+
+QStringList SearchFormat::defaultColumns()
+{
+    return {QStringLiteral("score"), QStringLiteral("kind"), QStringLiteral("modified"),
+            QStringLiteral("path")};
+}
+
+std::optional<double> SearchFormat::parseTime(const QString& value, qint64 nowSecs)
+{
+    const QString trimmed = value.trimmed();
+    static const QRegularExpression relativePattern(QStringLiteral(R"(^(\d+)([mhdw])$)"));
+    const QRegularExpressionMatch relative = relativePattern.match(trimmed.toLower());
+    if (relative.hasMatch()) {
+        const qint64 amount = relative.captured(1).toLongLong();
+        const QChar unit = relative.captured(2).at(0);
+        qint64 unitSecs = 60;
+        if (unit == QLatin1Char('h')) {
+            unitSecs = 3600;
+        } else if (unit == QLatin1Char('d')) {
+            unitSecs = 86400;
+        } else if (unit == QLatin1Char('w')) {
+            unitSecs = 7 * 86400;
+        }
+        return static_cast<double>(nowSecs - amount * unitSecs);
+    }
+
+    // Bare dates are local midnight, matching what the user sees in Finder.
+    const QDate date = QDate::fromString(trimmed, Qt::ISODate);
+    if (date.isValid()) {
+        return static_cast<double>(date.startOfDay().toSecsSinceEpoch());
+    }
+    const QDateTime dateTime = QDateTime::fromString(trimmed, Qt::ISODate);
+    if (dateTime.isValid()) {
+        return static_cast<double>(dateTime.toSecsSinceEpoch());
+    }
+    return std::nullopt;
+}
+
+std::optional<qint64> SearchFormat::parseSize(const QString& value)
+{
+    static const QRegularExpression pattern(QStringLiteral(R"(^(\d+)([kmgt]?)b?$)"));
+    const QRegularExpressionMatch match = pattern.match(value.trimmed().toLower());
+    if (!match.hasMatch()) {
+        return std::nullopt;
+    }
+    bool ok = false;
+    qint64 bytes = match.captured(1).toLongLong(&ok);
+    if (!ok) {
+        return std::nullopt;
+    }
+    const QString suffix = match.captured(2);
+    const int shift = suffix.isEmpty() ? 0
+        : 10 * (QStringLiteral("kmgt").indexOf(suffix) + 1);
+    if (shift > 0 && bytes > (std::numeric_limits<qint64>::max() >> shift)) {
+        return std::nullopt;
+    }
+    return bytes << shift;
+}
+
+QString SearchFormat::columnValue(const QJsonObject& hit, const QString& column)
+{
+    const QJsonObject metadata = hit.value(QStringLiteral("metadata")).toObject();
+    if (column == QLatin1String("score")) {
+        return QString::number(hit.value(QStringLiteral("score")).toDouble(), 'f', 1);
+    }
+    if (column == QLatin1String("kind")) {
+        return hit.value(QStringLiteral("kind")).toString();
+    }
+    if (column == QLatin1String("modified")) {
+        const QDateTime modified = QDateTime::fromString(
+            metadata.value(QStringLiteral("modificationDate")).toString(), Qt::ISODate);
+        return modified.isValid()
+            ? modified.toLocalTime().toString(QStringLiteral("yyyy-MM-dd HH:mm"))
+            : QString();
+    }
+    if (column == QLatin1String("size")) {
+        if (!metadata.contains(QStringLiteral("fileSize"))) {
+            return QString();
+        }
+        return humanSize(metadata.value(QStringLiteral("fileSize")).toInteger());
+    }
+    if (column == QLatin1String("name")) {
+        return hit.value(QStringLiteral("name")).toString();
+    }
+    if (column == QLatin1String("path")) {
+        return hit.value(QStringLiteral("path")).toString();
+    }
+    if (column == QLatin1String("itemId")) {
+        return QString::number(hit.value(QStringLiteral("itemId")).toInteger());
+    }
+    if (column == QLatin1String("match")) {
+        return hit.value(QStringLiteral("matchType")).toString();
+    }
+    if (column == QLatin1String("snippet")) {
+        return hit.value(QStringLiteral("snippet")).toString().simplified();
+    }
+    return QString();
+}
+
+QString SearchFormat::renderColumns(const QJsonArray& results,
+                                    const QStringList& columns,
+                                    bool header)
+{
+    std::vector<QStringList> rows;
+    rows.reserve(static_cast<size_t>(results.size()) + 1);
+    if (header) {
+        QStringList headings;
+        for (const QString& column : columns) {
+            headings.append(column.toUpper());
+        }
+        rows.push_back(headings);
+    }
+    for (const QJsonValue& value : results) {
+        const QJsonObject hit = value.toObject();
+        QStringList cells;
+        for (const QString& column : columns) {
+            cells.append(columnValue(hit, column));
+        }
+        rows.push_back(cells);
+    }
+
+    std::vector<int> widths(static_cast<size_t>(columns.size()), 0);
+    for (const QStringList& row : rows) {
+        for (int i = 0; i < row.size(); ++i) {
+            widths[static_cast<size_t>(i)] =
+                std::max(widths[static_cast<size_t>(i)], static_cast<int>(row.at(i).size()));
+        }
+    }
+
+    QString output;
+    for (const QStringList& row : rows) {
+        for (int i = 0; i < row.size(); ++i) {
+            const bool last = i == row.size() - 1;
+            output += last ? row.at(i) : row.at(i).leftJustified(widths[static_cast<size_t>(i)]);
+            if (!last) {
+                output += QStringLiteral("  ");
+            }
+        }
+        output += QLatin1Char('\n');
+    }
+    return output;
+}
+
+QString SearchFormat::renderNdjson(const QJsonArray& results)
+{
+    QString output;
+    for (const QJsonValue& value : results) {
+        output += QString::fromUtf8(QJsonDocument(value.toObject()).toJson(QJsonDocument::Compact));
+        output += QLatin1Char('\n');
+    }
+    return output;
+}
+
+QString SearchFormat::renderAlfred(const QJsonArray& results, const QString& query,
+                                  const QString& homeDir, const QJsonObject& instantAnswer,
+                                  const QJsonObject& definition,
+                                  const QJsonArray& applications,
+                                  const QJsonArray& settings)
+{
+    if (results.isEmpty() && instantAnswer.isEmpty() && definition.isEmpty()
+        && applications.isEmpty() && settings.isEmpty()) {
+        return renderAlfredMessage(QStringLiteral("No results"),
+                                   QStringLiteral("Nothing indexed matches \"%1\"").arg(query));
+    }
+
+    QJsonArray items;
+    if (!instantAnswer.isEmpty()) {
+        const QString value = instantAnswer.value(QStringLiteral("value")).toString();
+        const QString unit = instantAnswer.value(QStringLiteral("unit")).toString();
+        const QString display = instantAnswer.value(QStringLiteral("display")).toString();
+
+        QJsonObject variables;
+        variables[QStringLiteral("action")] = QStringLiteral("copy");
+
+        QJsonObject text;
+        text[QStringLiteral("copy")] = value;
+        text[QStringLiteral("largetype")] = display;
+
+        QJsonObject item;
+        item[QStringLiteral("title")] =
+            unit.isEmpty() ? value : QStringLiteral("%1 %2").arg(value, unit);
+        item[QStringLiteral("subtitle")] = display;
+        item[QStringLiteral("arg")] = value;
+        item[QStringLiteral("variables")] = variables;
+        item[QStringLiteral("text")] = text;
+        items.append(item);
+    }
+    for (const QJsonValue& value : applications) {
+        const QJsonObject app = value.toObject();
+        const QString path = app.value(QStringLiteral("path")).toString();
+
+        QJsonObject icon;
+        icon[QStringLiteral("type")] = QStringLiteral("fileicon");
+        icon[QStringLiteral("path")] = path;
+
+        QJsonObject variables;
+        variables[QStringLiteral("action")] = QStringLiteral("open");
+
+        QJsonObject item;
+        item[QStringLiteral("uid")] = path;
+        item[QStringLiteral("type")] = QStringLiteral("file");
+        item[QStringLiteral("title")] = app.value(QStringLiteral("name")).toString();
+        item[QStringLiteral("subtitle")] = abbreviateHome(path, homeDir);
+        item[QStringLiteral("arg")] = path;
+        item[QStringLiteral("icon")] = icon;
+        item[QStringLiteral("variables")] = variables;
+        items.append(item);
+    }
+    for (const QJsonValue& value : settings) {
+        const QJsonObject pane = value.toObject();
+        const QString url = pane.value(QStringLiteral("url")).toString();
+
+        QJsonObject icon;
+        icon[QStringLiteral("type")] = QStringLiteral("fileicon");
+        icon[QStringLiteral("path")] = QStringLiteral("/System/Applications/System Settings.app");
+
+        QJsonObject variables;
+        variables[QStringLiteral("action")] = QStringLiteral("open");
+
+        QJsonObject item;
+        item[QStringLiteral("uid")] = url;
+        item[QStringLiteral("title")] = pane.value(QStringLiteral("name")).toString();
+        item[QStringLiteral("subtitle")] = QStringLiteral("System Settings");
+        item[QStringLiteral("arg")] = url;
+        item[QStringLiteral("icon")] = icon;
+        item[QStringLiteral("variables")] = variables;
+        items.append(item);
+    }
+    for (const QJsonValue& value : results) {
+        const QJsonObject hit = value.toObject();
+        const QString path = hit.value(QStringLiteral("path")).toString();
+        const QString name = hit.value(QStringLiteral("name")).toString();
+        const QString displayPath = abbreviateHome(path, homeDir);
+
+        QJsonObject icon;
+        icon[QStringLiteral("type")] = QStringLiteral("fileicon");
+        icon[QStringLiteral("path")] = path;
+
+        QJsonObject variables;
+        variables[QStringLiteral("action")] = QStringLiteral("open");
+        variables[QStringLiteral("itemId")] =
+            QString::number(hit.value(QStringLiteral("itemId")).toInteger());
+
+        QJsonObject mods;
+        mods[QStringLiteral("cmd")] =
+            alfredMod(path, QStringLiteral("Reveal in Finder"), QStringLiteral("reveal"));
+        mods[QStringLiteral("alt")] =
+            alfredMod(path, QStringLiteral("Copy path"), QStringLiteral("copyPath"));
+
+        const QString snippet = hit.value(QStringLiteral("snippet")).toString().simplified();
+        QJsonObject text;
+        text[QStringLiteral("copy")] = path;
+        text[QStringLiteral("largetype")] = snippet.isEmpty() ? displayPath : snippet;
+
+        QJsonObject item;
+        item[QStringLiteral("uid")] = path;
+        item[QStringLiteral("type")] = QStringLiteral("file");
+        item[QStringLiteral("title")] = name;
+        item[QStringLiteral("subtitle")] = displayPath;
+        item[QStringLiteral("arg")] = path;
+        item[QStringLiteral("autocomplete")] = name;
+        item[QStringLiteral("icon")] = icon;
+        item[QStringLiteral("quicklookurl")] = path;
+        item[QStringLiteral("variables")] = variables;
+        item[QStringLiteral("mods")] = mods;
+        item[QStringLiteral("text")] = text;
+        items.append(item);
+    }
+    if (!definition.isEmpty()) {
+        const QString word = definition.value(QStringLiteral("word")).toString();
+        const QString partOfSpeech = definition.value(QStringLiteral("partOfSpeech")).toString();
+        const QString summary = definition.value(QStringLiteral("summary")).toString();
+
+        QJsonObject variables;
+        variables[QStringLiteral("action")] = QStringLiteral("open");
+
+        QJsonObject text;
+        text[QStringLiteral("copy")] = summary;
+        text[QStringLiteral("largetype")] = summary;
+
+        QJsonObject item;
+        item[QStringLiteral("title")] =
+            partOfSpeech.isEmpty() ? word : QStringLiteral("%1 (%2)").arg(word, partOfSpeech);
+        item[QStringLiteral("subtitle")] = summary;
+        item[QStringLiteral("arg")] = definition.value(QStringLiteral("url")).toString();
+        item[QStringLiteral("variables")] = variables;
+        item[QStringLiteral("text")] = text;
+        items.append(item);
+    }
+    return alfredDocument(items);
+}
+
+QString SearchFormat::renderAlfredMessage(const QString& title, const QString& subtitle)
+{
+    QJsonObject item;
+    item[QStringLiteral("title")] = title;
+    item[QStringLiteral("subtitle")] = subtitle;
+    item[QStringLiteral("valid")] = false;
+    return alfredDocument(QJsonArray{item});
+}
+
+} // namespace bs
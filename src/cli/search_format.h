@@ -0,0 +1,59 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// SearchFormat — pure helpers behind `bspot search`: turning filter flag
+// values into `search` params and rendering results for terminals and
+// pipelines. No I/O, so they can be unit tested.
+class SearchFormat {
+public:
+    // Columns accepted by --columns, and the default selection.
+    static QStringList columnNames();
+    static QStringList defaultColumns();
+
+    // Epoch seconds for an absolute date or date-time (2026-01-31,
+    // 2026-01-31T09:00:00Z) or an age relative to nowSecs (30m, 12h, 7d, 2w).
+    static std::optional<double> parseTime(const QString& value, qint64 nowSecs);
+
+    // Bytes for 512, 10k, 5M, 1G (binary units, suffix case-insensitive).
+    static std::optional<qint64> parseSize(const QString& value);
+
+    // One cell of column output; empty when the hit has no such field.
+    static QString columnValue(const QJsonObject& hit, const QString& column);
+
+    // Space-aligned columns, one hit per line; the last column is not
+    // padded. Scripts that need exact fields should use --json/--ndjson.
+    static QString renderColumns(const QJsonArray& results,
+                                 const QStringList& columns,
+                                 bool header);
+
+    // One compact JSON object per line.
+    static QString renderNdjson(const QJsonArray& results);
+
+    // Alfred script-filter JSON (`{"items": [...]}`) in ranked order: the
+    // file icon, a ~-abbreviated path as subtitle, and the path as `arg`
+    // with an `action` workflow variable (open; reveal on Cmd; copyPath on
+    // Alt). An `instantAnswer` from the search response comes first, with
+    // its value as `arg` and `action` set to `copy`, then matching
+    // `applications` and System Settings panes (`settings`, deep link as
+    // `arg`); a `definition` comes last, with its dict:// URL as `arg`.
+    // With none of them and no results, one non-actionable row.
+    static QString renderAlfred(const QJsonArray& results, const QString& query,
+                                const QString& homeDir,
+                                const QJsonObject& instantAnswer = QJsonObject(),
+                                const QJsonObject& definition = QJsonObject(),
+                                const QJsonArray& applications = QJsonArray(),
+                                const QJsonArray& settings = QJsonArray());
+
+    // A single non-actionable Alfred row, for errors and empty results.
+    static QString renderAlfredMessage(const QString& title, const QString& subtitle);
+};
+
+} // namespace bs
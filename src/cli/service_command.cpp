@@ -0,0 +1,253 @@
+#include "cli/cli_common.h"
+#include "cli/launch_agent.h"
+
+#include <QCoreApplication>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QProcess>
+#include <QSaveFile>
+
+#include <unistd.h>
+
+namespace bs {
+
+namespace {
+
+constexpr int kLaunchctlTimeoutMs = 10000;
+
+QString guiDomain()
+{
+    return QStringLiteral("gui/%1").arg(static_cast<qulonglong>(::getuid()));
+}
+
+int runLaunchctl(const QStringList& args, QString* output = nullptr)
+{
+    QProcess process;
+    process.setProcessChannelMode(QProcess::MergedChannels);
+    process.start(QStringLiteral("/bin/launchctl"), args);
+    if (!process.waitForStarted(kLaunchctlTimeoutMs)
+        || !process.waitForFinished(kLaunchctlTimeoutMs)) {
+        if (output) {
+            *output = QStringLiteral("launchctl did not complete");
+        }
+        process.kill();
+        return -1;
+    }
+    if (output) {
+        *output = QString::fromUtf8(process.readAll());
+    }
+    return process.exitStatus() == QProcess::NormalExit ? process.exitCode() : -1;
+}
+
+QCommandLineOption labelOption()
+{
+    return QCommandLineOption(QStringLiteral("label"),
+                              QStringLiteral("launchd job label."),
+                              QStringLiteral("label"),
+                              LaunchAgent::defaultLabel());
+}
+
+int serviceInstall(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Install a LaunchAgent that starts BetterSpotlight at login "
+                       "and restarts it after a crash."));
+    const QCommandLineOption label = labelOption();
+    const QCommandLineOption programOption(
+        QStringLiteral("program"),
+        QStringLiteral("Path to the BetterSpotlight executable (auto-detected)."),
+        QStringLiteral("path"));
+    const QCommandLineOption noLoadOption(
+        QStringLiteral("no-load"),
+        QStringLiteral("Write the plist without loading it into launchd."));
+    parser.addOptions({label, programOption, noLoadOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot service install"),
+                                                args)) {
+        return exitCode.value();
+    }
+
+    QString program = parser.value(programOption);
+    if (program.isEmpty()) {
+        program = LaunchAgent::locateAppExecutable(QCoreApplication::applicationFilePath());
+    }
+    if (program.isEmpty() || !QFileInfo(program).isExecutable()) {
+        cliErr() << "Could not find the BetterSpotlight executable; pass --program." << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    LaunchAgentConfig config;
+    config.label = parser.value(label);
+    config.programPath = QFileInfo(program).absoluteFilePath();
+    config.stdoutPath = LaunchAgent::logDirectory() + QStringLiteral("/agent.log");
+    config.stderrPath = LaunchAgent::logDirectory() + QStringLiteral("/agent.err.log");
+
+    const QString plistPath = LaunchAgent::plistPath(config.label);
+    if (!QDir().mkpath(QFileInfo(plistPath).absolutePath())
+        || !QDir().mkpath(LaunchAgent::logDirectory())) {
+        cliErr() << "Failed to create LaunchAgents or log directory." << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    // Replacing an existing job: launchd keeps the old definition until bootout.
+    const QString target = guiDomain() + QLatin1Char('/') + config.label;
+    if (!parser.isSet(noLoadOption)) {
+        runLaunchctl({QStringLiteral("bootout"), target});
+    }
+
+    QSaveFile file(plistPath);
+    if (!file.open(QIODevice::WriteOnly)
+        || file.write(LaunchAgent::renderPlist(config)) < 0
+        || !file.commit()) {
+        cliErr() << "Failed to write " << plistPath << ": " << file.errorString() << Qt::endl;
+        return kCliExitFailure;
+    }
+    cliOut() << "Wrote " << plistPath << Qt::endl;
+    cliOut() << "Logs:  " << config.stdoutPath << Qt::endl;
+
+    if (parser.isSet(noLoadOption)) {
+        return kCliExitOk;
+    }
+    QString output;
+    if (runLaunchctl({QStringLiteral("bootstrap"), guiDomain(), plistPath}, &output) != 0) {
+        cliErr() << "launchctl bootstrap failed: " << output.trimmed() << Qt::endl;
+        return kCliExitFailure;
+    }
+    cliOut() << "Loaded " << config.label << Qt::endl;
+    return kCliExitOk;
+}
+
+int serviceUninstall(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Unload and remove the BetterSpotlight LaunchAgent."));
+    const QCommandLineOption label = labelOption();
+    parser.addOption(label);
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot service uninstall"),
+                                                args)) {
+        return exitCode.value();
+    }
+
+    const QString jobLabel = parser.value(label);
+    const QString plistPath = LaunchAgent::plistPath(jobLabel);
+    const int bootoutCode =
+        runLaunchctl({QStringLiteral("bootout"), guiDomain() + QLatin1Char('/') + jobLabel});
+    const bool hadPlist = QFile::exists(plistPath);
+    if (hadPlist && !QFile::remove(plistPath)) {
+        cliErr() << "Failed to remove " << plistPath << Qt::endl;
+        return kCliExitFailure;
+    }
+    if (!hadPlist && bootoutCode != 0) {
+        cliOut() << jobLabel << " is not installed" << Qt::endl;
+        return kCliExitOk;
+    }
+    cliOut() << "Removed " << jobLabel << Qt::endl;
+    return kCliExitOk;
+}
+
+int serviceStatus(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Show LaunchAgent state and whether the services answer."));
+    const QCommandLineOption label = labelOption();
+    const QCommandLineOption jsonOption(QStringLiteral("json"),
+                                        QStringLiteral("Print machine-readable JSON."));
+    parser.addOptions({label, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot service status"),
+                                                args)) {
+        return exitCode.value();
+    }
+
+    const QString jobLabel = parser.value(label);
+    const QString plistPath = LaunchAgent::plistPath(jobLabel);
+    QString printOutput;
+    const int printCode = runLaunchctl(
+        {QStringLiteral("print"), guiDomain() + QLatin1Char('/') + jobLabel}, &printOutput);
+    const LaunchAgentStatus status =
+        LaunchAgent::parseLaunchctlPrint(printCode == 0 ? printOutput : QString());
+
+    QJsonObject services;
+    for (const QString& name : {QStringLiteral("indexer"), QStringLiteral("query")}) {
+        QString error;
+        const auto response = callService(name, QStringLiteral("ping"), {}, 1000, &error);
+        services[name] = response.has_value()
+            && response->value(QStringLiteral("type")).toString() == QLatin1String("response");
+    }
+
+    QJsonObject report;
+    report[QStringLiteral("label")] = jobLabel;
+    report[QStringLiteral("installed")] = QFile::exists(plistPath);
+    report[QStringLiteral("plistPath")] = plistPath;
+    report[QStringLiteral("loaded")] = status.loaded;
+    report[QStringLiteral("state")] = status.state;
+    report[QStringLiteral("pid")] = status.pid;
+    report[QStringLiteral("runs")] = status.runs;
+    if (status.lastExitCode.has_value()) {
+        report[QStringLiteral("lastExitCode")] = status.lastExitCode.value();
+    }
+    report[QStringLiteral("logDirectory")] = LaunchAgent::logDirectory();
+    report[QStringLiteral("services")] = services;
+
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(report).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else {
+        cliOut() << "LaunchAgent: " << jobLabel
+                 << (report.value(QStringLiteral("installed")).toBool() ? "" : " (not installed)")
+                 << Qt::endl;
+        cliOut() << "  loaded:    " << (status.loaded ? "yes" : "no") << Qt::endl;
+        if (status.loaded) {
+            cliOut() << "  state:     " << status.state << Qt::endl;
+            if (status.pid > 0) {
+                cliOut() << "  pid:       " << status.pid << Qt::endl;
+            }
+            cliOut() << "  runs:      " << status.runs << Qt::endl;
+            if (status.lastExitCode.has_value()) {
+                cliOut() << "  last exit: " << status.lastExitCode.value() << Qt::endl;
+            }
+        }
+        cliOut() << "  logs:      " << LaunchAgent::logDirectory() << Qt::endl;
+        for (auto it = services.constBegin(); it != services.constEnd(); ++it) {
+            cliOut() << "  " << it.key() << ": "
+                     << (it.value().toBool() ? "responding" : "not responding") << Qt::endl;
+        }
+    }
+    return status.loaded ? kCliExitOk : kCliExitFailure;
+}
+
+void printServiceUsage(QTextStream& stream)
+{
+    stream << "Usage: bspot service <install|uninstall|status> [options]\n"
+              "\n"
+              "  install    Write and load the LaunchAgent (restarts on crash)\n"
+              "  uninstall  Unload and remove the LaunchAgent\n"
+              "  status     Show launchd state and service liveness\n"
+           << Qt::flush;
+}
+
+} // namespace
+
+int runServiceCommand(const QStringList& args)
+{
+    if (args.isEmpty()) {
+        printServiceUsage(cliErr());
+        return kCliExitUsage;
+    }
+    const QString action = args.first();
+    const QStringList rest = args.mid(1);
+    if (action == QLatin1String("install"))   return serviceInstall(rest);
+    if (action == QLatin1String("uninstall")) return serviceUninstall(rest);
+    if (action == QLatin1String("status"))    return serviceStatus(rest);
+    if (action == QLatin1String("--help") || action == QLatin1String("-h")) {
+        printServiceUsage(cliOut());
+        return kCliExitOk;
+    }
+    cliErr() << "bspot service: unknown action '" << action << "'" << Qt::endl;
+    printServiceUsage(cliErr());
+    return kCliExitUsage;
+}
+
+} // namespace bs
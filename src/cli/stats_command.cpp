@@ -0,0 +1,240 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// Walks every item row, so allow a large index the time to answer.
+constexpr int kDefaultTimeoutMs = 60000;
+constexpr int kQueryStatsTimeoutMs = 5000;
+constexpr int kDefaultTop = 10;
+
+QString formatBytes(qint64 bytes)
+{
+    const double value = static_cast<double>(bytes);
+    if (bytes < 1024 * 1024) {
+        return QStringLiteral("%1 KB").arg(QString::number(value / 1024.0, 'f', 1));
+    }
+    if (bytes < 1024LL * 1024 * 1024) {
+        return QStringLiteral("%1 MB").arg(QString::number(value / (1024.0 * 1024.0), 'f', 1));
+    }
+    return QStringLiteral("%1 GB").arg(
+        QString::number(value / (1024.0 * 1024.0 * 1024.0), 'f', 2));
+}
+
+// "  pdf          120.3 MB   31%  1204 files", share of `of` bytes.
+QString renderBuckets(const QString& title, const QJsonArray& buckets, qint64 of,
+                      bool withItems)
+{
+    if (buckets.isEmpty()) {
+        return QString();
+    }
+    qsizetype width = 0;
+    for (const QJsonValue& value : buckets) {
+        width = std::max(width, value.toObject().value(QStringLiteral("name")).toString().size());
+    }
+    QString out = title + QStringLiteral(":\n");
+    for (const QJsonValue& value : buckets) {
+        const QJsonObject bucket = value.toObject();
+        const qint64 bytes = bucket.value(QStringLiteral("bytes")).toInteger();
+        const double pct = of > 0 ? 100.0 * static_cast<double>(bytes) / static_cast<double>(of)
+                                  : 0.0;
+        out += QStringLiteral("  %1  %2  %3%")
+                   .arg(bucket.value(QStringLiteral("name")).toString().leftJustified(width),
+                        formatBytes(bytes).rightJustified(9))
+                   .arg(pct, 3, 'f', 0);
+        if (withItems) {
+            out += QStringLiteral("  %1 files").arg(bucket.value(QStringLiteral("items")).toInteger());
+        }
+        out += QLatin1Char('\n');
+    }
+    return out;
+}
+
+QString formatMs(const QJsonValue& value)
+{
+    return QStringLiteral("%1 ms").arg(QString::number(value.toDouble(), 'f', 1));
+}
+
+// One row per window, then each method over the longest window.
+QString renderQueryStats(const QJsonObject& stats)
+{
+    const QJsonArray windows = stats.value(QStringLiteral("windows")).toArray();
+    if (windows.isEmpty()) {
+        return QString();
+    }
+    QString out = QStringLiteral("Queries:           requests  errors  cache hits"
+                                 "  search p50 p95        p99\n");
+    for (const QJsonValue& value : windows) {
+        const QJsonObject window = value.toObject();
+        const QJsonValue hitRate = window.value(QStringLiteral("cacheHitRate"));
+        const QJsonObject search = window.value(QStringLiteral("methods")).toObject()
+                                       .value(QStringLiteral("search")).toObject();
+        out += QStringLiteral("  last %1 %2 %3 %4")
+                   .arg(window.value(QStringLiteral("window")).toString().leftJustified(11),
+                        QString::number(window.value(QStringLiteral("requests")).toInteger())
+                            .rightJustified(8),
+                        QString::number(window.value(QStringLiteral("errors")).toInteger())
+                            .rightJustified(7),
+                        (hitRate.isDouble()
+                             ? QStringLiteral("%1%").arg(hitRate.toDouble() * 100.0, 0, 'f', 0)
+                             : QStringLiteral("-"))
+                            .rightJustified(11));
+        if (!search.isEmpty()) {
+            out += QStringLiteral("  %1 %2 %3")
+                       .arg(formatMs(search.value(QStringLiteral("p50Ms"))).rightJustified(10),
+                            formatMs(search.value(QStringLiteral("p95Ms"))).leftJustified(10),
+                            formatMs(search.value(QStringLiteral("p99Ms"))));
+        }
+        out += QLatin1Char('\n');
+    }
+
+    const QJsonObject longest = windows.last().toObject();
+    const QJsonObject methods = longest.value(QStringLiteral("methods")).toObject();
+    if (!methods.isEmpty()) {
+        out += QStringLiteral("\nBy method, last %1:\n")
+                   .arg(longest.value(QStringLiteral("window")).toString());
+        qsizetype width = 0;
+        for (auto it = methods.begin(); it != methods.end(); ++it) {
+            width = std::max(width, it.key().size());
+        }
+        for (auto it = methods.begin(); it != methods.end(); ++it) {
+            const QJsonObject method = it.value().toObject();
+            out += QStringLiteral("  %1  %2 requests  %3 errors  p50 %4  p95 %5  p99 %6\n")
+                       .arg(it.key().leftJustified(width),
+                            QString::number(method.value(QStringLiteral("requests")).toInteger())
+                                .rightJustified(6),
+                            QString::number(method.value(QStringLiteral("errors")).toInteger()),
+                            formatMs(method.value(QStringLiteral("p50Ms"))),
+                            formatMs(method.value(QStringLiteral("p95Ms"))),
+                            formatMs(method.value(QStringLiteral("p99Ms"))));
+        }
+    }
+    return out + QLatin1Char('\n');
+}
+
+QString renderStats(const QJsonObject& result, bool breakdown)
+{
+    const qint64 total = result.value(QStringLiteral("totalBytes")).toInteger();
+    const qint64 fileBytes = result.value(QStringLiteral("fileBytes")).toInteger();
+    const bool measured = result.value(QStringLiteral("measured")).toBool();
+
+    QString out = QStringLiteral("Index: %1").arg(formatBytes(total));
+    if (measured) {
+        out += QStringLiteral(" (%1 in items, content and search_index)").arg(formatBytes(fileBytes));
+    }
+    out += QStringLiteral("\n\n");
+    if (measured) {
+        out += renderBuckets(QStringLiteral("Tables"),
+                             result.value(QStringLiteral("tables")).toArray(), total, false);
+    } else {
+        out += QStringLiteral("Table sizes unavailable (SQLite built without dbstat); "
+                              "estimates below split the whole index.\n");
+    }
+    if (!breakdown) {
+        return out;
+    }
+
+    // Everything below splits fileBytes by the text each file contributes.
+    out += QLatin1Char('\n')
+           + renderBuckets(QStringLiteral("Fields (estimated)"),
+                           result.value(QStringLiteral("fields")).toArray(), fileBytes, true)
+           + QLatin1Char('\n')
+           + renderBuckets(QStringLiteral("File types (estimated)"),
+                           result.value(QStringLiteral("fileTypes")).toArray(), fileBytes, true)
+           + QLatin1Char('\n')
+           + renderBuckets(QStringLiteral("Directories (estimated)"),
+                           result.value(QStringLiteral("directories")).toArray(), fileBytes,
+                           true);
+    return out;
+}
+
+} // namespace
+
+int runStatsCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Show request counts, errors, cache hit rate and latency percentiles "
+                       "over the last 1, 5 and 15 minutes, then how big the index is and "
+                       "which tables hold it. --breakdown adds "
+                       "the bytes attributable to each field (file name, path, content), file "
+                       "type and top-level directory; those are estimates that split the "
+                       "per-file tables by the text each file contributes.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 query service unavailable or "
+                       "failed."));
+    const QCommandLineOption breakdownOption(
+        QStringLiteral("breakdown"),
+        QStringLiteral("Add the split by field, file type and directory."));
+    const QCommandLineOption topOption(
+        QStringLiteral("top"),
+        QStringLiteral("File types and directories to list before \"(other)\" (default %1).")
+            .arg(kDefaultTop),
+        QStringLiteral("n"), QString::number(kDefaultTop));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"),
+        QStringLiteral("Print {\"queries\": getQueryStats, \"index\": getIndexBreakdown}."));
+    parser.addOptions({breakdownOption, topOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot stats"), args)) {
+        return exitCode.value();
+    }
+
+    bool ok = false;
+    const int top = parser.value(topOption).toInt(&ok);
+    if (!ok || top < 1) {
+        cliErr() << "bspot stats: --top must be a positive number" << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    // Directory names cover every root, so the breakdown is admin-only.
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QJsonObject params;
+    params[QStringLiteral("top")] = top;
+    QString error;
+    const auto response = callService(QStringLiteral("query"),
+                                      QStringLiteral("getIndexBreakdown"), params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot stats: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot stats: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+
+    // In-memory counters; if they cannot be read the index sizes still print.
+    QJsonObject queryStats;
+    const auto statsResponse = callService(QStringLiteral("query"),
+                                           QStringLiteral("getQueryStats"), {},
+                                           kQueryStatsTimeoutMs, &error);
+    if (statsResponse.has_value()
+        && statsResponse->value(QStringLiteral("type")).toString() == QLatin1String("response")) {
+        queryStats = statsResponse->value(QStringLiteral("result")).toObject();
+    }
+
+    if (parser.isSet(jsonOption)) {
+        QJsonObject combined;
+        combined[QStringLiteral("queries")] = queryStats;
+        combined[QStringLiteral("index")] = result;
+        cliOut() << QJsonDocument(combined).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    cliOut() << renderQueryStats(queryStats) << renderStats(result, parser.isSet(breakdownOption))
+             << Qt::flush;
+    return kCliExitOk;
+}
+
+} // namespace bs
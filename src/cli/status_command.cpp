@@ -0,0 +1,200 @@
+#include "cli/cli_common.h"
+#include "cli/status_format.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QCoreApplication>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 2000;
+
+QString describeEvent(const QJsonObject& event)
+{
+    const QString type = event.value(QStringLiteral("event")).toString();
+    const QString root = event.value(QStringLiteral("root")).toString();
+    const qint64 files = event.value(QStringLiteral("files")).toInteger();
+    if (type == QLatin1String("rootStarted")) {
+        return QStringLiteral("Scanning %1").arg(root);
+    }
+    if (type == QLatin1String("rootScanned")) {
+        return QStringLiteral("Scanned  %1: %2 files").arg(root).arg(files);
+    }
+    if (type == QLatin1String("rootCompleted")) {
+        return QStringLiteral("Indexed  %1: %2 files").arg(root).arg(files);
+    }
+    if (type == QLatin1String("fileFailed")) {
+        QString line = QStringLiteral("Failed   %1").arg(event.value(QStringLiteral("path")).toString());
+        const QString error = event.value(QStringLiteral("error")).toString();
+        if (!error.isEmpty()) {
+            line += QStringLiteral(" (%1)").arg(error);
+        }
+        return line;
+    }
+    if (type == QLatin1String("progress")) {
+        QString line = QStringLiteral("%1%  %2/%3  %4 items/s")
+                           .arg(event.value(QStringLiteral("percent")).toDouble(), 5, 'f', 1)
+                           .arg(event.value(QStringLiteral("processed")).toInteger())
+                           .arg(event.value(QStringLiteral("discovered")).toInteger())
+                           .arg(event.value(QStringLiteral("itemsPerSec")).toDouble(), 0, 'f', 1);
+        const QJsonValue eta = event.value(QStringLiteral("etaSeconds"));
+        if (eta.isDouble()) {
+            line += QStringLiteral("  ETA %1s").arg(eta.toInteger());
+        }
+        return line;
+    }
+    return type;
+}
+
+// `--follow`: print indexingEvent notifications until the crawl is complete
+// or the indexer goes away.
+int followEvents(bool json, int timeoutMs)
+{
+    bool serviceLost = false;  // outlives the client, whose teardown emits disconnected()
+    SocketClient client;
+    if (!client.connectToServer(ServiceBase::socketPath(QStringLiteral("indexer")), timeoutMs)) {
+        cliErr() << "bspot status: indexer service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    // Listen before asking, and drop what the snapshot already covers.
+    qint64 cursor = -1;
+    bool crawlComplete = false;
+    QList<QJsonObject> early;
+    const auto print = [&](const QJsonObject& event) {
+        if (event.value(QStringLiteral("seq")).toInteger() <= cursor) {
+            return;
+        }
+        if (json) {
+            cliOut() << QJsonDocument(event).toJson(QJsonDocument::Compact) << Qt::flush;
+        } else {
+            cliOut() << describeEvent(event) << Qt::endl;
+        }
+        if (event.value(QStringLiteral("event")).toString() == QLatin1String("progress")
+            && event.value(QStringLiteral("crawlComplete")).toBool()) {
+            crawlComplete = true;
+            QCoreApplication::quit();
+        }
+    };
+    client.setNotificationHandler([&](const QString& method, const QJsonObject& params) {
+        if (method != QLatin1String("indexingEvent")) {
+            return;
+        }
+        if (cursor < 0) {
+            early.append(params);
+        } else {
+            print(params);
+        }
+    });
+
+    QJsonObject params;
+    params[QStringLiteral("limit")] = 1;
+    const auto response = client.sendRequest(QStringLiteral("getIndexingEvents"), params,
+                                             timeoutMs);
+    if (!response.has_value()
+        || response->value(QStringLiteral("type")).toString() != QLatin1String("response")) {
+        cliErr() << "bspot status: indexer service did not answer 'getIndexingEvents'"
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    const QJsonObject progress = result.value(QStringLiteral("progress")).toObject();
+    if (progress.isEmpty() || progress.value(QStringLiteral("crawlComplete")).toBool()) {
+        if (!json) {
+            cliOut() << (progress.isEmpty() ? "Not indexing" : "Initial index complete")
+                     << Qt::endl;
+        }
+        return kCliExitOk;
+    }
+    cursor = result.value(QStringLiteral("currentSeq")).toInteger();
+    if (!json) {
+        cliOut() << QStringLiteral("%1/%2 files indexed so far")
+                        .arg(progress.value(QStringLiteral("processed")).toInteger())
+                        .arg(progress.value(QStringLiteral("discovered")).toInteger())
+                 << Qt::endl;
+    }
+    for (const QJsonObject& event : std::as_const(early)) {
+        print(event);
+    }
+
+    QObject::connect(&client, &SocketClient::disconnected, [&serviceLost]() {
+        serviceLost = true;
+        QCoreApplication::quit();
+    });
+    if (!crawlComplete) {
+        QCoreApplication::exec();
+    }
+    if (serviceLost) {
+        cliErr() << "bspot status: lost connection to the indexer service" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    return kCliExitOk;
+}
+
+} // namespace
+
+int runStatusCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Show indexing progress: documents indexed, queue depth, per-root "
+                       "progress, throughput and ETA. --follow prints indexing events "
+                       "(roots started, scanned and completed, failed files, progress) "
+                       "until the initial index is complete.\n"
+                       "Exit status: 0 ok, 2 usage error, 3 indexer unavailable or failed."));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw getIndexingProgress result."));
+    const QCommandLineOption timeoutOption(
+        QStringLiteral("timeout"),
+        QStringLiteral("Give up after this many milliseconds (default %1).").arg(kDefaultTimeoutMs),
+        QStringLiteral("ms"), QString::number(kDefaultTimeoutMs));
+    const QCommandLineOption followOption(
+        {QStringLiteral("f"), QStringLiteral("follow")},
+        QStringLiteral("Stream indexing events; with --json, one JSON object per line."));
+    parser.addOptions({jsonOption, timeoutOption, followOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot status"), args)) {
+        return exitCode.value();
+    }
+
+    bool ok = false;
+    const int timeoutMs = parser.value(timeoutOption).toInt(&ok);
+    if (!ok || timeoutMs < 1) {
+        cliErr() << "bspot status: --timeout must be a positive number of milliseconds"
+                 << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    if (parser.isSet(followOption)) {
+        return followEvents(parser.isSet(jsonOption), timeoutMs);
+    }
+
+    QString error;
+    const auto response = callService(QStringLiteral("indexer"),
+                                      QStringLiteral("getIndexingProgress"), {}, timeoutMs,
+                                      &error);
+    if (!response.has_value()) {
+        cliErr() << "bspot status: " << error << Qt::endl;
+        return kCliExitUnavailable;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << "bspot status: "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result).toJson(QJsonDocument::Indented) << Qt::flush;
+    } else {
+        cliOut() << StatusFormat::render(result) << Qt::flush;
+    }
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,226 @@
+#include "cli/status_format.h"
+
+#include <QJsonArray>
+#include <QStringList>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+QString stateLabel(const QString& state)
+{
+    if (state == QLatin1String("initial_crawl")) return QStringLiteral("initial crawl");
+    if (state == QLatin1String("rebuilding"))    return QStringLiteral("rebuilding index");
+    if (state == QLatin1String("incremental"))   return QStringLiteral("indexing changes");
+    if (state == QLatin1String("idle"))          return QStringLiteral("up to date");
+    if (state == QLatin1String("paused"))        return QStringLiteral("paused");
+    if (state == QLatin1String("stopped"))       return QStringLiteral("not indexing");
+    return state;
+}
+
+QString etaLabel(const QJsonObject& progress)
+{
+    const QString state = progress.value(QStringLiteral("state")).toString();
+    if (state == QLatin1String("idle") || state == QLatin1String("stopped")) {
+        return QStringLiteral("-");
+    }
+    const QJsonValue eta = progress.value(QStringLiteral("etaSeconds"));
+    if (eta.isDouble()) {
+        return StatusFormat::formatDuration(eta.toInteger());
+    }
+    for (const QJsonValue& root : progress.value(QStringLiteral("roots")).toArray()) {
+        if (!root.toObject().value(QStringLiteral("scanComplete")).toBool()) {
+            return QStringLiteral("unknown until the scan finishes");
+        }
+    }
+    return QStringLiteral("unknown");
+}
+
+QString accessLabel(const QString& reason)
+{
+    if (reason == QLatin1String("privacy_denied"))    return QStringLiteral("blocked by macOS privacy");
+    if (reason == QLatin1String("permission_denied")) return QStringLiteral("permission denied");
+    if (reason == QLatin1String("missing"))           return QStringLiteral("missing");
+    return reason;
+}
+
+// Unreadable roots and protected folders, then how to fix them. Empty
+// when the indexer can read everything it was given.
+QString renderAccess(const QJsonObject& access)
+{
+    QString out;
+    const auto append = [&out](const QJsonValue& value, const QString& kind) {
+        const QJsonObject folder = value.toObject();
+        out += QStringLiteral("  %1  (%2, %3)\n")
+                   .arg(folder.value(QStringLiteral("path")).toString(),
+                        accessLabel(folder.value(QStringLiteral("reason")).toString()), kind);
+    };
+    for (const QJsonValue& value : access.value(QStringLiteral("unreadableRoots")).toArray()) {
+        append(value, QStringLiteral("root skipped"));
+    }
+    for (const QJsonValue& value : access.value(QStringLiteral("protectedFolders")).toArray()) {
+        append(value, QStringLiteral("folder skipped"));
+    }
+    if (out.isEmpty()) {
+        return out;
+    }
+    out.prepend(QStringLiteral("\nNot readable:\n"));
+    const QStringList steps =
+        access.value(QStringLiteral("remediation")).toString().split(QLatin1Char('\n'),
+                                                                     Qt::SkipEmptyParts);
+    for (int i = 0; i < steps.size(); ++i) {
+        out += (i == 0 ? QStringLiteral("Fix: ") : QStringLiteral("     ")) + steps.at(i)
+               + QLatin1Char('\n');
+    }
+    return out;
+}
+
+QString formatRate(double bytesPerSec)
+{
+    if (bytesPerSec < 1024.0 * 1024.0) {
+        return QStringLiteral("%1 KB/s").arg(bytesPerSec / 1024.0, 0, 'f', 0);
+    }
+    return QStringLiteral("%1 MB/s").arg(bytesPerSec / (1024.0 * 1024.0), 0, 'f', 1);
+}
+
+// "12.3% CPU, 182 MB memory, 42 open files, disk 3.1 MB/s read, 80 KB/s
+// write"; parts the platform does not report are left out.
+QString resourcesLabel(const QJsonObject& resources)
+{
+    QStringList parts;
+    const QJsonValue cpu = resources.value(QStringLiteral("cpuPercent"));
+    if (cpu.isDouble()) {
+        parts.append(QStringLiteral("%1% CPU").arg(cpu.toDouble(), 0, 'f', 1));
+    }
+    const QJsonValue rss = resources.value(QStringLiteral("rssBytes"));
+    if (rss.isDouble()) {
+        parts.append(QStringLiteral("%1 MB memory").arg(rss.toInteger() / (1024 * 1024)));
+    }
+    const QJsonValue fds = resources.value(QStringLiteral("openFds"));
+    if (fds.isDouble()) {
+        parts.append(QStringLiteral("%1 open files").arg(fds.toInteger()));
+    }
+    const QJsonValue read = resources.value(QStringLiteral("diskReadBytesPerSec"));
+    const QJsonValue write = resources.value(QStringLiteral("diskWriteBytesPerSec"));
+    if (read.isDouble() && write.isDouble()) {
+        parts.append(QStringLiteral("disk %1 read, %2 write")
+                         .arg(formatRate(read.toDouble()), formatRate(write.toDouble())));
+    }
+    return parts.join(QStringLiteral(", "));
+}
+
+// Extractors that have run, busiest first: count, time, share of all
+// extraction time and failures. Empty before anything was extracted.
+QString renderExtractors(const QJsonArray& extractors)
+{
+    QList<QJsonObject> active;
+    for (const QJsonValue& value : extractors) {
+        const QJsonObject extractor = value.toObject();
+        if (extractor.value(QStringLiteral("extractions")).toInteger() > 0) {
+            active.append(extractor);
+        }
+    }
+    if (active.isEmpty()) {
+        return QString();
+    }
+    std::stable_sort(active.begin(), active.end(), [](const QJsonObject& a, const QJsonObject& b) {
+        return a.value(QStringLiteral("totalMs")).toInteger()
+            > b.value(QStringLiteral("totalMs")).toInteger();
+    });
+
+    QString out = QStringLiteral("\nExtractors:\n");
+    for (const QJsonObject& extractor : active) {
+        QString detail = QStringLiteral("%1 files, %2, %3% of extraction time")
+                             .arg(extractor.value(QStringLiteral("extractions")).toInteger())
+                             .arg(StatusFormat::formatDuration(
+                                 extractor.value(QStringLiteral("totalMs")).toInteger() / 1000))
+                             .arg(extractor.value(QStringLiteral("timeShare")).toDouble() * 100.0,
+                                  0, 'f', 0);
+        const qint64 failures = extractor.value(QStringLiteral("failures")).toInteger();
+        if (failures > 0) {
+            detail += QStringLiteral(", %1 failed").arg(failures);
+            const qint64 timeouts = extractor.value(QStringLiteral("timeouts")).toInteger();
+            if (timeouts > 0) {
+                detail += QStringLiteral(" (%1 timed out)").arg(timeouts);
+            }
+        }
+        out += QStringLiteral("  %1 %2\n")
+                   .arg(extractor.value(QStringLiteral("extractor")).toString(), -5)
+                   .arg(detail);
+    }
+    return out;
+}
+
+} // namespace
+
+QString StatusFormat::formatDuration(qint64 seconds)
+{
+    if (seconds < 0) {
+        seconds = 0;
+    }
+    if (seconds < 60) {
+        return QStringLiteral("%1s").arg(seconds);
+    }
+    if (seconds < 3600) {
+        return QStringLiteral("%1m %2s").arg(seconds / 60).arg(seconds % 60, 2, 10, QLatin1Char('0'));
+    }
+    if (seconds < 86400) {
+        return QStringLiteral("%1h %2m")
+            .arg(seconds / 3600)
+            .arg((seconds % 3600) / 60, 2, 10, QLatin1Char('0'));
+    }
+    return QStringLiteral("%1d %2h").arg(seconds / 86400).arg((seconds % 86400) / 3600);
+}
+
+QString StatusFormat::render(const QJsonObject& progress)
+{
+    QString out;
+    const auto line = [&out](const QString& label, const QString& value) {
+        out += QStringLiteral("%1 %2\n").arg(label + QLatin1Char(':'), -11).arg(value);
+    };
+
+    line(QStringLiteral("State"), stateLabel(progress.value(QStringLiteral("state")).toString()));
+    line(QStringLiteral("Indexed"),
+         QStringLiteral("%1 documents")
+             .arg(progress.value(QStringLiteral("indexedItems")).toInteger()));
+    line(QStringLiteral("Pending"),
+         QStringLiteral("%1 queued, %2 in progress")
+             .arg(progress.value(QStringLiteral("pending")).toInteger())
+             .arg(progress.value(QStringLiteral("processing")).toInteger()));
+    if (progress.contains(QStringLiteral("throughput"))) {
+        const double rate = progress.value(QStringLiteral("throughput")).toObject()
+                                .value(QStringLiteral("itemsPerSec")).toDouble();
+        line(QStringLiteral("Throughput"), QStringLiteral("%1 items/s").arg(rate, 0, 'f', 1));
+    }
+    line(QStringLiteral("ETA"), etaLabel(progress));
+    const QString resources = resourcesLabel(progress.value(QStringLiteral("resources")).toObject());
+    if (!resources.isEmpty()) {
+        line(QStringLiteral("Indexer"), resources);
+    }
+
+    const QString tail =
+        renderExtractors(progress.value(QStringLiteral("extractors")).toArray())
+        + renderAccess(progress.value(QStringLiteral("access")).toObject());
+    const QJsonArray roots = progress.value(QStringLiteral("roots")).toArray();
+    if (roots.isEmpty()) {
+        return out + tail;
+    }
+    out += QStringLiteral("\nRoots:\n");
+    for (const QJsonValue& value : roots) {
+        const QJsonObject root = value.toObject();
+        QString detail = QStringLiteral("%1/%2")
+                             .arg(root.value(QStringLiteral("processed")).toInteger())
+                             .arg(root.value(QStringLiteral("discovered")).toInteger());
+        if (!root.value(QStringLiteral("scanComplete")).toBool()) {
+            detail += QStringLiteral(", scanning");
+        }
+        out += QStringLiteral("  %1%  %2  (%3)\n")
+                   .arg(root.value(QStringLiteral("percent")).toDouble(), 5, 'f', 1)
+                   .arg(root.value(QStringLiteral("path")).toString(), detail);
+    }
+    return out + tail;
+}
+
+} // namespace bs
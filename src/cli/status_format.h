@@ -0,0 +1,23 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QString>
+
+namespace bs {
+
+// StatusFormat — renders an IndexerService `getIndexingProgress` result for
+// `bspot status`. No I/O, so it can be unit tested.
+class StatusFormat {
+public:
+    // "45s", "4m 10s", "2h 05m", "3d 4h".
+    static QString formatDuration(qint64 seconds);
+
+    // Multi-line human summary: state, totals, throughput, ETA, what the
+    // indexer process uses (CPU, memory, descriptors, disk IO), then one
+    // line per root with its percentage, time and failures per extractor,
+    // then any roots or protected folders the indexer cannot read and how
+    // to fix that.
+    static QString render(const QJsonObject& progress);
+};
+
+} // namespace bs
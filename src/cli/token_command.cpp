@@ -0,0 +1,208 @@
+#include "cli/cli_common.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QJsonArray>
+#include <QJsonDocument>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultTimeoutMs = 5000;
+
+QString absolutePath(const QString& argument)
+{
+    if (argument == QLatin1String("~") || argument.startsWith(QLatin1String("~/"))) {
+        return QDir::cleanPath(QDir::homePath() + argument.mid(1));
+    }
+    return QDir::cleanPath(QDir::current().absoluteFilePath(argument));
+}
+
+// Token methods are admin-only: a token is as good as the access it grants.
+std::optional<QJsonObject> callTokenMethod(const QString& command, const QString& method,
+                                           const QJsonObject& params)
+{
+    SocketClient::setDefaultAuthToken(IpcAuth::readTokenFile());
+    QString error;
+    const auto response = callService(QStringLiteral("query"), method, params,
+                                      kDefaultTimeoutMs, &error);
+    if (!response.has_value()) {
+        cliErr() << command << ": " << error << Qt::endl;
+        return std::nullopt;
+    }
+    if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        cliErr() << command << ": "
+                 << response->value(QStringLiteral("error")).toObject()
+                        .value(QStringLiteral("message")).toString()
+                 << Qt::endl;
+        return std::nullopt;
+    }
+    return response->value(QStringLiteral("result")).toObject();
+}
+
+QString renderToken(const QJsonObject& token)
+{
+    const QDateTime created = QDateTime::fromMSecsSinceEpoch(
+        static_cast<qint64>(token.value(QStringLiteral("createdAt")).toDouble() * 1000.0));
+    QStringList capabilities;
+    for (const QJsonValue& value : token.value(QStringLiteral("capabilities")).toArray()) {
+        capabilities.append(value.toString());
+    }
+    QStringList roots;
+    for (const QJsonValue& value : token.value(QStringLiteral("roots")).toArray()) {
+        roots.append(value.toString());
+    }
+    return QStringLiteral("%1  %2  %3  %4  %5")
+        .arg(token.value(QStringLiteral("id")).toString(),
+             created.toString(QStringLiteral("yyyy-MM-dd HH:mm")),
+             token.value(QStringLiteral("name")).toString().leftJustified(16),
+             capabilities.join(QLatin1Char(',')).leftJustified(22),
+             roots.isEmpty() ? QStringLiteral("(all roots)") : roots.join(QStringLiteral(", ")));
+}
+
+int tokenCreate(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Issue a scoped API token for an editor plugin or script. The token "
+                       "is printed once; only its hash is kept."));
+    const QCommandLineOption nameOption(
+        QStringLiteral("name"), QStringLiteral("Who the token is for, e.g. vscode."),
+        QStringLiteral("name"));
+    const QCommandLineOption capabilityOption(
+        QStringLiteral("capability"),
+        QStringLiteral("search, content, actions or metrics. Repeatable (default: search)."),
+        QStringLiteral("capability"));
+    const QCommandLineOption rootOption(
+        QStringLiteral("root"),
+        QStringLiteral("Only documents under this folder. Repeatable (default: all roots)."),
+        QStringLiteral("path"));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw createApiToken result."));
+    parser.addOptions({nameOption, capabilityOption, rootOption, jsonOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot token create"),
+                                                args)) {
+        return exitCode.value();
+    }
+    if (!parser.isSet(nameOption)) {
+        cliErr() << "bspot token create: --name is required" << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("name")] = parser.value(nameOption);
+    params[QStringLiteral("capabilities")] = QJsonArray::fromStringList(
+        parser.isSet(capabilityOption) ? parser.values(capabilityOption)
+                                       : QStringList{QStringLiteral("search")});
+    QJsonArray roots;
+    for (const QString& root : parser.values(rootOption)) {
+        roots.append(absolutePath(root));
+    }
+    params[QStringLiteral("roots")] = roots;
+
+    const auto result = callTokenMethod(QStringLiteral("bspot token create"),
+                                        QStringLiteral("createApiToken"), params);
+    if (!result.has_value()) {
+        return kCliExitUnavailable;
+    }
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result.value()).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    cliOut() << result->value(QStringLiteral("token")).toString() << Qt::endl;
+    cliErr() << "Issued token " << result->value(QStringLiteral("id")).toString()
+             << "; it is not shown again." << Qt::endl;
+    return kCliExitOk;
+}
+
+int tokenList(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(QStringLiteral("List issued API tokens."));
+    const QCommandLineOption jsonOption(
+        QStringLiteral("json"), QStringLiteral("Print the raw listApiTokens result."));
+    parser.addOption(jsonOption);
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot token list"),
+                                                args)) {
+        return exitCode.value();
+    }
+
+    const auto result = callTokenMethod(QStringLiteral("bspot token list"),
+                                        QStringLiteral("listApiTokens"), {});
+    if (!result.has_value()) {
+        return kCliExitUnavailable;
+    }
+    if (parser.isSet(jsonOption)) {
+        cliOut() << QJsonDocument(result.value()).toJson(QJsonDocument::Indented) << Qt::flush;
+        return kCliExitOk;
+    }
+    for (const QJsonValue& token : result->value(QStringLiteral("tokens")).toArray()) {
+        cliOut() << renderToken(token.toObject()) << '\n';
+    }
+    cliOut() << Qt::flush;
+    return kCliExitOk;
+}
+
+int tokenRevoke(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Revoke an API token by id. Requests made with it fail from then on."));
+    parser.addPositionalArgument(QStringLiteral("id"), QStringLiteral("Token id (see list)."));
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot token revoke"),
+                                                args)) {
+        return exitCode.value();
+    }
+    if (parser.positionalArguments().size() != 1) {
+        cliErr() << "bspot token revoke: expected exactly one token id" << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("id")] = parser.positionalArguments().first();
+    const auto result = callTokenMethod(QStringLiteral("bspot token revoke"),
+                                        QStringLiteral("revokeApiToken"), params);
+    if (!result.has_value()) {
+        return kCliExitUnavailable;
+    }
+    cliOut() << "Revoked " << result->value(QStringLiteral("id")).toString() << Qt::endl;
+    return kCliExitOk;
+}
+
+void printTokenUsage(QTextStream& stream)
+{
+    stream << "Usage: bspot token <create|list|revoke> [options]\n"
+              "\n"
+              "  create     Issue a token limited to capabilities and folders\n"
+              "  list       Show issued tokens (never the secrets)\n"
+              "  revoke     Invalidate a token by id\n"
+           << Qt::flush;
+}
+
+} // namespace
+
+int runTokenCommand(const QStringList& args)
+{
+    if (args.isEmpty()) {
+        printTokenUsage(cliErr());
+        return kCliExitUsage;
+    }
+    const QString action = args.first();
+    const QStringList rest = args.mid(1);
+    if (action == QLatin1String("create")) return tokenCreate(rest);
+    if (action == QLatin1String("list"))   return tokenList(rest);
+    if (action == QLatin1String("revoke")) return tokenRevoke(rest);
+    if (action == QLatin1String("--help") || action == QLatin1String("-h")) {
+        printTokenUsage(cliOut());
+        return kCliExitOk;
+    }
+    cliErr() << "bspot token: unknown action '" << action << "'" << Qt::endl;
+    printTokenUsage(cliErr());
+    return kCliExitUsage;
+}
+
+} // namespace bs
@@ -0,0 +1,300 @@
+#include "cli/cli_common.h"
+#include "cli/tui_model.h"
+
+#include "core/ipc/service_base.h"
+#include "core/ipc/socket_client.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+
+#include <poll.h>
+#include <sys/ioctl.h>
+#include <termios.h>
+#include <unistd.h>
+
+#include <algorithm>
+#include <cstdio>
+
+namespace bs {
+
+namespace {
+
+constexpr int kConnectTimeoutMs = 1000;
+constexpr int kSearchTimeoutMs = 2000;
+constexpr int kDebounceMs = 120;
+constexpr int kEscapeIdleMs = 30;
+
+// Raw, no-echo input plus the alternate screen; restored on destruction so
+// the shell is left usable even when the loop exits early.
+class TerminalSession {
+public:
+    bool begin()
+    {
+        if (::tcgetattr(STDIN_FILENO, &m_saved) != 0) {
+            return false;
+        }
+        termios raw = m_saved;
+        raw.c_iflag &= ~static_cast<tcflag_t>(ICRNL | IXON | ISTRIP | INPCK | BRKINT);
+        raw.c_lflag &= ~static_cast<tcflag_t>(ECHO | ICANON | ISIG | IEXTEN);
+        raw.c_cc[VMIN] = 0;
+        raw.c_cc[VTIME] = 0;
+        if (::tcsetattr(STDIN_FILENO, TCSAFLUSH, &raw) != 0) {
+            return false;
+        }
+        m_active = true;
+        write("\x1b[?1049h\x1b[H\x1b[2J");
+        return true;
+    }
+
+    ~TerminalSession()
+    {
+        if (m_active) {
+            write("\x1b[?1049l");
+            ::tcsetattr(STDIN_FILENO, TCSAFLUSH, &m_saved);
+        }
+    }
+
+    static void write(const QByteArray& bytes)
+    {
+        qsizetype offset = 0;
+        while (offset < bytes.size()) {
+            const ssize_t written =
+                ::write(STDOUT_FILENO, bytes.constData() + offset,
+                        static_cast<size_t>(bytes.size() - offset));
+            if (written <= 0) {
+                return;
+            }
+            offset += written;
+        }
+    }
+
+    static void size(int* columns, int* rows)
+    {
+        winsize ws {};
+        if (::ioctl(STDOUT_FILENO, TIOCGWINSZ, &ws) == 0 && ws.ws_col > 0 && ws.ws_row > 0) {
+            *columns = ws.ws_col;
+            *rows = ws.ws_row;
+        } else {
+            *columns = 80;
+            *rows = 24;
+        }
+    }
+
+private:
+    termios m_saved {};
+    bool m_active = false;
+};
+
+} // namespace
+
+int runTuiCommand(const QStringList& args)
+{
+    QCommandLineParser parser;
+    parser.setApplicationDescription(
+        QStringLiteral("Interactive search. Type to search; Up/Down (or Ctrl-P/N) to select, "
+                       "Enter to open, Ctrl-O to reveal in Finder, Ctrl-Y to copy the path, "
+                       "Esc or Ctrl-C to quit."));
+    parser.addPositionalArgument(QStringLiteral("query"), QStringLiteral("Initial query."),
+                                 QStringLiteral("[query...]"));
+    const QCommandLineOption limitOption(
+        {QStringLiteral("n"), QStringLiteral("limit")},
+        QStringLiteral("Maximum results (1-200, default 50)."), QStringLiteral("n"),
+        QStringLiteral("50"));
+    const QCommandLineOption privateOption(
+        QStringLiteral("private"),
+        QStringLiteral("Don't record these searches or the files opened from them."));
+    parser.addOptions({limitOption, privateOption});
+    if (const auto exitCode = parseCliArguments(parser, QStringLiteral("bspot tui"), args)) {
+        return exitCode.value();
+    }
+    bool ok = false;
+    const int limit = parser.value(limitOption).toInt(&ok);
+    if (!ok || limit < 1 || limit > 200) {
+        cliErr() << "bspot tui: --limit must be between 1 and 200" << Qt::endl;
+        return kCliExitUsage;
+    }
+    const bool privateMode = parser.isSet(privateOption);
+    if (!::isatty(STDIN_FILENO) || !::isatty(STDOUT_FILENO)) {
+        cliErr() << "bspot tui: needs an interactive terminal; use 'bspot search' in pipelines"
+                 << Qt::endl;
+        return kCliExitUsage;
+    }
+
+    SocketClient client;
+    const QString socketPath = ServiceBase::socketPath(QStringLiteral("query"));
+    if (!client.connectToServer(socketPath, kConnectTimeoutMs)) {
+        cliErr() << "bspot tui: query service is not running" << Qt::endl;
+        return kCliExitUnavailable;
+    }
+
+    TuiModel model;
+    const QString initialQuery = parser.positionalArguments().join(QLatin1Char(' '));
+    if (!initialQuery.isEmpty()) {
+        TuiKeyEvent event;
+        event.key = TuiKeyEvent::Key::Text;
+        event.text = initialQuery;
+        model.handleKey(event);
+    }
+
+    const auto runSearch = [&]() {
+        if (model.query().trimmed().isEmpty()) {
+            model.clearResults();
+            model.setStatus(QString());
+            return;
+        }
+        QJsonObject params;
+        params[QStringLiteral("query")] = model.query();
+        params[QStringLiteral("limit")] = limit;
+        if (privateMode) {
+            params[QStringLiteral("private")] = true;
+        }
+        // Keystroke searches are superseded quickly; don't let one run long.
+        params[QStringLiteral("deadlineMs")] =
+            QDateTime::currentMSecsSinceEpoch() + kSearchTimeoutMs - 250;
+        std::optional<QJsonObject> response;
+        for (int attempt = 0; attempt < 2 && !response.has_value(); ++attempt) {
+            if (!client.isConnected() && !client.connectToServer(socketPath, kConnectTimeoutMs)) {
+                break;
+            }
+            response = client.sendRequest(QStringLiteral("search"), params, kSearchTimeoutMs);
+            if (!response.has_value()) {
+                client.disconnect();
+            }
+        }
+        if (!response.has_value()) {
+            model.setStatus(QStringLiteral("Query service is not responding"));
+            return;
+        }
+        if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            model.clearResults();
+            model.setStatus(response->value(QStringLiteral("error")).toObject()
+                                .value(QStringLiteral("message")).toString());
+            return;
+        }
+        const QJsonObject result = response->value(QStringLiteral("result")).toObject();
+        model.setResults(result.value(QStringLiteral("results")).toArray(),
+                         result.value(QStringLiteral("queryTime")).toInt(),
+                         result.value(QStringLiteral("deadlineExceeded")).toBool());
+    };
+
+    // The query service runs the action and records it for frecency, the
+    // same as picking the result in the app.
+    const auto performAction = [&](const QString& action, const QString& doneLabel) {
+        const QJsonObject selected = model.selectedResult();
+        if (selected.isEmpty()) {
+            return;
+        }
+        QJsonObject params;
+        params[QStringLiteral("action")] = action;
+        params[QStringLiteral("itemId")] = selected.value(QStringLiteral("itemId"));
+        params[QStringLiteral("query")] = model.query();
+        params[QStringLiteral("position")] = model.selectedIndex();
+        if (privateMode) {
+            params[QStringLiteral("private")] = true;
+        }
+        if (!client.isConnected() && !client.connectToServer(socketPath, kConnectTimeoutMs)) {
+            model.setStatus(QStringLiteral("Query service is not responding"));
+            return;
+        }
+        const auto response =
+            client.sendRequest(QStringLiteral("performAction"), params, kSearchTimeoutMs);
+        if (!response.has_value()) {
+            client.disconnect();
+            model.setStatus(QStringLiteral("Query service is not responding"));
+        } else if (response->value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            model.setStatus(response->value(QStringLiteral("error")).toObject()
+                                .value(QStringLiteral("message")).toString());
+        } else {
+            model.setStatus(QStringLiteral("%1 %2").arg(
+                doneLabel, selected.value(QStringLiteral("path")).toString()));
+        }
+    };
+
+    TerminalSession terminal;
+    if (!terminal.begin()) {
+        cliErr() << "bspot tui: failed to configure the terminal" << Qt::endl;
+        return kCliExitFailure;
+    }
+
+    TuiKeyDecoder decoder;
+    qint64 searchDueAtMs = initialQuery.isEmpty() ? 0 : QDateTime::currentMSecsSinceEpoch();
+    int lastColumns = 0;
+    int lastRows = 0;
+    bool dirty = true;
+
+    while (true) {
+        const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+        if (searchDueAtMs > 0 && nowMs >= searchDueAtMs) {
+            searchDueAtMs = 0;
+            runSearch();
+            dirty = true;
+        }
+
+        // SIGWINCH is not handled; polling the size each wakeup is enough.
+        int columns = 0;
+        int rows = 0;
+        TerminalSession::size(&columns, &rows);
+        if (dirty || columns != lastColumns || rows != lastRows) {
+            TerminalSession::write(model.render(columns, rows).toUtf8());
+            lastColumns = columns;
+            lastRows = rows;
+            dirty = false;
+        }
+
+        int waitMs = 200;
+        if (searchDueAtMs > 0) {
+            waitMs = static_cast<int>(std::clamp<qint64>(searchDueAtMs - nowMs, 0, waitMs));
+        }
+        if (decoder.hasPending()) {
+            waitMs = std::min(waitMs, kEscapeIdleMs);
+        }
+        pollfd input {STDIN_FILENO, POLLIN, 0};
+        const int ready = ::poll(&input, 1, waitMs);
+
+        std::vector<TuiKeyEvent> events;
+        if (ready > 0) {
+            char bytes[256];
+            const ssize_t count = ::read(STDIN_FILENO, bytes, sizeof(bytes));
+            if (count <= 0) {
+                break;  // terminal went away
+            }
+            events = decoder.feed(QByteArray(bytes, static_cast<qsizetype>(count)));
+        } else if (ready == 0 && decoder.hasPending()) {
+            events = decoder.flushPendingEscape();
+        }
+
+        bool quit = false;
+        for (const TuiKeyEvent& event : events) {
+            const TuiModel::Action action = model.handleKey(event);
+            dirty = true;
+            switch (action) {
+            case TuiModel::Action::Search:
+                searchDueAtMs = QDateTime::currentMSecsSinceEpoch() + kDebounceMs;
+                break;
+            case TuiModel::Action::Open:
+                performAction(QStringLiteral("open"), QStringLiteral("Opened"));
+                break;
+            case TuiModel::Action::Reveal:
+                performAction(QStringLiteral("reveal"), QStringLiteral("Revealed"));
+                break;
+            case TuiModel::Action::CopyPath:
+                performAction(QStringLiteral("copyPath"), QStringLiteral("Copied"));
+                break;
+            case TuiModel::Action::Quit:
+                quit = true;
+                break;
+            case TuiModel::Action::None:
+                break;
+            }
+            if (quit) {
+                break;
+            }
+        }
+        if (quit) {
+            break;
+        }
+    }
+    return kCliExitOk;
+}
+
+} // namespace bs
@@ -0,0 +1,410 @@
+#include "cli/tui_model.h"
+#include "cli/search_format.h"
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+const QString kReset = QStringLiteral("\x1b[0m");
+const QString kBold = QStringLiteral("\x1b[1m");
+const QString kDim = QStringLiteral("\x1b[2m");
+const QString kReverse = QStringLiteral("\x1b[7m");
+const QString kHighlight = QStringLiteral("\x1b[1;33m");
+const QString kClearToEol = QStringLiteral("\x1b[K");
+
+// Keep the end of long text (paths, queries), which is the part that differs.
+QString fitLeft(const QString& text, int width)
+{
+    if (width <= 0) {
+        return QString();
+    }
+    if (text.size() <= width) {
+        return text;
+    }
+    return QChar(0x2026) + text.right(width - 1);
+}
+
+QString fitRight(const QString& text, int width)
+{
+    if (width <= 0) {
+        return QString();
+    }
+    if (text.size() <= width) {
+        return text;
+    }
+    return text.left(width - 1) + QChar(0x2026);
+}
+
+// Plain snippet plus a per-character highlight mask.
+struct MarkedText {
+    QString text;
+    std::vector<bool> marked;
+};
+
+MarkedText markedSnippet(const QJsonObject& hit)
+{
+    MarkedText marked;
+    marked.text = hit.value(QStringLiteral("snippet")).toString();
+    marked.text.replace(QLatin1Char('\n'), QLatin1Char(' '));
+    marked.text.replace(QLatin1Char('\t'), QLatin1Char(' '));
+    marked.marked.assign(static_cast<size_t>(marked.text.size()), false);
+    for (const QJsonValue& value : hit.value(QStringLiteral("highlights")).toArray()) {
+        const QJsonObject highlight = value.toObject();
+        const int offset = highlight.value(QStringLiteral("offset")).toInt(-1);
+        const int length = highlight.value(QStringLiteral("length")).toInt(0);
+        if (offset < 0 || length <= 0) {
+            continue;
+        }
+        const int end = std::min(static_cast<int>(marked.text.size()), offset + length);
+        for (int i = offset; i < end; ++i) {
+            marked.marked[static_cast<size_t>(i)] = true;
+        }
+    }
+    return marked;
+}
+
+QString applyMarks(const MarkedText& marked, int start, int length,
+                   const QString& open, const QString& close)
+{
+    QString out;
+    bool inMark = false;
+    const int end = std::min(static_cast<int>(marked.text.size()), start + length);
+    for (int i = start; i < end; ++i) {
+        const bool mark = marked.marked[static_cast<size_t>(i)];
+        if (mark != inMark) {
+            out += mark ? open : close;
+            inMark = mark;
+        }
+        out += marked.text.at(i);
+    }
+    if (inMark) {
+        out += close;
+    }
+    return out;
+}
+
+} // namespace
+
+std::vector<TuiKeyEvent> TuiKeyDecoder::feed(const QByteArray& bytes)
+{
+    m_buffer.append(bytes);
+    std::vector<TuiKeyEvent> events;
+    const auto emitKey = [&events](TuiKeyEvent::Key key) {
+        TuiKeyEvent event;
+        event.key = key;
+        events.push_back(event);
+    };
+
+    int i = 0;
+    while (i < m_buffer.size()) {
+        const unsigned char byte = static_cast<unsigned char>(m_buffer.at(i));
+
+        if (byte == 0x1b) {
+            if (i + 1 >= m_buffer.size()) {
+                break;  // ESC alone or the start of a sequence; wait for more
+            }
+            const char next = m_buffer.at(i + 1);
+            if (next != '[' && next != 'O') {
+                emitKey(TuiKeyEvent::Key::Escape);
+                ++i;
+                continue;
+            }
+            // CSI / SS3: parameters then a final byte in 0x40-0x7e.
+            int j = i + 2;
+            while (j < m_buffer.size()
+                   && (static_cast<unsigned char>(m_buffer.at(j)) < 0x40
+                       || static_cast<unsigned char>(m_buffer.at(j)) > 0x7e)) {
+                ++j;
+            }
+            if (j >= m_buffer.size()) {
+                break;
+            }
+            const QByteArray sequence = m_buffer.mid(i + 2, j - i - 1);
+            if (sequence == "A") {
+                emitKey(TuiKeyEvent::Key::Up);
+            } else if (sequence == "B") {
+                emitKey(TuiKeyEvent::Key::Down);
+            } else if (sequence == "5~") {
+                emitKey(TuiKeyEvent::Key::PageUp);
+            } else if (sequence == "6~") {
+                emitKey(TuiKeyEvent::Key::PageDown);
+            }
+            // Other sequences (arrows left/right, function keys) are ignored.
+            i = j + 1;
+            continue;
+        }
+
+        if (byte == '\r' || byte == '\n') {
+            emitKey(TuiKeyEvent::Key::Enter);
+        } else if (byte == 0x7f || byte == 0x08) {
+            emitKey(TuiKeyEvent::Key::Backspace);
+        } else if (byte == 0x03 || byte == 0x04) {
+            emitKey(TuiKeyEvent::Key::Interrupt);
+        } else if (byte == 0x0e) {
+            emitKey(TuiKeyEvent::Key::Down);      // Ctrl-N
+        } else if (byte == 0x10) {
+            emitKey(TuiKeyEvent::Key::Up);        // Ctrl-P
+        } else if (byte == 0x15) {
+            emitKey(TuiKeyEvent::Key::ClearLine);
+        } else if (byte == 0x17) {
+            emitKey(TuiKeyEvent::Key::DeleteWord);
+        } else if (byte == 0x0f) {
+            emitKey(TuiKeyEvent::Key::Reveal);
+        } else if (byte == 0x19) {
+            emitKey(TuiKeyEvent::Key::CopyPath);
+        } else if (byte >= 0x20) {
+            int length = 1;
+            if (byte >= 0xf0) {
+                length = 4;
+            } else if (byte >= 0xe0) {
+                length = 3;
+            } else if (byte >= 0xc0) {
+                length = 2;
+            }
+            if (i + length > m_buffer.size()) {
+                break;  // partial UTF-8 character
+            }
+            TuiKeyEvent event;
+            event.key = TuiKeyEvent::Key::Text;
+            event.text = QString::fromUtf8(m_buffer.mid(i, length));
+            if (!events.empty() && events.back().key == TuiKeyEvent::Key::Text) {
+                events.back().text += event.text;  // coalesce pasted text
+            } else {
+                events.push_back(event);
+            }
+            i += length;
+            continue;
+        }
+        ++i;
+    }
+    m_buffer.remove(0, i);
+    return events;
+}
+
+std::vector<TuiKeyEvent> TuiKeyDecoder::flushPendingEscape()
+{
+    std::vector<TuiKeyEvent> events;
+    if (m_buffer.startsWith('\x1b')) {
+        TuiKeyEvent event;
+        event.key = TuiKeyEvent::Key::Escape;
+        events.push_back(event);
+        m_buffer.remove(0, 1);
+        const auto rest = feed(QByteArray());
+        events.insert(events.end(), rest.begin(), rest.end());
+    }
+    return events;
+}
+
+TuiModel::Action TuiModel::handleKey(const TuiKeyEvent& event)
+{
+    using Key = TuiKeyEvent::Key;
+    switch (event.key) {
+    case Key::Text:
+        m_query += event.text;
+        return Action::Search;
+    case Key::Backspace:
+        if (m_query.isEmpty()) {
+            return Action::None;
+        }
+        m_query.chop(1);
+        return Action::Search;
+    case Key::ClearLine:
+        if (m_query.isEmpty()) {
+            return Action::None;
+        }
+        m_query.clear();
+        return Action::Search;
+    case Key::DeleteWord: {
+        if (m_query.isEmpty()) {
+            return Action::None;
+        }
+        QString trimmed = m_query;
+        while (!trimmed.isEmpty() && trimmed.back().isSpace()) {
+            trimmed.chop(1);
+        }
+        const int lastSpace = trimmed.lastIndexOf(QLatin1Char(' '));
+        m_query = lastSpace < 0 ? QString() : trimmed.left(lastSpace + 1);
+        return Action::Search;
+    }
+    case Key::Up:
+        moveSelection(-1);
+        return Action::None;
+    case Key::Down:
+        moveSelection(1);
+        return Action::None;
+    case Key::PageUp:
+        moveSelection(-10);
+        return Action::None;
+    case Key::PageDown:
+        moveSelection(10);
+        return Action::None;
+    case Key::Enter:
+        return m_results.isEmpty() ? Action::None : Action::Open;
+    case Key::Reveal:
+        return m_results.isEmpty() ? Action::None : Action::Reveal;
+    case Key::CopyPath:
+        return m_results.isEmpty() ? Action::None : Action::CopyPath;
+    case Key::Escape:
+    case Key::Interrupt:
+        return Action::Quit;
+    }
+    return Action::None;
+}
+
+void TuiModel::setResults(const QJsonArray& results, int queryTimeMs, bool incomplete)
+{
+    // Keep the highlighted file selected when it survives a refresh.
+    const QString selectedPath =
+        selectedResult().value(QStringLiteral("path")).toString();
+    m_results = results;
+    m_queryTimeMs = queryTimeMs;
+    m_incomplete = incomplete;
+    m_hasResults = true;
+    m_selected = 0;
+    for (int i = 0; i < m_results.size(); ++i) {
+        if (!selectedPath.isEmpty()
+            && m_results.at(i).toObject().value(QStringLiteral("path")).toString()
+                == selectedPath) {
+            m_selected = i;
+            break;
+        }
+    }
+    m_status.clear();
+}
+
+void TuiModel::clearResults()
+{
+    m_results = QJsonArray();
+    m_selected = 0;
+    m_hasResults = false;
+    m_incomplete = false;
+}
+
+void TuiModel::setStatus(const QString& status)
+{
+    m_status = status;
+}
+
+QJsonObject TuiModel::selectedResult() const
+{
+    if (m_selected < 0 || m_selected >= m_results.size()) {
+        return QJsonObject();
+    }
+    return m_results.at(m_selected).toObject();
+}
+
+void TuiModel::moveSelection(int delta)
+{
+    if (m_results.isEmpty()) {
+        return;
+    }
+    m_selected = std::clamp(m_selected + delta, 0, static_cast<int>(m_results.size()) - 1);
+}
+
+QString TuiModel::highlightSnippet(const QJsonObject& hit,
+                                   const QString& open, const QString& close)
+{
+    const MarkedText marked = markedSnippet(hit);
+    return applyMarks(marked, 0, static_cast<int>(marked.text.size()), open, close);
+}
+
+QString TuiModel::render(int columns, int rows) const
+{
+    columns = std::max(columns, 20);
+    rows = std::max(rows, 6);
+    QStringList lines;
+
+    const QString prompt = QStringLiteral("> ");
+    const QString visibleQuery = fitLeft(m_query, columns - prompt.size() - 1);
+    lines.append(kBold + prompt + kReset + visibleQuery);
+
+    QString status = m_status;
+    if (status.isEmpty()) {
+        if (m_query.trimmed().isEmpty()) {
+            status = QStringLiteral("Type to search");
+        } else if (m_hasResults) {
+            status = QStringLiteral("%1 result%2 · %3 ms%4")
+                         .arg(m_results.size())
+                         .arg(m_results.size() == 1 ? QString() : QStringLiteral("s"))
+                         .arg(m_queryTimeMs)
+                         .arg(m_incomplete ? QStringLiteral(" · incomplete") : QString());
+        }
+    }
+    const QString hints = QStringLiteral(
+        "↑↓ select  ⏎ open  ^O reveal  ^Y copy path  esc quit");
+    const int gap = columns - static_cast<int>(status.size()) - static_cast<int>(hints.size());
+    lines.append(kDim + (gap >= 2 ? status + QString(gap, QLatin1Char(' ')) + hints
+                                  : fitRight(status, columns)) + kReset);
+
+    const int resultCount = static_cast<int>(m_results.size());
+    const int previewRows = resultCount > 0 ? std::clamp(rows / 3, 4, 12) : 0;
+    const int listRows = rows - 2 - (previewRows > 0 ? previewRows + 1 : 0);
+    const int first = std::clamp(m_selected - listRows / 2, 0,
+                                 std::max(0, resultCount - listRows));
+    const int nameWidth = std::min(40, columns / 3);
+
+    for (int row = 0; row < listRows; ++row) {
+        const int index = first + row;
+        if (index >= resultCount) {
+            lines.append(QString());
+            continue;
+        }
+        const QJsonObject hit = m_results.at(index).toObject();
+        const QString name = hit.value(QStringLiteral("name")).toString();
+        const QString path = hit.value(QStringLiteral("path")).toString();
+        const QString directory = path.left(std::max(0, static_cast<int>(path.size())
+                                                            - static_cast<int>(name.size()) - 1));
+        QString text = QStringLiteral(" ") + fitRight(name, nameWidth).leftJustified(nameWidth)
+            + QStringLiteral("  ");
+        text += fitLeft(directory, columns - static_cast<int>(text.size()) - 1);
+        if (index == m_selected) {
+            lines.append(kReverse + text.leftJustified(columns - 1) + kReset);
+        } else {
+            lines.append(text.left(nameWidth + 3) + kDim + text.mid(nameWidth + 3) + kReset);
+        }
+    }
+
+    if (previewRows > 0) {
+        lines.append(kDim + QString(columns, QChar(0x2500)) + kReset);
+        const QJsonObject hit = selectedResult();
+        lines.append(kBold + fitLeft(hit.value(QStringLiteral("path")).toString(), columns - 1)
+                     + kReset);
+
+        QStringList facts;
+        for (const QString& column :
+             {QStringLiteral("kind"), QStringLiteral("size"), QStringLiteral("modified")}) {
+            const QString value = SearchFormat::columnValue(hit, column);
+            if (!value.isEmpty()) {
+                facts.append(value);
+            }
+        }
+        lines.append(kDim + fitRight(facts.join(QStringLiteral(" · ")), columns - 1) + kReset);
+
+        const MarkedText snippet = markedSnippet(hit);
+        const int width = columns - 1;
+        int offset = 0;
+        for (int row = 2; row < previewRows; ++row) {
+            if (offset >= snippet.text.size()) {
+                lines.append(QString());
+                continue;
+            }
+            lines.append(applyMarks(snippet, offset, width, kHighlight, kReset));
+            offset += width;
+        }
+    }
+
+    QString frame = QStringLiteral("\x1b[H");
+    for (int i = 0; i < rows; ++i) {
+        frame += i < lines.size() ? lines.at(i) : QString();
+        frame += kClearToEol;
+        if (i + 1 < rows) {
+            frame += QStringLiteral("\r\n");
+        }
+    }
+    frame += QStringLiteral("\x1b[1;%1H").arg(prompt.size() + visibleQuery.size() + 1);
+    return frame;
+}
+
+} // namespace bs
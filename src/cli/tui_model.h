@@ -0,0 +1,86 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <vector>
+
+namespace bs {
+
+// One decoded keypress from a raw-mode terminal.
+struct TuiKeyEvent {
+    enum class Key {
+        Text,        // printable input in `text`
+        Backspace,
+        Enter,
+        Escape,
+        Up,
+        Down,
+        PageUp,
+        PageDown,
+        ClearLine,   // Ctrl-U
+        DeleteWord,  // Ctrl-W
+        Reveal,      // Ctrl-O
+        CopyPath,    // Ctrl-Y
+        Interrupt,   // Ctrl-C
+    };
+    Key key = Key::Text;
+    QString text;
+};
+
+// TuiKeyDecoder — turns raw terminal bytes into key events. Incomplete
+// escape or UTF-8 sequences stay buffered until the next feed(); a lone ESC
+// is reported once flushPendingEscape() is called after a short idle.
+class TuiKeyDecoder {
+public:
+    std::vector<TuiKeyEvent> feed(const QByteArray& bytes);
+    std::vector<TuiKeyEvent> flushPendingEscape();
+    bool hasPending() const { return !m_buffer.isEmpty(); }
+
+private:
+    QByteArray m_buffer;
+};
+
+// TuiModel — state and rendering for `bspot tui`, kept free of terminal I/O.
+//
+// The screen is a one-line prompt, a status line, the result list and a
+// preview pane with the selected hit's metadata and highlighted snippet.
+class TuiModel {
+public:
+    enum class Action { None, Search, Open, Reveal, CopyPath, Quit };
+
+    // Apply a key; returns what the driver should do next. Search means the
+    // query text changed and results should be refreshed (after debounce).
+    Action handleKey(const TuiKeyEvent& event);
+
+    void setResults(const QJsonArray& results, int queryTimeMs, bool incomplete);
+    void clearResults();
+    void setStatus(const QString& status);
+
+    const QString& query() const { return m_query; }
+    int selectedIndex() const { return m_selected; }
+    QJsonObject selectedResult() const;
+
+    // Full ANSI frame for a terminal of the given size, ending with the
+    // cursor on the prompt.
+    QString render(int columns, int rows) const;
+
+    // Snippet with highlight spans wrapped in `open`/`close` markers.
+    static QString highlightSnippet(const QJsonObject& hit,
+                                    const QString& open, const QString& close);
+
+private:
+    void moveSelection(int delta);
+
+    QString m_query;
+    QJsonArray m_results;
+    int m_selected = 0;
+    int m_queryTimeMs = 0;
+    bool m_incomplete = false;
+    bool m_hasResults = false;
+    QString m_status;
+};
+
+} // namespace bs
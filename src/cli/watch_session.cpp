@@ -0,0 +1,59 @@
+#include "cli/watch_session.h"
+
+#include <utility>
+
+namespace bs {
+
+WatchSession::WatchSession(Print print, Unsubscribe unsubscribe)
+    : m_print(std::move(print))
+    , m_unsubscribe(std::move(unsubscribe))
+{
+}
+
+WatchSession::~WatchSession()
+{
+    finish();
+}
+
+void WatchSession::start(const QJsonObject& snapshot)
+{
+    m_subscriptionId = snapshot.value(QStringLiteral("subscriptionId")).toInteger();
+    printNew(snapshot.value(QStringLiteral("results")).toArray());
+}
+
+void WatchSession::handleNotification(const QString& method, const QJsonObject& params)
+{
+    if (method == QLatin1String("liveQueryUpdated") && m_subscriptionId != 0
+        && params.value(QStringLiteral("subscriptionId")).toInteger() == m_subscriptionId) {
+        printNew(params.value(QStringLiteral("added")).toArray());
+    }
+}
+
+void WatchSession::finish()
+{
+    if (m_subscriptionId == 0) {
+        return;
+    }
+    // Cleared first: the unsubscribe round trip can deliver notifications.
+    const qint64 subscriptionId = std::exchange(m_subscriptionId, 0);
+    if (m_unsubscribe) {
+        m_unsubscribe(subscriptionId);
+    }
+}
+
+void WatchSession::printNew(const QJsonArray& results)
+{
+    QJsonArray fresh;
+    for (const QJsonValue& value : results) {
+        const qint64 itemId = value.toObject().value(QStringLiteral("itemId")).toInteger();
+        if (!m_seen.contains(itemId)) {
+            m_seen.insert(itemId);
+            fresh.append(value);
+        }
+    }
+    if (!fresh.isEmpty() && m_print) {
+        m_print(fresh);
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,52 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QSet>
+#include <QString>
+
+#include <functional>
+
+namespace bs {
+
+// WatchSession — the bookkeeping behind `bspot search --watch`: which
+// subscription is ours, which items have been printed, and unsubscribing on
+// the way out. The socket and signal handling stay in search_command.cpp;
+// tests drive this directly.
+class WatchSession {
+public:
+    using Print = std::function<void(const QJsonArray& results)>;
+    using Unsubscribe = std::function<void(qint64 subscriptionId)>;
+
+    WatchSession(Print print, Unsubscribe unsubscribe);
+    // Calls finish(), so every way out of a watch releases the service's
+    // live query slot.
+    ~WatchSession();
+
+    WatchSession(const WatchSession&) = delete;
+    WatchSession& operator=(const WatchSession&) = delete;
+
+    // The subscribeQuery result: keeps its subscriptionId and prints its
+    // results.
+    void start(const QJsonObject& snapshot);
+
+    // A notification from the query service. They are broadcast to every
+    // client, so only liveQueryUpdated for our subscription prints, and each
+    // item prints once, even if it drops out and comes back.
+    void handleNotification(const QString& method, const QJsonObject& params);
+
+    // Unsubscribe, once; later notifications are ignored.
+    void finish();
+
+    qint64 subscriptionId() const { return m_subscriptionId; }
+
+private:
+    void printNew(const QJsonArray& results);
+
+    Print m_print;
+    Unsubscribe m_unsubscribe;
+    QSet<qint64> m_seen;
+    qint64 m_subscriptionId = 0;
+};
+
+} // namespace bs
@@ -1,4 +1,5 @@
 #include "core/embedding/embedding_manager.h"
+#include "core/shared/logging.h"
 #include "core/embedding/tokenizer.h"
 #include "core/models/model_registry.h"
 #include "core/models/model_session.h"
@@ -72,7 +73,7 @@ bool EmbeddingManager::initialize()
 {
 #if BS_WITH_ONNX
     if (!m_registry) {
-        qWarning() << "EmbeddingManager initialize failed: null registry";
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed: null registry";
         m_available = false;
         return false;
     }
@@ -83,8 +84,8 @@ bool EmbeddingManager::initialize()
 
     ModelSession* modelSession = m_registry->getSession(m_role);
     if (!modelSession || !modelSession->isAvailable()) {
-        qWarning() << "EmbeddingManager initialize failed:" << QString::fromStdString(m_role)
-                   << "session unavailable";
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed:" << QString::fromStdString(m_role)
+                               << "session unavailable";
         m_available = false;
         return false;
     }
@@ -93,14 +94,14 @@ bool EmbeddingManager::initialize()
 
     m_tokenizer = TokenizerFactory::create(entry, m_registry->modelsDir());
     if (!m_tokenizer || !m_tokenizer->isLoaded()) {
-        qWarning() << "EmbeddingManager initialize failed: tokenizer creation failed";
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed: tokenizer creation failed";
         m_available = false;
         return false;
     }
 
     m_embeddingSize = entry.dimensions;
     if (m_embeddingSize <= 0) {
-        qWarning() << "EmbeddingManager initialize failed: invalid dimensions" << m_embeddingSize;
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed: invalid dimensions" << m_embeddingSize;
         m_available = false;
         return false;
     }
@@ -112,7 +113,7 @@ bool EmbeddingManager::initialize()
 
     m_impl->session = static_cast<Ort::Session*>(modelSession->rawSession());
     if (!m_impl->session) {
-        qWarning() << "EmbeddingManager initialize failed: null ONNX session";
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed: null ONNX session";
         m_available = false;
         return false;
     }
@@ -120,7 +121,7 @@ bool EmbeddingManager::initialize()
 
     const auto& outputNames = modelSession->outputNames();
     if (outputNames.empty()) {
-        qWarning() << "EmbeddingManager initialize failed: no output names";
+        qCWarning(bsEmbedding) << "EmbeddingManager initialize failed: no output names";
         m_available = false;
         return false;
     }
@@ -129,7 +130,7 @@ bool EmbeddingManager::initialize()
     m_available = true;
     return true;
 #else
-    qWarning() << "EmbeddingManager initialize skipped: ONNX Runtime not enabled";
+    qCWarning(bsEmbedding) << "EmbeddingManager initialize skipped: ONNX Runtime not enabled";
     m_available = false;
     return false;
 #endif
@@ -205,7 +206,7 @@ std::vector<std::vector<float>> EmbeddingManager::embedBatch(const std::vector<Q
     }
 
     if (m_circuitBreaker.isOpen()) {
-        qWarning() << "EmbeddingManager circuit breaker is open, skipping inference";
+        qCWarning(bsEmbedding) << "EmbeddingManager circuit breaker is open, skipping inference";
         return {};
     }
 
@@ -266,7 +267,7 @@ std::vector<std::vector<float>> EmbeddingManager::embedBatch(const std::vector<Q
             1);
 
         if (outputs.empty() || !outputs[0].IsTensor()) {
-            qWarning() << "EmbeddingManager inference failed: missing tensor output";
+            qCWarning(bsEmbedding) << "EmbeddingManager inference failed: missing tensor output";
             return {};
         }
 
@@ -309,11 +310,11 @@ std::vector<std::vector<float>> EmbeddingManager::embedBatch(const std::vector<Q
             return embeddings;
         }
 
-        qWarning() << "EmbeddingManager inference failed: unsupported output shape";
+        qCWarning(bsEmbedding) << "EmbeddingManager inference failed: unsupported output shape";
         m_circuitBreaker.recordFailure();
         return {};
     } catch (const Ort::Exception& ex) {
-        qWarning() << "EmbeddingManager inference failed:" << ex.what();
+        qCWarning(bsEmbedding) << "EmbeddingManager inference failed:" << ex.what();
         m_circuitBreaker.recordFailure();
         return {};
     }
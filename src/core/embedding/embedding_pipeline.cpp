@@ -1,4 +1,5 @@
 #include "core/embedding/embedding_pipeline.h"
+#include "core/shared/logging.h"
 
 #include "core/embedding/embedding_manager.h"
 #include "core/vector/vector_index.h"
@@ -285,13 +286,13 @@ void EmbeddingPipeline::processBatch(const std::vector<UnembeddedItem>& batch)
         } else {
             embedding = m_embeddingManager->embed(batch[i].content);
             if (embedding.empty()) {
-                qWarning() << "EmbeddingPipeline individual embed failed for item" << batch[i].itemId;
+                qCWarning(bsEmbedding) << "EmbeddingPipeline individual embed failed for item" << batch[i].itemId;
                 continue;
             }
         }
 
         if (!processSingleEmbedding(batch[i].itemId, embedding)) {
-            qWarning() << "EmbeddingPipeline failed to persist embedding for item" << batch[i].itemId;
+            qCWarning(bsEmbedding) << "EmbeddingPipeline failed to persist embedding for item" << batch[i].itemId;
         }
     }
 }
@@ -299,15 +300,15 @@ void EmbeddingPipeline::processBatch(const std::vector<UnembeddedItem>& batch)
 bool EmbeddingPipeline::processSingleEmbedding(int64_t itemId, const std::vector<float>& embedding)
 {
     if (embedding.size() != static_cast<size_t>(m_vectorIndex->dimensions())) {
-        qWarning() << "EmbeddingPipeline invalid embedding size for item" << itemId
-                   << "expected" << m_vectorIndex->dimensions()
-                   << "got" << embedding.size();
+        qCWarning(bsEmbedding) << "EmbeddingPipeline invalid embedding size for item" << itemId
+                               << "expected" << m_vectorIndex->dimensions()
+                               << "got" << embedding.size();
         return false;
     }
 
     const uint64_t label = m_vectorIndex->addVector(embedding.data());
     if (label == std::numeric_limits<uint64_t>::max()) {
-        qWarning() << "EmbeddingPipeline addVector failed for item" << itemId;
+        qCWarning(bsEmbedding) << "EmbeddingPipeline addVector failed for item" << itemId;
         return false;
     }
 
@@ -327,7 +328,7 @@ bool EmbeddingPipeline::processSingleEmbedding(int64_t itemId, const std::vector
     if (!m_vectorStore->addMapping(itemId, label, modelId, generationId,
                                    m_vectorIndex->dimensions(), provider, 0,
                                    std::string("active"))) {
-        qWarning() << "EmbeddingPipeline addMapping failed for item" << itemId;
+        qCWarning(bsEmbedding) << "EmbeddingPipeline addMapping failed for item" << itemId;
         m_vectorIndex->deleteVector(label);
         return false;
     }
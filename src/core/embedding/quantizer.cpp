@@ -1,4 +1,5 @@
 #include "core/embedding/quantizer.h"
+#include "core/shared/logging.h"
 
 #include <QDebug>
 
@@ -14,7 +15,7 @@ QuantizedVector Quantizer::quantize(const std::vector<float>& embedding) const
     QuantizedVector qv;
 
     if (embedding.size() != static_cast<size_t>(kEmbeddingDimensions)) {
-        qWarning() << "Quantizer::quantize expected 384 values, got" << embedding.size();
+        qCWarning(bsEmbedding) << "Quantizer::quantize expected 384 values, got" << embedding.size();
         return qv;
     }
 
@@ -53,7 +54,7 @@ std::vector<float> Quantizer::dequantize(const QuantizedVector& qv) const
 {
     std::vector<float> output;
     if (qv.data.size() != static_cast<size_t>(kEmbeddingDimensions)) {
-        qWarning() << "Quantizer::dequantize expected 384 int8 values, got" << qv.data.size();
+        qCWarning(bsEmbedding) << "Quantizer::dequantize expected 384 int8 values, got" << qv.data.size();
         return output;
     }
 
@@ -71,7 +72,7 @@ std::vector<uint8_t> Quantizer::serialize(const QuantizedVector& qv) const
 {
     std::vector<uint8_t> buffer;
     if (qv.data.size() != static_cast<size_t>(kEmbeddingDimensions)) {
-        qWarning() << "Quantizer::serialize expected 384 int8 values, got" << qv.data.size();
+        qCWarning(bsEmbedding) << "Quantizer::serialize expected 384 int8 values, got" << qv.data.size();
         return buffer;
     }
 
@@ -8,6 +8,7 @@
 #include <utility>
 
 #include "core/embedding/tokenizer.h"
+#include "core/shared/logging.h"
 
 namespace bs {
 
@@ -15,7 +16,7 @@ WordPieceTokenizer::WordPieceTokenizer(const QString& vocabPath)
 {
     QFile file(vocabPath);
     if (!file.open(QIODevice::ReadOnly | QIODevice::Text)) {
-        qWarning() << "WordPieceTokenizer failed to open vocab:" << vocabPath;
+        qCWarning(bsEmbedding) << "WordPieceTokenizer failed to open vocab:" << vocabPath;
         return;
     }
 
@@ -32,7 +33,7 @@ WordPieceTokenizer::WordPieceTokenizer(const QString& vocabPath)
     }
 
     if (m_vocab.empty()) {
-        qWarning() << "WordPieceTokenizer loaded empty vocab from" << vocabPath;
+        qCWarning(bsEmbedding) << "WordPieceTokenizer loaded empty vocab from" << vocabPath;
         return;
     }
 
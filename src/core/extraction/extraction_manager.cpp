@@ -6,6 +6,8 @@
 #include <QFile>
 #include <QFileInfo>
 
+#include <algorithm>
+
 namespace bs {
 
 namespace {
@@ -63,6 +65,11 @@ ExtractionManager::ExtractionManager()
     , m_pdfExtractor(std::make_unique<PdfExtractor>())
     , m_ocrExtractor(std::make_unique<OcrExtractor>())
 {
+    for (const QString& name : extractorNames()) {
+        ExtractorStats stats;
+        stats.extractor = name;
+        m_stats.push_back(stats);
+    }
     LOG_INFO(bsExtraction, "ExtractionManager initialised (concurrency=%d, timeout=%d ms, maxSize=%lld)",
              m_maxConcurrent, m_timeoutMs, static_cast<long long>(m_maxFileSize));
 }
@@ -116,6 +123,26 @@ void ExtractionManager::setMaxFileSizeBytes(int64_t sz)
              static_cast<long long>(m_maxFileSize));
 }
 
+void ExtractionManager::setSecretRedactor(SecretRedactor redactor)
+{
+    m_secretRedactor = std::move(redactor);
+    LOG_INFO(bsExtraction, "Secret redaction: %s",
+             qUtf8Printable(SecretRedactor::modeToString(m_secretRedactor.mode())));
+}
+
+void ExtractionManager::setSandboxedExtraction(bool enabled, QString helperPath)
+{
+    m_sandboxEnabled = enabled;
+    m_helperPath = std::move(helperPath);
+    if (enabled && m_helperPath.isEmpty()) {
+        LOG_WARN(bsExtraction, "Sandboxed extraction requested but %s was not found; "
+                 "PDF and OCR extraction stay in-process", SandboxedExtraction::kHelperName);
+        return;
+    }
+    LOG_INFO(bsExtraction, "Sandboxed extraction: %s",
+             enabled ? qUtf8Printable(m_helperPath) : "off");
+}
+
 void ExtractionManager::requestCancel()
 {
     m_cancelRequested.store(true);
@@ -160,6 +187,58 @@ FileExtractor* ExtractionManager::selectExtractor(ItemKind kind) const
     return nullptr;
 }
 
+QString ExtractionManager::extractorName(ItemKind kind)
+{
+    switch (kind) {
+    case ItemKind::Text:
+    case ItemKind::Code:
+    case ItemKind::Markdown:
+    case ItemKind::Unknown:
+        return QStringLiteral("text");
+    case ItemKind::Pdf:
+        return QStringLiteral("pdf");
+    case ItemKind::Image:
+        return QStringLiteral("ocr");
+    case ItemKind::Directory:
+    case ItemKind::Archive:
+    case ItemKind::Binary:
+        return QString();
+    }
+    return QString();
+}
+
+QStringList ExtractionManager::extractorNames()
+{
+    return {QStringLiteral("text"), QStringLiteral("pdf"), QStringLiteral("ocr")};
+}
+
+// ── Statistics ──────────────────────────────────────────────
+
+std::vector<ExtractionManager::ExtractorStats> ExtractionManager::extractorStats() const
+{
+    std::lock_guard<std::mutex> lock(m_statsMutex);
+    return m_stats;
+}
+
+void ExtractionManager::recordStats(ItemKind kind, const ExtractionResult& result)
+{
+    const int index = extractorNames().indexOf(extractorName(kind));
+    if (index < 0) {
+        return;
+    }
+    std::lock_guard<std::mutex> lock(m_statsMutex);
+    ExtractorStats& stats = m_stats[static_cast<size_t>(index)];
+    ++stats.extractions;
+    if (result.status != ExtractionResult::Status::Success) {
+        ++stats.failures;
+    }
+    if (result.status == ExtractionResult::Status::Timeout) {
+        ++stats.timeouts;
+    }
+    stats.totalMs += result.durationMs;
+    stats.maxMs = std::max<int64_t>(stats.maxMs, result.durationMs);
+}
+
 // ── Main extraction entry point ─────────────────────────────
 
 ExtractionResult ExtractionManager::extract(const QString& filePath, ItemKind kind)
@@ -247,6 +326,7 @@ ExtractionResult ExtractionManager::extract(const QString& filePath, ItemKind ki
         result.errorMessage = QStringLiteral("Timed out waiting for extraction slot");
         result.durationMs = m_timeoutMs;
         LOG_WARN(bsExtraction, "Extraction slot timeout for: %s", qUtf8Printable(filePath));
+        recordStats(kind, result);
         return result;
     }
 
@@ -263,6 +343,7 @@ ExtractionResult ExtractionManager::extract(const QString& filePath, ItemKind ki
         result.errorMessage = QStringLiteral("Timed out waiting for extractor kind slot");
         result.durationMs = m_timeoutMs;
         LOG_WARN(bsExtraction, "Extractor kind slot timeout for: %s", qUtf8Printable(filePath));
+        recordStats(kind, result);
         return result;
     }
 
@@ -274,7 +355,15 @@ ExtractionResult ExtractionManager::extract(const QString& filePath, ItemKind ki
               qUtf8Printable(filePath),
               qUtf8Printable(itemKindToString(kind)));
 
-    if (kind == ItemKind::Image) {
+    if (isSandboxed() && SandboxedExtraction::isIsolatedKind(kind)) {
+        SandboxLimits limits;
+        limits.timeoutMs = m_timeoutMs > 0 ? m_timeoutMs : kMaxExtractionMs;
+        limits.cpuLimitSecs = limits.timeoutMs / 1000 + 5;
+        result = SandboxedExtraction::run(m_helperPath, filePath, kind, limits);
+        if (result.status == ExtractionResult::Status::Crashed) {
+            m_helperCrashes.fetch_add(1);
+        }
+    } else if (kind == ItemKind::Image) {
         // Tesseract's API object is mutable and not safe for concurrent calls.
         std::lock_guard<std::mutex> lock(m_ocrMutex);
         result = extractor->extract(filePath);
@@ -300,9 +389,15 @@ ExtractionResult ExtractionManager::extract(const QString& filePath, ItemKind ki
         heavySemaphore->release();
     }
     m_concurrencySemaphore.release();
+    recordStats(kind, result);
 
     if (result.status == ExtractionResult::Status::Success && result.content.has_value()) {
-        result.content = TextCleaner::clean(result.content.value());
+        result.content = m_secretRedactor.redact(TextCleaner::clean(result.content.value()),
+                                                 &result.redactedSecrets);
+        if (result.redactedSecrets > 0) {
+            LOG_INFO(bsExtraction, "Redacted %d secret(s) in: %s", result.redactedSecrets,
+                     qUtf8Printable(filePath));
+        }
     }
 
     if (result.status == ExtractionResult::Status::Success) {
@@ -5,12 +5,16 @@
 #include "core/extraction/text_extractor.h"
 #include "core/extraction/pdf_extractor.h"
 #include "core/extraction/ocr_extractor.h"
+#include "core/extraction/sandboxed_extraction.h"
+#include "core/extraction/secret_redactor.h"
 #include "core/shared/types.h"
 
 #include <QSemaphore>
+#include <QStringList>
 #include <atomic>
 #include <mutex>
 #include <memory>
+#include <vector>
 
 namespace bs {
 
@@ -28,6 +32,18 @@ namespace bs {
 // The semaphore limits the number of in-flight extractions.
 class ExtractionManager {
 public:
+    // Time and failures per extractor since this manager was created.
+    // Only files handed to an extractor count: oversized, missing and
+    // unsupported files are rejected before one runs.
+    struct ExtractorStats {
+        QString extractor;        // extractorName(): "text", "pdf", "ocr"
+        uint64_t extractions = 0;
+        uint64_t failures = 0;    // any status but Success
+        uint64_t timeouts = 0;    // failures with Status::Timeout
+        int64_t totalMs = 0;      // a slot timeout counts its wait
+        int64_t maxMs = 0;
+    };
+
     ExtractionManager();
     ~ExtractionManager();
 
@@ -42,6 +58,13 @@ public:
     // kinds (Directory, Archive, Binary, Unknown) with no content.
     ExtractionResult extract(const QString& filePath, ItemKind kind);
 
+    // Name of the extractor that handles a kind: "text", "pdf" or "ocr",
+    // or empty for kinds that are indexed by metadata only.
+    static QString extractorName(ItemKind kind);
+
+    // Every name extractorName() can return.
+    static QStringList extractorNames();
+
     // ── Configuration ───────────────────────────────────────
 
     // Maximum concurrent extractions (default 4).
@@ -56,6 +79,24 @@ public:
     // Files exceeding this are rejected with SizeExceeded.
     void setMaxFileSizeBytes(int64_t sz);
 
+    // Applied to all extracted text (default: skip secrets). Set before
+    // extraction starts.
+    void setSecretRedactor(SecretRedactor redactor);
+    const SecretRedactor& secretRedactor() const { return m_secretRedactor; }
+
+    // Run PDF and OCR extraction in the sandboxed helper at `helperPath`
+    // (default off). Falls back to in-process extraction when the helper
+    // path is empty. Set before extraction starts.
+    void setSandboxedExtraction(bool enabled,
+                                QString helperPath = SandboxedExtraction::defaultHelperPath());
+    bool isSandboxed() const { return m_sandboxEnabled && !m_helperPath.isEmpty(); }
+
+    // Files the helper crashed on since this manager was created.
+    int helperCrashCount() const { return m_helperCrashes.load(); }
+
+    // One entry per extractorNames() entry, in that order.
+    std::vector<ExtractorStats> extractorStats() const;
+
     // Request cancellation of any in-progress or upcoming extraction.
     void requestCancel();
 
@@ -77,17 +118,26 @@ private:
     int m_maxConcurrent = 4;
     int m_timeoutMs = 30000;
     int64_t m_maxFileSize = 50 * 1024 * 1024;
+    SecretRedactor m_secretRedactor;
+    bool m_sandboxEnabled = false;
+    QString m_helperPath;
 
     std::atomic<bool> m_cancelRequested{false};
+    std::atomic<int> m_helperCrashes{0};
 
     QSemaphore m_concurrencySemaphore{4};
     QSemaphore m_pdfSemaphore{1};
     QSemaphore m_ocrSemaphore{1};
     std::mutex m_ocrMutex;
 
+    mutable std::mutex m_statsMutex;
+    std::vector<ExtractorStats> m_stats;  // parallel to extractorNames()
+
     // Select the appropriate extractor for a given ItemKind.
     // Returns nullptr for non-extractable kinds.
     FileExtractor* selectExtractor(ItemKind kind) const;
+
+    void recordStats(ItemKind kind, const ExtractionResult& result);
 };
 
 } // namespace bs
@@ -17,12 +17,14 @@ struct ExtractionResult {
         Inaccessible,
         Unknown,
         Cancelled,
+        Crashed,  // the sandboxed helper died on this file; not retried
     };
 
     Status status = Status::Unknown;
     std::optional<QString> content;
     std::optional<QString> errorMessage;
     int durationMs = 0;
+    int redactedSecrets = 0;  // spans SecretRedactor replaced in `content`
 };
 
 // FileExtractor — abstract interface for content extraction backends.
@@ -0,0 +1,320 @@
+#include "core/extraction/sandboxed_extraction.h"
+#include "core/shared/logging.h"
+
+#include <QCoreApplication>
+#include <QDir>
+#include <QElapsedTimer>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QProcess>
+
+#include <algorithm>
+
+#include <sys/resource.h>
+
+#if defined(__APPLE__)
+// sandbox_init() is deprecated but remains the only way for an unbundled
+// helper to drop into a custom profile.
+#pragma clang diagnostic push
+#pragma clang diagnostic ignored "-Wdeprecated-declarations"
+#include <sandbox.h>
+#endif
+
+namespace bs {
+
+namespace {
+
+constexpr int kHelperStartTimeoutMs = 5000;
+constexpr int kHelperKillWaitMs = 1000;
+constexpr rlim_t kHelperMaxWriteBytes = 64 * 1024 * 1024;
+
+struct StatusName {
+    ExtractionResult::Status status;
+    const char* name;
+};
+
+constexpr StatusName kStatusNames[] = {
+    {ExtractionResult::Status::Success, "success"},
+    {ExtractionResult::Status::Timeout, "timeout"},
+    {ExtractionResult::Status::CorruptedFile, "corrupted"},
+    {ExtractionResult::Status::UnsupportedFormat, "unsupported"},
+    {ExtractionResult::Status::SizeExceeded, "size_exceeded"},
+    {ExtractionResult::Status::Inaccessible, "inaccessible"},
+    {ExtractionResult::Status::Unknown, "unknown"},
+    {ExtractionResult::Status::Cancelled, "cancelled"},
+    {ExtractionResult::Status::Crashed, "crashed"},
+};
+
+QString statusToString(ExtractionResult::Status status)
+{
+    for (const StatusName& entry : kStatusNames) {
+        if (entry.status == status) {
+            return QString::fromLatin1(entry.name);
+        }
+    }
+    return QStringLiteral("unknown");
+}
+
+std::optional<ExtractionResult::Status> statusFromString(const QString& name)
+{
+    for (const StatusName& entry : kStatusNames) {
+        if (name == QLatin1String(entry.name)) {
+            return entry.status;
+        }
+    }
+    return std::nullopt;
+}
+
+ExtractionResult failure(ExtractionResult::Status status, const QString& message, int durationMs)
+{
+    ExtractionResult result;
+    result.status = status;
+    result.errorMessage = message;
+    result.durationMs = durationMs;
+    return result;
+}
+
+bool setLimit(int resource, rlim_t value)
+{
+    struct rlimit limit {};
+    if (::getrlimit(resource, &limit) != 0) {
+        return false;
+    }
+    limit.rlim_cur = std::min(value, limit.rlim_max);
+    return ::setrlimit(resource, &limit) == 0;
+}
+
+QString schemeString(const QString& value)
+{
+    QString escaped = value;
+    escaped.replace(QLatin1Char('\\'), QStringLiteral("\\\\"));
+    escaped.replace(QLatin1Char('"'), QStringLiteral("\\\""));
+    return QLatin1Char('"') + escaped + QLatin1Char('"');
+}
+
+} // namespace
+
+bool SandboxedExtraction::isIsolatedKind(ItemKind kind)
+{
+    return kind == ItemKind::Pdf || kind == ItemKind::Image;
+}
+
+QString SandboxedExtraction::defaultHelperPath()
+{
+    const QString fromEnv = qEnvironmentVariable("BETTERSPOTLIGHT_EXTRACT_HELPER").trimmed();
+    if (!fromEnv.isEmpty()) {
+        return QFileInfo(fromEnv).isExecutable() ? fromEnv : QString();
+    }
+    if (!QCoreApplication::instance()) {
+        return {};
+    }
+    const QString sibling = QDir(QCoreApplication::applicationDirPath())
+                                .filePath(QString::fromLatin1(kHelperName));
+    return QFileInfo(sibling).isExecutable() ? sibling : QString();
+}
+
+ExtractionResult SandboxedExtraction::run(const QString& helperPath, const QString& filePath,
+                                          ItemKind kind, const SandboxLimits& limits)
+{
+    QElapsedTimer timer;
+    timer.start();
+
+    QProcess helper;
+    helper.setProgram(helperPath);
+    helper.setArguments(helperArguments(filePath, kind, limits));
+    helper.setStandardInputFile(QProcess::nullDevice());
+    helper.start();
+    if (!helper.waitForStarted(kHelperStartTimeoutMs)) {
+        return failure(ExtractionResult::Status::Unknown,
+                       QStringLiteral("Could not start extractor helper: %1")
+                           .arg(helper.errorString()),
+                       static_cast<int>(timer.elapsed()));
+    }
+
+    if (!helper.waitForFinished(std::max(1, limits.timeoutMs))) {
+        helper.kill();
+        helper.waitForFinished(kHelperKillWaitMs);
+        LOG_WARN(bsExtraction, "Extractor helper killed after %d ms: %s", limits.timeoutMs,
+                 qUtf8Printable(filePath));
+        return failure(ExtractionResult::Status::Timeout,
+                       QStringLiteral("Extraction exceeded %1 ms; helper was stopped")
+                           .arg(limits.timeoutMs),
+                       static_cast<int>(timer.elapsed()));
+    }
+
+    const int durationMs = static_cast<int>(timer.elapsed());
+    if (helper.exitStatus() == QProcess::CrashExit) {
+        LOG_WARN(bsExtraction, "Extractor helper crashed on: %s", qUtf8Printable(filePath));
+        return failure(ExtractionResult::Status::Crashed,
+                       QStringLiteral("Extractor crashed or hit its resource limits on this "
+                                      "file; skipped until it changes"),
+                       durationMs);
+    }
+
+    const std::optional<ExtractionResult> decoded = decodeResult(helper.readAllStandardOutput());
+    if (helper.exitCode() != 0 || !decoded.has_value()) {
+        const QString stderrText =
+            QString::fromUtf8(helper.readAllStandardError()).trimmed().section(QLatin1Char('\n'), 0, 0);
+        return failure(ExtractionResult::Status::Unknown,
+                       stderrText.isEmpty()
+                           ? QStringLiteral("Extractor helper exited with code %1")
+                                 .arg(helper.exitCode())
+                           : QStringLiteral("Extractor helper failed: %1").arg(stderrText),
+                       durationMs);
+    }
+    ExtractionResult result = decoded.value();
+    result.durationMs = durationMs;
+    return result;
+}
+
+QStringList SandboxedExtraction::helperArguments(const QString& filePath, ItemKind kind,
+                                                 const SandboxLimits& limits)
+{
+    return {
+        QStringLiteral("--kind"), itemKindToString(kind),
+        QStringLiteral("--memory-mb"), QString::number(limits.memoryLimitMb),
+        QStringLiteral("--cpu-secs"), QString::number(limits.cpuLimitSecs),
+        QStringLiteral("--"), filePath,
+    };
+}
+
+std::optional<SandboxedExtraction::HelperRequest> SandboxedExtraction::parseHelperArguments(
+    const QStringList& args, QString* error)
+{
+    HelperRequest request;
+    bool hasKind = false;
+    for (int i = 0; i < args.size(); ++i) {
+        const QString& arg = args.at(i);
+        if (arg == QLatin1String("--")) {
+            if (i + 2 != args.size()) {
+                *error = QStringLiteral("expected exactly one path after --");
+                return std::nullopt;
+            }
+            request.filePath = args.at(i + 1);
+            break;
+        }
+        if (i + 1 >= args.size()) {
+            *error = QStringLiteral("missing value for %1").arg(arg);
+            return std::nullopt;
+        }
+        const QString value = args.at(++i);
+        bool ok = true;
+        if (arg == QLatin1String("--kind")) {
+            request.kind = itemKindFromString(value);
+            hasKind = true;
+        } else if (arg == QLatin1String("--memory-mb")) {
+            request.limits.memoryLimitMb = value.toInt(&ok);
+        } else if (arg == QLatin1String("--cpu-secs")) {
+            request.limits.cpuLimitSecs = value.toInt(&ok);
+        } else {
+            *error = QStringLiteral("unknown option %1").arg(arg);
+            return std::nullopt;
+        }
+        if (!ok) {
+            *error = QStringLiteral("invalid value for %1: %2").arg(arg, value);
+            return std::nullopt;
+        }
+    }
+    if (!hasKind || !isIsolatedKind(request.kind)) {
+        *error = QStringLiteral("--kind must be one of pdf, image");
+        return std::nullopt;
+    }
+    if (request.filePath.isEmpty() || !QDir::isAbsolutePath(request.filePath)) {
+        *error = QStringLiteral("expected an absolute path");
+        return std::nullopt;
+    }
+    if (request.limits.memoryLimitMb < 64 || request.limits.cpuLimitSecs < 1) {
+        *error = QStringLiteral("limits are too small");
+        return std::nullopt;
+    }
+    return request;
+}
+
+bool SandboxedExtraction::applyResourceLimits(const SandboxLimits& limits, QString* error)
+{
+    const rlim_t memoryBytes = static_cast<rlim_t>(limits.memoryLimitMb) * 1024 * 1024;
+    if (!setLimit(RLIMIT_CPU, static_cast<rlim_t>(limits.cpuLimitSecs))) {
+        *error = QStringLiteral("could not limit CPU time");
+        return false;
+    }
+    // A crash should not leave a core of someone's document behind.
+    setLimit(RLIMIT_CORE, 0);
+    setLimit(RLIMIT_FSIZE, kHelperMaxWriteBytes);
+    // macOS does not enforce RLIMIT_AS; RLIMIT_DATA covers malloc there.
+    setLimit(RLIMIT_DATA, memoryBytes);
+    setLimit(RLIMIT_AS, memoryBytes);
+    return true;
+}
+
+bool SandboxedExtraction::enterSandbox(QString* error)
+{
+#if defined(__APPLE__)
+    const QString tempDir = QFileInfo(QDir::tempPath()).canonicalFilePath();
+    const QString profile = QStringLiteral(
+        "(version 1)\n"
+        "(allow default)\n"
+        "(deny network*)\n"
+        "(deny process-fork)\n"
+        "(deny process-exec*)\n"
+        "(deny file-write*)\n"
+        "(allow file-write* (subpath %1))\n"
+        "(allow file-write-data (literal \"/dev/null\"))\n")
+        .arg(schemeString(tempDir));
+    char* sandboxError = nullptr;
+    if (sandbox_init(profile.toUtf8().constData(), 0, &sandboxError) != 0) {
+        *error = QStringLiteral("sandbox_init failed: %1")
+                     .arg(QString::fromUtf8(sandboxError ? sandboxError : "unknown error"));
+        sandbox_free_error(sandboxError);
+        return false;
+    }
+    return true;
+#else
+    Q_UNUSED(error);
+    // Resource limits only; there is no profile to enter off macOS.
+    return true;
+#endif
+}
+
+QByteArray SandboxedExtraction::encodeResult(const ExtractionResult& result)
+{
+    QJsonObject json;
+    json[QStringLiteral("status")] = statusToString(result.status);
+    if (result.content.has_value()) {
+        json[QStringLiteral("content")] = result.content.value();
+    }
+    if (result.errorMessage.has_value()) {
+        json[QStringLiteral("errorMessage")] = result.errorMessage.value();
+    }
+    json[QStringLiteral("durationMs")] = result.durationMs;
+    return QJsonDocument(json).toJson(QJsonDocument::Compact);
+}
+
+std::optional<ExtractionResult> SandboxedExtraction::decodeResult(const QByteArray& payload)
+{
+    const QJsonDocument doc = QJsonDocument::fromJson(payload);
+    if (!doc.isObject()) {
+        return std::nullopt;
+    }
+    const QJsonObject json = doc.object();
+    const auto status = statusFromString(json.value(QStringLiteral("status")).toString());
+    if (!status.has_value()) {
+        return std::nullopt;
+    }
+    ExtractionResult result;
+    result.status = status.value();
+    if (json.value(QStringLiteral("content")).isString()) {
+        result.content = json.value(QStringLiteral("content")).toString();
+    }
+    if (json.value(QStringLiteral("errorMessage")).isString()) {
+        result.errorMessage = json.value(QStringLiteral("errorMessage")).toString();
+    }
+    result.durationMs = json.value(QStringLiteral("durationMs")).toInt();
+    return result;
+}
+
+} // namespace bs
+
+#if defined(__APPLE__)
+#pragma clang diagnostic pop
+#endif
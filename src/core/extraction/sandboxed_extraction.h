@@ -0,0 +1,65 @@
+#pragma once
+
+#include "core/extraction/extractor.h"
+#include "core/shared/types.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// Resource limits applied inside the helper before it touches the file.
+struct SandboxLimits {
+    int timeoutMs = 30000;     // wall clock; the helper is killed past it
+    int memoryLimitMb = 1024;  // address space / data segment
+    int cpuLimitSecs = 35;     // SIGXCPU past it
+};
+
+// SandboxedExtraction — runs the parsers for complex formats (PDF, OCR) in
+// a short-lived `betterspotlight-extract-helper` process, so a malformed
+// file that crashes Poppler or Tesseract, or makes it run away with memory,
+// takes down the helper instead of the indexer.
+//
+// One helper per file: it lowers its rlimits, enters a macOS sandbox that
+// denies network access and writes outside its temp dir, extracts, prints
+// an encoded ExtractionResult on stdout and exits. A helper that crashes
+// yields Status::Crashed, which the indexer records and does not retry, so
+// the file is skipped until it changes.
+class SandboxedExtraction {
+public:
+    static constexpr const char* kHelperName = "betterspotlight-extract-helper";
+
+    // Kinds whose extractors run in the helper.
+    static bool isIsolatedKind(ItemKind kind);
+
+    // BETTERSPOTLIGHT_EXTRACT_HELPER, else the helper next to this
+    // executable. Empty when neither exists.
+    static QString defaultHelperPath();
+
+    // Parent side: spawn `helperPath`, wait, decode.
+    static ExtractionResult run(const QString& helperPath, const QString& filePath,
+                                ItemKind kind, const SandboxLimits& limits);
+
+    // Helper side. Arguments are `--kind <kind> --memory-mb N --cpu-secs N --
+    // <path>`; parsing returns nullopt with *error when malformed.
+    struct HelperRequest {
+        QString filePath;
+        ItemKind kind = ItemKind::Unknown;
+        SandboxLimits limits;
+    };
+    static QStringList helperArguments(const QString& filePath, ItemKind kind,
+                                       const SandboxLimits& limits);
+    static std::optional<HelperRequest> parseHelperArguments(const QStringList& args,
+                                                             QString* error);
+    static bool applyResourceLimits(const SandboxLimits& limits, QString* error);
+    static bool enterSandbox(QString* error);
+
+    // The helper's stdout.
+    static QByteArray encodeResult(const ExtractionResult& result);
+    static std::optional<ExtractionResult> decodeResult(const QByteArray& payload);
+};
+
+} // namespace bs
@@ -0,0 +1,201 @@
+#include "core/extraction/secret_redactor.h"
+
+#include <QMessageAuthenticationCode>
+#include <QRegularExpression>
+#include <QSet>
+
+#include <algorithm>
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+struct Detector {
+    const char* kind;
+    QRegularExpression pattern;
+    // Capture groups holding the secret, first match wins; empty for the
+    // whole match.
+    std::vector<int> groups;
+};
+
+const std::vector<Detector>& detectors()
+{
+    static const std::vector<Detector> kDetectors = [] {
+        const auto rx = [](const char* pattern,
+                           QRegularExpression::PatternOptions options = {}) {
+            QRegularExpression expression(QString::fromLatin1(pattern), options);
+            expression.optimize();
+            return expression;
+        };
+        std::vector<Detector> out;
+        // An unterminated block (truncated file) runs to the end of the text.
+        out.push_back({"private_key",
+                       rx("-----BEGIN ((?:[A-Z0-9]+ )*)PRIVATE KEY(?: BLOCK)?-----"
+                          "[\\s\\S]*?(?:-----END \\1PRIVATE KEY(?: BLOCK)?-----|\\z)"),
+                       {}});
+        out.push_back({"aws_access_key", rx("\\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\\b"), {}});
+        out.push_back({"github_token",
+                       rx("\\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\\b"),
+                       {}});
+        out.push_back({"gitlab_token", rx("\\bglpat-[A-Za-z0-9_-]{20,}"), {}});
+        out.push_back({"slack_token", rx("\\bxox[abposr]-[A-Za-z0-9-]{10,}"), {}});
+        out.push_back({"stripe_key", rx("\\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\\b"), {}});
+        out.push_back({"google_api_key", rx("\\bAIza[0-9A-Za-z_-]{35}(?![0-9A-Za-z_-])"), {}});
+        out.push_back({"api_key", rx("\\bsk-[A-Za-z0-9_-]{20,}"), {}});
+        out.push_back({"jwt",
+                       rx("\\beyJ[A-Za-z0-9_-]{8,}\\.eyJ[A-Za-z0-9_-]{8,}\\.[A-Za-z0-9_-]{8,}"),
+                       {}});
+        out.push_back({"url_password",
+                       rx("\\b[A-Za-z][A-Za-z0-9+.-]*://[^\\s:/@]+:([^\\s:/@]{3,})@"),
+                       {1}});
+        // The keyword must end the key or be followed by a separator, so
+        // "secretary:" and "author:" are left alone.
+        out.push_back({"password",
+                       rx("\\b[\\w.-]*?(?:password|passwd|pwd|passphrase|secret|token|"
+                          "api[_-]?key|access[_-]?key|private[_-]?key|credentials?)"
+                          "(?:[_.-][\\w.-]*)?[\"']?[ \\t]*(?:=>|:=|=|:)[ \\t]*"
+                          "(?:\"([^\"\\n]{6,})\"|'([^'\\n]{6,})'|([^\\s\"',;]{6,}))",
+                          QRegularExpression::CaseInsensitiveOption),
+                       {1, 2, 3}});
+        return out;
+    }();
+    return kDetectors;
+}
+
+// Assignment values that name where a secret comes from rather than being
+// one: "${DB_PASSWORD}", "os.environ['TOKEN']", "config.secret", "string".
+bool isReference(const QString& value)
+{
+    static const QRegularExpression kDottedName(
+        QStringLiteral("^[A-Za-z_][A-Za-z0-9_]*(?:\\.[A-Za-z_][A-Za-z0-9_]*)+$"));
+    static const QSet<QString> kPlaceholders = {
+        QStringLiteral("string"),   QStringLiteral("str"),      QStringLiteral("null"),
+        QStringLiteral("none"),     QStringLiteral("nil"),      QStringLiteral("undefined"),
+        QStringLiteral("required"), QStringLiteral("optional"), QStringLiteral("redacted"),
+        QStringLiteral("<redacted>"), QStringLiteral("********"),
+    };
+    if (kPlaceholders.contains(value.toLower())) {
+        return true;
+    }
+    for (const QChar ch : {QLatin1Char('$'), QLatin1Char('%'), QLatin1Char('{'),
+                           QLatin1Char('<'), QLatin1Char('('), QLatin1Char('[')}) {
+        if (value.contains(ch)) {
+            return true;
+        }
+    }
+    return kDottedName.match(value).hasMatch()
+           || value.startsWith(QLatin1String(SecretRedactor::kTokenPrefix));
+}
+
+struct Span {
+    qsizetype start = 0;
+    qsizetype end = 0;
+    const char* kind = nullptr;
+};
+
+} // namespace
+
+SecretRedactor::SecretRedactor(Mode mode, QByteArray hashKey)
+    : m_mode(mode)
+    , m_hashKey(std::move(hashKey))
+{
+}
+
+QString SecretRedactor::redact(const QString& text, int* redactedCount) const
+{
+    if (redactedCount) {
+        *redactedCount = 0;
+    }
+    if (m_mode == Mode::Off || text.isEmpty()) {
+        return text;
+    }
+
+    // Every detector runs on the original text; overlapping finds (a key
+    // inside a JWT-looking assignment) are merged into the earliest one.
+    std::vector<Span> spans;
+    for (const Detector& detector : detectors()) {
+        QRegularExpressionMatchIterator it = detector.pattern.globalMatch(text);
+        while (it.hasNext()) {
+            const QRegularExpressionMatch match = it.next();
+            Span span{match.capturedStart(0), match.capturedEnd(0), detector.kind};
+            if (!detector.groups.empty()) {
+                const auto group = std::find_if(
+                    detector.groups.begin(), detector.groups.end(),
+                    [&match](int g) { return match.capturedStart(g) >= 0; });
+                if (group == detector.groups.end() || isReference(match.captured(*group))) {
+                    continue;
+                }
+                span = {match.capturedStart(*group), match.capturedEnd(*group), detector.kind};
+            }
+            spans.push_back(span);
+        }
+    }
+    if (spans.empty()) {
+        return text;
+    }
+    std::sort(spans.begin(), spans.end(), [](const Span& a, const Span& b) {
+        return a.start != b.start ? a.start < b.start : a.end > b.end;
+    });
+
+    QString out;
+    out.reserve(text.size());
+    qsizetype cursor = 0;
+    int count = 0;
+    for (const Span& span : spans) {
+        if (span.start < cursor) {
+            continue;
+        }
+        out.append(QStringView(text).mid(cursor, span.start - cursor));
+        if (m_mode == Mode::Hash) {
+            out.append(hashToken(QString::fromLatin1(span.kind),
+                                 text.mid(span.start, span.end - span.start)));
+        }
+        cursor = span.end;
+        ++count;
+    }
+    out.append(QStringView(text).mid(cursor));
+
+    if (redactedCount) {
+        *redactedCount = count;
+    }
+    return out;
+}
+
+QString SecretRedactor::hashToken(const QString& kind, const QString& secret) const
+{
+    const QByteArray mac = QMessageAuthenticationCode::hash(
+        secret.toUtf8(), m_hashKey, QCryptographicHash::Sha256);
+    return QString::fromLatin1(kTokenPrefix) + QLatin1Char('_') + kind + QLatin1Char('_')
+           + QString::fromLatin1(mac.toHex().left(16));
+}
+
+std::optional<SecretRedactor::Mode> SecretRedactor::modeFromString(const QString& value)
+{
+    const QString normalized = value.trimmed().toLower();
+    if (normalized == QLatin1String("off")) {
+        return Mode::Off;
+    }
+    if (normalized == QLatin1String("skip")) {
+        return Mode::Skip;
+    }
+    if (normalized == QLatin1String("hash")) {
+        return Mode::Hash;
+    }
+    return std::nullopt;
+}
+
+QString SecretRedactor::modeToString(Mode mode)
+{
+    switch (mode) {
+    case Mode::Off:
+        return QStringLiteral("off");
+    case Mode::Skip:
+        return QStringLiteral("skip");
+    case Mode::Hash:
+        return QStringLiteral("hash");
+    }
+    return QStringLiteral("skip");
+}
+
+} // namespace bs
@@ -0,0 +1,58 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// SecretRedactor — finds credentials in extracted text before it is chunked
+// and indexed, so the index cannot be searched for someone's keys.
+//
+// Detected:
+// 1. PEM private key blocks (RSA, EC, OpenSSH, PGP, ...), whole block
+// 2. Provider tokens with a fixed shape: AWS access key IDs, GitHub,
+//    GitLab, Slack, Stripe and Google API keys, sk-... API keys, JWTs
+// 3. The password in a URL's user:password@ part
+// 4. The value of a password/secret/token/api_key style assignment
+//    ("DB_PASSWORD=...", "api_key: ..."); the key name itself is kept
+//
+// Hash mode replaces each secret with "bssecret_<kind>_<16 hex>", an HMAC
+// of the secret under a per-install key: files holding the same secret
+// share a token, and "bssecret aws" finds files that held AWS keys, but the
+// secret cannot be recovered or guessed from the index. Skip mode drops
+// the secret entirely.
+class SecretRedactor {
+public:
+    enum class Mode {
+        Off,
+        Skip,
+        Hash,
+    };
+
+    static constexpr const char* kTokenPrefix = "bssecret";
+
+    // Skip mode; hashing needs a key.
+    SecretRedactor() = default;
+    explicit SecretRedactor(Mode mode, QByteArray hashKey = {});
+
+    Mode mode() const { return m_mode; }
+
+    // `text` with every detected secret replaced or removed. *redactedCount,
+    // when given, receives the number of secrets found.
+    QString redact(const QString& text, int* redactedCount = nullptr) const;
+
+    // Hash-mode replacement for `secret` of detector `kind`.
+    QString hashToken(const QString& kind, const QString& secret) const;
+
+    // "off", "skip" or "hash".
+    static std::optional<Mode> modeFromString(const QString& value);
+    static QString modeToString(Mode mode);
+
+private:
+    Mode m_mode = Mode::Skip;
+    QByteArray m_hashKey;
+};
+
+} // namespace bs
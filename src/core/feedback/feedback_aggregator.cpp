@@ -1,4 +1,5 @@
 #include "core/feedback/feedback_aggregator.h"
+#include "core/shared/logging.h"
 
 #include <QDebug>
 #include <QTimeZone>
@@ -17,7 +18,7 @@ bool execSql(sqlite3* db, const char* sql)
     char* errMsg = nullptr;
     const int rc = sqlite3_exec(db, sql, nullptr, nullptr, &errMsg);
     if (rc != SQLITE_OK) {
-        qWarning() << "FeedbackAggregator SQL failed:" << (errMsg ? errMsg : "unknown");
+        qCWarning(bsFeedback) << "FeedbackAggregator SQL failed:" << (errMsg ? errMsg : "unknown");
         sqlite3_free(errMsg);
         return false;
     }
@@ -49,7 +50,7 @@ FeedbackAggregator::FeedbackAggregator(sqlite3* db)
 bool FeedbackAggregator::runAggregation()
 {
     if (!m_db) {
-        qWarning() << "FeedbackAggregator::runAggregation called with null DB";
+        qCWarning(bsFeedback) << "FeedbackAggregator::runAggregation called with null DB";
         return false;
     }
 
@@ -117,7 +118,7 @@ bool FeedbackAggregator::runAggregation()
         sqlite3_prepare_v2(m_db, kUpsertFreqSql, -1, &upsertFreqStmt, nullptr) != SQLITE_OK ||
         sqlite3_prepare_v2(m_db, kUpdatePinnedSql, -1, &updatePinnedStmt, nullptr) != SQLITE_OK ||
         sqlite3_prepare_v2(m_db, kUpdateSettingSql, -1, &updateSettingStmt, nullptr) != SQLITE_OK) {
-        qWarning() << "FeedbackAggregator::runAggregation prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "FeedbackAggregator::runAggregation prepare failed:" << sqlite3_errmsg(m_db);
         ok = false;
     }
 
@@ -157,7 +158,7 @@ bool FeedbackAggregator::runAggregation()
             sqlite3_bind_int(upsertFreqStmt, 4, totalInteractions);
 
             if (sqlite3_step(upsertFreqStmt) != SQLITE_DONE) {
-                qWarning() << "FeedbackAggregator frequencies upsert failed for item" << itemId << ":" << sqlite3_errmsg(m_db);
+                qCWarning(bsFeedback) << "FeedbackAggregator frequencies upsert failed for item" << itemId << ":" << sqlite3_errmsg(m_db);
                 ok = false;
                 break;
             }
@@ -176,7 +177,7 @@ bool FeedbackAggregator::runAggregation()
                 sqlite3_bind_int64(updatePinnedStmt, 2, itemId);
 
                 if (sqlite3_step(updatePinnedStmt) != SQLITE_DONE) {
-                    qWarning() << "FeedbackAggregator pin update failed for item" << itemId << ":" << sqlite3_errmsg(m_db);
+                    qCWarning(bsFeedback) << "FeedbackAggregator pin update failed for item" << itemId << ":" << sqlite3_errmsg(m_db);
                     ok = false;
                     break;
                 }
@@ -191,7 +192,7 @@ bool FeedbackAggregator::runAggregation()
         sqlite3_bind_text(updateSettingStmt, 1, nowUtf8.constData(), -1, SQLITE_STATIC);
 
         if (sqlite3_step(updateSettingStmt) != SQLITE_DONE) {
-            qWarning() << "FeedbackAggregator setting update failed:" << sqlite3_errmsg(m_db);
+            qCWarning(bsFeedback) << "FeedbackAggregator setting update failed:" << sqlite3_errmsg(m_db);
             ok = false;
         }
     }
@@ -213,14 +214,14 @@ bool FeedbackAggregator::runAggregation()
         return false;
     }
 
-    qDebug() << "FeedbackAggregator::runAggregation completed at" << nowEpoch;
+    qCDebug(bsFeedback) << "FeedbackAggregator::runAggregation completed at" << nowEpoch;
     return true;
 }
 
 bool FeedbackAggregator::cleanup(int feedbackRetentionDays, int interactionRetentionDays)
 {
     if (!m_db) {
-        qWarning() << "FeedbackAggregator::cleanup called with null DB";
+        qCWarning(bsFeedback) << "FeedbackAggregator::cleanup called with null DB";
         return false;
     }
 
@@ -234,7 +235,7 @@ bool FeedbackAggregator::cleanup(int feedbackRetentionDays, int interactionReten
 
     if (sqlite3_prepare_v2(m_db, kFeedbackSql, -1, &feedbackStmt, nullptr) != SQLITE_OK ||
         sqlite3_prepare_v2(m_db, kInteractionSql, -1, &interactionStmt, nullptr) != SQLITE_OK) {
-        qWarning() << "FeedbackAggregator::cleanup prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "FeedbackAggregator::cleanup prepare failed:" << sqlite3_errmsg(m_db);
         sqlite3_finalize(feedbackStmt);
         sqlite3_finalize(interactionStmt);
         return false;
@@ -252,18 +253,18 @@ bool FeedbackAggregator::cleanup(int feedbackRetentionDays, int interactionReten
     sqlite3_finalize(interactionStmt);
 
     if (feedbackRc != SQLITE_DONE || interactionRc != SQLITE_DONE) {
-        qWarning() << "FeedbackAggregator::cleanup step failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "FeedbackAggregator::cleanup step failed:" << sqlite3_errmsg(m_db);
         return false;
     }
 
-    qDebug() << "FeedbackAggregator::cleanup complete";
+    qCDebug(bsFeedback) << "FeedbackAggregator::cleanup complete";
     return true;
 }
 
 QDateTime FeedbackAggregator::lastAggregationTime()
 {
     if (!m_db) {
-        qWarning() << "FeedbackAggregator::lastAggregationTime called with null DB";
+        qCWarning(bsFeedback) << "FeedbackAggregator::lastAggregationTime called with null DB";
         return {};
     }
 
@@ -272,7 +273,7 @@ QDateTime FeedbackAggregator::lastAggregationTime()
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "FeedbackAggregator::lastAggregationTime prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "FeedbackAggregator::lastAggregationTime prepare failed:" << sqlite3_errmsg(m_db);
         return {};
     }
 
@@ -1,4 +1,5 @@
 #include "core/feedback/interaction_tracker.h"
+#include "core/shared/logging.h"
 
 #include <QJsonObject>
 #include <QRegularExpression>
@@ -27,7 +28,7 @@ InteractionTracker::InteractionTracker(sqlite3* db)
 bool InteractionTracker::recordInteraction(const Interaction& interaction)
 {
     if (!m_db) {
-        qWarning() << "InteractionTracker::recordInteraction called with null DB";
+        qCWarning(bsFeedback) << "InteractionTracker::recordInteraction called with null DB";
         return false;
     }
 
@@ -46,7 +47,7 @@ bool InteractionTracker::recordInteraction(const Interaction& interaction)
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "InteractionTracker::recordInteraction prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::recordInteraction prepare failed:" << sqlite3_errmsg(m_db);
         return false;
     }
 
@@ -77,7 +78,7 @@ bool InteractionTracker::recordInteraction(const Interaction& interaction)
     sqlite3_finalize(stmt);
 
     if (rc != SQLITE_DONE) {
-        qWarning() << "InteractionTracker::recordInteraction step failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::recordInteraction step failed:" << sqlite3_errmsg(m_db);
         return false;
     }
     return true;
@@ -96,7 +97,7 @@ QString InteractionTracker::normalizeQuery(const QString& query)
 int InteractionTracker::getInteractionCount(const QString& query, int64_t itemId)
 {
     if (!m_db) {
-        qWarning() << "InteractionTracker::getInteractionCount called with null DB";
+        qCWarning(bsFeedback) << "InteractionTracker::getInteractionCount called with null DB";
         return 0;
     }
 
@@ -110,7 +111,7 @@ int InteractionTracker::getInteractionCount(const QString& query, int64_t itemId
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "InteractionTracker::getInteractionCount prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::getInteractionCount prepare failed:" << sqlite3_errmsg(m_db);
         return 0;
     }
 
@@ -137,7 +138,7 @@ int InteractionTracker::getInteractionBoost(const QString& query, int64_t itemId
 bool InteractionTracker::cleanup(int retentionDays)
 {
     if (!m_db) {
-        qWarning() << "InteractionTracker::cleanup called with null DB";
+        qCWarning(bsFeedback) << "InteractionTracker::cleanup called with null DB";
         return false;
     }
 
@@ -146,7 +147,7 @@ bool InteractionTracker::cleanup(int retentionDays)
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "InteractionTracker::cleanup prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::cleanup prepare failed:" << sqlite3_errmsg(m_db);
         return false;
     }
 
@@ -158,11 +159,11 @@ bool InteractionTracker::cleanup(int retentionDays)
     sqlite3_finalize(stmt);
 
     if (rc != SQLITE_DONE) {
-        qWarning() << "InteractionTracker::cleanup step failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::cleanup step failed:" << sqlite3_errmsg(m_db);
         return false;
     }
 
-    qDebug() << "InteractionTracker::cleanup removed" << sqlite3_changes(m_db) << "rows";
+    qCDebug(bsFeedback) << "InteractionTracker::cleanup removed" << sqlite3_changes(m_db) << "rows";
     return true;
 }
 
@@ -170,7 +171,7 @@ QJsonArray InteractionTracker::exportData()
 {
     QJsonArray output;
     if (!m_db) {
-        qWarning() << "InteractionTracker::exportData called with null DB";
+        qCWarning(bsFeedback) << "InteractionTracker::exportData called with null DB";
         return output;
     }
 
@@ -182,7 +183,7 @@ QJsonArray InteractionTracker::exportData()
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "InteractionTracker::exportData prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "InteractionTracker::exportData prepare failed:" << sqlite3_errmsg(m_db);
         return output;
     }
 
@@ -1,4 +1,5 @@
 #include "core/feedback/path_preferences.h"
+#include "core/shared/logging.h"
 
 #include <QDebug>
 #include <QtGlobal>
@@ -39,7 +40,7 @@ QVector<PathPreferences::DirPreference> PathPreferences::getTopDirectories(int l
 {
     QVector<DirPreference> output;
     if (!m_db) {
-        qWarning() << "PathPreferences::getTopDirectories called with null DB";
+        qCWarning(bsFeedback) << "PathPreferences::getTopDirectories called with null DB";
         return output;
     }
 
@@ -80,7 +81,7 @@ QVector<PathPreferences::DirPreference> PathPreferences::getTopDirectories(int l
 
     sqlite3_stmt* stmt = nullptr;
     if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
-        qWarning() << "PathPreferences::getTopDirectories prepare failed:" << sqlite3_errmsg(m_db);
+        qCWarning(bsFeedback) << "PathPreferences::getTopDirectories prepare failed:" << sqlite3_errmsg(m_db);
         return output;
     }
 
@@ -101,7 +102,7 @@ QVector<PathPreferences::DirPreference> PathPreferences::getTopDirectories(int l
     }
 
     sqlite3_finalize(stmt);
-    qDebug() << "PathPreferences::getTopDirectories loaded" << output.size() << "directories";
+    qCDebug(bsFeedback) << "PathPreferences::getTopDirectories loaded" << output.size() << "directories";
     return output;
 }
 
@@ -131,7 +132,7 @@ void PathPreferences::invalidateCache()
     m_cache.clear();
     m_cacheValid = false;
     m_lastRefresh = QDateTime();
-    qDebug() << "PathPreferences::invalidateCache cleared cache";
+    qCDebug(bsFeedback) << "PathPreferences::invalidateCache cleared cache";
 }
 
 bool PathPreferences::shouldRefreshCache() const
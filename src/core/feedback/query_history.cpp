@@ -0,0 +1,262 @@
+#include "core/feedback/query_history.h"
+#include "core/feedback/interaction_tracker.h"
+#include "core/shared/logging.h"
+
+#include <QDebug>
+#include <QTimeZone>
+
+#include <sqlite3.h>
+
+namespace bs {
+
+namespace {
+
+constexpr const char* kTimestampFormat = "yyyy-MM-dd HH:mm:ss";
+
+QString toDbTimestamp(const QDateTime& dt)
+{
+    const QDateTime utc = dt.isValid() ? dt.toUTC() : QDateTime::currentDateTimeUtc();
+    return utc.toString(QLatin1String(kTimestampFormat));
+}
+
+QDateTime fromDbTimestamp(const unsigned char* text)
+{
+    if (!text) {
+        return QDateTime();
+    }
+    QDateTime dt = QDateTime::fromString(QString::fromUtf8(reinterpret_cast<const char*>(text)),
+                                         QLatin1String(kTimestampFormat));
+    dt.setTimeZone(QTimeZone::UTC);
+    return dt;
+}
+
+// LIKE wildcards in user text match literally (pair with ESCAPE '\').
+QString escapeLike(const QString& text)
+{
+    QString escaped;
+    escaped.reserve(text.size());
+    for (const QChar ch : text) {
+        if (ch == QLatin1Char('\\') || ch == QLatin1Char('%') || ch == QLatin1Char('_')) {
+            escaped.append(QLatin1Char('\\'));
+        }
+        escaped.append(ch);
+    }
+    return escaped;
+}
+
+} // namespace
+
+QueryHistory::QueryHistory(sqlite3* db)
+    : m_db(db)
+{
+}
+
+bool QueryHistory::record(const QString& query, int resultCount, const QDateTime& at)
+{
+    if (!m_db) {
+        qCWarning(bsFeedback) << "QueryHistory::record called with null DB";
+        return false;
+    }
+    const QString normalized = InteractionTracker::normalizeQuery(query);
+    if (normalized.isEmpty()) {
+        return false;
+    }
+
+    static constexpr const char* kSql = R"(
+        INSERT INTO query_history (
+            query_normalized, query, hit_count, last_result_count, first_used_at, last_used_at
+        ) VALUES (?1, ?2, 1, ?3, ?4, ?4)
+        ON CONFLICT(query_normalized) DO UPDATE SET
+            query = excluded.query,
+            hit_count = hit_count + 1,
+            last_result_count = excluded.last_result_count,
+            last_used_at = excluded.last_used_at
+    )";
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, kSql, -1, &stmt, nullptr) != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::record prepare failed:" << sqlite3_errmsg(m_db);
+        return false;
+    }
+
+    const QByteArray normalizedUtf8 = normalized.toUtf8();
+    const QByteArray queryUtf8 = query.simplified().toUtf8();
+    const QByteArray timestampUtf8 = toDbTimestamp(at).toUtf8();
+    sqlite3_bind_text(stmt, 1, normalizedUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 2, queryUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_int(stmt, 3, resultCount);
+    sqlite3_bind_text(stmt, 4, timestampUtf8.constData(), -1, SQLITE_STATIC);
+
+    const int rc = sqlite3_step(stmt);
+    sqlite3_finalize(stmt);
+
+    if (rc != SQLITE_DONE) {
+        qCWarning(bsFeedback) << "QueryHistory::record step failed:" << sqlite3_errmsg(m_db);
+        return false;
+    }
+    return true;
+}
+
+QVector<QueryHistory::Entry> QueryHistory::search(const QString& text, int limit, Order order,
+                                                  bool prefixOnly)
+{
+    QVector<Entry> entries;
+    if (!m_db) {
+        qCWarning(bsFeedback) << "QueryHistory::search called with null DB";
+        return entries;
+    }
+
+    const QString normalized = InteractionTracker::normalizeQuery(text);
+    QByteArray sql = R"(
+        SELECT query, query_normalized, hit_count, last_result_count, first_used_at, last_used_at
+        FROM query_history
+    )";
+    if (!normalized.isEmpty()) {
+        sql += "WHERE query_normalized LIKE ?1 ESCAPE '\\' ";
+    }
+    sql += order == Order::Frequent
+        ? "ORDER BY hit_count / (1.0 + (julianday('now') - julianday(last_used_at)) / 7.0) DESC, "
+          "last_used_at DESC "
+        : "ORDER BY last_used_at DESC ";
+    sql += "LIMIT ?2";
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::search prepare failed:" << sqlite3_errmsg(m_db);
+        return entries;
+    }
+
+    const QString pattern = (prefixOnly ? QString() : QStringLiteral("%"))
+                            + escapeLike(normalized) + QLatin1Char('%');
+    const QByteArray patternUtf8 = pattern.toUtf8();
+    if (!normalized.isEmpty()) {
+        sqlite3_bind_text(stmt, 1, patternUtf8.constData(), -1, SQLITE_STATIC);
+    }
+    sqlite3_bind_int(stmt, 2, qMax(limit, 1));
+
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        Entry entry;
+        entry.query = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        entry.normalizedQuery =
+            QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        entry.hitCount = sqlite3_column_int(stmt, 2);
+        entry.lastResultCount = sqlite3_column_int(stmt, 3);
+        entry.firstUsedAt = fromDbTimestamp(sqlite3_column_text(stmt, 4));
+        entry.lastUsedAt = fromDbTimestamp(sqlite3_column_text(stmt, 5));
+        entries.push_back(entry);
+    }
+
+    sqlite3_finalize(stmt);
+    return entries;
+}
+
+int64_t QueryHistory::remove(const QString& query)
+{
+    if (!m_db) {
+        qCWarning(bsFeedback) << "QueryHistory::remove called with null DB";
+        return -1;
+    }
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "DELETE FROM query_history WHERE query_normalized = ?1", -1,
+                           &stmt, nullptr)
+        != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::remove prepare failed:" << sqlite3_errmsg(m_db);
+        return -1;
+    }
+    const QByteArray normalizedUtf8 = InteractionTracker::normalizeQuery(query).toUtf8();
+    sqlite3_bind_text(stmt, 1, normalizedUtf8.constData(), -1, SQLITE_STATIC);
+
+    const int rc = sqlite3_step(stmt);
+    sqlite3_finalize(stmt);
+    if (rc != SQLITE_DONE) {
+        qCWarning(bsFeedback) << "QueryHistory::remove step failed:" << sqlite3_errmsg(m_db);
+        return -1;
+    }
+    return sqlite3_changes(m_db);
+}
+
+int64_t QueryHistory::purge(const QDateTime& before)
+{
+    if (!m_db) {
+        qCWarning(bsFeedback) << "QueryHistory::purge called with null DB";
+        return -1;
+    }
+
+    const char* sql = before.isValid() ? "DELETE FROM query_history WHERE last_used_at < ?1"
+                                       : "DELETE FROM query_history";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::purge prepare failed:" << sqlite3_errmsg(m_db);
+        return -1;
+    }
+    const QByteArray beforeUtf8 = toDbTimestamp(before).toUtf8();
+    if (before.isValid()) {
+        sqlite3_bind_text(stmt, 1, beforeUtf8.constData(), -1, SQLITE_STATIC);
+    }
+
+    const int rc = sqlite3_step(stmt);
+    sqlite3_finalize(stmt);
+    if (rc != SQLITE_DONE) {
+        qCWarning(bsFeedback) << "QueryHistory::purge step failed:" << sqlite3_errmsg(m_db);
+        return -1;
+    }
+    return sqlite3_changes(m_db);
+}
+
+bool QueryHistory::cleanup(int retentionDays)
+{
+    if (!m_db) {
+        qCWarning(bsFeedback) << "QueryHistory::cleanup called with null DB";
+        return false;
+    }
+
+    const int64_t expired = purge(QDateTime::currentDateTimeUtc().addDays(-qMax(retentionDays, 1)));
+    if (expired < 0) {
+        return false;
+    }
+
+    static constexpr const char* kTrimSql = R"(
+        DELETE FROM query_history
+        WHERE query_normalized NOT IN (
+            SELECT query_normalized FROM query_history ORDER BY last_used_at DESC LIMIT ?1
+        )
+    )";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, kTrimSql, -1, &stmt, nullptr) != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::cleanup prepare failed:" << sqlite3_errmsg(m_db);
+        return false;
+    }
+    sqlite3_bind_int(stmt, 1, kMaxEntries);
+    const int rc = sqlite3_step(stmt);
+    sqlite3_finalize(stmt);
+    if (rc != SQLITE_DONE) {
+        qCWarning(bsFeedback) << "QueryHistory::cleanup step failed:" << sqlite3_errmsg(m_db);
+        return false;
+    }
+
+    qCDebug(bsFeedback) << "QueryHistory::cleanup removed" << expired + sqlite3_changes(m_db)
+                        << "rows";
+    return true;
+}
+
+int64_t QueryHistory::count()
+{
+    if (!m_db) {
+        return 0;
+    }
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "SELECT COUNT(*) FROM query_history", -1, &stmt, nullptr)
+        != SQLITE_OK) {
+        qCWarning(bsFeedback) << "QueryHistory::count prepare failed:" << sqlite3_errmsg(m_db);
+        return 0;
+    }
+    int64_t count = 0;
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        count = sqlite3_column_int64(stmt, 0);
+    }
+    sqlite3_finalize(stmt);
+    return count;
+}
+
+} // namespace bs
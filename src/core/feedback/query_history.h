@@ -0,0 +1,59 @@
+#pragma once
+
+#include <QDateTime>
+#include <QString>
+#include <QVector>
+#include <cstdint>
+
+struct sqlite3;
+
+namespace bs {
+
+// QueryHistory -- the searches a user has run, one row per distinct query
+// (normalized the way InteractionTracker matches them), with how often and
+// when it was last run. Backs history recall and query suggestions.
+//
+// Private searches never reach it; the query service decides what is
+// recorded. Rows older than the retention setting are dropped by cleanup(),
+// and only the kMaxEntries most recently used are kept.
+class QueryHistory {
+public:
+    static constexpr int kDefaultRetentionDays = 90;
+    static constexpr int kMaxEntries = 5000;
+
+    struct Entry {
+        QString query;  // as last typed
+        QString normalizedQuery;
+        int hitCount = 0;
+        int lastResultCount = 0;
+        QDateTime firstUsedAt;
+        QDateTime lastUsedAt;
+    };
+
+    enum class Order {
+        Recent,    // last used first
+        Frequent,  // hit count divided by (1 + weeks since last use)
+    };
+
+    explicit QueryHistory(sqlite3* db);
+
+    // Counts one more run of `query`. Blank queries are ignored (false).
+    bool record(const QString& query, int resultCount, const QDateTime& at = QDateTime());
+
+    // Entries whose normalized query contains `text` (starts with it when
+    // prefixOnly); all entries when `text` is empty.
+    QVector<Entry> search(const QString& text, int limit, Order order, bool prefixOnly = false);
+
+    // Rows deleted, or -1 on error. remove() matches the normalized query;
+    // purge() deletes everything, or only what was last used before `before`.
+    int64_t remove(const QString& query);
+    int64_t purge(const QDateTime& before = QDateTime());
+
+    bool cleanup(int retentionDays = kDefaultRetentionDays);
+    int64_t count();
+
+private:
+    sqlite3* m_db = nullptr;
+};
+
+} // namespace bs
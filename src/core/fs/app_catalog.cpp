@@ -0,0 +1,262 @@
+#include "core/fs/app_catalog.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QSet>
+
+#include <algorithm>
+#include <cmath>
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxScanDepth = 2;
+
+bool isAppBundle(const QFileInfo& info)
+{
+    return info.isDir() && info.suffix().compare(QLatin1String("app"), Qt::CaseInsensitive) == 0;
+}
+
+void scanDirectory(const QString& dirPath, int depth, std::vector<QString>* bundles)
+{
+    const QFileInfoList children =
+        QDir(dirPath).entryInfoList(QDir::Dirs | QDir::NoDotAndDotDot, QDir::Name);
+    for (const QFileInfo& child : children) {
+        if (isAppBundle(child)) {
+            bundles->push_back(QDir::cleanPath(child.absoluteFilePath()));
+        } else if (depth + 1 < kMaxScanDepth && !child.isSymLink()) {
+            scanDirectory(child.absoluteFilePath(), depth + 1, bundles);
+        }
+    }
+}
+
+// "Visual Studio Code" -> {visual, studio, code}; "GarageBand" -> {garage, band}.
+QStringList nameWords(const QString& name)
+{
+    QStringList words;
+    QString current;
+    for (int i = 0; i < name.size(); ++i) {
+        const QChar ch = name.at(i);
+        if (!ch.isLetterOrNumber()) {
+            if (!current.isEmpty()) {
+                words.append(current.toLower());
+                current.clear();
+            }
+            continue;
+        }
+        if (ch.isUpper() && !current.isEmpty() && current.back().isLower()) {
+            words.append(current.toLower());
+            current.clear();
+        }
+        current.append(ch);
+    }
+    if (!current.isEmpty()) {
+        words.append(current.toLower());
+    }
+    return words;
+}
+
+} // namespace
+
+QStringList AppCatalog::defaultRoots(const QString& homeDir)
+{
+    return {
+        QStringLiteral("/Applications"),
+        QStringLiteral("/System/Applications"),
+        QDir(homeDir).filePath(QStringLiteral("Applications")),
+        QStringLiteral("/System/Library/CoreServices/Applications"),
+    };
+}
+
+std::vector<AppEntry> AppCatalog::scan(const QStringList& roots)
+{
+    std::vector<AppEntry> entries;
+    QSet<QString> seenPaths;
+    QSet<QString> seenBundleIds;
+    for (const QString& root : roots) {
+        std::vector<QString> bundles;
+        scanDirectory(root, 0, &bundles);
+        for (const QString& path : bundles) {
+            // /Applications/Safari.app may be a link into a cryptex.
+            const QString canonical = QFileInfo(path).canonicalFilePath();
+            const QString key = canonical.isEmpty() ? path : canonical;
+            if (seenPaths.contains(key)) {
+                continue;
+            }
+            seenPaths.insert(key);
+
+            AppEntry entry;
+            entry.path = path;
+            readBundleInfo(&entry);
+            if (!entry.bundleId.isEmpty()) {
+                if (seenBundleIds.contains(entry.bundleId)) {
+                    continue;
+                }
+                seenBundleIds.insert(entry.bundleId);
+            }
+            entries.push_back(std::move(entry));
+        }
+    }
+    return entries;
+}
+
+double AppCatalog::nameScore(const QString& query, const QString& name)
+{
+    const QString needle = query.simplified().toLower();
+    const QString haystack = name.simplified().toLower();
+    if (needle.isEmpty() || haystack.isEmpty()) {
+        return 0.0;
+    }
+    if (needle == haystack) {
+        return 1.0;
+    }
+    // Shorter names are the likelier completion: "mail" before "mailmate".
+    const double coverage = static_cast<double>(needle.size()) / haystack.size();
+    if (haystack.startsWith(needle)) {
+        return 0.85 + 0.1 * coverage;
+    }
+    const QStringList words = nameWords(name);
+    for (int i = 1; i < words.size(); ++i) {
+        if (words.at(i).startsWith(needle)) {
+            return 0.7 + 0.1 * coverage;
+        }
+    }
+    if (needle.size() >= 2 && words.size() >= 2) {
+        QString initials;
+        for (const QString& word : words) {
+            initials.append(word.at(0));
+        }
+        if (initials.startsWith(needle)) {
+            return 0.65;
+        }
+    }
+    if (needle.size() >= 3 && haystack.contains(needle)) {
+        return 0.4 + 0.1 * coverage;
+    }
+    return 0.0;
+}
+
+double AppCatalog::frecency(const Launch& launch, qint64 nowSecs)
+{
+    if (launch.count <= 0) {
+        return 0.0;
+    }
+    const double ageDays =
+        static_cast<double>(std::max<qint64>(0, nowSecs - launch.lastLaunchedAt)) / 86400.0;
+    const double recency = std::pow(0.5, ageDays / kLaunchHalfLifeDays);
+    // Twenty launches is as familiar as an app gets.
+    const double familiarity =
+        std::min(1.0, std::log1p(static_cast<double>(launch.count)) / std::log1p(20.0));
+    return std::clamp(familiarity * recency, 0.0, 1.0);
+}
+
+void AppCatalog::setEntries(std::vector<AppEntry> entries)
+{
+    m_entries = std::move(entries);
+}
+
+std::vector<AppCatalog::Match> AppCatalog::match(const QString& query, int limit,
+                                                 qint64 nowSecs) const
+{
+    std::vector<Match> matches;
+    if (limit <= 0 || query.trimmed().isEmpty()) {
+        return matches;
+    }
+    for (const AppEntry& entry : m_entries) {
+        double best = nameScore(query, entry.name);
+        QString matchedName = entry.name;
+        for (const QString& alternate : entry.alternateNames) {
+            // An alternate name counts slightly less than the shown one.
+            const double score = nameScore(query, alternate) * 0.95;
+            if (score > best) {
+                best = score;
+                matchedName = alternate;
+            }
+        }
+        if (best <= 0.0) {
+            continue;
+        }
+        const Launch launched = launch(entry);
+        Match match;
+        match.entry = entry;
+        match.matchedName = matchedName;
+        match.score = best * (1.0 + kFrecencyWeight * frecency(launched, nowSecs));
+        match.launchCount = launched.count;
+        matches.push_back(std::move(match));
+    }
+    std::sort(matches.begin(), matches.end(), [](const Match& a, const Match& b) {
+        if (a.score != b.score) {
+            return a.score > b.score;
+        }
+        if (a.entry.name.size() != b.entry.name.size()) {
+            return a.entry.name.size() < b.entry.name.size();
+        }
+        return a.entry.name.compare(b.entry.name, Qt::CaseInsensitive) < 0;
+    });
+    if (static_cast<int>(matches.size()) > limit) {
+        matches.resize(static_cast<size_t>(limit));
+    }
+    return matches;
+}
+
+const AppEntry* AppCatalog::find(const QString& pathOrBundleId) const
+{
+    const QString cleaned = QDir::cleanPath(pathOrBundleId);
+    for (const AppEntry& entry : m_entries) {
+        if (entry.path == cleaned
+            || (!entry.bundleId.isEmpty()
+                && entry.bundleId.compare(pathOrBundleId, Qt::CaseInsensitive) == 0)) {
+            return &entry;
+        }
+    }
+    return nullptr;
+}
+
+void AppCatalog::recordLaunch(const AppEntry& entry, qint64 nowSecs)
+{
+    Launch& launched = m_launches[entry.launchKey()];
+    ++launched.count;
+    launched.lastLaunchedAt = nowSecs;
+    if (m_launches.size() <= kMaxRememberedLaunches) {
+        return;
+    }
+    // Forget the longest-unused app.
+    auto oldest = m_launches.begin();
+    for (auto it = m_launches.begin(); it != m_launches.end(); ++it) {
+        if (it.value().lastLaunchedAt < oldest.value().lastLaunchedAt) {
+            oldest = it;
+        }
+    }
+    m_launches.erase(oldest);
+}
+
+QJsonObject AppCatalog::launchesToJson() const
+{
+    QJsonObject json;
+    for (auto it = m_launches.cbegin(); it != m_launches.cend(); ++it) {
+        json[it.key()] = QJsonObject{
+            {QStringLiteral("count"), it.value().count},
+            {QStringLiteral("lastLaunchedAt"), it.value().lastLaunchedAt},
+        };
+    }
+    return json;
+}
+
+void AppCatalog::setLaunches(const QJsonObject& json)
+{
+    m_launches.clear();
+    for (auto it = json.constBegin(); it != json.constEnd(); ++it) {
+        const QJsonObject value = it.value().toObject();
+        Launch launched;
+        launched.count = value.value(QStringLiteral("count")).toInt();
+        launched.lastLaunchedAt = value.value(QStringLiteral("lastLaunchedAt")).toInteger();
+        if (it.key().isEmpty() || launched.count <= 0) {
+            continue;
+        }
+        m_launches.insert(it.key(), launched);
+    }
+}
+
+} // namespace bs
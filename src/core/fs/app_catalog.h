@@ -0,0 +1,90 @@
+#pragma once
+
+#include <QHash>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+#include <QtGlobal>
+
+#include <vector>
+
+namespace bs {
+
+// One installed application bundle.
+struct AppEntry {
+    QString path;                // /Applications/Safari.app
+    QString bundleId;            // com.apple.Safari; empty if unreadable
+    QString name;                // what Finder shows, localized
+    QStringList alternateNames;  // bundle, display and executable names, ...
+
+    // Launch history is kept per bundle id, or per path without one.
+    QString launchKey() const { return bundleId.isEmpty() ? path : bundleId; }
+};
+
+// AppCatalog -- installed applications, matched by name ahead of file hits
+// so typing an app's name launches it.
+//
+// Apps live outside the index: the catalog is rebuilt from the application
+// folders (scan()) and matched in memory. Names are matched by prefix, by
+// word prefix ("code" for Visual Studio Code), by initials ("vsc") and, from
+// three characters, anywhere; apps launched often and recently rank higher.
+class AppCatalog {
+public:
+    static constexpr const char* kLaunchesSettingKey = "appLaunches";
+    static constexpr double kLaunchHalfLifeDays = 14.0;
+    static constexpr double kFrecencyWeight = 0.5;
+    static constexpr int kMaxRememberedLaunches = 500;
+
+    struct Launch {
+        int count = 0;
+        qint64 lastLaunchedAt = 0;  // epoch seconds
+    };
+
+    struct Match {
+        AppEntry entry;
+        QString matchedName;  // the name that matched best
+        double score = 0.0;
+        int launchCount = 0;
+    };
+
+    // /Applications, the system's apps, ~/Applications and CoreServices' apps;
+    // Utilities folders are reached by scan()'s second level.
+    static QStringList defaultRoots(const QString& homeDir);
+
+    // Every .app under the roots, two levels deep ("Adobe X/Adobe X.app"),
+    // without descending into bundles. An app reachable from two roots, or
+    // installed twice under one bundle id, is listed once: first root wins.
+    static std::vector<AppEntry> scan(const QStringList& roots);
+
+    // Fills bundleId, name and alternateNames from the bundle's Info.plist
+    // and its localized Finder name. Off macOS only the file name is known.
+    static void readBundleInfo(AppEntry* entry);
+
+    // 0 (no match) to 1 (exact), case-insensitive.
+    static double nameScore(const QString& query, const QString& name);
+
+    // 0..1: grows with launches, halves every kLaunchHalfLifeDays since the last.
+    static double frecency(const Launch& launch, qint64 nowSecs);
+
+    void setEntries(std::vector<AppEntry> entries);
+    const std::vector<AppEntry>& entries() const { return m_entries; }
+
+    // Best first; ties go to the shorter, then alphabetically first name.
+    std::vector<Match> match(const QString& query, int limit, qint64 nowSecs) const;
+
+    // nullptr unless `pathOrBundleId` names a catalogued app.
+    const AppEntry* find(const QString& pathOrBundleId) const;
+
+    void recordLaunch(const AppEntry& entry, qint64 nowSecs);
+    Launch launch(const AppEntry& entry) const { return m_launches.value(entry.launchKey()); }
+
+    // {"<bundle id or path>": {"count": n, "lastLaunchedAt": secs}}
+    QJsonObject launchesToJson() const;
+    void setLaunches(const QJsonObject& json);
+
+private:
+    std::vector<AppEntry> m_entries;
+    QHash<QString, Launch> m_launches;
+};
+
+} // namespace bs
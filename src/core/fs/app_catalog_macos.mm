@@ -0,0 +1,68 @@
+#include "core/fs/app_catalog.h"
+
+#include <QFileInfo>
+
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+QString stringValue(NSDictionary* info, NSString* key)
+{
+    id value = info[key];
+    return [value isKindOfClass:NSString.class] ? toQString(value) : QString();
+}
+
+void addAlternate(AppEntry* entry, const QString& name)
+{
+    const QString trimmed = name.trimmed();
+    if (trimmed.isEmpty() || trimmed.compare(entry->name, Qt::CaseInsensitive) == 0) {
+        return;
+    }
+    for (const QString& existing : entry->alternateNames) {
+        if (existing.compare(trimmed, Qt::CaseInsensitive) == 0) {
+            return;
+        }
+    }
+    entry->alternateNames.append(trimmed);
+}
+
+} // namespace
+
+void AppCatalog::readBundleInfo(AppEntry* entry)
+{
+    const QString fileName = QFileInfo(entry->path).completeBaseName();
+    @autoreleasepool {
+        NSString* path = entry->path.toNSString();
+        // Finder's name, localized ("Rechner" for Calculator in German).
+        NSString* displayName = [NSFileManager.defaultManager displayNameAtPath:path];
+        entry->name = toQString(displayName.stringByDeletingPathExtension);
+        if (entry->name.isEmpty()) {
+            entry->name = fileName;
+        }
+
+        NSBundle* bundle = [NSBundle bundleWithPath:path];
+        NSDictionary* info = bundle.infoDictionary;
+        NSDictionary* localized = bundle.localizedInfoDictionary;
+        entry->bundleId = toQString(bundle.bundleIdentifier);
+
+        addAlternate(entry, fileName);
+        addAlternate(entry, stringValue(localized, @"CFBundleDisplayName"));
+        addAlternate(entry, stringValue(localized, @"CFBundleName"));
+        addAlternate(entry, stringValue(info, @"CFBundleDisplayName"));
+        addAlternate(entry, stringValue(info, @"CFBundleName"));
+        addAlternate(entry, stringValue(info, @"CFBundleExecutable"));
+        // "com.microsoft.VSCode" -> "VSCode": often what people type.
+        if (!entry->bundleId.isEmpty()) {
+            addAlternate(entry, entry->bundleId.section(QLatin1Char('.'), -1));
+        }
+    }
+}
+
+} // namespace bs
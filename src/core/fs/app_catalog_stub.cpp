@@ -0,0 +1,12 @@
+#include "core/fs/app_catalog.h"
+
+#include <QFileInfo>
+
+namespace bs {
+
+void AppCatalog::readBundleInfo(AppEntry* entry)
+{
+    entry->name = QFileInfo(entry->path).completeBaseName();
+}
+
+} // namespace bs
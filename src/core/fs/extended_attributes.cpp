@@ -0,0 +1,136 @@
+#include "core/fs/extended_attributes.h"
+#include "core/fs/spotlight_metadata.h"
+
+#include <CoreFoundation/CoreFoundation.h>
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QStringDecoder>
+
+#include <sys/xattr.h>
+
+namespace bs {
+
+namespace {
+
+// Larger values are blobs (resource forks, quarantine databases), not text.
+constexpr ssize_t kMaxXattrBytes = 64 * 1024;
+
+bool looksLikePlist(const QByteArray& data)
+{
+    return data.startsWith("bplist") || data.startsWith("<?xml") || data.startsWith("<plist");
+}
+
+QStringList decodePlist(const QByteArray& data)
+{
+    QStringList values;
+    CFDataRef cfData = CFDataCreate(kCFAllocatorDefault,
+                                    reinterpret_cast<const UInt8*>(data.constData()),
+                                    data.size());
+    if (!cfData) {
+        return values;
+    }
+    CFPropertyListRef plist = CFPropertyListCreateWithData(
+        kCFAllocatorDefault, cfData, kCFPropertyListImmutable, nullptr, nullptr);
+    CFRelease(cfData);
+    if (!plist) {
+        return values;
+    }
+
+    const auto appendString = [&values](CFTypeRef value) {
+        if (value && CFGetTypeID(value) == CFStringGetTypeID()) {
+            values.append(QString::fromCFString(static_cast<CFStringRef>(value)));
+        }
+    };
+    if (CFGetTypeID(plist) == CFArrayGetTypeID()) {
+        const auto array = static_cast<CFArrayRef>(plist);
+        const CFIndex count = CFArrayGetCount(array);
+        for (CFIndex i = 0; i < count; ++i) {
+            appendString(CFArrayGetValueAtIndex(array, i));
+        }
+    } else {
+        appendString(plist);
+    }
+    CFRelease(plist);
+    return values;
+}
+
+} // anonymous namespace
+
+std::vector<ItemAttribute> ExtendedAttributes::read(const QString& path, const QStringList& names)
+{
+    std::vector<ItemAttribute> raw;
+    if (names.isEmpty()) {
+        return raw;
+    }
+
+    const QByteArray pathUtf8 = path.toUtf8();
+    for (const QString& name : names) {
+        const QByteArray nameUtf8 = name.toUtf8();
+        const ssize_t size = getxattr(pathUtf8.constData(), nameUtf8.constData(), nullptr, 0, 0,
+                                      XATTR_NOFOLLOW);
+        if (size <= 0 || size > kMaxXattrBytes) {
+            continue;
+        }
+        QByteArray data(static_cast<int>(size), Qt::Uninitialized);
+        const ssize_t copied = getxattr(pathUtf8.constData(), nameUtf8.constData(), data.data(),
+                                        static_cast<size_t>(data.size()), 0, XATTR_NOFOLLOW);
+        if (copied <= 0) {
+            continue;
+        }
+        data.truncate(static_cast<int>(copied));
+        for (const QString& value : decode(data)) {
+            raw.push_back({name, value, true});
+        }
+    }
+    return SpotlightMetadata::normalize(raw);
+}
+
+std::vector<ItemAttribute> ExtendedAttributes::readFinderComment(const QString& path)
+{
+    std::vector<ItemAttribute> comment =
+        read(path, {QString::fromLatin1(kFinderCommentXattr)});
+    for (ItemAttribute& attribute : comment) {
+        attribute.name = QString::fromLatin1(kFinderCommentAttribute);
+    }
+    return comment;
+}
+
+QStringList ExtendedAttributes::decode(const QByteArray& data)
+{
+    if (looksLikePlist(data)) {
+        return decodePlist(data);
+    }
+
+    QByteArray text = data;
+    while (text.endsWith('\0')) {
+        text.chop(1);
+    }
+    if (text.isEmpty() || text.contains('\0')) {
+        return {};
+    }
+    QStringDecoder decoder(QStringDecoder::Utf8);
+    const QString value = decoder(text);
+    if (decoder.hasError()) {
+        return {};
+    }
+    return {value};
+}
+
+QStringList ExtendedAttributes::parseNameList(const QString& json)
+{
+    QStringList names;
+    const QJsonDocument doc = QJsonDocument::fromJson(json.toUtf8());
+    if (!doc.isArray()) {
+        return names;
+    }
+    for (const QJsonValue& value : doc.array()) {
+        const QString name = value.toString().trimmed();
+        if (!name.isEmpty() && !names.contains(name)) {
+            names.append(name);
+        }
+    }
+    return names;
+}
+
+} // namespace bs
@@ -0,0 +1,44 @@
+#pragma once
+
+#include "core/shared/types.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+#include <vector>
+
+namespace bs {
+
+// ExtendedAttributes — indexes user-chosen extended attributes (the
+// `indexed_xattrs` setting) so workflows that stamp files with xattrs,
+// e.g. com.apple.metadata:kMDItemWhereFroms, become searchable.
+//
+// Stored attribute names are the xattr names themselves, which never
+// collide with SpotlightMetadata's kMDItem-less names.
+class ExtendedAttributes {
+public:
+    // Finder keeps the Get Info "Comments" field in this xattr (a plist
+    // string), so comments are readable even with Spotlight turned off.
+    static constexpr const char* kFinderCommentXattr = "com.apple.metadata:kMDItemFinderComment";
+    // Attribute name the comment is stored under.
+    static constexpr const char* kFinderCommentAttribute = "finderComment";
+
+    // Values of the named xattrs of one file as searchable attributes.
+    // Missing xattrs and values that are neither text nor a plist of
+    // strings are skipped.
+    static std::vector<ItemAttribute> read(const QString& path, const QStringList& names);
+
+    // The file's Finder comment as a searchable `finderComment` attribute;
+    // empty when it has none.
+    static std::vector<ItemAttribute> readFinderComment(const QString& path);
+
+    // Text of one xattr value: a binary or XML plist string or array of
+    // strings, else UTF-8 text (trailing NULs dropped). Empty for binary data.
+    static QStringList decode(const QByteArray& data);
+
+    // xattr names from the `indexed_xattrs` setting, a JSON array of
+    // strings; trimmed and de-duplicated. Empty for anything else.
+    static QStringList parseNameList(const QString& json);
+};
+
+} // namespace bs
@@ -8,6 +8,7 @@ namespace bs {
 
 FileMonitorMacOS::FileMonitorMacOS(double latencySeconds)
     : m_latency(latencySeconds)
+    , m_recordingPath(qEnvironmentVariable(FsEventRecorder::kEnvRecordPath).trimmed())
 {
 }
 
@@ -114,6 +115,15 @@ bool FileMonitorMacOS::start(const std::vector<std::string>& roots,
         return false;
     }
 
+    if (!m_recordingPath.isEmpty()) {
+        QString error;
+        if (m_recorder.open(m_recordingPath, roots, &error)) {
+            LOG_INFO(bsFs, "Recording FSEvents to %s", qUtf8Printable(m_recordingPath));
+        } else {
+            LOG_WARN(bsFs, "Not recording FSEvents: %s", qUtf8Printable(error));
+        }
+    }
+
     m_running.store(true);
 
     LOG_INFO(bsFs, "FileMonitorMacOS started watching %zu root(s), "
@@ -131,24 +141,31 @@ void FileMonitorMacOS::stop()
         return;
     }
 
-    std::lock_guard<std::mutex> lock(m_mutex);
+    {
+        std::lock_guard<std::mutex> lock(m_mutex);
 
-    if (m_stream) {
-        FSEventStreamStop(m_stream);
-        FSEventStreamInvalidate(m_stream);
-        FSEventStreamRelease(m_stream);
-        m_stream = nullptr;
-    }
+        if (m_stream) {
+            FSEventStreamStop(m_stream);
+            FSEventStreamInvalidate(m_stream);
+            FSEventStreamRelease(m_stream);
+            m_stream = nullptr;
+        }
 
-    if (m_queue) {
-        dispatch_release(m_queue);
-        m_queue = nullptr;
+        if (m_queue) {
+            dispatch_release(m_queue);
+            m_queue = nullptr;
+        }
     }
 
     // Flush any remaining buffered events before clearing the callback.
+    // flushPendingEvents() takes m_mutex itself to read the callback.
     flushPendingEvents();
 
-    m_callback = nullptr;
+    {
+        std::lock_guard<std::mutex> lock(m_mutex);
+        m_callback = nullptr;
+    }
+    m_recorder.close();
     m_running.store(false);
 
     LOG_INFO(bsFs, "FileMonitorMacOS stopped");
@@ -178,26 +195,83 @@ void FileMonitorMacOS::handleEvents(
     const FSEventStreamEventFlags flags[],
     const FSEventStreamEventId eventIds[])
 {
-    std::vector<WorkItem> items;
-    items.reserve(numEvents);
+    std::vector<FsEventRecord> records;
+    records.reserve(numEvents);
 
     for (size_t i = 0; i < numEvents; ++i) {
-        const FSEventStreamEventFlags eventFlags = flags[i];
+        FsEventRecord record;
+        record.eventId = static_cast<uint64_t>(eventIds[i]);
+        record.flags = static_cast<uint32_t>(flags[i]);
+        record.path = paths[i];
+
+        // Stat now, while the event is current; translateEvents() only
+        // looks at the result, so a replay needs no file.
+        struct stat st{};
+        if (stat(paths[i], &st) == 0) {
+            record.exists = true;
+            record.isDirectory = S_ISDIR(st.st_mode);
+            record.modTime = static_cast<uint64_t>(st.st_mtime);
+            record.size = static_cast<uint64_t>(st.st_size);
+        }
+        records.push_back(std::move(record));
+    }
+
+    m_recorder.append(records);
+    std::vector<WorkItem> items = translateEvents(records, m_errorCallback);
+
+    // Track the latest event ID for persistence
+    // (the caller stores this in SQLite settings for restart recovery)
+    if (numEvents > 0) {
+        m_lastEventId = eventIds[numEvents - 1];
+    }
+
+    if (items.empty()) {
+        return;
+    }
+
+    // Buffer events and schedule a debounced delivery.
+    {
+        std::lock_guard<std::mutex> lock(m_bufferMutex);
+        m_pendingEvents.insert(m_pendingEvents.end(),
+                               std::make_move_iterator(items.begin()),
+                               std::make_move_iterator(items.end()));
+
+        if (!m_deliveryScheduled) {
+            m_deliveryScheduled = true;
+
+            dispatch_after(
+                dispatch_time(DISPATCH_TIME_NOW,
+                              static_cast<int64_t>(kDebounceMs) * NSEC_PER_MSEC),
+                m_queue,
+                ^{ flushPendingEvents(); });
+        }
+    }
+}
+
+std::vector<WorkItem> FileMonitorMacOS::translateEvents(
+    const std::vector<FsEventRecord>& events,
+    const ErrorCallback& onError)
+{
+    std::vector<WorkItem> items;
+    items.reserve(events.size());
+
+    for (const FsEventRecord& event : events) {
+        const FSEventStreamEventFlags eventFlags = event.flags;
 
         if (eventFlags & kFSEventStreamEventFlagMustScanSubDirs) {
-            if (m_errorCallback) {
-                m_errorCallback(QStringLiteral("FSEvents: must rescan subdirs at ") +
-                                QString::fromUtf8(paths[i]));
+            if (onError) {
+                onError(QStringLiteral("FSEvents: must rescan subdirs at ") +
+                        QString::fromStdString(event.path));
             }
         }
         if (eventFlags & kFSEventStreamEventFlagKernelDropped) {
-            if (m_errorCallback) {
-                m_errorCallback(QStringLiteral("FSEvents: kernel dropped events"));
+            if (onError) {
+                onError(QStringLiteral("FSEvents: kernel dropped events"));
             }
         }
         if (eventFlags & kFSEventStreamEventFlagUserDropped) {
-            if (m_errorCallback) {
-                m_errorCallback(QStringLiteral("FSEvents: user dropped events"));
+            if (onError) {
+                onError(QStringLiteral("FSEvents: user dropped events"));
             }
         }
 
@@ -208,10 +282,10 @@ void FileMonitorMacOS::handleEvents(
 
         // If the root itself changed (e.g., renamed/deleted), emit a rescan.
         if (eventFlags & kFSEventStreamEventFlagRootChanged) {
-            LOG_WARN(bsFs, "Watched root changed: %s", paths[i]);
+            LOG_WARN(bsFs, "Watched root changed: %s", event.path.c_str());
             WorkItem item;
             item.type = WorkItem::Type::RescanDirectory;
-            item.filePath = paths[i];
+            item.filePath = event.path;
             items.push_back(std::move(item));
             continue;
         }
@@ -224,17 +298,16 @@ void FileMonitorMacOS::handleEvents(
 
         WorkItem item;
         item.type = classifyEvent(eventFlags);
-        item.filePath = paths[i];
+        item.filePath = event.path;
 
-        // For non-delete events, try to stat the file for size/mtime.
+        // For non-delete events, use the stat for size/mtime.
         if (item.type != WorkItem::Type::Delete) {
-            struct stat st{};
-            if (stat(paths[i], &st) == 0) {
-                item.knownModTime = static_cast<uint64_t>(st.st_mtime);
-                item.knownSize = static_cast<uint64_t>(st.st_size);
+            if (event.exists) {
+                item.knownModTime = event.modTime;
+                item.knownSize = event.size;
 
                 // If it's a directory, emit as RescanDirectory.
-                if (S_ISDIR(st.st_mode)) {
+                if (event.isDirectory) {
                     item.type = WorkItem::Type::RescanDirectory;
                 }
             } else {
@@ -247,33 +320,7 @@ void FileMonitorMacOS::handleEvents(
         items.push_back(std::move(item));
     }
 
-    // Track the latest event ID for persistence
-    // (the caller stores this in SQLite settings for restart recovery)
-    if (numEvents > 0) {
-        m_lastEventId = eventIds[numEvents - 1];
-    }
-
-    if (items.empty()) {
-        return;
-    }
-
-    // Buffer events and schedule a debounced delivery.
-    {
-        std::lock_guard<std::mutex> lock(m_bufferMutex);
-        m_pendingEvents.insert(m_pendingEvents.end(),
-                               std::make_move_iterator(items.begin()),
-                               std::make_move_iterator(items.end()));
-
-        if (!m_deliveryScheduled) {
-            m_deliveryScheduled = true;
-
-            dispatch_after(
-                dispatch_time(DISPATCH_TIME_NOW,
-                              static_cast<int64_t>(kDebounceMs) * NSEC_PER_MSEC),
-                m_queue,
-                ^{ flushPendingEvents(); });
-        }
-    }
+    return items;
 }
 
 void FileMonitorMacOS::flushPendingEvents()
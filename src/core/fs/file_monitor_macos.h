@@ -1,6 +1,7 @@
 #pragma once
 
 #include "core/fs/file_monitor.h"
+#include "core/fs/fsevent_recording.h"
 #include <CoreServices/CoreServices.h>
 #include <atomic>
 #include <mutex>
@@ -16,6 +17,9 @@ namespace bs {
 // and delivered on a dedicated dispatch queue.
 class FileMonitorMacOS final : public FileMonitor {
 public:
+    // Events are delivered this long after the first one of a burst.
+    static constexpr int kDebounceMs = 500;
+
     explicit FileMonitorMacOS(double latencySeconds = 0.5);
     ~FileMonitorMacOS() override;
 
@@ -32,6 +36,17 @@ public:
         return static_cast<uint64_t>(m_lastEventId);
     }
 
+    // Record the raw stream of the next start() to `path` (see
+    // FsEventRecorder); empty stops recording. Defaults to
+    // $BETTERSPOTLIGHT_FSEVENTS_RECORD.
+    void setRecordingPath(const QString& path) { m_recordingPath = path; }
+
+    // The WorkItems one FSEvents callback turns into, reporting dropped
+    // events and must-rescan directories to `onError`. Live events and
+    // replayed recordings both go through here.
+    static std::vector<WorkItem> translateEvents(const std::vector<FsEventRecord>& events,
+                                                 const ErrorCallback& onError);
+
 private:
     // FSEvents callback — static trampoline that forwards to the instance.
     static void fsEventsCallback(ConstFSEventStreamRef streamRef,
@@ -41,7 +56,8 @@ private:
                                  const FSEventStreamEventFlags eventFlags[],
                                  const FSEventStreamEventId eventIds[]);
 
-    // Process a batch of raw FSEvents into WorkItems and deliver them.
+    // Stat, record and translate a batch of raw FSEvents, then buffer the
+    // WorkItems for delivery.
     void handleEvents(size_t numEvents,
                       char** paths,
                       const FSEventStreamEventFlags flags[],
@@ -64,9 +80,11 @@ private:
     std::vector<std::string> m_roots;
     FSEventStreamEventId m_lastEventId = kFSEventStreamEventIdSinceNow;
 
+    QString m_recordingPath;
+    FsEventRecorder m_recorder;
+
     // Debounce buffer: events accumulate here and are delivered
-    // kDebounceMs after the last event arrives.
-    static constexpr int kDebounceMs = 500;
+    // kDebounceMs after the first event arrives.
     std::vector<WorkItem> m_pendingEvents;
     std::mutex m_bufferMutex;
     bool m_deliveryScheduled = false;
@@ -22,13 +22,22 @@ FileScanner::FileScanner(const PathRules* rules)
 
 std::vector<FileMetadata> FileScanner::scanDirectory(
     const std::string& root) const
+{
+    bool complete = true;
+    return scanDirectory(root, {}, &complete);
+}
+
+std::vector<FileMetadata> FileScanner::scanDirectory(
+    const std::string& root, const std::function<bool()>& visit, bool* complete) const
 {
     std::vector<FileMetadata> results;
+    *complete = true;
 
     QString qRoot = QString::fromStdString(root);
     QDir rootDir(qRoot);
     if (!rootDir.exists()) {
         LOG_WARN(bsFs, "Scan root does not exist: %s", root.c_str());
+        *complete = false;
         return results;
     }
 
@@ -37,25 +46,30 @@ std::vector<FileMetadata> FileScanner::scanDirectory(
     uint64_t scannedCount = 0;
     uint64_t excludedCount = 0;
 
-    scanRecursive(qRoot, results, scannedCount, excludedCount);
+    *complete = scanRecursive(qRoot, results, scannedCount, excludedCount,
+                              visit ? &visit : nullptr);
 
-    LOG_INFO(bsFs, "Scan complete: %s — %" PRIu64 " files, "
+    LOG_INFO(bsFs, "Scan %s: %s — %" PRIu64 " files, "
                    "%" PRIu64 " excluded",
-             root.c_str(), scannedCount, excludedCount);
+             *complete ? "complete" : "stopped", root.c_str(), scannedCount, excludedCount);
 
     return results;
 }
 
-void FileScanner::scanRecursive(const QString& dirPath,
+bool FileScanner::scanRecursive(const QString& dirPath,
                                 std::vector<FileMetadata>& results,
                                 uint64_t& scannedCount,
                                 uint64_t& excludedCount,
+                                const std::function<bool()>* visit,
                                 int depth) const
 {
     if (depth >= kMaxDepth) {
         LOG_WARN(bsFs, "Max scan depth (%d) reached at: %s", kMaxDepth,
                  qUtf8Printable(dirPath));
-        return;
+        return true;
+    }
+    if (visit && !(*visit)()) {
+        return false;
     }
 
     QDir dir(dirPath);
@@ -94,8 +108,10 @@ void FileScanner::scanRecursive(const QString& dirPath,
                          scannedCount, excludedCount, filePath.c_str());
             }
 
-            scanRecursive(fi.absoluteFilePath(), results, scannedCount,
-                          excludedCount, depth + 1);
+            if (!scanRecursive(fi.absoluteFilePath(), results, scannedCount,
+                               excludedCount, visit, depth + 1)) {
+                return false;
+            }
             continue;
         }
 
@@ -107,6 +123,9 @@ void FileScanner::scanRecursive(const QString& dirPath,
             continue;
         }
 
+        if (visit && !(*visit)()) {
+            return false;
+        }
         ++scannedCount;
 
         FileMetadata meta;
@@ -133,6 +152,7 @@ void FileScanner::scanRecursive(const QString& dirPath,
 
         results.push_back(std::move(meta));
     }
+    return true;
 }
 
 ItemKind FileScanner::classifyItemKind(const std::string& extension,
@@ -2,6 +2,7 @@
 
 #include "core/shared/types.h"
 #include "core/fs/path_rules.h"
+#include <functional>
 #include <string>
 #include <unordered_map>
 #include <vector>
@@ -24,6 +25,13 @@ public:
     // in the result set (they are traversed but not emitted).
     std::vector<FileMetadata> scanDirectory(const std::string& root) const;
 
+    // scanDirectory() that calls `visit` before listing each directory and
+    // before each file's stat, so a caller can pace the walk or stop it:
+    // a false return ends the walk early and sets *complete to false.
+    std::vector<FileMetadata> scanDirectory(const std::string& root,
+                                            const std::function<bool()>& visit,
+                                            bool* complete) const;
+
     // Classify a file's ItemKind based on its extension and permissions.
     // mode is the POSIX file mode (from stat); used to detect executables.
     static ItemKind classifyItemKind(const std::string& extension,
@@ -31,10 +39,12 @@ public:
 
 private:
     // Recursive helper that prunes excluded directories before entering them.
-    void scanRecursive(const QString& dirPath,
+    // Returns false when `visit` stopped the walk.
+    bool scanRecursive(const QString& dirPath,
                        std::vector<FileMetadata>& results,
                        uint64_t& scannedCount,
                        uint64_t& excludedCount,
+                       const std::function<bool()>* visit,
                        int depth = 0) const;
 
     // Build the extension -> ItemKind lookup table.
@@ -0,0 +1,86 @@
+#include "core/fs/finder_tags.h"
+
+#include <CoreFoundation/CoreFoundation.h>
+
+#include <cerrno>
+#include <sys/xattr.h>
+
+namespace bs {
+
+namespace {
+
+// Finder caps tag names well below this; anything longer is not a tag.
+constexpr int kMaxTagChars = 255;
+
+} // anonymous namespace
+
+std::optional<QStringList> FinderTags::read(const QString& path)
+{
+    const QByteArray pathUtf8 = path.toUtf8();
+    const ssize_t size = getxattr(pathUtf8.constData(), kXattrName, nullptr, 0, 0, XATTR_NOFOLLOW);
+    if (size < 0) {
+        if (errno == ENOATTR) {
+            return QStringList{};
+        }
+        return std::nullopt;
+    }
+
+    QByteArray data(static_cast<int>(size), Qt::Uninitialized);
+    const ssize_t copied = getxattr(pathUtf8.constData(), kXattrName, data.data(),
+                                    static_cast<size_t>(data.size()), 0, XATTR_NOFOLLOW);
+    if (copied < 0) {
+        return std::nullopt;
+    }
+    data.truncate(static_cast<int>(copied));
+    return parse(data);
+}
+
+std::optional<QStringList> FinderTags::parse(const QByteArray& plistData)
+{
+    if (plistData.isEmpty()) {
+        return QStringList{};
+    }
+
+    CFDataRef cfData = CFDataCreate(kCFAllocatorDefault,
+                                    reinterpret_cast<const UInt8*>(plistData.constData()),
+                                    plistData.size());
+    if (!cfData) {
+        return std::nullopt;
+    }
+    CFPropertyListRef plist = CFPropertyListCreateWithData(
+        kCFAllocatorDefault, cfData, kCFPropertyListImmutable, nullptr, nullptr);
+    CFRelease(cfData);
+    if (!plist) {
+        return std::nullopt;
+    }
+    if (CFGetTypeID(plist) != CFArrayGetTypeID()) {
+        CFRelease(plist);
+        return std::nullopt;
+    }
+
+    QStringList tags;
+    const auto array = static_cast<CFArrayRef>(plist);
+    const CFIndex count = CFArrayGetCount(array);
+    for (CFIndex i = 0; i < count; ++i) {
+        const CFTypeRef value = CFArrayGetValueAtIndex(array, i);
+        if (!value || CFGetTypeID(value) != CFStringGetTypeID()) {
+            continue;
+        }
+        const QString name = entryName(QString::fromCFString(static_cast<CFStringRef>(value)));
+        if (name.isEmpty() || name.size() > kMaxTagChars
+            || tags.contains(name, Qt::CaseInsensitive)) {
+            continue;
+        }
+        tags.append(name);
+    }
+    CFRelease(plist);
+    return tags;
+}
+
+QString FinderTags::entryName(const QString& entry)
+{
+    const int newline = entry.indexOf(QLatin1Char('\n'));
+    return (newline >= 0 ? entry.left(newline) : entry).trimmed();
+}
+
+} // namespace bs
@@ -0,0 +1,33 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+#include <optional>
+
+namespace bs {
+
+// FinderTags — reads the user's Finder tags from the
+// com.apple.metadata:_kMDItemUserTags extended attribute.
+//
+// The attribute is a property list array of "Name\nColorIndex" strings;
+// only names are kept. The built-in colour tags are named after their
+// colour ("Red", "Orange", ...), so `tag:red` needs no colour mapping.
+class FinderTags {
+public:
+    static constexpr const char* kXattrName = "com.apple.metadata:_kMDItemUserTags";
+
+    // Tags of one file, in Finder order. Empty when the file has no tags;
+    // nullopt when the attribute could not be read (permissions, I/O), so
+    // callers keep whatever was stored before.
+    static std::optional<QStringList> read(const QString& path);
+
+    // Decode the attribute value (binary or XML plist). Returns nullopt
+    // for data that is not a plist array.
+    static std::optional<QStringList> parse(const QByteArray& plistData);
+
+    // Tag name of one "Name\nColorIndex" entry, trimmed.
+    static QString entryName(const QString& entry);
+};
+
+} // namespace bs
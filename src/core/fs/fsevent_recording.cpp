@@ -0,0 +1,169 @@
+#include "core/fs/fsevent_recording.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+
+namespace bs {
+
+namespace {
+
+QJsonObject recordToJson(const FsEventRecord& record)
+{
+    return {
+        {QStringLiteral("id"), static_cast<qint64>(record.eventId)},
+        {QStringLiteral("flags"), static_cast<qint64>(record.flags)},
+        {QStringLiteral("path"), QString::fromStdString(record.path)},
+        {QStringLiteral("exists"), record.exists},
+        {QStringLiteral("dir"), record.isDirectory},
+        {QStringLiteral("mtime"), static_cast<qint64>(record.modTime)},
+        {QStringLiteral("size"), static_cast<qint64>(record.size)},
+    };
+}
+
+std::optional<FsEventRecord> recordFromJson(const QJsonValue& value)
+{
+    const QJsonObject object = value.toObject();
+    const QString path = object.value(QStringLiteral("path")).toString();
+    if (path.isEmpty() || !object.value(QStringLiteral("flags")).isDouble()) {
+        return std::nullopt;
+    }
+    FsEventRecord record;
+    record.eventId = static_cast<uint64_t>(object.value(QStringLiteral("id")).toInteger());
+    record.flags = static_cast<uint32_t>(object.value(QStringLiteral("flags")).toInteger());
+    record.path = path.toStdString();
+    record.exists = object.value(QStringLiteral("exists")).toBool();
+    record.isDirectory = object.value(QStringLiteral("dir")).toBool();
+    record.modTime = static_cast<uint64_t>(object.value(QStringLiteral("mtime")).toInteger());
+    record.size = static_cast<uint64_t>(object.value(QStringLiteral("size")).toInteger());
+    return record;
+}
+
+QByteArray jsonLine(const QJsonObject& object)
+{
+    return QJsonDocument(object).toJson(QJsonDocument::Compact) + '\n';
+}
+
+} // namespace
+
+bool FsEventRecorder::open(const QString& path, const std::vector<std::string>& roots,
+                           QString* error)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (m_file.isOpen()) {
+        m_file.close();
+    }
+    m_file.setFileName(path);
+    if (!m_file.open(QIODevice::WriteOnly | QIODevice::Truncate)) {
+        *error = QStringLiteral("cannot write %1: %2").arg(path, m_file.errorString());
+        return false;
+    }
+    QJsonArray rootArray;
+    for (const std::string& root : roots) {
+        rootArray.append(QString::fromStdString(root));
+    }
+    m_file.write(jsonLine({{QStringLiteral("format"), QLatin1String(kFormat)},
+                           {QStringLiteral("version"), kVersion},
+                           {QStringLiteral("roots"), rootArray}}));
+    m_file.flush();
+    m_clock.start();
+    return true;
+}
+
+bool FsEventRecorder::isOpen() const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_file.isOpen();
+}
+
+void FsEventRecorder::close()
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    m_file.close();
+}
+
+void FsEventRecorder::append(const std::vector<FsEventRecord>& events)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (!m_file.isOpen() || events.empty()) {
+        return;
+    }
+    QJsonArray eventArray;
+    for (const FsEventRecord& record : events) {
+        eventArray.append(recordToJson(record));
+    }
+    m_file.write(jsonLine({{QStringLiteral("t"), m_clock.elapsed()},
+                           {QStringLiteral("events"), eventArray}}));
+    m_file.flush();
+}
+
+std::optional<FsEventRecording> FsEventRecorder::load(const QString& path, QString* error)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("cannot read %1: %2").arg(path, file.errorString());
+        return std::nullopt;
+    }
+    return parse(file.readAll(), error);
+}
+
+std::optional<FsEventRecording> FsEventRecorder::parse(const QByteArray& ndjson, QString* error)
+{
+    FsEventRecording recording;
+    bool sawHeader = false;
+    int lineNumber = 0;
+    for (const QByteArray& rawLine : ndjson.split('\n')) {
+        ++lineNumber;
+        const QByteArray line = rawLine.trimmed();
+        if (line.isEmpty()) {
+            continue;
+        }
+        QJsonParseError parseError;
+        const QJsonDocument document = QJsonDocument::fromJson(line, &parseError);
+        if (!document.isObject()) {
+            *error = QStringLiteral("line %1: %2").arg(QString::number(lineNumber),
+                                                       parseError.errorString());
+            return std::nullopt;
+        }
+        const QJsonObject object = document.object();
+
+        if (!sawHeader) {
+            if (object.value(QStringLiteral("format")).toString() != QLatin1String(kFormat)
+                || object.value(QStringLiteral("version")).toInt() != kVersion) {
+                *error = QStringLiteral("not a version %1 FSEvents recording")
+                             .arg(kVersion);
+                return std::nullopt;
+            }
+            for (const QJsonValue& root : object.value(QStringLiteral("roots")).toArray()) {
+                recording.roots.push_back(root.toString().toStdString());
+            }
+            sawHeader = true;
+            continue;
+        }
+
+        FsEventBatch batch;
+        batch.offsetMs = object.value(QStringLiteral("t")).toInteger();
+        for (const QJsonValue& value : object.value(QStringLiteral("events")).toArray()) {
+            std::optional<FsEventRecord> record = recordFromJson(value);
+            if (!record.has_value()) {
+                *error = QStringLiteral("line %1: an event needs a path and flags")
+                             .arg(lineNumber);
+                return std::nullopt;
+            }
+            batch.events.push_back(std::move(*record));
+        }
+        if (!recording.batches.empty() && batch.offsetMs < recording.batches.back().offsetMs) {
+            *error = QStringLiteral("line %1: events go back in time").arg(lineNumber);
+            return std::nullopt;
+        }
+        recording.batches.push_back(std::move(batch));
+    }
+    if (!sawHeader) {
+        *error = QStringLiteral("empty recording");
+        return std::nullopt;
+    }
+    return recording;
+}
+
+} // namespace bs
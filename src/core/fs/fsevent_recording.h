@@ -0,0 +1,79 @@
+#pragma once
+
+#include <QByteArray>
+#include <QElapsedTimer>
+#include <QFile>
+#include <QString>
+
+#include <cstdint>
+#include <mutex>
+#include <optional>
+#include <string>
+#include <vector>
+
+namespace bs {
+
+// One raw FSEvents event, with what stat() said about its path when the
+// monitor handled it, so that a replay classifies it as the live stream did
+// without the file being there.
+struct FsEventRecord {
+    uint64_t eventId = 0;
+    uint32_t flags = 0;        // FSEventStreamEventFlags
+    std::string path;
+    bool exists = false;       // stat() succeeded
+    bool isDirectory = false;
+    uint64_t modTime = 0;      // st_mtime, seconds
+    uint64_t size = 0;
+};
+
+// The events of one FSEvents callback.
+struct FsEventBatch {
+    int64_t offsetMs = 0;      // since the recording started
+    std::vector<FsEventRecord> events;
+};
+
+struct FsEventRecording {
+    std::vector<std::string> roots;
+    std::vector<FsEventBatch> batches;
+};
+
+// FsEventRecorder -- appends the FSEvents callbacks a monitor receives to a
+// file: a header line naming the watched roots, then one JSON line per
+// callback. Each line is flushed as it is written, so a recording survives
+// the process crashing on the events that caused it.
+//
+//   {"format":"betterspotlight-fsevents","version":1,"roots":["/Users/me"]}
+//   {"t":0,"events":[{"id":4821,"flags":69888,"path":"/Users/me/a.txt",
+//                     "exists":true,"dir":false,"mtime":1760000000,"size":12}]}
+//
+// Set BETTERSPOTLIGHT_FSEVENTS_RECORD to a file path to record the indexer's
+// stream; Tests/Support/fsevent_replay.h plays a recording back.
+class FsEventRecorder {
+public:
+    static constexpr const char* kFormat = "betterspotlight-fsevents";
+    static constexpr int kVersion = 1;
+    static constexpr const char* kEnvRecordPath = "BETTERSPOTLIGHT_FSEVENTS_RECORD";
+
+    // Starts a recording at `path`, replacing the file. False with *error
+    // when it cannot be written.
+    bool open(const QString& path, const std::vector<std::string>& roots, QString* error);
+    bool isOpen() const;
+    void close();
+
+    // Appends one callback's events, stamped with the time since open().
+    // A no-op while closed.
+    void append(const std::vector<FsEventRecord>& events);
+
+    // A recording from a file, or from its bytes. Nullopt with *error when
+    // it is unreadable, not a recording of this version, or has a malformed
+    // line.
+    static std::optional<FsEventRecording> load(const QString& path, QString* error);
+    static std::optional<FsEventRecording> parse(const QByteArray& ndjson, QString* error);
+
+private:
+    mutable std::mutex m_mutex;
+    QFile m_file;
+    QElapsedTimer m_clock;
+};
+
+} // namespace bs
@@ -113,39 +113,44 @@ ValidationResult PathRules::validate(const std::string& filePath,
 {
     // Decision table (doc 03 Stage 3), evaluated in order:
 
-    // 1. .bsignore match -> Exclude
+    // 1. Privacy folder -> Exclude
+    if (isPrivacyExcluded(filePath)) {
+        return ValidationResult::Exclude;
+    }
+
+    // 2. .bsignore match -> Exclude
     if (isBsignoreExcluded(filePath)) {
         return ValidationResult::Exclude;
     }
 
-    // 2. Built-in exclusion match -> Exclude
+    // 3. Built-in exclusion match -> Exclude
     if (matchesDefaultExclusion(filePath)) {
         return ValidationResult::Exclude;
     }
 
-    // 3. Cloud artifact -> Exclude
+    // 4. Cloud artifact -> Exclude
     if (isCloudArtifact(filePath)) {
         return ValidationResult::Exclude;
     }
 
-    // 4. Sensitive path -> MetadataOnly
+    // 5. Sensitive path -> MetadataOnly
     if (isSensitivePath(filePath)) {
         return ValidationResult::MetadataOnly;
     }
 
-    // 5. Hidden path (dot-prefixed directory) -> Exclude unless explicitly added.
+    // 6. Hidden path (dot-prefixed directory) -> Exclude unless explicitly added.
     //    Only hidden directories, not files that happen to start with '.'.
     //    Cloud and bsignore-handled paths are already caught above.
     if (isHiddenPath(filePath) && !isExplicitIncludePath(filePath)) {
         return ValidationResult::Exclude;
     }
 
-    // 6. Size > 5 GB -> Exclude
+    // 7. Size > 5 GB -> Exclude
     if (fileSize > 0 && fileSize > kMaxFileSize) {
         return ValidationResult::Exclude;
     }
 
-    // 7. Otherwise -> Include
+    // 8. Otherwise -> Include
     return ValidationResult::Include;
 }
 
@@ -316,6 +321,45 @@ void PathRules::setExplicitIncludeRoots(const std::vector<std::string>& roots)
     }
 }
 
+void PathRules::setPrivacyExclusions(const std::vector<std::string>& roots)
+{
+    std::vector<std::string> normalizedRoots;
+    normalizedRoots.reserve(roots.size());
+    for (const std::string& root : roots) {
+        std::string normalized = root;
+        while (normalized.size() > 1 && normalized.back() == '/') {
+            normalized.pop_back();
+        }
+        if (!normalized.empty()) {
+            normalizedRoots.push_back(std::move(normalized));
+        }
+    }
+
+    std::lock_guard<std::mutex> lock(m_privacy->mutex);
+    m_privacy->roots = std::move(normalizedRoots);
+}
+
+std::vector<std::string> PathRules::privacyExclusions() const
+{
+    std::lock_guard<std::mutex> lock(m_privacy->mutex);
+    return m_privacy->roots;
+}
+
+bool PathRules::isPrivacyExcluded(const std::string& filePath) const
+{
+    std::lock_guard<std::mutex> lock(m_privacy->mutex);
+    for (const std::string& root : m_privacy->roots) {
+        if (filePath.compare(0, root.size(), root) != 0) {
+            continue;
+        }
+        // Directories are validated with a trailing slash.
+        if (filePath.size() == root.size() || root == "/" || filePath[root.size()] == '/') {
+            return true;
+        }
+    }
+    return false;
+}
+
 bool PathRules::matchesDefaultExclusion(const std::string& path) const
 {
     for (const auto& pattern : m_defaultExclusions) {
@@ -3,6 +3,8 @@
 #include "core/shared/types.h"
 #include "core/fs/bsignore_parser.h"
 #include <cstdint>
+#include <memory>
+#include <mutex>
 #include <string>
 #include <vector>
 
@@ -11,14 +13,15 @@ namespace bs {
 // PathRules — exclusion rules, sensitivity classification, and cloud detection.
 //
 // Validation decision table (doc 03 Stage 3, evaluated in order):
-//   1. .bsignore match             -> Exclude
-//   2. Built-in exclusion match    -> Exclude
-//   3. Cloud artifact              -> Exclude
-//   4. Sensitive path              -> MetadataOnly
-//   5. Hidden path (dot-prefixed)  -> Exclude (hidden dirs only; not
+//   1. Privacy folder              -> Exclude
+//   2. .bsignore match             -> Exclude
+//   3. Built-in exclusion match    -> Exclude
+//   4. Cloud artifact              -> Exclude
+//   5. Sensitive path              -> MetadataOnly
+//   6. Hidden path (dot-prefixed)  -> Exclude (hidden dirs only; not
 //      cloud/bsignore-handled paths), unless explicitly added as a root
-//   6. Size > 5 GB                 -> Exclude
-//   7. Otherwise                   -> Include
+//   7. Size > 5 GB                 -> Exclude
+//   8. Otherwise                   -> Include
 class PathRules {
 public:
     PathRules();
@@ -58,6 +61,15 @@ public:
     // This is used to support opt-in indexing of hidden folders.
     void setExplicitIncludeRoots(const std::vector<std::string>& roots);
 
+    // Privacy folders (Settings > Privacy): nothing at or below them is
+    // indexed, not even metadata, and they win over explicit roots.
+    // Copies of a PathRules share one list, so a folder added through the
+    // indexer service's rules applies at once to the running pipeline's
+    // copy and the scanners and indexer built on it. Thread-safe.
+    void setPrivacyExclusions(const std::vector<std::string>& roots);
+    std::vector<std::string> privacyExclusions() const;
+    bool isPrivacyExcluded(const std::string& filePath) const;
+
     // Maximum file size for indexing (5 GB).
     static constexpr uint64_t kMaxFileSize = 5ULL * 1024 * 1024 * 1024;
 
@@ -85,6 +97,12 @@ private:
     std::vector<std::string> m_defaultExclusions;
     std::vector<std::string> m_sensitivePatterns;
     std::vector<std::string> m_explicitIncludeRoots;
+
+    struct PrivacyList {
+        mutable std::mutex mutex;
+        std::vector<std::string> roots;
+    };
+    std::shared_ptr<PrivacyList> m_privacy = std::make_shared<PrivacyList>();
     BsignoreParser m_bsignoreParser;
     std::string m_bsignorePath;
     int64_t m_bsignoreLastLoadedAtMs = 0;
@@ -0,0 +1,22 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// QuickLookThumbnail — renders a file preview with QuickLookThumbnailing,
+// the generator Finder uses, so result lists can show real page and image
+// previews. Files QuickLook cannot preview fall back to their Finder icon.
+class QuickLookThumbnail {
+public:
+    // PNG fitting in pixelSize x pixelSize (aspect preserved). Blocks until
+    // QuickLook answers or timeoutMs passes. On failure returns nullopt and
+    // sets *error to "timeout" or QuickLook's message.
+    static std::optional<QByteArray> generatePng(const QString& path, int pixelSize,
+                                                 int timeoutMs, QString* error = nullptr);
+};
+
+} // namespace bs
@@ -0,0 +1,97 @@
+#include "core/fs/quicklook_thumbnail.h"
+
+#import <Foundation/Foundation.h>
+#import <ImageIO/ImageIO.h>
+#import <QuickLookThumbnailing/QuickLookThumbnailing.h>
+#import <dispatch/dispatch.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+std::optional<QByteArray> encodePng(CGImageRef image)
+{
+    NSMutableData* data = [NSMutableData data];
+    CGImageDestinationRef destination = CGImageDestinationCreateWithData(
+        reinterpret_cast<CFMutableDataRef>(data), CFSTR("public.png"), 1, nullptr);
+    if (!destination) {
+        return std::nullopt;
+    }
+    CGImageDestinationAddImage(destination, image, nullptr);
+    const bool finalized = CGImageDestinationFinalize(destination);
+    CFRelease(destination);
+    if (!finalized) {
+        return std::nullopt;
+    }
+    return QByteArray::fromNSData(data);
+}
+
+} // anonymous namespace
+
+std::optional<QByteArray> QuickLookThumbnail::generatePng(const QString& path, int pixelSize,
+                                                          int timeoutMs, QString* error)
+{
+    @autoreleasepool {
+        NSURL* url = [NSURL fileURLWithPath:path.toNSString()];
+        QLThumbnailGenerationRequest* request = [[QLThumbnailGenerationRequest alloc]
+              initWithFileAtURL:url
+                           size:CGSizeMake(pixelSize, pixelSize)
+                          scale:1.0
+            representationTypes:(QLThumbnailGenerationRequestRepresentationTypeThumbnail
+                                 | QLThumbnailGenerationRequestRepresentationTypeIcon)];
+
+        // The handler runs on a QuickLook queue; the block storage outlives
+        // this frame, so a completion racing the timeout is harmless. The
+        // semaphore is deliberately leaked on timeout for the same reason.
+        __block CGImageRef image = nullptr;
+        __block NSString* failure = nil;
+        dispatch_semaphore_t completion = dispatch_semaphore_create(0);
+        QLThumbnailGenerator* generator = [QLThumbnailGenerator sharedGenerator];
+        [generator generateBestRepresentationForRequest:request
+                                      completionHandler:^(QLThumbnailRepresentation* representation,
+                                                          NSError* generationError) {
+            if (representation && representation.CGImage) {
+                image = CGImageRetain(representation.CGImage);
+            } else {
+                failure = [generationError.localizedDescription copy];
+            }
+            dispatch_semaphore_signal(completion);
+        }];
+
+        const long waitResult = dispatch_semaphore_wait(
+            completion,
+            dispatch_time(DISPATCH_TIME_NOW, static_cast<int64_t>(timeoutMs) * NSEC_PER_MSEC));
+        if (waitResult != 0) {
+            [generator cancelRequest:request];
+            [request release];
+            if (error) {
+                *error = QStringLiteral("timeout");
+            }
+            return std::nullopt;
+        }
+        [request release];
+        dispatch_release(completion);
+
+        if (!image) {
+            if (error) {
+                *error = failure ? toQString(failure) : QStringLiteral("no thumbnail generated");
+            }
+            [failure release];
+            return std::nullopt;
+        }
+
+        std::optional<QByteArray> png = encodePng(image);
+        CGImageRelease(image);
+        if (!png.has_value() && error) {
+            *error = QStringLiteral("png encoding failed");
+        }
+        return png;
+    }
+}
+
+} // namespace bs
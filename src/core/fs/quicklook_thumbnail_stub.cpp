@@ -0,0 +1,17 @@
+#include "core/fs/quicklook_thumbnail.h"
+
+namespace bs {
+
+std::optional<QByteArray> QuickLookThumbnail::generatePng(const QString& path, int pixelSize,
+                                                          int timeoutMs, QString* error)
+{
+    Q_UNUSED(path)
+    Q_UNUSED(pixelSize)
+    Q_UNUSED(timeoutMs)
+    if (error) {
+        *error = QStringLiteral("quicklook_not_supported_on_this_platform");
+    }
+    return std::nullopt;
+}
+
+} // namespace bs
@@ -0,0 +1,46 @@
+#include "core/fs/recent_documents.h"
+
+#include <QDir>
+
+#include <algorithm>
+#include <cmath>
+#include <utility>
+
+namespace bs {
+
+double RecentDocuments::entryScore(const Entry& entry, qint64 nowSecs)
+{
+    if (entry.path.isEmpty() || entry.rank < 0) {
+        return 0.0;
+    }
+    const double ageDays =
+        static_cast<double>(std::max<qint64>(0, nowSecs - entry.listModifiedAt)) / 86400.0;
+    const double recency = std::pow(0.5, ageDays / kHalfLifeDays);
+    const double position = 1.0 / (1.0 + 0.25 * static_cast<double>(entry.rank));
+    return std::clamp(recency * position, 0.0, 1.0);
+}
+
+void RecentDocuments::update(const std::vector<Entry>& entries, qint64 nowSecs)
+{
+    QHash<QString, double> scores;
+    scores.reserve(static_cast<qsizetype>(entries.size()));
+    for (const Entry& entry : entries) {
+        const double entryValue = entryScore(entry, nowSecs);
+        if (entryValue <= 0.0) {
+            continue;
+        }
+        double& best = scores[QDir::cleanPath(entry.path)];
+        best = std::max(best, entryValue);
+    }
+    m_scores = std::move(scores);
+}
+
+double RecentDocuments::score(const QString& path) const
+{
+    if (m_scores.isEmpty()) {
+        return 0.0;
+    }
+    return m_scores.value(QDir::cleanPath(path), 0.0);
+}
+
+} // namespace bs
@@ -0,0 +1,52 @@
+#pragma once
+
+#include <QHash>
+#include <QString>
+#include <QtGlobal>
+
+#include <vector>
+
+namespace bs {
+
+// RecentDocuments -- documents other apps opened recently, as a ranking
+// signal, so something just worked on in Pages or Xcode ranks higher before
+// it was ever opened through BetterSpotlight.
+//
+// macOS keeps the File > Open Recent list of every app as a shared file list
+// under ~/Library/Application Support/com.apple.sharedfilelist. An app's own
+// NSDocumentController only ever sees that app's list, so the lists are read
+// directly; lists the process may not read (privacy settings, newer formats)
+// are skipped. The lists carry no per-document time: a document's recency is
+// that of the list change, its rank in the list discounting it.
+class RecentDocuments {
+public:
+    static constexpr double kHalfLifeDays = 3.0;
+
+    struct Entry {
+        QString path;
+        QString appBundleId;        // empty for the system-wide list
+        int rank = 0;               // 0 = most recent in its list
+        qint64 listModifiedAt = 0;  // epoch seconds
+    };
+
+    // Entries of every readable list. *unreadableLists counts the lists
+    // found but not read. Always empty off macOS.
+    static std::vector<Entry> readSystemLists(int* unreadableLists = nullptr);
+
+    // 0..1: halves every kHalfLifeDays since the list changed, and falls off
+    // with rank (a quarter per place).
+    static double entryScore(const Entry& entry, qint64 nowSecs);
+
+    // Replaces the known documents. A path in several lists keeps its best
+    // score.
+    void update(const std::vector<Entry>& entries, qint64 nowSecs);
+
+    // 0 for documents not in any list.
+    double score(const QString& path) const;
+    int size() const { return static_cast<int>(m_scores.size()); }
+
+private:
+    QHash<QString, double> m_scores;
+};
+
+} // namespace bs
@@ -0,0 +1,130 @@
+#include "core/fs/recent_documents.h"
+
+#include <QDir>
+
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+NSString* sharedFileListDirectory()
+{
+    NSArray<NSString*>* dirs = NSSearchPathForDirectoriesInDomains(
+        NSApplicationSupportDirectory, NSUserDomainMask, YES);
+    if (dirs.count == 0) {
+        return nil;
+    }
+    return [dirs.firstObject stringByAppendingPathComponent:@"com.apple.sharedfilelist"];
+}
+
+// A .sfl2/.sfl3 file is a keyed archive of {items: [{Bookmark: <data>}, ...]},
+// most recent first. Bookmarks are only read, not resolved, so nothing is
+// mounted and no file is touched. Returns false if the list cannot be read.
+bool readList(NSString* listPath, const QString& appBundleId,
+              std::vector<RecentDocuments::Entry>& entries)
+{
+    NSError* error = nil;
+    NSData* data = [NSData dataWithContentsOfFile:listPath options:0 error:&error];
+    if (!data) {
+        return false;
+    }
+    NSSet* classes = [NSSet setWithObjects:NSDictionary.class, NSArray.class, NSString.class,
+                                           NSData.class, NSNumber.class, NSUUID.class,
+                                           NSDate.class, nil];
+    id root = [NSKeyedUnarchiver unarchivedObjectOfClasses:classes fromData:data error:&error];
+    if (![root isKindOfClass:NSDictionary.class]) {
+        return false;
+    }
+    NSArray* items = [(NSDictionary*)root objectForKey:@"items"];
+    if (![items isKindOfClass:NSArray.class]) {
+        return false;
+    }
+
+    NSDictionary<NSFileAttributeKey, id>* attributes =
+        [NSFileManager.defaultManager attributesOfItemAtPath:listPath error:nil];
+    const qint64 modifiedAt = static_cast<qint64>(attributes.fileModificationDate.timeIntervalSince1970);
+
+    int rank = 0;
+    for (id item in items) {
+        if (![item isKindOfClass:NSDictionary.class]) {
+            continue;
+        }
+        NSData* bookmark = [(NSDictionary*)item objectForKey:@"Bookmark"];
+        if (![bookmark isKindOfClass:NSData.class]) {
+            continue;
+        }
+        NSDictionary<NSURLResourceKey, id>* values =
+            [NSURL resourceValuesForKeys:@[NSURLPathKey] fromBookmarkData:bookmark];
+        NSString* path = values[NSURLPathKey];
+        if (path.length == 0) {
+            continue;
+        }
+        RecentDocuments::Entry entry;
+        entry.path = QDir::cleanPath(toQString(path));
+        entry.appBundleId = appBundleId;
+        entry.rank = rank++;
+        entry.listModifiedAt = modifiedAt;
+        entries.push_back(std::move(entry));
+    }
+    return true;
+}
+
+bool isSharedFileList(NSString* name)
+{
+    return [name.pathExtension isEqualToString:@"sfl2"]
+        || [name.pathExtension isEqualToString:@"sfl3"];
+}
+
+} // anonymous namespace
+
+std::vector<RecentDocuments::Entry> RecentDocuments::readSystemLists(int* unreadableLists)
+{
+    std::vector<Entry> entries;
+    int unreadable = 0;
+
+    @autoreleasepool {
+        NSString* root = sharedFileListDirectory();
+        if (root) {
+            // Per app: <bundle id>.sfl2 / .sfl3.
+            NSString* appDir = [root stringByAppendingPathComponent:
+                @"com.apple.LSSharedFileList.ApplicationRecentDocuments"];
+            NSArray<NSString*>* names =
+                [NSFileManager.defaultManager contentsOfDirectoryAtPath:appDir error:nil];
+            for (NSString* name in names) {
+                if (!isSharedFileList(name)) {
+                    continue;
+                }
+                const QString bundleId = toQString(name.stringByDeletingPathExtension);
+                if (!readList([appDir stringByAppendingPathComponent:name], bundleId, entries)) {
+                    ++unreadable;
+                }
+            }
+
+            // System-wide: Apple menu > Recent Items.
+            for (NSString* name in @[@"com.apple.LSSharedFileList.RecentDocuments.sfl3",
+                                     @"com.apple.LSSharedFileList.RecentDocuments.sfl2"]) {
+                NSString* listPath = [root stringByAppendingPathComponent:name];
+                if (![NSFileManager.defaultManager fileExistsAtPath:listPath]) {
+                    continue;
+                }
+                if (!readList(listPath, QString(), entries)) {
+                    ++unreadable;
+                }
+                break;
+            }
+        }
+    }
+
+    if (unreadableLists) {
+        *unreadableLists = unreadable;
+    }
+    return entries;
+}
+
+} // namespace bs
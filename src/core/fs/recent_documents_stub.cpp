@@ -0,0 +1,13 @@
+#include "core/fs/recent_documents.h"
+
+namespace bs {
+
+std::vector<RecentDocuments::Entry> RecentDocuments::readSystemLists(int* unreadableLists)
+{
+    if (unreadableLists) {
+        *unreadableLists = 0;
+    }
+    return {};
+}
+
+} // namespace bs
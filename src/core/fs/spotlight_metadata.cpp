@@ -0,0 +1,103 @@
+#include "core/fs/spotlight_metadata.h"
+
+#include <CoreServices/CoreServices.h>
+
+#include <QHash>
+#include <QSet>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxValuesPerAttribute = 16;
+constexpr int kMaxValueChars = 2048;
+
+QString cfStringToQString(CFTypeRef value)
+{
+    if (!value || CFGetTypeID(value) != CFStringGetTypeID()) {
+        return {};
+    }
+    return QString::fromCFString(static_cast<CFStringRef>(value));
+}
+
+// Spotlight attributes are a string or an array of strings; anything else
+// (dates, numbers) is not imported.
+void appendValues(std::vector<ItemAttribute>& out, const SpotlightMetadata::Field& field,
+                  CFTypeRef value)
+{
+    if (CFGetTypeID(value) == CFArrayGetTypeID()) {
+        const auto array = static_cast<CFArrayRef>(value);
+        const CFIndex count = CFArrayGetCount(array);
+        for (CFIndex i = 0; i < count; ++i) {
+            out.push_back({field.name, cfStringToQString(CFArrayGetValueAtIndex(array, i)),
+                           field.searchable});
+        }
+        return;
+    }
+    out.push_back({field.name, cfStringToQString(value), field.searchable});
+}
+
+} // anonymous namespace
+
+const std::vector<SpotlightMetadata::Field>& SpotlightMetadata::fields()
+{
+    static const std::vector<Field> kFields = {
+        {QStringLiteral("kMDItemContentType"), QStringLiteral("contentType"), false},
+        {QStringLiteral("kMDItemTitle"), QStringLiteral("title"), true},
+        {QStringLiteral("kMDItemAuthors"), QStringLiteral("authors"), true},
+        {QStringLiteral("kMDItemCreator"), QStringLiteral("creator"), true},
+        {QStringLiteral("kMDItemKeywords"), QStringLiteral("keywords"), true},
+        {QStringLiteral("kMDItemWhereFroms"), QStringLiteral("whereFroms"), true},
+    };
+    return kFields;
+}
+
+std::optional<std::vector<ItemAttribute>> SpotlightMetadata::read(const QString& path)
+{
+    const CFStringRef cfPath = path.toCFString();
+    if (!cfPath) {
+        return std::nullopt;
+    }
+    MDItemRef item = MDItemCreate(kCFAllocatorDefault, cfPath);
+    CFRelease(cfPath);
+    if (!item) {
+        return std::nullopt;
+    }
+
+    std::vector<ItemAttribute> raw;
+    for (const Field& field : fields()) {
+        const CFStringRef key = field.mdKey.toCFString();
+        const CFTypeRef value = MDItemCopyAttribute(item, key);
+        CFRelease(key);
+        if (value) {
+            appendValues(raw, field, value);
+            CFRelease(value);
+        }
+    }
+    CFRelease(item);
+    return normalize(raw);
+}
+
+std::vector<ItemAttribute> SpotlightMetadata::normalize(const std::vector<ItemAttribute>& raw)
+{
+    std::vector<ItemAttribute> out;
+    out.reserve(raw.size());
+    QSet<QString> seen;
+    QHash<QString, int> perName;
+    for (const ItemAttribute& attribute : raw) {
+        const QString value = attribute.value.trimmed().left(kMaxValueChars);
+        if (value.isEmpty() || perName.value(attribute.name) >= kMaxValuesPerAttribute) {
+            continue;
+        }
+        const QString key = attribute.name + QLatin1Char('\n') + value;
+        if (seen.contains(key)) {
+            continue;
+        }
+        seen.insert(key);
+        ++perName[attribute.name];
+        out.push_back({attribute.name, value, attribute.searchable});
+    }
+    return out;
+}
+
+} // namespace bs
@@ -0,0 +1,37 @@
+#pragma once
+
+#include "core/shared/types.h"
+
+#include <QString>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// SpotlightMetadata — imports what Spotlight already knows about a file
+// (content type, authors, download origin, ...) through the MDItem API, so
+// a fresh index is as rich as the Spotlight store it replaces.
+//
+// Stored names drop the kMDItem prefix: kMDItemWhereFroms -> "whereFroms".
+class SpotlightMetadata {
+public:
+    struct Field {
+        QString mdKey;    // Spotlight attribute, e.g. "kMDItemWhereFroms"
+        QString name;     // stored attribute name, e.g. "whereFroms"
+        bool searchable;  // values are also matched by plain text queries
+    };
+
+    // Imported attributes, in storage order.
+    static const std::vector<Field>& fields();
+
+    // Read the imported attributes of one file. Returns nullopt when
+    // Spotlight has no record of it (unindexed volume, file not imported
+    // yet), so callers keep whatever was stored before.
+    static std::optional<std::vector<ItemAttribute>> read(const QString& path);
+
+    // Trim values, drop empty and repeated ones, and cap the number and
+    // length of values per attribute.
+    static std::vector<ItemAttribute> normalize(const std::vector<ItemAttribute>& raw);
+};
+
+} // namespace bs
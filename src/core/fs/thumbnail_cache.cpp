@@ -0,0 +1,128 @@
+#include "core/fs/thumbnail_cache.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QDir>
+#include <QDirIterator>
+#include <QFile>
+#include <QFileInfo>
+#include <QSaveFile>
+
+#include <algorithm>
+#include <utility>
+#include <vector>
+
+namespace bs {
+
+ThumbnailCache::ThumbnailCache(QString rootDir, qint64 maxBytes)
+    : m_rootDir(std::move(rootDir))
+    , m_maxBytes(maxBytes)
+{
+}
+
+int ThumbnailCache::normalizePixelSize(int pixelSize)
+{
+    return std::clamp(pixelSize, kMinPixelSize, kMaxPixelSize);
+}
+
+QString ThumbnailCache::cacheKey(const QString& path, double modifiedAt, int pixelSize)
+{
+    QCryptographicHash hash(QCryptographicHash::Sha1);
+    hash.addData(path.toUtf8());
+    hash.addData(QByteArrayLiteral("\n"));
+    hash.addData(QByteArray::number(modifiedAt, 'f', 3));
+    hash.addData(QByteArrayLiteral("\n"));
+    hash.addData(QByteArray::number(pixelSize));
+    return QString::fromLatin1(hash.result().toHex());
+}
+
+QString ThumbnailCache::entryPath(const QString& key) const
+{
+    return m_rootDir + QLatin1Char('/') + key.left(2) + QLatin1Char('/') + key
+        + QStringLiteral(".png");
+}
+
+std::optional<QByteArray> ThumbnailCache::lookup(const QString& key) const
+{
+    QFile file(entryPath(key));
+    if (!file.open(QIODevice::ReadWrite)) {
+        return std::nullopt;
+    }
+    const QByteArray png = file.readAll();
+    if (png.isEmpty()) {
+        return std::nullopt;
+    }
+    file.setFileTime(QDateTime::currentDateTimeUtc(), QFileDevice::FileModificationTime);
+    return png;
+}
+
+bool ThumbnailCache::store(const QString& key, const QByteArray& png)
+{
+    const QString path = entryPath(key);
+    if (!QDir().mkpath(QFileInfo(path).absolutePath())) {
+        return false;
+    }
+    QSaveFile file(path);
+    if (!file.open(QIODevice::WriteOnly) || file.write(png) != png.size() || !file.commit()) {
+        return false;
+    }
+    if (++m_storesSincePrune >= kStoresPerPrune) {
+        prune();
+    }
+    return true;
+}
+
+int ThumbnailCache::prune()
+{
+    m_storesSincePrune = 0;
+
+    struct Entry {
+        QString path;
+        qint64 size = 0;
+        QDateTime lastUsed;
+    };
+    std::vector<Entry> entries;
+    qint64 totalBytes = 0;
+    QDirIterator it(m_rootDir, {QStringLiteral("*.png")}, QDir::Files,
+                    QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        it.next();
+        const QFileInfo info = it.fileInfo();
+        entries.push_back({info.filePath(), info.size(), info.lastModified()});
+        totalBytes += info.size();
+    }
+    if (totalBytes <= m_maxBytes) {
+        return 0;
+    }
+
+    std::sort(entries.begin(), entries.end(), [](const Entry& a, const Entry& b) {
+        return a.lastUsed < b.lastUsed;
+    });
+    int removed = 0;
+    for (const Entry& entry : entries) {
+        if (totalBytes <= m_maxBytes) {
+            break;
+        }
+        if (QFile::remove(entry.path)) {
+            totalBytes -= entry.size;
+            ++removed;
+        }
+    }
+    return removed;
+}
+
+int ThumbnailCache::clear()
+{
+    int removed = 0;
+    QDirIterator it(m_rootDir, {QStringLiteral("*.png")}, QDir::Files,
+                    QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        if (QFile::remove(it.next())) {
+            ++removed;
+        }
+    }
+    m_storesSincePrune = 0;
+    return removed;
+}
+
+} // namespace bs
@@ -0,0 +1,54 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// ThumbnailCache — PNG previews on disk (<dataDir>/thumbnails), so a result
+// list only pays for QuickLook once per document version.
+//
+// Keys combine path, modification time and pixel size: an edited file
+// misses the cache instead of serving a stale preview. Entries for old
+// versions are left behind and removed by prune(), least recently used
+// first.
+class ThumbnailCache {
+public:
+    static constexpr int kDefaultPixelSize = 256;
+    static constexpr int kMinPixelSize = 16;
+    static constexpr int kMaxPixelSize = 1024;
+    static constexpr qint64 kDefaultMaxBytes = 256LL * 1024 * 1024;
+
+    explicit ThumbnailCache(QString rootDir, qint64 maxBytes = kDefaultMaxBytes);
+
+    // pixelSize clamped to [kMinPixelSize, kMaxPixelSize].
+    static int normalizePixelSize(int pixelSize);
+    static QString cacheKey(const QString& path, double modifiedAt, int pixelSize);
+
+    // <rootDir>/<first two key chars>/<key>.png
+    QString entryPath(const QString& key) const;
+
+    // Cached PNG for key; a hit counts as a use for prune().
+    std::optional<QByteArray> lookup(const QString& key) const;
+    // Atomically writes the entry; prunes every kStoresPerPrune stores.
+    bool store(const QString& key, const QByteArray& png);
+    // Deletes least recently used entries until the cache fits in maxBytes.
+    // Returns the number of entries removed.
+    int prune();
+    // Deletes every entry. Keys hash the path, so a folder's entries cannot
+    // be found on their own. Returns the number of entries removed.
+    int clear();
+
+    const QString& rootDir() const { return m_rootDir; }
+
+private:
+    static constexpr int kStoresPerPrune = 64;
+
+    QString m_rootDir;
+    qint64 m_maxBytes = kDefaultMaxBytes;
+    int m_storesSincePrune = 0;
+};
+
+} // namespace bs
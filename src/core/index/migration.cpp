@@ -278,6 +278,90 @@ bool applyMigrations(sqlite3* db, int targetVersion)
         current = 4;
     }
 
+    if (current < 5 && targetVersion >= 5) {
+        LOG_INFO(bsIndex, "Applying schema migration 4 -> 5");
+
+        if (!exec(R"(
+            CREATE TABLE IF NOT EXISTS item_attributes (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+                name TEXT NOT NULL,
+                value TEXT NOT NULL,
+                searchable INTEGER NOT NULL DEFAULT 1,
+                UNIQUE(item_id, name, value)
+            );
+        )")) {
+            return false;
+        }
+        if (!exec("CREATE INDEX IF NOT EXISTS idx_item_attributes_name_value ON item_attributes(name, value);")) {
+            return false;
+        }
+
+        if (!exec("INSERT OR REPLACE INTO settings (key, value) VALUES ('schema_version', '5');")) {
+            return false;
+        }
+
+        current = 5;
+    }
+
+    if (current < 6 && targetVersion >= 6) {
+        LOG_INFO(bsIndex, "Applying schema migration 5 -> 6");
+
+        if (!exec(R"(
+            CREATE TABLE IF NOT EXISTS query_history (
+                query_normalized TEXT PRIMARY KEY,
+                query TEXT NOT NULL,
+                hit_count INTEGER NOT NULL DEFAULT 1,
+                last_result_count INTEGER NOT NULL DEFAULT 0,
+                first_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+        )")) {
+            return false;
+        }
+        if (!exec("CREATE INDEX IF NOT EXISTS idx_query_history_last_used ON query_history(last_used_at);")
+            || !exec("INSERT OR IGNORE INTO settings (key, value) VALUES ('queryHistoryRetentionDays', '90');")) {
+            return false;
+        }
+
+        if (!exec("INSERT OR REPLACE INTO settings (key, value) VALUES ('schema_version', '6');")) {
+            return false;
+        }
+
+        current = 6;
+    }
+
+    if (current < 7 && targetVersion >= 7) {
+        LOG_INFO(bsIndex, "Applying schema migration 6 -> 7");
+
+        // Deleted item ids, so a peer sync delta can carry deletions. Only
+        // the id is kept: no path outlives its item.
+        if (!exec(R"(
+            CREATE TABLE IF NOT EXISTS item_tombstones (
+                seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+                item_id    INTEGER NOT NULL,
+                deleted_at REAL NOT NULL
+            );
+        )")) {
+            return false;
+        }
+        if (!exec("CREATE INDEX IF NOT EXISTS idx_item_tombstones_deleted_at ON item_tombstones(deleted_at);")
+            || !exec(R"(
+            CREATE TRIGGER IF NOT EXISTS items_tombstone AFTER DELETE ON items BEGIN
+                INSERT INTO item_tombstones (item_id, deleted_at)
+                VALUES (old.id, (julianday('now') - 2440587.5) * 86400.0);
+            END;
+        )")) {
+            return false;
+        }
+
+        if (!exec("INSERT OR REPLACE INTO settings (key, value) VALUES ('schema_version', '7');")) {
+            return false;
+        }
+
+        current = 7;
+    }
+
     if (current != targetVersion) {
         LOG_ERROR(bsIndex, "Schema migration incomplete: current=%d target=%d",
                   current, targetVersion);
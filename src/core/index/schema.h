@@ -234,6 +234,41 @@ CREATE TABLE IF NOT EXISTS learning_model_state_v1 (
     value TEXT NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS item_attributes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    searchable INTEGER NOT NULL DEFAULT 1,
+    UNIQUE(item_id, name, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_attributes_name_value ON item_attributes(name, value);
+
+CREATE TABLE IF NOT EXISTS query_history (
+    query_normalized TEXT PRIMARY KEY,
+    query         TEXT NOT NULL,
+    hit_count     INTEGER NOT NULL DEFAULT 1,
+    last_result_count INTEGER NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_history_last_used ON query_history(last_used_at);
+
+CREATE TABLE IF NOT EXISTS item_tombstones (
+    seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id    INTEGER NOT NULL,
+    deleted_at REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_tombstones_deleted_at ON item_tombstones(deleted_at);
+
+CREATE TRIGGER IF NOT EXISTS items_tombstone AFTER DELETE ON items BEGIN
+    INSERT INTO item_tombstones (item_id, deleted_at)
+    VALUES (old.id, (julianday('now') - 2440587.5) * 86400.0);
+END;
+
 CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
     file_name,
     file_path,
@@ -250,7 +285,7 @@ constexpr const char* kFts5WeightConfig =
 
 // Default settings rows (doc 04 Section 10.1)
 constexpr const char* kDefaultSettings = R"(
-INSERT OR IGNORE INTO settings (key, value) VALUES ('schema_version', '4');
+INSERT OR IGNORE INTO settings (key, value) VALUES ('schema_version', '7');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('last_full_index_at', '0');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('last_vacuum_at', '0');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('max_file_size', '104857600');
@@ -287,6 +322,7 @@ INSERT OR IGNORE INTO settings (key, value) VALUES ('enablePathPreferences', '1'
 INSERT OR IGNORE INTO settings (key, value) VALUES ('enableFileTypeAffinity', '1');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('feedbackRetentionDays', '90');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('interactionRetentionDays', '180');
+INSERT OR IGNORE INTO settings (key, value) VALUES ('queryHistoryRetentionDays', '90');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('nextHnswLabel', '0');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('hnswDeletedCount', '0');
 INSERT OR IGNORE INTO settings (key, value) VALUES ('activeVectorGeneration', 'v1');
@@ -370,6 +406,6 @@ INSERT OR IGNORE INTO settings (key, value) VALUES ('onlineRankerCoreMlInitError
 INSERT OR IGNORE INTO settings (key, value) VALUES ('learningDenylistApps', '[]');
 )";
 
-constexpr int kCurrentSchemaVersion = 4;
+constexpr int kCurrentSchemaVersion = 7;
 
 } // namespace bs
@@ -6,12 +6,15 @@
 #include <sqlite3.h>
 #include <QDateTime>
 #include <QFile>
+#include <QHash>
 #include <QRegularExpression>
 #include <QSet>
 #include <QThread>
 
+#include <algorithm>
 #include <cmath>
 #include <cstring>
+#include <limits>
 
 namespace bs {
 
@@ -31,6 +34,71 @@ static int stepWithRetry(sqlite3_stmt* stmt, int maxAttempts = 5)
     return rc;
 }
 
+// Bounds of "root itself or anything below it": "root/" up to (not
+// including) "root0". '0' follows '/' in byte order, so the range holds
+// exactly the descendants and never "root-old/...".
+struct SubtreeBounds {
+    QByteArray exact;
+    QByteArray lower;
+    QByteArray upper;
+};
+
+static SubtreeBounds subtreeBounds(const QString& root)
+{
+    QString normalized = root;
+    while (normalized.size() > 1 && normalized.endsWith(QLatin1Char('/'))) {
+        normalized.chop(1);
+    }
+    SubtreeBounds bounds;
+    bounds.exact = normalized.toUtf8();
+    const QByteArray base = normalized == QLatin1String("/") ? QByteArray() : bounds.exact;
+    bounds.lower = base + '/';
+    bounds.upper = base + '0';
+    return bounds;
+}
+
+// Binds ?1 (exact), ?2 and ?3 (descendant range). `bounds` must outlive
+// the statement's execution.
+static void bindSubtreeBounds(sqlite3_stmt* stmt, const SubtreeBounds& bounds)
+{
+    sqlite3_bind_text(stmt, 1, bounds.exact.constData(), bounds.exact.size(), SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 2, bounds.lower.constData(), bounds.lower.size(), SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 3, bounds.upper.constData(), bounds.upper.size(), SQLITE_STATIC);
+}
+
+// Deleted items are only overwritten once compact() runs; count them in
+// the same transaction as the delete, so the tally survives a restart.
+static void countPendingCompactionDeletes(sqlite3* db, int64_t deletes)
+{
+    const char* sql = R"(
+        INSERT INTO settings (key, value) VALUES ('pending_compaction_deletes', ?1)
+        ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + excluded.value
+    )";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_WARN(bsIndex, "Pending compaction count prepare: %s", sqlite3_errmsg(db));
+        return;
+    }
+    sqlite3_bind_int64(stmt, 1, deletes);
+    stepWithRetry(stmt);
+    sqlite3_finalize(stmt);
+}
+
+// Escape LIKE metacharacters so user input is matched literally
+// (pair with ESCAPE '\').
+static QString escapeLikeLiteral(const QString& text)
+{
+    QString escaped;
+    escaped.reserve(text.size() + 1);
+    for (const QChar ch : text) {
+        if (ch == QLatin1Char('%') || ch == QLatin1Char('_') || ch == QLatin1Char('\\')) {
+            escaped.append(QLatin1Char('\\'));
+        }
+        escaped.append(ch);
+    }
+    return escaped;
+}
+
 SQLiteStore::~SQLiteStore()
 {
     if (m_db) {
@@ -48,6 +116,15 @@ std::optional<SQLiteStore> SQLiteStore::open(const QString& dbPath)
     return store;
 }
 
+std::optional<SQLiteStore> SQLiteStore::openReadOnly(const QString& dbPath)
+{
+    SQLiteStore store;
+    if (!store.initReadOnly(dbPath)) {
+        return std::nullopt;
+    }
+    return store;
+}
+
 bool SQLiteStore::init(const QString& dbPath)
 {
     int rc = sqlite3_open(dbPath.toUtf8().constData(), &m_db);
@@ -154,6 +231,36 @@ bool SQLiteStore::init(const QString& dbPath)
     return true;
 }
 
+bool SQLiteStore::initReadOnly(const QString& dbPath)
+{
+    int rc = sqlite3_open_v2(dbPath.toUtf8().constData(), &m_db, SQLITE_OPEN_READONLY, nullptr);
+    if (rc != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Failed to open database read-only: %s", sqlite3_errmsg(m_db));
+        return false;
+    }
+    // Short: a publisher replacing the file should cost a search one
+    // index's results, not a stall.
+    sqlite3_busy_timeout(m_db, 500);
+
+    // query_only makes even a stray write statement fail, on top of the
+    // read-only connection.
+    if (!execSql("PRAGMA query_only = ON; PRAGMA cache_size = -16384;")) {
+        LOG_ERROR(bsIndex, "Failed to set read-only connection pragmas");
+        return false;
+    }
+
+    // Its queries assume this schema; migrating is the publisher's job.
+    const int version = currentSchemaVersion(m_db);
+    if (version != kCurrentSchemaVersion) {
+        LOG_WARN(bsIndex, "Read-only index %s has schema version %d, expected %d",
+                 dbPath.toUtf8().constData(), version, kCurrentSchemaVersion);
+        return false;
+    }
+
+    LOG_INFO(bsIndex, "Read-only database opened: %s", dbPath.toUtf8().constData());
+    return true;
+}
+
 bool SQLiteStore::execSql(const char* sql)
 {
     char* errMsg = nullptr;
@@ -166,6 +273,20 @@ bool SQLiteStore::execSql(const char* sql)
     return true;
 }
 
+int64_t SQLiteStore::queryInt(const char* sql)
+{
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return -1;
+    }
+    int64_t value = -1;
+    if (sqlite3_step(stmt) == SQLITE_ROW && sqlite3_column_type(stmt, 0) != SQLITE_NULL) {
+        value = sqlite3_column_int64(stmt, 0);
+    }
+    sqlite3_finalize(stmt);
+    return value;
+}
+
 // ── Items CRUD ──────────────────────────────────────────────
 
 std::optional<int64_t> SQLiteStore::upsertItem(
@@ -272,6 +393,9 @@ bool SQLiteStore::deleteItemByPath(const QString& path)
     sqlite3_bind_text(stmt, 1, pathUtf8.constData(), -1, SQLITE_STATIC);
     int rc = stepWithRetry(stmt);
     sqlite3_finalize(stmt);
+    if (rc == SQLITE_DONE && sqlite3_changes64(m_db) > 0) {
+        countPendingCompactionDeletes(m_db, 1);
+    }
     return rc == SQLITE_DONE;
 }
 
@@ -293,7 +417,8 @@ bool SQLiteStore::updateContentHash(int64_t itemId, const QString& contentHash)
 std::optional<SQLiteStore::ItemRow> SQLiteStore::getItemByPath(const QString& path)
 {
     const char* sql = R"(
-        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned
+        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned,
+               created_at
         FROM items WHERE path = ?1
     )";
     sqlite3_stmt* stmt = nullptr;
@@ -316,6 +441,7 @@ std::optional<SQLiteStore::ItemRow> SQLiteStore::getItemByPath(const QString& pa
         const char* hash = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
         row.contentHash = hash ? QString::fromUtf8(hash) : QString();
         row.isPinned = sqlite3_column_int(stmt, 8) != 0;
+        row.createdAt = sqlite3_column_double(stmt, 9);
         result = row;
     }
     sqlite3_finalize(stmt);
@@ -325,7 +451,8 @@ std::optional<SQLiteStore::ItemRow> SQLiteStore::getItemByPath(const QString& pa
 std::optional<SQLiteStore::ItemRow> SQLiteStore::getItemById(int64_t id)
 {
     const char* sql = R"(
-        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned
+        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned,
+               created_at
         FROM items WHERE id = ?1
     )";
     sqlite3_stmt* stmt = nullptr;
@@ -347,12 +474,149 @@ std::optional<SQLiteStore::ItemRow> SQLiteStore::getItemById(int64_t id)
         const char* hash = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
         row.contentHash = hash ? QString::fromUtf8(hash) : QString();
         row.isPinned = sqlite3_column_int(stmt, 8) != 0;
+        row.createdAt = sqlite3_column_double(stmt, 9);
         result = row;
     }
     sqlite3_finalize(stmt);
     return result;
 }
 
+std::vector<SQLiteStore::ItemRow> SQLiteStore::getItemsUnderPath(const QString& root)
+{
+    const char* sql = R"(
+        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned,
+               created_at
+        FROM items WHERE path = ?1 OR (path >= ?2 AND path < ?3)
+        ORDER BY path
+    )";
+    std::vector<ItemRow> rows;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Subtree lookup prepare: %s", sqlite3_errmsg(m_db));
+        return rows;
+    }
+    const SubtreeBounds bounds = subtreeBounds(root);
+    bindSubtreeBounds(stmt, bounds);
+
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ItemRow row;
+        row.id = sqlite3_column_int64(stmt, 0);
+        row.path = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        row.name = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2)));
+        row.kind = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 3)));
+        row.size = sqlite3_column_int64(stmt, 4);
+        row.modifiedAt = sqlite3_column_double(stmt, 5);
+        row.indexedAt = sqlite3_column_double(stmt, 6);
+        const char* hash = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
+        row.contentHash = hash ? QString::fromUtf8(hash) : QString();
+        row.isPinned = sqlite3_column_int(stmt, 8) != 0;
+        row.createdAt = sqlite3_column_double(stmt, 9);
+        rows.push_back(std::move(row));
+    }
+    sqlite3_finalize(stmt);
+    return rows;
+}
+
+std::vector<SQLiteStore::ItemRow> SQLiteStore::getItemsInIdRange(int64_t firstId, int64_t lastId)
+{
+    const char* sql = R"(
+        SELECT id, path, name, kind, size, modified_at, indexed_at, content_hash, is_pinned,
+               created_at
+        FROM items WHERE id BETWEEN ?1 AND ?2
+        ORDER BY id
+    )";
+    std::vector<ItemRow> rows;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Id range lookup prepare: %s", sqlite3_errmsg(m_db));
+        return rows;
+    }
+    sqlite3_bind_int64(stmt, 1, firstId);
+    sqlite3_bind_int64(stmt, 2, lastId);
+
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ItemRow row;
+        row.id = sqlite3_column_int64(stmt, 0);
+        row.path = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        row.name = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2)));
+        row.kind = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 3)));
+        row.size = sqlite3_column_int64(stmt, 4);
+        row.modifiedAt = sqlite3_column_double(stmt, 5);
+        row.indexedAt = sqlite3_column_double(stmt, 6);
+        const char* hash = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
+        row.contentHash = hash ? QString::fromUtf8(hash) : QString();
+        row.isPinned = sqlite3_column_int(stmt, 8) != 0;
+        row.createdAt = sqlite3_column_double(stmt, 9);
+        rows.push_back(std::move(row));
+    }
+    sqlite3_finalize(stmt);
+    return rows;
+}
+
+std::vector<int64_t> SQLiteStore::getItemIdsIndexedSince(double since)
+{
+    std::vector<int64_t> ids;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "SELECT id FROM items WHERE indexed_at >= ?1 ORDER BY id", -1,
+                           &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Indexed-since lookup prepare: %s", sqlite3_errmsg(m_db));
+        return ids;
+    }
+    sqlite3_bind_double(stmt, 1, since);
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ids.push_back(sqlite3_column_int64(stmt, 0));
+    }
+    sqlite3_finalize(stmt);
+    return ids;
+}
+
+SQLiteStore::PathFootprint SQLiteStore::footprintUnderPath(const QString& root)
+{
+    const SubtreeBounds bounds = subtreeBounds(root);
+    const auto count = [this, &bounds](const char* sql) -> int64_t {
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+            LOG_ERROR(bsIndex, "Footprint prepare: %s", sqlite3_errmsg(m_db));
+            return -1;
+        }
+        bindSubtreeBounds(stmt, bounds);
+        int64_t rows = -1;
+        if (sqlite3_step(stmt) == SQLITE_ROW) {
+            rows = sqlite3_column_int64(stmt, 0);
+        }
+        sqlite3_finalize(stmt);
+        return rows;
+    };
+
+    // -1 (lookup failed) keeps empty() false: a purge is unverified then.
+    PathFootprint footprint;
+    footprint.items = count(
+        "SELECT COUNT(*) FROM items WHERE path = ?1 OR (path >= ?2 AND path < ?3)");
+    footprint.ftsRows = count(
+        "SELECT COUNT(*) FROM search_index "
+        "WHERE file_path = ?1 OR (file_path >= ?2 AND file_path < ?3)");
+    footprint.behaviorEvents = count(
+        "SELECT COUNT(*) FROM behavior_events_v1 "
+        "WHERE item_path = ?1 OR (item_path >= ?2 AND item_path < ?3)");
+    return footprint;
+}
+
+int64_t SQLiteStore::deleteBehaviorEventsUnderPath(const QString& root)
+{
+    const char* sql = "DELETE FROM behavior_events_v1 "
+                      "WHERE item_path = ?1 OR (item_path >= ?2 AND item_path < ?3)";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Behavior event purge prepare: %s", sqlite3_errmsg(m_db));
+        return 0;
+    }
+    const SubtreeBounds bounds = subtreeBounds(root);
+    bindSubtreeBounds(stmt, bounds);
+    const int rc = stepWithRetry(stmt);
+    sqlite3_finalize(stmt);
+    return rc == SQLITE_DONE ? sqlite3_changes64(m_db) : 0;
+}
+
 std::optional<SQLiteStore::ItemAvailability> SQLiteStore::getItemAvailability(int64_t id)
 {
     const char* sql = R"(
@@ -400,6 +664,43 @@ std::optional<SQLiteStore::ItemAvailability> SQLiteStore::getItemAvailability(in
     return result;
 }
 
+QString SQLiteStore::getItemContent(int64_t id, int maxChars, bool* truncated)
+{
+    if (truncated) {
+        *truncated = false;
+    }
+    const char* sql =
+        "SELECT chunk_text FROM content WHERE item_id = ?1 ORDER BY chunk_index";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return {};
+    }
+    sqlite3_bind_int64(stmt, 1, id);
+
+    QString text;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        const char* chunkText = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0));
+        if (!chunkText) {
+            continue;
+        }
+        const QString chunk = QString::fromUtf8(chunkText);
+        if (!text.isEmpty()) {
+            text += QLatin1Char('\n');
+        }
+        if (text.size() + chunk.size() > maxChars) {
+            text += chunk.left(std::max<qsizetype>(0, maxChars - text.size()));
+            if (truncated) {
+                *truncated = true;
+            }
+            break;
+        }
+        text += chunk;
+    }
+
+    sqlite3_finalize(stmt);
+    return text;
+}
+
 // ── Chunks + FTS5 (atomic — THE critical path) ─────────────
 
 bool SQLiteStore::insertChunks(
@@ -498,6 +799,14 @@ bool SQLiteStore::insertChunks(
         }
     }
 
+    // Re-append the attributes row so all of an item's FTS5 rows stay adjacent.
+    if (!indexItemAttributes(itemId, fileName, filePath)) {
+        LOG_ERROR(bsIndex, "FTS5 attributes insert failed: %s", sqlite3_errmsg(m_db));
+        execSql("ROLLBACK TO SAVEPOINT insert_chunks");
+        execSql("RELEASE SAVEPOINT insert_chunks");
+        return false;
+    }
+
     return execSql("RELEASE SAVEPOINT insert_chunks");
 }
 
@@ -525,109 +834,416 @@ bool SQLiteStore::deleteChunksForItem(int64_t itemId, const QString& filePath)
     return true;
 }
 
-// ── FTS5 Search ─────────────────────────────────────────────
+// ── Item attributes ─────────────────────────────────────────
 
-QString SQLiteStore::sanitizeFtsQueryStrict(const QString& raw)
+bool SQLiteStore::replaceItemAttributes(int64_t itemId,
+                                        const QString& fileName,
+                                        const QString& filePath,
+                                        const std::vector<ItemAttribute>& attributes)
 {
-    const QString trimmedRaw = raw.trimmed();
-    if (trimmedRaw.isEmpty()) {
-        return {};
+    if (getItemAttributes(itemId) == attributes) {
+        return true;
     }
 
-    int quoteCount = 0;
-    for (const QChar ch : trimmedRaw) {
-        if (ch == QLatin1Char('"')) {
-            ++quoteCount;
-        }
-    }
-    const bool stripQuotes = (quoteCount % 2) != 0;
+    if (!execSql("SAVEPOINT replace_attributes")) return false;
+    const auto fail = [this](const char* what) {
+        LOG_ERROR(bsIndex, "replaceItemAttributes %s: %s", what, sqlite3_errmsg(m_db));
+        execSql("ROLLBACK TO SAVEPOINT replace_attributes");
+        execSql("RELEASE SAVEPOINT replace_attributes");
+        return false;
+    };
 
-    QString stripped;
-    stripped.reserve(trimmedRaw.size());
-    for (const QChar ch : trimmedRaw) {
-        if (ch == QLatin1Char('*') || ch == QLatin1Char('^') ||
-            ch == QLatin1Char(':') || ch == QLatin1Char('(') ||
-            ch == QLatin1Char(')')) {
-            continue;
-        }
-        if (stripQuotes && ch == QLatin1Char('"')) {
-            continue;
-        }
-        stripped.append(ch);
+    {
+        const char* sql = "DELETE FROM item_attributes WHERE item_id = ?1";
+        sqlite3_stmt* stmt = nullptr;
+        sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr);
+        sqlite3_bind_int64(stmt, 1, itemId);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("delete");
     }
 
-    QString normalized;
-    normalized.reserve(stripped.size());
-    bool inQuote = false;
-    int i = 0;
-    while (i < stripped.size()) {
-        const QChar ch = stripped.at(i);
-        if (ch == QLatin1Char('"')) {
-            inQuote = !inQuote;
-            normalized.append(ch);
-            ++i;
-            continue;
-        }
-
-        if (!inQuote && (ch.isLetterOrNumber() || ch == QLatin1Char('_'))) {
-            const int start = i;
-            while (i < stripped.size()) {
-                const QChar wordCh = stripped.at(i);
-                if (!wordCh.isLetterOrNumber() && wordCh != QLatin1Char('_')) {
-                    break;
-                }
-                ++i;
-            }
-
-            const QString token = stripped.mid(start, i - start);
-            if (token == QStringLiteral("OR")) {
-                normalized.append(QStringLiteral("or"));
-            } else if (token == QStringLiteral("NOT")) {
-                normalized.append(QStringLiteral("not"));
-            } else if (token == QStringLiteral("AND")) {
-                normalized.append(QStringLiteral("and"));
-            } else if (token == QStringLiteral("NEAR")) {
-                normalized.append(QStringLiteral("near"));
-            } else {
-                normalized.append(token);
-            }
-            continue;
+    const char* insertSql = R"(
+        INSERT OR IGNORE INTO item_attributes (item_id, name, value, searchable)
+        VALUES (?1, ?2, ?3, ?4)
+    )";
+    for (const ItemAttribute& attribute : attributes) {
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, insertSql, -1, &stmt, nullptr) != SQLITE_OK) {
+            return fail("insert prepare");
         }
-
-        normalized.append(ch);
-        ++i;
+        const QByteArray nameUtf8 = attribute.name.toUtf8();
+        const QByteArray valueUtf8 = attribute.value.toUtf8();
+        sqlite3_bind_int64(stmt, 1, itemId);
+        sqlite3_bind_text(stmt, 2, nameUtf8.constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_text(stmt, 3, valueUtf8.constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_int(stmt, 4, attribute.searchable ? 1 : 0);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("insert");
     }
 
-    QString compact;
-    compact.reserve(normalized.size());
-    bool lastWasSpace = false;
-    for (const QChar ch : normalized) {
-        if (ch.isSpace()) {
-            if (!lastWasSpace) {
-                compact.append(QLatin1Char(' '));
-                lastWasSpace = true;
-            }
-            continue;
+    // Rewrite the item's FTS5 rows from its stored chunks plus the new
+    // attributes row, keeping them adjacent in rowid order (exportMatches
+    // folds runs of one file_id into one item).
+    const QByteArray fileNameUtf8 = fileName.toUtf8();
+    const QByteArray filePathUtf8 = filePath.toUtf8();
+    {
+        const char* sql = "DELETE FROM search_index WHERE file_id = ?1";
+        sqlite3_stmt* stmt = nullptr;
+        sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr);
+        sqlite3_bind_int64(stmt, 1, itemId);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("FTS5 delete");
+    }
+    {
+        const char* sql = R"(
+            INSERT INTO search_index (file_name, file_path, content, chunk_id, file_id)
+            SELECT ?1, ?2, chunk_text, chunk_hash, item_id
+            FROM content WHERE item_id = ?3 ORDER BY chunk_index
+        )";
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+            return fail("FTS5 chunk prepare");
         }
-        compact.append(ch);
-        lastWasSpace = false;
+        sqlite3_bind_text(stmt, 1, fileNameUtf8.constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_text(stmt, 2, filePathUtf8.constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_int64(stmt, 3, itemId);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("FTS5 chunk insert");
     }
+    if (!indexItemAttributes(itemId, fileName, filePath)) return fail("FTS5 insert");
 
-    return compact.trimmed();
+    return execSql("RELEASE SAVEPOINT replace_attributes");
 }
 
-std::vector<SQLiteStore::FtsHit> SQLiteStore::searchFts5(
-    const QString& query, int limit, bool relaxed)
+bool SQLiteStore::indexItemAttributes(int64_t itemId, const QString& fileName,
+                                      const QString& filePath)
 {
-    const QString sanitized = relaxed ? sanitizeFtsQueryRelaxed(query)
-                                      : sanitizeFtsQueryStrict(query);
-    if (sanitized.isEmpty()) {
-        LOG_DEBUG(bsIndex, "FTS5 search skipped after sanitization");
-        return {};
-    }
-    if (sanitized != query) {
-        LOG_DEBUG(bsIndex, "FTS5 query (%s) sanitized from '%s' to '%s'",
-                  relaxed ? "relaxed" : "strict",
+    QStringList searchable;
+    {
+        const char* sql = "SELECT value FROM item_attributes"
+                          " WHERE item_id = ?1 AND searchable = 1 ORDER BY id";
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+            return false;
+        }
+        sqlite3_bind_int64(stmt, 1, itemId);
+        while (sqlite3_step(stmt) == SQLITE_ROW) {
+            searchable.append(QString::fromUtf8(
+                reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0))));
+        }
+        sqlite3_finalize(stmt);
+    }
+    if (searchable.isEmpty()) {
+        return true;
+    }
+
+    const char* ftsSql = R"(
+        INSERT INTO search_index (file_name, file_path, content, chunk_id, file_id)
+        VALUES (?1, ?2, ?3, ?4, ?5)
+    )";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, ftsSql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return false;
+    }
+    const QByteArray fileNameUtf8 = fileName.toUtf8();
+    const QByteArray filePathUtf8 = filePath.toUtf8();
+    const QByteArray contentUtf8 = searchable.join(QLatin1Char('\n')).toUtf8();
+    sqlite3_bind_text(stmt, 1, fileNameUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 2, filePathUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 3, contentUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_text(stmt, 4, kAttributesChunkId, -1, SQLITE_STATIC);
+    sqlite3_bind_int64(stmt, 5, itemId);
+    const int rc = stepWithRetry(stmt);
+    sqlite3_finalize(stmt);
+    return rc == SQLITE_DONE;
+}
+
+std::vector<ItemAttribute> SQLiteStore::getItemAttributes(int64_t itemId)
+{
+    std::vector<ItemAttribute> attributes;
+    const char* sql =
+        "SELECT name, value, searchable FROM item_attributes WHERE item_id = ?1 ORDER BY id";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return attributes;
+    }
+    sqlite3_bind_int64(stmt, 1, itemId);
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ItemAttribute attribute;
+        attribute.name = QString::fromUtf8(
+            reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        attribute.value = QString::fromUtf8(
+            reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        attribute.searchable = sqlite3_column_int(stmt, 2) != 0;
+        attributes.push_back(attribute);
+    }
+    sqlite3_finalize(stmt);
+    return attributes;
+}
+
+QString SQLiteStore::getItemAttributeValue(int64_t itemId, const QString& name)
+{
+    QString value;
+    const char* sql = "SELECT value FROM item_attributes WHERE item_id = ?1 AND name = ?2"
+                      " ORDER BY id LIMIT 1";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return value;
+    }
+    const QByteArray nameUtf8 = name.toUtf8();
+    sqlite3_bind_int64(stmt, 1, itemId);
+    sqlite3_bind_text(stmt, 2, nameUtf8.constData(), -1, SQLITE_STATIC);
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        value = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+    }
+    sqlite3_finalize(stmt);
+    return value;
+}
+
+// ── Tags ────────────────────────────────────────────────────
+
+bool SQLiteStore::replaceItemTags(int64_t itemId, const QString& source,
+                                  const QStringList& tags)
+{
+    if (!execSql("SAVEPOINT replace_tags")) return false;
+    const auto fail = [this](const char* what) {
+        LOG_ERROR(bsIndex, "replaceItemTags %s: %s", what, sqlite3_errmsg(m_db));
+        execSql("ROLLBACK TO SAVEPOINT replace_tags");
+        execSql("RELEASE SAVEPOINT replace_tags");
+        return false;
+    };
+
+    const QByteArray sourceUtf8 = source.toUtf8();
+    {
+        const char* sql = "DELETE FROM tags WHERE item_id = ?1 AND source = ?2";
+        sqlite3_stmt* stmt = nullptr;
+        sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr);
+        sqlite3_bind_int64(stmt, 1, itemId);
+        sqlite3_bind_text(stmt, 2, sourceUtf8.constData(), -1, SQLITE_STATIC);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("delete");
+    }
+
+    const char* insertSql =
+        "INSERT OR IGNORE INTO tags (item_id, tag, source) VALUES (?1, ?2, ?3)";
+    for (const QString& tag : tags) {
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, insertSql, -1, &stmt, nullptr) != SQLITE_OK) {
+            return fail("insert prepare");
+        }
+        const QByteArray tagUtf8 = tag.toUtf8();
+        sqlite3_bind_int64(stmt, 1, itemId);
+        sqlite3_bind_text(stmt, 2, tagUtf8.constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_text(stmt, 3, sourceUtf8.constData(), -1, SQLITE_STATIC);
+        const int rc = stepWithRetry(stmt);
+        sqlite3_finalize(stmt);
+        if (rc != SQLITE_DONE) return fail("insert");
+    }
+
+    return execSql("RELEASE SAVEPOINT replace_tags");
+}
+
+QStringList SQLiteStore::getItemTags(int64_t itemId)
+{
+    QStringList tags;
+    const char* sql = "SELECT tag FROM tags WHERE item_id = ?1 ORDER BY id";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return tags;
+    }
+    sqlite3_bind_int64(stmt, 1, itemId);
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        tags.append(QString::fromUtf8(
+            reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0))));
+    }
+    sqlite3_finalize(stmt);
+    return tags;
+}
+
+std::vector<SQLiteStore::TagCount> SQLiteStore::countTags(const std::vector<int64_t>& itemIds)
+{
+    std::vector<TagCount> counts;
+    if (itemIds.empty()) {
+        return counts;
+    }
+
+    QStringList placeholders;
+    placeholders.reserve(static_cast<int>(itemIds.size()));
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        placeholders.append(QStringLiteral("?%1").arg(static_cast<int>(i) + 1));
+    }
+    const QString sql = QStringLiteral(
+        "SELECT MIN(tag), COUNT(DISTINCT item_id) AS n FROM tags"
+        " WHERE item_id IN (%1)"
+        " GROUP BY tag COLLATE NOCASE ORDER BY n DESC, MIN(tag)")
+        .arg(placeholders.join(QStringLiteral(", ")));
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql.toUtf8().constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Tag count prepare: %s", sqlite3_errmsg(m_db));
+        return counts;
+    }
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        sqlite3_bind_int64(stmt, static_cast<int>(i + 1), itemIds[i]);
+    }
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        TagCount count;
+        count.tag = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        count.count = sqlite3_column_int(stmt, 1);
+        counts.push_back(std::move(count));
+    }
+    sqlite3_finalize(stmt);
+    return counts;
+}
+
+std::vector<SQLiteStore::AttributeCount> SQLiteStore::countAttributeValues(
+    const std::vector<int64_t>& itemIds, const QString& name)
+{
+    std::vector<AttributeCount> counts;
+    if (itemIds.empty()) {
+        return counts;
+    }
+
+    QStringList placeholders;
+    placeholders.reserve(static_cast<int>(itemIds.size()));
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        placeholders.append(QStringLiteral("?%1").arg(static_cast<int>(i) + 2));
+    }
+    const QString sql = QStringLiteral(
+        "SELECT value, COUNT(DISTINCT item_id) AS n FROM item_attributes"
+        " WHERE name = ?1 AND item_id IN (%1)"
+        " GROUP BY value ORDER BY n DESC, value")
+        .arg(placeholders.join(QStringLiteral(", ")));
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql.toUtf8().constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Attribute count prepare: %s", sqlite3_errmsg(m_db));
+        return counts;
+    }
+    const QByteArray nameUtf8 = name.toUtf8();
+    sqlite3_bind_text(stmt, 1, nameUtf8.constData(), -1, SQLITE_TRANSIENT);
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        sqlite3_bind_int64(stmt, static_cast<int>(i + 2), itemIds[i]);
+    }
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        AttributeCount count;
+        count.value =
+            QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        count.count = sqlite3_column_int(stmt, 1);
+        counts.push_back(std::move(count));
+    }
+    sqlite3_finalize(stmt);
+    return counts;
+}
+
+// ── FTS5 Search ─────────────────────────────────────────────
+
+QString SQLiteStore::sanitizeFtsQueryStrict(const QString& raw)
+{
+    const QString trimmedRaw = raw.trimmed();
+    if (trimmedRaw.isEmpty()) {
+        return {};
+    }
+
+    int quoteCount = 0;
+    for (const QChar ch : trimmedRaw) {
+        if (ch == QLatin1Char('"')) {
+            ++quoteCount;
+        }
+    }
+    const bool stripQuotes = (quoteCount % 2) != 0;
+
+    QString stripped;
+    stripped.reserve(trimmedRaw.size());
+    for (const QChar ch : trimmedRaw) {
+        if (ch == QLatin1Char('*') || ch == QLatin1Char('^') ||
+            ch == QLatin1Char(':') || ch == QLatin1Char('(') ||
+            ch == QLatin1Char(')')) {
+            continue;
+        }
+        if (stripQuotes && ch == QLatin1Char('"')) {
+            continue;
+        }
+        stripped.append(ch);
+    }
+
+    QString normalized;
+    normalized.reserve(stripped.size());
+    bool inQuote = false;
+    int i = 0;
+    while (i < stripped.size()) {
+        const QChar ch = stripped.at(i);
+        if (ch == QLatin1Char('"')) {
+            inQuote = !inQuote;
+            normalized.append(ch);
+            ++i;
+            continue;
+        }
+
+        if (!inQuote && (ch.isLetterOrNumber() || ch == QLatin1Char('_'))) {
+            const int start = i;
+            while (i < stripped.size()) {
+                const QChar wordCh = stripped.at(i);
+                if (!wordCh.isLetterOrNumber() && wordCh != QLatin1Char('_')) {
+                    break;
+                }
+                ++i;
+            }
+
+            const QString token = stripped.mid(start, i - start);
+            if (token == QStringLiteral("OR")) {
+                normalized.append(QStringLiteral("or"));
+            } else if (token == QStringLiteral("NOT")) {
+                normalized.append(QStringLiteral("not"));
+            } else if (token == QStringLiteral("AND")) {
+                normalized.append(QStringLiteral("and"));
+            } else if (token == QStringLiteral("NEAR")) {
+                normalized.append(QStringLiteral("near"));
+            } else {
+                normalized.append(token);
+            }
+            continue;
+        }
+
+        normalized.append(ch);
+        ++i;
+    }
+
+    QString compact;
+    compact.reserve(normalized.size());
+    bool lastWasSpace = false;
+    for (const QChar ch : normalized) {
+        if (ch.isSpace()) {
+            if (!lastWasSpace) {
+                compact.append(QLatin1Char(' '));
+                lastWasSpace = true;
+            }
+            continue;
+        }
+        compact.append(ch);
+        lastWasSpace = false;
+    }
+
+    return compact.trimmed();
+}
+
+std::vector<SQLiteStore::FtsHit> SQLiteStore::searchFts5(
+    const QString& query, int limit, bool relaxed)
+{
+    const QString sanitized = relaxed ? sanitizeFtsQueryRelaxed(query)
+                                      : sanitizeFtsQueryStrict(query);
+    if (sanitized.isEmpty()) {
+        LOG_DEBUG(bsIndex, "FTS5 search skipped after sanitization");
+        return {};
+    }
+    if (sanitized != query) {
+        LOG_DEBUG(bsIndex, "FTS5 query (%s) sanitized from '%s' to '%s'",
+                  relaxed ? "relaxed" : "strict",
                   query.toUtf8().constData(),
                   sanitized.toUtf8().constData());
     }
@@ -746,6 +1362,14 @@ std::vector<SQLiteStore::FtsHit> SQLiteStore::searchFts5(
             && item.modifiedAt > options.modifiedBefore.value()) {
             passes = false;
         }
+        if (passes && options.createdAfter.has_value()
+            && item.createdAt < options.createdAfter.value()) {
+            passes = false;
+        }
+        if (passes && options.createdBefore.has_value()
+            && item.createdAt > options.createdBefore.value()) {
+            passes = false;
+        }
         if (passes && options.minSizeBytes.has_value()
             && item.size < options.minSizeBytes.value()) {
             passes = false;
@@ -754,6 +1378,22 @@ std::vector<SQLiteStore::FtsHit> SQLiteStore::searchFts5(
             && item.size > options.maxSizeBytes.value()) {
             passes = false;
         }
+        if (passes && !options.tags.empty()) {
+            const QStringList itemTags = getItemTags(item.id);
+            for (const QString& tag : options.tags) {
+                if (!itemTags.contains(tag, Qt::CaseInsensitive)) {
+                    passes = false;
+                    break;
+                }
+            }
+        }
+        if (passes && (options.dueAfter.has_value() || options.dueBefore.has_value())) {
+            bool hasDue = false;
+            const double due = getItemAttributeValue(
+                item.id, QString::fromLatin1(SearchOptions::kDueDateAttribute)).toDouble(&hasDue);
+            passes = hasDue && due >= options.dueAfter.value_or(due)
+                     && due <= options.dueBefore.value_or(due);
+        }
 
         if (passes) {
             filtered.push_back(hit);
@@ -906,6 +1546,14 @@ std::vector<SQLiteStore::NameHit> SQLiteStore::searchByNameFuzzy(
             && item.modifiedAt > options.modifiedBefore.value()) {
             passes = false;
         }
+        if (passes && options.createdAfter.has_value()
+            && item.createdAt < options.createdAfter.value()) {
+            passes = false;
+        }
+        if (passes && options.createdBefore.has_value()
+            && item.createdAt > options.createdBefore.value()) {
+            passes = false;
+        }
         if (passes && options.minSizeBytes.has_value()
             && item.size < options.minSizeBytes.value()) {
             passes = false;
@@ -914,6 +1562,22 @@ std::vector<SQLiteStore::NameHit> SQLiteStore::searchByNameFuzzy(
             && item.size > options.maxSizeBytes.value()) {
             passes = false;
         }
+        if (passes && !options.tags.empty()) {
+            const QStringList itemTags = getItemTags(item.id);
+            for (const QString& tag : options.tags) {
+                if (!itemTags.contains(tag, Qt::CaseInsensitive)) {
+                    passes = false;
+                    break;
+                }
+            }
+        }
+        if (passes && (options.dueAfter.has_value() || options.dueBefore.has_value())) {
+            bool hasDue = false;
+            const double due = getItemAttributeValue(
+                item.id, QString::fromLatin1(SearchOptions::kDueDateAttribute)).toDouble(&hasDue);
+            passes = hasDue && due >= options.dueAfter.value_or(due)
+                     && due <= options.dueBefore.value_or(due);
+        }
 
         if (passes) {
             filtered.push_back(hit);
@@ -926,6 +1590,163 @@ std::vector<SQLiteStore::NameHit> SQLiteStore::searchByNameFuzzy(
     return filtered;
 }
 
+std::vector<SQLiteStore::NameHit> SQLiteStore::suggestByNamePrefix(const QString& prefix,
+                                                                  int limit)
+{
+    const QString trimmed = prefix.trimmed().toLower();
+    if (trimmed.isEmpty()) {
+        return {};
+    }
+
+    const QString escaped = escapeLikeLiteral(trimmed) + QLatin1Char('%');
+
+    const char* sql = R"(
+        SELECT i.id, i.name, i.path
+        FROM items i
+        LEFT JOIN frequencies f ON f.item_id = i.id
+        WHERE LOWER(i.name) LIKE ?1 ESCAPE '\'
+        ORDER BY COALESCE(f.open_count, 0) DESC, LENGTH(i.name) ASC, i.modified_at DESC
+        LIMIT ?2
+    )";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Name prefix suggest prepare: %s", sqlite3_errmsg(m_db));
+        return {};
+    }
+
+    const QByteArray patternUtf8 = escaped.toUtf8();
+    sqlite3_bind_text(stmt, 1, patternUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_int(stmt, 2, std::max(1, limit));
+
+    std::vector<NameHit> hits;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        NameHit hit;
+        hit.fileId = sqlite3_column_int64(stmt, 0);
+        const char* name = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1));
+        hit.name = name ? QString::fromUtf8(name) : QString();
+        const char* path = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2));
+        hit.path = path ? QString::fromUtf8(path) : QString();
+        hits.push_back(std::move(hit));
+    }
+    sqlite3_finalize(stmt);
+    return hits;
+}
+
+SQLiteStore::PathCompletionPage SQLiteStore::completePaths(const QString& prefix, int limit,
+                                                           const PathCompletionCursor& cursor)
+{
+    // Opened items are ranked ahead of the path-ordered walk, but only this
+    // many: beyond it, frecency stops being a useful signal for completion.
+    constexpr int kFrequentHead = 500;
+
+    PathCompletionPage page;
+    limit = std::max(1, limit);
+
+    // ?1/?2 carry the match, ?3 the keyset position, ?4 limits.
+    QByteArray lower;
+    QByteArray upper;
+    const char* filter = "1";
+    if (prefix.startsWith(QLatin1Char('/'))) {
+        // Byte range on the UNIQUE path index: [prefix, prefix + U+10FFFF).
+        lower = prefix.toUtf8();
+        upper = lower + QByteArrayLiteral("\xF4\x8F\xBF\xBF");
+        filter = "i.path >= ?1 AND i.path < ?2";
+    } else if (prefix.contains(QLatin1Char('/'))) {
+        lower = (QStringLiteral("%/") + escapeLikeLiteral(prefix.toLower())
+                 + QLatin1Char('%')).toUtf8();
+        filter = "LOWER(i.path) LIKE ?1 ESCAPE '\\'";
+    } else if (!prefix.isEmpty()) {
+        lower = (escapeLikeLiteral(prefix.toLower()) + QLatin1Char('%')).toUtf8();
+        filter = "LOWER(i.name) LIKE ?1 ESCAPE '\\'";
+    }
+
+    const auto bindMatch = [&](sqlite3_stmt* stmt) {
+        if (!lower.isEmpty()) {
+            sqlite3_bind_text(stmt, 1, lower.constData(), lower.size(), SQLITE_STATIC);
+        }
+        if (!upper.isEmpty()) {
+            sqlite3_bind_text(stmt, 2, upper.constData(), upper.size(), SQLITE_STATIC);
+        }
+    };
+
+    const auto readRows = [&](sqlite3_stmt* stmt) {
+        while (sqlite3_step(stmt) == SQLITE_ROW) {
+            PathCandidate candidate;
+            candidate.fileId = sqlite3_column_int64(stmt, 0);
+            const char* path = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1));
+            candidate.path = path ? QString::fromUtf8(path) : QString();
+            const char* kind = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2));
+            candidate.kind = kind ? QString::fromUtf8(kind) : QString();
+            candidate.openCount = sqlite3_column_int(stmt, 3);
+            page.candidates.push_back(std::move(candidate));
+        }
+        sqlite3_finalize(stmt);
+    };
+
+    const QByteArray frequentSql = QByteArray(R"(
+        SELECT i.id, i.path, i.kind, f.open_count
+        FROM frequencies f
+        JOIN items i ON i.id = f.item_id
+        WHERE f.open_count > 0 AND )") + filter + QByteArray(R"(
+        ORDER BY f.open_count DESC, f.last_opened_at DESC, i.id ASC
+        LIMIT ?4
+    )");
+
+    int frequentTaken = cursor.frequentTaken;
+    if (frequentTaken < 0) {
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, frequentSql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+            LOG_ERROR(bsIndex, "Path completion prepare: %s", sqlite3_errmsg(m_db));
+            return page;
+        }
+        bindMatch(stmt);
+        sqlite3_bind_int(stmt, 4, std::min(limit, kFrequentHead));
+        readRows(stmt);
+        frequentTaken = static_cast<int>(page.candidates.size());
+        if (frequentTaken == limit) {
+            // Page full; the path-ordered walk starts on the next one.
+            page.next = PathCompletionCursor{frequentTaken, QString()};
+            return page;
+        }
+    }
+
+    // The rest in path order, skipping what the head already returned.
+    // Same ORDER BY/LIMIT as the head, so the NOT IN set is identical.
+    const QByteArray restSql = QByteArray(R"(
+        SELECT i.id, i.path, i.kind, COALESCE(f.open_count, 0)
+        FROM items i
+        LEFT JOIN frequencies f ON f.item_id = i.id
+        WHERE )") + filter + QByteArray(R"( AND i.path > ?3
+          AND i.id NOT IN (
+              SELECT i.id
+              FROM frequencies f
+              JOIN items i ON i.id = f.item_id
+              WHERE f.open_count > 0 AND )") + filter + QByteArray(R"(
+              ORDER BY f.open_count DESC, f.last_opened_at DESC, i.id ASC
+              LIMIT ?5)
+        ORDER BY i.path ASC
+        LIMIT ?4
+    )");
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, restSql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Path completion prepare: %s", sqlite3_errmsg(m_db));
+        return page;
+    }
+    const int remaining = limit - static_cast<int>(page.candidates.size());
+    const QByteArray afterUtf8 = cursor.afterPath.toUtf8();
+    bindMatch(stmt);
+    sqlite3_bind_text(stmt, 3, afterUtf8.constData(), afterUtf8.size(), SQLITE_STATIC);
+    sqlite3_bind_int(stmt, 4, remaining);
+    sqlite3_bind_int(stmt, 5, frequentTaken);
+    const size_t before = page.candidates.size();
+    readRows(stmt);
+
+    if (static_cast<int>(page.candidates.size() - before) == remaining) {
+        page.next = PathCompletionCursor{frequentTaken, page.candidates.back().path};
+    }
+    return page;
+}
+
 QString SQLiteStore::sanitizeFtsQueryRelaxed(const QString& raw)
 {
     const QString normalized = raw.toLower().trimmed();
@@ -1036,7 +1857,7 @@ bool SQLiteStore::clearFailures(int64_t itemId)
 // ── Feedback ────────────────────────────────────────────────
 
 bool SQLiteStore::recordFeedback(int64_t itemId, const QString& action,
-                                  const QString& query, int position)
+                                  const QString& query, int position, double timestamp)
 {
     const char* sql = R"(
         INSERT INTO feedback (item_id, action, query, result_position, timestamp)
@@ -1051,7 +1872,9 @@ bool SQLiteStore::recordFeedback(int64_t itemId, const QString& action,
 
     const QByteArray actionUtf8 = action.toUtf8();
     const QByteArray queryUtf8 = query.toUtf8();
-    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    const double now = timestamp > 0.0
+        ? timestamp
+        : static_cast<double>(QDateTime::currentSecsSinceEpoch());
 
     sqlite3_bind_int64(stmt, 1, itemId);
     sqlite3_bind_text(stmt, 2, actionUtf8.constData(), -1, SQLITE_STATIC);
@@ -1069,6 +1892,137 @@ bool SQLiteStore::recordFeedback(int64_t itemId, const QString& action,
     return true;
 }
 
+namespace {
+
+SQLiteStore::ActionRow readActionRow(sqlite3_stmt* stmt)
+{
+    const auto text = [stmt](int column) {
+        const unsigned char* value = sqlite3_column_text(stmt, column);
+        return value ? QString::fromUtf8(reinterpret_cast<const char*>(value)) : QString();
+    };
+    SQLiteStore::ActionRow row;
+    row.id = sqlite3_column_int64(stmt, 0);
+    row.itemId = sqlite3_column_int64(stmt, 1);
+    row.path = text(2);
+    row.name = text(3);
+    row.action = text(4);
+    row.query = text(5);
+    row.position = sqlite3_column_int(stmt, 6);
+    row.timestamp = sqlite3_column_double(stmt, 7);
+    return row;
+}
+
+constexpr const char* kActionRowColumns =
+    "SELECT f.id, f.item_id, i.path, i.name, f.action, COALESCE(f.query, ''),"
+    " COALESCE(f.result_position, 0), f.timestamp"
+    " FROM feedback f JOIN items i ON i.id = f.item_id";
+
+} // namespace
+
+std::vector<SQLiteStore::ActionRow> SQLiteStore::getRecentActions(int limit,
+                                                                  const QStringList& actions,
+                                                                  int64_t itemId)
+{
+    QString sql = QString::fromLatin1(kActionRowColumns) + QStringLiteral(" WHERE 1 = 1");
+    if (!actions.isEmpty()) {
+        QStringList placeholders;
+        for (int i = 0; i < actions.size(); ++i) {
+            placeholders.push_back(QStringLiteral("?%1").arg(i + 3));
+        }
+        sql += QStringLiteral(" AND f.action IN (") + placeholders.join(QStringLiteral(", "))
+               + QLatin1Char(')');
+    }
+    if (itemId > 0) {
+        sql += QStringLiteral(" AND f.item_id = ?2");
+    }
+    sql += QStringLiteral(" ORDER BY f.timestamp DESC, f.id DESC LIMIT ?1");
+
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sqlUtf8 = sql.toUtf8();
+    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "getRecentActions prepare failed: %s", sqlite3_errmsg(m_db));
+        return {};
+    }
+    sqlite3_bind_int(stmt, 1, std::max(limit, 1));
+    if (itemId > 0) {
+        sqlite3_bind_int64(stmt, 2, itemId);
+    }
+    std::vector<QByteArray> actionUtf8;
+    actionUtf8.reserve(static_cast<size_t>(actions.size()));
+    for (int i = 0; i < actions.size(); ++i) {
+        actionUtf8.push_back(actions.at(i).toUtf8());
+        sqlite3_bind_text(stmt, i + 3, actionUtf8.back().constData(), -1, SQLITE_STATIC);
+    }
+
+    std::vector<ActionRow> rows;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        rows.push_back(readActionRow(stmt));
+    }
+    sqlite3_finalize(stmt);
+    return rows;
+}
+
+std::optional<SQLiteStore::ActionRow> SQLiteStore::getAction(int64_t actionId)
+{
+    const QByteArray sql = QByteArray(kActionRowColumns) + " WHERE f.id = ?1";
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "getAction prepare failed: %s", sqlite3_errmsg(m_db));
+        return std::nullopt;
+    }
+    sqlite3_bind_int64(stmt, 1, actionId);
+    std::optional<ActionRow> row;
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        row = readActionRow(stmt);
+    }
+    sqlite3_finalize(stmt);
+    return row;
+}
+
+std::unordered_map<int64_t, SQLiteStore::ActionCounts> SQLiteStore::getActionCountsBatch(
+    const std::vector<int64_t>& itemIds, double sinceEpoch)
+{
+    if (itemIds.empty()) {
+        return {};
+    }
+
+    QString sql = QStringLiteral(
+        "SELECT item_id,"
+        " SUM(CASE WHEN action = 'reveal' THEN 1 ELSE 0 END),"
+        " SUM(CASE WHEN action = 'copyPath' THEN 1 ELSE 0 END),"
+        " MAX(timestamp)"
+        " FROM feedback WHERE action IN ('reveal', 'copyPath') AND timestamp >= ?1"
+        " AND item_id IN (");
+    QStringList placeholders;
+    placeholders.reserve(static_cast<int>(itemIds.size()));
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        placeholders.push_back(QStringLiteral("?%1").arg(static_cast<int>(i) + 2));
+    }
+    sql += placeholders.join(QStringLiteral(", ")) + QStringLiteral(") GROUP BY item_id");
+
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sqlUtf8 = sql.toUtf8();
+    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Batch action counts prepare: %s", sqlite3_errmsg(m_db));
+        return {};
+    }
+    sqlite3_bind_double(stmt, 1, sinceEpoch);
+    for (size_t i = 0; i < itemIds.size(); ++i) {
+        sqlite3_bind_int64(stmt, static_cast<int>(i) + 2, itemIds[i]);
+    }
+
+    std::unordered_map<int64_t, ActionCounts> result;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ActionCounts counts;
+        counts.reveals = sqlite3_column_int(stmt, 1);
+        counts.copies = sqlite3_column_int(stmt, 2);
+        counts.lastActedAt = sqlite3_column_double(stmt, 3);
+        result[sqlite3_column_int64(stmt, 0)] = counts;
+    }
+    sqlite3_finalize(stmt);
+    return result;
+}
+
 // ── Frequencies ─────────────────────────────────────────────
 
 bool SQLiteStore::incrementFrequency(int64_t itemId)
@@ -1096,6 +2050,104 @@ bool SQLiteStore::incrementFrequency(int64_t itemId)
     return rc == SQLITE_DONE;
 }
 
+bool SQLiteStore::setFrequency(int64_t itemId, int openCount, double lastOpenedAt)
+{
+    const char* sql = R"(
+        INSERT INTO frequencies (item_id, open_count, last_opened_at, total_interactions)
+        VALUES (?1, ?2, ?3, 0)
+        ON CONFLICT(item_id) DO UPDATE SET
+            open_count = excluded.open_count,
+            last_opened_at = excluded.last_opened_at
+    )";
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        return false;
+    }
+    sqlite3_bind_int64(stmt, 1, itemId);
+    sqlite3_bind_int(stmt, 2, openCount);
+    if (lastOpenedAt > 0.0) {
+        sqlite3_bind_double(stmt, 3, lastOpenedAt);
+    } else {
+        sqlite3_bind_null(stmt, 3);
+    }
+
+    int rc = stepWithRetry(stmt);
+    sqlite3_finalize(stmt);
+    return rc == SQLITE_DONE;
+}
+
+std::unordered_map<int64_t, SQLiteStore::ItemSignals> SQLiteStore::getItemSignalsInIdRange(
+    int64_t firstId, int64_t lastId)
+{
+    std::unordered_map<int64_t, ItemSignals> signalsById;
+    const auto forEachRow = [this, firstId, lastId](const char* sql, const auto& onRow) {
+        sqlite3_stmt* stmt = nullptr;
+        if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+            LOG_ERROR(bsIndex, "Item signals prepare: %s", sqlite3_errmsg(m_db));
+            return;
+        }
+        sqlite3_bind_int64(stmt, 1, firstId);
+        sqlite3_bind_int64(stmt, 2, lastId);
+        while (sqlite3_step(stmt) == SQLITE_ROW) {
+            onRow(stmt);
+        }
+        sqlite3_finalize(stmt);
+    };
+
+    forEachRow("SELECT item_id, tag, source FROM tags WHERE item_id BETWEEN ?1 AND ?2 "
+               "ORDER BY item_id, id",
+               [&signalsById](sqlite3_stmt* stmt) {
+        ItemSignals& itemSignals = signalsById[sqlite3_column_int64(stmt, 0)];
+        const QString tag =
+            QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        itemSignals.tags.append(tag);
+        const char* source = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2));
+        if (source && qstrcmp(source, kSyncTagSource) == 0) {
+            itemSignals.syncedTags.append(tag);
+        }
+    });
+    forEachRow("SELECT id FROM items WHERE id BETWEEN ?1 AND ?2 AND is_pinned = 1",
+               [&signalsById](sqlite3_stmt* stmt) {
+        signalsById[sqlite3_column_int64(stmt, 0)].pinned = true;
+    });
+    // Feedback written before timestamps were REAL holds them as text.
+    forEachRow("SELECT item_id, MAX(CASE WHEN typeof(timestamp) = 'text' "
+               "THEN CAST(strftime('%s', timestamp) AS REAL) ELSE CAST(timestamp AS REAL) END) "
+               "FROM feedback WHERE item_id BETWEEN ?1 AND ?2 AND action IN ('pin', 'unpin') "
+               "GROUP BY item_id",
+               [&signalsById](sqlite3_stmt* stmt) {
+        signalsById[sqlite3_column_int64(stmt, 0)].pinChangedAt = sqlite3_column_double(stmt, 1);
+    });
+    forEachRow("SELECT item_id, open_count, last_opened_at FROM frequencies "
+               "WHERE item_id BETWEEN ?1 AND ?2 "
+               "AND (open_count > 0 OR last_opened_at IS NOT NULL)",
+               [&signalsById](sqlite3_stmt* stmt) {
+        ItemSignals& itemSignals = signalsById[sqlite3_column_int64(stmt, 0)];
+        itemSignals.openCount = sqlite3_column_int(stmt, 1);
+        itemSignals.lastOpenedAt = sqlite3_column_double(stmt, 2);
+    });
+    return signalsById;
+}
+
+bool SQLiteStore::setItemPinned(int64_t itemId, bool pinned, double changedAt)
+{
+    if (!recordFeedback(itemId, pinned ? QStringLiteral("pin") : QStringLiteral("unpin"),
+                        QString(), 0, changedAt)) {
+        return false;
+    }
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "UPDATE items SET is_pinned = ?1 WHERE id = ?2", -1, &stmt,
+                           nullptr) != SQLITE_OK) {
+        return false;
+    }
+    sqlite3_bind_int(stmt, 1, pinned ? 1 : 0);
+    sqlite3_bind_int64(stmt, 2, itemId);
+    const int rc = stepWithRetry(stmt);
+    sqlite3_finalize(stmt);
+    return rc == SQLITE_DONE;
+}
+
 std::optional<SQLiteStore::FrequencyRow> SQLiteStore::getFrequency(int64_t itemId)
 {
     const char* sql = R"(
@@ -1124,59 +2176,63 @@ std::optional<SQLiteStore::FrequencyRow> SQLiteStore::getFrequency(int64_t itemI
 
 // ── Joined FTS5 Search ──────────────────────────────────────
 
-std::vector<SQLiteStore::FtsJoinedHit> SQLiteStore::searchFts5Joined(
-    const QString& query, int limit, bool relaxed, const SearchOptions& options)
+// SearchOptions as SQL on the items table (alias `i`). Placeholders are
+// numbered from *bindIndex; bindItemFilters() binds them in the same order.
+static std::vector<QString> normalizedFileTypes(const SearchOptions& options)
 {
-    const QString sanitized = relaxed ? sanitizeFtsQueryRelaxed(query)
-                                      : sanitizeFtsQueryStrict(query);
-    if (sanitized.isEmpty()) {
-        LOG_DEBUG(bsIndex, "FTS5 joined search skipped after sanitization");
-        return {};
+    std::vector<QString> fileTypes;
+    for (const QString& fileType : options.fileTypes) {
+        QString normalized = fileType.trimmed().toLower();
+        if (normalized.startsWith(QLatin1Char('.'))) {
+            normalized = normalized.mid(1);
+        }
+        if (!normalized.isEmpty()) {
+            fileTypes.push_back(normalized);
+        }
     }
+    return fileTypes;
+}
 
-    // Build dynamic SQL with JOIN and optional filters
-    QString sql = QStringLiteral(
-        "SELECT si.file_id, si.chunk_id, si.rank,"
-        " snippet(search_index, 2, '<b>', '</b>', '...', 32),"
-        " i.path, i.name, i.kind, i.size, i.modified_at,"
-        " i.parent_path, i.is_pinned, i.content_hash"
-        " FROM search_index si"
-        " JOIN items i ON i.id = si.file_id"
-        " WHERE search_index MATCH ?1");
-
-    int bindIndex = 2;
-    std::vector<QByteArray> boundStrings;
-
+static void appendItemFilterSql(QString& sql, const SearchOptions& options,
+                                const std::vector<QString>& fileTypes, int* bindIndex)
+{
     if (options.modifiedAfter.has_value()) {
-        sql += QStringLiteral(" AND i.modified_at >= ?%1").arg(bindIndex++);
+        sql += QStringLiteral(" AND i.modified_at >= ?%1").arg((*bindIndex)++);
+    }
+    if (options.modifiedBefore.has_value()) {
+        sql += QStringLiteral(" AND i.modified_at <= ?%1").arg((*bindIndex)++);
+    }
+    if (options.createdAfter.has_value()) {
+        sql += QStringLiteral(" AND i.created_at >= ?%1").arg((*bindIndex)++);
+    }
+    if (options.createdBefore.has_value()) {
+        sql += QStringLiteral(" AND i.created_at <= ?%1").arg((*bindIndex)++);
     }
-    if (options.modifiedBefore.has_value()) {
-        sql += QStringLiteral(" AND i.modified_at <= ?%1").arg(bindIndex++);
+    // Items without a due date have no row, so they never match.
+    if (options.dueAfter.has_value()) {
+        sql += QStringLiteral(" AND i.id IN (SELECT item_id FROM item_attributes"
+                              " WHERE name = '%1' AND CAST(value AS REAL) >= ?%2)")
+                   .arg(QLatin1String(SearchOptions::kDueDateAttribute))
+                   .arg((*bindIndex)++);
+    }
+    if (options.dueBefore.has_value()) {
+        sql += QStringLiteral(" AND i.id IN (SELECT item_id FROM item_attributes"
+                              " WHERE name = '%1' AND CAST(value AS REAL) <= ?%2)")
+                   .arg(QLatin1String(SearchOptions::kDueDateAttribute))
+                   .arg((*bindIndex)++);
     }
     if (options.minSizeBytes.has_value()) {
-        sql += QStringLiteral(" AND i.size >= ?%1").arg(bindIndex++);
+        sql += QStringLiteral(" AND i.size >= ?%1").arg((*bindIndex)++);
     }
     if (options.maxSizeBytes.has_value()) {
-        sql += QStringLiteral(" AND i.size <= ?%1").arg(bindIndex++);
-    }
-
-    // Normalize file types: strip leading dot, lowercase
-    std::vector<QString> normalizedFileTypes;
-    for (const QString& fileType : options.fileTypes) {
-        QString normalized = fileType.trimmed().toLower();
-        if (normalized.startsWith(QLatin1Char('.'))) {
-            normalized = normalized.mid(1);
-        }
-        if (!normalized.isEmpty()) {
-            normalizedFileTypes.push_back(normalized);
-        }
+        sql += QStringLiteral(" AND i.size <= ?%1").arg((*bindIndex)++);
     }
 
-    if (!normalizedFileTypes.empty()) {
+    if (!fileTypes.empty()) {
         QStringList placeholders;
-        placeholders.reserve(static_cast<int>(normalizedFileTypes.size()));
-        for (size_t i = 0; i < normalizedFileTypes.size(); ++i) {
-            placeholders.push_back(QStringLiteral("?%1").arg(bindIndex++));
+        placeholders.reserve(static_cast<int>(fileTypes.size()));
+        for (size_t i = 0; i < fileTypes.size(); ++i) {
+            placeholders.push_back(QStringLiteral("?%1").arg((*bindIndex)++));
         }
         sql += QStringLiteral(" AND i.extension IN (")
              + placeholders.join(QStringLiteral(", "))
@@ -1188,7 +2244,7 @@ std::vector<SQLiteStore::FtsJoinedHit> SQLiteStore::searchFts5Joined(
         pathConds.reserve(static_cast<int>(options.includePaths.size()));
         for (size_t i = 0; i < options.includePaths.size(); ++i) {
             if (!options.includePaths[i].isEmpty()) {
-                pathConds.push_back(QStringLiteral("i.path LIKE ?%1").arg(bindIndex++));
+                pathConds.push_back(QStringLiteral("i.path LIKE ?%1").arg((*bindIndex)++));
             }
         }
         if (!pathConds.isEmpty()) {
@@ -1200,56 +2256,114 @@ std::vector<SQLiteStore::FtsJoinedHit> SQLiteStore::searchFts5Joined(
 
     for (size_t i = 0; i < options.excludePaths.size(); ++i) {
         if (!options.excludePaths[i].isEmpty()) {
-            sql += QStringLiteral(" AND i.path NOT LIKE ?%1").arg(bindIndex++);
+            sql += QStringLiteral(" AND i.path NOT LIKE ?%1").arg((*bindIndex)++);
         }
     }
 
-    sql += QStringLiteral(" ORDER BY si.rank LIMIT ?%1").arg(bindIndex);
-
-    sqlite3_stmt* stmt = nullptr;
-    const QByteArray sqlUtf8 = sql.toUtf8();
-    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
-        LOG_ERROR(bsIndex, "FTS5 joined search prepare: %s", sqlite3_errmsg(m_db));
-        return {};
+    for (size_t i = 0; i < options.tags.size(); ++i) {
+        sql += QStringLiteral(" AND i.id IN (SELECT item_id FROM tags"
+                              " WHERE tag = ?%1 COLLATE NOCASE)").arg((*bindIndex)++);
     }
+}
 
-    // Bind parameters
-    int idx = 1;
-    const QByteArray queryUtf8 = sanitized.toUtf8();
-    sqlite3_bind_text(stmt, idx++, queryUtf8.constData(), -1, SQLITE_STATIC);
-
+// `boundStrings` must outlive the statement's execution (SQLITE_STATIC).
+static void bindItemFilters(sqlite3_stmt* stmt, const SearchOptions& options,
+                            const std::vector<QString>& fileTypes, int* idx,
+                            std::vector<QByteArray>& boundStrings)
+{
     if (options.modifiedAfter.has_value()) {
-        sqlite3_bind_double(stmt, idx++, options.modifiedAfter.value());
+        sqlite3_bind_double(stmt, (*idx)++, options.modifiedAfter.value());
     }
     if (options.modifiedBefore.has_value()) {
-        sqlite3_bind_double(stmt, idx++, options.modifiedBefore.value());
+        sqlite3_bind_double(stmt, (*idx)++, options.modifiedBefore.value());
+    }
+    if (options.createdAfter.has_value()) {
+        sqlite3_bind_double(stmt, (*idx)++, options.createdAfter.value());
+    }
+    if (options.createdBefore.has_value()) {
+        sqlite3_bind_double(stmt, (*idx)++, options.createdBefore.value());
+    }
+    if (options.dueAfter.has_value()) {
+        sqlite3_bind_double(stmt, (*idx)++, options.dueAfter.value());
+    }
+    if (options.dueBefore.has_value()) {
+        sqlite3_bind_double(stmt, (*idx)++, options.dueBefore.value());
     }
     if (options.minSizeBytes.has_value()) {
-        sqlite3_bind_int64(stmt, idx++, options.minSizeBytes.value());
+        sqlite3_bind_int64(stmt, (*idx)++, options.minSizeBytes.value());
     }
     if (options.maxSizeBytes.has_value()) {
-        sqlite3_bind_int64(stmt, idx++, options.maxSizeBytes.value());
+        sqlite3_bind_int64(stmt, (*idx)++, options.maxSizeBytes.value());
     }
 
-    for (const QString& ext : normalizedFileTypes) {
+    boundStrings.reserve(boundStrings.size() + fileTypes.size() + options.includePaths.size()
+                         + options.excludePaths.size() + options.tags.size());
+    for (const QString& ext : fileTypes) {
         boundStrings.push_back(ext.toUtf8());
-        sqlite3_bind_text(stmt, idx++, boundStrings.back().constData(), -1, SQLITE_STATIC);
+        sqlite3_bind_text(stmt, (*idx)++, boundStrings.back().constData(), -1, SQLITE_STATIC);
     }
 
     for (const QString& includePath : options.includePaths) {
         if (!includePath.isEmpty()) {
             boundStrings.push_back((includePath + QStringLiteral("%")).toUtf8());
-            sqlite3_bind_text(stmt, idx++, boundStrings.back().constData(), -1, SQLITE_STATIC);
+            sqlite3_bind_text(stmt, (*idx)++, boundStrings.back().constData(), -1, SQLITE_STATIC);
         }
     }
 
     for (const QString& excludePath : options.excludePaths) {
         if (!excludePath.isEmpty()) {
             boundStrings.push_back((excludePath + QStringLiteral("%")).toUtf8());
-            sqlite3_bind_text(stmt, idx++, boundStrings.back().constData(), -1, SQLITE_STATIC);
+            sqlite3_bind_text(stmt, (*idx)++, boundStrings.back().constData(), -1, SQLITE_STATIC);
         }
     }
 
+    for (const QString& tag : options.tags) {
+        boundStrings.push_back(tag.toUtf8());
+        sqlite3_bind_text(stmt, (*idx)++, boundStrings.back().constData(), -1, SQLITE_STATIC);
+    }
+}
+
+std::vector<SQLiteStore::FtsJoinedHit> SQLiteStore::searchFts5Joined(
+    const QString& query, int limit, bool relaxed, const SearchOptions& options)
+{
+    const QString sanitized = relaxed ? sanitizeFtsQueryRelaxed(query)
+                                      : sanitizeFtsQueryStrict(query);
+    if (sanitized.isEmpty()) {
+        LOG_DEBUG(bsIndex, "FTS5 joined search skipped after sanitization");
+        return {};
+    }
+
+    // Build dynamic SQL with JOIN and optional filters
+    QString sql = QStringLiteral(
+        "SELECT si.file_id, si.chunk_id, si.rank,"
+        " snippet(search_index, 2, '<b>', '</b>', '...', 32),"
+        " i.path, i.name, i.kind, i.size, i.modified_at,"
+        " i.parent_path, i.is_pinned, i.content_hash"
+        " FROM search_index si"
+        " JOIN items i ON i.id = si.file_id"
+        " WHERE search_index MATCH ?1");
+
+    int bindIndex = 2;
+    const std::vector<QString> fileTypes = normalizedFileTypes(options);
+    appendItemFilterSql(sql, options, fileTypes, &bindIndex);
+
+    sql += QStringLiteral(" ORDER BY si.rank LIMIT ?%1").arg(bindIndex);
+
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sqlUtf8 = sql.toUtf8();
+    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "FTS5 joined search prepare: %s", sqlite3_errmsg(m_db));
+        return {};
+    }
+
+    // Bind parameters
+    int idx = 1;
+    const QByteArray queryUtf8 = sanitized.toUtf8();
+    sqlite3_bind_text(stmt, idx++, queryUtf8.constData(), -1, SQLITE_STATIC);
+
+    std::vector<QByteArray> boundStrings;
+    bindItemFilters(stmt, options, fileTypes, &idx, boundStrings);
+
     sqlite3_bind_int(stmt, idx, std::max(1, limit));
 
     // Step through results
@@ -1281,6 +2395,143 @@ std::vector<SQLiteStore::FtsJoinedHit> SQLiteStore::searchFts5Joined(
     return hits;
 }
 
+SQLiteStore::ExportPage SQLiteStore::exportMatches(const QString& query, bool relaxed,
+                                                   const SearchOptions& options, int limit,
+                                                   int64_t afterRowid)
+{
+    ExportPage page;
+    const QString sanitized = relaxed ? sanitizeFtsQueryRelaxed(query)
+                                      : sanitizeFtsQueryStrict(query);
+    if (sanitized.isEmpty()) {
+        LOG_DEBUG(bsIndex, "FTS5 export skipped after sanitization");
+        return page;
+    }
+
+    // FTS5 hands rows back in rowid order for ORDER BY rowid, so there is
+    // no sort step however many rows match. highlight() marks the columns
+    // the query matched in this chunk.
+    QString sql = QStringLiteral(
+        "SELECT si.rowid, si.file_id,"
+        " instr(highlight(search_index, 0, char(1), char(2)), char(1)) > 0,"
+        " instr(highlight(search_index, 1, char(1), char(2)), char(1)) > 0,"
+        " instr(highlight(search_index, 2, char(1), char(2)), char(1)) > 0,"
+        " i.path, i.name, i.kind, i.size, i.created_at, i.modified_at, i.indexed_at"
+        " FROM search_index si"
+        " JOIN items i ON i.id = si.file_id"
+        " WHERE search_index MATCH ?1 AND si.rowid > ?2");
+
+    int bindIndex = 3;
+    const std::vector<QString> fileTypes = normalizedFileTypes(options);
+    appendItemFilterSql(sql, options, fileTypes, &bindIndex);
+    sql += QStringLiteral(" ORDER BY si.rowid");
+
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sqlUtf8 = sql.toUtf8();
+    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "FTS5 export prepare: %s", sqlite3_errmsg(m_db));
+        return page;
+    }
+
+    int idx = 1;
+    const QByteArray queryUtf8 = sanitized.toUtf8();
+    sqlite3_bind_text(stmt, idx++, queryUtf8.constData(), -1, SQLITE_STATIC);
+    sqlite3_bind_int64(stmt, idx++, afterRowid);
+    std::vector<QByteArray> boundStrings;
+    bindItemFilters(stmt, options, fileTypes, &idx, boundStrings);
+
+    // insertChunks() and replaceItemAttributes() rewrite all of an item's
+    // FTS5 rows (chunks, then attributes) in one transaction, so they are
+    // adjacent in rowid order: fold runs of the same file_id into one row,
+    // and only end a page between items.
+    const size_t pageSize = static_cast<size_t>(std::max(1, limit));
+    int64_t lastRowid = afterRowid;
+    bool more = false;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        const int64_t rowid = sqlite3_column_int64(stmt, 0);
+        const int64_t fileId = sqlite3_column_int64(stmt, 1);
+        const bool sameItem = !page.rows.empty() && page.rows.back().fileId == fileId;
+        if (!sameItem && page.rows.size() == pageSize) {
+            more = true;
+            break;
+        }
+        if (!sameItem) {
+            ExportRow row;
+            row.fileId = fileId;
+            const char* path = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 5));
+            row.path = path ? QString::fromUtf8(path) : QString();
+            const char* name = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 6));
+            row.name = name ? QString::fromUtf8(name) : QString();
+            const char* kind = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
+            row.kind = kind ? QString::fromUtf8(kind) : QString();
+            row.size = sqlite3_column_int64(stmt, 8);
+            row.createdAt = sqlite3_column_double(stmt, 9);
+            row.modifiedAt = sqlite3_column_double(stmt, 10);
+            row.indexedAt = sqlite3_column_double(stmt, 11);
+            page.rows.push_back(std::move(row));
+        }
+
+        ExportRow& row = page.rows.back();
+        static const char* const kFields[] = {"name", "path", "content"};
+        for (int column = 0; column < 3; ++column) {
+            const QString field = QString::fromLatin1(kFields[column]);
+            if (sqlite3_column_int(stmt, 2 + column) != 0 && !row.matchedFields.contains(field)) {
+                row.matchedFields.append(field);
+            }
+        }
+        lastRowid = rowid;
+    }
+    sqlite3_finalize(stmt);
+
+    if (more) {
+        page.nextAfterRowid = lastRowid;
+    }
+    return page;
+}
+
+std::vector<SQLiteStore::ItemRow> SQLiteStore::listFilteredItems(const SearchOptions& options,
+                                                                 int limit)
+{
+    QString sql = QStringLiteral(
+        "SELECT i.id, i.path, i.name, i.kind, i.size, i.modified_at, i.indexed_at,"
+        " i.content_hash, i.is_pinned, i.created_at"
+        " FROM items i WHERE 1 = 1");
+    int bindIndex = 1;
+    const std::vector<QString> fileTypes = normalizedFileTypes(options);
+    appendItemFilterSql(sql, options, fileTypes, &bindIndex);
+    sql += QStringLiteral(" ORDER BY i.modified_at DESC, i.id LIMIT ?%1").arg(bindIndex);
+
+    std::vector<ItemRow> rows;
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sqlUtf8 = sql.toUtf8();
+    if (sqlite3_prepare_v2(m_db, sqlUtf8.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Filtered item listing prepare: %s", sqlite3_errmsg(m_db));
+        return rows;
+    }
+
+    int idx = 1;
+    std::vector<QByteArray> boundStrings;
+    bindItemFilters(stmt, options, fileTypes, &idx, boundStrings);
+    sqlite3_bind_int(stmt, idx, std::max(1, limit));
+
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        ItemRow row;
+        row.id = sqlite3_column_int64(stmt, 0);
+        row.path = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1)));
+        row.name = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 2)));
+        row.kind = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 3)));
+        row.size = sqlite3_column_int64(stmt, 4);
+        row.modifiedAt = sqlite3_column_double(stmt, 5);
+        row.indexedAt = sqlite3_column_double(stmt, 6);
+        const char* hash = reinterpret_cast<const char*>(sqlite3_column_text(stmt, 7));
+        row.contentHash = hash ? QString::fromUtf8(hash) : QString();
+        row.isPinned = sqlite3_column_int(stmt, 8) != 0;
+        row.createdAt = sqlite3_column_double(stmt, 9);
+        rows.push_back(std::move(row));
+    }
+    sqlite3_finalize(stmt);
+    return rows;
+}
+
 // ── Batch Frequencies ───────────────────────────────────────
 
 std::unordered_map<int64_t, SQLiteStore::FrequencyRow> SQLiteStore::getFrequenciesBatch(
@@ -1477,6 +2728,281 @@ bool SQLiteStore::setBm25Weights(double nameWeight, double pathWeight, double co
 
 // ── Health ──────────────────────────────────────────────────
 
+int64_t SQLiteStore::itemCount()
+{
+    int64_t count = 0;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "SELECT COUNT(*) FROM items", -1, &stmt, nullptr) != SQLITE_OK) {
+        return 0;
+    }
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        count = sqlite3_column_int64(stmt, 0);
+    }
+    sqlite3_finalize(stmt);
+    return count;
+}
+
+int SQLiteStore::ftsSegmentCount()
+{
+    // The structure record (rowid 10 of <table>_data) is a 4-byte cookie,
+    // an optional "\xff\x00\x00\x01" marker for the v2 layout, then
+    // varints nLevel and nSegment.
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db, "SELECT block FROM search_index_data WHERE id = 10", -1,
+                           &stmt, nullptr) != SQLITE_OK) {
+        return -1;
+    }
+    QByteArray block;
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        block = QByteArray(static_cast<const char*>(sqlite3_column_blob(stmt, 0)),
+                           sqlite3_column_bytes(stmt, 0));
+    }
+    sqlite3_finalize(stmt);
+
+    int offset = 4;
+    if (block.size() >= 9 && block.mid(4, 4) == QByteArray("\xff\x00\x00\x01", 4)) {
+        offset += 4;
+    }
+    const auto readVarint = [&block, &offset](int64_t* value) {
+        *value = 0;
+        for (int i = 0; i < 9; ++i) {
+            if (offset >= block.size()) {
+                return false;
+            }
+            const auto byte = static_cast<uint8_t>(block.at(offset++));
+            if (i == 8) {
+                *value = (*value << 8) | byte;
+                return true;
+            }
+            *value = (*value << 7) | (byte & 0x7f);
+            if ((byte & 0x80) == 0) {
+                return true;
+            }
+        }
+        return true;
+    };
+    int64_t levels = 0;
+    int64_t segments = 0;
+    if (!readVarint(&levels) || !readVarint(&segments)) {
+        return -1;
+    }
+    return static_cast<int>(segments);
+}
+
+std::vector<SQLiteStore::FailureCount> SQLiteStore::extractionFailureCounts()
+{
+    std::vector<FailureCount> counts;
+    sqlite3_stmt* stmt = nullptr;
+    const char* sql = R"(
+        SELECT i.kind,
+               (f.error_message LIKE 'PDF extraction unavailable (%'
+                OR f.error_message LIKE 'OCR extraction unavailable (%'
+                OR f.error_message LIKE 'Leptonica failed to read image%'
+                OR f.error_message LIKE 'Extension % is not supported by extractor'
+                OR f.error_message LIKE 'File size % exceeds configured limit %'
+                OR f.error_message = 'File does not exist or is not a regular file'
+                OR f.error_message = 'File is not readable'
+                OR f.error_message = 'Failed to load PDF document'
+                OR f.error_message = 'PDF is encrypted or password-protected'
+                OR f.error_message = 'File appears to be a cloud placeholder (size reported but no content readable)'
+               ) AS expected,
+               COUNT(*)
+        FROM failures f
+        JOIN items i ON i.id = f.item_id
+        WHERE f.stage = 'extraction'
+        GROUP BY i.kind, expected
+        ORDER BY i.kind, expected
+    )";
+    if (sqlite3_prepare_v2(m_db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Failure count prepare: %s", sqlite3_errmsg(m_db));
+        return counts;
+    }
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        FailureCount count;
+        count.kind = QString::fromUtf8(reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        count.expected = sqlite3_column_int(stmt, 1) != 0;
+        count.count = sqlite3_column_int64(stmt, 2);
+        counts.push_back(std::move(count));
+    }
+    sqlite3_finalize(stmt);
+    return counts;
+}
+
+QString SQLiteStore::breakdownDirectory(const QString& path, const QStringList& roots)
+{
+    QString bestRoot;
+    for (QString root : roots) {
+        while (root.size() > 1 && root.endsWith(QLatin1Char('/'))) {
+            root.chop(1);
+        }
+        const bool holds = path == root
+            || path.startsWith(root == QLatin1String("/") ? root : root + QLatin1Char('/'));
+        if (holds && root.size() > bestRoot.size()) {
+            bestRoot = root;
+        }
+    }
+    if (bestRoot.isEmpty()) {
+        const qsizetype slash = path.lastIndexOf(QLatin1Char('/'));
+        return slash > 0 ? path.left(slash) : QStringLiteral("/");
+    }
+
+    const qsizetype start = bestRoot == QLatin1String("/") ? 1 : bestRoot.size() + 1;
+    const qsizetype next = path.indexOf(QLatin1Char('/'), start);
+    if (next < 0) {
+        return bestRoot;  // the root itself, or a file directly in it
+    }
+    return path.left(next);
+}
+
+// Folds everything past the first maxBuckets (by bytes) into "(other)".
+static std::vector<SQLiteStore::SizeBucket> rankedBuckets(
+    const QHash<QString, SQLiteStore::SizeBucket>& buckets, int maxBuckets)
+{
+    std::vector<SQLiteStore::SizeBucket> ranked;
+    ranked.reserve(static_cast<size_t>(buckets.size()));
+    for (const auto& bucket : buckets) {
+        ranked.push_back(bucket);
+    }
+    std::sort(ranked.begin(), ranked.end(), [](const auto& a, const auto& b) {
+        return a.bytes != b.bytes ? a.bytes > b.bytes : a.name < b.name;
+    });
+    if (maxBuckets > 0 && static_cast<int>(ranked.size()) > maxBuckets) {
+        SQLiteStore::SizeBucket other;
+        other.name = QStringLiteral("(other)");
+        for (size_t i = static_cast<size_t>(maxBuckets); i < ranked.size(); ++i) {
+            other.items += ranked[i].items;
+            other.bytes += ranked[i].bytes;
+        }
+        ranked.resize(static_cast<size_t>(maxBuckets));
+        ranked.push_back(std::move(other));
+    }
+    return ranked;
+}
+
+SQLiteStore::SizeBreakdown SQLiteStore::sizeBreakdown(const QStringList& roots, int maxBuckets)
+{
+    SizeBreakdown breakdown;
+    breakdown.totalBytes = std::max<int64_t>(
+        queryInt("SELECT (page_count - freelist_count) * page_size "
+                 "FROM pragma_page_count(), pragma_freelist_count(), pragma_page_size()"),
+        0);
+
+    // Pages per b-tree, folded onto the table each index or FTS5 shadow
+    // table (search_index_data, _idx, _content, _docsize, _config) serves.
+    sqlite3_stmt* stmt = nullptr;
+    const char* tableSql = R"(
+        SELECT COALESCE(m.tbl_name, d.name), SUM(d.pgsize)
+        FROM dbstat d
+        LEFT JOIN sqlite_master m ON m.name = d.name
+        GROUP BY 1
+    )";
+    if (sqlite3_prepare_v2(m_db, tableSql, -1, &stmt, nullptr) == SQLITE_OK) {
+        QHash<QString, SizeBucket> tables;
+        while (sqlite3_step(stmt) == SQLITE_ROW) {
+            QString name = QString::fromUtf8(
+                reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+            if (name.startsWith(QLatin1String("search_index_"))) {
+                name = QStringLiteral("search_index");
+            }
+            SizeBucket& bucket = tables[name];
+            bucket.name = name;
+            bucket.bytes += sqlite3_column_int64(stmt, 1);
+        }
+        sqlite3_finalize(stmt);
+        breakdown.measured = true;
+        for (const char* name : {"items", "content", "search_index"}) {
+            breakdown.fileBytes += tables.value(QLatin1String(name)).bytes;
+        }
+        breakdown.tables = rankedBuckets(tables, 0);
+    } else {
+        LOG_WARN(bsIndex, "Size breakdown without dbstat: %s", sqlite3_errmsg(m_db));
+        breakdown.fileBytes = breakdown.totalBytes;
+    }
+
+    // Name and path are stored once in items and again in every FTS5 row
+    // of the file; chunk text once in content and once in search_index.
+    const char* itemSql = R"(
+        SELECT i.path, i.extension,
+               length(CAST(i.name AS BLOB)), length(CAST(i.path AS BLOB)),
+               COALESCE(c.bytes, 0), COALESCE(c.chunks, 0)
+        FROM items i
+        LEFT JOIN (SELECT item_id, SUM(length(CAST(chunk_text AS BLOB))) AS bytes,
+                          COUNT(*) AS chunks
+                   FROM content GROUP BY item_id) c ON c.item_id = i.id
+    )";
+    if (sqlite3_prepare_v2(m_db, itemSql, -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Size breakdown prepare: %s", sqlite3_errmsg(m_db));
+        return breakdown;
+    }
+    QHash<QString, SizeBucket> fileTypes;
+    QHash<QString, SizeBucket> directories;
+    QHash<QString, double> typeWeights;
+    QHash<QString, double> directoryWeights;
+    double nameWeight = 0.0;
+    double pathWeight = 0.0;
+    double contentWeight = 0.0;
+    int64_t itemCount = 0;
+    int64_t itemsWithContent = 0;
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        const QString path = QString::fromUtf8(
+            reinterpret_cast<const char*>(sqlite3_column_text(stmt, 0)));
+        QString extension = QString::fromUtf8(
+            reinterpret_cast<const char*>(sqlite3_column_text(stmt, 1))).toLower();
+        if (extension.isEmpty()) {
+            extension = QStringLiteral("(none)");
+        }
+        const double rows = 1.0 + static_cast<double>(sqlite3_column_int64(stmt, 5));
+        const double name = static_cast<double>(sqlite3_column_int64(stmt, 2)) * rows;
+        const double pathBytes = static_cast<double>(sqlite3_column_int64(stmt, 3)) * rows;
+        const double content = 2.0 * static_cast<double>(sqlite3_column_int64(stmt, 4));
+        const double weight = name + pathBytes + content;
+        nameWeight += name;
+        pathWeight += pathBytes;
+        contentWeight += content;
+        ++itemCount;
+        if (content > 0.0) {
+            ++itemsWithContent;
+        }
+
+        SizeBucket& type = fileTypes[extension];
+        type.name = extension;
+        ++type.items;
+        typeWeights[extension] += weight;
+
+        const QString directory = breakdownDirectory(path, roots);
+        SizeBucket& dir = directories[directory];
+        dir.name = directory;
+        ++dir.items;
+        directoryWeights[directory] += weight;
+    }
+    sqlite3_finalize(stmt);
+
+    const double totalWeight = nameWeight + pathWeight + contentWeight;
+    const auto share = [&](double weight) -> int64_t {
+        return totalWeight > 0.0
+            ? std::llround(weight / totalWeight * static_cast<double>(breakdown.fileBytes))
+            : 0;
+    };
+    for (auto it = fileTypes.begin(); it != fileTypes.end(); ++it) {
+        it->bytes = share(typeWeights.value(it.key()));
+    }
+    for (auto it = directories.begin(); it != directories.end(); ++it) {
+        it->bytes = share(directoryWeights.value(it.key()));
+    }
+    QHash<QString, SizeBucket> fields;
+    fields.insert(QStringLiteral("file_name"),
+                  SizeBucket{QStringLiteral("file_name"), itemCount, share(nameWeight)});
+    fields.insert(QStringLiteral("file_path"),
+                  SizeBucket{QStringLiteral("file_path"), itemCount, share(pathWeight)});
+    fields.insert(QStringLiteral("content"),
+                  SizeBucket{QStringLiteral("content"), itemsWithContent, share(contentWeight)});
+
+    breakdown.fields = rankedBuckets(fields, 0);
+    breakdown.fileTypes = rankedBuckets(fileTypes, maxBuckets);
+    breakdown.directories = rankedBuckets(directories, maxBuckets);
+    return breakdown;
+}
+
 IndexHealth SQLiteStore::getHealth()
 {
     IndexHealth health;
@@ -1607,6 +3133,84 @@ IndexHealth SQLiteStore::getHealth()
     return health;
 }
 
+// ── Tombstones ──────────────────────────────────────────────
+
+std::vector<SQLiteStore::Tombstone> SQLiteStore::getTombstonesAfter(int64_t seq, int limit)
+{
+    std::vector<Tombstone> tombstones;
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db,
+                           "SELECT seq, item_id FROM item_tombstones WHERE seq > ?1 "
+                           "ORDER BY seq LIMIT ?2",
+                           -1, &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Tombstone lookup prepare: %s", sqlite3_errmsg(m_db));
+        return tombstones;
+    }
+    sqlite3_bind_int64(stmt, 1, seq);
+    sqlite3_bind_int(stmt, 2, limit);
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        Tombstone tombstone;
+        tombstone.seq = sqlite3_column_int64(stmt, 0);
+        tombstone.itemId = sqlite3_column_int64(stmt, 1);
+        tombstones.push_back(tombstone);
+    }
+    sqlite3_finalize(stmt);
+    return tombstones;
+}
+
+int64_t SQLiteStore::lastTombstoneSeq()
+{
+    // AUTOINCREMENT's counter outlives the rows it numbered.
+    return std::max<int64_t>(
+        queryInt("SELECT seq FROM sqlite_sequence WHERE name = 'item_tombstones'"), 0);
+}
+
+int64_t SQLiteStore::prunedTombstoneSeq()
+{
+    return std::max<int64_t>(
+        queryInt("SELECT CAST(value AS INTEGER) FROM settings "
+                 "WHERE key = 'tombstones_pruned_seq'"),
+        0);
+}
+
+int64_t SQLiteStore::pruneTombstones(double before)
+{
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_db,
+                           "SELECT MAX(seq) FROM item_tombstones WHERE deleted_at < ?1", -1,
+                           &stmt, nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Tombstone prune prepare: %s", sqlite3_errmsg(m_db));
+        return 0;
+    }
+    sqlite3_bind_double(stmt, 1, before);
+    int64_t pruneThrough = 0;
+    if (sqlite3_step(stmt) == SQLITE_ROW && sqlite3_column_type(stmt, 0) != SQLITE_NULL) {
+        pruneThrough = sqlite3_column_int64(stmt, 0);
+    }
+    sqlite3_finalize(stmt);
+    if (pruneThrough <= 0) {
+        return 0;
+    }
+
+    // By seq rather than date, so what was pruned is exactly a prefix.
+    if (sqlite3_prepare_v2(m_db, "DELETE FROM item_tombstones WHERE seq <= ?1", -1, &stmt,
+                           nullptr) != SQLITE_OK) {
+        LOG_ERROR(bsIndex, "Tombstone prune prepare: %s", sqlite3_errmsg(m_db));
+        return 0;
+    }
+    sqlite3_bind_int64(stmt, 1, pruneThrough);
+    const bool deleted = sqlite3_step(stmt) == SQLITE_DONE;
+    sqlite3_finalize(stmt);
+    if (!deleted) {
+        LOG_WARN(bsIndex, "Tombstone prune failed: %s", sqlite3_errmsg(m_db));
+        return 0;
+    }
+    const int64_t removed = sqlite3_changes64(m_db);
+    setSetting(QStringLiteral("tombstones_pruned_seq"),
+               QString::number(std::max(pruneThrough, prunedTombstoneSeq())));
+    return removed;
+}
+
 // ── Transactions ────────────────────────────────────────────
 
 bool SQLiteStore::beginTransaction()
@@ -1638,6 +3242,10 @@ bool SQLiteStore::deleteAll()
         LOG_ERROR(bsIndex, "deleteAll: failed to clear items");
         return false;
     }
+    if (const int64_t deleted = sqlite3_changes64(m_db); deleted > 0) {
+        countPendingCompactionDeletes(m_db, deleted);
+    }
+    pruneTombstones(std::numeric_limits<double>::max());
     LOG_INFO(bsIndex, "deleteAll: all indexed data cleared");
     return true;
 }
@@ -1654,6 +3262,76 @@ bool SQLiteStore::vacuum()
     return execSql("VACUUM");
 }
 
+int64_t SQLiteStore::pendingCompactionDeletes()
+{
+    return std::max<int64_t>(
+        queryInt("SELECT CAST(value AS INTEGER) FROM settings "
+                 "WHERE key = 'pending_compaction_deletes'"),
+        0);
+}
+
+int64_t SQLiteStore::freePageCount()
+{
+    return queryInt("PRAGMA freelist_count");
+}
+
+SQLiteStore::CompactionSummary SQLiteStore::compact()
+{
+    CompactionSummary summary;
+    const auto databaseBytes = [this]() {
+        return queryInt("PRAGMA page_count") * queryInt("PRAGMA page_size");
+    };
+    summary.compactedDeletes = pendingCompactionDeletes();
+    pruneTombstones(static_cast<double>(QDateTime::currentSecsSinceEpoch())
+                    - kTombstoneRetentionDays * 86400.0);
+    summary.bytesBefore = databaseBytes();
+
+    // 'optimize' rewrites every segment into one, so postings of deleted
+    // rows are dropped rather than shadowed by tombstones. VACUUM then
+    // rebuilds the file without free pages, the only place deleted rows
+    // were left, and the checkpoint flushes the WAL that held the rewrite.
+    summary.ftsOptimized = optimizeFts5();
+    summary.vacuumed = summary.ftsOptimized && vacuum();
+    summary.walCheckpointed = summary.vacuumed && checkpointWal();
+    summary.freePages = freePageCount();
+    summary.bytesAfter = databaseBytes();
+
+    if (summary.complete()) {
+        setSetting(QStringLiteral("pending_compaction_deletes"), QStringLiteral("0"));
+        setSetting(QStringLiteral("last_vacuum_at"),
+                   QString::number(QDateTime::currentSecsSinceEpoch()));
+    } else {
+        LOG_WARN(bsIndex, "Compaction incomplete (optimize=%d vacuum=%d checkpoint=%d): %s",
+                 summary.ftsOptimized ? 1 : 0, summary.vacuumed ? 1 : 0,
+                 summary.walCheckpointed ? 1 : 0, sqlite3_errmsg(m_db));
+    }
+    return summary;
+}
+
+bool SQLiteStore::setSecureDelete(bool enabled)
+{
+    // FTS5 keeps the option in search_index_config; once set the table
+    // needs SQLite 3.42+ to read, which the vendored copy is.
+    return execSql(enabled ? "PRAGMA secure_delete = ON" : "PRAGMA secure_delete = OFF")
+        && execSql(enabled
+                       ? "INSERT INTO search_index(search_index, rank) VALUES('secure-delete', 1)"
+                       : "INSERT INTO search_index(search_index, rank) VALUES('secure-delete', 0)");
+}
+
+bool SQLiteStore::checkpointWal()
+{
+    int logFrames = 0;
+    int checkpointedFrames = 0;
+    const int rc = sqlite3_wal_checkpoint_v2(m_db, nullptr, SQLITE_CHECKPOINT_TRUNCATE,
+                                             &logFrames, &checkpointedFrames);
+    if (rc != SQLITE_OK) {
+        LOG_WARN(bsIndex, "WAL checkpoint incomplete (%d/%d frames): %s",
+                 checkpointedFrames, logFrames, sqlite3_errmsg(m_db));
+        return false;
+    }
+    return true;
+}
+
 bool SQLiteStore::integrityCheck() const
 {
     if (!m_db) return false;
@@ -1693,6 +3371,25 @@ bool SQLiteStore::fts5IntegrityCheck() const
     return true;
 }
 
+void SQLiteStore::setQueryDeadline(int64_t deadlineAtMs)
+{
+    if (!m_db) return;
+
+    if (deadlineAtMs <= 0) {
+        sqlite3_progress_handler(m_db, 0, nullptr, nullptr);
+        return;
+    }
+    // The deadline rides in the context pointer so a moved store cannot
+    // leave the handler pointing at a stale object.
+    sqlite3_progress_handler(
+        m_db, 1000,
+        [](void* context) -> int {
+            const auto deadline = static_cast<int64_t>(reinterpret_cast<intptr_t>(context));
+            return QDateTime::currentMSecsSinceEpoch() >= deadline ? 1 : 0;
+        },
+        reinterpret_cast<void*>(static_cast<intptr_t>(deadlineAtMs)));
+}
+
 bool SQLiteStore::walCheckpoint()
 {
     if (!m_db) return false;
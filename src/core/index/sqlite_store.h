@@ -5,6 +5,7 @@
 #include "core/shared/index_health.h"
 #include "core/shared/search_options.h"
 #include <QString>
+#include <QStringList>
 #include <optional>
 #include <unordered_map>
 #include <vector>
@@ -42,6 +43,11 @@ public:
     // Creates schema and sets pragmas on first open.
     static std::optional<SQLiteStore> open(const QString& dbPath);
 
+    // Open someone else's index (a published team index) for searching
+    // only: the connection is read-only and query_only, nothing is created
+    // or migrated, and an index of another schema version is refused.
+    static std::optional<SQLiteStore> openReadOnly(const QString& dbPath);
+
     // ── Items CRUD ──────────────────────────────────────────
 
     // Insert or update an item. Returns the item id.
@@ -70,11 +76,38 @@ public:
         double indexedAt = 0.0;
         QString contentHash;
         bool isPinned = false;
+        double createdAt = 0.0;
     };
 
     std::optional<ItemRow> getItemByPath(const QString& path);
     std::optional<ItemRow> getItemById(int64_t id);
 
+    // Items at `root` or anywhere below it, in path order. Uses the path
+    // index, so it is cheap for a subtree of a large index.
+    std::vector<ItemRow> getItemsUnderPath(const QString& root);
+    // Items whose id is in [firstId, lastId], in id order.
+    std::vector<ItemRow> getItemsInIdRange(int64_t firstId, int64_t lastId);
+    // Ids of the items indexed (or reindexed) at or after `since`, in
+    // seconds since the epoch, in id order.
+    std::vector<int64_t> getItemIdsIndexedSince(double since);
+
+    // What the index still holds at `root` or below, table by table. FTS5
+    // rows are counted by their own file_path, so orphans left by a missed
+    // delete show up too. Scans search_index; meant for verifying a purge,
+    // not for polling.
+    struct PathFootprint {
+        int64_t items = 0;
+        int64_t ftsRows = 0;
+        int64_t behaviorEvents = 0;
+
+        bool empty() const { return items == 0 && ftsRows == 0 && behaviorEvents == 0; }
+    };
+    PathFootprint footprintUnderPath(const QString& root);
+
+    // Behavior events keep their item_path after the item is deleted
+    // (item_id is only nulled). Returns the number of rows removed.
+    int64_t deleteBehaviorEventsUnderPath(const QString& root);
+
     struct ItemAvailability {
         bool contentAvailable = false;
         QString availabilityStatus = QStringLiteral("available");
@@ -83,6 +116,10 @@ public:
 
     std::optional<ItemAvailability> getItemAvailability(int64_t id);
 
+    // Extracted text for an item, chunks joined in order, capped at maxChars.
+    // Empty when the item has no content rows.
+    QString getItemContent(int64_t id, int maxChars, bool* truncated = nullptr);
+
     // ── Chunks + FTS5 (atomic) ──────────────────────────────
 
     // Insert chunks AND index them in FTS5 in one transaction.
@@ -96,6 +133,63 @@ public:
     // Remove all chunks and FTS5 entries for an item.
     bool deleteChunksForItem(int64_t itemId, const QString& filePath);
 
+    // ── Item attributes ─────────────────────────────────────
+
+    // FTS5 chunk_id of the row holding an item's searchable attribute values.
+    static constexpr const char* kAttributesChunkId = "attributes";
+
+    // Replace an item's attributes. Searchable values are indexed in FTS5
+    // as one extra row after the item's chunks; insertChunks() re-appends
+    // it. A no-op when the stored set is already identical.
+    bool replaceItemAttributes(int64_t itemId,
+                               const QString& fileName,
+                               const QString& filePath,
+                               const std::vector<ItemAttribute>& attributes);
+
+    // Stored attributes of an item, in the order they were written.
+    std::vector<ItemAttribute> getItemAttributes(int64_t itemId);
+
+    // First stored value of one attribute of an item; empty when unset.
+    QString getItemAttributeValue(int64_t itemId, const QString& name);
+
+    // ── Tags ────────────────────────────────────────────────
+
+    // tags.source of tags read from Finder.
+    static constexpr const char* kFinderTagSource = "finder";
+    // tags.source of tags merged in from another Mac's copy of the item
+    // (MetadataMerge).
+    static constexpr const char* kSyncTagSource = "sync";
+
+    // Replace the tags an item carries from `source`; other sources' tags
+    // are left alone.
+    bool replaceItemTags(int64_t itemId, const QString& source, const QStringList& tags);
+
+    // All tags of an item, in the order they were written.
+    QStringList getItemTags(int64_t itemId);
+
+    struct TagCount {
+        QString tag;
+        int count = 0;
+    };
+
+    // How many of the given items carry each tag, most common first.
+    // Tags differing only in case are counted together.
+    std::vector<TagCount> countTags(const std::vector<int64_t>& itemIds);
+
+    struct AttributeCount {
+        QString value;
+        int count = 0;
+    };
+
+    // How many of the given items carry each value of attribute `name`,
+    // most common first. Backs facets such as Mail's mailboxes.
+    std::vector<AttributeCount> countAttributeValues(const std::vector<int64_t>& itemIds,
+                                                     const QString& name);
+
+    // Items passing `options`, most recently modified first. Backs tag-only
+    // queries (`tag:red`), which have no text to match.
+    std::vector<ItemRow> listFilteredItems(const SearchOptions& options, int limit);
+
     // ── FTS5 Search ─────────────────────────────────────────
 
     struct FtsHit {
@@ -137,10 +231,65 @@ public:
         const QString& query, int limit, bool relaxed,
         const SearchOptions& options = {});
 
+    struct ExportRow {
+        int64_t fileId = 0;
+        QString path;
+        QString name;
+        QString kind;
+        int64_t size = 0;
+        double createdAt = 0.0;
+        double modifiedAt = 0.0;
+        double indexedAt = 0.0;
+        QStringList matchedFields;   // "name", "path", "content"
+    };
+
+    struct ExportPage {
+        std::vector<ExportRow> rows;
+        std::optional<int64_t> nextAfterRowid;  // unset when the export is done
+    };
+
+    // Every FTS match of `query` (unranked), one row per item, in index
+    // order. Pages are keyset-positioned on the FTS rowid, so walking a
+    // 500k-hit export costs the same per page as the first one.
+    ExportPage exportMatches(const QString& query, bool relaxed, const SearchOptions& options,
+                             int limit, int64_t afterRowid = 0);
+
     std::vector<NameHit> searchByNameFuzzy(const QString& query, int limit = 20);
     std::vector<NameHit> searchByNameFuzzy(const QString& query, int limit,
                                            const SearchOptions& options);
 
+    // Prefix completion over item names, most-opened first. Backs the
+    // `suggest` IPC method for as-you-type completion.
+    std::vector<NameHit> suggestByNamePrefix(const QString& prefix, int limit = 10);
+
+    struct PathCandidate {
+        int64_t fileId = 0;
+        QString path;
+        QString kind;
+        int openCount = 0;
+    };
+
+    // Keyset position in a completePaths() feed.
+    struct PathCompletionCursor {
+        int frequentTaken = -1;    // -1 until the opened-items head was returned
+        QString afterPath;
+    };
+
+    struct PathCompletionPage {
+        std::vector<PathCandidate> candidates;
+        std::optional<PathCompletionCursor> next;  // unset when the feed is done
+    };
+
+    // Path completion feed for shells and fzf. `prefix` starting with '/'
+    // matches paths by byte prefix (uses the path index); one containing
+    // '/' matches a path fragment at a component boundary; anything else
+    // is a case-insensitive name prefix; empty matches everything.
+    // The first page leads with opened items (open count, then recency);
+    // later pages walk the remaining matches in path order, so paging
+    // stays cheap on large indexes.
+    PathCompletionPage completePaths(const QString& prefix, int limit,
+                                     const PathCompletionCursor& cursor = {});
+
     // ── Failures ────────────────────────────────────────────
 
     bool recordFailure(int64_t itemId, const QString& stage,
@@ -149,8 +298,37 @@ public:
 
     // ── Feedback ──────────────────────────────────────────
 
+    // `timestamp` 0 records it as of now.
     bool recordFeedback(int64_t itemId, const QString& action,
-                        const QString& query, int position);
+                        const QString& query, int position, double timestamp = 0.0);
+
+    // One feedback row with its item, for "recent actions".
+    struct ActionRow {
+        int64_t id = 0;
+        int64_t itemId = 0;
+        QString path;
+        QString name;
+        QString action;
+        QString query;
+        int position = 0;
+        double timestamp = 0.0;
+    };
+
+    // Newest first. `actions` limits the action names (all when empty);
+    // itemId > 0 limits to one item.
+    std::vector<ActionRow> getRecentActions(int limit, const QStringList& actions = {},
+                                            int64_t itemId = 0);
+    std::optional<ActionRow> getAction(int64_t actionId);
+
+    // Per item: reveal and copyPath actions since `sinceEpoch`. Opens are
+    // left out; the interaction boost already follows them.
+    struct ActionCounts {
+        int reveals = 0;
+        int copies = 0;
+        double lastActedAt = 0.0;
+    };
+    std::unordered_map<int64_t, ActionCounts> getActionCountsBatch(
+        const std::vector<int64_t>& itemIds, double sinceEpoch);
 
     // ── Frequencies ─────────────────────────────────────────
 
@@ -168,6 +346,32 @@ public:
     std::unordered_map<int64_t, FrequencyRow> getFrequenciesBatch(
         const std::vector<int64_t>& itemIds);
 
+    // Set an item's open count and last open outright; total_interactions
+    // is left as it is.
+    bool setFrequency(int64_t itemId, int openCount, double lastOpenedAt);
+
+    // ── User signals ──────────────────────────────────────
+
+    // What the user did to an item, the signals peer sync merges
+    // (MetadataMerge): its tags of every source, whether it is pinned and
+    // when that last changed, and its frecency.
+    struct ItemSignals {
+        QStringList tags;
+        QStringList syncedTags;     // those of `tags` merged in (kSyncTagSource)
+        bool pinned = false;
+        double pinChangedAt = 0.0;  // the latest pin or unpin; 0 for neither
+        int openCount = 0;
+        double lastOpenedAt = 0.0;
+    };
+    // Signals of the items with ids in [firstId, lastId]. Items with none
+    // are left out.
+    std::unordered_map<int64_t, ItemSignals> getItemSignalsInIdRange(int64_t firstId,
+                                                                     int64_t lastId);
+    // Pin or unpin an item as of `changedAt`. Recorded as the pin or unpin
+    // feedback row is_pinned is aggregated from, so a later local change
+    // still wins.
+    bool setItemPinned(int64_t itemId, bool pinned, double changedAt);
+
     // ── Feedback aggregation ──────────────────────────────
 
     // Aggregate feedback into frequencies table
@@ -191,6 +395,78 @@ public:
 
     IndexHealth getHealth();
 
+    // Row count of the items table only; cheap enough to poll.
+    int64_t itemCount();
+
+    // Segments of the FTS5 index across all levels, read from its
+    // structure record; -1 when that cannot be read. Grows as writes land
+    // and drops to one after optimizeFts5().
+    int ftsSegmentCount();
+
+    struct FailureCount {
+        QString kind;
+        bool expected = false;  // an expected gap, as getHealth() counts them
+        int64_t count = 0;
+    };
+
+    // Extraction failures per item kind, split into expected gaps
+    // (unsupported, offline, encrypted, ...) and the rest.
+    std::vector<FailureCount> extractionFailureCounts();
+
+    struct SizeBucket {
+        QString name;
+        int64_t items = 0;
+        int64_t bytes = 0;
+    };
+
+    // Where the index bytes go. `tables` are measured page by page with
+    // dbstat (indexes and FTS5 shadow tables count toward their table).
+    // The rest split the bytes of the per-file tables (items, content,
+    // search_index) in proportion to the text each file contributes, so
+    // they are estimates that add up to fileBytes. Every list is largest
+    // first; files past the top `maxBuckets` types or directories are
+    // summed into "(other)".
+    struct SizeBreakdown {
+        int64_t totalBytes = 0;  // live pages, as getHealth() reports them
+        int64_t fileBytes = 0;   // totalBytes when dbstat is unavailable
+        bool measured = false;   // false: dbstat unavailable, tables empty
+        std::vector<SizeBucket> tables;
+        std::vector<SizeBucket> fields;       // file_name, file_path, content
+        std::vector<SizeBucket> fileTypes;    // extension, "(none)" without one
+        std::vector<SizeBucket> directories;  // see breakdownDirectory()
+    };
+
+    SizeBreakdown sizeBreakdown(const QStringList& roots, int maxBuckets = 20);
+
+    // The directory a file counts toward: the first level below the
+    // longest root that holds it ("/Users/me/Documents/a/b.txt" under root
+    // "/Users/me" is "/Users/me/Documents"), the root itself for files
+    // directly in it, and the parent directory outside every root.
+    static QString breakdownDirectory(const QString& path, const QStringList& roots);
+
+    // ── Tombstones ──────────────────────────────────────────
+
+    // Every deleted item leaves its id in item_tombstones (a trigger), so
+    // a peer sync delta can carry deletions. Rows are numbered in delete
+    // order; compact() prunes those older than kTombstoneRetentionDays.
+    static constexpr int kTombstoneRetentionDays = 30;
+
+    struct Tombstone {
+        int64_t seq = 0;
+        int64_t itemId = 0;
+    };
+    // Tombstones after `seq`, oldest first, at most `limit`.
+    std::vector<Tombstone> getTombstonesAfter(int64_t seq, int limit);
+    // The newest tombstone's seq, 0 before the first delete. Pruning does
+    // not lower it.
+    int64_t lastTombstoneSeq();
+    // The newest seq pruned so far: a reader that last saw an older seq
+    // has missed deletes.
+    int64_t prunedTombstoneSeq();
+    // Drop tombstones of deletes before `before` (seconds since the
+    // epoch). Returns the number removed.
+    int64_t pruneTombstones(double before);
+
     // ── Transactions ────────────────────────────────────────
 
     bool beginTransaction();
@@ -200,7 +476,8 @@ public:
     // ── Bulk operations ────────────────────────────────────
 
     // Delete ALL indexed data (items, content, FTS5, failures,
-    // frequencies, feedback). Used by Pipeline::rebuildAll().
+    // frequencies, feedback). Used by Pipeline::rebuildAll(). Tombstones
+    // are pruned too: a reader catches up from segment digests instead.
     bool deleteAll();
 
     // ── Maintenance ─────────────────────────────────────────
@@ -208,6 +485,42 @@ public:
     bool optimizeFts5();
     bool vacuum();
 
+    // Items deleted since the last complete compact(). Until it runs their
+    // rows linger in free pages and their postings in FTS5 segments.
+    int64_t pendingCompactionDeletes();
+
+    // PRAGMA freelist_count: 0 right after a VACUUM.
+    int64_t freePageCount();
+
+    // Merge FTS5 segments, dropping deleted postings; VACUUM away the free
+    // pages that still hold deleted rows; truncate the WAL. When all three
+    // succeed, resets pendingCompactionDeletes() and records
+    // last_vacuum_at. Prunes expired tombstones first. Must run outside a
+    // transaction.
+    struct CompactionSummary {
+        int64_t compactedDeletes = 0;
+        bool ftsOptimized = false;
+        bool vacuumed = false;
+        bool walCheckpointed = false;
+        int64_t freePages = -1;
+        int64_t bytesBefore = 0;
+        int64_t bytesAfter = 0;
+
+        bool complete() const { return ftsOptimized && vacuumed && walCheckpointed; }
+    };
+    CompactionSummary compact();
+
+    // While enabled, deleted rows are overwritten rather than only
+    // unlinked: freed pages are zeroed (PRAGMA secure_delete) and FTS5
+    // removes a row's postings from its segments instead of recording a
+    // tombstone. Slower, so only privacy purges turn it on.
+    bool setSecureDelete(bool enabled);
+
+    // Copy the WAL into the database and truncate it, so overwritten
+    // pages do not survive in old WAL frames. Fails inside a transaction
+    // or while another connection holds a read snapshot.
+    bool checkpointWal();
+
     // Returns true if database passes PRAGMA integrity_check
     bool integrityCheck() const;
 
@@ -218,6 +531,10 @@ public:
     // Force a WAL checkpoint (PASSIVE mode — does not block readers).
     bool walCheckpoint();
 
+    // Statements running once deadlineAtMs (epoch ms) has passed are aborted
+    // with SQLITE_INTERRUPT; 0 clears. Bounds a search to its request deadline.
+    void setQueryDeadline(int64_t deadlineAtMs);
+
     // Raw handle for tests
     sqlite3* rawDb() const { return m_db; }
 
@@ -226,7 +543,13 @@ private:
     static QString sanitizeFtsQueryStrict(const QString& raw);
     static QString sanitizeFtsQueryRelaxed(const QString& raw);
     bool init(const QString& dbPath);
+    bool initReadOnly(const QString& dbPath);
     bool execSql(const char* sql);
+    // First column of a single-row PRAGMA or query; -1 on failure.
+    int64_t queryInt(const char* sql);
+    // Append the FTS5 attributes row for an item's searchable attributes.
+    bool indexItemAttributes(int64_t itemId, const QString& fileName,
+                             const QString& filePath);
 
     sqlite3* m_db = nullptr;
 };
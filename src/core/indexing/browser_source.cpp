@@ -0,0 +1,373 @@
+#include "core/indexing/browser_source.h"
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QHash>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QSet>
+#include <QTemporaryDir>
+#include <QUrl>
+
+#include <sqlite3.h>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// Safari counts seconds from 2001-01-01, Chromium microseconds from
+// 1601-01-01.
+constexpr double kSafariEpochOffset = 978307200.0;
+constexpr double kChromiumEpochOffset = 11644473600.0;
+
+double fromChromiumTime(double micros)
+{
+    return micros > 0.0 ? micros / 1000000.0 - kChromiumEpochOffset : 0.0;
+}
+
+struct ChromiumBrowser {
+    const char* name;
+    const char* directory;  // under ~/Library/Application Support
+};
+
+constexpr ChromiumBrowser kChromiumBrowsers[] = {
+    {"Chrome", "Google/Chrome"},
+    {"Chromium", "Chromium"},
+    {"Brave", "BraveSoftware/Brave-Browser"},
+    {"Edge", "Microsoft Edge"},
+    {"Vivaldi", "Vivaldi"},
+    {"Arc", "Arc/User Data"},
+};
+
+QString columnText(sqlite3_stmt* stmt, int column)
+{
+    const auto* text = reinterpret_cast<const char*>(sqlite3_column_text(stmt, column));
+    return text ? QString::fromUtf8(text) : QString();
+}
+
+// The browser holds the database open and may be writing to it: read a copy,
+// with its write-ahead log so the latest visits are in.
+std::optional<std::vector<BrowserPage>> queryHistoryCopy(const QString& path, const char* sql,
+                                                         double sinceArgument, int limit,
+                                                         bool chromiumTimes, QString* error)
+{
+    if (!QFileInfo::exists(path)) {
+        *error = QStringLiteral("%1 does not exist").arg(path);
+        return std::nullopt;
+    }
+    QTemporaryDir scratch;
+    if (!scratch.isValid()) {
+        *error = QStringLiteral("No temporary directory for %1").arg(path);
+        return std::nullopt;
+    }
+    const QString copy = scratch.filePath(QFileInfo(path).fileName());
+    if (!QFile::copy(path, copy)) {
+        *error = QStringLiteral("%1 could not be read").arg(path);
+        return std::nullopt;
+    }
+    if (QFileInfo::exists(path + QStringLiteral("-wal"))) {
+        QFile::copy(path + QStringLiteral("-wal"), copy + QStringLiteral("-wal"));
+    }
+
+    sqlite3* db = nullptr;
+    if (sqlite3_open_v2(copy.toUtf8().constData(), &db, SQLITE_OPEN_READWRITE, nullptr)
+        != SQLITE_OK) {
+        *error = QString::fromUtf8(db ? sqlite3_errmsg(db) : "sqlite3_open_v2 failed");
+        sqlite3_close(db);
+        return std::nullopt;
+    }
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(db, sql, -1, &stmt, nullptr) != SQLITE_OK) {
+        // Not the schema we know; a newer browser release.
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+        sqlite3_close(db);
+        return std::nullopt;
+    }
+    sqlite3_bind_double(stmt, 1, sinceArgument);
+    sqlite3_bind_int(stmt, 2, limit);
+
+    std::vector<BrowserPage> pages;
+    int rc = SQLITE_ROW;
+    while ((rc = sqlite3_step(stmt)) == SQLITE_ROW) {
+        BrowserPage page;
+        page.url = columnText(stmt, 0);
+        page.title = columnText(stmt, 1).trimmed();
+        const double visited = sqlite3_column_double(stmt, 2);
+        page.lastVisitedAt = chromiumTimes ? fromChromiumTime(visited)
+                                           : visited + kSafariEpochOffset;
+        page.visitCount = sqlite3_column_int(stmt, 3);
+        pages.push_back(std::move(page));
+    }
+    if (rc != SQLITE_DONE) {
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+    }
+    sqlite3_finalize(stmt);
+    sqlite3_close(db);
+    if (rc != SQLITE_DONE) {
+        return std::nullopt;
+    }
+    return pages;
+}
+
+void collectChromiumBookmarks(const QJsonObject& node, const QString& folder,
+                              std::vector<BrowserPage>* out)
+{
+    const QString type = node.value(QStringLiteral("type")).toString();
+    if (type == QLatin1String("url")) {
+        BrowserPage page;
+        page.url = node.value(QStringLiteral("url")).toString();
+        page.title = node.value(QStringLiteral("name")).toString().trimmed();
+        page.bookmarked = true;
+        page.bookmarkFolder = folder;
+        out->push_back(std::move(page));
+        return;
+    }
+    const QString name = node.value(QStringLiteral("name")).toString().trimmed();
+    const QString path = folder.isEmpty() ? name
+                         : name.isEmpty() ? folder
+                                          : folder + QLatin1Char('/') + name;
+    for (const QJsonValue& child : node.value(QStringLiteral("children")).toArray()) {
+        collectChromiumBookmarks(child.toObject(), path, out);
+    }
+}
+
+QString hostOf(const QString& url)
+{
+    QString host = QUrl(url).host();
+    if (host.startsWith(QLatin1String("www."))) {
+        host = host.mid(4);
+    }
+    return host;
+}
+
+// "example.com · Bookmarks Bar/Work" or "example.com · Safari, Chrome".
+QString descriptionLine(const BrowserPage& page)
+{
+    QStringList parts = {hostOf(page.url)};
+    if (page.bookmarked) {
+        parts.append(page.bookmarkFolder.isEmpty() ? QStringLiteral("Bookmark")
+                                                   : page.bookmarkFolder);
+    } else if (!page.browsers.isEmpty()) {
+        parts.append(page.browsers.join(QStringLiteral(", ")));
+    }
+    parts.removeAll(QString());
+    return parts.join(QStringLiteral(" · "));
+}
+
+DonatedItem toItem(const BrowserPage& page, const QString& identifier)
+{
+    const QUrl url(page.url);
+    DonatedItem item;
+    item.name = page.title.isEmpty()
+        ? hostOf(page.url) + url.path(QUrl::FullyDecoded)
+        : page.title;
+    item.path = SpotlightDonation::itemPath(QString::fromLatin1(BrowserSource::kBundleIdentifier),
+                                            identifier);
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(BrowserSource::kBundleIdentifier));
+
+    const QString description = descriptionLine(page);
+    QStringList lines = {item.name, page.url, description};
+    lines.removeDuplicates();
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    // A page is re-written when it is visited again, not on every sync.
+    item.createdAt = page.lastVisitedAt;
+    item.modifiedAt = page.lastVisitedAt;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(BrowserSource::kContentType), false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   description, true});
+    for (const QString& browser : page.browsers) {
+        raw.push_back({QString::fromLatin1(BrowserSource::kBrowsersAttribute), browser, false});
+    }
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute), page.url, false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+std::vector<BrowserProfile> BrowserSource::profiles(const QString& homePath)
+{
+    std::vector<BrowserProfile> out;
+    const QDir safari(homePath + QStringLiteral("/Library/Safari"));
+    if (safari.exists(QStringLiteral("History.db"))
+        || safari.exists(QStringLiteral("Bookmarks.plist"))) {
+        BrowserProfile profile;
+        profile.browser = QStringLiteral("Safari");
+        profile.engine = BrowserProfile::Engine::Safari;
+        profile.historyPath = safari.filePath(QStringLiteral("History.db"));
+        profile.bookmarksPath = safari.filePath(QStringLiteral("Bookmarks.plist"));
+        out.push_back(std::move(profile));
+    }
+
+    const QString support = homePath + QStringLiteral("/Library/Application Support/");
+    for (const ChromiumBrowser& browser : kChromiumBrowsers) {
+        const QDir root(support + QString::fromLatin1(browser.directory));
+        if (!root.exists()) {
+            continue;
+        }
+        QStringList names = root.entryList({QStringLiteral("Profile *")}, QDir::Dirs);
+        names.prepend(QStringLiteral("Default"));
+        for (const QString& name : names) {
+            const QDir dir(root.filePath(name));
+            if (!dir.exists(QStringLiteral("History")) && !dir.exists(QStringLiteral("Bookmarks"))) {
+                continue;
+            }
+            BrowserProfile profile;
+            profile.browser = QString::fromLatin1(browser.name);
+            profile.engine = BrowserProfile::Engine::Chromium;
+            profile.historyPath = dir.filePath(QStringLiteral("History"));
+            profile.bookmarksPath = dir.filePath(QStringLiteral("Bookmarks"));
+            out.push_back(std::move(profile));
+        }
+    }
+    return out;
+}
+
+std::optional<std::vector<BrowserPage>> BrowserSource::readHistory(BrowserProfile::Engine engine,
+                                                                   const QString& path,
+                                                                   double since, int limit,
+                                                                   QString* error)
+{
+    if (engine == BrowserProfile::Engine::Safari) {
+        // The title is that of the latest visit: SQLite takes bare columns
+        // from the row MAX() picked.
+        return queryHistoryCopy(
+            path,
+            "SELECT i.url, v.title, MAX(v.visit_time), i.visit_count"
+            " FROM history_items i JOIN history_visits v ON v.history_item = i.id"
+            " WHERE v.visit_time >= ?1 AND v.load_successful"
+            " GROUP BY i.id ORDER BY MAX(v.visit_time) DESC LIMIT ?2",
+            since - kSafariEpochOffset, limit, false, error);
+    }
+    return queryHistoryCopy(
+        path,
+        "SELECT url, title, last_visit_time, visit_count FROM urls"
+        " WHERE last_visit_time >= ?1 AND hidden = 0"
+        " ORDER BY last_visit_time DESC LIMIT ?2",
+        (since + kChromiumEpochOffset) * 1000000.0, limit, true, error);
+}
+
+std::optional<std::vector<BrowserPage>> BrowserSource::parseChromiumBookmarks(
+    const QByteArray& json)
+{
+    const QJsonDocument document = QJsonDocument::fromJson(json);
+    const QJsonObject roots = document.object().value(QStringLiteral("roots")).toObject();
+    if (roots.isEmpty()) {
+        return std::nullopt;
+    }
+    std::vector<BrowserPage> pages;
+    for (const QString& root : {QStringLiteral("bookmark_bar"), QStringLiteral("other"),
+                                QStringLiteral("synced")}) {
+        collectChromiumBookmarks(roots.value(root).toObject(), QString(), &pages);
+    }
+    return pages;
+}
+
+QString BrowserSource::normalizeUrl(const QString& url)
+{
+    if (url.size() > kMaxUrlChars) {
+        return QString();
+    }
+    QUrl parsed(url.trimmed(), QUrl::StrictMode);
+    const QString scheme = parsed.scheme().toLower();
+    if (!parsed.isValid() || parsed.host().isEmpty()
+        || (scheme != QLatin1String("http") && scheme != QLatin1String("https"))) {
+        return QString();
+    }
+    parsed.setScheme(scheme);
+    parsed.setHost(parsed.host().toLower());
+    parsed.setUserInfo(QString());
+    parsed.setFragment(QString());
+    if ((scheme == QLatin1String("http") && parsed.port() == 80)
+        || (scheme == QLatin1String("https") && parsed.port() == 443)) {
+        parsed.setPort(-1);
+    }
+    if (parsed.path() == QLatin1String("/")) {
+        parsed.setPath(QString());
+    }
+    return parsed.toString(QUrl::FullyEncoded);
+}
+
+std::vector<BrowserPage> BrowserSource::merge(const std::vector<BrowserPage>& pages)
+{
+    std::vector<BrowserPage> out;
+    QHash<QString, size_t> byUrl;
+    for (const BrowserPage& page : pages) {
+        const QString key = normalizeUrl(page.url);
+        if (key.isEmpty()) {
+            continue;
+        }
+        const auto it = byUrl.constFind(key);
+        if (it == byUrl.constEnd()) {
+            byUrl.insert(key, out.size());
+            BrowserPage first = page;
+            first.url = key;
+            first.browsers.removeDuplicates();
+            out.push_back(std::move(first));
+            continue;
+        }
+        BrowserPage& merged = out[it.value()];
+        if (page.lastVisitedAt > merged.lastVisitedAt) {
+            merged.lastVisitedAt = page.lastVisitedAt;
+            if (!page.title.isEmpty()) {
+                merged.title = page.title;
+            }
+        } else if (merged.title.isEmpty()) {
+            merged.title = page.title;
+        }
+        merged.visitCount += page.visitCount;
+        if (page.bookmarked && !merged.bookmarked) {
+            merged.bookmarked = true;
+            merged.bookmarkFolder = page.bookmarkFolder;
+        }
+        for (const QString& browser : page.browsers) {
+            if (!merged.browsers.contains(browser)) {
+                merged.browsers.append(browser);
+            }
+        }
+    }
+    return out;
+}
+
+std::vector<DonatedItem> BrowserSource::items(const std::vector<BrowserPage>& pages)
+{
+    std::vector<DonatedItem> out;
+    out.reserve(pages.size());
+    for (const BrowserPage& page : merge(pages)) {
+        out.push_back(toItem(page, page.url));
+    }
+    return out;
+}
+
+QStringList BrowserSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                            const std::vector<BrowserPage>& pages)
+{
+    QSet<QString> current;
+    for (const BrowserPage& page : pages) {
+        const QString key = normalizeUrl(page.url);
+        if (!key.isEmpty()) {
+            current.insert(key);
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+} // namespace bs
@@ -0,0 +1,93 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One browser profile's history and bookmark files. Either may be missing.
+struct BrowserProfile {
+    enum class Engine { Safari, Chromium };
+
+    QString browser;        // "Safari", "Chrome", "Brave", ...
+    Engine engine = Engine::Chromium;
+    QString historyPath;    // History.db (Safari) or History (Chromium)
+    QString bookmarksPath;  // Bookmarks.plist (Safari) or Bookmarks (Chromium JSON)
+};
+
+// A page from history or bookmarks, possibly seen by several browsers.
+struct BrowserPage {
+    QString url;
+    QString title;
+    double lastVisitedAt = 0.0;  // epoch seconds; 0 for a bookmark never visited
+    int visitCount = 0;
+    bool bookmarked = false;
+    QString bookmarkFolder;      // "Bookmarks Bar/Work"
+    QStringList browsers;
+};
+
+// BrowserSource -- the opt-in source that indexes pages visited in Safari and
+// Chromium-family browsers (Chrome, Brave, Edge, Vivaldi, Arc, Chromium) and
+// their bookmarks, so a page seen last week turns up by its title or URL. A
+// page open in several browsers is one item, keyed by its normalized URL, and
+// is written as a donation from kBundleIdentifier (see SpotlightDonation):
+// only pages visited since the last sync are written again.
+//
+// Browsers keep their databases open and locked, so history is read from a
+// copy. Safari's files need Full Disk Access.
+class BrowserSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.betterspotlight.browser";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_browser_history";
+    static constexpr const char* kContentType = "public.url";
+    static constexpr const char* kBrowsersAttribute = "browsers";
+
+    // Pages visited this long ago and earlier are left out (bookmarks are
+    // kept), at most kMaxPagesPerProfile of them per profile, newest first.
+    static constexpr int kHistoryDays = 90;
+    static constexpr int kMaxPagesPerProfile = 5000;
+    static constexpr int kMaxUrlChars = 2048;
+
+    // The profiles of every supported browser installed for `homePath`.
+    static std::vector<BrowserProfile> profiles(const QString& homePath);
+
+    // Pages the history database of `engine` at `path` has visited since
+    // `since` (epoch seconds), newest first, or nullopt with *error when it
+    // cannot be read. The browser may have it open.
+    static std::optional<std::vector<BrowserPage>> readHistory(BrowserProfile::Engine engine,
+                                                               const QString& path,
+                                                               double since, int limit,
+                                                               QString* error);
+
+    // Bookmarks from a Chromium Bookmarks file; nullopt for anything else.
+    static std::optional<std::vector<BrowserPage>> parseChromiumBookmarks(const QByteArray& json);
+    // Bookmarks and the Reading List from Safari's Bookmarks.plist. Always
+    // nullopt off macOS.
+    static std::optional<std::vector<BrowserPage>> readSafariBookmarks(const QString& path);
+
+    // The key pages are deduplicated by: http(s) only, lowercase scheme and
+    // host, no credentials, fragment, default port or bare trailing "/".
+    // Empty for anything else.
+    static QString normalizeUrl(const QString& url);
+
+    // One page per normalized URL: the latest visit and its title, the
+    // visit counts added up, bookmarked if any entry is.
+    static std::vector<BrowserPage> merge(const std::vector<BrowserPage>& pages);
+
+    // Merged pages as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations.
+    static std::vector<DonatedItem> items(const std::vector<BrowserPage>& pages);
+
+    // The indexed identifiers no longer among the merged `pages`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<BrowserPage>& pages);
+};
+
+} // namespace bs
@@ -0,0 +1,85 @@
+#include "core/indexing/browser_source.h"
+
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(id value)
+{
+    return [value isKindOfClass:[NSString class]]
+        ? QString::fromUtf8([static_cast<NSString*>(value) UTF8String])
+        : QString();
+}
+
+// Leaves are WebBookmarkTypeLeaf with URLString and URIDictionary.title;
+// lists carry Title and Children. The Reading List is the list titled
+// com.apple.ReadingList.
+void collectSafariBookmarks(NSDictionary* node, const QString& folder,
+                            std::vector<BrowserPage>* out)
+{
+    const QString type = toQString(node[@"WebBookmarkType"]);
+    if (type == QLatin1String("WebBookmarkTypeLeaf")) {
+        BrowserPage page;
+        page.url = toQString(node[@"URLString"]);
+        NSDictionary* uri = node[@"URIDictionary"];
+        if ([uri isKindOfClass:[NSDictionary class]]) {
+            page.title = toQString(uri[@"title"]).trimmed();
+        }
+        page.bookmarked = true;
+        page.bookmarkFolder = folder;
+        out->push_back(std::move(page));
+        return;
+    }
+    if (type != QLatin1String("WebBookmarkTypeList")) {
+        return;
+    }
+    QString name = toQString(node[@"Title"]).trimmed();
+    if (name == QLatin1String("com.apple.ReadingList")) {
+        name = QStringLiteral("Reading List");
+    } else if (name == QLatin1String("BookmarksBar")) {
+        name = QStringLiteral("Favorites");
+    } else if (name == QLatin1String("BookmarksMenu")) {
+        name = QStringLiteral("Bookmarks Menu");
+    }
+    const QString path = folder.isEmpty() ? name
+                         : name.isEmpty() ? folder
+                                          : folder + QLatin1Char('/') + name;
+    NSArray* children = node[@"Children"];
+    if (![children isKindOfClass:[NSArray class]]) {
+        return;
+    }
+    for (id child in children) {
+        if ([child isKindOfClass:[NSDictionary class]]) {
+            collectSafariBookmarks(child, path, out);
+        }
+    }
+}
+
+} // namespace
+
+std::optional<std::vector<BrowserPage>> BrowserSource::readSafariBookmarks(const QString& path)
+{
+    std::vector<BrowserPage> pages;
+    @autoreleasepool {
+        NSURL* url = [NSURL fileURLWithPath:path.toNSString()];
+        NSDictionary* root = [NSDictionary dictionaryWithContentsOfURL:url error:nil];
+        if (!root) {
+            // Missing, or unreadable without Full Disk Access.
+            return std::nullopt;
+        }
+        // The root list itself has no title worth showing.
+        NSArray* children = root[@"Children"];
+        if ([children isKindOfClass:[NSArray class]]) {
+            for (id child in children) {
+                if ([child isKindOfClass:[NSDictionary class]]) {
+                    collectSafariBookmarks(child, QString(), &pages);
+                }
+            }
+        }
+    }
+    return pages;
+}
+
+} // namespace bs
@@ -0,0 +1,10 @@
+#include "core/indexing/browser_source.h"
+
+namespace bs {
+
+std::optional<std::vector<BrowserPage>> BrowserSource::readSafariBookmarks(const QString&)
+{
+    return std::nullopt;
+}
+
+} // namespace bs
@@ -0,0 +1,227 @@
+#include "core/indexing/calendar_source.h"
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QRegularExpression>
+#include <QSet>
+#include <QUrl>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+void appendUnique(QStringList* list, const QString& value)
+{
+    const QString trimmed = value.trimmed();
+    if (!trimmed.isEmpty() && !list->contains(trimmed, Qt::CaseInsensitive)) {
+        list->append(trimmed);
+    }
+}
+
+QString epochString(double epoch)
+{
+    return QString::number(static_cast<qint64>(epoch));
+}
+
+// "2026-10-14 09:30-10:00", "2026-10-14" for all-day events, with the end
+// date when it is another day.
+QString whenLine(const CalendarEvent& event)
+{
+    const QDateTime start = QDateTime::fromSecsSinceEpoch(static_cast<qint64>(event.start));
+    // An all-day event ends at midnight after its last day.
+    const QDateTime end = QDateTime::fromSecsSinceEpoch(
+        static_cast<qint64>(event.allDay ? event.end - 1.0 : event.end));
+    const QString startDay = start.toString(QStringLiteral("yyyy-MM-dd"));
+    if (event.allDay) {
+        return end.date() > start.date()
+            ? QStringLiteral("%1 - %2").arg(startDay, end.toString(QStringLiteral("yyyy-MM-dd")))
+            : startDay;
+    }
+    const QString startTime = start.toString(QStringLiteral("HH:mm"));
+    if (end <= start) {
+        return startDay + QLatin1Char(' ') + startTime;
+    }
+    if (end.date() != start.date()) {
+        return QStringLiteral("%1 %2 - %3").arg(
+            startDay, startTime, end.toString(QStringLiteral("yyyy-MM-dd HH:mm")));
+    }
+    return QStringLiteral("%1 %2-%3").arg(startDay, startTime,
+                                           end.toString(QStringLiteral("HH:mm")));
+}
+
+// "2026-10-14 09:30-10:00 · Work · Room 4".
+QString descriptionLine(const CalendarEvent& event)
+{
+    QStringList parts = {whenLine(event)};
+    appendUnique(&parts, event.calendarName);
+    appendUnique(&parts, event.location);
+    return parts.join(QStringLiteral(" · "));
+}
+
+DonatedItem toItem(const CalendarEvent& event, const QString& homePath)
+{
+    QStringList people;
+    appendUnique(&people, event.organizer);
+    for (const QString& attendee : event.attendees) {
+        appendUnique(&people, attendee);
+    }
+    const QStringList linked = CalendarSource::linkedDocuments(event, homePath);
+
+    DonatedItem item;
+    item.name = event.title.trimmed();
+    item.path = SpotlightDonation::itemPath(QString::fromLatin1(CalendarSource::kBundleIdentifier),
+                                            event.identifier());
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(CalendarSource::kBundleIdentifier));
+
+    const QString description = descriptionLine(event);
+    QStringList lines = {item.name, description};
+    lines.append(people);
+    appendUnique(&lines, event.notes);
+    appendUnique(&lines, event.url);
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    // Dated by the start, so the hash stays put while the event does.
+    item.createdAt = event.start;
+    item.modifiedAt = event.start;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(CalendarSource::kContentType), false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   description, true});
+    raw.push_back({QString::fromLatin1(CalendarSource::kStartAttribute),
+                   epochString(event.start), false});
+    raw.push_back({QString::fromLatin1(CalendarSource::kEndAttribute),
+                   epochString(std::max(event.end, event.start)), false});
+    for (const QString& person : people) {
+        raw.push_back({QString::fromLatin1(CalendarSource::kAttendeesAttribute), person, true});
+    }
+    for (const QString& path : linked) {
+        raw.push_back({QString::fromLatin1(CalendarSource::kLinkedDocumentAttribute), path,
+                       false});
+    }
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                   CalendarSource::eventUrl(event), false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+QString trimTrailingPunctuation(QString path)
+{
+    static const QString kTrailing = QStringLiteral(".,;:!?)]}'\"");
+    while (!path.isEmpty() && kTrailing.contains(path.back())) {
+        path.chop(1);
+    }
+    return path;
+}
+
+} // namespace
+
+QString CalendarEvent::identifier() const
+{
+    if (!recurring) {
+        return eventIdentifier;
+    }
+    return eventIdentifier + QLatin1Char('@') + epochString(start);
+}
+
+QString CalendarSource::accessToString(Access access)
+{
+    switch (access) {
+    case Access::Authorized:
+        return QStringLiteral("authorized");
+    case Access::NotDetermined:
+        return QStringLiteral("notDetermined");
+    case Access::Denied:
+        return QStringLiteral("denied");
+    case Access::Unavailable:
+        break;
+    }
+    return QStringLiteral("unavailable");
+}
+
+std::vector<DonatedItem> CalendarSource::items(const std::vector<CalendarEvent>& events)
+{
+    const QString homePath = QDir::homePath();
+    std::vector<DonatedItem> out;
+    out.reserve(events.size());
+    QSet<QString> seen;
+    for (const CalendarEvent& event : events) {
+        const QString identifier = event.identifier();
+        if (event.eventIdentifier.trimmed().isEmpty() || event.title.trimmed().isEmpty()
+            || seen.contains(identifier)) {
+            continue;
+        }
+        seen.insert(identifier);
+        out.push_back(toItem(event, homePath));
+    }
+    return out;
+}
+
+QStringList CalendarSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                             const std::vector<CalendarEvent>& events)
+{
+    QSet<QString> current;
+    for (const CalendarEvent& event : events) {
+        if (!event.title.trimmed().isEmpty()) {
+            current.insert(event.identifier());
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+QStringList CalendarSource::linkedDocuments(const CalendarEvent& event, const QString& homePath)
+{
+    // file:///Users/me/Plan.key, or /Users/me/Plan.key and ~/Plan.key on
+    // their own; "https://host/a/b" and "and/or" are not paths.
+    static const QRegularExpression kFileUrl(QStringLiteral("file://[^\\s<>\"]+"),
+                                             QRegularExpression::CaseInsensitiveOption);
+    static const QRegularExpression kPath(
+        QStringLiteral("(?:^|(?<=[\\s(\"']))(~?/[^\\s<>\"/]+(?:/[^\\s<>\"/]+)*)"));
+
+    QStringList paths;
+    const QString text = event.notes + QLatin1Char('\n') + event.url;
+    auto it = kFileUrl.globalMatch(text);
+    while (it.hasNext()) {
+        const QUrl url(trimTrailingPunctuation(it.next().captured(0)));
+        if (url.isLocalFile()) {
+            appendUnique(&paths, QDir::cleanPath(url.toLocalFile()));
+        }
+    }
+    it = kPath.globalMatch(text);
+    while (it.hasNext()) {
+        QString path = trimTrailingPunctuation(it.next().captured(1));
+        if (path.startsWith(QLatin1Char('~'))) {
+            path = homePath + path.mid(1);
+        }
+        if (path.size() > 1) {
+            appendUnique(&paths, QDir::cleanPath(path));
+        }
+    }
+    return paths;
+}
+
+QString CalendarSource::eventUrl(const CalendarEvent& event)
+{
+    const QString occurrence = QDateTime::fromSecsSinceEpoch(static_cast<qint64>(event.start))
+                                   .toUTC()
+                                   .toString(QStringLiteral("yyyyMMdd'T'HHmmss'Z'"));
+    return QStringLiteral("ical://ekevent/%1/%2?method=show&options=more")
+        .arg(occurrence,
+             QString::fromLatin1(QUrl::toPercentEncoding(event.eventIdentifier,
+                                                         QByteArrayLiteral(":"))));
+}
+
+} // namespace bs
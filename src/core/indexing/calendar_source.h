@@ -0,0 +1,92 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One occurrence of a calendar event.
+struct CalendarEvent {
+    QString eventIdentifier;  // EKEvent.eventIdentifier, shared by a series
+    QString title;
+    QString location;
+    QString notes;
+    QString url;              // the event's URL field
+    QString calendarName;     // "Work", "Home"
+    QString organizer;
+    QStringList attendees;    // names, else email addresses
+    double start = 0.0;       // epoch seconds
+    double end = 0.0;
+    bool allDay = false;
+    bool recurring = false;
+
+    // eventIdentifier, plus the start for one occurrence of a series, so
+    // every occurrence is its own item.
+    QString identifier() const;
+};
+
+// CalendarSource -- the opt-in source that indexes events from the local
+// calendars, so searching a meeting's name, an attendee or a word from its
+// notes ("standup notes") surfaces the event and the documents its notes
+// link to. Events are written as donations from com.apple.iCal (see
+// SpotlightDonation) dated by their start, which the query service ranks by
+// closeness to now (Scorer::computeEventTimeBoost) instead of by recency.
+class CalendarSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.iCal";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_calendar";
+    static constexpr const char* kContentType = "public.calendar-event";
+
+    // Stored attributes beyond SpotlightDonation's.
+    static constexpr const char* kStartAttribute = "startDate";  // epoch seconds
+    static constexpr const char* kEndAttribute = "endDate";
+    static constexpr const char* kAttendeesAttribute = "attendees";
+    static constexpr const char* kLinkedDocumentAttribute = "linkedDocument";
+
+    // The events read: this far back and ahead of now.
+    static constexpr int kPastDays = 180;
+    static constexpr int kFutureDays = 90;
+
+    enum class Access {
+        Authorized,
+        NotDetermined,  // the user has not been asked yet
+        Denied,         // denied, restricted or write-only in Privacy & Security
+        Unavailable,    // not macOS
+    };
+    static Access accessStatus();
+    static QString accessToString(Access access);
+
+    // Asks for full calendar access when the user has not been asked yet
+    // and returns without waiting; the answer applies to the next sync.
+    static void requestAccess();
+
+    // Every occurrence starting in [from, to), or nullopt with *error when
+    // the store cannot be read.
+    static std::optional<std::vector<CalendarEvent>> readEvents(double from, double to,
+                                                                QString* error);
+
+    // The events as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations. Untitled events and repeated identifiers
+    // are left out.
+    static std::vector<DonatedItem> items(const std::vector<CalendarEvent>& events);
+
+    // The indexed identifiers no longer in `events`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<CalendarEvent>& events);
+
+    // Local files the event's notes or URL point at: file:// URLs and
+    // absolute or ~/ paths, expanded against `homePath`.
+    static QStringList linkedDocuments(const CalendarEvent& event, const QString& homePath);
+
+    // ical://ekevent/<occurrence>/<identifier>?method=show&options=more,
+    // which Calendar opens the event for.
+    static QString eventUrl(const CalendarEvent& event);
+};
+
+} // namespace bs
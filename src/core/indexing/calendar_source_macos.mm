@@ -0,0 +1,129 @@
+#include "core/indexing/calendar_source.h"
+
+#import <EventKit/EventKit.h>
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+double toEpoch(NSDate* date)
+{
+    return date ? date.timeIntervalSince1970 : 0.0;
+}
+
+// "Ada Lovelace", else the address of a mailto: participant.
+QString participantName(EKParticipant* participant)
+{
+    const QString name = toQString(participant.name).trimmed();
+    if (!name.isEmpty()) {
+        return name;
+    }
+    NSURL* url = participant.URL;
+    if ([url.scheme caseInsensitiveCompare:@"mailto"] == NSOrderedSame) {
+        return toQString(url.resourceSpecifier);
+    }
+    return QString();
+}
+
+// One store for the process; it outlives the access request's callback.
+EKEventStore* eventStore()
+{
+    static EKEventStore* store = [[EKEventStore alloc] init];
+    return store;
+}
+
+} // namespace
+
+CalendarSource::Access CalendarSource::accessStatus()
+{
+    const EKAuthorizationStatus status =
+        [EKEventStore authorizationStatusForEntityType:EKEntityTypeEvent];
+    if (status == EKAuthorizationStatusNotDetermined) {
+        return Access::NotDetermined;
+    }
+    // EKAuthorizationStatusAuthorized is EKAuthorizationStatusFullAccess
+    // from macOS 14 on; write-only access cannot read events.
+    if (status == EKAuthorizationStatusAuthorized) {
+        return Access::Authorized;
+    }
+    return Access::Denied;
+}
+
+void CalendarSource::requestAccess()
+{
+    if (accessStatus() != Access::NotDetermined) {
+        return;
+    }
+    // A store made before access was granted keeps showing no calendars
+    // until it is reset.
+    void (^completion)(BOOL, NSError*) = ^(BOOL granted, NSError*) {
+        if (granted) {
+            [eventStore() reset];
+        }
+    };
+    if (@available(macOS 14.0, *)) {
+        [eventStore() requestFullAccessToEventsWithCompletion:completion];
+    } else {
+        [eventStore() requestAccessToEntityType:EKEntityTypeEvent completion:completion];
+    }
+}
+
+std::optional<std::vector<CalendarEvent>> CalendarSource::readEvents(double from, double to,
+                                                                     QString* error)
+{
+    if (accessStatus() != Access::Authorized) {
+        *error = QStringLiteral("Calendar access is %1").arg(accessToString(accessStatus()));
+        return std::nullopt;
+    }
+    if (to <= from) {
+        return std::vector<CalendarEvent>();
+    }
+
+    std::vector<CalendarEvent> events;
+    @autoreleasepool {
+        EKEventStore* store = eventStore();
+        NSPredicate* predicate =
+            [store predicateForEventsWithStartDate:[NSDate dateWithTimeIntervalSince1970:from]
+                                           endDate:[NSDate dateWithTimeIntervalSince1970:to]
+                                         calendars:nil];
+        NSArray<EKEvent*>* matches = [store eventsMatchingPredicate:predicate];
+        if (!matches) {
+            *error = QStringLiteral("Calendar events could not be read");
+            return std::nullopt;
+        }
+        events.reserve(matches.count);
+        for (EKEvent* match in matches) {
+            CalendarEvent event;
+            event.eventIdentifier = toQString(match.eventIdentifier);
+            event.title = toQString(match.title);
+            event.location = toQString(match.location);
+            event.notes = toQString(match.notes);
+            event.url = toQString(match.URL.absoluteString);
+            event.calendarName = toQString(match.calendar.title);
+            if (match.organizer) {
+                event.organizer = participantName(match.organizer);
+            }
+            for (EKParticipant* attendee in match.attendees) {
+                const QString name = participantName(attendee);
+                if (!name.isEmpty()) {
+                    event.attendees.append(name);
+                }
+            }
+            // The occurrence's own dates, not the series'.
+            event.start = toEpoch(match.startDate);
+            event.end = toEpoch(match.endDate);
+            event.allDay = match.allDay;
+            event.recurring = match.hasRecurrenceRules;
+            events.push_back(std::move(event));
+        }
+    }
+    return events;
+}
+
+} // namespace bs
@@ -0,0 +1,21 @@
+#include "core/indexing/calendar_source.h"
+
+namespace bs {
+
+CalendarSource::Access CalendarSource::accessStatus()
+{
+    return Access::Unavailable;
+}
+
+void CalendarSource::requestAccess()
+{
+}
+
+std::optional<std::vector<CalendarEvent>> CalendarSource::readEvents(double, double,
+                                                                     QString* error)
+{
+    *error = QStringLiteral("Calendars are only available on macOS");
+    return std::nullopt;
+}
+
+} // namespace bs
@@ -0,0 +1,300 @@
+#include "core/indexing/clipboard_history.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QMessageAuthenticationCode>
+#include <QRandomGenerator>
+#include <QRegularExpression>
+#include <QSaveFile>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// Sealed file: magic, IV, ciphertext, then an HMAC-SHA256 tag over all
+// three.
+constexpr char kMagic[] = "BSC1";
+constexpr qsizetype kMagicBytes = 4;
+constexpr qsizetype kIvBytes = 16;
+constexpr qsizetype kTagBytes = 32;
+constexpr qsizetype kKeyBytes = 32;
+constexpr int kFormatVersion = 1;
+
+// Separate keys for encryption and authentication, derived from the one
+// in the Keychain.
+QByteArray subkey(const QByteArray& key, const char* purpose)
+{
+    return QMessageAuthenticationCode::hash(QByteArray(purpose), key,
+                                            QCryptographicHash::Sha256);
+}
+
+QByteArray tagFor(const QByteArray& body, const QByteArray& key)
+{
+    return QMessageAuthenticationCode::hash(body, subkey(key, "authenticate"),
+                                            QCryptographicHash::Sha256);
+}
+
+bool constantTimeEquals(const QByteArray& a, const QByteArray& b)
+{
+    if (a.size() != b.size()) {
+        return false;
+    }
+    char diff = 0;
+    for (qsizetype i = 0; i < a.size(); ++i) {
+        diff |= static_cast<char>(a.at(i) ^ b.at(i));
+    }
+    return diff == 0;
+}
+
+void prune(std::vector<ClipboardEntry>& entries, double now)
+{
+    const double cutoff = now - ClipboardHistory::kRetentionDays * 86400.0;
+    entries.erase(std::remove_if(entries.begin(), entries.end(),
+                                 [cutoff](const ClipboardEntry& entry) {
+                                     return entry.copiedAt < cutoff;
+                                 }),
+                  entries.end());
+    if (entries.size() > static_cast<size_t>(ClipboardHistory::kMaxEntries)) {
+        entries.resize(ClipboardHistory::kMaxEntries);
+    }
+}
+
+} // namespace
+
+ClipboardHistory::ClipboardHistory(QString path)
+    : m_path(std::move(path))
+{
+}
+
+const QString& ClipboardHistory::path() const
+{
+    return m_path;
+}
+
+bool ClipboardHistory::load(QString* error)
+{
+    m_entries.clear();
+    QFile file(m_path);
+    if (!file.exists()) {
+        return true;
+    }
+    if (!file.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("%1 could not be read").arg(m_path);
+        return false;
+    }
+    const auto key = storageKey(error);
+    if (!key) {
+        return false;
+    }
+    const auto plaintext = unseal(file.readAll(), *key, error);
+    if (!plaintext) {
+        return false;
+    }
+    auto entries = deserialize(*plaintext);
+    if (!entries) {
+        *error = QStringLiteral("%1 is not a clipboard history").arg(m_path);
+        return false;
+    }
+    prune(*entries, static_cast<double>(QDateTime::currentSecsSinceEpoch()));
+    m_entries = std::move(*entries);
+    return true;
+}
+
+bool ClipboardHistory::save(QString* error) const
+{
+    const auto key = storageKey(error);
+    if (!key) {
+        return false;
+    }
+    const auto sealed = seal(serialize(m_entries), *key, error);
+    if (!sealed) {
+        return false;
+    }
+    QDir().mkpath(QFileInfo(m_path).absolutePath());
+    QSaveFile file(m_path);
+    if (!file.open(QIODevice::WriteOnly)) {
+        *error = QStringLiteral("%1 could not be written").arg(m_path);
+        return false;
+    }
+    file.write(*sealed);
+    if (!file.commit()) {
+        *error = QStringLiteral("%1 could not be written").arg(m_path);
+        return false;
+    }
+    QFile::setPermissions(m_path, QFile::ReadOwner | QFile::WriteOwner);
+    return true;
+}
+
+void ClipboardHistory::clear()
+{
+    m_entries.clear();
+    QFile::remove(m_path);
+}
+
+bool ClipboardHistory::record(const QString& text, double copiedAt)
+{
+    if (text.trimmed().isEmpty() || text.toUtf8().size() > kMaxEntryBytes) {
+        return false;
+    }
+    m_entries.erase(std::remove_if(m_entries.begin(), m_entries.end(),
+                                   [&text](const ClipboardEntry& entry) {
+                                       return entry.text == text;
+                                   }),
+                    m_entries.end());
+    m_entries.insert(m_entries.begin(), ClipboardEntry{text, copiedAt});
+    prune(m_entries, copiedAt);
+    return true;
+}
+
+const std::vector<ClipboardEntry>& ClipboardHistory::entries() const
+{
+    return m_entries;
+}
+
+std::vector<ClipboardEntry> ClipboardHistory::search(const QString& query, int limit) const
+{
+    static const QRegularExpression whitespace(QStringLiteral("\\s+"));
+    const QStringList words = query.toCaseFolded().split(whitespace, Qt::SkipEmptyParts);
+    std::vector<ClipboardEntry> matches;
+    if (words.isEmpty() || limit <= 0) {
+        return matches;
+    }
+    for (const ClipboardEntry& entry : m_entries) {
+        const QString text = entry.text.toCaseFolded();
+        const bool all = std::all_of(words.begin(), words.end(), [&text](const QString& word) {
+            return text.contains(word);
+        });
+        if (!all) {
+            continue;
+        }
+        matches.push_back(entry);
+        if (matches.size() >= static_cast<size_t>(limit)) {
+            break;
+        }
+    }
+    return matches;
+}
+
+bool ClipboardHistory::isExcludedFormat(const QStringList& formats)
+{
+    // Qt lists these as "org.nspasteboard.ConcealedType" or folded into a
+    // MIME name of its own, so the marker is matched anywhere in it.
+    static const QStringList markers = {
+        QStringLiteral("ConcealedType"),
+        QStringLiteral("TransientType"),
+        QStringLiteral("AutoGeneratedType"),
+    };
+    for (const QString& format : formats) {
+        for (const QString& marker : markers) {
+            if (format.contains(marker)) {
+                return true;
+            }
+        }
+    }
+    return false;
+}
+
+QString ClipboardHistory::title(const ClipboardEntry& entry)
+{
+    const QString text = entry.text.trimmed();
+    const qsizetype newline = text.indexOf(QLatin1Char('\n'));
+    return (newline < 0 ? text : text.left(newline)).simplified().left(kMaxNameChars);
+}
+
+QString ClipboardHistory::identifier(const ClipboardEntry& entry)
+{
+    return QString::fromLatin1(
+        QCryptographicHash::hash(entry.text.toUtf8(), QCryptographicHash::Sha256)
+            .toHex()
+            .left(16));
+}
+
+QByteArray ClipboardHistory::serialize(const std::vector<ClipboardEntry>& entries)
+{
+    QJsonArray array;
+    for (const ClipboardEntry& entry : entries) {
+        QJsonObject object;
+        object[QStringLiteral("text")] = entry.text;
+        object[QStringLiteral("copiedAt")] = entry.copiedAt;
+        array.append(object);
+    }
+    QJsonObject root;
+    root[QStringLiteral("version")] = kFormatVersion;
+    root[QStringLiteral("entries")] = array;
+    return QJsonDocument(root).toJson(QJsonDocument::Compact);
+}
+
+std::optional<std::vector<ClipboardEntry>> ClipboardHistory::deserialize(const QByteArray& data)
+{
+    const QJsonDocument document = QJsonDocument::fromJson(data);
+    if (!document.isObject()
+        || document.object().value(QStringLiteral("version")).toInt() != kFormatVersion) {
+        return std::nullopt;
+    }
+    std::vector<ClipboardEntry> entries;
+    for (const QJsonValue& value : document.object().value(QStringLiteral("entries")).toArray()) {
+        const QJsonObject object = value.toObject();
+        ClipboardEntry entry;
+        entry.text = object.value(QStringLiteral("text")).toString();
+        entry.copiedAt = object.value(QStringLiteral("copiedAt")).toDouble();
+        if (!entry.text.isEmpty()) {
+            entries.push_back(std::move(entry));
+        }
+    }
+    return entries;
+}
+
+std::optional<QByteArray> ClipboardHistory::seal(const QByteArray& plaintext,
+                                                 const QByteArray& key, QString* error)
+{
+    if (key.size() != kKeyBytes) {
+        *error = QStringLiteral("The clipboard history key must be %1 bytes").arg(kKeyBytes);
+        return std::nullopt;
+    }
+    QByteArray iv(kIvBytes, Qt::Uninitialized);
+    QRandomGenerator::system()->fillRange(reinterpret_cast<quint32*>(iv.data()),
+                                          kIvBytes / sizeof(quint32));
+    const auto ciphertext = aesCbc(true, subkey(key, "encrypt"), iv, plaintext);
+    if (!ciphertext) {
+        *error = QStringLiteral("The clipboard history could not be encrypted");
+        return std::nullopt;
+    }
+    const QByteArray body = QByteArray(kMagic, kMagicBytes) + iv + *ciphertext;
+    return body + tagFor(body, key);
+}
+
+std::optional<QByteArray> ClipboardHistory::unseal(const QByteArray& sealed,
+                                                   const QByteArray& key, QString* error)
+{
+    if (key.size() != kKeyBytes) {
+        *error = QStringLiteral("The clipboard history key must be %1 bytes").arg(kKeyBytes);
+        return std::nullopt;
+    }
+    if (sealed.size() < kMagicBytes + kIvBytes + kTagBytes
+        || !sealed.startsWith(QByteArray(kMagic, kMagicBytes))) {
+        *error = QStringLiteral("The clipboard history is not sealed");
+        return std::nullopt;
+    }
+    const QByteArray body = sealed.left(sealed.size() - kTagBytes);
+    if (!constantTimeEquals(tagFor(body, key), sealed.right(kTagBytes))) {
+        *error = QStringLiteral("The clipboard history failed authentication");
+        return std::nullopt;
+    }
+    const auto plaintext = aesCbc(false, subkey(key, "encrypt"), body.mid(kMagicBytes, kIvBytes),
+                                  body.mid(kMagicBytes + kIvBytes));
+    if (!plaintext) {
+        *error = QStringLiteral("The clipboard history could not be decrypted");
+        return std::nullopt;
+    }
+    return plaintext;
+}
+
+} // namespace bs
@@ -0,0 +1,98 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One copied text.
+struct ClipboardEntry {
+    QString text;
+    double copiedAt = 0.0;  // epoch seconds of the latest copy
+};
+
+// ClipboardHistory -- the opt-in clipboard history, so "that UUID I copied
+// yesterday" is found by searching. Text only: images and files are never
+// kept, nor anything a password manager marks concealed or transient, nor a
+// copy over kMaxEntryBytes.
+//
+// Unlike the other sources it is not indexed. The history lives in the app,
+// sealed at rest (AES-256-CBC with an HMAC-SHA256 tag, under a key held in
+// the login Keychain), and is searched in memory: it never enters index.db,
+// and nothing outside the app's own panel sees it.
+class ClipboardHistory {
+public:
+    // The newest copies kept; older ones, and any over kRetentionDays, are
+    // dropped as new ones come in.
+    static constexpr int kMaxEntries = 500;
+    static constexpr int kMaxEntryBytes = 16 * 1024;
+    static constexpr int kRetentionDays = 30;
+    static constexpr int kMaxNameChars = 120;
+
+    // `path` is the sealed history file.
+    explicit ClipboardHistory(QString path);
+
+    const QString& path() const;
+
+    // Reads and unseals the file; no file is an empty history. False with
+    // *error when it cannot be read or fails authentication.
+    bool load(QString* error);
+    // Seals and writes the history, replacing the file in one rename.
+    bool save(QString* error) const;
+    // Empties the history and deletes its file.
+    void clear();
+
+    // Records a copy made at `copiedAt`; false when it is not kept (blank
+    // or over kMaxEntryBytes). Copying a text again moves it to the top.
+    bool record(const QString& text, double copiedAt);
+
+    // Newest first.
+    const std::vector<ClipboardEntry>& entries() const;
+
+    // The entries holding every word of `query`, case-insensitively,
+    // newest first, at most `limit`.
+    std::vector<ClipboardEntry> search(const QString& query, int limit) const;
+
+    // Whether a pasteboard offering `formats` must not be recorded: it
+    // carries one of the nspasteboard.org markers password managers set.
+    static bool isExcludedFormat(const QStringList& formats);
+
+    // The first line, trimmed to kMaxNameChars: the result's title.
+    static QString title(const ClipboardEntry& entry);
+    // A stable identifier for the entry's text.
+    static QString identifier(const ClipboardEntry& entry);
+
+    // The entries as the JSON the file seals, and back; nullopt when
+    // `data` is not such JSON.
+    static QByteArray serialize(const std::vector<ClipboardEntry>& entries);
+    static std::optional<std::vector<ClipboardEntry>> deserialize(const QByteArray& data);
+
+    // `plaintext` encrypted and authenticated under the 32-byte `key`, and
+    // back. unseal fails, with *error, on a wrong key or any change to the
+    // sealed bytes.
+    static std::optional<QByteArray> seal(const QByteArray& plaintext, const QByteArray& key,
+                                          QString* error);
+    static std::optional<QByteArray> unseal(const QByteArray& sealed, const QByteArray& key,
+                                            QString* error);
+
+    // The 32-byte storage key, created in the Keychain on first use (this
+    // device only, never synced). Nullopt with *error off macOS or when the
+    // Keychain refuses.
+    static std::optional<QByteArray> storageKey(QString* error);
+    // Deletes the storage key; the history sealed under it is unreadable.
+    static void removeStorageKey();
+
+private:
+    // AES-256-CBC with PKCS#7 padding; nullopt when it fails or off macOS.
+    static std::optional<QByteArray> aesCbc(bool encrypt, const QByteArray& key,
+                                            const QByteArray& iv, const QByteArray& data);
+
+    QString m_path;
+    std::vector<ClipboardEntry> m_entries;
+};
+
+} // namespace bs
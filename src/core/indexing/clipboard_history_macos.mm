@@ -0,0 +1,100 @@
+#include "core/indexing/clipboard_history.h"
+
+#import <CommonCrypto/CommonCryptor.h>
+#import <Foundation/Foundation.h>
+#import <Security/Security.h>
+
+namespace bs {
+
+namespace {
+
+constexpr size_t kStorageKeyBytes = 32;
+
+NSString* const kKeychainService = @"com.betterspotlight.clipboard-history";
+NSString* const kKeychainAccount = @"storage-key";
+
+NSMutableDictionary* keychainQuery()
+{
+    return [NSMutableDictionary dictionaryWithDictionary:@{
+        (id)kSecClass: (id)kSecClassGenericPassword,
+        (id)kSecAttrService: kKeychainService,
+        (id)kSecAttrAccount: kKeychainAccount,
+    }];
+}
+
+} // namespace
+
+std::optional<QByteArray> ClipboardHistory::aesCbc(bool encrypt, const QByteArray& key,
+                                                   const QByteArray& iv, const QByteArray& data)
+{
+    if (key.size() != kCCKeySizeAES256 || iv.size() != kCCBlockSizeAES128) {
+        return std::nullopt;
+    }
+    QByteArray out(data.size() + kCCBlockSizeAES128, Qt::Uninitialized);
+    size_t moved = 0;
+    const CCCryptorStatus status =
+        CCCrypt(encrypt ? kCCEncrypt : kCCDecrypt, kCCAlgorithmAES, kCCOptionPKCS7Padding,
+                key.constData(), kCCKeySizeAES256, iv.constData(), data.constData(),
+                static_cast<size_t>(data.size()), out.data(), static_cast<size_t>(out.size()),
+                &moved);
+    if (status != kCCSuccess) {
+        return std::nullopt;
+    }
+    out.truncate(static_cast<qsizetype>(moved));
+    return out;
+}
+
+std::optional<QByteArray> ClipboardHistory::storageKey(QString* error)
+{
+    @autoreleasepool {
+        NSMutableDictionary* query = keychainQuery();
+        query[(id)kSecReturnData] = @YES;
+        query[(id)kSecMatchLimit] = (id)kSecMatchLimitOne;
+        CFTypeRef found = nullptr;
+        OSStatus status = SecItemCopyMatching((CFDictionaryRef)query, &found);
+        if (status == errSecSuccess && found) {
+            NSData* data = (NSData*)found;
+            QByteArray key(static_cast<const char*>(data.bytes),
+                           static_cast<qsizetype>(data.length));
+            CFRelease(found);
+            if (key.size() == static_cast<qsizetype>(kStorageKeyBytes)) {
+                return key;
+            }
+            *error = QStringLiteral("The clipboard history key in the Keychain is malformed");
+            return std::nullopt;
+        }
+        if (status != errSecItemNotFound) {
+            *error = QStringLiteral("The Keychain refused the clipboard history key (%1)")
+                         .arg(static_cast<int>(status));
+            return std::nullopt;
+        }
+
+        QByteArray key(static_cast<qsizetype>(kStorageKeyBytes), Qt::Uninitialized);
+        if (SecRandomCopyBytes(kSecRandomDefault, kStorageKeyBytes, key.data()) != errSecSuccess) {
+            *error = QStringLiteral("No random bytes for the clipboard history key");
+            return std::nullopt;
+        }
+        NSMutableDictionary* item = keychainQuery();
+        item[(id)kSecValueData] = [NSData dataWithBytes:key.constData() length:kStorageKeyBytes];
+        // Readable only while the Mac is unlocked, and never synced to
+        // other devices through iCloud Keychain.
+        item[(id)kSecAttrAccessible] = (id)kSecAttrAccessibleWhenUnlockedThisDeviceOnly;
+        item[(id)kSecAttrLabel] = @"BetterSpotlight clipboard history";
+        status = SecItemAdd((CFDictionaryRef)item, nullptr);
+        if (status != errSecSuccess) {
+            *error = QStringLiteral("The Keychain refused the clipboard history key (%1)")
+                         .arg(static_cast<int>(status));
+            return std::nullopt;
+        }
+        return key;
+    }
+}
+
+void ClipboardHistory::removeStorageKey()
+{
+    @autoreleasepool {
+        SecItemDelete((CFDictionaryRef)keychainQuery());
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,21 @@
+#include "core/indexing/clipboard_history.h"
+
+namespace bs {
+
+std::optional<QByteArray> ClipboardHistory::aesCbc(bool, const QByteArray&, const QByteArray&,
+                                                   const QByteArray&)
+{
+    return std::nullopt;
+}
+
+std::optional<QByteArray> ClipboardHistory::storageKey(QString* error)
+{
+    *error = QStringLiteral("Clipboard history is only available on macOS");
+    return std::nullopt;
+}
+
+void ClipboardHistory::removeStorageKey()
+{
+}
+
+} // namespace bs
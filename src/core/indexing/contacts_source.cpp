@@ -0,0 +1,170 @@
+#include "core/indexing/contacts_source.h"
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QSet>
+#include <QUrl>
+
+namespace bs {
+
+namespace {
+
+void appendUnique(QStringList* list, const QString& value)
+{
+    const QString trimmed = value.trimmed();
+    if (!trimmed.isEmpty() && !list->contains(trimmed, Qt::CaseInsensitive)) {
+        list->append(trimmed);
+    }
+}
+
+// "+1 (555) 010-9999" -> "15550109999", so digits typed without
+// punctuation still find the card.
+QString phoneDigits(const QString& phone)
+{
+    QString digits;
+    for (const QChar ch : phone) {
+        if (ch.isDigit()) {
+            digits.append(ch);
+        }
+    }
+    return digits;
+}
+
+QString fullName(const ContactRecord& record)
+{
+    const QString given = record.givenName.trimmed();
+    const QString family = record.familyName.trimmed();
+    if (given.isEmpty() || family.isEmpty()) {
+        return given.isEmpty() ? family : given;
+    }
+    return given + QLatin1Char(' ') + family;
+}
+
+// "Engineer at Analytical Engines", "Analytical Engines" or "Engineer".
+QString roleLine(const ContactRecord& record)
+{
+    const QString job = record.jobTitle.trimmed();
+    const QString organization = record.organization.trimmed();
+    if (!job.isEmpty() && !organization.isEmpty()) {
+        return QStringLiteral("%1 at %2").arg(job, organization);
+    }
+    return job.isEmpty() ? organization : job;
+}
+
+DonatedItem toItem(const ContactRecord& record, double date)
+{
+    QStringList keywords;
+    for (const QString& email : record.emails) {
+        appendUnique(&keywords, email);
+    }
+    for (const QString& phone : record.phones) {
+        appendUnique(&keywords, phone);
+        appendUnique(&keywords, phoneDigits(phone));
+    }
+
+    DonatedItem item;
+    item.name = record.displayName();
+    item.path = SpotlightDonation::itemPath(QString::fromLatin1(ContactsSource::kBundleIdentifier),
+                                            record.identifier);
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(ContactsSource::kBundleIdentifier));
+
+    const QString role = roleLine(record);
+    QStringList lines = {item.name};
+    appendUnique(&lines, record.nickname);
+    appendUnique(&lines, role);
+    lines.append(keywords);
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    item.createdAt = date;
+    item.modifiedAt = date;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(ContactsSource::kContentType), false});
+    for (const QString& keyword : keywords) {
+        raw.push_back({QStringLiteral("keywords"), keyword, true});
+    }
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute), role, true});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                   ContactsSource::cardUrl(record.identifier), false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+QString ContactRecord::displayName() const
+{
+    const QString name = fullName(*this);
+    if (!name.isEmpty()) {
+        return name;
+    }
+    if (!nickname.trimmed().isEmpty()) {
+        return nickname.trimmed();
+    }
+    if (!organization.trimmed().isEmpty()) {
+        return organization.trimmed();
+    }
+    return emails.isEmpty() ? QString() : emails.first().trimmed();
+}
+
+QString ContactsSource::accessToString(Access access)
+{
+    switch (access) {
+    case Access::Authorized:
+        return QStringLiteral("authorized");
+    case Access::NotDetermined:
+        return QStringLiteral("notDetermined");
+    case Access::Denied:
+        return QStringLiteral("denied");
+    case Access::Unavailable:
+        break;
+    }
+    return QStringLiteral("unavailable");
+}
+
+std::vector<DonatedItem> ContactsSource::items(const std::vector<ContactRecord>& records,
+                                               const QHash<QString, double>& indexedDates,
+                                               double now)
+{
+    std::vector<DonatedItem> out;
+    out.reserve(records.size());
+    QSet<QString> seen;
+    for (const ContactRecord& record : records) {
+        if (record.identifier.trimmed().isEmpty() || record.displayName().isEmpty()
+            || seen.contains(record.identifier)) {
+            continue;
+        }
+        seen.insert(record.identifier);
+        out.push_back(toItem(record, indexedDates.value(record.identifier, now)));
+    }
+    return out;
+}
+
+QStringList ContactsSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                             const std::vector<ContactRecord>& records)
+{
+    QSet<QString> current;
+    for (const ContactRecord& record : records) {
+        if (!record.displayName().isEmpty()) {
+            current.insert(record.identifier);
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+QString ContactsSource::cardUrl(const QString& identifier)
+{
+    return QStringLiteral("addressbook://")
+           + QString::fromLatin1(QUrl::toPercentEncoding(identifier, QByteArrayLiteral(":")));
+}
+
+} // namespace bs
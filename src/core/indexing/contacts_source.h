@@ -0,0 +1,75 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QHash>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One card from the Contacts database.
+struct ContactRecord {
+    QString identifier;  // CNContact.identifier, stable across edits
+    QString givenName;
+    QString familyName;
+    QString nickname;
+    QString organization;
+    QString jobTitle;
+    QStringList emails;
+    QStringList phones;
+
+    // "Ada Lovelace", else the organization, else the first email.
+    QString displayName() const;
+};
+
+// ContactsSource -- the opt-in source that indexes the local Contacts
+// database, so searching a person's name surfaces their card next to the
+// files and mail that mention them. Cards are written as donations from
+// com.apple.Contacts (see SpotlightDonation): re-syncing an unchanged card
+// is skipped by content hash, and opening one opens it in Contacts.app.
+class ContactsSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.Contacts";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_contacts";
+    static constexpr const char* kContentType = "public.vcard";
+
+    enum class Access {
+        Authorized,
+        NotDetermined,  // the user has not been asked yet
+        Denied,         // denied or restricted in Privacy & Security
+        Unavailable,    // not macOS
+    };
+    static Access accessStatus();
+    static QString accessToString(Access access);
+
+    // Asks for Contacts access when the user has not been asked yet and
+    // returns without waiting; the answer applies to the next sync.
+    static void requestAccess();
+
+    // Every card, or nullopt with *error when the store cannot be read.
+    static std::optional<std::vector<ContactRecord>> readContacts(QString* error);
+
+    // The cards as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations. Contacts keeps no modification date, so a
+    // card is dated when it is first indexed: `indexedDates` maps the
+    // identifiers already in the index to their date, and new cards get
+    // `now`. Keeping the date keeps an unchanged card's content hash.
+    // Cards with nothing to show are left out.
+    static std::vector<DonatedItem> items(const std::vector<ContactRecord>& records,
+                                          const QHash<QString, double>& indexedDates,
+                                          double now);
+
+    // The indexed identifiers no longer in `records`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<ContactRecord>& records);
+
+    // addressbook://<identifier>, which Contacts opens the card for.
+    static QString cardUrl(const QString& identifier);
+};
+
+} // namespace bs
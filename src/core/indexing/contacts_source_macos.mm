@@ -0,0 +1,95 @@
+#include "core/indexing/contacts_source.h"
+
+#import <Contacts/Contacts.h>
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+// One store for the process; it outlives the access request's callback.
+CNContactStore* contactStore()
+{
+    static CNContactStore* store = [[CNContactStore alloc] init];
+    return store;
+}
+
+} // namespace
+
+ContactsSource::Access ContactsSource::accessStatus()
+{
+    switch ([CNContactStore authorizationStatusForEntityType:CNEntityTypeContacts]) {
+    case CNAuthorizationStatusAuthorized:
+        return Access::Authorized;
+    case CNAuthorizationStatusNotDetermined:
+        return Access::NotDetermined;
+    default:
+        // Denied, restricted, and the limited access of newer releases,
+        // which hides most cards.
+        return Access::Denied;
+    }
+}
+
+void ContactsSource::requestAccess()
+{
+    if (accessStatus() != Access::NotDetermined) {
+        return;
+    }
+    [contactStore() requestAccessForEntityType:CNEntityTypeContacts
+                             completionHandler:^(BOOL, NSError*) {
+                             }];
+}
+
+std::optional<std::vector<ContactRecord>> ContactsSource::readContacts(QString* error)
+{
+    if (accessStatus() != Access::Authorized) {
+        *error = QStringLiteral("Contacts access is %1").arg(accessToString(accessStatus()));
+        return std::nullopt;
+    }
+
+    std::vector<ContactRecord> records;
+    std::vector<ContactRecord>* out = &records;
+    @autoreleasepool {
+        NSArray<id<CNKeyDescriptor>>* keys = @[
+            CNContactIdentifierKey, CNContactGivenNameKey, CNContactFamilyNameKey,
+            CNContactNicknameKey, CNContactOrganizationNameKey, CNContactJobTitleKey,
+            CNContactEmailAddressesKey, CNContactPhoneNumbersKey,
+        ];
+        CNContactFetchRequest* request = [[CNContactFetchRequest alloc] initWithKeysToFetch:keys];
+        request.unifyResults = YES;
+
+        NSError* fetchError = nil;
+        const BOOL ok = [contactStore() enumerateContactsWithFetchRequest:request
+                                                                    error:&fetchError
+                                                               usingBlock:^(CNContact* contact,
+                                                                            BOOL*) {
+            ContactRecord record;
+            record.identifier = toQString(contact.identifier);
+            record.givenName = toQString(contact.givenName);
+            record.familyName = toQString(contact.familyName);
+            record.nickname = toQString(contact.nickname);
+            record.organization = toQString(contact.organizationName);
+            record.jobTitle = toQString(contact.jobTitle);
+            for (CNLabeledValue<NSString*>* email in contact.emailAddresses) {
+                record.emails.append(toQString(email.value));
+            }
+            for (CNLabeledValue<CNPhoneNumber*>* phone in contact.phoneNumbers) {
+                record.phones.append(toQString(phone.value.stringValue));
+            }
+            out->push_back(std::move(record));
+        }];
+        [request release];
+        if (!ok) {
+            *error = toQString(fetchError.localizedDescription);
+            return std::nullopt;
+        }
+    }
+    return records;
+}
+
+} // namespace bs
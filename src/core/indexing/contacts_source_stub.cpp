@@ -0,0 +1,20 @@
+#include "core/indexing/contacts_source.h"
+
+namespace bs {
+
+ContactsSource::Access ContactsSource::accessStatus()
+{
+    return Access::Unavailable;
+}
+
+void ContactsSource::requestAccess()
+{
+}
+
+std::optional<std::vector<ContactRecord>> ContactsSource::readContacts(QString* error)
+{
+    *error = QStringLiteral("Contacts are only available on macOS");
+    return std::nullopt;
+}
+
+} // namespace bs
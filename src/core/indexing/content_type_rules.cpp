@@ -0,0 +1,186 @@
+#include "core/indexing/content_type_rules.h"
+
+#include <QDir>
+#include <QJsonObject>
+#include <QMimeDatabase>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+bool isKindName(const QString& name)
+{
+    static const QSet<QString> kKinds = {
+        QStringLiteral("text"),  QStringLiteral("code"),    QStringLiteral("markdown"),
+        QStringLiteral("pdf"),   QStringLiteral("image"),   QStringLiteral("archive"),
+        QStringLiteral("binary"), QStringLiteral("unknown"),
+    };
+    return kKinds.contains(name);
+}
+
+bool isValidType(const QString& type)
+{
+    if (type == QLatin1String("*") || type == QLatin1String("document") || isKindName(type)) {
+        return true;
+    }
+    if (type.startsWith(QLatin1Char('.'))) {
+        return type.size() > 1 && !type.contains(QLatin1Char('/'));
+    }
+    const qsizetype slash = type.indexOf(QLatin1Char('/'));
+    return slash > 0 && slash < type.size() - 1 && type.count(QLatin1Char('/')) == 1;
+}
+
+std::optional<QStringList> parseTypes(const QJsonValue& value, const QString& root,
+                                      QString* error)
+{
+    QStringList types;
+    for (const QJsonValue& entry : value.toArray()) {
+        const QString type = entry.toString().trimmed().toLower();
+        if (!isValidType(type)) {
+            *error = QStringLiteral("Unknown content type '%1' for root '%2'")
+                         .arg(entry.toString(), root.isEmpty() ? QStringLiteral("*") : root);
+            return std::nullopt;
+        }
+        if (!types.contains(type)) {
+            types.append(type);
+        }
+    }
+    return types;
+}
+
+// Resolved once per file, and only when a MIME pattern needs it.
+class FileType {
+public:
+    FileType(const QString& path, ItemKind kind) : m_path(path), m_kind(kind) {}
+
+    bool matches(const QString& type)
+    {
+        if (type == QLatin1String("*")) {
+            return true;
+        }
+        if (type == QLatin1String("document")) {
+            return m_kind == ItemKind::Text || m_kind == ItemKind::Markdown
+                   || m_kind == ItemKind::Pdf;
+        }
+        if (type.startsWith(QLatin1Char('.'))) {
+            return m_path.endsWith(type, Qt::CaseInsensitive);
+        }
+        if (!type.contains(QLatin1Char('/'))) {
+            return itemKindToString(m_kind) == type;
+        }
+        const QMimeType& mime = mimeType();
+        if (type.endsWith(QLatin1String("/*"))) {
+            const QString prefix = type.chopped(1);
+            if (mime.name().startsWith(prefix)) {
+                return true;
+            }
+            const QStringList ancestors = mime.allAncestors();
+            return std::any_of(ancestors.begin(), ancestors.end(),
+                               [&prefix](const QString& ancestor) {
+                                   return ancestor.startsWith(prefix);
+                               });
+        }
+        return mime.inherits(type);
+    }
+
+private:
+    const QMimeType& mimeType()
+    {
+        if (!m_mime.has_value()) {
+            // Extension only: the rule must not cost a read of every file.
+            m_mime = QMimeDatabase().mimeTypeForFile(m_path, QMimeDatabase::MatchExtension);
+        }
+        return m_mime.value();
+    }
+
+    const QString& m_path;
+    ItemKind m_kind;
+    std::optional<QMimeType> m_mime;
+};
+
+bool covers(const QString& root, const QString& path)
+{
+    return root.isEmpty() || path == root || path.startsWith(root + QLatin1Char('/'));
+}
+
+} // namespace
+
+std::optional<ContentTypeRules> ContentTypeRules::fromJson(const QJsonArray& json, QString* error)
+{
+    ContentTypeRules rules;
+    QSet<QString> seenRoots;
+    for (const QJsonValue& value : json) {
+        const QJsonObject object = value.toObject();
+        QString root = object.value(QStringLiteral("root")).toString().trimmed();
+        if (root == QLatin1String("*")) {
+            root.clear();
+        }
+        if (!root.isEmpty()) {
+            if (!QDir::isAbsolutePath(root)) {
+                *error = QStringLiteral("Content type rule root '%1' is not absolute").arg(root);
+                return std::nullopt;
+            }
+            root = QDir::cleanPath(root);
+        }
+        if (seenRoots.contains(root)) {
+            *error = QStringLiteral("More than one content type rule for root '%1'")
+                         .arg(root.isEmpty() ? QStringLiteral("*") : root);
+            return std::nullopt;
+        }
+        seenRoots.insert(root);
+
+        ContentTypeRule rule;
+        rule.root = root;
+        const auto allow = parseTypes(object.value(QStringLiteral("allow")), root, error);
+        const auto deny = allow.has_value()
+                              ? parseTypes(object.value(QStringLiteral("deny")), root, error)
+                              : std::nullopt;
+        if (!allow.has_value() || !deny.has_value()) {
+            return std::nullopt;
+        }
+        rule.allow = allow.value();
+        rule.deny = deny.value();
+        // Kept even when empty: it lifts a broader rule for its subtree.
+        rules.m_rules.push_back(std::move(rule));
+    }
+    std::sort(rules.m_rules.begin(), rules.m_rules.end(),
+              [](const ContentTypeRule& a, const ContentTypeRule& b) {
+                  return a.root.size() > b.root.size();
+              });
+    return rules;
+}
+
+QJsonArray ContentTypeRules::toJson() const
+{
+    QJsonArray out;
+    for (const ContentTypeRule& rule : m_rules) {
+        QJsonObject json;
+        json[QStringLiteral("root")] = rule.root.isEmpty() ? QStringLiteral("*") : rule.root;
+        json[QStringLiteral("allow")] = QJsonArray::fromStringList(rule.allow);
+        json[QStringLiteral("deny")] = QJsonArray::fromStringList(rule.deny);
+        out.append(json);
+    }
+    return out;
+}
+
+bool ContentTypeRules::allowsContent(const QString& path, ItemKind kind) const
+{
+    const auto rule = std::find_if(m_rules.begin(), m_rules.end(),
+                                   [&path](const ContentTypeRule& candidate) {
+                                       return covers(candidate.root, path);
+                                   });
+    if (rule == m_rules.end()) {
+        return true;
+    }
+    FileType type(path, kind);
+    const auto matches = [&type](const QString& pattern) { return type.matches(pattern); };
+    if (std::any_of(rule->deny.begin(), rule->deny.end(), matches)) {
+        return false;
+    }
+    return rule->allow.isEmpty() || std::any_of(rule->allow.begin(), rule->allow.end(), matches);
+}
+
+} // namespace bs
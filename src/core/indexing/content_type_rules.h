@@ -0,0 +1,46 @@
+#pragma once
+
+#include "core/shared/types.h"
+
+#include <QJsonArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One root's content policy. File names, metadata, tags and attributes are
+// indexed regardless; the rule only decides whether content is extracted.
+// Types are item kinds ("code", "pdf", ...), "document" (text, markdown and
+// pdf), MIME types ("application/pdf", "image/*"), extensions (".psd") or
+// "*" for every type, so `deny: ["*"]` indexes names only.
+struct ContentTypeRule {
+    QString root;       // absolute path; empty applies under every root
+    QStringList allow;  // empty allows every type not denied
+    QStringList deny;   // wins over allow
+};
+
+// ContentTypeRules — the `content_type_rules` setting, matched per file.
+//
+// The most specific rule whose root contains the file decides; a file no
+// rule covers gets its content extracted, so an empty set changes nothing.
+class ContentTypeRules {
+public:
+    // [{root, allow, deny}]. Rejects relative roots, duplicate roots and
+    // type names that are none of the forms above.
+    static std::optional<ContentTypeRules> fromJson(const QJsonArray& json, QString* error);
+    QJsonArray toJson() const;
+
+    bool isEmpty() const { return m_rules.empty(); }
+    const std::vector<ContentTypeRule>& rules() const { return m_rules; }
+
+    // Safe to call from several prep workers at once.
+    bool allowsContent(const QString& path, ItemKind kind) const;
+
+private:
+    std::vector<ContentTypeRule> m_rules;  // longest root first
+};
+
+} // namespace bs
@@ -2,8 +2,11 @@
 #include "core/index/sqlite_store.h"
 #include "core/extraction/extraction_manager.h"
 #include "core/extraction/extractor.h"
+#include "core/fs/extended_attributes.h"
 #include "core/fs/file_scanner.h"
+#include "core/fs/finder_tags.h"
 #include "core/fs/path_rules.h"
+#include "core/fs/spotlight_metadata.h"
 #include "core/shared/logging.h"
 
 #include <QCryptographicHash>
@@ -40,6 +43,16 @@ bool isUnsupportedExtensionMessage(const std::optional<QString>& errorMessage)
         QStringLiteral("is not supported by extractor"), Qt::CaseInsensitive);
 }
 
+bool isSpotlightAttribute(const QString& name)
+{
+    for (const SpotlightMetadata::Field& field : SpotlightMetadata::fields()) {
+        if (field.name == name) {
+            return true;
+        }
+    }
+    return false;
+}
+
 } // namespace
 
 // ── Construction ────────────────────────────────────────────
@@ -149,11 +162,27 @@ PreparedWork Indexer::prepareNewOrModified(const WorkItem& item, uint64_t genera
     QFileInfo fi(prepared.path);
     prepared.parentPath = fi.path();
 
+    // Tags are the user's own labels, not file content, so sensitive
+    // paths and folders keep them too.
+    prepared.finderTags = FinderTags::read(prepared.path);
+
     if (validation == ValidationResult::MetadataOnly) {
+        // Sensitive paths keep no imported metadata either.
+        prepared.attributes = std::vector<ItemAttribute>{};
         prepared.prepDurationMs = static_cast<int>(timer.elapsed());
         return prepared;
     }
 
+    prepared.xattrAttributes = ExtendedAttributes::read(prepared.path, m_indexedXattrs);
+    const std::vector<ItemAttribute> comment = ExtendedAttributes::readFinderComment(prepared.path);
+    prepared.xattrAttributes.insert(prepared.xattrAttributes.end(), comment.begin(), comment.end());
+
+    // Archives and binaries are where download provenance matters most,
+    // so this runs before the extractable-kind check.
+    if (meta->itemKind != ItemKind::Directory) {
+        prepared.attributes = SpotlightMetadata::read(prepared.path);
+    }
+
     if (meta->itemKind == ItemKind::Directory
         || meta->itemKind == ItemKind::Archive
         || meta->itemKind == ItemKind::Binary) {
@@ -162,6 +191,12 @@ PreparedWork Indexer::prepareNewOrModified(const WorkItem& item, uint64_t genera
         return prepared;
     }
 
+    if (!m_contentTypeRules.allowsContent(prepared.path, meta->itemKind)) {
+        prepared.contentExcluded = true;
+        prepared.prepDurationMs = static_cast<int>(timer.elapsed());
+        return prepared;
+    }
+
     prepareExtractedContent(prepared, meta.value(), item.retryCount);
 
     prepared.prepDurationMs = static_cast<int>(timer.elapsed());
@@ -221,6 +256,11 @@ void Indexer::prepareExtractedContent(PreparedWork& prepared,
             && extraction.content.has_value()) {
             break;
         }
+        // Feeding the file that killed the helper back to it only crashes
+        // it again; record the failure and skip until the file changes.
+        if (extraction.status == ExtractionResult::Status::Crashed) {
+            break;
+        }
 
         ++attempts;
         if (attempts <= kMaxRetries) {
@@ -286,6 +326,26 @@ IndexResult Indexer::applyDelete(const PreparedWork& prepared)
     return result;
 }
 
+void Indexer::applyAttributes(int64_t itemId, const PreparedWork& prepared,
+                              const QString& fileName)
+{
+    // Without a Spotlight record, keep the Spotlight attributes stored
+    // earlier and only refresh the xattr ones.
+    std::vector<ItemAttribute> attributes;
+    if (prepared.attributes.has_value()) {
+        attributes = prepared.attributes.value();
+    } else {
+        for (const ItemAttribute& stored : m_store.getItemAttributes(itemId)) {
+            if (isSpotlightAttribute(stored.name)) {
+                attributes.push_back(stored);
+            }
+        }
+    }
+    attributes.insert(attributes.end(), prepared.xattrAttributes.begin(),
+                      prepared.xattrAttributes.end());
+    m_store.replaceItemAttributes(itemId, fileName, prepared.path, attributes);
+}
+
 IndexResult Indexer::applyNewOrModified(const PreparedWork& prepared)
 {
     QElapsedTimer timer;
@@ -293,7 +353,9 @@ IndexResult Indexer::applyNewOrModified(const PreparedWork& prepared)
 
     IndexResult result;
 
-    if (prepared.validation == ValidationResult::Exclude) {
+    // Excluded at prep, or under a privacy folder added since then.
+    if (prepared.validation == ValidationResult::Exclude
+        || m_pathRules.isPrivacyExcluded(prepared.path.toStdString())) {
         result.status = IndexResult::Status::Excluded;
         result.durationMs = static_cast<int>(timer.elapsed());
         return result;
@@ -311,6 +373,15 @@ IndexResult Indexer::applyNewOrModified(const PreparedWork& prepared)
     if (existing.has_value() && prepared.type == WorkItem::Type::ModifiedContent) {
         if (static_cast<int64_t>(meta.fileSize) == existing->size
             && meta.modifiedAt == existing->modifiedAt) {
+            // Spotlight can import metadata after the file was last written,
+            // and setting an xattr leaves size and mtime alone.
+            applyAttributes(existing->id, prepared, QString::fromStdString(meta.fileName));
+            // Tagging in Finder writes an xattr and leaves size and mtime alone.
+            if (prepared.finderTags.has_value()) {
+                m_store.replaceItemTags(existing->id,
+                                        QString::fromLatin1(SQLiteStore::kFinderTagSource),
+                                        prepared.finderTags.value());
+            }
             result.status = IndexResult::Status::Skipped;
             result.durationMs = static_cast<int>(timer.elapsed());
             return result;
@@ -338,12 +409,31 @@ IndexResult Indexer::applyNewOrModified(const PreparedWork& prepared)
         return result;
     }
 
+    applyAttributes(itemId.value(), prepared, QString::fromStdString(meta.fileName));
+    if (prepared.finderTags.has_value()) {
+        m_store.replaceItemTags(itemId.value(), QString::fromLatin1(SQLiteStore::kFinderTagSource),
+                                prepared.finderTags.value());
+    }
+
     if (prepared.validation == ValidationResult::MetadataOnly) {
         result.status = IndexResult::Status::MetadataOnly;
         result.durationMs = static_cast<int>(timer.elapsed());
         return result;
     }
 
+    if (prepared.contentExcluded) {
+        // The rule is meant to save disk, so content indexed before it
+        // (or before the file's root got one) goes now.
+        if (!existingHash.isEmpty()) {
+            m_store.deleteChunksForItem(itemId.value(), prepared.path);
+            m_store.updateContentHash(itemId.value(), QString());
+        }
+        m_store.clearFailures(itemId.value());
+        result.status = IndexResult::Status::MetadataOnly;
+        result.durationMs = static_cast<int>(timer.elapsed());
+        return result;
+    }
+
     if (prepared.nonExtractable) {
         m_store.clearFailures(itemId.value());
         result.status = IndexResult::Status::Indexed;
@@ -409,7 +499,9 @@ IndexResult Indexer::applyRescan(const PreparedWork& prepared)
 
     IndexResult result;
 
-    if (prepared.validation == ValidationResult::Exclude) {
+    // Excluded at prep, or under a privacy folder added since then.
+    if (prepared.validation == ValidationResult::Exclude
+        || m_pathRules.isPrivacyExcluded(prepared.path.toStdString())) {
         result.status = IndexResult::Status::Excluded;
         result.durationMs = static_cast<int>(timer.elapsed());
         return result;
@@ -449,6 +541,113 @@ IndexResult Indexer::applyRescan(const PreparedWork& prepared)
     return result;
 }
 
+// ── Donated items ───────────────────────────────────────────
+
+IndexResult Indexer::applyDonation(const DonatedItem& item)
+{
+    QElapsedTimer timer;
+    timer.start();
+
+    IndexResult result;
+
+    const auto existing = m_store.getItemByPath(item.path);
+    const QString existingHash = existing.has_value() ? existing->contentHash : QString();
+    if (!existingHash.isEmpty() && existingHash == item.contentHash) {
+        result.status = IndexResult::Status::Skipped;
+        result.durationMs = static_cast<int>(timer.elapsed());
+        return result;
+    }
+
+    auto itemId = m_store.upsertItem(item.path, item.name, QString(), ItemKind::Text, item.size,
+                                     item.createdAt, item.modifiedAt, existingHash,
+                                     QStringLiteral("normal"), item.parentPath);
+    if (!itemId.has_value()) {
+        result.status = IndexResult::Status::ExtractionFailed;
+        result.durationMs = static_cast<int>(timer.elapsed());
+        return result;
+    }
+
+    m_store.replaceItemAttributes(itemId.value(), item.name, item.path, item.attributes);
+
+    // Empty text still goes through insertChunks() so a re-donation that
+    // dropped textContent clears the old chunks. Donated text skips the
+    // extractor, so it is redacted here.
+    const std::vector<Chunk> chunks = item.textContent.isEmpty()
+        ? std::vector<Chunk>()
+        : m_chunker.chunkContent(item.path,
+                                 m_extractor.secretRedactor().redact(item.textContent));
+    if (!m_store.insertChunks(itemId.value(), item.name, item.path, chunks)) {
+        m_store.recordFailure(itemId.value(),
+                              QStringLiteral("fts5_insert"),
+                              QStringLiteral("insertChunks() returned false"));
+        result.status = IndexResult::Status::ExtractionFailed;
+        result.durationMs = static_cast<int>(timer.elapsed());
+        return result;
+    }
+
+    m_store.updateContentHash(itemId.value(), item.contentHash);
+    m_store.clearFailures(itemId.value());
+
+    result.status = IndexResult::Status::Indexed;
+    result.chunksInserted = static_cast<int>(chunks.size());
+    result.durationMs = static_cast<int>(timer.elapsed());
+    return result;
+}
+
+size_t Indexer::applyDonationRemoval(const DonationRemoval& removal)
+{
+    std::vector<SQLiteStore::ItemRow> doomed;
+    if (!removal.uniqueIdentifiers.isEmpty()) {
+        for (const QString& identifier : removal.uniqueIdentifiers) {
+            auto row = m_store.getItemByPath(
+                SpotlightDonation::itemPath(removal.bundleIdentifier, identifier));
+            if (row.has_value()) {
+                doomed.push_back(std::move(row.value()));
+            }
+        }
+    } else {
+        const QString domainAttribute = QString::fromLatin1(SpotlightDonation::kDomainAttribute);
+        for (auto& row : m_store.getItemsUnderPath(
+                 SpotlightDonation::bundlePath(removal.bundleIdentifier))) {
+            if (removal.all) {
+                doomed.push_back(std::move(row));
+                continue;
+            }
+            const QString domain = m_store.getItemAttributeValue(row.id, domainAttribute);
+            for (const QString& wanted : removal.domainIdentifiers) {
+                if (SpotlightDonation::isInDomain(domain, wanted)) {
+                    doomed.push_back(std::move(row));
+                    break;
+                }
+            }
+        }
+    }
+
+    for (const SQLiteStore::ItemRow& row : doomed) {
+        m_store.deleteChunksForItem(row.id, row.path);
+        m_store.deleteItemByPath(row.path);
+    }
+    if (!doomed.empty()) {
+        LOG_INFO(bsIndex, "Removed %d donated item(s) of %s",
+                 static_cast<int>(doomed.size()), qUtf8Printable(removal.bundleIdentifier));
+    }
+    return doomed.size();
+}
+
+size_t Indexer::purgeSubtree(const QString& root)
+{
+    const std::vector<SQLiteStore::ItemRow> doomed = m_store.getItemsUnderPath(root);
+    for (const SQLiteStore::ItemRow& row : doomed) {
+        m_store.deleteChunksForItem(row.id, row.path);
+        m_store.deleteItemByPath(row.path);
+    }
+    const int64_t events = m_store.deleteBehaviorEventsUnderPath(root);
+    LOG_INFO(bsIndex, "Purged %s: %d item(s), %lld behavior event(s)",
+             qUtf8Printable(root), static_cast<int>(doomed.size()),
+             static_cast<long long>(events));
+    return doomed.size();
+}
+
 // ── Stage 4: Metadata extraction ────────────────────────────
 
 std::optional<FileMetadata> Indexer::extractMetadata(const std::string& filePath)
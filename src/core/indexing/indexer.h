@@ -1,9 +1,13 @@
 #pragma once
 
 #include "core/indexing/chunker.h"
+#include "core/indexing/content_type_rules.h"
+#include "core/indexing/spotlight_donation.h"
 #include "core/extraction/extractor.h"
 #include "core/shared/types.h"
 
+#include <QStringList>
+
 #include <optional>
 #include <string>
 #include <vector>
@@ -19,7 +23,7 @@ class PathRules;
 struct IndexResult {
     enum class Status {
         Indexed,           // Successfully indexed in FTS5
-        MetadataOnly,      // Sensitive file, metadata stored but no content
+        MetadataOnly,      // Sensitive or content-excluded file, metadata but no content
         Deleted,           // Removed from index
         Excluded,          // Skipped by path rules
         ExtractionFailed,  // Content extraction failed, logged in failures table
@@ -51,8 +55,18 @@ struct PreparedWork {
     std::optional<FileMetadata> metadata;
     QString parentPath;
     Sensitivity sensitivity = Sensitivity::Normal;
+    // Imported Spotlight attributes; nullopt leaves the stored ones alone.
+    std::optional<std::vector<ItemAttribute>> attributes;
+    // Values of the configured xattrs (Indexer::setIndexedXattrs) and the
+    // Finder comment.
+    std::vector<ItemAttribute> xattrAttributes;
+    // Finder tags; nullopt leaves the stored ones alone.
+    std::optional<QStringList> finderTags;
 
     bool nonExtractable = false;
+    // A content type rule skips this file's content; stored content from
+    // before the rule is dropped at write.
+    bool contentExcluded = false;
     bool hasExtractedContent = false;
     QString contentHash;
     std::vector<Chunk> chunks;
@@ -60,6 +74,7 @@ struct PreparedWork {
     std::optional<PreparedFailure> failure;
     int prepDurationMs = 0;
     bool rebuildLane = false;
+    PendingSpan trace;
 };
 
 // Indexer — coordinates per-file processing through pipeline stages 3-7.
@@ -88,6 +103,27 @@ public:
     // staged prepare+apply flow. Kept for compatibility with existing tests.
     IndexResult processWorkItem(const WorkItem& item);
 
+    // Writer stage for app donations (SpotlightDonation). A re-donation with
+    // an unchanged contentHash is Skipped.
+    IndexResult applyDonation(const DonatedItem& item);
+
+    // Delete the donated items `removal` selects. Returns how many.
+    size_t applyDonationRemoval(const DonationRemoval& removal);
+
+    // Writer stage for a privacy folder: delete every item at or below
+    // `root` (content, postings, vector mappings and the rest cascade) and
+    // the behavior events recorded against those paths. Returns the number
+    // of items deleted.
+    size_t purgeSubtree(const QString& root);
+
+    // Extended attributes to index as item attributes (the `indexed_xattrs`
+    // setting). Not synchronized with prep workers: set before work starts.
+    void setIndexedXattrs(const QStringList& names) { m_indexedXattrs = names; }
+
+    // Which types get content extracted, per root (the `content_type_rules`
+    // setting). Same threading contract as setIndexedXattrs.
+    void setContentTypeRules(ContentTypeRules rules) { m_contentTypeRules = std::move(rules); }
+
 private:
     PreparedWork prepareNewOrModified(const WorkItem& item, uint64_t generation);
     PreparedWork prepareDelete(const WorkItem& item, uint64_t generation);
@@ -105,10 +141,15 @@ private:
     void prepareExtractedContent(PreparedWork& prepared, const FileMetadata& meta,
                                  int initialRetryCount);
 
+    // Writer-side: store Spotlight and xattr attributes together.
+    void applyAttributes(int64_t itemId, const PreparedWork& prepared, const QString& fileName);
+
     SQLiteStore& m_store;
     ExtractionManager& m_extractor;
     const PathRules& m_pathRules;
     const Chunker& m_chunker;
+    QStringList m_indexedXattrs;
+    ContentTypeRules m_contentTypeRules;
 };
 
 } // namespace bs
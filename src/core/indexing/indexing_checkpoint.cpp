@@ -0,0 +1,159 @@
+#include "core/indexing/indexing_checkpoint.h"
+#include "core/shared/logging.h"
+
+#include <QFile>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QSaveFile>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+QJsonArray stringArray(const std::vector<std::string>& values)
+{
+    QJsonArray array;
+    for (const std::string& value : values) {
+        array.append(QString::fromStdString(value));
+    }
+    return array;
+}
+
+std::vector<std::string> toStringVector(const QJsonArray& array)
+{
+    std::vector<std::string> values;
+    values.reserve(static_cast<size_t>(array.size()));
+    for (const QJsonValue& value : array) {
+        const QString text = value.toString();
+        if (!text.isEmpty()) {
+            values.push_back(text.toStdString());
+        }
+    }
+    return values;
+}
+
+std::optional<WorkItem::Type> parseWorkItemType(int raw)
+{
+    switch (raw) {
+    case static_cast<int>(WorkItem::Type::Delete):          return WorkItem::Type::Delete;
+    case static_cast<int>(WorkItem::Type::ModifiedContent): return WorkItem::Type::ModifiedContent;
+    case static_cast<int>(WorkItem::Type::NewFile):         return WorkItem::Type::NewFile;
+    case static_cast<int>(WorkItem::Type::RescanDirectory): return WorkItem::Type::RescanDirectory;
+    default:
+        return std::nullopt;
+    }
+}
+
+} // namespace
+
+bool IndexingCheckpoint::matchesRoots(const std::vector<std::string>& otherRoots) const
+{
+    std::vector<std::string> lhs = roots;
+    std::vector<std::string> rhs = otherRoots;
+    std::sort(lhs.begin(), lhs.end());
+    std::sort(rhs.begin(), rhs.end());
+    return lhs == rhs;
+}
+
+QJsonObject IndexingCheckpoint::toJson() const
+{
+    QJsonArray items;
+    for (const WorkItem& item : pendingItems) {
+        QJsonObject entry;
+        entry[QStringLiteral("type")] = static_cast<int>(item.type);
+        entry[QStringLiteral("path")] = QString::fromStdString(item.filePath);
+        if (item.rebuildLane) {
+            entry[QStringLiteral("rebuildLane")] = true;
+        }
+        if (item.retryCount > 0) {
+            entry[QStringLiteral("retryCount")] = item.retryCount;
+        }
+        items.append(entry);
+    }
+
+    QJsonObject json;
+    json[QStringLiteral("version")] = kFormatVersion;
+    json[QStringLiteral("roots")] = stringArray(roots);
+    json[QStringLiteral("unscannedRoots")] = stringArray(unscannedRoots);
+    json[QStringLiteral("pendingItems")] = items;
+    if (lastEventId.has_value()) {
+        // Stored as a string: FSEvents ids exceed the 2^53 JSON-safe range.
+        json[QStringLiteral("lastEventId")] = QString::number(lastEventId.value());
+    }
+    json[QStringLiteral("savedAtMs")] = static_cast<qint64>(savedAtMs);
+    return json;
+}
+
+std::optional<IndexingCheckpoint> IndexingCheckpoint::fromJson(const QJsonObject& json)
+{
+    if (json.value(QStringLiteral("version")).toInt() != kFormatVersion) {
+        return std::nullopt;
+    }
+
+    IndexingCheckpoint checkpoint;
+    checkpoint.roots = toStringVector(json.value(QStringLiteral("roots")).toArray());
+    if (checkpoint.roots.empty()) {
+        return std::nullopt;
+    }
+    checkpoint.unscannedRoots =
+        toStringVector(json.value(QStringLiteral("unscannedRoots")).toArray());
+
+    for (const QJsonValue& value : json.value(QStringLiteral("pendingItems")).toArray()) {
+        const QJsonObject entry = value.toObject();
+        const auto type = parseWorkItemType(entry.value(QStringLiteral("type")).toInt(-1));
+        const QString path = entry.value(QStringLiteral("path")).toString();
+        if (!type.has_value() || path.isEmpty()) {
+            continue;
+        }
+        WorkItem item;
+        item.type = type.value();
+        item.filePath = path.toStdString();
+        item.rebuildLane = entry.value(QStringLiteral("rebuildLane")).toBool(false);
+        item.retryCount = entry.value(QStringLiteral("retryCount")).toInt(0);
+        checkpoint.pendingItems.push_back(std::move(item));
+    }
+
+    bool ok = false;
+    const uint64_t eventId =
+        json.value(QStringLiteral("lastEventId")).toString().toULongLong(&ok);
+    if (ok) {
+        checkpoint.lastEventId = eventId;
+    }
+    checkpoint.savedAtMs = json.value(QStringLiteral("savedAtMs")).toInteger();
+    return checkpoint;
+}
+
+bool IndexingCheckpoint::save(const QString& path) const
+{
+    QSaveFile file(path);
+    if (!file.open(QIODevice::WriteOnly)) {
+        LOG_WARN(bsIndex, "Failed to open checkpoint %s: %s",
+                 qUtf8Printable(path), qUtf8Printable(file.errorString()));
+        return false;
+    }
+    file.write(QJsonDocument(toJson()).toJson(QJsonDocument::Compact));
+    if (!file.commit()) {
+        LOG_WARN(bsIndex, "Failed to write checkpoint %s: %s",
+                 qUtf8Printable(path), qUtf8Printable(file.errorString()));
+        return false;
+    }
+    return true;
+}
+
+std::optional<IndexingCheckpoint> IndexingCheckpoint::load(const QString& path)
+{
+    QFile file(path);
+    if (!file.open(QIODevice::ReadOnly)) {
+        return std::nullopt;
+    }
+    const QJsonDocument doc = QJsonDocument::fromJson(file.readAll());
+    if (!doc.isObject()) {
+        LOG_WARN(bsIndex, "Ignoring unreadable checkpoint %s", qUtf8Printable(path));
+        return std::nullopt;
+    }
+    return fromJson(doc.object());
+}
+
+} // namespace bs
@@ -0,0 +1,44 @@
+#pragma once
+
+#include "core/shared/types.h"
+
+#include <QJsonObject>
+#include <QString>
+
+#include <cstdint>
+#include <optional>
+#include <string>
+#include <vector>
+
+namespace bs {
+
+// IndexingCheckpoint — crawl frontier saved on graceful shutdown.
+//
+// Holds everything the pipeline had not yet written: roots whose initial scan
+// never started, work items still queued (including the unscanned tail of
+// the root being walked), and the FSEvents id to replay watcher changes from.
+// In-flight extraction is drained before the checkpoint is taken, so nothing
+// between "dequeued" and "committed" needs to be recorded.
+struct IndexingCheckpoint {
+    static constexpr int kFormatVersion = 1;
+
+    std::vector<std::string> roots;           // roots the pipeline was started with
+    std::vector<std::string> unscannedRoots;  // initial scan not yet started
+    std::vector<WorkItem> pendingItems;
+    std::optional<uint64_t> lastEventId;      // unset = watcher had no history
+    int64_t savedAtMs = 0;
+
+    // True when resuming is valid for a pipeline started with `otherRoots`
+    // (same set, any order). A changed root set falls back to a full scan.
+    bool matchesRoots(const std::vector<std::string>& otherRoots) const;
+
+    QJsonObject toJson() const;
+    static std::optional<IndexingCheckpoint> fromJson(const QJsonObject& json);
+
+    // Atomic write; load() returns nullopt for missing, corrupt or
+    // other-version files.
+    bool save(const QString& path) const;
+    static std::optional<IndexingCheckpoint> load(const QString& path);
+};
+
+} // namespace bs
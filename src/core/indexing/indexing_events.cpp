@@ -0,0 +1,40 @@
+#include "core/indexing/indexing_events.h"
+
+#include <algorithm>
+
+namespace bs {
+
+QJsonObject IndexingEventLog::append(const QString& event, QJsonObject fields, int64_t nowMs)
+{
+    fields[QStringLiteral("seq")] = static_cast<qint64>(m_nextSeq++);
+    fields[QStringLiteral("event")] = event;
+    fields[QStringLiteral("timestampMs")] = static_cast<qint64>(nowMs);
+    m_events.push_back(fields);
+    while (m_events.size() > kCapacity) {
+        m_events.pop_front();
+    }
+    return fields;
+}
+
+IndexingEventLog::Page IndexingEventLog::since(int64_t seq, int limit) const
+{
+    Page page;
+    if (seq > lastSeq()) {
+        seq = 0;
+        page.truncated = true;
+    }
+    const int64_t oldest = m_nextSeq - static_cast<int64_t>(m_events.size());
+    page.truncated = page.truncated || seq + 1 < oldest;
+
+    const int64_t first = std::max<int64_t>(seq + 1, oldest);
+    const size_t offset = static_cast<size_t>(first - oldest);
+    const size_t count = std::min(m_events.size() - offset,
+                                  static_cast<size_t>(std::max(limit, 0)));
+    for (size_t i = 0; i < count; ++i) {
+        page.events.append(m_events[offset + i]);
+    }
+    page.lastSeq = first + static_cast<int64_t>(count) - 1;
+    return page;
+}
+
+} // namespace bs
@@ -0,0 +1,45 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <cstddef>
+#include <cstdint>
+#include <deque>
+
+namespace bs {
+
+// IndexingEventLog — numbered indexing events (rootStarted, rootScanned,
+// progress, fileFailed, rootCompleted) for clients that draw progress from
+// a stream instead of polling.
+//
+// append() stamps each event with the next sequence number and keeps the
+// newest kCapacity, so a client that connects late or reconnects can ask
+// for what it missed and then follow the live notifications. Not
+// synchronized: the indexer appends and reads on its event loop.
+class IndexingEventLog {
+public:
+    static constexpr size_t kCapacity = 1000;
+
+    // Returns the stored event: `fields` plus seq, event and timestampMs.
+    QJsonObject append(const QString& event, QJsonObject fields, int64_t nowMs);
+
+    struct Page {
+        QJsonArray events;       // seq > since, oldest first, at most `limit`
+        int64_t lastSeq = 0;     // pass back as `since` to continue
+        // Some events after `since` are gone: dropped for capacity, or
+        // `since` is from before a restart (sequence numbers start over).
+        bool truncated = false;
+    };
+    Page since(int64_t seq, int limit) const;
+
+    // Sequence number of the newest event, 0 before the first.
+    int64_t lastSeq() const { return m_nextSeq - 1; }
+
+private:
+    std::deque<QJsonObject> m_events;
+    int64_t m_nextSeq = 1;
+};
+
+} // namespace bs
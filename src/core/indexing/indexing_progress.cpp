@@ -0,0 +1,223 @@
+#include "core/indexing/indexing_progress.h"
+
+#include <QJsonArray>
+#include <QString>
+
+#include <algorithm>
+#include <cmath>
+
+namespace bs {
+
+namespace {
+
+std::string normalizeRoot(const std::string& root)
+{
+    std::string normalized = root;
+    while (normalized.size() > 1 && normalized.back() == '/') {
+        normalized.pop_back();
+    }
+    return normalized;
+}
+
+bool pathIsUnder(const std::string& path, const std::string& root)
+{
+    if (root == "/") {
+        return !path.empty() && path.front() == '/';
+    }
+    if (path.size() < root.size() || path.compare(0, root.size(), root) != 0) {
+        return false;
+    }
+    return path.size() == root.size() || path[root.size()] == '/';
+}
+
+} // namespace
+
+QJsonObject IndexingProgress::toJson() const
+{
+    QJsonArray rootsJson;
+    for (const RootProgress& root : roots) {
+        QJsonObject entry;
+        entry[QStringLiteral("path")] = QString::fromStdString(root.root);
+        entry[QStringLiteral("discovered")] = static_cast<qint64>(root.discovered);
+        entry[QStringLiteral("processed")] = static_cast<qint64>(root.processed);
+        entry[QStringLiteral("scanComplete")] = root.scanComplete;
+        entry[QStringLiteral("percent")] = std::round(root.percent * 10.0) / 10.0;
+        rootsJson.append(entry);
+    }
+
+    QJsonObject throughput;
+    throughput[QStringLiteral("itemsPerSec")] = std::round(itemsPerSecond * 10.0) / 10.0;
+    throughput[QStringLiteral("windowMs")] = throughputWindowMs;
+
+    QJsonObject json;
+    json[QStringLiteral("roots")] = rootsJson;
+    json[QStringLiteral("discovered")] = static_cast<qint64>(discovered);
+    json[QStringLiteral("processed")] = static_cast<qint64>(processed);
+    json[QStringLiteral("crawlComplete")] = crawlComplete;
+    json[QStringLiteral("throughput")] = throughput;
+    json[QStringLiteral("etaSeconds")] = etaSeconds.has_value()
+        ? QJsonValue(static_cast<qint64>(*etaSeconds))
+        : QJsonValue(QJsonValue::Null);
+    return json;
+}
+
+void IndexingProgressTracker::reset(const std::vector<std::string>& roots, int64_t nowMs)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    m_roots.clear();
+    m_roots.reserve(roots.size());
+    for (const std::string& root : roots) {
+        RootState state;
+        state.root = normalizeRoot(root);
+        m_roots.push_back(std::move(state));
+    }
+    m_startedAtMs = nowMs;
+    m_buckets.clear();
+}
+
+void IndexingProgressTracker::recordDiscovered(const std::string& path)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (RootState* state = rootForPathLocked(path)) {
+        ++state->discovered;
+    }
+}
+
+size_t IndexingProgressTracker::markScanComplete(const std::string& root)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (RootState* state = findRootLocked(normalizeRoot(root))) {
+        state->scanComplete = true;
+        return state->discovered;
+    }
+    return 0;
+}
+
+void IndexingProgressTracker::recordProcessed(const std::string& path, bool crawlItem,
+                                              int64_t nowMs)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (crawlItem) {
+        if (RootState* state = rootForPathLocked(path)) {
+            ++state->processed;
+        }
+    }
+
+    const int64_t second = nowMs / 1000;
+    if (!m_buckets.empty() && m_buckets.back().first == second) {
+        ++m_buckets.back().second;
+    } else {
+        m_buckets.emplace_back(second, 1);
+    }
+    trimBucketsLocked(nowMs);
+}
+
+IndexingProgress IndexingProgressTracker::snapshot(int64_t nowMs, bool drained) const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    IndexingProgress progress;
+
+    bool allScansComplete = true;
+    for (const RootState& state : m_roots) {
+        progress.discovered += state.discovered;
+        progress.processed += std::min(state.processed, state.discovered);
+        allScansComplete = allScansComplete && state.scanComplete;
+    }
+    progress.crawlComplete = allScansComplete
+        && (drained || progress.processed >= progress.discovered);
+
+    for (const RootState& state : m_roots) {
+        RootProgress root;
+        root.root = state.root;
+        root.discovered = state.discovered;
+        root.processed = std::min(state.processed, state.discovered);
+        root.scanComplete = state.scanComplete;
+        if (progress.crawlComplete) {
+            root.percent = 100.0;
+        } else if (state.discovered > 0) {
+            root.percent = 100.0 * static_cast<double>(root.processed)
+                / static_cast<double>(state.discovered);
+        } else {
+            root.percent = state.scanComplete ? 100.0 : 0.0;
+        }
+        progress.roots.push_back(std::move(root));
+    }
+
+    const int64_t windowStartSecond = (nowMs - kThroughputWindowMs) / 1000;
+    size_t windowItems = 0;
+    for (const auto& bucket : m_buckets) {
+        if (bucket.first > windowStartSecond) {
+            windowItems += bucket.second;
+        }
+    }
+    const int64_t span = std::clamp<int64_t>(nowMs - m_startedAtMs, 1000, kThroughputWindowMs);
+    progress.throughputWindowMs = span;
+    progress.itemsPerSecond = static_cast<double>(windowItems) * 1000.0
+        / static_cast<double>(span);
+
+    if (progress.crawlComplete) {
+        progress.etaSeconds = 0;
+    } else if (allScansComplete && progress.itemsPerSecond > 0.0) {
+        const double remaining =
+            static_cast<double>(progress.discovered - progress.processed);
+        progress.etaSeconds =
+            static_cast<int64_t>(std::ceil(remaining / progress.itemsPerSecond));
+    }
+    return progress;
+}
+
+std::vector<RootProgress> IndexingProgressTracker::takeCompletedRoots(bool drained)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    std::vector<RootProgress> completed;
+    for (RootState& state : m_roots) {
+        if (state.completionReported || !state.scanComplete
+            || (!drained && state.processed < state.discovered)) {
+            continue;
+        }
+        state.completionReported = true;
+        RootProgress root;
+        root.root = state.root;
+        root.discovered = state.discovered;
+        root.processed = std::min(state.processed, state.discovered);
+        root.scanComplete = true;
+        root.percent = 100.0;
+        completed.push_back(std::move(root));
+    }
+    return completed;
+}
+
+IndexingProgressTracker::RootState* IndexingProgressTracker::findRootLocked(
+    const std::string& root)
+{
+    for (RootState& state : m_roots) {
+        if (state.root == root) {
+            return &state;
+        }
+    }
+    return nullptr;
+}
+
+IndexingProgressTracker::RootState* IndexingProgressTracker::rootForPathLocked(
+    const std::string& path)
+{
+    // Nested roots: credit the most specific one.
+    RootState* best = nullptr;
+    for (RootState& state : m_roots) {
+        if (pathIsUnder(path, state.root)
+            && (best == nullptr || state.root.size() > best->root.size())) {
+            best = &state;
+        }
+    }
+    return best;
+}
+
+void IndexingProgressTracker::trimBucketsLocked(int64_t nowMs)
+{
+    const int64_t windowStartSecond = (nowMs - kThroughputWindowMs) / 1000;
+    while (!m_buckets.empty() && m_buckets.front().first <= windowStartSecond) {
+        m_buckets.pop_front();
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,84 @@
+#pragma once
+
+#include <QJsonObject>
+
+#include <cstddef>
+#include <cstdint>
+#include <deque>
+#include <mutex>
+#include <optional>
+#include <string>
+#include <utility>
+#include <vector>
+
+namespace bs {
+
+struct RootProgress {
+    std::string root;
+    size_t discovered = 0;     // crawl items queued by the scan
+    size_t processed = 0;      // crawl items the writer has finished
+    bool scanComplete = false;
+    double percent = 0.0;      // processed / discovered, 0-100
+};
+
+struct IndexingProgress {
+    std::vector<RootProgress> roots;
+    size_t discovered = 0;
+    size_t processed = 0;
+    bool crawlComplete = false;
+    double itemsPerSecond = 0.0;       // all writer output, crawl and live
+    int64_t throughputWindowMs = 0;    // span the rate was measured over
+    std::optional<int64_t> etaSeconds; // unset until every scan has finished
+
+    QJsonObject toJson() const;
+};
+
+// IndexingProgressTracker — per-root crawl progress, throughput and ETA.
+//
+// The scan thread reports each crawl item it queues and the writer each item
+// it finishes; both sides are attributed to the longest matching root so
+// nested roots stay consistent. Throughput is a sliding window of per-second
+// buckets. ETA is only estimated once all scans are complete, because until
+// then the total is still growing. Thread-safe.
+class IndexingProgressTracker {
+public:
+    static constexpr int64_t kThroughputWindowMs = 30000;
+
+    void reset(const std::vector<std::string>& roots, int64_t nowMs);
+
+    void recordDiscovered(const std::string& path);
+    // Returns the crawl items discovered under `root`.
+    size_t markScanComplete(const std::string& root);
+    void recordProcessed(const std::string& path, bool crawlItem, int64_t nowMs);
+
+    // `drained` = the pipeline has nothing queued or in flight. Once every
+    // scan is complete, a drained pipeline reports the crawl as finished even
+    // when coalescing left processed short of discovered.
+    IndexingProgress snapshot(int64_t nowMs, bool drained) const;
+
+    // Roots that finished since the last call: scan complete and every
+    // crawl item written (or, with `drained`, nothing left anywhere). Each
+    // root is returned once per reset().
+    std::vector<RootProgress> takeCompletedRoots(bool drained);
+
+private:
+    struct RootState {
+        std::string root;
+        size_t discovered = 0;
+        size_t processed = 0;
+        bool scanComplete = false;
+        bool completionReported = false;
+    };
+
+    RootState* findRootLocked(const std::string& root);
+    RootState* rootForPathLocked(const std::string& path);
+    void trimBucketsLocked(int64_t nowMs);
+
+    mutable std::mutex m_mutex;
+    std::vector<RootState> m_roots;
+    int64_t m_startedAtMs = 0;
+    // (second since epoch, items finished in that second), oldest first.
+    std::deque<std::pair<int64_t, size_t>> m_buckets;
+};
+
+} // namespace bs
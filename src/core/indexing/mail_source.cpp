@@ -0,0 +1,564 @@
+#include "core/indexing/mail_source.h"
+
+#include "core/extraction/text_cleaner.h"
+#include "core/fs/spotlight_metadata.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QDirIterator>
+#include <QFileInfo>
+#include <QRegularExpression>
+#include <QStringDecoder>
+#include <QUrl>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// Nested multiparts deeper than this are not looked into.
+constexpr int kMaxMimeDepth = 8;
+
+struct Header {
+    QByteArray name;   // lowercase
+    QByteArray value;  // unfolded, undecoded
+};
+
+struct Part {
+    std::vector<Header> headers;
+    QByteArray body;
+};
+
+QByteArray headerValue(const std::vector<Header>& headers, const char* name)
+{
+    for (const Header& header : headers) {
+        if (header.name == name) {
+            return header.value;
+        }
+    }
+    return QByteArray();
+}
+
+// Headers end at the first empty line; continuation lines start with
+// whitespace.
+Part splitPart(const QByteArray& data)
+{
+    Part part;
+    qsizetype pos = 0;
+    while (pos < data.size()) {
+        qsizetype end = data.indexOf('\n', pos);
+        if (end < 0) {
+            end = data.size();
+        }
+        QByteArray line = data.mid(pos, end - pos);
+        if (line.endsWith('\r')) {
+            line.chop(1);
+        }
+        pos = end + 1;
+        if (line.isEmpty()) {
+            part.body = data.mid(std::min(pos, data.size()));
+            return part;
+        }
+        if ((line.startsWith(' ') || line.startsWith('\t')) && !part.headers.empty()) {
+            part.headers.back().value += ' ' + line.trimmed();
+            continue;
+        }
+        const qsizetype colon = line.indexOf(':');
+        if (colon <= 0) {
+            continue;
+        }
+        part.headers.push_back({line.left(colon).trimmed().toLower(), line.mid(colon + 1).trimmed()});
+    }
+    return part;
+}
+
+QString decodeCharset(const QByteArray& bytes, const QByteArray& charset)
+{
+    const QByteArray name = charset.trimmed().toLower();
+    if (name.isEmpty() || name == "us-ascii" || name == "utf-8" || name == "utf8") {
+        return QString::fromUtf8(bytes);
+    }
+    QStringDecoder decoder(name.constData());
+    if (!decoder.isValid()) {
+        return QString::fromLatin1(bytes);
+    }
+    return decoder.decode(bytes);
+}
+
+QByteArray decodeQuotedPrintable(const QByteArray& data, bool underscoreIsSpace)
+{
+    QByteArray out;
+    out.reserve(data.size());
+    for (qsizetype i = 0; i < data.size(); ++i) {
+        const char ch = data.at(i);
+        if (ch == '_' && underscoreIsSpace) {
+            out.append(' ');
+        } else if (ch == '=' && i + 1 < data.size()) {
+            if (data.at(i + 1) == '\n') {
+                ++i;  // soft line break
+            } else if (data.at(i + 1) == '\r' && i + 2 < data.size() && data.at(i + 2) == '\n') {
+                i += 2;
+            } else if (i + 2 < data.size()) {
+                bool ok = false;
+                const int value = data.mid(i + 1, 2).toInt(&ok, 16);
+                if (ok) {
+                    out.append(static_cast<char>(value));
+                    i += 2;
+                } else {
+                    out.append(ch);
+                }
+            } else {
+                out.append(ch);
+            }
+        } else {
+            out.append(ch);
+        }
+    }
+    return out;
+}
+
+// RFC 2047: =?charset?B?...?= and =?charset?Q?...?=, with the whitespace
+// between two encoded words dropped.
+QString decodeHeader(const QByteArray& raw)
+{
+    static const QRegularExpression kEncodedWord(
+        QStringLiteral("=\\?([^?]+)\\?([bBqQ])\\?([^?]*)\\?="));
+    const QString text = QString::fromLatin1(raw);
+    QString out;
+    qsizetype last = 0;
+    bool previousWasEncoded = false;
+    auto it = kEncodedWord.globalMatch(text);
+    while (it.hasNext()) {
+        const QRegularExpressionMatch match = it.next();
+        const QString between = text.mid(last, match.capturedStart() - last);
+        if (!(previousWasEncoded && between.trimmed().isEmpty())) {
+            out += QString::fromUtf8(between.toLatin1());
+        }
+        QByteArray charset = match.captured(1).toLatin1();
+        const qsizetype language = charset.indexOf('*');  // RFC 2231 language suffix
+        if (language >= 0) {
+            charset.truncate(language);
+        }
+        const QByteArray payload = match.captured(3).toLatin1();
+        const QByteArray bytes = match.captured(2).compare(QLatin1String("b"), Qt::CaseInsensitive) == 0
+            ? QByteArray::fromBase64(payload)
+            : decodeQuotedPrintable(payload, true);
+        out += decodeCharset(bytes, charset);
+        last = match.capturedEnd();
+        previousWasEncoded = true;
+    }
+    out += QString::fromUtf8(text.mid(last).toLatin1());
+    return out.simplified();
+}
+
+// `text/plain; charset="utf-8"` -> "text/plain", and parameters by name.
+QByteArray mimeType(const QByteArray& contentType)
+{
+    const qsizetype semicolon = contentType.indexOf(';');
+    return (semicolon < 0 ? contentType : contentType.left(semicolon)).trimmed().toLower();
+}
+
+QByteArray mimeParameter(const QByteArray& contentType, const char* name)
+{
+    const QByteArray key = QByteArray(name) + '=';
+    for (const QByteArray& piece : contentType.split(';')) {
+        const QByteArray trimmed = piece.trimmed();
+        if (trimmed.toLower().startsWith(key)) {
+            QByteArray value = trimmed.mid(key.size()).trimmed();
+            if (value.size() >= 2 && value.startsWith('"') && value.endsWith('"')) {
+                value = value.mid(1, value.size() - 2);
+            }
+            return value;
+        }
+    }
+    return QByteArray();
+}
+
+QByteArray decodeTransfer(const QByteArray& body, const QByteArray& encoding)
+{
+    const QByteArray name = encoding.trimmed().toLower();
+    if (name == "base64") {
+        return QByteArray::fromBase64(body);
+    }
+    if (name == "quoted-printable") {
+        return decodeQuotedPrintable(body, false);
+    }
+    return body;
+}
+
+QString stripHtml(const QString& html)
+{
+    static const QRegularExpression kHidden(
+        QStringLiteral("<(style|script|head)\\b[^>]*>.*?</\\1\\s*>"),
+        QRegularExpression::CaseInsensitiveOption | QRegularExpression::DotMatchesEverythingOption);
+    static const QRegularExpression kBreak(QStringLiteral("<(br|/p|/div|/tr|/li|/h\\d)\\b[^>]*>"),
+                                           QRegularExpression::CaseInsensitiveOption);
+    static const QRegularExpression kTag(QStringLiteral("<[^>]*>"));
+    static const QRegularExpression kNumericEntity(QStringLiteral("&#(x?)([0-9a-fA-F]+);"));
+
+    QString text = html;
+    text.remove(kHidden);
+    text.replace(kBreak, QStringLiteral("\n"));
+    text.replace(kTag, QStringLiteral(" "));
+    QString decoded;
+    qsizetype last = 0;
+    auto it = kNumericEntity.globalMatch(text);
+    while (it.hasNext()) {
+        const QRegularExpressionMatch match = it.next();
+        decoded += text.mid(last, match.capturedStart() - last);
+        bool ok = false;
+        const uint code = match.captured(2).toUInt(&ok, match.captured(1).isEmpty() ? 10 : 16);
+        if (ok && code > 0 && code <= 0x10FFFF) {
+            const char32_t ch = code;
+            decoded += QString::fromUcs4(&ch, 1);
+        }
+        last = match.capturedEnd();
+    }
+    decoded += text.mid(last);
+    decoded.replace(QStringLiteral("&nbsp;"), QStringLiteral(" "));
+    decoded.replace(QStringLiteral("&lt;"), QStringLiteral("<"));
+    decoded.replace(QStringLiteral("&gt;"), QStringLiteral(">"));
+    decoded.replace(QStringLiteral("&quot;"), QStringLiteral("\""));
+    decoded.replace(QStringLiteral("&#39;"), QStringLiteral("'"));
+    decoded.replace(QStringLiteral("&amp;"), QStringLiteral("&"));
+    return decoded;
+}
+
+// Text of a part: text/plain as is, text/html without markup, and for
+// multiparts the plain alternative or every inline text part. Attachments
+// are skipped.
+QString partText(const Part& part, int depth)
+{
+    const QByteArray contentType = headerValue(part.headers, "content-type");
+    const QByteArray type = contentType.isEmpty() ? QByteArray("text/plain") : mimeType(contentType);
+    const QByteArray disposition = mimeType(headerValue(part.headers, "content-disposition"));
+    if (disposition == "attachment") {
+        return QString();
+    }
+
+    if (type.startsWith("multipart/")) {
+        const QByteArray boundary = mimeParameter(contentType, "boundary");
+        if (boundary.isEmpty() || depth >= kMaxMimeDepth) {
+            return QString();
+        }
+        std::vector<Part> children;
+        const QByteArray delimiter = "--" + boundary;
+        qsizetype pos = part.body.indexOf(delimiter);
+        while (pos >= 0) {
+            qsizetype start = pos + delimiter.size();
+            if (part.body.mid(start, 2) == "--") {
+                break;  // closing delimiter
+            }
+            const qsizetype lineEnd = part.body.indexOf('\n', start);
+            if (lineEnd < 0) {
+                break;
+            }
+            start = lineEnd + 1;
+            const qsizetype next = part.body.indexOf(delimiter, start);
+            QByteArray chunk = part.body.mid(start, next < 0 ? -1 : next - start);
+            if (chunk.endsWith('\n')) {
+                chunk.chop(1);
+            }
+            if (chunk.endsWith('\r')) {
+                chunk.chop(1);
+            }
+            children.push_back(splitPart(chunk));
+            pos = next;
+        }
+
+        if (type == "multipart/alternative") {
+            // The last alternative is the richest; prefer plain text.
+            for (const Part& child : children) {
+                if (mimeType(headerValue(child.headers, "content-type")) == "text/plain") {
+                    return partText(child, depth + 1);
+                }
+            }
+            return children.empty() ? QString() : partText(children.back(), depth + 1);
+        }
+        QStringList texts;
+        for (const Part& child : children) {
+            const QString text = partText(child, depth + 1).trimmed();
+            if (!text.isEmpty()) {
+                texts.append(text);
+            }
+        }
+        return texts.join(QStringLiteral("\n\n"));
+    }
+
+    if (type != "text/plain" && type != "text/html") {
+        return QString();
+    }
+    const QByteArray bytes =
+        decodeTransfer(part.body, headerValue(part.headers, "content-transfer-encoding"));
+    const QString text = decodeCharset(bytes, mimeParameter(contentType, "charset"));
+    return type == "text/html" ? stripHtml(text) : text;
+}
+
+// Address lists split at commas outside quotes and angle brackets.
+QStringList splitAddresses(const QString& list)
+{
+    QStringList out;
+    QString current;
+    bool quoted = false;
+    int angle = 0;
+    for (const QChar ch : list) {
+        if (ch == QLatin1Char('"')) {
+            quoted = !quoted;
+        } else if (!quoted && ch == QLatin1Char('<')) {
+            ++angle;
+        } else if (!quoted && ch == QLatin1Char('>')) {
+            angle = std::max(0, angle - 1);
+        } else if (!quoted && angle == 0 && ch == QLatin1Char(',')) {
+            if (!current.trimmed().isEmpty()) {
+                out.append(current.trimmed());
+            }
+            current.clear();
+            continue;
+        }
+        current.append(ch);
+    }
+    if (!current.trimmed().isEmpty()) {
+        out.append(current.trimmed());
+    }
+    return out;
+}
+
+// "Ada Lovelace" of "Ada Lovelace <ada@example.com>", else the address.
+QString displayName(const QString& mailbox)
+{
+    const qsizetype angle = mailbox.indexOf(QLatin1Char('<'));
+    QString name = angle > 0 ? mailbox.left(angle).trimmed() : QString();
+    if (name.size() >= 2 && name.startsWith(QLatin1Char('"')) && name.endsWith(QLatin1Char('"'))) {
+        name = name.mid(1, name.size() - 2).trimmed();
+    }
+    return name.isEmpty() ? MailSource::address(mailbox) : name;
+}
+
+} // namespace
+
+QString MailSource::storePath(const QString& homePath)
+{
+    const QDir mail(homePath + QStringLiteral("/Library/Mail"));
+    int newest = -1;
+    QString path;
+    for (const QString& name : mail.entryList({QStringLiteral("V*")}, QDir::Dirs)) {
+        bool ok = false;
+        const int version = name.mid(1).toInt(&ok);
+        if (ok && version > newest) {
+            newest = version;
+            path = mail.filePath(name);
+        }
+    }
+    return path;
+}
+
+std::vector<MailMailbox> MailSource::mailboxes(const QString& storePath)
+{
+    std::vector<MailMailbox> out;
+    const QDir store(storePath);
+    for (const QString& account : store.entryList(QDir::Dirs | QDir::NoDotAndDotDot, QDir::Name)) {
+        if (account == QLatin1String("MailData")) {
+            continue;  // Mail's own databases, not an account
+        }
+        const QString accountPath = store.filePath(account);
+        QDirIterator it(accountPath, {QStringLiteral("*.mbox")}, QDir::Dirs,
+                        QDirIterator::Subdirectories);
+        while (it.hasNext()) {
+            const QString path = it.next();
+            QStringList names;
+            for (const QString& component :
+                 QDir(accountPath).relativeFilePath(path).split(QLatin1Char('/'))) {
+                if (component.endsWith(QLatin1String(".mbox"))) {
+                    names.append(component.chopped(5));
+                }
+            }
+            MailMailbox mailbox;
+            mailbox.accountId = account;
+            mailbox.name = names.join(QLatin1Char('/'));
+            mailbox.path = path;
+            out.push_back(std::move(mailbox));
+        }
+    }
+    std::sort(out.begin(), out.end(), [](const MailMailbox& a, const MailMailbox& b) {
+        return a.path < b.path;
+    });
+    return out;
+}
+
+QStringList MailSource::messageFiles(const MailMailbox& mailbox)
+{
+    QStringList files;
+    const QDir root(mailbox.path);
+    QDirIterator it(mailbox.path, {QStringLiteral("*.emlx")}, QDir::Files,
+                    QDirIterator::Subdirectories);
+    while (it.hasNext()) {
+        const QString path = it.next();
+        // Nested mailboxes are mailboxes of their own.
+        if (root.relativeFilePath(path).contains(QLatin1String(".mbox/"))) {
+            continue;
+        }
+        files.append(path);
+    }
+    files.sort();
+    return files;
+}
+
+std::optional<MailMessage> MailSource::parseEmlx(const QByteArray& data)
+{
+    const qsizetype newline = data.indexOf('\n');
+    if (newline <= 0) {
+        return std::nullopt;
+    }
+    bool ok = false;
+    const qsizetype length = data.left(newline).trimmed().toLongLong(&ok);
+    if (!ok || length <= 0) {
+        return std::nullopt;
+    }
+    return parseMessage(data.mid(newline + 1, length));
+}
+
+std::optional<MailMessage> MailSource::parseMessage(const QByteArray& message)
+{
+    const Part part = splitPart(message);
+    if (part.headers.empty()) {
+        return std::nullopt;
+    }
+
+    MailMessage out;
+    out.messageId = decodeHeader(headerValue(part.headers, "message-id"));
+    if (out.messageId.startsWith(QLatin1Char('<')) && out.messageId.endsWith(QLatin1Char('>'))) {
+        out.messageId = out.messageId.mid(1, out.messageId.size() - 2);
+    }
+    out.subject = decodeHeader(headerValue(part.headers, "subject"));
+    out.from = decodeHeader(headerValue(part.headers, "from"));
+    for (const char* name : {"to", "cc"}) {
+        for (const Header& header : part.headers) {
+            if (header.name == name) {
+                out.to.append(splitAddresses(decodeHeader(header.value)));
+            }
+        }
+    }
+    out.to.removeDuplicates();
+
+    QString date = QString::fromLatin1(headerValue(part.headers, "date")).simplified();
+    // "Tue, 14 Oct 2026 09:30:00 +0200 (CEST)": Qt wants no comment.
+    const qsizetype comment = date.indexOf(QLatin1Char('('));
+    if (comment > 0) {
+        date = date.left(comment).trimmed();
+    }
+    const QDateTime parsed = QDateTime::fromString(date, Qt::RFC2822Date);
+    if (parsed.isValid()) {
+        out.date = static_cast<double>(parsed.toSecsSinceEpoch());
+    }
+
+    out.body = TextCleaner::clean(partText(part, 0)).left(kMaxBodyChars);
+    if (out.subject.isEmpty() && out.from.isEmpty() && out.body.isEmpty()) {
+        return std::nullopt;
+    }
+    return out;
+}
+
+QString MailSource::address(const QString& mailbox)
+{
+    const qsizetype open = mailbox.lastIndexOf(QLatin1Char('<'));
+    const qsizetype close = mailbox.lastIndexOf(QLatin1Char('>'));
+    const QString address = (open >= 0 && close > open)
+        ? mailbox.mid(open + 1, close - open - 1)
+        : mailbox;
+    return address.trimmed().toLower();
+}
+
+bool MailSource::isExcluded(const QString& accountId, const QStringList& accountAddresses,
+                            const QStringList& excluded)
+{
+    for (const QString& entry : excluded) {
+        const QString trimmed = entry.trimmed();
+        if (trimmed.isEmpty()) {
+            continue;
+        }
+        if (trimmed.compare(accountId, Qt::CaseInsensitive) == 0
+            || accountAddresses.contains(trimmed, Qt::CaseInsensitive)) {
+            return true;
+        }
+    }
+    return false;
+}
+
+QString MailSource::accountAddress(const QString& accountId)
+{
+    // IMAP-ada@imap.example.com, POP-ada@example.com@pop.example.com,
+    // EWS-ada@example.com.
+    const qsizetype dash = accountId.indexOf(QLatin1Char('-'));
+    const qsizetype at = accountId.indexOf(QLatin1Char('@'));
+    if (dash <= 0 || at < dash) {
+        return QString();
+    }
+    const QString rest = accountId.mid(dash + 1);
+    const qsizetype lastAt = rest.lastIndexOf(QLatin1Char('@'));
+    const qsizetype firstAt = rest.indexOf(QLatin1Char('@'));
+    // With two @, the part before the server is the address.
+    return (lastAt != firstAt ? rest.left(lastAt) : rest).toLower();
+}
+
+DonatedItem MailSource::item(const MailMessage& message, const MailMailbox& mailbox,
+                             const QString& identifier)
+{
+    DonatedItem out;
+    out.name = message.subject.isEmpty() ? QStringLiteral("(No Subject)") : message.subject;
+    out.path = SpotlightDonation::itemPath(QString::fromLatin1(kBundleIdentifier), identifier);
+    out.parentPath = SpotlightDonation::bundlePath(QString::fromLatin1(kBundleIdentifier));
+
+    const QString sender = displayName(message.from);
+    const QString description = sender.isEmpty()
+        ? mailbox.name
+        : QStringLiteral("%1 · %2").arg(sender, mailbox.name);
+    QStringList lines = {out.name, message.from};
+    lines.append(message.to);
+    lines.append(message.body);
+    lines.removeAll(QString());
+    out.textContent = lines.join(QLatin1Char('\n'));
+    out.size = out.textContent.toUtf8().size();
+    out.createdAt = message.date;
+    out.modifiedAt = message.date;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"), QString::fromLatin1(kContentType), false});
+    raw.push_back({QStringLiteral("title"), message.subject, true});
+    raw.push_back({QStringLiteral("authors"), message.from, true});
+    for (const QString& recipient : message.to) {
+        raw.push_back({QString::fromLatin1(kRecipientsAttribute), recipient, true});
+    }
+    raw.push_back({QString::fromLatin1(kMailboxAttribute), mailbox.name, false});
+    raw.push_back({QString::fromLatin1(kAccountAttribute), mailbox.accountId, false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute), description,
+                   false});
+    if (!message.messageId.isEmpty()) {
+        raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                       messageUrl(message.messageId), false});
+    }
+    out.attributes = SpotlightMetadata::normalize(raw);
+    out.contentHash = SpotlightDonation::contentHash(out);
+    return out;
+}
+
+QString MailSource::identifier(const MailMailbox& mailbox, const QString& filePath)
+{
+    QString name = QFileInfo(filePath).fileName();
+    for (const QLatin1String suffix : {QLatin1String(".partial.emlx"), QLatin1String(".emlx")}) {
+        if (name.endsWith(suffix)) {
+            name.chop(suffix.size());
+            break;
+        }
+    }
+    return QStringLiteral("%1/%2/%3").arg(mailbox.accountId, mailbox.name, name);
+}
+
+QString MailSource::messageUrl(const QString& messageId)
+{
+    return QStringLiteral("message://")
+           + QString::fromLatin1(QUrl::toPercentEncoding(
+               QLatin1Char('<') + messageId + QLatin1Char('>'), QByteArrayLiteral("@")));
+}
+
+} // namespace bs
@@ -0,0 +1,96 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One message of Mail.app's store, with the fields the index maps.
+struct MailMessage {
+    QString messageId;    // Message-ID without the angle brackets
+    QString subject;
+    QString from;         // "Ada Lovelace <ada@example.com>"
+    QStringList to;       // To and Cc, one address each
+    double date = 0.0;    // epoch seconds, from the Date header
+    QString body;         // text/plain, else text/html without markup
+    bool partial = false; // .partial.emlx: Mail has not downloaded the body
+};
+
+// A mailbox (.mbox folder) of one account on disk.
+struct MailMailbox {
+    QString accountId;  // the account's folder: a UUID, or IMAP-<user>@<host>
+    QString name;       // "INBOX", "Archive/2026"
+    QString path;       // the .mbox folder
+};
+
+// MailSource -- the opt-in source that indexes Mail.app's on-disk store
+// (~/Library/Mail/V<n>/<account>/<mailbox>.mbox/.../Messages/<id>.emlx), so
+// mail is searched by sender, recipient, subject, date and body next to
+// files. Messages are written as donations from com.apple.mail (see
+// SpotlightDonation) with their mailbox and account as attributes, which
+// search results report as facets; opening one opens it in Mail through its
+// message:// URL. Accounts can be left out by id or address (kExcludedAccountsKey).
+class MailSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.mail";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_mail";
+    // JSON array of account ids ("4F1C...", "IMAP-ada@imap.example.com") or
+    // addresses whose mail is not indexed.
+    static constexpr const char* kExcludedAccountsKey = "mail_excluded_accounts";
+    static constexpr const char* kContentType = "com.apple.mail.emlx";
+
+    // Stored attributes beyond SpotlightMetadata's title and authors.
+    static constexpr const char* kRecipientsAttribute = "recipients";
+    static constexpr const char* kMailboxAttribute = "mailbox";
+    static constexpr const char* kAccountAttribute = "account";
+
+    // The newest messages indexed per sync, and the body text kept of each.
+    static constexpr int kMaxMessages = 50000;
+    static constexpr int kMaxBodyChars = 20000;
+
+    // The newest V<n> folder under `homePath`/Library/Mail, or empty.
+    static QString storePath(const QString& homePath);
+
+    // Every mailbox of the store, nested mailboxes included.
+    static std::vector<MailMailbox> mailboxes(const QString& storePath);
+
+    // The .emlx and .partial.emlx files of a mailbox.
+    static QStringList messageFiles(const MailMailbox& mailbox);
+
+    // An .emlx file: a byte count line, the RFC 5322 message, then a plist
+    // of Mail's flags. Nullopt when it is not one.
+    static std::optional<MailMessage> parseEmlx(const QByteArray& data);
+    // The RFC 5322 message itself, MIME and RFC 2047 encoded words decoded.
+    static std::optional<MailMessage> parseMessage(const QByteArray& message);
+
+    // The address in "Ada Lovelace <ada@example.com>", lowercased.
+    static QString address(const QString& mailbox);
+
+    // True when `accountId` or one of `accountAddresses` is in `excluded`
+    // (case-insensitive).
+    static bool isExcluded(const QString& accountId, const QStringList& accountAddresses,
+                           const QStringList& excluded);
+
+    // The address in IMAP-ada@imap.example.com style account ids; empty for
+    // UUIDs.
+    static QString accountAddress(const QString& accountId);
+
+    // `message` of `mailbox`, stored as donated://com.apple.mail/<identifier>.
+    static DonatedItem item(const MailMessage& message, const MailMailbox& mailbox,
+                            const QString& identifier);
+
+    // "<account>/<mailbox>/<file name without extension>".
+    static QString identifier(const MailMailbox& mailbox, const QString& filePath);
+
+    // message://%3C<message id>%3E, which Mail opens the message for.
+    static QString messageUrl(const QString& messageId);
+};
+
+} // namespace bs
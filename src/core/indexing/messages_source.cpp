@@ -0,0 +1,310 @@
+#include "core/indexing/messages_source.h"
+
+#include "core/extraction/text_cleaner.h"
+#include "core/fs/spotlight_metadata.h"
+
+#include <QFile>
+#include <QFileInfo>
+#include <QSet>
+#include <QTemporaryDir>
+#include <QUrl>
+
+#include <sqlite3.h>
+
+namespace bs {
+
+namespace {
+
+// message.date counts from 2001-01-01: seconds before High Sierra,
+// nanoseconds since.
+constexpr double kAppleEpochOffset = 978307200.0;
+constexpr double kNanosecondDates = 1e11;
+
+QString columnText(sqlite3_stmt* stmt, int column)
+{
+    const auto* text = reinterpret_cast<const char*>(sqlite3_column_text(stmt, column));
+    return text ? QString::fromUtf8(text) : QString();
+}
+
+// Messages keeps its database open: read a copy, with the write-ahead log
+// so the latest messages are in.
+sqlite3* openCopy(const QString& path, const QTemporaryDir& scratch, QString* error)
+{
+    if (!QFileInfo::exists(path)) {
+        *error = QStringLiteral("%1 does not exist").arg(path);
+        return nullptr;
+    }
+    if (!scratch.isValid()) {
+        *error = QStringLiteral("No temporary directory for %1").arg(path);
+        return nullptr;
+    }
+    const QString copy = scratch.filePath(QFileInfo(path).fileName());
+    if (!QFile::copy(path, copy)) {
+        *error = QStringLiteral("%1 could not be read").arg(path);
+        return nullptr;
+    }
+    if (QFileInfo::exists(path + QStringLiteral("-wal"))) {
+        QFile::copy(path + QStringLiteral("-wal"), copy + QStringLiteral("-wal"));
+    }
+    sqlite3* db = nullptr;
+    if (sqlite3_open_v2(copy.toUtf8().constData(), &db, SQLITE_OPEN_READWRITE, nullptr)
+        != SQLITE_OK) {
+        *error = QString::fromUtf8(db ? sqlite3_errmsg(db) : "sqlite3_open_v2 failed");
+        sqlite3_close(db);
+        return nullptr;
+    }
+    return db;
+}
+
+QSet<QString> tableColumns(sqlite3* db, const char* table)
+{
+    QSet<QString> columns;
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sql = QByteArrayLiteral("PRAGMA table_info(") + table + ')';
+    if (sqlite3_prepare_v2(db, sql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        return columns;
+    }
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        columns.insert(columnText(stmt, 1).toLower());
+    }
+    sqlite3_finalize(stmt);
+    return columns;
+}
+
+QString firstLine(const QString& text)
+{
+    const qsizetype newline = text.indexOf(QLatin1Char('\n'));
+    return (newline < 0 ? text : text.left(newline)).trimmed();
+}
+
+QString senderLabel(const ChatMessage& message)
+{
+    return message.fromMe ? QStringLiteral("Me") : message.sender;
+}
+
+DonatedItem toItem(const ChatMessage& message)
+{
+    DonatedItem item;
+    item.name = firstLine(message.text).left(MessagesSource::kMaxNameChars);
+    item.path = SpotlightDonation::itemPath(
+        QString::fromLatin1(MessagesSource::kBundleIdentifier), message.guid);
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(MessagesSource::kBundleIdentifier));
+
+    const QString sender = senderLabel(message);
+    QStringList location = {sender};
+    if (message.conversationName != message.sender) {
+        location.append(message.conversationName);
+    }
+    location.removeAll(QString());
+    const QString description = location.join(QStringLiteral(" · "));
+    // The sender and conversation are searchable too: "texts from Ada".
+    QStringList lines = {message.text, message.sender, location.value(1)};
+    lines.removeAll(QString());
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    item.createdAt = message.date;
+    item.modifiedAt = message.date;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(MessagesSource::kContentType), false});
+    raw.push_back({QStringLiteral("authors"), sender, true});
+    raw.push_back({QString::fromLatin1(MessagesSource::kConversationAttribute),
+                   message.conversation, false});
+    raw.push_back({QString::fromLatin1(MessagesSource::kConversationNameAttribute),
+                   message.conversationName, false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute), description,
+                   false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                   MessagesSource::messageUrl(message), false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+QString MessagesSource::storePath(const QString& homePath)
+{
+    return homePath + QStringLiteral("/Library/Messages/chat.db");
+}
+
+std::optional<std::vector<ChatMessage>> MessagesSource::readMessages(const QString& path,
+                                                                    int limit, QString* error)
+{
+    QTemporaryDir scratch;
+    sqlite3* db = openCopy(path, scratch, error);
+    if (!db) {
+        return std::nullopt;
+    }
+
+    const QSet<QString> columns = tableColumns(db, "message");
+    for (const char* required : {"guid", "text", "handle_id", "date", "is_from_me"}) {
+        if (!columns.contains(QString::fromLatin1(required))) {
+            *error = QStringLiteral("%1 has no message.%2").arg(path, QLatin1String(required));
+            sqlite3_close(db);
+            return std::nullopt;
+        }
+    }
+    QStringList filters = {QStringLiteral("m.guid IS NOT NULL")};
+    // Tapbacks and group events ("Ada named the conversation") are rows
+    // too; only plain messages are wanted.
+    if (columns.contains(QStringLiteral("associated_message_type"))) {
+        filters.append(QStringLiteral("COALESCE(m.associated_message_type, 0) = 0"));
+    }
+    if (columns.contains(QStringLiteral("item_type"))) {
+        filters.append(QStringLiteral("COALESCE(m.item_type, 0) = 0"));
+    }
+    const QString attributedBody = columns.contains(QStringLiteral("attributedbody"))
+        ? QStringLiteral("m.attributedBody")
+        : QStringLiteral("NULL");
+    const QString sql =
+        QStringLiteral("SELECT m.guid, m.text, %1, m.is_from_me, m.date, h.id,"
+                       " c.guid, c.display_name, c.chat_identifier"
+                       " FROM message m"
+                       " JOIN chat_message_join j ON j.message_id = m.ROWID"
+                       " JOIN chat c ON c.ROWID = j.chat_id"
+                       " LEFT JOIN handle h ON h.ROWID = m.handle_id"
+                       " WHERE %2 ORDER BY m.date DESC, m.ROWID DESC LIMIT ?")
+            .arg(attributedBody, filters.join(QStringLiteral(" AND ")));
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(db, sql.toUtf8().constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+        sqlite3_close(db);
+        return std::nullopt;
+    }
+    sqlite3_bind_int(stmt, 1, limit);
+
+    std::vector<ChatMessage> messages;
+    int rc = SQLITE_ROW;
+    while ((rc = sqlite3_step(stmt)) == SQLITE_ROW) {
+        ChatMessage message;
+        message.guid = columnText(stmt, 0);
+        message.text = columnText(stmt, 1);
+        if (message.text.trimmed().isEmpty() && sqlite3_column_type(stmt, 2) == SQLITE_BLOB) {
+            const QByteArray data(static_cast<const char*>(sqlite3_column_blob(stmt, 2)),
+                                  sqlite3_column_bytes(stmt, 2));
+            message.text = attributedBodyText(data).value_or(QString());
+        }
+        // Attachments sit in the text as U+FFFC.
+        message.text.remove(QChar(0xFFFC));
+        message.text = TextCleaner::clean(message.text).left(kMaxTextChars);
+        message.fromMe = sqlite3_column_int(stmt, 3) != 0;
+        const double date = sqlite3_column_double(stmt, 4);
+        if (date > 0.0) {
+            message.date = (date > kNanosecondDates ? date / 1e9 : date) + kAppleEpochOffset;
+        }
+        // A sent message's handle is the recipient's.
+        if (!message.fromMe) {
+            message.sender = columnText(stmt, 5).trimmed();
+        }
+        message.conversation = columnText(stmt, 6);
+        message.conversationName = columnText(stmt, 7).trimmed();
+        if (message.conversationName.isEmpty()) {
+            message.conversationName = columnText(stmt, 8).trimmed();
+        }
+        messages.push_back(std::move(message));
+    }
+    if (rc != SQLITE_DONE) {
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+    }
+    sqlite3_finalize(stmt);
+    sqlite3_close(db);
+    if (rc != SQLITE_DONE) {
+        return std::nullopt;
+    }
+    return messages;
+}
+
+std::optional<QString> MessagesSource::attributedBodyText(const QByteArray& data)
+{
+    // The string follows its class name: "NSString", a few type bytes
+    // ending in '+', then the UTF-8 length -- one byte, or 0x81 and a
+    // little-endian 16-bit, or 0x82 and a 32-bit length.
+    const qsizetype classAt = data.indexOf("NSString");
+    if (classAt < 0) {
+        return std::nullopt;
+    }
+    const qsizetype searchFrom = classAt + 8;
+    const qsizetype plus = data.indexOf('+', searchFrom);
+    if (plus < 0 || plus - searchFrom > 8) {
+        return std::nullopt;
+    }
+    qsizetype pos = plus + 1;
+    if (pos >= data.size()) {
+        return std::nullopt;
+    }
+    const auto marker = static_cast<quint8>(data.at(pos++));
+    quint64 length = 0;
+    int lengthBytes = 0;
+    if (marker == 0x81) {
+        lengthBytes = 2;
+    } else if (marker == 0x82) {
+        lengthBytes = 4;
+    } else if (marker < 0x80) {
+        length = marker;
+    } else {
+        return std::nullopt;
+    }
+    if (pos + lengthBytes > data.size()) {
+        return std::nullopt;
+    }
+    for (int i = 0; i < lengthBytes; ++i) {
+        length |= static_cast<quint64>(static_cast<quint8>(data.at(pos + i))) << (8 * i);
+    }
+    pos += lengthBytes;
+    if (length == 0 || length > static_cast<quint64>(data.size() - pos)) {
+        return std::nullopt;
+    }
+    return QString::fromUtf8(data.mid(pos, static_cast<qsizetype>(length)));
+}
+
+std::vector<DonatedItem> MessagesSource::items(const std::vector<ChatMessage>& messages)
+{
+    std::vector<DonatedItem> out;
+    out.reserve(messages.size());
+    QSet<QString> seen;
+    for (const ChatMessage& message : messages) {
+        // A message shared by two chats (a merged SMS/iMessage thread) is
+        // kept in the first.
+        if (message.guid.isEmpty() || message.text.isEmpty() || seen.contains(message.guid)) {
+            continue;
+        }
+        seen.insert(message.guid);
+        out.push_back(toItem(message));
+    }
+    return out;
+}
+
+QStringList MessagesSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                             const std::vector<ChatMessage>& messages)
+{
+    QSet<QString> current;
+    for (const ChatMessage& message : messages) {
+        if (!message.text.isEmpty()) {
+            current.insert(message.guid);
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+QString MessagesSource::messageUrl(const ChatMessage& message)
+{
+    return QStringLiteral("sms://open?message-guid=")
+           + QString::fromLatin1(QUrl::toPercentEncoding(message.guid));
+}
+
+bool MessagesSource::isLocalOnly(const QString& path)
+{
+    return SpotlightDonation::bundleIdentifier(path) == QLatin1String(kBundleIdentifier);
+}
+
+} // namespace bs
@@ -0,0 +1,78 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One message of the Messages database.
+struct ChatMessage {
+    QString guid;              // message.guid
+    QString text;
+    QString sender;            // handle: "+15551234567", "ada@example.com"; empty when sent
+    bool fromMe = false;
+    double date = 0.0;         // epoch seconds
+    QString conversation;      // chat.guid
+    QString conversationName;  // the group's name, else the other party's handle
+};
+
+// MessagesSource -- the opt-in source that indexes Messages (iMessage and
+// SMS) text, so "the address someone texted me" is found by searching. Read
+// from Messages' own database (~/Library/Messages/chat.db, which needs Full
+// Disk Access), written as donations from com.apple.MobileSMS (see
+// SpotlightDonation), and opened back in Messages through an sms: URL.
+//
+// Messages are local-only: searches collapse them to one result per
+// conversation, and nothing outside the app's own socket -- the HTTP API,
+// Raycast, live queries over HTTP -- ever sees them (isLocalOnly).
+class MessagesSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.MobileSMS";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_messages";
+    static constexpr const char* kContentType = "public.message";
+    static constexpr const char* kConversationAttribute = "conversation";
+    static constexpr const char* kConversationNameAttribute = "conversationName";
+
+    // The newest messages indexed; older history is left out.
+    static constexpr int kMaxMessages = 50000;
+    static constexpr int kMaxTextChars = 20000;
+    static constexpr int kMaxNameChars = 120;
+
+    // `homePath`/Library/Messages/chat.db
+    static QString storePath(const QString& homePath);
+
+    // The newest `limit` text messages of the database at `path`, newest
+    // first, or nullopt with *error when it cannot be read. Messages keeps
+    // it open, so a copy is read.
+    static std::optional<std::vector<ChatMessage>> readMessages(const QString& path, int limit,
+                                                               QString* error);
+
+    // The text of a message.attributedBody blob, an NSAttributedString in
+    // NSArchiver's typedstream format; newer macOS leaves message.text NULL
+    // and keeps the text only here. Nullopt when it holds no string.
+    static std::optional<QString> attributedBodyText(const QByteArray& data);
+
+    // The messages as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations.
+    static std::vector<DonatedItem> items(const std::vector<ChatMessage>& messages);
+
+    // The indexed identifiers no longer in `messages`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<ChatMessage>& messages);
+
+    // sms://open?message-guid=<guid>, which Messages opens the message for.
+    static QString messageUrl(const ChatMessage& message);
+
+    // Whether an indexed path is a message, which must not leave the
+    // machine's own UI.
+    static bool isLocalOnly(const QString& path);
+};
+
+} // namespace bs
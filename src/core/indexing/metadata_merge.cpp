@@ -0,0 +1,315 @@
+#include "core/indexing/metadata_merge.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr const char* kValueKey = "value";
+constexpr const char* kChangedAtKey = "changedAt";
+
+bool isKnownField(const QString& field)
+{
+    return field == QLatin1String(MetadataMerge::kTagsField)
+        || field == QLatin1String(MetadataMerge::kPinnedField)
+        || field == QLatin1String(MetadataMerge::kOpenCountField)
+        || field == QLatin1String(MetadataMerge::kLastOpenedAtField);
+}
+
+bool supports(const QString& field, MergePolicy policy)
+{
+    switch (policy) {
+    case MergePolicy::LastWriterWins:
+        return true;
+    case MergePolicy::Max:
+        return field != QLatin1String(MetadataMerge::kTagsField);
+    case MergePolicy::Union:
+        return field == QLatin1String(MetadataMerge::kTagsField);
+    }
+    return false;
+}
+
+// Sorted and without duplicates, so two Macs holding the same tags in
+// another order hold equal values.
+QStringList normalizedTags(QStringList tags)
+{
+    tags.removeAll(QString());
+    std::sort(tags.begin(), tags.end());
+    tags.erase(std::unique(tags.begin(), tags.end()), tags.end());
+    return tags;
+}
+
+QStringList tagsOf(const QJsonValue& value)
+{
+    QStringList tags;
+    for (const QJsonValue& tag : value.toArray()) {
+        tags.append(tag.toString());
+    }
+    return tags;
+}
+
+QJsonArray tagsToJson(const QStringList& tags)
+{
+    QJsonArray json;
+    for (const QString& tag : tags) {
+        json.append(tag);
+    }
+    return json;
+}
+
+// A value of the field's type, normalized; undefined otherwise.
+QJsonValue validValue(const QString& field, const QJsonValue& value)
+{
+    if (field == QLatin1String(MetadataMerge::kTagsField)) {
+        if (!value.isArray()) {
+            return QJsonValue(QJsonValue::Undefined);
+        }
+        for (const QJsonValue& tag : value.toArray()) {
+            if (!tag.isString()) {
+                return QJsonValue(QJsonValue::Undefined);
+            }
+        }
+        return tagsToJson(normalizedTags(tagsOf(value)));
+    }
+    if (field == QLatin1String(MetadataMerge::kPinnedField)) {
+        return value.isBool() ? value : QJsonValue(QJsonValue::Undefined);
+    }
+    if (field == QLatin1String(MetadataMerge::kOpenCountField)) {
+        return value.isDouble() && value.toDouble() >= 0.0
+            ? QJsonValue(value.toInteger())
+            : QJsonValue(QJsonValue::Undefined);
+    }
+    if (field == QLatin1String(MetadataMerge::kLastOpenedAtField)) {
+        return value.isDouble() && value.toDouble() >= 0.0 ? value
+                                                           : QJsonValue(QJsonValue::Undefined);
+    }
+    return QJsonValue(QJsonValue::Undefined);
+}
+
+QByteArray canonical(const QJsonValue& value)
+{
+    return QJsonDocument(QJsonArray{value}).toJson(QJsonDocument::Compact);
+}
+
+double numberOf(const QJsonValue& value)
+{
+    return value.isBool() ? (value.toBool() ? 1.0 : 0.0) : value.toDouble();
+}
+
+} // namespace
+
+MetadataMerge::Policies MetadataMerge::defaultPolicies()
+{
+    return {
+        {QLatin1String(kTagsField), MergePolicy::Union},
+        {QLatin1String(kPinnedField), MergePolicy::LastWriterWins},
+        {QLatin1String(kOpenCountField), MergePolicy::Max},
+        {QLatin1String(kLastOpenedAtField), MergePolicy::Max},
+    };
+}
+
+QString MetadataMerge::policyName(MergePolicy policy)
+{
+    switch (policy) {
+    case MergePolicy::LastWriterWins:
+        return QStringLiteral("last-writer-wins");
+    case MergePolicy::Max:
+        return QStringLiteral("max");
+    case MergePolicy::Union:
+        return QStringLiteral("union");
+    }
+    return QString();
+}
+
+std::optional<MergePolicy> MetadataMerge::policyFromName(const QString& name)
+{
+    for (MergePolicy policy : {MergePolicy::LastWriterWins, MergePolicy::Max, MergePolicy::Union}) {
+        if (name == policyName(policy)) {
+            return policy;
+        }
+    }
+    return std::nullopt;
+}
+
+std::optional<MetadataMerge::Policies> MetadataMerge::parsePolicies(const QString& json,
+                                                                    QString* error)
+{
+    Policies policies = defaultPolicies();
+    if (json.trimmed().isEmpty()) {
+        return policies;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("%1 must be a JSON object of field -> policy")
+                     .arg(QLatin1String(kSettingKey));
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    for (auto it = object.begin(); it != object.end(); ++it) {
+        if (!isKnownField(it.key())) {
+            *error = QStringLiteral("%1: unknown field '%2'")
+                         .arg(QLatin1String(kSettingKey), it.key());
+            return std::nullopt;
+        }
+        const std::optional<MergePolicy> policy = policyFromName(it.value().toString());
+        if (!policy.has_value()) {
+            *error = QStringLiteral("%1: unknown policy '%2' for %3")
+                         .arg(QLatin1String(kSettingKey), it.value().toString(), it.key());
+            return std::nullopt;
+        }
+        if (!supports(it.key(), *policy)) {
+            *error = QStringLiteral("%1: %2 cannot be merged by %3")
+                         .arg(QLatin1String(kSettingKey), it.key(), policyName(*policy));
+            return std::nullopt;
+        }
+        policies[it.key()] = *policy;
+    }
+    return policies;
+}
+
+MetadataValue MetadataMerge::mergeValue(MergePolicy policy, const MetadataValue& a,
+                                        const MetadataValue& b)
+{
+    // The merged value changed when the later side did, so a later local
+    // change still sorts after it.
+    MetadataValue merged;
+    merged.changedAt = std::max(a.changedAt, b.changedAt);
+    switch (policy) {
+    case MergePolicy::LastWriterWins:
+        if (a.changedAt != b.changedAt) {
+            merged.value = a.changedAt > b.changedAt ? a.value : b.value;
+        } else {
+            merged.value = canonical(a.value) >= canonical(b.value) ? a.value : b.value;
+        }
+        break;
+    case MergePolicy::Max:
+        merged.value = numberOf(a.value) >= numberOf(b.value) ? a.value : b.value;
+        break;
+    case MergePolicy::Union:
+        merged.value = tagsToJson(normalizedTags(tagsOf(a.value) + tagsOf(b.value)));
+        break;
+    }
+    return merged;
+}
+
+DocumentMetadata MetadataMerge::merge(const DocumentMetadata& local,
+                                      const DocumentMetadata& remote, const Policies& policies)
+{
+    DocumentMetadata merged = local;
+    for (auto it = remote.begin(); it != remote.end(); ++it) {
+        const auto mine = local.constFind(it.key());
+        if (mine == local.constEnd()) {
+            merged.insert(it.key(), it.value());
+            continue;
+        }
+        const MergePolicy policy =
+            policies.value(it.key(), defaultPolicies().value(it.key(), MergePolicy::LastWriterWins));
+        merged.insert(it.key(), mergeValue(policy, mine.value(), it.value()));
+    }
+    return merged;
+}
+
+QJsonObject MetadataMerge::toJson(const DocumentMetadata& metadata)
+{
+    QJsonObject json;
+    for (auto it = metadata.begin(); it != metadata.end(); ++it) {
+        json[it.key()] = QJsonObject{{QLatin1String(kValueKey), it.value().value},
+                                     {QLatin1String(kChangedAtKey), it.value().changedAt}};
+    }
+    return json;
+}
+
+DocumentMetadata MetadataMerge::fromJson(const QJsonObject& json)
+{
+    DocumentMetadata metadata;
+    for (auto it = json.begin(); it != json.end(); ++it) {
+        const QJsonObject entry = it.value().toObject();
+        const QJsonValue value = validValue(it.key(), entry.value(QLatin1String(kValueKey)));
+        if (value.isUndefined()) {
+            continue;
+        }
+        metadata.insert(it.key(),
+                        MetadataValue{value, entry.value(QLatin1String(kChangedAtKey)).toDouble()});
+    }
+    return metadata;
+}
+
+DocumentMetadata MetadataMerge::fromSignals(const SQLiteStore::ItemSignals& itemSignals,
+                                            double indexedAt)
+{
+    DocumentMetadata metadata;
+    const QStringList tags = normalizedTags(itemSignals.tags);
+    if (!tags.isEmpty()) {
+        metadata.insert(QLatin1String(kTagsField), MetadataValue{tagsToJson(tags), indexedAt});
+    }
+    if (itemSignals.pinned || itemSignals.pinChangedAt > 0.0) {
+        metadata.insert(QLatin1String(kPinnedField),
+                        MetadataValue{itemSignals.pinned, itemSignals.pinChangedAt});
+    }
+    if (itemSignals.openCount > 0) {
+        metadata.insert(QLatin1String(kOpenCountField),
+                        MetadataValue{itemSignals.openCount, itemSignals.lastOpenedAt});
+    }
+    if (itemSignals.lastOpenedAt > 0.0) {
+        metadata.insert(QLatin1String(kLastOpenedAtField),
+                        MetadataValue{itemSignals.lastOpenedAt, itemSignals.lastOpenedAt});
+    }
+    return metadata;
+}
+
+bool MetadataMerge::apply(SQLiteStore& store, int64_t itemId, double indexedAt,
+                          const DocumentMetadata& remote, const Policies& policies)
+{
+    if (remote.isEmpty()) {
+        return false;
+    }
+    const auto found = store.getItemSignalsInIdRange(itemId, itemId);
+    const SQLiteStore::ItemSignals localSignals =
+        found.count(itemId) > 0 ? found.at(itemId) : SQLiteStore::ItemSignals{};
+    const DocumentMetadata merged = merge(fromSignals(localSignals, indexedAt), remote, policies);
+    bool changed = false;
+
+    // Only the tags the merge adds to the item's own are written, as sync
+    // tags; the next merge can take those back, never the item's own.
+    const auto tags = merged.constFind(QLatin1String(kTagsField));
+    if (tags != merged.constEnd()) {
+        QSet<QString> own(localSignals.tags.begin(), localSignals.tags.end());
+        for (const QString& tag : localSignals.syncedTags) {
+            own.remove(tag);
+        }
+        QStringList synced;
+        for (const QString& tag : tagsOf(tags->value)) {
+            if (!own.contains(tag)) {
+                synced.append(tag);
+            }
+        }
+        if (synced != normalizedTags(localSignals.syncedTags)) {
+            store.replaceItemTags(itemId, QLatin1String(SQLiteStore::kSyncTagSource), synced);
+            changed = true;
+        }
+    }
+
+    const auto pinned = merged.constFind(QLatin1String(kPinnedField));
+    if (pinned != merged.constEnd() && pinned->value.toBool() != localSignals.pinned) {
+        store.setItemPinned(itemId, pinned->value.toBool(), pinned->changedAt);
+        changed = true;
+    }
+
+    const int openCount =
+        static_cast<int>(merged.value(QLatin1String(kOpenCountField)).value.toInteger());
+    const double lastOpenedAt = merged.value(QLatin1String(kLastOpenedAtField)).value.toDouble();
+    if (openCount != localSignals.openCount || lastOpenedAt != localSignals.lastOpenedAt) {
+        store.setFrequency(itemId, openCount, lastOpenedAt);
+        changed = true;
+    }
+    return changed;
+}
+
+} // namespace bs
@@ -0,0 +1,83 @@
+#pragma once
+
+#include "core/index/sqlite_store.h"
+
+#include <QJsonObject>
+#include <QJsonValue>
+#include <QMap>
+#include <QString>
+
+#include <cstdint>
+#include <optional>
+
+namespace bs {
+
+enum class MergePolicy {
+    LastWriterWins,  // the value changed last
+    Max,             // the greater number; a set flag over a cleared one
+    Union,           // every entry of either list
+};
+
+// One user signal of a document as one Mac holds it, and when it last
+// changed there (0 when unknown).
+struct MetadataValue {
+    QJsonValue value;
+    double changedAt = 0.0;
+};
+
+// Field name (MetadataMerge::k*Field) -> value.
+using DocumentMetadata = QMap<QString, MetadataValue>;
+
+// MetadataMerge -- reconciles the user signals of one file indexed on two
+// Macs (tags, pin, frecency), so syncing an index adds to what the user
+// did on either Mac instead of overwriting it.
+//
+// Every field has a policy, its default or the one kSettingKey names.
+// Each policy is commutative, associative and idempotent, and breaks ties
+// on the value rather than the side, so two Macs merging each other's
+// values in any order, any number of times, end up with the same ones.
+// Tags read from the file itself are never removed: a merge can only
+// change the tags it added.
+class MetadataMerge {
+public:
+    // A JSON object of field -> policy name overriding the defaults, e.g.
+    // {"tags": "last-writer-wins"}.
+    static constexpr const char* kSettingKey = "sync_merge_policies";
+
+    static constexpr const char* kTagsField = "tags";                  // union
+    static constexpr const char* kPinnedField = "pinned";              // last-writer-wins
+    static constexpr const char* kOpenCountField = "openCount";        // max
+    static constexpr const char* kLastOpenedAtField = "lastOpenedAt";  // max
+
+    using Policies = QMap<QString, MergePolicy>;
+
+    static Policies defaultPolicies();
+    // "last-writer-wins", "max", "union".
+    static QString policyName(MergePolicy policy);
+    static std::optional<MergePolicy> policyFromName(const QString& name);
+    // The kSettingKey value over the defaults; empty keeps them. Nullopt
+    // with *error for an unknown field or policy, or a policy the field's
+    // values do not support (max of tags, union of a pin).
+    static std::optional<Policies> parsePolicies(const QString& json, QString* error);
+
+    static MetadataValue mergeValue(MergePolicy policy, const MetadataValue& a,
+                                    const MetadataValue& b);
+    // Field by field; a field only one side has is taken as it is.
+    static DocumentMetadata merge(const DocumentMetadata& local, const DocumentMetadata& remote,
+                                  const Policies& policies);
+
+    static QJsonObject toJson(const DocumentMetadata& metadata);
+    // Unknown fields and values of the wrong type are dropped.
+    static DocumentMetadata fromJson(const QJsonObject& json);
+
+    // An item's signals as stored; its tags count as changed at
+    // `indexedAt`, when the file's tags were last read.
+    static DocumentMetadata fromSignals(const SQLiteStore::ItemSignals& itemSignals,
+                                        double indexedAt);
+    // Merge `remote` into item `itemId` of `store` and write what changed.
+    // Returns whether anything did.
+    static bool apply(SQLiteStore& store, int64_t itemId, double indexedAt,
+                      const DocumentMetadata& remote, const Policies& policies);
+};
+
+} // namespace bs
@@ -0,0 +1,221 @@
+#include "core/indexing/network_share.h"
+
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+#include <QRegularExpression>
+#include <QSet>
+#include <QStorageInfo>
+
+#include <algorithm>
+#include <cmath>
+#include <unordered_map>
+#include <unordered_set>
+
+namespace bs {
+
+namespace {
+
+// Idle time that may be spent at once, per resource.
+constexpr double kBurstMs = 1000.0;
+
+bool overlaps(const QString& a, const QString& b)
+{
+    const auto under = [](const QString& path, const QString& root) {
+        return path == root || path.startsWith(root + QLatin1Char('/'));
+    };
+    return under(a, b) || under(b, a);
+}
+
+} // namespace
+
+ShareThrottle::ShareThrottle(int opsPerSecond, int64_t bytesPerSecond)
+    : m_msPerOp(opsPerSecond > 0 ? 1000.0 / opsPerSecond : 0.0)
+    , m_msPerByte(bytesPerSecond > 0 ? 1000.0 / static_cast<double>(bytesPerSecond) : 0.0)
+{
+}
+
+int64_t ShareThrottle::reserve(int ops, int64_t bytes, int64_t nowMs)
+{
+    const double now = static_cast<double>(nowMs);
+    const auto book = [now](double& readyAtMs, double costMs) {
+        if (costMs <= 0.0) {
+            return 0.0;
+        }
+        readyAtMs = std::max(readyAtMs, now - kBurstMs) + costMs;
+        return std::max(0.0, readyAtMs - now);
+    };
+    const double opsWait = book(m_opsReadyAtMs, ops * m_msPerOp);
+    const double bytesWait = book(m_bytesReadyAtMs, static_cast<double>(bytes) * m_msPerByte);
+    return static_cast<int64_t>(std::ceil(std::max(opsWait, bytesWait)));
+}
+
+std::optional<std::vector<NetworkShare>> NetworkShares::parseShares(const QString& json,
+                                                                     QString* error)
+{
+    std::vector<NetworkShare> shares;
+    if (json.trimmed().isEmpty()) {
+        return shares;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("Network shares must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxShares) {
+        *error = QStringLiteral("At most %1 network shares").arg(kMaxShares);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    static const QRegularExpression validHours(QStringLiteral("^\\s*(\\d{1,2})\\s*-\\s*(\\d{1,2})\\s*$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        NetworkShare share;
+        share.name = object.value(QStringLiteral("name")).toString().trimmed();
+        const QString path = object.value(QStringLiteral("path")).toString().trimmed();
+        share.path = path.isEmpty() ? QString() : QDir::cleanPath(path);
+        share.maxOpsPerSecond = object.value(QStringLiteral("maxOpsPerSecond"))
+                                    .toInt(kDefaultMaxOpsPerSecond);
+        share.maxBytesPerSecond = object.value(QStringLiteral("maxBytesPerSecond"))
+                                      .toInteger(kDefaultMaxBytesPerSecond);
+        share.schedule.intervalMinutes = object.value(QStringLiteral("intervalMinutes"))
+                                             .toInt(kDefaultIntervalMinutes);
+
+        const QString label = share.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : share.name;
+        if (share.name.size() > kMaxNameChars || !validName.match(share.name).hasMatch()) {
+            *error = QStringLiteral("Network share %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (names.contains(share.name.toLower())) {
+            *error = QStringLiteral("Network share %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (!QDir::isAbsolutePath(share.path) || share.path == QLatin1String("/")) {
+            *error = QStringLiteral("Network share %1: the path must be an absolute folder below /")
+                         .arg(label);
+            return std::nullopt;
+        }
+        for (const NetworkShare& other : shares) {
+            if (overlaps(share.path, other.path)) {
+                *error = QStringLiteral("Network shares %1 and %2 overlap").arg(other.name, label);
+                return std::nullopt;
+            }
+        }
+        // 0 is unlimited, and must be said so.
+        if (share.maxOpsPerSecond < 0 || share.maxBytesPerSecond < 0) {
+            *error = QStringLiteral("Network share %1: rates cannot be negative").arg(label);
+            return std::nullopt;
+        }
+        if (share.schedule.intervalMinutes < kMinIntervalMinutes) {
+            *error = QStringLiteral("Network share %1: crawl at most every %2 minutes")
+                         .arg(label)
+                         .arg(kMinIntervalMinutes);
+            return std::nullopt;
+        }
+        const QString hours = object.value(QStringLiteral("hours")).toString();
+        if (!hours.trimmed().isEmpty()) {
+            const QRegularExpressionMatch match = validHours.match(hours);
+            const int start = match.hasMatch() ? match.captured(1).toInt() : -1;
+            const int end = match.hasMatch() ? match.captured(2).toInt() : -1;
+            if (start < 0 || start > 23 || end < 0 || end > 24 || start == end % 24) {
+                *error = QStringLiteral("Network share %1: hours must look like \"22-6\"").arg(label);
+                return std::nullopt;
+            }
+            share.schedule.startHour = start;
+            share.schedule.endHour = end % 24;
+        }
+        names.insert(share.name.toLower());
+        shares.push_back(std::move(share));
+    }
+    return shares;
+}
+
+bool NetworkShares::isMounted(const QString& path)
+{
+    if (!QFileInfo(path).isDir()) {
+        return false;
+    }
+    // An unmounted share leaves its folder under /Volumes on the boot
+    // volume; crawling that would look like every file was deleted.
+    static const QSet<QByteArray> kNetworkFileSystems = {
+        QByteArrayLiteral("smbfs"), QByteArrayLiteral("nfs"),    QByteArrayLiteral("afpfs"),
+        QByteArrayLiteral("webdav"), QByteArrayLiteral("cifs"),  QByteArrayLiteral("nfs4"),
+    };
+    const QStorageInfo storage(path);
+    return storage.isValid() && storage.isReady()
+           && kNetworkFileSystems.contains(storage.fileSystemType().toLower());
+}
+
+bool NetworkShares::isInWindow(const ShareSchedule& schedule, const QTime& localTime)
+{
+    if (schedule.startHour < 0 || schedule.endHour < 0) {
+        return true;
+    }
+    const int hour = localTime.hour();
+    return schedule.startHour < schedule.endHour
+               ? hour >= schedule.startHour && hour < schedule.endHour
+               : hour >= schedule.startHour || hour < schedule.endHour;
+}
+
+bool NetworkShares::isDue(const ShareSchedule& schedule, int64_t lastCompletedMs,
+                          const QDateTime& now)
+{
+    if (!isInWindow(schedule, now.toLocalTime().time())) {
+        return false;
+    }
+    return lastCompletedMs <= 0
+           || now.toMSecsSinceEpoch() - lastCompletedMs
+                  >= static_cast<int64_t>(schedule.intervalMinutes) * 60 * 1000;
+}
+
+int64_t NetworkShares::retryDelayMs(int failures)
+{
+    if (failures <= 0) {
+        return 0;
+    }
+    const int doublings = std::min(failures - 1, 16);
+    return std::min(kRetryBaseMs << doublings, kRetryMaxMs);
+}
+
+ShareChanges NetworkShares::diff(const std::vector<FileMetadata>& scanned,
+                                 const std::vector<SQLiteStore::ItemRow>& indexed)
+{
+    std::unordered_map<std::string, const SQLiteStore::ItemRow*> rows;
+    for (const SQLiteStore::ItemRow& row : indexed) {
+        if (itemKindFromString(row.kind) != ItemKind::Directory) {
+            rows.emplace(row.path.toStdString(), &row);
+        }
+    }
+
+    ShareChanges changes;
+    std::unordered_set<std::string> seen;
+    for (const FileMetadata& meta : scanned) {
+        seen.insert(meta.filePath);
+        const auto it = rows.find(meta.filePath);
+        // The scan has whole seconds, the index what stat() said.
+        if (it != rows.end() && it->second->size == static_cast<int64_t>(meta.fileSize)
+            && std::abs(it->second->modifiedAt - meta.modifiedAt) < 1.0) {
+            ++changes.unchanged;
+        } else {
+            changes.changed.push_back(meta);
+        }
+    }
+    for (const auto& [path, row] : rows) {
+        if (seen.count(path) == 0) {
+            changes.removed.push_back(path);
+        }
+    }
+    std::sort(changes.removed.begin(), changes.removed.end());
+    return changes;
+}
+
+} // namespace bs
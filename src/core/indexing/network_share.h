@@ -0,0 +1,120 @@
+#pragma once
+
+#include "core/index/sqlite_store.h"
+#include "core/shared/types.h"
+
+#include <QDateTime>
+#include <QString>
+
+#include <cstdint>
+#include <optional>
+#include <string>
+#include <vector>
+
+namespace bs {
+
+// When a share is crawled: every `intervalMinutes`, and only inside the
+// local-time window [startHour, endHour) when one is set (22 to 6 wraps
+// past midnight).
+struct ShareSchedule {
+    int intervalMinutes = 0;
+    int startHour = -1;    // -1: any time of day
+    int endHour = -1;
+};
+
+// A mounted SMB, NFS or AFP share (or a folder on one) to crawl.
+struct NetworkShare {
+    QString name;          // label, letters, digits and '-'
+    QString path;          // absolute: /Volumes/Projects, /Volumes/Projects/2026
+    int maxOpsPerSecond = 0;       // directory listings and stats
+    int64_t maxBytesPerSecond = 0; // file content read for extraction
+    ShareSchedule schedule;
+};
+
+// What one crawl found against the index.
+struct ShareChanges {
+    std::vector<FileMetadata> changed;  // new, or size or mtime differ
+    std::vector<std::string> removed;   // indexed, no longer on the share
+    size_t unchanged = 0;
+};
+
+// ShareThrottle -- paces a crawl to an operation rate and a byte rate.
+//
+// Each call books its cost and says how long to wait before doing it; up
+// to a second's budget passes straight away after an idle spell. A large
+// file waits for its whole size and is then read at the share's speed, so
+// the rate holds on average rather than at every instant. A rate of 0 is
+// unlimited. Not thread-safe: one crawl owns one throttle.
+class ShareThrottle {
+public:
+    ShareThrottle(int opsPerSecond, int64_t bytesPerSecond);
+
+    // Milliseconds to wait at `nowMs` before `ops` operations that read
+    // `bytes` bytes.
+    int64_t reserve(int ops, int64_t bytes, int64_t nowMs);
+
+private:
+    double m_msPerOp = 0.0;
+    double m_msPerByte = 0.0;
+    double m_opsReadyAtMs = 0.0;
+    double m_bytesReadyAtMs = 0.0;
+};
+
+// NetworkShares -- indexing mounted network shares without saturating the
+// network they are on.
+//
+// The `network_shares` setting (settings.json `networkShares`) lists the
+// shares. A share is not a watched root: FSEvents does not see changes
+// made by other machines, so each one is crawled on its own schedule,
+// paced by its ShareThrottle. The crawl stats the tree, compares it with
+// the index by size and mtime, and queues only what changed on the
+// regular pipeline; a file's content is read once its bytes fit the
+// budget. A share that is not mounted (or answers for the boot volume
+// instead) is offline: nothing is removed, and it is tried again after
+// retryDelayMs(). Files are indexed under their own paths, so results
+// open as any other file.
+//
+// Pure logic here; the crawl itself runs in IndexerService.
+class NetworkShares {
+public:
+    static constexpr const char* kSettingKey = "network_shares";
+    // {name: {path, lastCompletedMs, failures, nextAttemptMs}}.
+    static constexpr const char* kStateSettingKey = "network_share_state";
+
+    static constexpr int kMaxShares = 16;
+    static constexpr int kMaxNameChars = 32;
+    static constexpr int kDefaultIntervalMinutes = 60;
+    static constexpr int kMinIntervalMinutes = 5;
+    static constexpr int kDefaultMaxOpsPerSecond = 200;
+    static constexpr int64_t kDefaultMaxBytesPerSecond = 4 * 1024 * 1024;
+    static constexpr int64_t kRetryBaseMs = 60 * 1000;
+    static constexpr int64_t kRetryMaxMs = 60 * 60 * 1000;
+
+    // The setting's JSON array of {name, path, maxOpsPerSecond?,
+    // maxBytesPerSecond?, intervalMinutes?, hours?: "22-6"}. Empty text is
+    // no shares; nullopt with *error when an entry is invalid, a name
+    // repeats or two paths overlap.
+    static std::optional<std::vector<NetworkShare>> parseShares(const QString& json,
+                                                                QString* error);
+
+    // Whether `path` is a reachable folder on a network file system
+    // (smbfs, nfs, afpfs, webdav), rather than the empty mount point left
+    // behind on the boot volume.
+    static bool isMounted(const QString& path);
+
+    static bool isInWindow(const ShareSchedule& schedule, const QTime& localTime);
+    // Whether a share last crawled completely at `lastCompletedMs` (0:
+    // never) is due at `now`.
+    static bool isDue(const ShareSchedule& schedule, int64_t lastCompletedMs,
+                      const QDateTime& now);
+    // Backoff after `failures` offline or failed attempts in a row:
+    // 1, 2, 4 ... minutes, at most an hour.
+    static int64_t retryDelayMs(int failures);
+
+    // `scanned` (a complete crawl of the share) against the indexed items
+    // under it. Directories are left to the crawl that lists them.
+    static ShareChanges diff(const std::vector<FileMetadata>& scanned,
+                             const std::vector<SQLiteStore::ItemRow>& indexed);
+};
+
+} // namespace bs
@@ -0,0 +1,388 @@
+#include "core/indexing/notes_source.h"
+
+#include "core/extraction/text_cleaner.h"
+#include "core/fs/spotlight_metadata.h"
+
+#include <QFile>
+#include <QFileInfo>
+#include <QSet>
+#include <QTemporaryDir>
+#include <QUrl>
+
+#include <sqlite3.h>
+#include <zlib.h>
+
+namespace bs {
+
+namespace {
+
+// Core Data counts seconds from 2001-01-01.
+constexpr double kCoreDataEpochOffset = 978307200.0;
+// A note's document is a few hundred kilobytes at most; anything larger is
+// not one.
+constexpr qsizetype kMaxInflatedBytes = 64 * 1024 * 1024;
+// ZFOLDERTYPE of Recently Deleted.
+constexpr int kTrashFolderType = 1;
+
+QString columnText(sqlite3_stmt* stmt, int column)
+{
+    const auto* text = reinterpret_cast<const char*>(sqlite3_column_text(stmt, column));
+    return text ? QString::fromUtf8(text) : QString();
+}
+
+std::optional<QByteArray> gunzip(const QByteArray& data)
+{
+    z_stream stream{};
+    // 32 + MAX_WBITS: gzip or zlib header, whichever it has.
+    if (inflateInit2(&stream, 32 + MAX_WBITS) != Z_OK) {
+        return std::nullopt;
+    }
+    stream.next_in = reinterpret_cast<Bytef*>(const_cast<char*>(data.constData()));
+    stream.avail_in = static_cast<uInt>(data.size());
+
+    QByteArray out;
+    char buffer[16384];
+    int rc = Z_OK;
+    while (rc == Z_OK) {
+        stream.next_out = reinterpret_cast<Bytef*>(buffer);
+        stream.avail_out = sizeof(buffer);
+        rc = inflate(&stream, Z_NO_FLUSH);
+        if (rc != Z_OK && rc != Z_STREAM_END) {
+            break;
+        }
+        out.append(buffer, static_cast<qsizetype>(sizeof(buffer) - stream.avail_out));
+        if (out.size() > kMaxInflatedBytes) {
+            rc = Z_MEM_ERROR;
+            break;
+        }
+    }
+    inflateEnd(&stream);
+    if (rc != Z_STREAM_END) {
+        return std::nullopt;
+    }
+    return out;
+}
+
+bool readVarint(const QByteArray& data, qsizetype* pos, quint64* value)
+{
+    *value = 0;
+    for (int shift = 0; shift < 64 && *pos < data.size(); shift += 7) {
+        const auto byte = static_cast<quint8>(data.at((*pos)++));
+        *value |= static_cast<quint64>(byte & 0x7F) << shift;
+        if ((byte & 0x80) == 0) {
+            return true;
+        }
+    }
+    return false;
+}
+
+// The first length-delimited field `number` of a protobuf message; every
+// other field is skipped.
+std::optional<QByteArray> protobufField(const QByteArray& message, quint64 number)
+{
+    qsizetype pos = 0;
+    while (pos < message.size()) {
+        quint64 key = 0;
+        if (!readVarint(message, &pos, &key)) {
+            return std::nullopt;
+        }
+        quint64 length = 0;
+        switch (key & 0x7) {
+        case 0:  // varint
+            if (!readVarint(message, &pos, &length)) {
+                return std::nullopt;
+            }
+            continue;
+        case 1:  // 64-bit
+            length = 8;
+            break;
+        case 2:  // length-delimited
+            if (!readVarint(message, &pos, &length)) {
+                return std::nullopt;
+            }
+            if (length > static_cast<quint64>(message.size() - pos)) {
+                return std::nullopt;
+            }
+            if ((key >> 3) == number) {
+                return message.mid(pos, static_cast<qsizetype>(length));
+            }
+            break;
+        case 5:  // 32-bit
+            length = 4;
+            break;
+        default:  // groups, long deprecated
+            return std::nullopt;
+        }
+        if (length > static_cast<quint64>(message.size() - pos)) {
+            return std::nullopt;
+        }
+        pos += static_cast<qsizetype>(length);
+    }
+    return std::nullopt;
+}
+
+// Notes keeps its database open: read a copy, with the write-ahead log so
+// the latest edits are in.
+sqlite3* openCopy(const QString& path, const QTemporaryDir& scratch, QString* error)
+{
+    if (!QFileInfo::exists(path)) {
+        *error = QStringLiteral("%1 does not exist").arg(path);
+        return nullptr;
+    }
+    if (!scratch.isValid()) {
+        *error = QStringLiteral("No temporary directory for %1").arg(path);
+        return nullptr;
+    }
+    const QString copy = scratch.filePath(QFileInfo(path).fileName());
+    if (!QFile::copy(path, copy)) {
+        *error = QStringLiteral("%1 could not be read").arg(path);
+        return nullptr;
+    }
+    if (QFileInfo::exists(path + QStringLiteral("-wal"))) {
+        QFile::copy(path + QStringLiteral("-wal"), copy + QStringLiteral("-wal"));
+    }
+    sqlite3* db = nullptr;
+    if (sqlite3_open_v2(copy.toUtf8().constData(), &db, SQLITE_OPEN_READWRITE, nullptr)
+        != SQLITE_OK) {
+        *error = QString::fromUtf8(db ? sqlite3_errmsg(db) : "sqlite3_open_v2 failed");
+        sqlite3_close(db);
+        return nullptr;
+    }
+    return db;
+}
+
+QSet<QString> tableColumns(sqlite3* db, const char* table)
+{
+    QSet<QString> columns;
+    sqlite3_stmt* stmt = nullptr;
+    const QByteArray sql = QByteArrayLiteral("PRAGMA table_info(") + table + ')';
+    if (sqlite3_prepare_v2(db, sql.constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        return columns;
+    }
+    while (sqlite3_step(stmt) == SQLITE_ROW) {
+        columns.insert(columnText(stmt, 1).toUpper());
+    }
+    sqlite3_finalize(stmt);
+    return columns;
+}
+
+// Each macOS release renumbers ZICCLOUDSYNCINGOBJECT's shared columns
+// (ZTITLE1, ZCREATIONDATE3, ...): `alias.<first present>`, else NULL.
+QString pickColumn(const QSet<QString>& columns, const char* alias,
+                   std::initializer_list<const char*> candidates)
+{
+    for (const char* candidate : candidates) {
+        if (columns.contains(QString::fromLatin1(candidate))) {
+            return QStringLiteral("%1.%2").arg(QLatin1String(alias), QLatin1String(candidate));
+        }
+    }
+    return QStringLiteral("NULL");
+}
+
+QString firstLine(const QString& text)
+{
+    const qsizetype newline = text.indexOf(QLatin1Char('\n'));
+    return (newline < 0 ? text : text.left(newline)).trimmed();
+}
+
+DonatedItem toItem(const AppleNote& note)
+{
+    DonatedItem item;
+    item.name = note.title;
+    item.path = SpotlightDonation::itemPath(QString::fromLatin1(NotesSource::kBundleIdentifier),
+                                            note.identifier);
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(NotesSource::kBundleIdentifier));
+
+    QStringList location = {note.folder, note.account};
+    location.removeAll(QString());
+    const QString description = location.join(QStringLiteral(" · "));
+    // The body starts with the title line; don't index it twice.
+    const QString body = note.body.startsWith(note.title)
+        ? note.body.mid(note.title.size()).trimmed()
+        : note.body;
+    QStringList lines = {note.title, body};
+    lines.removeAll(QString());
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    item.createdAt = note.createdAt;
+    item.modifiedAt = note.modifiedAt;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(NotesSource::kContentType), false});
+    raw.push_back({QStringLiteral("title"), note.title, true});
+    raw.push_back({QString::fromLatin1(NotesSource::kFolderAttribute), note.folder, false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute), description,
+                   false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                   NotesSource::noteUrl(note), false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+QString NotesSource::storePath(const QString& homePath)
+{
+    return homePath
+           + QStringLiteral("/Library/Group Containers/group.com.apple.notes/NoteStore.sqlite");
+}
+
+std::optional<std::vector<AppleNote>> NotesSource::readNotes(const QString& path, QString* error)
+{
+    QTemporaryDir scratch;
+    sqlite3* db = openCopy(path, scratch, error);
+    if (!db) {
+        return std::nullopt;
+    }
+
+    const QSet<QString> columns = tableColumns(db, "ZICCLOUDSYNCINGOBJECT");
+    for (const char* required : {"ZIDENTIFIER", "ZNOTEDATA", "ZFOLDER"}) {
+        if (!columns.contains(QString::fromLatin1(required))) {
+            // Not a store we know: pre-High Sierra, or a future layout.
+            *error = QStringLiteral("%1 has no ZICCLOUDSYNCINGOBJECT.%2")
+                         .arg(path, QLatin1String(required));
+            sqlite3_close(db);
+            return std::nullopt;
+        }
+    }
+    QStringList filters = {QStringLiteral("n.ZIDENTIFIER IS NOT NULL")};
+    if (columns.contains(QStringLiteral("ZMARKEDFORDELETION"))) {
+        filters.append(QStringLiteral("COALESCE(n.ZMARKEDFORDELETION, 0) = 0"));
+    }
+    if (columns.contains(QStringLiteral("ZFOLDERTYPE"))) {
+        filters.append(QStringLiteral("COALESCE(f.ZFOLDERTYPE, 0) <> %1").arg(kTrashFolderType));
+    }
+    const QString account = columns.contains(QStringLiteral("ZOWNER"))
+        ? QStringLiteral(" LEFT JOIN ZICCLOUDSYNCINGOBJECT a ON a.Z_PK = f.ZOWNER")
+        : QString();
+    const QString sql =
+        QStringLiteral("SELECT n.ZIDENTIFIER, %1, %2, %3, %4, %5, %6, %7, d.ZDATA"
+                       " FROM ZICCLOUDSYNCINGOBJECT n"
+                       " JOIN ZICNOTEDATA d ON d.Z_PK = n.ZNOTEDATA"
+                       " LEFT JOIN ZICCLOUDSYNCINGOBJECT f ON f.Z_PK = n.ZFOLDER%8"
+                       " WHERE %9 ORDER BY n.Z_PK")
+            .arg(pickColumn(columns, "n", {"ZTITLE1", "ZTITLE"}),
+                 pickColumn(columns, "n", {"ZCREATIONDATE3", "ZCREATIONDATE1", "ZCREATIONDATE"}),
+                 pickColumn(columns, "n", {"ZMODIFICATIONDATE1", "ZMODIFICATIONDATE"}),
+                 pickColumn(columns, "f", {"ZTITLE2", "ZTITLE"}),
+                 account.isEmpty() ? QStringLiteral("NULL") : pickColumn(columns, "a", {"ZNAME"}),
+                 account.isEmpty() ? QStringLiteral("NULL") : QStringLiteral("a.ZIDENTIFIER"),
+                 pickColumn(columns, "n", {"ZISPASSWORDPROTECTED"}), account,
+                 filters.join(QStringLiteral(" AND ")));
+
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(db, sql.toUtf8().constData(), -1, &stmt, nullptr) != SQLITE_OK) {
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+        sqlite3_close(db);
+        return std::nullopt;
+    }
+
+    std::vector<AppleNote> notes;
+    int rc = SQLITE_ROW;
+    while ((rc = sqlite3_step(stmt)) == SQLITE_ROW) {
+        AppleNote note;
+        note.identifier = columnText(stmt, 0);
+        note.title = columnText(stmt, 1).trimmed();
+        if (sqlite3_column_type(stmt, 2) != SQLITE_NULL) {
+            note.createdAt = sqlite3_column_double(stmt, 2) + kCoreDataEpochOffset;
+        }
+        if (sqlite3_column_type(stmt, 3) != SQLITE_NULL) {
+            note.modifiedAt = sqlite3_column_double(stmt, 3) + kCoreDataEpochOffset;
+        }
+        note.folder = columnText(stmt, 4).trimmed();
+        note.account = columnText(stmt, 5).trimmed();
+        note.accountIdentifier = columnText(stmt, 6);
+        note.locked = sqlite3_column_int(stmt, 7) != 0;
+        if (!note.locked) {
+            const QByteArray data(static_cast<const char*>(sqlite3_column_blob(stmt, 8)),
+                                  sqlite3_column_bytes(stmt, 8));
+            note.body = noteText(data).value_or(QString());
+        }
+        if (note.title.isEmpty()) {
+            note.title = firstLine(note.body);
+        }
+        notes.push_back(std::move(note));
+    }
+    if (rc != SQLITE_DONE) {
+        *error = QString::fromUtf8(sqlite3_errmsg(db));
+    }
+    sqlite3_finalize(stmt);
+    sqlite3_close(db);
+    if (rc != SQLITE_DONE) {
+        return std::nullopt;
+    }
+    return notes;
+}
+
+std::optional<QString> NotesSource::noteText(const QByteArray& data)
+{
+    if (data.isEmpty()) {
+        return std::nullopt;
+    }
+    const std::optional<QByteArray> proto = gunzip(data);
+    if (!proto.has_value()) {
+        return std::nullopt;
+    }
+    // NoteStoreProto.document (2) -> Document.note (3) -> Note.note_text (2).
+    const std::optional<QByteArray> document = protobufField(proto.value(), 2);
+    const std::optional<QByteArray> note =
+        document.has_value() ? protobufField(document.value(), 3) : std::nullopt;
+    const std::optional<QByteArray> text =
+        note.has_value() ? protobufField(note.value(), 2) : std::nullopt;
+    if (!text.has_value()) {
+        return std::nullopt;
+    }
+    QString out = QString::fromUtf8(text.value());
+    // Attachments (images, tables, drawings) sit in the text as U+FFFC.
+    out.remove(QChar(0xFFFC));
+    return TextCleaner::clean(out).left(kMaxBodyChars);
+}
+
+std::vector<DonatedItem> NotesSource::items(const std::vector<AppleNote>& notes)
+{
+    std::vector<DonatedItem> out;
+    out.reserve(notes.size());
+    QSet<QString> seen;
+    for (const AppleNote& note : notes) {
+        if (note.identifier.isEmpty() || note.title.isEmpty() || seen.contains(note.identifier)) {
+            continue;
+        }
+        seen.insert(note.identifier);
+        out.push_back(toItem(note));
+    }
+    return out;
+}
+
+QStringList NotesSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                          const std::vector<AppleNote>& notes)
+{
+    QSet<QString> current;
+    for (const AppleNote& note : notes) {
+        if (!note.title.isEmpty()) {
+            current.insert(note.identifier);
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+QString NotesSource::noteUrl(const AppleNote& note)
+{
+    QString url = QStringLiteral("applenotes:note/")
+                  + QString::fromLatin1(QUrl::toPercentEncoding(note.identifier));
+    if (!note.accountIdentifier.isEmpty()) {
+        url += QStringLiteral("?ownerIdentifier=")
+               + QString::fromLatin1(QUrl::toPercentEncoding(note.accountIdentifier));
+    }
+    return url;
+}
+
+} // namespace bs
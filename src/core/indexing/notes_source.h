@@ -0,0 +1,68 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One note of the Notes store.
+struct AppleNote {
+    QString identifier;         // ZIDENTIFIER, a UUID
+    QString title;
+    QString body;               // plain text; empty for a locked note
+    QString folder;             // "Notes", "Recipes"
+    QString account;            // "iCloud", "On My Mac"
+    QString accountIdentifier;  // the account's ZIDENTIFIER
+    double createdAt = 0.0;     // epoch seconds
+    double modifiedAt = 0.0;
+    bool locked = false;        // password-protected: only the title is readable
+};
+
+// NotesSource -- the opt-in source that indexes Apple Notes, so a note's
+// title and text are searched next to files. Notes are read from Notes'
+// own database (NoteStore.sqlite in its group container, which needs Full
+// Disk Access), written as donations from com.apple.Notes (see
+// SpotlightDonation), and open back in Notes through an applenotes: URL.
+// Notes in Recently Deleted are left out; locked notes are indexed by title
+// only.
+class NotesSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.Notes";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_notes";
+    static constexpr const char* kContentType = "com.apple.notes.note";
+    static constexpr const char* kFolderAttribute = "folder";
+
+    static constexpr int kMaxBodyChars = 100000;
+
+    // `homePath`/Library/Group Containers/group.com.apple.notes/NoteStore.sqlite
+    static QString storePath(const QString& homePath);
+
+    // Every note of the database at `path`, or nullopt with *error when it
+    // cannot be read. Notes may have it open, so a copy is read.
+    static std::optional<std::vector<AppleNote>> readNotes(const QString& path, QString* error);
+
+    // The text of a ZICNOTEDATA.ZDATA blob: a gzipped protobuf whose
+    // document holds the note's text. Nullopt when it is not one.
+    static std::optional<QString> noteText(const QByteArray& data);
+
+    // The notes as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations.
+    static std::vector<DonatedItem> items(const std::vector<AppleNote>& notes);
+
+    // The indexed identifiers no longer in `notes`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<AppleNote>& notes);
+
+    // applenotes:note/<identifier>?ownerIdentifier=<account>, which Notes
+    // opens the note for.
+    static QString noteUrl(const AppleNote& note);
+};
+
+} // namespace bs
@@ -0,0 +1,460 @@
+#include "core/indexing/peer_sync.h"
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QCryptographicHash>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QRegularExpression>
+#include <QSet>
+
+#include <algorithm>
+#include <cmath>
+#include <map>
+
+namespace bs {
+
+namespace {
+
+QString parentOf(const QString& path)
+{
+    const qsizetype slash = path.lastIndexOf(QLatin1Char('/'));
+    return slash > 0 ? path.left(slash) : QStringLiteral("/");
+}
+
+} // namespace
+
+std::optional<std::vector<SyncPeer>> PeerSync::parsePeers(const QString& json, QString* error)
+{
+    std::vector<SyncPeer> peers;
+    if (json.trimmed().isEmpty()) {
+        return peers;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("Sync peers must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxPeers) {
+        *error = QStringLiteral("At most %1 sync peers").arg(kMaxPeers);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        SyncPeer peer;
+        peer.name = object.value(QStringLiteral("name")).toString().trimmed();
+        peer.url = QUrl(object.value(QStringLiteral("url")).toString().trimmed());
+        peer.token = object.value(QStringLiteral("token")).toString().trimmed();
+        peer.fingerprint = object.value(QStringLiteral("fingerprint")).toString().trimmed();
+        const QString label = peer.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : peer.name;
+        if (peer.name.size() > kMaxNameChars || !validName.match(peer.name).hasMatch()) {
+            *error = QStringLiteral("Sync peer %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (names.contains(peer.name.toLower())) {
+            *error = QStringLiteral("Sync peer %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (!isAllowedUrl(peer.url)) {
+            *error = QStringLiteral("Sync peer %1: the URL must be https").arg(label);
+            return std::nullopt;
+        }
+        if (peer.token.isEmpty()) {
+            *error = QStringLiteral("Sync peer %1 has no token").arg(label);
+            return std::nullopt;
+        }
+        names.insert(peer.name.toLower());
+        peers.push_back(std::move(peer));
+    }
+    return peers;
+}
+
+bool PeerSync::isAllowedUrl(const QUrl& url)
+{
+    if (!url.isValid() || url.host().isEmpty()) {
+        return false;
+    }
+    if (url.scheme() == QLatin1String("https")) {
+        return true;
+    }
+    // Plain http only to this Mac: credentials must not cross the network
+    // unencrypted.
+    if (url.scheme() != QLatin1String("http")) {
+        return false;
+    }
+    const QString host = url.host();
+    return host == QLatin1String("localhost") || host == QLatin1String("::1")
+           || host.startsWith(QLatin1String("127."));
+}
+
+QString PeerSync::bundleIdentifier(const QString& peerName)
+{
+    return QLatin1String(kBundlePrefix) + peerName.toLower();
+}
+
+bool PeerSync::isPeerPath(const QString& path)
+{
+    return SpotlightDonation::isDonatedPath(path)
+           && SpotlightDonation::bundleIdentifier(path).startsWith(QLatin1String(kBundlePrefix));
+}
+
+QString PeerSync::peerName(const QString& path)
+{
+    if (!isPeerPath(path)) {
+        return {};
+    }
+    return SpotlightDonation::bundleIdentifier(path).mid(
+        static_cast<qsizetype>(qstrlen(kBundlePrefix)));
+}
+
+bool PeerSync::isSyncable(const QString& path)
+{
+    return path.startsWith(QLatin1Char('/'));
+}
+
+int64_t PeerSync::segmentFirst(int64_t id)
+{
+    return id < 0 ? 0 : (id / kSegmentSpan) * kSegmentSpan;
+}
+
+std::vector<SyncSegment> PeerSync::segments(const std::vector<SyncedItem>& items)
+{
+    std::map<int64_t, std::vector<const SyncedItem*>> grouped;
+    for (const SyncedItem& item : items) {
+        grouped[segmentFirst(item.id)].push_back(&item);
+    }
+    std::vector<SyncSegment> out;
+    out.reserve(grouped.size());
+    for (auto& [first, members] : grouped) {
+        std::sort(members.begin(), members.end(),
+                  [](const SyncedItem* a, const SyncedItem* b) { return a->id < b->id; });
+        QCryptographicHash hash(QCryptographicHash::Sha256);
+        for (const SyncedItem* item : members) {
+            hash.addData(QByteArray::number(static_cast<qlonglong>(item->id)));
+            hash.addData(QByteArrayView("\x1f", 1));
+            hash.addData(item->path.toUtf8());
+            hash.addData(QByteArrayView("\x1f", 1));
+            hash.addData(QByteArray::number(item->modifiedAt, 'f', 3));
+            hash.addData(QByteArrayView("\x1f", 1));
+            hash.addData(QByteArray::number(item->indexedAt, 'f', 3));
+            hash.addData(QByteArrayView("\x1f", 1));
+            hash.addData(item->contentHash.toUtf8());
+            // Signals count only once there are any, so digests of items
+            // without them stay what they were.
+            if (!item->metadata.isEmpty()) {
+                hash.addData(QByteArrayView("\x1f", 1));
+                hash.addData(QJsonDocument(MetadataMerge::toJson(item->metadata))
+                                 .toJson(QJsonDocument::Compact));
+            }
+            hash.addData(QByteArrayView("\x1e", 1));
+        }
+        SyncSegment segment;
+        segment.first = first;
+        segment.count = static_cast<int>(members.size());
+        segment.digest = QString::fromLatin1(hash.result().toHex().left(32));
+        out.push_back(std::move(segment));
+    }
+    return out;
+}
+
+QJsonArray PeerSync::segmentsToJson(const std::vector<SyncSegment>& segments)
+{
+    QJsonArray out;
+    for (const SyncSegment& segment : segments) {
+        QJsonObject json;
+        json[QStringLiteral("first")] = static_cast<qint64>(segment.first);
+        json[QStringLiteral("count")] = segment.count;
+        json[QStringLiteral("digest")] = segment.digest;
+        out.append(json);
+    }
+    return out;
+}
+
+std::vector<SyncSegment> PeerSync::segmentsFromJson(const QJsonArray& json)
+{
+    std::vector<SyncSegment> out;
+    for (const QJsonValue& value : json) {
+        const QJsonObject object = value.toObject();
+        SyncSegment segment;
+        segment.first = object.value(QStringLiteral("first")).toInteger(-1);
+        segment.count = object.value(QStringLiteral("count")).toInt();
+        segment.digest = object.value(QStringLiteral("digest")).toString();
+        // A first id off the segment grid would file items under the wrong
+        // segment; such an entry is not ours to apply.
+        if (segment.first < 0 || segment.first % kSegmentSpan != 0 || segment.digest.isEmpty()) {
+            continue;
+        }
+        out.push_back(std::move(segment));
+    }
+    return out;
+}
+
+QJsonObject PeerSync::itemToJson(const SyncedItem& item)
+{
+    QJsonObject json;
+    json[QStringLiteral("id")] = static_cast<qint64>(item.id);
+    json[QStringLiteral("path")] = item.path;
+    json[QStringLiteral("name")] = item.name;
+    json[QStringLiteral("kind")] = item.kind;
+    json[QStringLiteral("size")] = static_cast<qint64>(item.size);
+    json[QStringLiteral("createdAt")] = item.createdAt;
+    json[QStringLiteral("modifiedAt")] = item.modifiedAt;
+    json[QStringLiteral("indexedAt")] = item.indexedAt;
+    json[QStringLiteral("contentHash")] = item.contentHash;
+    if (!item.text.isEmpty()) {
+        json[QStringLiteral("text")] = item.text;
+    }
+    if (!item.metadata.isEmpty()) {
+        json[QStringLiteral("metadata")] = MetadataMerge::toJson(item.metadata);
+    }
+    return json;
+}
+
+std::optional<SyncedItem> PeerSync::itemFromJson(const QJsonObject& json)
+{
+    SyncedItem item;
+    item.id = json.value(QStringLiteral("id")).toInteger(-1);
+    item.path = json.value(QStringLiteral("path")).toString();
+    if (item.id < 0 || !isSyncable(item.path)) {
+        return std::nullopt;
+    }
+    item.name = json.value(QStringLiteral("name")).toString();
+    if (item.name.isEmpty()) {
+        item.name = item.path.mid(item.path.lastIndexOf(QLatin1Char('/')) + 1);
+    }
+    item.kind = json.value(QStringLiteral("kind")).toString();
+    item.size = json.value(QStringLiteral("size")).toInteger();
+    item.createdAt = json.value(QStringLiteral("createdAt")).toDouble();
+    item.modifiedAt = json.value(QStringLiteral("modifiedAt")).toDouble();
+    item.indexedAt = json.value(QStringLiteral("indexedAt")).toDouble();
+    item.contentHash = json.value(QStringLiteral("contentHash")).toString();
+    item.text = json.value(QStringLiteral("text")).toString().left(kMaxTextChars);
+    item.metadata = MetadataMerge::fromJson(json.value(QStringLiteral("metadata")).toObject());
+    return item;
+}
+
+QJsonObject PeerSync::digestsToJson(const SegmentDigests& digests)
+{
+    QJsonObject json;
+    for (auto it = digests.cbegin(); it != digests.cend(); ++it) {
+        json[QString::number(it.key())] = it.value();
+    }
+    return json;
+}
+
+PeerSync::SegmentDigests PeerSync::digestsFromJson(const QJsonObject& json)
+{
+    SegmentDigests digests;
+    for (auto it = json.constBegin(); it != json.constEnd(); ++it) {
+        bool ok = false;
+        const int64_t first = it.key().toLongLong(&ok);
+        if (ok && first >= 0 && !it.value().toString().isEmpty()) {
+            digests.insert(first, it.value().toString());
+        }
+    }
+    return digests;
+}
+
+std::vector<int64_t> PeerSync::changedSegments(const SegmentDigests& known,
+                                               const std::vector<SyncSegment>& remote)
+{
+    std::vector<int64_t> changed;
+    for (const SyncSegment& segment : remote) {
+        if (known.value(segment.first) != segment.digest) {
+            changed.push_back(segment.first);
+        }
+    }
+    std::sort(changed.begin(), changed.end());
+    return changed;
+}
+
+std::vector<int64_t> PeerSync::removedSegments(const SegmentDigests& known,
+                                               const std::vector<SyncSegment>& remote)
+{
+    QSet<int64_t> present;
+    for (const SyncSegment& segment : remote) {
+        present.insert(segment.first);
+    }
+    std::vector<int64_t> removed;
+    for (auto it = known.cbegin(); it != known.cend(); ++it) {
+        if (!present.contains(it.key())) {
+            removed.push_back(it.key());
+        }
+    }
+    std::sort(removed.begin(), removed.end());
+    return removed;
+}
+
+QString PeerSync::cursorToken(const SyncCursor& cursor)
+{
+    return QStringLiteral("c1-%1-%2")
+        .arg(static_cast<qint64>(std::floor(cursor.indexedSince)))
+        .arg(static_cast<qint64>(cursor.tombstoneSeq));
+}
+
+std::optional<SyncCursor> PeerSync::cursorFromToken(const QString& token)
+{
+    const QStringList parts = token.split(QLatin1Char('-'));
+    if (parts.size() != 3 || parts[0] != QLatin1String("c1")) {
+        return std::nullopt;
+    }
+    bool sinceOk = false;
+    bool seqOk = false;
+    SyncCursor cursor;
+    cursor.indexedSince = static_cast<double>(parts[1].toLongLong(&sinceOk));
+    cursor.tombstoneSeq = parts[2].toLongLong(&seqOk);
+    if (!sinceOk || !seqOk || cursor.indexedSince < 0 || cursor.tombstoneSeq < 0) {
+        return std::nullopt;
+    }
+    return cursor;
+}
+
+std::vector<int64_t> PeerSync::touchedSegments(const std::vector<int64_t>& itemIds,
+                                               const std::vector<int64_t>& tombstoneIds)
+{
+    std::vector<int64_t> firsts;
+    firsts.reserve(itemIds.size() + tombstoneIds.size());
+    for (const std::vector<int64_t>* ids : {&itemIds, &tombstoneIds}) {
+        for (const int64_t id : *ids) {
+            firsts.push_back(segmentFirst(id));
+        }
+    }
+    std::sort(firsts.begin(), firsts.end());
+    firsts.erase(std::unique(firsts.begin(), firsts.end()), firsts.end());
+    return firsts;
+}
+
+SegmentDelta PeerSync::segmentDelta(int64_t first, const std::vector<SyncedItem>& items,
+                                    double since, const std::vector<int64_t>& tombstoneIds)
+{
+    SegmentDelta delta;
+    delta.segment.first = first;
+    std::vector<SyncedItem> members;
+    for (const SyncedItem& item : items) {
+        if (segmentFirst(item.id) != first) {
+            continue;
+        }
+        members.push_back(item);
+        // A signal change leaves indexedAt alone but is in the digest, so
+        // items with signals always go along.
+        if (item.indexedAt >= since || !item.metadata.isEmpty()) {
+            delta.items.push_back(item);
+        }
+    }
+    // The digest of the very entries the delta was cut from, so applying
+    // it and recording the digest agree even if the index moved on.
+    const std::vector<SyncSegment> digests = segments(members);
+    if (!digests.empty()) {
+        delta.segment = digests.front();
+    }
+    for (const int64_t id : tombstoneIds) {
+        if (segmentFirst(id) == first) {
+            delta.tombstones.push_back(id);
+        }
+    }
+    std::sort(delta.tombstones.begin(), delta.tombstones.end());
+    delta.tombstones.erase(std::unique(delta.tombstones.begin(), delta.tombstones.end()),
+                           delta.tombstones.end());
+    return delta;
+}
+
+QJsonObject PeerSync::segmentDeltaToJson(const SegmentDelta& delta)
+{
+    QJsonObject json;
+    json[QStringLiteral("first")] = static_cast<qint64>(delta.segment.first);
+    json[QStringLiteral("count")] = delta.segment.count;
+    json[QStringLiteral("digest")] = delta.segment.digest;
+    QJsonArray items;
+    for (const SyncedItem& item : delta.items) {
+        items.append(itemToJson(item));
+    }
+    json[QStringLiteral("items")] = items;
+    QJsonArray tombstones;
+    for (const int64_t id : delta.tombstones) {
+        tombstones.append(static_cast<qint64>(id));
+    }
+    json[QStringLiteral("tombstones")] = tombstones;
+    return json;
+}
+
+std::optional<SegmentDelta> PeerSync::segmentDeltaFromJson(const QJsonObject& json)
+{
+    SegmentDelta delta;
+    delta.segment.first = json.value(QStringLiteral("first")).toInteger(-1);
+    delta.segment.count = json.value(QStringLiteral("count")).toInt();
+    delta.segment.digest = json.value(QStringLiteral("digest")).toString();
+    if (delta.segment.first < 0 || delta.segment.first % kSegmentSpan != 0
+        || delta.segment.count < 0 || delta.segment.count > kSegmentSpan
+        || delta.segment.digest.isEmpty() != (delta.segment.count == 0)) {
+        return std::nullopt;
+    }
+    for (const QJsonValue& value : json.value(QStringLiteral("items")).toArray()) {
+        const auto item = itemFromJson(value.toObject());
+        if (!item.has_value() || segmentFirst(item->id) != delta.segment.first) {
+            return std::nullopt;
+        }
+        delta.items.push_back(item.value());
+    }
+    for (const QJsonValue& value : json.value(QStringLiteral("tombstones")).toArray()) {
+        const qint64 id = value.toInteger(-1);
+        if (id < 0 || segmentFirst(id) != delta.segment.first) {
+            return std::nullopt;
+        }
+        delta.tombstones.push_back(id);
+    }
+    return delta;
+}
+
+DonatedItem PeerSync::toDonatedItem(const QString& peerName, const SyncedItem& item)
+{
+    const QString bundle = bundleIdentifier(peerName);
+    DonatedItem donated;
+    donated.path = SpotlightDonation::itemPath(bundle, QString::number(item.id));
+    donated.parentPath = SpotlightDonation::bundlePath(bundle);
+    donated.name = item.name;
+    // The path is searchable too: "budget desktop Projects".
+    QStringList lines = {item.text, item.path};
+    lines.removeAll(QString());
+    donated.textContent = lines.join(QLatin1Char('\n'));
+    donated.size = item.size;
+    donated.createdAt = item.createdAt;
+    donated.modifiedAt = item.modifiedAt;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"), QString::fromLatin1(kContentType), false});
+    raw.push_back({QString::fromLatin1(kPeerAttribute), peerName, false});
+    raw.push_back({QString::fromLatin1(kPeerPathAttribute), item.path, false});
+    raw.push_back({QString::fromLatin1(kPeerKindAttribute), item.kind, false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   QStringLiteral("%1 · %2").arg(peerName, parentOf(item.path)), false});
+    donated.attributes = SpotlightMetadata::normalize(raw);
+    donated.contentHash = SpotlightDonation::contentHash(donated);
+    return donated;
+}
+
+QStringList PeerSync::staleIdentifiers(const QStringList& indexedIdentifiers, int64_t first,
+                                       const std::vector<SyncedItem>& items)
+{
+    QSet<QString> current;
+    for (const SyncedItem& item : items) {
+        current.insert(QString::number(item.id));
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        bool ok = false;
+        const int64_t id = identifier.toLongLong(&ok);
+        if (ok && segmentFirst(id) == first && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+} // namespace bs
@@ -0,0 +1,189 @@
+#pragma once
+
+#include "core/indexing/metadata_merge.h"
+#include "core/indexing/spotlight_donation.h"
+
+#include <QHash>
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+#include <QUrl>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// Another Mac whose index is replicated here.
+struct SyncPeer {
+    QString name;          // "desktop": the result label and the bundle suffix
+    QUrl url;              // its server-mode HTTP API
+    QString token;         // a scoped API token with the "sync" capability
+    QString fingerprint;   // pinned certificate SHA-256; empty trusts the system CAs
+};
+
+// One indexed file as a peer publishes it: its index entry and extracted
+// text, never its bytes.
+struct SyncedItem {
+    int64_t id = 0;        // the item id on the publishing Mac
+    QString path;
+    QString name;
+    QString kind;          // ItemKind string: "text", "pdf", "directory", ...
+    int64_t size = 0;
+    double createdAt = 0.0;
+    double modifiedAt = 0.0;
+    double indexedAt = 0.0;
+    QString contentHash;
+    QString text;          // at most PeerSync::kMaxTextChars
+    DocumentMetadata metadata;  // tags, pin, frecency, merged by MetadataMerge
+};
+
+// A run of kSegmentSpan item ids and a digest of the entries in it.
+struct SyncSegment {
+    int64_t first = 0;     // a multiple of kSegmentSpan
+    int count = 0;
+    QString digest;
+};
+
+// How far a subscriber's last complete delta pass got, in the publisher's
+// terms: what it has sent since is the next delta.
+struct SyncCursor {
+    double indexedSince = 0.0;  // entries indexed at or after this changed
+    int64_t tombstoneSeq = 0;   // the last delete already sent
+};
+
+// One segment's changes since a cursor: the entries (re)indexed since, the
+// ids deleted since, and the digest the segment has with them applied.
+struct SegmentDelta {
+    SyncSegment segment;        // count 0 and an empty digest once it is empty
+    std::vector<SyncedItem> items;
+    std::vector<int64_t> tombstones;
+};
+
+// PeerSync -- replicates index segments between Macs, so one machine's
+// index of drives only it can reach is searchable from another.
+//
+// The publisher pages its index by item id into segments and serves each
+// segment's digest (getSyncSegments) and entries (getSyncSegment) in server
+// mode. A subscriber fetches the digests, downloads only the segments whose
+// digest changed, and writes their entries as donations of
+// bundleIdentifier(peer.name), so they are searched like any other item and
+// dropped with the peer. Only files are published: donated items (contacts,
+// mail, messages) and other peers' replicas never leave the Mac that
+// indexed them.
+//
+// Once a subscriber has caught up it asks for deltas (getSyncDelta) with
+// the cursor of its last pass: only the segments that changed since, with
+// only their changed entries and the ids deleted from them. Pages hold
+// whole segments and the subscriber records where it is after each, so an
+// interrupted pass resumes where it stopped. The digest comparison still
+// runs after it and repairs whatever a delta could not carry.
+class PeerSync {
+public:
+    static constexpr const char* kBundlePrefix = "com.betterspotlight.peer.";
+    // A JSON array of {name, url, token, fingerprint}; empty or no row
+    // syncs nothing, and removes what was replicated.
+    static constexpr const char* kSettingKey = "sync_peers";
+    // Per-peer segment digests of the last applied sync.
+    static constexpr const char* kStateSettingKey = "sync_state";
+    // Per-peer {cursor, pending, from}: the cursor of the last complete
+    // delta pass, and of the one in progress with the next segment it
+    // needs.
+    static constexpr const char* kCursorSettingKey = "sync_cursors";
+    static constexpr const char* kContentType = "com.betterspotlight.peer-item";
+
+    // Stored attributes beyond SpotlightDonation's.
+    static constexpr const char* kPeerAttribute = "peer";
+    static constexpr const char* kPeerPathAttribute = "peerPath";
+    static constexpr const char* kPeerKindAttribute = "peerKind";
+
+    static constexpr int64_t kSegmentSpan = 256;
+    // Extracted text past this is not sent; it still finds the file.
+    static constexpr int kMaxTextChars = 50000;
+    static constexpr int kMaxPeers = 8;
+    static constexpr int kMaxNameChars = 32;
+    // A delta page ends with the segment that takes it past this many
+    // entries.
+    static constexpr int kDeltaPageItems = 256;
+    // More deletes than this since a cursor and the delta is a reset:
+    // comparing digests is cheaper.
+    static constexpr int kMaxDeltaTombstones = 20000;
+    // A new cursor starts this far before the pass that made it, so an
+    // entry committed while the pass ran is sent again, not missed.
+    static constexpr int kCursorOverlapSecs = 120;
+
+    // The kSettingKey value. Names are letters, digits and '-', unique;
+    // URLs are https, or http to loopback. Nullopt with *error naming the
+    // offending peer otherwise.
+    static std::optional<std::vector<SyncPeer>> parsePeers(const QString& json, QString* error);
+    // Whether `url` may carry credentials: https, or http to loopback. The
+    // same rule as PeerClient::isAllowedUrl, for code without Qt Network.
+    static bool isAllowedUrl(const QUrl& url);
+
+    static QString bundleIdentifier(const QString& peerName);
+    // Whether `path` is a replica of a peer's item, and whose.
+    static bool isPeerPath(const QString& path);
+    static QString peerName(const QString& path);
+
+    // Whether the publisher serves the item at `path`: absolute file paths
+    // only.
+    static bool isSyncable(const QString& path);
+
+    static int64_t segmentFirst(int64_t id);
+
+    // `items` grouped into segments, in id order. The digest covers each
+    // entry's id, path, dates and content hash, so a reindexed file changes
+    // its segment.
+    static std::vector<SyncSegment> segments(const std::vector<SyncedItem>& items);
+
+    static QJsonArray segmentsToJson(const std::vector<SyncSegment>& segments);
+    static std::vector<SyncSegment> segmentsFromJson(const QJsonArray& json);
+    static QJsonObject itemToJson(const SyncedItem& item);
+    // Nullopt when `json` lacks an id or an absolute path.
+    static std::optional<SyncedItem> itemFromJson(const QJsonObject& json);
+
+    // Segment first id -> digest, as kept in kStateSettingKey.
+    using SegmentDigests = QHash<int64_t, QString>;
+    static QJsonObject digestsToJson(const SegmentDigests& digests);
+    static SegmentDigests digestsFromJson(const QJsonObject& json);
+
+    // The remote segments to download: new ones and those whose digest
+    // differs from `known`.
+    static std::vector<int64_t> changedSegments(const SegmentDigests& known,
+                                                const std::vector<SyncSegment>& remote);
+    // The known segments the peer no longer has.
+    static std::vector<int64_t> removedSegments(const SegmentDigests& known,
+                                                const std::vector<SyncSegment>& remote);
+
+    // A cursor as the opaque "cursor" parameter of getSyncDelta, and back;
+    // nullopt for anything else.
+    static QString cursorToken(const SyncCursor& cursor);
+    static std::optional<SyncCursor> cursorFromToken(const QString& token);
+
+    // The segments holding any of `itemIds` or `tombstoneIds`, in order.
+    static std::vector<int64_t> touchedSegments(const std::vector<int64_t>& itemIds,
+                                                const std::vector<int64_t>& tombstoneIds);
+    // The delta of the segment at `first` from its current entries
+    // (`items`, all of them) and the ids deleted since the cursor: the
+    // entries indexed at or after `since` or carrying metadata, and the
+    // tombstones inside it.
+    static SegmentDelta segmentDelta(int64_t first, const std::vector<SyncedItem>& items,
+                                     double since, const std::vector<int64_t>& tombstoneIds);
+    static QJsonObject segmentDeltaToJson(const SegmentDelta& delta);
+    // Nullopt when the segment is off the grid or an entry or tombstone
+    // lies outside it.
+    static std::optional<SegmentDelta> segmentDeltaFromJson(const QJsonObject& json);
+
+    // `item` as a donation of the peer's bundle. Its path, "on <peer>",
+    // is the result subtitle; the original path and kind are attributes.
+    static DonatedItem toDonatedItem(const QString& peerName, const SyncedItem& item);
+
+    // Of the replicas indexed for the segment at `first`, those no longer
+    // in `items`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers, int64_t first,
+                                        const std::vector<SyncedItem>& items);
+};
+
+} // namespace bs
@@ -1,16 +1,22 @@
 #include "core/indexing/pipeline.h"
-#include "core/index/sqlite_store.h"
 #include "core/extraction/extraction_manager.h"
 #include "core/fs/file_scanner.h"
 #include "core/shared/logging.h"
+#include "core/shared/tracing.h"
 
 #include <QDateTime>
 #include <QElapsedTimer>
+#include <QFileInfo>
 #include <QByteArray>
+#include <QJsonArray>
+#include <QJsonDocument>
 
 #include <algorithm>
 #include <chrono>
 #include <cstdlib>
+#include <future>
+#include <memory>
+#include <unordered_set>
 
 #if defined(__APPLE__)
 #include <mach/mach.h>
@@ -56,6 +62,19 @@ int currentProcessRssMb()
 #endif
 }
 
+QString indexResultStatusName(IndexResult::Status status)
+{
+    switch (status) {
+    case IndexResult::Status::Indexed:          return QStringLiteral("indexed");
+    case IndexResult::Status::MetadataOnly:     return QStringLiteral("metadata_only");
+    case IndexResult::Status::Deleted:          return QStringLiteral("deleted");
+    case IndexResult::Status::Excluded:         return QStringLiteral("excluded");
+    case IndexResult::Status::ExtractionFailed: return QStringLiteral("extraction_failed");
+    case IndexResult::Status::Skipped:          return QStringLiteral("skipped");
+    }
+    return QStringLiteral("unknown");
+}
+
 bool isTransientExtractionFailure(const PreparedWork& prepared)
 {
     if (!prepared.failure.has_value()) {
@@ -76,6 +95,7 @@ bool isTransientExtractionFailure(const PreparedWork& prepared)
     case ExtractionResult::Status::UnsupportedFormat:
     case ExtractionResult::Status::SizeExceeded:
     case ExtractionResult::Status::Cancelled:
+    case ExtractionResult::Status::Crashed:
         return false;
     }
 
@@ -150,6 +170,13 @@ Pipeline::Pipeline(SQLiteStore& store, ExtractionManager& extractor,
     schedulerConfig.liveDispatchRatioPct = 70;
     m_schedulerActor = std::make_unique<PipelineSchedulerActor>(schedulerConfig);
     m_telemetryActor = std::make_unique<PipelineTelemetryActor>();
+    m_monitor->setErrorCallback([this](const QString& error) {
+        LOG_WARN(bsIndex, "File watcher: %s", qUtf8Printable(error));
+        std::lock_guard<std::mutex> lock(m_watcherMutex);
+        ++m_watcherStatus.errorCount;
+        m_watcherStatus.lastError = error;
+        m_watcherStatus.lastErrorAtMs = QDateTime::currentMSecsSinceEpoch();
+    });
     LOG_INFO(bsIndex, "Pipeline created (idle prep workers=%d)",
              static_cast<int>(m_idlePrepWorkers));
 }
@@ -162,6 +189,24 @@ Pipeline::~Pipeline()
 // ── Lifecycle ───────────────────────────────────────────────
 
 void Pipeline::start(const std::vector<std::string>& roots)
+{
+    startInternal(roots, roots, {});
+}
+
+void Pipeline::startFromCheckpoint(const IndexingCheckpoint& checkpoint)
+{
+    if (checkpoint.lastEventId.has_value()) {
+        m_monitor->setLastEventId(checkpoint.lastEventId.value());
+    }
+    LOG_INFO(bsIndex, "Resuming from checkpoint: %d pending item(s), %d unscanned root(s)",
+             static_cast<int>(checkpoint.pendingItems.size()),
+             static_cast<int>(checkpoint.unscannedRoots.size()));
+    startInternal(checkpoint.roots, checkpoint.unscannedRoots, checkpoint.pendingItems);
+}
+
+void Pipeline::startInternal(const std::vector<std::string>& roots,
+                             const std::vector<std::string>& scanRoots,
+                             std::vector<WorkItem> resumeItems)
 {
     if (m_running.load()) {
         LOG_WARN(bsIndex, "Pipeline::start() called while already running");
@@ -172,7 +217,20 @@ void Pipeline::start(const std::vector<std::string>& roots)
 
     resetRuntimeState();
 
-    m_scanRoots = roots;
+    m_roots = roots;
+    m_scanRoots = scanRoots;
+    m_resumeItems = std::move(resumeItems);
+    m_progress.reset(roots, QDateTime::currentMSecsSinceEpoch());
+    for (const std::string& root : roots) {
+        if (std::find(scanRoots.begin(), scanRoots.end(), root) == scanRoots.end()) {
+            m_progress.markScanComplete(root);
+        }
+    }
+    {
+        std::lock_guard<std::mutex> lock(m_carryoverMutex);
+        m_carryoverItems.clear();
+        m_unscannedRoots.clear();
+    }
     m_running.store(true);
     m_stopping.store(false);
     m_paused.store(false);
@@ -214,6 +272,9 @@ void Pipeline::stop()
 
     m_stopping.store(true);
     m_running.store(false);
+    // Prep workers refuse work while paused; clear it so already-dispatched
+    // extraction drains and commits instead of leaving workers spinning.
+    m_paused.store(false);
 
     if (m_monitor) {
         m_monitor->stop();
@@ -247,6 +308,42 @@ void Pipeline::stop()
              m_processedCount.load());
 }
 
+IndexingCheckpoint Pipeline::stopWithCheckpoint()
+{
+    stop();
+
+    IndexingCheckpoint checkpoint;
+    checkpoint.roots = m_roots;
+
+    // Queue contents were enqueued ahead of the scan tail, so they go first.
+    if (m_actorMode == ActorMode::ActorPrimary && m_schedulerActor) {
+        for (auto& scheduled : m_schedulerActor->takeAll()) {
+            checkpoint.pendingItems.push_back(std::move(scheduled.item));
+        }
+    } else {
+        checkpoint.pendingItems = m_workQueue.takeAll();
+    }
+    {
+        std::lock_guard<std::mutex> lock(m_carryoverMutex);
+        checkpoint.pendingItems.insert(checkpoint.pendingItems.end(),
+                                       std::make_move_iterator(m_carryoverItems.begin()),
+                                       std::make_move_iterator(m_carryoverItems.end()));
+        m_carryoverItems.clear();
+        checkpoint.unscannedRoots = m_unscannedRoots;
+    }
+
+    const uint64_t eventId = m_monitor->lastEventId();
+    if (eventId != static_cast<uint64_t>(kFSEventStreamEventIdSinceNow)) {
+        checkpoint.lastEventId = eventId;
+    }
+    checkpoint.savedAtMs = QDateTime::currentMSecsSinceEpoch();
+
+    LOG_INFO(bsIndex, "Checkpoint: %d pending item(s), %d unscanned root(s)",
+             static_cast<int>(checkpoint.pendingItems.size()),
+             static_cast<int>(checkpoint.unscannedRoots.size()));
+    return checkpoint;
+}
+
 // ── Pause / resume ──────────────────────────────────────────
 
 void Pipeline::pause()
@@ -278,6 +375,16 @@ void Pipeline::setUserActive(bool active)
     LOG_INFO(bsIndex, "Pipeline user activity changed: active=%d", active ? 1 : 0);
 }
 
+void Pipeline::setIndexedXattrs(const QStringList& names)
+{
+    m_indexer->setIndexedXattrs(names);
+}
+
+void Pipeline::setContentTypeRules(const ContentTypeRules& rules)
+{
+    m_indexer->setContentTypeRules(rules);
+}
+
 void Pipeline::updatePrepConcurrencyPolicy()
 {
     const size_t allowed = m_userActive.load() ? 1 : m_idlePrepWorkers;
@@ -453,6 +560,9 @@ bool Pipeline::enqueueLaneWorkItem(const WorkItem& item, PipelineLane lane, int
 {
     WorkItem laneItem = item;
     laneItem.rebuildLane = (lane == PipelineLane::Rebuild);
+    if (!laneItem.trace.isActive()) {
+        laneItem.trace = PendingSpan::startTrace();
+    }
 
     for (int attempt = 0; attempt < maxAttempts; ++attempt) {
         if (!m_running.load() || m_stopping.load()) {
@@ -548,19 +658,98 @@ WorkItem::Type Pipeline::mergeWorkTypes(WorkItem::Type lhs, WorkItem::Type rhs)
 
 // ── Re-index / rebuild ──────────────────────────────────────
 
-void Pipeline::reindexPath(const QString& path)
+ReindexSummary Pipeline::reindexPath(const QString& path, const QStringList& extractors)
 {
-    WorkItem item;
-    item.type = WorkItem::Type::ModifiedContent;
-    item.filePath = path.toStdString();
-    item.rebuildLane = false;
+    ReindexSummary summary;
+    const auto wanted = [&extractors](ItemKind kind) {
+        return extractors.isEmpty()
+            || extractors.contains(ExtractionManager::extractorName(kind));
+    };
+    // NewFile rather than ModifiedContent: the writer skips modified items
+    // whose size and mtime match the index, which is what a reindex is for.
+    const auto enqueueReextract = [&](std::string filePath) {
+        WorkItem item;
+        item.type = WorkItem::Type::NewFile;
+        item.filePath = std::move(filePath);
+        item.rebuildLane = false;
+        if (enqueuePrimaryWorkItem(item)) {
+            ++summary.queued;
+        } else {
+            m_failedCount.fetch_add(1);
+            LOG_WARN(bsIndex, "Re-index request dropped after retries: %s",
+                     item.filePath.c_str());
+        }
+    };
 
-    LOG_INFO(bsIndex, "Re-index requested: %s", qUtf8Printable(path));
-    if (!enqueuePrimaryWorkItem(item, 200)) {
-        m_failedCount.fetch_add(1);
-        LOG_WARN(bsIndex, "Re-index request dropped after retries: %s",
-                 qUtf8Printable(path));
+    const QFileInfo info(path);
+    if (!info.isDir()) {
+        LOG_INFO(bsIndex, "Re-index requested: %s", qUtf8Printable(path));
+        const std::string extension =
+            info.suffix().isEmpty() ? std::string()
+                                    : ("." + info.suffix().toLower()).toStdString();
+        if (wanted(FileScanner::classifyItemKind(extension))) {
+            enqueueReextract(path.toStdString());
+        }
+        return summary;
+    }
+
+    LOG_INFO(bsIndex, "Re-index requested for subtree: %s (extractors: %s)",
+             qUtf8Printable(path),
+             extractors.isEmpty() ? "all" : qUtf8Printable(extractors.join(QLatin1Char(','))));
+
+    FileScanner scanner(&m_pathRules);
+    std::unordered_set<std::string> onDisk;
+    for (auto& meta : scanner.scanDirectory(path.toStdString())) {
+        onDisk.insert(meta.filePath);
+        if (wanted(meta.itemKind)) {
+            enqueueReextract(std::move(meta.filePath));
+        }
+    }
+
+    // Directories are never emitted by the scanner, so only drop their
+    // entries once they are gone from disk.
+    for (const SQLiteStore::ItemRow& row : m_store.getItemsUnderPath(path)) {
+        const ItemKind kind = itemKindFromString(row.kind);
+        const std::string rowPath = row.path.toStdString();
+        const bool stale = kind == ItemKind::Directory
+            ? extractors.isEmpty() && !QFileInfo::exists(row.path)
+            : onDisk.count(rowPath) == 0 && wanted(kind);
+        if (!stale) {
+            continue;
+        }
+        WorkItem item;
+        item.type = WorkItem::Type::Delete;
+        item.filePath = rowPath;
+        if (enqueuePrimaryWorkItem(item)) {
+            ++summary.removed;
+        } else {
+            m_failedCount.fetch_add(1);
+        }
+    }
+
+    LOG_INFO(bsIndex, "Re-index of %s: queued %d, removing %d stale",
+             qUtf8Printable(path), static_cast<int>(summary.queued),
+             static_cast<int>(summary.removed));
+    return summary;
+}
+
+size_t Pipeline::enqueueChanges(const std::vector<WorkItem>& items)
+{
+    size_t queued = 0;
+    for (const WorkItem& item : items) {
+        if (item.type != WorkItem::Type::Delete
+            && m_pathRules.validate(item.filePath, item.knownSize.value_or(0))
+                   == ValidationResult::Exclude) {
+            continue;
+        }
+        if (enqueuePrimaryWorkItem(item)) {
+            ++queued;
+        } else {
+            m_failedCount.fetch_add(1);
+            LOG_WARN(bsIndex, "Change dropped after retries: %s", item.filePath.c_str());
+        }
     }
+    return queued;
 }
 
 void Pipeline::rebuildAll(const std::vector<std::string>& roots)
@@ -585,6 +774,7 @@ void Pipeline::rebuildAll(const std::vector<std::string>& roots)
     }
 
     m_processedCount.store(0);
+    m_progress.reset(roots, QDateTime::currentMSecsSinceEpoch());
     {
         std::lock_guard<std::mutex> lock(m_coordMutex);
         m_pathCoordinator.clear();
@@ -593,6 +783,7 @@ void Pipeline::rebuildAll(const std::vector<std::string>& roots)
     FileScanner scanner(&m_pathRules);
     size_t enqueued = 0;
     for (const auto& root : roots) {
+        emit rootScanStarted(QString::fromStdString(root));
         auto files = scanner.scanDirectory(root);
         for (auto& meta : files) {
             WorkItem item;
@@ -600,11 +791,13 @@ void Pipeline::rebuildAll(const std::vector<std::string>& roots)
             item.filePath = std::move(meta.filePath);
             item.rebuildLane = true;
             if (enqueueLaneWorkItem(item, PipelineLane::Rebuild)) {
+                m_progress.recordDiscovered(item.filePath);
                 ++enqueued;
             } else {
                 m_failedCount.fetch_add(1);
             }
         }
+        finishRootScan(root);
     }
 
     resume();
@@ -645,6 +838,25 @@ QueueStats Pipeline::queueStatus() const
     return stats;
 }
 
+IndexingProgress Pipeline::progressSnapshot() const
+{
+    const bool drained = totalPendingDepth() == 0
+        && m_preparingCount.load() == 0
+        && m_writingCount.load() == 0;
+    return m_progress.snapshot(QDateTime::currentMSecsSinceEpoch(), drained);
+}
+
+WatcherStatus Pipeline::watcherStatus() const
+{
+    WatcherStatus status;
+    {
+        std::lock_guard<std::mutex> lock(m_watcherMutex);
+        status = m_watcherStatus;
+    }
+    status.running = m_monitor && m_monitor->isRunning();
+    return status;
+}
+
 QJsonObject Pipeline::telemetrySnapshot() const
 {
     QJsonObject out;
@@ -694,37 +906,107 @@ QString Pipeline::actorModeString() const
 
 void Pipeline::scanEntry()
 {
+    // Checkpointed work was already ahead of the scan when it was saved.
+    std::vector<WorkItem> resumeItems = std::move(m_resumeItems);
+    m_resumeItems.clear();
+    for (size_t i = 0; i < resumeItems.size(); ++i) {
+        const WorkItem& item = resumeItems[i];
+        const PipelineLane lane = item.rebuildLane ? PipelineLane::Rebuild : PipelineLane::Live;
+        if (enqueueLaneWorkItem(item, lane)) {
+            if (item.rebuildLane) {
+                m_progress.recordDiscovered(item.filePath);
+            }
+            continue;
+        }
+        if (m_stopping.load()) {
+            recordCarryover(std::vector<WorkItem>(resumeItems.begin() + static_cast<long>(i),
+                                                  resumeItems.end()),
+                            0);
+            return;
+        }
+        m_failedCount.fetch_add(1);
+    }
+
     FileScanner scanner(&m_pathRules);
 
-    for (const auto& root : m_scanRoots) {
+    for (size_t rootIndex = 0; rootIndex < m_scanRoots.size(); ++rootIndex) {
+        const auto& root = m_scanRoots[rootIndex];
         if (!m_running.load() || m_stopping.load()) {
-            break;
+            recordCarryover({}, rootIndex);
+            return;
         }
 
         LOG_INFO(bsIndex, "Initial scan: %s", root.c_str());
+        emit rootScanStarted(QString::fromStdString(root));
+        // Its own trace: each discovered file starts one as it is enqueued.
+        Span crawlSpan(QStringLiteral("index.crawl"), TraceContext{});
         auto files = scanner.scanDirectory(root);
+        crawlSpan.setAttribute(QStringLiteral("files"), static_cast<qint64>(files.size()));
+        crawlSpan.end();
         LOG_INFO(bsIndex, "Initial scan found %d files in %s",
                  static_cast<int>(files.size()), root.c_str());
 
-        for (auto& meta : files) {
-            if (!m_running.load() || m_stopping.load()) {
-                break;
-            }
-
+        for (size_t fileIndex = 0; fileIndex < files.size(); ++fileIndex) {
             WorkItem item;
             item.type = WorkItem::Type::NewFile;
-            item.filePath = std::move(meta.filePath);
+            item.filePath = files[fileIndex].filePath;
             item.rebuildLane = true;
-            if (!enqueueLaneWorkItem(item, PipelineLane::Rebuild)) {
-                m_failedCount.fetch_add(1);
+            if (!m_running.load() || m_stopping.load()
+                || !enqueueLaneWorkItem(item, PipelineLane::Rebuild)) {
+                if (!m_stopping.load()) {
+                    m_failedCount.fetch_add(1);
+                    continue;
+                }
+                // Stopping: keep the unscanned tail of this root for resume.
+                std::vector<WorkItem> tail;
+                tail.reserve(files.size() - fileIndex);
+                for (size_t rest = fileIndex; rest < files.size(); ++rest) {
+                    WorkItem pending;
+                    pending.type = WorkItem::Type::NewFile;
+                    pending.filePath = std::move(files[rest].filePath);
+                    pending.rebuildLane = true;
+                    tail.push_back(std::move(pending));
+                }
+                recordCarryover(std::move(tail), rootIndex + 1);
+                return;
             }
+            m_progress.recordDiscovered(item.filePath);
         }
+        finishRootScan(root);
     }
 
     LOG_INFO(bsIndex, "Initial scan complete, queue depth: %d",
              static_cast<int>(m_workQueue.size()));
 }
 
+void Pipeline::finishRootScan(const std::string& root)
+{
+    const size_t files = m_progress.markScanComplete(root);
+    emit rootScanned(QString::fromStdString(root), static_cast<qint64>(files));
+    // An empty root, or one the writer already caught up with.
+    emitCompletedRoots(false);
+}
+
+void Pipeline::emitCompletedRoots(bool drained)
+{
+    for (const RootProgress& root : m_progress.takeCompletedRoots(drained)) {
+        emit rootCompleted(QString::fromStdString(root.root),
+                           static_cast<qint64>(root.discovered));
+    }
+}
+
+void Pipeline::recordCarryover(std::vector<WorkItem> items, size_t nextScanRootIndex)
+{
+    std::lock_guard<std::mutex> lock(m_carryoverMutex);
+    m_carryoverItems.insert(m_carryoverItems.end(),
+                            std::make_move_iterator(items.begin()),
+                            std::make_move_iterator(items.end()));
+    if (nextScanRootIndex < m_scanRoots.size()) {
+        m_unscannedRoots.assign(m_scanRoots.begin() + static_cast<long>(nextScanRootIndex),
+                                m_scanRoots.end());
+    }
+}
+
 // ── Coordinator helpers ─────────────────────────────────────
 
 std::optional<Pipeline::PrepTask> Pipeline::tryDispatchFromIngress(const WorkItem& item)
@@ -947,9 +1229,26 @@ void Pipeline::prepWorkerLoop(size_t workerIndex)
             m_prepQueue.pop_front();
         }
 
+        if (!task.item.trace.isActive()) {
+            // Follow-ups for coalesced events are traced from here.
+            task.item.trace = PendingSpan::startTrace();
+        }
+
         m_preparingCount.fetch_add(1);
+        Span extractSpan(QStringLiteral("index.extract"), task.item.trace.context);
         PreparedWork prepared = m_indexer->prepareWorkItem(task.item, task.generation);
         prepared.rebuildLane = (task.lane == PipelineLane::Rebuild);
+        prepared.trace = task.item.trace;
+        if (prepared.metadata.has_value()) {
+            extractSpan.setAttribute(QStringLiteral("kind"),
+                                     itemKindToString(prepared.metadata->itemKind));
+        }
+        extractSpan.setAttribute(QStringLiteral("chunks"),
+                                 static_cast<qint64>(prepared.chunks.size()));
+        if (prepared.failure.has_value()) {
+            extractSpan.setError(prepared.failure->stage);
+        }
+        extractSpan.end();
         m_preparingCount.fetch_sub(1);
 
         {
@@ -973,6 +1272,110 @@ void Pipeline::prepWorkerLoop(size_t workerIndex)
     LOG_INFO(bsIndex, "Prep worker %d exiting", static_cast<int>(workerIndex));
 }
 
+void Pipeline::runOnWriter(const std::function<void()>& task,
+                           const std::function<void()>& afterCommit)
+{
+    // start() and stop() run on the caller's thread too, so the writer
+    // cannot exit between this check and the task being picked up.
+    if (!m_writerThread.joinable()) {
+        m_store.beginTransaction();
+        task();
+        m_store.commitTransaction();
+        if (afterCommit) {
+            afterCommit();
+        }
+        return;
+    }
+
+    WriterTask writerTask{task, afterCommit, std::make_shared<std::promise<void>>()};
+    std::future<void> finished = writerTask.done->get_future();
+    {
+        std::lock_guard<std::mutex> lock(m_preparedMutex);
+        m_writerTasks.push_back(std::move(writerTask));
+    }
+    m_preparedCv.notify_all();
+    finished.wait();
+}
+
+std::vector<IndexResult> Pipeline::applyDonations(const std::vector<DonatedItem>& items)
+{
+    std::vector<IndexResult> results;
+    results.reserve(items.size());
+    runOnWriter([this, &items, &results] {
+        for (const DonatedItem& item : items) {
+            results.push_back(m_indexer->applyDonation(item));
+        }
+    });
+    return results;
+}
+
+size_t Pipeline::removeDonations(const DonationRemoval& removal)
+{
+    size_t removed = 0;
+    runOnWriter([this, &removal, &removed] {
+        removed = m_indexer->applyDonationRemoval(removal);
+    });
+    return removed;
+}
+
+std::vector<SQLiteStore::ItemRow> Pipeline::itemsUnderPath(const QString& path)
+{
+    std::vector<SQLiteStore::ItemRow> rows;
+    runOnWriter([this, &path, &rows] { rows = m_store.getItemsUnderPath(path); });
+    return rows;
+}
+
+void Pipeline::setPrivacyExclusions(const QStringList& roots)
+{
+    std::vector<std::string> rules;
+    rules.reserve(static_cast<size_t>(roots.size()));
+    for (const QString& root : roots) {
+        rules.push_back(root.toStdString());
+    }
+    m_pathRules.setPrivacyExclusions(rules);
+
+    const QString json = QString::fromUtf8(
+        QJsonDocument(QJsonArray::fromStringList(roots)).toJson(QJsonDocument::Compact));
+    runOnWriter([this, &json] {
+        m_store.setSetting(QString::fromLatin1(kPrivacyExclusionsSetting), json);
+    });
+}
+
+PrivacyPurgeSummary Pipeline::purgePrivacyPath(const QString& root)
+{
+    PrivacyPurgeSummary summary;
+    runOnWriter(
+        [this, &root, &summary] {
+            if (!m_store.setSecureDelete(true)) {
+                LOG_WARN(bsIndex, "Privacy purge of %s without secure delete",
+                         qUtf8Printable(root));
+            }
+            summary.deletedItems = m_indexer->purgeSubtree(root);
+            m_store.setSecureDelete(false);
+        },
+        [this, &root, &summary] {
+            summary.walCheckpointed = m_store.checkpointWal();
+            summary.remaining = m_store.footprintUnderPath(root);
+        });
+    if (!summary.remaining.empty()) {
+        LOG_ERROR(bsIndex, "Privacy purge of %s left %lld item(s), %lld FTS row(s)",
+                  qUtf8Printable(root), static_cast<long long>(summary.remaining.items),
+                  static_cast<long long>(summary.remaining.ftsRows));
+    }
+    return summary;
+}
+
+SQLiteStore::CompactionSummary Pipeline::compactIndex()
+{
+    SQLiteStore::CompactionSummary summary;
+    runOnWriter([] {}, [this, &summary] { summary = m_store.compact(); });
+    LOG_INFO(bsIndex, "Compacted index: %lld deletion(s), %lld -> %lld bytes, complete=%d",
+             static_cast<long long>(summary.compactedDeletes),
+             static_cast<long long>(summary.bytesBefore),
+             static_cast<long long>(summary.bytesAfter), summary.complete() ? 1 : 0);
+    return summary;
+}
+
 void Pipeline::writerLoop()
 {
     LOG_INFO(bsIndex, "Writer loop started");
@@ -998,12 +1401,15 @@ void Pipeline::writerLoop()
         }
 
         const int processed = m_processedCount.load();
-        const int total = processed + static_cast<int>(totalPendingDepth());
+        const size_t pending = totalPendingDepth();
+        const int total = processed + static_cast<int>(pending);
         emit progressUpdated(processed, total);
+        emitCompletedRoots(pending == 0 && m_preparingCount.load() == 0);
     };
 
     while (true) {
         PreparedWork prepared;
+        WriterTask task;
         bool shouldCommitIdleBatch = false;
         bool shouldBreak = false;
         bool hasPreparedWork = false;
@@ -1011,12 +1417,16 @@ void Pipeline::writerLoop()
         {
             std::unique_lock<std::mutex> lock(m_preparedMutex);
             m_preparedCv.wait_for(lock, std::chrono::milliseconds(50), [this] {
-                return !m_preparedLiveQueue.empty()
+                return !m_writerTasks.empty()
+                    || !m_preparedLiveQueue.empty()
                     || !m_preparedRebuildQueue.empty()
                     || m_stopping.load();
             });
 
-            if (m_preparedLiveQueue.empty() && m_preparedRebuildQueue.empty()) {
+            if (!m_writerTasks.empty()) {
+                task = std::move(m_writerTasks.front());
+                m_writerTasks.pop_front();
+            } else if (m_preparedLiveQueue.empty() && m_preparedRebuildQueue.empty()) {
                 bool prepQueueEmpty = false;
                 {
                     std::lock_guard<std::mutex> prepLock(m_prepMutex);
@@ -1050,6 +1460,23 @@ void Pipeline::writerLoop()
             }
         }
 
+        if (task.run) {
+            // Joins the open batch, then commits it so the caller's writes
+            // are durable once runOnWriter() returns.
+            if (!inTransaction) {
+                m_store.beginTransaction();
+                inTransaction = true;
+                batchCount = 0;
+                batchTimer.start();
+            }
+            task.run();
+            commitBatch();
+            if (task.afterCommit) {
+                task.afterCommit();
+            }
+            task.done->set_value();
+            continue;
+        }
         if (shouldBreak) {
             break;
         }
@@ -1071,7 +1498,12 @@ void Pipeline::writerLoop()
         const PipelineLane lane =
             prepared.rebuildLane ? PipelineLane::Rebuild : PipelineLane::Live;
 
-        if (isStalePreparedWork(prepared)) {
+        // Superseded crawl items still settle their slot in the progress.
+        m_progress.recordProcessed(prepared.path.toStdString(), prepared.rebuildLane,
+                                   QDateTime::currentMSecsSinceEpoch());
+
+        const bool isStale = isStalePreparedWork(prepared);
+        if (isStale) {
             m_staleDroppedCount.fetch_add(1);
             if (m_schedulerActor) {
                 m_schedulerActor->recordStaleDropped();
@@ -1083,7 +1515,11 @@ void Pipeline::writerLoop()
                       qUtf8Printable(prepared.path),
                       static_cast<long long>(prepared.generation));
         } else {
+            Span writeSpan(QStringLiteral("index.write"), prepared.trace.context);
             IndexResult result = m_indexer->applyPreparedWork(prepared);
+            writeSpan.setAttribute(QStringLiteral("status"), indexResultStatusName(result.status));
+            writeSpan.setAttribute(QStringLiteral("batchDepth"), batchCount);
+            writeSpan.end();
             m_processedCount.fetch_add(1);
 
             if (result.status == IndexResult::Status::ExtractionFailed) {
@@ -1118,6 +1554,9 @@ void Pipeline::writerLoop()
                     }
                 } else {
                     m_failedCount.fetch_add(1);
+                    emit fileFailed(prepared.path,
+                                    prepared.failure ? prepared.failure->stage : QString(),
+                                    prepared.failure ? prepared.failure->message : QString());
                 }
             }
 
@@ -1136,6 +1575,11 @@ void Pipeline::writerLoop()
                       prepared.prepDurationMs,
                       result.durationMs);
         }
+        prepared.trace.finish(QStringLiteral("index.item"),
+                              {{QStringLiteral("lane"), prepared.rebuildLane
+                                    ? QStringLiteral("rebuild") : QStringLiteral("live")},
+                               {QStringLiteral("retry"), prepared.retryCount},
+                               {QStringLiteral("stale"), isStale}});
 
         m_writingCount.store(0);
 
@@ -1191,6 +1635,7 @@ void Pipeline::writerLoop()
 void Pipeline::onFileSystemEvents(const std::vector<WorkItem>& items)
 {
     int enqueued = 0;
+    std::vector<WorkItem> carryover;
     for (const auto& item : items) {
         auto validation = m_pathRules.validate(item.filePath);
         if (validation == ValidationResult::Exclude) {
@@ -1201,14 +1646,25 @@ void Pipeline::onFileSystemEvents(const std::vector<WorkItem>& items)
             || item.type == WorkItem::Type::ModifiedContent) {
             if (enqueueLaneWorkItem(item, lane, 80)) {
                 ++enqueued;
+            } else if (m_stopping.load()) {
+                // The monitor flushes its debounce buffer while stopping, after
+                // the FSEvents id has moved past these; keep them for resume.
+                carryover.push_back(item);
             } else {
                 m_failedCount.fetch_add(1);
             }
         } else if (enqueueLaneWorkItem(item, lane, 80)) {
             ++enqueued;
+        } else if (m_stopping.load()) {
+            carryover.push_back(item);
         }
     }
 
+    if (!carryover.empty()) {
+        std::lock_guard<std::mutex> lock(m_carryoverMutex);
+        m_carryoverItems.insert(m_carryoverItems.end(), carryover.begin(), carryover.end());
+    }
+
     if (enqueued > 0) {
         LOG_DEBUG(bsIndex, "FS events: %d received, %d enqueued",
                   static_cast<int>(items.size()), enqueued);
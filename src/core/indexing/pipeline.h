@@ -1,7 +1,10 @@
 #pragma once
 
+#include "core/index/sqlite_store.h"
 #include "core/indexing/chunker.h"
 #include "core/indexing/indexer.h"
+#include "core/indexing/indexing_checkpoint.h"
+#include "core/indexing/indexing_progress.h"
 #include "core/indexing/path_state_actor.h"
 #include "core/indexing/pipeline_scheduler_actor.h"
 #include "core/indexing/pipeline_telemetry_actor.h"
@@ -12,12 +15,14 @@
 #include <QObject>
 #include <QJsonObject>
 #include <QString>
+#include <QStringList>
 
 #include <atomic>
 #include <condition_variable>
 #include <cstdint>
 #include <deque>
 #include <functional>
+#include <future>
 #include <memory>
 #include <mutex>
 #include <optional>
@@ -28,7 +33,6 @@
 
 namespace bs {
 
-class SQLiteStore;
 class ExtractionManager;
 
 struct PipelineRuntimeConfig {
@@ -50,6 +54,29 @@ struct PipelineRuntimeConfig {
     std::function<int()> rssProvider;
 };
 
+// File watcher state for diagnostics. Errors are FSEvents notices that
+// events were dropped or a subtree must be rescanned.
+struct WatcherStatus {
+    bool running = false;
+    size_t errorCount = 0;
+    QString lastError;
+    int64_t lastErrorAtMs = 0;
+};
+
+// What a reindexPath() call queued.
+struct ReindexSummary {
+    size_t queued = 0;   // files queued for re-extraction
+    size_t removed = 0;  // stale index entries queued for deletion
+};
+
+// What a purgePrivacyPath() call removed, and what the index still holds
+// under the folder afterwards (all zero unless something failed).
+struct PrivacyPurgeSummary {
+    size_t deletedItems = 0;
+    SQLiteStore::PathFootprint remaining;
+    bool walCheckpointed = false;  // old WAL frames no longer hold the rows
+};
+
 // Pipeline — top-level indexing orchestrator.
 //
 // Architecture:
@@ -81,23 +108,77 @@ public:
     // Start monitoring the given root directories and processing items.
     void start(const std::vector<std::string>& roots);
 
+    // Resume from a checkpoint taken by stopWithCheckpoint(): requeue its
+    // pending items, scan only roots that were never scanned, and replay
+    // watcher events recorded after the saved FSEvents id.
+    void startFromCheckpoint(const IndexingCheckpoint& checkpoint);
+
     // Stop monitoring and processing. Blocks until all worker threads exit.
     void stop();
 
+    // stop(), then return the unwritten frontier. In-flight extraction is
+    // drained and committed first, so the checkpoint only holds queued work.
+    IndexingCheckpoint stopWithCheckpoint();
+
     // Pause/resume ingestion and prep scheduling.
     void pause();
     void resume();
 
-    // Enqueue a single path for re-indexing.
-    void reindexPath(const QString& path);
+    // Re-extract a file, or every file under a directory, even when its
+    // size and mtime are unchanged. A non-empty `extractors` (names from
+    // ExtractionManager::extractorName()) limits the run to files those
+    // extractors handle. Entries under a directory that are gone from disk
+    // or now excluded are removed. Scans the subtree before returning.
+    ReindexSummary reindexPath(const QString& path, const QStringList& extractors = {});
+
+    // Queue changes found outside the watched roots, by a caller that does
+    // its own walk (network shares, see NetworkShares). Excluded paths are
+    // dropped; returns how many items were queued.
+    size_t enqueueChanges(const std::vector<WorkItem>& items);
 
     // Drop all indexed data and re-scan from scratch.
     void rebuildAll(const std::vector<std::string>& roots);
 
+    // Write or remove app-donated items (IndexerService `donateItems` /
+    // `deleteDonatedItems`). They go through the DB writer between file
+    // batches and are committed when these return; while the pipeline is
+    // stopped they are written directly.
+    std::vector<IndexResult> applyDonations(const std::vector<DonatedItem>& items);
+    size_t removeDonations(const DonationRemoval& removal);
+
+    // SQLiteStore::getItemsUnderPath() on the DB writer, for sync workers
+    // on their own threads: the store's connection is not shared across
+    // threads. Direct while the pipeline is stopped, like the above.
+    std::vector<SQLiteStore::ItemRow> itemsUnderPath(const QString& path);
+
+    // Replace the privacy folder list on the PathRules this pipeline shares
+    // with its owner, and persist it (JSON array) as the
+    // kPrivacyExclusionsSetting setting through the writer.
+    static constexpr const char* kPrivacyExclusionsSetting = "privacy_exclusions";
+    void setPrivacyExclusions(const QStringList& roots);
+
+    // Privacy folders: delete everything indexed at or below `root` on the
+    // DB writer with secure delete on, so rows and postings are overwritten
+    // rather than only unlinked, then checkpoint the WAL and count what is
+    // left. Add `root` to the PathRules privacy list first; queued work
+    // under it is then dropped at the writer instead of re-indexed.
+    PrivacyPurgeSummary purgePrivacyPath(const QString& root);
+
+    // SQLiteStore::compact() on the DB writer, between batches. VACUUM
+    // rewrites the whole file and holds up writes meanwhile, so callers
+    // wait for an idle queue.
+    SQLiteStore::CompactionSummary compactIndex();
+
     // Hint that the user is actively interacting (e.g. typing a query).
     // Active mode clamps prep concurrency to one worker.
     void setUserActive(bool active);
 
+    // Extended attributes indexed as item attributes. Call before start().
+    void setIndexedXattrs(const QStringList& names);
+
+    // Per-root content type allow/deny lists. Call before start().
+    void setContentTypeRules(const ContentTypeRules& rules);
+
     // Snapshot of current queue statistics.
     QueueStats queueStatus() const;
     QJsonObject telemetrySnapshot() const;
@@ -106,10 +187,25 @@ public:
     // Number of items processed so far.
     int processedCount() const { return m_processedCount.load(); }
 
+    // Per-root crawl progress, throughput and ETA since the last start or
+    // rebuild.
+    IndexingProgress progressSnapshot() const;
+
+    WatcherStatus watcherStatus() const;
+
 signals:
     void progressUpdated(int processedCount, int totalCount);
     void indexingComplete();
     void indexingError(const QString& error);
+    // Crawl milestones, from the scan and writer threads. rootScanned
+    // carries how many files the walk queued; rootCompleted fires once all
+    // of them are written.
+    void rootScanStarted(const QString& root);
+    void rootScanned(const QString& root, qint64 files);
+    void rootCompleted(const QString& root, qint64 files);
+    // A file whose extraction failed for good (retries exhausted or not
+    // retryable).
+    void fileFailed(const QString& path, const QString& stage, const QString& error);
 
 private:
     struct PrepTask {
@@ -125,14 +221,34 @@ private:
         bool pendingRebuildLane = false;
     };
 
+    void startInternal(const std::vector<std::string>& roots,
+                       const std::vector<std::string>& scanRoots,
+                       std::vector<WorkItem> resumeItems);
+
     // Scan thread entry point: walks directories and enqueues work items.
     void scanEntry();
+    // Marks `root` scanned in the progress tracker and emits rootScanned
+    // (and rootCompleted when nothing is left for it).
+    void finishRootScan(const std::string& root);
+    void emitCompletedRoots(bool drained);
+    void recordCarryover(std::vector<WorkItem> items, size_t nextScanRootIndex);
 
     // Stage loops.
     void prepDispatcherLoop();
     void prepWorkerLoop(size_t workerIndex);
     void writerLoop();
 
+    // Run `task` on the writer thread inside a batch, commit the batch, run
+    // `afterCommit` outside any transaction and return. Inline, in its own
+    // transaction, when the writer is not running.
+    struct WriterTask {
+        std::function<void()> run;
+        std::function<void()> afterCommit;
+        std::shared_ptr<std::promise<void>> done;
+    };
+    void runOnWriter(const std::function<void()>& task,
+                     const std::function<void()>& afterCommit = {});
+
     // Callback from FileMonitorMacOS — enqueues incoming FS events.
     void onFileSystemEvents(const std::vector<WorkItem>& items);
 
@@ -167,6 +283,8 @@ private:
     WorkQueue m_workQueue;
     std::unique_ptr<Indexer> m_indexer;
     std::unique_ptr<FileMonitorMacOS> m_monitor;
+    mutable std::mutex m_watcherMutex;
+    WatcherStatus m_watcherStatus;
 
     // Threads
     std::thread m_scanThread;
@@ -188,6 +306,7 @@ private:
     std::condition_variable m_preparedCv;
     std::deque<PreparedWork> m_preparedLiveQueue;
     std::deque<PreparedWork> m_preparedRebuildQueue;
+    std::deque<WriterTask> m_writerTasks;  // runOnWriter(), ahead of prepared work
 
     // Path coordinator state
     mutable std::mutex m_coordMutex;
@@ -209,6 +328,7 @@ private:
     std::atomic<size_t> m_writerBatchDepth{0};
     std::atomic<size_t> m_livePending{0};
     std::atomic<size_t> m_rebuildPending{0};
+    IndexingProgressTracker m_progress;
 
     // Concurrency policy
     std::atomic<bool> m_userActive{false};
@@ -218,7 +338,15 @@ private:
     int m_memorySoftLimitMb = 900;
     int m_memoryHardLimitMb = 1200;
 
+    std::vector<std::string> m_roots;
     std::vector<std::string> m_scanRoots;
+    std::vector<WorkItem> m_resumeItems;
+
+    // Work the scan thread or watcher could not enqueue because a stop was
+    // in progress; folded into the next checkpoint.
+    std::mutex m_carryoverMutex;
+    std::vector<WorkItem> m_carryoverItems;
+    std::vector<std::string> m_unscannedRoots;
     PipelineRuntimeConfig m_runtimeConfig;
     ActorMode m_actorMode = ActorMode::Dual;
     std::unique_ptr<PipelineSchedulerActor> m_schedulerActor;
@@ -126,6 +126,22 @@ void PipelineSchedulerActor::notifyAll()
     m_cv.notify_all();
 }
 
+std::vector<PipelineSchedulerActor::ScheduledItem> PipelineSchedulerActor::takeAll()
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    std::vector<ScheduledItem> items;
+    items.reserve(m_liveQueue.size() + m_rebuildQueue.size());
+    for (WorkItem& item : m_liveQueue) {
+        items.push_back({std::move(item), PipelineLane::Live});
+    }
+    for (WorkItem& item : m_rebuildQueue) {
+        items.push_back({std::move(item), PipelineLane::Rebuild});
+    }
+    m_liveQueue.clear();
+    m_rebuildQueue.clear();
+    return items;
+}
+
 void PipelineSchedulerActor::recordDrop(PipelineLane lane, const QString& reason)
 {
     std::lock_guard<std::mutex> lock(m_mutex);
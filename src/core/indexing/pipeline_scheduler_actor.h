@@ -11,6 +11,7 @@
 #include <deque>
 #include <mutex>
 #include <optional>
+#include <vector>
 
 namespace bs {
 
@@ -59,6 +60,9 @@ public:
     void shutdown();
     void notifyAll();
 
+    // Remove and return everything still queued, live lane first.
+    std::vector<ScheduledItem> takeAll();
+
     void recordDrop(PipelineLane lane, const QString& reason);
     void recordCoalesced();
     void recordStaleDropped();
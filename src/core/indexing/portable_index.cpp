@@ -0,0 +1,199 @@
+#include "core/indexing/portable_index.h"
+
+#include "core/index/sqlite_store.h"
+#include "core/indexing/chunker.h"
+#include "core/shared/types.h"
+
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+QJsonObject field(const char* name, const char* type, bool required)
+{
+    QJsonObject json;
+    json[QStringLiteral("name")] = QLatin1String(name);
+    json[QStringLiteral("type")] = QLatin1String(type);
+    json[QStringLiteral("required")] = required;
+    return json;
+}
+
+} // namespace
+
+QString PortableIndex::segmentFile(int64_t first)
+{
+    return QStringLiteral("segments/%1.ndjson").arg(first, 12, 10, QLatin1Char('0'));
+}
+
+QJsonObject PortableIndex::manifestToJson(const PortableManifest& manifest)
+{
+    QJsonObject json;
+    json[QStringLiteral("format")] = QLatin1String(kFormat);
+    json[QStringLiteral("formatVersion")] = manifest.formatVersion;
+    json[QStringLiteral("schemaVersion")] = manifest.schemaVersion;
+    json[QStringLiteral("source")] = manifest.source;
+    json[QStringLiteral("createdAt")] = manifest.createdAt.toUTC().toString(Qt::ISODate);
+    json[QStringLiteral("segmentSpan")] = static_cast<qint64>(PeerSync::kSegmentSpan);
+    json[QStringLiteral("itemCount")] = static_cast<qint64>(manifest.itemCount);
+    QJsonArray segments = PeerSync::segmentsToJson(manifest.segments);
+    for (qsizetype i = 0; i < segments.size(); ++i) {
+        QJsonObject segment = segments.at(i).toObject();
+        segment[QStringLiteral("file")] = segmentFile(manifest.segments[i].first);
+        segments[i] = segment;
+    }
+    json[QStringLiteral("segments")] = segments;
+    return json;
+}
+
+std::optional<PortableManifest> PortableIndex::manifestFromJson(const QByteArray& json,
+                                                                QString* error)
+{
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json, &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        *error = QStringLiteral("The manifest is not a JSON object");
+        return std::nullopt;
+    }
+    const QJsonObject object = document.object();
+    if (object.value(QStringLiteral("format")).toString() != QLatin1String(kFormat)) {
+        *error = QStringLiteral("Not a portable BetterSpotlight index");
+        return std::nullopt;
+    }
+
+    PortableManifest manifest;
+    manifest.formatVersion = object.value(QStringLiteral("formatVersion")).toInt();
+    if (manifest.formatVersion < 1 || manifest.formatVersion > kFormatVersion) {
+        *error = QStringLiteral("Format version %1 is not supported (this build reads up to %2)")
+                     .arg(manifest.formatVersion)
+                     .arg(kFormatVersion);
+        return std::nullopt;
+    }
+    if (object.value(QStringLiteral("segmentSpan")).toInteger() != PeerSync::kSegmentSpan) {
+        *error = QStringLiteral("Segments must span %1 item ids").arg(PeerSync::kSegmentSpan);
+        return std::nullopt;
+    }
+    manifest.schemaVersion = object.value(QStringLiteral("schemaVersion")).toInt();
+    manifest.source = object.value(QStringLiteral("source")).toString();
+    manifest.createdAt = QDateTime::fromString(object.value(QStringLiteral("createdAt")).toString(),
+                                               Qt::ISODate);
+    manifest.itemCount = object.value(QStringLiteral("itemCount")).toInteger();
+
+    const QJsonArray segments = object.value(QStringLiteral("segments")).toArray();
+    manifest.segments = PeerSync::segmentsFromJson(segments);
+    if (manifest.segments.size() != static_cast<size_t>(segments.size())) {
+        *error = QStringLiteral("The manifest lists a segment off the %1-id grid")
+                     .arg(PeerSync::kSegmentSpan);
+        return std::nullopt;
+    }
+    QSet<int64_t> firsts;
+    int64_t items = 0;
+    for (const SyncSegment& segment : manifest.segments) {
+        if (firsts.contains(segment.first) || segment.count < 0
+            || segment.count > PeerSync::kSegmentSpan) {
+            *error = QStringLiteral("The manifest's segment %1 is invalid").arg(segment.first);
+            return std::nullopt;
+        }
+        firsts.insert(segment.first);
+        items += segment.count;
+    }
+    if (items != manifest.itemCount) {
+        *error = QStringLiteral("The manifest lists %1 items but its segments hold %2")
+                     .arg(manifest.itemCount)
+                     .arg(items);
+        return std::nullopt;
+    }
+    std::sort(manifest.segments.begin(), manifest.segments.end(),
+              [](const SyncSegment& a, const SyncSegment& b) { return a.first < b.first; });
+    return manifest;
+}
+
+QJsonObject PortableIndex::schemaJson()
+{
+    QJsonObject schema;
+    schema[QStringLiteral("format")] = QLatin1String(kFormat);
+    schema[QStringLiteral("formatVersion")] = kFormatVersion;
+    schema[QStringLiteral("segmentSpan")] = static_cast<qint64>(PeerSync::kSegmentSpan);
+    schema[QStringLiteral("maxTextChars")] = PeerSync::kMaxTextChars;
+    schema[QStringLiteral("entry")] = QJsonArray{
+        field("id", "integer", true),
+        field("path", "string", true),
+        field("name", "string", false),
+        field("kind", "string", false),
+        field("size", "integer", false),
+        field("createdAt", "number", false),
+        field("modifiedAt", "number", false),
+        field("indexedAt", "number", false),
+        field("contentHash", "string", false),
+        field("text", "string", false),
+        field("metadata", "object", false),
+    };
+    return schema;
+}
+
+QByteArray PortableIndex::segmentToNdjson(const std::vector<SyncedItem>& items)
+{
+    QByteArray out;
+    for (const SyncedItem& item : items) {
+        out += QJsonDocument(PeerSync::itemToJson(item)).toJson(QJsonDocument::Compact);
+        out += '\n';
+    }
+    return out;
+}
+
+std::optional<std::vector<SyncedItem>> PortableIndex::segmentFromNdjson(const QByteArray& ndjson,
+                                                                        const SyncSegment& segment,
+                                                                        QString* error)
+{
+    std::vector<SyncedItem> items;
+    int lineNumber = 0;
+    for (const QByteArray& line : ndjson.split('\n')) {
+        ++lineNumber;
+        if (line.trimmed().isEmpty()) {
+            continue;
+        }
+        const QJsonDocument document = QJsonDocument::fromJson(line);
+        const std::optional<SyncedItem> item =
+            document.isObject() ? PeerSync::itemFromJson(document.object()) : std::nullopt;
+        if (!item.has_value() || PeerSync::segmentFirst(item->id) != segment.first) {
+            *error = QStringLiteral("Segment %1, line %2 is not one of its entries")
+                         .arg(segment.first)
+                         .arg(lineNumber);
+            return std::nullopt;
+        }
+        items.push_back(*item);
+    }
+    const std::vector<SyncSegment> digests = PeerSync::segments(items);
+    const QString digest = digests.empty() ? QString() : digests.front().digest;
+    if (static_cast<int>(items.size()) != segment.count || digest != segment.digest) {
+        *error = QStringLiteral("Segment %1 does not match the manifest; the archive is damaged")
+                     .arg(segment.first);
+        return std::nullopt;
+    }
+    return items;
+}
+
+bool PortableIndex::importItem(SQLiteStore& store, const SyncedItem& item)
+{
+    const QFileInfo info(item.path);
+    const QString suffix = info.suffix().toLower();
+    const std::optional<int64_t> id = store.upsertItem(
+        item.path, item.name, suffix.isEmpty() ? QString() : QLatin1Char('.') + suffix,
+        itemKindFromString(item.kind), item.size, item.createdAt, item.modifiedAt,
+        item.contentHash, QStringLiteral("normal"), info.path());
+    if (!id.has_value()) {
+        return false;
+    }
+    MetadataMerge::apply(store, *id, item.indexedAt, item.metadata,
+                         MetadataMerge::defaultPolicies());
+    const std::vector<Chunk> chunks = Chunker().chunkContent(item.path, item.text);
+    return chunks.empty() || store.insertChunks(*id, item.name, item.path, chunks);
+}
+
+} // namespace bs
@@ -0,0 +1,75 @@
+#pragma once
+
+#include "core/indexing/peer_sync.h"
+
+#include <QByteArray>
+#include <QDateTime>
+#include <QJsonObject>
+#include <QString>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+class SQLiteStore;
+
+// What a portable index archive holds, as listed in its manifest.
+struct PortableManifest {
+    int formatVersion = 0;
+    int schemaVersion = 0;           // of the index it was exported from
+    QString source;                  // the exporting Mac's host name
+    QDateTime createdAt;
+    int64_t itemCount = 0;
+    std::vector<SyncSegment> segments;  // in first-id order
+};
+
+// PortableIndex -- the `bspot export --portable` archive, for moving an
+// index to another Mac or attaching it there as a team index.
+//
+// A gzipped tar of one kRootDirectory with kManifestFile, kSchemaFile (the
+// fields of an entry) and one NDJSON file per PeerSync segment. Entries are
+// what PeerSync publishes: index entries and at most
+// PeerSync::kMaxTextChars of extracted text, never file contents. Each
+// segment's manifest digest is PeerSync's, so `bspot import` can tell a
+// damaged or truncated segment from a good one, and archives are compared
+// (or synced from) segment by segment. A reader refuses a newer
+// formatVersion; fields it does not know are ignored.
+class PortableIndex {
+public:
+    static constexpr const char* kFormat = "betterspotlight-portable-index";
+    static constexpr int kFormatVersion = 1;
+    static constexpr const char* kFileExtension = ".bsindex";
+    static constexpr const char* kRootDirectory = "betterspotlight-index";
+    static constexpr const char* kManifestFile = "manifest.json";
+    static constexpr const char* kSchemaFile = "schema.json";
+    static constexpr int64_t kMaxManifestBytes = 16 * 1024 * 1024;
+    static constexpr int64_t kMaxSegmentBytes = 256 * 1024 * 1024;
+
+    // "segments/000000000512.ndjson", relative to kRootDirectory.
+    static QString segmentFile(int64_t first);
+
+    static QJsonObject manifestToJson(const PortableManifest& manifest);
+    // Nullopt with *error for another format, a newer formatVersion, or a
+    // segment off the PeerSync grid or listed twice.
+    static std::optional<PortableManifest> manifestFromJson(const QByteArray& json, QString* error);
+
+    // kSchemaFile's content: formatVersion, segmentSpan and each entry
+    // field's name, JSON type and whether it is required.
+    static QJsonObject schemaJson();
+
+    static QByteArray segmentToNdjson(const std::vector<SyncedItem>& items);
+    // The entries of `segment`'s file; nullopt with *error when a line is
+    // not an entry, an id is outside the segment, or count or digest
+    // differ from the manifest's.
+    static std::optional<std::vector<SyncedItem>> segmentFromNdjson(const QByteArray& ndjson,
+                                                                    const SyncSegment& segment,
+                                                                    QString* error);
+
+    // Write `item` into `store` as an indexed file, its text chunked as the
+    // pipeline chunks extracted text and its metadata merged in.
+    static bool importItem(SQLiteStore& store, const SyncedItem& item);
+};
+
+} // namespace bs
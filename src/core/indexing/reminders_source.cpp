@@ -0,0 +1,139 @@
+#include "core/indexing/reminders_source.h"
+
+#include "core/fs/spotlight_metadata.h"
+#include "core/shared/search_options.h"
+
+#include <QDateTime>
+#include <QSet>
+#include <QUrl>
+
+namespace bs {
+
+namespace {
+
+QString epochString(double epoch)
+{
+    return QString::number(static_cast<qint64>(epoch));
+}
+
+// "2026-10-14 17:00", "2026-10-14" for a reminder due on a day.
+QString dueLine(const Reminder& reminder)
+{
+    const QDateTime due = QDateTime::fromSecsSinceEpoch(static_cast<qint64>(reminder.dueDate));
+    return due.toString(reminder.allDay ? QStringLiteral("yyyy-MM-dd")
+                                        : QStringLiteral("yyyy-MM-dd HH:mm"));
+}
+
+// "Due 2026-10-14 17:00 · Work", "Completed · Work", or the list alone.
+QString descriptionLine(const Reminder& reminder)
+{
+    QStringList parts;
+    if (reminder.completed) {
+        parts.append(QStringLiteral("Completed"));
+    } else if (reminder.dueDate > 0.0) {
+        parts.append(QStringLiteral("Due ") + dueLine(reminder));
+    }
+    parts.append(reminder.listName.trimmed());
+    parts.removeAll(QString());
+    return parts.join(QStringLiteral(" · "));
+}
+
+DonatedItem toItem(const Reminder& reminder)
+{
+    DonatedItem item;
+    item.name = reminder.title.trimmed();
+    item.path = SpotlightDonation::itemPath(
+        QString::fromLatin1(RemindersSource::kBundleIdentifier), reminder.identifier);
+    item.parentPath =
+        SpotlightDonation::bundlePath(QString::fromLatin1(RemindersSource::kBundleIdentifier));
+
+    const QString description = descriptionLine(reminder);
+    QStringList lines = {item.name, description, reminder.notes.trimmed()};
+    lines.removeAll(QString());
+    item.textContent = lines.join(QLatin1Char('\n'));
+    item.size = item.textContent.toUtf8().size();
+    item.createdAt = reminder.createdAt;
+    item.modifiedAt = reminder.modifiedAt > 0.0 ? reminder.modifiedAt : reminder.createdAt;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   QString::fromLatin1(RemindersSource::kContentType), false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute), description,
+                   false});
+    raw.push_back({QString::fromLatin1(RemindersSource::kListAttribute),
+                   reminder.listName.trimmed(), true});
+    if (reminder.dueDate > 0.0) {
+        raw.push_back({QString::fromLatin1(SearchOptions::kDueDateAttribute),
+                       epochString(reminder.dueDate), false});
+    }
+    raw.push_back({QString::fromLatin1(RemindersSource::kCompletedAttribute),
+                   reminder.completed ? QStringLiteral("1") : QStringLiteral("0"), false});
+    if (reminder.priority > 0) {
+        raw.push_back({QString::fromLatin1(RemindersSource::kPriorityAttribute),
+                       QString::number(reminder.priority), false});
+    }
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute),
+                   RemindersSource::reminderUrl(reminder), false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+QString RemindersSource::accessToString(Access access)
+{
+    switch (access) {
+    case Access::Authorized:
+        return QStringLiteral("authorized");
+    case Access::NotDetermined:
+        return QStringLiteral("notDetermined");
+    case Access::Denied:
+        return QStringLiteral("denied");
+    case Access::Unavailable:
+        break;
+    }
+    return QStringLiteral("unavailable");
+}
+
+std::vector<DonatedItem> RemindersSource::items(const std::vector<Reminder>& reminders)
+{
+    std::vector<DonatedItem> out;
+    out.reserve(reminders.size());
+    QSet<QString> seen;
+    for (const Reminder& reminder : reminders) {
+        if (reminder.identifier.trimmed().isEmpty() || reminder.title.trimmed().isEmpty()
+            || seen.contains(reminder.identifier)) {
+            continue;
+        }
+        seen.insert(reminder.identifier);
+        out.push_back(toItem(reminder));
+    }
+    return out;
+}
+
+QStringList RemindersSource::staleIdentifiers(const QStringList& indexedIdentifiers,
+                                              const std::vector<Reminder>& reminders)
+{
+    QSet<QString> current;
+    for (const Reminder& reminder : reminders) {
+        if (!reminder.title.trimmed().isEmpty()) {
+            current.insert(reminder.identifier);
+        }
+    }
+    QStringList stale;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!identifier.isEmpty() && !current.contains(identifier)) {
+            stale.append(identifier);
+        }
+    }
+    return stale;
+}
+
+QString RemindersSource::reminderUrl(const Reminder& reminder)
+{
+    return QStringLiteral("x-apple-reminderkit://REMCDReminder/")
+           + QString::fromLatin1(QUrl::toPercentEncoding(reminder.identifier));
+}
+
+} // namespace bs
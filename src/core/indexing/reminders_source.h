@@ -0,0 +1,81 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One reminder of the local Reminders lists.
+struct Reminder {
+    QString identifier;       // EKReminder.calendarItemIdentifier
+    QString title;
+    QString notes;
+    QString listName;         // "Groceries", "Work"
+    double dueDate = 0.0;     // epoch seconds; 0 when there is none
+    bool allDay = false;      // due on a day rather than at a time
+    bool completed = false;
+    double completedAt = 0.0;
+    double createdAt = 0.0;
+    double modifiedAt = 0.0;
+    int priority = 0;         // EventKit's: 0 none, 1 high ... 9 low
+};
+
+// RemindersSource -- the opt-in source that indexes reminders (title,
+// notes, due date, list and completion state), so a task's name is found
+// from the launcher and "due:today kind:reminder" lists what is due. Read
+// through EventKit, written as donations from com.apple.reminders (see
+// SpotlightDonation), with the due date stored as SearchOptions'
+// kDueDateAttribute for the due: filter.
+class RemindersSource {
+public:
+    static constexpr const char* kBundleIdentifier = "com.apple.reminders";
+    // "1" turns the source on; anything else, or no row, leaves it off.
+    static constexpr const char* kSettingKey = "index_reminders";
+    static constexpr const char* kContentType = "com.apple.reminders.reminder";
+
+    // Stored attributes beyond SpotlightDonation's and the due date.
+    static constexpr const char* kListAttribute = "list";
+    static constexpr const char* kCompletedAttribute = "completed";  // "1" or "0"
+    static constexpr const char* kPriorityAttribute = "priority";
+
+    // Completed reminders are read this far back; older ones are left out.
+    static constexpr int kCompletedDays = 30;
+
+    enum class Access {
+        Authorized,
+        NotDetermined,  // the user has not been asked yet
+        Denied,         // denied, restricted or write-only in Privacy & Security
+        Unavailable,    // not macOS
+    };
+    static Access accessStatus();
+    static QString accessToString(Access access);
+
+    // Asks for full reminders access when the user has not been asked yet
+    // and returns without waiting; the answer applies to the next sync.
+    static void requestAccess();
+
+    // Every incomplete reminder, and those completed since `completedSince`
+    // (epoch seconds), or nullopt with *error when the store cannot be read.
+    static std::optional<std::vector<Reminder>> readReminders(double completedSince,
+                                                              QString* error);
+
+    // The reminders as donated items of kBundleIdentifier, ready for
+    // Pipeline::applyDonations. Untitled reminders and repeated identifiers
+    // are left out.
+    static std::vector<DonatedItem> items(const std::vector<Reminder>& reminders);
+
+    // The indexed identifiers no longer in `reminders`.
+    static QStringList staleIdentifiers(const QStringList& indexedIdentifiers,
+                                        const std::vector<Reminder>& reminders);
+
+    // x-apple-reminderkit://REMCDReminder/<identifier>, which Reminders
+    // opens the reminder for.
+    static QString reminderUrl(const Reminder& reminder);
+};
+
+} // namespace bs
@@ -0,0 +1,142 @@
+#include "core/indexing/reminders_source.h"
+
+#import <EventKit/EventKit.h>
+#import <Foundation/Foundation.h>
+
+namespace bs {
+
+namespace {
+
+// How long a fetch may take before the sync gives up on it.
+constexpr int64_t kFetchTimeoutSeconds = 30;
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+double toEpoch(NSDate* date)
+{
+    return date ? date.timeIntervalSince1970 : 0.0;
+}
+
+// One store for the process; it outlives the access request's callback.
+EKEventStore* eventStore()
+{
+    static EKEventStore* store = [[EKEventStore alloc] init];
+    return store;
+}
+
+// The reminders matching `predicate`. EventKit fetches reminders
+// asynchronously; the sync waits for them. Nil on failure or timeout.
+NSArray<EKReminder*>* fetchReminders(NSPredicate* predicate)
+{
+    __block NSArray<EKReminder*>* fetched = nil;
+    dispatch_semaphore_t done = dispatch_semaphore_create(0);
+    [eventStore() fetchRemindersMatchingPredicate:predicate
+                                       completion:^(NSArray<EKReminder*>* reminders) {
+                                           fetched = [reminders retain];
+                                           dispatch_semaphore_signal(done);
+                                       }];
+    const long timedOut = dispatch_semaphore_wait(
+        done, dispatch_time(DISPATCH_TIME_NOW, kFetchTimeoutSeconds * NSEC_PER_SEC));
+    dispatch_release(done);
+    if (timedOut != 0) {
+        return nil;
+    }
+    return [fetched autorelease];
+}
+
+Reminder toReminder(EKReminder* match)
+{
+    Reminder reminder;
+    reminder.identifier = toQString(match.calendarItemIdentifier);
+    reminder.title = toQString(match.title);
+    reminder.notes = toQString(match.notes);
+    reminder.listName = toQString(match.calendar.title);
+    NSDateComponents* due = match.dueDateComponents;
+    if (due) {
+        NSCalendar* calendar = due.calendar ?: [NSCalendar currentCalendar];
+        reminder.dueDate = toEpoch([calendar dateFromComponents:due]);
+        reminder.allDay = due.hour == NSDateComponentUndefined;
+    }
+    reminder.completed = match.completed;
+    reminder.completedAt = toEpoch(match.completionDate);
+    reminder.createdAt = toEpoch(match.creationDate);
+    reminder.modifiedAt = toEpoch(match.lastModifiedDate);
+    reminder.priority = static_cast<int>(match.priority);
+    return reminder;
+}
+
+} // namespace
+
+RemindersSource::Access RemindersSource::accessStatus()
+{
+    const EKAuthorizationStatus status =
+        [EKEventStore authorizationStatusForEntityType:EKEntityTypeReminder];
+    if (status == EKAuthorizationStatusNotDetermined) {
+        return Access::NotDetermined;
+    }
+    // EKAuthorizationStatusAuthorized is EKAuthorizationStatusFullAccess
+    // from macOS 14 on.
+    if (status == EKAuthorizationStatusAuthorized) {
+        return Access::Authorized;
+    }
+    return Access::Denied;
+}
+
+void RemindersSource::requestAccess()
+{
+    if (accessStatus() != Access::NotDetermined) {
+        return;
+    }
+    // A store made before access was granted keeps showing no lists until
+    // it is reset.
+    void (^completion)(BOOL, NSError*) = ^(BOOL granted, NSError*) {
+        if (granted) {
+            [eventStore() reset];
+        }
+    };
+    if (@available(macOS 14.0, *)) {
+        [eventStore() requestFullAccessToRemindersWithCompletion:completion];
+    } else {
+        [eventStore() requestAccessToEntityType:EKEntityTypeReminder completion:completion];
+    }
+}
+
+std::optional<std::vector<Reminder>> RemindersSource::readReminders(double completedSince,
+                                                                    QString* error)
+{
+    if (accessStatus() != Access::Authorized) {
+        *error = QStringLiteral("Reminders access is %1").arg(accessToString(accessStatus()));
+        return std::nullopt;
+    }
+
+    std::vector<Reminder> reminders;
+    @autoreleasepool {
+        EKEventStore* store = eventStore();
+        NSArray<EKReminder*>* incomplete = fetchReminders(
+            [store predicateForIncompleteRemindersWithDueDateStarting:nil
+                                                               ending:nil
+                                                            calendars:nil]);
+        NSArray<EKReminder*>* completed = fetchReminders(
+            [store predicateForCompletedRemindersWithCompletionDateStarting:
+                       [NSDate dateWithTimeIntervalSince1970:completedSince]
+                                                                    ending:nil
+                                                                 calendars:nil]);
+        if (!incomplete || !completed) {
+            *error = QStringLiteral("Reminders could not be read");
+            return std::nullopt;
+        }
+        reminders.reserve(incomplete.count + completed.count);
+        for (EKReminder* match in incomplete) {
+            reminders.push_back(toReminder(match));
+        }
+        for (EKReminder* match in completed) {
+            reminders.push_back(toReminder(match));
+        }
+    }
+    return reminders;
+}
+
+} // namespace bs
@@ -0,0 +1,20 @@
+#include "core/indexing/reminders_source.h"
+
+namespace bs {
+
+RemindersSource::Access RemindersSource::accessStatus()
+{
+    return Access::Unavailable;
+}
+
+void RemindersSource::requestAccess()
+{
+}
+
+std::optional<std::vector<Reminder>> RemindersSource::readReminders(double, QString* error)
+{
+    *error = QStringLiteral("Reminders are only available on macOS");
+    return std::nullopt;
+}
+
+} // namespace bs
@@ -0,0 +1,504 @@
+#include "core/indexing/s3_source.h"
+
+#include "core/fs/file_scanner.h"
+#include "core/fs/spotlight_metadata.h"
+#include "core/indexing/peer_sync.h"
+#include "core/indexing/ssh_source.h"
+
+#include <QCryptographicHash>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+#include <QMessageAuthenticationCode>
+#include <QRegularExpression>
+#include <QSet>
+#include <QXmlStreamReader>
+
+#include <algorithm>
+#include <cmath>
+#include <map>
+
+namespace bs {
+
+namespace {
+
+constexpr const char* kAlgorithm = "AWS4-HMAC-SHA256";
+
+// The SHA-256 of an empty body: every request here is a GET.
+QByteArray emptyPayloadHash()
+{
+    return QCryptographicHash::hash(QByteArray(), QCryptographicHash::Sha256).toHex();
+}
+
+// RFC 3986 encoding as SigV4 wants it: everything but unreserved
+// characters, and '/' too unless it separates a path.
+QByteArray awsEncode(const QString& value, bool keepSlash)
+{
+    return value.toUtf8().toPercentEncoding(keepSlash ? QByteArrayLiteral("/") : QByteArray());
+}
+
+QByteArray hmac(const QByteArray& key, const QByteArray& message)
+{
+    return QMessageAuthenticationCode::hash(message, key, QCryptographicHash::Sha256);
+}
+
+// host[:port] as sent, without a default port.
+QString hostHeader(const QUrl& url)
+{
+    const int defaultPort = url.scheme() == QLatin1String("https") ? 443 : 80;
+    return url.port() > 0 && url.port() != defaultPort
+               ? QStringLiteral("%1:%2").arg(url.host()).arg(url.port())
+               : url.host();
+}
+
+QString baseUrl(const S3Bucket& bucket)
+{
+    QString host = bucket.pathStyle ? bucket.endpoint.host()
+                                    : bucket.bucket + QLatin1Char('.') + bucket.endpoint.host();
+    QUrl base;
+    base.setScheme(bucket.endpoint.scheme());
+    base.setHost(host);
+    base.setPort(bucket.endpoint.port());
+    return base.toString(QUrl::FullyEncoded);
+}
+
+QString bucketPath(const S3Bucket& bucket)
+{
+    return bucket.pathStyle ? QLatin1Char('/') + QString::fromLatin1(awsEncode(bucket.bucket, false))
+                            : QString();
+}
+
+QString parentOf(const QString& key)
+{
+    const qsizetype slash = key.lastIndexOf(QLatin1Char('/'));
+    return slash > 0 ? key.left(slash) : QString();
+}
+
+QString readText(QXmlStreamReader& reader)
+{
+    return reader.readElementText(QXmlStreamReader::SkipChildElements);
+}
+
+} // namespace
+
+std::optional<std::vector<S3Bucket>> S3Source::parseBuckets(const QString& json, QString* error)
+{
+    std::vector<S3Bucket> buckets;
+    if (json.trimmed().isEmpty()) {
+        return buckets;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("S3 sources must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxBuckets) {
+        *error = QStringLiteral("At most %1 S3 sources").arg(kMaxBuckets);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    static const QRegularExpression validBucket(QStringLiteral("^[A-Za-z0-9][A-Za-z0-9._-]{0,254}$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        S3Bucket bucket;
+        bucket.name = object.value(QStringLiteral("name")).toString().trimmed();
+        bucket.endpoint = QUrl(object.value(QStringLiteral("endpoint")).toString().trimmed());
+        bucket.bucket = object.value(QStringLiteral("bucket")).toString().trimmed();
+        bucket.prefix = object.value(QStringLiteral("prefix")).toString();
+        bucket.region = object.value(QStringLiteral("region")).toString().trimmed();
+        if (bucket.region.isEmpty()) {
+            bucket.region = QString::fromLatin1(kDefaultRegion);
+        }
+        // AWS itself prefers virtual-hosted buckets; other stores (MinIO,
+        // Ceph, a NAS) mostly only do path-style.
+        bucket.pathStyle = object.value(QStringLiteral("pathStyle"))
+                               .toBool(!bucket.endpoint.host().endsWith(QLatin1String("amazonaws.com")));
+        bucket.profile = object.value(QStringLiteral("profile")).toString().trimmed();
+        if (bucket.profile.isEmpty()) {
+            bucket.profile = QString::fromLatin1(kDefaultProfile);
+        }
+        for (const QJsonValue& value : object.value(QStringLiteral("contentTypes")).toArray()) {
+            QString extension = value.toString().trimmed().toLower();
+            if (extension.startsWith(QLatin1Char('.'))) {
+                extension.remove(0, 1);
+            }
+            if (!extension.isEmpty() && !bucket.contentTypes.contains(extension)) {
+                bucket.contentTypes.append(extension);
+            }
+        }
+        bucket.maxContentBytes = object.value(QStringLiteral("maxContentBytes"))
+                                     .toInteger(kDefaultMaxContentBytes);
+        if (bucket.maxContentBytes <= 0) {
+            bucket.maxContentBytes = kDefaultMaxContentBytes;
+        }
+
+        const QString label = bucket.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : bucket.name;
+        if (bucket.name.size() > kMaxNameChars || !validName.match(bucket.name).hasMatch()) {
+            *error = QStringLiteral("S3 source %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (names.contains(bucket.name.toLower())) {
+            *error = QStringLiteral("S3 source %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (!PeerSync::isAllowedUrl(bucket.endpoint)) {
+            *error = QStringLiteral("S3 source %1: the endpoint must be https").arg(label);
+            return std::nullopt;
+        }
+        if (!validBucket.match(bucket.bucket).hasMatch()) {
+            *error = QStringLiteral("S3 source %1: '%2' is not a bucket name").arg(label, bucket.bucket);
+            return std::nullopt;
+        }
+        // Settings are plain JSON in two places; keys stay in the
+        // credentials file.
+        if (object.contains(QStringLiteral("accessKeyId"))
+            || object.contains(QStringLiteral("secretAccessKey"))) {
+            *error = QStringLiteral("S3 source %1: put its keys under [%2] in %3, not in settings")
+                         .arg(label, bucket.profile, credentialsPath());
+            return std::nullopt;
+        }
+        names.insert(bucket.name.toLower());
+        buckets.push_back(std::move(bucket));
+    }
+    return buckets;
+}
+
+QString S3Source::credentialsPath()
+{
+    const QString configured = qEnvironmentVariable(kEnvCredentialsFile);
+    if (!configured.isEmpty()) {
+        return configured;
+    }
+    return QDir::homePath() + QStringLiteral("/.aws/credentials");
+}
+
+bool S3Source::applyCredentials(S3Bucket& bucket, const QByteArray& credentials, QString* error)
+{
+    bool inProfile = false;
+    bool found = false;
+    QString accessKeyId;
+    QString secretAccessKey;
+    for (const QByteArray& rawLine : credentials.split('\n')) {
+        const QString line = QString::fromUtf8(rawLine).trimmed();
+        if (line.isEmpty() || line.startsWith(QLatin1Char('#')) || line.startsWith(QLatin1Char(';'))) {
+            continue;
+        }
+        if (line.startsWith(QLatin1Char('[')) && line.endsWith(QLatin1Char(']'))) {
+            inProfile = line.mid(1, line.size() - 2).trimmed() == bucket.profile;
+            found = found || inProfile;
+            continue;
+        }
+        const qsizetype equals = line.indexOf(QLatin1Char('='));
+        if (!inProfile || equals < 0) {
+            continue;
+        }
+        const QString key = line.left(equals).trimmed().toLower();
+        const QString value = line.mid(equals + 1).trimmed();
+        if (key == QLatin1String("aws_access_key_id")) {
+            accessKeyId = value;
+        } else if (key == QLatin1String("aws_secret_access_key")) {
+            secretAccessKey = value;
+        }
+    }
+    if (!found) {
+        *error = QStringLiteral("S3 source %1: no [%2] profile in the credentials file")
+                     .arg(bucket.name, bucket.profile);
+        return false;
+    }
+    if (accessKeyId.isEmpty() || secretAccessKey.isEmpty()) {
+        *error = QStringLiteral("S3 source %1: profile [%2] needs aws_access_key_id and "
+                                "aws_secret_access_key")
+                     .arg(bucket.name, bucket.profile);
+        return false;
+    }
+    bucket.accessKeyId = accessKeyId;
+    bucket.secretAccessKey = secretAccessKey;
+    return true;
+}
+
+QString S3Source::bundleIdentifier(const QString& bucketName)
+{
+    return QLatin1String(kBundlePrefix) + bucketName.toLower();
+}
+
+bool S3Source::isS3Path(const QString& path)
+{
+    return SpotlightDonation::isDonatedPath(path)
+           && SpotlightDonation::bundleIdentifier(path).startsWith(QLatin1String(kBundlePrefix));
+}
+
+QUrl S3Source::listUrl(const S3Bucket& bucket, const QString& startAfter)
+{
+    // Sorted by name, as the signature's canonical query is.
+    std::map<QByteArray, QByteArray> query = {
+        {QByteArrayLiteral("list-type"), QByteArrayLiteral("2")},
+        {QByteArrayLiteral("max-keys"), QByteArray::number(kPageSize)},
+    };
+    if (!bucket.prefix.isEmpty()) {
+        query[QByteArrayLiteral("prefix")] = awsEncode(bucket.prefix, false);
+    }
+    if (!startAfter.isEmpty()) {
+        query[QByteArrayLiteral("start-after")] = awsEncode(startAfter, false);
+    }
+    QByteArrayList pairs;
+    for (const auto& [key, value] : query) {
+        pairs.append(key + '=' + value);
+    }
+    const QString path = bucketPath(bucket);
+    return QUrl::fromEncoded(baseUrl(bucket).toUtf8() + (path.isEmpty() ? QByteArrayLiteral("/") : path.toUtf8())
+                             + '?' + pairs.join('&'));
+}
+
+QUrl S3Source::objectUrl(const S3Bucket& bucket, const QString& key)
+{
+    return QUrl::fromEncoded(baseUrl(bucket).toUtf8() + bucketPath(bucket).toUtf8() + '/'
+                             + awsEncode(key, true));
+}
+
+S3Headers S3Source::signGet(const S3Bucket& bucket, const QUrl& url, const QDateTime& now,
+                            const S3Headers& extra)
+{
+    const QDateTime utc = now.toUTC();
+    const QByteArray amzDate = utc.toString(QStringLiteral("yyyyMMdd'T'HHmmss'Z'")).toLatin1();
+    const QByteArray day = amzDate.left(8);
+    const QByteArray payloadHash = emptyPayloadHash();
+
+    std::map<QByteArray, QByteArray> headers = {
+        {QByteArrayLiteral("host"), hostHeader(url).toLatin1()},
+        {QByteArrayLiteral("x-amz-content-sha256"), payloadHash},
+        {QByteArrayLiteral("x-amz-date"), amzDate},
+    };
+    for (const auto& [name, value] : extra) {
+        headers[name.toLower()] = value.trimmed();
+    }
+
+    // URLs come from listUrl()/objectUrl(), already encoded as SigV4 wants.
+    QByteArray path = url.path(QUrl::FullyEncoded).toUtf8();
+    if (path.isEmpty()) {
+        path = QByteArrayLiteral("/");
+    }
+    QByteArrayList queryPairs = url.query(QUrl::FullyEncoded).toUtf8().split('&');
+    queryPairs.removeAll(QByteArray());
+    for (QByteArray& pair : queryPairs) {
+        if (!pair.contains('=')) {
+            pair += '=';
+        }
+    }
+    // By name, then value: not a plain sort of "name=value".
+    std::sort(queryPairs.begin(), queryPairs.end(), [](const QByteArray& a, const QByteArray& b) {
+        const qsizetype aEquals = a.indexOf('=');
+        const qsizetype bEquals = b.indexOf('=');
+        const QByteArray aName = a.left(aEquals);
+        const QByteArray bName = b.left(bEquals);
+        return aName != bName ? aName < bName : a.mid(aEquals + 1) < b.mid(bEquals + 1);
+    });
+
+    QByteArray canonicalHeaders;
+    QByteArrayList signedNames;
+    for (const auto& [name, value] : headers) {
+        canonicalHeaders += name + ':' + value + '\n';
+        signedNames.append(name);
+    }
+    const QByteArray signedHeaders = signedNames.join(';');
+    const QByteArray canonicalRequest = QByteArrayLiteral("GET\n") + path + '\n'
+                                        + queryPairs.join('&') + '\n' + canonicalHeaders + '\n'
+                                        + signedHeaders + '\n' + payloadHash;
+
+    const QByteArray scope = day + '/' + bucket.region.toLatin1() + QByteArrayLiteral("/s3/aws4_request");
+    const QByteArray stringToSign =
+        QByteArray(kAlgorithm) + '\n' + amzDate + '\n' + scope + '\n'
+        + QCryptographicHash::hash(canonicalRequest, QCryptographicHash::Sha256).toHex();
+
+    QByteArray key = hmac(QByteArrayLiteral("AWS4") + bucket.secretAccessKey.toUtf8(), day);
+    key = hmac(key, bucket.region.toLatin1());
+    key = hmac(key, QByteArrayLiteral("s3"));
+    key = hmac(key, QByteArrayLiteral("aws4_request"));
+    const QByteArray signature = hmac(key, stringToSign).toHex();
+
+    S3Headers result;
+    for (const auto& [name, value] : headers) {
+        result.append({name, value});
+    }
+    result.append({QByteArrayLiteral("authorization"),
+                   QByteArray(kAlgorithm) + " Credential=" + bucket.accessKeyId.toUtf8() + '/' + scope
+                       + ",SignedHeaders=" + signedHeaders + ",Signature=" + signature});
+    return result;
+}
+
+std::optional<S3Listing> S3Source::parseListing(const QByteArray& xml, QString* error)
+{
+    QXmlStreamReader reader(xml);
+    if (!reader.readNextStartElement()) {
+        *error = QStringLiteral("The listing is not XML");
+        return std::nullopt;
+    }
+    if (reader.name() != QLatin1String("ListBucketResult")) {
+        const QString message = errorMessage(xml);
+        *error = message.isEmpty() ? QStringLiteral("Unexpected <%1> listing").arg(reader.name())
+                                   : message;
+        return std::nullopt;
+    }
+
+    S3Listing listing;
+    while (reader.readNextStartElement()) {
+        if (reader.name() == QLatin1String("IsTruncated")) {
+            listing.truncated = readText(reader) == QLatin1String("true");
+        } else if (reader.name() == QLatin1String("Contents")) {
+            S3Object object;
+            while (reader.readNextStartElement()) {
+                if (reader.name() == QLatin1String("Key")) {
+                    object.key = readText(reader);
+                } else if (reader.name() == QLatin1String("Size")) {
+                    object.size = readText(reader).toLongLong();
+                } else if (reader.name() == QLatin1String("LastModified")) {
+                    const QDateTime modified =
+                        QDateTime::fromString(readText(reader), Qt::ISODateWithMs);
+                    object.modifiedAt = modified.isValid()
+                                            ? static_cast<double>(modified.toMSecsSinceEpoch()) / 1000.0
+                                            : 0.0;
+                } else if (reader.name() == QLatin1String("ETag")) {
+                    object.etag = readText(reader).remove(QLatin1Char('"'));
+                } else {
+                    reader.skipCurrentElement();
+                }
+            }
+            if (!object.key.isEmpty()) {
+                listing.lastKey = object.key;
+            }
+            if (!object.key.isEmpty() && !object.key.endsWith(QLatin1Char('/'))) {
+                listing.objects.push_back(std::move(object));
+            }
+        } else {
+            reader.skipCurrentElement();
+        }
+    }
+    if (reader.hasError()) {
+        *error = QStringLiteral("Malformed listing: %1").arg(reader.errorString());
+        return std::nullopt;
+    }
+    return listing;
+}
+
+QString S3Source::errorMessage(const QByteArray& xml)
+{
+    QXmlStreamReader reader(xml);
+    if (!reader.readNextStartElement() || reader.name() != QLatin1String("Error")) {
+        return {};
+    }
+    QString code;
+    QString message;
+    while (reader.readNextStartElement()) {
+        if (reader.name() == QLatin1String("Code")) {
+            code = readText(reader);
+        } else if (reader.name() == QLatin1String("Message")) {
+            message = readText(reader);
+        } else {
+            reader.skipCurrentElement();
+        }
+    }
+    if (code.isEmpty()) {
+        return message;
+    }
+    return message.isEmpty() ? code : QStringLiteral("%1: %2").arg(code, message);
+}
+
+ItemKind S3Source::kindOf(const QString& key)
+{
+    return FileScanner::classifyItemKind(QFileInfo(key).suffix().toStdString());
+}
+
+bool S3Source::isFetchable(const S3Bucket& bucket, const S3Object& object)
+{
+    if (object.size <= 0 || object.size > bucket.maxContentBytes) {
+        return false;
+    }
+    if (!bucket.contentTypes.isEmpty()
+        && !bucket.contentTypes.contains(QFileInfo(object.key).suffix().toLower())) {
+        return false;
+    }
+    switch (kindOf(object.key)) {
+    case ItemKind::Text:
+    case ItemKind::Code:
+    case ItemKind::Markdown:
+    case ItemKind::Pdf:
+        return true;
+    default:
+        return false;
+    }
+}
+
+bool S3Source::isUnchanged(const S3Object& object, int64_t indexedSize, double indexedModifiedAt)
+{
+    return indexedSize == object.size && std::abs(indexedModifiedAt - object.modifiedAt) < 0.001;
+}
+
+DonatedItem S3Source::toDonatedItem(const S3Bucket& bucket, const S3Object& object,
+                                    const QString& text)
+{
+    const QString bundle = bundleIdentifier(bucket.name);
+    const QString folder = parentOf(object.key);
+    DonatedItem donated;
+    donated.path = SpotlightDonation::itemPath(bundle, object.key);
+    donated.parentPath = SpotlightDonation::bundlePath(bundle);
+    donated.name = object.key.mid(object.key.lastIndexOf(QLatin1Char('/')) + 1);
+    // The key is searchable too: "contracts 2019 acme".
+    QStringList lines = {text.left(kMaxTextChars), object.key};
+    lines.removeAll(QString());
+    donated.textContent = lines.join(QLatin1Char('\n'));
+    donated.size = object.size;
+    donated.createdAt = object.modifiedAt;
+    donated.modifiedAt = object.modifiedAt;
+
+    // The attributes of SshSource's files, so results read the same.
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"), QString::fromLatin1(kContentType), false});
+    raw.push_back({QString::fromLatin1(SshSource::kRemoteAttribute), bucket.name, false});
+    raw.push_back({QString::fromLatin1(SshSource::kRemotePathAttribute),
+                   QStringLiteral("s3://%1/%2").arg(bucket.bucket, object.key), false});
+    raw.push_back({QString::fromLatin1(SshSource::kRemoteKindAttribute),
+                   itemKindToString(kindOf(object.key)), false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   QStringLiteral("%1 · %2").arg(bucket.name,
+                                                 folder.isEmpty() ? bucket.bucket
+                                                                  : bucket.bucket + QLatin1Char('/') + folder),
+                   false});
+    if (!object.etag.isEmpty()) {
+        raw.push_back({QStringLiteral("etag"), object.etag, false});
+    }
+    donated.attributes = SpotlightMetadata::normalize(raw);
+    donated.contentHash = SpotlightDonation::contentHash(donated);
+    return donated;
+}
+
+QStringList S3Source::staleKeys(const QStringList& indexedKeys, const QString& after,
+                                const QString& upTo, const std::vector<S3Object>& listed)
+{
+    QSet<QString> current;
+    for (const S3Object& object : listed) {
+        current.insert(object.key);
+    }
+    const QByteArray lower = after.toUtf8();
+    const QByteArray upper = upTo.toUtf8();
+    QStringList stale;
+    for (const QString& key : indexedKeys) {
+        const QByteArray bytes = key.toUtf8();
+        if ((!after.isEmpty() && bytes <= lower) || (!upTo.isEmpty() && bytes > upper)) {
+            continue;
+        }
+        if (!current.contains(key)) {
+            stale.append(key);
+        }
+    }
+    return stale;
+}
+
+} // namespace bs
@@ -0,0 +1,140 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+#include "core/shared/types.h"
+
+#include <QByteArray>
+#include <QDateTime>
+#include <QList>
+#include <QPair>
+#include <QString>
+#include <QStringList>
+#include <QUrl>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One bucket (or a prefix of one) in S3 or an S3-compatible store.
+struct S3Bucket {
+    QString name;             // label and bundle suffix, letters, digits and '-'
+    QUrl endpoint;            // https://s3.eu-west-1.amazonaws.com, http://127.0.0.1:9000
+    QString bucket;
+    QString prefix;           // only keys starting with it
+    QString region;           // signing region, "us-east-1" when not given
+    bool pathStyle = true;    // endpoint/bucket/key; false: bucket.endpoint/key
+    QString profile;          // section of the AWS credentials file, "default" when not given
+    QString accessKeyId;      // both from the profile, see applyCredentials
+    QString secretAccessKey;
+    QStringList contentTypes; // extensions whose content is read; empty: all extractable
+    int64_t maxContentBytes = 0;
+};
+
+struct S3Object {
+    QString key;
+    int64_t size = 0;
+    double modifiedAt = 0.0;  // epoch seconds
+    QString etag;
+};
+
+// One ListObjectsV2 page.
+struct S3Listing {
+    std::vector<S3Object> objects;  // in key order
+    QString lastKey;                // the page's last key, folder markers included
+    bool truncated = false;         // more keys follow the last one
+};
+
+using S3Headers = QList<QPair<QByteArray, QByteArray>>;
+
+// S3Source -- objects in S3-compatible buckets, for documents archived in
+// object storage.
+//
+// The `s3_sources` setting (settings.json `s3Sources`) lists the buckets.
+// A sync lists keys in order from where the previous one stopped (the key
+// marker kept in `s3_state`), at most kMaxPagesPerSync pages of
+// kPageSize, so a large bucket is covered over several syncs and a small
+// one in each. Every object is indexed by name, key, size and date; the
+// content of objects with a configured type and size is downloaded,
+// extracted from a temporary copy and deleted. Since a listing page covers
+// a contiguous key range, indexed keys in that range it no longer lists
+// are removed as the pass goes. Objects are donated items under
+// donated://com.betterspotlight.s3.<name>/<key>.
+//
+// Requests are signed with AWS Signature Version 4; the transport is
+// S3Client (core/ipc). The keys are never part of the setting: each bucket
+// names a profile of the AWS shared credentials file (~/.aws/credentials,
+// as the aws CLI uses), read when a sync starts. Pure logic here, no
+// network.
+class S3Source {
+public:
+    static constexpr const char* kSettingKey = "s3_sources";
+    // {name: {"marker": last key of the pass so far}}.
+    static constexpr const char* kStateSettingKey = "s3_state";
+    static constexpr const char* kBundlePrefix = "com.betterspotlight.s3.";
+    static constexpr const char* kContentType = "com.betterspotlight.remote-object";
+    static constexpr const char* kDefaultRegion = "us-east-1";
+    static constexpr const char* kDefaultProfile = "default";
+    static constexpr const char* kEnvCredentialsFile = "AWS_SHARED_CREDENTIALS_FILE";
+
+    static constexpr int kMaxBuckets = 8;
+    static constexpr int kMaxNameChars = 32;
+    static constexpr int kPageSize = 1000;
+    static constexpr int kMaxPagesPerSync = 20;
+    static constexpr int64_t kDefaultMaxContentBytes = 10 * 1024 * 1024;
+    static constexpr int kMaxTextChars = 200000;
+    static constexpr int64_t kMaxListingBytes = 8 * 1024 * 1024;
+    static constexpr int kListTimeoutMs = 60000;
+    static constexpr int kFetchTimeoutMs = 120000;
+
+    // The setting's JSON array of {name, endpoint, bucket, profile?,
+    // prefix?, region?, pathStyle?, contentTypes?, maxContentBytes?}.
+    // Empty text is no buckets; nullopt with *error when an entry is
+    // invalid, carries accessKeyId or secretAccessKey, or a name repeats.
+    static std::optional<std::vector<S3Bucket>> parseBuckets(const QString& json, QString* error);
+
+    // $AWS_SHARED_CREDENTIALS_FILE, else ~/.aws/credentials.
+    static QString credentialsPath();
+    // Sets the bucket's keys from aws_access_key_id and
+    // aws_secret_access_key under its profile in a credentials file's
+    // text. False with *error when the profile or either key is missing.
+    static bool applyCredentials(S3Bucket& bucket, const QByteArray& credentials, QString* error);
+
+    static QString bundleIdentifier(const QString& bucketName);
+    static bool isS3Path(const QString& path);
+
+    // ListObjectsV2 for the page after `startAfter` (empty: the first).
+    static QUrl listUrl(const S3Bucket& bucket, const QString& startAfter);
+    static QUrl objectUrl(const S3Bucket& bucket, const QString& key);
+
+    // The headers that sign a GET of `url` at `now`: Host, x-amz-date,
+    // x-amz-content-sha256 and Authorization. `extra` headers (lowercase
+    // names) are signed and returned too.
+    static S3Headers signGet(const S3Bucket& bucket, const QUrl& url, const QDateTime& now,
+                             const S3Headers& extra = {});
+
+    // A ListObjectsV2 response body; nullopt with *error for an S3 error
+    // document or malformed XML. Folder markers (keys ending in '/') are
+    // left out.
+    static std::optional<S3Listing> parseListing(const QByteArray& xml, QString* error);
+    // "AccessDenied: Access Denied" from an S3 error document, or empty.
+    static QString errorMessage(const QByteArray& xml);
+
+    static ItemKind kindOf(const QString& key);
+    // Whether an object's content is downloaded and extracted, or only its
+    // name and key are indexed.
+    static bool isFetchable(const S3Bucket& bucket, const S3Object& object);
+    // Whether the index already holds `object` as listed now.
+    static bool isUnchanged(const S3Object& object, int64_t indexedSize, double indexedModifiedAt);
+    static DonatedItem toDonatedItem(const S3Bucket& bucket, const S3Object& object,
+                                     const QString& text);
+
+    // Indexed keys a page no longer lists: those after `after` (empty: from
+    // the first key) up to and including `upTo` (empty: to the last) that
+    // are not in `listed`. Keys compare as S3 orders them, by UTF-8 bytes.
+    static QStringList staleKeys(const QStringList& indexedKeys, const QString& after,
+                                 const QString& upTo, const std::vector<S3Object>& listed);
+};
+
+} // namespace bs
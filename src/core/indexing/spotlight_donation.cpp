@@ -0,0 +1,298 @@
+#include "core/indexing/spotlight_donation.h"
+
+#include "core/fs/spotlight_metadata.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QRegularExpression>
+#include <QSet>
+#include <QUrl>
+
+namespace bs {
+
+namespace {
+
+bool isValidBundleIdentifier(const QString& bundleIdentifier)
+{
+    static const QRegularExpression kPattern(QStringLiteral("^[A-Za-z0-9][A-Za-z0-9.-]{0,254}$"));
+    return kPattern.match(bundleIdentifier).hasMatch();
+}
+
+std::optional<QString> parseBundleIdentifier(const QJsonObject& params, QString* error)
+{
+    const QString bundleIdentifier =
+        params.value(QStringLiteral("bundleIdentifier")).toString().trimmed();
+    if (bundleIdentifier.isEmpty()) {
+        *error = QStringLiteral("Missing 'bundleIdentifier'");
+        return std::nullopt;
+    }
+    if (!isValidBundleIdentifier(bundleIdentifier)) {
+        *error = QStringLiteral("Invalid bundleIdentifier '%1'").arg(bundleIdentifier);
+        return std::nullopt;
+    }
+    return bundleIdentifier;
+}
+
+bool isValidIdentifier(const QString& identifier)
+{
+    return !identifier.trimmed().isEmpty()
+           && identifier.size() <= SpotlightDonation::kMaxIdentifierChars;
+}
+
+// A string or an array of strings.
+QStringList stringList(const QJsonValue& value)
+{
+    if (value.isString()) {
+        return {value.toString()};
+    }
+    QStringList out;
+    for (const QJsonValue& entry : value.toArray()) {
+        if (entry.isString()) {
+            out.append(entry.toString());
+        }
+    }
+    return out;
+}
+
+// Epoch seconds or an ISO 8601 string. Nullopt when present but unreadable;
+// `fallback` when absent.
+std::optional<double> parseDate(const QJsonValue& value, double fallback)
+{
+    if (value.isUndefined() || value.isNull()) {
+        return fallback;
+    }
+    if (value.isDouble()) {
+        return value.toDouble();
+    }
+    QDateTime date = QDateTime::fromString(value.toString(), Qt::ISODateWithMs);
+    if (!date.isValid()) {
+        date = QDateTime::fromString(value.toString(), Qt::ISODate);
+    }
+    if (!date.isValid()) {
+        return std::nullopt;
+    }
+    return static_cast<double>(date.toMSecsSinceEpoch()) / 1000.0;
+}
+
+std::optional<DonatedItem> parseItem(const QString& bundleIdentifier, const QJsonObject& json,
+                                     double now, QString* error)
+{
+    const QString uniqueIdentifier = json.value(QStringLiteral("uniqueIdentifier")).toString();
+    if (!isValidIdentifier(uniqueIdentifier)) {
+        *error = QStringLiteral("missing or invalid 'uniqueIdentifier'");
+        return std::nullopt;
+    }
+    const QString domainIdentifier = json.value(QStringLiteral("domainIdentifier")).toString();
+    if (domainIdentifier.size() > SpotlightDonation::kMaxIdentifierChars) {
+        *error = QStringLiteral("'domainIdentifier' is too long");
+        return std::nullopt;
+    }
+    const QJsonObject set = json.value(QStringLiteral("attributeSet")).toObject();
+
+    DonatedItem item;
+    item.path = SpotlightDonation::itemPath(bundleIdentifier, uniqueIdentifier);
+    item.parentPath = SpotlightDonation::bundlePath(bundleIdentifier);
+
+    const QString title = set.value(QStringLiteral("title")).toString().trimmed();
+    item.name = set.value(QStringLiteral("displayName")).toString().trimmed();
+    if (item.name.isEmpty()) {
+        item.name = title;
+    }
+    if (item.name.isEmpty()) {
+        *error = QStringLiteral("attributeSet needs a 'title' or 'displayName'");
+        return std::nullopt;
+    }
+
+    // `url` is what the app wants opened; contentURL is the backing content.
+    QString url = set.value(QStringLiteral("url")).toString().trimmed();
+    if (url.isEmpty()) {
+        url = set.value(QStringLiteral("contentURL")).toString().trimmed();
+    }
+    if (!url.isEmpty()) {
+        const QUrl parsed(url, QUrl::StrictMode);
+        if (!parsed.isValid() || parsed.scheme().isEmpty()) {
+            *error = QStringLiteral("invalid url '%1'").arg(url);
+            return std::nullopt;
+        }
+    }
+
+    const std::optional<double> modifiedAt =
+        parseDate(set.value(QStringLiteral("contentModificationDate")), now);
+    const std::optional<double> createdAt = modifiedAt.has_value()
+        ? parseDate(set.value(QStringLiteral("contentCreationDate")), modifiedAt.value())
+        : std::nullopt;
+    if (!modifiedAt.has_value() || !createdAt.has_value()) {
+        *error = QStringLiteral("dates must be epoch seconds or ISO 8601 strings");
+        return std::nullopt;
+    }
+    item.modifiedAt = modifiedAt.value();
+    item.createdAt = createdAt.value();
+
+    item.textContent = set.value(QStringLiteral("textContent")).toString()
+                           .left(SpotlightDonation::kMaxTextChars);
+    item.size = item.textContent.toUtf8().size();
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"),
+                   set.value(QStringLiteral("contentType")).toString(), false});
+    if (title != item.name) {
+        raw.push_back({QStringLiteral("title"), title, true});
+    }
+    for (const QString& author : stringList(set.value(QStringLiteral("authorNames")))) {
+        raw.push_back({QStringLiteral("authors"), author, true});
+    }
+    for (const QString& keyword : stringList(set.value(QStringLiteral("keywords")))) {
+        raw.push_back({QStringLiteral("keywords"), keyword, true});
+    }
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   set.value(QStringLiteral("contentDescription")).toString(), true});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDomainAttribute), domainIdentifier,
+                   false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kUrlAttribute), url, false});
+    item.attributes = SpotlightMetadata::normalize(raw);
+
+    item.contentHash = SpotlightDonation::contentHash(item);
+    return item;
+}
+
+} // namespace
+
+std::optional<std::vector<DonatedItem>> SpotlightDonation::parseItems(const QJsonObject& params,
+                                                                      double now,
+                                                                      QString* error)
+{
+    const std::optional<QString> bundleIdentifier = parseBundleIdentifier(params, error);
+    if (!bundleIdentifier.has_value()) {
+        return std::nullopt;
+    }
+    const QJsonArray items = params.value(QStringLiteral("items")).toArray();
+    if (items.isEmpty()) {
+        *error = QStringLiteral("'items' must be a non-empty array");
+        return std::nullopt;
+    }
+    if (items.size() > kMaxItemsPerRequest) {
+        *error = QStringLiteral("At most %1 items per request").arg(kMaxItemsPerRequest);
+        return std::nullopt;
+    }
+
+    std::vector<DonatedItem> out;
+    out.reserve(static_cast<size_t>(items.size()));
+    QSet<QString> seen;
+    for (int i = 0; i < items.size(); ++i) {
+        QString itemError;
+        std::optional<DonatedItem> item =
+            parseItem(bundleIdentifier.value(), items.at(i).toObject(), now, &itemError);
+        if (!item.has_value()) {
+            *error = QStringLiteral("items[%1]: %2").arg(i).arg(itemError);
+            return std::nullopt;
+        }
+        if (seen.contains(item->path)) {
+            *error = QStringLiteral("items[%1]: duplicate uniqueIdentifier").arg(i);
+            return std::nullopt;
+        }
+        seen.insert(item->path);
+        out.push_back(std::move(item.value()));
+    }
+    return out;
+}
+
+std::optional<DonationRemoval> SpotlightDonation::parseRemoval(const QJsonObject& params,
+                                                               QString* error)
+{
+    const std::optional<QString> bundleIdentifier = parseBundleIdentifier(params, error);
+    if (!bundleIdentifier.has_value()) {
+        return std::nullopt;
+    }
+
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier.value();
+    removal.uniqueIdentifiers = stringList(params.value(QStringLiteral("uniqueIdentifiers")));
+    removal.domainIdentifiers = stringList(params.value(QStringLiteral("domainIdentifiers")));
+    removal.all = params.value(QStringLiteral("all")).toBool(false);
+
+    const int selectors = (removal.uniqueIdentifiers.isEmpty() ? 0 : 1)
+                          + (removal.domainIdentifiers.isEmpty() ? 0 : 1)
+                          + (removal.all ? 1 : 0);
+    if (selectors != 1) {
+        *error = QStringLiteral(
+            "Pass exactly one of 'uniqueIdentifiers', 'domainIdentifiers' or 'all': true");
+        return std::nullopt;
+    }
+    for (const QString& identifier : removal.uniqueIdentifiers + removal.domainIdentifiers) {
+        if (!isValidIdentifier(identifier)) {
+            *error = QStringLiteral("Invalid identifier '%1'").arg(identifier.left(64));
+            return std::nullopt;
+        }
+    }
+    return removal;
+}
+
+QString SpotlightDonation::bundlePath(const QString& bundleIdentifier)
+{
+    return QString::fromLatin1(kScheme) + QStringLiteral("://") + bundleIdentifier;
+}
+
+QString SpotlightDonation::itemPath(const QString& bundleIdentifier,
+                                    const QString& uniqueIdentifier)
+{
+    // Encoding '/' keeps every item one level below its bundle, so
+    // getItemsUnderPath(bundlePath()) is exactly the app's donations.
+    return bundlePath(bundleIdentifier) + QLatin1Char('/')
+           + QString::fromLatin1(QUrl::toPercentEncoding(uniqueIdentifier));
+}
+
+bool SpotlightDonation::isDonatedPath(const QString& path)
+{
+    return path.startsWith(QString::fromLatin1(kScheme) + QStringLiteral("://"));
+}
+
+QString SpotlightDonation::bundleIdentifier(const QString& path)
+{
+    if (!isDonatedPath(path)) {
+        return {};
+    }
+    const QString rest = path.mid(static_cast<int>(qstrlen(kScheme)) + 3);
+    return rest.section(QLatin1Char('/'), 0, 0);
+}
+
+QString SpotlightDonation::uniqueIdentifier(const QString& path)
+{
+    if (!isDonatedPath(path)) {
+        return {};
+    }
+    const QString rest = path.mid(static_cast<int>(qstrlen(kScheme)) + 3);
+    const int slash = static_cast<int>(rest.indexOf(QLatin1Char('/')));
+    if (slash < 0) {
+        return {};
+    }
+    return QUrl::fromPercentEncoding(rest.mid(slash + 1).toUtf8());
+}
+
+bool SpotlightDonation::isInDomain(const QString& domainIdentifier, const QString& domain)
+{
+    return domainIdentifier == domain
+           || domainIdentifier.startsWith(domain + QLatin1Char('.'));
+}
+
+QString SpotlightDonation::contentHash(const DonatedItem& item)
+{
+    QJsonArray attributes;
+    for (const ItemAttribute& attribute : item.attributes) {
+        attributes.append(QJsonArray({attribute.name, attribute.value, attribute.searchable}));
+    }
+    const QJsonObject hashed = {
+        {QStringLiteral("name"), item.name},
+        {QStringLiteral("text"), item.textContent},
+        {QStringLiteral("attributes"), attributes},
+        {QStringLiteral("createdAt"), item.createdAt},
+        {QStringLiteral("modifiedAt"), item.modifiedAt},
+    };
+    return QString::fromLatin1(
+        QCryptographicHash::hash(QJsonDocument(hashed).toJson(QJsonDocument::Compact),
+                                 QCryptographicHash::Sha256)
+            .toHex());
+}
+
+} // namespace bs
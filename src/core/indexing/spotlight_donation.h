@@ -0,0 +1,89 @@
+#pragma once
+
+#include "core/shared/types.h"
+
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One item an app donated, ready for the index writer.
+struct DonatedItem {
+    QString path;        // donated://<bundleIdentifier>/<uniqueIdentifier, percent-encoded>
+    QString parentPath;  // donated://<bundleIdentifier>
+    QString name;        // displayName, else title
+    QString textContent; // chunked into FTS5 like extracted file text
+    std::vector<ItemAttribute> attributes;
+    double createdAt = 0.0;   // epoch seconds
+    double modifiedAt = 0.0;
+    int64_t size = 0;         // UTF-8 bytes of textContent
+    QString contentHash;      // unchanged re-donations are skipped
+};
+
+// What deleteDonatedItems removes from one app's donations. Exactly one of
+// the three is set.
+struct DonationRemoval {
+    QString bundleIdentifier;
+    QStringList uniqueIdentifiers;
+    QStringList domainIdentifiers;  // each also removes its subdomains
+    bool all = false;
+};
+
+// SpotlightDonation — parses the CSSearchableItem-shaped payloads apps send
+// to the indexer's `donateItems`, so content an app already donates to Core
+// Spotlight can be donated here the same way and ranked alongside files:
+//
+//   {"bundleIdentifier": "com.example.notes",
+//    "items": [{"uniqueIdentifier": "42", "domainIdentifier": "notes",
+//               "attributeSet": {"title": "Groceries", "textContent": "...",
+//                                "url": "notes://42"}}]}
+//
+// Donated items live in the items table under synthetic donated:// paths;
+// they have no file, so opening one opens its `url` attribute instead.
+class SpotlightDonation {
+public:
+    static constexpr const char* kScheme = "donated";
+    // Stored attribute names beyond SpotlightMetadata's (title, authors, ...).
+    static constexpr const char* kUrlAttribute = "url";
+    static constexpr const char* kDomainAttribute = "domainIdentifier";
+    static constexpr const char* kDescriptionAttribute = "contentDescription";
+
+    static constexpr int kMaxItemsPerRequest = 1000;
+    static constexpr int kMaxIdentifierChars = 1024;
+    // Longer textContent is truncated, not rejected.
+    static constexpr int kMaxTextChars = 1000000;
+
+    // `donateItems` params. `now` dates items that carry no
+    // contentModificationDate. Nullopt with *error naming the offending
+    // item when anything is invalid; nothing is donated then.
+    static std::optional<std::vector<DonatedItem>> parseItems(const QJsonObject& params,
+                                                              double now, QString* error);
+
+    // `deleteDonatedItems` params.
+    static std::optional<DonationRemoval> parseRemoval(const QJsonObject& params,
+                                                       QString* error);
+
+    static QString bundlePath(const QString& bundleIdentifier);
+    static QString itemPath(const QString& bundleIdentifier, const QString& uniqueIdentifier);
+    static bool isDonatedPath(const QString& path);
+
+    // Bundle identifier of a donated:// path; empty for anything else.
+    static QString bundleIdentifier(const QString& path);
+    // Unique identifier of a donated:// item path, decoded; empty for
+    // anything else.
+    static QString uniqueIdentifier(const QString& path);
+
+    // What unchanged re-donations are recognized by: name, text,
+    // attributes and dates.
+    static QString contentHash(const DonatedItem& item);
+
+    // Core Spotlight domains are hierarchical: "mail.inbox" is inside "mail".
+    static bool isInDomain(const QString& domainIdentifier, const QString& domain);
+};
+
+} // namespace bs
@@ -0,0 +1,339 @@
+#include "core/indexing/ssh_source.h"
+
+#include "core/fs/file_scanner.h"
+#include "core/fs/spotlight_metadata.h"
+
+#include <QDir>
+#include <QElapsedTimer>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonObject>
+#include <QJsonParseError>
+#include <QProcess>
+#include <QRegularExpression>
+#include <QSet>
+#include <QStandardPaths>
+
+#include <algorithm>
+#include <cmath>
+
+namespace bs {
+
+namespace {
+
+// Exit status listCommand() uses for a root it cannot enter.
+constexpr int kMissingRootExitCode = 3;
+
+QString parentOf(const QString& path)
+{
+    const qsizetype slash = path.lastIndexOf(QLatin1Char('/'));
+    return slash > 0 ? path.left(slash) : QStringLiteral("/");
+}
+
+QString sshProgram()
+{
+    const QString found = QStandardPaths::findExecutable(QStringLiteral("ssh"));
+    return found.isEmpty() ? QStringLiteral("/usr/bin/ssh") : found;
+}
+
+} // namespace
+
+std::optional<std::vector<SshRemote>> SshSource::parseRemotes(const QString& json, QString* error)
+{
+    std::vector<SshRemote> remotes;
+    if (json.trimmed().isEmpty()) {
+        return remotes;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("SSH sources must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxRemotes) {
+        *error = QStringLiteral("At most %1 SSH sources").arg(kMaxRemotes);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    // An ssh destination, not an option: ssh would read "-oProxyCommand=..."
+    // as one.
+    static const QRegularExpression validHost(QStringLiteral("^[A-Za-z0-9_.@%:\\[\\]-]+$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        SshRemote remote;
+        remote.name = object.value(QStringLiteral("name")).toString().trimmed();
+        remote.host = object.value(QStringLiteral("host")).toString().trimmed();
+        remote.port = object.value(QStringLiteral("port")).toInt(0);
+        remote.root = object.value(QStringLiteral("root")).toString().trimmed();
+        remote.identityFile = object.value(QStringLiteral("identityFile")).toString().trimmed();
+        remote.maxFileBytes = object.value(QStringLiteral("maxFileBytes"))
+                                  .toInteger(kDefaultMaxFileBytes);
+        const QString label = remote.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : remote.name;
+        if (remote.name.size() > kMaxNameChars || !validName.match(remote.name).hasMatch()) {
+            *error = QStringLiteral("SSH source %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (names.contains(remote.name.toLower())) {
+            *error = QStringLiteral("SSH source %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (remote.host.startsWith(QLatin1Char('-')) || !validHost.match(remote.host).hasMatch()) {
+            *error = QStringLiteral("SSH source %1: '%2' is not a host").arg(label, remote.host);
+            return std::nullopt;
+        }
+        if (remote.port < 0 || remote.port > 65535) {
+            *error = QStringLiteral("SSH source %1: the port must be 1-65535").arg(label);
+            return std::nullopt;
+        }
+        if (!remote.root.startsWith(QLatin1Char('/'))) {
+            *error = QStringLiteral("SSH source %1: the root must be an absolute path").arg(label);
+            return std::nullopt;
+        }
+        remote.root = QDir::cleanPath(remote.root);
+        if (!remote.identityFile.isEmpty()) {
+            remote.identityFile = QDir::cleanPath(
+                remote.identityFile.startsWith(QLatin1String("~/"))
+                    ? QDir::homePath() + remote.identityFile.mid(1)
+                    : remote.identityFile);
+            if (!QFileInfo(remote.identityFile).isFile()) {
+                *error = QStringLiteral("SSH source %1: no identity file at %2")
+                             .arg(label, remote.identityFile);
+                return std::nullopt;
+            }
+        }
+        if (remote.maxFileBytes <= 0) {
+            remote.maxFileBytes = kDefaultMaxFileBytes;
+        }
+        names.insert(remote.name.toLower());
+        remotes.push_back(std::move(remote));
+    }
+    return remotes;
+}
+
+QString SshSource::bundleIdentifier(const QString& remoteName)
+{
+    return QLatin1String(kBundlePrefix) + remoteName.toLower();
+}
+
+bool SshSource::isSshPath(const QString& path)
+{
+    return SpotlightDonation::isDonatedPath(path)
+           && SpotlightDonation::bundleIdentifier(path).startsWith(QLatin1String(kBundlePrefix));
+}
+
+QString SshSource::shellQuote(const QString& value)
+{
+    QString quoted = value;
+    quoted.replace(QLatin1Char('\''), QStringLiteral("'\\''"));
+    return QLatin1Char('\'') + quoted + QLatin1Char('\'');
+}
+
+QString SshSource::listCommand(const QString& root)
+{
+    // find exits 1 when some directory is unreadable; what it could list
+    // still counts.
+    return QStringLiteral(
+               "cd -- %1 || exit %2; "
+               "find . -mindepth 1 \\( -name '.*' -o -name node_modules \\) -prune "
+               "-o -type f -printf '%s %T@ %P\\0' 2>/dev/null; [ $? -le 1 ]")
+        .arg(shellQuote(root))
+        .arg(kMissingRootExitCode);
+}
+
+QString SshSource::fetchCommand(const QString& path)
+{
+    return QStringLiteral("cat -- %1").arg(shellQuote(path));
+}
+
+QStringList SshSource::sshArguments(const SshRemote& remote, const QString& controlPath,
+                                    const QString& command)
+{
+    QStringList arguments = {
+        QStringLiteral("-o"), QStringLiteral("BatchMode=yes"),
+        QStringLiteral("-o"), QStringLiteral("ConnectTimeout=15"),
+        QStringLiteral("-o"), QStringLiteral("ServerAliveInterval=15"),
+    };
+    if (!controlPath.isEmpty()) {
+        arguments << QStringLiteral("-o") << QStringLiteral("ControlMaster=auto")
+                  << QStringLiteral("-o") << QStringLiteral("ControlPath=") + controlPath
+                  << QStringLiteral("-o") << QStringLiteral("ControlPersist=15");
+    }
+    if (remote.port > 0) {
+        arguments << QStringLiteral("-p") << QString::number(remote.port);
+    }
+    if (!remote.identityFile.isEmpty()) {
+        arguments << QStringLiteral("-i") << remote.identityFile
+                  << QStringLiteral("-o") << QStringLiteral("IdentitiesOnly=yes");
+    }
+    arguments << QStringLiteral("--") << remote.host << command;
+    return arguments;
+}
+
+std::vector<RemoteFile> SshSource::parseListing(const QByteArray& output, const QString& root,
+                                                bool* truncated)
+{
+    std::vector<RemoteFile> files;
+    *truncated = false;
+    const QString base = root == QLatin1String("/") ? QString() : root;
+    for (const QByteArray& record : output.split('\0')) {
+        if (record.isEmpty()) {
+            continue;
+        }
+        const qsizetype firstSpace = record.indexOf(' ');
+        const qsizetype secondSpace = firstSpace < 0 ? -1 : record.indexOf(' ', firstSpace + 1);
+        if (secondSpace < 0) {
+            continue;
+        }
+        bool sizeOk = false;
+        bool timeOk = false;
+        RemoteFile file;
+        file.size = record.left(firstSpace).toLongLong(&sizeOk);
+        file.modifiedAt = record.mid(firstSpace + 1, secondSpace - firstSpace - 1).toDouble(&timeOk);
+        const QString relative = QString::fromUtf8(record.mid(secondSpace + 1));
+        // %P never starts with '/' or climbs; anything else is not ours.
+        if (!sizeOk || !timeOk || relative.isEmpty() || relative.startsWith(QLatin1Char('/'))
+            || QDir::cleanPath(relative) != relative || relative.startsWith(QLatin1String("../"))) {
+            continue;
+        }
+        if (static_cast<int>(files.size()) >= kMaxFiles) {
+            *truncated = true;
+            break;
+        }
+        file.path = base + QLatin1Char('/') + relative;
+        files.push_back(std::move(file));
+    }
+    return files;
+}
+
+ItemKind SshSource::kindOf(const QString& path)
+{
+    return FileScanner::classifyItemKind(QFileInfo(path).suffix().toStdString());
+}
+
+bool SshSource::isFetchable(const SshRemote& remote, const RemoteFile& file)
+{
+    if (file.size <= 0 || file.size > remote.maxFileBytes) {
+        return false;
+    }
+    switch (kindOf(file.path)) {
+    case ItemKind::Text:
+    case ItemKind::Code:
+    case ItemKind::Markdown:
+    case ItemKind::Pdf:
+        return true;
+    default:
+        return false;
+    }
+}
+
+QString SshSource::uniqueIdentifier(const SshRemote& remote, const RemoteFile& file)
+{
+    const QString base = remote.root == QLatin1String("/") ? QString() : remote.root;
+    return file.path.mid(base.size() + 1);
+}
+
+bool SshSource::isUnchanged(const RemoteFile& file, int64_t indexedSize, double indexedModifiedAt)
+{
+    // find prints fractional seconds; the index keeps what it was given.
+    return indexedSize == file.size && std::abs(indexedModifiedAt - file.modifiedAt) < 0.001;
+}
+
+DonatedItem SshSource::toDonatedItem(const SshRemote& remote, const RemoteFile& file,
+                                     const QString& text)
+{
+    const QString bundle = bundleIdentifier(remote.name);
+    const QString remotePath = remote.host + QLatin1Char(':') + file.path;
+    DonatedItem donated;
+    donated.path = SpotlightDonation::itemPath(bundle, uniqueIdentifier(remote, file));
+    donated.parentPath = SpotlightDonation::bundlePath(bundle);
+    donated.name = QFileInfo(file.path).fileName();
+    // The path is searchable too: "deploy devbox services".
+    QStringList lines = {text.left(kMaxTextChars), file.path};
+    lines.removeAll(QString());
+    donated.textContent = lines.join(QLatin1Char('\n'));
+    donated.size = file.size;
+    donated.createdAt = file.modifiedAt;
+    donated.modifiedAt = file.modifiedAt;
+
+    std::vector<ItemAttribute> raw;
+    raw.push_back({QStringLiteral("contentType"), QString::fromLatin1(kContentType), false});
+    raw.push_back({QString::fromLatin1(kRemoteAttribute), remote.name, false});
+    raw.push_back({QString::fromLatin1(kRemotePathAttribute), remotePath, false});
+    raw.push_back({QString::fromLatin1(kRemoteKindAttribute),
+                   itemKindToString(kindOf(file.path)), false});
+    raw.push_back({QString::fromLatin1(SpotlightDonation::kDescriptionAttribute),
+                   QStringLiteral("%1 · %2").arg(remote.name, parentOf(file.path)), false});
+    donated.attributes = SpotlightMetadata::normalize(raw);
+    donated.contentHash = SpotlightDonation::contentHash(donated);
+    return donated;
+}
+
+std::optional<QByteArray> SshSource::run(const SshRemote& remote, const QString& controlPath,
+                                         const QString& command, int timeoutMs, int64_t maxBytes,
+                                         QString* error, const std::atomic<bool>* cancel)
+{
+    QProcess process;
+    process.setProgram(sshProgram());
+    process.setArguments(sshArguments(remote, controlPath, command));
+    process.setStandardInputFile(QProcess::nullDevice());
+    process.start();
+    if (!process.waitForStarted(5000)) {
+        *error = QStringLiteral("Could not run ssh: %1").arg(process.errorString());
+        return std::nullopt;
+    }
+
+    QElapsedTimer timer;
+    timer.start();
+    QByteArray output;
+    while (process.state() != QProcess::NotRunning) {
+        if (cancel && cancel->load()) {
+            process.kill();
+            process.waitForFinished(1000);
+            *error = QStringLiteral("Stopped");
+            return std::nullopt;
+        }
+        const qint64 left = timeoutMs - timer.elapsed();
+        if (left <= 0) {
+            process.kill();
+            process.waitForFinished(1000);
+            *error = QStringLiteral("%1 did not finish within %2 s")
+                         .arg(remote.host)
+                         .arg(timeoutMs / 1000);
+            return std::nullopt;
+        }
+        process.waitForFinished(static_cast<int>(std::min<qint64>(left, 250)));
+        output += process.readAllStandardOutput();
+        if (output.size() > maxBytes) {
+            process.kill();
+            process.waitForFinished(1000);
+            *error = QStringLiteral("%1 sent more than %2 bytes").arg(remote.host).arg(maxBytes);
+            return std::nullopt;
+        }
+    }
+    output += process.readAllStandardOutput();
+
+    if (process.exitStatus() != QProcess::NormalExit || process.exitCode() != 0) {
+        // ssh's own failures (255) explain themselves on stderr: "Permission
+        // denied (publickey)", "Could not resolve hostname".
+        const QString detail = QString::fromUtf8(process.readAllStandardError())
+                                   .trimmed().section(QLatin1Char('\n'), -1);
+        if (process.exitCode() == kMissingRootExitCode) {
+            *error = QStringLiteral("%1 has no directory %2").arg(remote.host, remote.root);
+        } else {
+            *error = QStringLiteral("%1: ssh exited with %2%3")
+                         .arg(remote.host)
+                         .arg(process.exitCode())
+                         .arg(detail.isEmpty() ? QString() : QStringLiteral(": ") + detail);
+        }
+        return std::nullopt;
+    }
+    return output;
+}
+
+} // namespace bs
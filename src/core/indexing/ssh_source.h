@@ -0,0 +1,114 @@
+#pragma once
+
+#include "core/indexing/spotlight_donation.h"
+#include "core/shared/types.h"
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <atomic>
+#include <cstdint>
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One directory on another machine, reached with the system ssh client.
+struct SshRemote {
+    QString name;          // label and bundle suffix, letters, digits and '-'
+    QString host;          // ssh destination: "dev", "me@dev.example.com"
+    int port = 0;          // 0 = ssh's own default (or ~/.ssh/config)
+    QString root;          // absolute directory on the remote
+    QString identityFile;  // optional private key; else ssh-agent / config
+    int64_t maxFileBytes = 0;
+};
+
+// One regular file of a remote listing.
+struct RemoteFile {
+    QString path;          // absolute, under the remote's root
+    int64_t size = 0;
+    double modifiedAt = 0.0;  // epoch seconds
+};
+
+// SshSource -- files under a directory on a server, crawled over SSH so
+// they are searchable without mounting it.
+//
+// The `ssh_sources` setting (settings.json `sshSources`) lists the
+// directories. Each sync lists the remote tree with one `find`, then
+// copies only files whose size or modification time changed, and only
+// those an extractor reads (text, code, Markdown, PDF); text is extracted
+// here from a temporary copy that is deleted straight after. Everything
+// else is indexed by name and path alone. Files are donated items under
+// donated://com.betterspotlight.ssh.<name>/<path below the root>.
+//
+// ssh runs with BatchMode, so keys must load without a prompt (an agent,
+// or an unencrypted identity file), and over one shared connection
+// (ControlMaster) per sync. The listing needs GNU find on the remote.
+class SshSource {
+public:
+    static constexpr const char* kSettingKey = "ssh_sources";
+    // The names synced so far, so removed ones can be cleaned up.
+    static constexpr const char* kStateSettingKey = "ssh_state";
+    static constexpr const char* kBundlePrefix = "com.betterspotlight.ssh.";
+    static constexpr const char* kContentType = "com.betterspotlight.remote-file";
+    static constexpr const char* kRemoteAttribute = "remote";
+    static constexpr const char* kRemotePathAttribute = "remotePath";  // host:/path
+    static constexpr const char* kRemoteKindAttribute = "remoteKind";
+
+    static constexpr int kMaxRemotes = 8;
+    static constexpr int kMaxNameChars = 32;
+    // A larger listing is cut off here, and the sync says so.
+    static constexpr int kMaxFiles = 50000;
+    static constexpr int64_t kDefaultMaxFileBytes = 10 * 1024 * 1024;
+    static constexpr int kMaxTextChars = 200000;
+    static constexpr int kListTimeoutMs = 120000;
+    static constexpr int kFetchTimeoutMs = 60000;
+
+    // The setting's JSON array of {name, host, root, port?, identityFile?,
+    // maxFileBytes?}. Empty text is no remotes; nullopt with *error when an
+    // entry is invalid or a name repeats.
+    static std::optional<std::vector<SshRemote>> parseRemotes(const QString& json,
+                                                              QString* error);
+
+    static QString bundleIdentifier(const QString& remoteName);
+    static bool isSshPath(const QString& path);
+
+    // `value` as one word for a POSIX shell: single-quoted.
+    static QString shellQuote(const QString& value);
+    // Regular files under `root`, skipping dot-entries and node_modules,
+    // as "<size> <mtime> <path>" records ending in NUL.
+    static QString listCommand(const QString& root);
+    static QString fetchCommand(const QString& path);
+    // Arguments for the ssh binary to run `command` on the remote,
+    // multiplexed over the master connection at `controlPath`.
+    static QStringList sshArguments(const SshRemote& remote, const QString& controlPath,
+                                    const QString& command);
+
+    // listCommand() output. Records outside `root` or malformed are
+    // skipped; *truncated is set past kMaxFiles.
+    static std::vector<RemoteFile> parseListing(const QByteArray& output, const QString& root,
+                                                bool* truncated);
+
+    static ItemKind kindOf(const QString& path);
+    // Whether a file's content is copied over and extracted, or only its
+    // name and path are indexed.
+    static bool isFetchable(const SshRemote& remote, const RemoteFile& file);
+
+    // The file's donated-item identifier: its path below the root.
+    static QString uniqueIdentifier(const SshRemote& remote, const RemoteFile& file);
+    // Whether the index already holds `file` as listed now.
+    static bool isUnchanged(const RemoteFile& file, int64_t indexedSize, double indexedModifiedAt);
+    static DonatedItem toDonatedItem(const SshRemote& remote, const RemoteFile& file,
+                                     const QString& text);
+
+    // Runs ssh with sshArguments(); its standard output, or nullopt with
+    // *error when it fails, times out, writes more than `maxBytes` or
+    // `cancel` is set while it runs.
+    static std::optional<QByteArray> run(const SshRemote& remote, const QString& controlPath,
+                                         const QString& command, int timeoutMs, int64_t maxBytes,
+                                         QString* error,
+                                         const std::atomic<bool>* cancel = nullptr);
+};
+
+} // namespace bs
@@ -117,6 +117,18 @@ void WorkQueue::shutdown()
     }
 }
 
+std::vector<WorkItem> WorkQueue::takeAll()
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    std::vector<WorkItem> items;
+    items.reserve(m_queue.size());
+    while (!m_queue.empty()) {
+        items.push_back(m_queue.top());
+        m_queue.pop();
+    }
+    return items;
+}
+
 // ── Size / stats ────────────────────────────────────────────
 
 size_t WorkQueue::size() const
@@ -73,6 +73,10 @@ public:
     // After shutdown(), dequeue() always returns nullopt.
     void shutdown();
 
+    // Remove and return every queued item in priority order. Works after
+    // shutdown(); used to checkpoint the frontier on graceful stop.
+    std::vector<WorkItem> takeAll();
+
     // Number of items currently in the queue.
     size_t size() const;
 
@@ -0,0 +1,356 @@
+#include "core/ipc/api_tokens.h"
+#include "core/ipc/ipc_auth.h"
+#include "core/shared/logging.h"
+
+#include <QCryptographicHash>
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QRandomGenerator>
+#include <QSaveFile>
+#include <QStandardPaths>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxNameChars = 64;
+
+const QStringList& contentKeys()
+{
+    static const QStringList kKeys = {
+        QStringLiteral("snippet"),
+        QStringLiteral("highlights"),
+        QStringLiteral("content"),
+        QStringLiteral("contentTruncated"),
+    };
+    return kKeys;
+}
+
+const QStringList& listKeys()
+{
+    static const QStringList kKeys = {
+        QStringLiteral("results"),
+        QStringLiteral("suggestions"),
+        QStringLiteral("candidates"),
+        QStringLiteral("items"),
+        QStringLiteral("applications"),
+    };
+    return kKeys;
+}
+
+QByteArray hashToken(const QByteArray& token)
+{
+    return QCryptographicHash::hash(token, QCryptographicHash::Sha256).toHex();
+}
+
+QJsonObject tokenToJson(const ApiToken& token)
+{
+    QJsonObject json = token.scope.toJson();
+    json[QStringLiteral("id")] = token.id;
+    json[QStringLiteral("name")] = token.name;
+    json[QStringLiteral("createdAt")] = token.createdAt;
+    json[QStringLiteral("sha256")] = QString::fromLatin1(token.secretHash);
+    return json;
+}
+
+std::vector<ApiToken> loadTokens(const QString& filePath)
+{
+    std::vector<ApiToken> tokens;
+    QFile file(filePath);
+    if (!file.open(QIODevice::ReadOnly)) {
+        return tokens;
+    }
+    const QJsonDocument doc = QJsonDocument::fromJson(file.readAll());
+    if (!doc.isObject()) {
+        qCWarning(bsIpc, "Ignoring unreadable API token file: %s", qPrintable(filePath));
+        return tokens;
+    }
+    for (const QJsonValue& value : doc.object().value(QStringLiteral("tokens")).toArray()) {
+        const QJsonObject json = value.toObject();
+        QString error;
+        const auto scope = ApiTokenScope::fromJson(json, &error);
+        const QByteArray hash = json.value(QStringLiteral("sha256")).toString().toLatin1();
+        if (!scope.has_value() || hash.isEmpty()) {
+            continue;
+        }
+        ApiToken token;
+        token.id = json.value(QStringLiteral("id")).toString();
+        token.name = json.value(QStringLiteral("name")).toString();
+        token.scope = scope.value();
+        token.createdAt = json.value(QStringLiteral("createdAt")).toDouble();
+        token.secretHash = hash;
+        tokens.push_back(std::move(token));
+    }
+    return tokens;
+}
+
+} // namespace
+
+// ── ApiTokenScope ───────────────────────────────────────────
+
+bool ApiTokenScope::allows(const QString& capability) const
+{
+    return capabilities.contains(capability);
+}
+
+bool ApiTokenScope::coversPath(const QString& path) const
+{
+    if (roots.isEmpty()) {
+        return true;
+    }
+    const QString cleaned = QDir::cleanPath(path);
+    return std::any_of(roots.cbegin(), roots.cend(), [&](const QString& root) {
+        return cleaned == root || root == QLatin1String("/")
+               || cleaned.startsWith(root + QLatin1Char('/'));
+    });
+}
+
+QJsonArray ApiTokenScope::filterEntries(const QJsonArray& entries) const
+{
+    const bool stripContent = !allows(QStringLiteral("content"));
+    QJsonArray out;
+    for (const QJsonValue& value : entries) {
+        QJsonObject entry = value.toObject();
+        if (!coversPath(entry.value(QStringLiteral("path")).toString())) {
+            continue;
+        }
+        if (stripContent) {
+            for (const QString& key : contentKeys()) {
+                entry.remove(key);
+            }
+        }
+        out.append(entry);
+    }
+    return out;
+}
+
+std::optional<QJsonObject> ApiTokenScope::filterResult(const QJsonObject& result) const
+{
+    const QString path = result.value(QStringLiteral("path")).toString();
+    if (!path.isEmpty() && !coversPath(path)) {
+        return std::nullopt;
+    }
+    QJsonObject out = result;
+    for (const QString& key : listKeys()) {
+        if (out.value(key).isArray()) {
+            out[key] = filterEntries(out.value(key).toArray());
+        }
+    }
+    out.remove(QStringLiteral("debugInfo"));
+    if (!roots.isEmpty()) {
+        // Counted over the whole index, so they reveal what is outside.
+        out.remove(QStringLiteral("facets"));
+        out.remove(QStringLiteral("totalMatches"));
+    }
+    if (!allows(QStringLiteral("content"))) {
+        for (const QString& key : contentKeys()) {
+            out.remove(key);
+        }
+    }
+    return out;
+}
+
+QJsonObject ApiTokenScope::narrowSearch(const QJsonObject& params) const
+{
+    if (roots.isEmpty()) {
+        return params;
+    }
+    QJsonObject filters = params.value(QStringLiteral("filters")).toObject();
+    QJsonArray includePaths;
+    for (const QJsonValue& value : filters.value(QStringLiteral("includePaths")).toArray()) {
+        if (coversPath(value.toString())) {
+            includePaths.append(value);
+        }
+    }
+    if (includePaths.isEmpty()) {
+        includePaths = QJsonArray::fromStringList(roots);
+    }
+    filters[QStringLiteral("includePaths")] = includePaths;
+    QJsonObject narrowed = params;
+    narrowed[QStringLiteral("filters")] = filters;
+    return narrowed;
+}
+
+QJsonObject ApiTokenScope::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("capabilities")] = QJsonArray::fromStringList(capabilities);
+    json[QStringLiteral("roots")] = QJsonArray::fromStringList(roots);
+    return json;
+}
+
+std::optional<ApiTokenScope> ApiTokenScope::fromJson(const QJsonObject& json, QString* error)
+{
+    ApiTokenScope scope;
+    const QStringList known = ApiTokenStore::capabilityNames();
+    for (const QJsonValue& value : json.value(QStringLiteral("capabilities")).toArray()) {
+        const QString capability = value.toString().trimmed().toLower();
+        if (!known.contains(capability)) {
+            *error = QStringLiteral("Unknown capability '%1' (expected %2)")
+                         .arg(value.toString(), known.join(QStringLiteral(", ")));
+            return std::nullopt;
+        }
+        if (!scope.capabilities.contains(capability)) {
+            scope.capabilities.append(capability);
+        }
+    }
+    if (scope.capabilities.isEmpty()) {
+        *error = QStringLiteral("A token needs at least one capability");
+        return std::nullopt;
+    }
+    for (const QJsonValue& value : json.value(QStringLiteral("roots")).toArray()) {
+        const QString root = value.toString().trimmed();
+        if (!QDir::isAbsolutePath(root)) {
+            *error = QStringLiteral("Token roots must be absolute paths: '%1'").arg(root);
+            return std::nullopt;
+        }
+        const QString cleaned = QDir::cleanPath(root);
+        if (!scope.roots.contains(cleaned)) {
+            scope.roots.append(cleaned);
+        }
+    }
+    return scope;
+}
+
+// ── ApiTokenStore ───────────────────────────────────────────
+
+ApiTokenStore::ApiTokenStore(QString filePath)
+    : m_filePath(std::move(filePath))
+{
+}
+
+QString ApiTokenStore::defaultFilePath()
+{
+    QString dataDir = qEnvironmentVariable("BETTERSPOTLIGHT_DATA_DIR").trimmed();
+    if (dataDir.isEmpty()) {
+        dataDir = QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+                  + QStringLiteral("/betterspotlight");
+    }
+    return QDir::cleanPath(dataDir) + QStringLiteral("/api-tokens.json");
+}
+
+QStringList ApiTokenStore::capabilityNames()
+{
+    return {QStringLiteral("search"), QStringLiteral("content"), QStringLiteral("actions"),
+            QStringLiteral("metrics"), QStringLiteral("sync")};
+}
+
+std::optional<QString> ApiTokenStore::create(const QString& name, const ApiTokenScope& scope,
+                                             ApiToken* created, QString* error)
+{
+    const QString trimmedName = name.trimmed();
+    if (trimmedName.isEmpty() || trimmedName.size() > kMaxNameChars) {
+        *error = QStringLiteral("Token name must be 1-%1 characters").arg(kMaxNameChars);
+        return std::nullopt;
+    }
+    // Round-trip so callers cannot store capabilities fromJson would reject.
+    const auto validated = ApiTokenScope::fromJson(scope.toJson(), error);
+    if (!validated.has_value()) {
+        return std::nullopt;
+    }
+
+    std::vector<ApiToken> tokens = loadTokens(m_filePath);
+    if (static_cast<int>(tokens.size()) >= kMaxTokens) {
+        *error = QStringLiteral("At most %1 API tokens can exist; revoke one first")
+                     .arg(kMaxTokens);
+        return std::nullopt;
+    }
+
+    const QString secret = QString::fromLatin1(kTokenPrefix)
+                           + QString::fromLatin1(IpcAuth::generateToken());
+    ApiToken token;
+    token.id = QStringLiteral("%1")
+                   .arg(QRandomGenerator::system()->generate64(), 16, 16, QLatin1Char('0'))
+                   .left(12);
+    token.name = trimmedName;
+    token.scope = validated.value();
+    token.createdAt = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    token.secretHash = hashToken(secret.toLatin1());
+    tokens.push_back(token);
+    if (!save(tokens, error)) {
+        return std::nullopt;
+    }
+    if (created) {
+        *created = token;
+    }
+    return secret;
+}
+
+std::vector<ApiToken> ApiTokenStore::list() const
+{
+    return loadTokens(m_filePath);
+}
+
+std::optional<ApiToken> ApiTokenStore::find(const QString& id) const
+{
+    for (ApiToken& token : loadTokens(m_filePath)) {
+        if (token.id == id) {
+            return std::move(token);
+        }
+    }
+    return std::nullopt;
+}
+
+bool ApiTokenStore::revoke(const QString& id)
+{
+    std::vector<ApiToken> tokens = loadTokens(m_filePath);
+    const auto it = std::find_if(tokens.begin(), tokens.end(),
+                                 [&](const ApiToken& token) { return token.id == id; });
+    if (it == tokens.end()) {
+        return false;
+    }
+    tokens.erase(it);
+    QString error;
+    return save(tokens, &error);
+}
+
+bool ApiTokenStore::isEmpty() const
+{
+    return loadTokens(m_filePath).empty();
+}
+
+std::optional<ApiToken> ApiTokenStore::verify(const QByteArray& token) const
+{
+    if (!token.startsWith(kTokenPrefix)) {
+        return std::nullopt;
+    }
+    const QByteArray hash = hashToken(token);
+    for (const ApiToken& candidate : loadTokens(m_filePath)) {
+        if (IpcAuth::tokensEqual(candidate.secretHash, hash)) {
+            return candidate;
+        }
+    }
+    return std::nullopt;
+}
+
+bool ApiTokenStore::save(const std::vector<ApiToken>& tokens, QString* error) const
+{
+    QDir().mkpath(QFileInfo(m_filePath).absolutePath());
+    QJsonArray array;
+    for (const ApiToken& token : tokens) {
+        array.append(tokenToJson(token));
+    }
+    QJsonObject root;
+    root[QStringLiteral("version")] = 1;
+    root[QStringLiteral("tokens")] = array;
+    const QByteArray bytes = QJsonDocument(root).toJson(QJsonDocument::Indented);
+
+    QSaveFile file(m_filePath);
+    if (!file.open(QIODevice::WriteOnly)) {
+        *error = QStringLiteral("Failed to open %1: %2").arg(m_filePath, file.errorString());
+        return false;
+    }
+    file.setPermissions(QFileDevice::ReadOwner | QFileDevice::WriteOwner);
+    if (file.write(bytes) != bytes.size() || !file.commit()) {
+        *error = QStringLiteral("Failed to write %1: %2").arg(m_filePath, file.errorString());
+        return false;
+    }
+    return true;
+}
+
+} // namespace bs
@@ -0,0 +1,92 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// What a scoped API token may do. Capabilities:
+//   search   run searches and suggestions, read document metadata
+//   content  read extracted text, snippets, answers and thumbnails
+//   actions  open/reveal files and record feedback
+//   metrics  scrape /metrics (index-wide counts; roots do not narrow it)
+//   sync     replicate index entries and their extracted text to another
+//            Mac (PeerSync)
+// Admin methods are never reachable with a scoped token.
+struct ApiTokenScope {
+    QStringList capabilities;
+    QStringList roots;  // cleaned absolute paths; empty = every indexed root
+
+    bool allows(const QString& capability) const;
+    bool coversPath(const QString& path) const;
+
+    // Drops entries whose `path` is outside the roots and, without the
+    // content capability, their snippets and highlights.
+    QJsonArray filterEntries(const QJsonArray& entries) const;
+
+    // Applies filterEntries() to the result lists the query service returns
+    // (results, suggestions, candidates, items) and removes fields that
+    // describe the whole index (facets and totals under root limits, debug
+    // info always). Nullopt when the result is a single document outside
+    // the roots.
+    std::optional<QJsonObject> filterResult(const QJsonObject& result) const;
+
+    // Search params with filters.includePaths limited to the roots, so the
+    // search spends its limit on documents the token can see.
+    QJsonObject narrowSearch(const QJsonObject& params) const;
+
+    QJsonObject toJson() const;
+    static std::optional<ApiTokenScope> fromJson(const QJsonObject& json, QString* error);
+};
+
+struct ApiToken {
+    QString id;       // public handle, used to revoke
+    QString name;     // who it was issued to, e.g. "vscode"
+    ApiTokenScope scope;
+    double createdAt = 0.0;
+    QByteArray secretHash;  // SHA-256 of the token, hex
+};
+
+// ApiTokenStore — scoped tokens for third-party clients (editor plugins,
+// scripts), kept in <dataDir>/api-tokens.json with owner-only permissions.
+//
+// Only a SHA-256 of each token is stored; the token itself is returned once
+// by create(). The file is re-read on every verify(), so a revocation made
+// by any process applies to the next request.
+class ApiTokenStore {
+public:
+    static constexpr const char* kTokenPrefix = "bst_";
+    static constexpr int kMaxTokens = 64;
+
+    explicit ApiTokenStore(QString filePath = defaultFilePath());
+
+    static QString defaultFilePath();
+    static QStringList capabilityNames();
+
+    // Returns the new token text; *created describes it. Nullopt with
+    // *error when the scope is invalid or the file cannot be written.
+    std::optional<QString> create(const QString& name, const ApiTokenScope& scope,
+                                  ApiToken* created, QString* error);
+    std::vector<ApiToken> list() const;
+    std::optional<ApiToken> find(const QString& id) const;
+    bool revoke(const QString& id);
+    bool isEmpty() const;
+
+    // The token matching `token` (constant-time per candidate), if any.
+    std::optional<ApiToken> verify(const QByteArray& token) const;
+
+    const QString& filePath() const { return m_filePath; }
+
+private:
+    bool save(const std::vector<ApiToken>& tokens, QString* error) const;
+
+    QString m_filePath;
+};
+
+} // namespace bs
@@ -0,0 +1,512 @@
+#include "core/ipc/http_server.h"
+#include "core/shared/ipc_messages.h"
+#include "core/shared/logging.h"
+
+#include <QFile>
+#include <QJsonDocument>
+#include <QTcpServer>
+#include <QTcpSocket>
+#include <QUrl>
+
+#if QT_CONFIG(ssl)
+#include <QSslCertificate>
+#include <QSslConfiguration>
+#include <QSslKey>
+#include <QSslServer>
+#endif
+
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+int httpStatusForIpcError(int code)
+{
+    switch (static_cast<IpcErrorCode>(code)) {
+    case IpcErrorCode::InvalidParams:      return 400;
+    case IpcErrorCode::PermissionDenied:   return 403;
+    case IpcErrorCode::NotFound:           return 404;
+    case IpcErrorCode::AlreadyRunning:     return 409;
+    case IpcErrorCode::Unsupported:        return 501;
+    case IpcErrorCode::ServiceUnavailable: return 503;
+    case IpcErrorCode::Timeout:            return 504;
+    case IpcErrorCode::InternalError:
+    case IpcErrorCode::CorruptedIndex:
+        break;
+    }
+    return 500;
+}
+
+} // namespace
+
+QString HttpRequest::header(const QString& name) const
+{
+    return headers.value(name.toLower());
+}
+
+QString HttpRequest::queryValue(const QString& key) const
+{
+    return query.queryItemValue(key, QUrl::FullyDecoded);
+}
+
+bool HttpRequest::isRemote() const
+{
+    if (peerAddress.isNull()) {
+        return false;
+    }
+    // A dual-stack listener sees IPv4 peers as ::ffff:a.b.c.d.
+    bool isIpv4 = false;
+    const quint32 ipv4 = peerAddress.toIPv4Address(&isIpv4);
+    return isIpv4 ? !QHostAddress(ipv4).isLoopback() : !peerAddress.isLoopback();
+}
+
+HttpResponse HttpResponse::json(int status, const QJsonObject& payload)
+{
+    HttpResponse response;
+    response.status = status;
+    response.body = QJsonDocument(payload).toJson(QJsonDocument::Compact);
+    return response;
+}
+
+HttpResponse HttpResponse::error(int status, const QString& message)
+{
+    QJsonObject errorObj;
+    errorObj[QStringLiteral("status")] = status;
+    errorObj[QStringLiteral("message")] = message;
+
+    QJsonObject payload;
+    payload[QStringLiteral("error")] = errorObj;
+    return json(status, payload);
+}
+
+HttpResponse HttpResponse::fromIpc(const QJsonObject& ipcMessage)
+{
+    if (ipcMessage.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        const QJsonObject errorObj = ipcMessage.value(QStringLiteral("error")).toObject();
+        const int status = httpStatusForIpcError(errorObj.value(QStringLiteral("code")).toInt());
+        QJsonObject payload;
+        payload[QStringLiteral("error")] = errorObj;
+        return json(status, payload);
+    }
+    return json(200, ipcMessage.value(QStringLiteral("result")).toObject());
+}
+
+HttpServer::HttpServer(QObject* parent)
+    : QObject(parent)
+    , m_server(std::make_unique<QTcpServer>(this))
+{
+    connect(m_server.get(), &QTcpServer::newConnection,
+            this, &HttpServer::onNewConnection);
+}
+
+HttpServer::~HttpServer()
+{
+    close();
+}
+
+void HttpServer::route(const QString& method, const QString& path, Handler handler)
+{
+    m_routes.push_back({method.toUpper(), path, false, std::move(handler), StreamHandler()});
+}
+
+void HttpServer::routePrefix(const QString& method, const QString& prefix, Handler handler)
+{
+    m_routes.push_back({method.toUpper(), prefix, true, std::move(handler), StreamHandler()});
+}
+
+void HttpServer::routeStream(const QString& method, const QString& path,
+                             StreamHandler handler)
+{
+    Route route{method.toUpper(), path, false, Handler(), std::move(handler)};
+    m_routes.push_back(std::move(route));
+}
+
+bool HttpServer::sendEvent(quint64 streamId, const QString& event, const QJsonObject& data)
+{
+    auto it = m_streams.find(streamId);
+    if (it == m_streams.end()) {
+        return false;
+    }
+    QByteArray frame;
+    if (!event.isEmpty()) {
+        frame += "event: " + event.toUtf8() + '\n';
+    }
+    frame += "data: " + QJsonDocument(data).toJson(QJsonDocument::Compact) + "\n\n";
+    if (!it->headersSent) {
+        it->pending += frame;
+    } else {
+        it->socket->write(frame);
+        it->socket->flush();
+    }
+    return true;
+}
+
+void HttpServer::closeStream(quint64 streamId)
+{
+    const auto it = m_streams.find(streamId);
+    if (it == m_streams.end()) {
+        return;
+    }
+    QTcpSocket* client = it->socket;
+    m_streams.erase(it);
+    client->disconnectFromHost();
+}
+
+bool HttpServer::useTls(const QString& certificatePath, const QString& keyPath,
+                        QString* error)
+{
+#if QT_CONFIG(ssl)
+    if (m_server->isListening()) {
+        *error = QStringLiteral("TLS must be set up before the server listens");
+        return false;
+    }
+    const QList<QSslCertificate> chain = QSslCertificate::fromPath(certificatePath, QSsl::Pem);
+    if (chain.isEmpty()) {
+        *error = QStringLiteral("No PEM certificate in %1").arg(certificatePath);
+        return false;
+    }
+    QFile keyFile(keyPath);
+    if (!keyFile.open(QIODevice::ReadOnly)) {
+        *error = QStringLiteral("%1 could not be read").arg(keyPath);
+        return false;
+    }
+    const QByteArray keyPem = keyFile.readAll();
+    QSslKey key(keyPem, QSsl::Rsa, QSsl::Pem);
+    if (key.isNull()) {
+        key = QSslKey(keyPem, QSsl::Ec, QSsl::Pem);
+    }
+    if (key.isNull()) {
+        *error = QStringLiteral("No unencrypted RSA or EC private key in %1").arg(keyPath);
+        return false;
+    }
+
+    QSslConfiguration configuration = QSslConfiguration::defaultConfiguration();
+    configuration.setLocalCertificateChain(chain);
+    configuration.setPrivateKey(key);
+    configuration.setProtocol(QSsl::TlsV1_2OrLater);
+    // Clients authenticate with bearer tokens, not certificates.
+    configuration.setPeerVerifyMode(QSslSocket::VerifyNone);
+
+    auto server = std::make_unique<QSslServer>(this);
+    server->setSslConfiguration(configuration);
+    server->setHandshakeTimeout(kTlsHandshakeTimeoutMs);
+    // Connections are handed over once the handshake has finished.
+    connect(server.get(), &QSslServer::pendingConnectionAvailable,
+            this, &HttpServer::onNewConnection);
+    connect(server.get(), &QSslServer::errorOccurred, this,
+            [](QSslSocket* socket, QAbstractSocket::SocketError) {
+        qCDebug(bsIpc, "TLS connection from %s failed: %s",
+                qPrintable(socket->peerAddress().toString()), qPrintable(socket->errorString()));
+    });
+    m_server = std::move(server);
+    m_certificateFingerprint =
+        QString::fromLatin1(chain.first().digest(QCryptographicHash::Sha256).toHex(':').toUpper());
+    return true;
+#else
+    Q_UNUSED(certificatePath);
+    Q_UNUSED(keyPath);
+    *error = QStringLiteral("This build has no TLS support");
+    return false;
+#endif
+}
+
+bool HttpServer::isTls() const
+{
+    return !m_certificateFingerprint.isEmpty();
+}
+
+QString HttpServer::certificateFingerprint() const
+{
+    return m_certificateFingerprint;
+}
+
+bool HttpServer::listen(const QHostAddress& address, quint16 port)
+{
+    if (!m_server->listen(address, port)) {
+        const QString err = m_server->errorString();
+        qCCritical(bsIpc, "HTTP server failed to listen on %s:%u: %s",
+                   qPrintable(address.toString()), static_cast<unsigned>(port),
+                   qPrintable(err));
+        emit errorOccurred(err);
+        return false;
+    }
+    qCInfo(bsIpc, "%s server listening on %s:%u", isTls() ? "HTTPS" : "HTTP",
+           qPrintable(address.toString()), static_cast<unsigned>(m_server->serverPort()));
+    return true;
+}
+
+void HttpServer::close()
+{
+    QList<QTcpSocket*> clients = m_readBuffers.keys();
+    for (const Stream& stream : std::as_const(m_streams)) {
+        clients.append(stream.socket);
+    }
+    m_readBuffers.clear();
+    m_streams.clear();
+    for (QTcpSocket* client : clients) {
+        client->disconnect(this);
+        client->abort();
+        client->deleteLater();
+    }
+    if (m_server->isListening()) {
+        m_server->close();
+    }
+}
+
+bool HttpServer::isListening() const
+{
+    return m_server->isListening();
+}
+
+quint16 HttpServer::serverPort() const
+{
+    return m_server->serverPort();
+}
+
+std::optional<HttpRequest> HttpServer::parseRequest(const QByteArray& raw, bool* malformed)
+{
+    if (malformed) {
+        *malformed = false;
+    }
+    const auto fail = [malformed]() -> std::optional<HttpRequest> {
+        if (malformed) {
+            *malformed = true;
+        }
+        return std::nullopt;
+    };
+
+    const int headerEnd = raw.indexOf("\r\n\r\n");
+    if (headerEnd < 0) {
+        if (raw.size() > kMaxRequestBytes) {
+            return fail();
+        }
+        return std::nullopt;
+    }
+
+    const QList<QByteArray> lines = raw.left(headerEnd).split('\n');
+    const QList<QByteArray> requestLine = lines.value(0).trimmed().split(' ');
+    if (requestLine.size() != 3 || !requestLine.at(2).startsWith("HTTP/1.")) {
+        return fail();
+    }
+
+    HttpRequest request;
+    request.method = QString::fromLatin1(requestLine.at(0)).toUpper();
+
+    const QByteArray target = requestLine.at(1);
+    const int queryStart = target.indexOf('?');
+    const QByteArray rawPath = queryStart >= 0 ? target.left(queryStart) : target;
+    if (!rawPath.startsWith('/')) {
+        return fail();
+    }
+    request.path = QUrl::fromPercentEncoding(rawPath);
+    if (queryStart >= 0) {
+        // Form-style encoding uses '+' for spaces; QUrlQuery does not.
+        QByteArray rawQuery = target.mid(queryStart + 1);
+        rawQuery.replace('+', "%20");
+        request.query = QUrlQuery(QString::fromLatin1(rawQuery));
+    }
+
+    for (int i = 1; i < lines.size(); ++i) {
+        const QByteArray line = lines.at(i).trimmed();
+        if (line.isEmpty()) {
+            continue;
+        }
+        const int colon = line.indexOf(':');
+        if (colon <= 0) {
+            return fail();
+        }
+        request.headers.insert(QString::fromLatin1(line.left(colon).trimmed()).toLower(),
+                               QString::fromUtf8(line.mid(colon + 1).trimmed()));
+    }
+
+    qint64 contentLength = 0;
+    const QString lengthHeader = request.header(QStringLiteral("content-length"));
+    if (!lengthHeader.isEmpty()) {
+        bool ok = false;
+        contentLength = lengthHeader.toLongLong(&ok);
+        if (!ok || contentLength < 0 || contentLength > kMaxRequestBytes) {
+            return fail();
+        }
+    }
+
+    const qint64 bodyStart = headerEnd + 4;
+    if (raw.size() < bodyStart + contentLength) {
+        return std::nullopt;
+    }
+    request.body = raw.mid(static_cast<int>(bodyStart), static_cast<int>(contentLength));
+    return request;
+}
+
+QByteArray HttpServer::reasonPhrase(int status)
+{
+    switch (status) {
+    case 200: return QByteArrayLiteral("OK");
+    case 204: return QByteArrayLiteral("No Content");
+    case 400: return QByteArrayLiteral("Bad Request");
+    case 401: return QByteArrayLiteral("Unauthorized");
+    case 403: return QByteArrayLiteral("Forbidden");
+    case 404: return QByteArrayLiteral("Not Found");
+    case 405: return QByteArrayLiteral("Method Not Allowed");
+    case 409: return QByteArrayLiteral("Conflict");
+    case 413: return QByteArrayLiteral("Payload Too Large");
+    case 415: return QByteArrayLiteral("Unsupported Media Type");
+    case 429: return QByteArrayLiteral("Too Many Requests");
+    case 500: return QByteArrayLiteral("Internal Server Error");
+    case 501: return QByteArrayLiteral("Not Implemented");
+    case 503: return QByteArrayLiteral("Service Unavailable");
+    case 504: return QByteArrayLiteral("Gateway Timeout");
+    default:  return QByteArrayLiteral("Unknown");
+    }
+}
+
+QByteArray HttpServer::serializeResponse(const HttpResponse& response)
+{
+    QByteArray out;
+    out.reserve(128 + response.body.size());
+    out += "HTTP/1.1 " + QByteArray::number(response.status) + ' '
+        + reasonPhrase(response.status) + "\r\n";
+    out += "Content-Type: " + response.contentType + "\r\n";
+    out += "Content-Length: " + QByteArray::number(response.body.size()) + "\r\n";
+    out += "Cache-Control: no-store\r\n";
+    out += "Connection: close\r\n\r\n";
+    out += response.body;
+    return out;
+}
+
+void HttpServer::onNewConnection()
+{
+    while (QTcpSocket* client = m_server->nextPendingConnection()) {
+        m_readBuffers.insert(client, QByteArray());
+        connect(client, &QTcpSocket::readyRead, this, [this, client]() {
+            onClientReadyRead(client);
+        });
+        connect(client, &QTcpSocket::disconnected, this, [this, client]() {
+            onClientDisconnected(client);
+        });
+    }
+}
+
+void HttpServer::onClientDisconnected(QTcpSocket* client)
+{
+    m_readBuffers.remove(client);
+    for (auto it = m_streams.begin(); it != m_streams.end(); ++it) {
+        if (it->socket == client) {
+            const quint64 streamId = it.key();
+            m_streams.erase(it);
+            emit streamClosed(streamId);
+            break;
+        }
+    }
+    client->deleteLater();
+}
+
+void HttpServer::onClientReadyRead(QTcpSocket* client)
+{
+    auto it = m_readBuffers.find(client);
+    if (it == m_readBuffers.end()) {
+        return;
+    }
+    it->append(client->readAll());
+
+    if (it->size() > kMaxRequestBytes) {
+        respond(client, HttpResponse::error(413, QStringLiteral("Request too large")));
+        return;
+    }
+
+    bool malformed = false;
+    auto request = parseRequest(*it, &malformed);
+    if (malformed) {
+        respond(client, HttpResponse::error(400, QStringLiteral("Malformed HTTP request")));
+        return;
+    }
+    if (!request.has_value()) {
+        return; // wait for more bytes
+    }
+    request->peerAddress = client->peerAddress();
+
+    qCDebug(bsIpc, "HTTP %s %s", qPrintable(request->method), qPrintable(request->path));
+    HttpResponse failure;
+    const Route* route = matchRoute(request.value(), &failure);
+    if (!route) {
+        respond(client, failure);
+    } else if (route->streamHandler) {
+        acceptStream(client, *route, request.value());
+    } else {
+        respond(client, route->handler(request.value()));
+    }
+}
+
+void HttpServer::respond(QTcpSocket* client, const HttpResponse& response)
+{
+    m_readBuffers.remove(client);
+    client->write(serializeResponse(response));
+    client->disconnectFromHost();
+}
+
+void HttpServer::acceptStream(QTcpSocket* client, const Route& route,
+                              const HttpRequest& request)
+{
+    m_readBuffers.remove(client);
+    const quint64 streamId = m_nextStreamId++;
+    m_streams.insert(streamId, Stream{client, false, QByteArray()});
+
+    if (const auto rejection = route.streamHandler(request, streamId)) {
+        m_streams.remove(streamId);
+        respond(client, rejection.value());
+        return;
+    }
+
+    auto it = m_streams.find(streamId);
+    if (it == m_streams.end()) {
+        return; // handler closed the stream itself
+    }
+    client->write(QByteArrayLiteral("HTTP/1.1 200 OK\r\n"
+                                    "Content-Type: text/event-stream\r\n"
+                                    "Cache-Control: no-store\r\n"
+                                    "Connection: keep-alive\r\n\r\n"));
+    it->headersSent = true;
+    if (!it->pending.isEmpty()) {
+        client->write(it->pending);
+        it->pending.clear();
+    }
+    // Push now rather than on the next event-loop pass: the caller may keep
+    // the loop busy (e.g. ranking the next page) right after accepting.
+    client->flush();
+}
+
+const HttpServer::Route* HttpServer::matchRoute(const HttpRequest& request,
+                                                HttpResponse* failure) const
+{
+    const Route* exact = nullptr;
+    const Route* prefixed = nullptr;
+    bool pathKnown = false;
+
+    for (const Route& candidate : m_routes) {
+        const bool pathMatches = candidate.prefix
+            ? request.path.startsWith(candidate.path)
+            : request.path == candidate.path;
+        if (!pathMatches) {
+            continue;
+        }
+        pathKnown = true;
+        if (candidate.method != request.method) {
+            continue;
+        }
+        if (!candidate.prefix && !exact) {
+            exact = &candidate;
+        } else if (candidate.prefix && !prefixed) {
+            prefixed = &candidate;
+        }
+    }
+
+    if (const Route* chosen = exact ? exact : prefixed) {
+        return chosen;
+    }
+    *failure = pathKnown
+        ? HttpResponse::error(405, QStringLiteral("Method not allowed"))
+        : HttpResponse::error(404, QStringLiteral("No route for %1").arg(request.path));
+    return nullptr;
+}
+
+} // namespace bs
@@ -0,0 +1,142 @@
+#pragma once
+
+#include <QByteArray>
+#include <QHash>
+#include <QHostAddress>
+#include <QJsonObject>
+#include <QObject>
+#include <QString>
+#include <QUrlQuery>
+
+#include <functional>
+#include <memory>
+#include <optional>
+#include <vector>
+
+class QTcpServer;
+class QTcpSocket;
+
+namespace bs {
+
+struct HttpRequest {
+    QString method;                   // "GET", "POST", ...
+    QString path;                     // decoded path without query string
+    QUrlQuery query;
+    QHash<QString, QString> headers;  // keys are lower-cased
+    QByteArray body;
+    QHostAddress peerAddress;         // null for requests parsed outside a connection
+
+    QString header(const QString& name) const;
+    QString queryValue(const QString& key) const;
+    // Whether the request came over the network rather than loopback.
+    bool isRemote() const;
+};
+
+struct HttpResponse {
+    int status = 200;
+    QByteArray contentType = QByteArrayLiteral("application/json");
+    QByteArray body;
+
+    static HttpResponse json(int status, const QJsonObject& payload);
+    static HttpResponse error(int status, const QString& message);
+
+    // Translate an IPC envelope (IpcMessage::makeResponse / makeError) into
+    // an HTTP response: results become 200 bodies, error codes map onto the
+    // closest HTTP status.
+    static HttpResponse fromIpc(const QJsonObject& ipcMessage);
+};
+
+// HttpServer — minimal HTTP/1.1 listener for local tooling (curl, scripts).
+//
+// One request per connection: the server parses the request, dispatches it
+// to the registered route, writes the response and closes. Stream routes
+// instead hold the connection open as a text/event-stream. Handlers run on
+// the owning thread's event loop, the same model as SocketServer.
+//
+// With useTls() it serves HTTPS instead, which is what a listener on
+// anything but loopback needs.
+class HttpServer : public QObject {
+    Q_OBJECT
+public:
+    static constexpr int kMaxRequestBytes = 1024 * 1024; // 1 MB
+    static constexpr int kTlsHandshakeTimeoutMs = 10000;
+
+    explicit HttpServer(QObject* parent = nullptr);
+    ~HttpServer() override;
+
+    using Handler = std::function<HttpResponse(const HttpRequest& request)>;
+
+    // Exact method + path match.
+    void route(const QString& method, const QString& path, Handler handler);
+    // Any path under prefix (e.g. "/v1/documents/"). Exact routes win.
+    void routePrefix(const QString& method, const QString& prefix, Handler handler);
+
+    // Server-sent events. The handler returns nullopt to accept the stream
+    // (the connection stays open for sendEvent) or a response to reject it.
+    // Events sent from inside the handler are flushed after the headers.
+    using StreamHandler = std::function<std::optional<HttpResponse>(
+        const HttpRequest& request, quint64 streamId)>;
+    void routeStream(const QString& method, const QString& path, StreamHandler handler);
+    bool sendEvent(quint64 streamId, const QString& event, const QJsonObject& data);
+    // Close a stream from the server side. Does not emit streamClosed.
+    void closeStream(quint64 streamId);
+
+    // Serve HTTPS with the PEM certificate chain and RSA or EC private key
+    // at these paths. Call before listen(). False with *error when they
+    // cannot be read or this Qt build has no TLS backend.
+    bool useTls(const QString& certificatePath, const QString& keyPath, QString* error);
+    bool isTls() const;
+    // SHA-256 of the leaf certificate as colon-separated hex, for clients
+    // to pin; empty without TLS.
+    QString certificateFingerprint() const;
+
+    bool listen(const QHostAddress& address, quint16 port);
+    void close();
+    bool isListening() const;
+    quint16 serverPort() const;
+
+    // Parse a raw request buffer. Returns nullopt while the request is
+    // incomplete; sets *malformed when the buffer can never parse.
+    static std::optional<HttpRequest> parseRequest(const QByteArray& raw,
+                                                   bool* malformed = nullptr);
+    static QByteArray serializeResponse(const HttpResponse& response);
+    static QByteArray reasonPhrase(int status);
+
+signals:
+    void errorOccurred(const QString& error);
+    // The client went away; the stream id is no longer valid.
+    void streamClosed(quint64 streamId);
+
+private slots:
+    void onNewConnection();
+
+private:
+    struct Route {
+        QString method;
+        QString path;
+        bool prefix = false;
+        Handler handler;
+        StreamHandler streamHandler;
+    };
+
+    struct Stream {
+        QTcpSocket* socket = nullptr;
+        bool headersSent = false;
+        QByteArray pending;
+    };
+
+    void onClientReadyRead(QTcpSocket* client);
+    void onClientDisconnected(QTcpSocket* client);
+    void respond(QTcpSocket* client, const HttpResponse& response);
+    void acceptStream(QTcpSocket* client, const Route& route, const HttpRequest& request);
+    const Route* matchRoute(const HttpRequest& request, HttpResponse* failure) const;
+
+    std::unique_ptr<QTcpServer> m_server;
+    QString m_certificateFingerprint;
+    std::vector<Route> m_routes;
+    QHash<QTcpSocket*, QByteArray> m_readBuffers;
+    QHash<quint64, Stream> m_streams;
+    quint64 m_nextStreamId = 1;
+};
+
+} // namespace bs
@@ -0,0 +1,129 @@
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/service_base.h"
+#include "core/shared/logging.h"
+
+#include <QDir>
+#include <QFile>
+#include <QRandomGenerator>
+#include <QSaveFile>
+
+#include <sys/socket.h>
+#include <sys/types.h>
+#include <sys/un.h>
+#include <unistd.h>
+
+#include <array>
+
+namespace bs {
+
+QByteArray IpcAuth::generateToken()
+{
+    std::array<quint32, 8> words{};
+    QRandomGenerator::system()->fillRange(words.data(), words.size());
+    return QByteArray(reinterpret_cast<const char*>(words.data()),
+                      static_cast<qsizetype>(words.size() * sizeof(quint32)))
+        .toHex();
+}
+
+QByteArray IpcAuth::environmentToken()
+{
+    return qgetenv(kTokenEnvVar).trimmed();
+}
+
+QString IpcAuth::tokenFilePath()
+{
+    return QDir::cleanPath(ServiceBase::runtimeDirectory() + QStringLiteral("/admin.token"));
+}
+
+bool IpcAuth::writeTokenFile(const QByteArray& token)
+{
+    const QString path = tokenFilePath();
+    QSaveFile file(path);
+    if (!file.open(QIODevice::WriteOnly)) {
+        qCWarning(bsIpc, "Failed to open admin token file %s: %s",
+                  qPrintable(path), qPrintable(file.errorString()));
+        return false;
+    }
+    // Restrict before any bytes land on disk; QSaveFile renames over the target.
+    file.setPermissions(QFileDevice::ReadOwner | QFileDevice::WriteOwner);
+    if (file.write(token) != token.size() || !file.commit()) {
+        qCWarning(bsIpc, "Failed to write admin token file %s: %s",
+                  qPrintable(path), qPrintable(file.errorString()));
+        return false;
+    }
+    return true;
+}
+
+QByteArray IpcAuth::readTokenFile()
+{
+    QFile file(tokenFilePath());
+    if (!file.open(QIODevice::ReadOnly)) {
+        return {};
+    }
+    return file.readAll().trimmed();
+}
+
+void IpcAuth::removeTokenFile()
+{
+    QFile::remove(tokenFilePath());
+}
+
+bool IpcAuth::tokensEqual(const QByteArray& expected, const QByteArray& provided)
+{
+    if (expected.isEmpty() || provided.isEmpty()) {
+        return false;
+    }
+    // Length is not secret (fixed-size hex); only the contents are.
+    if (expected.size() != provided.size()) {
+        return false;
+    }
+    unsigned char diff = 0;
+    for (qsizetype i = 0; i < expected.size(); ++i) {
+        diff |= static_cast<unsigned char>(expected.at(i) ^ provided.at(i));
+    }
+    return diff == 0;
+}
+
+std::optional<PeerCredentials> IpcAuth::peerCredentials(qintptr socketDescriptor)
+{
+    if (socketDescriptor < 0) {
+        return std::nullopt;
+    }
+    const int fd = static_cast<int>(socketDescriptor);
+    PeerCredentials creds;
+
+#if defined(__APPLE__)
+    uid_t uid = 0;
+    gid_t gid = 0;
+    if (::getpeereid(fd, &uid, &gid) != 0) {
+        return std::nullopt;
+    }
+    creds.uid = static_cast<qint64>(uid);
+#if defined(LOCAL_PEERPID)
+    pid_t pid = 0;
+    socklen_t pidLen = sizeof(pid);
+    if (::getsockopt(fd, SOL_LOCAL, LOCAL_PEERPID, &pid, &pidLen) == 0) {
+        creds.pid = static_cast<qint64>(pid);
+    }
+#endif
+#elif defined(SO_PEERCRED)
+    struct ucred cred {};
+    socklen_t credLen = sizeof(cred);
+    if (::getsockopt(fd, SOL_SOCKET, SO_PEERCRED, &cred, &credLen) != 0) {
+        return std::nullopt;
+    }
+    creds.uid = static_cast<qint64>(cred.uid);
+    creds.pid = static_cast<qint64>(cred.pid);
+#else
+    return std::nullopt;
+#endif
+
+    return creds;
+}
+
+qint64 IpcAuth::currentUid()
+{
+    return static_cast<qint64>(::geteuid());
+}
+
+} // namespace bs
@@ -0,0 +1,46 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// Peer identity of a connected Unix-domain socket, from the kernel.
+struct PeerCredentials {
+    qint64 uid = -1;
+    qint64 pid = -1;   // -1 when the platform does not report it
+};
+
+// IpcAuth — helpers for authenticating clients of the service sockets.
+//
+// Two layers: the kernel-reported peer uid must match the service's uid
+// (checked on every connection), and admin methods (reindex, rebuild, purge,
+// shutdown) additionally require the per-session admin token the Supervisor
+// generates and hands to its services through the environment.
+class IpcAuth {
+public:
+    static constexpr const char* kTokenEnvVar = "BETTERSPOTLIGHT_ADMIN_TOKEN";
+
+    // 32 random bytes, hex-encoded.
+    static QByteArray generateToken();
+
+    // Token passed down by the Supervisor, or empty when unmanaged.
+    static QByteArray environmentToken();
+
+    // <runtimeDir>/admin.token, readable only by the owning user. Lets
+    // same-user tools such as `bspot` elevate explicitly.
+    static QString tokenFilePath();
+    static bool writeTokenFile(const QByteArray& token);
+    static QByteArray readTokenFile();
+    static void removeTokenFile();
+
+    // Constant-time comparison; false when either side is empty.
+    static bool tokensEqual(const QByteArray& expected, const QByteArray& provided);
+
+    static std::optional<PeerCredentials> peerCredentials(qintptr socketDescriptor);
+    static qint64 currentUid();
+};
+
+} // namespace bs
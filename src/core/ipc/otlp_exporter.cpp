@@ -0,0 +1,131 @@
+#include "core/ipc/otlp_exporter.h"
+
+#include "core/shared/logging.h"
+#include "core/shared/tracing.h"
+
+#include <QDateTime>
+#include <QElapsedTimer>
+#include <QEventLoop>
+#include <QNetworkAccessManager>
+#include <QNetworkReply>
+#include <QNetworkRequest>
+
+#include <algorithm>
+
+namespace bs {
+
+QUrl OtlpExporter::endpointFromEnvironment()
+{
+    if (qEnvironmentVariable("OTEL_SDK_DISABLED").trimmed().compare(
+            QLatin1String("true"), Qt::CaseInsensitive) == 0) {
+        return {};
+    }
+    QString endpoint = qEnvironmentVariable("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT").trimmed();
+    if (endpoint.isEmpty()) {
+        const QString base = qEnvironmentVariable("OTEL_EXPORTER_OTLP_ENDPOINT").trimmed();
+        if (base.isEmpty()) {
+            return {};
+        }
+        endpoint = base;
+        while (endpoint.endsWith(QLatin1Char('/'))) {
+            endpoint.chop(1);
+        }
+        endpoint += QStringLiteral("/v1/traces");
+    }
+    const QUrl url(endpoint);
+    if (!url.isValid()
+        || (url.scheme() != QLatin1String("http") && url.scheme() != QLatin1String("https"))) {
+        qCWarning(bsIpc, "Ignoring OTLP endpoint '%s': only http(s) is supported",
+                  qPrintable(endpoint));
+        return {};
+    }
+    return url;
+}
+
+double OtlpExporter::sampleRatioFromEnvironment()
+{
+    bool ok = false;
+    const double ratio = qEnvironmentVariable("OTEL_TRACES_SAMPLER_ARG").toDouble(&ok);
+    return ok ? std::clamp(ratio, 0.0, 1.0) : 1.0;
+}
+
+OtlpExporter::OtlpExporter(const QUrl& endpoint, QObject* parent)
+    : QObject(parent)
+    , m_endpoint(endpoint)
+    , m_network(new QNetworkAccessManager(this))
+{
+    m_timer.setInterval(kPollIntervalMs);
+    connect(&m_timer, &QTimer::timeout, this, &OtlpExporter::poll);
+}
+
+OtlpExporter::~OtlpExporter() = default;
+
+void OtlpExporter::start()
+{
+    m_lastExportMs = QDateTime::currentMSecsSinceEpoch();
+    m_timer.start();
+}
+
+void OtlpExporter::poll()
+{
+    const qint64 now = QDateTime::currentMSecsSinceEpoch();
+    if (now - m_lastExportMs < kFlushIntervalMs
+        && Tracer::instance().bufferedCount() < kMaxBatchSpans) {
+        return;
+    }
+    m_lastExportMs = now;
+    while (Tracer::instance().bufferedCount() > 0) {
+        exportBatch();
+    }
+}
+
+void OtlpExporter::exportBatch()
+{
+    const std::vector<FinishedSpan> spans = Tracer::instance().takeFinished(kMaxBatchSpans);
+    if (spans.empty()) {
+        return;
+    }
+
+    QNetworkRequest request(m_endpoint);
+    request.setHeader(QNetworkRequest::ContentTypeHeader, QByteArrayLiteral("application/json"));
+    QNetworkReply* reply = m_network->post(
+        request, Tracer::encodeOtlpJson(Tracer::instance().serviceName(), spans));
+    ++m_inFlight;
+    const size_t batchSize = spans.size();
+    connect(reply, &QNetworkReply::finished, this, [this, reply, batchSize]() {
+        --m_inFlight;
+        if (reply->error() != QNetworkReply::NoError) {
+            if (!m_warnedUnreachable) {
+                m_warnedUnreachable = true;
+                qCWarning(bsIpc, "OTLP export to %s failed (%s); dropping %d span(s)",
+                          qPrintable(m_endpoint.toString()), qPrintable(reply->errorString()),
+                          static_cast<int>(batchSize));
+            }
+        } else if (m_warnedUnreachable) {
+            m_warnedUnreachable = false;
+            qCInfo(bsIpc, "OTLP export to %s recovered", qPrintable(m_endpoint.toString()));
+        }
+        reply->deleteLater();
+    });
+}
+
+void OtlpExporter::flush(int timeoutMs)
+{
+    m_timer.stop();
+    while (Tracer::instance().bufferedCount() > 0) {
+        exportBatch();
+    }
+
+    QElapsedTimer elapsed;
+    elapsed.start();
+    QEventLoop loop;
+    while (m_inFlight > 0 && elapsed.elapsed() < timeoutMs) {
+        loop.processEvents(QEventLoop::AllEvents, 50);
+    }
+    if (Tracer::instance().droppedCount() > 0) {
+        qCInfo(bsIpc, "Tracing dropped %llu span(s) while the exporter was behind",
+               static_cast<unsigned long long>(Tracer::instance().droppedCount()));
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,53 @@
+#pragma once
+
+#include <QObject>
+#include <QTimer>
+#include <QUrl>
+
+class QNetworkAccessManager;
+
+namespace bs {
+
+// OtlpExporter -- ships spans from Tracer to an OpenTelemetry collector as
+// OTLP/HTTP JSON (POST to the traces endpoint, usually
+// http://localhost:4318/v1/traces).
+//
+// Runs on the owning thread's event loop: every kFlushIntervalMs, or sooner
+// when kMaxBatchSpans are waiting, it posts a batch and moves on without
+// waiting for the reply. A collector that is down costs one warning, not a
+// queue; Tracer drops what does not fit in its buffer.
+class OtlpExporter : public QObject {
+    Q_OBJECT
+public:
+    static constexpr int kFlushIntervalMs = 2000;
+    static constexpr int kPollIntervalMs = 250;
+    static constexpr size_t kMaxBatchSpans = 512;
+
+    // OTEL_EXPORTER_OTLP_TRACES_ENDPOINT as given, else
+    // OTEL_EXPORTER_OTLP_ENDPOINT + "/v1/traces". Empty (tracing off) when
+    // neither is set, OTEL_SDK_DISABLED is "true", or the URL is not http(s).
+    static QUrl endpointFromEnvironment();
+    // OTEL_TRACES_SAMPLER_ARG, clamped to 0..1; 1 when unset or malformed.
+    static double sampleRatioFromEnvironment();
+
+    explicit OtlpExporter(const QUrl& endpoint, QObject* parent = nullptr);
+    ~OtlpExporter() override;
+
+    void start();
+    // Posts everything buffered and waits up to `timeoutMs` for the replies.
+    // For shutdown, after the producers have stopped.
+    void flush(int timeoutMs = 1000);
+
+private:
+    void poll();
+    void exportBatch();
+
+    QUrl m_endpoint;
+    QTimer m_timer;
+    QNetworkAccessManager* m_network = nullptr;
+    qint64 m_lastExportMs = 0;
+    int m_inFlight = 0;
+    bool m_warnedUnreachable = false;
+};
+
+} // namespace bs
@@ -0,0 +1,257 @@
+#include "core/ipc/peer_client.h"
+
+#include <QElapsedTimer>
+#include <QEventLoop>
+#include <QHostAddress>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QNetworkAccessManager>
+#include <QNetworkReply>
+#include <QNetworkRequest>
+#include <QTimer>
+
+#if QT_CONFIG(ssl)
+#include <QCryptographicHash>
+#include <QSslCertificate>
+#include <QSslConfiguration>
+#include <QSslError>
+#endif
+
+namespace bs {
+
+namespace {
+
+#if QT_CONFIG(ssl)
+// Same form HttpServer::certificateFingerprint() logs.
+QString fingerprintOf(const QSslCertificate& certificate)
+{
+    return QString::fromLatin1(
+        certificate.digest(QCryptographicHash::Sha256).toHex(':').toUpper());
+}
+
+bool matchesPin(const QNetworkReply* reply, const QString& fingerprint)
+{
+    const QSslCertificate certificate = reply->sslConfiguration().peerCertificate();
+    return !certificate.isNull()
+           && fingerprintOf(certificate).compare(fingerprint.trimmed(), Qt::CaseInsensitive) == 0;
+}
+#endif
+
+} // namespace
+
+bool PeerClient::isAllowedUrl(const QUrl& url)
+{
+    if (!url.isValid() || url.host().isEmpty()) {
+        return false;
+    }
+    if (url.scheme() == QLatin1String("https")) {
+        return true;
+    }
+    if (url.scheme() != QLatin1String("http")) {
+        return false;
+    }
+    if (url.host() == QLatin1String("localhost")) {
+        return true;
+    }
+    const QHostAddress address(url.host());
+    return !address.isNull() && address.isLoopback();
+}
+
+namespace {
+
+// One request in flight, with what its signal handlers observed.
+struct InFlight {
+    QUrl url;
+    QNetworkReply* reply = nullptr;
+    QElapsedTimer clock;
+    bool pinMismatch = false;
+    bool tooLarge = false;
+    bool timedOut = false;
+    bool done = false;
+};
+
+void startRequest(QNetworkAccessManager& network, const PeerRequest& request, InFlight& flight,
+                  bool hardDeadline)
+{
+    const QString base = request.peer.baseUrl.toString(QUrl::StripTrailingSlash);
+    flight.url = QUrl(base + (request.path.startsWith(QLatin1Char('/'))
+                                  ? request.path : QLatin1Char('/') + request.path));
+    flight.clock.start();
+
+    QNetworkRequest networkRequest(flight.url);
+    networkRequest.setRawHeader(QByteArrayLiteral("Accept"), QByteArrayLiteral("application/json"));
+    if (!request.peer.token.isEmpty()) {
+        networkRequest.setRawHeader(QByteArrayLiteral("Authorization"),
+                                    QByteArrayLiteral("Bearer ") + request.peer.token);
+    }
+    const int timeoutMs = request.timeoutMs > 0 ? request.timeoutMs : PeerClient::kDefaultTimeoutMs;
+    networkRequest.setTransferTimeout(timeoutMs);
+    if (request.body.isEmpty()) {
+        flight.reply = network.get(networkRequest);
+    } else {
+        networkRequest.setHeader(QNetworkRequest::ContentTypeHeader,
+                                 QStringLiteral("application/json"));
+        flight.reply = network.post(networkRequest, request.body);
+    }
+    QNetworkReply* reply = flight.reply;
+    InFlight* state = &flight;
+
+#if QT_CONFIG(ssl)
+    if (!request.peer.fingerprint.isEmpty()) {
+        // A pinned certificate stands in for a CA: its own errors
+        // (self-signed, wrong host name) are expected and ignored, and a
+        // CA-signed certificate that does not match is still refused.
+        const QString fingerprint = request.peer.fingerprint;
+        QObject::connect(reply, &QNetworkReply::sslErrors, reply,
+                         [reply, fingerprint, state](const QList<QSslError>&) {
+            if (matchesPin(reply, fingerprint)) {
+                reply->ignoreSslErrors();
+            } else {
+                state->pinMismatch = true;
+            }
+        });
+        QObject::connect(reply, &QNetworkReply::encrypted, reply,
+                         [reply, fingerprint, state]() {
+            if (!matchesPin(reply, fingerprint)) {
+                state->pinMismatch = true;
+                reply->abort();
+            }
+        });
+    }
+#endif
+    QObject::connect(reply, &QNetworkReply::downloadProgress, reply,
+                     [reply, state](qint64 received, qint64) {
+        if (received > PeerClient::kMaxReplyBytes) {
+            state->tooLarge = true;
+            reply->abort();
+        }
+    });
+    if (hardDeadline) {
+        QTimer::singleShot(timeoutMs, reply, [reply, state]() {
+            if (!state->done) {
+                state->timedOut = true;
+                reply->abort();
+            }
+        });
+    }
+}
+
+PeerReply finishRequest(InFlight& flight)
+{
+    PeerReply result;
+    result.elapsedMs = flight.clock.elapsed();
+    QNetworkReply* reply = flight.reply;
+    const QString host = flight.url.host();
+    const QByteArray body = reply->readAll();
+    const int status = reply->attribute(QNetworkRequest::HttpStatusCodeAttribute).toInt();
+    const QNetworkReply::NetworkError networkError = reply->error();
+    const QString networkMessage = reply->errorString();
+    reply->deleteLater();
+
+    if (flight.timedOut) {
+        result.timedOut = true;
+        result.error = QStringLiteral("%1 did not answer within %2 ms")
+                           .arg(host)
+                           .arg(result.elapsedMs);
+        return result;
+    }
+    if (flight.pinMismatch) {
+        result.error = QStringLiteral("%1 presented a certificate that does not match its fingerprint")
+                           .arg(host);
+        return result;
+    }
+    if (flight.tooLarge) {
+        result.error = QStringLiteral("%1 sent more than %2 bytes")
+                           .arg(host)
+                           .arg(PeerClient::kMaxReplyBytes);
+        return result;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(body, &parseError);
+    if (status != 200) {
+        // The API's own errors carry a message; prefer it to Qt's.
+        const QString message = document.object()
+                                    .value(QStringLiteral("error")).toObject()
+                                    .value(QStringLiteral("message")).toString();
+        if (status > 0) {
+            result.error = QStringLiteral("%1 answered HTTP %2%3")
+                               .arg(host)
+                               .arg(status)
+                               .arg(message.isEmpty() ? QString() : QStringLiteral(": ") + message);
+        } else {
+            result.timedOut = networkError == QNetworkReply::OperationCanceledError
+                              || networkError == QNetworkReply::TimeoutError;
+            result.error = QStringLiteral("%1: %2").arg(host, networkMessage);
+        }
+        return result;
+    }
+    if (networkError != QNetworkReply::NoError) {
+        result.error = QStringLiteral("%1: %2").arg(host, networkMessage);
+        return result;
+    }
+    if (parseError.error != QJsonParseError::NoError || !document.isObject()) {
+        result.error = QStringLiteral("%1 did not answer with a JSON object").arg(host);
+        return result;
+    }
+    result.json = document.object();
+    return result;
+}
+
+std::vector<PeerReply> run(const std::vector<PeerRequest>& requests, bool hardDeadline)
+{
+    std::vector<PeerReply> replies(requests.size());
+    // Stable addresses: the signal handlers hold pointers into it.
+    std::vector<InFlight> flights(requests.size());
+    QNetworkAccessManager network;
+    QEventLoop loop;
+    int pending = 0;
+    for (size_t i = 0; i < requests.size(); ++i) {
+        if (!PeerClient::isAllowedUrl(requests[i].peer.baseUrl)) {
+            replies[i].error =
+                QStringLiteral("%1 is not an https URL").arg(requests[i].peer.baseUrl.toString());
+            flights[i].done = true;
+            continue;
+        }
+        startRequest(network, requests[i], flights[i], hardDeadline);
+        InFlight* state = &flights[i];
+        ++pending;
+        QObject::connect(state->reply, &QNetworkReply::finished, &loop, [state, &pending, &loop]() {
+            state->done = true;
+            if (--pending == 0) {
+                loop.quit();
+            }
+        });
+    }
+    if (pending > 0) {
+        loop.exec();
+    }
+    for (size_t i = 0; i < requests.size(); ++i) {
+        if (flights[i].reply != nullptr) {
+            replies[i] = finishRequest(flights[i]);
+        }
+    }
+    return replies;
+}
+
+} // namespace
+
+std::optional<QJsonObject> PeerClient::getJson(const PeerEndpoint& peer, const QString& path,
+                                               QString* error, int timeoutMs)
+{
+    PeerRequest request;
+    request.peer = peer;
+    request.path = path;
+    request.timeoutMs = timeoutMs;
+    PeerReply reply = std::move(run({request}, false).front());
+    if (!reply.json.has_value()) {
+        *error = reply.error;
+    }
+    return reply.json;
+}
+
+std::vector<PeerReply> PeerClient::fetchAll(const std::vector<PeerRequest>& requests)
+{
+    return run(requests, true);
+}
+
+} // namespace bs
@@ -0,0 +1,66 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QString>
+#include <QUrl>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One other machine's HTTP API, as a client sees it (server mode on the
+// far side, see query_service_http.cpp).
+struct PeerEndpoint {
+    QUrl baseUrl;          // https://desktop.local:8765
+    QByteArray token;      // scoped API token, sent as a Bearer token
+    QString fingerprint;   // pinned SHA-256 of its certificate, colon-separated hex
+};
+
+// One request in a PeerClient::fetchAll() batch: a POST of `body` when it
+// is set, otherwise a GET.
+struct PeerRequest {
+    PeerEndpoint peer;
+    QString path;          // with its query, under the base URL
+    QByteArray body;       // JSON
+    int timeoutMs = 0;
+};
+
+struct PeerReply {
+    std::optional<QJsonObject> json;
+    QString error;         // set when json is not
+    bool timedOut = false;
+    qint64 elapsedMs = 0;
+};
+
+// PeerClient -- blocking JSON requests to another machine's index.
+//
+// HTTPS only, except to a loopback address. With a pinned fingerprint the
+// peer's certificate must match it, and then a self-signed one is fine;
+// without one the system trust store decides. Runs a local event loop, so
+// call it from a thread that can wait up to `timeoutMs`.
+class PeerClient {
+public:
+    static constexpr int kDefaultTimeoutMs = 30000;
+    // Larger replies are cut off and reported as an error.
+    static constexpr qint64 kMaxReplyBytes = 64 * 1024 * 1024;
+
+    // Whether `url` may carry a token: https, or http to loopback.
+    static bool isAllowedUrl(const QUrl& url);
+
+    // GET `path` (with its query) under the peer's base URL; the reply's
+    // JSON object, or nullopt with *error on a network, TLS, HTTP or JSON
+    // failure.
+    static std::optional<QJsonObject> getJson(const PeerEndpoint& peer, const QString& path,
+                                              QString* error,
+                                              int timeoutMs = kDefaultTimeoutMs);
+
+    // Runs `requests` at the same time and returns their replies in the
+    // same order. Unlike getJson(), whose timeout is for a stalled
+    // transfer, each request's `timeoutMs` bounds the whole request; the
+    // call returns once the slowest one answers or runs out of time.
+    static std::vector<PeerReply> fetchAll(const std::vector<PeerRequest>& requests);
+};
+
+} // namespace bs
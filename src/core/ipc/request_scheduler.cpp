@@ -0,0 +1,100 @@
+#include "core/ipc/request_scheduler.h"
+
+#include <algorithm>
+
+namespace bs {
+
+bool RequestScheduler::enqueue(ClientKey client, const QJsonObject& message, int64_t nowMs)
+{
+    std::deque<Pending>& queue = m_queues[client];
+    if (queue.size() >= kMaxPendingPerClient) {
+        ++m_rejected;
+        return false;
+    }
+    if (queue.empty()) {
+        m_readyClients.push_back(client);
+    }
+
+    Pending pending;
+    pending.client = client;
+    pending.message = message;
+    pending.enqueuedAtMs = nowMs;
+    pending.deadlineAtMs = deadlineOf(message);
+    queue.push_back(std::move(pending));
+    ++m_pending;
+    return true;
+}
+
+std::optional<RequestScheduler::Pending> RequestScheduler::next(int64_t nowMs)
+{
+    while (!m_readyClients.empty()) {
+        const ClientKey client = m_readyClients.front();
+        m_readyClients.pop_front();
+
+        auto it = m_queues.find(client);
+        if (it == m_queues.end() || it->empty()) {
+            m_queues.remove(client);
+            continue;
+        }
+
+        Pending pending = std::move(it->front());
+        it->pop_front();
+        --m_pending;
+        if (it->empty()) {
+            m_queues.erase(it);
+        } else {
+            // Back of the line: one message per client per turn.
+            m_readyClients.push_back(client);
+        }
+
+        m_maxQueueDelayMs = std::max(m_maxQueueDelayMs, nowMs - pending.enqueuedAtMs);
+        if (pending.deadlineAtMs > 0 && nowMs >= pending.deadlineAtMs) {
+            pending.expired = true;
+            ++m_expired;
+        } else {
+            ++m_dispatched;
+        }
+        return pending;
+    }
+    return std::nullopt;
+}
+
+void RequestScheduler::removeClient(ClientKey client)
+{
+    auto it = m_queues.find(client);
+    if (it == m_queues.end()) {
+        return;
+    }
+    m_pending -= it->size();
+    m_queues.erase(it);
+    m_readyClients.erase(std::remove(m_readyClients.begin(), m_readyClients.end(), client),
+                         m_readyClients.end());
+}
+
+void RequestScheduler::clear()
+{
+    m_queues.clear();
+    m_readyClients.clear();
+    m_pending = 0;
+}
+
+RequestSchedulerStats RequestScheduler::stats() const
+{
+    RequestSchedulerStats stats;
+    stats.pending = m_pending;
+    stats.clients = m_readyClients.size();
+    stats.dispatched = m_dispatched;
+    stats.expired = m_expired;
+    stats.rejected = m_rejected;
+    stats.maxQueueDelayMs = m_maxQueueDelayMs;
+    return stats;
+}
+
+int64_t RequestScheduler::deadlineOf(const QJsonObject& message)
+{
+    const qint64 deadline = message.value(QStringLiteral("params")).toObject()
+                                .value(QStringLiteral("deadlineMs")).toInteger(0);
+    return deadline > 0 ? deadline : 0;
+}
+
+} // namespace bs
@@ -0,0 +1,74 @@
+#pragma once
+
+#include <QHash>
+#include <QJsonObject>
+
+#include <cstddef>
+#include <cstdint>
+#include <deque>
+#include <optional>
+
+namespace bs {
+
+struct RequestSchedulerStats {
+    size_t pending = 0;
+    size_t clients = 0;          // clients with at least one queued message
+    uint64_t dispatched = 0;
+    uint64_t expired = 0;        // deadline passed while queued
+    uint64_t rejected = 0;       // per-client queue full
+    int64_t maxQueueDelayMs = 0;
+};
+
+// RequestScheduler — per-client fair ordering for service messages.
+//
+// Messages are queued per client and dispatched round-robin, one at a time,
+// so a client that pipelines many (or slow) requests cannot hold up another
+// client's next keystroke search. Order within a single client is preserved.
+//
+// Requests may carry `params.deadlineMs` (epoch ms, the same convention the
+// query service uses for inference calls). A request whose deadline passes
+// while queued is returned with `expired` set so the server can answer with
+// a Timeout error instead of running it.
+//
+// Not thread-safe; owned and driven by SocketServer on its event loop.
+class RequestScheduler {
+public:
+    static constexpr size_t kMaxPendingPerClient = 64;
+
+    using ClientKey = const void*;
+
+    struct Pending {
+        ClientKey client = nullptr;
+        QJsonObject message;
+        int64_t enqueuedAtMs = 0;
+        int64_t deadlineAtMs = 0;  // 0 = no deadline
+        bool expired = false;
+    };
+
+    // False when the client already has kMaxPendingPerClient queued.
+    bool enqueue(ClientKey client, const QJsonObject& message, int64_t nowMs);
+
+    // Next message in round-robin client order, or nullopt when idle.
+    std::optional<Pending> next(int64_t nowMs);
+
+    // Drop everything queued for a client (e.g. it disconnected).
+    void removeClient(ClientKey client);
+    void clear();
+
+    bool isEmpty() const { return m_readyClients.empty(); }
+    RequestSchedulerStats stats() const;
+
+    // params.deadlineMs of a request, or 0 when absent/invalid.
+    static int64_t deadlineOf(const QJsonObject& message);
+
+private:
+    QHash<ClientKey, std::deque<Pending>> m_queues;
+    std::deque<ClientKey> m_readyClients;  // clients with queued messages, in turn order
+    size_t m_pending = 0;
+    uint64_t m_dispatched = 0;
+    uint64_t m_expired = 0;
+    uint64_t m_rejected = 0;
+    int64_t m_maxQueueDelayMs = 0;
+};
+
+} // namespace bs
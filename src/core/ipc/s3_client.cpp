@@ -0,0 +1,78 @@
+#include "core/ipc/s3_client.h"
+
+#include "core/ipc/peer_client.h"
+
+#include <QEventLoop>
+#include <QNetworkAccessManager>
+#include <QNetworkReply>
+#include <QNetworkRequest>
+#include <QTimer>
+
+namespace bs {
+
+S3Response S3Client::get(const QUrl& url, const QList<QPair<QByteArray, QByteArray>>& headers,
+                         qint64 maxBytes, int timeoutMs, const std::atomic<bool>* cancel)
+{
+    S3Response response;
+    if (!PeerClient::isAllowedUrl(url)) {
+        response.error = QStringLiteral("%1 is not an https URL").arg(url.host());
+        return response;
+    }
+
+    QNetworkAccessManager network;
+    QNetworkRequest request(url);
+    for (const auto& [name, value] : headers) {
+        request.setRawHeader(name, value);
+    }
+    request.setTransferTimeout(timeoutMs);
+    // A redirect would leave the signed host.
+    request.setAttribute(QNetworkRequest::RedirectPolicyAttribute,
+                         QNetworkRequest::ManualRedirectPolicy);
+
+    QNetworkReply* reply = network.get(request);
+    bool tooLarge = false;
+    QObject::connect(reply, &QNetworkReply::downloadProgress, reply,
+                     [reply, maxBytes, &tooLarge](qint64 received, qint64) {
+        if (received > maxBytes) {
+            tooLarge = true;
+            reply->abort();
+        }
+    });
+    QEventLoop loop;
+    QObject::connect(reply, &QNetworkReply::finished, &loop, &QEventLoop::quit);
+    QTimer cancelPoll;
+    bool cancelled = false;
+    if (cancel) {
+        cancelPoll.setInterval(250);
+        QObject::connect(&cancelPoll, &QTimer::timeout, reply, [reply, cancel, &cancelled]() {
+            if (cancel->load()) {
+                cancelled = true;
+                reply->abort();
+            }
+        });
+        cancelPoll.start();
+    }
+    loop.exec();
+
+    response.status = reply->attribute(QNetworkRequest::HttpStatusCodeAttribute).toInt();
+    response.body = reply->readAll();
+    const QString networkMessage = reply->errorString();
+    reply->deleteLater();
+
+    if (cancelled) {
+        response.status = 0;
+        response.body.clear();
+        response.error = QStringLiteral("Stopped");
+    } else if (tooLarge) {
+        response.status = 0;
+        response.body.clear();
+        response.error = QStringLiteral("%1 sent more than %2 bytes").arg(url.host()).arg(maxBytes);
+    } else if (response.status == 0) {
+        response.error = QStringLiteral("%1: %2").arg(url.host(), networkMessage);
+    } else if (response.status < 200 || response.status >= 300) {
+        response.error = QStringLiteral("%1 answered HTTP %2").arg(url.host()).arg(response.status);
+    }
+    return response;
+}
+
+} // namespace bs
@@ -0,0 +1,34 @@
+#pragma once
+
+#include <QByteArray>
+#include <QList>
+#include <QPair>
+#include <QString>
+#include <QUrl>
+
+#include <atomic>
+
+namespace bs {
+
+struct S3Response {
+    int status = 0;        // HTTP status; 0 when there was no answer
+    QByteArray body;       // also an error document's, for its message
+    QString error;         // set unless status is 2xx
+};
+
+// S3Client -- the transport for S3Source: one blocking GET with headers
+// signed by the caller.
+//
+// Same rule as PeerClient for where credentials may go: https, or http to
+// a loopback address. Runs a local event loop, so call it from a thread
+// that can wait up to `timeoutMs`.
+class S3Client {
+public:
+    // The body is cut off past `maxBytes` and the request fails; the
+    // timeout is for a stalled transfer. Setting `cancel` aborts it.
+    static S3Response get(const QUrl& url, const QList<QPair<QByteArray, QByteArray>>& headers,
+                          qint64 maxBytes, int timeoutMs,
+                          const std::atomic<bool>* cancel = nullptr);
+};
+
+} // namespace bs
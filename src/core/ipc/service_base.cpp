@@ -1,12 +1,19 @@
 #include "core/ipc/service_base.h"
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/otlp_exporter.h"
+#include "core/shared/crash_report.h"
 #include "core/shared/logging.h"
+#include "core/shared/tracing.h"
 #include <QCoreApplication>
 #include <QDateTime>
 #include <QDir>
 #include <QFileInfo>
 #include <QJsonObject>
 
+#include <sys/socket.h>
 #include <sys/types.h>
+#include <fcntl.h>
+#include <signal.h>
 #include <unistd.h>
 
 #include <cstdio>
@@ -30,16 +37,36 @@ QString normalizedEnvPath(const char* envName)
     return QDir::cleanPath(value);
 }
 
+int g_terminationPipe[2] = {-1, -1};
+
+void onTerminationSignal(int)
+{
+    // Async-signal-safe; a full pipe just means a quit is already pending.
+    const char byte = 1;
+    (void)::write(g_terminationPipe[1], &byte, 1);
+}
+
 } // namespace
 
 ServiceBase::ServiceBase(const QString& serviceName, QObject* parent)
     : QObject(parent)
     , m_serviceName(serviceName)
     , m_server(std::make_unique<SocketServer>(this))
+    , m_auditLog(AuditLog::defaultDirectory(), serviceName)
 {
     m_server->setRequestHandler([this](const QJsonObject& request) {
-        return handleRequest(request);
+        QJsonObject response = dispatchRequest(request);
+        auditRequest(request, response);
+        return response;
+    });
+    m_server->setAdminMethodPredicate([this](const QString& method) {
+        return isAdminMethod(method);
+    });
+    m_server->setScopedTokenVerifier([this](const QByteArray& token) {
+        return scopedTokenScope(token);
     });
+    // The app, `bspot` and editor integrations share each service socket.
+    m_server->setFairScheduling(true);
 }
 
 ServiceBase::~ServiceBase() = default;
@@ -48,6 +75,17 @@ int ServiceBase::run()
 {
     QString path = socketPath(m_serviceName);
 
+    CrashReport::install(m_serviceName);
+    CrashReport::setContext(QStringLiteral("service"), QJsonObject{
+        {QStringLiteral("name"), m_serviceName},
+        {QStringLiteral("version"), QCoreApplication::applicationVersion()},
+        {QStringLiteral("startedAt"), QDateTime::currentSecsSinceEpoch()},
+        {QStringLiteral("socket"), path},
+    });
+    CrashReport::setContext(QStringLiteral("config"), CrashReport::environmentConfig());
+    // Baseline, so the first getResourceUsage already has a rate.
+    m_resources.usage(QDateTime::currentMSecsSinceEpoch());
+
     // Ensure the socket directory exists
     QDir dir = QFileInfo(path).dir();
     if (!dir.exists()) {
@@ -58,10 +96,29 @@ int ServiceBase::run()
         }
     }
 
+    const QByteArray adminToken = IpcAuth::environmentToken();
+    if (adminToken.isEmpty()) {
+        qCWarning(bsIpc, "Service '%s' has no admin token; admin methods are open to "
+                         "same-user clients", qPrintable(m_serviceName));
+    }
+    m_server->setAdminToken(adminToken);
+
+    const QUrl traceEndpoint = OtlpExporter::endpointFromEnvironment();
+    if (traceEndpoint.isValid()) {
+        const double sampleRatio = OtlpExporter::sampleRatioFromEnvironment();
+        Tracer::instance().configure(QStringLiteral("betterspotlight-") + m_serviceName,
+                                     sampleRatio);
+        m_traceExporter = std::make_unique<OtlpExporter>(traceEndpoint);
+        m_traceExporter->start();
+        qCInfo(bsIpc, "Service '%s' exporting traces to %s (sample ratio %.2f)",
+               qPrintable(m_serviceName), qPrintable(traceEndpoint.toString()), sampleRatio);
+    }
+
     if (!m_server->listen(path)) {
         qCCritical(bsIpc, "Service '%s' failed to start", qPrintable(m_serviceName));
         return 1;
     }
+    installTerminationHandlers();
 
     qCInfo(bsIpc, "Service '%s' started on %s", qPrintable(m_serviceName), qPrintable(path));
 
@@ -69,7 +126,42 @@ int ServiceBase::run()
     fprintf(stdout, "ready\n");
     fflush(stdout);
 
-    return QCoreApplication::exec();
+    const int exitCode = QCoreApplication::exec();
+    qCInfo(bsIpc, "Service '%s' stopping", qPrintable(m_serviceName));
+    m_server->close();
+    prepareForShutdown();
+    if (m_traceExporter) {
+        m_traceExporter->flush();
+    }
+    return exitCode;
+}
+
+void ServiceBase::installTerminationHandlers()
+{
+    if (g_terminationPipe[0] >= 0) {
+        return;
+    }
+    if (::socketpair(AF_UNIX, SOCK_STREAM, 0, g_terminationPipe) != 0) {
+        qCWarning(bsIpc, "Failed to create termination pipe; SIGTERM will not shut down cleanly");
+        return;
+    }
+    ::fcntl(g_terminationPipe[1], F_SETFL, O_NONBLOCK);
+
+    m_signalNotifier = new QSocketNotifier(g_terminationPipe[0], QSocketNotifier::Read, this);
+    connect(m_signalNotifier, &QSocketNotifier::activated, this, [this]() {
+        char byte = 0;
+        (void)::read(g_terminationPipe[0], &byte, 1);
+        qCInfo(bsIpc, "Termination signal received for service '%s'",
+               qPrintable(m_serviceName));
+        QCoreApplication::quit();
+    });
+
+    struct sigaction action {};
+    action.sa_handler = onTerminationSignal;
+    sigemptyset(&action.sa_mask);
+    action.sa_flags = SA_RESTART;
+    ::sigaction(SIGTERM, &action, nullptr);
+    ::sigaction(SIGINT, &action, nullptr);
 }
 
 QString ServiceBase::socketPath(const QString& serviceName)
@@ -111,6 +203,25 @@ QString ServiceBase::pidPath(const QString& serviceName)
                            + serviceName + QStringLiteral(".pid"));
 }
 
+QJsonObject ServiceBase::dispatchRequest(const QJsonObject& request)
+{
+    if (!Tracer::instance().isEnabled()) {
+        return handleRequest(request);
+    }
+    const ScopedTraceContext remoteParent(TraceContext::fromTraceparent(
+        request.value(QStringLiteral("traceparent")).toString().toLatin1()));
+    Span span(m_serviceName + QLatin1Char('.')
+                  + request.value(QStringLiteral("method")).toString(),
+              Span::Kind::Server);
+    QJsonObject response = handleRequest(request);
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        // The code, not the message: messages can name paths.
+        span.setError(response.value(QStringLiteral("error")).toObject()
+                          .value(QStringLiteral("codeString")).toString());
+    }
+    return response;
+}
+
 QJsonObject ServiceBase::handleRequest(const QJsonObject& request)
 {
     QString method = request.value(QStringLiteral("method")).toString();
@@ -122,6 +233,15 @@ QJsonObject ServiceBase::handleRequest(const QJsonObject& request)
     if (method == QLatin1String("shutdown")) {
         return handleShutdown(request);
     }
+    if (method == QLatin1String("getLogLevels")) {
+        return IpcMessage::makeResponse(id, Logging::describe());
+    }
+    if (method == QLatin1String("setLogLevel")) {
+        return handleSetLogLevel(request);
+    }
+    if (method == QLatin1String("getResourceUsage")) {
+        return IpcMessage::makeResponse(id, resourceUsageJson());
+    }
 
     qCWarning(bsIpc, "Unknown method '%s' in service '%s'",
               qPrintable(method), qPrintable(m_serviceName));
@@ -129,6 +249,61 @@ QJsonObject ServiceBase::handleRequest(const QJsonObject& request)
                                   QStringLiteral("Unknown method: %1").arg(method));
 }
 
+QJsonObject ServiceBase::resourceUsageJson()
+{
+    QJsonObject json = m_resources.usage(QDateTime::currentMSecsSinceEpoch()).toJson();
+    json[QStringLiteral("service")] = m_serviceName;
+    json[QStringLiteral("pid")] = static_cast<qint64>(QCoreApplication::applicationPid());
+    return json;
+}
+
+bool ServiceBase::isAdminMethod(const QString& method) const
+{
+    return method == QLatin1String("shutdown") || method == QLatin1String("setLogLevel");
+}
+
+std::optional<QJsonObject> ServiceBase::scopedTokenScope(const QByteArray& token) const
+{
+    Q_UNUSED(token);
+    return std::nullopt;
+}
+
+bool ServiceBase::isAuditedQueryMethod(const QString& method) const
+{
+    Q_UNUSED(method);
+    return false;
+}
+
+QJsonObject ServiceBase::auditQueryDetails(const QJsonObject& params,
+                                           const QJsonObject& response) const
+{
+    Q_UNUSED(response);
+    return AuditLog::summarizeParams(params);
+}
+
+void ServiceBase::auditRequest(const QJsonObject& request, const QJsonObject& response)
+{
+    const QString method = request.value(QStringLiteral("method")).toString();
+    const bool admin = isAdminMethod(method);
+    if ((!admin && !isAuditedQueryMethod(method)) || !m_auditLog.isEnabled()) {
+        return;
+    }
+    const QJsonObject params = request.value(QStringLiteral("params")).toObject();
+    const bool ok = response.value(QStringLiteral("type")).toString() != QLatin1String("error");
+    QJsonObject details = admin ? AuditLog::summarizeParams(params)
+                                : auditQueryDetails(params, response);
+    if (request.value(QStringLiteral("auth")).isObject()) {
+        details[QStringLiteral("apiToken")] = request.value(QStringLiteral("auth")).toObject()
+            .value(QStringLiteral("tokenId")).toString();
+    }
+    if (!ok) {
+        details[QStringLiteral("error")] = response.value(QStringLiteral("error")).toObject()
+            .value(QStringLiteral("message")).toString().left(256);
+    }
+    m_auditLog.append(admin ? QStringLiteral("admin") : QStringLiteral("query"), method,
+                      details, ok);
+}
+
 QJsonObject ServiceBase::handlePing(const QJsonObject& request)
 {
     uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
@@ -142,6 +317,34 @@ QJsonObject ServiceBase::handlePing(const QJsonObject& request)
     return IpcMessage::makeResponse(id, result);
 }
 
+QJsonObject ServiceBase::handleSetLogLevel(const QJsonObject& request)
+{
+    const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+    const QJsonObject params = request.value(QStringLiteral("params")).toObject();
+    const QString module = params.value(QStringLiteral("module")).toString();
+    const QString levelName = params.value(QStringLiteral("level")).toString();
+
+    // "default" drops a module's override; the default level has no default.
+    const bool reset = levelName.compare(QLatin1String("default"), Qt::CaseInsensitive) == 0;
+    const auto level = Logging::parseLevel(levelName);
+    if (!level.has_value() && !(reset && !module.isEmpty())) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'level' must be debug, info, warning or critical%1")
+                .arg(module.isEmpty() ? QString() : QStringLiteral(", or default")));
+    }
+
+    if (module.isEmpty()) {
+        Logging::setDefaultLevel(level.value());
+    } else if (!Logging::setModuleLevel(module, level)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Unknown log module: %1").arg(module));
+    }
+    qCInfo(bsIpc, "Service '%s' log level: %s = %s", qPrintable(m_serviceName),
+           module.isEmpty() ? "default" : qPrintable(module), qPrintable(levelName.toLower()));
+    return IpcMessage::makeResponse(id, Logging::describe());
+}
+
 QJsonObject ServiceBase::handleShutdown(const QJsonObject& request)
 {
     uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
@@ -1,7 +1,11 @@
 #pragma once
 
+#include "core/ipc/otlp_exporter.h"
 #include "core/ipc/socket_server.h"
+#include "core/shared/audit_log.h"
+#include "core/shared/resource_usage.h"
 #include <QCoreApplication>
+#include <QSocketNotifier>
 #include <QString>
 #include <functional>
 
@@ -27,15 +31,62 @@ protected:
     // Override to handle specific methods
     virtual QJsonObject handleRequest(const QJsonObject& request);
 
+    // Methods that require an authenticated admin client (state-changing or
+    // destructive). The base marks only `shutdown`; services extend it.
+    virtual bool isAdminMethod(const QString& method) const;
+
+    // Scope for a scoped API token presented via `authenticate`, or nullopt
+    // when the service does not accept scoped clients (the default). The
+    // object reaches handleRequest() as the request's `auth` field.
+    virtual std::optional<QJsonObject> scopedTokenScope(const QByteArray& token) const;
+
+    // Read-only methods recorded in the audit log as queries when it is on.
+    // Admin methods are always recorded. None by default.
+    virtual bool isAuditedQueryMethod(const QString& method) const;
+
+    // What a query entry records beyond method and outcome.
+    virtual QJsonObject auditQueryDetails(const QJsonObject& params,
+                                          const QJsonObject& response) const;
+
+    // handleRequest() inside a server span named "<service>.<method>" while
+    // tracing is on, continuing the caller's trace when the request carries
+    // a `traceparent`. Requests that arrive other than through the socket
+    // (e.g. HTTP) go through here too.
+    QJsonObject dispatchRequest(const QJsonObject& request);
+
+    // Records a handled request in the audit log when it is on. Requests
+    // that reach handleRequest() other than through the socket (e.g. HTTP)
+    // call this themselves.
+    void auditRequest(const QJsonObject& request, const QJsonObject& response);
+
+    // Called once after the event loop exits (shutdown request, SIGTERM or
+    // SIGINT), with the service still intact. Flush and checkpoint here.
+    virtual void prepareForShutdown() {}
+
     // Built-in handlers
     QJsonObject handlePing(const QJsonObject& request);
     QJsonObject handleShutdown(const QJsonObject& request);
+    // `setLogLevel` (admin): {level, module?}; see Logging.
+    QJsonObject handleSetLogLevel(const QJsonObject& request);
+    // This process's CPU, RSS, descriptors and disk IO (ResourceMonitor),
+    // with `service` and `pid`. Answers `getResourceUsage`; services add it
+    // to their own status methods too.
+    QJsonObject resourceUsageJson();
 
     // Send a notification to connected clients
     void sendNotification(const QString& method, const QJsonObject& params = {});
 
     QString m_serviceName;
     std::unique_ptr<SocketServer> m_server;
+    AuditLog m_auditLog;
+    ResourceMonitor m_resources;
+
+private:
+    // SIGTERM/SIGINT are turned into QCoreApplication::quit() via a self-pipe.
+    void installTerminationHandlers();
+    QSocketNotifier* m_signalNotifier = nullptr;
+    // Set when an OTLP endpoint is configured (see OtlpExporter).
+    std::unique_ptr<OtlpExporter> m_traceExporter;
 };
 
 } // namespace bs
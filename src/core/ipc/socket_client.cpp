@@ -1,5 +1,6 @@
 #include "core/ipc/socket_client.h"
 #include "core/shared/logging.h"
+#include "core/shared/tracing.h"
 #include <QPointer>
 #include <QElapsedTimer>
 #include <QJsonDocument>
@@ -7,6 +8,7 @@
 #include <QTimer>
 
 #include <algorithm>
+#include <mutex>
 
 namespace bs {
 
@@ -24,6 +26,10 @@ bool isTransientConnectError(QLocalSocket::LocalSocketError error)
     }
 }
 
+// Set by the Supervisor's thread, read by clients on any thread.
+std::mutex g_defaultAuthTokenMutex;
+QByteArray g_defaultAuthToken;
+
 } // namespace
 
 SocketClient::SocketClient(QObject* parent)
@@ -70,6 +76,7 @@ bool SocketClient::connectToServer(const QString& socketPath, int timeoutMs)
     m_socket->abort();
     m_readBuffer.clear();
     m_pending.clear();
+    m_authenticated = false;
 
     qCDebug(bsIpc, "Connecting to %s (timeout=%dms)", qPrintable(normalizedSocketPath), timeoutMs);
 
@@ -89,9 +96,39 @@ bool SocketClient::connectToServer(const QString& socketPath, int timeoutMs)
     }
 
     qCInfo(bsIpc, "Connected to %s", qPrintable(normalizedSocketPath));
+    authenticate(timeoutMs);
     return true;
 }
 
+void SocketClient::setDefaultAuthToken(const QByteArray& token)
+{
+    std::lock_guard<std::mutex> lock(g_defaultAuthTokenMutex);
+    g_defaultAuthToken = token;
+}
+
+void SocketClient::authenticate(int timeoutMs)
+{
+    QByteArray token;
+    {
+        std::lock_guard<std::mutex> lock(g_defaultAuthTokenMutex);
+        token = g_defaultAuthToken;
+    }
+    if (token.isEmpty()) {
+        return;
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("token")] = QString::fromLatin1(token);
+    const auto response = sendRequest(QStringLiteral("authenticate"), params, timeoutMs);
+    m_authenticated = response.has_value()
+        && response->value(QStringLiteral("type")).toString() == QLatin1String("response");
+    if (!m_authenticated) {
+        // Stay connected: read-only methods still work, admin ones will be denied.
+        qCWarning(bsIpc, "Admin authentication failed for %s",
+                  qPrintable(m_socket->serverName()));
+    }
+}
+
 void SocketClient::disconnect()
 {
     if (m_socket->state() != QLocalSocket::UnconnectedState) {
@@ -99,6 +136,7 @@ void SocketClient::disconnect()
     }
     m_readBuffer.clear();
     m_pending.clear();
+    m_authenticated = false;
 }
 
 bool SocketClient::isConnected() const
@@ -117,6 +155,11 @@ std::optional<QJsonObject> SocketClient::sendRequest(const QString& method,
 
     uint64_t id = m_nextRequestId++;
     QJsonObject request = IpcMessage::makeRequest(id, method, params);
+    // Calls made while handling a traced request continue its trace.
+    const TraceContext trace = Tracer::current();
+    if (trace.isValid()) {
+        request[QStringLiteral("traceparent")] = QString::fromLatin1(trace.toTraceparent());
+    }
     QByteArray encoded = IpcMessage::encode(request);
 
     if (encoded.isEmpty()) {
@@ -260,6 +303,7 @@ void SocketClient::onReadyRead()
 void SocketClient::onDisconnected()
 {
     qCInfo(bsIpc, "Disconnected from server");
+    m_authenticated = false;
 
     // Mark all pending requests as failed
     for (auto it = m_pending.begin(); it != m_pending.end(); ++it) {
@@ -20,6 +20,12 @@ public:
     void disconnect();
     bool isConnected() const;
 
+    // Process-wide admin token sent as `authenticate` after every (re)connect.
+    // The Supervisor sets it for the app process; clients in the services
+    // leave it unset and stay read-only.
+    static void setDefaultAuthToken(const QByteArray& token);
+    bool isAuthenticated() const { return m_authenticated; }
+
     // Send a request and get the response (blocking with timeout)
     std::optional<QJsonObject> sendRequest(const QString& method,
                                             const QJsonObject& params = {},
@@ -60,6 +66,7 @@ private:
     QByteArray m_readBuffer;
     uint64_t m_nextRequestId = 1;
     NotificationHandler m_notificationHandler;
+    bool m_authenticated = false;
 
     struct PendingRequest {
         QJsonObject response;
@@ -75,6 +82,7 @@ private:
     int m_reconnectAttempt = 0;
 
     void attemptReconnect();
+    void authenticate(int timeoutMs);
 };
 
 } // namespace bs
@@ -1,5 +1,7 @@
 #include "core/ipc/socket_server.h"
+#include "core/ipc/ipc_auth.h"
 #include "core/shared/logging.h"
+#include <QDateTime>
 #include <QFile>
 #include <QJsonDocument>
 #include <QJsonObject>
@@ -87,6 +89,8 @@ void SocketServer::close()
     const QList<QLocalSocket*> clients = m_clients;
     m_clients.clear();
     m_readBuffers.clear();
+    m_clientStates.clear();
+    m_scheduler.clear();
 
     for (QLocalSocket* client : clients) {
         if (!client) {
@@ -118,6 +122,31 @@ void SocketServer::setRequestHandler(RequestHandler handler)
     m_handler = std::move(handler);
 }
 
+void SocketServer::setAdminToken(const QByteArray& token)
+{
+    m_adminToken = token;
+}
+
+void SocketServer::setAdminMethodPredicate(MethodPredicate predicate)
+{
+    m_adminMethodPredicate = std::move(predicate);
+}
+
+void SocketServer::setScopedTokenVerifier(ScopedTokenVerifier verifier)
+{
+    m_scopedTokenVerifier = std::move(verifier);
+}
+
+void SocketServer::setFairScheduling(bool enabled)
+{
+    m_fairScheduling = enabled;
+}
+
+RequestSchedulerStats SocketServer::schedulingStats() const
+{
+    return m_scheduler.stats();
+}
+
 void SocketServer::broadcast(const QJsonObject& notification)
 {
     QByteArray encoded = IpcMessage::encode(notification);
@@ -127,6 +156,9 @@ void SocketServer::broadcast(const QJsonObject& notification)
     }
 
     for (auto* client : m_clients) {
+        if (m_clientStates.value(client).scope.has_value()) {
+            continue;
+        }
         client->write(encoded);
         client->flush();
     }
@@ -137,11 +169,20 @@ void SocketServer::broadcast(const QJsonObject& notification)
 void SocketServer::onNewConnection()
 {
     while (QLocalSocket* client = m_server->nextPendingConnection()) {
-        qCInfo(bsIpc, "Client connected (fd=%lld)",
-               static_cast<long long>(client->socketDescriptor()));
+        ClientState state;
+        if (!acceptPeer(client, state)) {
+            client->disconnectFromServer();
+            client->deleteLater();
+            continue;
+        }
+        qCInfo(bsIpc, "Client connected (fd=%lld uid=%lld pid=%lld)",
+               static_cast<long long>(client->socketDescriptor()),
+               static_cast<long long>(state.peerUid),
+               static_cast<long long>(state.peerPid));
 
         m_clients.append(client);
         m_readBuffers[client] = QByteArray();
+        m_clientStates[client] = state;
 
         connect(client, &QLocalSocket::readyRead,
                 this, &SocketServer::onClientReadyRead);
@@ -196,9 +237,85 @@ bool SocketServer::detachClient(QLocalSocket* client)
 
     const bool removedClient = m_clients.removeOne(client);
     const bool removedBuffer = m_readBuffers.remove(client) > 0;
+    m_clientStates.remove(client);
+    m_scheduler.removeClient(client);
     return removedClient || removedBuffer;
 }
 
+bool SocketServer::acceptPeer(QLocalSocket* client, ClientState& state)
+{
+    // The socket file is already 0700-scoped by UserAccessOption; the kernel
+    // peer uid closes the gap if the runtime directory is ever loosened.
+    const auto creds = IpcAuth::peerCredentials(client->socketDescriptor());
+    if (!creds.has_value()) {
+        qCWarning(bsIpc, "Rejecting client: peer credentials unavailable");
+        return false;
+    }
+    if (creds->uid != IpcAuth::currentUid()) {
+        qCWarning(bsIpc, "Rejecting client from uid %lld (pid %lld): uid mismatch",
+                  static_cast<long long>(creds->uid), static_cast<long long>(creds->pid));
+        return false;
+    }
+    state.peerUid = creds->uid;
+    state.peerPid = creds->pid;
+    state.admin = m_adminToken.isEmpty();
+    return true;
+}
+
+QJsonObject SocketServer::handleAuthenticate(QLocalSocket* client, const QJsonObject& request)
+{
+    const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+    const QByteArray provided = request.value(QStringLiteral("params")).toObject()
+                                    .value(QStringLiteral("token")).toString().toUtf8();
+    ClientState& state = m_clientStates[client];
+
+    if (m_scopedTokenVerifier) {
+        if (auto scope = m_scopedTokenVerifier(provided)) {
+            state.admin = false;
+            state.scope = std::move(scope);
+            QJsonObject result;
+            result[QStringLiteral("authenticated")] = true;
+            result[QStringLiteral("role")] = QStringLiteral("scoped");
+            result[QStringLiteral("scope")] = state.scope.value();
+            return IpcMessage::makeResponse(id, result);
+        }
+    }
+
+    if (!m_adminToken.isEmpty() && !IpcAuth::tokensEqual(m_adminToken, provided)) {
+        qCWarning(bsIpc, "Admin authentication failed for pid %lld",
+                  static_cast<long long>(state.peerPid));
+        return IpcMessage::makeError(id, IpcErrorCode::PermissionDenied,
+                                     QStringLiteral("Invalid admin token"));
+    }
+
+    state.admin = true;
+    state.scope.reset();
+    QJsonObject result;
+    result[QStringLiteral("authenticated")] = true;
+    result[QStringLiteral("role")] = QStringLiteral("admin");
+    return IpcMessage::makeResponse(id, result);
+}
+
+bool SocketServer::isAuthorized(QLocalSocket* client, const QString& method) const
+{
+    if (!m_adminMethodPredicate || !m_adminMethodPredicate(method)) {
+        return true;
+    }
+    const auto it = m_clientStates.constFind(client);
+    return it != m_clientStates.constEnd() && it->admin;
+}
+
+QJsonObject SocketServer::withClientScope(QLocalSocket* client, const QJsonObject& incoming) const
+{
+    QJsonObject message = incoming;
+    message.remove(QStringLiteral("auth"));
+    const auto it = m_clientStates.constFind(client);
+    if (it != m_clientStates.constEnd() && it->scope.has_value()) {
+        message[QStringLiteral("auth")] = it->scope.value();
+    }
+    return message;
+}
+
 void SocketServer::processBuffer(QLocalSocket* client)
 {
     if (!m_readBuffers.contains(client)) {
@@ -214,38 +331,127 @@ void SocketServer::processBuffer(QLocalSocket* client)
         buffer.remove(0, result->bytesConsumed);
 
         const QJsonObject& incoming = result->json;
-        QString type = incoming.value(QStringLiteral("type")).toString();
-
-        if (type == QLatin1String("request")) {
-            qCDebug(bsIpc, "Received request: method=%s id=%lld",
-                    qPrintable(incoming.value(QStringLiteral("method")).toString()),
-                    incoming.value(QStringLiteral("id")).toInteger());
-
-            QJsonObject response;
-            if (m_handler) {
-                response = m_handler(incoming);
-            } else {
-                uint64_t id = static_cast<uint64_t>(incoming.value(QStringLiteral("id")).toInteger());
-                response = IpcMessage::makeError(id, IpcErrorCode::InternalError,
-                                                  QStringLiteral("No request handler registered"));
+        if (!m_fairScheduling) {
+            handleMessage(client, incoming);
+            // The handler may have disconnected this client.
+            if (!m_readBuffers.contains(client)) {
+                return;
             }
+            continue;
+        }
 
-            QByteArray encoded = IpcMessage::encode(response);
-            if (!encoded.isEmpty()) {
-                client->write(encoded);
-                client->flush();
+        if (!m_scheduler.enqueue(client, incoming, QDateTime::currentMSecsSinceEpoch())) {
+            qCWarning(bsIpc, "Client has %d queued requests, rejecting '%s'",
+                      static_cast<int>(RequestScheduler::kMaxPendingPerClient),
+                      qPrintable(incoming.value(QStringLiteral("method")).toString()));
+            if (incoming.value(QStringLiteral("type")).toString() == QLatin1String("request")) {
+                const uint64_t id =
+                    static_cast<uint64_t>(incoming.value(QStringLiteral("id")).toInteger());
+                writeMessage(client, IpcMessage::makeError(
+                    id, IpcErrorCode::ServiceUnavailable,
+                    QStringLiteral("Too many queued requests from this client")));
             }
-        } else if (type == QLatin1String("notification")) {
-            qCDebug(bsIpc, "Received notification: method=%s",
-                    qPrintable(incoming.value(QStringLiteral("method")).toString()));
+            continue;
+        }
+        scheduleDispatch();
+    }
+}
 
-            // Notifications are fire-and-forget; pass to handler but discard result
-            if (m_handler) {
-                m_handler(incoming);
-            }
+void SocketServer::scheduleDispatch()
+{
+    if (m_dispatchScheduled) {
+        return;
+    }
+    m_dispatchScheduled = true;
+    QMetaObject::invokeMethod(this, &SocketServer::dispatchNext, Qt::QueuedConnection);
+}
+
+void SocketServer::dispatchNext()
+{
+    m_dispatchScheduled = false;
+
+    auto pending = m_scheduler.next(QDateTime::currentMSecsSinceEpoch());
+    if (pending.has_value()) {
+        // Keys are only ever live, tracked clients: detachClient() drops a
+        // client's queue before the socket is released.
+        auto* client = static_cast<QLocalSocket*>(const_cast<void*>(pending->client));
+        const QJsonObject& incoming = pending->message;
+        if (!pending->expired) {
+            handleMessage(client, incoming);
+        } else if (incoming.value(QStringLiteral("type")).toString() == QLatin1String("request")) {
+            const QString method = incoming.value(QStringLiteral("method")).toString();
+            const uint64_t id =
+                static_cast<uint64_t>(incoming.value(QStringLiteral("id")).toInteger());
+            qCDebug(bsIpc, "Request '%s' expired after %lld ms in queue",
+                    qPrintable(method),
+                    static_cast<long long>(QDateTime::currentMSecsSinceEpoch()
+                                           - pending->enqueuedAtMs));
+            writeMessage(client, IpcMessage::makeError(
+                id, IpcErrorCode::Timeout,
+                QStringLiteral("Deadline exceeded before '%1' was dispatched").arg(method)));
+        }
+    }
+
+    if (!m_scheduler.isEmpty()) {
+        scheduleDispatch();
+    }
+}
+
+void SocketServer::writeMessage(QLocalSocket* client, const QJsonObject& message)
+{
+    if (!m_clientStates.contains(client)) {
+        return;
+    }
+    QByteArray encoded = IpcMessage::encode(message);
+    if (!encoded.isEmpty()) {
+        client->write(encoded);
+        client->flush();
+    }
+}
+
+void SocketServer::handleMessage(QLocalSocket* client, const QJsonObject& incoming)
+{
+    QString type = incoming.value(QStringLiteral("type")).toString();
+
+    if (type == QLatin1String("request")) {
+        qCDebug(bsIpc, "Received request: method=%s id=%lld",
+                qPrintable(incoming.value(QStringLiteral("method")).toString()),
+                incoming.value(QStringLiteral("id")).toInteger());
+
+        const QString method = incoming.value(QStringLiteral("method")).toString();
+        const uint64_t id =
+            static_cast<uint64_t>(incoming.value(QStringLiteral("id")).toInteger());
+        QJsonObject response;
+        if (method == QLatin1String("authenticate")) {
+            response = handleAuthenticate(client, incoming);
+        } else if (!isAuthorized(client, method)) {
+            qCWarning(bsIpc, "Denied admin method '%s' to unauthenticated client",
+                      qPrintable(method));
+            response = IpcMessage::makeError(
+                id, IpcErrorCode::PermissionDenied,
+                QStringLiteral("Method '%1' requires admin authentication").arg(method));
+        } else if (m_handler) {
+            response = m_handler(withClientScope(client, incoming));
         } else {
-            qCWarning(bsIpc, "Received unknown message type: %s", qPrintable(type));
+            response = IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                              QStringLiteral("No request handler registered"));
+        }
+
+        writeMessage(client, response);
+    } else if (type == QLatin1String("notification")) {
+        qCDebug(bsIpc, "Received notification: method=%s",
+                qPrintable(incoming.value(QStringLiteral("method")).toString()));
+
+        // Notifications are fire-and-forget; pass to handler but discard result
+        const QString method = incoming.value(QStringLiteral("method")).toString();
+        if (!isAuthorized(client, method)) {
+            qCWarning(bsIpc, "Dropped admin notification '%s' from unauthenticated client",
+                      qPrintable(method));
+        } else if (m_handler) {
+            m_handler(withClientScope(client, incoming));
         }
+    } else {
+        qCWarning(bsIpc, "Received unknown message type: %s", qPrintable(type));
     }
 }
 
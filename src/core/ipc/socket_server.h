@@ -1,11 +1,13 @@
 #pragma once
 
 #include "core/ipc/message.h"
+#include "core/ipc/request_scheduler.h"
 #include <QObject>
 #include <QLocalServer>
 #include <QLocalSocket>
 #include <functional>
 #include <memory>
+#include <optional>
 
 namespace bs {
 
@@ -30,6 +32,30 @@ public:
     // Broadcast a notification to all connected clients
     void broadcast(const QJsonObject& notification);
 
+    // Admin permissions. With a token set, clients start read-only and must
+    // send `authenticate {token}` before methods matching the predicate are
+    // dispatched. With no token, every same-user client is treated as admin.
+    using MethodPredicate = std::function<bool(const QString& method)>;
+    void setAdminToken(const QByteArray& token);
+    void setAdminMethodPredicate(MethodPredicate predicate);
+
+    // Scoped tokens. `authenticate {token}` with a token the verifier
+    // accepts makes the client a scoped client: admin methods stay denied,
+    // every request it sends reaches the handler with the returned scope in
+    // a top-level `auth` field, and it receives no broadcasts (those carry
+    // other clients' results). Clients cannot set `auth` themselves.
+    using ScopedTokenVerifier = std::function<std::optional<QJsonObject>(const QByteArray& token)>;
+    void setScopedTokenVerifier(ScopedTokenVerifier verifier);
+
+    // Fair scheduling. When enabled, decoded messages are queued per client
+    // and handled one at a time in round-robin order, returning to the event
+    // loop between messages so other clients' input is read in between.
+    // Requests whose params.deadlineMs passes while queued get a Timeout
+    // error instead of being handled. Off by default: messages are handled
+    // inline as they are decoded.
+    void setFairScheduling(bool enabled);
+    RequestSchedulerStats schedulingStats() const;
+
 signals:
     void clientConnected();
     void clientDisconnected();
@@ -39,16 +65,38 @@ private slots:
     void onNewConnection();
     void onClientReadyRead();
     void onClientDisconnected();
+    void dispatchNext();
 
 private:
+    struct ClientState {
+        qint64 peerUid = -1;
+        qint64 peerPid = -1;
+        bool admin = false;
+        std::optional<QJsonObject> scope;
+    };
+
     std::unique_ptr<QLocalServer> m_server;
     QList<QLocalSocket*> m_clients;
     RequestHandler m_handler;
     QMap<QLocalSocket*, QByteArray> m_readBuffers;
+    QMap<QLocalSocket*, ClientState> m_clientStates;
+    QByteArray m_adminToken;
+    MethodPredicate m_adminMethodPredicate;
+    ScopedTokenVerifier m_scopedTokenVerifier;
+    RequestScheduler m_scheduler;
+    bool m_fairScheduling = false;
+    bool m_dispatchScheduled = false;
     bool m_closing = false;
 
     bool detachClient(QLocalSocket* client);
     void processBuffer(QLocalSocket* client);
+    bool acceptPeer(QLocalSocket* client, ClientState& state);
+    QJsonObject handleAuthenticate(QLocalSocket* client, const QJsonObject& request);
+    bool isAuthorized(QLocalSocket* client, const QString& method) const;
+    QJsonObject withClientScope(QLocalSocket* client, const QJsonObject& incoming) const;
+    void handleMessage(QLocalSocket* client, const QJsonObject& incoming);
+    void writeMessage(QLocalSocket* client, const QJsonObject& message);
+    void scheduleDispatch();
 };
 
 } // namespace bs
@@ -1,4 +1,5 @@
 #include "core/ipc/supervisor.h"
+#include "core/ipc/ipc_auth.h"
 #include "core/ipc/service_base.h"
 #include "core/shared/logging.h"
 #include <QCoreApplication>
@@ -95,6 +96,7 @@ void Supervisor::addService(const QString& name, const QString& execPath)
 bool Supervisor::startAll()
 {
     createRuntimeDirectories();
+    ensureAdminToken();
     m_stopping = false;
 
     bool allStarted = true;
@@ -160,6 +162,7 @@ void Supervisor::stopAll()
         emit serviceStopped(svc->info.name);
     }
 
+    IpcAuth::removeTokenFile();
     m_stopping = false;
 }
 
@@ -377,7 +380,9 @@ void Supervisor::startService(ManagedService& svc)
 
     svc.process = std::make_unique<QProcess>(this);
     svc.process->setProgram(svc.info.executablePath);
-    svc.process->setProcessEnvironment(QProcessEnvironment::systemEnvironment());
+    QProcessEnvironment env = QProcessEnvironment::systemEnvironment();
+    env.insert(QString::fromLatin1(IpcAuth::kTokenEnvVar), QString::fromLatin1(m_adminToken));
+    svc.process->setProcessEnvironment(env);
 
     // Forward service stdout/stderr to the parent process
     svc.process->setProcessChannelMode(QProcess::ForwardedChannels);
@@ -509,6 +514,21 @@ void Supervisor::createRuntimeDirectories()
     }
 }
 
+void Supervisor::ensureAdminToken()
+{
+    if (m_adminToken.isEmpty()) {
+        m_adminToken = IpcAuth::environmentToken();
+    }
+    if (m_adminToken.isEmpty()) {
+        m_adminToken = IpcAuth::generateToken();
+    }
+    // Our own clients (heartbeat, UI controllers) act as admin.
+    SocketClient::setDefaultAuthToken(m_adminToken);
+    if (!IpcAuth::writeTokenFile(m_adminToken)) {
+        qCWarning(bsIpc, "Admin token not persisted; CLI admin commands will be denied");
+    }
+}
+
 Supervisor::ManagedService* Supervisor::findService(const QString& name)
 {
     for (auto& svc : m_services) {
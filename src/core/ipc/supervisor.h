@@ -74,6 +74,7 @@ private:
 
     std::vector<std::unique_ptr<ManagedService>> m_services;
     std::unique_ptr<QTimer> m_heartbeatTimer;
+    QByteArray m_adminToken;
     bool m_stopping = false;
 
     static constexpr int kHeartbeatIntervalMs = 10000;
@@ -85,6 +86,7 @@ private:
     void restartService(ManagedService& svc);
     int restartDelayMs(int crashCount) const;
     void createRuntimeDirectories();
+    void ensureAdminToken();
     ManagedService* findService(const QString& name);
     void transitionState(ManagedService& svc, ServiceLifecycleState nextState);
     static QString stateToString(ServiceLifecycleState state);
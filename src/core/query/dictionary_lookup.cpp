@@ -0,0 +1,115 @@
+#include "core/query/dictionary_lookup.h"
+
+#include <QRegularExpression>
+#include <QSet>
+#include <QUrl>
+
+namespace bs {
+
+namespace {
+
+const QSet<QString>& partsOfSpeech()
+{
+    static const QSet<QString> kParts = {
+        QStringLiteral("noun"),        QStringLiteral("verb"),
+        QStringLiteral("adjective"),   QStringLiteral("adverb"),
+        QStringLiteral("pronoun"),     QStringLiteral("preposition"),
+        QStringLiteral("conjunction"), QStringLiteral("exclamation"),
+        QStringLiteral("determiner"),  QStringLiteral("abbreviation"),
+        QStringLiteral("prefix"),      QStringLiteral("suffix"),
+    };
+    return kParts;
+}
+
+// Where the first sense ends: the next sense, another part of speech
+// ("▶verb"), or the entry's trailing sections.
+int firstSenseEnd(const QString& body)
+{
+    static const QRegularExpression kBoundary(QStringLiteral(
+        "\\s(?:2\\s|ORIGIN\\b|DERIVATIVES\\b|PHRASES\\b|PHRASAL VERBS\\b|USAGE\\b)|▶|:\\s"));
+    const QRegularExpressionMatch match = kBoundary.match(body);
+    return match.hasMatch() ? static_cast<int>(match.capturedStart()) : static_cast<int>(body.size());
+}
+
+QString truncateAtWord(const QString& text, int maxLength)
+{
+    if (text.size() <= maxLength) {
+        return text;
+    }
+    QString cut = text.left(maxLength);
+    const int space = static_cast<int>(cut.lastIndexOf(QLatin1Char(' ')));
+    if (space > maxLength / 2) {
+        cut.truncate(space);
+    }
+    return cut + QChar(0x2026);  // …
+}
+
+} // namespace
+
+QString DictionaryDefinition::url() const
+{
+    return QStringLiteral("dict://") + QString::fromLatin1(QUrl::toPercentEncoding(word));
+}
+
+QJsonObject DictionaryDefinition::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("word")] = word;
+    if (!pronunciation.isEmpty()) {
+        json[QStringLiteral("pronunciation")] = pronunciation;
+    }
+    if (!partOfSpeech.isEmpty()) {
+        json[QStringLiteral("partOfSpeech")] = partOfSpeech;
+    }
+    json[QStringLiteral("summary")] = summary;
+    json[QStringLiteral("url")] = url();
+    return json;
+}
+
+bool DictionaryLookup::isDefinableQuery(const QString& query)
+{
+    static const QRegularExpression kWord(QStringLiteral("^\\p{L}[\\p{L}'\\-]{1,39}$"));
+    return kWord.match(query.trimmed()).hasMatch();
+}
+
+std::optional<DictionaryDefinition> DictionaryLookup::parse(const QString& word,
+                                                            const QString& text)
+{
+    const QString simplified = text.simplified();
+    if (simplified.isEmpty()) {
+        return std::nullopt;
+    }
+
+    DictionaryDefinition definition;
+    definition.word = word;
+    definition.text = simplified;
+
+    QString body = simplified;
+    const QStringList parts = simplified.split(QStringLiteral(" | "));
+    if (parts.size() >= 3) {
+        definition.word = parts.at(0).trimmed();
+        definition.pronunciation = parts.at(1).trimmed();
+        body = parts.mid(2).join(QStringLiteral(" | "));
+    } else if (body.startsWith(word, Qt::CaseInsensitive)) {
+        definition.word = body.left(word.size());
+        body = body.mid(word.size()).trimmed();
+    }
+
+    const int space = static_cast<int>(body.indexOf(QLatin1Char(' ')));
+    const QString firstWord = space > 0 ? body.left(space) : body;
+    if (partsOfSpeech().contains(firstWord)) {
+        definition.partOfSpeech = firstWord;
+        body = space > 0 ? body.mid(space + 1) : QString();
+    }
+    static const QRegularExpression kSenseNumber(QStringLiteral("^1\\s+"));
+    body.remove(kSenseNumber);
+
+    definition.summary = truncateAtWord(body.left(firstSenseEnd(body)).trimmed(),
+                                        kMaxSummaryLength);
+    if (definition.summary.isEmpty()) {
+        return std::nullopt;
+    }
+    return definition;
+}
+
+} // namespace bs
@@ -0,0 +1,41 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// A dictionary entry condensed for a result row.
+struct DictionaryDefinition {
+    QString word;           // headword as the dictionary spells it
+    QString pronunciation;  // "ˌserənˈdipədē"; may be empty
+    QString partOfSpeech;   // "noun"; may be empty
+    QString summary;        // first sense, without examples or etymology
+    QString text;           // the full entry as returned
+
+    // dict://word, which opens the entry in Dictionary.app.
+    QString url() const;
+    QJsonObject toJson() const;
+};
+
+// DictionaryLookup -- definitions from the system dictionary (macOS
+// Dictionary Services, the source of Spotlight's "Define" row), offered when
+// a single-word query finds little on disk.
+class DictionaryLookup {
+public:
+    static constexpr int kMaxSummaryLength = 280;
+
+    // One word of letters (hyphens and apostrophes allowed), 2-40 characters.
+    static bool isDefinableQuery(const QString& query);
+
+    // The system dictionary's entry for `word`. Always nullopt off macOS.
+    static std::optional<DictionaryDefinition> define(const QString& word);
+
+    // Condenses Dictionary Services' plain text ("serendipity | ˌserənˈdipədē |
+    // noun the occurrence ... ORIGIN 1750s ..."). nullopt for empty text.
+    static std::optional<DictionaryDefinition> parse(const QString& word, const QString& text);
+};
+
+} // namespace bs
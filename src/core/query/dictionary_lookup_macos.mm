@@ -0,0 +1,25 @@
+#include "core/query/dictionary_lookup.h"
+
+#include <CoreServices/CoreServices.h>
+
+namespace bs {
+
+std::optional<DictionaryDefinition> DictionaryLookup::define(const QString& word)
+{
+    const QString trimmed = word.trimmed();
+    if (trimmed.isEmpty()) {
+        return std::nullopt;
+    }
+    CFStringRef term = trimmed.toCFString();
+    // Null dictionary: the user's active dictionaries, as Spotlight uses.
+    CFStringRef entry = DCSCopyTextDefinition(nullptr, term, CFRangeMake(0, CFStringGetLength(term)));
+    CFRelease(term);
+    if (!entry) {
+        return std::nullopt;
+    }
+    const QString text = QString::fromCFString(entry);
+    CFRelease(entry);
+    return parse(trimmed, text);
+}
+
+} // namespace bs
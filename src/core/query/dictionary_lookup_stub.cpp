@@ -0,0 +1,11 @@
+#include "core/query/dictionary_lookup.h"
+
+namespace bs {
+
+std::optional<DictionaryDefinition> DictionaryLookup::define(const QString& word)
+{
+    Q_UNUSED(word)
+    return std::nullopt;
+}
+
+} // namespace bs
@@ -0,0 +1,229 @@
+#include "core/query/federated_search.h"
+
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QRegularExpression>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+QString parentOf(const QString& path)
+{
+    const qsizetype slash = path.lastIndexOf(QLatin1Char('/'));
+    return slash <= 0 ? QStringLiteral("/") : path.left(slash);
+}
+
+// Same identity a PeerSync replica carries, so the two can be matched.
+QString peerKey(const QString& peer, const QString& path)
+{
+    return peer.toLower() + QLatin1Char('\n') + path;
+}
+
+// A row from another index, as a peer row of this one.
+QJsonObject remoteRow(QJsonObject row, const QString& source)
+{
+    const QString path = row.value(QStringLiteral("path")).toString();
+    row[QStringLiteral("sourceItemId")] = row.value(QStringLiteral("itemId"));
+    row[QStringLiteral("itemId")] = 0;
+    row[QStringLiteral("peer")] = source;
+    row[QStringLiteral("peerPath")] = path;
+    row[QStringLiteral("contentDescription")] =
+        QStringLiteral("%1 · %2").arg(source, parentOf(path));
+    row[QStringLiteral("source")] = source;
+    return row;
+}
+
+// A row from a team index: the file is (or may be) reachable here under
+// its path, but is not in this index.
+QJsonObject teamRow(QJsonObject row, const QString& source)
+{
+    const QString path = row.value(QStringLiteral("path")).toString();
+    row[QStringLiteral("sourceItemId")] = row.value(QStringLiteral("itemId"));
+    row[QStringLiteral("itemId")] = 0;
+    row[QStringLiteral("team")] = source;
+    row[QStringLiteral("contentDescription")] =
+        QStringLiteral("%1 · %2").arg(source, parentOf(path));
+    row[QStringLiteral("source")] = source;
+    return row;
+}
+
+struct Ranked {
+    QJsonObject row;
+    double score = 0.0;
+    int order = 0;  // ties keep source order, then rank
+};
+
+QJsonObject sourceJson(const QString& name, const QString& status, int results,
+                       qint64 elapsedMs, const QString& error)
+{
+    QJsonObject source;
+    source[QStringLiteral("name")] = name;
+    source[QStringLiteral("status")] = status;
+    source[QStringLiteral("results")] = results;
+    source[QStringLiteral("elapsedMs")] = elapsedMs;
+    if (!error.isEmpty()) {
+        source[QStringLiteral("error")] = error;
+    }
+    return source;
+}
+
+} // namespace
+
+std::optional<std::vector<FederatedIndex>> FederatedSearch::parseIndexes(const QString& json,
+                                                                         QString* error)
+{
+    std::vector<FederatedIndex> indexes;
+    if (json.trimmed().isEmpty()) {
+        return indexes;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("Federated indexes must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxIndexes) {
+        *error = QStringLiteral("At most %1 federated indexes").arg(kMaxIndexes);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        FederatedIndex index;
+        index.name = object.value(QStringLiteral("name")).toString().trimmed();
+        index.endpoint.baseUrl = QUrl(object.value(QStringLiteral("url")).toString().trimmed());
+        index.endpoint.token = object.value(QStringLiteral("token")).toString().trimmed().toUtf8();
+        index.endpoint.fingerprint = object.value(QStringLiteral("fingerprint")).toString().trimmed();
+        index.timeoutMs = std::clamp(object.value(QStringLiteral("timeoutMs")).toInt(kDefaultTimeoutMs),
+                                     kMinTimeoutMs, kMaxTimeoutMs);
+        const QString label = index.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : index.name;
+        if (index.name.size() > kMaxNameChars || !validName.match(index.name).hasMatch()) {
+            *error = QStringLiteral("Federated index %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (index.name.compare(QLatin1String(kLocalSource), Qt::CaseInsensitive) == 0
+            || names.contains(index.name.toLower())) {
+            *error = QStringLiteral("Federated index %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (!PeerClient::isAllowedUrl(index.endpoint.baseUrl)) {
+            *error = QStringLiteral("Federated index %1: the URL must be https").arg(label);
+            return std::nullopt;
+        }
+        if (index.endpoint.token.isEmpty()) {
+            *error = QStringLiteral("Federated index %1 has no token").arg(label);
+            return std::nullopt;
+        }
+        names.insert(index.name.toLower());
+        indexes.push_back(std::move(index));
+    }
+    return indexes;
+}
+
+QJsonObject FederatedSearch::remoteParams(const QJsonObject& params)
+{
+    QJsonObject remote = params;
+    for (const char* key : {"auth", "scope", "context", "deadlineMs", "debug"}) {
+        remote.remove(QLatin1String(key));
+    }
+    remote[QStringLiteral("federated")] = false;
+    remote[QStringLiteral("limit")] = limitOf(params);
+    return remote;
+}
+
+int FederatedSearch::limitOf(const QJsonObject& params)
+{
+    return std::clamp(params.value(QStringLiteral("limit")).toInt(20), 1, 200);
+}
+
+QJsonObject FederatedSearch::merge(const QJsonObject& local, qint64 localElapsedMs,
+                                   const std::vector<FederatedSource>& remotes, int limit)
+{
+    QJsonArray sources;
+    std::vector<std::vector<QJsonObject>> lists;
+    qint64 totalMatches = local.value(QStringLiteral("totalMatches")).toInteger();
+    QSet<QString> live;
+
+    for (const FederatedSource& remote : remotes) {
+        std::vector<QJsonObject> rows;
+        if (remote.reply.json.has_value()) {
+            for (const QJsonValue& value :
+                 remote.reply.json->value(QStringLiteral("results")).toArray()) {
+                const QJsonObject row = value.toObject();
+                // Files only: its donated items, apps and replicas mean
+                // nothing on this Mac.
+                const QString path = row.value(QStringLiteral("path")).toString();
+                if (!path.startsWith(QLatin1Char('/')) || row.contains(QStringLiteral("peer"))) {
+                    continue;
+                }
+                if (remote.team) {
+                    rows.push_back(teamRow(row, remote.name));
+                    continue;
+                }
+                live.insert(peerKey(remote.name, path));
+                rows.push_back(remoteRow(row, remote.name));
+            }
+            totalMatches += remote.reply.json->value(QStringLiteral("totalMatches")).toInteger();
+        }
+        const QString status = remote.reply.json.has_value() ? QStringLiteral("ok")
+                               : remote.reply.timedOut       ? QStringLiteral("timeout")
+                                                             : QStringLiteral("error");
+        sources.append(sourceJson(remote.name, status, static_cast<int>(rows.size()),
+                                  remote.reply.elapsedMs, remote.reply.error));
+        lists.push_back(std::move(rows));
+    }
+
+    std::vector<QJsonObject> localRows;
+    for (const QJsonValue& value : local.value(QStringLiteral("results")).toArray()) {
+        QJsonObject row = value.toObject();
+        if (row.contains(QStringLiteral("peer"))
+            && live.contains(peerKey(row.value(QStringLiteral("peer")).toString(),
+                                     row.value(QStringLiteral("peerPath")).toString()))) {
+            continue;
+        }
+        row[QStringLiteral("source")] = QLatin1String(kLocalSource);
+        localRows.push_back(std::move(row));
+    }
+    sources.prepend(sourceJson(QLatin1String(kLocalSource), QStringLiteral("ok"),
+                               static_cast<int>(localRows.size()), localElapsedMs, QString()));
+    lists.insert(lists.begin(), std::move(localRows));
+
+    std::vector<Ranked> ranked;
+    int order = 0;
+    for (std::vector<QJsonObject>& rows : lists) {
+        for (size_t rank = 0; rank < rows.size(); ++rank) {
+            ranked.push_back({std::move(rows[rank]),
+                              1.0 / (kRankConstant + static_cast<double>(rank) + 1.0), order++});
+        }
+    }
+    std::stable_sort(ranked.begin(), ranked.end(), [](const Ranked& a, const Ranked& b) {
+        if (a.score != b.score) {
+            return a.score > b.score;
+        }
+        return a.order < b.order;
+    });
+
+    QJsonArray results;
+    for (const Ranked& entry : ranked) {
+        if (results.size() >= limit) {
+            break;
+        }
+        results.append(entry.row);
+    }
+    QJsonObject merged = local;
+    merged[QStringLiteral("results")] = results;
+    merged[QStringLiteral("totalMatches")] = totalMatches;
+    merged[QStringLiteral("sources")] = sources;
+    return merged;
+}
+
+} // namespace bs
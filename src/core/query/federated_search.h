@@ -0,0 +1,77 @@
+#pragma once
+
+#include "core/ipc/peer_client.h"
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// One other index a search fans out to: a Mac (or anything else) serving
+// the HTTP API, see query_service_http.cpp.
+struct FederatedIndex {
+    QString name;          // label on its results, letters, digits and '-'
+    PeerEndpoint endpoint;
+    int timeoutMs = 0;     // for the whole request; slower answers are dropped
+};
+
+// What one index answered to a fanned-out search.
+struct FederatedSource {
+    QString name;
+    PeerReply reply;       // a search result object when it succeeded
+    bool team = false;     // a read-only team index searched here (TeamIndexes)
+};
+
+// FederatedSearch -- one search over the local index plus the indexes in
+// the `federated_indexes` setting.
+//
+// The query service sends each index the same search with `federated:
+// false` (so two Macs listing each other do not recurse), runs its own
+// meanwhile, and merges the lists by reciprocal rank fusion: scores from
+// different indexes are not comparable, positions are. Every row is
+// labelled with its `source`, and the response lists each source's
+// status, so a slow or unreachable index costs at most its timeout and
+// shows up as such instead of failing the search.
+class FederatedSearch {
+public:
+    static constexpr const char* kSettingKey = "federated_indexes";
+    static constexpr const char* kLocalSource = "local";
+    static constexpr int kMaxIndexes = 8;
+    static constexpr int kMaxNameChars = 32;
+    static constexpr int kDefaultTimeoutMs = 800;
+    static constexpr int kMinTimeoutMs = 100;
+    static constexpr int kMaxTimeoutMs = 10000;
+    // The usual RRF constant: damps the gap between the first few ranks.
+    static constexpr int kRankConstant = 60;
+
+    // The setting's JSON array of {name, url, token, fingerprint?,
+    // timeoutMs?}. Empty text is no indexes; nullopt with *error when an
+    // entry is invalid (a bad name, a non-https URL, no token) or a name
+    // repeats.
+    static std::optional<std::vector<FederatedIndex>> parseIndexes(const QString& json,
+                                                                   QString* error);
+
+    // The search params sent to another index: the caller's, minus what
+    // only means something here (auth, scope, frontmost app, deadline)
+    // and with federation off.
+    static QJsonObject remoteParams(const QJsonObject& params);
+
+    // The result's row limit, as handleSearch reads it.
+    static int limitOf(const QJsonObject& params);
+
+    // `local` (a search result object) with `results` replaced by the fused
+    // list of at most `limit` rows, `totalMatches` summed over the sources
+    // that answered, and `sources`: [{name, status: ok|timeout|error,
+    // results, elapsedMs, error?}]. Remote rows are kept only for real
+    // file paths and become peer rows (peer, peerPath, itemId 0), or team
+    // rows (team, itemId 0) for a team source; a local replica of a file
+    // (see PeerSync) an index returned live is dropped.
+    static QJsonObject merge(const QJsonObject& local, qint64 localElapsedMs,
+                             const std::vector<FederatedSource>& remotes, int limit);
+};
+
+} // namespace bs
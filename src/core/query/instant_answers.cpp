@@ -0,0 +1,553 @@
+#include "core/query/instant_answers.h"
+
+#include <QRegularExpression>
+#include <QStringList>
+
+#include <cmath>
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+// ── Calculator ──────────────────────────────────────────────
+
+struct Token {
+    enum class Type { Number, Operator, LeftParen, RightParen, Name };
+    Type type = Type::Number;
+    double number = 0.0;
+    QChar op;
+    QString name;
+};
+
+bool tokenize(const QString& input, std::vector<Token>* tokens)
+{
+    int i = 0;
+    while (i < input.size()) {
+        const QChar ch = input.at(i);
+        if (ch.isSpace()) {
+            ++i;
+        } else if (ch.isDigit() || ch == QLatin1Char('.')) {
+            int end = i;
+            while (end < input.size()
+                   && (input.at(end).isDigit() || input.at(end) == QLatin1Char('.'))) {
+                ++end;
+            }
+            bool ok = false;
+            const double number = input.mid(i, end - i).toDouble(&ok);
+            if (!ok) {
+                return false;  // "1.2.3"
+            }
+            tokens->push_back({Token::Type::Number, number, {}, {}});
+            i = end;
+        } else if (ch.isLetter()) {
+            int end = i;
+            while (end < input.size() && input.at(end).isLetter()) {
+                ++end;
+            }
+            const QString name = input.mid(i, end - i);
+            if (name == QLatin1String("x")) {
+                tokens->push_back({Token::Type::Operator, 0.0, QLatin1Char('*'), {}});
+            } else {
+                tokens->push_back({Token::Type::Name, 0.0, {}, name});
+            }
+            i = end;
+        } else if (ch == QLatin1Char('(')) {
+            tokens->push_back({Token::Type::LeftParen, 0.0, {}, {}});
+            ++i;
+        } else if (ch == QLatin1Char(')')) {
+            tokens->push_back({Token::Type::RightParen, 0.0, {}, {}});
+            ++i;
+        } else if (QStringLiteral("+-*/^%").contains(ch)) {
+            QChar op = ch;
+            if (ch == QLatin1Char('*') && i + 1 < input.size()
+                && input.at(i + 1) == QLatin1Char('*')) {
+                op = QLatin1Char('^');
+                ++i;
+            }
+            tokens->push_back({Token::Type::Operator, 0.0, op, {}});
+            ++i;
+        } else {
+            return false;
+        }
+    }
+    return true;
+}
+
+// Recursive descent over
+//   expr    := term (('+' | '-') term)*
+//   term    := power (('*' | '/') power)*
+//   power   := unary ('^' power)?
+//   unary   := ('-' | '+') unary | postfix
+//   postfix := primary ('%' ('of' unary)?)?
+//   primary := number | constant | function '(' expr ')' | '(' expr ')'
+class Calculator {
+public:
+    explicit Calculator(std::vector<Token> tokens) : m_tokens(std::move(tokens)) {}
+
+    std::optional<double> evaluate()
+    {
+        const double value = expression();
+        if (m_failed || m_pos != m_tokens.size() || !std::isfinite(value)) {
+            return std::nullopt;
+        }
+        return value;
+    }
+
+    int operatorCount() const { return m_operators; }
+    bool onlySubtraction() const { return m_onlySubtraction; }
+
+private:
+    bool atOperator(QChar op) const
+    {
+        return m_pos < m_tokens.size() && m_tokens[m_pos].type == Token::Type::Operator
+               && m_tokens[m_pos].op == op;
+    }
+
+    bool atName(const char* name) const
+    {
+        return m_pos < m_tokens.size() && m_tokens[m_pos].type == Token::Type::Name
+               && m_tokens[m_pos].name == QLatin1String(name);
+    }
+
+    void countOperator(QChar op)
+    {
+        ++m_operators;
+        if (op != QLatin1Char('-')) {
+            m_onlySubtraction = false;
+        }
+    }
+
+    double fail()
+    {
+        m_failed = true;
+        return 0.0;
+    }
+
+    double expression()
+    {
+        double value = term();
+        while (!m_failed && (atOperator(QLatin1Char('+')) || atOperator(QLatin1Char('-')))) {
+            const QChar op = m_tokens[m_pos++].op;
+            countOperator(op);
+            const double right = term();
+            value = op == QLatin1Char('+') ? value + right : value - right;
+        }
+        return value;
+    }
+
+    double term()
+    {
+        double value = power();
+        while (!m_failed && (atOperator(QLatin1Char('*')) || atOperator(QLatin1Char('/')))) {
+            const QChar op = m_tokens[m_pos++].op;
+            countOperator(op);
+            const double right = power();
+            if (op == QLatin1Char('/')) {
+                if (right == 0.0) {
+                    return fail();
+                }
+                value /= right;
+            } else {
+                value *= right;
+            }
+        }
+        return value;
+    }
+
+    double power()
+    {
+        const double base = unary();
+        if (m_failed || !atOperator(QLatin1Char('^'))) {
+            return base;
+        }
+        ++m_pos;
+        countOperator(QLatin1Char('^'));
+        return std::pow(base, power());
+    }
+
+    double unary()
+    {
+        if (atOperator(QLatin1Char('-'))) {
+            ++m_pos;
+            return -unary();
+        }
+        if (atOperator(QLatin1Char('+'))) {
+            ++m_pos;
+            return unary();
+        }
+        return postfix();
+    }
+
+    double postfix()
+    {
+        const double value = primary();
+        if (m_failed || !atOperator(QLatin1Char('%'))) {
+            return value;
+        }
+        ++m_pos;
+        countOperator(QLatin1Char('%'));
+        if (atName("of")) {  // "15% of 80"
+            ++m_pos;
+            return value / 100.0 * unary();
+        }
+        return value / 100.0;
+    }
+
+    double primary()
+    {
+        if (m_pos >= m_tokens.size()) {
+            return fail();
+        }
+        const Token token = m_tokens[m_pos++];
+        if (token.type == Token::Type::Number) {
+            return token.number;
+        }
+        if (token.type == Token::Type::LeftParen) {
+            const double value = expression();
+            if (m_pos >= m_tokens.size() || m_tokens[m_pos].type != Token::Type::RightParen) {
+                return fail();
+            }
+            ++m_pos;
+            return value;
+        }
+        if (token.type != Token::Type::Name) {
+            return fail();
+        }
+        if (token.name == QLatin1String("pi")) {
+            return M_PI;
+        }
+        if (token.name == QLatin1String("e")) {
+            return M_E;
+        }
+        if (m_pos >= m_tokens.size() || m_tokens[m_pos].type != Token::Type::LeftParen) {
+            return fail();
+        }
+        ++m_pos;
+        const double argument = expression();
+        if (m_pos >= m_tokens.size() || m_tokens[m_pos].type != Token::Type::RightParen) {
+            return fail();
+        }
+        ++m_pos;
+        ++m_operators;
+        m_onlySubtraction = false;
+        const QString& name = token.name;
+        if (name == QLatin1String("sqrt")) {
+            return argument < 0.0 ? fail() : std::sqrt(argument);
+        }
+        if (name == QLatin1String("abs")) {
+            return std::fabs(argument);
+        }
+        if (name == QLatin1String("ln")) {
+            return argument <= 0.0 ? fail() : std::log(argument);
+        }
+        if (name == QLatin1String("log")) {
+            return argument <= 0.0 ? fail() : std::log10(argument);
+        }
+        if (name == QLatin1String("exp")) {
+            return std::exp(argument);
+        }
+        if (name == QLatin1String("sin")) {
+            return std::sin(argument);
+        }
+        if (name == QLatin1String("cos")) {
+            return std::cos(argument);
+        }
+        if (name == QLatin1String("tan")) {
+            return std::tan(argument);
+        }
+        if (name == QLatin1String("round")) {
+            return std::round(argument);
+        }
+        if (name == QLatin1String("floor")) {
+            return std::floor(argument);
+        }
+        if (name == QLatin1String("ceil")) {
+            return std::ceil(argument);
+        }
+        return fail();
+    }
+
+    std::vector<Token> m_tokens;
+    size_t m_pos = 0;
+    int m_operators = 0;
+    bool m_onlySubtraction = true;
+    bool m_failed = false;
+};
+
+std::optional<InstantAnswer> calculate(const QString& query)
+{
+    static const QRegularExpression kSlashDate(QStringLiteral("^\\d{1,2}/\\d{1,2}/\\d{2,4}$"));
+    if (kSlashDate.match(query).hasMatch()) {
+        return std::nullopt;
+    }
+    QString input = query.toLower();
+    input.replace(QChar(0x00D7), QLatin1Char('*'));  // ×
+    input.replace(QChar(0x00F7), QLatin1Char('/'));  // ÷
+    input.replace(QChar(0x2212), QLatin1Char('-'));  // −
+    // "1,499.50": thousands separators.
+    static const QRegularExpression kThousands(QStringLiteral("(?<=\\d),(?=\\d{3}(?!\\d))"));
+    input.remove(kThousands);
+
+    std::vector<Token> tokens;
+    if (!tokenize(input, &tokens) || tokens.empty()) {
+        return std::nullopt;
+    }
+    Calculator calculator(std::move(tokens));
+    const std::optional<double> value = calculator.evaluate();
+    if (!value.has_value() || calculator.operatorCount() == 0) {
+        return std::nullopt;
+    }
+    // "2024-10-14", "555-1234": identifiers more often than sums.
+    if (calculator.onlySubtraction() && !query.contains(QLatin1Char(' '))) {
+        return std::nullopt;
+    }
+
+    InstantAnswer answer;
+    answer.kind = InstantAnswer::Kind::Calculation;
+    answer.expression = query.simplified();
+    answer.number = value.value();
+    answer.value = InstantAnswers::formatNumber(value.value());
+    answer.display = QStringLiteral("%1 = %2").arg(answer.expression, answer.value);
+    return answer;
+}
+
+// ── Unit conversion ─────────────────────────────────────────
+
+enum class Dimension { Length, Mass, Volume, Time, Speed, Data, Temperature };
+
+struct Unit {
+    QStringList names;
+    const char* symbol;
+    Dimension dimension;
+    double toBase;  // for temperature: 0 Celsius, 1 Fahrenheit, 2 Kelvin
+};
+
+const std::vector<Unit>& units()
+{
+    static const std::vector<Unit> kUnits = {
+        {{QStringLiteral("mm"), QStringLiteral("millimeter"), QStringLiteral("millimeters"),
+          QStringLiteral("millimetre"), QStringLiteral("millimetres")},
+         "mm", Dimension::Length, 0.001},
+        {{QStringLiteral("cm"), QStringLiteral("centimeter"), QStringLiteral("centimeters"),
+          QStringLiteral("centimetre"), QStringLiteral("centimetres")},
+         "cm", Dimension::Length, 0.01},
+        {{QStringLiteral("m"), QStringLiteral("meter"), QStringLiteral("meters"),
+          QStringLiteral("metre"), QStringLiteral("metres")},
+         "m", Dimension::Length, 1.0},
+        {{QStringLiteral("km"), QStringLiteral("kilometer"), QStringLiteral("kilometers"),
+          QStringLiteral("kilometre"), QStringLiteral("kilometres")},
+         "km", Dimension::Length, 1000.0},
+        {{QStringLiteral("in"), QStringLiteral("inch"), QStringLiteral("inches"),
+          QStringLiteral("\"")},
+         "in", Dimension::Length, 0.0254},
+        {{QStringLiteral("ft"), QStringLiteral("foot"), QStringLiteral("feet"),
+          QStringLiteral("'")},
+         "ft", Dimension::Length, 0.3048},
+        {{QStringLiteral("yd"), QStringLiteral("yard"), QStringLiteral("yards")},
+         "yd", Dimension::Length, 0.9144},
+        {{QStringLiteral("mi"), QStringLiteral("mile"), QStringLiteral("miles")},
+         "mi", Dimension::Length, 1609.344},
+        {{QStringLiteral("nmi"), QStringLiteral("nautical mile"), QStringLiteral("nautical miles")},
+         "nmi", Dimension::Length, 1852.0},
+
+        {{QStringLiteral("mg"), QStringLiteral("milligram"), QStringLiteral("milligrams")},
+         "mg", Dimension::Mass, 0.001},
+        {{QStringLiteral("g"), QStringLiteral("gram"), QStringLiteral("grams")},
+         "g", Dimension::Mass, 1.0},
+        {{QStringLiteral("kg"), QStringLiteral("kilogram"), QStringLiteral("kilograms"),
+          QStringLiteral("kilo"), QStringLiteral("kilos")},
+         "kg", Dimension::Mass, 1000.0},
+        {{QStringLiteral("t"), QStringLiteral("tonne"), QStringLiteral("tonnes")},
+         "t", Dimension::Mass, 1e6},
+        {{QStringLiteral("oz"), QStringLiteral("ounce"), QStringLiteral("ounces")},
+         "oz", Dimension::Mass, 28.349523125},
+        {{QStringLiteral("lb"), QStringLiteral("lbs"), QStringLiteral("pound"),
+          QStringLiteral("pounds")},
+         "lb", Dimension::Mass, 453.59237},
+        {{QStringLiteral("st"), QStringLiteral("stone"), QStringLiteral("stones")},
+         "st", Dimension::Mass, 6350.29318},
+
+        {{QStringLiteral("ml"), QStringLiteral("milliliter"), QStringLiteral("milliliters"),
+          QStringLiteral("millilitre"), QStringLiteral("millilitres")},
+         "ml", Dimension::Volume, 0.001},
+        {{QStringLiteral("cl"), QStringLiteral("centiliter"), QStringLiteral("centiliters")},
+         "cl", Dimension::Volume, 0.01},
+        {{QStringLiteral("l"), QStringLiteral("liter"), QStringLiteral("liters"),
+          QStringLiteral("litre"), QStringLiteral("litres")},
+         "l", Dimension::Volume, 1.0},
+        {{QStringLiteral("tsp"), QStringLiteral("teaspoon"), QStringLiteral("teaspoons")},
+         "tsp", Dimension::Volume, 0.00492892159375},
+        {{QStringLiteral("tbsp"), QStringLiteral("tablespoon"), QStringLiteral("tablespoons")},
+         "tbsp", Dimension::Volume, 0.01478676478125},
+        {{QStringLiteral("fl oz"), QStringLiteral("floz")},
+         "fl oz", Dimension::Volume, 0.0295735295625},
+        {{QStringLiteral("cup"), QStringLiteral("cups")}, "cup", Dimension::Volume, 0.2365882365},
+        {{QStringLiteral("pt"), QStringLiteral("pint"), QStringLiteral("pints")},
+         "pt", Dimension::Volume, 0.473176473},
+        {{QStringLiteral("qt"), QStringLiteral("quart"), QStringLiteral("quarts")},
+         "qt", Dimension::Volume, 0.946352946},
+        {{QStringLiteral("gal"), QStringLiteral("gallon"), QStringLiteral("gallons")},
+         "gal", Dimension::Volume, 3.785411784},
+
+        {{QStringLiteral("ms"), QStringLiteral("millisecond"), QStringLiteral("milliseconds")},
+         "ms", Dimension::Time, 0.001},
+        {{QStringLiteral("s"), QStringLiteral("sec"), QStringLiteral("secs"),
+          QStringLiteral("second"), QStringLiteral("seconds")},
+         "s", Dimension::Time, 1.0},
+        {{QStringLiteral("min"), QStringLiteral("mins"), QStringLiteral("minute"),
+          QStringLiteral("minutes")},
+         "min", Dimension::Time, 60.0},
+        {{QStringLiteral("h"), QStringLiteral("hr"), QStringLiteral("hrs"),
+          QStringLiteral("hour"), QStringLiteral("hours")},
+         "h", Dimension::Time, 3600.0},
+        {{QStringLiteral("day"), QStringLiteral("days")}, "days", Dimension::Time, 86400.0},
+        {{QStringLiteral("week"), QStringLiteral("weeks")}, "weeks", Dimension::Time, 604800.0},
+
+        {{QStringLiteral("m/s"), QStringLiteral("mps")}, "m/s", Dimension::Speed, 1.0},
+        {{QStringLiteral("km/h"), QStringLiteral("kmh"), QStringLiteral("kph")},
+         "km/h", Dimension::Speed, 1.0 / 3.6},
+        {{QStringLiteral("mph")}, "mph", Dimension::Speed, 0.44704},
+        {{QStringLiteral("kn"), QStringLiteral("knot"), QStringLiteral("knots")},
+         "kn", Dimension::Speed, 1852.0 / 3600.0},
+
+        {{QStringLiteral("b"), QStringLiteral("byte"), QStringLiteral("bytes")},
+         "B", Dimension::Data, 1.0},
+        {{QStringLiteral("kb"), QStringLiteral("kilobyte"), QStringLiteral("kilobytes")},
+         "KB", Dimension::Data, 1e3},
+        {{QStringLiteral("mb"), QStringLiteral("megabyte"), QStringLiteral("megabytes")},
+         "MB", Dimension::Data, 1e6},
+        {{QStringLiteral("gb"), QStringLiteral("gigabyte"), QStringLiteral("gigabytes")},
+         "GB", Dimension::Data, 1e9},
+        {{QStringLiteral("tb"), QStringLiteral("terabyte"), QStringLiteral("terabytes")},
+         "TB", Dimension::Data, 1e12},
+        {{QStringLiteral("kib")}, "KiB", Dimension::Data, 1024.0},
+        {{QStringLiteral("mib")}, "MiB", Dimension::Data, 1048576.0},
+        {{QStringLiteral("gib")}, "GiB", Dimension::Data, 1073741824.0},
+        {{QStringLiteral("tib")}, "TiB", Dimension::Data, 1099511627776.0},
+
+        {{QStringLiteral("c"), QStringLiteral("celsius"), QStringLiteral("centigrade")},
+         "°C", Dimension::Temperature, 0},
+        {{QStringLiteral("f"), QStringLiteral("fahrenheit")}, "°F", Dimension::Temperature, 1},
+        {{QStringLiteral("k"), QStringLiteral("kelvin")}, "K", Dimension::Temperature, 2},
+    };
+    return kUnits;
+}
+
+const Unit* unitNamed(QString name)
+{
+    name = name.trimmed();
+    if (name.startsWith(QChar(0x00B0))) {  // "°f"
+        name.remove(0, 1);
+    }
+    if (name.startsWith(QLatin1String("degrees "))) {
+        name = name.mid(8);
+    }
+    for (const Unit& unit : units()) {
+        if (unit.names.contains(name)) {
+            return &unit;
+        }
+    }
+    return nullptr;
+}
+
+double toCelsius(double value, int scale)
+{
+    if (scale == 1) {
+        return (value - 32.0) * 5.0 / 9.0;
+    }
+    return scale == 2 ? value - 273.15 : value;
+}
+
+double fromCelsius(double celsius, int scale)
+{
+    if (scale == 1) {
+        return celsius * 9.0 / 5.0 + 32.0;
+    }
+    return scale == 2 ? celsius + 273.15 : celsius;
+}
+
+std::optional<InstantAnswer> convert(const QString& query)
+{
+    static const QRegularExpression kConversionPattern(QStringLiteral(
+        "^(-?(?:\\d[\\d,]*(?:\\.\\d+)?|\\.\\d+))\\s*(.+?)\\s+(?:in|to|as|into)\\s+(.+)$"));
+    const QRegularExpressionMatch match = kConversionPattern.match(query.simplified().toLower());
+    if (!match.hasMatch()) {
+        return std::nullopt;
+    }
+    const Unit* from = unitNamed(match.captured(2));
+    const Unit* to = unitNamed(match.captured(3));
+    if (!from || !to || from->dimension != to->dimension || from == to) {
+        return std::nullopt;
+    }
+    bool ok = false;
+    const double amount = match.captured(1).remove(QLatin1Char(',')).toDouble(&ok);
+    if (!ok) {
+        return std::nullopt;
+    }
+
+    double result = 0.0;
+    if (from->dimension == Dimension::Temperature) {
+        result = fromCelsius(toCelsius(amount, static_cast<int>(from->toBase)),
+                             static_cast<int>(to->toBase));
+    } else {
+        result = amount * from->toBase / to->toBase;
+    }
+
+    InstantAnswer answer;
+    answer.kind = InstantAnswer::Kind::Conversion;
+    answer.expression = QStringLiteral("%1 %2").arg(InstantAnswers::formatNumber(amount),
+                                                    QString::fromUtf8(from->symbol));
+    answer.number = result;
+    answer.value = InstantAnswers::formatNumber(result);
+    answer.unit = QString::fromUtf8(to->symbol);
+    answer.display = QStringLiteral("%1 = %2 %3").arg(answer.expression, answer.value, answer.unit);
+    return answer;
+}
+
+} // namespace
+
+QJsonObject InstantAnswer::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("kind")] = kind == Kind::Conversion ? QStringLiteral("conversion")
+                                                            : QStringLiteral("calculation");
+    json[QStringLiteral("expression")] = expression;
+    json[QStringLiteral("number")] = number;
+    json[QStringLiteral("value")] = value;
+    if (!unit.isEmpty()) {
+        json[QStringLiteral("unit")] = unit;
+    }
+    json[QStringLiteral("display")] = display;
+    return json;
+}
+
+std::optional<InstantAnswer> InstantAnswers::evaluate(const QString& query)
+{
+    const QString trimmed = query.trimmed();
+    if (trimmed.isEmpty() || trimmed.size() > 200) {
+        return std::nullopt;
+    }
+    if (auto conversion = convert(trimmed)) {
+        return conversion;
+    }
+    return calculate(trimmed);
+}
+
+QString InstantAnswers::formatNumber(double value)
+{
+    const double magnitude = std::fabs(value);
+    if (magnitude >= 1e15 || (magnitude > 0.0 && magnitude < 1e-6)) {
+        return QString::number(value, 'g', 10);
+    }
+    QString text = QString::number(value, 'f', 6);
+    while (text.endsWith(QLatin1Char('0'))) {
+        text.chop(1);
+    }
+    if (text.endsWith(QLatin1Char('.'))) {
+        text.chop(1);
+    }
+    return text == QLatin1String("-0") ? QStringLiteral("0") : text;
+}
+
+} // namespace bs
@@ -0,0 +1,37 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+// A calculator or unit conversion result shown ahead of file hits.
+struct InstantAnswer {
+    enum class Kind { Calculation, Conversion };
+
+    Kind kind = Kind::Calculation;
+    QString expression;  // "1499*1.21", "72 °F"
+    double number = 0.0;
+    QString value;       // "1813.79", formatted
+    QString unit;        // "°C"; empty for calculations
+    QString display;     // "1499*1.21 = 1813.79", "72 °F = 22.222222 °C"
+
+    QJsonObject toJson() const;
+};
+
+// InstantAnswers -- recognises queries that are arithmetic ("1499*1.21",
+// "(3+4)^2", "15% of 80", "sqrt(2)") or a unit conversion ("72 f in c",
+// "5 km to mi", "2 gb in mib"). Anything else, including bare numbers and
+// date-like inputs such as "2024-10", is not an answer.
+class InstantAnswers {
+public:
+    // `query` is the raw query: the normalizer drops operators.
+    static std::optional<InstantAnswer> evaluate(const QString& query);
+
+    // At most 6 decimals, no trailing zeros; scientific when huge or tiny.
+    static QString formatNumber(double value);
+};
+
+} // namespace bs
@@ -0,0 +1,258 @@
+#include "core/query/natural_language_query.h"
+
+#include <QJsonArray>
+#include <QSet>
+#include <QStringList>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+struct KindEntry {
+    QStringList words;
+    QString kind;
+    QStringList fileTypes;
+    QString term;  // kept in the query: screenshots are named "Screenshot ..."
+};
+
+const std::vector<KindEntry>& kinds()
+{
+    static const std::vector<KindEntry> kKinds = {
+        {{QStringLiteral("screenshot"), QStringLiteral("screenshots")},
+         QStringLiteral("screenshots"),
+         {QStringLiteral("png"), QStringLiteral("jpg"), QStringLiteral("heic")},
+         QStringLiteral("screenshot")},
+        {{QStringLiteral("photo"), QStringLiteral("photos"), QStringLiteral("picture"),
+          QStringLiteral("pictures"), QStringLiteral("image"), QStringLiteral("images"),
+          QStringLiteral("pics")},
+         QStringLiteral("images"),
+         {QStringLiteral("jpg"), QStringLiteral("jpeg"), QStringLiteral("png"),
+          QStringLiteral("heic"), QStringLiteral("gif")},
+         QString()},
+        {{QStringLiteral("pdf"), QStringLiteral("pdfs")},
+         QStringLiteral("pdfs"),
+         {QStringLiteral("pdf")},
+         QString()},
+        {{QStringLiteral("document"), QStringLiteral("documents"), QStringLiteral("docs")},
+         QStringLiteral("documents"),
+         {QStringLiteral("pdf"), QStringLiteral("doc"), QStringLiteral("docx"),
+          QStringLiteral("pages"), QStringLiteral("rtf"), QStringLiteral("txt"),
+          QStringLiteral("md")},
+         QString()},
+        {{QStringLiteral("spreadsheet"), QStringLiteral("spreadsheets")},
+         QStringLiteral("spreadsheets"),
+         {QStringLiteral("xlsx"), QStringLiteral("xls"), QStringLiteral("numbers"),
+          QStringLiteral("csv")},
+         QString()},
+        {{QStringLiteral("presentation"), QStringLiteral("presentations"),
+          QStringLiteral("slides"), QStringLiteral("deck"), QStringLiteral("decks")},
+         QStringLiteral("presentations"),
+         {QStringLiteral("pptx"), QStringLiteral("ppt"), QStringLiteral("key")},
+         QString()},
+        {{QStringLiteral("video"), QStringLiteral("videos"), QStringLiteral("movie"),
+          QStringLiteral("movies")},
+         QStringLiteral("videos"),
+         {QStringLiteral("mp4"), QStringLiteral("mov"), QStringLiteral("m4v"),
+          QStringLiteral("avi")},
+         QString()},
+        {{QStringLiteral("recording"), QStringLiteral("recordings"), QStringLiteral("audio"),
+          QStringLiteral("song"), QStringLiteral("songs")},
+         QStringLiteral("audio"),
+         {QStringLiteral("mp3"), QStringLiteral("m4a"), QStringLiteral("wav"),
+          QStringLiteral("aiff")},
+         QString()},
+    };
+    return kKinds;
+}
+
+const KindEntry* kindForWord(const QString& word)
+{
+    for (const KindEntry& entry : kinds()) {
+        if (entry.words.contains(word)) {
+            return &entry;
+        }
+    }
+    return nullptr;
+}
+
+const QSet<QString>& modifiedVerbs()
+{
+    static const QSet<QString> kVerbs = {
+        QStringLiteral("modified"), QStringLiteral("edited"), QStringLiteral("changed"),
+        QStringLiteral("updated"), QStringLiteral("saved"),
+    };
+    return kVerbs;
+}
+
+const QSet<QString>& createdVerbs()
+{
+    static const QSet<QString> kVerbs = {
+        QStringLiteral("created"), QStringLiteral("taken"), QStringLiteral("made"),
+        QStringLiteral("shot"), QStringLiteral("added"),
+    };
+    return kVerbs;
+}
+
+const QSet<QString>& prepositions()
+{
+    static const QSet<QString> kPrepositions = {
+        QStringLiteral("from"), QStringLiteral("since"), QStringLiteral("on"),
+        QStringLiteral("in"), QStringLiteral("during"),
+    };
+    return kPrepositions;
+}
+
+// Words that carry nothing once the rest is understood ("show me my ...").
+const QSet<QString>& fillers()
+{
+    static const QSet<QString> kFillers = {
+        QStringLiteral("show"), QStringLiteral("find"), QStringLiteral("me"),
+        QStringLiteral("my"), QStringLiteral("all"), QStringLiteral("the"),
+        QStringLiteral("any"), QStringLiteral("file"), QStringLiteral("files"),
+    };
+    return kFillers;
+}
+
+// Without a verb or preposition only unmistakably relative phrases count:
+// in "pdfs 2025 budget" the year is more likely part of a name.
+bool startsRelativePhrase(const QString& word)
+{
+    static const QSet<QString> kStarts = {
+        QStringLiteral("today"),     QStringLiteral("yesterday"), QStringLiteral("this"),
+        QStringLiteral("last"),      QStringLiteral("past"),      QStringLiteral("monday"),
+        QStringLiteral("tuesday"),   QStringLiteral("wednesday"), QStringLiteral("thursday"),
+        QStringLiteral("friday"),    QStringLiteral("saturday"),  QStringLiteral("sunday"),
+    };
+    return kStarts.contains(word);
+}
+
+struct DateSpan {
+    int start = 0;
+    int end = 0;  // exclusive
+    bool created = false;
+    DateBounds dates;
+};
+
+std::optional<DateSpan> findDateSpan(const QStringList& tokens, bool kindNamed,
+                                     const QDateTime& now)
+{
+    const int count = static_cast<int>(tokens.size());
+    for (int i = 0; i < count; ++i) {
+        int j = i;
+        const bool created = createdVerbs().contains(tokens.at(j));
+        if (created || modifiedVerbs().contains(tokens.at(j))) {
+            ++j;
+        }
+        const bool since = j < count && tokens.at(j) == QLatin1String("since");
+        if (j < count && prepositions().contains(tokens.at(j))) {
+            ++j;
+        }
+        if (j >= count) {
+            break;
+        }
+        const bool introduced = j > i;
+        if (!introduced && (!kindNamed || !startsRelativePhrase(tokens.at(j)))) {
+            continue;
+        }
+        for (int length = std::min(3, count - j); length >= 1; --length) {
+            const auto dates =
+                RelativeDates::resolve(tokens.mid(j, length).join(QLatin1Char(' ')), now);
+            if (!dates.has_value()) {
+                continue;
+            }
+            DateSpan span{i, j + length, created, dates.value()};
+            if (since) {
+                span.dates.before.reset();
+            }
+            return span;
+        }
+    }
+    return std::nullopt;
+}
+
+} // namespace
+
+QJsonObject QueryInterpretation::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("summary")] = summary;
+    json[QStringLiteral("query")] = query;
+    if (!kind.isEmpty()) {
+        json[QStringLiteral("kind")] = kind;
+        QJsonArray types;
+        for (const QString& type : fileTypes) {
+            types.append(type);
+        }
+        json[QStringLiteral("fileTypes")] = types;
+    }
+    json[QStringLiteral("dateField")] =
+        created ? QStringLiteral("created") : QStringLiteral("modified");
+    json[QStringLiteral("datePhrase")] = datePhrase;
+    if (dates.after.has_value()) {
+        json[QStringLiteral("after")] = dates.after.value();
+    }
+    if (dates.before.has_value()) {
+        json[QStringLiteral("before")] = dates.before.value();
+    }
+    return json;
+}
+
+std::optional<QueryInterpretation> NaturalLanguageQuery::interpret(
+    const QString& normalizedQuery, const QDateTime& now)
+{
+    QStringList tokens = normalizedQuery.toLower().split(QLatin1Char(' '), Qt::SkipEmptyParts);
+    for (const QString& token : tokens) {
+        if (token.startsWith(QLatin1String("modified:"))
+            || token.startsWith(QLatin1String("created:"))) {
+            return std::nullopt;
+        }
+    }
+
+    const bool kindNamed = std::any_of(tokens.cbegin(), tokens.cend(), [](const QString& token) {
+        return kindForWord(token) != nullptr;
+    });
+    const auto span = findDateSpan(tokens, kindNamed, now);
+    if (!span.has_value()) {
+        return std::nullopt;
+    }
+
+    QueryInterpretation interpretation;
+    interpretation.created = span->created;
+    interpretation.dates = span->dates;
+    interpretation.datePhrase = tokens.mid(span->start, span->end - span->start)
+                                    .join(QLatin1Char(' '));
+    tokens.erase(tokens.begin() + span->start, tokens.begin() + span->end);
+
+    QStringList matchWords;
+    for (const QString& token : tokens) {
+        const KindEntry* entry = interpretation.kind.isEmpty() ? kindForWord(token) : nullptr;
+        if (entry) {
+            interpretation.kind = entry->kind;
+            interpretation.fileTypes.assign(entry->fileTypes.cbegin(), entry->fileTypes.cend());
+            if (!entry->term.isEmpty()) {
+                interpretation.query = entry->term;
+            }
+        } else if (!fillers().contains(token)) {
+            matchWords.append(token);
+        }
+    }
+    const QString matchText = matchWords.join(QLatin1Char(' '));
+    interpretation.query = QStringList{interpretation.query, matchText}
+                               .join(QLatin1Char(' '))
+                               .trimmed();
+
+    QString summary = interpretation.kind.isEmpty() ? QStringLiteral("items")
+                                                    : interpretation.kind;
+    if (!matchText.isEmpty()) {
+        summary += QStringLiteral(" matching \"%1\"").arg(matchText);
+    }
+    summary += interpretation.created ? QStringLiteral(" created ")
+                                      : QStringLiteral(" modified ");
+    summary += RelativeDates::describe(interpretation.dates, now.timeZone());
+    interpretation.summary = summary;
+    return interpretation;
+}
+
+} // namespace bs
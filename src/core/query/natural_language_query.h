@@ -0,0 +1,39 @@
+#pragma once
+
+#include "core/query/relative_dates.h"
+
+#include <QDateTime>
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// What NaturalLanguageQuery read out of a query, echoed back with the
+// results so the user can see, and undo, the interpretation.
+struct QueryInterpretation {
+    QString query;                  // the words left to match; may be empty
+    QString kind;                   // "screenshots"; empty when none was named
+    std::vector<QString> fileTypes; // the kind's extensions
+    bool created = false;           // "taken", "created": a creation date
+    DateBounds dates;
+    QString datePhrase;             // "from last tuesday", as read
+    QString summary;                // "screenshots modified 2026-10-13"
+
+    QJsonObject toJson() const;
+};
+
+// NaturalLanguageQuery -- a lightweight preprocessor for phrasings like
+// "screenshots from last Tuesday" or "photos taken in march": a date phrase
+// becomes a date range and, alongside one, a kind word becomes file types.
+// Queries without a date phrase, or with explicit `modified:`/`created:`
+// tokens, are left alone.
+class NaturalLanguageQuery {
+public:
+    static std::optional<QueryInterpretation> interpret(
+        const QString& normalizedQuery, const QDateTime& now = QDateTime::currentDateTime());
+};
+
+} // namespace bs
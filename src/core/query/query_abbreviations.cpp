@@ -0,0 +1,113 @@
+#include "core/query/query_abbreviations.h"
+
+namespace bs {
+
+namespace {
+
+bool isTermChar(QChar ch)
+{
+    return ch.isLetterOrNumber() || ch == QLatin1Char('.') || ch == QLatin1Char('_')
+           || ch == QLatin1Char('-') || ch == QLatin1Char('+');
+}
+
+} // namespace
+
+QString QueryAbbreviations::normalizeTerm(const QString& term)
+{
+    const QString normalized = term.trimmed().toLower();
+    if (normalized.isEmpty() || normalized.size() > kMaxTermLength) {
+        return QString();
+    }
+    for (const QChar ch : normalized) {
+        if (!isTermChar(ch)) {
+            return QString();
+        }
+    }
+    return normalized;
+}
+
+bool QueryAbbreviations::set(const QString& term, const QString& expansion, QString* error)
+{
+    const QString key = normalizeTerm(term);
+    if (key.isEmpty()) {
+        if (error) {
+            *error = QStringLiteral("Abbreviation must be one word of up to %1 letters, digits "
+                                    "or . _ - +").arg(kMaxTermLength);
+        }
+        return false;
+    }
+    const QString value = expansion.simplified();
+    if (value.isEmpty() || value.size() > kMaxExpansionLength) {
+        if (error) {
+            *error = QStringLiteral("Expansion must be 1 to %1 characters")
+                         .arg(kMaxExpansionLength);
+        }
+        return false;
+    }
+    if (!m_entries.contains(key) && m_entries.size() >= kMaxEntries) {
+        if (error) {
+            *error = QStringLiteral("At most %1 abbreviations can be defined").arg(kMaxEntries);
+        }
+        return false;
+    }
+    m_entries.insert(key, value);
+    return true;
+}
+
+bool QueryAbbreviations::remove(const QString& term)
+{
+    return m_entries.remove(normalizeTerm(term)) > 0;
+}
+
+QString QueryAbbreviations::expand(const QString& query, QStringList* applied) const
+{
+    if (m_entries.isEmpty()) {
+        return query;
+    }
+
+    QStringList words = query.split(QLatin1Char(' '));
+    bool changed = false;
+    for (QString& word : words) {
+        if (word.isEmpty() || word.contains(QLatin1Char(':'))) {
+            continue;
+        }
+        const QString key = word.toLower();
+        const auto it = m_entries.constFind(key);
+        if (it == m_entries.cend()) {
+            continue;
+        }
+        word = it.value();
+        changed = true;
+        if (applied && !applied->contains(key)) {
+            applied->append(key);
+        }
+    }
+    return changed ? words.join(QLatin1Char(' ')) : query;
+}
+
+QJsonObject QueryAbbreviations::toJson() const
+{
+    QJsonObject json;
+    for (auto it = m_entries.cbegin(); it != m_entries.cend(); ++it) {
+        json[it.key()] = it.value();
+    }
+    return json;
+}
+
+QueryAbbreviations QueryAbbreviations::fromJson(const QJsonObject& json, int* skipped)
+{
+    QueryAbbreviations abbreviations;
+    int invalid = 0;
+    for (auto it = json.constBegin(); it != json.constEnd(); ++it) {
+        if (!it.value().isString()
+            || !abbreviations.set(it.key(), it.value().toString(), nullptr)) {
+            ++invalid;
+        }
+    }
+    if (skipped) {
+        *skipped = invalid;
+    }
+    return abbreviations;
+}
+
+} // namespace bs
@@ -0,0 +1,54 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QMap>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// QueryAbbreviations -- user-defined shorthands ("inv" -> "invoice",
+// "k8s" -> "kubernetes") expanded in a search before it is tokenized.
+//
+// A term is one word, matched case-insensitively against whole words of the
+// normalized query; field filters (kind:pdf) and words inside a longer
+// token are left alone. Expansions are not expanded again, so a term may
+// appear in its own expansion ("js" -> "js javascript"). The query service
+// keeps the set in the profile's settings table as toJson().
+class QueryAbbreviations {
+public:
+    static constexpr int kMaxEntries = 500;
+    static constexpr int kMaxTermLength = 32;
+    static constexpr int kMaxExpansionLength = 200;
+    static constexpr const char* kSettingKey = "queryAbbreviations";
+
+    // Lowercased and trimmed; empty when `term` is not a single word of
+    // letters, digits, '.', '_', '-' or '+' within kMaxTermLength.
+    static QString normalizeTerm(const QString& term);
+
+    // False with *error when the term or expansion is invalid, or the set is
+    // full and `term` is new.
+    bool set(const QString& term, const QString& expansion, QString* error);
+    bool remove(const QString& term);
+    void clear() { m_entries.clear(); }
+
+    const QMap<QString, QString>& entries() const { return m_entries; }
+    bool isEmpty() const { return m_entries.isEmpty(); }
+    int size() const { return static_cast<int>(m_entries.size()); }
+
+    // `query` with every abbreviated word replaced; *applied gets the terms
+    // that were, once each.
+    QString expand(const QString& query, QStringList* applied = nullptr) const;
+
+    // {"inv": "invoice", ...}
+    QJsonObject toJson() const;
+    // Invalid entries are skipped; *skipped counts them.
+    static QueryAbbreviations fromJson(const QJsonObject& json, int* skipped = nullptr);
+
+private:
+    QMap<QString, QString> m_entries;  // normalized term -> expansion
+};
+
+} // namespace bs
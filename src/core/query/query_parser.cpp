@@ -1,7 +1,11 @@
 #include "core/query/query_parser.h"
+#include "core/query/relative_dates.h"
 
 #include <QSet>
 
+#include <algorithm>
+#include <optional>
+
 namespace bs {
 
 namespace {
@@ -51,9 +55,85 @@ const QSet<QString>& knownTypeTokens()
     return kKnownTypes;
 }
 
+void intersectBounds(const DateBounds& bounds, std::optional<double>& after,
+                     std::optional<double>& before)
+{
+    if (bounds.after.has_value()) {
+        after = std::max(bounds.after.value(), after.value_or(bounds.after.value()));
+    }
+    if (bounds.before.has_value()) {
+        before = std::min(bounds.before.value(), before.value_or(bounds.before.value()));
+    }
+}
+
+// Moves `modified:`/`created:`/`due:` tokens into the filters' date
+// bounds. The normalizer has dropped the quotes around a phrase, so a
+// token's value takes up to two of the following words, longest reading
+// first.
+void extractDateFilters(QStringList& tokens, const QDateTime& now, ParsedQuery& parsed)
+{
+    struct DateField {
+        QString prefix;
+        std::optional<double>* after;
+        std::optional<double>* before;
+        bool ahead;  // read with RelativeDates::resolveDue
+    };
+    const DateField fields[] = {
+        {QStringLiteral("modified:"), &parsed.filters.modifiedAfter,
+         &parsed.filters.modifiedBefore, false},
+        {QStringLiteral("created:"), &parsed.filters.createdAfter,
+         &parsed.filters.createdBefore, false},
+        {QStringLiteral("due:"), &parsed.filters.dueAfter, &parsed.filters.dueBefore,
+         true},
+    };
+    for (int i = 0; i < tokens.size();) {
+        const QString token = tokens.at(i);
+        const DateField* field = nullptr;
+        for (const DateField& candidate : fields) {
+            if (token.startsWith(candidate.prefix, Qt::CaseInsensitive)
+                && token.size() > candidate.prefix.size()) {
+                field = &candidate;
+                break;
+            }
+        }
+        if (!field) {
+            ++i;
+            continue;
+        }
+        const QString& prefix = field->prefix;
+
+        const QString value = token.mid(prefix.size()).toLower().remove(QLatin1Char('"'));
+        std::optional<DateBounds> bounds;
+        int consumed = 1;
+        for (int extra = std::min<int>(2, tokens.size() - i - 1); extra >= 0; --extra) {
+            QStringList words{value};
+            for (int k = 1; k <= extra; ++k) {
+                words.append(tokens.at(i + k).toLower().remove(QLatin1Char('"')));
+            }
+            const QString phrase = words.join(QLatin1Char(' '));
+            bounds = field->ahead ? RelativeDates::resolveDue(phrase, now)
+                                  : RelativeDates::resolve(phrase, now);
+            if (bounds.has_value()) {
+                consumed = extra + 1;
+                break;
+            }
+        }
+        if (!bounds.has_value()) {
+            if (parsed.dateError.isEmpty()) {
+                parsed.dateError =
+                    QStringLiteral("Unrecognized date '%1' in %2").arg(value, prefix);
+            }
+        } else {
+            intersectBounds(bounds.value(), *field->after, *field->before);
+        }
+        parsed.hasDateFilter = true;
+        tokens.erase(tokens.begin() + i, tokens.begin() + i + consumed);
+    }
+}
+
 } // namespace
 
-ParsedQuery QueryParser::parse(const QString& normalizedQuery)
+ParsedQuery QueryParser::parse(const QString& normalizedQuery, const QDateTime& now)
 {
     ParsedQuery parsed;
     parsed.cleanedQuery = normalizedQuery.trimmed();
@@ -62,6 +142,57 @@ ParsedQuery QueryParser::parse(const QString& normalizedQuery)
     }
 
     QStringList tokens = parsed.cleanedQuery.split(QChar(' '), Qt::SkipEmptyParts);
+
+    // `tag:name` tokens anywhere in the query become tag filters.
+    const QString tagPrefix = QStringLiteral("tag:");
+    for (auto it = tokens.begin(); it != tokens.end();) {
+        if (!it->startsWith(tagPrefix, Qt::CaseInsensitive) || it->size() == tagPrefix.size()) {
+            ++it;
+            continue;
+        }
+        const QString tag = it->mid(tagPrefix.size());
+        const bool alreadyPresent = std::any_of(
+            parsed.filters.tags.begin(), parsed.filters.tags.end(),
+            [&](const QString& existing) {
+                return existing.compare(tag, Qt::CaseInsensitive) == 0;
+            });
+        if (!alreadyPresent) {
+            parsed.filters.tags.push_back(tag);
+        }
+        parsed.hasTagFilter = true;
+        it = tokens.erase(it);
+    }
+    // `scope:id` names a search scope the service resolves.
+    const QString scopePrefix = QStringLiteral("scope:");
+    bool hasScope = false;
+    for (auto it = tokens.begin(); it != tokens.end();) {
+        if (!it->startsWith(scopePrefix, Qt::CaseInsensitive) || it->size() == scopePrefix.size()) {
+            ++it;
+            continue;
+        }
+        parsed.scope = it->mid(scopePrefix.size()).toLower();
+        hasScope = true;
+        it = tokens.erase(it);
+    }
+    // `kind:reminder` keeps results to one kind of item; the service maps
+    // the names.
+    const QString kindPrefix = QStringLiteral("kind:");
+    for (auto it = tokens.begin(); it != tokens.end();) {
+        if (!it->startsWith(kindPrefix, Qt::CaseInsensitive) || it->size() == kindPrefix.size()) {
+            ++it;
+            continue;
+        }
+        const QString kind = it->mid(kindPrefix.size()).toLower();
+        if (!parsed.kinds.contains(kind)) {
+            parsed.kinds.append(kind);
+        }
+        parsed.hasKindFilter = true;
+        it = tokens.erase(it);
+    }
+    extractDateFilters(tokens, now, parsed);
+    if (parsed.hasTagFilter || hasScope || parsed.hasDateFilter || parsed.hasKindFilter) {
+        parsed.cleanedQuery = tokens.join(QChar(' ')).trimmed();
+    }
     if (tokens.isEmpty()) {
         return parsed;
     }
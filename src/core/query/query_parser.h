@@ -2,6 +2,7 @@
 
 #include "core/shared/search_options.h"
 
+#include <QDateTime>
 #include <QString>
 #include <QStringList>
 
@@ -12,11 +13,20 @@ struct ParsedQuery {
     SearchOptions filters;
     QStringList extractedTypes;
     bool hasTypeHint = false;
+    bool hasTagFilter = false;   // `tag:` tokens were moved into filters.tags
+    QString scope;               // from a `scope:` token, the last one if several
+    bool hasDateFilter = false;  // `modified:`/`created:`/`due:` tokens set filters' date bounds
+    QString dateError;           // set when a date token could not be read
+    QStringList kinds;           // from `kind:` tokens, lowercased ("reminder", "mail")
+    bool hasKindFilter = false;
 };
 
 class QueryParser {
 public:
-    static ParsedQuery parse(const QString& normalizedQuery);
+    // Relative dates ("today", "last week") resolve against `now`, in its
+    // time zone.
+    static ParsedQuery parse(const QString& normalizedQuery,
+                             const QDateTime& now = QDateTime::currentDateTime());
 };
 
 } // namespace bs
@@ -0,0 +1,178 @@
+#include "core/query/raycast_view.h"
+
+#include <QFileInfo>
+#include <QHash>
+#include <QStringList>
+
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+QString abbreviateHome(const QString& path, const QString& homeDir)
+{
+    if (!homeDir.isEmpty()
+        && (path == homeDir || path.startsWith(homeDir + QLatin1Char('/')))) {
+        return QLatin1Char('~') + path.mid(homeDir.size());
+    }
+    return path;
+}
+
+QJsonObject shortcut(const QStringList& modifiers, const QString& key)
+{
+    QJsonObject value;
+    value[QStringLiteral("modifiers")] = QJsonArray::fromStringList(modifiers);
+    value[QStringLiteral("key")] = key;
+    return value;
+}
+
+QJsonObject action(const QString& id, const QString& title, const QJsonObject& keys,
+                   qint64 itemId, const QString& query, int position)
+{
+    QJsonObject body;
+    body[QStringLiteral("action")] = id;
+    body[QStringLiteral("itemId")] = itemId;
+    body[QStringLiteral("query")] = query;
+    body[QStringLiteral("position")] = position;
+
+    QJsonObject request;
+    request[QStringLiteral("method")] = QStringLiteral("POST");
+    request[QStringLiteral("path")] = QStringLiteral("/v1/actions");
+    request[QStringLiteral("body")] = body;
+
+    QJsonObject entry;
+    entry[QStringLiteral("id")] = id;
+    entry[QStringLiteral("title")] = title;
+    if (!keys.isEmpty()) {
+        entry[QStringLiteral("shortcut")] = keys;
+    }
+    entry[QStringLiteral("request")] = request;
+    return entry;
+}
+
+QJsonObject section(const QString& id, const QJsonArray& items)
+{
+    QJsonObject value;
+    value[QStringLiteral("id")] = id;
+    value[QStringLiteral("title")] = RaycastView::sectionTitle(id);
+    value[QStringLiteral("subtitle")] = QString::number(items.size());
+    value[QStringLiteral("items")] = items;
+    return value;
+}
+
+} // namespace
+
+QJsonArray RaycastView::sections(const QJsonArray& results, const QString& query,
+                                 const QString& homeDir)
+{
+    QJsonArray out;
+    if (results.isEmpty()) {
+        return out;
+    }
+    out.append(section(QStringLiteral("topHit"),
+                       QJsonArray{item(results.at(0).toObject(), query, 0, homeDir)}));
+
+    // Kind sections in the order their best hit ranks.
+    std::vector<QString> order;
+    QHash<QString, QJsonArray> byKind;
+    for (int i = 1; i < results.size(); ++i) {
+        const QJsonObject hit = results.at(i).toObject();
+        const QString id = sectionId(hit.value(QStringLiteral("kind")).toString());
+        if (!byKind.contains(id)) {
+            order.push_back(id);
+        }
+        byKind[id].append(item(hit, query, i, homeDir));
+    }
+    for (const QString& id : order) {
+        out.append(section(id, byKind.value(id)));
+    }
+    return out;
+}
+
+QJsonObject RaycastView::item(const QJsonObject& hit, const QString& query, int position,
+                              const QString& homeDir)
+{
+    const qint64 itemId = hit.value(QStringLiteral("itemId")).toInteger();
+    const QString path = hit.value(QStringLiteral("path")).toString();
+    const QString name = hit.value(QStringLiteral("name")).toString();
+
+    QJsonObject icon;
+    icon[QStringLiteral("fileIcon")] = path;
+
+    QJsonArray accessories;
+    for (const QJsonValue& tag : hit.value(QStringLiteral("tags")).toArray()) {
+        QJsonObject accessory;
+        accessory[QStringLiteral("tag")] = tag.toString();
+        accessories.append(accessory);
+    }
+    const QString modified = hit.value(QStringLiteral("metadata")).toObject()
+                                 .value(QStringLiteral("modificationDate")).toString();
+    if (!modified.isEmpty()) {
+        QJsonObject accessory;
+        accessory[QStringLiteral("date")] = modified;
+        accessories.append(accessory);
+    }
+
+    QJsonObject quickLook;
+    quickLook[QStringLiteral("path")] = path;
+    quickLook[QStringLiteral("name")] = name;
+
+    const QStringList cmd = {QStringLiteral("cmd")};
+    QJsonArray actions;
+    actions.append(action(QStringLiteral("open"), QStringLiteral("Open"), {}, itemId, query,
+                          position));
+    actions.append(action(QStringLiteral("reveal"), QStringLiteral("Show in Finder"),
+                          shortcut(cmd, QStringLiteral("enter")), itemId, query, position));
+    actions.append(action(QStringLiteral("quicklook"), QStringLiteral("Quick Look"),
+                          shortcut(cmd, QStringLiteral("y")), itemId, query, position));
+    actions.append(action(QStringLiteral("copyPath"), QStringLiteral("Copy Path"),
+                          shortcut({QStringLiteral("cmd"), QStringLiteral("shift")},
+                                   QStringLiteral("c")),
+                          itemId, query, position));
+
+    QJsonObject out;
+    out[QStringLiteral("id")] = QString::number(itemId);
+    out[QStringLiteral("itemId")] = itemId;
+    out[QStringLiteral("title")] = name;
+    out[QStringLiteral("subtitle")] = abbreviateHome(QFileInfo(path).path(), homeDir);
+    out[QStringLiteral("path")] = path;
+    out[QStringLiteral("icon")] = icon;
+    out[QStringLiteral("thumbnail")] =
+        QStringLiteral("/v1/documents/%1/thumbnail").arg(itemId);
+    out[QStringLiteral("accessories")] = accessories;
+    out[QStringLiteral("quickLook")] = quickLook;
+    const QString snippet = hit.value(QStringLiteral("snippet")).toString().simplified();
+    if (!snippet.isEmpty()) {
+        out[QStringLiteral("snippet")] = snippet;
+    }
+    out[QStringLiteral("actions")] = actions;
+    return out;
+}
+
+QString RaycastView::sectionId(const QString& kind)
+{
+    static const QStringList kSectionKinds = {
+        QStringLiteral("directory"), QStringLiteral("pdf"), QStringLiteral("markdown"),
+        QStringLiteral("text"), QStringLiteral("code"), QStringLiteral("image"),
+        QStringLiteral("archive"),
+    };
+    return kSectionKinds.contains(kind) ? kind : QStringLiteral("other");
+}
+
+QString RaycastView::sectionTitle(const QString& sectionId)
+{
+    static const QHash<QString, QString> kTitles = {
+        {QStringLiteral("topHit"), QStringLiteral("Top Hit")},
+        {QStringLiteral("directory"), QStringLiteral("Folders")},
+        {QStringLiteral("pdf"), QStringLiteral("PDF Documents")},
+        {QStringLiteral("markdown"), QStringLiteral("Markdown")},
+        {QStringLiteral("text"), QStringLiteral("Text Documents")},
+        {QStringLiteral("code"), QStringLiteral("Source Code")},
+        {QStringLiteral("image"), QStringLiteral("Images")},
+        {QStringLiteral("archive"), QStringLiteral("Archives")},
+    };
+    return kTitles.value(sectionId, QStringLiteral("Other"));
+}
+
+} // namespace bs
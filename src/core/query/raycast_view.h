@@ -0,0 +1,32 @@
+#pragma once
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+namespace bs {
+
+// RaycastView — shapes `search` results for the Raycast extension
+// (GET /v1/raycast/search): a "Top Hit" section, then one section per kind
+// in rank order, with List.Item fields and ActionPanel entries ready to map
+// one-to-one. Actions are requests back to the daemon (POST /v1/actions), so
+// opens are performed and counted the same way as from the app.
+//
+// The wire shape is docs/foundation/schemas/raycast-search.schema.json.
+class RaycastView {
+public:
+    // Sections for results in rank order; empty for no results.
+    static QJsonArray sections(const QJsonArray& results, const QString& query,
+                               const QString& homeDir);
+
+    // One list item. position is the hit's rank in the whole result list.
+    static QJsonObject item(const QJsonObject& hit, const QString& query, int position,
+                            const QString& homeDir);
+
+    // Section id and title for a result kind ("pdf" -> "PDF Documents");
+    // binary and unknown kinds share "other".
+    static QString sectionId(const QString& kind);
+    static QString sectionTitle(const QString& sectionId);
+};
+
+} // namespace bs
@@ -0,0 +1,260 @@
+#include "core/query/relative_dates.h"
+
+#include <QRegularExpression>
+
+namespace bs {
+
+namespace {
+
+constexpr const char* kWeekdays[] = {
+    "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday",
+};
+
+constexpr const char* kMonths[] = {
+    "january", "february", "march",     "april",   "may",      "june",
+    "july",    "august",   "september", "october", "november", "december",
+};
+
+double startOfDay(QDate date, const QTimeZone& zone)
+{
+    return static_cast<double>(QDateTime(date, QTime(0, 0), zone).toSecsSinceEpoch());
+}
+
+// The last millisecond before `next` starts; the date filters are inclusive.
+double endBefore(QDate next, const QTimeZone& zone)
+{
+    return static_cast<double>(QDateTime(next, QTime(0, 0), zone).toMSecsSinceEpoch() - 1)
+           / 1000.0;
+}
+
+DateBounds dayBounds(QDate day, const QTimeZone& zone)
+{
+    return {startOfDay(day, zone), endBefore(day.addDays(1), zone)};
+}
+
+DateBounds monthBounds(int year, int month, const QTimeZone& zone)
+{
+    const QDate first(year, month, 1);
+    return {startOfDay(first, zone), endBefore(first.addMonths(1), zone)};
+}
+
+// 1-based; 0 when `name` is not one.
+int indexOf(const char* const* names, int count, const QString& name)
+{
+    for (int i = 0; i < count; ++i) {
+        if (name == QLatin1String(names[i])) {
+            return i + 1;
+        }
+    }
+    return 0;
+}
+
+} // namespace
+
+std::optional<DateBounds> RelativeDates::resolve(const QString& phrase, const QDateTime& now)
+{
+    const QTimeZone zone = now.timeZone();
+    const QDate today = now.date();
+    const QDate weekStart = today.addDays(1 - today.dayOfWeek());
+    const QDate monthStart(today.year(), today.month(), 1);
+    const QDate yearStart(today.year(), 1, 1);
+
+    if (phrase == QLatin1String("today")) {
+        return DateBounds{startOfDay(today, zone), std::nullopt};
+    }
+    if (phrase == QLatin1String("yesterday")) {
+        return dayBounds(today.addDays(-1), zone);
+    }
+    if (phrase == QLatin1String("this week")) {
+        return DateBounds{startOfDay(weekStart, zone), std::nullopt};
+    }
+    if (phrase == QLatin1String("this month")) {
+        return DateBounds{startOfDay(monthStart, zone), std::nullopt};
+    }
+    if (phrase == QLatin1String("this year")) {
+        return DateBounds{startOfDay(yearStart, zone), std::nullopt};
+    }
+    if (phrase == QLatin1String("last week")) {
+        return DateBounds{startOfDay(weekStart.addDays(-7), zone), endBefore(weekStart, zone)};
+    }
+    if (phrase == QLatin1String("last month")) {
+        return DateBounds{startOfDay(monthStart.addMonths(-1), zone),
+                          endBefore(monthStart, zone)};
+    }
+    if (phrase == QLatin1String("last year")) {
+        return DateBounds{startOfDay(yearStart.addYears(-1), zone), endBefore(yearStart, zone)};
+    }
+
+    // "tuesday" and "last tuesday": the most recent one before today.
+    {
+        QString name = phrase;
+        if (name.startsWith(QLatin1String("last "))) {
+            name = name.mid(5);
+        }
+        const int weekday = indexOf(kWeekdays, 7, name);
+        if (weekday > 0) {
+            int back = today.dayOfWeek() - weekday;
+            if (back <= 0) {
+                back += 7;
+            }
+            return dayBounds(today.addDays(-back), zone);
+        }
+    }
+
+    // "march": the most recent March, this year's if it has begun.
+    // "march 2025": that one.
+    {
+        static const QRegularExpression kMonthPattern(QStringLiteral("^([a-z]+)(?: (\\d{4}))?$"));
+        const QRegularExpressionMatch match = kMonthPattern.match(phrase);
+        const int month = match.hasMatch() ? indexOf(kMonths, 12, match.captured(1)) : 0;
+        if (month > 0) {
+            int year = today.year();
+            if (!match.captured(2).isEmpty()) {
+                year = match.captured(2).toInt();
+            } else if (month > today.month()) {
+                --year;
+            }
+            return monthBounds(year, month, zone);
+        }
+    }
+
+    static const QRegularExpression kRollingPattern(
+        QStringLiteral("^(?:past|last)(?: (\\d{1,4}))? (day|week|month|year)s?$"));
+    const QRegularExpressionMatch rolling = kRollingPattern.match(phrase);
+    if (rolling.hasMatch()) {
+        // "last week" is the calendar week (above); "last 1 week" is rolling.
+        if (rolling.captured(1).isEmpty() && phrase.startsWith(QLatin1String("last"))) {
+            return std::nullopt;
+        }
+        const int count = rolling.captured(1).isEmpty() ? 1 : rolling.captured(1).toInt();
+        const QString unit = rolling.captured(2);
+        QDateTime start = now;
+        if (unit == QLatin1String("day")) {
+            start = now.addDays(-count);
+        } else if (unit == QLatin1String("week")) {
+            start = now.addDays(-7 * count);
+        } else if (unit == QLatin1String("month")) {
+            start = now.addMonths(-count);
+        } else {
+            start = now.addYears(-count);
+        }
+        return DateBounds{static_cast<double>(start.toSecsSinceEpoch()), std::nullopt};
+    }
+
+    // "3 days ago": that day; "2 weeks ago": that calendar week, and so on.
+    static const QRegularExpression kAgoPattern(
+        QStringLiteral("^(\\d{1,4}) (day|week|month|year)s? ago$"));
+    const QRegularExpressionMatch ago = kAgoPattern.match(phrase);
+    if (ago.hasMatch()) {
+        const int count = ago.captured(1).toInt();
+        const QString unit = ago.captured(2);
+        if (unit == QLatin1String("day")) {
+            return dayBounds(today.addDays(-count), zone);
+        }
+        if (unit == QLatin1String("week")) {
+            const QDate start = weekStart.addDays(-7 * count);
+            return DateBounds{startOfDay(start, zone), endBefore(start.addDays(7), zone)};
+        }
+        if (unit == QLatin1String("month")) {
+            const QDate start = monthStart.addMonths(-count);
+            return monthBounds(start.year(), start.month(), zone);
+        }
+        const QDate start = yearStart.addYears(-count);
+        return DateBounds{startOfDay(start, zone), endBefore(start.addYears(1), zone)};
+    }
+
+    static const QRegularExpression kIsoPattern(
+        QStringLiteral("^(\\d{4})(?:-(\\d{2})(?:-(\\d{2}))?)?$"));
+    const QRegularExpressionMatch iso = kIsoPattern.match(phrase);
+    if (iso.hasMatch()) {
+        const int year = iso.captured(1).toInt();
+        if (iso.captured(2).isEmpty()) {
+            const QDate first(year, 1, 1);
+            return DateBounds{startOfDay(first, zone), endBefore(first.addYears(1), zone)};
+        }
+        const int month = iso.captured(2).toInt();
+        if (month < 1 || month > 12) {
+            return std::nullopt;
+        }
+        if (iso.captured(3).isEmpty()) {
+            return monthBounds(year, month, zone);
+        }
+        const QDate day(year, month, iso.captured(3).toInt());
+        if (!day.isValid()) {
+            return std::nullopt;
+        }
+        return dayBounds(day, zone);
+    }
+    return std::nullopt;
+}
+
+std::optional<DateBounds> RelativeDates::resolveDue(const QString& phrase,
+                                                    const QDateTime& now)
+{
+    const QTimeZone zone = now.timeZone();
+    const QDate today = now.date();
+    const QDate weekStart = today.addDays(1 - today.dayOfWeek());
+    const QDate monthStart(today.year(), today.month(), 1);
+    const QDate yearStart(today.year(), 1, 1);
+
+    if (phrase == QLatin1String("overdue")) {
+        return DateBounds{std::nullopt, static_cast<double>(now.toMSecsSinceEpoch() - 1) / 1000.0};
+    }
+    if (phrase == QLatin1String("today")) {
+        return dayBounds(today, zone);
+    }
+    if (phrase == QLatin1String("tomorrow")) {
+        return dayBounds(today.addDays(1), zone);
+    }
+    if (phrase == QLatin1String("this week")) {
+        return DateBounds{startOfDay(weekStart, zone), endBefore(weekStart.addDays(7), zone)};
+    }
+    if (phrase == QLatin1String("next week")) {
+        return DateBounds{startOfDay(weekStart.addDays(7), zone),
+                          endBefore(weekStart.addDays(14), zone)};
+    }
+    if (phrase == QLatin1String("this month")) {
+        return monthBounds(monthStart.year(), monthStart.month(), zone);
+    }
+    if (phrase == QLatin1String("next month")) {
+        const QDate next = monthStart.addMonths(1);
+        return monthBounds(next.year(), next.month(), zone);
+    }
+    if (phrase == QLatin1String("this year")) {
+        return DateBounds{startOfDay(yearStart, zone), endBefore(yearStart.addYears(1), zone)};
+    }
+
+    static const QRegularExpression kAheadPattern(
+        QStringLiteral("^next (\\d{1,4}) (day|week)s?$"));
+    const QRegularExpressionMatch ahead = kAheadPattern.match(phrase);
+    if (ahead.hasMatch()) {
+        const int days = ahead.captured(1).toInt()
+                         * (ahead.captured(2) == QLatin1String("week") ? 7 : 1);
+        return DateBounds{static_cast<double>(now.toSecsSinceEpoch()),
+                          static_cast<double>(now.addDays(days).toSecsSinceEpoch())};
+    }
+    return resolve(phrase, now);
+}
+
+QString RelativeDates::describe(const DateBounds& bounds, const QTimeZone& zone)
+{
+    const auto dateOf = [&](double epoch) {
+        return QDateTime::fromMSecsSinceEpoch(static_cast<qint64>(epoch * 1000.0), zone)
+            .date()
+            .toString(Qt::ISODate);
+    };
+    if (bounds.after.has_value() && bounds.before.has_value()) {
+        const QString first = dateOf(bounds.after.value());
+        const QString last = dateOf(bounds.before.value());
+        return first == last ? first : QStringLiteral("%1 to %2").arg(first, last);
+    }
+    if (bounds.after.has_value()) {
+        return QStringLiteral("since %1").arg(dateOf(bounds.after.value()));
+    }
+    if (bounds.before.has_value()) {
+        return QStringLiteral("until %1").arg(dateOf(bounds.before.value()));
+    }
+    return QString();
+}
+
+} // namespace bs
@@ -0,0 +1,37 @@
+#pragma once
+
+#include <QDateTime>
+#include <QString>
+#include <QTimeZone>
+
+#include <optional>
+
+namespace bs {
+
+// Epoch-second bounds of a date phrase; both inclusive, either may be open.
+struct DateBounds {
+    std::optional<double> after;
+    std::optional<double> before;
+};
+
+// RelativeDates -- reads the date phrases `modified:`/`due:` tokens and
+// natural language queries use into bounds, in the time zone of `now`.
+class RelativeDates {
+public:
+    // `phrase` is lowercase with single spaces: "today", "yesterday",
+    // "this|last week|month|year", "past week", "past|last 3 days|weeks|
+    // months|years", "3 days ago", "tuesday", "last tuesday", "march",
+    // "march 2025", "2025", "2025-03" or "2025-03-14". Nullopt otherwise.
+    static std::optional<DateBounds> resolve(const QString& phrase, const QDateTime& now);
+
+    // The same for `due:`, which looks ahead: "today", "tomorrow" and
+    // "this week|month|year" are the whole period, "next week|month",
+    // "next 3 days|weeks" run from now, and "overdue" is everything before
+    // now. Anything else reads as resolve() does.
+    static std::optional<DateBounds> resolveDue(const QString& phrase, const QDateTime& now);
+
+    // "2026-10-13", "2026-10-05 to 2026-10-11", "since 2026-10-01".
+    static QString describe(const DateBounds& bounds, const QTimeZone& zone);
+};
+
+} // namespace bs
@@ -0,0 +1,225 @@
+#include "core/query/search_scopes.h"
+
+#include <QDir>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxNameLength = 64;
+
+bool isUnder(const QString& path, const QString& root)
+{
+    return path == root || root == QLatin1String("/")
+           || path.startsWith(root + QLatin1Char('/'));
+}
+
+// Cleaned, absolute and unique; false with *error on a relative path.
+bool readPaths(const QJsonValue& value, const QString& key, QStringList* out, QString* error)
+{
+    for (const QJsonValue& entry : value.toArray()) {
+        const QString raw = entry.toString().trimmed();
+        if (!QDir::isAbsolutePath(raw)) {
+            *error = QStringLiteral("'%1' entries must be absolute paths: '%2'").arg(key, raw);
+            return false;
+        }
+        const QString cleaned = QDir::cleanPath(raw);
+        if (!out->contains(cleaned)) {
+            out->append(cleaned);
+        }
+    }
+    return true;
+}
+
+} // namespace
+
+bool SearchScope::coversPath(const QString& path) const
+{
+    const QString cleaned = QDir::cleanPath(path);
+    return std::any_of(roots.cbegin(), roots.cend(),
+                       [&](const QString& root) { return isUnder(cleaned, root); });
+}
+
+bool SearchScope::narrow(SearchOptions& options) const
+{
+    std::vector<QString> includePaths;
+    if (options.includePaths.empty()) {
+        includePaths.assign(roots.cbegin(), roots.cend());
+    } else {
+        for (const QString& given : options.includePaths) {
+            const QString cleaned = QDir::cleanPath(given);
+            if (coversPath(cleaned)) {
+                includePaths.push_back(cleaned);
+                continue;
+            }
+            for (const QString& root : roots) {
+                if (isUnder(root, cleaned)
+                    && std::find(includePaths.begin(), includePaths.end(), root)
+                           == includePaths.end()) {
+                    includePaths.push_back(root);
+                }
+            }
+        }
+        if (includePaths.empty()) {
+            return false;
+        }
+    }
+    options.includePaths = std::move(includePaths);
+
+    for (const QString& path : excludePaths) {
+        if (std::find(options.excludePaths.begin(), options.excludePaths.end(), path)
+            == options.excludePaths.end()) {
+            options.excludePaths.push_back(path);
+        }
+    }
+    if (options.fileTypes.empty()) {
+        options.fileTypes.assign(fileTypes.cbegin(), fileTypes.cend());
+    }
+    for (const QString& tag : tags) {
+        const bool alreadyPresent = std::any_of(
+            options.tags.begin(), options.tags.end(),
+            [&](const QString& existing) { return existing.compare(tag, Qt::CaseInsensitive) == 0; });
+        if (!alreadyPresent) {
+            options.tags.push_back(tag);
+        }
+    }
+    return true;
+}
+
+QJsonObject SearchScope::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("id")] = id;
+    json[QStringLiteral("name")] = name;
+    json[QStringLiteral("roots")] = QJsonArray::fromStringList(roots);
+    json[QStringLiteral("excludePaths")] = QJsonArray::fromStringList(excludePaths);
+    json[QStringLiteral("fileTypes")] = QJsonArray::fromStringList(fileTypes);
+    json[QStringLiteral("tags")] = QJsonArray::fromStringList(tags);
+    return json;
+}
+
+std::optional<SearchScope> SearchScope::fromJson(const QJsonObject& json, QString* error)
+{
+    SearchScope scope;
+    scope.name = json.value(QStringLiteral("name")).toString().simplified();
+    if (scope.name.isEmpty() || scope.name.size() > kMaxNameLength) {
+        *error = QStringLiteral("A scope needs a name of 1 to %1 characters").arg(kMaxNameLength);
+        return std::nullopt;
+    }
+    scope.id = idForName(json.contains(QStringLiteral("id"))
+                             ? json.value(QStringLiteral("id")).toString()
+                             : scope.name);
+    if (scope.id.isEmpty()) {
+        *error = QStringLiteral("Scope id must contain letters or digits");
+        return std::nullopt;
+    }
+
+    if (!readPaths(json.value(QStringLiteral("roots")), QStringLiteral("roots"), &scope.roots,
+                   error)
+        || !readPaths(json.value(QStringLiteral("excludePaths")), QStringLiteral("excludePaths"),
+                      &scope.excludePaths, error)) {
+        return std::nullopt;
+    }
+    if (scope.roots.isEmpty() || scope.roots.size() > SearchScopes::kMaxRoots) {
+        *error = QStringLiteral("A scope needs 1 to %1 roots").arg(SearchScopes::kMaxRoots);
+        return std::nullopt;
+    }
+
+    for (const QJsonValue& value : json.value(QStringLiteral("fileTypes")).toArray()) {
+        QString type = value.toString().trimmed().toLower();
+        if (type.startsWith(QLatin1Char('.'))) {
+            type.remove(0, 1);
+        }
+        if (!type.isEmpty() && !scope.fileTypes.contains(type)) {
+            scope.fileTypes.append(type);
+        }
+    }
+    for (const QJsonValue& value : json.value(QStringLiteral("tags")).toArray()) {
+        const QString tag = value.toString().trimmed();
+        if (!tag.isEmpty() && !scope.tags.contains(tag, Qt::CaseInsensitive)) {
+            scope.tags.append(tag);
+        }
+    }
+    return scope;
+}
+
+QString SearchScope::idForName(const QString& name)
+{
+    QString id;
+    for (const QChar ch : name.toLower()) {
+        if (ch.isLetterOrNumber() || ch == QLatin1Char('-') || ch == QLatin1Char('_')) {
+            id.append(ch);
+        }
+    }
+    return id.left(kMaxNameLength);
+}
+
+bool SearchScopes::set(const SearchScope& scope, QString* error)
+{
+    const auto it = std::lower_bound(
+        m_scopes.begin(), m_scopes.end(), scope.id,
+        [](const SearchScope& existing, const QString& id) { return existing.id < id; });
+    if (it != m_scopes.end() && it->id == scope.id) {
+        *it = scope;
+        return true;
+    }
+    if (static_cast<int>(m_scopes.size()) >= kMaxScopes) {
+        *error = QStringLiteral("At most %1 scopes can be defined").arg(kMaxScopes);
+        return false;
+    }
+    m_scopes.insert(it, scope);
+    return true;
+}
+
+bool SearchScopes::remove(const QString& id)
+{
+    const QString key = SearchScope::idForName(id);
+    const auto it = std::find_if(m_scopes.begin(), m_scopes.end(),
+                                 [&](const SearchScope& scope) { return scope.id == key; });
+    if (it == m_scopes.end()) {
+        return false;
+    }
+    m_scopes.erase(it);
+    return true;
+}
+
+std::optional<SearchScope> SearchScopes::find(const QString& id) const
+{
+    const QString key = SearchScope::idForName(id);
+    for (const SearchScope& scope : m_scopes) {
+        if (scope.id == key) {
+            return scope;
+        }
+    }
+    return std::nullopt;
+}
+
+QJsonArray SearchScopes::toJson() const
+{
+    QJsonArray json;
+    for (const SearchScope& scope : m_scopes) {
+        json.append(scope.toJson());
+    }
+    return json;
+}
+
+SearchScopes SearchScopes::fromJson(const QJsonArray& json, int* skipped)
+{
+    SearchScopes scopes;
+    int invalid = 0;
+    for (const QJsonValue& value : json) {
+        QString error;
+        const auto scope = SearchScope::fromJson(value.toObject(), &error);
+        if (!scope.has_value() || !scopes.set(scope.value(), &error)) {
+            ++invalid;
+        }
+    }
+    if (skipped) {
+        *skipped = invalid;
+    }
+    return scopes;
+}
+
+} // namespace bs
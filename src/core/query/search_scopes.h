@@ -0,0 +1,67 @@
+#pragma once
+
+#include "core/shared/search_options.h"
+
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// SearchScope -- a named set of roots plus filters ("Client A" = two project
+// folders and a mail folder) that a search selects with `scope:<id>` or the
+// `scope` param instead of repeating path filters.
+struct SearchScope {
+    QString id;    // lowercase, what `scope:` matches
+    QString name;  // as given
+    QStringList roots;         // cleaned absolute paths
+    QStringList excludePaths;  // cleaned absolute paths
+    QStringList fileTypes;     // lowercase extensions, no dot
+    QStringList tags;
+
+    bool coversPath(const QString& path) const;
+
+    // Confines `options` to the scope: include paths become the scope roots,
+    // or the given ones that lie under them (a given path containing a root
+    // narrows to that root); exclusions and tags are added, and the scope's
+    // file types apply when `options` has none. False, leaving `options`
+    // unchanged, when the given include paths are all outside the roots.
+    bool narrow(SearchOptions& options) const;
+
+    QJsonObject toJson() const;
+    static std::optional<SearchScope> fromJson(const QJsonObject& json, QString* error);
+
+    // "Client A" -> "clienta": letters, digits, '-' and '_', lowercased.
+    static QString idForName(const QString& name);
+};
+
+// SearchScopes -- the defined scopes, kept by the query service in the
+// profile's settings table as toJson().
+class SearchScopes {
+public:
+    static constexpr int kMaxScopes = 100;
+    static constexpr int kMaxRoots = 32;
+    static constexpr const char* kSettingKey = "searchScopes";
+
+    // Adds or replaces the scope with the same id. False with *error when
+    // the set is full.
+    bool set(const SearchScope& scope, QString* error);
+    bool remove(const QString& id);
+    std::optional<SearchScope> find(const QString& id) const;
+
+    const std::vector<SearchScope>& scopes() const { return m_scopes; }
+    bool isEmpty() const { return m_scopes.empty(); }
+
+    QJsonArray toJson() const;
+    // Invalid entries are skipped; *skipped counts them.
+    static SearchScopes fromJson(const QJsonArray& json, int* skipped = nullptr);
+
+private:
+    std::vector<SearchScope> m_scopes;  // ordered by id
+};
+
+} // namespace bs
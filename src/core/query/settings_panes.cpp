@@ -0,0 +1,187 @@
+#include "core/query/settings_panes.h"
+
+#include "core/fs/app_catalog.h"
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// An alias scores a little below the pane's own name.
+constexpr double kKeywordWeight = 0.9;
+
+SettingsPane pane(const char* id, const char* name, const char* paneId,
+                  std::initializer_list<const char*> keywords)
+{
+    SettingsPane entry;
+    entry.id = QString::fromLatin1(id);
+    entry.name = QString::fromUtf8(name);
+    entry.url = QStringLiteral("x-apple.systempreferences:") + QString::fromLatin1(paneId);
+    for (const char* keyword : keywords) {
+        entry.keywords.append(QString::fromUtf8(keyword));
+    }
+    return entry;
+}
+
+} // namespace
+
+QJsonObject SettingsPane::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("id")] = id;
+    json[QStringLiteral("name")] = name;
+    json[QStringLiteral("url")] = url;
+    json[QStringLiteral("kind")] = QStringLiteral("settings");
+    return json;
+}
+
+const std::vector<SettingsPane>& SettingsPanes::all()
+{
+    static const std::vector<SettingsPane> kPanes = {
+        pane("wifi", "Wi-Fi", "com.apple.wifi-settings-extension",
+             {"wifi", "wireless", "wlan", "internet"}),
+        pane("bluetooth", "Bluetooth", "com.apple.BluetoothSettings",
+             {"airpods", "headphones", "pairing"}),
+        pane("network", "Network", "com.apple.Network-Settings.extension",
+             {"ethernet", "vpn", "dns", "proxy", "firewall", "ip address"}),
+        pane("notifications", "Notifications", "com.apple.Notifications-Settings.extension",
+             {"alerts", "banners", "badges"}),
+        pane("sound", "Sound", "com.apple.Sound-Settings.extension",
+             {"volume", "audio", "speakers", "microphone input", "output", "alert sound"}),
+        pane("focus", "Focus", "com.apple.Focus-Settings.extension",
+             {"do not disturb", "dnd"}),
+        pane("screen-time", "Screen Time", "com.apple.Screen-Time-Settings.extension",
+             {"parental controls", "app limits", "downtime"}),
+        pane("general", "General", "com.apple.systempreferences.GeneralSettings",
+             {"about", "about this mac"}),
+        pane("appearance", "Appearance", "com.apple.Appearance-Settings.extension",
+             {"dark mode", "light mode", "accent color", "highlight color", "scroll bars"}),
+        pane("accessibility", "Accessibility", "com.apple.Accessibility-Settings.extension",
+             {"voiceover", "zoom", "reduce motion", "contrast"}),
+        pane("control-center", "Control Center", "com.apple.ControlCenter-Settings.extension",
+             {"menu bar", "menubar"}),
+        pane("siri", "Siri & Spotlight", "com.apple.Siri-Settings.extension",
+             {"siri", "spotlight", "voice assistant"}),
+        pane("privacy", "Privacy & Security", "com.apple.settings.PrivacySecurity.extension",
+             {"privacy", "security", "permissions", "filevault", "gatekeeper"}),
+        pane("privacy-full-disk-access", "Full Disk Access (Privacy & Security)",
+             "com.apple.preference.security?Privacy_AllFiles",
+             {"full disk access", "fda", "disk access"}),
+        pane("privacy-camera", "Camera (Privacy & Security)",
+             "com.apple.preference.security?Privacy_Camera",
+             {"camera", "webcam"}),
+        pane("privacy-microphone", "Microphone (Privacy & Security)",
+             "com.apple.preference.security?Privacy_Microphone",
+             {"microphone", "mic"}),
+        pane("privacy-location", "Location Services (Privacy & Security)",
+             "com.apple.preference.security?Privacy_LocationServices",
+             {"location", "gps"}),
+        pane("privacy-screen-recording", "Screen Recording (Privacy & Security)",
+             "com.apple.preference.security?Privacy_ScreenCapture",
+             {"screen recording", "screen capture", "screen sharing permission"}),
+        pane("privacy-accessibility", "Accessibility Access (Privacy & Security)",
+             "com.apple.preference.security?Privacy_Accessibility",
+             {"accessibility access", "accessibility permission"}),
+        pane("desktop-dock", "Desktop & Dock", "com.apple.Desktop-Settings.extension",
+             {"dock", "mission control", "stage manager", "hot corners", "windows"}),
+        pane("displays", "Displays", "com.apple.Displays-Settings.extension",
+             {"display", "monitor", "screen", "resolution", "brightness", "night shift",
+              "true tone", "external display"}),
+        pane("wallpaper", "Wallpaper", "com.apple.Wallpaper-Settings.extension",
+             {"background", "desktop picture"}),
+        pane("screen-saver", "Screen Saver", "com.apple.ScreenSaver-Settings.extension",
+             {"screensaver"}),
+        pane("battery", "Battery", "com.apple.Battery-Settings.extension",
+             {"energy", "energy saver", "power", "low power mode", "charging"}),
+        pane("lock-screen", "Lock Screen", "com.apple.Lock-Screen-Settings.extension",
+             {"screen lock", "require password", "sleep"}),
+        pane("touch-id", "Touch ID & Password", "com.apple.Touch-ID-Settings.extension",
+             {"touch id", "fingerprint", "login password"}),
+        pane("users-groups", "Users & Groups", "com.apple.Users-Groups-Settings.extension",
+             {"users", "accounts", "guest user", "user account"}),
+        pane("passwords", "Passwords", "com.apple.Passwords-Settings.extension",
+             {"keychain", "passkeys"}),
+        pane("internet-accounts", "Internet Accounts",
+             "com.apple.Internet-Accounts-Settings.extension",
+             {"email accounts", "mail accounts", "google account", "exchange"}),
+        pane("game-center", "Game Center", "com.apple.Game-Center-Settings.extension", {}),
+        pane("wallet", "Wallet & Apple Pay", "com.apple.WalletSettingsExtension",
+             {"apple pay", "wallet", "cards"}),
+        pane("keyboard", "Keyboard", "com.apple.Keyboard-Settings.extension",
+             {"keyboard shortcuts", "shortcuts", "key repeat", "dictation", "input sources",
+              "text replacement"}),
+        pane("mouse", "Mouse", "com.apple.Mouse-Settings.extension",
+             {"pointer", "scroll direction", "tracking speed"}),
+        pane("trackpad", "Trackpad", "com.apple.Trackpad-Settings.extension",
+             {"gestures", "tap to click", "force click"}),
+        pane("printers", "Printers & Scanners", "com.apple.Print-Scan-Settings.extension",
+             {"printer", "scanner", "print"}),
+        pane("date-time", "Date & Time", "com.apple.Date-Time-Settings.extension",
+             {"clock", "time zone", "timezone", "date", "time"}),
+        pane("software-update", "Software Update",
+             "com.apple.Software-Update-Settings.extension",
+             {"update", "upgrade", "macos update"}),
+        pane("storage", "Storage", "com.apple.settings.Storage",
+             {"disk space", "free space", "disk usage"}),
+        pane("sharing", "Sharing", "com.apple.Sharing-Settings.extension",
+             {"airdrop", "file sharing", "screen sharing", "remote login", "ssh",
+              "computer name", "hostname", "airplay receiver"}),
+        pane("startup-disk", "Startup Disk", "com.apple.Startup-Disk-Settings.extension",
+             {"boot disk"}),
+        pane("time-machine", "Time Machine", "com.apple.Time-Machine-Settings.extension",
+             {"backup", "backups"}),
+        pane("login-items", "Login Items", "com.apple.LoginItems-Settings.extension",
+             {"startup items", "launch at login", "background items"}),
+        pane("language-region", "Language & Region",
+             "com.apple.Localization-Settings.extension",
+             {"language", "region", "locale", "translation"}),
+        pane("apple-id", "Apple Account", "com.apple.systempreferences.AppleIDSettings",
+             {"apple id", "icloud", "icloud drive", "account"}),
+        pane("family", "Family", "com.apple.Family-Settings.extension",
+             {"family sharing"}),
+    };
+    return kPanes;
+}
+
+std::vector<SettingsPanes::Match> SettingsPanes::match(const QString& query, int limit)
+{
+    std::vector<Match> matches;
+    if (limit <= 0 || query.simplified().size() < 2) {
+        return matches;
+    }
+    for (const SettingsPane& entry : all()) {
+        Match match;
+        match.pane = entry;
+        match.score = AppCatalog::nameScore(query, entry.name);
+        for (const QString& keyword : entry.keywords) {
+            const double score = AppCatalog::nameScore(query, keyword) * kKeywordWeight;
+            if (score > match.score) {
+                match.score = score;
+                match.matchedKeyword = keyword;
+            }
+        }
+        if (match.score > 0.0) {
+            matches.push_back(std::move(match));
+        }
+    }
+    std::stable_sort(matches.begin(), matches.end(), [](const Match& a, const Match& b) {
+        return a.score > b.score;
+    });
+    if (static_cast<int>(matches.size()) > limit) {
+        matches.resize(static_cast<size_t>(limit));
+    }
+    return matches;
+}
+
+const SettingsPane* SettingsPanes::find(const QString& id)
+{
+    for (const SettingsPane& entry : all()) {
+        if (entry.id == id) {
+            return &entry;
+        }
+    }
+    return nullptr;
+}
+
+} // namespace bs
@@ -0,0 +1,43 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QString>
+#include <QStringList>
+
+#include <vector>
+
+namespace bs {
+
+// A System Settings pane (or a section of one) that can be opened directly.
+struct SettingsPane {
+    QString id;           // "bluetooth", "privacy-camera"
+    QString name;         // "Bluetooth", "Camera (Privacy & Security)"
+    QString url;          // x-apple.systempreferences:<pane id>[?<anchor>]
+    QStringList keywords; // other words people type for it
+
+    QJsonObject toJson() const;
+};
+
+// SettingsPanes -- the System Settings panes of macOS 13 and later with
+// their searchable keywords, so "displays" or "bluetooth" opens the right
+// pane from the launcher. The table is curated: the panes' own search terms
+// ship in private, per-release formats.
+class SettingsPanes {
+public:
+    struct Match {
+        SettingsPane pane;
+        QString matchedKeyword;  // empty when the name matched
+        double score = 0.0;
+    };
+
+    static const std::vector<SettingsPane>& all();
+
+    // Best first, by name (AppCatalog::nameScore) or, slightly lower, by
+    // keyword. Queries shorter than two characters match nothing.
+    static std::vector<Match> match(const QString& query, int limit);
+
+    // nullptr for unknown ids.
+    static const SettingsPane* find(const QString& id);
+};
+
+} // namespace bs
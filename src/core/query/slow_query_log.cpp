@@ -0,0 +1,105 @@
+#include "core/query/slow_query_log.h"
+
+#include <algorithm>
+
+namespace bs {
+
+SearchPhaseTimer::SearchPhaseTimer()
+{
+    m_timer.start();
+}
+
+void SearchPhaseTimer::mark(const QString& phase)
+{
+    const qint64 nowNs = m_timer.nsecsElapsed();
+    QJsonObject entry;
+    entry[QStringLiteral("phase")] = phase;
+    entry[QStringLiteral("ms")] = static_cast<double>(nowNs - m_lastMarkNs) / 1e6;
+    m_phases.append(entry);
+    m_lastMarkNs = nowNs;
+}
+
+double SearchPhaseTimer::elapsedMs() const
+{
+    return static_cast<double>(m_timer.nsecsElapsed()) / 1e6;
+}
+
+QJsonArray SearchPhaseTimer::toJson() const
+{
+    return m_phases;
+}
+
+QJsonObject SlowQueryLog::Entry::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("timestamp")] = timestamp;
+    json[QStringLiteral("query")] = query;
+    json[QStringLiteral("elapsedMs")] = elapsedMs;
+    json[QStringLiteral("phases")] = phases;
+    json[QStringLiteral("plan")] = plan;
+    return json;
+}
+
+SlowQueryLog::SlowQueryLog(SlowQueryLogConfig config)
+    : m_config(config)
+{
+    m_config.maxEntries = std::max(1, m_config.maxEntries);
+}
+
+void SlowQueryLog::setThresholdMs(int thresholdMs)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    m_config.thresholdMs = std::max(0, thresholdMs);
+}
+
+int SlowQueryLog::thresholdMs() const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_config.thresholdMs;
+}
+
+bool SlowQueryLog::isSlow(double elapsedMs) const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_config.thresholdMs > 0 && elapsedMs >= m_config.thresholdMs;
+}
+
+bool SlowQueryLog::record(Entry entry)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (m_config.thresholdMs <= 0 || entry.elapsedMs < m_config.thresholdMs) {
+        return false;
+    }
+    m_entries.push_back(std::move(entry));
+    while (static_cast<int>(m_entries.size()) > m_config.maxEntries) {
+        m_entries.pop_front();
+    }
+    ++m_recorded;
+    return true;
+}
+
+std::vector<SlowQueryLog::Entry> SlowQueryLog::entries(int limit) const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    std::vector<Entry> out;
+    for (auto it = m_entries.rbegin(); it != m_entries.rend() && static_cast<int>(out.size()) < limit;
+         ++it) {
+        out.push_back(*it);
+    }
+    return out;
+}
+
+uint64_t SlowQueryLog::recordedCount() const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_recorded;
+}
+
+void SlowQueryLog::clear()
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    m_entries.clear();
+    m_recorded = 0;
+}
+
+} // namespace bs
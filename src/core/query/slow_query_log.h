@@ -0,0 +1,79 @@
+#pragma once
+
+#include <QElapsedTimer>
+#include <QJsonArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <deque>
+#include <mutex>
+#include <vector>
+
+namespace bs {
+
+// SearchPhaseTimer -- wall time per search phase, in the order they ran.
+// Each mark() closes the phase that started at the previous mark (or at
+// construction).
+class SearchPhaseTimer {
+public:
+    SearchPhaseTimer();
+
+    void mark(const QString& phase);
+    double elapsedMs() const;
+
+    // [{"phase": "parse", "ms": 0.4}, ...]
+    QJsonArray toJson() const;
+
+private:
+    QElapsedTimer m_timer;
+    qint64 m_lastMarkNs = 0;
+    QJsonArray m_phases;
+};
+
+struct SlowQueryLogConfig {
+    int thresholdMs = 250;  // 0 turns the log off
+    int maxEntries = 100;
+};
+
+// SlowQueryLog -- the most recent searches that took at least the
+// threshold, with the plan they ran and their phase timings.
+//
+// Entries hold query text, so the log lives in memory only: it is empty
+// after a restart, and callers leave private searches out.
+class SlowQueryLog {
+public:
+    struct Entry {
+        double timestamp = 0.0;  // epoch seconds
+        QString query;
+        double elapsedMs = 0.0;
+        QJsonArray phases;  // SearchPhaseTimer::toJson()
+        QJsonObject plan;
+
+        QJsonObject toJson() const;
+    };
+
+    explicit SlowQueryLog(SlowQueryLogConfig config = {});
+
+    void setThresholdMs(int thresholdMs);
+    int thresholdMs() const;
+    bool isSlow(double elapsedMs) const;
+
+    // False (and nothing kept) below the threshold. The oldest entry is
+    // dropped past maxEntries.
+    bool record(Entry entry);
+
+    // Newest first, at most `limit`.
+    std::vector<Entry> entries(int limit) const;
+    // Slow searches seen since start or the last clear(), including ones
+    // no longer kept.
+    uint64_t recordedCount() const;
+    void clear();
+
+private:
+    SlowQueryLogConfig m_config;
+    mutable std::mutex m_mutex;
+    std::deque<Entry> m_entries;  // back = newest
+    uint64_t m_recorded = 0;
+};
+
+} // namespace bs
@@ -0,0 +1,854 @@
+#include "core/query/spotlight_predicate.h"
+
+#include <QDir>
+#include <QHash>
+#include <QJsonArray>
+#include <QLocale>
+#include <QRegularExpression>
+#include <QStringList>
+#include <QTimeZone>
+
+#include <algorithm>
+#include <vector>
+
+namespace bs {
+
+namespace {
+
+struct Token {
+    enum class Type { End, Ident, String, Number, Time, LParen, RParen, Comma, Op };
+    Type type = Type::End;
+    QString text;        // identifier, string value or operator
+    QString modifiers;   // "cd" of "budget"cd or LIKE[cd]
+    double number = 0.0; // Number; epoch seconds for Time
+};
+
+struct Node {
+    enum class Type { And, Or, Not, Compare, InRange };
+    Type type = Type::Compare;
+    std::vector<Node> children;
+    QString attribute;
+    QString op;  // ==, !=, <, <=, >, >=, like, contains, beginswith, endswith
+    Token value;
+    Token upper; // InRange only
+};
+
+enum class AttributeClass { Text, Name, ContentType, Tags, Path, Date, Size, Unknown };
+
+AttributeClass attributeClass(const QString& attribute)
+{
+    static const QHash<QString, AttributeClass> kClasses = {
+        {QStringLiteral("*"), AttributeClass::Text},
+        {QStringLiteral("kMDItemTextContent"), AttributeClass::Text},
+        {QStringLiteral("kMDItemTitle"), AttributeClass::Text},
+        {QStringLiteral("kMDItemAuthors"), AttributeClass::Text},
+        {QStringLiteral("kMDItemCreator"), AttributeClass::Text},
+        {QStringLiteral("kMDItemKeywords"), AttributeClass::Text},
+        {QStringLiteral("kMDItemDescription"), AttributeClass::Text},
+        {QStringLiteral("kMDItemSubject"), AttributeClass::Text},
+        {QStringLiteral("kMDItemComment"), AttributeClass::Text},
+        {QStringLiteral("kMDItemFinderComment"), AttributeClass::Text},
+        {QStringLiteral("kMDItemWhereFroms"), AttributeClass::Text},
+        {QStringLiteral("kMDItemDisplayName"), AttributeClass::Name},
+        {QStringLiteral("kMDItemFSName"), AttributeClass::Name},
+        {QStringLiteral("kMDItemContentType"), AttributeClass::ContentType},
+        {QStringLiteral("kMDItemContentTypeTree"), AttributeClass::ContentType},
+        {QStringLiteral("kMDItemUserTags"), AttributeClass::Tags},
+        {QStringLiteral("kMDItemPath"), AttributeClass::Path},
+        {QStringLiteral("kMDItemFSContentChangeDate"), AttributeClass::Date},
+        {QStringLiteral("kMDItemContentModificationDate"), AttributeClass::Date},
+        {QStringLiteral("kMDItemFSSize"), AttributeClass::Size},
+        {QStringLiteral("kMDItemLogicalSize"), AttributeClass::Size},
+    };
+    return kClasses.value(attribute, AttributeClass::Unknown);
+}
+
+// Extensions for a content type; an empty list matches every item.
+std::optional<QStringList> extensionsForContentType(const QString& uti)
+{
+    static const QStringList kImages = {
+        QStringLiteral("png"), QStringLiteral("jpg"), QStringLiteral("jpeg"),
+        QStringLiteral("gif"), QStringLiteral("heic"), QStringLiteral("tiff"),
+        QStringLiteral("tif"), QStringLiteral("bmp"), QStringLiteral("webp"),
+    };
+    static const QStringList kSource = {
+        QStringLiteral("c"), QStringLiteral("h"), QStringLiteral("cpp"), QStringLiteral("cc"),
+        QStringLiteral("cxx"), QStringLiteral("hpp"), QStringLiteral("m"), QStringLiteral("mm"),
+        QStringLiteral("swift"), QStringLiteral("py"), QStringLiteral("rb"), QStringLiteral("go"),
+        QStringLiteral("rs"), QStringLiteral("java"), QStringLiteral("js"), QStringLiteral("ts"),
+        QStringLiteral("sh"), QStringLiteral("pl"), QStringLiteral("php"),
+    };
+    static const QStringList kArchives = {
+        QStringLiteral("zip"), QStringLiteral("tar"), QStringLiteral("gz"),
+        QStringLiteral("tgz"), QStringLiteral("bz2"), QStringLiteral("xz"), QStringLiteral("7z"),
+    };
+    static const QHash<QString, QStringList> kTypes = {
+        {QStringLiteral("public.item"), {}},
+        {QStringLiteral("public.content"), {}},
+        {QStringLiteral("public.data"), {}},
+        {QStringLiteral("com.adobe.pdf"), {QStringLiteral("pdf")}},
+        {QStringLiteral("public.text"),
+         {QStringLiteral("txt"), QStringLiteral("md"), QStringLiteral("markdown"),
+          QStringLiteral("rtf"), QStringLiteral("csv"), QStringLiteral("json"),
+          QStringLiteral("xml"), QStringLiteral("html"), QStringLiteral("htm")}},
+        {QStringLiteral("public.plain-text"), {QStringLiteral("txt")}},
+        {QStringLiteral("public.utf8-plain-text"), {QStringLiteral("txt")}},
+        {QStringLiteral("net.daringfireball.markdown"),
+         {QStringLiteral("md"), QStringLiteral("markdown")}},
+        {QStringLiteral("public.rtf"), {QStringLiteral("rtf")}},
+        {QStringLiteral("public.comma-separated-values-text"), {QStringLiteral("csv")}},
+        {QStringLiteral("public.json"), {QStringLiteral("json")}},
+        {QStringLiteral("public.xml"), {QStringLiteral("xml")}},
+        {QStringLiteral("public.html"), {QStringLiteral("html"), QStringLiteral("htm")}},
+        {QStringLiteral("public.source-code"), kSource},
+        {QStringLiteral("public.c-source"), {QStringLiteral("c")}},
+        {QStringLiteral("public.c-header"), {QStringLiteral("h")}},
+        {QStringLiteral("public.c-plus-plus-source"),
+         {QStringLiteral("cpp"), QStringLiteral("cc"), QStringLiteral("cxx")}},
+        {QStringLiteral("public.swift-source"), {QStringLiteral("swift")}},
+        {QStringLiteral("public.python-script"), {QStringLiteral("py")}},
+        {QStringLiteral("public.shell-script"), {QStringLiteral("sh")}},
+        {QStringLiteral("public.image"), kImages},
+        {QStringLiteral("public.png"), {QStringLiteral("png")}},
+        {QStringLiteral("public.jpeg"), {QStringLiteral("jpg"), QStringLiteral("jpeg")}},
+        {QStringLiteral("public.heic"), {QStringLiteral("heic")}},
+        {QStringLiteral("public.tiff"), {QStringLiteral("tiff"), QStringLiteral("tif")}},
+        {QStringLiteral("com.compuserve.gif"), {QStringLiteral("gif")}},
+        {QStringLiteral("public.archive"), kArchives},
+        {QStringLiteral("com.pkware.zip-archive"), {QStringLiteral("zip")}},
+        {QStringLiteral("public.zip-archive"), {QStringLiteral("zip")}},
+        {QStringLiteral("org.openxmlformats.wordprocessingml.document"),
+         {QStringLiteral("docx")}},
+        {QStringLiteral("com.microsoft.word.doc"), {QStringLiteral("doc")}},
+        {QStringLiteral("org.openxmlformats.spreadsheetml.sheet"), {QStringLiteral("xlsx")}},
+        {QStringLiteral("com.microsoft.excel.xls"), {QStringLiteral("xls")}},
+        {QStringLiteral("org.openxmlformats.presentationml.presentation"),
+         {QStringLiteral("pptx")}},
+        {QStringLiteral("com.microsoft.powerpoint.ppt"), {QStringLiteral("ppt")}},
+    };
+    const auto it = kTypes.constFind(uti.toLower());
+    if (it == kTypes.constEnd()) {
+        return std::nullopt;
+    }
+    return it.value();
+}
+
+class Parser {
+public:
+    Parser(const QString& input, const QDateTime& now) : m_input(input), m_now(now) {}
+
+    std::optional<Node> parse(QString* error)
+    {
+        if (!tokenize()) {
+            *error = m_error;
+            return std::nullopt;
+        }
+        std::optional<Node> root = parseOr();
+        if (root.has_value() && peek().type != Token::Type::End) {
+            fail(QStringLiteral("Unexpected '%1'").arg(peek().text));
+            root.reset();
+        }
+        if (!root.has_value()) {
+            *error = m_error;
+        }
+        return root;
+    }
+
+private:
+    bool fail(const QString& message)
+    {
+        if (m_error.isEmpty()) {
+            m_error = message;
+        }
+        return false;
+    }
+
+    bool tokenize()
+    {
+        int i = 0;
+        const int n = m_input.size();
+        while (i < n) {
+            const QChar c = m_input.at(i);
+            if (c.isSpace()) {
+                ++i;
+                continue;
+            }
+            Token token;
+            if (c == QLatin1Char('(') || c == QLatin1Char(')') || c == QLatin1Char(',')) {
+                token.type = c == QLatin1Char('(')   ? Token::Type::LParen
+                             : c == QLatin1Char(')') ? Token::Type::RParen
+                                                     : Token::Type::Comma;
+                token.text = c;
+                ++i;
+            } else if (c == QLatin1Char('"') || c == QLatin1Char('\'')) {
+                token.type = Token::Type::String;
+                ++i;
+                bool closed = false;
+                while (i < n) {
+                    const QChar ch = m_input.at(i++);
+                    if (ch == c) {
+                        closed = true;
+                        break;
+                    }
+                    if (ch == QLatin1Char('\\') && i < n) {
+                        token.text += m_input.at(i++);
+                    } else {
+                        token.text += ch;
+                    }
+                }
+                if (!closed) {
+                    return fail(QStringLiteral("Unterminated string"));
+                }
+                while (i < n && m_input.at(i).isLetter()) {
+                    token.modifiers += m_input.at(i++).toLower();
+                }
+            } else if (c == QLatin1Char('$')) {
+                int end = i + 1;
+                while (end < n && (m_input.at(end).isLetterOrNumber()
+                                   || m_input.at(end) == QLatin1Char('_')
+                                   || m_input.at(end) == QLatin1Char('.'))) {
+                    ++end;
+                }
+                const QString name = m_input.mid(i, end - i).toLower();
+                QString argument;
+                if (end < n && m_input.at(end) == QLatin1Char('(')) {
+                    const int close = m_input.indexOf(QLatin1Char(')'), end);
+                    if (close < 0) {
+                        return fail(QStringLiteral("Unterminated %1(").arg(name));
+                    }
+                    argument = m_input.mid(end + 1, close - end - 1).trimmed();
+                    end = close + 1;
+                }
+                const std::optional<double> epoch = evaluateTime(name, argument);
+                if (!epoch.has_value()) {
+                    return false;
+                }
+                token.type = Token::Type::Time;
+                token.text = m_input.mid(i, end - i);
+                token.number = epoch.value();
+                i = end;
+            } else if (c.isDigit() || ((c == QLatin1Char('-') || c == QLatin1Char('+'))
+                                       && i + 1 < n && m_input.at(i + 1).isDigit())) {
+                int end = i + 1;
+                while (end < n && (m_input.at(end).isDigit() || m_input.at(end) == QLatin1Char('.'))) {
+                    ++end;
+                }
+                token.type = Token::Type::Number;
+                token.text = m_input.mid(i, end - i);
+                bool ok = false;
+                token.number = token.text.toDouble(&ok);
+                if (!ok) {
+                    return fail(QStringLiteral("Invalid number '%1'").arg(token.text));
+                }
+                i = end;
+            } else if (c.isLetter() || c == QLatin1Char('_') || c == QLatin1Char('*')) {
+                int end = i + 1;
+                if (c != QLatin1Char('*')) {
+                    while (end < n && (m_input.at(end).isLetterOrNumber()
+                                       || m_input.at(end) == QLatin1Char('_'))) {
+                        ++end;
+                    }
+                }
+                token.type = Token::Type::Ident;
+                token.text = m_input.mid(i, end - i);
+                i = end;
+                // LIKE[cd], CONTAINS[c], ...
+                if (i < n && m_input.at(i) == QLatin1Char('[')) {
+                    const int close = m_input.indexOf(QLatin1Char(']'), i);
+                    if (close < 0) {
+                        return fail(QStringLiteral("Unterminated '['"));
+                    }
+                    token.modifiers = m_input.mid(i + 1, close - i - 1).toLower();
+                    i = close + 1;
+                }
+            } else {
+                static const QStringList kOperators = {
+                    QStringLiteral("&&"), QStringLiteral("||"), QStringLiteral("=="),
+                    QStringLiteral("!="), QStringLiteral(">="), QStringLiteral("<="),
+                    QStringLiteral("="), QStringLiteral(">"), QStringLiteral("<"),
+                    QStringLiteral("!"),
+                };
+                for (const QString& op : kOperators) {
+                    if (QStringView(m_input).mid(i).startsWith(op)) {
+                        token.type = Token::Type::Op;
+                        token.text = op;
+                        break;
+                    }
+                }
+                if (token.type != Token::Type::Op) {
+                    return fail(QStringLiteral("Unexpected character '%1'").arg(c));
+                }
+                i += static_cast<int>(token.text.size());
+            }
+            m_tokens.push_back(token);
+        }
+        m_tokens.push_back(Token{});
+        return true;
+    }
+
+    // $time.now(+-seconds), $time.today(+-days), $time.yesterday,
+    // $time.this_week / this_month / this_year(+-n), $time.iso(ISO-8601).
+    std::optional<double> evaluateTime(const QString& name, const QString& argument)
+    {
+        if (name == QLatin1String("$time.iso")) {
+            QDateTime value = QDateTime::fromString(argument, Qt::ISODate);
+            if (!value.isValid()) {
+                const QDate date = QDate::fromString(argument, Qt::ISODate);
+                value = date.isValid() ? date.startOfDay(m_now.timeZone()) : QDateTime();
+            }
+            if (!value.isValid()) {
+                fail(QStringLiteral("Invalid $time.iso date '%1'").arg(argument));
+                return std::nullopt;
+            }
+            return static_cast<double>(value.toSecsSinceEpoch());
+        }
+
+        double offset = 0.0;
+        if (!argument.isEmpty()) {
+            bool ok = false;
+            offset = argument.toDouble(&ok);
+            if (!ok) {
+                fail(QStringLiteral("Invalid %1 offset '%2'").arg(name, argument));
+                return std::nullopt;
+            }
+        }
+        const int steps = static_cast<int>(offset);
+        const QDate today = m_now.date();
+        const QTimeZone zone = m_now.timeZone();
+        if (name == QLatin1String("$time.now")) {
+            return static_cast<double>(m_now.toSecsSinceEpoch()) + offset;
+        }
+        if (name == QLatin1String("$time.today")) {
+            return static_cast<double>(today.addDays(steps).startOfDay(zone).toSecsSinceEpoch());
+        }
+        if (name == QLatin1String("$time.yesterday")) {
+            return static_cast<double>(
+                today.addDays(steps - 1).startOfDay(zone).toSecsSinceEpoch());
+        }
+        if (name == QLatin1String("$time.this_week")) {
+            const int firstDay = static_cast<int>(QLocale().firstDayOfWeek());
+            const QDate weekStart = today.addDays(-((today.dayOfWeek() - firstDay + 7) % 7));
+            return static_cast<double>(
+                weekStart.addDays(7 * steps).startOfDay(zone).toSecsSinceEpoch());
+        }
+        if (name == QLatin1String("$time.this_month")) {
+            return static_cast<double>(QDate(today.year(), today.month(), 1)
+                                           .addMonths(steps).startOfDay(zone)
+                                           .toSecsSinceEpoch());
+        }
+        if (name == QLatin1String("$time.this_year")) {
+            return static_cast<double>(
+                QDate(today.year() + steps, 1, 1).startOfDay(zone).toSecsSinceEpoch());
+        }
+        fail(QStringLiteral("Unsupported time function '%1'").arg(name));
+        return std::nullopt;
+    }
+
+    const Token& peek() const { return m_tokens.at(m_pos); }
+    Token next() { return m_tokens.at(m_pos < m_tokens.size() - 1 ? m_pos++ : m_pos); }
+
+    bool isKeyword(const Token& token, const char* keyword) const
+    {
+        return token.type == Token::Type::Ident
+               && token.text.compare(QLatin1String(keyword), Qt::CaseInsensitive) == 0;
+    }
+    bool isOp(const Token& token, const char* op) const
+    {
+        return token.type == Token::Type::Op && token.text == QLatin1String(op);
+    }
+
+    std::optional<Node> parseOr()
+    {
+        return parseChain(Node::Type::Or, "||", "OR", &Parser::parseAnd);
+    }
+    std::optional<Node> parseAnd()
+    {
+        return parseChain(Node::Type::And, "&&", "AND", &Parser::parseUnary);
+    }
+
+    std::optional<Node> parseChain(Node::Type type, const char* op, const char* keyword,
+                                   std::optional<Node> (Parser::*operand)())
+    {
+        std::optional<Node> first = (this->*operand)();
+        if (!first.has_value()) {
+            return std::nullopt;
+        }
+        if (!isOp(peek(), op) && !isKeyword(peek(), keyword)) {
+            return first;
+        }
+        Node chain;
+        chain.type = type;
+        chain.children.push_back(std::move(first.value()));
+        while (isOp(peek(), op) || isKeyword(peek(), keyword)) {
+            next();
+            std::optional<Node> child = (this->*operand)();
+            if (!child.has_value()) {
+                return std::nullopt;
+            }
+            chain.children.push_back(std::move(child.value()));
+        }
+        return chain;
+    }
+
+    std::optional<Node> parseUnary()
+    {
+        if (isOp(peek(), "!") || isKeyword(peek(), "NOT")) {
+            next();
+            std::optional<Node> child = parseUnary();
+            if (!child.has_value()) {
+                return std::nullopt;
+            }
+            Node negation;
+            negation.type = Node::Type::Not;
+            negation.children.push_back(std::move(child.value()));
+            return negation;
+        }
+        if (peek().type == Token::Type::LParen) {
+            next();
+            std::optional<Node> inner = parseOr();
+            if (inner.has_value() && next().type != Token::Type::RParen) {
+                fail(QStringLiteral("Expected ')'"));
+                return std::nullopt;
+            }
+            return inner;
+        }
+        if (isKeyword(peek(), "InRange")) {
+            next();
+            Node range;
+            range.type = Node::Type::InRange;
+            if (next().type != Token::Type::LParen) {
+                fail(QStringLiteral("Expected '(' after InRange"));
+                return std::nullopt;
+            }
+            const Token attribute = next();
+            const Token comma1 = next();
+            range.value = next();
+            const Token comma2 = next();
+            range.upper = next();
+            if (attribute.type != Token::Type::Ident || comma1.type != Token::Type::Comma
+                || comma2.type != Token::Type::Comma || next().type != Token::Type::RParen) {
+                fail(QStringLiteral("Expected InRange(attribute, lower, upper)"));
+                return std::nullopt;
+            }
+            range.attribute = attribute.text;
+            return range;
+        }
+
+        const Token attribute = next();
+        if (attribute.type != Token::Type::Ident) {
+            fail(attribute.type == Token::Type::End
+                     ? QStringLiteral("Unexpected end of predicate")
+                     : QStringLiteral("Expected an attribute name, got '%1'").arg(attribute.text));
+            return std::nullopt;
+        }
+        const Token op = next();
+        static const QStringList kWordOperators = {
+            QStringLiteral("like"), QStringLiteral("contains"), QStringLiteral("beginswith"),
+            QStringLiteral("endswith"),
+        };
+        Node compare;
+        compare.attribute = attribute.text;
+        if (op.type == Token::Type::Op && op.text != QLatin1String("!")
+            && op.text != QLatin1String("&&") && op.text != QLatin1String("||")) {
+            compare.op = op.text == QLatin1String("=") ? QStringLiteral("==") : op.text;
+        } else if (op.type == Token::Type::Ident && kWordOperators.contains(op.text.toLower())) {
+            compare.op = op.text.toLower();
+        } else {
+            fail(QStringLiteral("Expected a comparison after %1").arg(attribute.text));
+            return std::nullopt;
+        }
+        compare.value = next();
+        if (compare.value.type != Token::Type::String && compare.value.type != Token::Type::Number
+            && compare.value.type != Token::Type::Time) {
+            fail(QStringLiteral("Expected a value after %1 %2").arg(attribute.text, op.text));
+            return std::nullopt;
+        }
+        return compare;
+    }
+
+    QString m_input;
+    QDateTime m_now;
+    std::vector<Token> m_tokens;
+    size_t m_pos = 0;
+    QString m_error;
+};
+
+struct Translation {
+    QStringList terms;
+    std::optional<QStringList> fileTypes;
+    QStringList tags;
+    QStringList includePaths;
+    QStringList excludePaths;
+    std::optional<double> modifiedAfter;
+    std::optional<double> modifiedBefore;
+    std::optional<qint64> minSize;
+    std::optional<qint64> maxSize;
+    QString error;
+
+    bool fail(const QString& message)
+    {
+        error = message;
+        return false;
+    }
+};
+
+bool isTextOperator(const QString& op)
+{
+    return op == QLatin1String("==") || op == QLatin1String("like")
+           || op == QLatin1String("contains") || op == QLatin1String("beginswith")
+           || op == QLatin1String("endswith");
+}
+
+// Extensions a clause restricts to: content types, and name patterns like
+// "*.pdf" or ENDSWITH ".pdf". Nullopt when the clause is something else.
+std::optional<QStringList> clauseExtensions(const Node& node)
+{
+    if (node.type != Node::Type::Compare || node.value.type != Token::Type::String) {
+        return std::nullopt;
+    }
+    const AttributeClass cls = attributeClass(node.attribute);
+    if (cls == AttributeClass::ContentType && node.op == QLatin1String("==")) {
+        return extensionsForContentType(node.value.text);
+    }
+    if (cls == AttributeClass::Name) {
+        static const QRegularExpression kExtensionPattern(
+            QStringLiteral("^\\*?\\.([A-Za-z0-9]{1,10})$"));
+        const QRegularExpressionMatch match = kExtensionPattern.match(node.value.text);
+        const bool pattern = node.op == QLatin1String("endswith")
+                             || (isTextOperator(node.op)
+                                 && node.value.text.startsWith(QLatin1Char('*')));
+        if (match.hasMatch() && pattern) {
+            return QStringList{match.captured(1).toLower()};
+        }
+    }
+    return std::nullopt;
+}
+
+// Query text for a text or name clause; nullopt when the clause is not one.
+std::optional<QString> clauseTerm(const Node& node)
+{
+    if (node.type != Node::Type::Compare || node.value.type != Token::Type::String
+        || !isTextOperator(node.op)) {
+        return std::nullopt;
+    }
+    const AttributeClass cls = attributeClass(node.attribute);
+    if (cls != AttributeClass::Text && cls != AttributeClass::Name) {
+        return std::nullopt;
+    }
+    QString term = node.value.text;
+    term.remove(QLatin1Char('*'));
+    term.remove(QLatin1Char('?'));
+    return term.simplified();
+}
+
+bool restrictFileTypes(Translation& out, const QStringList& extensions)
+{
+    if (extensions.isEmpty()) {
+        return true;  // public.item and friends match everything
+    }
+    if (!out.fileTypes.has_value()) {
+        out.fileTypes = extensions;
+        return true;
+    }
+    QStringList both;
+    for (const QString& extension : out.fileTypes.value()) {
+        if (extensions.contains(extension)) {
+            both.append(extension);
+        }
+    }
+    if (both.isEmpty()) {
+        return out.fail(QStringLiteral("Content type clauses exclude each other"));
+    }
+    out.fileTypes = both;
+    return true;
+}
+
+void boundAfter(Translation& out, double value)
+{
+    out.modifiedAfter = std::max(value, out.modifiedAfter.value_or(value));
+}
+void boundBefore(Translation& out, double value)
+{
+    out.modifiedBefore = std::min(value, out.modifiedBefore.value_or(value));
+}
+void boundMinSize(Translation& out, qint64 value)
+{
+    out.minSize = std::max(value, out.minSize.value_or(value));
+}
+void boundMaxSize(Translation& out, qint64 value)
+{
+    out.maxSize = std::min(value, out.maxSize.value_or(value));
+}
+
+std::optional<double> dateValue(const Token& token)
+{
+    if (token.type == Token::Type::Time) {
+        return token.number;
+    }
+    if (token.type == Token::Type::String) {
+        const QDateTime value = QDateTime::fromString(token.text, Qt::ISODate);
+        if (value.isValid()) {
+            return static_cast<double>(value.toSecsSinceEpoch());
+        }
+    }
+    return std::nullopt;
+}
+
+bool isUnderPath(const QString& path, const QString& prefix)
+{
+    return path == prefix || prefix == QLatin1String("/")
+           || path.startsWith(prefix + QLatin1Char('/'));
+}
+
+bool translatePath(const Node& node, bool negated, Translation& out)
+{
+    if (node.value.type != Token::Type::String) {
+        return out.fail(QStringLiteral("kMDItemPath needs a string value"));
+    }
+    const bool exclude = negated != (node.op == QLatin1String("!="));
+    if (node.op != QLatin1String("==") && node.op != QLatin1String("!=")
+        && node.op != QLatin1String("beginswith") && node.op != QLatin1String("like")) {
+        return out.fail(QStringLiteral("kMDItemPath supports ==, != and BEGINSWITH"));
+    }
+    QString prefix = node.value.text;
+    while (prefix.endsWith(QLatin1Char('*'))) {
+        prefix.chop(1);
+    }
+    if (prefix.contains(QLatin1Char('*')) || prefix.contains(QLatin1Char('?'))
+        || !QDir::isAbsolutePath(prefix)) {
+        return out.fail(
+            QStringLiteral("kMDItemPath must be an absolute path or a path prefix ending in *"));
+    }
+    prefix = QDir::cleanPath(prefix);
+    if (exclude) {
+        out.excludePaths.append(prefix);
+        return true;
+    }
+    // includePaths are alternatives; ANDed path clauses keep the narrower one.
+    const QString current = out.includePaths.value(0);
+    if (current.isEmpty() || isUnderPath(prefix, current)) {
+        out.includePaths = QStringList{prefix};
+    } else if (!isUnderPath(current, prefix)) {
+        return out.fail(QStringLiteral("kMDItemPath clauses exclude each other"));
+    }
+    return true;
+}
+
+bool translateCompare(const Node& node, Translation& out)
+{
+    const AttributeClass cls = attributeClass(node.attribute);
+    if (cls == AttributeClass::Unknown) {
+        return out.fail(QStringLiteral("Unsupported attribute %1").arg(node.attribute));
+    }
+    if (cls == AttributeClass::Path) {
+        return translatePath(node, false, out);
+    }
+    if (const std::optional<QStringList> extensions = clauseExtensions(node)) {
+        return restrictFileTypes(out, extensions.value());
+    }
+    if (cls == AttributeClass::ContentType) {
+        return out.fail(node.op == QLatin1String("==")
+                            ? QStringLiteral("Unsupported content type '%1'").arg(node.value.text)
+                            : QStringLiteral("%1 supports == only").arg(node.attribute));
+    }
+    if (const std::optional<QString> term = clauseTerm(node)) {
+        if (!term->isEmpty()) {
+            out.terms.append(term.value());
+        }
+        return true;
+    }
+    if (cls == AttributeClass::Text || cls == AttributeClass::Name) {
+        return out.fail(QStringLiteral("%1 supports string ==, LIKE, CONTAINS, BEGINSWITH "
+                                       "and ENDSWITH")
+                            .arg(node.attribute));
+    }
+    if (cls == AttributeClass::Tags) {
+        if (node.op != QLatin1String("==") || node.value.type != Token::Type::String
+            || node.value.text.contains(QLatin1Char('*')) || node.value.text.trimmed().isEmpty()) {
+            return out.fail(QStringLiteral("kMDItemUserTags supports == \"TagName\" only"));
+        }
+        out.tags.append(node.value.text.trimmed());
+        return true;
+    }
+    if (cls == AttributeClass::Date) {
+        const std::optional<double> value = dateValue(node.value);
+        if (!value.has_value()) {
+            return out.fail(
+                QStringLiteral("%1 needs a $time value or an ISO 8601 date").arg(node.attribute));
+        }
+        if (node.op == QLatin1String(">") || node.op == QLatin1String(">=")) {
+            boundAfter(out, value.value());
+        } else if (node.op == QLatin1String("<") || node.op == QLatin1String("<=")) {
+            boundBefore(out, value.value());
+        } else {
+            return out.fail(QStringLiteral("%1 supports >, >=, <, <= and InRange")
+                                .arg(node.attribute));
+        }
+        return true;
+    }
+
+    // Size
+    if (node.value.type != Token::Type::Number) {
+        return out.fail(QStringLiteral("%1 needs a number of bytes").arg(node.attribute));
+    }
+    const qint64 bytes = static_cast<qint64>(node.value.number);
+    if (node.op == QLatin1String(">")) {
+        boundMinSize(out, bytes + 1);
+    } else if (node.op == QLatin1String(">=")) {
+        boundMinSize(out, bytes);
+    } else if (node.op == QLatin1String("<")) {
+        boundMaxSize(out, bytes - 1);
+    } else if (node.op == QLatin1String("<=")) {
+        boundMaxSize(out, bytes);
+    } else if (node.op == QLatin1String("==")) {
+        boundMinSize(out, bytes);
+        boundMaxSize(out, bytes);
+    } else {
+        return out.fail(QStringLiteral("%1 supports ==, >, >=, <, <= and InRange")
+                            .arg(node.attribute));
+    }
+    return true;
+}
+
+bool translateNode(const Node& node, Translation& out)
+{
+    switch (node.type) {
+    case Node::Type::And:
+        for (const Node& child : node.children) {
+            if (!translateNode(child, out)) {
+                return false;
+            }
+        }
+        return true;
+    case Node::Type::Compare:
+        return translateCompare(node, out);
+    case Node::Type::Not: {
+        const Node& child = node.children.front();
+        if (child.type == Node::Type::Compare
+            && attributeClass(child.attribute) == AttributeClass::Path) {
+            return translatePath(child, true, out);
+        }
+        return out.fail(QStringLiteral("NOT is only supported on kMDItemPath"));
+    }
+    case Node::Type::InRange: {
+        const AttributeClass cls = attributeClass(node.attribute);
+        if (cls == AttributeClass::Date) {
+            const std::optional<double> lower = dateValue(node.value);
+            const std::optional<double> upper = dateValue(node.upper);
+            if (!lower.has_value() || !upper.has_value()) {
+                return out.fail(QStringLiteral("InRange(%1, ...) needs $time values")
+                                    .arg(node.attribute));
+            }
+            boundAfter(out, lower.value());
+            boundBefore(out, upper.value());
+            return true;
+        }
+        if (cls == AttributeClass::Size && node.value.type == Token::Type::Number
+            && node.upper.type == Token::Type::Number) {
+            boundMinSize(out, static_cast<qint64>(node.value.number));
+            boundMaxSize(out, static_cast<qint64>(node.upper.number));
+            return true;
+        }
+        return out.fail(QStringLiteral("InRange is supported on dates and sizes only"));
+    }
+    case Node::Type::Or: {
+        // Either alternatives of content type (a union of extensions) ...
+        QStringList extensions;
+        bool allExtensions = true;
+        bool matchesAnything = false;
+        for (const Node& child : node.children) {
+            const std::optional<QStringList> childExtensions = clauseExtensions(child);
+            if (!childExtensions.has_value()) {
+                allExtensions = false;
+                break;
+            }
+            matchesAnything = matchesAnything || childExtensions->isEmpty();
+            for (const QString& extension : childExtensions.value()) {
+                if (!extensions.contains(extension)) {
+                    extensions.append(extension);
+                }
+            }
+        }
+        if (allExtensions) {
+            return matchesAnything || restrictFileTypes(out, extensions);
+        }
+
+        // ... or the same text looked up in several attributes, as Finder writes
+        // (kMDItemDisplayName == "x*"cd || kMDItemTextContent == "x*"cd).
+        std::optional<QString> shared;
+        for (const Node& child : node.children) {
+            const std::optional<QString> term = clauseTerm(child);
+            if (!term.has_value()
+                || (shared.has_value() && shared->compare(term.value(), Qt::CaseInsensitive) != 0)) {
+                return out.fail(QStringLiteral("OR is only supported between content types or "
+                                               "between attributes matching the same text"));
+            }
+            shared = term;
+        }
+        if (!shared->isEmpty()) {
+            out.terms.append(shared.value());
+        }
+        return true;
+    }
+    }
+    return false;
+}
+
+} // namespace
+
+std::optional<SpotlightQuery> SpotlightPredicate::translate(const QString& predicate,
+                                                            const QDateTime& now, QString* error)
+{
+    const auto failWith = [error](const QString& message) -> std::optional<SpotlightQuery> {
+        if (error) {
+            *error = message;
+        }
+        return std::nullopt;
+    };
+    if (predicate.trimmed().isEmpty()) {
+        return failWith(QStringLiteral("Empty predicate"));
+    }
+
+    QString parseError;
+    Parser parser(predicate, now);
+    const std::optional<Node> root = parser.parse(&parseError);
+    if (!root.has_value()) {
+        return failWith(parseError);
+    }
+    Translation out;
+    if (!translateNode(root.value(), out)) {
+        return failWith(out.error);
+    }
+
+    SpotlightQuery query;
+    query.text = out.terms.join(QLatin1Char(' '));
+    if (out.fileTypes.has_value()) {
+        query.filters[QStringLiteral("fileTypes")] = QJsonArray::fromStringList(*out.fileTypes);
+    }
+    if (!out.tags.isEmpty()) {
+        query.filters[QStringLiteral("tags")] = QJsonArray::fromStringList(out.tags);
+    }
+    if (!out.includePaths.isEmpty()) {
+        query.filters[QStringLiteral("includePaths")] =
+            QJsonArray::fromStringList(out.includePaths);
+    }
+    if (!out.excludePaths.isEmpty()) {
+        query.filters[QStringLiteral("excludePaths")] =
+            QJsonArray::fromStringList(out.excludePaths);
+    }
+    if (out.modifiedAfter.has_value()) {
+        query.filters[QStringLiteral("modifiedAfter")] = out.modifiedAfter.value();
+    }
+    if (out.modifiedBefore.has_value()) {
+        query.filters[QStringLiteral("modifiedBefore")] = out.modifiedBefore.value();
+    }
+    if (out.minSize.has_value()) {
+        query.filters[QStringLiteral("minSize")] = out.minSize.value();
+    }
+    if (out.maxSize.has_value()) {
+        query.filters[QStringLiteral("maxSize")] = out.maxSize.value();
+    }
+    return query;
+}
+
+} // namespace bs
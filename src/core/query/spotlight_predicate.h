@@ -0,0 +1,35 @@
+#pragma once
+
+#include <QDateTime>
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+
+namespace bs {
+
+struct SpotlightQuery {
+    QString text;         // free text for `query`; empty for filter-only predicates
+    QJsonObject filters;  // keys of `search` filters (fileTypes, tags, modifiedAfter, ...)
+};
+
+// SpotlightPredicate — translates NSMetadataQuery / mdfind predicate strings
+// into a search query plus filters, e.g.
+//
+//   kMDItemContentType == "com.adobe.pdf" && kMDItemTextContent == "budget*"cd
+//     && kMDItemFSContentChangeDate >= $time.today(-7)
+//
+// Supported: text attributes (kMDItemTextContent, kMDItemDisplayName, `*`,
+// ...) become query terms; content types, Finder tags, kMDItemPath prefixes,
+// modification dates and sizes become filters. Clauses join with && / AND;
+// || / OR only between content types or between attributes matching the same
+// text; NOT / != only on kMDItemPath. Anything else is rejected rather than
+// silently widened.
+class SpotlightPredicate {
+public:
+    // `now` anchors $time.now / $time.today(...) and friends.
+    static std::optional<SpotlightQuery> translate(const QString& predicate,
+                                                   const QDateTime& now, QString* error);
+};
+
+} // namespace bs
@@ -0,0 +1,170 @@
+#include "core/query/team_index.h"
+
+#include "core/query/federated_search.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QRegularExpression>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// An absolute path without trailing '/' or "..", or empty when `raw` is
+// not one.
+QString cleanAbsolutePath(const QString& raw)
+{
+    const QString trimmed = raw.trimmed();
+    if (!trimmed.startsWith(QLatin1Char('/'))) {
+        return QString();
+    }
+    return QDir::cleanPath(trimmed);
+}
+
+} // namespace
+
+std::optional<std::vector<TeamIndex>> TeamIndexes::parseIndexes(const QString& json, QString* error)
+{
+    std::vector<TeamIndex> indexes;
+    if (json.trimmed().isEmpty()) {
+        return indexes;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument document = QJsonDocument::fromJson(json.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !document.isArray()) {
+        *error = QStringLiteral("Team indexes must be a JSON array");
+        return std::nullopt;
+    }
+    const QJsonArray array = document.array();
+    if (array.size() > kMaxIndexes) {
+        *error = QStringLiteral("At most %1 team indexes").arg(kMaxIndexes);
+        return std::nullopt;
+    }
+
+    static const QRegularExpression validName(QStringLiteral("^[A-Za-z0-9-]+$"));
+    QSet<QString> names;
+    for (qsizetype i = 0; i < array.size(); ++i) {
+        const QJsonObject object = array.at(i).toObject();
+        TeamIndex index;
+        index.name = object.value(QStringLiteral("name")).toString().trimmed();
+        index.path = cleanAbsolutePath(object.value(QStringLiteral("path")).toString());
+        const QString publishedRoot = object.value(QStringLiteral("publishedRoot")).toString();
+        const QString localRoot = object.value(QStringLiteral("localRoot")).toString();
+        index.publishedRoot = cleanAbsolutePath(publishedRoot);
+        index.localRoot = cleanAbsolutePath(localRoot);
+        const QString label = index.name.isEmpty() ? QStringLiteral("#%1").arg(i + 1) : index.name;
+        if (index.name.size() > kMaxNameChars || !validName.match(index.name).hasMatch()) {
+            *error = QStringLiteral("Team index %1: the name must be up to %2 letters, digits or '-'")
+                         .arg(label)
+                         .arg(kMaxNameChars);
+            return std::nullopt;
+        }
+        if (index.name.compare(QLatin1String(FederatedSearch::kLocalSource), Qt::CaseInsensitive) == 0
+            || names.contains(index.name.toLower())) {
+            *error = QStringLiteral("Team index %1 is listed twice").arg(label);
+            return std::nullopt;
+        }
+        if (index.path.isEmpty()) {
+            *error = QStringLiteral("Team index %1: the path must be absolute").arg(label);
+            return std::nullopt;
+        }
+        if (publishedRoot.trimmed().isEmpty() != localRoot.trimmed().isEmpty()
+            || (!publishedRoot.trimmed().isEmpty()
+                && (index.publishedRoot.isEmpty() || index.localRoot.isEmpty()))) {
+            *error = QStringLiteral("Team index %1: publishedRoot and localRoot must both be "
+                                    "absolute paths, or both left out")
+                         .arg(label);
+            return std::nullopt;
+        }
+        names.insert(index.name.toLower());
+        indexes.push_back(std::move(index));
+    }
+    return indexes;
+}
+
+QString TeamIndexes::localPath(const TeamIndex& index, const QString& path)
+{
+    if (index.publishedRoot.isEmpty() || index.publishedRoot == index.localRoot) {
+        return path;
+    }
+    if (path == index.publishedRoot) {
+        return index.localRoot;
+    }
+    // "/" as the published root keeps its slash; any other has none.
+    const QString prefix = index.publishedRoot.endsWith(QLatin1Char('/'))
+                               ? index.publishedRoot
+                               : index.publishedRoot + QLatin1Char('/');
+    if (!path.startsWith(prefix)) {
+        return path;
+    }
+    const QString rest = path.mid(prefix.size());
+    return index.localRoot.endsWith(QLatin1Char('/')) ? index.localRoot + rest
+                                                      : index.localRoot + QLatin1Char('/') + rest;
+}
+
+QJsonObject TeamIndexes::resultJson(const TeamIndex& index, const SQLiteStore::FtsJoinedHit& hit)
+{
+    QJsonObject metadata;
+    metadata[QStringLiteral("fileSize")] = static_cast<qint64>(hit.size);
+    if (hit.modifiedAt > 0.0) {
+        metadata[QStringLiteral("modificationDate")] =
+            QDateTime::fromMSecsSinceEpoch(static_cast<qint64>(hit.modifiedAt * 1000.0))
+                .toUTC()
+                .toString(Qt::ISODate);
+    }
+
+    QString plainSnippet = hit.snippet;
+    plainSnippet.replace(QStringLiteral("<b>"), QString());
+    plainSnippet.replace(QStringLiteral("</b>"), QString());
+
+    QJsonObject row;
+    row[QStringLiteral("itemId")] = static_cast<qint64>(hit.fileId);
+    row[QStringLiteral("path")] = localPath(index, hit.path);
+    row[QStringLiteral("name")] = hit.name;
+    row[QStringLiteral("kind")] = hit.kind;
+    row[QStringLiteral("matchType")] = QStringLiteral("contentMatch");
+    row[QStringLiteral("score")] = std::max(0.0, -hit.bm25Score);
+    row[QStringLiteral("bm25Raw")] = hit.bm25Score;
+    row[QStringLiteral("snippet")] = plainSnippet;
+    row[QStringLiteral("metadata")] = metadata;
+    row[QStringLiteral("contentAvailable")] = true;
+    row[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+    return row;
+}
+
+QJsonObject TeamIndexes::search(SQLiteStore& store, const TeamIndex& index, const QString& query,
+                                int limit)
+{
+    // Chunks of one file come back as separate hits; ask for a few more
+    // than needed so the list still fills after folding them.
+    std::vector<SQLiteStore::FtsJoinedHit> hits = store.searchFts5Joined(query, limit * 3, false);
+    if (hits.empty()) {
+        hits = store.searchFts5Joined(query, limit * 3, true);
+    }
+
+    QJsonArray results;
+    QSet<qint64> seen;
+    for (const SQLiteStore::FtsJoinedHit& hit : hits) {
+        if (results.size() >= limit) {
+            break;
+        }
+        if (seen.contains(hit.fileId) || !hit.path.startsWith(QLatin1Char('/'))) {
+            continue;
+        }
+        seen.insert(hit.fileId);
+        results.append(resultJson(index, hit));
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("results")] = results;
+    result[QStringLiteral("totalMatches")] = results.size();
+    return result;
+}
+
+} // namespace bs
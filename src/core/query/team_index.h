@@ -0,0 +1,58 @@
+#pragma once
+
+#include "core/index/sqlite_store.h"
+
+#include <QJsonObject>
+#include <QString>
+
+#include <optional>
+#include <vector>
+
+namespace bs {
+
+// An index someone else publishes, attached read-only next to this Mac's:
+// a team's documentation corpus on a shared volume, say.
+struct TeamIndex {
+    QString name;           // label on its results, letters, digits and '-'
+    QString path;           // the published index.db
+    QString publishedRoot;  // the publisher's folder its paths start with
+    QString localRoot;      // where that folder is mounted here
+};
+
+// TeamIndexes -- the `team_indexes` setting (settings.json `teamIndexes`).
+//
+// The query service opens each listed index with
+// SQLiteStore::openReadOnly and searches it alongside the local one;
+// FederatedSearch::merge fuses the lists and labels every row with the
+// index it came from. A team index is never written to: its rows carry
+// no item id here, so opens, feedback and pins stay out of both indexes.
+// When the publisher indexed the corpus under another path than the one
+// it is mounted at, publishedRoot/localRoot rewrite its paths.
+class TeamIndexes {
+public:
+    static constexpr const char* kSettingKey = "team_indexes";
+    static constexpr int kMaxIndexes = 8;
+    static constexpr int kMaxNameChars = 32;
+
+    // The setting's JSON array of {name, path, publishedRoot?, localRoot?}.
+    // Empty text is no indexes; nullopt with *error when an entry is
+    // invalid (a bad name, a relative path, only one of the roots) or a
+    // name repeats.
+    static std::optional<std::vector<TeamIndex>> parseIndexes(const QString& json, QString* error);
+
+    // `path` as it is reached here: under localRoot when it is under
+    // publishedRoot, unchanged otherwise.
+    static QString localPath(const TeamIndex& index, const QString& path);
+
+    // A hit from the index as a search result row, in the shape
+    // handleSearch() uses; its itemId is the one in the team index.
+    static QJsonObject resultJson(const TeamIndex& index, const SQLiteStore::FtsJoinedHit& hit);
+
+    // `limit` rows for `query` from `store`: the strict FTS5 match, then the
+    // relaxed one when that finds nothing. A search result object
+    // ({results, totalMatches}) for FederatedSearch::merge.
+    static QJsonObject search(SQLiteStore& store, const TeamIndex& index, const QString& query,
+                              int limit);
+};
+
+} // namespace bs
@@ -0,0 +1,23 @@
+#pragma once
+
+#include <QString>
+
+namespace bs {
+
+// AppDocumentTypes -- the file types an installed app says it opens, from
+// the CFBundleDocumentTypes of its Info.plist, so app-context ranking works
+// for apps ContextSignals has no built-in entry for.
+//
+// Declarations with the role None are ignored, as are catch-alls ("*",
+// public.data, public.item, public.content): an app that opens anything says
+// nothing about what it is for. Lookups are cached per bundle for the life
+// of the process and are safe from any thread.
+class AppDocumentTypes {
+public:
+    // True if the app opens files with this extension (no dot, any case),
+    // by name or because the file's type conforms to one the app declares.
+    // False for apps that are not installed, and always off macOS.
+    static bool handlesExtension(const QString& bundleId, const QString& extension);
+};
+
+} // namespace bs
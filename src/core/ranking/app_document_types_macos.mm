@@ -0,0 +1,129 @@
+#include "core/ranking/app_document_types.h"
+
+#include <QHash>
+#include <QMutex>
+#include <QSet>
+#include <QStringList>
+
+#import <CoreServices/CoreServices.h>
+#import <Foundation/Foundation.h>
+#import <UniformTypeIdentifiers/UniformTypeIdentifiers.h>
+
+namespace bs {
+
+namespace {
+
+QString toQString(NSString* value)
+{
+    return value ? QString::fromUtf8(value.UTF8String) : QString();
+}
+
+struct DeclaredTypes {
+    QSet<QString> extensions;             // lowercase, no dot
+    QStringList contentTypes;             // UTIs
+    QHash<QString, bool> conformsByExt;   // contentTypes verdicts, by extension
+};
+
+bool isCatchAllType(NSString* identifier)
+{
+    static NSSet<NSString*>* catchAll =
+        [[NSSet alloc] initWithObjects:@"public.data", @"public.item", @"public.content",
+                                       @"public.composite-content", @"public.folder",
+                                       @"public.directory", nil];
+    return [catchAll containsObject:identifier];
+}
+
+DeclaredTypes loadDeclaredTypes(const QString& bundleId)
+{
+    DeclaredTypes declared;
+    @autoreleasepool {
+        NSArray<NSURL*>* appUrls = CFBridgingRelease(LSCopyApplicationURLsForBundleIdentifier(
+            (__bridge CFStringRef)bundleId.toNSString(), nullptr));
+        NSBundle* bundle = appUrls.count > 0 ? [NSBundle bundleWithURL:appUrls.firstObject] : nil;
+        NSArray* documentTypes = [bundle objectForInfoDictionaryKey:@"CFBundleDocumentTypes"];
+        if (![documentTypes isKindOfClass:NSArray.class]) {
+            return declared;
+        }
+
+        for (id documentType in documentTypes) {
+            if (![documentType isKindOfClass:NSDictionary.class]) {
+                continue;
+            }
+            NSDictionary* type = documentType;
+            NSString* role = type[@"CFBundleTypeRole"];
+            if ([role isKindOfClass:NSString.class] && [role isEqualToString:@"None"]) {
+                continue;
+            }
+            NSArray* extensions = type[@"CFBundleTypeExtensions"];
+            if ([extensions isKindOfClass:NSArray.class]) {
+                for (id ext in extensions) {
+                    if ([ext isKindOfClass:NSString.class] && ![ext isEqualToString:@"*"]) {
+                        declared.extensions.insert(toQString(ext).toLower());
+                    }
+                }
+            }
+            NSArray* contentTypes = type[@"LSItemContentTypes"];
+            if ([contentTypes isKindOfClass:NSArray.class]) {
+                for (id identifier in contentTypes) {
+                    if ([identifier isKindOfClass:NSString.class] && !isCatchAllType(identifier)) {
+                        declared.contentTypes.append(toQString(identifier));
+                    }
+                }
+            }
+        }
+    }
+    return declared;
+}
+
+bool conformsToAny(const QString& extension, const QStringList& contentTypes)
+{
+    @autoreleasepool {
+        UTType* fileType = [UTType typeWithFilenameExtension:extension.toNSString()];
+        if (!fileType || fileType.dynamic) {
+            return false;
+        }
+        for (const QString& identifier : contentTypes) {
+            UTType* declaredType = [UTType typeWithIdentifier:identifier.toNSString()];
+            if (declaredType && [fileType conformsToType:declaredType]) {
+                return true;
+            }
+        }
+    }
+    return false;
+}
+
+} // anonymous namespace
+
+bool AppDocumentTypes::handlesExtension(const QString& bundleId, const QString& extension)
+{
+    const QString bundleKey = bundleId.trimmed().toLower();
+    const QString ext = extension.toLower();
+    if (bundleKey.isEmpty() || ext.isEmpty()) {
+        return false;
+    }
+
+    static QMutex mutex;
+    static QHash<QString, DeclaredTypes> cache;
+    QMutexLocker locker(&mutex);
+
+    auto it = cache.find(bundleKey);
+    if (it == cache.end()) {
+        it = cache.insert(bundleKey, loadDeclaredTypes(bundleId.trimmed()));
+    }
+    DeclaredTypes& declared = it.value();
+    if (declared.extensions.contains(ext)) {
+        return true;
+    }
+    if (declared.contentTypes.isEmpty()) {
+        return false;
+    }
+    const auto verdict = declared.conformsByExt.constFind(ext);
+    if (verdict != declared.conformsByExt.constEnd()) {
+        return verdict.value();
+    }
+    const bool conforms = conformsToAny(ext, declared.contentTypes);
+    declared.conformsByExt.insert(ext, conforms);
+    return conforms;
+}
+
+} // namespace bs
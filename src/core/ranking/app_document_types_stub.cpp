@@ -0,0 +1,12 @@
+#include "core/ranking/app_document_types.h"
+
+namespace bs {
+
+bool AppDocumentTypes::handlesExtension(const QString& bundleId, const QString& extension)
+{
+    Q_UNUSED(bundleId)
+    Q_UNUSED(extension)
+    return false;
+}
+
+} // namespace bs
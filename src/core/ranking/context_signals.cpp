@@ -1,8 +1,11 @@
 #include "core/ranking/context_signals.h"
+#include "core/ranking/app_document_types.h"
 #include "core/shared/logging.h"
 
 #include <QFileInfo>
 
+#include <utility>
+
 namespace bs {
 
 ContextSignals::ContextSignals()
@@ -137,6 +140,14 @@ void ContextSignals::initAppExtensionMap()
     m_appExtensionMap[QStringLiteral("com.spotify.client")] = mediaExts;
     m_appExtensionMap[QStringLiteral("com.colliderli.iina")] = mediaExts;
 
+    // Bundle IDs are case-insensitive, and the behavior-event fallback
+    // hands them over lowercased.
+    QMap<QString, QSet<QString>> lowered;
+    for (auto it = m_appExtensionMap.cbegin(); it != m_appExtensionMap.cend(); ++it) {
+        lowered.insert(it.key().toLower(), it.value());
+    }
+    m_appExtensionMap = std::move(lowered);
+
     LOG_DEBUG(bsRanking, "initAppExtensionMap: registered %d bundle IDs",
               static_cast<int>(m_appExtensionMap.size()));
 }
@@ -191,12 +202,6 @@ double ContextSignals::appContextBoost(const QString& filePath,
         return 0.0;
     }
 
-    // Look up the extension set for this bundle ID
-    auto it = m_appExtensionMap.find(frontmostAppBundleId);
-    if (it == m_appExtensionMap.end()) {
-        return 0.0;
-    }
-
     // Extract file extension (without dot, lowered)
     const QFileInfo info(filePath);
     const QString ext = info.suffix().toLower();
@@ -204,7 +209,12 @@ double ContextSignals::appContextBoost(const QString& filePath,
         return 0.0;
     }
 
-    if (it.value().contains(ext)) {
+    // The built-in table wins; other apps get the types they declare.
+    const auto it = m_appExtensionMap.constFind(frontmostAppBundleId.trimmed().toLower());
+    const bool handled = it != m_appExtensionMap.constEnd()
+        ? it.value().contains(ext)
+        : AppDocumentTypes::handlesExtension(frontmostAppBundleId, ext);
+    if (handled) {
         LOG_DEBUG(bsRanking, "appContextBoost: file='%s' app='%s' ext='%s' boost=%d",
                   qUtf8Printable(filePath), qUtf8Printable(frontmostAppBundleId),
                   qUtf8Printable(ext), appContextBoostWeight);
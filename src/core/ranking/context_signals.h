@@ -22,7 +22,9 @@ public:
 
     // Compute app-context boost.
     // Returns appContextBoostWeight if the file's extension matches the
-    // frontmost application's associated file types.
+    // frontmost application's associated file types: the built-in table for
+    // well-known apps (bundle IDs compared case-insensitively), otherwise the
+    // document types the app declares (AppDocumentTypes).
     double appContextBoost(const QString& filePath,
                            const QString& frontmostAppBundleId,
                            int appContextBoostWeight) const;
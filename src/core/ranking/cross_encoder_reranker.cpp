@@ -1,4 +1,5 @@
 #include "core/ranking/cross_encoder_reranker.h"
+#include "core/shared/logging.h"
 #include "core/embedding/tokenizer.h"
 #include "core/models/model_registry.h"
 #include "core/models/model_session.h"
@@ -70,7 +71,7 @@ bool CrossEncoderReranker::initialize()
 {
 #if BS_WITH_ONNX
     if (!m_impl->registry) {
-        qWarning() << "CrossEncoderReranker: null registry";
+        qCWarning(bsRanking) << "CrossEncoderReranker: null registry";
         return false;
     }
 
@@ -79,8 +80,8 @@ bool CrossEncoderReranker::initialize()
     }
     ModelSession* modelSession = m_impl->registry->getSession(m_role);
     if (!modelSession || !modelSession->isAvailable()) {
-        qWarning() << "CrossEncoderReranker:" << QString::fromStdString(m_role)
-                   << "session unavailable";
+        qCWarning(bsRanking) << "CrossEncoderReranker:" << QString::fromStdString(m_role)
+                             << "session unavailable";
         return false;
     }
 
@@ -88,19 +89,19 @@ bool CrossEncoderReranker::initialize()
 
     m_impl->tokenizer = TokenizerFactory::create(entry, m_impl->registry->modelsDir());
     if (!m_impl->tokenizer || !m_impl->tokenizer->isLoaded()) {
-        qWarning() << "CrossEncoderReranker: tokenizer creation failed";
+        qCWarning(bsRanking) << "CrossEncoderReranker: tokenizer creation failed";
         return false;
     }
 
     m_impl->session = static_cast<Ort::Session*>(modelSession->rawSession());
     if (!m_impl->session) {
-        qWarning() << "CrossEncoderReranker: null ONNX session";
+        qCWarning(bsRanking) << "CrossEncoderReranker: null ONNX session";
         return false;
     }
 
     const auto& outputNames = modelSession->outputNames();
     if (outputNames.empty()) {
-        qWarning() << "CrossEncoderReranker: no output names";
+        qCWarning(bsRanking) << "CrossEncoderReranker: no output names";
         return false;
     }
     m_impl->outputName = outputNames.front();
@@ -108,7 +109,7 @@ bool CrossEncoderReranker::initialize()
     m_impl->available = true;
     return true;
 #else
-    qWarning() << "CrossEncoderReranker: ONNX Runtime not enabled";
+    qCWarning(bsRanking) << "CrossEncoderReranker: ONNX Runtime not enabled";
     return false;
 #endif
 }
@@ -189,7 +190,7 @@ int CrossEncoderReranker::rerank(const QString& query,
             outputNames, 1);
 
         if (outputs.empty() || !outputs[0].IsTensor()) {
-            qWarning() << "CrossEncoderReranker: missing tensor output";
+            qCWarning(bsRanking) << "CrossEncoderReranker: missing tensor output";
             return 0;
         }
 
@@ -216,7 +217,7 @@ int CrossEncoderReranker::rerank(const QString& query,
 
         return boostedCount;
     } catch (const Ort::Exception& ex) {
-        qWarning() << "CrossEncoderReranker inference failed:" << ex.what();
+        qCWarning(bsRanking) << "CrossEncoderReranker inference failed:" << ex.what();
         return 0;
     }
 #else
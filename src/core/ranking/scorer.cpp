@@ -83,6 +83,40 @@ double Scorer::computeFrequencyBoost(int openCount, double lastOpenEpoch) const
     return baseTierBoost;
 }
 
+double Scorer::computeActionBoost(int reveals, int copies, double lastActedAtEpoch) const
+{
+    const int actions = std::max(reveals, 0) + std::max(copies, 0);
+    if (actions == 0 || m_weights.actionBoostWeight <= 0) {
+        return 0.0;
+    }
+
+    const double volume = std::min(1.0, static_cast<double>(actions) / 3.0);
+    double decay = 1.0;
+    if (lastActedAtEpoch > 0.0) {
+        const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+        const double daysSince = std::max(0.0, (now - lastActedAtEpoch) / 86400.0);
+        decay = std::pow(0.5, daysSince / 14.0);
+    }
+    return static_cast<double>(m_weights.actionBoostWeight) * volume * decay;
+}
+
+double Scorer::computeEventTimeBoost(double startEpoch, double endEpoch) const
+{
+    if (m_weights.eventTimeWeight <= 0) {
+        return 0.0;
+    }
+
+    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    const double end = std::max(startEpoch, endEpoch);
+    double halfLives = 0.0;
+    if (now < startEpoch) {
+        halfLives = (startEpoch - now) / (3.0 * 86400.0);
+    } else if (now > end) {
+        halfLives = (now - end) / (7.0 * 86400.0);
+    }
+    return static_cast<double>(m_weights.eventTimeWeight) * std::pow(0.5, halfLives);
+}
+
 double Scorer::computeJunkPenalty(const QString& filePath) const
 {
     if (m_weights.junkPenaltyWeight <= 0) {
@@ -30,6 +30,16 @@ public:
     // Frequency boost: tiered lookup with recency modifier.
     double computeFrequencyBoost(int openCount, double lastOpenEpoch = 0.0) const;
 
+    // Action boost: actionBoostWeight * min(1, (reveals + copies) / 3),
+    // halved for every two weeks since the last such action.
+    double computeActionBoost(int reveals, int copies, double lastActedAtEpoch) const;
+
+    // Event time boost, which stands in for the recency boost of calendar
+    // events: eventTimeWeight while the event is on, then halved for every
+    // 3 days until it starts or every 7 days since it ended. A meeting
+    // next year is no more relevant than one last year.
+    double computeEventTimeBoost(double startEpoch, double endEpoch) const;
+
     // Junk penalty: returns junkPenaltyWeight if path contains a known junk pattern.
     double computeJunkPenalty(const QString& filePath) const;
 
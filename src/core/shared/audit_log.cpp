@@ -0,0 +1,265 @@
+#include "core/shared/audit_log.h"
+#include "core/shared/logging.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QMutexLocker>
+#include <QRegularExpression>
+#include <QSaveFile>
+#include <QStandardPaths>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxLoggedStringChars = 256;
+constexpr int kMaxLoggedArrayItems = 20;
+
+QString enabledMarkerPath(const QString& directory)
+{
+    return QDir(directory).filePath(QStringLiteral("enabled"));
+}
+
+QStringList logFiles(const QString& directory)
+{
+    QStringList out;
+    const QFileInfoList entries = QDir(directory).entryInfoList(
+        {QStringLiteral("*.jsonl")}, QDir::Files | QDir::NoDotAndDotDot);
+    for (const QFileInfo& entry : entries) {
+        out.append(entry.absoluteFilePath());
+    }
+    return out;
+}
+
+template <typename Fn>
+void forEachEntry(const QString& filePath, Fn&& fn)
+{
+    QFile file(filePath);
+    if (!file.open(QIODevice::ReadOnly)) {
+        return;
+    }
+    while (!file.atEnd()) {
+        const QByteArray line = file.readLine().trimmed();
+        if (line.isEmpty()) {
+            continue;
+        }
+        const QJsonDocument doc = QJsonDocument::fromJson(line);
+        if (doc.isObject()) {
+            fn(doc.object(), line);
+        }
+    }
+}
+
+bool isSecretKey(const QString& key)
+{
+    static const QRegularExpression kPattern(
+        QStringLiteral("token|secret|password|passwd|credential|key$"),
+        QRegularExpression::CaseInsensitiveOption);
+    return kPattern.match(key).hasMatch();
+}
+
+QJsonValue summarizeValue(const QJsonValue& value)
+{
+    if (value.isString()) {
+        const QString text = value.toString();
+        if (text.size() <= kMaxLoggedStringChars) {
+            return text;
+        }
+        return text.left(kMaxLoggedStringChars) + QStringLiteral("...");
+    }
+    if (value.isArray()) {
+        const QJsonArray array = value.toArray();
+        QJsonArray out;
+        for (int i = 0; i < array.size() && i < kMaxLoggedArrayItems; ++i) {
+            out.append(summarizeValue(array.at(i)));
+        }
+        if (array.size() > kMaxLoggedArrayItems) {
+            out.append(QStringLiteral("(+%1 more)").arg(array.size() - kMaxLoggedArrayItems));
+        }
+        return out;
+    }
+    if (value.isObject()) {
+        return AuditLog::summarizeParams(value.toObject());
+    }
+    return value;
+}
+
+} // namespace
+
+AuditLog::AuditLog(QString directory, QString service, qint64 maxFileBytes, int maxFiles)
+    : m_directory(std::move(directory))
+    , m_service(std::move(service))
+    , m_maxFileBytes(std::max<qint64>(1024, maxFileBytes))
+    , m_maxFiles(std::max(1, maxFiles))
+{
+}
+
+QString AuditLog::defaultDirectory()
+{
+    QString dataDir = qEnvironmentVariable("BETTERSPOTLIGHT_DATA_DIR").trimmed();
+    if (dataDir.isEmpty()) {
+        dataDir = QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+                  + QStringLiteral("/betterspotlight");
+    }
+    return QDir::cleanPath(dataDir) + QStringLiteral("/audit");
+}
+
+bool AuditLog::isEnabled(const QString& directory)
+{
+    return QFileInfo::exists(enabledMarkerPath(directory));
+}
+
+bool AuditLog::setEnabled(const QString& directory, bool enabled)
+{
+    const QString marker = enabledMarkerPath(directory);
+    if (!enabled) {
+        return !QFileInfo::exists(marker) || QFile::remove(marker);
+    }
+    if (!QDir().mkpath(directory)) {
+        LOG_ERROR(bsCore, "Failed to create audit log directory: %s", qUtf8Printable(directory));
+        return false;
+    }
+    QFile::setPermissions(directory,
+                          QFile::ReadOwner | QFile::WriteOwner | QFile::ExeOwner);
+    QFile file(marker);
+    if (!file.open(QIODevice::WriteOnly | QIODevice::Truncate)) {
+        LOG_ERROR(bsCore, "Failed to enable audit log: %s", qUtf8Printable(marker));
+        return false;
+    }
+    file.write(QDateTime::currentDateTimeUtc().toString(Qt::ISODate).toUtf8() + '\n');
+    return true;
+}
+
+bool AuditLog::append(const QString& kind, const QString& method, const QJsonObject& details,
+                      bool ok)
+{
+    if (!isEnabled()) {
+        return false;
+    }
+
+    QJsonObject entry;
+    entry[QStringLiteral("ts")] =
+        static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    entry[QStringLiteral("service")] = m_service;
+    entry[QStringLiteral("kind")] = kind;
+    entry[QStringLiteral("method")] = method;
+    entry[QStringLiteral("ok")] = ok;
+    if (!details.isEmpty()) {
+        entry[QStringLiteral("details")] = details;
+    }
+    const QByteArray line = QJsonDocument(entry).toJson(QJsonDocument::Compact) + '\n';
+
+    QMutexLocker lock(&m_mutex);
+    rotateIfNeeded(line.size());
+    QFile file(filePath());
+    if (!file.open(QIODevice::WriteOnly | QIODevice::Append)) {
+        LOG_WARN(bsCore, "Failed to open audit log: %s", qUtf8Printable(file.fileName()));
+        return false;
+    }
+    if (file.size() == 0) {
+        file.setPermissions(QFile::ReadOwner | QFile::WriteOwner);
+    }
+    return file.write(line) == line.size();
+}
+
+std::vector<QJsonObject> AuditLog::read(const QString& directory, const AuditLogQuery& query)
+{
+    std::vector<QJsonObject> entries;
+    for (const QString& path : logFiles(directory)) {
+        forEachEntry(path, [&](const QJsonObject& entry, const QByteArray&) {
+            const double ts = entry.value(QStringLiteral("ts")).toDouble();
+            if (ts < query.since || (query.until > 0.0 && ts >= query.until)) {
+                return;
+            }
+            if (!query.kinds.isEmpty()
+                && !query.kinds.contains(entry.value(QStringLiteral("kind")).toString())) {
+                return;
+            }
+            if (!query.services.isEmpty()
+                && !query.services.contains(entry.value(QStringLiteral("service")).toString())) {
+                return;
+            }
+            entries.push_back(entry);
+        });
+    }
+    std::stable_sort(entries.begin(), entries.end(), [](const QJsonObject& a, const QJsonObject& b) {
+        return a.value(QStringLiteral("ts")).toDouble() > b.value(QStringLiteral("ts")).toDouble();
+    });
+    const size_t limit = static_cast<size_t>(std::clamp(query.limit, 1, kMaxReadLimit));
+    if (entries.size() > limit) {
+        entries.resize(limit);
+    }
+    return entries;
+}
+
+int64_t AuditLog::purge(const QString& directory, double before)
+{
+    int64_t removed = 0;
+    for (const QString& path : logFiles(directory)) {
+        QByteArray kept;
+        forEachEntry(path, [&](const QJsonObject& entry, const QByteArray& line) {
+            if (before > 0.0 && entry.value(QStringLiteral("ts")).toDouble() >= before) {
+                kept += line + '\n';
+            } else {
+                ++removed;
+            }
+        });
+        if (kept.isEmpty()) {
+            if (!QFile::remove(path)) {
+                LOG_WARN(bsCore, "Failed to remove audit log: %s", qUtf8Printable(path));
+            }
+            continue;
+        }
+        QSaveFile file(path);
+        if (!file.open(QIODevice::WriteOnly) || file.write(kept) != kept.size()
+            || !file.commit()) {
+            LOG_WARN(bsCore, "Failed to rewrite audit log: %s", qUtf8Printable(path));
+        }
+    }
+    return removed;
+}
+
+QJsonObject AuditLog::summarizeParams(const QJsonObject& params)
+{
+    QJsonObject out;
+    for (auto it = params.begin(); it != params.end(); ++it) {
+        out[it.key()] = isSecretKey(it.key()) ? QJsonValue(QStringLiteral("[redacted]"))
+                                              : summarizeValue(it.value());
+    }
+    return out;
+}
+
+QString AuditLog::filePath() const
+{
+    return rotatedPath(0);
+}
+
+QString AuditLog::rotatedPath(int index) const
+{
+    const QString name = index == 0
+        ? QStringLiteral("%1.jsonl").arg(m_service)
+        : QStringLiteral("%1.%2.jsonl").arg(m_service).arg(index);
+    return QDir(m_directory).filePath(name);
+}
+
+void AuditLog::rotateIfNeeded(qint64 incomingBytes)
+{
+    const qint64 currentBytes = QFileInfo(filePath()).size();
+    if (currentBytes == 0 || currentBytes + incomingBytes <= m_maxFileBytes) {
+        return;
+    }
+    QFile::remove(rotatedPath(m_maxFiles - 1));
+    for (int index = m_maxFiles - 2; index >= 0; --index) {
+        if (QFileInfo::exists(rotatedPath(index))) {
+            QFile::rename(rotatedPath(index), rotatedPath(index + 1));
+        }
+    }
+}
+
+} // namespace bs
@@ -0,0 +1,81 @@
+#pragma once
+
+#include <QJsonObject>
+#include <QMutex>
+#include <QString>
+#include <QStringList>
+
+#include <cstdint>
+#include <vector>
+
+namespace bs {
+
+// Filters for AuditLog::read. Empty lists and zero bounds match everything.
+struct AuditLogQuery {
+    double since = 0.0;   // epoch seconds, inclusive
+    double until = 0.0;   // epoch seconds, exclusive
+    QStringList kinds;    // "query", "admin"
+    QStringList services;
+    int limit = 200;
+};
+
+// AuditLog -- opt-in, append-only record of queries and admin operations for
+// shared or compliance-managed machines.
+//
+// Each service appends JSON lines to <directory>/<service>.jsonl:
+//
+//   {"ts": 1773100000.123, "service": "query", "kind": "query",
+//    "method": "search", "ok": true, "details": {"query": "budget", ...}}
+//
+// Nothing is written unless <directory>/enabled exists; the app creates it
+// when the user turns the log on, so every service (and a restarted one)
+// sees the same switch without a restart. A file that would grow past
+// maxFileBytes is rotated to <service>.1.jsonl, <service>.2.jsonl, ...;
+// files beyond maxFiles are deleted oldest first.
+class AuditLog {
+public:
+    static constexpr qint64 kDefaultMaxFileBytes = 5 * 1024 * 1024;
+    static constexpr int kDefaultMaxFiles = 5;
+    static constexpr int kMaxReadLimit = 5000;
+
+    explicit AuditLog(QString directory, QString service,
+                      qint64 maxFileBytes = kDefaultMaxFileBytes,
+                      int maxFiles = kDefaultMaxFiles);
+
+    // <data dir>/audit, honouring BETTERSPOTLIGHT_DATA_DIR like the services.
+    static QString defaultDirectory();
+
+    static bool isEnabled(const QString& directory);
+    static bool setEnabled(const QString& directory, bool enabled);
+    bool isEnabled() const { return isEnabled(m_directory); }
+
+    // Appends one entry when the log is enabled. Returns false when disabled
+    // or the write failed.
+    bool append(const QString& kind, const QString& method, const QJsonObject& details,
+                bool ok = true);
+
+    // Entries from every service's files, newest first.
+    static std::vector<QJsonObject> read(const QString& directory, const AuditLogQuery& query);
+
+    // Removes entries older than `before` (epoch seconds), or every entry
+    // when `before` is zero. Returns how many were removed.
+    static int64_t purge(const QString& directory, double before = 0.0);
+
+    // Admin params as logged: strings are shortened, large arrays trimmed
+    // and values under secret-looking keys dropped.
+    static QJsonObject summarizeParams(const QJsonObject& params);
+
+    QString filePath() const;
+
+private:
+    QString rotatedPath(int index) const;
+    void rotateIfNeeded(qint64 incomingBytes);
+
+    QString m_directory;
+    QString m_service;
+    qint64 m_maxFileBytes;
+    int m_maxFiles;
+    QMutex m_mutex;
+};
+
+} // namespace bs
@@ -0,0 +1,385 @@
+#include "core/shared/crash_report.h"
+
+#include <QCoreApplication>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QProcessEnvironment>
+#include <QRegularExpression>
+#include <QStandardPaths>
+
+#include <algorithm>
+#include <atomic>
+#include <csignal>
+#include <cstring>
+#include <ctime>
+#include <mutex>
+#include <string>
+
+#include <execinfo.h>
+#include <fcntl.h>
+#include <unistd.h>
+
+namespace bs {
+
+namespace {
+
+constexpr int kFatalSignals[] = {SIGSEGV, SIGBUS, SIGILL, SIGFPE, SIGABRT};
+constexpr int kMaxFrames = 64;
+constexpr std::size_t kAltStackBytes = 64 * 1024;
+
+// Fixed buffers the handler reads; written only while holding g_mutex.
+std::mutex g_mutex;
+char g_service[64] = "unknown";
+char g_version[32] = "";
+char g_pathPrefix[1024] = "";  // "<directory>/<service>-"
+char g_logRing[CrashReport::kLogRingBytes];
+std::atomic<std::size_t> g_logWritten{0};  // total bytes ever recorded
+
+// Serialized context. The previous copy stays alive one generation, so a
+// handler reading it while setContext() swaps never sees freed memory.
+QJsonObject g_contextObject;
+std::atomic<const std::string*> g_context{nullptr};
+const std::string* g_retiredContext = nullptr;
+
+alignas(16) char g_altStack[kAltStackBytes];
+bool g_installed = false;
+
+void copyTruncated(char* dest, std::size_t size, const QByteArray& source)
+{
+    const std::size_t length = std::min(size - 1, static_cast<std::size_t>(source.size()));
+    std::memcpy(dest, source.constData(), length);
+    dest[length] = '\0';
+}
+
+// ── Async-signal-safe output ────────────────────────────────
+
+void writeRaw(int fd, const char* data, std::size_t length)
+{
+    while (length > 0) {
+        const ssize_t written = ::write(fd, data, length);
+        if (written <= 0) {
+            return;
+        }
+        data += written;
+        length -= static_cast<std::size_t>(written);
+    }
+}
+
+void writeText(int fd, const char* text)
+{
+    writeRaw(fd, text, std::strlen(text));
+}
+
+// Decimal into buffer (at least 21 bytes); returns the digits' length.
+std::size_t formatInt(char* buffer, long long value)
+{
+    char digits[21];
+    std::size_t count = 0;
+    const bool negative = value < 0;
+    unsigned long long magnitude = negative ? 0ULL - static_cast<unsigned long long>(value)
+                                            : static_cast<unsigned long long>(value);
+    do {
+        digits[count++] = static_cast<char>('0' + magnitude % 10);
+        magnitude /= 10;
+    } while (magnitude > 0);
+    std::size_t length = 0;
+    if (negative) {
+        buffer[length++] = '-';
+    }
+    while (count > 0) {
+        buffer[length++] = digits[--count];
+    }
+    buffer[length] = '\0';
+    return length;
+}
+
+void writeInt(int fd, long long value)
+{
+    char buffer[24];
+    writeRaw(fd, buffer, formatInt(buffer, value));
+}
+
+const char* signalName(int signal)
+{
+    switch (signal) {
+    case SIGSEGV: return "SIGSEGV";
+    case SIGBUS:  return "SIGBUS";
+    case SIGILL:  return "SIGILL";
+    case SIGFPE:  return "SIGFPE";
+    case SIGABRT: return "SIGABRT";
+    default:      return "signal";
+    }
+}
+
+void writeRecentLog(int fd)
+{
+    const std::size_t written = g_logWritten.load(std::memory_order_acquire);
+    if (written <= CrashReport::kLogRingBytes) {
+        writeRaw(fd, g_logRing, written);
+        return;
+    }
+    // Wrapped: the oldest bytes start at the write position. Skip to the
+    // first whole line.
+    std::size_t start = written % CrashReport::kLogRingBytes;
+    std::size_t skipped = 0;
+    while (skipped < CrashReport::kLogRingBytes && g_logRing[start] != '\n') {
+        start = (start + 1) % CrashReport::kLogRingBytes;
+        ++skipped;
+    }
+    start = (start + 1) % CrashReport::kLogRingBytes;
+    const std::size_t end = written % CrashReport::kLogRingBytes;
+    if (start <= end) {
+        writeRaw(fd, g_logRing + start, end - start);
+    } else {
+        writeRaw(fd, g_logRing + start, CrashReport::kLogRingBytes - start);
+        writeRaw(fd, g_logRing, end);
+    }
+}
+
+void onFatalSignal(int signal)
+{
+    // A second fault while reporting goes straight to the default action.
+    static std::atomic_flag reporting = ATOMIC_FLAG_INIT;
+    if (!reporting.test_and_set()) {
+        char path[sizeof(g_pathPrefix) + 64];
+        std::size_t length = std::strlen(g_pathPrefix);
+        std::memcpy(path, g_pathPrefix, length);
+        length += formatInt(path + length, static_cast<long long>(std::time(nullptr)));
+        path[length++] = '-';
+        length += formatInt(path + length, static_cast<long long>(::getpid()));
+        std::memcpy(path + length, ".crash", sizeof(".crash"));
+
+        const int fd = ::open(path, O_WRONLY | O_CREAT | O_TRUNC, 0600);
+        writeText(STDERR_FILENO, g_service);
+        writeText(STDERR_FILENO, ": fatal ");
+        writeText(STDERR_FILENO, signalName(signal));
+        if (fd >= 0) {
+            CrashReport::writeReport(fd, signal);
+            ::close(fd);
+            writeText(STDERR_FILENO, ", crash report written to ");
+            writeText(STDERR_FILENO, path);
+        }
+        writeText(STDERR_FILENO, "\n");
+    }
+    // SA_RESETHAND restored the default action.
+    ::raise(signal);
+}
+
+const QRegularExpression& secretKeyPattern()
+{
+    // The audit log's pattern, plus what carries credentials in config:
+    // OTEL_EXPORTER_OTLP_HEADERS, cookies, authorization values.
+    static const QRegularExpression kPattern(
+        QStringLiteral("token|secret|password|passwd|credential|key$|cookie|authoriz|headers"),
+        QRegularExpression::CaseInsensitiveOption);
+    return kPattern;
+}
+
+QString stripUserInfo(const QString& value)
+{
+    static const QRegularExpression kUserInfo(QStringLiteral("(://)[^/@\\s]+@"));
+    QString stripped = value;
+    return stripped.replace(kUserInfo, QStringLiteral("\\1"));
+}
+
+QJsonValue stripValue(const QJsonValue& value);
+
+QJsonArray stripArray(const QJsonArray& array)
+{
+    QJsonArray out;
+    for (const QJsonValue& value : array) {
+        out.append(stripValue(value));
+    }
+    return out;
+}
+
+QJsonValue stripValue(const QJsonValue& value)
+{
+    if (value.isObject()) {
+        return CrashReport::stripSecrets(value.toObject());
+    }
+    if (value.isArray()) {
+        return stripArray(value.toArray());
+    }
+    if (value.isString()) {
+        return stripUserInfo(value.toString());
+    }
+    return value;
+}
+
+} // namespace
+
+QString CrashReport::defaultDirectory()
+{
+    QString dataDir = qEnvironmentVariable("BETTERSPOTLIGHT_DATA_DIR").trimmed();
+    if (dataDir.isEmpty()) {
+        dataDir = QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+                  + QStringLiteral("/betterspotlight");
+    }
+    return QDir::cleanPath(dataDir) + QStringLiteral("/crashes");
+}
+
+void CrashReport::install(const QString& service, const QString& directory)
+{
+    QDir().mkpath(directory);
+    {
+        std::lock_guard<std::mutex> lock(g_mutex);
+        copyTruncated(g_service, sizeof(g_service), service.toUtf8());
+        copyTruncated(g_version, sizeof(g_version),
+                      QCoreApplication::applicationVersion().toUtf8());
+        copyTruncated(g_pathPrefix, sizeof(g_pathPrefix),
+                      QDir(directory).filePath(service + QLatin1Char('-')).toUtf8());
+    }
+    if (g_installed) {
+        return;
+    }
+    g_installed = true;
+
+    // backtrace() loads its unwinder on first use, which allocates; do that
+    // now rather than in the handler.
+    void* frames[1];
+    (void)::backtrace(frames, 1);
+
+    // Overflowing the main thread's stack must still leave room to report.
+    stack_t altStack {};
+    altStack.ss_sp = g_altStack;
+    altStack.ss_size = sizeof(g_altStack);
+    ::sigaltstack(&altStack, nullptr);
+
+    struct sigaction action {};
+    action.sa_handler = onFatalSignal;
+    sigemptyset(&action.sa_mask);
+    action.sa_flags = SA_RESETHAND | SA_ONSTACK;
+    for (const int signal : kFatalSignals) {
+        ::sigaction(signal, &action, nullptr);
+    }
+}
+
+void CrashReport::setContext(const QString& key, const QJsonObject& value)
+{
+    std::lock_guard<std::mutex> lock(g_mutex);
+    g_contextObject[key] = stripSecrets(value);
+    const QByteArray json = QJsonDocument(g_contextObject).toJson(QJsonDocument::Indented);
+    const std::string* next = new std::string(json.constData(), static_cast<size_t>(json.size()));
+    delete g_retiredContext;
+    g_retiredContext = g_context.exchange(next, std::memory_order_acq_rel);
+}
+
+QJsonObject CrashReport::context()
+{
+    std::lock_guard<std::mutex> lock(g_mutex);
+    return g_contextObject;
+}
+
+void CrashReport::recordLogLine(const QByteArray& line)
+{
+    std::lock_guard<std::mutex> lock(g_mutex);
+    const char* data = line.constData();
+    std::size_t length = static_cast<std::size_t>(line.size());
+    if (length > kLogRingBytes) {
+        data += length - kLogRingBytes;
+        length = kLogRingBytes;
+    }
+    std::size_t written = g_logWritten.load(std::memory_order_relaxed);
+    while (length > 0) {
+        const std::size_t position = written % kLogRingBytes;
+        const std::size_t chunk = std::min(length, kLogRingBytes - position);
+        std::memcpy(g_logRing + position, data, chunk);
+        data += chunk;
+        length -= chunk;
+        written += chunk;
+    }
+    g_logWritten.store(written, std::memory_order_release);
+}
+
+QJsonObject CrashReport::stripSecrets(const QJsonObject& object)
+{
+    QJsonObject out;
+    for (auto it = object.begin(); it != object.end(); ++it) {
+        const bool secret = secretKeyPattern().match(it.key()).hasMatch()
+            && !it.value().isBool() && !it.value().isNull();
+        out[it.key()] = secret ? QJsonValue(QStringLiteral("[redacted]")) : stripValue(it.value());
+    }
+    return out;
+}
+
+QJsonObject CrashReport::environmentConfig()
+{
+    const QProcessEnvironment environment = QProcessEnvironment::systemEnvironment();
+    QJsonObject config;
+    for (const QString& name : environment.keys()) {
+        if (name.startsWith(QLatin1String("BETTERSPOTLIGHT_"))
+            || name.startsWith(QLatin1String("OTEL_"))) {
+            config[name] = environment.value(name);
+        }
+    }
+    return stripSecrets(config);
+}
+
+void CrashReport::writeReport(int fd, int signal)
+{
+    writeText(fd, "BetterSpotlight crash report\nservice: ");
+    writeText(fd, g_service);
+    writeText(fd, "\nversion: ");
+    writeText(fd, g_version);
+    writeText(fd, "\npid: ");
+    writeInt(fd, static_cast<long long>(::getpid()));
+    writeText(fd, "\nsignal: ");
+    writeInt(fd, signal);
+    writeText(fd, " ");
+    writeText(fd, signalName(signal));
+    writeText(fd, "\ntime: ");
+    writeInt(fd, static_cast<long long>(std::time(nullptr)));
+
+    writeText(fd, "\n\n== stack ==\n");
+    void* frames[kMaxFrames];
+    const int frameCount = ::backtrace(frames, kMaxFrames);
+    ::backtrace_symbols_fd(frames, frameCount, fd);
+
+    writeText(fd, "\n== context ==\n");
+    if (const std::string* context = g_context.load(std::memory_order_acquire)) {
+        writeRaw(fd, context->data(), context->size());
+    } else {
+        writeText(fd, "{}\n");
+    }
+
+    writeText(fd, "\n== recent log ==\n");
+    writeRecentLog(fd);
+}
+
+std::vector<CrashReport::Report> CrashReport::list(const QString& directory)
+{
+    static const QRegularExpression kName(QStringLiteral("^(.+)-(\\d+)-(\\d+)\\.crash$"));
+    static const QRegularExpression kSignal(QStringLiteral("^signal: (\\d+)"),
+                                            QRegularExpression::MultilineOption);
+    std::vector<Report> reports;
+    const QFileInfoList files = QDir(directory).entryInfoList(
+        {QStringLiteral("*.crash")}, QDir::Files);
+    for (const QFileInfo& file : files) {
+        const QRegularExpressionMatch name = kName.match(file.fileName());
+        if (!name.hasMatch()) {
+            continue;
+        }
+        Report report;
+        report.path = file.absoluteFilePath();
+        report.service = name.captured(1);
+        report.crashedAt = name.captured(2).toLongLong();
+        report.pid = name.captured(3).toLongLong();
+        QFile handle(report.path);
+        if (handle.open(QIODevice::ReadOnly)) {
+            const QRegularExpressionMatch signal =
+                kSignal.match(QString::fromUtf8(handle.read(512)));
+            report.signal = signal.hasMatch() ? signal.captured(1).toInt() : 0;
+        }
+        reports.push_back(std::move(report));
+    }
+    std::sort(reports.begin(), reports.end(), [](const Report& a, const Report& b) {
+        return a.crashedAt != b.crashedAt ? a.crashedAt > b.crashedAt : a.path < b.path;
+    });
+    return reports;
+}
+
+} // namespace bs
@@ -0,0 +1,74 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QString>
+
+#include <cstddef>
+#include <vector>
+
+namespace bs {
+
+// CrashReport -- what a service leaves behind when it dies on a fatal
+// signal.
+//
+// install() hooks SIGSEGV, SIGBUS, SIGILL, SIGFPE and SIGABRT; qFatal and
+// uncaught exceptions end in abort(), so they are covered too. A signal
+// handler may not allocate or lock, so everything but the stack is
+// prepared while the process is healthy: Logging hands every record to
+// recordLogLine(), which keeps the last kLogRingBytes, and services publish
+// what the report should carry (configuration, index metadata) with
+// setContext() whenever it changes. On a crash the handler writes
+// <directory>/<service>-<epoch>-<pid>.crash, says so on stderr and
+// re-raises the signal, so the exit status the supervisor sees is
+// unchanged.
+//
+// Context goes through stripSecrets() before it is kept. Log lines are kept
+// as written, so whatever the log holds, file paths included, the report
+// holds too.
+class CrashReport {
+public:
+    static constexpr std::size_t kLogRingBytes = 64 * 1024;
+
+    // <data dir>/crashes, honouring BETTERSPOTLIGHT_DATA_DIR like the
+    // services.
+    static QString defaultDirectory();
+
+    // Call once per process, early. Later calls only update the service
+    // name and the directory.
+    static void install(const QString& service, const QString& directory = defaultDirectory());
+
+    // Replaces one top-level key of the context ("service", "config",
+    // "index", ...).
+    static void setContext(const QString& key, const QJsonObject& value);
+    static QJsonObject context();
+
+    static void recordLogLine(const QByteArray& line);
+
+    // Values under keys that name a credential (token, secret, password,
+    // ...key, cookie, authorization, headers) become "[redacted]" at any
+    // depth; switches (booleans) are kept. user:password@ is dropped from
+    // URLs in any string.
+    static QJsonObject stripSecrets(const QJsonObject& object);
+
+    // The BETTERSPOTLIGHT_* and OTEL_* variables of this process, stripped.
+    static QJsonObject environmentConfig();
+
+    // Writes the report for `signal` to an open descriptor, exactly as the
+    // handler does. Async-signal-safe.
+    static void writeReport(int fd, int signal);
+
+    struct Report {
+        QString path;
+        QString service;
+        qint64 crashedAt = 0;  // epoch seconds
+        qint64 pid = 0;
+        int signal = 0;
+    };
+
+    // Reports in `directory`, newest first. Files that do not follow the
+    // naming scheme are skipped.
+    static std::vector<Report> list(const QString& directory = defaultDirectory());
+};
+
+} // namespace bs
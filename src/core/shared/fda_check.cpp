@@ -1,12 +1,77 @@
 #include "core/shared/fda_check.h"
 #include "core/shared/logging.h"
 
+#include <QDir>
+#include <QFile>
+#include <QJsonArray>
 #include <QStandardPaths>
 
+#include <algorithm>
+#include <cerrno>
 #include <dirent.h>
 
 namespace bs {
 
+namespace {
+
+QJsonArray foldersToJson(const std::vector<UnreadableFolder>& folders)
+{
+    QJsonArray out;
+    for (const UnreadableFolder& folder : folders) {
+        QJsonObject json;
+        json[QStringLiteral("path")] = folder.path;
+        json[QStringLiteral("reason")] = FdaCheck::reasonToString(folder.reason);
+        out.append(json);
+    }
+    return out;
+}
+
+bool anyReason(const FileAccessReport& report, FolderAccess reason)
+{
+    const auto matches = [reason](const UnreadableFolder& folder) {
+        return folder.reason == reason;
+    };
+    return std::any_of(report.unreadableRoots.begin(), report.unreadableRoots.end(), matches)
+           || std::any_of(report.protectedFolders.begin(), report.protectedFolders.end(), matches);
+}
+
+bool isWithin(const QString& path, const QString& root)
+{
+    return path == root || path.startsWith(root + QLatin1Char('/'));
+}
+
+} // namespace
+
+QJsonObject FileAccessReport::toJson() const
+{
+    // One step per kind of failure, in the order the user is likely to fix them.
+    QStringList steps;
+    if (anyReason(*this, FolderAccess::PrivacyDenied)) {
+        steps.append(QStringLiteral(
+            "Open System Settings > Privacy & Security > Full Disk Access, enable "
+            "BetterSpotlight, then restart it."));
+    }
+    if (anyReason(*this, FolderAccess::PermissionDenied)) {
+        steps.append(QStringLiteral(
+            "Give your user read access to the listed folders (Finder > Get Info > "
+            "Sharing & Permissions)."));
+    }
+    if (anyReason(*this, FolderAccess::Missing)) {
+        steps.append(QStringLiteral(
+            "Reconnect the volume or remove the missing folder from the indexed roots."));
+    }
+    if (anyReason(*this, FolderAccess::Error)) {
+        steps.append(QStringLiteral("Check the indexer log for the listed folders."));
+    }
+
+    QJsonObject json;
+    json[QStringLiteral("fullDiskAccess")] = fullDiskAccess;
+    json[QStringLiteral("unreadableRoots")] = foldersToJson(unreadableRoots);
+    json[QStringLiteral("protectedFolders")] = foldersToJson(protectedFolders);
+    json[QStringLiteral("remediation")] = steps.join(QLatin1Char('\n'));
+    return json;
+}
+
 bool FdaCheck::hasFullDiskAccess()
 {
     // ~/Library/Mail/ is a well-known FDA-gated directory.
@@ -36,4 +101,78 @@ QString FdaCheck::instructionMessage()
         "4. Restart BetterSpotlight");
 }
 
+FolderAccess FdaCheck::probeFolder(const QString& path)
+{
+    DIR* dir = opendir(QFile::encodeName(path).constData());
+    if (dir) {
+        closedir(dir);
+        return FolderAccess::Readable;
+    }
+    // TCC refuses with EPERM; plain mode bits refuse with EACCES.
+    switch (errno) {
+    case EPERM:   return FolderAccess::PrivacyDenied;
+    case EACCES:  return FolderAccess::PermissionDenied;
+    case ENOENT:
+    case ENOTDIR: return FolderAccess::Missing;
+    default:      return FolderAccess::Error;
+    }
+}
+
+FileAccessReport FdaCheck::probeRoots(const QStringList& roots, const QString& homePath)
+{
+    FileAccessReport report;
+    report.fullDiskAccess =
+        probeFolder(homePath + QStringLiteral("/Library/Mail")) == FolderAccess::Readable;
+
+    QStringList readableRoots;
+    for (const QString& root : roots) {
+        const QString path = QDir::cleanPath(root);
+        const FolderAccess access = probeFolder(path);
+        if (access == FolderAccess::Readable) {
+            readableRoots.append(path);
+        } else {
+            report.unreadableRoots.push_back({path, access});
+        }
+    }
+
+    for (const QString& name : protectedFolderNames()) {
+        const QString path = QDir::cleanPath(homePath + QLatin1Char('/') + name);
+        const bool covered = std::any_of(readableRoots.begin(), readableRoots.end(),
+                                         [&path](const QString& root) {
+                                             return isWithin(path, root);
+                                         });
+        if (!covered) {
+            continue;
+        }
+        // Not every account has every folder; absent is not blocked.
+        const FolderAccess access = probeFolder(path);
+        if (access != FolderAccess::Readable && access != FolderAccess::Missing) {
+            report.protectedFolders.push_back({path, access});
+        }
+    }
+    return report;
+}
+
+QStringList FdaCheck::protectedFolderNames()
+{
+    return {
+        QStringLiteral("Desktop"),
+        QStringLiteral("Documents"),
+        QStringLiteral("Downloads"),
+        QStringLiteral("Library/Mobile Documents"),
+    };
+}
+
+QString FdaCheck::reasonToString(FolderAccess reason)
+{
+    switch (reason) {
+    case FolderAccess::Readable:         return QStringLiteral("readable");
+    case FolderAccess::PrivacyDenied:    return QStringLiteral("privacy_denied");
+    case FolderAccess::PermissionDenied: return QStringLiteral("permission_denied");
+    case FolderAccess::Missing:          return QStringLiteral("missing");
+    case FolderAccess::Error:            return QStringLiteral("error");
+    }
+    return QStringLiteral("error");
+}
+
 } // namespace bs
@@ -1,9 +1,44 @@
 #pragma once
 
+#include <QJsonObject>
 #include <QString>
+#include <QStringList>
+
+#include <vector>
 
 namespace bs {
 
+// Why a folder could not be listed.
+enum class FolderAccess {
+    Readable,
+    PrivacyDenied,    // EPERM: blocked by macOS privacy (TCC); needs a grant
+    PermissionDenied, // EACCES: ordinary file permissions
+    Missing,          // ENOENT/ENOTDIR: unmounted volume, moved folder
+    Error,
+};
+
+struct UnreadableFolder {
+    QString path;
+    FolderAccess reason = FolderAccess::Error;
+};
+
+// What the indexer can and cannot read of what it was asked to index.
+struct FileAccessReport {
+    bool fullDiskAccess = false;
+    // Configured roots that cannot be listed at all: nothing under them
+    // gets indexed.
+    std::vector<UnreadableFolder> unreadableRoots;
+    // TCC-protected folders (Desktop, Documents, ...) inside a readable
+    // root that are blocked: the root indexes, minus these.
+    std::vector<UnreadableFolder> protectedFolders;
+
+    bool blocksIndexing() const { return !unreadableRoots.empty() || !protectedFolders.empty(); }
+
+    // {fullDiskAccess, unreadableRoots: [{path, reason}], protectedFolders:
+    // [...], remediation}. `remediation` is empty when nothing is blocked.
+    QJsonObject toJson() const;
+};
+
 // FdaCheck -- verifies Full Disk Access permissions on macOS.
 //
 // macOS requires Full Disk Access (FDA) for apps to read certain
@@ -18,6 +53,21 @@ public:
 
     // Returns a user-friendly message explaining how to grant FDA.
     static QString instructionMessage();
+
+    // Lists `path` once and classifies the failure, if any.
+    static FolderAccess probeFolder(const QString& path);
+
+    // Probes every root, then the protected folders under `homePath` that
+    // fall inside a readable root. Does not log; cheap enough to call per
+    // status request.
+    static FileAccessReport probeRoots(const QStringList& roots, const QString& homePath);
+
+    // Home-relative content folders macOS gates behind privacy prompts.
+    // Mail and Messages are excluded from indexing anyway, so only the FDA
+    // probe looks at them.
+    static QStringList protectedFolderNames();
+
+    static QString reasonToString(FolderAccess reason);
 };
 
 } // namespace bs
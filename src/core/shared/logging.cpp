@@ -1,4 +1,18 @@
 #include "core/shared/logging.h"
+#include "core/shared/crash_report.h"
+#include "core/shared/tracing.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFile>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QMutex>
+#include <QStandardPaths>
+#include <QTimeZone>
+
+#include <cstdio>
+#include <cstdlib>
 
 Q_LOGGING_CATEGORY(bsCore,       "bs.core")
 Q_LOGGING_CATEGORY(bsIndex,      "bs.index")
@@ -6,3 +20,391 @@ Q_LOGGING_CATEGORY(bsExtraction, "bs.extraction")
 Q_LOGGING_CATEGORY(bsFs,         "bs.fs")
 Q_LOGGING_CATEGORY(bsRanking,    "bs.ranking")
 Q_LOGGING_CATEGORY(bsIpc,        "bs.ipc")
+Q_LOGGING_CATEGORY(bsEmbedding,  "bs.embedding")
+Q_LOGGING_CATEGORY(bsVector,     "bs.vector")
+Q_LOGGING_CATEGORY(bsFeedback,   "bs.feedback")
+
+namespace bs {
+
+namespace {
+
+// Every category declared above; categories are created on first use, so
+// the filter cannot be asked for the list.
+const QStringList kModules = {
+    QStringLiteral("bs.core"),      QStringLiteral("bs.index"),  QStringLiteral("bs.extraction"),
+    QStringLiteral("bs.fs"),        QStringLiteral("bs.ranking"), QStringLiteral("bs.ipc"),
+    QStringLiteral("bs.embedding"), QStringLiteral("bs.vector"), QStringLiteral("bs.feedback"),
+};
+
+struct LogState {
+    QMutex mutex;
+    QString service;
+    Logging::Options options;
+    QFile file;
+    qint64 fileBytes = 0;
+    QLoggingCategory::CategoryFilter previousFilter = nullptr;
+    bool installed = false;
+};
+
+LogState& logState()
+{
+    static LogState state;
+    return state;
+}
+
+Logging::Level levelForMessage(QtMsgType type)
+{
+    switch (type) {
+    case QtDebugMsg:    return Logging::Level::Debug;
+    case QtInfoMsg:     return Logging::Level::Info;
+    case QtWarningMsg:  return Logging::Level::Warning;
+    case QtCriticalMsg:
+    case QtFatalMsg:    return Logging::Level::Critical;
+    }
+    return Logging::Level::Critical;
+}
+
+const char* consoleLevelName(QtMsgType type)
+{
+    switch (levelForMessage(type)) {
+    case Logging::Level::Debug:    return "DEBUG";
+    case Logging::Level::Info:     return "INFO ";
+    case Logging::Level::Warning:  return "WARN ";
+    case Logging::Level::Critical: return "ERROR";
+    }
+    return "ERROR";
+}
+
+QString rotatedPath(const LogState& state, int index)
+{
+    const QString name = index == 0
+        ? QStringLiteral("%1.log").arg(state.service)
+        : QStringLiteral("%1.%2.log").arg(state.service).arg(index);
+    return QDir(state.options.directory).filePath(name);
+}
+
+// Called with the mutex held. Must not log: the handler is the caller.
+void openLogFile(LogState& state)
+{
+    state.file.close();
+    state.fileBytes = 0;
+    if (state.options.directory.isEmpty()) {
+        return;
+    }
+    if (!QDir().mkpath(state.options.directory)) {
+        std::fprintf(stderr, "Cannot create log directory %s\n",
+                     qUtf8Printable(state.options.directory));
+        return;
+    }
+    state.file.setFileName(rotatedPath(state, 0));
+    if (!state.file.open(QIODevice::WriteOnly | QIODevice::Append)) {
+        std::fprintf(stderr, "Cannot open log file %s\n", qUtf8Printable(state.file.fileName()));
+        return;
+    }
+    state.file.setPermissions(QFile::ReadOwner | QFile::WriteOwner);
+    state.fileBytes = state.file.size();
+}
+
+void rotateIfNeeded(LogState& state, qint64 incomingBytes)
+{
+    if (state.fileBytes == 0 || state.fileBytes + incomingBytes <= state.options.maxFileBytes) {
+        return;
+    }
+    state.file.close();
+    QFile::remove(rotatedPath(state, state.options.maxFiles - 1));
+    for (int index = state.options.maxFiles - 2; index >= 0; --index) {
+        if (QFileInfo::exists(rotatedPath(state, index))) {
+            QFile::rename(rotatedPath(state, index), rotatedPath(state, index + 1));
+        }
+    }
+    openLogFile(state);
+}
+
+void messageHandler(QtMsgType type, const QMessageLogContext& context, const QString& message)
+{
+    LogState& state = logState();
+    const TraceContext trace = Tracer::current();
+    const QString module = context.category ? QString::fromLatin1(context.category)
+                                            : QStringLiteral("default");
+    {
+        QMutexLocker lock(&state.mutex);
+        const QByteArray line =
+            Logging::formatRecord(state.options.format, QDateTime::currentMSecsSinceEpoch(), type,
+                                  state.service, module, message,
+                                  trace.sampled ? trace.traceId : QByteArray())
+            + '\n';
+        std::fwrite(line.constData(), 1, static_cast<size_t>(line.size()), stderr);
+        std::fflush(stderr);
+        CrashReport::recordLogLine(line);
+        if (state.file.isOpen()) {
+            rotateIfNeeded(state, line.size());
+            if (state.file.isOpen() && state.file.write(line) == line.size()) {
+                state.file.flush();
+                state.fileBytes += line.size();
+            }
+        }
+    }
+    if (type == QtFatalMsg) {
+        std::abort();
+    }
+}
+
+void categoryFilter(QLoggingCategory* category)
+{
+    LogState& state = logState();
+    if (state.previousFilter) {
+        state.previousFilter(category);
+    }
+    const QString name = QString::fromLatin1(category->categoryName());
+    // Qt's own categories (qt.*) keep Qt's rules.
+    if (!name.startsWith(QLatin1String("bs.")) && name != QLatin1String("default")) {
+        return;
+    }
+    Logging::Level level = Logging::Level::Info;
+    {
+        QMutexLocker lock(&state.mutex);
+        level = state.options.moduleLevels.value(name, state.options.defaultLevel);
+    }
+    category->setEnabled(QtDebugMsg, level <= Logging::Level::Debug);
+    category->setEnabled(QtInfoMsg, level <= Logging::Level::Info);
+    category->setEnabled(QtWarningMsg, level <= Logging::Level::Warning);
+    category->setEnabled(QtCriticalMsg, true);
+}
+
+// Re-runs the filter over every existing category. Without the mutex held:
+// the registry calls back into categoryFilter.
+void reapplyLevels()
+{
+    QLoggingCategory::installFilter(categoryFilter);
+}
+
+} // namespace
+
+Logging::Options Logging::optionsFromEnvironment(QStringList* warnings)
+{
+    const auto warn = [warnings](const QString& message) {
+        if (warnings) {
+            warnings->append(message);
+        }
+    };
+
+    Options options;
+    const QString format = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_FORMAT").trimmed().toLower();
+    if (format == QLatin1String("json")) {
+        options.format = Format::Json;
+    } else if (!format.isEmpty() && format != QLatin1String("console")) {
+        warn(QStringLiteral("BETTERSPOTLIGHT_LOG_FORMAT '%1' is not console or json").arg(format));
+    }
+
+    const QString level = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_LEVEL").trimmed();
+    if (!level.isEmpty()) {
+        if (const auto parsed = parseLevel(level)) {
+            options.defaultLevel = parsed.value();
+        } else {
+            warn(QStringLiteral("BETTERSPOTLIGHT_LOG_LEVEL '%1' is not a level").arg(level));
+        }
+    }
+
+    const QString modules = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_MODULES");
+    for (const QString& entry : modules.split(QLatin1Char(','), Qt::SkipEmptyParts)) {
+        const qsizetype equals = entry.indexOf(QLatin1Char('='));
+        const auto module = moduleName(entry.left(equals).trimmed());
+        const auto moduleLevel =
+            equals > 0 ? parseLevel(entry.mid(equals + 1).trimmed()) : std::nullopt;
+        if (!module.has_value() || !moduleLevel.has_value()) {
+            warn(QStringLiteral("BETTERSPOTLIGHT_LOG_MODULES entry '%1' is not module=level")
+                     .arg(entry.trimmed()));
+            continue;
+        }
+        options.moduleLevels.insert(module.value(), moduleLevel.value());
+    }
+
+    const QString directory = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_DIR").trimmed();
+    if (directory.isEmpty()) {
+        options.directory = defaultDirectory();
+    } else if (directory != QLatin1String("-")) {
+        options.directory = QDir::cleanPath(directory);
+    }
+
+    bool ok = false;
+    const QString maxBytes = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_MAX_BYTES").trimmed();
+    if (!maxBytes.isEmpty()) {
+        const qint64 value = maxBytes.toLongLong(&ok);
+        if (ok && value >= 64 * 1024) {
+            options.maxFileBytes = value;
+        } else {
+            warn(QStringLiteral("BETTERSPOTLIGHT_LOG_MAX_BYTES '%1' is below 65536 or not a "
+                                "number").arg(maxBytes));
+        }
+    }
+    const QString maxFiles = qEnvironmentVariable("BETTERSPOTLIGHT_LOG_MAX_FILES").trimmed();
+    if (!maxFiles.isEmpty()) {
+        const int value = maxFiles.toInt(&ok);
+        if (ok && value >= 1) {
+            options.maxFiles = value;
+        } else {
+            warn(QStringLiteral("BETTERSPOTLIGHT_LOG_MAX_FILES '%1' is not a positive number")
+                     .arg(maxFiles));
+        }
+    }
+    return options;
+}
+
+QString Logging::defaultDirectory()
+{
+    QString dataDir = qEnvironmentVariable("BETTERSPOTLIGHT_DATA_DIR").trimmed();
+    if (dataDir.isEmpty()) {
+        dataDir = QStandardPaths::writableLocation(QStandardPaths::GenericDataLocation)
+                  + QStringLiteral("/betterspotlight");
+    }
+    return QDir::cleanPath(dataDir) + QStringLiteral("/logs");
+}
+
+void Logging::install(const QString& service, const Options& options)
+{
+    LogState& state = logState();
+    {
+        QMutexLocker lock(&state.mutex);
+        state.service = service;
+        state.options = options;
+        openLogFile(state);
+    }
+    qInstallMessageHandler(messageHandler);
+    if (!state.installed) {
+        state.installed = true;
+        state.previousFilter = QLoggingCategory::installFilter(categoryFilter);
+    } else {
+        reapplyLevels();
+    }
+}
+
+void Logging::install(const QString& service)
+{
+    QStringList warnings;
+    install(service, optionsFromEnvironment(&warnings));
+    for (const QString& warning : warnings) {
+        LOG_WARN(bsCore, "%s", qUtf8Printable(warning));
+    }
+}
+
+std::optional<QString> Logging::moduleName(const QString& name)
+{
+    const QString lower = name.trimmed().toLower();
+    const QString full = lower.startsWith(QLatin1String("bs.")) ? lower
+                                                                : QStringLiteral("bs.") + lower;
+    if (!kModules.contains(full)) {
+        return std::nullopt;
+    }
+    return full;
+}
+
+std::optional<Logging::Level> Logging::parseLevel(const QString& name)
+{
+    const QString lower = name.trimmed().toLower();
+    if (lower == QLatin1String("debug")) {
+        return Level::Debug;
+    }
+    if (lower == QLatin1String("info")) {
+        return Level::Info;
+    }
+    if (lower == QLatin1String("warning") || lower == QLatin1String("warn")) {
+        return Level::Warning;
+    }
+    if (lower == QLatin1String("critical") || lower == QLatin1String("error")) {
+        return Level::Critical;
+    }
+    return std::nullopt;
+}
+
+QString Logging::levelName(Level level)
+{
+    switch (level) {
+    case Level::Debug:    return QStringLiteral("debug");
+    case Level::Info:     return QStringLiteral("info");
+    case Level::Warning:  return QStringLiteral("warning");
+    case Level::Critical: return QStringLiteral("critical");
+    }
+    return QStringLiteral("critical");
+}
+
+void Logging::setDefaultLevel(Level level)
+{
+    LogState& state = logState();
+    {
+        QMutexLocker lock(&state.mutex);
+        state.options.defaultLevel = level;
+    }
+    reapplyLevels();
+}
+
+bool Logging::setModuleLevel(const QString& module, std::optional<Level> level)
+{
+    const auto name = moduleName(module);
+    if (!name.has_value()) {
+        return false;
+    }
+    LogState& state = logState();
+    {
+        QMutexLocker lock(&state.mutex);
+        if (level.has_value()) {
+            state.options.moduleLevels.insert(name.value(), level.value());
+        } else {
+            state.options.moduleLevels.remove(name.value());
+        }
+    }
+    reapplyLevels();
+    return true;
+}
+
+QJsonObject Logging::describe()
+{
+    LogState& state = logState();
+    QMutexLocker lock(&state.mutex);
+    QJsonObject modules;
+    for (const QString& module : kModules) {
+        const auto it = state.options.moduleLevels.constFind(module);
+        QJsonObject entry;
+        entry[QStringLiteral("level")] =
+            levelName(it != state.options.moduleLevels.constEnd() ? it.value()
+                                                                  : state.options.defaultLevel);
+        entry[QStringLiteral("override")] = it != state.options.moduleLevels.constEnd();
+        modules[module] = entry;
+    }
+    QJsonObject result;
+    result[QStringLiteral("default")] = levelName(state.options.defaultLevel);
+    result[QStringLiteral("format")] = state.options.format == Format::Json
+        ? QStringLiteral("json") : QStringLiteral("console");
+    result[QStringLiteral("file")] = state.file.isOpen() ? QJsonValue(state.file.fileName())
+                                                         : QJsonValue(QJsonValue::Null);
+    result[QStringLiteral("modules")] = modules;
+    return result;
+}
+
+QByteArray Logging::formatRecord(Format format, qint64 timestampMs, QtMsgType type,
+                                 const QString& service, const QString& module,
+                                 const QString& message, const QByteArray& traceId)
+{
+    if (format == Format::Json) {
+        QJsonObject record;
+        record[QStringLiteral("ts")] = static_cast<double>(timestampMs) / 1000.0;
+        record[QStringLiteral("level")] = levelName(levelForMessage(type));
+        record[QStringLiteral("service")] = service;
+        record[QStringLiteral("module")] = module;
+        record[QStringLiteral("msg")] = message;
+        if (!traceId.isEmpty()) {
+            record[QStringLiteral("traceId")] = QString::fromLatin1(traceId);
+        }
+        return QJsonDocument(record).toJson(QJsonDocument::Compact);
+    }
+
+    QString line = QStringLiteral("%1 %2 [%3] %4: %5")
+        .arg(QDateTime::fromMSecsSinceEpoch(timestampMs, QTimeZone::utc())
+                 .toString(Qt::ISODateWithMs),
+             QLatin1String(consoleLevelName(type)), service, module, message);
+    if (!traceId.isEmpty()) {
+        line += QStringLiteral(" trace=") + QString::fromLatin1(traceId);
+    }
+    return line.toUtf8();
+}
+
+} // namespace bs
@@ -1,6 +1,12 @@
 #pragma once
 
+#include <QHash>
+#include <QJsonObject>
 #include <QLoggingCategory>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
 
 Q_DECLARE_LOGGING_CATEGORY(bsCore)
 Q_DECLARE_LOGGING_CATEGORY(bsIndex)
@@ -8,8 +14,79 @@ Q_DECLARE_LOGGING_CATEGORY(bsExtraction)
 Q_DECLARE_LOGGING_CATEGORY(bsFs)
 Q_DECLARE_LOGGING_CATEGORY(bsRanking)
 Q_DECLARE_LOGGING_CATEGORY(bsIpc)
+Q_DECLARE_LOGGING_CATEGORY(bsEmbedding)
+Q_DECLARE_LOGGING_CATEGORY(bsVector)
+Q_DECLARE_LOGGING_CATEGORY(bsFeedback)
 
 #define LOG_DEBUG(cat, ...) qCDebug(cat, __VA_ARGS__)
 #define LOG_INFO(cat, ...)  qCInfo(cat, __VA_ARGS__)
 #define LOG_WARN(cat, ...)  qCWarning(cat, __VA_ARGS__)
 #define LOG_ERROR(cat, ...) qCCritical(cat, __VA_ARGS__)
+
+namespace bs {
+
+// Logging -- how a process writes its log.
+//
+// One line per message, console text or JSON, to stderr and, with a log
+// directory, to <directory>/<service>.log. A file that would grow past
+// maxFileBytes is rotated to <service>.1.log, <service>.2.log, ...; files
+// beyond maxFiles are deleted oldest first.
+//
+// Each module (a bs.* category: bs.index, bs.extraction, ...) logs at the
+// default level unless it has an override, so one subsystem can go to
+// debug without the rest. Levels can change while the process runs
+// (ServiceBase `setLogLevel`); they last until it exits.
+class Logging {
+public:
+    enum class Format { Console, Json };
+    enum class Level { Debug, Info, Warning, Critical };
+
+    static constexpr qint64 kDefaultMaxFileBytes = 10 * 1024 * 1024;
+    static constexpr int kDefaultMaxFiles = 5;
+
+    struct Options {
+        Format format = Format::Console;
+        Level defaultLevel = Level::Info;
+        QHash<QString, Level> moduleLevels;  // full category names
+        QString directory;                   // empty: stderr only
+        qint64 maxFileBytes = kDefaultMaxFileBytes;
+        int maxFiles = kDefaultMaxFiles;
+    };
+
+    // BETTERSPOTLIGHT_LOG_FORMAT (console|json), BETTERSPOTLIGHT_LOG_LEVEL,
+    // BETTERSPOTLIGHT_LOG_MODULES ("extraction=debug,ipc=warning"),
+    // BETTERSPOTLIGHT_LOG_DIR (default <data dir>/logs; "-" for stderr
+    // only), BETTERSPOTLIGHT_LOG_MAX_BYTES and BETTERSPOTLIGHT_LOG_MAX_FILES.
+    // Unreadable values keep the default and are reported in *warnings.
+    static Options optionsFromEnvironment(QStringList* warnings = nullptr);
+
+    // <data dir>/logs, honouring BETTERSPOTLIGHT_DATA_DIR like the services.
+    static QString defaultDirectory();
+
+    // Installs the message handler and level filter for this process. Call
+    // once, early in main().
+    static void install(const QString& service, const Options& options);
+    static void install(const QString& service);
+
+    // "extraction" and "bs.extraction" name the same module. Nullopt for a
+    // name that is not a bs.* module.
+    static std::optional<QString> moduleName(const QString& name);
+    static std::optional<Level> parseLevel(const QString& name);
+    static QString levelName(Level level);
+
+    static void setDefaultLevel(Level level);
+    // Nullopt clears the override. False for an unknown module.
+    static bool setModuleLevel(const QString& module, std::optional<Level> level);
+
+    // {"default": "info", "format": "console", "file": "<path>" or null,
+    //  "modules": {"bs.index": {"level": "info", "override": false}, ...}}
+    static QJsonObject describe();
+
+    // One record as written, without the newline. `traceId` is omitted
+    // when empty.
+    static QByteArray formatRecord(Format format, qint64 timestampMs, QtMsgType type,
+                                   const QString& service, const QString& module,
+                                   const QString& message, const QByteArray& traceId = {});
+};
+
+} // namespace bs
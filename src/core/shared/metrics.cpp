@@ -0,0 +1,241 @@
+#include "core/shared/metrics.h"
+
+#include <QStringList>
+
+#include <algorithm>
+#include <cmath>
+
+namespace bs {
+
+namespace {
+
+constexpr double kWindowedMinSeconds = 0.0001;
+constexpr double kWindowedGrowth = 1.2;
+constexpr int kWindowedBuckets = 78;  // 0.1 ms * 1.2^77 is about 125 s
+
+QString escapeLabelValue(const QString& value)
+{
+    QString out;
+    out.reserve(value.size());
+    for (const QChar c : value) {
+        if (c == QLatin1Char('\\')) {
+            out += QStringLiteral("\\\\");
+        } else if (c == QLatin1Char('"')) {
+            out += QStringLiteral("\\\"");
+        } else if (c == QLatin1Char('\n')) {
+            out += QStringLiteral("\\n");
+        } else {
+            out += c;
+        }
+    }
+    return out;
+}
+
+QString escapeHelp(const QString& help)
+{
+    QString out = help;
+    out.replace(QLatin1Char('\\'), QStringLiteral("\\\\"));
+    out.replace(QLatin1Char('\n'), QStringLiteral("\\n"));
+    return out;
+}
+
+} // namespace
+
+const std::vector<double>& LatencyHistogram::defaultBounds()
+{
+    // Search answers in tens of milliseconds; the tail is semantic search
+    // and reranking under load.
+    static const std::vector<double> kBounds = {
+        0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0,
+    };
+    return kBounds;
+}
+
+LatencyHistogram::LatencyHistogram(std::vector<double> bounds)
+    : m_bounds(std::move(bounds))
+    , m_buckets(m_bounds.size(), 0)
+{
+}
+
+void LatencyHistogram::observe(double seconds)
+{
+    const auto it = std::lower_bound(m_bounds.begin(), m_bounds.end(), seconds);
+    if (it != m_bounds.end()) {
+        ++m_buckets[static_cast<size_t>(it - m_bounds.begin())];
+    }
+    ++m_count;
+    m_sum += seconds;
+}
+
+std::vector<uint64_t> LatencyHistogram::cumulativeCounts() const
+{
+    std::vector<uint64_t> cumulative(m_buckets.size(), 0);
+    uint64_t running = 0;
+    for (size_t i = 0; i < m_buckets.size(); ++i) {
+        running += m_buckets[i];
+        cumulative[i] = running;
+    }
+    return cumulative;
+}
+
+WindowedLatency::WindowedLatency(int slotSeconds, int maxWindowSeconds)
+    : m_slotMs(static_cast<qint64>(std::max(1, slotSeconds)) * 1000)
+    , m_slots(static_cast<size_t>(std::max(1, maxWindowSeconds / std::max(1, slotSeconds))) + 1)
+{
+}
+
+int WindowedLatency::bucketFor(double seconds)
+{
+    if (!(seconds > kWindowedMinSeconds)) {
+        return 0;
+    }
+    const int bucket = static_cast<int>(
+        std::ceil(std::log(seconds / kWindowedMinSeconds) / std::log(kWindowedGrowth)));
+    return std::clamp(bucket, 0, kWindowedBuckets - 1);
+}
+
+double WindowedLatency::bucketMidpointSeconds(int bucket)
+{
+    // Bucket b holds (min * g^(b-1), min * g^b]; the geometric midpoint
+    // keeps the relative error under 10%.
+    if (bucket == 0) {
+        return kWindowedMinSeconds;
+    }
+    return kWindowedMinSeconds * std::pow(kWindowedGrowth, static_cast<double>(bucket) - 0.5);
+}
+
+void WindowedLatency::observe(double seconds, bool error, bool hit, qint64 nowMs)
+{
+    const qint64 index = nowMs / m_slotMs;
+    Slot& slot = m_slots[static_cast<size_t>(index % static_cast<qint64>(m_slots.size()))];
+    if (slot.index != index) {
+        slot = Slot{};
+        slot.index = index;
+        slot.buckets.assign(kWindowedBuckets, 0);
+    }
+    ++slot.count;
+    slot.errors += error ? 1 : 0;
+    slot.hits += hit ? 1 : 0;
+    slot.maxSeconds = std::max(slot.maxSeconds, seconds);
+    ++slot.buckets[static_cast<size_t>(bucketFor(seconds))];
+}
+
+WindowedLatency::Summary WindowedLatency::summarize(int windowSeconds, qint64 nowMs) const
+{
+    const qint64 current = nowMs / m_slotMs;
+    const qint64 slotsWanted = std::clamp<qint64>(
+        (static_cast<qint64>(windowSeconds) * 1000 + m_slotMs - 1) / m_slotMs, 1,
+        static_cast<qint64>(m_slots.size()) - 1);
+
+    Summary summary;
+    std::vector<uint64_t> merged(kWindowedBuckets, 0);
+    double maxSeconds = 0.0;
+    for (const Slot& slot : m_slots) {
+        if (slot.index < 0 || slot.index > current || slot.index <= current - slotsWanted) {
+            continue;
+        }
+        summary.count += slot.count;
+        summary.errors += slot.errors;
+        summary.hits += slot.hits;
+        maxSeconds = std::max(maxSeconds, slot.maxSeconds);
+        for (int i = 0; i < kWindowedBuckets; ++i) {
+            merged[static_cast<size_t>(i)] += slot.buckets[static_cast<size_t>(i)];
+        }
+    }
+    if (summary.count == 0) {
+        return summary;
+    }
+
+    const auto quantileMs = [&](double q) {
+        const uint64_t rank = std::max<uint64_t>(
+            1, static_cast<uint64_t>(std::ceil(q * static_cast<double>(summary.count))));
+        uint64_t running = 0;
+        for (int i = 0; i < kWindowedBuckets; ++i) {
+            running += merged[static_cast<size_t>(i)];
+            if (running >= rank) {
+                return std::min(bucketMidpointSeconds(i), maxSeconds) * 1000.0;
+            }
+        }
+        return maxSeconds * 1000.0;
+    };
+    summary.p50Ms = quantileMs(0.50);
+    summary.p95Ms = quantileMs(0.95);
+    summary.p99Ms = quantileMs(0.99);
+    summary.maxMs = maxSeconds * 1000.0;
+    return summary;
+}
+
+void PrometheusText::gauge(const QString& name, const QString& help, double value,
+                           const Labels& labels)
+{
+    writeFamily(name, help, "gauge");
+    writeSample(name, labels, value);
+}
+
+void PrometheusText::counter(const QString& name, const QString& help, double value,
+                             const Labels& labels)
+{
+    writeFamily(name, help, "counter");
+    writeSample(name, labels, value);
+}
+
+void PrometheusText::histogram(const QString& name, const QString& help,
+                               const LatencyHistogram& histogram, const Labels& labels)
+{
+    writeFamily(name, help, "histogram");
+    const std::vector<uint64_t> cumulative = histogram.cumulativeCounts();
+    const QString bucketName = name + QStringLiteral("_bucket");
+    for (size_t i = 0; i < cumulative.size(); ++i) {
+        Labels bucketLabels = labels;
+        bucketLabels.emplace_back(QStringLiteral("le"), formatValue(histogram.bounds()[i]));
+        writeSample(bucketName, bucketLabels, static_cast<double>(cumulative[i]));
+    }
+    Labels infLabels = labels;
+    infLabels.emplace_back(QStringLiteral("le"), QStringLiteral("+Inf"));
+    writeSample(bucketName, infLabels, static_cast<double>(histogram.count()));
+    writeSample(name + QStringLiteral("_sum"), labels, histogram.sum());
+    writeSample(name + QStringLiteral("_count"), labels, static_cast<double>(histogram.count()));
+}
+
+QString PrometheusText::formatValue(double value)
+{
+    if (std::isnan(value)) {
+        return QStringLiteral("NaN");
+    }
+    if (std::isinf(value)) {
+        return value > 0 ? QStringLiteral("+Inf") : QStringLiteral("-Inf");
+    }
+    // Counts print as integers; 2^53 is where doubles stop holding them.
+    if (value == std::floor(value) && std::fabs(value) < 9007199254740992.0) {
+        return QString::number(static_cast<qint64>(value));
+    }
+    return QString::number(value, 'g', 15);
+}
+
+void PrometheusText::writeFamily(const QString& name, const QString& help, const char* type)
+{
+    if (m_families.contains(name)) {
+        return;
+    }
+    m_families.insert(name);
+    m_text += QStringLiteral("# HELP %1 %2\n# TYPE %1 %3\n")
+                  .arg(name, escapeHelp(help), QLatin1String(type))
+                  .toUtf8();
+}
+
+void PrometheusText::writeSample(const QString& name, const Labels& labels, double value)
+{
+    QString line = name;
+    if (!labels.empty()) {
+        QStringList parts;
+        parts.reserve(static_cast<qsizetype>(labels.size()));
+        for (const auto& [key, labelValue] : labels) {
+            parts.append(QStringLiteral("%1=\"%2\"").arg(key, escapeLabelValue(labelValue)));
+        }
+        line += QLatin1Char('{') + parts.join(QLatin1Char(',')) + QLatin1Char('}');
+    }
+    line += QLatin1Char(' ') + formatValue(value) + QLatin1Char('\n');
+    m_text += line.toUtf8();
+}
+
+} // namespace bs
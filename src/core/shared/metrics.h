@@ -0,0 +1,115 @@
+#pragma once
+
+#include <QByteArray>
+#include <QtGlobal>
+#include <QSet>
+#include <QString>
+
+#include <cstdint>
+#include <utility>
+#include <vector>
+
+namespace bs {
+
+// LatencyHistogram -- durations bucketed the way Prometheus histograms are.
+//
+// Not synchronized: record and read it on one thread. The query service
+// observes requests and serves /metrics from its event loop.
+class LatencyHistogram {
+public:
+    // Upper bounds in seconds, ascending; the +Inf bucket is implicit.
+    static const std::vector<double>& defaultBounds();
+
+    explicit LatencyHistogram(std::vector<double> bounds = defaultBounds());
+
+    void observe(double seconds);
+
+    const std::vector<double>& bounds() const { return m_bounds; }
+    // Observations at or below each bound (cumulative, as exposed).
+    std::vector<uint64_t> cumulativeCounts() const;
+    uint64_t count() const { return m_count; }
+    double sum() const { return m_sum; }
+
+private:
+    std::vector<double> m_bounds;
+    std::vector<uint64_t> m_buckets;  // per bound, not cumulative
+    uint64_t m_count = 0;
+    double m_sum = 0.0;
+};
+
+// WindowedLatency -- requests, errors and latency quantiles over the last
+// few minutes rather than since start.
+//
+// Time is cut into slots of slotSeconds, kept for maxWindowSeconds. Each
+// slot holds a log-scale histogram with buckets 20% apart (0.1 ms to about
+// two minutes), so memory does not grow with traffic and a quantile is
+// accurate to within its bucket. A window covers whole slots, the current
+// one included, so it slides a slot at a time. Not synchronized, like
+// LatencyHistogram.
+class WindowedLatency {
+public:
+    struct Summary {
+        uint64_t count = 0;
+        uint64_t errors = 0;
+        uint64_t hits = 0;  // observations flagged as hits (e.g. cache hits)
+        double p50Ms = 0.0;
+        double p95Ms = 0.0;
+        double p99Ms = 0.0;
+        double maxMs = 0.0;
+    };
+
+    explicit WindowedLatency(int slotSeconds = 10, int maxWindowSeconds = 900);
+
+    void observe(double seconds, bool error, bool hit, qint64 nowMs);
+    // Windows longer than maxWindowSeconds are cut to it.
+    Summary summarize(int windowSeconds, qint64 nowMs) const;
+
+private:
+    struct Slot {
+        qint64 index = -1;  // nowMs / slot length; -1 never used
+        uint64_t count = 0;
+        uint64_t errors = 0;
+        uint64_t hits = 0;
+        double maxSeconds = 0.0;
+        std::vector<uint32_t> buckets;
+    };
+
+    static int bucketFor(double seconds);
+    static double bucketMidpointSeconds(int bucket);
+
+    qint64 m_slotMs;
+    std::vector<Slot> m_slots;  // ring, by index modulo size
+};
+
+// PrometheusText -- builds a scrape in the Prometheus text format (0.0.4).
+//
+// Samples of one metric are expected to be added together: HELP and TYPE
+// are written before the first sample of each name only. Counter names
+// should end in `_total`.
+class PrometheusText {
+public:
+    static constexpr const char* kContentType = "text/plain; version=0.0.4; charset=utf-8";
+
+    using Labels = std::vector<std::pair<QString, QString>>;
+
+    void gauge(const QString& name, const QString& help, double value,
+               const Labels& labels = {});
+    void counter(const QString& name, const QString& help, double value,
+                 const Labels& labels = {});
+    // Writes name_bucket{le=...}, name_sum and name_count.
+    void histogram(const QString& name, const QString& help, const LatencyHistogram& histogram,
+                   const Labels& labels = {});
+
+    const QByteArray& text() const { return m_text; }
+
+    static QString formatValue(double value);
+
+private:
+    void writeFamily(const QString& name, const QString& help, const char* type);
+    void writeSample(const QString& name, const Labels& labels, double value);
+
+    QSet<QString> m_families;
+    QByteArray m_text;
+};
+
+} // namespace bs
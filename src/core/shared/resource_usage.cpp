@@ -0,0 +1,152 @@
+#include "core/shared/resource_usage.h"
+
+#include <QJsonValue>
+
+#include <algorithm>
+#include <cmath>
+
+#include <dirent.h>
+#include <sys/resource.h>
+#include <unistd.h>
+
+#if defined(__APPLE__)
+#include <libproc.h>
+#include <mach/mach.h>
+#endif
+
+namespace bs {
+
+namespace {
+
+double timevalSeconds(const timeval& value)
+{
+    return static_cast<double>(value.tv_sec) + static_cast<double>(value.tv_usec) / 1e6;
+}
+
+std::optional<int> countOpenFds()
+{
+    // /dev/fd lists this process's descriptors, the one reading it included.
+    DIR* dir = ::opendir("/dev/fd");
+    if (dir == nullptr) {
+        return std::nullopt;
+    }
+    int count = 0;
+    while (const dirent* entry = ::readdir(dir)) {
+        if (entry->d_name[0] != '.') {
+            ++count;
+        }
+    }
+    ::closedir(dir);
+    return count > 0 ? count - 1 : 0;
+}
+
+template <typename T>
+QJsonValue optionalJson(const std::optional<T>& value)
+{
+    return value.has_value() ? QJsonValue(static_cast<qint64>(*value)) : QJsonValue(QJsonValue::Null);
+}
+
+QJsonValue roundedJson(const std::optional<double>& value, double scale)
+{
+    return value.has_value() ? QJsonValue(std::round(*value * scale) / scale)
+                             : QJsonValue(QJsonValue::Null);
+}
+
+} // namespace
+
+QJsonObject ResourceUsage::toJson() const
+{
+    QJsonObject json;
+    json[QStringLiteral("cpuPercent")] = roundedJson(cpuPercent, 10.0);
+    json[QStringLiteral("cpuSeconds")] = std::round(current.cpuSeconds * 10.0) / 10.0;
+    json[QStringLiteral("rssBytes")] = optionalJson(current.rssBytes);
+    json[QStringLiteral("openFds")] = optionalJson(current.openFds);
+    json[QStringLiteral("fdLimit")] = optionalJson(current.fdLimit);
+    json[QStringLiteral("diskReadBytesPerSec")] = roundedJson(diskReadBytesPerSec, 1.0);
+    json[QStringLiteral("diskWriteBytesPerSec")] = roundedJson(diskWriteBytesPerSec, 1.0);
+    json[QStringLiteral("diskReadBytes")] = optionalJson(current.diskReadBytes);
+    json[QStringLiteral("diskWriteBytes")] = optionalJson(current.diskWriteBytes);
+    json[QStringLiteral("windowMs")] = static_cast<qint64>(windowMs);
+    return json;
+}
+
+ResourceSample ResourceMonitor::sampleProcess(int64_t nowMs)
+{
+    ResourceSample sample;
+    sample.atMs = nowMs;
+
+    // Sandboxed extraction runs in short-lived children; once reaped they
+    // count here, so the figure is what indexing costs, not just this pid.
+    rusage self {};
+    rusage children {};
+    if (::getrusage(RUSAGE_SELF, &self) == 0) {
+        sample.cpuSeconds += timevalSeconds(self.ru_utime) + timevalSeconds(self.ru_stime);
+    }
+    if (::getrusage(RUSAGE_CHILDREN, &children) == 0) {
+        sample.cpuSeconds += timevalSeconds(children.ru_utime) + timevalSeconds(children.ru_stime);
+    }
+
+    sample.openFds = countOpenFds();
+    rlimit limit {};
+    if (::getrlimit(RLIMIT_NOFILE, &limit) == 0 && limit.rlim_cur != RLIM_INFINITY) {
+        sample.fdLimit = static_cast<int64_t>(limit.rlim_cur);
+    }
+
+#if defined(__APPLE__)
+    mach_task_basic_info_data_t info;
+    mach_msg_type_number_t count = MACH_TASK_BASIC_INFO_COUNT;
+    if (task_info(mach_task_self(), MACH_TASK_BASIC_INFO, reinterpret_cast<task_info_t>(&info),
+                  &count)
+        == KERN_SUCCESS) {
+        sample.rssBytes = static_cast<int64_t>(info.resident_size);
+    }
+
+    rusage_info_v2 io {};
+    if (proc_pid_rusage(::getpid(), RUSAGE_INFO_V2, reinterpret_cast<rusage_info_t*>(&io)) == 0) {
+        sample.diskReadBytes = static_cast<int64_t>(io.ri_diskio_bytesread);
+        sample.diskWriteBytes = static_cast<int64_t>(io.ri_diskio_byteswritten);
+    }
+#endif
+    return sample;
+}
+
+ResourceUsage ResourceMonitor::usage(int64_t nowMs)
+{
+    return record(sampleProcess(nowMs));
+}
+
+ResourceUsage ResourceMonitor::record(const ResourceSample& sample)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    // Keep one sample at least a window old, so the rate spans the window.
+    while (m_samples.size() >= 2 && sample.atMs - m_samples[1].atMs >= kWindowMs) {
+        m_samples.pop_front();
+    }
+
+    ResourceUsage usage;
+    usage.current = sample;
+    if (!m_samples.empty() && sample.atMs > m_samples.front().atMs) {
+        const ResourceSample& base = m_samples.front();
+        usage.windowMs = sample.atMs - base.atMs;
+        const double seconds = static_cast<double>(usage.windowMs) / 1000.0;
+        usage.cpuPercent = std::max(0.0, sample.cpuSeconds - base.cpuSeconds) / seconds * 100.0;
+        if (sample.diskReadBytes.has_value() && base.diskReadBytes.has_value()) {
+            usage.diskReadBytesPerSec =
+                static_cast<double>(std::max<int64_t>(0, *sample.diskReadBytes - *base.diskReadBytes))
+                / seconds;
+        }
+        if (sample.diskWriteBytes.has_value() && base.diskWriteBytes.has_value()) {
+            usage.diskWriteBytesPerSec =
+                static_cast<double>(
+                    std::max<int64_t>(0, *sample.diskWriteBytes - *base.diskWriteBytes))
+                / seconds;
+        }
+    }
+
+    if (m_samples.empty() || sample.atMs - m_samples.back().atMs >= kMinSpacingMs) {
+        m_samples.push_back(sample);
+    }
+    return usage;
+}
+
+} // namespace bs
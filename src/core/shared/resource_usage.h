@@ -0,0 +1,60 @@
+#pragma once
+
+#include <QJsonObject>
+
+#include <cstdint>
+#include <deque>
+#include <mutex>
+#include <optional>
+
+namespace bs {
+
+// One reading of this process's counters. Fields a platform cannot read
+// stay unset (RSS and disk IO are macOS only).
+struct ResourceSample {
+    int64_t atMs = 0;
+    double cpuSeconds = 0.0;  // user + system, finished child processes included
+    std::optional<int64_t> rssBytes;
+    std::optional<int> openFds;
+    std::optional<int64_t> fdLimit;  // soft RLIMIT_NOFILE
+    std::optional<int64_t> diskReadBytes;
+    std::optional<int64_t> diskWriteBytes;
+};
+
+struct ResourceUsage {
+    ResourceSample current;
+    // Rates over windowMs, which ends now; unset until two samples exist.
+    std::optional<double> cpuPercent;  // 100 = one core fully busy
+    std::optional<double> diskReadBytesPerSec;
+    std::optional<double> diskWriteBytesPerSec;
+    int64_t windowMs = 0;
+
+    QJsonObject toJson() const;
+};
+
+// ResourceMonitor -- what the service itself costs: CPU, memory, file
+// descriptors and disk IO, for status APIs ("indexing is using 12% CPU").
+//
+// Rates are measured against samples kept from earlier calls, spaced at
+// least kMinSpacingMs apart, so they cover roughly the last kWindowMs when
+// polled regularly and the time since the previous call otherwise. No
+// timer: a service nobody asks pays nothing. Thread-safe.
+class ResourceMonitor {
+public:
+    static constexpr int64_t kWindowMs = 10000;
+    static constexpr int64_t kMinSpacingMs = 1000;
+
+    // Reads the counters of the calling process.
+    static ResourceSample sampleProcess(int64_t nowMs);
+
+    // sampleProcess() folded in with record().
+    ResourceUsage usage(int64_t nowMs);
+    // Adds `sample` to the history and returns the usage it ends.
+    ResourceUsage record(const ResourceSample& sample);
+
+private:
+    std::mutex m_mutex;
+    std::deque<ResourceSample> m_samples;  // oldest first
+};
+
+} // namespace bs
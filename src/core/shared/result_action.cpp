@@ -0,0 +1,91 @@
+#include "core/shared/result_action.h"
+
+#include <QProcess>
+
+namespace bs {
+
+QString resultActionToString(ResultAction action)
+{
+    switch (action) {
+    case ResultAction::Open:      return QStringLiteral("open");
+    case ResultAction::Reveal:    return QStringLiteral("reveal");
+    case ResultAction::QuickLook: return QStringLiteral("quicklook");
+    case ResultAction::CopyPath:  return QStringLiteral("copyPath");
+    }
+    return QStringLiteral("open");
+}
+
+std::optional<ResultAction> resultActionFromString(const QString& name)
+{
+    QString key = name.trimmed().toLower();
+    key.remove(QLatin1Char('-'));
+    key.remove(QLatin1Char('_'));
+    if (key == QLatin1String("open"))      return ResultAction::Open;
+    if (key == QLatin1String("reveal"))    return ResultAction::Reveal;
+    if (key == QLatin1String("quicklook")) return ResultAction::QuickLook;
+    if (key == QLatin1String("copypath"))  return ResultAction::CopyPath;
+    return std::nullopt;
+}
+
+bool resultActionCountsAsSelection(ResultAction action)
+{
+    return action != ResultAction::QuickLook;
+}
+
+ResultActionCommand resultActionCommand(ResultAction action, const QString& path)
+{
+    ResultActionCommand command;
+    switch (action) {
+    case ResultAction::Open:
+        command.program = QStringLiteral("/usr/bin/open");
+        command.arguments = {path};
+        break;
+    case ResultAction::Reveal:
+        command.program = QStringLiteral("/usr/bin/open");
+        command.arguments = {QStringLiteral("-R"), path};
+        break;
+    case ResultAction::QuickLook:
+        command.program = QStringLiteral("/usr/bin/qlmanage");
+        command.arguments = {QStringLiteral("-p"), path};
+        break;
+    case ResultAction::CopyPath:
+        command.program = QStringLiteral("/usr/bin/pbcopy");
+        command.input = path.toUtf8();
+        break;
+    }
+    return command;
+}
+
+bool runResultActionCommand(const ResultActionCommand& command, QString* error)
+{
+    if (command.input.isEmpty()) {
+        if (QProcess::startDetached(command.program, command.arguments)) {
+            return true;
+        }
+        if (error) {
+            *error = QStringLiteral("Could not start %1").arg(command.program);
+        }
+        return false;
+    }
+
+    QProcess process;
+    process.start(command.program, command.arguments);
+    if (!process.waitForStarted(1000)) {
+        if (error) {
+            *error = QStringLiteral("Could not start %1").arg(command.program);
+        }
+        return false;
+    }
+    process.write(command.input);
+    process.closeWriteChannel();
+    if (!process.waitForFinished(2000) || process.exitStatus() != QProcess::NormalExit
+        || process.exitCode() != 0) {
+        if (error) {
+            *error = QStringLiteral("%1 failed").arg(command.program);
+        }
+        return false;
+    }
+    return true;
+}
+
+} // namespace bs
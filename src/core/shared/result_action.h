@@ -0,0 +1,43 @@
+#pragma once
+
+#include <QByteArray>
+#include <QString>
+#include <QStringList>
+
+#include <optional>
+
+namespace bs {
+
+// What a client can ask the query service to do with a search hit
+// (`performAction`), so every frontend gets the same behavior and the same
+// frecency signal.
+enum class ResultAction {
+    Open,       // default app
+    Reveal,     // select in Finder
+    QuickLook,  // preview panel
+    CopyPath,   // path to the pasteboard
+};
+
+QString resultActionToString(ResultAction action);
+
+// Accepts the canonical names plus "copy-path"/"copy_path" and "quick-look";
+// case-insensitive.
+std::optional<ResultAction> resultActionFromString(const QString& name);
+
+// Open, reveal and copy mean the user picked this result; a preview is
+// still browsing, so it is recorded as feedback but does not bump frecency.
+bool resultActionCountsAsSelection(ResultAction action);
+
+struct ResultActionCommand {
+    QString program;
+    QStringList arguments;
+    QByteArray input;       // written to stdin; non-empty means wait for exit
+};
+
+ResultActionCommand resultActionCommand(ResultAction action, const QString& path);
+
+// Launchers are started detached; pasteboard writes wait (up to 2s) so a
+// failure can be reported.
+bool runResultActionCommand(const ResultActionCommand& command, QString* error = nullptr);
+
+} // namespace bs
@@ -24,6 +24,10 @@ struct ScoringWeights {
     int semanticWeight = 40;          // M2 only
     double semanticSimilarityThreshold = 0.7; // M2 only
     int pinnedBoostWeight = 200;
+    int recentDocumentBoostWeight = 20;  // times RecentDocuments::score()
+    int actionBoostWeight = 12;          // reveals and path copies, see computeActionBoost
+    int eventTimeWeight = 30;            // calendar events, see computeEventTimeBoost
+    int eventLinkBoostWeight = 15;       // documents linked from a matching event
     int junkPenaltyWeight = 50;
 
     // Wave 2: cross-encoder + structured query signal weights
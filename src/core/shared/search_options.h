@@ -9,12 +9,21 @@ namespace bs {
 // Filter options for narrowing search results.
 // When all fields are empty/unset, no filtering is applied.
 struct SearchOptions {
+    // Stored attribute holding an item's due date in epoch seconds
+    // (reminders); what dueAfter/dueBefore compare.
+    static constexpr const char* kDueDateAttribute = "dueDate";
+
     std::vector<QString> fileTypes;      // Extensions to include (e.g. "pdf", "docx")
     std::vector<QString> includePaths;   // Path prefixes that results must match
     std::vector<QString> excludePaths;   // Path prefixes to exclude
+    std::vector<QString> tags;           // Finder tags results must all carry (case-insensitive)
 
     std::optional<double> modifiedAfter;  // Epoch seconds — results modified after this time
     std::optional<double> modifiedBefore; // Epoch seconds — results modified before this time
+    std::optional<double> createdAfter;   // Epoch seconds — results created after this time
+    std::optional<double> createdBefore;  // Epoch seconds — results created before this time
+    std::optional<double> dueAfter;       // Epoch seconds — results due after this time;
+    std::optional<double> dueBefore;      // items without a due date never match either
 
     std::optional<int64_t> minSizeBytes;
     std::optional<int64_t> maxSizeBytes;
@@ -25,8 +34,13 @@ struct SearchOptions {
         return !fileTypes.empty()
             || !includePaths.empty()
             || !excludePaths.empty()
+            || !tags.empty()
             || modifiedAfter.has_value()
             || modifiedBefore.has_value()
+            || createdAfter.has_value()
+            || createdBefore.has_value()
+            || dueAfter.has_value()
+            || dueBefore.has_value()
             || minSizeBytes.has_value()
             || maxSizeBytes.has_value();
     }
@@ -0,0 +1,63 @@
+#include "core/shared/service_readiness.h"
+
+namespace bs {
+
+QString readinessStateToString(ReadinessState state)
+{
+    switch (state) {
+    case ReadinessState::OpeningIndex: return QStringLiteral("opening_index");
+    case ReadinessState::InitialCrawl: return QStringLiteral("initial_crawl");
+    case ReadinessState::Serving:      return QStringLiteral("serving");
+    case ReadinessState::Degraded:     return QStringLiteral("degraded");
+    }
+    return QStringLiteral("unknown");
+}
+
+ReadinessReport evaluateReadiness(const ReadinessInputs& inputs)
+{
+    ReadinessReport report;
+    if (!inputs.indexOpen) {
+        report.state = ReadinessState::OpeningIndex;
+        report.reason = QStringLiteral("index_not_open");
+        return report;
+    }
+
+    // Everything below can answer queries from whatever is already indexed.
+    report.acceptingQueries = true;
+
+    if (!inputs.indexerReachable) {
+        report.state = ReadinessState::Degraded;
+        report.reason = QStringLiteral("indexer_unavailable");
+        return report;
+    }
+
+    // A paused queue is expected to stand still; anything else with work
+    // queued and no progress past the threshold is wedged, crawl or not.
+    if (inputs.indexerActive && !inputs.indexerPaused
+        && inputs.msSinceIndexerProgress >= inputs.stallThresholdMs) {
+        report.state = ReadinessState::Degraded;
+        report.reason = QStringLiteral("indexer_stalled");
+        return report;
+    }
+
+    if (!inputs.initialIndexCompleted
+        && (inputs.indexerActive || inputs.indexedItems == 0)) {
+        report.state = ReadinessState::InitialCrawl;
+        report.reason = inputs.indexerPaused ? QStringLiteral("initial_crawl_paused")
+                                             : QStringLiteral("initial_crawl");
+        return report;
+    }
+
+    if (inputs.criticalFailures > 0) {
+        report.state = ReadinessState::Degraded;
+        report.reason = QStringLiteral("critical_failures");
+        return report;
+    }
+
+    report.state = ReadinessState::Serving;
+    report.reason = inputs.indexerActive ? QStringLiteral("incremental_indexing")
+                                         : QStringLiteral("idle");
+    return report;
+}
+
+} // namespace bs
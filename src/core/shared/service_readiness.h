@@ -0,0 +1,39 @@
+#pragma once
+
+#include <QString>
+#include <cstdint>
+
+namespace bs {
+
+// Coarse lifecycle state reported by /readyz and getReadiness, so callers can
+// tell a slow first index from a wedged one.
+enum class ReadinessState {
+    OpeningIndex,   // database not open yet (or failed to open)
+    InitialCrawl,   // first full index of the roots still in progress
+    Serving,        // index complete and indexer making progress
+    Degraded,       // serving, but a dependency is missing or stuck
+};
+
+QString readinessStateToString(ReadinessState state);
+
+struct ReadinessInputs {
+    bool indexOpen = false;
+    bool indexerReachable = false;
+    bool indexerActive = false;         // queued or in-flight work
+    bool indexerPaused = false;
+    bool initialIndexCompleted = false; // indexer recorded last_full_index_at
+    int64_t indexedItems = 0;
+    int64_t criticalFailures = 0;
+    int64_t msSinceIndexerProgress = 0; // only meaningful while active
+    int64_t stallThresholdMs = 120000;
+};
+
+struct ReadinessReport {
+    ReadinessState state = ReadinessState::OpeningIndex;
+    QString reason;
+    bool acceptingQueries = false;
+};
+
+ReadinessReport evaluateReadiness(const ReadinessInputs& inputs);
+
+} // namespace bs
@@ -0,0 +1,347 @@
+#include "core/shared/tracing.h"
+
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QRandomGenerator>
+
+#include <algorithm>
+#include <chrono>
+#include <cmath>
+
+namespace bs {
+
+namespace {
+
+thread_local TraceContext t_current;
+
+QByteArray randomHexId(int bytes)
+{
+    QByteArray raw(bytes, Qt::Uninitialized);
+    do {
+        QRandomGenerator::global()->fillRange(reinterpret_cast<quint32*>(raw.data()),
+                                              bytes / 4);
+    } while (raw.count('\0') == raw.size());  // all-zero ids are invalid
+    return raw.toHex();
+}
+
+bool isLowerHex(const QByteArray& value)
+{
+    return std::all_of(value.begin(), value.end(), [](char c) {
+        return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f');
+    });
+}
+
+QJsonObject otlpValue(const QJsonValue& value)
+{
+    QJsonObject out;
+    if (value.isBool()) {
+        out[QStringLiteral("boolValue")] = value.toBool();
+    } else if (value.isDouble()) {
+        const double number = value.toDouble();
+        // OTLP JSON carries 64-bit integers as strings.
+        if (number == std::floor(number) && std::fabs(number) < 9007199254740992.0) {
+            out[QStringLiteral("intValue")] =
+                QString::number(static_cast<qint64>(number));
+        } else {
+            out[QStringLiteral("doubleValue")] = number;
+        }
+    } else {
+        out[QStringLiteral("stringValue")] = value.toVariant().toString();
+    }
+    return out;
+}
+
+QJsonArray otlpAttributes(const QJsonObject& attributes)
+{
+    QJsonArray out;
+    for (auto it = attributes.begin(); it != attributes.end(); ++it) {
+        QJsonObject attribute;
+        attribute[QStringLiteral("key")] = it.key();
+        attribute[QStringLiteral("value")] = otlpValue(it.value());
+        out.append(attribute);
+    }
+    return out;
+}
+
+} // namespace
+
+QByteArray TraceContext::toTraceparent() const
+{
+    if (!isValid()) {
+        return {};
+    }
+    return QByteArrayLiteral("00-") + traceId + '-' + spanId
+        + (sampled ? QByteArrayLiteral("-01") : QByteArrayLiteral("-00"));
+}
+
+TraceContext TraceContext::fromTraceparent(const QByteArray& header)
+{
+    const QList<QByteArray> parts = header.trimmed().toLower().split('-');
+    if (parts.size() != 4 || parts[0] != "00" || parts[1].size() != 32
+        || parts[2].size() != 16 || parts[3].size() != 2) {
+        return {};
+    }
+    for (const QByteArray& part : parts) {
+        if (!isLowerHex(part)) {
+            return {};
+        }
+    }
+    if (parts[1].count('0') == 32 || parts[2].count('0') == 16) {
+        return {};
+    }
+    TraceContext context;
+    context.traceId = parts[1];
+    context.spanId = parts[2];
+    context.sampled = (parts[3].toInt(nullptr, 16) & 0x01) != 0;
+    return context;
+}
+
+Tracer& Tracer::instance()
+{
+    static Tracer tracer;
+    return tracer;
+}
+
+void Tracer::configure(const QString& serviceName, double sampleRatio)
+{
+    {
+        std::lock_guard<std::mutex> lock(m_mutex);
+        m_serviceName = serviceName;
+    }
+    m_sampleRatio.store(std::clamp(sampleRatio, 0.0, 1.0));
+    m_enabled.store(true);
+}
+
+void Tracer::disable()
+{
+    m_enabled.store(false);
+    std::lock_guard<std::mutex> lock(m_mutex);
+    m_finished.clear();
+}
+
+QString Tracer::serviceName() const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_serviceName;
+}
+
+TraceContext Tracer::newContext(const TraceContext& parent)
+{
+    TraceContext context;
+    if (parent.isValid()) {
+        context.traceId = parent.traceId;
+        context.sampled = parent.sampled;
+    } else {
+        context.traceId = randomHexId(16);
+        const double ratio = m_sampleRatio.load();
+        context.sampled = ratio >= 1.0
+            || (ratio > 0.0 && QRandomGenerator::global()->generateDouble() < ratio);
+    }
+    context.spanId = randomHexId(8);
+    return context;
+}
+
+void Tracer::record(FinishedSpan span)
+{
+    if (!isEnabled()) {
+        return;
+    }
+    std::lock_guard<std::mutex> lock(m_mutex);
+    if (m_finished.size() >= kMaxBufferedSpans) {
+        m_dropped.fetch_add(1, std::memory_order_relaxed);
+        return;
+    }
+    m_finished.push_back(std::move(span));
+}
+
+std::vector<FinishedSpan> Tracer::takeFinished(size_t maxSpans)
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    const size_t count = std::min(maxSpans, m_finished.size());
+    std::vector<FinishedSpan> taken;
+    taken.reserve(count);
+    for (size_t i = 0; i < count; ++i) {
+        taken.push_back(std::move(m_finished.front()));
+        m_finished.pop_front();
+    }
+    return taken;
+}
+
+size_t Tracer::bufferedCount() const
+{
+    std::lock_guard<std::mutex> lock(m_mutex);
+    return m_finished.size();
+}
+
+TraceContext Tracer::current()
+{
+    return t_current;
+}
+
+void Tracer::setCurrent(const TraceContext& context)
+{
+    t_current = context;
+}
+
+qint64 Tracer::nowNs()
+{
+    return std::chrono::duration_cast<std::chrono::nanoseconds>(
+               std::chrono::system_clock::now().time_since_epoch())
+        .count();
+}
+
+QByteArray Tracer::encodeOtlpJson(const QString& serviceName,
+                                  const std::vector<FinishedSpan>& spans)
+{
+    QJsonArray spanArray;
+    for (const FinishedSpan& span : spans) {
+        QJsonObject out;
+        out[QStringLiteral("traceId")] = QString::fromLatin1(span.traceId);
+        out[QStringLiteral("spanId")] = QString::fromLatin1(span.spanId);
+        if (!span.parentSpanId.isEmpty()) {
+            out[QStringLiteral("parentSpanId")] = QString::fromLatin1(span.parentSpanId);
+        }
+        out[QStringLiteral("name")] = span.name;
+        out[QStringLiteral("kind")] = span.kind;
+        out[QStringLiteral("startTimeUnixNano")] = QString::number(span.startNs);
+        out[QStringLiteral("endTimeUnixNano")] = QString::number(span.endNs);
+        if (!span.attributes.isEmpty()) {
+            out[QStringLiteral("attributes")] = otlpAttributes(span.attributes);
+        }
+        QJsonObject status;
+        if (span.error) {
+            status[QStringLiteral("code")] = 2;
+            if (!span.statusMessage.isEmpty()) {
+                status[QStringLiteral("message")] = span.statusMessage;
+            }
+        }
+        out[QStringLiteral("status")] = status;
+        spanArray.append(out);
+    }
+
+    QJsonObject serviceAttribute;
+    serviceAttribute[QStringLiteral("key")] = QStringLiteral("service.name");
+    serviceAttribute[QStringLiteral("value")] = otlpValue(serviceName);
+    QJsonObject resource;
+    resource[QStringLiteral("attributes")] = QJsonArray{serviceAttribute};
+
+    QJsonObject scope;
+    scope[QStringLiteral("name")] = QStringLiteral("betterspotlight");
+    QJsonObject scopeSpans;
+    scopeSpans[QStringLiteral("scope")] = scope;
+    scopeSpans[QStringLiteral("spans")] = spanArray;
+
+    QJsonObject resourceSpans;
+    resourceSpans[QStringLiteral("resource")] = resource;
+    resourceSpans[QStringLiteral("scopeSpans")] = QJsonArray{scopeSpans};
+
+    QJsonObject body;
+    body[QStringLiteral("resourceSpans")] = QJsonArray{resourceSpans};
+    return QJsonDocument(body).toJson(QJsonDocument::Compact);
+}
+
+Span::Span(const QString& name, Kind kind)
+{
+    if (Tracer::instance().isEnabled()) {
+        begin(name, Tracer::current(), kind);
+    }
+}
+
+Span::Span(const QString& name, const TraceContext& parent, Kind kind)
+{
+    if (Tracer::instance().isEnabled()) {
+        begin(name, parent, kind);
+    }
+}
+
+Span::~Span()
+{
+    end();
+}
+
+void Span::begin(const QString& name, const TraceContext& parent, Kind kind)
+{
+    m_context = Tracer::instance().newContext(parent);
+    m_previous = Tracer::current();
+    Tracer::setCurrent(m_context);
+    m_active = true;
+    m_recording = m_context.sampled;
+    if (m_recording) {
+        m_span.traceId = m_context.traceId;
+        m_span.spanId = m_context.spanId;
+        if (parent.isValid()) {
+            m_span.parentSpanId = parent.spanId;
+        }
+        m_span.name = name;
+        m_span.kind = static_cast<int>(kind);
+        m_span.startNs = Tracer::nowNs();
+    }
+}
+
+void Span::setAttribute(const QString& key, const QJsonValue& value)
+{
+    if (m_recording) {
+        m_span.attributes[key] = value;
+    }
+}
+
+void Span::setError(const QString& message)
+{
+    if (m_recording) {
+        m_span.error = true;
+        m_span.statusMessage = message;
+    }
+}
+
+void Span::end()
+{
+    if (!m_active) {
+        return;
+    }
+    m_active = false;
+    Tracer::setCurrent(m_previous);
+    if (m_recording) {
+        m_recording = false;
+        m_span.endNs = Tracer::nowNs();
+        Tracer::instance().record(std::move(m_span));
+    }
+}
+
+ScopedTraceContext::ScopedTraceContext(const TraceContext& context)
+    : m_previous(Tracer::current())
+{
+    Tracer::setCurrent(context);
+}
+
+ScopedTraceContext::~ScopedTraceContext()
+{
+    Tracer::setCurrent(m_previous);
+}
+
+PendingSpan PendingSpan::startTrace()
+{
+    PendingSpan pending;
+    if (Tracer::instance().isEnabled()) {
+        pending.context = Tracer::instance().newContext();
+        pending.startNs = Tracer::nowNs();
+    }
+    return pending;
+}
+
+void PendingSpan::finish(const QString& name, const QJsonObject& attributes, bool error) const
+{
+    if (!isActive() || !context.sampled) {
+        return;
+    }
+    FinishedSpan span;
+    span.traceId = context.traceId;
+    span.spanId = context.spanId;
+    span.name = name;
+    span.startNs = startNs;
+    span.endNs = Tracer::nowNs();
+    span.attributes = attributes;
+    span.error = error;
+    Tracer::instance().record(std::move(span));
+}
+
+} // namespace bs
@@ -0,0 +1,164 @@
+#pragma once
+
+#include <QByteArray>
+#include <QJsonObject>
+#include <QJsonValue>
+#include <QString>
+
+#include <atomic>
+#include <cstdint>
+#include <deque>
+#include <mutex>
+#include <vector>
+
+namespace bs {
+
+// TraceContext -- the W3C trace context of one span: which trace it belongs
+// to, its own id, and whether the trace is being recorded.
+struct TraceContext {
+    QByteArray traceId;  // 32 lowercase hex chars
+    QByteArray spanId;   // 16 lowercase hex chars
+    bool sampled = false;
+
+    bool isValid() const { return traceId.size() == 32 && spanId.size() == 16; }
+
+    // "00-<traceId>-<spanId>-<flags>", the `traceparent` header and IPC field.
+    QByteArray toTraceparent() const;
+    // Invalid context for anything that is not a version-00 traceparent.
+    static TraceContext fromTraceparent(const QByteArray& header);
+};
+
+// A span that has ended, waiting for the exporter.
+struct FinishedSpan {
+    QByteArray traceId;
+    QByteArray spanId;
+    QByteArray parentSpanId;  // empty for a root span
+    QString name;
+    int kind = 1;             // OTLP SpanKind: 1 internal, 2 server, 3 client
+    qint64 startNs = 0;       // Unix nanoseconds
+    qint64 endNs = 0;
+    QJsonObject attributes;
+    bool error = false;
+    QString statusMessage;
+};
+
+// Tracer -- process-wide span buffer, off until configure() is called.
+//
+// Spans are recorded from any thread into a bounded buffer that an exporter
+// drains (OtlpExporter); when the exporter falls behind, new spans are
+// dropped and counted rather than held. Sampling is decided once per trace,
+// at its root, and inherited by every span below it.
+//
+// Span names and attributes must not carry paths, file names or query text:
+// traces leave the machine whenever the collector does.
+class Tracer {
+public:
+    static constexpr size_t kMaxBufferedSpans = 8192;
+
+    static Tracer& instance();
+
+    // Turns tracing on. `sampleRatio` (0..1) is the share of root spans
+    // whose trace is recorded.
+    void configure(const QString& serviceName, double sampleRatio = 1.0);
+    void disable();
+    bool isEnabled() const { return m_enabled.load(std::memory_order_relaxed); }
+    QString serviceName() const;
+
+    // Context for a new span: a child of `parent` when it is valid,
+    // otherwise the root of a new trace, sampled by ratio.
+    TraceContext newContext(const TraceContext& parent = {});
+
+    void record(FinishedSpan span);
+    std::vector<FinishedSpan> takeFinished(size_t maxSpans);
+    size_t bufferedCount() const;
+    uint64_t droppedCount() const { return m_dropped.load(std::memory_order_relaxed); }
+
+    // The innermost live Span on this thread; new spans without an explicit
+    // parent become its children.
+    static TraceContext current();
+    static void setCurrent(const TraceContext& context);
+
+    static qint64 nowNs();
+
+    // OTLP/HTTP JSON body (ExportTraceServiceRequest) for `spans`.
+    static QByteArray encodeOtlpJson(const QString& serviceName,
+                                     const std::vector<FinishedSpan>& spans);
+
+private:
+    Tracer() = default;
+
+    std::atomic<bool> m_enabled{false};
+    std::atomic<double> m_sampleRatio{1.0};
+    std::atomic<uint64_t> m_dropped{0};
+    mutable std::mutex m_mutex;
+    QString m_serviceName;
+    std::deque<FinishedSpan> m_finished;
+};
+
+// Span -- RAII span on the current thread. It is this thread's current
+// context while it lives, so spans must end in the reverse order they
+// began. Everything is a no-op while tracing is off.
+class Span {
+public:
+    enum class Kind { Internal = 1, Server = 2, Client = 3 };
+
+    // Child of Tracer::current(), or a new root.
+    explicit Span(const QString& name, Kind kind = Kind::Internal);
+    // Child of `parent` (e.g. a context carried across threads), or a new
+    // root when it is invalid.
+    Span(const QString& name, const TraceContext& parent, Kind kind = Kind::Internal);
+    ~Span();
+
+    Span(const Span&) = delete;
+    Span& operator=(const Span&) = delete;
+
+    bool isRecording() const { return m_recording; }
+    const TraceContext& context() const { return m_context; }
+
+    void setAttribute(const QString& key, const QJsonValue& value);
+    void setError(const QString& message = {});
+
+    // Ends the span early; the destructor is then a no-op.
+    void end();
+
+private:
+    void begin(const QString& name, const TraceContext& parent, Kind kind);
+
+    TraceContext m_context;
+    TraceContext m_previous;
+    FinishedSpan m_span;
+    bool m_active = false;
+    bool m_recording = false;
+};
+
+// ScopedTraceContext -- makes a remote parent (an incoming traceparent)
+// current for the lifetime of the object.
+class ScopedTraceContext {
+public:
+    explicit ScopedTraceContext(const TraceContext& context);
+    ~ScopedTraceContext();
+
+    ScopedTraceContext(const ScopedTraceContext&) = delete;
+    ScopedTraceContext& operator=(const ScopedTraceContext&) = delete;
+
+private:
+    TraceContext m_previous;
+};
+
+// PendingSpan -- a span that outlives the stack frame that began it, such as
+// a work item's trip from enqueue to write across pipeline threads.
+// Copyable so it can travel with the work; nothing is recorded until
+// finish().
+struct PendingSpan {
+    TraceContext context;
+    qint64 startNs = 0;
+
+    // Root of a new trace, sampled by ratio. Inactive while tracing is off.
+    static PendingSpan startTrace();
+
+    bool isActive() const { return context.isValid(); }
+    void finish(const QString& name, const QJsonObject& attributes = {},
+                bool error = false) const;
+};
+
+} // namespace bs
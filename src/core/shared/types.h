@@ -1,5 +1,7 @@
 #pragma once
 
+#include "core/shared/tracing.h"
+
 #include <QString>
 #include <cstdint>
 #include <optional>
@@ -55,6 +57,8 @@ struct WorkItem {
     std::optional<uint64_t> knownSize;
     int retryCount = 0;
     bool rebuildLane = false;
+    // The item's trip through the pipeline, from enqueue to write.
+    PendingSpan trace;
 };
 
 // Filesystem metadata extracted in Stage 4
@@ -70,4 +74,14 @@ struct FileMetadata {
     ItemKind itemKind = ItemKind::Unknown;
 };
 
+// Named value describing a file that does not come from its content (e.g.
+// Spotlight's kMDItemWhereFroms). Multi-valued attributes repeat the name.
+struct ItemAttribute {
+    QString name;
+    QString value;
+    bool searchable = true;  // also indexed in FTS5
+
+    bool operator==(const ItemAttribute& other) const = default;
+};
+
 } // namespace bs
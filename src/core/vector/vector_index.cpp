@@ -1,4 +1,5 @@
 #include "core/vector/vector_index.h"
+#include "core/shared/logging.h"
 
 #include "hnswlib/hnswlib.h"
 
@@ -10,6 +11,7 @@
 #include <QTextStream>
 
 #include <algorithm>
+#include <cstring>
 #include <limits>
 
 namespace bs {
@@ -36,11 +38,11 @@ VectorIndex::~VectorIndex()
 bool VectorIndex::configure(const IndexMetadata& metadata)
 {
     if (m_index) {
-        qWarning() << "VectorIndex::configure ignored: index already initialized";
+        qCWarning(bsVector) << "VectorIndex::configure ignored: index already initialized";
         return false;
     }
     if (metadata.dimensions <= 0) {
-        qWarning() << "VectorIndex::configure rejected invalid dimensions:" << metadata.dimensions;
+        qCWarning(bsVector) << "VectorIndex::configure rejected invalid dimensions:" << metadata.dimensions;
         return false;
     }
     m_metadata = metadata;
@@ -50,7 +52,7 @@ bool VectorIndex::configure(const IndexMetadata& metadata)
 bool VectorIndex::create(int initialCapacity)
 {
     if (m_metadata.dimensions <= 0) {
-        qCritical() << "VectorIndex::create requires a positive runtime dimension";
+        qCCritical(bsVector) << "VectorIndex::create requires a positive runtime dimension";
         return false;
     }
 
@@ -67,7 +69,7 @@ bool VectorIndex::create(int initialCapacity)
         m_deletedCount = 0;
         return true;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::create failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::create failed:" << e.what();
         m_index.reset();
         m_space.reset();
         return false;
@@ -78,7 +80,7 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
 {
     QFileInfo indexInfo(QString::fromStdString(indexPath));
     if (!indexInfo.exists() || !indexInfo.isFile()) {
-        qCritical() << "VectorIndex::load missing index file:" << indexInfo.filePath();
+        qCCritical(bsVector) << "VectorIndex::load missing index file:" << indexInfo.filePath();
         return false;
     }
 
@@ -86,13 +88,13 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
     // Reject clearly invalid blobs before attempting to deserialize.
     constexpr qint64 kMinSerializedIndexBytes = 96;
     if (indexInfo.size() < kMinSerializedIndexBytes) {
-        qCritical() << "VectorIndex::load index payload too small:" << indexInfo.size();
+        qCCritical(bsVector) << "VectorIndex::load index payload too small:" << indexInfo.size();
         return false;
     }
 
     QFile metaFile(QString::fromStdString(metaPath));
     if (!metaFile.open(QIODevice::ReadOnly)) {
-        qCritical() << "VectorIndex::load failed to open meta file:" << metaFile.fileName();
+        qCCritical(bsVector) << "VectorIndex::load failed to open meta file:" << metaFile.fileName();
         return false;
     }
 
@@ -100,19 +102,19 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
     const QJsonDocument metaDoc = QJsonDocument::fromJson(metaFile.readAll(), &parseError);
     metaFile.close();
     if (parseError.error != QJsonParseError::NoError || !metaDoc.isObject()) {
-        qCritical() << "VectorIndex::load invalid meta JSON:" << parseError.errorString();
+        qCCritical(bsVector) << "VectorIndex::load invalid meta JSON:" << parseError.errorString();
         return false;
     }
 
     const QJsonObject meta = metaDoc.object();
     const int dimensions = meta.value(QStringLiteral("dimensions")).toInt(-1);
     if (dimensions <= 0) {
-        qCritical() << "VectorIndex::load missing/invalid dimensions in metadata";
+        qCCritical(bsVector) << "VectorIndex::load missing/invalid dimensions in metadata";
         return false;
     }
     if (m_metadata.dimensions > 0 && dimensions != m_metadata.dimensions) {
-        qCritical() << "VectorIndex::load dimension mismatch:" << dimensions
-                    << "expected" << m_metadata.dimensions;
+        qCCritical(bsVector) << "VectorIndex::load dimension mismatch:" << dimensions
+                             << "expected" << m_metadata.dimensions;
         return false;
     }
 
@@ -131,9 +133,9 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
     const int efConstruction = meta.value(QStringLiteral("ef_construction")).toInt(kEfConstruction);
     const int m = meta.value(QStringLiteral("m")).toInt(kM);
     if (efConstruction != kEfConstruction || m != kM) {
-        qWarning() << "VectorIndex::load metadata params differ from compiled defaults"
-                   << "ef_construction=" << efConstruction
-                   << "m=" << m;
+        qCWarning(bsVector) << "VectorIndex::load metadata params differ from compiled defaults"
+                            << "ef_construction=" << efConstruction
+                            << "m=" << m;
     }
 
     const uint64_t totalElementsMeta = meta.value(QStringLiteral("total_elements")).toVariant().toULongLong();
@@ -146,7 +148,7 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
     targetCapacity = std::max(targetCapacity, totalElementsMeta * 2);
 
     if (targetCapacity > static_cast<uint64_t>(std::numeric_limits<size_t>::max())) {
-        qCritical() << "VectorIndex::load target capacity too large:" << targetCapacity;
+        qCCritical(bsVector) << "VectorIndex::load target capacity too large:" << targetCapacity;
         return false;
     }
 
@@ -159,7 +161,7 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
         m_deletedCount = std::max(deletedElementsMeta, 0);
         return true;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::load failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::load failed:" << e.what();
         m_index.reset();
         m_space.reset();
         return false;
@@ -169,14 +171,14 @@ bool VectorIndex::load(const std::string& indexPath, const std::string& metaPath
 bool VectorIndex::save(const std::string& indexPath, const std::string& metaPath)
 {
     if (!m_index) {
-        qWarning() << "VectorIndex::save called with unavailable index";
+        qCWarning(bsVector) << "VectorIndex::save called with unavailable index";
         return false;
     }
 
     try {
         m_index->saveIndex(indexPath);
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::save failed to persist index:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::save failed to persist index:" << e.what();
         return false;
     }
 
@@ -196,7 +198,7 @@ bool VectorIndex::save(const std::string& indexPath, const std::string& metaPath
 
     QFile metaFile(QString::fromStdString(metaPath));
     if (!metaFile.open(QIODevice::WriteOnly | QIODevice::Truncate)) {
-        qCritical() << "VectorIndex::save failed to open meta file for write:" << metaFile.fileName();
+        qCCritical(bsVector) << "VectorIndex::save failed to open meta file for write:" << metaFile.fileName();
         return false;
     }
 
@@ -204,7 +206,7 @@ bool VectorIndex::save(const std::string& indexPath, const std::string& metaPath
     const qint64 written = metaFile.write(doc.toJson(QJsonDocument::Indented));
     metaFile.close();
     if (written < 0) {
-        qCritical() << "VectorIndex::save failed writing meta file:" << metaFile.fileName();
+        qCCritical(bsVector) << "VectorIndex::save failed writing meta file:" << metaFile.fileName();
         return false;
     }
     return true;
@@ -213,7 +215,7 @@ bool VectorIndex::save(const std::string& indexPath, const std::string& metaPath
 uint64_t VectorIndex::addVector(const float* embedding)
 {
     if (!m_index || embedding == nullptr) {
-        qWarning() << "VectorIndex::addVector called with unavailable index or null embedding";
+        qCWarning(bsVector) << "VectorIndex::addVector called with unavailable index or null embedding";
         return std::numeric_limits<uint64_t>::max();
     }
 
@@ -229,7 +231,7 @@ uint64_t VectorIndex::addVector(const float* embedding)
         ++m_nextLabel;
         return label;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::addVector failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::addVector failed:" << e.what();
         return std::numeric_limits<uint64_t>::max();
     }
 }
@@ -237,7 +239,7 @@ uint64_t VectorIndex::addVector(const float* embedding)
 bool VectorIndex::deleteVector(uint64_t label)
 {
     if (!m_index) {
-        qWarning() << "VectorIndex::deleteVector called with unavailable index";
+        qCWarning(bsVector) << "VectorIndex::deleteVector called with unavailable index";
         return false;
     }
 
@@ -247,11 +249,83 @@ bool VectorIndex::deleteVector(uint64_t label)
         ++m_deletedCount;
         return true;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::deleteVector failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::deleteVector failed:" << e.what();
         return false;
     }
 }
 
+bool VectorIndex::eraseVector(uint64_t label)
+{
+    if (!m_index) {
+        qCWarning(bsVector) << "VectorIndex::eraseVector called with unavailable index";
+        return false;
+    }
+
+    std::lock_guard<std::mutex> lock(m_writeMutex);
+    hnswlib::tableint internalId = 0;
+    {
+        std::lock_guard<std::mutex> lookupLock(m_index->label_lookup_lock);
+        const auto it = m_index->label_lookup_.find(static_cast<hnswlib::labeltype>(label));
+        if (it == m_index->label_lookup_.end()) {
+            return false;
+        }
+        internalId = it->second;
+    }
+
+    std::memset(m_index->getDataByInternalId(internalId), 0, m_index->data_size_);
+    if (!m_index->isMarkedDeleted(internalId)) {
+        try {
+            m_index->markDelete(static_cast<hnswlib::labeltype>(label));
+            ++m_deletedCount;
+        } catch (const std::exception& e) {
+            qCCritical(bsVector) << "VectorIndex::eraseVector failed:" << e.what();
+            return false;
+        }
+    }
+    return true;
+}
+
+std::vector<uint64_t> VectorIndex::liveLabels() const
+{
+    std::vector<uint64_t> labels;
+    if (!m_index) {
+        return labels;
+    }
+
+    std::lock_guard<std::mutex> lock(m_writeMutex);
+    std::lock_guard<std::mutex> lookupLock(m_index->label_lookup_lock);
+    labels.reserve(m_index->label_lookup_.size());
+    for (const auto& [label, internalId] : m_index->label_lookup_) {
+        if (!m_index->isMarkedDeleted(internalId)) {
+            labels.push_back(static_cast<uint64_t>(label));
+        }
+    }
+    std::sort(labels.begin(), labels.end());
+    return labels;
+}
+
+std::vector<uint64_t> VectorIndex::unerasedDeletedLabels() const
+{
+    std::vector<uint64_t> labels;
+    if (!m_index) {
+        return labels;
+    }
+
+    std::lock_guard<std::mutex> lock(m_writeMutex);
+    std::lock_guard<std::mutex> lookupLock(m_index->label_lookup_lock);
+    for (const auto& [label, internalId] : m_index->label_lookup_) {
+        if (!m_index->isMarkedDeleted(internalId)) {
+            continue;
+        }
+        const char* data = m_index->getDataByInternalId(internalId);
+        if (std::any_of(data, data + m_index->data_size_, [](char byte) { return byte != 0; })) {
+            labels.push_back(static_cast<uint64_t>(label));
+        }
+    }
+    std::sort(labels.begin(), labels.end());
+    return labels;
+}
+
 std::vector<VectorIndex::KnnResult> VectorIndex::search(const float* queryVector, int k)
 {
     std::vector<KnnResult> results;
@@ -274,7 +348,7 @@ std::vector<VectorIndex::KnnResult> VectorIndex::search(const float* queryVector
         });
         return results;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex::search failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex::search failed:" << e.what();
         return {};
     }
 }
@@ -324,14 +398,14 @@ const VectorIndex::IndexMetadata& VectorIndex::metadata() const
 bool VectorIndex::ensureCapacityForOneMore()
 {
     if (!m_index) {
-        qWarning() << "VectorIndex::ensureCapacityForOneMore called with unavailable index";
+        qCWarning(bsVector) << "VectorIndex::ensureCapacityForOneMore called with unavailable index";
         return false;
     }
 
     const size_t current = m_index->getCurrentElementCount();
     const size_t maxElements = m_index->getMaxElements();
     if (maxElements == 0) {
-        qCritical() << "VectorIndex has zero max elements";
+        qCCritical(bsVector) << "VectorIndex has zero max elements";
         return false;
     }
 
@@ -342,16 +416,16 @@ bool VectorIndex::ensureCapacityForOneMore()
 
     const size_t newCapacity = maxElements * 2;
     if (newCapacity <= maxElements) {
-        qCritical() << "VectorIndex resize overflow";
+        qCCritical(bsVector) << "VectorIndex resize overflow";
         return false;
     }
 
     try {
         m_index->resizeIndex(newCapacity);
-        qWarning() << "VectorIndex resized to capacity" << static_cast<qulonglong>(newCapacity);
+        qCWarning(bsVector) << "VectorIndex resized to capacity" << static_cast<qulonglong>(newCapacity);
         return true;
     } catch (const std::exception& e) {
-        qCritical() << "VectorIndex resize failed:" << e.what();
+        qCCritical(bsVector) << "VectorIndex resize failed:" << e.what();
         return false;
     }
 }
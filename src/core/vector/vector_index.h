@@ -49,6 +49,16 @@ public:
 
     uint64_t addVector(const float* embedding);
     bool deleteVector(uint64_t label);
+    // deleteVector() only marks a label deleted; its embedding stays in
+    // memory and in the saved index. eraseVector() zeroes the stored
+    // embedding too (and marks it deleted if it was not), so a purged
+    // vector cannot be read back after the next save().
+    bool eraseVector(uint64_t label);
+    // Labels not marked deleted.
+    std::vector<uint64_t> liveLabels() const;
+    // Labels deleteVector() marked deleted whose embedding is still stored,
+    // i.e. what eraseVector() has yet to zero.
+    std::vector<uint64_t> unerasedDeletedLabels() const;
 
     std::vector<KnnResult> search(const float* queryVector, int k = 50);
 
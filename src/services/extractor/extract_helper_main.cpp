@@ -0,0 +1,49 @@
+#include "core/extraction/ocr_extractor.h"
+#include "core/extraction/pdf_extractor.h"
+#include "core/extraction/sandboxed_extraction.h"
+
+#include <QCoreApplication>
+
+#include <cstdio>
+
+// betterspotlight-extract-helper — extracts one PDF or image and exits.
+// Started by ExtractionManager; see core/extraction/sandboxed_extraction.h.
+// Exit status: 0 with an encoded ExtractionResult on stdout, 2 when the
+// arguments are malformed or the sandbox could not be entered.
+int main(int argc, char* argv[])
+{
+    QCoreApplication app(argc, argv);
+    app.setApplicationName(QString::fromLatin1(bs::SandboxedExtraction::kHelperName));
+    app.setApplicationVersion(QStringLiteral("0.1.0"));
+
+    QString error;
+    const auto request =
+        bs::SandboxedExtraction::parseHelperArguments(app.arguments().mid(1), &error);
+    if (!request.has_value()) {
+        std::fprintf(stderr, "%s: %s\n", bs::SandboxedExtraction::kHelperName,
+                     qUtf8Printable(error));
+        return 2;
+    }
+
+    // Lock down before any parser sees the file.
+    if (!bs::SandboxedExtraction::applyResourceLimits(request->limits, &error)
+        || !bs::SandboxedExtraction::enterSandbox(&error)) {
+        std::fprintf(stderr, "%s: %s\n", bs::SandboxedExtraction::kHelperName,
+                     qUtf8Printable(error));
+        return 2;
+    }
+
+    bs::ExtractionResult result;
+    if (request->kind == bs::ItemKind::Pdf) {
+        bs::PdfExtractor extractor;
+        result = extractor.extract(request->filePath);
+    } else {
+        bs::OcrExtractor extractor;
+        result = extractor.extract(request->filePath);
+    }
+
+    const QByteArray payload = bs::SandboxedExtraction::encodeResult(result);
+    std::fwrite(payload.constData(), 1, static_cast<size_t>(payload.size()), stdout);
+    std::fflush(stdout);
+    return 0;
+}
@@ -9,6 +9,7 @@
 #include <QFile>
 #include <QFileInfo>
 #include <QJsonArray>
+#include <QSet>
 #include <QStringList>
 #include <QStandardPaths>
 
@@ -42,6 +43,15 @@ QJsonObject ExtractorService::handleRequest(const QJsonObject& request)
     return ServiceBase::handleRequest(request);
 }
 
+bool ExtractorService::isAdminMethod(const QString& method) const
+{
+    static const QSet<QString> kAdminMethods = {
+        QStringLiteral("clearExtractionCache"),
+        QStringLiteral("clearCache"),
+    };
+    return kAdminMethods.contains(method) || ServiceBase::isAdminMethod(method);
+}
+
 QJsonObject ExtractorService::handleExtractText(uint64_t id, const QJsonObject& params)
 {
     QString path = params.value(QStringLiteral("path")).toString();
@@ -82,6 +92,9 @@ QJsonObject ExtractorService::handleExtractText(uint64_t id, const QJsonObject&
         case ExtractionResult::Status::SizeExceeded:
             errorCode = IpcErrorCode::InvalidParams;
             break;
+        case ExtractionResult::Status::Crashed:
+            errorCode = IpcErrorCode::CorruptedIndex;
+            break;
         default:
             errorCode = IpcErrorCode::InternalError;
             break;
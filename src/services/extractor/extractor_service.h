@@ -12,6 +12,7 @@ public:
 
 protected:
     QJsonObject handleRequest(const QJsonObject& request) override;
+    bool isAdminMethod(const QString& method) const override;
 
 private:
     QJsonObject handleExtractText(uint64_t id, const QJsonObject& params);
@@ -1,4 +1,6 @@
 #include "extractor_service.h"
+#include "core/shared/logging.h"
+
 #include <QCoreApplication>
 
 int main(int argc, char* argv[])
@@ -6,6 +8,7 @@ int main(int argc, char* argv[])
     QCoreApplication app(argc, argv);
     app.setApplicationName(QStringLiteral("betterspotlight-extractor"));
     app.setApplicationVersion(QStringLiteral("0.1.0"));
+    bs::Logging::install(QStringLiteral("extractor"));
 
     bs::ExtractorService service;
     return service.run();
@@ -1,15 +1,43 @@
 #include "indexer_service.h"
+#include "core/fs/extended_attributes.h"
+#include "core/fs/file_scanner.h"
+#include "core/indexing/indexing_checkpoint.h"
+#include "core/indexing/browser_source.h"
+#include "core/indexing/calendar_source.h"
+#include "core/indexing/contacts_source.h"
+#include "core/indexing/mail_source.h"
+#include "core/indexing/messages_source.h"
+#include "core/indexing/metadata_merge.h"
+#include "core/indexing/network_share.h"
+#include "core/indexing/notes_source.h"
+#include "core/indexing/peer_sync.h"
+#include "core/indexing/reminders_source.h"
+#include "core/indexing/s3_source.h"
+#include "core/indexing/spotlight_donation.h"
+#include "core/indexing/ssh_source.h"
 #include "core/ipc/message.h"
+#include "core/ipc/peer_client.h"
+#include "core/ipc/s3_client.h"
+#include "core/shared/crash_report.h"
+#include "core/shared/fda_check.h"
 #include "core/shared/logging.h"
 
 #include <QDateTime>
 #include <QByteArray>
 #include <QDir>
+#include <QElapsedTimer>
+#include <QFile>
 #include <QFileInfo>
 #include <QJsonArray>
+#include <QJsonDocument>
 #include <QStandardPaths>
+#include <QTemporaryDir>
 #include <QMetaObject>
+#include <QRandomGenerator>
+#include <QSet>
+#include <chrono>
 #include <cinttypes>
+#include <cmath>
 #include <algorithm>
 
 #if defined(__APPLE__)
@@ -20,6 +48,43 @@ namespace bs {
 
 namespace {
 
+// Deletions wait for the next compaction; check for one this often, and
+// run at most one a day unless `purgePath` asks for it.
+constexpr int kCompactionCheckIntervalMs = 15 * 60 * 1000;
+constexpr qint64 kCompactionMinIntervalSecs = 24 * 60 * 60;
+
+// Contacts has no change feed we can watch; re-read it this often.
+constexpr int kContactsSyncIntervalMs = 30 * 60 * 1000;
+// Nor does EventKit outside a running app; events move more often.
+constexpr int kCalendarSyncIntervalMs = 15 * 60 * 1000;
+// Browser files are only re-read when one of them changed.
+constexpr int kBrowserSyncIntervalMs = 10 * 60 * 1000;
+// Mail writes new messages as files; unchanged ones are not parsed again.
+constexpr int kMailSyncIntervalMs = 15 * 60 * 1000;
+// The Notes database is only re-read when it changed.
+constexpr int kNotesSyncIntervalMs = 10 * 60 * 1000;
+// chat.db changes with every message; only re-read when it did.
+constexpr int kMessagesSyncIntervalMs = 5 * 60 * 1000;
+// Reminders come from EventKit too, and are ticked off through the day.
+constexpr int kRemindersSyncIntervalMs = 10 * 60 * 1000;
+// Peers are asked for their segment digests; unchanged segments cost
+// nothing more.
+constexpr int kPeersSyncIntervalMs = 15 * 60 * 1000;
+// A remote listing is one command, but copies go over the network.
+constexpr int kSshSyncIntervalMs = 30 * 60 * 1000;
+// Each sync lists at most S3Source::kMaxPagesPerSync pages.
+constexpr int kS3SyncIntervalMs = 30 * 60 * 1000;
+// How often share schedules are checked; each share has its own interval.
+constexpr int kSharesTickIntervalMs = 60 * 1000;
+// A share crawl checks the share is still mounted this often, in visits.
+constexpr int kShareMountCheckInterval = 500;
+
+constexpr qsizetype kSecretHashKeyBytes = 32;
+
+// `progress` events between crawl milestones, at most this often.
+constexpr qint64 kProgressEventIntervalMs = 500;
+constexpr int kDefaultIndexingEventsLimit = 200;
+
 int readEnvInt(const char* key, int fallback, int minValue, int maxValue)
 {
     const QByteArray value = qgetenv(key);
@@ -54,6 +119,60 @@ int currentProcessRssMb()
 #endif
 }
 
+// Absolute, cleaned `path` param; nullopt with *error otherwise.
+std::optional<QString> absolutePathParam(const QJsonObject& params, QString* error)
+{
+    const QString path = params.value(QStringLiteral("path")).toString();
+    if (path.isEmpty()) {
+        *error = QStringLiteral("Missing 'path' parameter");
+        return std::nullopt;
+    }
+    if (!QDir::isAbsolutePath(path)) {
+        *error = QStringLiteral("'path' must be absolute");
+        return std::nullopt;
+    }
+    return QDir::cleanPath(path);
+}
+
+QJsonObject footprintJson(const SQLiteStore::PathFootprint& footprint)
+{
+    QJsonObject json;
+    json[QStringLiteral("items")] = static_cast<qint64>(footprint.items);
+    json[QStringLiteral("ftsRows")] = static_cast<qint64>(footprint.ftsRows);
+    json[QStringLiteral("behaviorEvents")] = static_cast<qint64>(footprint.behaviorEvents);
+    return json;
+}
+
+QJsonObject compactionJson(const SQLiteStore::CompactionSummary& summary)
+{
+    QJsonObject json;
+    json[QStringLiteral("compactedDeletes")] = static_cast<qint64>(summary.compactedDeletes);
+    json[QStringLiteral("ftsOptimized")] = summary.ftsOptimized;
+    json[QStringLiteral("vacuumed")] = summary.vacuumed;
+    json[QStringLiteral("walCheckpointed")] = summary.walCheckpointed;
+    json[QStringLiteral("bytesBefore")] = static_cast<qint64>(summary.bytesBefore);
+    json[QStringLiteral("bytesAfter")] = static_cast<qint64>(summary.bytesAfter);
+    json[QStringLiteral("complete")] = summary.complete();
+    return json;
+}
+
+// What the index still holds for `path`. Rows aside, deletions not yet
+// compacted may still sit in free pages or FTS5 segments, this path's
+// included, so verification needs none to be pending.
+QJsonObject purgeStatusJson(SQLiteStore& store, const QString& path)
+{
+    const SQLiteStore::PathFootprint remaining = store.footprintUnderPath(path);
+    const int64_t pendingDeletes = store.pendingCompactionDeletes();
+
+    QJsonObject json;
+    json[QStringLiteral("path")] = path;
+    json[QStringLiteral("remaining")] = footprintJson(remaining);
+    json[QStringLiteral("pendingCompactionDeletes")] = static_cast<qint64>(pendingDeletes);
+    json[QStringLiteral("freePages")] = static_cast<qint64>(store.freePageCount());
+    json[QStringLiteral("verified")] = remaining.empty() && pendingDeletes == 0;
+    return json;
+}
+
 QJsonObject memoryTelemetry()
 {
     const int rssMb = currentProcessRssMb();
@@ -82,20 +201,132 @@ QJsonObject memoryTelemetry()
     return memory;
 }
 
+// timeShare is each extractor's share of all extraction time, so the one
+// that dominates indexing stands out.
+QJsonArray extractorStatsJson(const ExtractionManager* extractor)
+{
+    QJsonArray extractors;
+    if (!extractor) {
+        return extractors;
+    }
+    const std::vector<ExtractionManager::ExtractorStats> stats = extractor->extractorStats();
+    int64_t allMs = 0;
+    for (const auto& entry : stats) {
+        allMs += entry.totalMs;
+    }
+    for (const auto& entry : stats) {
+        QJsonObject json;
+        json[QStringLiteral("extractor")] = entry.extractor;
+        json[QStringLiteral("extractions")] = static_cast<qint64>(entry.extractions);
+        json[QStringLiteral("failures")] = static_cast<qint64>(entry.failures);
+        json[QStringLiteral("timeouts")] = static_cast<qint64>(entry.timeouts);
+        json[QStringLiteral("totalMs")] = static_cast<qint64>(entry.totalMs);
+        json[QStringLiteral("avgMs")] = entry.extractions > 0
+            ? static_cast<double>(entry.totalMs) / static_cast<double>(entry.extractions)
+            : 0.0;
+        json[QStringLiteral("maxMs")] = static_cast<qint64>(entry.maxMs);
+        json[QStringLiteral("timeShare")] = allMs > 0
+            ? static_cast<double>(entry.totalMs) / static_cast<double>(allMs)
+            : 0.0;
+        extractors.append(json);
+    }
+    return extractors;
+}
+
 } // namespace
 
 IndexerService::IndexerService(QObject* parent)
     : ServiceBase(QStringLiteral("indexer"), parent)
 {
+    m_compactionTimer.setInterval(kCompactionCheckIntervalMs);
+    connect(&m_compactionTimer, &QTimer::timeout, this, &IndexerService::maybeCompactIndex);
+    m_contactsTimer.setInterval(kContactsSyncIntervalMs);
+    connect(&m_contactsTimer, &QTimer::timeout, this, [this]() { syncContacts(); });
+    m_calendarTimer.setInterval(kCalendarSyncIntervalMs);
+    connect(&m_calendarTimer, &QTimer::timeout, this, [this]() { syncCalendar(); });
+    m_browserTimer.setInterval(kBrowserSyncIntervalMs);
+    connect(&m_browserTimer, &QTimer::timeout, this, [this]() { syncBrowsers(false); });
+    m_mailTimer.setInterval(kMailSyncIntervalMs);
+    connect(&m_mailTimer, &QTimer::timeout, this, [this]() { syncMail(false); });
+    m_notesTimer.setInterval(kNotesSyncIntervalMs);
+    connect(&m_notesTimer, &QTimer::timeout, this, [this]() { syncNotes(false); });
+    m_messagesTimer.setInterval(kMessagesSyncIntervalMs);
+    connect(&m_messagesTimer, &QTimer::timeout, this, [this]() { syncMessages(false); });
+    m_remindersTimer.setInterval(kRemindersSyncIntervalMs);
+    connect(&m_remindersTimer, &QTimer::timeout, this, [this]() { syncReminders(); });
+    m_peersTimer.setInterval(kPeersSyncIntervalMs);
+    connect(&m_peersTimer, &QTimer::timeout, this, [this]() { syncPeers(); });
+    m_sshTimer.setInterval(kSshSyncIntervalMs);
+    connect(&m_sshTimer, &QTimer::timeout, this, [this]() { startSshSync(); });
+    m_s3Timer.setInterval(kS3SyncIntervalMs);
+    connect(&m_s3Timer, &QTimer::timeout, this, [this]() { startS3Sync(); });
+    m_sharesTimer.setInterval(kSharesTickIntervalMs);
+    connect(&m_sharesTimer, &QTimer::timeout, this, [this]() { startNetworkShareCrawl(false); });
     LOG_INFO(bsIpc, "IndexerService created");
 }
 
 IndexerService::~IndexerService()
 {
+    m_compactionTimer.stop();
+    m_contactsTimer.stop();
+    m_calendarTimer.stop();
+    m_browserTimer.stop();
+    m_mailTimer.stop();
+    m_notesTimer.stop();
+    m_messagesTimer.stop();
+    m_remindersTimer.stop();
+    m_peersTimer.stop();
+    m_sshTimer.stop();
+    m_s3Timer.stop();
+    m_sharesTimer.stop();
+    stopSshSync();
+    stopS3Sync();
+    stopNetworkShareCrawl();
+    joinCompactionThreadIfNeeded();
     if (m_pipeline) {
         m_pipeline->stop();
     }
     joinRebuildThreadIfNeeded();
+    joinReindexThreadIfNeeded();
+}
+
+void IndexerService::prepareForShutdown()
+{
+    m_compactionTimer.stop();
+    m_contactsTimer.stop();
+    m_calendarTimer.stop();
+    m_browserTimer.stop();
+    m_mailTimer.stop();
+    m_notesTimer.stop();
+    m_messagesTimer.stop();
+    m_remindersTimer.stop();
+    m_peersTimer.stop();
+    m_sshTimer.stop();
+    m_s3Timer.stop();
+    m_sharesTimer.stop();
+    stopSshSync();
+    stopS3Sync();
+    stopNetworkShareCrawl();
+    if (!m_pipeline || !m_isIndexing) {
+        return;
+    }
+    if (m_rebuildRunning.load()) {
+        // A rebuild has already cleared the index; its frontier is not
+        // meaningful on its own, so let the next start rescan from roots.
+        m_pipeline->stop();
+        m_isIndexing = false;
+        return;
+    }
+
+    const IndexingCheckpoint checkpoint = m_pipeline->stopWithCheckpoint();
+    m_isIndexing = false;
+    if (!checkpoint.save(m_checkpointPath)) {
+        LOG_WARN(bsIpc, "Failed to write indexing checkpoint to %s", qPrintable(m_checkpointPath));
+        return;
+    }
+    LOG_INFO(bsIpc, "Saved indexing checkpoint: %d pending item(s), %d unscanned root(s)",
+             static_cast<int>(checkpoint.pendingItems.size()),
+             static_cast<int>(checkpoint.unscannedRoots.size()));
 }
 
 QJsonObject IndexerService::handleRequest(const QJsonObject& request)
@@ -110,12 +341,58 @@ QJsonObject IndexerService::handleRequest(const QJsonObject& request)
     if (method == QLatin1String("setUserActive"))   return handleSetUserActive(id, params);
     if (method == QLatin1String("reindexPath"))     return handleReindexPath(id, params);
     if (method == QLatin1String("rebuildAll"))       return handleRebuildAll(id);
+    if (method == QLatin1String("donateItems"))     return handleDonateItems(id, params);
+    if (method == QLatin1String("deleteDonatedItems")) return handleDeleteDonatedItems(id, params);
+    if (method == QLatin1String("syncContacts"))    return handleSyncContacts(id);
+    if (method == QLatin1String("syncCalendar"))    return handleSyncCalendar(id);
+    if (method == QLatin1String("syncBrowsers"))    return handleSyncBrowsers(id, params);
+    if (method == QLatin1String("syncMail"))        return handleSyncMail(id, params);
+    if (method == QLatin1String("syncNotes"))       return handleSyncNotes(id, params);
+    if (method == QLatin1String("syncMessages"))    return handleSyncMessages(id, params);
+    if (method == QLatin1String("syncReminders"))   return handleSyncReminders(id);
+    if (method == QLatin1String("syncPeers"))       return handleSyncPeers(id);
+    if (method == QLatin1String("syncSshSources"))  return handleSyncSshSources(id);
+    if (method == QLatin1String("syncS3Sources"))   return handleSyncS3Sources(id);
+    if (method == QLatin1String("syncNetworkShares")) return handleSyncNetworkShares(id, params);
+    if (method == QLatin1String("addPrivacyExclusion")) return handleAddPrivacyExclusion(id, params);
+    if (method == QLatin1String("removePrivacyExclusion")) return handleRemovePrivacyExclusion(id, params);
+    if (method == QLatin1String("purgePath"))       return handlePurgePath(id, params);
+    if (method == QLatin1String("verifyPurged"))    return handleVerifyPurged(id, params);
     if (method == QLatin1String("getQueueStatus"))  return handleGetQueueStatus(id);
+    if (method == QLatin1String("getIndexingProgress")) return handleGetIndexingProgress(id);
+    if (method == QLatin1String("getIndexingEvents")) return handleGetIndexingEvents(id, params);
+    if (method == QLatin1String("getDiagnostics"))  return handleGetDiagnostics(id);
 
     // Fall through to base (ping, shutdown, unknown)
     return ServiceBase::handleRequest(request);
 }
 
+bool IndexerService::isAdminMethod(const QString& method) const
+{
+    static const QSet<QString> kAdminMethods = {
+        QStringLiteral("startIndexing"),
+        QStringLiteral("pauseIndexing"),
+        QStringLiteral("resumeIndexing"),
+        QStringLiteral("reindexPath"),
+        QStringLiteral("rebuildAll"),
+        QStringLiteral("addPrivacyExclusion"),
+        QStringLiteral("removePrivacyExclusion"),
+        QStringLiteral("purgePath"),
+        QStringLiteral("syncContacts"),
+        QStringLiteral("syncCalendar"),
+        QStringLiteral("syncBrowsers"),
+        QStringLiteral("syncMail"),
+        QStringLiteral("syncNotes"),
+        QStringLiteral("syncMessages"),
+        QStringLiteral("syncReminders"),
+        QStringLiteral("syncPeers"),
+        QStringLiteral("syncSshSources"),
+        QStringLiteral("syncS3Sources"),
+        QStringLiteral("syncNetworkShares"),
+    };
+    return kAdminMethods.contains(method) || ServiceBase::isAdminMethod(method);
+}
+
 QJsonObject IndexerService::handleStartIndexing(uint64_t id, const QJsonObject& params)
 {
     if (m_isIndexing) {
@@ -154,6 +431,7 @@ QJsonObject IndexerService::handleStartIndexing(uint64_t id, const QJsonObject&
     }
     QDir().mkpath(dataDir);
     QString dbPath = dataDir + QStringLiteral("/index.db");
+    m_checkpointPath = dataDir + QStringLiteral("/indexer-checkpoint.json");
 
     auto store = SQLiteStore::open(dbPath);
     if (!store.has_value()) {
@@ -161,6 +439,14 @@ QJsonObject IndexerService::handleStartIndexing(uint64_t id, const QJsonObject&
                                      QStringLiteral("Failed to open database at: %1").arg(dbPath));
     }
     m_store.emplace(std::move(store.value()));
+    CrashReport::setContext(QStringLiteral("index"), QJsonObject{
+        {QStringLiteral("dbPath"), dbPath},
+        {QStringLiteral("dbBytes"), QFileInfo(dbPath).size()},
+        {QStringLiteral("items"), static_cast<qint64>(m_store->itemCount())},
+        {QStringLiteral("schemaVersion"),
+         m_store->getSetting(QStringLiteral("schema_version")).value_or(QString())},
+        {QStringLiteral("roots"), static_cast<int>(roots.size())},
+    });
 
     // Load user-defined exclusion patterns from ~/.bsignore and start watching
     // for live updates so exclusions apply without restarting services.
@@ -192,8 +478,38 @@ QJsonObject IndexerService::handleStartIndexing(uint64_t id, const QJsonObject&
             LOG_INFO(bsIpc, "Extraction timeout: %d ms", timeoutMs);
         }
     }
-
+    m_extractor->setSecretRedactor(loadSecretRedactor());
+    // On unless explicitly turned off; a crash in Poppler or Tesseract then
+    // costs one file instead of the indexer.
+    const auto sandboxSetting = m_store->getSetting(QStringLiteral("extraction_sandbox"));
+    m_extractor->setSandboxedExtraction(!sandboxSetting.has_value()
+                                        || sandboxSetting.value() != QLatin1String("0"));
+
+    const QStringList privacyFolders = loadPrivacyExclusions(params);
     m_pipeline = std::make_unique<Pipeline>(m_store.value(), *m_extractor, m_pathRules);
+    if (auto xattrsJson = m_store->getSetting(QStringLiteral("indexed_xattrs"))) {
+        const QStringList xattrs = ExtendedAttributes::parseNameList(xattrsJson.value());
+        if (!xattrs.isEmpty()) {
+            m_pipeline->setIndexedXattrs(xattrs);
+            LOG_INFO(bsIpc, "Indexing xattrs: %s", qUtf8Printable(xattrs.join(QStringLiteral(", "))));
+        }
+    }
+    m_contentTypeRules = QJsonArray();
+    if (auto rulesJson = m_store->getSetting(QStringLiteral("content_type_rules"))) {
+        // A bad setting must not stop indexing; without rules every type
+        // keeps its content, as before the setting existed.
+        QString error;
+        const auto rules = ContentTypeRules::fromJson(
+            QJsonDocument::fromJson(rulesJson->toUtf8()).array(), &error);
+        if (!rules.has_value()) {
+            LOG_WARN(bsIpc, "Ignoring content_type_rules: %s", qUtf8Printable(error));
+        } else if (!rules->isEmpty()) {
+            m_pipeline->setContentTypeRules(rules.value());
+            m_contentTypeRules = rules->toJson();
+            LOG_INFO(bsIpc, "Content type rules for %d root(s)",
+                     static_cast<int>(rules->rules().size()));
+        }
+    }
 
     // Connect pipeline signals to IPC notifications
     connect(m_pipeline.get(), &Pipeline::progressUpdated,
@@ -219,16 +535,78 @@ QJsonObject IndexerService::handleStartIndexing(uint64_t id, const QJsonObject&
         params[QStringLiteral("timestamp")] = static_cast<qint64>(QDateTime::currentSecsSinceEpoch());
         sendNotification(QStringLiteral("indexingError"), params);
     });
+    connectIndexingEvents();
+
+    // Folders added while the indexer was down are purged before anything
+    // is scanned; already-purged ones cost one range lookup each.
+    m_pipeline->setPrivacyExclusions(privacyFolders);
+    size_t privacyPurged = 0;
+    for (const QString& folder : privacyFolders) {
+        privacyPurged += m_pipeline->purgePrivacyPath(folder).deletedItems;
+    }
 
-    // Start the pipeline
-    m_pipeline->start(roots);
+    // Resume from the last graceful shutdown when the root set is unchanged.
+    // The checkpoint is consumed either way: a crash after this point must
+    // fall back to a full scan rather than replay a stale frontier.
+    auto checkpoint = IndexingCheckpoint::load(m_checkpointPath);
+    QFile::remove(m_checkpointPath);
+    const bool resumed = checkpoint.has_value() && checkpoint->matchesRoots(roots);
+    if (resumed) {
+        m_pipeline->startFromCheckpoint(*checkpoint);
+    } else {
+        if (checkpoint.has_value()) {
+            LOG_INFO(bsIpc, "Discarding indexing checkpoint: roots changed");
+        }
+        m_pipeline->start(roots);
+    }
     m_currentRoots = roots;
     m_isIndexing = true;
+    m_compactionTimer.start();
+    syncContacts();
+    m_contactsTimer.start();
+    syncCalendar();
+    m_calendarTimer.start();
+    syncBrowsers();
+    m_browserTimer.start();
+    syncMail();
+    m_mailTimer.start();
+    syncNotes();
+    m_notesTimer.start();
+    syncMessages();
+    m_messagesTimer.start();
+    syncReminders();
+    m_remindersTimer.start();
+    syncPeers();
+    m_peersTimer.start();
+    startSshSync();
+    m_sshTimer.start();
+    startS3Sync();
+    m_s3Timer.start();
+    startNetworkShareCrawl(false);
+    m_sharesTimer.start();
 
     LOG_INFO(bsIpc, "Indexing started with %d root(s)", static_cast<int>(roots.size()));
+    // An unreadable root scans as empty, so say so instead of looking idle.
+    QStringList rootPaths;
+    for (const std::string& root : roots) {
+        rootPaths.append(QString::fromStdString(root));
+    }
+    const FileAccessReport access = FdaCheck::probeRoots(rootPaths, QDir::homePath());
+    for (const UnreadableFolder& folder : access.unreadableRoots) {
+        LOG_WARN(bsIpc, "Root %s is unreadable (%s); nothing under it will be indexed",
+                 qUtf8Printable(folder.path),
+                 qUtf8Printable(FdaCheck::reasonToString(folder.reason)));
+    }
+    for (const UnreadableFolder& folder : access.protectedFolders) {
+        LOG_WARN(bsIpc, "Protected folder %s is unreadable (%s) and will be skipped",
+                 qUtf8Printable(folder.path),
+                 qUtf8Printable(FdaCheck::reasonToString(folder.reason)));
+    }
 
     QJsonObject result;
     result[QStringLiteral("success")] = true;
+    result[QStringLiteral("resumedFromCheckpoint")] = resumed;
+    result[QStringLiteral("privacyPurgedItems")] = static_cast<qint64>(privacyPurged);
     result[QStringLiteral("queuedPaths")] = static_cast<qint64>(m_pipeline->queueStatus().depth);
     result[QStringLiteral("timestamp")] = static_cast<qint64>(QDateTime::currentSecsSinceEpoch());
     return IpcMessage::makeResponse(id, result);
@@ -297,18 +675,79 @@ QJsonObject IndexerService::handleReindexPath(uint64_t id, const QJsonObject& pa
         return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
                                      QStringLiteral("Missing 'path' parameter"));
     }
+    if (!QDir::isAbsolutePath(path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'path' must be absolute"));
+    }
+    path = QDir::cleanPath(path);
+
+    QStringList extractors;
+    const QStringList knownExtractors = ExtractionManager::extractorNames();
+    for (const QJsonValue& value : params.value(QStringLiteral("extractors")).toArray()) {
+        const QString name = value.toString();
+        if (!knownExtractors.contains(name)) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::InvalidParams,
+                QStringLiteral("Unknown extractor '%1' (expected one of: %2)")
+                    .arg(name, knownExtractors.join(QStringLiteral(", "))));
+        }
+        if (!extractors.contains(name)) {
+            extractors.append(name);
+        }
+    }
 
     if (!m_isIndexing || !m_pipeline) {
         return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
                                      QStringLiteral("Indexing is not running"));
     }
 
-    m_pipeline->reindexPath(path);
+    if (!QFileInfo(path).isDir()) {
+        const ReindexSummary summary = m_pipeline->reindexPath(path, extractors);
+        LOG_INFO(bsIpc, "Reindex queued for path: %s", qPrintable(path));
+
+        QJsonObject result;
+        result[QStringLiteral("queued")] = true;
+        result[QStringLiteral("scanning")] = false;
+        result[QStringLiteral("queuedFiles")] = static_cast<qint64>(summary.queued);
+        result[QStringLiteral("deletedEntries")] = 0;
+        return IpcMessage::makeResponse(id, result);
+    }
+
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    if (m_reindexRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A folder reindex is already running"));
+    }
+
+    joinReindexThreadIfNeeded();
+
+    // The subtree scan and its enqueues can wait on backpressure for a
+    // long time; the outcome goes out as a reindexPathComplete notification.
+    const qint64 reindexId = ++m_lastReindexId;
+    m_reindexRunning.store(true);
+    m_reindexThread = std::thread([this, path, extractors, reindexId]() {
+        const ReindexSummary summary = m_pipeline->reindexPath(path, extractors);
+
+        QJsonObject notification;
+        notification[QStringLiteral("reindexId")] = reindexId;
+        notification[QStringLiteral("path")] = path;
+        notification[QStringLiteral("queuedFiles")] = static_cast<qint64>(summary.queued);
+        notification[QStringLiteral("deletedEntries")] = static_cast<qint64>(summary.removed);
+        m_reindexRunning.store(false);
+        QMetaObject::invokeMethod(this, [this, notification]() {
+            sendNotification(QStringLiteral("reindexPathComplete"), notification);
+        }, Qt::QueuedConnection);
+    });
 
-    LOG_INFO(bsIpc, "Reindex queued for path: %s", qPrintable(path));
+    LOG_INFO(bsIpc, "Reindex started for folder: %s", qPrintable(path));
 
     QJsonObject result;
     result[QStringLiteral("queued")] = true;
+    result[QStringLiteral("scanning")] = true;
+    result[QStringLiteral("reindexId")] = reindexId;
     result[QStringLiteral("deletedEntries")] = 0;
     return IpcMessage::makeResponse(id, result);
 }
@@ -358,172 +797,2777 @@ QJsonObject IndexerService::handleRebuildAll(uint64_t id)
     return IpcMessage::makeResponse(id, result);
 }
 
-QJsonObject IndexerService::handleGetQueueStatus(uint64_t id)
+QJsonObject IndexerService::handleDonateItems(uint64_t id, const QJsonObject& params)
 {
-    QJsonArray roots;
-    for (const std::string& root : m_currentRoots) {
-        roots.append(QString::fromStdString(root));
+    QString error;
+    const auto items = SpotlightDonation::parseItems(
+        params, static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0, &error);
+    if (!items.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
     }
-
     if (!m_pipeline) {
-        // Return zeroed stats when pipeline is not yet created
-        QJsonObject lastProgress;
-        lastProgress[QStringLiteral("scanned")] = 0;
-        lastProgress[QStringLiteral("total")] = 0;
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
 
-        QJsonObject result;
-        result[QStringLiteral("pending")] = 0;
-        result[QStringLiteral("processing")] = 0;
-        result[QStringLiteral("failed")] = 0;
-        result[QStringLiteral("dropped")] = 0;
-        result[QStringLiteral("paused")] = false;
-        result[QStringLiteral("roots")] = roots;
-        result[QStringLiteral("lastProgressReport")] = lastProgress;
-        result[QStringLiteral("rebuildRunning")] = m_rebuildRunning.load();
-        result[QStringLiteral("rebuildStatus")] = m_rebuildRunning.load()
-            ? QStringLiteral("running")
-            : QStringLiteral("idle");
-        result[QStringLiteral("rebuildStartedAtMs")] = m_rebuildStartedAtMs.load();
-        result[QStringLiteral("rebuildFinishedAtMs")] = m_rebuildFinishedAtMs.load();
-        const QJsonObject bsignore = bsignoreStatusJson();
-        result[QStringLiteral("bsignorePath")] = bsignore.value(QStringLiteral("path")).toString();
-        result[QStringLiteral("bsignoreFileExists")] =
-            bsignore.value(QStringLiteral("fileExists")).toBool(false);
-        result[QStringLiteral("bsignoreLoaded")] = bsignore.value(QStringLiteral("loaded")).toBool(false);
-        result[QStringLiteral("bsignorePatternCount")] =
-            bsignore.value(QStringLiteral("patternCount")).toInt(0);
-        result[QStringLiteral("bsignoreLastLoadedAtMs")] =
-            bsignore.value(QStringLiteral("lastLoadedAtMs")).toInteger();
-        result[QStringLiteral("memory")] = memoryTelemetry();
-        result[QStringLiteral("actorMode")] = QStringLiteral("legacy");
-        result[QStringLiteral("bulkhead")] = QJsonObject();
-        return IpcMessage::makeResponse(id, result);
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (const IndexResult& outcome : m_pipeline->applyDonations(items.value())) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
     }
+    LOG_INFO(bsIpc, "Donation from %s: %d indexed, %d unchanged, %d failed",
+             qUtf8Printable(params.value(QStringLiteral("bundleIdentifier")).toString()),
+             indexed, unchanged, failed);
 
-    QueueStats stats = m_pipeline->queueStatus();
-    int processed = m_pipeline->processedCount();
-    const bool active = stats.depth > 0
-        || stats.activeItems > 0
-        || stats.preparing > 0
-        || stats.writing > 0;
+    QJsonObject result;
+    result[QStringLiteral("indexed")] = indexed;
+    result[QStringLiteral("unchanged")] = unchanged;
+    result[QStringLiteral("failed")] = failed;
+    return IpcMessage::makeResponse(id, result);
+}
 
-    if (m_lastQueueActive && !active) {
-        m_store->setSetting(QStringLiteral("last_full_index_at"),
-                            QString::number(QDateTime::currentSecsSinceEpoch()));
+QJsonObject IndexerService::handleDeleteDonatedItems(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const std::optional<DonationRemoval> removal = SpotlightDonation::parseRemoval(params, &error);
+    if (!removal.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
     }
-    m_lastQueueActive = active;
 
-    if (m_rebuildRunning.load() && m_rebuildAwaitingDrain.load() && !active) {
-        bool expected = true;
-        if (m_rebuildAwaitingDrain.compare_exchange_strong(expected, false)) {
-            const qint64 finishedAtMs = QDateTime::currentMSecsSinceEpoch();
-            m_rebuildFinishedAtMs.store(finishedAtMs);
-            m_rebuildRunning.store(false);
+    QJsonObject result;
+    result[QStringLiteral("deleted")] =
+        static_cast<qint64>(m_pipeline->removeDonations(removal.value()));
+    return IpcMessage::makeResponse(id, result);
+}
 
-            QJsonObject params;
-            params[QStringLiteral("startedAtMs")] = m_rebuildStartedAtMs.load();
-            params[QStringLiteral("finishedAtMs")] = finishedAtMs;
-            params[QStringLiteral("status")] = QStringLiteral("succeeded");
-            sendNotification(QStringLiteral("rebuildAllComplete"), params);
+QJsonObject IndexerService::handleSyncContacts(uint64_t id)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(id, syncContacts());
+}
+
+QJsonObject IndexerService::syncContacts()
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastContactsSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(ContactsSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(ContactsSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QHash<QString, double> indexedDates;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedDates.insert(identifier, row.modifiedAt);
         }
     }
 
-    QJsonObject lastProgress;
-    lastProgress[QStringLiteral("scanned")] = processed;
-    lastProgress[QStringLiteral("total")] = processed + static_cast<int>(stats.depth);
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    const auto removeAll = [&]() {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedDates.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+    };
+    if (!enabled) {
+        removeAll();
+        m_lastContactsSync = summary;
+        return summary;
+    }
 
-    QJsonObject result;
-    result[QStringLiteral("pending")] = static_cast<qint64>(stats.depth);
-    result[QStringLiteral("processing")] = static_cast<qint64>(stats.activeItems);
-    result[QStringLiteral("failed")] = static_cast<qint64>(stats.failedItems);
-    result[QStringLiteral("dropped")] = static_cast<qint64>(stats.droppedItems);
-    result[QStringLiteral("paused")] = stats.isPaused;
-    result[QStringLiteral("preparing")] = static_cast<qint64>(stats.preparing);
-    result[QStringLiteral("writing")] = static_cast<qint64>(stats.writing);
-    result[QStringLiteral("coalesced")] = static_cast<qint64>(stats.coalesced);
-    result[QStringLiteral("staleDropped")] = static_cast<qint64>(stats.staleDropped);
-    result[QStringLiteral("prepWorkers")] = static_cast<qint64>(stats.prepWorkers);
-    result[QStringLiteral("writerBatchDepth")] = static_cast<qint64>(stats.writerBatchDepth);
-    result[QStringLiteral("roots")] = roots;
-    result[QStringLiteral("lastProgressReport")] = lastProgress;
-    result[QStringLiteral("rebuildRunning")] = m_rebuildRunning.load();
-    result[QStringLiteral("rebuildStatus")] = m_rebuildRunning.load()
-        ? QStringLiteral("running")
-        : QStringLiteral("idle");
-    result[QStringLiteral("rebuildStartedAtMs")] = m_rebuildStartedAtMs.load();
-    result[QStringLiteral("rebuildFinishedAtMs")] = m_rebuildFinishedAtMs.load();
-    const QJsonObject bsignore = bsignoreStatusJson();
-    result[QStringLiteral("bsignorePath")] = bsignore.value(QStringLiteral("path")).toString();
-    result[QStringLiteral("bsignoreFileExists")] =
-        bsignore.value(QStringLiteral("fileExists")).toBool(false);
-    result[QStringLiteral("bsignoreLoaded")] = bsignore.value(QStringLiteral("loaded")).toBool(false);
-    result[QStringLiteral("bsignorePatternCount")] =
-        bsignore.value(QStringLiteral("patternCount")).toInt(0);
-    result[QStringLiteral("bsignoreLastLoadedAtMs")] =
-        bsignore.value(QStringLiteral("lastLoadedAtMs")).toInteger();
-    result[QStringLiteral("memory")] = memoryTelemetry();
-    const QJsonObject telemetry = m_pipeline->telemetrySnapshot();
-    result[QStringLiteral("actorMode")] =
-        telemetry.value(QStringLiteral("actorMode")).toString(QStringLiteral("legacy"));
-    result[QStringLiteral("bulkhead")] = telemetry;
-    return IpcMessage::makeResponse(id, result);
+    const ContactsSource::Access access = ContactsSource::accessStatus();
+    summary[QStringLiteral("access")] = ContactsSource::accessToString(access);
+    if (access == ContactsSource::Access::NotDetermined) {
+        ContactsSource::requestAccess();
+    }
+    if (access == ContactsSource::Access::Denied) {
+        // Revoking access in System Settings takes the cards out too.
+        removeAll();
+        m_lastContactsSync = summary;
+        return summary;
+    }
+    QString error;
+    const auto records = ContactsSource::readContacts(&error);
+    if (!records.has_value()) {
+        // Keep what is indexed; the next sync tries again.
+        LOG_WARN(bsIpc, "Contacts sync skipped: %s", qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastContactsSync = summary;
+        return summary;
+    }
+
+    const double now = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (const IndexResult& outcome :
+         m_pipeline->applyDonations(ContactsSource::items(records.value(), indexedDates, now))) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
+    }
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    removal.uniqueIdentifiers =
+        ContactsSource::staleIdentifiers(indexedDates.keys(), records.value());
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    LOG_INFO(bsIpc, "Contacts sync: %d indexed, %d unchanged, %d failed, %d removed",
+             indexed, unchanged, failed, static_cast<int>(removed));
+
+    summary[QStringLiteral("contacts")] = static_cast<qint64>(records->size());
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastContactsSync = summary;
+    return summary;
 }
 
-void IndexerService::joinRebuildThreadIfNeeded()
+QJsonObject IndexerService::handleSyncCalendar(uint64_t id)
 {
-    if (m_rebuildThread.joinable()) {
-        if (m_rebuildThread.get_id() == std::this_thread::get_id()) {
-            return;
-        }
-        m_rebuildThread.join();
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
     }
+    return IpcMessage::makeResponse(id, syncCalendar());
 }
 
-void IndexerService::configureBsignoreWatcher()
+QJsonObject IndexerService::syncCalendar()
 {
-    if (m_bsignorePath.isEmpty()) {
-        return;
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastCalendarSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(CalendarSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(CalendarSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
     }
 
-    if (!m_bsignoreWatcher) {
-        m_bsignoreWatcher = std::make_unique<QFileSystemWatcher>(this);
-        connect(m_bsignoreWatcher.get(), &QFileSystemWatcher::fileChanged,
-                this, &IndexerService::onBsignorePathChanged);
-        connect(m_bsignoreWatcher.get(), &QFileSystemWatcher::directoryChanged,
-                this, &IndexerService::onBsignoreDirectoryChanged);
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    const auto removeAll = [&]() {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+    };
+    if (!enabled) {
+        removeAll();
+        m_lastCalendarSync = summary;
+        return summary;
     }
 
-    if (!m_bsignoreWatcher->files().isEmpty()) {
-        m_bsignoreWatcher->removePaths(m_bsignoreWatcher->files());
+    const CalendarSource::Access access = CalendarSource::accessStatus();
+    summary[QStringLiteral("access")] = CalendarSource::accessToString(access);
+    if (access == CalendarSource::Access::NotDetermined) {
+        CalendarSource::requestAccess();
     }
-    if (!m_bsignoreWatcher->directories().isEmpty()) {
-        m_bsignoreWatcher->removePaths(m_bsignoreWatcher->directories());
+    if (access == CalendarSource::Access::Denied) {
+        // Revoking access in System Settings takes the events out too.
+        removeAll();
+        m_lastCalendarSync = summary;
+        return summary;
+    }
+    const double now = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    QString error;
+    const auto events = CalendarSource::readEvents(
+        now - CalendarSource::kPastDays * 86400.0, now + CalendarSource::kFutureDays * 86400.0,
+        &error);
+    if (!events.has_value()) {
+        // Keep what is indexed; the next sync tries again.
+        LOG_WARN(bsIpc, "Calendar sync skipped: %s", qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastCalendarSync = summary;
+        return summary;
     }
 
-    const QFileInfo info(m_bsignorePath);
-    const QString parentDir = info.absoluteDir().absolutePath();
-    if (QFileInfo::exists(parentDir)) {
-        m_bsignoreWatcher->addPath(parentDir);
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (const IndexResult& outcome :
+         m_pipeline->applyDonations(CalendarSource::items(events.value()))) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
     }
-    if (info.exists()) {
-        m_bsignoreWatcher->addPath(m_bsignorePath);
+    // Deleted events, and events that have moved out of the window.
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    removal.uniqueIdentifiers =
+        CalendarSource::staleIdentifiers(indexedIdentifiers, events.value());
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    LOG_INFO(bsIpc, "Calendar sync: %d indexed, %d unchanged, %d failed, %d removed",
+             indexed, unchanged, failed, static_cast<int>(removed));
+
+    summary[QStringLiteral("events")] = static_cast<qint64>(events->size());
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastCalendarSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncBrowsers(uint64_t id, const QJsonObject& params)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
     }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(
+        id, syncBrowsers(params.value(QStringLiteral("force")).toBool(true)));
 }
 
-void IndexerService::reloadBsignore()
+QJsonObject IndexerService::syncBrowsers(bool force)
 {
-    if (m_bsignorePath.isEmpty()) {
-        return;
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastBrowserSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(BrowserSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(BrowserSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
     }
 
-    m_bsignoreLoaded = m_pathRules.loadBsignore(m_bsignorePath.toStdString());
-    m_bsignorePatternCount = static_cast<int>(m_pathRules.bsignorePatternCount());
-    m_bsignoreLastLoadedAtMs = m_pathRules.bsignoreLastLoadedAtMs();
-    configureBsignoreWatcher();
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    if (!enabled) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+        m_browserStamps.clear();
+        m_lastBrowserSync = summary;
+        return summary;
+    }
 
-    QJsonObject params = bsignoreStatusJson();
-    params[QStringLiteral("timestamp")] = static_cast<qint64>(QDateTime::currentMSecsSinceEpoch());
-    sendNotification(QStringLiteral("bsignoreReloaded"), params);
+    // Incremental: nothing is read while no history or bookmark file (or
+    // write-ahead log) has changed since the last complete sync.
+    const std::vector<BrowserProfile> profiles = BrowserSource::profiles(QDir::homePath());
+    QHash<QString, qint64> stamps;
+    for (const BrowserProfile& profile : profiles) {
+        for (const QString& path : {profile.historyPath,
+                                    profile.historyPath + QStringLiteral("-wal"),
+                                    profile.bookmarksPath}) {
+            const QFileInfo info(path);
+            if (info.exists()) {
+                stamps.insert(path, info.lastModified().toMSecsSinceEpoch() ^ info.size());
+            }
+        }
+    }
+    if (!force && !m_lastBrowserSync.isEmpty() && stamps == m_browserStamps) {
+        return m_lastBrowserSync;
+    }
+
+    const double now = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    const double since = now - BrowserSource::kHistoryDays * 86400.0;
+    std::vector<BrowserPage> pages;
+    QJsonArray profileSummaries;
+    bool complete = true;
+    for (const BrowserProfile& profile : profiles) {
+        QJsonObject profileSummary;
+        profileSummary[QStringLiteral("browser")] = profile.browser;
+        QStringList errors;
+        int visited = 0;
+        int bookmarks = 0;
+        const auto add = [&](std::vector<BrowserPage> found, int* count) {
+            for (BrowserPage& page : found) {
+                page.browsers = {profile.browser};
+                pages.push_back(std::move(page));
+            }
+            *count = static_cast<int>(found.size());
+        };
+
+        if (QFileInfo::exists(profile.historyPath)) {
+            QString error;
+            auto history = BrowserSource::readHistory(profile.engine, profile.historyPath, since,
+                                                      BrowserSource::kMaxPagesPerProfile, &error);
+            if (history.has_value()) {
+                add(std::move(history.value()), &visited);
+            } else {
+                errors.append(error);
+            }
+        }
+        if (QFileInfo::exists(profile.bookmarksPath)) {
+            std::optional<std::vector<BrowserPage>> found;
+            if (profile.engine == BrowserProfile::Engine::Safari) {
+                found = BrowserSource::readSafariBookmarks(profile.bookmarksPath);
+            } else {
+                QFile file(profile.bookmarksPath);
+                if (file.open(QIODevice::ReadOnly)) {
+                    found = BrowserSource::parseChromiumBookmarks(file.readAll());
+                }
+            }
+            if (found.has_value()) {
+                add(std::move(found.value()), &bookmarks);
+            } else {
+                errors.append(QStringLiteral("%1 could not be read").arg(profile.bookmarksPath));
+            }
+        }
+        profileSummary[QStringLiteral("visited")] = visited;
+        profileSummary[QStringLiteral("bookmarks")] = bookmarks;
+        if (!errors.isEmpty()) {
+            // Usually Safari without Full Disk Access.
+            LOG_WARN(bsIpc, "Browser sync: %s: %s", qUtf8Printable(profile.browser),
+                     qUtf8Printable(errors.join(QStringLiteral("; "))));
+            profileSummary[QStringLiteral("error")] = errors.join(QStringLiteral("; "));
+            complete = false;
+        }
+        profileSummaries.append(profileSummary);
+    }
+
+    const std::vector<DonatedItem> items = BrowserSource::items(pages);
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (const IndexResult& outcome : m_pipeline->applyDonations(items)) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
+    }
+    // Pages nobody visited in kHistoryDays and that are not bookmarked. A
+    // profile that failed to read keeps its pages until it reads again.
+    size_t removed = 0;
+    if (complete) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.uniqueIdentifiers = BrowserSource::staleIdentifiers(indexedIdentifiers, pages);
+        removed = removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+        m_browserStamps = stamps;
+    }
+    LOG_INFO(bsIpc, "Browser sync: %d pages, %d indexed, %d unchanged, %d failed, %d removed",
+             static_cast<int>(items.size()), indexed, unchanged, failed,
+             static_cast<int>(removed));
+
+    summary[QStringLiteral("profiles")] = profileSummaries;
+    summary[QStringLiteral("pages")] = static_cast<qint64>(items.size());
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastBrowserSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncMail(uint64_t id, const QJsonObject& params)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(
+        id, syncMail(params.value(QStringLiteral("force")).toBool(true)));
+}
+
+QJsonObject IndexerService::syncMail(bool force)
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastMailSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(MailSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(MailSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
+    }
+
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    const QString storePath = MailSource::storePath(QDir::homePath());
+    if (!enabled || storePath.isEmpty()) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+        if (enabled) {
+            // No V<n> folder, or one this process may not list.
+            summary[QStringLiteral("error")] = QStringLiteral("Mail's store was not found");
+        }
+        m_mailStamps.clear();
+        m_lastMailSync = summary;
+        return summary;
+    }
+
+    QStringList excluded;
+    const QJsonDocument excludedDoc = QJsonDocument::fromJson(
+        m_store->getSetting(QString::fromLatin1(MailSource::kExcludedAccountsKey))
+            .value_or(QString())
+            .toUtf8());
+    for (const QJsonValue& value : excludedDoc.array()) {
+        excluded.append(value.toString());
+    }
+
+    struct MessageFile {
+        QString path;
+        QString identifier;
+        qint64 stamp = 0;
+        qint64 modifiedMs = 0;
+        size_t mailbox = 0;
+    };
+    const std::vector<MailMailbox> mailboxes = MailSource::mailboxes(storePath);
+    std::vector<MessageFile> files;
+    QHash<QString, QJsonObject> accounts;
+    QHash<QString, QStringList> accountMailboxes;
+    for (size_t i = 0; i < mailboxes.size(); ++i) {
+        const MailMailbox& mailbox = mailboxes[i];
+        if (!accounts.contains(mailbox.accountId)) {
+            const QString address = MailSource::accountAddress(mailbox.accountId);
+            QJsonObject account;
+            account[QStringLiteral("id")] = mailbox.accountId;
+            if (!address.isEmpty()) {
+                account[QStringLiteral("address")] = address;
+            }
+            account[QStringLiteral("excluded")] = MailSource::isExcluded(
+                mailbox.accountId, address.isEmpty() ? QStringList() : QStringList{address},
+                excluded);
+            accounts.insert(mailbox.accountId, account);
+        }
+        if (accounts.value(mailbox.accountId).value(QStringLiteral("excluded")).toBool()
+            || m_pathRules.isPrivacyExcluded(mailbox.path.toStdString())) {
+            continue;
+        }
+        accountMailboxes[mailbox.accountId].append(mailbox.name);
+        for (const QString& path : MailSource::messageFiles(mailbox)) {
+            const QFileInfo info(path);
+            const qint64 modifiedMs = info.lastModified().toMSecsSinceEpoch();
+            files.push_back({path, MailSource::identifier(mailbox, path),
+                             modifiedMs ^ info.size(), modifiedMs, i});
+        }
+    }
+
+    // The newest kMaxMessages; a downloaded message replaces its .partial.
+    std::sort(files.begin(), files.end(), [](const MessageFile& a, const MessageFile& b) {
+        return a.modifiedMs > b.modifiedMs;
+    });
+    QSet<QString> current;
+    std::vector<const MessageFile*> wanted;
+    for (const MessageFile& file : files) {
+        if (static_cast<int>(wanted.size()) >= MailSource::kMaxMessages) {
+            break;
+        }
+        if (!current.contains(file.identifier)) {
+            current.insert(file.identifier);
+            wanted.push_back(&file);
+        }
+    }
+
+    // Incremental: a message whose file has not changed since the last sync
+    // is not read again.
+    const QSet<QString> indexedSet(indexedIdentifiers.begin(), indexedIdentifiers.end());
+    QHash<QString, qint64> stamps;
+    std::vector<DonatedItem> items;
+    int unreadable = 0;
+    for (const MessageFile* file : wanted) {
+        if (!force && indexedSet.contains(file->identifier)
+            && m_mailStamps.value(file->identifier) == file->stamp) {
+            stamps.insert(file->identifier, file->stamp);
+            continue;
+        }
+        QFile handle(file->path);
+        std::optional<MailMessage> message;
+        if (handle.open(QIODevice::ReadOnly)) {
+            message = MailSource::parseEmlx(handle.readAll());
+        }
+        if (!message.has_value()) {
+            ++unreadable;
+            continue;
+        }
+        message->partial = file->path.endsWith(QLatin1String(".partial.emlx"));
+        items.push_back(MailSource::item(message.value(), mailboxes[file->mailbox],
+                                         file->identifier));
+        stamps.insert(file->identifier, file->stamp);
+    }
+
+    int indexed = 0;
+    int unchanged = static_cast<int>(wanted.size() - items.size()) - unreadable;
+    int failed = 0;
+    for (size_t offset = 0; offset < items.size();
+         offset += SpotlightDonation::kMaxItemsPerRequest) {
+        const size_t end = std::min(items.size(), offset + SpotlightDonation::kMaxItemsPerRequest);
+        const std::vector<DonatedItem> batch(items.begin() + offset, items.begin() + end);
+        for (const IndexResult& outcome : m_pipeline->applyDonations(batch)) {
+            switch (outcome.status) {
+            case IndexResult::Status::Indexed:  ++indexed; break;
+            case IndexResult::Status::Skipped:  ++unchanged; break;
+            default:                            ++failed; break;
+            }
+        }
+    }
+
+    // Deleted or moved messages, excluded accounts and those past the cap.
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    for (const QString& identifier : indexedIdentifiers) {
+        if (!current.contains(identifier)) {
+            removal.uniqueIdentifiers.append(identifier);
+        }
+    }
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    m_mailStamps = stamps;
+    LOG_INFO(bsIpc, "Mail sync: %d messages, %d indexed, %d unchanged, %d unreadable, "
+                    "%d failed, %d removed",
+             static_cast<int>(wanted.size()), indexed, unchanged, unreadable, failed,
+             static_cast<int>(removed));
+
+    QJsonArray accountSummaries;
+    QStringList accountIds = accounts.keys();
+    accountIds.sort();
+    for (const QString& accountId : accountIds) {
+        QJsonObject account = accounts.value(accountId);
+        account[QStringLiteral("mailboxes")] =
+            QJsonArray::fromStringList(accountMailboxes.value(accountId));
+        accountSummaries.append(account);
+    }
+    summary[QStringLiteral("store")] = storePath;
+    summary[QStringLiteral("accounts")] = accountSummaries;
+    summary[QStringLiteral("messages")] = static_cast<qint64>(wanted.size());
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("unreadable")] = unreadable;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastMailSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncNotes(uint64_t id, const QJsonObject& params)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(
+        id, syncNotes(params.value(QStringLiteral("force")).toBool(true)));
+}
+
+QJsonObject IndexerService::syncNotes(bool force)
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastNotesSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(NotesSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(NotesSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
+    }
+
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    if (!enabled) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+        m_notesStamps.clear();
+        m_lastNotesSync = summary;
+        return summary;
+    }
+
+    // Incremental: nothing is read while neither the database nor its
+    // write-ahead log has changed since the last complete sync.
+    const QString storePath = NotesSource::storePath(QDir::homePath());
+    QHash<QString, qint64> stamps;
+    for (const QString& path : {storePath, storePath + QStringLiteral("-wal")}) {
+        const QFileInfo info(path);
+        if (info.exists()) {
+            stamps.insert(path, info.lastModified().toMSecsSinceEpoch() ^ info.size());
+        }
+    }
+    if (!force && !m_lastNotesSync.isEmpty() && stamps == m_notesStamps) {
+        return m_lastNotesSync;
+    }
+
+    QString error;
+    const std::optional<std::vector<AppleNote>> notes = NotesSource::readNotes(storePath, &error);
+    if (!notes.has_value()) {
+        // Usually no Full Disk Access. What was indexed stays until the
+        // database reads again.
+        LOG_WARN(bsIpc, "Notes sync: %s", qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastNotesSync = summary;
+        return summary;
+    }
+
+    const std::vector<DonatedItem> items = NotesSource::items(notes.value());
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (const IndexResult& outcome : m_pipeline->applyDonations(items)) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
+    }
+    int locked = 0;
+    for (const AppleNote& note : notes.value()) {
+        locked += note.locked ? 1 : 0;
+    }
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    removal.uniqueIdentifiers = NotesSource::staleIdentifiers(indexedIdentifiers, notes.value());
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    m_notesStamps = stamps;
+    LOG_INFO(bsIpc, "Notes sync: %d notes, %d indexed, %d unchanged, %d failed, %d removed",
+             static_cast<int>(items.size()), indexed, unchanged, failed,
+             static_cast<int>(removed));
+
+    summary[QStringLiteral("notes")] = static_cast<qint64>(items.size());
+    summary[QStringLiteral("locked")] = locked;
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastNotesSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncMessages(uint64_t id, const QJsonObject& params)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(
+        id, syncMessages(params.value(QStringLiteral("force")).toBool(true)));
+}
+
+QJsonObject IndexerService::syncMessages(bool force)
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastMessagesSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(MessagesSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(MessagesSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
+    }
+
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    if (!enabled) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+        m_messagesStamps.clear();
+        m_lastMessagesSync = summary;
+        return summary;
+    }
+
+    // Incremental: nothing is read while neither the database nor its
+    // write-ahead log has changed since the last complete sync.
+    const QString storePath = MessagesSource::storePath(QDir::homePath());
+    QHash<QString, qint64> stamps;
+    for (const QString& path : {storePath, storePath + QStringLiteral("-wal")}) {
+        const QFileInfo info(path);
+        if (info.exists()) {
+            stamps.insert(path, info.lastModified().toMSecsSinceEpoch() ^ info.size());
+        }
+    }
+    if (!force && !m_lastMessagesSync.isEmpty() && stamps == m_messagesStamps) {
+        return m_lastMessagesSync;
+    }
+
+    QString error;
+    const std::optional<std::vector<ChatMessage>> messages =
+        MessagesSource::readMessages(storePath, MessagesSource::kMaxMessages, &error);
+    if (!messages.has_value()) {
+        // Usually no Full Disk Access. What was indexed stays until the
+        // database reads again.
+        LOG_WARN(bsIpc, "Messages sync: %s", qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastMessagesSync = summary;
+        return summary;
+    }
+
+    const std::vector<DonatedItem> items = MessagesSource::items(messages.value());
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    for (size_t offset = 0; offset < items.size();
+         offset += SpotlightDonation::kMaxItemsPerRequest) {
+        const size_t end = std::min(items.size(), offset + SpotlightDonation::kMaxItemsPerRequest);
+        const std::vector<DonatedItem> batch(items.begin() + offset, items.begin() + end);
+        for (const IndexResult& outcome : m_pipeline->applyDonations(batch)) {
+            switch (outcome.status) {
+            case IndexResult::Status::Indexed:  ++indexed; break;
+            case IndexResult::Status::Skipped:  ++unchanged; break;
+            default:                            ++failed; break;
+            }
+        }
+    }
+    QSet<QString> conversations;
+    for (const ChatMessage& message : messages.value()) {
+        conversations.insert(message.conversation);
+    }
+    // Deleted messages and those that aged past the cap.
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    removal.uniqueIdentifiers =
+        MessagesSource::staleIdentifiers(indexedIdentifiers, messages.value());
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    m_messagesStamps = stamps;
+    // Counts only: message text and handles stay out of the log.
+    LOG_INFO(bsIpc, "Messages sync: %d messages, %d indexed, %d unchanged, %d failed, %d removed",
+             static_cast<int>(items.size()), indexed, unchanged, failed,
+             static_cast<int>(removed));
+
+    summary[QStringLiteral("messages")] = static_cast<qint64>(items.size());
+    summary[QStringLiteral("conversations")] = static_cast<qint64>(conversations.size());
+    summary[QStringLiteral("capped")] =
+        static_cast<int>(messages->size()) >= MessagesSource::kMaxMessages;
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastMessagesSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncReminders(uint64_t id)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    return IpcMessage::makeResponse(id, syncReminders());
+}
+
+QJsonObject IndexerService::syncReminders()
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load()) {
+        return m_lastRemindersSync;
+    }
+    const QString bundleIdentifier = QString::fromLatin1(RemindersSource::kBundleIdentifier);
+    const bool enabled =
+        m_store->getSetting(QString::fromLatin1(RemindersSource::kSettingKey)).value_or(QString())
+        == QLatin1String("1");
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
+    }
+
+    QJsonObject summary;
+    summary[QStringLiteral("enabled")] = enabled;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+    const auto removeAll = [&]() {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.all = true;
+        summary[QStringLiteral("removed")] = indexedIdentifiers.isEmpty()
+            ? 0
+            : static_cast<qint64>(m_pipeline->removeDonations(removal));
+    };
+    if (!enabled) {
+        removeAll();
+        m_lastRemindersSync = summary;
+        return summary;
+    }
+
+    const RemindersSource::Access access = RemindersSource::accessStatus();
+    summary[QStringLiteral("access")] = RemindersSource::accessToString(access);
+    if (access == RemindersSource::Access::NotDetermined) {
+        RemindersSource::requestAccess();
+    }
+    if (access == RemindersSource::Access::Denied) {
+        removeAll();
+        m_lastRemindersSync = summary;
+        return summary;
+    }
+    const double now = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+    QString error;
+    const auto reminders = RemindersSource::readReminders(
+        now - RemindersSource::kCompletedDays * 86400.0, &error);
+    if (!reminders.has_value()) {
+        // Keep what is indexed; the next sync tries again.
+        LOG_WARN(bsIpc, "Reminders sync skipped: %s", qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastRemindersSync = summary;
+        return summary;
+    }
+
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    int completed = 0;
+    for (const Reminder& reminder : reminders.value()) {
+        completed += reminder.completed ? 1 : 0;
+    }
+    for (const IndexResult& outcome :
+         m_pipeline->applyDonations(RemindersSource::items(reminders.value()))) {
+        switch (outcome.status) {
+        case IndexResult::Status::Indexed:  ++indexed; break;
+        case IndexResult::Status::Skipped:  ++unchanged; break;
+        default:                            ++failed; break;
+        }
+    }
+    // Deleted reminders, and completed ones older than kCompletedDays.
+    DonationRemoval removal;
+    removal.bundleIdentifier = bundleIdentifier;
+    removal.uniqueIdentifiers =
+        RemindersSource::staleIdentifiers(indexedIdentifiers, reminders.value());
+    const size_t removed =
+        removal.uniqueIdentifiers.isEmpty() ? 0 : m_pipeline->removeDonations(removal);
+    LOG_INFO(bsIpc, "Reminders sync: %d indexed, %d unchanged, %d failed, %d removed",
+             indexed, unchanged, failed, static_cast<int>(removed));
+
+    summary[QStringLiteral("reminders")] = static_cast<qint64>(reminders->size());
+    summary[QStringLiteral("completed")] = completed;
+    summary[QStringLiteral("indexed")] = indexed;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    m_lastRemindersSync = summary;
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncPeers(uint64_t id)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    if (m_peersSyncing) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A peer sync is already running"));
+    }
+    return IpcMessage::makeResponse(id, syncPeers());
+}
+
+QJsonObject IndexerService::syncPeers()
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load() || m_peersSyncing) {
+        return m_lastPeersSync;
+    }
+    QJsonObject summary;
+    summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+
+    QString error;
+    auto peers = PeerSync::parsePeers(
+        m_store->getSetting(QString::fromLatin1(PeerSync::kSettingKey)).value_or(QString()),
+        &error);
+    if (!peers.has_value()) {
+        // A typo in the list must not drop every replica; keep them until
+        // it parses again.
+        LOG_WARN(bsIpc, "Ignoring %s: %s", PeerSync::kSettingKey, qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        m_lastPeersSync = summary;
+        return summary;
+    }
+    summary[QStringLiteral("enabled")] = !peers->empty();
+
+    const auto loadState = [this](const char* key) {
+        return QJsonDocument::fromJson(
+                   m_store->getSetting(QString::fromLatin1(key)).value_or(QString()).toUtf8())
+            .object();
+    };
+    QJsonObject state = loadState(PeerSync::kStateSettingKey);
+    QJsonObject cursors = loadState(PeerSync::kCursorSettingKey);
+
+    auto policies = MetadataMerge::parsePolicies(
+        m_store->getSetting(QString::fromLatin1(MetadataMerge::kSettingKey)).value_or(QString()),
+        &error);
+    if (!policies.has_value()) {
+        LOG_WARN(bsIpc, "Ignoring %s: %s", MetadataMerge::kSettingKey, qUtf8Printable(error));
+        policies = MetadataMerge::defaultPolicies();
+    }
+
+    // PeerClient waits in a local event loop, which also delivers the
+    // timer and IPC requests; another sync started from there would save
+    // its own copy of the state and cursors over this one's.
+    m_peersSyncing = true;
+    QJsonArray peerSummaries;
+    QSet<QString> configured;
+    for (const SyncPeer& peer : peers.value()) {
+        configured.insert(peer.name.toLower());
+        peerSummaries.append(syncPeer(peer, policies.value(), state, cursors));
+    }
+
+    // Peers taken off the list take their replicas with them.
+    int removedPeers = 0;
+    for (const QString& name : state.keys()) {
+        if (configured.contains(name)) {
+            continue;
+        }
+        DonationRemoval removal;
+        removal.bundleIdentifier = PeerSync::bundleIdentifier(name);
+        removal.all = true;
+        m_pipeline->removeDonations(removal);
+        state.remove(name);
+        ++removedPeers;
+    }
+    for (const QString& name : cursors.keys()) {
+        if (!configured.contains(name)) {
+            cursors.remove(name);
+        }
+    }
+    saveSyncState(state, cursors);
+    m_peersSyncing = false;
+
+    summary[QStringLiteral("peers")] = peerSummaries;
+    summary[QStringLiteral("removedPeers")] = removedPeers;
+    m_lastPeersSync = summary;
+    return summary;
+}
+
+void IndexerService::saveSyncState(const QJsonObject& state, const QJsonObject& cursors)
+{
+    m_store->setSetting(QString::fromLatin1(PeerSync::kStateSettingKey),
+                        QString::fromUtf8(QJsonDocument(state).toJson(QJsonDocument::Compact)));
+    m_store->setSetting(QString::fromLatin1(PeerSync::kCursorSettingKey),
+                        QString::fromUtf8(QJsonDocument(cursors).toJson(QJsonDocument::Compact)));
+}
+
+QJsonObject IndexerService::syncPeer(const SyncPeer& peer,
+                                     const MetadataMerge::Policies& policies, QJsonObject& state,
+                                     QJsonObject& cursors)
+{
+    const QString name = peer.name.toLower();
+    const QString bundleIdentifier = PeerSync::bundleIdentifier(name);
+    QJsonObject summary;
+    summary[QStringLiteral("name")] = peer.name;
+
+    PeerEndpoint endpoint;
+    endpoint.baseUrl = peer.url;
+    endpoint.token = peer.token.toUtf8();
+    endpoint.fingerprint = peer.fingerprint;
+
+    PeerSync::SegmentDigests digests =
+        PeerSync::digestsFromJson(state.value(name).toObject());
+    QJsonObject replication = cursors.value(name).toObject();
+    const auto persist = [&]() {
+        state[name] = PeerSync::digestsToJson(digests);
+        cursors[name] = replication;
+        saveSyncState(state, cursors);
+    };
+
+    int deltaSegments = 0;
+    int fetched = 0;
+    int failedSegments = 0;
+    int indexed = 0;
+    int unchanged = 0;
+    int failed = 0;
+    int merged = 0;
+    size_t removed = 0;
+    const auto applyItems = [&](const std::vector<SyncedItem>& items) {
+        std::vector<DonatedItem> donations;
+        donations.reserve(items.size());
+        for (const SyncedItem& item : items) {
+            donations.push_back(PeerSync::toDonatedItem(name, item));
+        }
+        for (const IndexResult& outcome : m_pipeline->applyDonations(donations)) {
+            switch (outcome.status) {
+            case IndexResult::Status::Indexed:  ++indexed; break;
+            case IndexResult::Status::Skipped:  ++unchanged; break;
+            default:                            ++failed; break;
+            }
+        }
+        // The same file indexed here: its tags, pin and frecency take in
+        // the peer's. The replica keeps none of them.
+        for (const SyncedItem& item : items) {
+            if (item.metadata.isEmpty()) {
+                continue;
+            }
+            const auto local = m_store->getItemByPath(item.path);
+            if (local.has_value()
+                && MetadataMerge::apply(*m_store, local->id, local->indexedAt, item.metadata,
+                                        policies)) {
+                ++merged;
+            }
+        }
+    };
+    const auto removeReplicas = [&](const QStringList& identifiers) {
+        if (identifiers.isEmpty()) {
+            return;
+        }
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.uniqueIdentifiers = identifiers;
+        removed += m_pipeline->removeDonations(removal);
+    };
+    const auto report = [&](int segmentCount, int itemCount) {
+        // Counts only: the peer's paths and text stay out of the log.
+        LOG_INFO(bsIpc,
+                 "Peer sync with %s: %d segments, %d from the delta, %d fetched, %d failed to "
+                 "fetch, %d indexed, %d unchanged, %d failed, %d removed, %d merged",
+                 qUtf8Printable(peer.name), segmentCount, deltaSegments, fetched,
+                 failedSegments, indexed, unchanged, failed, static_cast<int>(removed), merged);
+        summary[QStringLiteral("segments")] = segmentCount;
+        summary[QStringLiteral("items")] = itemCount;
+        summary[QStringLiteral("deltaSegments")] = deltaSegments;
+        summary[QStringLiteral("fetched")] = fetched;
+        summary[QStringLiteral("failedSegments")] = failedSegments;
+        summary[QStringLiteral("indexed")] = indexed;
+        summary[QStringLiteral("unchanged")] = unchanged;
+        summary[QStringLiteral("failed")] = failed;
+        summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+        summary[QStringLiteral("merged")] = merged;
+        return summary;
+    };
+
+    // 1. The delta since the last complete pass, page by page. A pass in
+    // progress ("pending") resumes at the segment it needs next.
+    QString error;
+    QString freshCursor;  // adopted once the digests below agree
+    const QString cursor = replication.value(QStringLiteral("cursor")).toString();
+    if (cursor.isEmpty()) {
+        // A publisher without deltas fails this; its segments still sync.
+        QString ignored;
+        const auto page = PeerClient::getJson(endpoint, QStringLiteral("/v1/sync/delta"), &ignored);
+        if (page.has_value()) {
+            freshCursor = page->value(QStringLiteral("cursor")).toString();
+        }
+    } else {
+        QString pending = replication.value(QStringLiteral("pending")).toString();
+        qint64 from = pending.isEmpty() ? 0 : replication.value(QStringLiteral("from")).toInteger();
+        while (true) {
+            const auto page = PeerClient::getJson(
+                endpoint,
+                QStringLiteral("/v1/sync/delta?cursor=%1&from=%2").arg(cursor).arg(from), &error);
+            if (!page.has_value()) {
+                // Unreachable; the next sync picks the pass up at `from`.
+                LOG_WARN(bsIpc, "Peer sync with %s skipped: %s", qUtf8Printable(peer.name),
+                         qUtf8Printable(error));
+                summary[QStringLiteral("error")] = error;
+                return report(0, 0);
+            }
+            if (page->value(QStringLiteral("reset")).toBool()) {
+                replication = QJsonObject();
+                freshCursor = page->value(QStringLiteral("cursor")).toString();
+                break;
+            }
+            if (pending.isEmpty()) {
+                pending = page->value(QStringLiteral("cursor")).toString();
+            }
+            for (const QJsonValue& value : page->value(QStringLiteral("segments")).toArray()) {
+                const auto delta = PeerSync::segmentDeltaFromJson(value.toObject());
+                if (!delta.has_value()) {
+                    continue;  // the digest comparison below fetches it whole
+                }
+                QStringList tombstones;
+                for (const int64_t id : delta->tombstones) {
+                    tombstones.append(QString::number(id));
+                }
+                removeReplicas(tombstones);
+                applyItems(delta->items);
+                if (delta->segment.count == 0) {
+                    digests.remove(delta->segment.first);
+                } else {
+                    digests.insert(delta->segment.first, delta->segment.digest);
+                }
+                ++deltaSegments;
+            }
+            if (!page->contains(QStringLiteral("next"))) {
+                replication = QJsonObject{{QStringLiteral("cursor"), pending}};
+                persist();
+                break;
+            }
+            from = page->value(QStringLiteral("next")).toInteger();
+            replication = QJsonObject{{QStringLiteral("cursor"), cursor},
+                                      {QStringLiteral("pending"), pending},
+                                      {QStringLiteral("from"), from}};
+            persist();
+        }
+    }
+
+    // 2. Segment digests: whatever the delta did not bring up to date, or
+    // everything the first time, is downloaded whole.
+    const auto listing = PeerClient::getJson(endpoint, QStringLiteral("/v1/sync/segments"), &error);
+    if (listing.has_value()
+        && listing->value(QStringLiteral("segmentSpan")).toInteger() != PeerSync::kSegmentSpan) {
+        error = QStringLiteral("%1 pages its index differently; update both Macs")
+                    .arg(peer.name);
+    }
+    if (!error.isEmpty()) {
+        // Unreachable (asleep, off the network): what was replicated
+        // stays searchable until the peer answers again.
+        LOG_WARN(bsIpc, "Peer sync with %s skipped: %s", qUtf8Printable(peer.name),
+                 qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        return report(0, 0);
+    }
+    const std::vector<SyncSegment> remote =
+        PeerSync::segmentsFromJson(listing->value(QStringLiteral("segments")).toArray());
+
+    QStringList indexedIdentifiers;
+    for (const SQLiteStore::ItemRow& row :
+         m_store->getItemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexedIdentifiers.append(identifier);
+        }
+    }
+    // Replicas in segments the state does not know (it was lost, or a sync
+    // stopped part way) are dropped unless the peer still has the segment.
+    for (const QString& identifier : indexedIdentifiers) {
+        const int64_t first = PeerSync::segmentFirst(identifier.toLongLong());
+        if (!digests.contains(first)) {
+            digests.insert(first, QString());
+        }
+    }
+
+    for (const int64_t first : PeerSync::changedSegments(digests, remote)) {
+        const auto segment = PeerClient::getJson(
+            endpoint, QStringLiteral("/v1/sync/segments/%1").arg(first), &error);
+        if (!segment.has_value()) {
+            LOG_WARN(bsIpc, "Peer sync with %s: segment %lld: %s", qUtf8Printable(peer.name),
+                     static_cast<long long>(first), qUtf8Printable(error));
+            // Its digest is left as it was, so the next sync asks again.
+            summary[QStringLiteral("error")] = error;
+            ++failedSegments;
+            continue;
+        }
+        ++fetched;
+        std::vector<SyncedItem> items;
+        for (const QJsonValue& value : segment->value(QStringLiteral("items")).toArray()) {
+            const auto item = PeerSync::itemFromJson(value.toObject());
+            if (item.has_value() && PeerSync::segmentFirst(item->id) == first) {
+                items.push_back(item.value());
+            }
+        }
+        applyItems(items);
+        removeReplicas(PeerSync::staleIdentifiers(indexedIdentifiers, first, items));
+        const QString digest = segment->value(QStringLiteral("digest")).toString();
+        if (digest.isEmpty()) {
+            digests.remove(first);
+        } else {
+            digests.insert(first, digest);
+        }
+        persist();
+    }
+    for (const int64_t first : PeerSync::removedSegments(digests, remote)) {
+        removeReplicas(PeerSync::staleIdentifiers(indexedIdentifiers, first, {}));
+        digests.remove(first);
+    }
+    // A cursor only once every segment matched, so no delta starts from a
+    // state this Mac does not have.
+    if (failedSegments == 0 && !freshCursor.isEmpty()) {
+        replication = QJsonObject{{QStringLiteral("cursor"), freshCursor}};
+    }
+    persist();
+
+    int itemCount = 0;
+    for (const SyncSegment& segment : remote) {
+        itemCount += segment.count;
+    }
+    return report(static_cast<int>(remote.size()), itemCount);
+}
+
+QJsonObject IndexerService::handleSyncSshSources(uint64_t id)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    if (m_sshRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("An SSH sync is already running"));
+    }
+    if (!startSshSync()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     m_lastSshSync.value(QStringLiteral("error")).toString());
+    }
+    // Listing and copying can take minutes; the outcome goes out as an
+    // sshSourcesSynced notification.
+    QJsonObject result;
+    result[QStringLiteral("started")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+bool IndexerService::startSshSync()
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load() || m_sshRunning.load()) {
+        return false;
+    }
+    if (m_sshThread.joinable()) {
+        m_sshThread.join();
+    }
+
+    QString error;
+    auto remotes = SshSource::parseRemotes(
+        m_store->getSetting(QString::fromLatin1(SshSource::kSettingKey)).value_or(QString()),
+        &error);
+    if (!remotes.has_value()) {
+        // As for sync_peers: keep what is indexed until the list parses.
+        LOG_WARN(bsIpc, "Ignoring %s: %s", SshSource::kSettingKey, qUtf8Printable(error));
+        QJsonObject summary;
+        summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+        summary[QStringLiteral("error")] = error;
+        m_lastSshSync = summary;
+        return false;
+    }
+
+    const QString stateKey = QString::fromLatin1(SshSource::kStateSettingKey);
+    const QJsonArray previous = QJsonDocument::fromJson(
+        m_store->getSetting(stateKey).value_or(QString()).toUtf8()).array();
+
+    m_sshRunning.store(true);
+    m_sshStopping.store(false);
+    m_sshThread = std::thread([this, sources = std::move(*remotes), previous, stateKey]() {
+        QJsonObject summary;
+        summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+        summary[QStringLiteral("enabled")] = !sources.empty();
+
+        QJsonArray sourceSummaries;
+        QJsonArray synced;
+        QSet<QString> configured;
+        if (!sources.empty()) {
+            // Short and fixed: ssh's control socket path must fit sun_path.
+            QTemporaryDir scratch(QStringLiteral("/tmp/bs-ssh-XXXXXX"));
+            for (size_t i = 0; i < sources.size() && !m_sshStopping.load(); ++i) {
+                const SshRemote& remote = sources[i];
+                configured.insert(remote.name.toLower());
+                synced.append(remote.name.toLower());
+                if (!scratch.isValid()) {
+                    QJsonObject failed;
+                    failed[QStringLiteral("name")] = remote.name;
+                    failed[QStringLiteral("error")] = scratch.errorString();
+                    sourceSummaries.append(failed);
+                    continue;
+                }
+                sourceSummaries.append(syncSshSource(
+                    remote, scratch.filePath(QStringLiteral("c%1").arg(i)), scratch.path()));
+            }
+        }
+        if (m_sshStopping.load()) {
+            return;  // ssh_state stays as it was; the next sync starts over
+        }
+
+        // Sources taken off the list take their files with them.
+        int removedSources = 0;
+        for (const QJsonValue& value : previous) {
+            const QString name = value.toString();
+            if (name.isEmpty() || configured.contains(name)) {
+                continue;
+            }
+            DonationRemoval removal;
+            removal.bundleIdentifier = SshSource::bundleIdentifier(name);
+            removal.all = true;
+            m_pipeline->removeDonations(removal);
+            ++removedSources;
+        }
+        summary[QStringLiteral("sources")] = sourceSummaries;
+        summary[QStringLiteral("removedSources")] = removedSources;
+
+        QMetaObject::invokeMethod(this, [this, summary, synced, stateKey]() {
+            if (m_store.has_value()) {
+                m_store->setSetting(stateKey, QString::fromUtf8(QJsonDocument(synced)
+                                                                    .toJson(QJsonDocument::Compact)));
+            }
+            m_lastSshSync = summary;
+            m_sshRunning.store(false);
+            sendNotification(QStringLiteral("sshSourcesSynced"), summary);
+        }, Qt::QueuedConnection);
+    });
+    return true;
+}
+
+QJsonObject IndexerService::syncSshSource(const SshRemote& remote, const QString& controlPath,
+                                          const QString& scratchDir)
+{
+    const QString bundleIdentifier = SshSource::bundleIdentifier(remote.name);
+    QJsonObject summary;
+    summary[QStringLiteral("name")] = remote.name;
+
+    QString error;
+    const auto listing = SshSource::run(remote, controlPath, SshSource::listCommand(remote.root),
+                                        SshSource::kListTimeoutMs,
+                                        // ~100 bytes a record, with room to spare.
+                                        static_cast<int64_t>(SshSource::kMaxFiles) * 4096,
+                                        &error, &m_sshStopping);
+    if (!listing.has_value()) {
+        // Unreachable or refused: what was indexed stays searchable.
+        LOG_WARN(bsIpc, "SSH source %s skipped: %s", qUtf8Printable(remote.name),
+                 qUtf8Printable(error));
+        summary[QStringLiteral("error")] = error;
+        return summary;
+    }
+    bool truncated = false;
+    const std::vector<RemoteFile> files =
+        SshSource::parseListing(listing.value(), remote.root, &truncated);
+
+    QHash<QString, SQLiteStore::ItemRow> indexed;
+    for (const SQLiteStore::ItemRow& row :
+         m_pipeline->itemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString identifier = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!identifier.isEmpty()) {
+            indexed.insert(identifier, row);
+        }
+    }
+
+    int fetched = 0;
+    int failedFetches = 0;
+    int indexedCount = 0;
+    int unchanged = 0;
+    int failed = 0;
+    QSet<QString> listed;
+    std::vector<DonatedItem> donations;
+    const auto flush = [&]() {
+        for (const IndexResult& outcome : m_pipeline->applyDonations(donations)) {
+            switch (outcome.status) {
+            case IndexResult::Status::Indexed:  ++indexedCount; break;
+            case IndexResult::Status::Skipped:  ++unchanged; break;
+            default:                            ++failed; break;
+            }
+        }
+        donations.clear();
+    };
+    for (const RemoteFile& file : files) {
+        if (m_sshStopping.load()) {
+            return summary;
+        }
+        const QString identifier = SshSource::uniqueIdentifier(remote, file);
+        listed.insert(identifier);
+        const auto existing = indexed.constFind(identifier);
+        if (existing != indexed.constEnd()
+            && SshSource::isUnchanged(file, existing->size, existing->modifiedAt)) {
+            ++unchanged;
+            continue;
+        }
+
+        QString text;
+        if (SshSource::isFetchable(remote, file)) {
+            const auto content = SshSource::run(remote, controlPath,
+                                                SshSource::fetchCommand(file.path),
+                                                SshSource::kFetchTimeoutMs, remote.maxFileBytes,
+                                                &error, &m_sshStopping);
+            if (!content.has_value()) {
+                // Left as indexed, so the next sync tries it again.
+                LOG_WARN(bsIpc, "SSH source %s: a file could not be copied: %s",
+                         qUtf8Printable(remote.name), qUtf8Printable(error));
+                summary[QStringLiteral("error")] = error;
+                ++failedFetches;
+                continue;
+            }
+            ++fetched;
+            // Named like the original so extractors see its extension.
+            const QString copyPath = QDir(scratchDir).filePath(
+                QStringLiteral("f-") + QFileInfo(file.path).fileName());
+            QFile copy(copyPath);
+            if (copy.open(QIODevice::WriteOnly | QIODevice::Truncate)
+                && copy.write(content.value()) == content->size()) {
+                copy.close();
+                const ExtractionResult extracted =
+                    m_extractor->extract(copyPath, SshSource::kindOf(file.path));
+                if (extracted.status == ExtractionResult::Status::Success
+                    && extracted.content.has_value()) {
+                    text = extracted.content.value();
+                }
+            }
+            QFile::remove(copyPath);
+        }
+        donations.push_back(SshSource::toDonatedItem(remote, file, text));
+        if (donations.size() >= static_cast<size_t>(SpotlightDonation::kMaxItemsPerRequest)) {
+            flush();
+        }
+    }
+    flush();
+
+    size_t removed = 0;
+    // A cut-off listing does not say what is gone.
+    if (!truncated) {
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        for (auto it = indexed.constBegin(); it != indexed.constEnd(); ++it) {
+            if (!listed.contains(it.key())) {
+                removal.uniqueIdentifiers.append(it.key());
+            }
+        }
+        if (!removal.uniqueIdentifiers.isEmpty()) {
+            removed = m_pipeline->removeDonations(removal);
+        }
+    }
+
+    // Counts only: remote paths stay out of the log.
+    LOG_INFO(bsIpc,
+             "SSH source %s: %d files%s, %d copied, %d failed to copy, %d indexed, "
+             "%d unchanged, %d failed, %d removed",
+             qUtf8Printable(remote.name), static_cast<int>(files.size()),
+             truncated ? " (truncated)" : "", fetched, failedFetches, indexedCount, unchanged,
+             failed, static_cast<int>(removed));
+    summary[QStringLiteral("files")] = static_cast<qint64>(files.size());
+    summary[QStringLiteral("truncated")] = truncated;
+    summary[QStringLiteral("fetched")] = fetched;
+    summary[QStringLiteral("failedFetches")] = failedFetches;
+    summary[QStringLiteral("indexed")] = indexedCount;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncS3Sources(uint64_t id)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    if (m_s3Running.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("An S3 sync is already running"));
+    }
+    if (!startS3Sync()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     m_lastS3Sync.value(QStringLiteral("error")).toString());
+    }
+    // Listing pages and downloads can take minutes; the outcome goes out
+    // as an s3SourcesSynced notification.
+    QJsonObject result;
+    result[QStringLiteral("started")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+bool IndexerService::startS3Sync()
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load() || m_s3Running.load()) {
+        return false;
+    }
+    if (m_s3Thread.joinable()) {
+        m_s3Thread.join();
+    }
+
+    QString error;
+    auto buckets = S3Source::parseBuckets(
+        m_store->getSetting(QString::fromLatin1(S3Source::kSettingKey)).value_or(QString()),
+        &error);
+    if (!buckets.has_value()) {
+        LOG_WARN(bsIpc, "Ignoring %s: %s", S3Source::kSettingKey, qUtf8Printable(error));
+        QJsonObject summary;
+        summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+        summary[QStringLiteral("error")] = error;
+        m_lastS3Sync = summary;
+        return false;
+    }
+
+    const QString stateKey = QString::fromLatin1(S3Source::kStateSettingKey);
+    const QJsonObject previous = QJsonDocument::fromJson(
+        m_store->getSetting(stateKey).value_or(QString()).toUtf8()).object();
+
+    m_s3Running.store(true);
+    m_s3Stopping.store(false);
+    m_s3Thread = std::thread([this, sources = std::move(*buckets), previous, stateKey]() {
+        QJsonObject summary;
+        summary[QStringLiteral("syncedAtMs")] = QDateTime::currentMSecsSinceEpoch();
+        summary[QStringLiteral("enabled")] = !sources.empty();
+
+        QJsonArray sourceSummaries;
+        QJsonObject state;
+        if (!sources.empty()) {
+            QTemporaryDir scratch;
+            // Read for this sync only; the keys are never stored here.
+            QFile credentialsFile(S3Source::credentialsPath());
+            const QByteArray credentials = credentialsFile.open(QIODevice::ReadOnly)
+                                               ? credentialsFile.readAll()
+                                               : QByteArray();
+            for (S3Bucket bucket : sources) {
+                if (m_s3Stopping.load()) {
+                    break;
+                }
+                const QString name = bucket.name.toLower();
+                QJsonObject entry = previous.value(name).toObject();
+                QString error;
+                if (!scratch.isValid()) {
+                    error = scratch.errorString();
+                } else if (!S3Source::applyCredentials(bucket, credentials, &error)) {
+                    LOG_WARN(bsIpc, "S3 source %s skipped: %s", qUtf8Printable(bucket.name),
+                             qUtf8Printable(error));
+                }
+                if (!error.isEmpty()) {
+                    QJsonObject failed;
+                    failed[QStringLiteral("name")] = bucket.name;
+                    failed[QStringLiteral("error")] = error;
+                    sourceSummaries.append(failed);
+                } else {
+                    sourceSummaries.append(syncS3Bucket(bucket, entry, scratch.path()));
+                }
+                state[name] = entry;
+            }
+        }
+        if (m_s3Stopping.load()) {
+            return;  // s3_state keeps the markers this sync started from
+        }
+
+        // Buckets taken off the list take their objects with them.
+        int removedSources = 0;
+        for (auto it = previous.constBegin(); it != previous.constEnd(); ++it) {
+            if (state.contains(it.key())) {
+                continue;
+            }
+            DonationRemoval removal;
+            removal.bundleIdentifier = S3Source::bundleIdentifier(it.key());
+            removal.all = true;
+            m_pipeline->removeDonations(removal);
+            ++removedSources;
+        }
+        summary[QStringLiteral("sources")] = sourceSummaries;
+        summary[QStringLiteral("removedSources")] = removedSources;
+
+        QMetaObject::invokeMethod(this, [this, summary, state, stateKey]() {
+            if (m_store.has_value()) {
+                m_store->setSetting(stateKey, QString::fromUtf8(QJsonDocument(state)
+                                                                    .toJson(QJsonDocument::Compact)));
+            }
+            m_lastS3Sync = summary;
+            m_s3Running.store(false);
+            sendNotification(QStringLiteral("s3SourcesSynced"), summary);
+        }, Qt::QueuedConnection);
+    });
+    return true;
+}
+
+QJsonObject IndexerService::syncS3Bucket(const S3Bucket& bucket, QJsonObject& state,
+                                         const QString& scratchDir)
+{
+    const QString bundleIdentifier = S3Source::bundleIdentifier(bucket.name);
+    QJsonObject summary;
+    summary[QStringLiteral("name")] = bucket.name;
+
+    // A changed prefix is a different listing: start it over.
+    QString marker = state.value(QStringLiteral("marker")).toString();
+    if (state.value(QStringLiteral("prefix")).toString() != bucket.prefix) {
+        marker.clear();
+    }
+
+    QHash<QString, SQLiteStore::ItemRow> indexed;
+    for (const SQLiteStore::ItemRow& row :
+         m_pipeline->itemsUnderPath(SpotlightDonation::bundlePath(bundleIdentifier))) {
+        const QString key = SpotlightDonation::uniqueIdentifier(row.path);
+        if (!key.isEmpty()) {
+            indexed.insert(key, row);
+        }
+    }
+
+    size_t removed = 0;
+    const auto remove = [&](const QStringList& keys) {
+        if (keys.isEmpty()) {
+            return;
+        }
+        DonationRemoval removal;
+        removal.bundleIdentifier = bundleIdentifier;
+        removal.uniqueIdentifiers = keys;
+        removed += m_pipeline->removeDonations(removal);
+    };
+    QStringList outsidePrefix;
+    QStringList indexedKeys;
+    for (auto it = indexed.constBegin(); it != indexed.constEnd(); ++it) {
+        if (it.key().startsWith(bucket.prefix)) {
+            indexedKeys.append(it.key());
+        } else {
+            outsidePrefix.append(it.key());
+        }
+    }
+    remove(outsidePrefix);
+
+    int listedCount = 0;
+    int pages = 0;
+    int fetched = 0;
+    int failedFetches = 0;
+    int indexedCount = 0;
+    int unchanged = 0;
+    int failed = 0;
+    bool complete = false;
+    QString error;
+    std::vector<DonatedItem> donations;
+    const auto flush = [&]() {
+        for (const IndexResult& outcome : m_pipeline->applyDonations(donations)) {
+            switch (outcome.status) {
+            case IndexResult::Status::Indexed:  ++indexedCount; break;
+            case IndexResult::Status::Skipped:  ++unchanged; break;
+            default:                            ++failed; break;
+            }
+        }
+        donations.clear();
+    };
+    while (pages < S3Source::kMaxPagesPerSync && !m_s3Stopping.load()) {
+        const QUrl url = S3Source::listUrl(bucket, marker);
+        const S3Response page = S3Client::get(
+            url, S3Source::signGet(bucket, url, QDateTime::currentDateTimeUtc()),
+            S3Source::kMaxListingBytes, S3Source::kListTimeoutMs, &m_s3Stopping);
+        std::optional<S3Listing> listing;
+        if (page.error.isEmpty()) {
+            listing = S3Source::parseListing(page.body, &error);
+        } else {
+            const QString message = S3Source::errorMessage(page.body);
+            error = message.isEmpty() ? page.error : page.error + QStringLiteral(": ") + message;
+        }
+        if (!listing.has_value()) {
+            // The marker stays, so the next sync resumes from this page.
+            LOG_WARN(bsIpc, "S3 source %s: listing failed: %s", qUtf8Printable(bucket.name),
+                     qUtf8Printable(error));
+            summary[QStringLiteral("error")] = error;
+            break;
+        }
+        ++pages;
+        listedCount += static_cast<int>(listing->objects.size());
+
+        for (const S3Object& object : listing->objects) {
+            if (m_s3Stopping.load()) {
+                return summary;
+            }
+            const auto existing = indexed.constFind(object.key);
+            if (existing != indexed.constEnd()
+                && S3Source::isUnchanged(object, existing->size, existing->modifiedAt)) {
+                ++unchanged;
+                continue;
+            }
+
+            QString text;
+            if (S3Source::isFetchable(bucket, object)) {
+                const QUrl objectUrl = S3Source::objectUrl(bucket, object.key);
+                const S3Response content = S3Client::get(
+                    objectUrl, S3Source::signGet(bucket, objectUrl, QDateTime::currentDateTimeUtc()),
+                    bucket.maxContentBytes, S3Source::kFetchTimeoutMs, &m_s3Stopping);
+                if (!content.error.isEmpty()) {
+                    // Left as indexed, so the next pass tries it again.
+                    LOG_WARN(bsIpc, "S3 source %s: an object could not be downloaded: %s",
+                             qUtf8Printable(bucket.name), qUtf8Printable(content.error));
+                    summary[QStringLiteral("error")] = content.error;
+                    ++failedFetches;
+                    continue;
+                }
+                ++fetched;
+                // Named like the object so extractors see its extension.
+                const QString copyPath = QDir(scratchDir).filePath(
+                    QStringLiteral("o-") + QFileInfo(object.key).fileName());
+                QFile copy(copyPath);
+                if (copy.open(QIODevice::WriteOnly | QIODevice::Truncate)
+                    && copy.write(content.body) == content.body.size()) {
+                    copy.close();
+                    const ExtractionResult extracted =
+                        m_extractor->extract(copyPath, S3Source::kindOf(object.key));
+                    if (extracted.status == ExtractionResult::Status::Success
+                        && extracted.content.has_value()) {
+                        text = extracted.content.value();
+                    }
+                }
+                QFile::remove(copyPath);
+            }
+            donations.push_back(S3Source::toDonatedItem(bucket, object, text));
+            if (donations.size() >= static_cast<size_t>(SpotlightDonation::kMaxItemsPerRequest)) {
+                flush();
+            }
+        }
+        flush();
+
+        // The page covers the keys after the marker up to its last one
+        // (to the end of the bucket when it is the last page).
+        const bool more = listing->truncated && !listing->lastKey.isEmpty();
+        const QString last = more ? listing->lastKey : QString();
+        remove(S3Source::staleKeys(indexedKeys, marker, last, listing->objects));
+        marker = last;
+        if (!more) {
+            complete = true;
+            break;
+        }
+    }
+    state[QStringLiteral("marker")] = marker;
+    state[QStringLiteral("prefix")] = bucket.prefix;
+
+    // Counts only: keys stay out of the log.
+    LOG_INFO(bsIpc,
+             "S3 source %s: %d objects in %d pages%s, %d downloaded, %d failed to download, "
+             "%d indexed, %d unchanged, %d failed, %d removed",
+             qUtf8Printable(bucket.name), listedCount, pages, complete ? "" : " (continues)",
+             fetched, failedFetches, indexedCount, unchanged, failed, static_cast<int>(removed));
+    summary[QStringLiteral("objects")] = listedCount;
+    summary[QStringLiteral("pages")] = pages;
+    summary[QStringLiteral("complete")] = complete;
+    summary[QStringLiteral("fetched")] = fetched;
+    summary[QStringLiteral("failedFetches")] = failedFetches;
+    summary[QStringLiteral("indexed")] = indexedCount;
+    summary[QStringLiteral("unchanged")] = unchanged;
+    summary[QStringLiteral("failed")] = failed;
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    return summary;
+}
+
+QJsonObject IndexerService::handleSyncNetworkShares(uint64_t id, const QJsonObject& params)
+{
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    if (m_rebuildRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A rebuild is in progress"));
+    }
+    if (m_sharesRunning.load()) {
+        return IpcMessage::makeError(id, IpcErrorCode::AlreadyRunning,
+                                     QStringLiteral("A network share crawl is already running"));
+    }
+    const QString name = params.value(QStringLiteral("name")).toString().trimmed();
+    const QStringList started = startNetworkShareCrawl(true, name);
+    if (started.isEmpty()) {
+        const QString error = m_lastSharesSync.value(QStringLiteral("error")).toString();
+        if (!error.isEmpty()) {
+            return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+        }
+        if (!name.isEmpty()) {
+            return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                         QStringLiteral("No network share named %1 outside "
+                                                        "the indexed folders").arg(name));
+        }
+    }
+    // The crawl is paced and can take a while; the outcome goes out as a
+    // networkSharesSynced notification.
+    QJsonObject result;
+    result[QStringLiteral("started")] = QJsonArray::fromStringList(started);
+    return IpcMessage::makeResponse(id, result);
+}
+
+QStringList IndexerService::startNetworkShareCrawl(bool force, const QString& onlyName)
+{
+    if (!m_pipeline || !m_store.has_value() || m_rebuildRunning.load() || m_sharesRunning.load()) {
+        return {};
+    }
+    if (m_sharesThread.joinable()) {
+        m_sharesThread.join();
+    }
+
+    QString error;
+    auto shares = NetworkShares::parseShares(
+        m_store->getSetting(QString::fromLatin1(NetworkShares::kSettingKey)).value_or(QString()),
+        &error);
+    if (!shares.has_value()) {
+        // As for sync_peers: keep what is indexed until the list parses.
+        LOG_WARN(bsIpc, "Ignoring %s: %s", NetworkShares::kSettingKey, qUtf8Printable(error));
+        m_lastSharesSync = QJsonObject{{QStringLiteral("error"), error}};
+        return {};
+    }
+    m_lastSharesSync.remove(QStringLiteral("error"));
+    const QString stateKey = QString::fromLatin1(NetworkShares::kStateSettingKey);
+    if (m_shareState.isEmpty()) {
+        m_shareState = QJsonDocument::fromJson(
+            m_store->getSetting(stateKey).value_or(QString()).toUtf8()).object();
+    }
+
+    const auto underRoot = [this](const QString& path) {
+        for (const std::string& root : m_currentRoots) {
+            const QString rootPath = QString::fromStdString(root);
+            if (path == rootPath || path.startsWith(rootPath + QLatin1Char('/'))) {
+                return true;
+            }
+        }
+        return false;
+    };
+
+    // Shares taken off the list lose their entries, unless a configured
+    // share or an indexed folder now covers the same files.
+    QStringList purgePaths;
+    QStringList purgedNames;
+    QSet<QString> configured;
+    for (const NetworkShare& share : *shares) {
+        configured.insert(share.name.toLower());
+    }
+    for (auto it = m_shareState.constBegin(); it != m_shareState.constEnd(); ++it) {
+        if (configured.contains(it.key())) {
+            continue;
+        }
+        purgedNames.append(it.key());
+        const QString path = it.value().toObject().value(QStringLiteral("path")).toString();
+        const bool covered = underRoot(path)
+            || std::any_of(shares->begin(), shares->end(), [&path](const NetworkShare& share) {
+                   return path == share.path || path.startsWith(share.path + QLatin1Char('/'))
+                          || share.path.startsWith(path + QLatin1Char('/'));
+               });
+        if (!path.isEmpty() && !covered) {
+            purgePaths.append(path);
+        }
+    }
+
+    const QDateTime now = QDateTime::currentDateTime();
+    std::vector<NetworkShare> due;
+    QJsonArray skipped;
+    for (const NetworkShare& share : *shares) {
+        const QJsonObject entry = m_shareState.value(share.name.toLower()).toObject();
+        if (!onlyName.isEmpty() && share.name.compare(onlyName, Qt::CaseInsensitive) != 0) {
+            continue;
+        }
+        if (underRoot(share.path)) {
+            // The regular crawl and FSEvents already cover it, unpaced.
+            skipped.append(QJsonObject{{QStringLiteral("name"), share.name},
+                                       {QStringLiteral("error"),
+                                        QStringLiteral("The share is inside an indexed folder")}});
+            continue;
+        }
+        if (!force
+            && (entry.value(QStringLiteral("nextAttemptMs")).toInteger() > now.toMSecsSinceEpoch()
+                || !NetworkShares::isDue(share.schedule,
+                                         entry.value(QStringLiteral("lastCompletedMs")).toInteger(),
+                                         now))) {
+            continue;
+        }
+        due.push_back(share);
+    }
+    if (due.empty() && purgedNames.isEmpty()) {
+        return {};
+    }
+
+    QStringList started;
+    for (const NetworkShare& share : due) {
+        started.append(share.name);
+    }
+    m_sharesRunning.store(true);
+    m_sharesStopping.store(false);
+    m_sharesThread = std::thread([this, due, purgePaths, purgedNames, skipped, stateKey]() {
+        QJsonArray summaries = skipped;
+        for (const NetworkShare& share : due) {
+            if (m_sharesStopping.load()) {
+                break;
+            }
+            QJsonObject summary = crawlNetworkShare(share);
+            summary[QStringLiteral("path")] = share.path;
+            summaries.append(summary);
+        }
+        size_t purged = 0;
+        for (const QString& path : purgePaths) {
+            std::vector<WorkItem> deletes;
+            for (const SQLiteStore::ItemRow& row : m_pipeline->itemsUnderPath(path)) {
+                WorkItem item;
+                item.type = WorkItem::Type::Delete;
+                item.filePath = row.path.toStdString();
+                deletes.push_back(std::move(item));
+            }
+            purged += m_pipeline->enqueueChanges(deletes);
+        }
+
+        QMetaObject::invokeMethod(this, [this, summaries, purgedNames, purged, stateKey]() {
+            const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+            for (const QJsonValue& value : summaries) {
+                const QJsonObject summary = value.toObject();
+                const QString status = summary.value(QStringLiteral("status")).toString();
+                if (status != QLatin1String("ok") && status != QLatin1String("offline")) {
+                    continue;  // stopped or skipped: nothing to record
+                }
+                const QString key = summary.value(QStringLiteral("name")).toString().toLower();
+                QJsonObject entry = m_shareState.value(key).toObject();
+                entry[QStringLiteral("path")] = summary.value(QStringLiteral("path"));
+                if (status == QLatin1String("ok")) {
+                    entry[QStringLiteral("lastCompletedMs")] = nowMs;
+                    entry[QStringLiteral("failures")] = 0;
+                    entry[QStringLiteral("nextAttemptMs")] = 0;
+                } else {
+                    const int failures = entry.value(QStringLiteral("failures")).toInt() + 1;
+                    entry[QStringLiteral("failures")] = failures;
+                    entry[QStringLiteral("nextAttemptMs")] =
+                        nowMs + NetworkShares::retryDelayMs(failures);
+                }
+                m_shareState[key] = entry;
+            }
+            for (const QString& name : purgedNames) {
+                m_shareState.remove(name);
+            }
+            if (m_store.has_value()) {
+                m_store->setSetting(stateKey, QString::fromUtf8(QJsonDocument(m_shareState)
+                                                                    .toJson(QJsonDocument::Compact)));
+            }
+            QJsonObject result;
+            result[QStringLiteral("syncedAtMs")] = nowMs;
+            result[QStringLiteral("sources")] = summaries;
+            result[QStringLiteral("removedSources")] = static_cast<int>(purgedNames.size());
+            result[QStringLiteral("removed")] = static_cast<qint64>(purged);
+            m_lastSharesSync = result;
+            m_sharesRunning.store(false);
+            sendNotification(QStringLiteral("networkSharesSynced"), result);
+        }, Qt::QueuedConnection);
+    });
+    LOG_INFO(bsIpc, "Network share crawl started for %d share(s)", static_cast<int>(due.size()));
+    return started;
+}
+
+QJsonObject IndexerService::crawlNetworkShare(const NetworkShare& share)
+{
+    QJsonObject summary;
+    summary[QStringLiteral("name")] = share.name;
+    QElapsedTimer clock;
+    clock.start();
+
+    if (!NetworkShares::isMounted(share.path)) {
+        // Not mounted, or the server is gone: everything indexed stays.
+        LOG_INFO(bsIpc, "Network share %s is offline", qUtf8Printable(share.name));
+        summary[QStringLiteral("status")] = QStringLiteral("offline");
+        return summary;
+    }
+
+    ShareThrottle throttle(share.maxOpsPerSecond, share.maxBytesPerSecond);
+    // Waits out the throttle in short steps, so a shutdown is not held up.
+    const auto pace = [this, &throttle](int ops, int64_t bytes) {
+        int64_t waitMs = throttle.reserve(ops, bytes, QDateTime::currentMSecsSinceEpoch());
+        while (waitMs > 0 && !m_sharesStopping.load()) {
+            const int64_t step = std::min<int64_t>(waitMs, 100);
+            std::this_thread::sleep_for(std::chrono::milliseconds(step));
+            waitMs -= step;
+        }
+        return !m_sharesStopping.load();
+    };
+
+    bool offline = false;
+    int visits = 0;
+    const auto visit = [&]() {
+        if (++visits % kShareMountCheckInterval == 0 && !NetworkShares::isMounted(share.path)) {
+            offline = true;
+            return false;
+        }
+        return pace(1, 0);
+    };
+    FileScanner scanner(&m_pathRules);
+    bool complete = false;
+    const std::vector<FileMetadata> files =
+        scanner.scanDirectory(share.path.toStdString(), visit, &complete);
+    if (m_sharesStopping.load()) {
+        summary[QStringLiteral("status")] = QStringLiteral("stopped");
+        return summary;
+    }
+    // A listing cut short says nothing about what was deleted.
+    if (offline || !complete || !NetworkShares::isMounted(share.path)) {
+        LOG_WARN(bsIpc, "Network share %s went offline during the crawl",
+                 qUtf8Printable(share.name));
+        summary[QStringLiteral("status")] = QStringLiteral("offline");
+        return summary;
+    }
+
+    const ShareChanges changes =
+        NetworkShares::diff(files, m_pipeline->itemsUnderPath(share.path));
+    size_t queued = 0;
+    size_t removed = 0;
+    for (size_t i = 0; i < changes.changed.size(); ++i) {
+        const FileMetadata& meta = changes.changed[i];
+        // The pipeline reads the file next; its bytes are booked first.
+        if (!pace(1, static_cast<int64_t>(meta.fileSize))) {
+            break;
+        }
+        if ((i + 1) % kShareMountCheckInterval == 0 && !NetworkShares::isMounted(share.path)) {
+            offline = true;
+            break;
+        }
+        WorkItem item;
+        item.type = WorkItem::Type::ModifiedContent;
+        item.filePath = meta.filePath;
+        item.knownSize = meta.fileSize;
+        queued += m_pipeline->enqueueChanges({item});
+    }
+    if (!offline && !m_sharesStopping.load()) {
+        std::vector<WorkItem> deletes;
+        for (const std::string& path : changes.removed) {
+            WorkItem item;
+            item.type = WorkItem::Type::Delete;
+            item.filePath = path;
+            deletes.push_back(std::move(item));
+        }
+        removed = m_pipeline->enqueueChanges(deletes);
+    }
+
+    // Counts only: share paths stay out of the log.
+    LOG_INFO(bsIpc, "Network share %s: %d files, %d queued, %d unchanged, %d removed in %lld ms%s",
+             qUtf8Printable(share.name), static_cast<int>(files.size()), static_cast<int>(queued),
+             static_cast<int>(changes.unchanged), static_cast<int>(removed),
+             static_cast<long long>(clock.elapsed()), offline ? " (went offline)" : "");
+    summary[QStringLiteral("status")] = offline ? QStringLiteral("offline")
+                                        : m_sharesStopping.load() ? QStringLiteral("stopped")
+                                                                  : QStringLiteral("ok");
+    summary[QStringLiteral("files")] = static_cast<qint64>(files.size());
+    summary[QStringLiteral("queued")] = static_cast<qint64>(queued);
+    summary[QStringLiteral("unchanged")] = static_cast<qint64>(changes.unchanged);
+    summary[QStringLiteral("removed")] = static_cast<qint64>(removed);
+    summary[QStringLiteral("elapsedMs")] = clock.elapsed();
+    return summary;
+}
+
+void IndexerService::stopSshSync()
+{
+    m_sshStopping.store(true);
+    if (m_sshThread.joinable()) {
+        m_sshThread.join();
+    }
+    // The thread's queued update will not run on a stopping service.
+    m_sshRunning.store(false);
+}
+
+void IndexerService::stopS3Sync()
+{
+    m_s3Stopping.store(true);
+    if (m_s3Thread.joinable()) {
+        m_s3Thread.join();
+    }
+    // The thread's queued update will not run on a stopping service.
+    m_s3Running.store(false);
+}
+
+void IndexerService::stopNetworkShareCrawl()
+{
+    m_sharesStopping.store(true);
+    if (m_sharesThread.joinable()) {
+        m_sharesThread.join();
+    }
+    // The thread's queued update will not run on a stopping service.
+    m_sharesRunning.store(false);
+}
+
+QJsonObject IndexerService::handleAddPrivacyExclusion(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const std::optional<QString> path = absolutePathParam(params, &error);
+    if (!path.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+
+    // Exclude first, so nothing under the folder is written again while
+    // (or after) it is purged.
+    QStringList folders = privacyExclusions();
+    if (!folders.contains(path.value())) {
+        folders.append(path.value());
+        m_pipeline->setPrivacyExclusions(folders);
+    }
+    const PrivacyPurgeSummary summary = m_pipeline->purgePrivacyPath(path.value());
+    LOG_INFO(bsIpc, "Privacy exclusion added: %s (%d item(s) purged)",
+             qUtf8Printable(path.value()), static_cast<int>(summary.deletedItems));
+
+    QJsonObject result;
+    result[QStringLiteral("path")] = path.value();
+    result[QStringLiteral("exclusions")] = QJsonArray::fromStringList(folders);
+    result[QStringLiteral("deletedItems")] = static_cast<qint64>(summary.deletedItems);
+    result[QStringLiteral("remaining")] = footprintJson(summary.remaining);
+    result[QStringLiteral("walCheckpointed")] = summary.walCheckpointed;
+    result[QStringLiteral("verified")] = summary.remaining.empty();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::handleRemovePrivacyExclusion(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const std::optional<QString> path = absolutePathParam(params, &error);
+    if (!path.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+
+    // The folder is indexed again by the next scan or reindexPath of it.
+    QStringList folders = privacyExclusions();
+    const bool removed = folders.removeAll(path.value()) > 0;
+    if (removed) {
+        m_pipeline->setPrivacyExclusions(folders);
+        LOG_INFO(bsIpc, "Privacy exclusion removed: %s", qUtf8Printable(path.value()));
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("path")] = path.value();
+    result[QStringLiteral("removed")] = removed;
+    result[QStringLiteral("exclusions")] = QJsonArray::fromStringList(folders);
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::handlePurgePath(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const std::optional<QString> path = absolutePathParam(params, &error);
+    if (!path.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!m_pipeline) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    // The next scan would index it again, undoing the purge.
+    if (QFileInfo::exists(path.value())
+        && m_pathRules.validate(path->toStdString()) != ValidationResult::Exclude) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("%1 still exists and is not excluded; delete or exclude it first")
+                .arg(path.value()));
+    }
+
+    // Whatever is left under the path goes with secure delete on; the
+    // compaction then clears earlier, ordinary deletes of it too.
+    const PrivacyPurgeSummary purge = m_pipeline->purgePrivacyPath(path.value());
+    const SQLiteStore::CompactionSummary compaction = m_pipeline->compactIndex();
+    if (compaction.complete()) {
+        m_compactedAtMs.store(QDateTime::currentMSecsSinceEpoch());
+    }
+    LOG_INFO(bsIpc, "Purged %s: %d item(s), compaction complete=%d",
+             qUtf8Printable(path.value()), static_cast<int>(purge.deletedItems),
+             compaction.complete() ? 1 : 0);
+
+    QJsonObject result = purgeStatusJson(m_store.value(), path.value());
+    result[QStringLiteral("deletedItems")] = static_cast<qint64>(purge.deletedItems);
+    result[QStringLiteral("compaction")] = compactionJson(compaction);
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::handleVerifyPurged(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const std::optional<QString> path = absolutePathParam(params, &error);
+    if (!path.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!m_store.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Index is not available"));
+    }
+    return IpcMessage::makeResponse(id, purgeStatusJson(m_store.value(), path.value()));
+}
+
+QJsonObject IndexerService::handleGetQueueStatus(uint64_t id)
+{
+    QJsonArray roots;
+    for (const std::string& root : m_currentRoots) {
+        roots.append(QString::fromStdString(root));
+    }
+
+    if (!m_pipeline) {
+        // Return zeroed stats when pipeline is not yet created
+        QJsonObject lastProgress;
+        lastProgress[QStringLiteral("scanned")] = 0;
+        lastProgress[QStringLiteral("total")] = 0;
+
+        QJsonObject result;
+        result[QStringLiteral("pending")] = 0;
+        result[QStringLiteral("processing")] = 0;
+        result[QStringLiteral("failed")] = 0;
+        result[QStringLiteral("dropped")] = 0;
+        result[QStringLiteral("paused")] = false;
+        result[QStringLiteral("roots")] = roots;
+        result[QStringLiteral("lastProgressReport")] = lastProgress;
+        result[QStringLiteral("rebuildRunning")] = m_rebuildRunning.load();
+        result[QStringLiteral("rebuildStatus")] = m_rebuildRunning.load()
+            ? QStringLiteral("running")
+            : QStringLiteral("idle");
+        result[QStringLiteral("rebuildStartedAtMs")] = m_rebuildStartedAtMs.load();
+        result[QStringLiteral("rebuildFinishedAtMs")] = m_rebuildFinishedAtMs.load();
+        result[QStringLiteral("compactionRunning")] = m_compactionRunning.load();
+        result[QStringLiteral("compactedAtMs")] = m_compactedAtMs.load();
+        const QJsonObject bsignore = bsignoreStatusJson();
+        result[QStringLiteral("bsignorePath")] = bsignore.value(QStringLiteral("path")).toString();
+        result[QStringLiteral("bsignoreFileExists")] =
+            bsignore.value(QStringLiteral("fileExists")).toBool(false);
+        result[QStringLiteral("bsignoreLoaded")] = bsignore.value(QStringLiteral("loaded")).toBool(false);
+        result[QStringLiteral("bsignorePatternCount")] =
+            bsignore.value(QStringLiteral("patternCount")).toInt(0);
+        result[QStringLiteral("bsignoreLastLoadedAtMs")] =
+            bsignore.value(QStringLiteral("lastLoadedAtMs")).toInteger();
+        result[QStringLiteral("memory")] = memoryTelemetry();
+        result[QStringLiteral("actorMode")] = QStringLiteral("legacy");
+        result[QStringLiteral("bulkhead")] = QJsonObject();
+        result[QStringLiteral("extractors")] = extractorStatsJson(m_extractor.get());
+        result[QStringLiteral("access")] = fileAccessJson();
+        result[QStringLiteral("resources")] = resourceUsageJson();
+        return IpcMessage::makeResponse(id, result);
+    }
+
+    QueueStats stats = m_pipeline->queueStatus();
+    int processed = m_pipeline->processedCount();
+    const bool active = stats.depth > 0
+        || stats.activeItems > 0
+        || stats.preparing > 0
+        || stats.writing > 0;
+
+    if (m_lastQueueActive && !active) {
+        m_store->setSetting(QStringLiteral("last_full_index_at"),
+                            QString::number(QDateTime::currentSecsSinceEpoch()));
+    }
+    m_lastQueueActive = active;
+
+    if (m_rebuildRunning.load() && m_rebuildAwaitingDrain.load() && !active) {
+        bool expected = true;
+        if (m_rebuildAwaitingDrain.compare_exchange_strong(expected, false)) {
+            const qint64 finishedAtMs = QDateTime::currentMSecsSinceEpoch();
+            m_rebuildFinishedAtMs.store(finishedAtMs);
+            m_rebuildRunning.store(false);
+
+            QJsonObject params;
+            params[QStringLiteral("startedAtMs")] = m_rebuildStartedAtMs.load();
+            params[QStringLiteral("finishedAtMs")] = finishedAtMs;
+            params[QStringLiteral("status")] = QStringLiteral("succeeded");
+            sendNotification(QStringLiteral("rebuildAllComplete"), params);
+        }
+    }
+
+    QJsonObject lastProgress;
+    lastProgress[QStringLiteral("scanned")] = processed;
+    lastProgress[QStringLiteral("total")] = processed + static_cast<int>(stats.depth);
+
+    QJsonObject result;
+    result[QStringLiteral("pending")] = static_cast<qint64>(stats.depth);
+    result[QStringLiteral("processing")] = static_cast<qint64>(stats.activeItems);
+    result[QStringLiteral("failed")] = static_cast<qint64>(stats.failedItems);
+    result[QStringLiteral("dropped")] = static_cast<qint64>(stats.droppedItems);
+    result[QStringLiteral("paused")] = stats.isPaused;
+    result[QStringLiteral("preparing")] = static_cast<qint64>(stats.preparing);
+    result[QStringLiteral("writing")] = static_cast<qint64>(stats.writing);
+    result[QStringLiteral("coalesced")] = static_cast<qint64>(stats.coalesced);
+    result[QStringLiteral("staleDropped")] = static_cast<qint64>(stats.staleDropped);
+    result[QStringLiteral("prepWorkers")] = static_cast<qint64>(stats.prepWorkers);
+    result[QStringLiteral("writerBatchDepth")] = static_cast<qint64>(stats.writerBatchDepth);
+    result[QStringLiteral("roots")] = roots;
+    result[QStringLiteral("lastProgressReport")] = lastProgress;
+    result[QStringLiteral("rebuildRunning")] = m_rebuildRunning.load();
+    result[QStringLiteral("rebuildStatus")] = m_rebuildRunning.load()
+        ? QStringLiteral("running")
+        : QStringLiteral("idle");
+    result[QStringLiteral("rebuildStartedAtMs")] = m_rebuildStartedAtMs.load();
+    result[QStringLiteral("rebuildFinishedAtMs")] = m_rebuildFinishedAtMs.load();
+    result[QStringLiteral("compactionRunning")] = m_compactionRunning.load();
+    result[QStringLiteral("compactedAtMs")] = m_compactedAtMs.load();
+    const QJsonObject bsignore = bsignoreStatusJson();
+    result[QStringLiteral("bsignorePath")] = bsignore.value(QStringLiteral("path")).toString();
+    result[QStringLiteral("bsignoreFileExists")] =
+        bsignore.value(QStringLiteral("fileExists")).toBool(false);
+    result[QStringLiteral("bsignoreLoaded")] = bsignore.value(QStringLiteral("loaded")).toBool(false);
+    result[QStringLiteral("bsignorePatternCount")] =
+        bsignore.value(QStringLiteral("patternCount")).toInt(0);
+    result[QStringLiteral("bsignoreLastLoadedAtMs")] =
+        bsignore.value(QStringLiteral("lastLoadedAtMs")).toInteger();
+    result[QStringLiteral("memory")] = memoryTelemetry();
+    const QJsonObject telemetry = m_pipeline->telemetrySnapshot();
+    result[QStringLiteral("actorMode")] =
+        telemetry.value(QStringLiteral("actorMode")).toString(QStringLiteral("legacy"));
+    result[QStringLiteral("bulkhead")] = telemetry;
+    result[QStringLiteral("extractors")] = extractorStatsJson(m_extractor.get());
+    result[QStringLiteral("access")] = fileAccessJson();
+    result[QStringLiteral("resources")] = resourceUsageJson();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::handleGetIndexingProgress(uint64_t id)
+{
+    QJsonObject result;
+    result[QStringLiteral("indexedItems")] =
+        m_store ? static_cast<qint64>(m_store->itemCount()) : 0;
+
+    if (!m_pipeline || !m_isIndexing) {
+        result[QStringLiteral("state")] = QStringLiteral("stopped");
+        result[QStringLiteral("pending")] = 0;
+        result[QStringLiteral("processing")] = 0;
+        result[QStringLiteral("roots")] = QJsonArray();
+        result[QStringLiteral("etaSeconds")] = QJsonValue(QJsonValue::Null);
+        result[QStringLiteral("extractors")] = extractorStatsJson(m_extractor.get());
+        result[QStringLiteral("access")] = fileAccessJson();
+        result[QStringLiteral("resources")] = resourceUsageJson();
+        return IpcMessage::makeResponse(id, result);
+    }
+
+    const QueueStats stats = m_pipeline->queueStatus();
+    const IndexingProgress progress = m_pipeline->progressSnapshot();
+
+    QString state;
+    if (stats.isPaused) {
+        state = QStringLiteral("paused");
+    } else if (!progress.crawlComplete) {
+        state = m_rebuildRunning.load() ? QStringLiteral("rebuilding")
+                                        : QStringLiteral("initial_crawl");
+    } else if (stats.depth > 0 || stats.activeItems > 0) {
+        state = QStringLiteral("incremental");
+    } else {
+        state = QStringLiteral("idle");
+    }
+
+    const QJsonObject progressJson = progress.toJson();
+    for (auto it = progressJson.begin(); it != progressJson.end(); ++it) {
+        result[it.key()] = it.value();
+    }
+    result[QStringLiteral("state")] = state;
+    result[QStringLiteral("pending")] = static_cast<qint64>(stats.depth);
+    result[QStringLiteral("processing")] = static_cast<qint64>(stats.activeItems);
+    result[QStringLiteral("failed")] = static_cast<qint64>(stats.failedItems);
+    result[QStringLiteral("extractors")] = extractorStatsJson(m_extractor.get());
+    result[QStringLiteral("access")] = fileAccessJson();
+    result[QStringLiteral("resources")] = resourceUsageJson();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::handleGetIndexingEvents(uint64_t id, const QJsonObject& params)
+{
+    const QJsonValue since = params.value(QStringLiteral("since"));
+    if (!since.isUndefined() && (!since.isDouble() || since.toInteger(-1) < 0)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'since' must be a non-negative integer"));
+    }
+    const int limit = std::clamp(
+        params.value(QStringLiteral("limit")).toInt(kDefaultIndexingEventsLimit), 1,
+        static_cast<int>(IndexingEventLog::kCapacity));
+
+    const IndexingEventLog::Page page = m_indexingEvents.since(since.toInteger(0), limit);
+    QJsonObject result;
+    result[QStringLiteral("events")] = page.events;
+    result[QStringLiteral("lastSeq")] = static_cast<qint64>(page.lastSeq);
+    result[QStringLiteral("currentSeq")] = static_cast<qint64>(m_indexingEvents.lastSeq());
+    result[QStringLiteral("truncated")] = page.truncated;
+    if (m_pipeline && m_isIndexing) {
+        const IndexingProgress progress = m_pipeline->progressSnapshot();
+        // Whoever saw this unfinished crawl gets its final progress event.
+        m_crawlProgressOpen = m_crawlProgressOpen || !progress.crawlComplete;
+        result[QStringLiteral("progress")] = progress.toJson();
+    } else {
+        result[QStringLiteral("progress")] = QJsonValue(QJsonValue::Null);
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+void IndexerService::publishIndexingEvent(const QString& event, const QJsonObject& fields)
+{
+    sendNotification(QStringLiteral("indexingEvent"),
+                     m_indexingEvents.append(event, fields,
+                                             QDateTime::currentMSecsSinceEpoch()));
+}
+
+// Pipeline signals arrive queued from its threads, so events are appended
+// here in the order the milestones happened.
+void IndexerService::connectIndexingEvents()
+{
+    Pipeline* pipeline = m_pipeline.get();
+    connect(pipeline, &Pipeline::rootScanStarted, this, [this](const QString& root) {
+        m_crawlProgressOpen = true;
+        publishIndexingEvent(QStringLiteral("rootStarted"), {{QStringLiteral("root"), root}});
+    });
+    connect(pipeline, &Pipeline::rootScanned, this, [this](const QString& root, qint64 files) {
+        publishIndexingEvent(QStringLiteral("rootScanned"),
+                             {{QStringLiteral("root"), root}, {QStringLiteral("files"), files}});
+    });
+    connect(pipeline, &Pipeline::rootCompleted, this, [this](const QString& root, qint64 files) {
+        publishIndexingEvent(QStringLiteral("rootCompleted"),
+                             {{QStringLiteral("root"), root}, {QStringLiteral("files"), files}});
+    });
+    connect(pipeline, &Pipeline::fileFailed, this,
+            [this](const QString& path, const QString& stage, const QString& error) {
+        publishIndexingEvent(QStringLiteral("fileFailed"),
+                             {{QStringLiteral("path"), path},
+                              {QStringLiteral("stage"), stage},
+                              {QStringLiteral("error"), error}});
+    });
+    connect(pipeline, &Pipeline::progressUpdated, this, [this](int, int) {
+        if (!m_pipeline) {
+            return;
+        }
+        const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+        const IndexingProgress progress = m_pipeline->progressSnapshot();
+        // Only a crawl has a total to draw a bar against: after it, one
+        // final event with crawlComplete and then silence until the next.
+        const bool skip = progress.crawlComplete
+            ? !m_crawlProgressOpen
+            : m_crawlProgressOpen && nowMs - m_lastProgressEventMs < kProgressEventIntervalMs;
+        if (skip) {
+            return;
+        }
+        m_crawlProgressOpen = !progress.crawlComplete;
+        m_lastProgressEventMs = nowMs;
+        QJsonObject fields;
+        fields[QStringLiteral("processed")] = static_cast<qint64>(progress.processed);
+        fields[QStringLiteral("discovered")] = static_cast<qint64>(progress.discovered);
+        // Coalesced duplicates can leave processed short of discovered.
+        fields[QStringLiteral("percent")] = progress.crawlComplete ? 100.0
+            : progress.discovered > 0
+                ? std::round(1000.0 * static_cast<double>(progress.processed)
+                             / static_cast<double>(progress.discovered)) / 10.0
+                : 0.0;
+        fields[QStringLiteral("crawlComplete")] = progress.crawlComplete;
+        fields[QStringLiteral("itemsPerSec")] = std::round(progress.itemsPerSecond * 10.0) / 10.0;
+        fields[QStringLiteral("etaSeconds")] = progress.etaSeconds.has_value()
+            ? QJsonValue(static_cast<qint64>(*progress.etaSeconds))
+            : QJsonValue(QJsonValue::Null);
+        publishIndexingEvent(QStringLiteral("progress"), fields);
+    });
+}
+
+QJsonObject IndexerService::handleGetDiagnostics(uint64_t id)
+{
+    QJsonArray roots;
+    for (const std::string& root : m_currentRoots) {
+        roots.append(QString::fromStdString(root));
+    }
+
+    QJsonObject watcher;
+    if (m_pipeline) {
+        const WatcherStatus status = m_pipeline->watcherStatus();
+        watcher[QStringLiteral("running")] = status.running;
+        watcher[QStringLiteral("errorCount")] = static_cast<qint64>(status.errorCount);
+        watcher[QStringLiteral("lastError")] = status.lastError;
+        watcher[QStringLiteral("lastErrorAtMs")] = status.lastErrorAtMs;
+    } else {
+        watcher[QStringLiteral("running")] = false;
+        watcher[QStringLiteral("errorCount")] = 0;
+    }
+
+    // FDA is granted per process, so only the indexer can answer for itself.
+    QJsonObject result;
+    result[QStringLiteral("fullDiskAccess")] = FdaCheck::hasFullDiskAccess();
+    result[QStringLiteral("access")] = fileAccessJson();
+    result[QStringLiteral("indexing")] = m_isIndexing;
+    result[QStringLiteral("roots")] = roots;
+    result[QStringLiteral("watcher")] = watcher;
+    result[QStringLiteral("privacyExclusions")] = QJsonArray::fromStringList(privacyExclusions());
+    result[QStringLiteral("contentTypeRules")] = m_contentTypeRules;
+    if (!m_lastContactsSync.isEmpty()) {
+        result[QStringLiteral("contacts")] = m_lastContactsSync;
+    }
+    if (!m_lastCalendarSync.isEmpty()) {
+        result[QStringLiteral("calendar")] = m_lastCalendarSync;
+    }
+    if (!m_lastBrowserSync.isEmpty()) {
+        result[QStringLiteral("browsers")] = m_lastBrowserSync;
+    }
+    if (!m_lastMailSync.isEmpty()) {
+        result[QStringLiteral("mail")] = m_lastMailSync;
+    }
+    if (!m_lastNotesSync.isEmpty()) {
+        result[QStringLiteral("notes")] = m_lastNotesSync;
+    }
+    if (!m_lastMessagesSync.isEmpty()) {
+        result[QStringLiteral("messages")] = m_lastMessagesSync;
+    }
+    if (!m_lastRemindersSync.isEmpty()) {
+        result[QStringLiteral("reminders")] = m_lastRemindersSync;
+    }
+    if (!m_lastPeersSync.isEmpty()) {
+        result[QStringLiteral("peers")] = m_lastPeersSync;
+    }
+    if (!m_lastSshSync.isEmpty() || m_sshRunning.load()) {
+        QJsonObject ssh = m_lastSshSync;
+        ssh[QStringLiteral("running")] = m_sshRunning.load();
+        result[QStringLiteral("ssh")] = ssh;
+    }
+    if (!m_lastS3Sync.isEmpty() || m_s3Running.load()) {
+        QJsonObject s3 = m_lastS3Sync;
+        s3[QStringLiteral("running")] = m_s3Running.load();
+        result[QStringLiteral("s3")] = s3;
+    }
+    if (!m_lastSharesSync.isEmpty() || !m_shareState.isEmpty()) {
+        QJsonObject shares = m_lastSharesSync;
+        shares[QStringLiteral("running")] = m_sharesRunning.load();
+        shares[QStringLiteral("state")] = m_shareState;
+        result[QStringLiteral("networkShares")] = shares;
+    }
+    if (m_extractor) {
+        result[QStringLiteral("secretRedaction")] =
+            SecretRedactor::modeToString(m_extractor->secretRedactor().mode());
+        QJsonObject sandbox;
+        sandbox[QStringLiteral("enabled")] = m_extractor->isSandboxed();
+        sandbox[QStringLiteral("helperCrashes")] = m_extractor->helperCrashCount();
+        result[QStringLiteral("extractionSandbox")] = sandbox;
+    }
+    result[QStringLiteral("pid")] = QCoreApplication::applicationPid();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject IndexerService::fileAccessJson() const
+{
+    QStringList roots;
+    for (const std::string& root : m_currentRoots) {
+        roots.append(QString::fromStdString(root));
+    }
+    return FdaCheck::probeRoots(roots, QDir::homePath()).toJson();
+}
+
+void IndexerService::joinRebuildThreadIfNeeded()
+{
+    if (m_rebuildThread.joinable()) {
+        if (m_rebuildThread.get_id() == std::this_thread::get_id()) {
+            return;
+        }
+        m_rebuildThread.join();
+    }
+}
+
+void IndexerService::joinReindexThreadIfNeeded()
+{
+    if (m_reindexThread.joinable()) {
+        m_reindexThread.join();
+    }
+}
+
+void IndexerService::joinCompactionThreadIfNeeded()
+{
+    if (m_compactionThread.joinable()) {
+        m_compactionThread.join();
+    }
+}
+
+void IndexerService::maybeCompactIndex()
+{
+    if (!m_pipeline || !m_isIndexing || m_compactionRunning.load() || m_rebuildRunning.load()
+        || m_reindexRunning.load()) {
+        return;
+    }
+    const QueueStats stats = m_pipeline->queueStatus();
+    if (stats.isPaused || stats.depth > 0 || stats.activeItems > 0 || stats.preparing > 0
+        || stats.writing > 0) {
+        return;
+    }
+    if (m_store->pendingCompactionDeletes() == 0) {
+        return;
+    }
+    const qint64 lastVacuumAt = static_cast<qint64>(
+        m_store->getSetting(QStringLiteral("last_vacuum_at")).value_or(QString()).toDouble());
+    if (QDateTime::currentSecsSinceEpoch() - lastVacuumAt < kCompactionMinIntervalSecs) {
+        return;
+    }
+
+    // VACUUM rewrites the whole file; keep the service answering meanwhile.
+    joinCompactionThreadIfNeeded();
+    m_compactionRunning.store(true);
+    m_compactionThread = std::thread([this]() {
+        const SQLiteStore::CompactionSummary summary = m_pipeline->compactIndex();
+        if (summary.complete()) {
+            m_compactedAtMs.store(QDateTime::currentMSecsSinceEpoch());
+        }
+        m_compactionRunning.store(false);
+        QMetaObject::invokeMethod(this, [this, params = compactionJson(summary)]() {
+            sendNotification(QStringLiteral("indexCompacted"), params);
+        }, Qt::QueuedConnection);
+    });
+}
+
+void IndexerService::configureBsignoreWatcher()
+{
+    if (m_bsignorePath.isEmpty()) {
+        return;
+    }
+
+    if (!m_bsignoreWatcher) {
+        m_bsignoreWatcher = std::make_unique<QFileSystemWatcher>(this);
+        connect(m_bsignoreWatcher.get(), &QFileSystemWatcher::fileChanged,
+                this, &IndexerService::onBsignorePathChanged);
+        connect(m_bsignoreWatcher.get(), &QFileSystemWatcher::directoryChanged,
+                this, &IndexerService::onBsignoreDirectoryChanged);
+    }
+
+    if (!m_bsignoreWatcher->files().isEmpty()) {
+        m_bsignoreWatcher->removePaths(m_bsignoreWatcher->files());
+    }
+    if (!m_bsignoreWatcher->directories().isEmpty()) {
+        m_bsignoreWatcher->removePaths(m_bsignoreWatcher->directories());
+    }
+
+    const QFileInfo info(m_bsignorePath);
+    const QString parentDir = info.absoluteDir().absolutePath();
+    if (QFileInfo::exists(parentDir)) {
+        m_bsignoreWatcher->addPath(parentDir);
+    }
+    if (info.exists()) {
+        m_bsignoreWatcher->addPath(m_bsignorePath);
+    }
+}
+
+void IndexerService::reloadBsignore()
+{
+    if (m_bsignorePath.isEmpty()) {
+        return;
+    }
+
+    m_bsignoreLoaded = m_pathRules.loadBsignore(m_bsignorePath.toStdString());
+    m_bsignorePatternCount = static_cast<int>(m_pathRules.bsignorePatternCount());
+    m_bsignoreLastLoadedAtMs = m_pathRules.bsignoreLastLoadedAtMs();
+    configureBsignoreWatcher();
+
+    QJsonObject params = bsignoreStatusJson();
+    params[QStringLiteral("timestamp")] = static_cast<qint64>(QDateTime::currentMSecsSinceEpoch());
+    sendNotification(QStringLiteral("bsignoreReloaded"), params);
+}
+
+QStringList IndexerService::loadPrivacyExclusions(const QJsonObject& startParams) const
+{
+    QJsonArray folders = startParams.value(QStringLiteral("privacyExclusions")).toArray();
+    if (!startParams.contains(QStringLiteral("privacyExclusions")) && m_store.has_value()) {
+        const auto persisted =
+            m_store->getSetting(QString::fromLatin1(Pipeline::kPrivacyExclusionsSetting));
+        if (persisted.has_value()) {
+            folders = QJsonDocument::fromJson(persisted->toUtf8()).array();
+        }
+    }
+
+    QStringList out;
+    for (const QJsonValue& value : folders) {
+        const QString folder = value.toString();
+        if (!QDir::isAbsolutePath(folder)) {
+            LOG_WARN(bsIpc, "Ignoring relative privacy exclusion: %s", qUtf8Printable(folder));
+            continue;
+        }
+        const QString cleaned = QDir::cleanPath(folder);
+        if (!out.contains(cleaned)) {
+            out.append(cleaned);
+        }
+    }
+    return out;
+}
+
+SecretRedactor IndexerService::loadSecretRedactor()
+{
+    SecretRedactor::Mode mode = SecretRedactor::Mode::Hash;
+    if (auto modeStr = m_store->getSetting(QStringLiteral("secret_redaction"))) {
+        const auto parsed = SecretRedactor::modeFromString(modeStr.value());
+        if (parsed.has_value()) {
+            mode = parsed.value();
+        } else {
+            LOG_WARN(bsIpc, "Unknown secret_redaction '%s', hashing secrets",
+                     qUtf8Printable(modeStr.value()));
+        }
+    }
+
+    // Hash tokens stay comparable across files and runs only under one key,
+    // so it is created once and kept with the index.
+    QByteArray key;
+    if (auto keyHex = m_store->getSetting(QStringLiteral("secret_hash_key"))) {
+        key = QByteArray::fromHex(keyHex->toLatin1());
+    }
+    if (key.size() < kSecretHashKeyBytes) {
+        key.resize(kSecretHashKeyBytes);
+        QRandomGenerator::system()->generate(key.begin(), key.end());
+        m_store->setSetting(QStringLiteral("secret_hash_key"), QString::fromLatin1(key.toHex()));
+    }
+    return SecretRedactor(mode, key);
+}
+
+QStringList IndexerService::privacyExclusions() const
+{
+    QStringList out;
+    for (const std::string& folder : m_pathRules.privacyExclusions()) {
+        out.append(QString::fromStdString(folder));
+    }
+    return out;
 }
 
 QJsonObject IndexerService::bsignoreStatusJson() const
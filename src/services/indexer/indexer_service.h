@@ -1,12 +1,20 @@
 #pragma once
 
 #include "core/ipc/service_base.h"
+#include "core/indexing/indexing_events.h"
+#include "core/indexing/network_share.h"
+#include "core/indexing/peer_sync.h"
 #include "core/indexing/pipeline.h"
+#include "core/indexing/s3_source.h"
+#include "core/indexing/ssh_source.h"
 #include "core/index/sqlite_store.h"
 #include "core/extraction/extraction_manager.h"
 #include "core/fs/path_rules.h"
 
 #include <QFileSystemWatcher>
+#include <QHash>
+#include <QJsonArray>
+#include <QTimer>
 
 #include <optional>
 #include <memory>
@@ -25,6 +33,8 @@ public:
 
 protected:
     QJsonObject handleRequest(const QJsonObject& request) override;
+    bool isAdminMethod(const QString& method) const override;
+    void prepareForShutdown() override;
 
 private:
     // Method handlers (doc 05)
@@ -34,11 +44,142 @@ private:
     QJsonObject handleSetUserActive(uint64_t id, const QJsonObject& params);
     QJsonObject handleReindexPath(uint64_t id, const QJsonObject& params);
     QJsonObject handleRebuildAll(uint64_t id);
+    QJsonObject handleDonateItems(uint64_t id, const QJsonObject& params);
+    QJsonObject handleDeleteDonatedItems(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSyncContacts(uint64_t id);
+    QJsonObject handleSyncCalendar(uint64_t id);
+    QJsonObject handleSyncBrowsers(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSyncMail(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSyncNotes(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSyncMessages(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSyncReminders(uint64_t id);
+    QJsonObject handleSyncPeers(uint64_t id);
+    QJsonObject handleSyncSshSources(uint64_t id);
+    QJsonObject handleSyncS3Sources(uint64_t id);
+    QJsonObject handleSyncNetworkShares(uint64_t id, const QJsonObject& params);
+    QJsonObject handleAddPrivacyExclusion(uint64_t id, const QJsonObject& params);
+    QJsonObject handleRemovePrivacyExclusion(uint64_t id, const QJsonObject& params);
+    QJsonObject handlePurgePath(uint64_t id, const QJsonObject& params);
+    QJsonObject handleVerifyPurged(uint64_t id, const QJsonObject& params);
     QJsonObject handleGetQueueStatus(uint64_t id);
+    QJsonObject handleGetIndexingProgress(uint64_t id);
+    QJsonObject handleGetIndexingEvents(uint64_t id, const QJsonObject& params);
+    // Appends to m_indexingEvents and broadcasts it as `indexingEvent`.
+    void publishIndexingEvent(const QString& event, const QJsonObject& fields);
+    void connectIndexingEvents();
+    QJsonObject handleGetDiagnostics(uint64_t id);
     void joinRebuildThreadIfNeeded();
+    void joinReindexThreadIfNeeded();
+    void joinCompactionThreadIfNeeded();
+    // The opt-in Contacts source (ContactsSource): brings the indexed cards
+    // in line with the Contacts database, or removes them when the
+    // index_contacts setting is off or access was denied. Runs when indexing
+    // starts, every 30 minutes while it runs, and on `syncContacts`.
+    QJsonObject syncContacts();
+    // The opt-in calendar source (CalendarSource), the same way: the events
+    // of the last 180 and next 90 days, under index_calendar. Runs when
+    // indexing starts, every 15 minutes while it runs, and on `syncCalendar`.
+    QJsonObject syncCalendar();
+    // The opt-in browser source (BrowserSource): pages visited in the last
+    // 90 days and bookmarks of every Safari and Chromium-family profile,
+    // under index_browser_history. Unless `force`, does nothing while no
+    // browser file changed since the last complete sync. Runs when indexing
+    // starts, every 10 minutes while it runs, and on `syncBrowsers`.
+    QJsonObject syncBrowsers(bool force = true);
+    // The opt-in Mail source (MailSource): the newest messages of every
+    // mailbox in Mail's store under index_mail, less the accounts listed in
+    // mail_excluded_accounts and mailboxes inside privacy folders. Unless
+    // `force`, only messages whose file changed since the last sync are
+    // parsed. Runs when indexing starts, every 15 minutes while it runs, and
+    // on `syncMail`.
+    QJsonObject syncMail(bool force = true);
+    // The opt-in Apple Notes source (NotesSource): every note outside
+    // Recently Deleted, under index_notes. Unless `force`, does nothing while
+    // the Notes database is unchanged since the last complete sync. Runs when
+    // indexing starts, every 10 minutes while it runs, and on `syncNotes`.
+    QJsonObject syncNotes(bool force = true);
+    // The opt-in Messages source (MessagesSource): the newest
+    // MessagesSource::kMaxMessages messages, under index_messages. Unless
+    // `force`, does nothing while chat.db is unchanged since the last
+    // complete sync. Runs when indexing starts, every 5 minutes while it
+    // runs, and on `syncMessages`.
+    QJsonObject syncMessages(bool force = true);
+    // The opt-in Reminders source (RemindersSource), the way syncCalendar
+    // does it: open reminders and those completed in the last 30 days, under
+    // index_reminders. Runs when indexing starts, every 10 minutes while it
+    // runs, and on `syncReminders`.
+    QJsonObject syncReminders();
+    // Index sync (PeerSync): for each peer in sync_peers, applies the delta
+    // since the last complete pass, then fetches its segment digests and
+    // downloads the segments that still differ, then drops the replicas of
+    // peers no longer configured. Files indexed on both Macs merge the
+    // peer's tags, pin and frecency by the sync_merge_policies policies
+    // (MetadataMerge). Blocks on the network, a request at a
+    // time. Runs when indexing starts, every 15 minutes while it runs, and
+    // on `syncPeers`.
+    QJsonObject syncPeers();
+    // One peer of syncPeers(); updates its entries of `state` and
+    // `cursors`, saving both after every delta page and segment so an
+    // interrupted sync resumes there.
+    QJsonObject syncPeer(const SyncPeer& peer, const MetadataMerge::Policies& policies,
+                         QJsonObject& state, QJsonObject& cursors);
+    void saveSyncState(const QJsonObject& state, const QJsonObject& cursors);
+    // SSH sources (SshSource): for each directory in ssh_sources, lists
+    // the remote tree and copies and extracts the files whose size or
+    // modification time changed, then drops files gone from the listing
+    // and sources no longer configured. ssh runs a command at a time on
+    // m_sshThread, and the summary goes out as sshSourcesSynced. Starts
+    // when indexing starts, every 30 minutes while it runs, and on
+    // `syncSshSources`. False when nothing started: a sync is running, or
+    // ssh_sources does not parse (m_lastSshSync then has the error).
+    bool startSshSync();
+    // One directory of a sync, on m_sshThread, over the master connection
+    // at `controlPath`, with temporary copies in `scratchDir`.
+    QJsonObject syncSshSource(const SshRemote& remote, const QString& controlPath,
+                              const QString& scratchDir);
+    // Stops a running sync at its next ssh command and waits for it.
+    void stopSshSync();
+    // S3 sources (S3Source): for each bucket in s3_sources, lists keys
+    // from the marker in s3_state, indexes new and changed objects
+    // (downloading the content of those configured for it) and drops keys
+    // the listing no longer has, then the buckets no longer configured.
+    // Requests go out a request at a time on m_s3Thread, and the summary
+    // goes out as s3SourcesSynced. Starts when indexing starts, every 30
+    // minutes while it runs, and on `syncS3Sources`. False when nothing
+    // started: a sync is running, or s3_sources does not parse
+    // (m_lastS3Sync then has the error).
+    bool startS3Sync();
+    // One bucket of a sync, on m_s3Thread; updates its entry of `state`,
+    // with temporary copies in `scratchDir`.
+    QJsonObject syncS3Bucket(const S3Bucket& bucket, QJsonObject& state, const QString& scratchDir);
+    // Stops a running sync at its next request and waits for it.
+    void stopS3Sync();
+    // Network shares (NetworkShares): crawls the shares in network_shares
+    // that are due (all of them with `force`, or only `onlyName`) on
+    // m_sharesThread, updating network_share_state when it is done. The
+    // timer calls it every minute while indexing runs. Returns the names
+    // of the shares started: none while a crawl is running.
+    QStringList startNetworkShareCrawl(bool force, const QString& onlyName = QString());
+    // One share, on m_sharesThread: paced walk, diff, queued changes.
+    QJsonObject crawlNetworkShare(const NetworkShare& share);
+    // Stops a running crawl at its next file and waits for it.
+    void stopNetworkShareCrawl();
+    // Timer slot: compact on a worker thread when deletions are pending,
+    // the queue is idle and the last compaction is old enough.
+    void maybeCompactIndex();
     void configureBsignoreWatcher();
     void reloadBsignore();
     QJsonObject bsignoreStatusJson() const;
+    // Privacy folders: the persisted list, replaced by startIndexing's
+    // `privacyExclusions` when the app sends one.
+    QStringList loadPrivacyExclusions(const QJsonObject& startParams) const;
+    QStringList privacyExclusions() const;
+    // `secret_redaction` mode (default hash) and the index's hash key,
+    // created on first use.
+    SecretRedactor loadSecretRedactor();
+    // Which current roots (and protected folders inside them) this process
+    // cannot read, probed fresh each call.
+    QJsonObject fileAccessJson() const;
 
 private slots:
     void onBsignorePathChanged(const QString& path);
@@ -57,10 +198,64 @@ private:
     std::atomic<qint64> m_rebuildStartedAtMs{0};
     std::atomic<qint64> m_rebuildFinishedAtMs{0};
     std::thread m_rebuildThread;
+    std::atomic<bool> m_reindexRunning{false};
+    std::thread m_reindexThread;  // folder reindexPath scans
+    QTimer m_compactionTimer;
+    std::atomic<bool> m_compactionRunning{false};
+    std::atomic<qint64> m_compactedAtMs{0};  // last complete compaction
+    std::thread m_compactionThread;
+    QTimer m_contactsTimer;
+    QJsonObject m_lastContactsSync;  // syncContacts() summary, for getDiagnostics
+    QTimer m_calendarTimer;
+    QJsonObject m_lastCalendarSync;  // syncCalendar() summary, for getDiagnostics
+    QTimer m_browserTimer;
+    QJsonObject m_lastBrowserSync;   // syncBrowsers() summary, for getDiagnostics
+    QHash<QString, qint64> m_browserStamps;  // browser file -> mtime ^ size at the last sync
+    QTimer m_mailTimer;
+    QJsonObject m_lastMailSync;      // syncMail() summary, for getDiagnostics
+    QHash<QString, qint64> m_mailStamps;  // message identifier -> mtime ^ size at the last sync
+    QTimer m_notesTimer;
+    QJsonObject m_lastNotesSync;     // syncNotes() summary, for getDiagnostics
+    QHash<QString, qint64> m_notesStamps;  // NoteStore file -> mtime ^ size at the last sync
+    QTimer m_messagesTimer;
+    QJsonObject m_lastMessagesSync;  // syncMessages() summary, for getDiagnostics
+    QHash<QString, qint64> m_messagesStamps;  // chat.db file -> mtime ^ size at the last sync
+    QTimer m_remindersTimer;
+    QJsonObject m_lastRemindersSync;  // syncReminders() summary, for getDiagnostics
+    QTimer m_peersTimer;
+    bool m_peersSyncing = false;     // syncPeers() is waiting on a peer
+    QJsonObject m_lastPeersSync;     // syncPeers() summary, for getDiagnostics
+    QTimer m_sshTimer;
+    std::atomic<bool> m_sshRunning{false};
+    std::atomic<bool> m_sshStopping{false};
+    std::thread m_sshThread;         // SSH source syncs
+    QJsonObject m_lastSshSync;       // last SSH sync's summary, for getDiagnostics
+    QTimer m_s3Timer;
+    std::atomic<bool> m_s3Running{false};
+    std::atomic<bool> m_s3Stopping{false};
+    std::thread m_s3Thread;          // S3 source syncs
+    QJsonObject m_lastS3Sync;        // last S3 sync's summary, for getDiagnostics
+    QTimer m_sharesTimer;
+    std::atomic<bool> m_sharesRunning{false};
+    std::atomic<bool> m_sharesStopping{false};
+    std::thread m_sharesThread;      // network share crawls
+    QJsonObject m_shareState;        // network_share_state, on the main thread
+    QJsonObject m_lastSharesSync;    // last crawl's summary, for getDiagnostics
+    qint64 m_lastReindexId = 0;
     bool m_lastQueueActive = false;
 
+    IndexingEventLog m_indexingEvents;
+    qint64 m_lastProgressEventMs = 0;
+    // A crawl is under way that has not had its final `progress` event.
+    bool m_crawlProgressOpen = false;
+
     // Stored roots for rebuild
     std::vector<std::string> m_currentRoots;
+    // content_type_rules in effect, as loaded at startIndexing
+    QJsonArray m_contentTypeRules;
+
+    // <dataDir>/indexer-checkpoint.json, written on graceful shutdown
+    QString m_checkpointPath;
 
     std::unique_ptr<QFileSystemWatcher> m_bsignoreWatcher;
     QString m_bsignorePath;
@@ -1,4 +1,6 @@
 #include "indexer_service.h"
+#include "core/shared/logging.h"
+
 #include <QCoreApplication>
 
 int main(int argc, char* argv[])
@@ -6,6 +8,7 @@ int main(int argc, char* argv[])
     QCoreApplication app(argc, argv);
     app.setApplicationName(QStringLiteral("betterspotlight-indexer"));
     app.setApplicationVersion(QStringLiteral("0.1.0"));
+    bs::Logging::install(QStringLiteral("indexer"));
 
     bs::IndexerService service;
     return service.run();
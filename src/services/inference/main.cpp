@@ -1,4 +1,5 @@
 #include "inference_service.h"
+#include "core/shared/logging.h"
 
 #include <QCoreApplication>
 
@@ -7,6 +8,7 @@ int main(int argc, char* argv[])
     QCoreApplication app(argc, argv);
     app.setApplicationName(QStringLiteral("betterspotlight-inference"));
     app.setApplicationVersion(QStringLiteral("0.1.0"));
+    bs::Logging::install(QStringLiteral("inference"));
 
     bs::InferenceService service;
     return service.run();
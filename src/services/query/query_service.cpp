@@ -1,16 +1,32 @@
 #include "query_service.h"
+#include "core/fs/extended_attributes.h"
+#include "core/indexing/browser_source.h"
+#include "core/indexing/calendar_source.h"
+#include "core/indexing/contacts_source.h"
+#include "core/indexing/mail_source.h"
+#include "core/indexing/messages_source.h"
+#include "core/indexing/notes_source.h"
+#include "core/indexing/reminders_source.h"
+#include "core/indexing/s3_source.h"
+#include "core/indexing/spotlight_donation.h"
+#include "core/indexing/ssh_source.h"
 #include "core/ipc/message.h"
 #include "core/ipc/socket_client.h"
 #include "core/query/doctype_classifier.h"
+#include "core/query/instant_answers.h"
+#include "core/query/natural_language_query.h"
 #include "core/query/query_normalizer.h"
 #include "core/query/query_parser.h"
 #include "core/query/query_router.h"
 #include "core/query/rules_engine.h"
+#include "core/query/settings_panes.h"
 #include "core/query/stopwords.h"
 #include "core/query/structured_query.h"
 #include "core/shared/search_result.h"
 #include "core/shared/search_options.h"
+#include "core/shared/crash_report.h"
 #include "core/shared/logging.h"
+#include "core/shared/tracing.h"
 #include "core/ranking/match_classifier.h"
 #include "core/ranking/cross_encoder_reranker.h"
 #include "core/ranking/personalized_ltr.h"
@@ -28,6 +44,7 @@
 #include "core/feedback/interaction_tracker.h"
 #include "core/feedback/feedback_aggregator.h"
 #include "core/feedback/path_preferences.h"
+#include "core/feedback/query_history.h"
 #include "core/feedback/type_affinity.h"
 
 #include <QDateTime>
@@ -273,6 +290,22 @@ QString normalizeFileTypeToken(const QString& token)
     return normalized;
 }
 
+// The source a `kind:` name keeps results to, as the `kind` results carry;
+// empty for names that are not one.
+QString kindBundleIdentifier(const QString& kind)
+{
+    static const QHash<QString, QString> kKinds = {
+        {QStringLiteral("contact"), QString::fromLatin1(ContactsSource::kBundleIdentifier)},
+        {QStringLiteral("event"), QString::fromLatin1(CalendarSource::kBundleIdentifier)},
+        {QStringLiteral("webpage"), QString::fromLatin1(BrowserSource::kBundleIdentifier)},
+        {QStringLiteral("mail"), QString::fromLatin1(MailSource::kBundleIdentifier)},
+        {QStringLiteral("note"), QString::fromLatin1(NotesSource::kBundleIdentifier)},
+        {QStringLiteral("message"), QString::fromLatin1(MessagesSource::kBundleIdentifier)},
+        {QStringLiteral("reminder"), QString::fromLatin1(RemindersSource::kBundleIdentifier)},
+    };
+    return kKinds.value(kind);
+}
+
 SearchQueryMode parseSearchQueryMode(const QJsonObject& params)
 {
     const QString mode = params.value(QStringLiteral("queryMode"))
@@ -288,6 +321,33 @@ SearchQueryMode parseSearchQueryMode(const QJsonObject& params)
     return SearchQueryMode::Auto;
 }
 
+QString searchQueryModeToString(SearchQueryMode mode)
+{
+    switch (mode) {
+    case SearchQueryMode::Strict:
+        return QStringLiteral("strict");
+    case SearchQueryMode::Relaxed:
+        return QStringLiteral("relaxed");
+    case SearchQueryMode::Auto:
+        break;
+    }
+    return QStringLiteral("auto");
+}
+
+QJsonArray settingsPaneMatches(const QString& query, int limit)
+{
+    QJsonArray matches;
+    for (const SettingsPanes::Match& match : SettingsPanes::match(query, limit)) {
+        QJsonObject json = match.pane.toJson();
+        if (!match.matchedKeyword.isEmpty()) {
+            json[QStringLiteral("matchedKeyword")] = match.matchedKeyword;
+        }
+        json[QStringLiteral("score")] = match.score;
+        matches.append(json);
+    }
+    return matches;
+}
+
 // queryStopwords() is now shared via core/query/stopwords.h
 
 QStringList tokenizeWords(const QString& text)
@@ -434,6 +494,23 @@ QueryHints parseQueryHints(const QString& queryLower)
     return hints;
 }
 
+// Applies a request deadline to the store for the lifetime of one search,
+// or until release() hands the connection back for bookkeeping writes.
+class ScopedQueryDeadline {
+public:
+    ScopedQueryDeadline(SQLiteStore& store, qint64 deadlineAtMs)
+        : m_store(store)
+    {
+        m_store.setQueryDeadline(deadlineAtMs);
+    }
+    ~ScopedQueryDeadline() { release(); }
+
+    void release() { m_store.setQueryDeadline(0); }
+
+private:
+    SQLiteStore& m_store;
+};
+
 QueryService::QueryService(QObject* parent)
     : ServiceBase(QStringLiteral("query"), parent)
 {
@@ -471,6 +548,8 @@ QueryService::QueryService(QObject* parent)
         }
     });
     initBsignoreWatch();
+    initLiveQueries();
+    initHttpApi();
 }
 
 QueryService::~QueryService()
@@ -615,7 +694,25 @@ QJsonObject QueryService::handleRequest(const QJsonObject& request)
     uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
     QJsonObject params = request.value(QStringLiteral("params")).toObject();
 
-    if (method == QLatin1String("search"))          return handleSearch(id, params);
+    if (request.value(QStringLiteral("auth")).isObject()) {
+        return handleScopedRequest(request);
+    }
+
+    // Scoped requests come back through here unscoped, so each is timed once.
+    if (!isTimedMethod(method)) {
+        return routeRequest(request, method, id, params);
+    }
+    QElapsedTimer timer;
+    timer.start();
+    QJsonObject response = routeRequest(request, method, id, params);
+    recordRequestMetrics(method, static_cast<double>(timer.nsecsElapsed()) / 1e9, response);
+    return response;
+}
+
+QJsonObject QueryService::routeRequest(const QJsonObject& request, const QString& method,
+                                       uint64_t id, const QJsonObject& params)
+{
+    if (method == QLatin1String("search"))          return handleFederatedSearch(id, params);
     if (method == QLatin1String("getAnswerSnippet")
         || method == QLatin1String("get_answer_snippet")) return handleGetAnswerSnippet(id, params);
     if (method == QLatin1String("getHealth"))        return handleGetHealth(id);
@@ -624,6 +721,43 @@ QJsonObject QueryService::handleRequest(const QJsonObject& request)
     if (method == QLatin1String("getHealthDetails")) return handleGetHealthDetails(id, params);
     if (method == QLatin1String("recordFeedback"))   return handleRecordFeedback(id, params);
     if (method == QLatin1String("getFrequency"))     return handleGetFrequency(id, params);
+    if (method == QLatin1String("suggest"))          return handleSuggest(id, params);
+    if (method == QLatin1String("completePaths"))    return handleCompletePaths(id, params);
+    if (method == QLatin1String("exportMatches"))    return handleExportMatches(id, params);
+    if (method == QLatin1String("getDocument"))      return handleGetDocument(id, params);
+    if (method == QLatin1String("getThumbnail"))     return handleGetThumbnail(id, params);
+    if (method == QLatin1String("performAction"))    return handlePerformAction(id, params);
+    if (method == QLatin1String("getRecentActions")) return handleGetRecentActions(id, params);
+    if (method == QLatin1String("repeatAction"))     return handleRepeatAction(id, params);
+    if (method == QLatin1String("getAbbreviations")) return handleGetAbbreviations(id);
+    if (method == QLatin1String("setAbbreviation"))  return handleSetAbbreviation(id, params);
+    if (method == QLatin1String("removeAbbreviation")) return handleRemoveAbbreviation(id, params);
+    if (method == QLatin1String("listSearchScopes")) return handleListSearchScopes(id);
+    if (method == QLatin1String("setSearchScope"))   return handleSetSearchScope(id, params);
+    if (method == QLatin1String("removeSearchScope")) return handleRemoveSearchScope(id, params);
+    if (method == QLatin1String("listApplications")) return handleListApplications(id);
+    if (method == QLatin1String("launchApp"))        return handleLaunchApp(id, params);
+    if (method == QLatin1String("findSimilar"))      return handleFindSimilar(id, params);
+    if (method == QLatin1String("subscribeQuery"))   return handleSubscribeQuery(id, params);
+    if (method == QLatin1String("unsubscribeQuery")) return handleUnsubscribeQuery(id, params);
+    if (method == QLatin1String("getReadiness"))     return handleGetReadiness(id);
+    if (method == QLatin1String("purgePrivacyPaths")) return handlePurgePrivacyPaths(id, params);
+    if (method == QLatin1String("purgeOrphans"))     return handlePurgeOrphans(id, params);
+    if (method == QLatin1String("getAuditLog"))      return handleGetAuditLog(id, params);
+    if (method == QLatin1String("purgeAuditLog"))    return handlePurgeAuditLog(id, params);
+    if (method == QLatin1String("getSlowQueries"))   return handleGetSlowQueries(id, params);
+    if (method == QLatin1String("clearSlowQueries")) return handleClearSlowQueries(id);
+    if (method == QLatin1String("getQueryHistory"))  return handleGetQueryHistory(id, params);
+    if (method == QLatin1String("purgeQueryHistory")) return handlePurgeQueryHistory(id, params);
+    if (method == QLatin1String("getIndexBreakdown")) return handleGetIndexBreakdown(id, params);
+    if (method == QLatin1String("createApiToken"))   return handleCreateApiToken(id, params);
+    if (method == QLatin1String("listApiTokens"))    return handleListApiTokens(id);
+    if (method == QLatin1String("revokeApiToken"))   return handleRevokeApiToken(id, params);
+    if (method == QLatin1String("getSyncSegments"))  return handleGetSyncSegments(id);
+    if (method == QLatin1String("getSyncSegment"))   return handleGetSyncSegment(id, params);
+    if (method == QLatin1String("getSyncDelta"))     return handleGetSyncDelta(id, params);
+    if (method == QLatin1String("getMetrics"))       return handleGetMetrics(id);
+    if (method == QLatin1String("getQueryStats"))    return handleGetQueryStats(id);
 
     if (method == QLatin1String("record_interaction"))       return handleRecordInteraction(id, params);
     if (method == QLatin1String("get_path_preferences"))     return handleGetPathPreferences(id, params);
@@ -642,6 +776,35 @@ QJsonObject QueryService::handleRequest(const QJsonObject& request)
     return ServiceBase::handleRequest(request);
 }
 
+bool QueryService::isAdminMethod(const QString& method) const
+{
+    static const QSet<QString> kAdminMethods = {
+        QStringLiteral("rebuildVectorIndex"),
+        QStringLiteral("rebuild_vector_index"),
+        QStringLiteral("run_aggregation"),
+        QStringLiteral("export_interaction_data"),
+        QStringLiteral("set_learning_consent"),
+        QStringLiteral("trigger_learning_cycle"),
+        QStringLiteral("purgePrivacyPaths"),
+        QStringLiteral("purgeOrphans"),
+        QStringLiteral("getAuditLog"),
+        QStringLiteral("purgeAuditLog"),
+        QStringLiteral("getSlowQueries"),
+        QStringLiteral("clearSlowQueries"),
+        QStringLiteral("getQueryHistory"),
+        QStringLiteral("purgeQueryHistory"),
+        QStringLiteral("setAbbreviation"),
+        QStringLiteral("removeAbbreviation"),
+        QStringLiteral("setSearchScope"),
+        QStringLiteral("removeSearchScope"),
+        QStringLiteral("getIndexBreakdown"),
+        QStringLiteral("createApiToken"),
+        QStringLiteral("listApiTokens"),
+        QStringLiteral("revokeApiToken"),
+    };
+    return kAdminMethods.contains(method) || ServiceBase::isAdminMethod(method);
+}
+
 bool QueryService::ensureStoreOpen()
 {
     if (m_store.has_value()) {
@@ -667,7 +830,18 @@ bool QueryService::ensureStoreOpen()
     }
 
     m_store.emplace(std::move(store.value()));
+    m_queryHistory = std::make_unique<QueryHistory>(m_store->rawDb());
+    m_queryAbbreviationsLoaded = false;
+    m_searchScopesLoaded = false;
     LOG_INFO(bsIpc, "Database opened at: %s", qPrintable(m_dbPath));
+    CrashReport::setContext(QStringLiteral("index"), QJsonObject{
+        {QStringLiteral("dbPath"), m_dbPath},
+        {QStringLiteral("dbBytes"), QFileInfo(m_dbPath).size()},
+        {QStringLiteral("items"), static_cast<qint64>(m_store->itemCount())},
+        {QStringLiteral("schemaVersion"),
+         m_store->getSetting(QStringLiteral("schema_version")).value_or(QString())},
+        {QStringLiteral("vectorGeneration"), m_activeVectorGeneration},
+    });
 
     initBsignoreWatch();
     return true;
@@ -1313,40 +1487,416 @@ QJsonObject QueryService::queryStatsSnapshot() const
         static_cast<qint64>(m_semanticOnlyAdmittedCount.load());
     stats[QStringLiteral("semanticOnlySuppressedCount")] =
         static_cast<qint64>(m_semanticOnlySuppressedCount.load());
+
+    const RequestSchedulerStats scheduling = m_server->schedulingStats();
+    QJsonObject schedulingJson;
+    schedulingJson[QStringLiteral("pending")] = static_cast<qint64>(scheduling.pending);
+    schedulingJson[QStringLiteral("dispatched")] = static_cast<qint64>(scheduling.dispatched);
+    schedulingJson[QStringLiteral("expired")] = static_cast<qint64>(scheduling.expired);
+    schedulingJson[QStringLiteral("rejected")] = static_cast<qint64>(scheduling.rejected);
+    schedulingJson[QStringLiteral("maxQueueDelayMs")] =
+        static_cast<qint64>(scheduling.maxQueueDelayMs);
+    stats[QStringLiteral("requestScheduling")] = schedulingJson;
+    stats[QStringLiteral("windows")] = requestWindowsJson();
     return stats;
 }
 
+SearchOptions QueryService::searchOptionsFromFilters(const QJsonObject& filters)
+{
+    SearchOptions options;
+    for (const auto& t : filters.value(QStringLiteral("fileTypes")).toArray()) {
+        const QString normalized = normalizeFileTypeToken(t.toString());
+        if (!normalized.isEmpty()
+            && std::find(options.fileTypes.begin(), options.fileTypes.end(), normalized)
+                   == options.fileTypes.end()) {
+            options.fileTypes.push_back(normalized);
+        }
+    }
+    const auto addPaths = [&filters](std::vector<QString>& container, const QString& key) {
+        const QJsonArray paths = filters.value(key).toArray();
+        container.reserve(static_cast<size_t>(paths.size()));
+        for (const auto& p : paths) {
+            const QString normalized = QDir::cleanPath(p.toString().trimmed());
+            if (!normalized.isEmpty()
+                && std::find(container.begin(), container.end(), normalized) == container.end()) {
+                container.push_back(normalized);
+            }
+        }
+    };
+    addPaths(options.excludePaths, QStringLiteral("excludePaths"));
+    addPaths(options.includePaths, QStringLiteral("includePaths"));
+    for (const auto& t : filters.value(QStringLiteral("tags")).toArray()) {
+        const QString tag = t.toString().trimmed();
+        const bool alreadyPresent = std::any_of(
+            options.tags.begin(), options.tags.end(),
+            [&](const QString& existing) {
+                return existing.compare(tag, Qt::CaseInsensitive) == 0;
+            });
+        if (!tag.isEmpty() && !alreadyPresent) {
+            options.tags.push_back(tag);
+        }
+    }
+    if (filters.contains(QStringLiteral("modifiedAfter"))) {
+        options.modifiedAfter = filters.value(QStringLiteral("modifiedAfter")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("modifiedBefore"))) {
+        options.modifiedBefore = filters.value(QStringLiteral("modifiedBefore")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("createdAfter"))) {
+        options.createdAfter = filters.value(QStringLiteral("createdAfter")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("createdBefore"))) {
+        options.createdBefore = filters.value(QStringLiteral("createdBefore")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("dueAfter"))) {
+        options.dueAfter = filters.value(QStringLiteral("dueAfter")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("dueBefore"))) {
+        options.dueBefore = filters.value(QStringLiteral("dueBefore")).toDouble();
+    }
+    if (filters.contains(QStringLiteral("minSize"))) {
+        options.minSizeBytes = static_cast<int64_t>(
+            filters.value(QStringLiteral("minSize")).toDouble());
+    }
+    if (filters.contains(QStringLiteral("maxSize"))) {
+        options.maxSizeBytes = static_cast<int64_t>(
+            filters.value(QStringLiteral("maxSize")).toDouble());
+    }
+    return options;
+}
+
+QJsonObject QueryService::searchResultJson(const SearchResult& sr)
+{
+    QJsonObject metadata;
+    metadata[QStringLiteral("fileSize")] = static_cast<qint64>(sr.fileSize);
+    metadata[QStringLiteral("modificationDate")] = sr.modificationDate;
+
+    QJsonObject frequency;
+    frequency[QStringLiteral("openCount")] = sr.openCount;
+    frequency[QStringLiteral("lastOpenDate")] = sr.lastOpenDate;
+
+    QJsonObject obj;
+    obj[QStringLiteral("itemId")] = static_cast<qint64>(sr.itemId);
+    obj[QStringLiteral("path")] = sr.path;
+    obj[QStringLiteral("name")] = sr.name;
+    obj[QStringLiteral("kind")] = sr.kind;
+    obj[QStringLiteral("matchType")] = matchTypeToString(sr.matchType);
+    obj[QStringLiteral("score")] = sr.score;
+    obj[QStringLiteral("bm25Raw")] = sr.bm25RawScore;
+
+    QString plainSnippet = sr.snippet;
+    plainSnippet.replace(QStringLiteral("<b>"), QString());
+    plainSnippet.replace(QStringLiteral("</b>"), QString());
+    obj[QStringLiteral("snippet")] = plainSnippet;
+
+    QJsonArray highlightsArray;
+    for (const auto& highlight : sr.highlights) {
+        QJsonObject highlightObj;
+        highlightObj[QStringLiteral("offset")] = highlight.offset;
+        highlightObj[QStringLiteral("length")] = highlight.length;
+        highlightsArray.append(highlightObj);
+    }
+    obj[QStringLiteral("highlights")] = highlightsArray;
+    obj[QStringLiteral("metadata")] = metadata;
+    obj[QStringLiteral("isPinned")] = sr.isPinned;
+    obj[QStringLiteral("frequency")] = frequency;
+    const auto availability = m_store->getItemAvailability(sr.itemId);
+    if (availability.has_value()) {
+        obj[QStringLiteral("contentAvailable")] = availability->contentAvailable;
+        obj[QStringLiteral("availabilityStatus")] = availability->availabilityStatus;
+    } else {
+        obj[QStringLiteral("contentAvailable")] = true;
+        obj[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+    }
+    const QStringList tags = m_store->getItemTags(sr.itemId);
+    if (!tags.isEmpty()) {
+        obj[QStringLiteral("tags")] = QJsonArray::fromStringList(tags);
+    }
+    const QString comment = m_store->getItemAttributeValue(
+        sr.itemId, QString::fromLatin1(ExtendedAttributes::kFinderCommentAttribute));
+    if (!comment.isEmpty()) {
+        obj[QStringLiteral("comment")] = comment;
+    }
+    if (SpotlightDonation::isDonatedPath(sr.path)) {
+        // No file behind it: clients open `url` instead of the path.
+        obj[QStringLiteral("bundleIdentifier")] = SpotlightDonation::bundleIdentifier(sr.path);
+        if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+            == QLatin1String(ContactsSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("contact");
+            const QString role = m_store->getItemAttributeValue(
+                sr.itemId, QString::fromLatin1(SpotlightDonation::kDescriptionAttribute));
+            if (!role.isEmpty()) {
+                obj[QStringLiteral("contentDescription")] = role;
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(CalendarSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("event");
+            QJsonArray linkedDocuments;
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name == QLatin1String(CalendarSource::kStartAttribute)) {
+                    obj[QStringLiteral("startDate")] = attribute.value.toDouble();
+                } else if (attribute.name == QLatin1String(CalendarSource::kEndAttribute)) {
+                    obj[QStringLiteral("endDate")] = attribute.value.toDouble();
+                } else if (attribute.name
+                           == QLatin1String(CalendarSource::kLinkedDocumentAttribute)) {
+                    linkedDocuments.append(attribute.value);
+                }
+            }
+            if (!linkedDocuments.isEmpty()) {
+                obj[QStringLiteral("linkedDocuments")] = linkedDocuments;
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(BrowserSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("webpage");
+            const QString description = m_store->getItemAttributeValue(
+                sr.itemId, QString::fromLatin1(SpotlightDonation::kDescriptionAttribute));
+            if (!description.isEmpty()) {
+                obj[QStringLiteral("contentDescription")] = description;
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(MailSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("mail");
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name == QLatin1String(MailSource::kMailboxAttribute)) {
+                    obj[QStringLiteral("mailbox")] = attribute.value;
+                }
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(NotesSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("note");
+            const QString folder = m_store->getItemAttributeValue(
+                sr.itemId, QString::fromLatin1(SpotlightDonation::kDescriptionAttribute));
+            if (!folder.isEmpty()) {
+                obj[QStringLiteral("contentDescription")] = folder;
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(MessagesSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("message");
+            // Clients must not forward it anywhere (clipboard sync, shares).
+            obj[QStringLiteral("localOnly")] = true;
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name
+                           == QLatin1String(MessagesSource::kConversationAttribute)) {
+                    obj[QStringLiteral("conversation")] = attribute.value;
+                } else if (attribute.name
+                           == QLatin1String(MessagesSource::kConversationNameAttribute)) {
+                    obj[QStringLiteral("conversationName")] = attribute.value;
+                }
+            }
+        } else if (obj.value(QStringLiteral("bundleIdentifier")).toString()
+                   == QLatin1String(RemindersSource::kBundleIdentifier)) {
+            obj[QStringLiteral("kind")] = QStringLiteral("reminder");
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name == QLatin1String(SearchOptions::kDueDateAttribute)) {
+                    obj[QStringLiteral("dueDate")] = attribute.value.toDouble();
+                } else if (attribute.name == QLatin1String(RemindersSource::kListAttribute)) {
+                    obj[QStringLiteral("list")] = attribute.value;
+                } else if (attribute.name
+                           == QLatin1String(RemindersSource::kCompletedAttribute)) {
+                    obj[QStringLiteral("completed")] = attribute.value == QLatin1String("1");
+                }
+            }
+        } else if (PeerSync::isPeerPath(sr.path)) {
+            // A file on another Mac: its own kind, and the path it has there.
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name == QLatin1String(PeerSync::kPeerAttribute)) {
+                    obj[QStringLiteral("peer")] = attribute.value;
+                } else if (attribute.name == QLatin1String(PeerSync::kPeerPathAttribute)) {
+                    obj[QStringLiteral("peerPath")] = attribute.value;
+                } else if (attribute.name == QLatin1String(PeerSync::kPeerKindAttribute)) {
+                    obj[QStringLiteral("kind")] = attribute.value;
+                }
+            }
+        } else if (SshSource::isSshPath(sr.path) || S3Source::isS3Path(sr.path)) {
+            // A file on a server or an object in a bucket: its kind, and
+            // "host:/path" or "s3://bucket/key" to reach it by.
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(SpotlightDonation::kDescriptionAttribute)) {
+                    obj[QStringLiteral("contentDescription")] = attribute.value;
+                } else if (attribute.name == QLatin1String(SshSource::kRemoteAttribute)) {
+                    obj[QStringLiteral("remote")] = attribute.value;
+                } else if (attribute.name == QLatin1String(SshSource::kRemotePathAttribute)) {
+                    obj[QStringLiteral("remotePath")] = attribute.value;
+                } else if (attribute.name == QLatin1String(SshSource::kRemoteKindAttribute)) {
+                    obj[QStringLiteral("kind")] = attribute.value;
+                }
+            }
+        }
+        const QString url = m_store->getItemAttributeValue(
+            sr.itemId, QString::fromLatin1(SpotlightDonation::kUrlAttribute));
+        if (!url.isEmpty()) {
+            obj[QStringLiteral("url")] = url;
+        }
+    }
+    return obj;
+}
+
+QJsonObject QueryService::searchFacetsJson(const std::vector<int64_t>& itemIds)
+{
+    QJsonArray tagFacets;
+    for (const SQLiteStore::TagCount& count : m_store->countTags(itemIds)) {
+        QJsonObject facet;
+        facet[QStringLiteral("tag")] = count.tag;
+        facet[QStringLiteral("count")] = count.count;
+        tagFacets.append(facet);
+    }
+    QJsonObject facets;
+    facets[QStringLiteral("tags")] = tagFacets;
+    // Only present when the results include mail.
+    QJsonArray mailboxFacets;
+    for (const SQLiteStore::AttributeCount& count : m_store->countAttributeValues(
+             itemIds, QString::fromLatin1(MailSource::kMailboxAttribute))) {
+        QJsonObject facet;
+        facet[QStringLiteral("mailbox")] = count.value;
+        facet[QStringLiteral("count")] = count.count;
+        mailboxFacets.append(facet);
+    }
+    if (!mailboxFacets.isEmpty()) {
+        facets[QStringLiteral("mailboxes")] = mailboxFacets;
+    }
+    return facets;
+}
+
+QJsonObject QueryService::handleTagOnlySearch(uint64_t id, const SearchOptions& options,
+                                              int limit)
+{
+    QElapsedTimer timer;
+    timer.start();
+
+    const std::vector<SQLiteStore::ItemRow> items = m_store->listFilteredItems(options, limit);
+    std::vector<int64_t> itemIds;
+    itemIds.reserve(items.size());
+    for (const auto& item : items) {
+        itemIds.push_back(item.id);
+    }
+    const auto freqMap = m_store->getFrequenciesBatch(itemIds);
+
+    QJsonArray resultsArray;
+    std::vector<int64_t> resultItemIds;
+    resultItemIds.reserve(items.size());
+    for (const auto& item : items) {
+        if (isExcludedByBsignore(item.path)) {
+            continue;
+        }
+        SearchResult sr;
+        sr.itemId = item.id;
+        sr.path = item.path;
+        sr.name = item.name;
+        sr.kind = item.kind;
+        sr.fileSize = item.size;
+        if (item.modifiedAt > 0.0) {
+            sr.modificationDate = QDateTime::fromMSecsSinceEpoch(
+                static_cast<qint64>(item.modifiedAt * 1000.0)).toUTC().toString(Qt::ISODate);
+        }
+        sr.isPinned = item.isPinned;
+        auto freqIt = freqMap.find(item.id);
+        if (freqIt != freqMap.end()) {
+            sr.openCount = freqIt->second.openCount;
+            if (freqIt->second.lastOpenedAt > 0.0) {
+                sr.lastOpenDate = QDateTime::fromMSecsSinceEpoch(
+                    static_cast<qint64>(freqIt->second.lastOpenedAt * 1000.0))
+                    .toUTC().toString(Qt::ISODate);
+            }
+        }
+        resultsArray.append(searchResultJson(sr));
+        resultItemIds.push_back(item.id);
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("results")] = resultsArray;
+    result[QStringLiteral("queryTime")] = static_cast<int>(timer.elapsed());
+    result[QStringLiteral("totalMatches")] = resultsArray.size();
+    result[QStringLiteral("facets")] = searchFacetsJson(resultItemIds);
+    return IpcMessage::makeResponse(id, result);
+}
+
 QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
 {
     if (!ensureM2ModulesInitialized()) {
         return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
                                      QStringLiteral("Database is not available"));
     }
-    if (m_learningEngine) {
+    if (params.contains(QStringLiteral("predicate"))) {
+        return handlePredicateSearch(id, params);
+    }
+    if (m_learningEngine && !m_liveQueryRefreshActive) {
         m_learningEngine->noteUserActivity();
     }
+    // Private searches rank as usual but leave nothing behind: no learning
+    // exposures and no cached result.
+    const bool privateQuery = params.value(QStringLiteral("private")).toBool(false);
+
+    // Stage spans for tracing (see docs/ipc-service-boundaries.md). Each
+    // ends where the next begins; an early return ends the open ones.
+    // `phases` times the same stages for the slow query log.
+    SearchPhaseTimer phases;
+    Span parseSpan(QStringLiteral("search.parse"));
 
     // Parse query
     const QString originalRawQuery = params.value(QStringLiteral("query")).toString();
+    // "1499*1.21", "72 f in c": answered inline, ahead of any file hits.
+    // Evaluated on the raw text since normalization drops the operators.
+    const std::optional<InstantAnswer> instantAnswer = InstantAnswers::evaluate(originalRawQuery);
+    const bool definitionsWanted = params.value(QStringLiteral("definitions")).toBool(true);
     QString query = originalRawQuery;
     {
         const auto nq = QueryNormalizer::normalize(query);
         query = nq.normalized;
     }
+    QStringList abbreviationsApplied;
+    query = queryAbbreviations().expand(query, &abbreviationsApplied);
     const QString normalizedQueryBeforeParse = query;
+    // "screenshots from last tuesday": kind and date phrases become filters,
+    // echoed as `interpretation`; `naturalLanguage: false` searches literally.
+    std::optional<QueryInterpretation> interpretation;
+    if (params.value(QStringLiteral("naturalLanguage")).toBool(true)) {
+        interpretation = NaturalLanguageQuery::interpret(query);
+        if (interpretation.has_value()) {
+            query = interpretation->query;
+        }
+    }
     const auto parsed = QueryParser::parse(query);
     if (parsed.hasTypeHint) {
+        // A private query's text stays out of the logs and the crash ring.
         LOG_INFO(bsIpc, "QueryParser: extracted types=[%s] from query='%s'",
                  qUtf8Printable(parsed.extractedTypes.join(QStringLiteral(","))),
-                 qUtf8Printable(query));
-    }
+                 privateQuery ? "<private>" : qUtf8Printable(query));
+    }
+    if (!parsed.dateError.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, parsed.dateError);
+    }
+    // Tag-, date- or kind-only inputs (e.g. "tag:red pdf", "modified:today",
+    // "due:today kind:reminder", "photos from yesterday") have nothing to
+    // match and list the filtered items instead.
+    const bool filterOnlyQuery =
+        parsed.cleanedQuery.isEmpty()
+        && (parsed.hasTagFilter || parsed.hasDateFilter || parsed.hasKindFilter
+            || interpretation.has_value());
     if (!parsed.cleanedQuery.isEmpty()) {
         query = parsed.cleanedQuery;
-    } else if (parsed.hasTypeHint) {
+    } else if (parsed.hasTypeHint && !filterOnlyQuery) {
         // Preserve query text for type-only inputs (e.g. "pdf") so search still runs.
-        query = normalizedQueryBeforeParse;
+        query = parsed.extractedTypes.join(QLatin1Char(' '));
     }
-    if (query.isEmpty()) {
+    if (query.isEmpty() && !filterOnlyQuery) {
+        if (instantAnswer.has_value()) {
+            QJsonObject result;
+            result[QStringLiteral("results")] = QJsonArray();
+            result[QStringLiteral("queryTime")] = 0;
+            result[QStringLiteral("totalMatches")] = 0;
+            result[QStringLiteral("instantAnswer")] = instantAnswer->toJson();
+            return IpcMessage::makeResponse(id, result);
+        }
         return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
                                      QStringLiteral("Missing 'query' parameter"));
     }
@@ -1363,10 +1913,28 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         }
     }
     const bool debugRequested = params.value(QStringLiteral("debug")).toBool(false);
+    // `bspot bench` replays the same queries and needs every run to be real.
+    const bool cacheBypassed =
+        debugRequested || params.value(QStringLiteral("noCache")).toBool(false);
     const SearchQueryMode queryMode = parseSearchQueryMode(params);
 
+    // Optional per-request deadline (epoch ms). Stage budgets shrink to fit it
+    // and SQLite work past it is interrupted, so a slow query returns partial
+    // results instead of holding up other clients queued behind it.
+    const qint64 deadlineAtMs = std::max<qint64>(
+        0, params.value(QStringLiteral("deadlineMs")).toInteger(0));
+    const auto clampToDeadline = [deadlineAtMs](int budgetMs) {
+        if (deadlineAtMs <= 0) {
+            return budgetMs;
+        }
+        const qint64 remainingMs = deadlineAtMs - QDateTime::currentMSecsSinceEpoch();
+        return static_cast<int>(std::clamp<qint64>(remainingMs, 1, budgetMs));
+    };
+
     SearchOptions searchOptions;
-    const bool hasUserProvidedFilters = params.contains(QStringLiteral("filters"));
+    // `kind:` confines the paths as the filters do.
+    const bool hasUserProvidedFilters =
+        params.contains(QStringLiteral("filters")) || parsed.hasKindFilter;
     const auto addFileTypeFilter = [&](const QString& rawType) {
         const QString normalized = normalizeFileTypeToken(rawType);
         if (normalized.isEmpty()) {
@@ -1398,46 +1966,121 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         }
     };
     if (params.contains(QStringLiteral("filters"))) {
-        const QJsonObject filters = params.value(QStringLiteral("filters")).toObject();
+        searchOptions = searchOptionsFromFilters(params.value(QStringLiteral("filters")).toObject());
+    }
 
-        if (filters.contains(QStringLiteral("fileTypes"))) {
-            const QJsonArray types = filters.value(QStringLiteral("fileTypes")).toArray();
-            for (const auto& t : types) {
-                addFileTypeFilter(t.toString());
-            }
-        }
-        if (filters.contains(QStringLiteral("excludePaths"))) {
-            const QJsonArray paths = filters.value(QStringLiteral("excludePaths")).toArray();
-            searchOptions.excludePaths.reserve(static_cast<size_t>(paths.size()));
-            for (const auto& p : paths) {
-                addPathFilterUnique(searchOptions.excludePaths, p.toString());
-            }
-        }
-        if (filters.contains(QStringLiteral("includePaths"))) {
-            const QJsonArray paths = filters.value(QStringLiteral("includePaths")).toArray();
-            searchOptions.includePaths.reserve(static_cast<size_t>(paths.size()));
-            for (const auto& p : paths) {
-                addPathFilterUnique(searchOptions.includePaths, p.toString());
-            }
-        }
-        if (filters.contains(QStringLiteral("modifiedAfter"))) {
-            searchOptions.modifiedAfter = filters.value(QStringLiteral("modifiedAfter")).toDouble();
+    for (const QString& parsedType : parsed.filters.fileTypes) {
+        addFileTypeFilter(parsedType);
+    }
+    for (const QString& parsedTag : parsed.filters.tags) {
+        const bool alreadyPresent = std::any_of(
+            searchOptions.tags.begin(), searchOptions.tags.end(),
+            [&](const QString& existing) {
+                return existing.compare(parsedTag, Qt::CaseInsensitive) == 0;
+            });
+        if (!alreadyPresent) {
+            searchOptions.tags.push_back(parsedTag);
         }
-        if (filters.contains(QStringLiteral("modifiedBefore"))) {
-            searchOptions.modifiedBefore = filters.value(QStringLiteral("modifiedBefore")).toDouble();
+    }
+    // Dates in the query narrow the filters' date bounds.
+    const auto boundAfter = [](std::optional<double>& bound, std::optional<double> value) {
+        if (value.has_value()) {
+            bound = std::max(value.value(), bound.value_or(value.value()));
         }
-        if (filters.contains(QStringLiteral("minSize"))) {
-            searchOptions.minSizeBytes = static_cast<int64_t>(
-                filters.value(QStringLiteral("minSize")).toDouble());
+    };
+    const auto boundBefore = [](std::optional<double>& bound, std::optional<double> value) {
+        if (value.has_value()) {
+            bound = std::min(value.value(), bound.value_or(value.value()));
         }
-        if (filters.contains(QStringLiteral("maxSize"))) {
-            searchOptions.maxSizeBytes = static_cast<int64_t>(
-                filters.value(QStringLiteral("maxSize")).toDouble());
+    };
+    boundAfter(searchOptions.modifiedAfter, parsed.filters.modifiedAfter);
+    boundBefore(searchOptions.modifiedBefore, parsed.filters.modifiedBefore);
+    boundAfter(searchOptions.createdAfter, parsed.filters.createdAfter);
+    boundBefore(searchOptions.createdBefore, parsed.filters.createdBefore);
+    boundAfter(searchOptions.dueAfter, parsed.filters.dueAfter);
+    boundBefore(searchOptions.dueBefore, parsed.filters.dueBefore);
+    // `kind:` keeps results to those sources, within any includePaths given.
+    if (parsed.hasKindFilter) {
+        std::vector<QString> kindPaths;
+        for (const QString& kind : parsed.kinds) {
+            const QString bundleIdentifier = kindBundleIdentifier(kind);
+            if (bundleIdentifier.isEmpty()) {
+                return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                             QStringLiteral("Unknown kind '%1'").arg(kind));
+            }
+            const QString prefix = SpotlightDonation::bundlePath(bundleIdentifier)
+                                   + QLatin1Char('/');
+            const bool included = searchOptions.includePaths.empty()
+                || std::any_of(searchOptions.includePaths.begin(),
+                               searchOptions.includePaths.end(),
+                               [&](const QString& path) { return prefix.startsWith(path); });
+            if (included) {
+                kindPaths.push_back(prefix);
+            }
         }
-    }
-
-    for (const QString& parsedType : parsed.filters.fileTypes) {
-        addFileTypeFilter(parsedType);
+        if (kindPaths.empty()) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::InvalidParams,
+                QStringLiteral("kind:%1 is outside filters.includePaths")
+                    .arg(parsed.kinds.join(QStringLiteral(","))));
+        }
+        searchOptions.includePaths = std::move(kindPaths);
+    }
+    if (interpretation.has_value()) {
+        for (const QString& type : interpretation->fileTypes) {
+            addFileTypeFilter(type);
+        }
+        boundAfter(interpretation->created ? searchOptions.createdAfter
+                                           : searchOptions.modifiedAfter,
+                   interpretation->dates.after);
+        boundBefore(interpretation->created ? searchOptions.createdBefore
+                                            : searchOptions.modifiedBefore,
+                    interpretation->dates.before);
+    }
+    // A named scope (`scope:` in the query wins over the param) confines the
+    // filters, including the ones in the query itself.
+    const QString scopeId = !parsed.scope.isEmpty()
+        ? parsed.scope
+        : params.value(QStringLiteral("scope")).toString().trimmed();
+    std::optional<SearchScope> searchScope;
+    if (!scopeId.isEmpty()) {
+        searchScope = searchScopes().find(scopeId);
+        if (!searchScope.has_value()) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::InvalidParams,
+                QStringLiteral("Unknown search scope '%1'").arg(scopeId));
+        }
+        if (!searchScope->narrow(searchOptions)) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::InvalidParams,
+                QStringLiteral("filters.includePaths are all outside scope '%1'")
+                    .arg(searchScope->id));
+        }
+    }
+    if (filterOnlyQuery) {
+        QJsonObject response = handleTagOnlySearch(id, searchOptions, limit);
+        if (interpretation.has_value()) {
+            QJsonObject result = response.value(QStringLiteral("result")).toObject();
+            result[QStringLiteral("interpretation")] = interpretation->toJson();
+            response[QStringLiteral("result")] = result;
+        }
+        return response;
+    }
+    // Filters the user asked for, before the location planner adds its own.
+    const bool requestedFilters = searchOptions.hasFilters();
+    // "saf" -> Safari: installed apps match by name, ahead of file hits.
+    QJsonArray applicationMatches;
+    if (params.value(QStringLiteral("applications")).toBool(true) && !requestedFilters
+        && !searchScope.has_value() && !interpretation.has_value()
+        && !instantAnswer.has_value()) {
+        applicationMatches = matchApplications(normalizedQueryBeforeParse);
+    }
+    // "bluetooth", "night shift" -> the System Settings pane, by deep link.
+    QJsonArray settingsMatches;
+    if (params.value(QStringLiteral("settings")).toBool(true) && !requestedFilters
+        && !searchScope.has_value() && !interpretation.has_value()
+        && !instantAnswer.has_value()) {
+        settingsMatches = settingsPaneMatches(normalizedQueryBeforeParse, kMaxSettingsMatches);
     }
 
     // Parse context
@@ -1633,12 +2276,16 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         readBoolSetting(QStringLiteral("dualEmbeddingFusionEnabled"), true);
     const int strongEmbeddingTopK = std::max(1, readIntSetting(QStringLiteral("strongEmbeddingTopK"), 40));
     const int fastEmbeddingTopK = std::max(1, readIntSetting(QStringLiteral("fastEmbeddingTopK"), 60));
-    const int semanticBudgetMs = std::max(20, readIntSetting(QStringLiteral("semanticBudgetMs"), 70));
+    const int semanticBudgetMs = clampToDeadline(
+        std::max(20, readIntSetting(QStringLiteral("semanticBudgetMs"), 70)));
     const bool rerankerCascadeEnabled = readBoolSetting(QStringLiteral("rerankerCascadeEnabled"), true);
-    const int rerankBudgetMs = std::max(40, readIntSetting(QStringLiteral("rerankBudgetMs"), 120));
+    const int rerankBudgetMs = clampToDeadline(
+        std::max(40, readIntSetting(QStringLiteral("rerankBudgetMs"), 120)));
     const int rerankerStage1Max = std::max(4, readIntSetting(QStringLiteral("rerankerStage1Max"), 40));
     const int rerankerStage2Max = std::max(4, readIntSetting(QStringLiteral("rerankerStage2Max"), 12));
     const bool personalizedLtrEnabled = readBoolSetting(QStringLiteral("personalizedLtrEnabled"), true);
+    const bool recentDocumentsSignalEnabled =
+        readBoolSetting(QStringLiteral("recentDocumentsSignalEnabled"), true);
     const bool learningEnabled = readBoolSetting(QStringLiteral("learningEnabled"), false);
     QString onlineRankerRolloutMode = readStringSetting(
         QStringLiteral("onlineRankerRolloutMode"),
@@ -1753,6 +2400,10 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
     const QString queryLower = query.toLower();
     const QueryHints queryHints = parseQueryHints(queryLower);
 
+    parseSpan.end();
+    phases.mark(QStringLiteral("parse"));
+    Span planSpan(QStringLiteral("search.plan"));
+
     // Stage 0: Query understanding (rules engine)
     const StructuredQuery structured = RulesEngine::analyze(originalRawQuery);
 
@@ -1798,7 +2449,8 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
     const QString desktopPath = homePath + QStringLiteral("/Desktop");
     const QString downloadsPath = homePath + QStringLiteral("/Downloads");
 
-    if (!hasUserProvidedFilters) {
+    // A scope is an explicit filter: the planner must not widen it.
+    if (!hasUserProvidedFilters && !searchScope.has_value()) {
         if (queryHints.documentsHint || queryHints.desktopHint || queryHints.downloadsHint) {
             plannerReason = QStringLiteral("query_location_hint");
             if (queryHints.documentsHint) {
@@ -1825,7 +2477,7 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
     const bool hasSearchFilters = searchOptions.hasFilters();
 
     LOG_INFO(bsIpc, "Search: query='%s' limit=%d mode=%d",
-             qPrintable(query), limit, static_cast<int>(queryMode));
+             privateQuery ? "<private>" : qPrintable(query), limit, static_cast<int>(queryMode));
 
     // Build cache key from normalized query + mode + filters
     QString cacheKey = query + QStringLiteral("|")
@@ -1844,20 +2496,83 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         sortedPaths.sort();
         cacheKey += QStringLiteral("|ip:") + sortedPaths.join(QStringLiteral(","));
     }
+    if (!searchOptions.excludePaths.empty()) {
+        QStringList sortedPaths;
+        sortedPaths.reserve(static_cast<int>(searchOptions.excludePaths.size()));
+        for (const auto& p : searchOptions.excludePaths) { sortedPaths.append(p); }
+        sortedPaths.sort();
+        cacheKey += QStringLiteral("|xp:") + sortedPaths.join(QStringLiteral(","));
+    }
+    if (!searchOptions.tags.empty()) {
+        QStringList sortedTags;
+        sortedTags.reserve(static_cast<int>(searchOptions.tags.size()));
+        for (const auto& t : searchOptions.tags) { sortedTags.append(t.toLower()); }
+        sortedTags.sort();
+        cacheKey += QStringLiteral("|tg:") + sortedTags.join(QStringLiteral(","));
+    }
+    // "modified:today" is a different range tomorrow: key on the bounds.
+    const std::pair<const char*, std::optional<double>> dateBounds[] = {
+        {"|ma:", searchOptions.modifiedAfter}, {"|mb:", searchOptions.modifiedBefore},
+        {"|ca:", searchOptions.createdAfter}, {"|cb:", searchOptions.createdBefore},
+        {"|da:", searchOptions.dueAfter}, {"|db:", searchOptions.dueBefore}};
+    for (const auto& [label, bound] : dateBounds) {
+        if (bound.has_value()) {
+            cacheKey += QLatin1String(label) + QString::number(bound.value(), 'f', 3);
+        }
+    }
+    // The frontmost app boosts the files it opens, so each app ranks apart.
+    if (context.frontmostAppBundleId.has_value()) {
+        const QString app = context.frontmostAppBundleId->trimmed().toLower();
+        if (!app.isEmpty()) {
+            cacheKey += QStringLiteral("|app:") + app;
+        }
+    }
 
-    // Check cache (skip for debug requests — callers expect fresh data)
-    if (!debugRequested) {
+    // Check cache (skip for debug and noCache requests — callers expect fresh data)
+    if (!cacheBypassed) {
         auto cached = m_queryCache.get(cacheKey);
         if (cached.has_value()) {
             QJsonObject cachedResult = cached.value();
             cachedResult[QStringLiteral("cached")] = true;
+            // "2*3" and "2 3" normalize alike; the answer follows the raw text.
+            if (instantAnswer.has_value()) {
+                cachedResult[QStringLiteral("instantAnswer")] = instantAnswer->toJson();
+            } else {
+                cachedResult.remove(QStringLiteral("instantAnswer"));
+            }
+            if (!definitionsWanted) {
+                cachedResult.remove(QStringLiteral("definition"));
+            }
+            // Launches since the result was cached reorder the apps.
+            if (applicationMatches.isEmpty()) {
+                cachedResult.remove(QStringLiteral("applications"));
+            } else {
+                cachedResult[QStringLiteral("applications")] = applicationMatches;
+            }
+            if (settingsMatches.isEmpty()) {
+                cachedResult.remove(QStringLiteral("settings"));
+            }
+            planSpan.setAttribute(QStringLiteral("cached"), true);
+            if (privateQuery) {
+                cachedResult[QStringLiteral("private")] = true;
+            } else if (!m_liveQueryRefreshActive) {
+                recordQueryHistory(originalRawQuery,
+                                   cachedResult.value(QStringLiteral("totalMatches")).toInt());
+            }
             return IpcMessage::makeResponse(id, cachedResult);
         }
     }
+    planSpan.setAttribute(QStringLiteral("cached"), false);
+    planSpan.end();
+    phases.mark(QStringLiteral("plan"));
+    Span executeSpan(QStringLiteral("search.execute"));
+    Span lexicalSpan(QStringLiteral("search.lexical"));
 
     QElapsedTimer timer;
     timer.start();
 
+    ScopedQueryDeadline queryDeadline(*m_store, deadlineAtMs);
+
     // Overquery for ranking: fetch limit * 2 from strict FTS5
     int ftsLimit = limit * 2;
     std::vector<SQLiteStore::FtsHit> hits;
@@ -2250,6 +2965,9 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
 
     // Apply multi-signal ranking (M1 base scoring)
     m_scorer.rankResults(results, context);
+    lexicalSpan.setAttribute(QStringLiteral("candidates"), static_cast<qint64>(results.size()));
+    lexicalSpan.end();
+    phases.mark(QStringLiteral("lexical"));
 
     std::unordered_set<int64_t> lexicalItemIds;
     lexicalItemIds.reserve(results.size());
@@ -2258,6 +2976,7 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
     }
 
     // M2: Semantic search + merge
+    Span semanticSpan(QStringLiteral("search.semantic"));
     const auto itemPassesSearchOptions = [&](const SQLiteStore::ItemRow& item) {
         if (!searchOptions.includePaths.empty()) {
             bool insideIncludedRoot = false;
@@ -2300,6 +3019,23 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
             && item.modifiedAt > searchOptions.modifiedBefore.value()) {
             return false;
         }
+        if (searchOptions.createdAfter.has_value()
+            && item.createdAt < searchOptions.createdAfter.value()) {
+            return false;
+        }
+        if (searchOptions.createdBefore.has_value()
+            && item.createdAt > searchOptions.createdBefore.value()) {
+            return false;
+        }
+        if (searchOptions.dueAfter.has_value() || searchOptions.dueBefore.has_value()) {
+            bool hasDue = false;
+            const double due = m_store->getItemAttributeValue(
+                item.id, QString::fromLatin1(SearchOptions::kDueDateAttribute)).toDouble(&hasDue);
+            if (!hasDue || due < searchOptions.dueAfter.value_or(due)
+                || due > searchOptions.dueBefore.value_or(due)) {
+                return false;
+            }
+        }
         if (searchOptions.minSizeBytes.has_value()
             && item.size < searchOptions.minSizeBytes.value()) {
             return false;
@@ -2309,6 +3045,15 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
             return false;
         }
 
+        if (!searchOptions.tags.empty()) {
+            const QStringList itemTags = m_store->getItemTags(item.id);
+            for (const QString& tag : searchOptions.tags) {
+                if (!itemTags.contains(tag, Qt::CaseInsensitive)) {
+                    return false;
+                }
+            }
+        }
+
         return true;
     };
 
@@ -2812,7 +3557,12 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         return highSemantic >= 3 && lowSemantic >= 3;
     };
 
+    semanticSpan.setAttribute(QStringLiteral("candidates"), static_cast<qint64>(results.size()));
+    semanticSpan.end();
+    phases.mark(QStringLiteral("semantic"));
+
     // Cross-encoder reranking (soft boost, before M2 boosts)
+    Span rerankSpan(QStringLiteral("search.rerank"));
     const int elapsedBeforeRerankMs = static_cast<int>(timer.elapsed());
     if (inferenceRerankOffloadActive && rerankerCascadeEnabled) {
         const float stage1Weight = static_cast<float>(std::max(
@@ -2925,7 +3675,11 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         m_crossEncoderReranker->rerank(originalRawQuery, results, rerankerConfig);
     }
 
+    rerankSpan.end();
+    phases.mark(QStringLiteral("rerank"));
+
     // StructuredQuery signal boosts (soft — rules engine only, nluConfidence=0.0)
+    Span boostSpan(QStringLiteral("search.boost"));
     {
         const auto& weights = m_scorer.weights();
         for (auto& candidate : results) {
@@ -2991,6 +3745,51 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
             || ext == QLatin1String("log");
     };
     int clipboardSignalBoostedResults = 0;
+    int recentDocumentBoostedResults = 0;
+    if (recentDocumentsSignalEnabled) {
+        refreshRecentDocuments();
+    }
+    // Revealing a result or copying its path is acting on it too; the
+    // interaction boost only follows opens.
+    std::unordered_map<int64_t, SQLiteStore::ActionCounts> actionCounts;
+    {
+        std::vector<int64_t> resultItemIds;
+        resultItemIds.reserve(results.size());
+        for (const auto& sr : results) {
+            resultItemIds.push_back(sr.itemId);
+        }
+        actionCounts = m_store->getActionCountsBatch(
+            resultItemIds,
+            static_cast<double>(QDateTime::currentSecsSinceEpoch()) - 30.0 * 86400.0);
+    }
+    int actionBoostedResults = 0;
+    // Calendar events rank by how close they are to now rather than by
+    // recency, and lift the documents their notes link to.
+    std::unordered_map<int64_t, double> eventTimeBoosts;
+    QSet<QString> eventLinkedPaths;
+    {
+        const QString calendarBundle = QString::fromLatin1(CalendarSource::kBundleIdentifier);
+        for (const auto& sr : results) {
+            if (!SpotlightDonation::isDonatedPath(sr.path)
+                || SpotlightDonation::bundleIdentifier(sr.path) != calendarBundle) {
+                continue;
+            }
+            double start = 0.0;
+            double end = 0.0;
+            for (const ItemAttribute& attribute : m_store->getItemAttributes(sr.itemId)) {
+                if (attribute.name == QLatin1String(CalendarSource::kStartAttribute)) {
+                    start = attribute.value.toDouble();
+                } else if (attribute.name == QLatin1String(CalendarSource::kEndAttribute)) {
+                    end = attribute.value.toDouble();
+                } else if (attribute.name
+                           == QLatin1String(CalendarSource::kLinkedDocumentAttribute)) {
+                    eventLinkedPaths.insert(attribute.value);
+                }
+            }
+            eventTimeBoosts[sr.itemId] = m_scorer.computeEventTimeBoost(start, end);
+        }
+    }
+    int eventBoostedResults = 0;
     bool ltrApplied = false;
     double ltrDeltaTop10 = 0.0;
     QString ltrModelVersion = QStringLiteral("unavailable");
@@ -3019,8 +3818,36 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         if (m_typeAffinity) {
             feedbackBoost += m_typeAffinity->getBoost(sr.path);
         }
+        if (recentDocumentsSignalEnabled) {
+            const double recentScore = m_recentDocuments.score(sr.path);
+            if (recentScore > 0.0) {
+                feedbackBoost += recentScore
+                    * static_cast<double>(m_scorer.weights().recentDocumentBoostWeight);
+                ++recentDocumentBoostedResults;
+            }
+        }
+        const auto actionIt = actionCounts.find(sr.itemId);
+        if (actionIt != actionCounts.end()) {
+            const double actionBoost = m_scorer.computeActionBoost(
+                actionIt->second.reveals, actionIt->second.copies, actionIt->second.lastActedAt);
+            if (actionBoost > 0.0) {
+                feedbackBoost += actionBoost;
+                ++actionBoostedResults;
+            }
+        }
+        if (eventLinkedPaths.contains(sr.path)) {
+            feedbackBoost += static_cast<double>(m_scorer.weights().eventLinkBoostWeight);
+            ++eventBoostedResults;
+        }
         sr.scoreBreakdown.feedbackBoost = feedbackBoost;
 
+        const auto eventIt = eventTimeBoosts.find(sr.itemId);
+        if (eventIt != eventTimeBoosts.end()) {
+            sr.score += eventIt->second - sr.scoreBreakdown.recencyBoost;
+            sr.scoreBreakdown.recencyBoost = eventIt->second;
+            ++eventBoostedResults;
+        }
+
         if (naturalLanguageQuery && sr.semanticNormalized > 0.0) {
             const bool semanticOnly = lexicalItemIds.find(sr.itemId) == lexicalItemIds.end();
             const double normalizedSemantic = std::clamp(sr.semanticNormalized, 0.0, 1.0);
@@ -3185,12 +4012,44 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         return a.itemId < b.itemId;
     });
 
+    boostSpan.end();
+    phases.mark(QStringLiteral("boost"));
+
+    // Messages are scoped to their conversation: its best-ranked match
+    // stands for the rest, which are counted on it, so one chatty thread
+    // cannot fill the page.
+    std::unordered_map<int64_t, int> conversationMatches;
+    {
+        QHash<QString, int64_t> conversationLeaders;
+        std::vector<SearchResult> collapsed;
+        collapsed.reserve(results.size());
+        for (auto& sr : results) {
+            if (MessagesSource::isLocalOnly(sr.path)) {
+                const QString conversation = m_store->getItemAttributeValue(
+                    sr.itemId, QString::fromLatin1(MessagesSource::kConversationAttribute));
+                if (!conversation.isEmpty()) {
+                    const auto leader = conversationLeaders.constFind(conversation);
+                    if (leader != conversationLeaders.cend()) {
+                        ++conversationMatches[leader.value()];
+                        continue;
+                    }
+                    conversationLeaders.insert(conversation, sr.itemId);
+                    conversationMatches[sr.itemId] = 1;
+                }
+            }
+            collapsed.push_back(std::move(sr));
+        }
+        results = std::move(collapsed);
+    }
+
     // Truncate to the requested limit
     if (static_cast<int>(results.size()) > limit) {
         results.resize(static_cast<size_t>(limit));
     }
+    executeSpan.setAttribute(QStringLiteral("results"), static_cast<qint64>(results.size()));
+    executeSpan.setAttribute(QStringLiteral("elapsedMs"), static_cast<qint64>(timer.elapsed()));
 
-    if (learningEnabled && m_learningEngine) {
+    if (learningEnabled && m_learningEngine && !privateQuery) {
         const int exposureLimit = std::min<int>(20, static_cast<int>(results.size()));
         const float semanticNeedForExposure =
             std::clamp(structured.semanticNeedScore, 0.0f, 1.0f);
@@ -3205,55 +4064,62 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
 
     // Serialize results to JSON array
     QJsonArray resultsArray;
+    std::vector<int64_t> resultItemIds;
+    resultItemIds.reserve(results.size());
     for (const auto& sr : results) {
-        QJsonObject metadata;
-        metadata[QStringLiteral("fileSize")] = static_cast<qint64>(sr.fileSize);
-        metadata[QStringLiteral("modificationDate")] = sr.modificationDate;
-
-        QJsonObject frequency;
-        frequency[QStringLiteral("openCount")] = sr.openCount;
-        frequency[QStringLiteral("lastOpenDate")] = sr.lastOpenDate;
-
-        QJsonObject obj;
-        obj[QStringLiteral("itemId")] = static_cast<qint64>(sr.itemId);
-        obj[QStringLiteral("path")] = sr.path;
-        obj[QStringLiteral("name")] = sr.name;
-        obj[QStringLiteral("kind")] = sr.kind;
-        obj[QStringLiteral("matchType")] = matchTypeToString(sr.matchType);
-        obj[QStringLiteral("score")] = sr.score;
-        obj[QStringLiteral("bm25Raw")] = sr.bm25RawScore;
-
-        QString plainSnippet = sr.snippet;
-        plainSnippet.replace(QStringLiteral("<b>"), QString());
-        plainSnippet.replace(QStringLiteral("</b>"), QString());
-        obj[QStringLiteral("snippet")] = plainSnippet;
-
-        QJsonArray highlightsArray;
-        for (const auto& highlight : sr.highlights) {
-            QJsonObject highlightObj;
-            highlightObj[QStringLiteral("offset")] = highlight.offset;
-            highlightObj[QStringLiteral("length")] = highlight.length;
-            highlightsArray.append(highlightObj);
-        }
-        obj[QStringLiteral("highlights")] = highlightsArray;
-        obj[QStringLiteral("metadata")] = metadata;
-        obj[QStringLiteral("isPinned")] = sr.isPinned;
-        obj[QStringLiteral("frequency")] = frequency;
-        const auto availability = m_store->getItemAvailability(sr.itemId);
-        if (availability.has_value()) {
-            obj[QStringLiteral("contentAvailable")] = availability->contentAvailable;
-            obj[QStringLiteral("availabilityStatus")] = availability->availabilityStatus;
-        } else {
-            obj[QStringLiteral("contentAvailable")] = true;
-            obj[QStringLiteral("availabilityStatus")] = QStringLiteral("available");
+        QJsonObject json = searchResultJson(sr);
+        const auto matchesIt = conversationMatches.find(sr.itemId);
+        if (matchesIt != conversationMatches.end()) {
+            json[QStringLiteral("conversationMatches")] = matchesIt->second;
         }
-        resultsArray.append(obj);
+        resultsArray.append(json);
+        resultItemIds.push_back(sr.itemId);
     }
 
     QJsonObject result;
     result[QStringLiteral("results")] = resultsArray;
     result[QStringLiteral("queryTime")] = static_cast<int>(timer.elapsed());
     result[QStringLiteral("totalMatches")] = totalMatches;
+    result[QStringLiteral("facets")] = searchFacetsJson(resultItemIds);
+    const bool deadlineExceeded =
+        deadlineAtMs > 0 && QDateTime::currentMSecsSinceEpoch() >= deadlineAtMs;
+    if (deadlineExceeded) {
+        result[QStringLiteral("deadlineExceeded")] = true;
+    }
+    if (searchScope.has_value()) {
+        result[QStringLiteral("scope")] = QJsonObject{
+            {QStringLiteral("id"), searchScope->id},
+            {QStringLiteral("name"), searchScope->name},
+        };
+    }
+    if (interpretation.has_value()) {
+        result[QStringLiteral("interpretation")] = interpretation->toJson();
+    }
+    if (instantAnswer.has_value()) {
+        result[QStringLiteral("instantAnswer")] = instantAnswer->toJson();
+    }
+    if (!applicationMatches.isEmpty()) {
+        result[QStringLiteral("applications")] = applicationMatches;
+    }
+    if (!settingsMatches.isEmpty()) {
+        result[QStringLiteral("settings")] = settingsMatches;
+    }
+    // A lone word that matches little on disk is probably a word to look up:
+    // offer the dictionary entry, as Spotlight's "Define" row does.
+    if (definitionsWanted && !instantAnswer.has_value() && !interpretation.has_value()
+        && !searchScope.has_value() && !requestedFilters && applicationMatches.isEmpty()
+        && settingsMatches.isEmpty()
+        && resultsArray.size() < kDefinitionMaxFileHits
+        && DictionaryLookup::isDefinableQuery(normalizedQueryBeforeParse)) {
+        if (const auto definition = dictionaryDefinition(normalizedQueryBeforeParse)) {
+            result[QStringLiteral("definition")] = definition->toJson();
+        }
+    }
+    if (!abbreviationsApplied.isEmpty()) {
+        result[QStringLiteral("expandedQuery")] = normalizedQueryBeforeParse;
+        result[QStringLiteral("abbreviationsApplied")] =
+            QJsonArray::fromStringList(abbreviationsApplied);
+    }
 
     if (rewriteDecision.applied) {
         m_rewriteAppliedCount.fetch_add(1);
@@ -3489,6 +4355,13 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
              || context.clipboardExtension.has_value());
         debugInfo[QStringLiteral("clipboardSignalBoostedResults")] =
             clipboardSignalBoostedResults;
+        debugInfo[QStringLiteral("recentDocumentsSignalEnabled")] = recentDocumentsSignalEnabled;
+        debugInfo[QStringLiteral("recentDocumentsKnown")] = m_recentDocuments.size();
+        debugInfo[QStringLiteral("recentDocumentBoostedResults")] = recentDocumentBoostedResults;
+        debugInfo[QStringLiteral("eventBoostedResults")] = eventBoostedResults;
+        debugInfo[QStringLiteral("messageConversations")] =
+            static_cast<int>(conversationMatches.size());
+        debugInfo[QStringLiteral("actionBoostedResults")] = actionBoostedResults;
         QJsonArray parsedTypes;
         for (const QString& extractedType : parsed.extractedTypes) {
             parsedTypes.append(normalizeFileTypeToken(extractedType));
@@ -3511,12 +4384,29 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
             excludePathsDebug.append(excludePath);
         }
         filtersDebug[QStringLiteral("excludePaths")] = excludePathsDebug;
+        QJsonArray tagsDebug;
+        for (const QString& tag : searchOptions.tags) {
+            tagsDebug.append(tag);
+        }
+        filtersDebug[QStringLiteral("tags")] = tagsDebug;
         if (searchOptions.modifiedAfter.has_value()) {
             filtersDebug[QStringLiteral("modifiedAfter")] = searchOptions.modifiedAfter.value();
         }
         if (searchOptions.modifiedBefore.has_value()) {
             filtersDebug[QStringLiteral("modifiedBefore")] = searchOptions.modifiedBefore.value();
         }
+        if (searchOptions.createdAfter.has_value()) {
+            filtersDebug[QStringLiteral("createdAfter")] = searchOptions.createdAfter.value();
+        }
+        if (searchOptions.createdBefore.has_value()) {
+            filtersDebug[QStringLiteral("createdBefore")] = searchOptions.createdBefore.value();
+        }
+        if (searchOptions.dueAfter.has_value()) {
+            filtersDebug[QStringLiteral("dueAfter")] = searchOptions.dueAfter.value();
+        }
+        if (searchOptions.dueBefore.has_value()) {
+            filtersDebug[QStringLiteral("dueBefore")] = searchOptions.dueBefore.value();
+        }
         if (searchOptions.minSizeBytes.has_value()) {
             filtersDebug[QStringLiteral("minSize")] =
                 static_cast<double>(searchOptions.minSizeBytes.value());
@@ -3585,8 +4475,62 @@ QJsonObject QueryService::handleSearch(uint64_t id, const QJsonObject& params)
         result[QStringLiteral("debugInfo")] = debugInfo;
     }
 
-    // Store in cache (skip debug requests)
-    if (!debugRequested) {
+    phases.mark(QStringLiteral("finish"));
+    m_slowQueryLog.setThresholdMs(
+        readIntSetting(QStringLiteral("slowQueryThresholdMs"),
+                       SlowQueryLogConfig().thresholdMs));
+    if (!privateQuery && m_slowQueryLog.isSlow(phases.elapsedMs())) {
+        QJsonObject plan;
+        plan[QStringLiteral("normalizedQuery")] = query;
+        plan[QStringLiteral("queryMode")] = searchQueryModeToString(queryMode);
+        plan[QStringLiteral("queryClass")] = queryClassToString(queryClass);
+        plan[QStringLiteral("queryDomain")] = queryDomainToString(queryDomain);
+        plan[QStringLiteral("routerApplied")] = routerApplied;
+        if (params.contains(QStringLiteral("filters"))) {
+            plan[QStringLiteral("filters")] = params.value(QStringLiteral("filters"));
+        }
+        if (parsed.hasTypeHint) {
+            plan[QStringLiteral("typeHints")] = QJsonArray::fromStringList(parsed.extractedTypes);
+        }
+        if (!rewrittenRelaxedQuery.isEmpty() && rewrittenRelaxedQuery != query) {
+            plan[QStringLiteral("rewrittenQuery")] = rewrittenRelaxedQuery;
+        }
+        plan[QStringLiteral("lexicalStrictHits")] = strictHitsCount;
+        plan[QStringLiteral("lexicalRelaxedHits")] = relaxedHitsCount;
+        plan[QStringLiteral("semanticCandidates")] = static_cast<int>(semanticResults.size());
+        plan[QStringLiteral("dualIndexUsed")] = dualIndexUsed;
+        plan[QStringLiteral("rerankDepth")] = rerankDepthApplied;
+        plan[QStringLiteral("semanticBudgetMs")] = semanticBudgetMs;
+        plan[QStringLiteral("rerankBudgetMs")] = rerankBudgetMs;
+        plan[QStringLiteral("limit")] = limit;
+        plan[QStringLiteral("results")] = resultsArray.size();
+        plan[QStringLiteral("totalMatches")] = totalMatches;
+        plan[QStringLiteral("deadlineExceeded")] = deadlineExceeded;
+
+        SlowQueryLog::Entry entry;
+        entry.timestamp = static_cast<double>(QDateTime::currentMSecsSinceEpoch()) / 1000.0;
+        entry.query = originalRawQuery;
+        entry.elapsedMs = phases.elapsedMs();
+        entry.phases = phases.toJson();
+        entry.plan = plan;
+        if (m_slowQueryLog.record(std::move(entry))) {
+            LOG_INFO(bsIpc, "Slow search: %.0f ms (threshold %d ms)",
+                     phases.elapsedMs(), m_slowQueryLog.thresholdMs());
+        }
+    }
+
+    // History shares the connection; a search that ran out of time must
+    // still be recorded rather than interrupted.
+    queryDeadline.release();
+    if (!privateQuery && !m_liveQueryRefreshActive) {
+        recordQueryHistory(originalRawQuery, totalMatches);
+    }
+
+    // Store in cache (skip debug requests, deadline-truncated results and
+    // private searches)
+    if (privateQuery) {
+        result[QStringLiteral("private")] = true;
+    } else if (!debugRequested && !deadlineExceeded) {
         m_queryCache.put(cacheKey, result);
     }
 
@@ -4079,6 +5023,7 @@ QJsonObject QueryService::handleGetHealthInternal(uint64_t id, bool includeIndex
     QJsonObject queueBulkhead;
     QString queueSource = QStringLiteral("unavailable");
     QJsonArray queueRoots;
+    QJsonObject queueAccess;
 
     if (includeIndexerQueueProbe) {
         // Compatibility path (legacy getHealth/getHealthV2): pull direct queue status from indexer.
@@ -4116,6 +5061,7 @@ QJsonObject QueryService::handleGetHealthInternal(uint64_t id, bool includeIndex
                 queueActorMode = queueResult.value(QStringLiteral("actorMode")).toString(QStringLiteral("legacy"));
                 queueBulkhead = queueResult.value(QStringLiteral("bulkhead")).toObject();
                 queueRoots = queueResult.value(QStringLiteral("roots")).toArray();
+                queueAccess = queueResult.value(QStringLiteral("access")).toObject();
                 queueSource = QStringLiteral("indexer_rpc");
             }
         }
@@ -4139,6 +5085,9 @@ QJsonObject QueryService::handleGetHealthInternal(uint64_t id, bool includeIndex
     } else if (health.criticalFailures > 0) {
         overallStatus = QStringLiteral("degraded");
         healthStatusReason = QStringLiteral("degraded_critical_failures");
+    } else if (!queueAccess.value(QStringLiteral("unreadableRoots")).toArray().isEmpty()) {
+        overallStatus = QStringLiteral("degraded");
+        healthStatusReason = QStringLiteral("degraded_roots_unreadable");
     }
 
     QJsonObject indexHealth;
@@ -4219,6 +5168,7 @@ QJsonObject QueryService::handleGetHealthInternal(uint64_t id, bool includeIndex
     indexHealth[QStringLiteral("activeVectorDimensions")] = m_activeVectorDimensions;
     indexHealth[QStringLiteral("recentErrors")] = recentErrors;
     indexHealth[QStringLiteral("indexRoots")] = queueRoots;
+    indexHealth[QStringLiteral("fileAccess")] = queueAccess;
     indexHealth[QStringLiteral("vectorRebuildStatus")] =
         vectorRebuildStatusToString(rebuildStateCopy.status);
     indexHealth[QStringLiteral("vectorRebuildRunId")] =
@@ -5156,6 +6106,14 @@ QJsonObject QueryService::handleRecordFeedback(uint64_t id, const QJsonObject& p
     QString query = params.value(QStringLiteral("query")).toString();
     int position = params.value(QStringLiteral("position")).toInt(0);
 
+    // Feedback on a private search is acknowledged and dropped.
+    if (params.value(QStringLiteral("private")).toBool(false)) {
+        QJsonObject result;
+        result[QStringLiteral("recorded")] = false;
+        result[QStringLiteral("private")] = true;
+        return IpcMessage::makeResponse(id, result);
+    }
+
     // Record feedback in the feedback table
     if (!m_store->recordFeedback(itemId, action, query, position)) {
         LOG_WARN(bsIpc, "Failed to insert feedback row for item %lld",
@@ -5228,4 +6186,243 @@ QJsonObject QueryService::handleGetFrequency(uint64_t id, const QJsonObject& par
     return IpcMessage::makeResponse(id, result);
 }
 
+QJsonObject QueryService::handleSuggest(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const QString prefix = params.value(QStringLiteral("prefix")).toString().trimmed();
+    if (prefix.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'prefix' parameter"));
+    }
+    const int limit = std::clamp(params.value(QStringLiteral("limit")).toInt(10), 1, 50);
+
+    QJsonArray suggestions;
+    for (const auto& hit : m_store->suggestByNamePrefix(prefix, limit * 2)) {
+        if (isExcludedByBsignore(hit.path)) {
+            continue;
+        }
+        QJsonObject entry;
+        entry[QStringLiteral("itemId")] = static_cast<qint64>(hit.fileId);
+        entry[QStringLiteral("name")] = hit.name;
+        entry[QStringLiteral("path")] = hit.path;
+        suggestions.append(entry);
+        if (suggestions.size() >= limit) {
+            break;
+        }
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("prefix")] = prefix;
+    result[QStringLiteral("suggestions")] = suggestions;
+    if (params.value(QStringLiteral("includeHistory")).toBool(true)) {
+        result[QStringLiteral("queries")] = queryHistorySuggestions(prefix, limit);
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleCompletePaths(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    // An empty prefix is valid: it streams the whole index (fzf feed).
+    const QString prefix = params.value(QStringLiteral("prefix")).toString();
+    const int limit = std::clamp(params.value(QStringLiteral("limit")).toInt(1000), 1, 5000);
+
+    SQLiteStore::PathCompletionCursor cursor;
+    const QJsonValue cursorValue = params.value(QStringLiteral("cursor"));
+    if (cursorValue.isObject()) {
+        const QJsonObject cursorObject = cursorValue.toObject();
+        cursor.frequentTaken = cursorObject.value(QStringLiteral("frequentTaken")).toInt(-1);
+        cursor.afterPath = cursorObject.value(QStringLiteral("afterPath")).toString();
+    } else if (!cursorValue.isUndefined() && !cursorValue.isNull()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'cursor' must be an object"));
+    }
+
+    const SQLiteStore::PathCompletionPage page = m_store->completePaths(prefix, limit, cursor);
+
+    // Excluded paths are dropped after paging, so a page may come back
+    // short; callers page until nextCursor is null.
+    QJsonArray candidates;
+    for (const auto& candidate : page.candidates) {
+        if (isExcludedByBsignore(candidate.path)) {
+            continue;
+        }
+        QJsonObject entry;
+        entry[QStringLiteral("itemId")] = static_cast<qint64>(candidate.fileId);
+        entry[QStringLiteral("path")] = candidate.path;
+        entry[QStringLiteral("kind")] = candidate.kind;
+        entry[QStringLiteral("openCount")] = candidate.openCount;
+        candidates.append(entry);
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("prefix")] = prefix;
+    result[QStringLiteral("candidates")] = candidates;
+    if (page.next.has_value()) {
+        QJsonObject next;
+        next[QStringLiteral("frequentTaken")] = page.next->frequentTaken;
+        next[QStringLiteral("afterPath")] = page.next->afterPath;
+        result[QStringLiteral("nextCursor")] = next;
+    } else {
+        result[QStringLiteral("nextCursor")] = QJsonValue(QJsonValue::Null);
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleGetDocument(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    std::optional<SQLiteStore::ItemRow> item;
+    if (params.contains(QStringLiteral("itemId"))) {
+        item = m_store->getItemById(
+            static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger()));
+    } else if (params.contains(QStringLiteral("path"))) {
+        item = m_store->getItemByPath(
+            QDir::cleanPath(params.value(QStringLiteral("path")).toString()));
+    } else {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'itemId' or 'path' parameter"));
+    }
+
+    if (!item.has_value() || isExcludedByBsignore(item->path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+
+    const auto toIso = [](double epochSecs) {
+        return epochSecs > 0.0
+            ? QDateTime::fromMSecsSinceEpoch(static_cast<qint64>(epochSecs * 1000.0))
+                  .toUTC().toString(Qt::ISODate)
+            : QString();
+    };
+
+    QJsonObject result;
+    result[QStringLiteral("itemId")] = static_cast<qint64>(item->id);
+    result[QStringLiteral("path")] = item->path;
+    result[QStringLiteral("name")] = item->name;
+    result[QStringLiteral("kind")] = item->kind;
+    result[QStringLiteral("fileSize")] = static_cast<qint64>(item->size);
+    result[QStringLiteral("modificationDate")] = toIso(item->modifiedAt);
+    result[QStringLiteral("indexedDate")] = toIso(item->indexedAt);
+    result[QStringLiteral("isPinned")] = item->isPinned;
+
+    if (const auto availability = m_store->getItemAvailability(item->id)) {
+        result[QStringLiteral("contentAvailable")] = availability->contentAvailable;
+        result[QStringLiteral("availabilityStatus")] = availability->availabilityStatus;
+        if (!availability->lastExtractionError.isEmpty()) {
+            result[QStringLiteral("lastExtractionError")] = availability->lastExtractionError;
+        }
+    }
+    if (const auto freq = m_store->getFrequency(item->id)) {
+        result[QStringLiteral("openCount")] = freq->openCount;
+        result[QStringLiteral("lastOpenDate")] = toIso(freq->lastOpenedAt);
+    } else {
+        result[QStringLiteral("openCount")] = 0;
+    }
+    QJsonObject attributes;
+    for (const ItemAttribute& attribute : m_store->getItemAttributes(item->id)) {
+        QJsonArray values = attributes.value(attribute.name).toArray();
+        values.append(attribute.value);
+        attributes[attribute.name] = values;
+    }
+    if (!attributes.isEmpty()) {
+        result[QStringLiteral("attributes")] = attributes;
+    }
+    if (params.value(QStringLiteral("includeContent")).toBool(false)) {
+        const int maxChars = std::clamp(
+            params.value(QStringLiteral("maxContentChars")).toInt(20000), 1, 200000);
+        bool truncated = false;
+        result[QStringLiteral("content")] = m_store->getItemContent(item->id, maxChars, &truncated);
+        result[QStringLiteral("contentTruncated")] = truncated;
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleFindSimilar(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureM2ModulesInitialized()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    std::optional<SQLiteStore::ItemRow> item;
+    if (params.contains(QStringLiteral("itemId"))) {
+        item = m_store->getItemById(
+            static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger()));
+    } else if (params.contains(QStringLiteral("path"))) {
+        item = m_store->getItemByPath(
+            QDir::cleanPath(params.value(QStringLiteral("path")).toString()));
+    } else {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'itemId' or 'path' parameter"));
+    }
+    if (!item.has_value() || isExcludedByBsignore(item->path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    const int limit = std::clamp(params.value(QStringLiteral("limit")).toInt(10), 1, 50);
+
+    // Embed the document's leading text the same way the indexer embedded it.
+    const QString seedText = m_store->getItemContent(item->id, 2000);
+    if (seedText.trimmed().isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document has no extracted content"));
+    }
+
+    std::shared_lock<std::shared_mutex> lock(m_vectorIndexMutex);
+    if (!m_vectorIndex || !m_vectorIndex->isAvailable() || !m_vectorStore
+        || !m_embeddingManager || !m_embeddingManager->isAvailable()) {
+        return IpcMessage::makeError(id, IpcErrorCode::Unsupported,
+                                     QStringLiteral("Semantic index is not available"));
+    }
+    const std::vector<float> embedding = m_embeddingManager->embed(seedText);
+    if (embedding.empty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to embed document"));
+    }
+
+    const std::string generationId = m_activeVectorGeneration.toStdString();
+    const auto hits = m_vectorIndex->search(embedding.data(), limit * 2 + 1);
+    QJsonArray similar;
+    QSet<int64_t> seen;
+    for (const auto& hit : hits) {
+        if (similar.size() >= limit) {
+            break;
+        }
+        const auto itemId = m_vectorStore->getItemId(hit.label, generationId);
+        if (!itemId.has_value() || itemId.value() == item->id || seen.contains(itemId.value())) {
+            continue;
+        }
+        seen.insert(itemId.value());
+        const auto neighbor = m_store->getItemById(itemId.value());
+        if (!neighbor.has_value() || isExcludedByBsignore(neighbor->path)) {
+            continue;
+        }
+        QJsonObject entry;
+        entry[QStringLiteral("itemId")] = static_cast<qint64>(neighbor->id);
+        entry[QStringLiteral("path")] = neighbor->path;
+        entry[QStringLiteral("name")] = neighbor->name;
+        entry[QStringLiteral("similarity")] = static_cast<double>(1.0f - hit.distance);
+        similar.append(entry);
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("itemId")] = static_cast<qint64>(item->id);
+    result[QStringLiteral("path")] = item->path;
+    result[QStringLiteral("results")] = similar;
+    return IpcMessage::makeResponse(id, result);
+}
+
 } // namespace bs
@@ -1,22 +1,38 @@
 #pragma once
 
+#include "core/indexing/peer_sync.h"
+#include "core/ipc/api_tokens.h"
+#include "core/ipc/http_server.h"
 #include "core/ipc/service_base.h"
 #include "core/index/sqlite_store.h"
 #include "core/index/typo_lexicon.h"
+#include "core/fs/app_catalog.h"
 #include "core/fs/bsignore_parser.h"
+#include "core/fs/recent_documents.h"
+#include "core/fs/thumbnail_cache.h"
 #include "core/ranking/scorer.h"
+#include "core/query/dictionary_lookup.h"
+#include "core/query/query_abbreviations.h"
 #include "core/query/query_cache.h"
+#include "core/query/search_scopes.h"
+#include "core/query/slow_query_log.h"
+#include "core/query/team_index.h"
+#include "core/shared/metrics.h"
 
 #include <atomic>
+#include <map>
 #include <memory>
 #include <mutex>
 #include <optional>
+#include <QDateTime>
 #include <QFileSystemWatcher>
 #include <QHash>
+#include <QJsonArray>
 #include <QTimer>
 #include <QStringList>
 #include <shared_mutex>
 #include <thread>
+#include <vector>
 
 namespace bs {
 
@@ -26,6 +42,7 @@ class EmbeddingPipeline;
 class SocketClient;
 class ModelRegistry;
 class InteractionTracker;
+class QueryHistory;
 class FeedbackAggregator;
 class PathPreferences;
 class TypeAffinity;
@@ -44,16 +61,59 @@ public:
 
 protected:
     QJsonObject handleRequest(const QJsonObject& request) override;
+    bool isAdminMethod(const QString& method) const override;
+    bool isAuditedQueryMethod(const QString& method) const override;
+    QJsonObject auditQueryDetails(const QJsonObject& params,
+                                  const QJsonObject& response) const override;
+    std::optional<QJsonObject> scopedTokenScope(const QByteArray& token) const override;
 
 private:
+    QJsonObject routeRequest(const QJsonObject& request, const QString& method, uint64_t id,
+                             const QJsonObject& params);
+
     // ── M1 handlers ──
     QJsonObject handleSearch(uint64_t id, const QJsonObject& params);
+    QJsonObject handleExportMatches(uint64_t id, const QJsonObject& params);
     QJsonObject handleGetAnswerSnippet(uint64_t id, const QJsonObject& params);
     QJsonObject handleGetHealth(uint64_t id);
     QJsonObject handleGetQueryHealthV3(uint64_t id);
     QJsonObject handleGetHealthDetails(uint64_t id, const QJsonObject& params);
     QJsonObject handleRecordFeedback(uint64_t id, const QJsonObject& params);
     QJsonObject handleGetFrequency(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSuggest(uint64_t id, const QJsonObject& params);
+    QJsonObject handleCompletePaths(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetDocument(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetThumbnail(uint64_t id, const QJsonObject& params);
+    QJsonObject handlePerformAction(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetRecentActions(uint64_t id, const QJsonObject& params);
+    QJsonObject handleRepeatAction(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetAbbreviations(uint64_t id);
+    QJsonObject handleSetAbbreviation(uint64_t id, const QJsonObject& params);
+    QJsonObject handleRemoveAbbreviation(uint64_t id, const QJsonObject& params);
+    QJsonObject handleListApplications(uint64_t id);
+    QJsonObject handleLaunchApp(uint64_t id, const QJsonObject& params);
+    QJsonObject handleListSearchScopes(uint64_t id);
+    QJsonObject handleSetSearchScope(uint64_t id, const QJsonObject& params);
+    QJsonObject handleRemoveSearchScope(uint64_t id, const QJsonObject& params);
+    QJsonObject handleFindSimilar(uint64_t id, const QJsonObject& params);
+    QJsonObject handleSubscribeQuery(uint64_t id, const QJsonObject& params);
+    QJsonObject handleUnsubscribeQuery(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetReadiness(uint64_t id);
+    QJsonObject handlePurgePrivacyPaths(uint64_t id, const QJsonObject& params);
+    QJsonObject handlePurgeOrphans(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetAuditLog(uint64_t id, const QJsonObject& params);
+    QJsonObject handlePurgeAuditLog(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetSlowQueries(uint64_t id, const QJsonObject& params);
+    QJsonObject handleClearSlowQueries(uint64_t id);
+    QJsonObject handleGetQueryHistory(uint64_t id, const QJsonObject& params);
+    QJsonObject handlePurgeQueryHistory(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetIndexBreakdown(uint64_t id, const QJsonObject& params);
+    QJsonObject handleCreateApiToken(uint64_t id, const QJsonObject& params);
+    QJsonObject handleListApiTokens(uint64_t id);
+    QJsonObject handleRevokeApiToken(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetSyncSegments(uint64_t id);
+    QJsonObject handleGetSyncSegment(uint64_t id, const QJsonObject& params);
+    QJsonObject handleGetSyncDelta(uint64_t id, const QJsonObject& params);
 
     // ── M2 handlers ──
     QJsonObject handleRecordInteraction(uint64_t id, const QJsonObject& params);
@@ -66,14 +126,94 @@ private:
     QJsonObject handleGetLearningHealth(uint64_t id);
     QJsonObject handleSetLearningConsent(uint64_t id, const QJsonObject& params);
     QJsonObject handleTriggerLearningCycle(uint64_t id, const QJsonObject& params);
+    // Embeddings left behind by deleted items, in both vector indexes.
+    // `erase` zeroes them and saves the indexes; either way `remaining`
+    // counts what is left afterwards.
+    struct VectorSweep {
+        int erased = 0;
+        int remaining = 0;
+        bool saved = true;
+    };
+    VectorSweep sweepVectorResidue(bool erase);
     void runVectorRebuildWorker(uint64_t runId, QString dbPath, QString dataDir,
                                 QString modelsDir,
                                 QString indexPath, QString metaPath,
                                 QString targetGeneration,
                                 QStringList includePaths);
 
+    // Query history (query_service_history.cpp). recordQueryHistory() also
+    // applies the retention setting, at most once an hour.
+    int queryHistoryRetentionDays();
+    void recordQueryHistory(const QString& query, int resultCount);
+    QJsonArray queryHistorySuggestions(const QString& prefix, int limit);
+
+    // User abbreviations (query_service_abbreviations.cpp), read from the
+    // settings table on first use.
+    const QueryAbbreviations& queryAbbreviations();
+    bool saveQueryAbbreviations(const QueryAbbreviations& abbreviations);
+
+    // Named search scopes (query_service_scopes.cpp), read the same way.
+    const SearchScopes& searchScopes();
+    bool saveSearchScopes(const SearchScopes& scopes);
+
+    // Installed applications (query_service_apps.cpp): rescanned when the
+    // last scan is more than five minutes old; launch counts persist in the
+    // settings table.
+    static constexpr int kMaxApplicationMatches = 3;
+    void refreshAppCatalog();
+    QJsonArray matchApplications(const QString& query);
+
+    // System Settings panes offered alongside applications.
+    static constexpr int kMaxSettingsMatches = 3;
+
+    // System dictionary entries (query_service_dictionary.cpp), remembered
+    // per word, misses included, for the life of the process.
+    static constexpr int kDefinitionMaxFileHits = 3;
+    static constexpr int kDefinitionCacheSize = 256;
+    std::optional<DictionaryDefinition> dictionaryDefinition(const QString& word);
+
+    // Index entries another Mac may replicate (query_service_sync.cpp):
+    // files with ids in [firstId, lastId], narrowed to the roots of the
+    // scoped token making the request.
+    std::vector<SyncedItem> syncableItems(int64_t firstId, int64_t lastId);
+
+    // `search` over the local index, the federated_indexes setting's and
+    // the team_indexes setting's (query_service_federated.cpp);
+    // handleSearch() alone when there are none, when `federated` is false,
+    // and for scoped tokens.
+    QJsonObject handleFederatedSearch(uint64_t id, const QJsonObject& params);
+    QString m_federatedConfigError;  // last one logged
+    QString m_teamConfigError;       // last one logged
+
+    // A team index's read-only store, reopened when its file is replaced;
+    // null while the file is missing or will not open.
+    SQLiteStore* teamStore(const TeamIndex& index);
+    struct TeamStore {
+        QString path;
+        QDateTime modified;
+        qint64 size = -1;
+        std::optional<SQLiteStore> store;
+    };
+    std::map<QString, TeamStore> m_teamStores;  // by index name
+
+    // Re-reads other apps' recent-document lists when the last read is more
+    // than a minute old (query_service_m2.cpp).
+    void refreshRecentDocuments();
+
     // ── Store + services ──
     std::optional<SQLiteStore> m_store;
+    std::unique_ptr<QueryHistory> m_queryHistory;  // created with the store
+    qint64 m_queryHistoryCleanedAtMs = 0;
+    QueryAbbreviations m_queryAbbreviations;
+    bool m_queryAbbreviationsLoaded = false;
+    SearchScopes m_searchScopes;
+    bool m_searchScopesLoaded = false;
+    QHash<QString, std::optional<DictionaryDefinition>> m_definitionCache;
+    AppCatalog m_appCatalog;
+    qint64 m_appCatalogScannedAtMs = 0;
+    bool m_appLaunchesLoaded = false;
+    RecentDocuments m_recentDocuments;
+    qint64 m_recentDocumentsReadAtMs = 0;
     TypoLexicon m_typoLexicon;
     Scorer m_scorer;
 
@@ -148,6 +288,121 @@ private:
     void refreshVectorGenerationState();
     void maybeStartBackgroundVectorMigration();
 
+    // Optional REST bridge (BETTERSPOTLIGHT_HTTP_PORT): loopback by default,
+    // or HTTPS on BETTERSPOTLIGHT_HTTP_BIND for remote clients.
+    void initHttpApi();
+    HttpResponse dispatchHttp(const HttpRequest& request, const QString& method,
+                              const QJsonObject& params);
+    // GET /v1/raycast/search: a fast first page, then the full ranking,
+    // both as Raycast-shaped sections (see RaycastView).
+    std::optional<HttpResponse> startRaycastSearch(const HttpRequest& request, quint64 streamId,
+                                                   const std::optional<ApiTokenScope>& scope);
+    std::unique_ptr<HttpServer> m_httpServer;
+    // Messages never leave the app's own UI (MessagesSource::isLocalOnly):
+    // they are dropped from every HTTP response, whichever token asked, and
+    // from everything a scoped token sees. A single local-only document
+    // reads as "not indexed".
+    static QJsonArray withoutLocalOnlyEntries(const QJsonArray& entries);
+    static QJsonObject withoutLocalOnly(const QJsonObject& response);
+    // Whether params name a local-only document by itemId or path.
+    bool targetsLocalOnly(const QJsonObject& params);
+
+    // Prometheus metrics (query_service_metrics.cpp): getMetrics and, with
+    // BETTERSPOTLIGHT_METRICS=1, GET /metrics on the HTTP API. Latency is
+    // recorded per method for the search-path requests, since start and
+    // over the last 1, 5 and 15 minutes (getQueryStats).
+    QJsonObject handleGetMetrics(uint64_t id);
+    QJsonObject handleGetQueryStats(uint64_t id);
+    QByteArray renderMetrics();
+    // [{"window": "1m", "seconds": 60, "requests", "errors", "cacheHitRate",
+    //   "methods": {"search": {...}, ...}}, ...]
+    QJsonArray requestWindowsJson() const;
+    static bool isTimedMethod(const QString& method);
+    void recordRequestMetrics(const QString& method, double seconds,
+                              const QJsonObject& response);
+    std::map<QString, LatencyHistogram> m_requestLatency;
+    std::map<QString, qint64> m_requestErrors;
+    std::map<QString, WindowedLatency> m_requestWindows;
+
+    // Scoped API tokens (query_service_tokens.cpp). A request whose `auth`
+    // field names a token is checked against that token's scope before it
+    // is dispatched, and its result is filtered to the scope afterwards.
+    QJsonObject handleScopedRequest(const QJsonObject& request);
+    std::optional<QJsonObject> scopeDenial(uint64_t id, const QString& method,
+                                           const QJsonObject& params,
+                                           const ApiTokenScope& scope);
+    static QJsonObject scopedResponse(const QJsonObject& response, const ApiTokenScope& scope);
+    // Bearer-token check for HTTP routes. Nullopt lets the request through;
+    // *auth is then set for a scoped token and empty for full access.
+    std::optional<HttpResponse> authorizeHttp(const HttpRequest& request,
+                                              std::optional<QJsonObject>* auth) const;
+    // authorizeHttp() plus the scope check, for routes that run a handler
+    // directly instead of through handleRequest(). The scoped token's
+    // `auth` object and scope are returned through the optional pointers.
+    std::optional<HttpResponse> authorizeHttpDirect(const HttpRequest& request,
+                                                    const QString& method,
+                                                    const QJsonObject& params,
+                                                    std::optional<QJsonObject>* auth,
+                                                    std::optional<ApiTokenScope>* scope);
+    ApiTokenStore m_apiTokens;
+    std::optional<ApiTokenScope> m_requestScope;  // set while a scoped request runs
+
+    // Live queries: re-run subscribed searches when the index changes and
+    // push the delta (liveQueryUpdated notification or SSE event).
+    struct LiveQuerySubscription {
+        QJsonObject searchParams;
+        std::vector<int64_t> itemIds;  // order of the last delivered results
+        quint64 httpStreamId = 0;      // 0 = IPC subscriber
+        std::optional<ApiTokenScope> scope;
+    };
+    static constexpr int kMaxLiveQueries = 32;
+    void initLiveQueries();
+    std::optional<QJsonObject> createLiveQuery(const QJsonObject& params,
+                                               quint64 httpStreamId, QJsonObject* error);
+    void removeLiveQuery(quint64 subscriptionId);
+    void pollLiveQueries();
+    qint64 readIndexDataVersion() const;
+    QHash<quint64, LiveQuerySubscription> m_liveQueries;
+    quint64 m_nextLiveQueryId = 1;
+    qint64 m_liveQueryDataVersion = -1;
+    bool m_liveQueryRefreshActive = false;
+    QTimer m_liveQueryTimer;
+
+    // Readiness (/healthz, /readyz, getReadiness). Progress is sampled from
+    // the indexer's processed count to detect a stuck queue.
+    QJsonObject readinessSnapshot();
+    const qint64 m_startedAtMs = QDateTime::currentMSecsSinceEpoch();
+    qint64 m_readinessLastProcessed = -1;
+    qint64 m_readinessProgressAtMs = 0;
+
+    // Document thumbnails (getThumbnail, GET /v1/documents/<id>/thumbnail).
+    // Returns nullopt with *error set to an IPC error envelope.
+    struct DocumentThumbnail {
+        int64_t itemId = 0;
+        int pixelSize = 0;
+        QByteArray png;
+        bool cached = false;
+    };
+    std::optional<DocumentThumbnail> documentThumbnail(uint64_t id, const QJsonObject& params,
+                                                       QJsonObject* error);
+    std::unique_ptr<ThumbnailCache> m_thumbnailCache;
+
+    // The `filters` object of search/exportMatches params as SearchOptions
+    // (file types and paths normalized and de-duplicated).
+    static SearchOptions searchOptionsFromFilters(const QJsonObject& filters);
+
+    // One search result as its `results[]` JSON object.
+    QJsonObject searchResultJson(const SearchResult& sr);
+    // The `facets` object of a search response: tag counts over the items.
+    QJsonObject searchFacetsJson(const std::vector<int64_t>& itemIds);
+    // A query of only `tag:` tokens (plus filters): tagged items, most
+    // recently modified first, unranked.
+    QJsonObject handleTagOnlySearch(uint64_t id, const SearchOptions& options, int limit);
+    // `search` with a Spotlight `predicate`: translated into query text and
+    // filters (see SpotlightPredicate); filter-only predicates list like
+    // handleTagOnlySearch.
+    QJsonObject handlePredicateSearch(uint64_t id, const QJsonObject& params);
+
     // Opens the store if not already open. Returns true on success.
     bool ensureStoreOpen();
     bool ensureM2ModulesInitialized();
@@ -208,6 +463,7 @@ private:
     QHash<QString, qint64> m_inferenceFallbackCountByRole;
 
     QueryCache m_queryCache;
+    SlowQueryLog m_slowQueryLog;
 };
 
 } // namespace bs
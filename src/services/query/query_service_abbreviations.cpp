@@ -0,0 +1,126 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QJsonDocument>
+#include <QJsonParseError>
+
+namespace bs {
+
+const QueryAbbreviations& QueryService::queryAbbreviations()
+{
+    if (m_queryAbbreviationsLoaded || !m_store.has_value()) {
+        return m_queryAbbreviations;
+    }
+    m_queryAbbreviationsLoaded = true;
+    const QString raw = m_store->getSetting(QString::fromLatin1(QueryAbbreviations::kSettingKey))
+                            .value_or(QString());
+    if (raw.isEmpty()) {
+        return m_queryAbbreviations;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument doc = QJsonDocument::fromJson(raw.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !doc.isObject()) {
+        LOG_WARN(bsIpc, "Ignoring unreadable %s setting: %s", QueryAbbreviations::kSettingKey,
+                 qUtf8Printable(parseError.errorString()));
+        return m_queryAbbreviations;
+    }
+    int skipped = 0;
+    m_queryAbbreviations = QueryAbbreviations::fromJson(doc.object(), &skipped);
+    if (skipped > 0) {
+        LOG_WARN(bsIpc, "Skipped %d invalid query abbreviations", skipped);
+    }
+    return m_queryAbbreviations;
+}
+
+bool QueryService::saveQueryAbbreviations(const QueryAbbreviations& abbreviations)
+{
+    const QString json = QString::fromUtf8(
+        QJsonDocument(abbreviations.toJson()).toJson(QJsonDocument::Compact));
+    if (!m_store->setSetting(QString::fromLatin1(QueryAbbreviations::kSettingKey), json)) {
+        return false;
+    }
+    m_queryAbbreviations = abbreviations;
+    m_queryAbbreviationsLoaded = true;
+    // Cached results were ranked for the old expansions.
+    m_queryCache.clear();
+    return true;
+}
+
+QJsonObject QueryService::handleGetAbbreviations(uint64_t id)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    QJsonObject result;
+    result[QStringLiteral("abbreviations")] = queryAbbreviations().toJson();
+    result[QStringLiteral("maxEntries")] = QueryAbbreviations::kMaxEntries;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleSetAbbreviation(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    if (!params.value(QStringLiteral("term")).isString()
+        || !params.value(QStringLiteral("expansion")).isString()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'term' or 'expansion' parameter"));
+    }
+
+    QueryAbbreviations updated = queryAbbreviations();
+    const QString term = params.value(QStringLiteral("term")).toString();
+    QString error;
+    if (!updated.set(term, params.value(QStringLiteral("expansion")).toString(), &error)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!saveQueryAbbreviations(updated)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to save abbreviations"));
+    }
+
+    const QString key = QueryAbbreviations::normalizeTerm(term);
+    LOG_INFO(bsIpc, "Query abbreviation '%s' set", qUtf8Printable(key));
+    QJsonObject result;
+    result[QStringLiteral("term")] = key;
+    result[QStringLiteral("expansion")] = updated.entries().value(key);
+    result[QStringLiteral("count")] = updated.size();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleRemoveAbbreviation(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    const bool all = params.value(QStringLiteral("all")).toBool(false);
+    if (!all && !params.value(QStringLiteral("term")).isString()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'term' parameter"));
+    }
+
+    QueryAbbreviations updated = queryAbbreviations();
+    const int before = updated.size();
+    if (all) {
+        updated.clear();
+    } else if (!updated.remove(params.value(QStringLiteral("term")).toString())) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("No such abbreviation"));
+    }
+    if (!saveQueryAbbreviations(updated)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to save abbreviations"));
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("removed")] = before - updated.size();
+    result[QStringLiteral("count")] = updated.size();
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
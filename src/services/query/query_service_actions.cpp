@@ -0,0 +1,235 @@
+#include "query_service.h"
+
+#include "core/indexing/spotlight_donation.h"
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+#include "core/shared/result_action.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonArray>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultRecentActions = 20;
+constexpr int kMaxRecentActions = 200;
+
+QJsonObject actionToJson(const SQLiteStore::ActionRow& row, bool includeQuery)
+{
+    QJsonObject json;
+    json[QStringLiteral("actionId")] = static_cast<qint64>(row.id);
+    json[QStringLiteral("action")] = row.action;
+    json[QStringLiteral("itemId")] = static_cast<qint64>(row.itemId);
+    json[QStringLiteral("path")] = row.path;
+    json[QStringLiteral("name")] = row.name;
+    if (includeQuery) {
+        json[QStringLiteral("query")] = row.query;
+    }
+    json[QStringLiteral("position")] = row.position;
+    json[QStringLiteral("at")] = QDateTime::fromMSecsSinceEpoch(
+        static_cast<qint64>(row.timestamp * 1000.0)).toUTC().toString(Qt::ISODate);
+    return json;
+}
+
+} // namespace
+
+QJsonObject QueryService::handlePerformAction(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const QString actionName = params.value(QStringLiteral("action")).toString();
+    const std::optional<ResultAction> action = resultActionFromString(actionName);
+    if (!action.has_value()) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("Unknown action '%1' (expected open, reveal, quicklook or copyPath)")
+                .arg(actionName));
+    }
+
+    // Only indexed items: this is not a general-purpose launcher.
+    std::optional<SQLiteStore::ItemRow> item;
+    if (params.contains(QStringLiteral("itemId"))) {
+        item = m_store->getItemById(
+            static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger()));
+    } else if (params.contains(QStringLiteral("path"))) {
+        const QString path = params.value(QStringLiteral("path")).toString();
+        item = m_store->getItemByPath(SpotlightDonation::isDonatedPath(path)
+                                          ? path
+                                          : QDir::cleanPath(path));
+    } else {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'itemId' or 'path' parameter"));
+    }
+    if (!item.has_value() || isExcludedByBsignore(item->path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    // Donated items have no file: open and copy use the app's url.
+    QString target = item->path;
+    if (SpotlightDonation::isDonatedPath(item->path)) {
+        if (action.value() == ResultAction::Reveal || action.value() == ResultAction::QuickLook) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::Unsupported,
+                QStringLiteral("Donated items can only be opened or copied"));
+        }
+        const QString url = m_store->getItemAttributeValue(
+            item->id, QString::fromLatin1(SpotlightDonation::kUrlAttribute));
+        if (url.isEmpty() && action.value() == ResultAction::Open) {
+            return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                         QStringLiteral("Donated item has no url to open"));
+        }
+        if (!url.isEmpty()) {
+            target = url;
+        }
+    } else if (action.value() != ResultAction::CopyPath && !QFileInfo::exists(item->path)) {
+        // Copying a path that no longer resolves is still useful; launching is not.
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("File no longer exists: %1").arg(item->path));
+    }
+
+    QString error;
+    if (!runResultActionCommand(resultActionCommand(action.value(), target), &error)) {
+        LOG_WARN(bsIpc, "performAction %s failed for '%s': %s",
+                 qUtf8Printable(resultActionToString(action.value())),
+                 qUtf8Printable(item->path), qUtf8Printable(error));
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError, error);
+    }
+
+    // An action on a private search's result runs but does not count.
+    const bool privateQuery = params.value(QStringLiteral("private")).toBool(false);
+    const QString query = params.value(QStringLiteral("query")).toString();
+    const int position = params.value(QStringLiteral("position")).toInt(0);
+    if (!privateQuery
+        && !m_store->recordFeedback(item->id, resultActionToString(action.value()), query,
+                                    position)) {
+        LOG_WARN(bsIpc, "Failed to insert feedback row for item %lld",
+                 static_cast<long long>(item->id));
+    }
+    const bool selection = !privateQuery && resultActionCountsAsSelection(action.value());
+    if (selection) {
+        if (!m_store->incrementFrequency(item->id)) {
+            LOG_WARN(bsIpc, "Failed to update frequency for item %lld",
+                     static_cast<long long>(item->id));
+        }
+    }
+    if (!privateQuery) {
+        // Frequency and the action boost feed the score; cached rankings
+        // are stale now.
+        m_queryCache.clear();
+    }
+
+    LOG_INFO(bsIpc, "performAction %s on item %lld",
+             qUtf8Printable(resultActionToString(action.value())),
+             static_cast<long long>(item->id));
+
+    QJsonObject result;
+    result[QStringLiteral("action")] = resultActionToString(action.value());
+    result[QStringLiteral("itemId")] = static_cast<qint64>(item->id);
+    result[QStringLiteral("path")] = item->path;
+    if (target != item->path) {
+        result[QStringLiteral("url")] = target;
+    }
+    result[QStringLiteral("frequencyUpdated")] = selection;
+    return IpcMessage::makeResponse(id, result);
+}
+
+// Newest first. Scoped tokens only see actions on documents under their
+// roots, and not the queries they came from (other clients typed those).
+QJsonObject QueryService::handleGetRecentActions(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const int limit = params.value(QStringLiteral("limit")).toInt(kDefaultRecentActions);
+    if (limit < 1 || limit > kMaxRecentActions) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'limit' must be between 1 and %1").arg(kMaxRecentActions));
+    }
+    QStringList actions;
+    if (params.contains(QStringLiteral("action"))) {
+        const QString actionName = params.value(QStringLiteral("action")).toString();
+        const std::optional<ResultAction> action = resultActionFromString(actionName);
+        if (!action.has_value()) {
+            return IpcMessage::makeError(
+                id, IpcErrorCode::InvalidParams,
+                QStringLiteral("Unknown action '%1' (expected open, reveal, quicklook or copyPath)")
+                    .arg(actionName));
+        }
+        actions.append(resultActionToString(action.value()));
+    }
+    const int64_t itemId =
+        static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger(0));
+
+    // Filtering by root happens after the query, so fetch the most a scoped
+    // caller could need.
+    const bool scoped = m_requestScope.has_value();
+    QJsonArray entries;
+    for (const auto& row : m_store->getRecentActions(scoped ? kMaxRecentActions : limit, actions,
+                                                     itemId)) {
+        if (scoped && !m_requestScope->coversPath(row.path)) {
+            continue;
+        }
+        entries.append(actionToJson(row, !scoped));
+        if (entries.size() >= limit) {
+            break;
+        }
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("actions")] = entries;
+    return IpcMessage::makeResponse(id, result);
+}
+
+// Runs a recorded action again through performAction, so it is checked and
+// recorded like the original: the file must still be indexed and exist.
+QJsonObject QueryService::handleRepeatAction(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    std::optional<SQLiteStore::ActionRow> row;
+    if (params.contains(QStringLiteral("actionId"))) {
+        row = m_store->getAction(
+            static_cast<int64_t>(params.value(QStringLiteral("actionId")).toInteger()));
+    } else {
+        for (const auto& candidate : m_store->getRecentActions(kMaxRecentActions)) {
+            if (!m_requestScope.has_value() || m_requestScope->coversPath(candidate.path)) {
+                row = candidate;
+                break;
+            }
+        }
+    }
+    // Outside the roots reads like a missing action, as for documents.
+    if (!row.has_value()
+        || (m_requestScope.has_value() && !m_requestScope->coversPath(row->path))) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Action not found"));
+    }
+
+    QJsonObject actionParams;
+    actionParams[QStringLiteral("action")] = row->action;
+    actionParams[QStringLiteral("itemId")] = static_cast<qint64>(row->itemId);
+    actionParams[QStringLiteral("query")] = row->query;
+    actionParams[QStringLiteral("position")] = row->position;
+    actionParams[QStringLiteral("private")] = params.value(QStringLiteral("private")).toBool(false);
+    QJsonObject response = handlePerformAction(id, actionParams);
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return response;
+    }
+    QJsonObject result = response.value(QStringLiteral("result")).toObject();
+    result[QStringLiteral("repeatedActionId")] = static_cast<qint64>(row->id);
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
@@ -0,0 +1,152 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+#include "core/shared/result_action.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QJsonParseError>
+
+namespace bs {
+
+namespace {
+
+constexpr qint64 kAppCatalogRefreshIntervalMs = 5 * 60 * 1000;
+
+QJsonObject appJson(const AppEntry& entry, int launchCount)
+{
+    QJsonObject json;
+    json[QStringLiteral("name")] = entry.name;
+    json[QStringLiteral("path")] = entry.path;
+    if (!entry.bundleId.isEmpty()) {
+        json[QStringLiteral("bundleId")] = entry.bundleId;
+    }
+    json[QStringLiteral("kind")] = QStringLiteral("application");
+    json[QStringLiteral("launchCount")] = launchCount;
+    return json;
+}
+
+} // namespace
+
+void QueryService::refreshAppCatalog()
+{
+    if (!m_appLaunchesLoaded && m_store.has_value()) {
+        m_appLaunchesLoaded = true;
+        const QString raw =
+            m_store->getSetting(QString::fromLatin1(AppCatalog::kLaunchesSettingKey))
+                .value_or(QString());
+        if (!raw.isEmpty()) {
+            QJsonParseError parseError;
+            const QJsonDocument doc = QJsonDocument::fromJson(raw.toUtf8(), &parseError);
+            if (parseError.error == QJsonParseError::NoError && doc.isObject()) {
+                m_appCatalog.setLaunches(doc.object());
+            } else {
+                LOG_WARN(bsIpc, "Ignoring unreadable %s setting: %s",
+                         AppCatalog::kLaunchesSettingKey,
+                         qUtf8Printable(parseError.errorString()));
+            }
+        }
+    }
+
+    const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+    if (m_appCatalogScannedAtMs > 0
+        && nowMs - m_appCatalogScannedAtMs < kAppCatalogRefreshIntervalMs) {
+        return;
+    }
+    m_appCatalogScannedAtMs = nowMs;
+    m_appCatalog.setEntries(AppCatalog::scan(AppCatalog::defaultRoots(QDir::homePath())));
+    LOG_DEBUG(bsIpc, "App catalog: %d applications",
+              static_cast<int>(m_appCatalog.entries().size()));
+}
+
+QJsonArray QueryService::matchApplications(const QString& query)
+{
+    refreshAppCatalog();
+    QJsonArray matches;
+    const qint64 nowSecs = QDateTime::currentSecsSinceEpoch();
+    for (const AppCatalog::Match& match :
+         m_appCatalog.match(query, kMaxApplicationMatches, nowSecs)) {
+        QJsonObject json = appJson(match.entry, match.launchCount);
+        if (match.matchedName != match.entry.name) {
+            json[QStringLiteral("matchedName")] = match.matchedName;
+        }
+        json[QStringLiteral("score")] = match.score;
+        matches.append(json);
+    }
+    return matches;
+}
+
+QJsonObject QueryService::handleListApplications(uint64_t id)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    refreshAppCatalog();
+    QJsonArray applications;
+    for (const AppEntry& entry : m_appCatalog.entries()) {
+        QJsonObject json = appJson(entry, m_appCatalog.launch(entry).count);
+        if (!entry.alternateNames.isEmpty()) {
+            json[QStringLiteral("alternateNames")] =
+                QJsonArray::fromStringList(entry.alternateNames);
+        }
+        applications.append(json);
+    }
+    QJsonObject result;
+    result[QStringLiteral("applications")] = applications;
+    result[QStringLiteral("count")] = applications.size();
+    result[QStringLiteral("scannedAt")] =
+        QDateTime::fromMSecsSinceEpoch(m_appCatalogScannedAtMs).toUTC().toString(Qt::ISODate);
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleLaunchApp(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    const QString target = params.contains(QStringLiteral("path"))
+                               ? params.value(QStringLiteral("path")).toString()
+                               : params.value(QStringLiteral("bundleId")).toString();
+    if (target.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'path' or 'bundleId' parameter"));
+    }
+    refreshAppCatalog();
+    // Only catalogued apps: this launches applications, not arbitrary paths.
+    const AppEntry* found = m_appCatalog.find(target);
+    if (!found || !QFileInfo::exists(found->path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Application is not installed: %1").arg(target));
+    }
+    const AppEntry entry = *found;
+
+    QString error;
+    if (!runResultActionCommand(resultActionCommand(ResultAction::Open, entry.path), &error)) {
+        LOG_WARN(bsIpc, "launchApp failed for '%s': %s", qUtf8Printable(entry.path),
+                 qUtf8Printable(error));
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError, error);
+    }
+
+    // A launch from a private search runs but does not count.
+    const bool privateQuery = params.value(QStringLiteral("private")).toBool(false);
+    if (!privateQuery) {
+        m_appCatalog.recordLaunch(entry, QDateTime::currentSecsSinceEpoch());
+        const QString json = QString::fromUtf8(
+            QJsonDocument(m_appCatalog.launchesToJson()).toJson(QJsonDocument::Compact));
+        if (!m_store->setSetting(QString::fromLatin1(AppCatalog::kLaunchesSettingKey), json)) {
+            LOG_WARN(bsIpc, "Failed to save app launch counts");
+        }
+    }
+    LOG_INFO(bsIpc, "launchApp %s", qUtf8Printable(entry.path));
+
+    QJsonObject result = appJson(entry, m_appCatalog.launch(entry).count);
+    result[QStringLiteral("launched")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
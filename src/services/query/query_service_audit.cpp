@@ -0,0 +1,125 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/audit_log.h"
+#include "core/shared/logging.h"
+
+#include <QJsonArray>
+#include <QSet>
+
+namespace bs {
+
+namespace {
+
+QStringList stringList(const QJsonValue& value)
+{
+    QStringList out;
+    for (const QJsonValue& entry : value.toArray()) {
+        const QString text = entry.toString().trimmed();
+        if (!text.isEmpty()) {
+            out.append(text);
+        }
+    }
+    return out;
+}
+
+} // namespace
+
+bool QueryService::isAuditedQueryMethod(const QString& method) const
+{
+    static const QSet<QString> kQueryMethods = {
+        QStringLiteral("search"),
+        QStringLiteral("suggest"),
+        QStringLiteral("exportMatches"),
+        QStringLiteral("findSimilar"),
+        QStringLiteral("subscribeQuery"),
+        QStringLiteral("getAnswerSnippet"),
+        QStringLiteral("get_answer_snippet"),
+    };
+    return kQueryMethods.contains(method);
+}
+
+// What was asked and how much came back; never the results themselves.
+QJsonObject QueryService::auditQueryDetails(const QJsonObject& params,
+                                            const QJsonObject& response) const
+{
+    static const QStringList kLoggedParams = {
+        QStringLiteral("query"),  QStringLiteral("predicate"), QStringLiteral("mode"),
+        QStringLiteral("filters"), QStringLiteral("limit"),    QStringLiteral("itemId"),
+        QStringLiteral("path"),
+    };
+    // A private search is recorded as having happened, but not what it asked.
+    const bool privateQuery = params.value(QStringLiteral("private")).toBool(false);
+    QJsonObject logged;
+    for (const QString& key : kLoggedParams) {
+        if (params.contains(key) && !privateQuery) {
+            logged[key] = params.value(key);
+        }
+    }
+    QJsonObject details = AuditLog::summarizeParams(logged);
+    if (privateQuery) {
+        details[QStringLiteral("private")] = true;
+    }
+
+    const QJsonObject result = response.value(QStringLiteral("result")).toObject();
+    if (result.value(QStringLiteral("results")).isArray()) {
+        details[QStringLiteral("resultCount")] =
+            result.value(QStringLiteral("results")).toArray().size();
+    } else if (result.value(QStringLiteral("suggestions")).isArray()) {
+        details[QStringLiteral("resultCount")] =
+            result.value(QStringLiteral("suggestions")).toArray().size();
+    }
+    return details;
+}
+
+QJsonObject QueryService::handleGetAuditLog(uint64_t id, const QJsonObject& params)
+{
+    AuditLogQuery query;
+    query.since = params.value(QStringLiteral("since")).toDouble(0.0);
+    query.until = params.value(QStringLiteral("until")).toDouble(0.0);
+    query.kinds = stringList(params.value(QStringLiteral("kinds")));
+    query.services = stringList(params.value(QStringLiteral("services")));
+    query.limit = params.value(QStringLiteral("limit")).toInt(query.limit);
+    for (const QString& kind : query.kinds) {
+        if (kind != QLatin1String("query") && kind != QLatin1String("admin")) {
+            return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                         QStringLiteral("Unknown audit kind '%1'").arg(kind));
+        }
+    }
+    if (query.limit < 1 || query.limit > AuditLog::kMaxReadLimit) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'limit' must be between 1 and %1").arg(AuditLog::kMaxReadLimit));
+    }
+
+    const QString directory = AuditLog::defaultDirectory();
+    QJsonArray entries;
+    for (const QJsonObject& entry : AuditLog::read(directory, query)) {
+        entries.append(entry);
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("enabled")] = AuditLog::isEnabled(directory);
+    result[QStringLiteral("directory")] = directory;
+    result[QStringLiteral("entries")] = entries;
+    return IpcMessage::makeResponse(id, result);
+}
+
+// The purge itself is recorded afterwards (it is an admin method), so a
+// cleared log still shows who cleared it and when, while the log is on.
+QJsonObject QueryService::handlePurgeAuditLog(uint64_t id, const QJsonObject& params)
+{
+    const double before = params.value(QStringLiteral("before")).toDouble(0.0);
+    if (before < 0.0) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'before' must be epoch seconds"));
+    }
+    const int64_t removed = AuditLog::purge(AuditLog::defaultDirectory(), before);
+    LOG_INFO(bsIpc, "Purged %lld audit log entries", static_cast<long long>(removed));
+
+    QJsonObject result;
+    result[QStringLiteral("removedEntries")] = static_cast<qint64>(removed);
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
@@ -0,0 +1,20 @@
+#include "query_service.h"
+
+namespace bs {
+
+std::optional<DictionaryDefinition> QueryService::dictionaryDefinition(const QString& word)
+{
+    const QString key = word.trimmed().toLower();
+    const auto it = m_definitionCache.constFind(key);
+    if (it != m_definitionCache.constEnd()) {
+        return it.value();
+    }
+    if (m_definitionCache.size() >= kDefinitionCacheSize) {
+        m_definitionCache.clear();
+    }
+    std::optional<DictionaryDefinition> definition = DictionaryLookup::define(key);
+    m_definitionCache.insert(key, definition);
+    return definition;
+}
+
+} // namespace bs
@@ -0,0 +1,94 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+QJsonValue isoDate(double epochSecs)
+{
+    if (epochSecs <= 0.0) {
+        return QJsonValue(QJsonValue::Null);
+    }
+    return QDateTime::fromMSecsSinceEpoch(static_cast<qint64>(epochSecs * 1000.0))
+        .toUTC()
+        .toString(Qt::ISODate);
+}
+
+} // namespace
+
+QJsonObject QueryService::handleExportMatches(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const QString query = params.value(QStringLiteral("query")).toString().trimmed();
+    if (query.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'query' parameter"));
+    }
+    const int limit = std::clamp(params.value(QStringLiteral("limit")).toInt(1000), 1, 5000);
+    // No ranking here, so "auto" has nothing to fall back from: it means strict.
+    const bool relaxed =
+        params.value(QStringLiteral("queryMode")).toString().trimmed().toLower()
+        == QLatin1String("relaxed");
+    const SearchOptions options =
+        searchOptionsFromFilters(params.value(QStringLiteral("filters")).toObject());
+
+    int64_t afterRowid = 0;
+    const QJsonValue cursorValue = params.value(QStringLiteral("cursor"));
+    if (cursorValue.isObject()) {
+        afterRowid = cursorValue.toObject().value(QStringLiteral("afterRowid")).toInteger(0);
+    } else if (!cursorValue.isUndefined() && !cursorValue.isNull()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'cursor' must be an object"));
+    }
+
+    const SQLiteStore::ExportPage page =
+        m_store->exportMatches(query, relaxed, options, limit, afterRowid);
+
+    // As with completePaths, excluded paths are dropped after paging.
+    QJsonArray items;
+    for (const auto& row : page.rows) {
+        if (isExcludedByBsignore(row.path)) {
+            continue;
+        }
+        QJsonObject entry;
+        entry[QStringLiteral("itemId")] = static_cast<qint64>(row.fileId);
+        entry[QStringLiteral("path")] = row.path;
+        entry[QStringLiteral("name")] = row.name;
+        entry[QStringLiteral("kind")] = row.kind;
+        entry[QStringLiteral("size")] = static_cast<qint64>(row.size);
+        entry[QStringLiteral("created")] = isoDate(row.createdAt);
+        entry[QStringLiteral("modified")] = isoDate(row.modifiedAt);
+        entry[QStringLiteral("indexed")] = isoDate(row.indexedAt);
+        entry[QStringLiteral("matchedFields")] = QJsonArray::fromStringList(row.matchedFields);
+        items.append(entry);
+    }
+
+    LOG_DEBUG(bsIpc, "exportMatches: query='%s' after=%lld rows=%d more=%d",
+              qUtf8Printable(query), static_cast<long long>(afterRowid),
+              static_cast<int>(page.rows.size()), page.nextAfterRowid.has_value() ? 1 : 0);
+
+    QJsonObject result;
+    result[QStringLiteral("items")] = items;
+    if (page.nextAfterRowid.has_value()) {
+        QJsonObject next;
+        next[QStringLiteral("afterRowid")] = static_cast<qint64>(page.nextAfterRowid.value());
+        result[QStringLiteral("nextCursor")] = next;
+    } else {
+        result[QStringLiteral("nextCursor")] = QJsonValue(QJsonValue::Null);
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
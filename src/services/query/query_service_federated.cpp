@@ -0,0 +1,156 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/query/federated_search.h"
+#include "core/shared/logging.h"
+
+#include <QElapsedTimer>
+#include <QFileInfo>
+#include <QJsonDocument>
+#include <QThread>
+
+#include <algorithm>
+#include <memory>
+
+namespace bs {
+
+QJsonObject QueryService::handleFederatedSearch(uint64_t id, const QJsonObject& params)
+{
+    // A scoped token's search stays on this Mac: its roots say nothing
+    // about other indexes, and a peer fanning out to us sends
+    // `federated: false` anyway.
+    if (m_requestScope.has_value() || !params.value(QStringLiteral("federated")).toBool(true)
+        || !ensureStoreOpen()) {
+        return handleSearch(id, params);
+    }
+    QString error;
+    std::optional<std::vector<FederatedIndex>> indexes = FederatedSearch::parseIndexes(
+        m_store->getSetting(QString::fromLatin1(FederatedSearch::kSettingKey)).value_or(QString()),
+        &error);
+    if (!indexes.has_value()) {
+        if (error != m_federatedConfigError) {
+            LOG_WARN(bsIpc, "Not searching federated indexes: %s", qUtf8Printable(error));
+            m_federatedConfigError = error;
+        }
+        indexes.emplace();
+    } else {
+        m_federatedConfigError.clear();
+    }
+    std::optional<std::vector<TeamIndex>> teams = TeamIndexes::parseIndexes(
+        m_store->getSetting(QString::fromLatin1(TeamIndexes::kSettingKey)).value_or(QString()),
+        &error);
+    if (teams.has_value()) {
+        // Rows are labelled by source name, so a name means one index.
+        const auto clash = std::find_if(teams->begin(), teams->end(), [&indexes](const TeamIndex& team) {
+            return std::any_of(indexes->begin(), indexes->end(), [&team](const FederatedIndex& index) {
+                return team.name.compare(index.name, Qt::CaseInsensitive) == 0;
+            });
+        });
+        if (clash != teams->end()) {
+            error = QStringLiteral("Team index %1 has the name of a federated index").arg(clash->name);
+            teams.reset();
+        }
+    }
+    if (!teams.has_value()) {
+        if (error != m_teamConfigError) {
+            LOG_WARN(bsIpc, "Not searching team indexes: %s", qUtf8Printable(error));
+            m_teamConfigError = error;
+        }
+        teams.emplace();
+    } else {
+        m_teamConfigError.clear();
+    }
+    for (auto it = m_teamStores.begin(); it != m_teamStores.end();) {
+        const bool listed = std::any_of(teams->begin(), teams->end(), [&it](const TeamIndex& team) {
+            return team.name == it->first;
+        });
+        it = listed ? std::next(it) : m_teamStores.erase(it);
+    }
+    if (indexes->empty() && teams->empty()) {
+        return handleSearch(id, params);
+    }
+
+    const QByteArray body =
+        QJsonDocument(FederatedSearch::remoteParams(params)).toJson(QJsonDocument::Compact);
+    std::vector<PeerRequest> requests;
+    for (const FederatedIndex& index : *indexes) {
+        requests.push_back({index.endpoint, QStringLiteral("/v1/search"), body, index.timeoutMs});
+    }
+    // The other indexes are asked on their own thread, with its own event
+    // loop, while this one searches; the wait afterwards is then only
+    // whatever the slowest of them needs beyond the local search.
+    std::vector<PeerReply> replies;
+    std::unique_ptr<QThread> fetcher(QThread::create([&requests, &replies]() {
+        replies = PeerClient::fetchAll(requests);
+    }));
+    fetcher->start();
+
+    QElapsedTimer localTimer;
+    localTimer.start();
+    const QJsonObject response = handleSearch(id, params);
+    const qint64 localElapsedMs = localTimer.elapsed();
+
+    // Team indexes are local files: searched here while the other indexes
+    // are still being waited for.
+    const int limit = FederatedSearch::limitOf(params);
+    const QString query = params.value(QStringLiteral("query")).toString();
+    std::vector<PeerReply> teamReplies;
+    for (const TeamIndex& team : *teams) {
+        QElapsedTimer teamTimer;
+        teamTimer.start();
+        PeerReply reply;
+        if (SQLiteStore* store = teamStore(team)) {
+            reply.json = TeamIndexes::search(*store, team, query, limit);
+        } else {
+            reply.error = QStringLiteral("Cannot open %1").arg(team.path);
+        }
+        reply.elapsedMs = teamTimer.elapsed();
+        teamReplies.push_back(std::move(reply));
+    }
+    fetcher->wait();
+
+    if (!response.contains(QStringLiteral("result"))) {
+        return response;
+    }
+    std::vector<FederatedSource> remotes;
+    for (size_t i = 0; i < indexes->size(); ++i) {
+        const FederatedIndex& index = (*indexes)[i];
+        if (!replies[i].json.has_value()) {
+            LOG_WARN(bsIpc, "Federated index %s: %s", qUtf8Printable(index.name),
+                     qUtf8Printable(replies[i].error));
+        }
+        remotes.push_back({index.name, std::move(replies[i])});
+    }
+    for (size_t i = 0; i < teams->size(); ++i) {
+        remotes.push_back({(*teams)[i].name, std::move(teamReplies[i]), true});
+    }
+    return IpcMessage::makeResponse(
+        id, FederatedSearch::merge(response.value(QStringLiteral("result")).toObject(),
+                                   localElapsedMs, remotes, limit));
+}
+
+SQLiteStore* QueryService::teamStore(const TeamIndex& index)
+{
+    const QFileInfo info(index.path);
+    if (!info.isFile()) {
+        m_teamStores.erase(index.name);
+        return nullptr;
+    }
+    TeamStore& entry = m_teamStores[index.name];
+    // A publisher replaces the file as a whole; the open connection would
+    // keep reading the old one.
+    if (entry.path != index.path || entry.modified != info.lastModified()
+        || entry.size != info.size()) {
+        entry.path = index.path;
+        entry.modified = info.lastModified();
+        entry.size = info.size();
+        entry.store = SQLiteStore::openReadOnly(index.path);
+        if (!entry.store.has_value()) {
+            LOG_WARN(bsIpc, "Team index %s: cannot open %s", qUtf8Printable(index.name),
+                     qUtf8Printable(index.path));
+        }
+    }
+    return entry.store.has_value() ? &*entry.store : nullptr;
+}
+
+} // namespace bs
@@ -0,0 +1,160 @@
+#include "query_service.h"
+
+#include "core/feedback/query_history.h"
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+#include <QTimeZone>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxHistoryLimit = 500;
+constexpr int kMaxRetentionDays = 3650;
+constexpr qint64 kHistoryCleanupIntervalMs = 60 * 60 * 1000;
+
+QJsonObject historyEntryJson(const QueryHistory::Entry& entry)
+{
+    QJsonObject json;
+    json[QStringLiteral("query")] = entry.query;
+    json[QStringLiteral("hitCount")] = entry.hitCount;
+    json[QStringLiteral("lastResultCount")] = entry.lastResultCount;
+    json[QStringLiteral("firstUsedAt")] = entry.firstUsedAt.toString(Qt::ISODate);
+    json[QStringLiteral("lastUsedAt")] = entry.lastUsedAt.toString(Qt::ISODate);
+    return json;
+}
+
+} // namespace
+
+int QueryService::queryHistoryRetentionDays()
+{
+    if (!m_store.has_value()) {
+        return QueryHistory::kDefaultRetentionDays;
+    }
+    bool ok = false;
+    const int days = m_store->getSetting(QStringLiteral("queryHistoryRetentionDays"))
+                         .value_or(QString()).toInt(&ok);
+    return ok ? std::clamp(days, 0, kMaxRetentionDays) : QueryHistory::kDefaultRetentionDays;
+}
+
+void QueryService::recordQueryHistory(const QString& query, int resultCount)
+{
+    if (!m_queryHistory) {
+        return;
+    }
+    const int retentionDays = queryHistoryRetentionDays();
+    const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+    if (nowMs - m_queryHistoryCleanedAtMs >= kHistoryCleanupIntervalMs) {
+        m_queryHistoryCleanedAtMs = nowMs;
+        if (retentionDays == 0) {
+            m_queryHistory->purge();
+        } else {
+            m_queryHistory->cleanup(retentionDays);
+        }
+    }
+    if (retentionDays == 0) {
+        return;
+    }
+    m_queryHistory->record(query, resultCount);
+}
+
+// Past searches starting with `prefix`, most used first. Scoped tokens do
+// not see other clients' queries, so they get none.
+QJsonArray QueryService::queryHistorySuggestions(const QString& prefix, int limit)
+{
+    QJsonArray queries;
+    if (!m_queryHistory || m_requestScope.has_value()) {
+        return queries;
+    }
+    for (const auto& entry : m_queryHistory->search(prefix, limit, QueryHistory::Order::Frequent,
+                                                    /*prefixOnly=*/true)) {
+        QJsonObject json;
+        json[QStringLiteral("query")] = entry.query;
+        json[QStringLiteral("hitCount")] = entry.hitCount;
+        json[QStringLiteral("lastUsedAt")] = entry.lastUsedAt.toString(Qt::ISODate);
+        queries.append(json);
+    }
+    return queries;
+}
+
+// Admin only, like the slow query log: it lists every client's queries.
+QJsonObject QueryService::handleGetQueryHistory(uint64_t id, const QJsonObject& params)
+{
+    const int limit = params.value(QStringLiteral("limit")).toInt(50);
+    if (limit < 1 || limit > kMaxHistoryLimit) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'limit' must be between 1 and %1").arg(kMaxHistoryLimit));
+    }
+    const QString orderName = params.value(QStringLiteral("order")).toString(QStringLiteral("recent"));
+    if (orderName != QLatin1String("recent") && orderName != QLatin1String("frequent")) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'order' must be 'recent' or 'frequent'"));
+    }
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const QueryHistory::Order order = orderName == QLatin1String("frequent")
+        ? QueryHistory::Order::Frequent
+        : QueryHistory::Order::Recent;
+    QJsonArray entries;
+    for (const auto& entry : m_queryHistory->search(
+             params.value(QStringLiteral("query")).toString(), limit, order,
+             params.value(QStringLiteral("prefix")).toBool(false))) {
+        entries.append(historyEntryJson(entry));
+    }
+
+    const int retentionDays = queryHistoryRetentionDays();
+    QJsonObject result;
+    result[QStringLiteral("entries")] = entries;
+    result[QStringLiteral("total")] = static_cast<qint64>(m_queryHistory->count());
+    result[QStringLiteral("enabled")] = retentionDays > 0;
+    result[QStringLiteral("retentionDays")] = retentionDays;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handlePurgeQueryHistory(uint64_t id, const QJsonObject& params)
+{
+    const QString query = params.value(QStringLiteral("query")).toString().trimmed();
+    const double before = params.value(QStringLiteral("before")).toDouble(0.0);
+    if (before < 0.0) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("'before' must be epoch seconds"));
+    }
+    if (!query.isEmpty() && before > 0.0) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Pass either 'query' or 'before', not both"));
+    }
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    int64_t removed = 0;
+    if (!query.isEmpty()) {
+        removed = m_queryHistory->remove(query);
+    } else if (before > 0.0) {
+        removed = m_queryHistory->purge(QDateTime::fromMSecsSinceEpoch(
+            static_cast<qint64>(before * 1000.0), QTimeZone::UTC));
+    } else {
+        removed = m_queryHistory->purge();
+    }
+    if (removed < 0) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to purge query history"));
+    }
+    LOG_INFO(bsIpc, "Purged %lld query history entries", static_cast<long long>(removed));
+
+    QJsonObject result;
+    result[QStringLiteral("removedEntries")] = static_cast<qint64>(removed);
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
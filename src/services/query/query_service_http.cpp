@@ -0,0 +1,484 @@
+#include "query_service.h"
+
+#include "core/indexing/messages_source.h"
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+#include <QJsonDocument>
+#include <QJsonParseError>
+#include <QProcessEnvironment>
+
+namespace bs {
+
+namespace {
+
+// Copy an integer query parameter into params when present and valid.
+void copyIntParam(const HttpRequest& request, const QString& key, QJsonObject& params)
+{
+    const QString raw = request.queryValue(key);
+    if (raw.isEmpty()) {
+        return;
+    }
+    bool ok = false;
+    const int value = raw.toInt(&ok);
+    if (ok) {
+        params[key] = value;
+    }
+}
+
+// The result lists whose entries carry a `path`.
+const QStringList& entryListKeys()
+{
+    static const QStringList kKeys = {
+        QStringLiteral("results"),
+        QStringLiteral("suggestions"),
+        QStringLiteral("candidates"),
+        QStringLiteral("items"),
+        QStringLiteral("actions"),
+    };
+    return kKeys;
+}
+
+// Actions open and reveal files, so they are kept off what a web page can
+// send without a CORS preflight: the body must be declared JSON and the
+// request must carry a bearer token, even while no API token exists.
+// dispatchHttp then checks the token itself.
+std::optional<HttpResponse> actionRequestDenial(const HttpRequest& request)
+{
+    const QString contentType = request.header(QStringLiteral("content-type"))
+                                    .section(QLatin1Char(';'), 0, 0).trimmed();
+    if (contentType.compare(QLatin1String("application/json"), Qt::CaseInsensitive) != 0) {
+        return HttpResponse::error(415, QStringLiteral("Content-Type must be application/json"));
+    }
+    if (request.header(QStringLiteral("authorization")).trimmed().isEmpty()) {
+        return HttpResponse::error(401, QStringLiteral("Actions need a bearer token"));
+    }
+    return std::nullopt;
+}
+
+} // namespace
+
+QJsonArray QueryService::withoutLocalOnlyEntries(const QJsonArray& entries)
+{
+    QJsonArray out;
+    for (const QJsonValue& value : entries) {
+        if (!MessagesSource::isLocalOnly(value.toObject().value(QStringLiteral("path")).toString())) {
+            out.append(value);
+        }
+    }
+    return out;
+}
+
+QJsonObject QueryService::withoutLocalOnly(const QJsonObject& response)
+{
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return response;
+    }
+    const uint64_t id = static_cast<uint64_t>(response.value(QStringLiteral("id")).toInteger());
+    QJsonObject result = response.value(QStringLiteral("result")).toObject();
+    if (MessagesSource::isLocalOnly(result.value(QStringLiteral("path")).toString())) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    for (const QString& key : entryListKeys()) {
+        if (result.value(key).isArray()) {
+            result[key] = withoutLocalOnlyEntries(result.value(key).toArray());
+        }
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+bool QueryService::targetsLocalOnly(const QJsonObject& params)
+{
+    if (params.contains(QStringLiteral("itemId"))) {
+        if (!ensureStoreOpen()) {
+            return false;
+        }
+        const auto item = m_store->getItemById(
+            static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger()));
+        return item.has_value() && MessagesSource::isLocalOnly(item->path);
+    }
+    return MessagesSource::isLocalOnly(params.value(QStringLiteral("path")).toString());
+}
+
+void QueryService::initHttpApi()
+{
+    const QString portValue = QProcessEnvironment::systemEnvironment()
+                                  .value(QStringLiteral("BETTERSPOTLIGHT_HTTP_PORT"))
+                                  .trimmed();
+    if (portValue.isEmpty()) {
+        return;
+    }
+    bool ok = false;
+    const int port = portValue.toInt(&ok);
+    if (!ok || port <= 0 || port > 65535) {
+        LOG_WARN(bsIpc, "Ignoring invalid BETTERSPOTLIGHT_HTTP_PORT=%s",
+                 qUtf8Printable(portValue));
+        return;
+    }
+
+    // Server mode: BETTERSPOTLIGHT_HTTP_BIND names another interface so
+    // other machines reach the index. Off loopback the API is HTTPS only
+    // and every request needs a scoped API token (authorizeHttp).
+    const QProcessEnvironment environment = QProcessEnvironment::systemEnvironment();
+    const QString bindValue = environment.value(QStringLiteral("BETTERSPOTLIGHT_HTTP_BIND")).trimmed();
+    QHostAddress address(QHostAddress::LocalHost);
+    if (!bindValue.isEmpty() && !address.setAddress(bindValue)) {
+        LOG_WARN(bsIpc, "Ignoring invalid BETTERSPOTLIGHT_HTTP_BIND=%s",
+                 qUtf8Printable(bindValue));
+        return;
+    }
+    const bool remote = !address.isLoopback();
+    const QString certificatePath =
+        environment.value(QStringLiteral("BETTERSPOTLIGHT_TLS_CERT")).trimmed();
+    const QString keyPath = environment.value(QStringLiteral("BETTERSPOTLIGHT_TLS_KEY")).trimmed();
+    if (remote && (certificatePath.isEmpty() || keyPath.isEmpty())) {
+        LOG_ERROR(bsIpc,
+                  "Not serving HTTP on %s: BETTERSPOTLIGHT_TLS_CERT and BETTERSPOTLIGHT_TLS_KEY "
+                  "are required off loopback",
+                  qUtf8Printable(address.toString()));
+        return;
+    }
+
+    m_httpServer = std::make_unique<HttpServer>();
+    if (!certificatePath.isEmpty() || !keyPath.isEmpty()) {
+        QString tlsError;
+        if (!m_httpServer->useTls(certificatePath, keyPath, &tlsError)) {
+            LOG_ERROR(bsIpc, "Not serving HTTP: %s", qUtf8Printable(tlsError));
+            m_httpServer.reset();
+            return;
+        }
+    }
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/search"),
+                        [this](const HttpRequest& request) {
+        QJsonObject params;
+        params[QStringLiteral("query")] = request.queryValue(QStringLiteral("q"));
+        if (request.query.hasQueryItem(QStringLiteral("predicate"))) {
+            params[QStringLiteral("predicate")] = request.queryValue(QStringLiteral("predicate"));
+        }
+        copyIntParam(request, QStringLiteral("limit"), params);
+        if (request.query.hasQueryItem(QStringLiteral("scope"))) {
+            params[QStringLiteral("scope")] = request.queryValue(QStringLiteral("scope"));
+        }
+        if (request.queryValue(QStringLiteral("literal")) == QLatin1String("1")) {
+            params[QStringLiteral("naturalLanguage")] = false;
+        }
+        if (request.query.hasQueryItem(QStringLiteral("app"))) {
+            QJsonObject context;
+            context[QStringLiteral("frontmostAppBundleId")] = request.queryValue(QStringLiteral("app"));
+            params[QStringLiteral("context")] = context;
+        }
+        return dispatchHttp(request, QStringLiteral("search"), params);
+    });
+
+    // POST accepts the full IPC search params (filters, debug, ...) as a body.
+    m_httpServer->route(QStringLiteral("POST"), QStringLiteral("/v1/search"),
+                        [this](const HttpRequest& request) {
+        QJsonParseError parseError;
+        const QJsonDocument doc = QJsonDocument::fromJson(request.body, &parseError);
+        if (parseError.error != QJsonParseError::NoError || !doc.isObject()) {
+            return HttpResponse::error(400, QStringLiteral("Request body must be a JSON object"));
+        }
+        return dispatchHttp(request, QStringLiteral("search"), doc.object());
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/suggest"),
+                        [this](const HttpRequest& request) {
+        QJsonObject params;
+        if (request.query.hasQueryItem(QStringLiteral("prefix"))) {
+            params[QStringLiteral("prefix")] = request.queryValue(QStringLiteral("prefix"));
+        }
+        copyIntParam(request, QStringLiteral("limit"), params);
+        return dispatchHttp(request, QStringLiteral("suggest"), params);
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/documents"),
+                        [this](const HttpRequest& request) {
+        QJsonObject params;
+        if (request.query.hasQueryItem(QStringLiteral("path"))) {
+            params[QStringLiteral("path")] = request.queryValue(QStringLiteral("path"));
+        } else if (request.query.hasQueryItem(QStringLiteral("id"))) {
+            bool ok = false;
+            const qint64 itemId = request.queryValue(QStringLiteral("id")).toLongLong(&ok);
+            if (!ok) {
+                return HttpResponse::error(400, QStringLiteral("'id' must be an integer"));
+            }
+            params[QStringLiteral("itemId")] = itemId;
+        }
+        return dispatchHttp(request, QStringLiteral("getDocument"), params);
+    });
+
+    // /v1/documents/<id> and /v1/documents/<id>/thumbnail (raw image/png).
+    m_httpServer->routePrefix(QStringLiteral("GET"), QStringLiteral("/v1/documents/"),
+                              [this](const HttpRequest& request) {
+        QString idPart = request.path.mid(QStringLiteral("/v1/documents/").size());
+        const bool wantsThumbnail = idPart.endsWith(QStringLiteral("/thumbnail"));
+        if (wantsThumbnail) {
+            idPart.chop(QStringLiteral("/thumbnail").size());
+        }
+        bool ok = false;
+        const qint64 itemId = idPart.toLongLong(&ok);
+        if (!ok) {
+            return HttpResponse::error(400, QStringLiteral("Document id must be an integer"));
+        }
+        QJsonObject params;
+        params[QStringLiteral("itemId")] = itemId;
+        if (!wantsThumbnail) {
+            return dispatchHttp(request, QStringLiteral("getDocument"), params);
+        }
+
+        copyIntParam(request, QStringLiteral("size"), params);
+        if (auto denied = authorizeHttpDirect(request, QStringLiteral("getThumbnail"), params,
+                                              nullptr, nullptr)) {
+            return denied.value();
+        }
+        if (targetsLocalOnly(params)) {
+            return HttpResponse::error(404, QStringLiteral("Document is not indexed"));
+        }
+        QJsonObject error;
+        const auto thumbnail = documentThumbnail(0, params, &error);
+        if (!thumbnail.has_value()) {
+            return HttpResponse::fromIpc(error);
+        }
+        HttpResponse response;
+        response.contentType = QByteArrayLiteral("image/png");
+        response.body = thumbnail->png;
+        return response;
+    });
+
+    // Result actions (open, reveal, quicklook, copyPath) run by the daemon,
+    // so they count toward frecency exactly like the app's.
+    m_httpServer->route(QStringLiteral("POST"), QStringLiteral("/v1/actions"),
+                        [this](const HttpRequest& request) {
+        if (auto denied = actionRequestDenial(request)) {
+            return denied.value();
+        }
+        QJsonParseError parseError;
+        const QJsonDocument doc = QJsonDocument::fromJson(request.body, &parseError);
+        if (parseError.error != QJsonParseError::NoError || !doc.isObject()) {
+            return HttpResponse::error(400, QStringLiteral("Request body must be a JSON object"));
+        }
+        return dispatchHttp(request, QStringLiteral("performAction"), doc.object());
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/actions/recent"),
+                        [this](const HttpRequest& request) {
+        QJsonObject params;
+        if (request.query.hasQueryItem(QStringLiteral("action"))) {
+            params[QStringLiteral("action")] = request.queryValue(QStringLiteral("action"));
+        }
+        copyIntParam(request, QStringLiteral("limit"), params);
+        copyIntParam(request, QStringLiteral("itemId"), params);
+        return dispatchHttp(request, QStringLiteral("getRecentActions"), params);
+    });
+
+    // An empty body repeats the latest action the token can see.
+    m_httpServer->route(QStringLiteral("POST"), QStringLiteral("/v1/actions/repeat"),
+                        [this](const HttpRequest& request) {
+        if (auto denied = actionRequestDenial(request)) {
+            return denied.value();
+        }
+        QJsonObject params;
+        if (!request.body.trimmed().isEmpty()) {
+            QJsonParseError parseError;
+            const QJsonDocument doc = QJsonDocument::fromJson(request.body, &parseError);
+            if (parseError.error != QJsonParseError::NoError || !doc.isObject()) {
+                return HttpResponse::error(400,
+                                           QStringLiteral("Request body must be a JSON object"));
+            }
+            params = doc.object();
+        }
+        return dispatchHttp(request, QStringLiteral("repeatAction"), params);
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/abbreviations"),
+                        [this](const HttpRequest& request) {
+        return dispatchHttp(request, QStringLiteral("getAbbreviations"), {});
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/scopes"),
+                        [this](const HttpRequest& request) {
+        return dispatchHttp(request, QStringLiteral("listSearchScopes"), {});
+    });
+
+    // Index sync (PeerSync): segment digests, then one segment's entries;
+    // or what changed since a cursor.
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/sync/segments"),
+                        [this](const HttpRequest& request) {
+        return dispatchHttp(request, QStringLiteral("getSyncSegments"), {});
+    });
+
+    m_httpServer->routePrefix(QStringLiteral("GET"), QStringLiteral("/v1/sync/segments/"),
+                              [this](const HttpRequest& request) {
+        bool ok = false;
+        const qint64 first =
+            request.path.mid(QStringLiteral("/v1/sync/segments/").size()).toLongLong(&ok);
+        if (!ok) {
+            return HttpResponse::error(400, QStringLiteral("Segment must be an integer"));
+        }
+        QJsonObject params;
+        params[QStringLiteral("first")] = first;
+        return dispatchHttp(request, QStringLiteral("getSyncSegment"), params);
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/sync/delta"),
+                        [this](const HttpRequest& request) {
+        QJsonObject params;
+        if (request.query.hasQueryItem(QStringLiteral("cursor"))) {
+            params[QStringLiteral("cursor")] = request.queryValue(QStringLiteral("cursor"));
+        }
+        if (request.query.hasQueryItem(QStringLiteral("from"))) {
+            bool ok = false;
+            const qint64 from = request.queryValue(QStringLiteral("from")).toLongLong(&ok);
+            if (!ok) {
+                return HttpResponse::error(400, QStringLiteral("Segment must be an integer"));
+            }
+            params[QStringLiteral("from")] = from;
+        }
+        return dispatchHttp(request, QStringLiteral("getSyncDelta"), params);
+    });
+
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/v1/stats"),
+                        [this](const HttpRequest& request) {
+        return dispatchHttp(request, QStringLiteral("getHealth"), {});
+    });
+
+    // Prometheus scrape target, opted into separately from the API itself.
+    if (QProcessEnvironment::systemEnvironment()
+            .value(QStringLiteral("BETTERSPOTLIGHT_METRICS"))
+            .trimmed() == QLatin1String("1")) {
+        m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/metrics"),
+                            [this](const HttpRequest& request) {
+            if (auto denied = authorizeHttpDirect(request, QStringLiteral("getMetrics"), {},
+                                                  nullptr, nullptr)) {
+                return denied.value();
+            }
+            HttpResponse response;
+            response.contentType = QByteArray(PrometheusText::kContentType);
+            response.body = renderMetrics();
+            return response;
+        });
+    }
+
+    // Liveness: answering at all means the event loop is not wedged.
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/healthz"),
+                        [this](const HttpRequest&) {
+        QJsonObject body;
+        body[QStringLiteral("status")] = QStringLiteral("ok");
+        body[QStringLiteral("service")] = m_serviceName;
+        body[QStringLiteral("uptimeMs")] = QDateTime::currentMSecsSinceEpoch() - m_startedAtMs;
+        return HttpResponse::json(200, body);
+    });
+
+    // Readiness: 200 only while serving; the body says why otherwise.
+    m_httpServer->route(QStringLiteral("GET"), QStringLiteral("/readyz"),
+                        [this](const HttpRequest&) {
+        const QJsonObject snapshot = readinessSnapshot();
+        return HttpResponse::json(snapshot.value(QStringLiteral("ready")).toBool() ? 200 : 503,
+                                  snapshot);
+    });
+
+    // Live query: text/event-stream with a "snapshot" event followed by
+    // "update" events whenever the result set changes.
+    m_httpServer->routeStream(QStringLiteral("GET"), QStringLiteral("/v1/live"),
+                              [this](const HttpRequest& request, quint64 streamId)
+                                  -> std::optional<HttpResponse> {
+        QJsonObject params;
+        params[QStringLiteral("query")] = request.queryValue(QStringLiteral("q"));
+        copyIntParam(request, QStringLiteral("limit"), params);
+
+        // A live query is a repeated search: it needs the search capability,
+        // and its snapshot and updates are filtered to the token's scope.
+        std::optional<QJsonObject> auth;
+        if (auto denied = authorizeHttpDirect(request, QStringLiteral("search"), params, &auth,
+                                              &m_requestScope)) {
+            return denied;
+        }
+
+        QJsonObject error;
+        const auto snapshot = createLiveQuery(params, streamId, &error);
+        m_requestScope.reset();
+        QJsonObject auditedRequest =
+            IpcMessage::makeRequest(0, QStringLiteral("subscribeQuery"), params);
+        if (auth.has_value()) {
+            auditedRequest[QStringLiteral("auth")] = auth.value();
+        }
+        auditRequest(auditedRequest,
+                     snapshot.has_value() ? IpcMessage::makeResponse(0, snapshot.value())
+                                          : error);
+        if (!snapshot.has_value()) {
+            return HttpResponse::fromIpc(error);
+        }
+        m_httpServer->sendEvent(streamId, QStringLiteral("snapshot"), snapshot.value());
+        return std::nullopt;
+    });
+    m_httpServer->routeStream(QStringLiteral("GET"), QStringLiteral("/v1/raycast/search"),
+                              [this](const HttpRequest& request, quint64 streamId)
+                                  -> std::optional<HttpResponse> {
+        std::optional<ApiTokenScope> scope;
+        if (auto denied = authorizeHttpDirect(request, QStringLiteral("search"), {}, nullptr,
+                                              &scope)) {
+            return denied;
+        }
+        return startRaycastSearch(request, streamId, scope);
+    });
+    QObject::connect(m_httpServer.get(), &HttpServer::streamClosed, this,
+                     [this](quint64 streamId) {
+        for (auto it = m_liveQueries.cbegin(); it != m_liveQueries.cend(); ++it) {
+            if (it->httpStreamId == streamId) {
+                removeLiveQuery(it.key());
+                break;
+            }
+        }
+    });
+
+    // Loopback by default. Bearer tokens (authorizeHttp) are required once
+    // any scoped API token has been issued, and always from the network.
+    if (!m_httpServer->listen(address, static_cast<quint16>(port))) {
+        m_httpServer.reset();
+        return;
+    }
+    if (m_httpServer->isTls()) {
+        LOG_INFO(bsIpc, "HTTPS certificate SHA-256 %s",
+                 qUtf8Printable(m_httpServer->certificateFingerprint()));
+    }
+    if (remote && m_apiTokens.isEmpty()) {
+        LOG_WARN(bsIpc, "HTTP API is on %s but no API token exists; every request is refused "
+                        "until one is issued (bspot token create)",
+                 qUtf8Printable(address.toString()));
+    }
+}
+
+HttpResponse QueryService::dispatchHttp(const HttpRequest& httpRequest, const QString& method,
+                                        const QJsonObject& params)
+{
+    std::optional<QJsonObject> auth;
+    if (auto denied = authorizeHttp(httpRequest, &auth)) {
+        return denied.value();
+    }
+    if (targetsLocalOnly(params)) {
+        return HttpResponse::error(404, QStringLiteral("Document is not indexed"));
+    }
+    // Actions open, reveal or copy on this Mac's own desktop; a client
+    // across the network opens the result itself.
+    if (httpRequest.isRemote()
+        && (method == QLatin1String("performAction") || method == QLatin1String("repeatAction"))) {
+        return HttpResponse::error(403, QStringLiteral("Actions are not available over the network"));
+    }
+    QJsonObject request = IpcMessage::makeRequest(0, method, params);
+    if (auth.has_value()) {
+        request[QStringLiteral("auth")] = auth.value();
+    }
+    const QString traceparent = httpRequest.header(QStringLiteral("traceparent"));
+    if (!traceparent.isEmpty()) {
+        request[QStringLiteral("traceparent")] = traceparent;
+    }
+    const QJsonObject response = withoutLocalOnly(dispatchRequest(request));
+    auditRequest(request, response);
+    return HttpResponse::fromIpc(response);
+}
+
+} // namespace bs
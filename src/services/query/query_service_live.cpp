@@ -0,0 +1,241 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <sqlite3.h>
+
+#include <QJsonArray>
+#include <QSet>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultLiveQueryPollMs = 1000;
+
+} // namespace
+
+void QueryService::initLiveQueries()
+{
+    int pollMs = kDefaultLiveQueryPollMs;
+    if (qEnvironmentVariableIsSet("BS_TEST_LIVE_QUERY_POLL_MS")) {
+        bool ok = false;
+        const int parsed = qEnvironmentVariableIntValue("BS_TEST_LIVE_QUERY_POLL_MS", &ok);
+        if (ok) {
+            pollMs = std::clamp(parsed, 50, 60000);
+        }
+    }
+    m_liveQueryTimer.setInterval(pollMs);
+    m_liveQueryTimer.setSingleShot(false);
+    QObject::connect(&m_liveQueryTimer, &QTimer::timeout, this, [this]() {
+        pollLiveQueries();
+    });
+}
+
+QJsonObject QueryService::handleSubscribeQuery(uint64_t id, const QJsonObject& params)
+{
+    QJsonObject error;
+    const auto snapshot = createLiveQuery(params, 0, &error);
+    if (!snapshot.has_value()) {
+        QJsonObject errorMessage = error;
+        errorMessage[QStringLiteral("id")] = static_cast<qint64>(id);
+        return errorMessage;
+    }
+    return IpcMessage::makeResponse(id, snapshot.value());
+}
+
+QJsonObject QueryService::handleUnsubscribeQuery(uint64_t id, const QJsonObject& params)
+{
+    const quint64 subscriptionId = static_cast<quint64>(
+        params.value(QStringLiteral("subscriptionId")).toInteger(0));
+    if (!m_liveQueries.contains(subscriptionId)
+        || m_liveQueries.value(subscriptionId).httpStreamId != 0) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Unknown subscriptionId"));
+    }
+    removeLiveQuery(subscriptionId);
+
+    QJsonObject result;
+    result[QStringLiteral("subscriptionId")] = static_cast<qint64>(subscriptionId);
+    result[QStringLiteral("unsubscribed")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+std::optional<QJsonObject> QueryService::createLiveQuery(const QJsonObject& params,
+                                                         quint64 httpStreamId,
+                                                         QJsonObject* error)
+{
+    if (m_liveQueries.size() >= kMaxLiveQueries) {
+        *error = IpcMessage::makeError(0, IpcErrorCode::ServiceUnavailable,
+                                       QStringLiteral("Too many live query subscriptions"));
+        return std::nullopt;
+    }
+
+    // Subscriptions always see fresh rows; debug payloads are not streamed.
+    // A deadline only makes sense for one run: replayed on every refresh it
+    // would already have passed.
+    QJsonObject searchParams = params;
+    searchParams.remove(QStringLiteral("subscriptionId"));
+    searchParams.remove(QStringLiteral("debug"));
+    searchParams.remove(QStringLiteral("deadlineMs"));
+    if (m_requestScope.has_value()) {
+        searchParams = m_requestScope->narrowSearch(searchParams);
+    }
+
+    const QJsonObject response = handleSearch(0, searchParams);
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        *error = response;
+        return std::nullopt;
+    }
+
+    QJsonArray results = response.value(QStringLiteral("result")).toObject()
+                             .value(QStringLiteral("results")).toArray();
+    if (m_requestScope.has_value()) {
+        results = m_requestScope->filterEntries(results);
+    }
+    if (m_requestScope.has_value() || httpStreamId != 0) {
+        results = withoutLocalOnlyEntries(results);
+    }
+    LiveQuerySubscription subscription;
+    subscription.searchParams = searchParams;
+    subscription.httpStreamId = httpStreamId;
+    subscription.scope = m_requestScope;
+    subscription.itemIds.reserve(static_cast<size_t>(results.size()));
+    for (const QJsonValue& value : results) {
+        subscription.itemIds.push_back(
+            value.toObject().value(QStringLiteral("itemId")).toInteger());
+    }
+
+    const quint64 subscriptionId = m_nextLiveQueryId++;
+    m_liveQueries.insert(subscriptionId, std::move(subscription));
+    if (!m_liveQueryTimer.isActive()) {
+        m_liveQueryDataVersion = readIndexDataVersion();
+        m_liveQueryTimer.start();
+    }
+    LOG_INFO(bsIpc, "Live query %llu subscribed (query='%s', active=%lld)",
+             static_cast<unsigned long long>(subscriptionId),
+             searchParams.value(QStringLiteral("private")).toBool(false)
+                 ? "<private>"
+                 : qUtf8Printable(searchParams.value(QStringLiteral("query")).toString()),
+             static_cast<long long>(m_liveQueries.size()));
+
+    QJsonObject snapshot;
+    snapshot[QStringLiteral("subscriptionId")] = static_cast<qint64>(subscriptionId);
+    snapshot[QStringLiteral("results")] = results;
+    return snapshot;
+}
+
+void QueryService::removeLiveQuery(quint64 subscriptionId)
+{
+    if (m_liveQueries.remove(subscriptionId) == 0) {
+        return;
+    }
+    if (m_liveQueries.isEmpty()) {
+        m_liveQueryTimer.stop();
+    }
+}
+
+qint64 QueryService::readIndexDataVersion() const
+{
+    // PRAGMA data_version changes whenever another connection (the indexer)
+    // commits, so it is a cheap "did anything change" probe.
+    if (!m_store.has_value() || !m_store->rawDb()) {
+        return -1;
+    }
+    sqlite3_stmt* stmt = nullptr;
+    if (sqlite3_prepare_v2(m_store->rawDb(), "PRAGMA data_version", -1, &stmt, nullptr)
+        != SQLITE_OK) {
+        return -1;
+    }
+    qint64 version = -1;
+    if (sqlite3_step(stmt) == SQLITE_ROW) {
+        version = sqlite3_column_int64(stmt, 0);
+    }
+    sqlite3_finalize(stmt);
+    return version;
+}
+
+void QueryService::pollLiveQueries()
+{
+    if (m_liveQueries.isEmpty()) {
+        m_liveQueryTimer.stop();
+        return;
+    }
+    const qint64 version = readIndexDataVersion();
+    if (version < 0 || version == m_liveQueryDataVersion) {
+        return;
+    }
+    m_liveQueryDataVersion = version;
+    m_queryCache.clear();
+
+    m_liveQueryRefreshActive = true;
+    const QList<quint64> subscriptionIds = m_liveQueries.keys();
+    for (const quint64 subscriptionId : subscriptionIds) {
+        auto it = m_liveQueries.find(subscriptionId);
+        if (it == m_liveQueries.end()) {
+            continue;
+        }
+        const QJsonObject response = handleSearch(0, it->searchParams);
+        if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+            continue;
+        }
+        QJsonArray results = response.value(QStringLiteral("result")).toObject()
+                                 .value(QStringLiteral("results")).toArray();
+        if (it->scope.has_value()) {
+            results = it->scope->filterEntries(results);
+        }
+        if (it->scope.has_value() || it->httpStreamId != 0) {
+            results = withoutLocalOnlyEntries(results);
+        }
+
+        std::vector<int64_t> itemIds;
+        itemIds.reserve(static_cast<size_t>(results.size()));
+        for (const QJsonValue& value : results) {
+            itemIds.push_back(value.toObject().value(QStringLiteral("itemId")).toInteger());
+        }
+        if (itemIds == it->itemIds) {
+            continue;
+        }
+
+        const QSet<int64_t> previous(it->itemIds.begin(), it->itemIds.end());
+        const QSet<int64_t> current(itemIds.begin(), itemIds.end());
+        QJsonArray added;
+        for (const QJsonValue& value : results) {
+            if (!previous.contains(value.toObject().value(QStringLiteral("itemId")).toInteger())) {
+                added.append(value);
+            }
+        }
+        QJsonArray removed;
+        for (const int64_t itemId : it->itemIds) {
+            if (!current.contains(itemId)) {
+                removed.append(static_cast<qint64>(itemId));
+            }
+        }
+        QJsonArray order;
+        for (const int64_t itemId : itemIds) {
+            order.append(static_cast<qint64>(itemId));
+        }
+        it->itemIds = std::move(itemIds);
+
+        QJsonObject update;
+        update[QStringLiteral("subscriptionId")] = static_cast<qint64>(subscriptionId);
+        update[QStringLiteral("added")] = added;
+        update[QStringLiteral("removed")] = removed;
+        update[QStringLiteral("order")] = order;
+
+        if (it->httpStreamId != 0) {
+            if (!m_httpServer
+                || !m_httpServer->sendEvent(it->httpStreamId, QStringLiteral("update"), update)) {
+                removeLiveQuery(subscriptionId);
+            }
+        } else {
+            sendNotification(QStringLiteral("liveQueryUpdated"), update);
+        }
+    }
+    m_liveQueryRefreshActive = false;
+}
+
+} // namespace bs
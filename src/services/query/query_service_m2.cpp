@@ -38,6 +38,7 @@ constexpr int kVectorRebuildProgressPersistStride = 32;
 constexpr qint64 kVectorRebuildProgressPersistIntervalMs = 1500;
 constexpr int kVectorRebuildChunksPerItem = 3;
 constexpr int kVectorRebuildMaxChunkChars = 8192;
+constexpr qint64 kRecentDocumentsRefreshIntervalMs = 60 * 1000;
 
 bool envFlagEnabled(const QString& raw)
 {
@@ -97,6 +98,24 @@ std::vector<float> makeDeterministicEmbedding(const QString& text, int dimension
 }
 } // namespace
 
+void QueryService::refreshRecentDocuments()
+{
+    const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+    if (m_recentDocumentsReadAtMs > 0
+        && nowMs - m_recentDocumentsReadAtMs < kRecentDocumentsRefreshIntervalMs) {
+        return;
+    }
+    m_recentDocumentsReadAtMs = nowMs;
+
+    int unreadableLists = 0;
+    const std::vector<RecentDocuments::Entry> entries =
+        RecentDocuments::readSystemLists(&unreadableLists);
+    m_recentDocuments.update(entries, nowMs / 1000);
+    if (unreadableLists > 0) {
+        LOG_DEBUG(bsIpc, "Recent documents: %d lists unreadable", unreadableLists);
+    }
+}
+
 QJsonObject QueryService::handleRecordInteraction(uint64_t id, const QJsonObject& params)
 {
     if (!ensureM2ModulesInitialized()) {
@@ -116,6 +135,12 @@ QJsonObject QueryService::handleRecordInteraction(uint64_t id, const QJsonObject
         return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
                                      QStringLiteral("Missing or invalid 'selectedItemId'"));
     }
+    if (params.value(QStringLiteral("private")).toBool(false)) {
+        QJsonObject result;
+        result[QStringLiteral("recorded")] = false;
+        result[QStringLiteral("private")] = true;
+        return IpcMessage::makeResponse(id, result);
+    }
 
     InteractionTracker::Interaction interaction;
     interaction.query = query;
@@ -332,6 +357,10 @@ QJsonObject QueryService::handleRecordBehaviorEvent(uint64_t id, const QJsonObje
     event.privacyFlags.privateContext = privacyFlags.value(QStringLiteral("privateContext")).toBool(false);
     event.privacyFlags.denylistedApp = privacyFlags.value(QStringLiteral("denylistedApp")).toBool(false);
     event.privacyFlags.redacted = privacyFlags.value(QStringLiteral("redacted")).toBool(false);
+    // A private search's events are filtered like any other private context.
+    if (params.value(QStringLiteral("private")).toBool(false)) {
+        event.privacyFlags.privateContext = true;
+    }
 
     bool eventPersisted = false;
     QString error;
@@ -0,0 +1,312 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/ipc/socket_client.h"
+#include "core/vector/vector_index.h"
+#include "core/vector/vector_store.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+#include <QSet>
+
+#include <cmath>
+#include <optional>
+#include <utility>
+
+namespace bs {
+
+namespace {
+
+const QString kPrefix = QStringLiteral("betterspotlight_");
+
+QString metricName(const char* name)
+{
+    return kPrefix + QLatin1String(name);
+}
+
+// Sliding windows reported by getQueryStats, shortest first.
+constexpr std::pair<const char*, int> kStatsWindows[] = {
+    {"1m", 60},
+    {"5m", 300},
+    {"15m", 900},
+};
+
+double roundedMs(double ms)
+{
+    return std::round(ms * 100.0) / 100.0;
+}
+
+} // namespace
+
+bool QueryService::isTimedMethod(const QString& method)
+{
+    // What a user waits on while typing or opening a result.
+    static const QSet<QString> kTimedMethods = {
+        QStringLiteral("search"),
+        QStringLiteral("suggest"),
+        QStringLiteral("completePaths"),
+        QStringLiteral("exportMatches"),
+        QStringLiteral("findSimilar"),
+        QStringLiteral("getDocument"),
+        QStringLiteral("getAnswerSnippet"),
+        QStringLiteral("performAction"),
+    };
+    return kTimedMethods.contains(method);
+}
+
+void QueryService::recordRequestMetrics(const QString& method, double seconds,
+                                        const QJsonObject& response)
+{
+    const bool error =
+        response.value(QStringLiteral("type")).toString() == QLatin1String("error");
+    const bool cached = response.value(QStringLiteral("result")).toObject()
+                            .value(QStringLiteral("cached")).toBool();
+    m_requestLatency[method].observe(seconds);
+    if (error) {
+        ++m_requestErrors[method];
+    }
+    m_requestWindows[method].observe(seconds, error, cached,
+                                     QDateTime::currentMSecsSinceEpoch());
+}
+
+QJsonArray QueryService::requestWindowsJson() const
+{
+    const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+    QJsonArray windows;
+    for (const auto& [label, seconds] : kStatsWindows) {
+        qint64 requests = 0;
+        qint64 errors = 0;
+        QJsonObject methods;
+        std::optional<WindowedLatency::Summary> search;
+        for (const auto& [method, latency] : m_requestWindows) {
+            const WindowedLatency::Summary summary = latency.summarize(seconds, nowMs);
+            if (summary.count == 0) {
+                continue;
+            }
+            requests += static_cast<qint64>(summary.count);
+            errors += static_cast<qint64>(summary.errors);
+            if (method == QLatin1String("search")) {
+                search = summary;
+            }
+            QJsonObject entry;
+            entry[QStringLiteral("requests")] = static_cast<qint64>(summary.count);
+            entry[QStringLiteral("errors")] = static_cast<qint64>(summary.errors);
+            entry[QStringLiteral("p50Ms")] = roundedMs(summary.p50Ms);
+            entry[QStringLiteral("p95Ms")] = roundedMs(summary.p95Ms);
+            entry[QStringLiteral("p99Ms")] = roundedMs(summary.p99Ms);
+            entry[QStringLiteral("maxMs")] = roundedMs(summary.maxMs);
+            methods[method] = entry;
+        }
+
+        QJsonObject window;
+        window[QStringLiteral("window")] = QLatin1String(label);
+        window[QStringLiteral("seconds")] = seconds;
+        window[QStringLiteral("requests")] = requests;
+        window[QStringLiteral("errors")] = errors;
+        // Only searches go through the result cache.
+        window[QStringLiteral("cacheHitRate")] =
+            search.has_value()
+                ? QJsonValue(static_cast<double>(search->hits)
+                             / static_cast<double>(search->count))
+                : QJsonValue(QJsonValue::Null);
+        window[QStringLiteral("methods")] = methods;
+        windows.append(window);
+    }
+    return windows;
+}
+
+QJsonObject QueryService::handleGetQueryStats(uint64_t id)
+{
+    QJsonObject result = queryStatsSnapshot();
+
+    const QueryCache::Stats cache = m_queryCache.stats();
+    QJsonObject cacheJson;
+    cacheJson[QStringLiteral("hits")] = static_cast<qint64>(cache.hits);
+    cacheJson[QStringLiteral("misses")] = static_cast<qint64>(cache.misses);
+    cacheJson[QStringLiteral("evictions")] = static_cast<qint64>(cache.evictions);
+    cacheJson[QStringLiteral("currentSize")] = cache.currentSize;
+    const uint64_t lookups = cache.hits + cache.misses;
+    cacheJson[QStringLiteral("hitRate")] =
+        lookups > 0 ? QJsonValue(static_cast<double>(cache.hits) / static_cast<double>(lookups))
+                    : QJsonValue(QJsonValue::Null);
+    result[QStringLiteral("queryCache")] = cacheJson;
+
+    QJsonObject lifetime;
+    for (const auto& [method, histogram] : m_requestLatency) {
+        const auto errors = m_requestErrors.find(method);
+        QJsonObject entry;
+        entry[QStringLiteral("requests")] = static_cast<qint64>(histogram.count());
+        entry[QStringLiteral("errors")] =
+            errors == m_requestErrors.end() ? 0 : errors->second;
+        lifetime[method] = entry;
+    }
+    result[QStringLiteral("methods")] = lifetime;
+    result[QStringLiteral("startedAtMs")] = m_startedAtMs;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleGetMetrics(uint64_t id)
+{
+    QJsonObject result;
+    result[QStringLiteral("contentType")] = QLatin1String(PrometheusText::kContentType);
+    result[QStringLiteral("text")] = QString::fromUtf8(renderMetrics());
+    return IpcMessage::makeResponse(id, result);
+}
+
+QByteArray QueryService::renderMetrics()
+{
+    PrometheusText out;
+
+    if (ensureStoreOpen()) {
+        const IndexHealth health = m_store->getHealth();
+        out.gauge(metricName("index_items"), QStringLiteral("Files and folders in the index."),
+                  static_cast<double>(health.totalIndexedItems));
+        out.gauge(metricName("index_chunks"), QStringLiteral("Content chunks in the index."),
+                  static_cast<double>(health.totalChunks));
+        out.gauge(metricName("index_items_without_content"),
+                  QStringLiteral("Indexed items with no extracted content."),
+                  static_cast<double>(health.itemsWithoutContent));
+        out.gauge(metricName("index_bytes"),
+                  QStringLiteral("Bytes of the index database in use, excluding free pages."),
+                  static_cast<double>(health.ftsIndexSize));
+        out.gauge(metricName("index_fts_segments"),
+                  QStringLiteral("FTS5 segments; merges and compaction reduce them."),
+                  static_cast<double>(m_store->ftsSegmentCount()));
+        out.gauge(metricName("index_pending_compaction_deletes"),
+                  QStringLiteral("Items deleted since the last complete compaction."),
+                  static_cast<double>(m_store->pendingCompactionDeletes()));
+        if (health.lastIndexTime > 0.0) {
+            out.gauge(metricName("index_age_seconds"),
+                      QStringLiteral("Seconds since the indexer last drained its queue."),
+                      health.indexAge);
+        }
+        for (const auto& failure : m_store->extractionFailureCounts()) {
+            out.gauge(metricName("extraction_failures"),
+                      QStringLiteral("Items whose content extraction failed, by kind. "
+                                     "expected=\"true\" are known gaps such as unsupported "
+                                     "or offline files."),
+                      static_cast<double>(failure.count),
+                      {{QStringLiteral("kind"), failure.kind},
+                       {QStringLiteral("expected"),
+                        failure.expected ? QStringLiteral("true") : QStringLiteral("false")}});
+        }
+        if (m_vectorStore) {
+            out.gauge(metricName("index_embedded_items"),
+                      QStringLiteral("Items with an embedding in the active vector generation."),
+                      static_cast<double>(m_vectorStore->countMappingsForGeneration(
+                          m_activeVectorGeneration.toStdString())));
+        }
+    }
+
+    {
+        std::shared_lock<std::shared_mutex> lock(m_vectorIndexMutex);
+        const std::pair<const char*, const VectorIndex*> indexes[] = {
+            {"main", m_vectorIndex.get()},
+            {"fast", m_fastVectorIndex.get()},
+        };
+        for (const auto& [label, index] : indexes) {
+            if (index && index->isAvailable()) {
+                out.gauge(metricName("vector_index_elements"),
+                          QStringLiteral("Vectors stored in each HNSW index, deleted included."),
+                          index->totalElements(), {{QStringLiteral("index"), QLatin1String(label)}});
+            }
+        }
+        for (const auto& [label, index] : indexes) {
+            if (index && index->isAvailable()) {
+                out.gauge(metricName("vector_index_deleted_elements"),
+                          QStringLiteral("Vectors marked deleted in each HNSW index."),
+                          index->deletedElements(),
+                          {{QStringLiteral("index"), QLatin1String(label)}});
+            }
+        }
+    }
+
+    // The queue lives in the indexer; same short probe as getReadiness.
+    SocketClient indexerClient;
+    std::optional<QJsonObject> queue;
+    if (indexerClient.connectToServer(ServiceBase::socketPath(QStringLiteral("indexer")), 75)) {
+        const auto response = indexerClient.sendRequest(QStringLiteral("getQueueStatus"), {}, 150);
+        if (response.has_value()
+            && response->value(QStringLiteral("type")).toString() == QLatin1String("response")) {
+            queue = response->value(QStringLiteral("result")).toObject();
+        }
+    }
+    out.gauge(metricName("indexer_up"),
+              QStringLiteral("1 when the indexer answered the queue probe."),
+              queue.has_value() ? 1 : 0);
+    if (queue.has_value()) {
+        for (const char* state : {"pending", "processing", "preparing", "writing"}) {
+            out.gauge(metricName("indexer_queue_items"),
+                      QStringLiteral("Work items in the indexer, by state."),
+                      static_cast<double>(queue->value(QLatin1String(state)).toInteger()),
+                      {{QStringLiteral("state"), QLatin1String(state)}});
+        }
+        out.gauge(metricName("indexer_paused"), QStringLiteral("1 while indexing is paused."),
+                  queue->value(QStringLiteral("paused")).toBool() ? 1 : 0);
+        out.counter(metricName("indexer_processed_total"),
+                    QStringLiteral("Work items the indexer finished since it started."),
+                    static_cast<double>(queue->value(QStringLiteral("lastProgressReport"))
+                                            .toObject()
+                                            .value(QStringLiteral("scanned"))
+                                            .toInteger()));
+        out.counter(metricName("indexer_failed_total"),
+                    QStringLiteral("Work items that failed since the indexer started."),
+                    static_cast<double>(queue->value(QStringLiteral("failed")).toInteger()));
+        out.counter(metricName("indexer_dropped_total"),
+                    QStringLiteral("Work items dropped by queue backpressure since the "
+                                   "indexer started."),
+                    static_cast<double>(queue->value(QStringLiteral("dropped")).toInteger()));
+
+        const QJsonArray extractors = queue->value(QStringLiteral("extractors")).toArray();
+        const auto extractorCounter = [&](const char* name, const QString& help,
+                                          const char* field, double scale) {
+            for (const QJsonValue& value : extractors) {
+                const QJsonObject extractor = value.toObject();
+                out.counter(metricName(name), help,
+                            static_cast<double>(extractor.value(QLatin1String(field)).toInteger())
+                                * scale,
+                            {{QStringLiteral("extractor"),
+                              extractor.value(QStringLiteral("extractor")).toString()}});
+            }
+        };
+        extractorCounter("extractor_extractions_total",
+                         QStringLiteral("Files handed to each extractor since the indexer "
+                                        "started."),
+                         "extractions", 1.0);
+        extractorCounter("extractor_failures_total",
+                         QStringLiteral("Extractions that did not succeed, by extractor."),
+                         "failures", 1.0);
+        extractorCounter("extractor_timeouts_total",
+                         QStringLiteral("Extractions that timed out, by extractor."),
+                         "timeouts", 1.0);
+        extractorCounter("extractor_seconds_total",
+                         QStringLiteral("Time spent extracting, by extractor."),
+                         "totalMs", 0.001);
+    }
+
+    for (const auto& [method, histogram] : m_requestLatency) {
+        out.histogram(metricName("request_duration_seconds"),
+                      QStringLiteral("Query service request latency, by method."), histogram,
+                      {{QStringLiteral("method"), method}});
+    }
+    for (const auto& [method, errors] : m_requestErrors) {
+        out.counter(metricName("request_errors_total"),
+                    QStringLiteral("Query service requests answered with an error, by method."),
+                    static_cast<double>(errors), {{QStringLiteral("method"), method}});
+    }
+
+    const QueryCache::Stats cache = m_queryCache.stats();
+    out.counter(metricName("query_cache_hits_total"),
+                QStringLiteral("Searches answered from the result cache."),
+                static_cast<double>(cache.hits));
+    out.counter(metricName("query_cache_misses_total"),
+                QStringLiteral("Searches that missed the result cache."),
+                static_cast<double>(cache.misses));
+    out.gauge(metricName("query_start_time_seconds"),
+              QStringLiteral("When the query service started, in Unix seconds."),
+              static_cast<double>(m_startedAtMs) / 1000.0);
+
+    return out.text();
+}
+
+} // namespace bs
@@ -0,0 +1,119 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/query/spotlight_predicate.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+bool isUnderPath(const QString& path, const QString& prefix)
+{
+    return path == prefix || prefix == QLatin1String("/")
+           || path.startsWith(prefix + QLatin1Char('/'));
+}
+
+// Caller `filters` AND the predicate's: both must hold. Nullopt with *error
+// set when they cannot both match anything.
+std::optional<QJsonObject> mergeFilters(QJsonObject filters, const QJsonObject& predicate,
+                                        QString* error)
+{
+    for (auto it = predicate.begin(); it != predicate.end(); ++it) {
+        const QString& key = it.key();
+        if (!filters.contains(key)) {
+            filters[key] = it.value();
+            continue;
+        }
+        const QJsonValue existing = filters.value(key);
+        if (key == QLatin1String("fileTypes") || key == QLatin1String("includePaths")) {
+            // Each list is a set of alternatives: keep what satisfies both.
+            const bool paths = key == QLatin1String("includePaths");
+            QJsonArray both;
+            for (const QJsonValue& a : existing.toArray()) {
+                for (const QJsonValue& b : it.value().toArray()) {
+                    const QString left = a.toString();
+                    const QString right = b.toString();
+                    if (!paths && left.compare(right, Qt::CaseInsensitive) == 0) {
+                        both.append(right);
+                    } else if (paths && isUnderPath(right, left)) {
+                        both.append(right);
+                    } else if (paths && isUnderPath(left, right)) {
+                        both.append(left);
+                    }
+                }
+            }
+            if (both.isEmpty()) {
+                *error = QStringLiteral("The predicate and filters.%1 exclude each other").arg(key);
+                return std::nullopt;
+            }
+            filters[key] = both;
+        } else if (key == QLatin1String("tags") || key == QLatin1String("excludePaths")) {
+            QJsonArray all = existing.toArray();
+            for (const QJsonValue& value : it.value().toArray()) {
+                all.append(value);
+            }
+            filters[key] = all;
+        } else if (key == QLatin1String("modifiedAfter") || key == QLatin1String("createdAfter")
+                   || key == QLatin1String("dueAfter") || key == QLatin1String("minSize")) {
+            filters[key] = std::max(existing.toDouble(), it.value().toDouble());
+        } else {
+            filters[key] = std::min(existing.toDouble(), it.value().toDouble());
+        }
+    }
+    return filters;
+}
+
+} // namespace
+
+QJsonObject QueryService::handlePredicateSearch(uint64_t id, const QJsonObject& params)
+{
+    const QString predicate = params.value(QStringLiteral("predicate")).toString();
+    QString error;
+    const std::optional<SpotlightQuery> translated =
+        SpotlightPredicate::translate(predicate, QDateTime::currentDateTime(), &error);
+    if (!translated.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Invalid predicate: %1").arg(error));
+    }
+    const std::optional<QJsonObject> filters = mergeFilters(
+        params.value(QStringLiteral("filters")).toObject(), translated->filters, &error);
+    if (!filters.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+
+    QJsonObject searchParams = params;
+    searchParams.remove(QStringLiteral("predicate"));
+    const QString query = (params.value(QStringLiteral("query")).toString() + QLatin1Char(' ')
+                           + translated->text).trimmed();
+    searchParams[QStringLiteral("query")] = query;
+    if (!filters->isEmpty()) {
+        searchParams[QStringLiteral("filters")] = filters.value();
+    }
+
+    QJsonObject response;
+    if (query.isEmpty()) {
+        // Filter-only predicates (kMDItemContentType == "com.adobe.pdf") list
+        // matching items like a tag-only query does.
+        const int limit = std::clamp(params.value(QStringLiteral("limit")).toInt(20), 1, 200);
+        response = handleTagOnlySearch(id, searchOptionsFromFilters(filters.value()), limit);
+    } else {
+        response = handleSearch(id, searchParams);
+    }
+
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("response")) {
+        QJsonObject echo;
+        echo[QStringLiteral("query")] = query;
+        echo[QStringLiteral("filters")] = filters.value();
+        QJsonObject result = response.value(QStringLiteral("result")).toObject();
+        result[QStringLiteral("predicate")] = echo;
+        response[QStringLiteral("result")] = result;
+    }
+    return response;
+}
+
+} // namespace bs
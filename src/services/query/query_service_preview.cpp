@@ -0,0 +1,107 @@
+#include "query_service.h"
+
+#include "core/fs/quicklook_thumbnail.h"
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QDir>
+#include <QFileInfo>
+
+namespace bs {
+
+namespace {
+
+// A miss renders on the service thread; cap how long one can hold it.
+constexpr int kThumbnailTimeoutMs = 5000;
+
+} // namespace
+
+std::optional<QueryService::DocumentThumbnail> QueryService::documentThumbnail(
+    uint64_t id, const QJsonObject& params, QJsonObject* error)
+{
+    if (!ensureStoreOpen()) {
+        *error = IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                       QStringLiteral("Database is not available"));
+        return std::nullopt;
+    }
+
+    std::optional<SQLiteStore::ItemRow> item;
+    if (params.contains(QStringLiteral("itemId"))) {
+        item = m_store->getItemById(
+            static_cast<int64_t>(params.value(QStringLiteral("itemId")).toInteger()));
+    } else if (params.contains(QStringLiteral("path"))) {
+        item = m_store->getItemByPath(
+            QDir::cleanPath(params.value(QStringLiteral("path")).toString()));
+    } else {
+        *error = IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                       QStringLiteral("Missing 'itemId' or 'path' parameter"));
+        return std::nullopt;
+    }
+    if (!item.has_value() || isExcludedByBsignore(item->path)) {
+        *error = IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                       QStringLiteral("Document is not indexed"));
+        return std::nullopt;
+    }
+    if (!QFileInfo::exists(item->path)) {
+        *error = IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                       QStringLiteral("Document no longer exists on disk"));
+        return std::nullopt;
+    }
+
+    if (!m_thumbnailCache) {
+        m_thumbnailCache = std::make_unique<ThumbnailCache>(
+            m_dataDir + QStringLiteral("/thumbnails"));
+    }
+
+    DocumentThumbnail thumbnail;
+    thumbnail.itemId = item->id;
+    thumbnail.pixelSize = ThumbnailCache::normalizePixelSize(
+        params.value(QStringLiteral("size")).toInt(ThumbnailCache::kDefaultPixelSize));
+    const QString key =
+        ThumbnailCache::cacheKey(item->path, item->modifiedAt, thumbnail.pixelSize);
+    if (auto cached = m_thumbnailCache->lookup(key)) {
+        thumbnail.png = std::move(cached.value());
+        thumbnail.cached = true;
+        return thumbnail;
+    }
+
+    QString generateError;
+    auto png = QuickLookThumbnail::generatePng(item->path, thumbnail.pixelSize,
+                                               kThumbnailTimeoutMs, &generateError);
+    if (!png.has_value()) {
+        LOG_WARN(bsIpc, "Thumbnail generation failed for %s: %s",
+                 qUtf8Printable(item->path), qUtf8Printable(generateError));
+        *error = generateError == QLatin1String("timeout")
+            ? IpcMessage::makeError(id, IpcErrorCode::Timeout,
+                                    QStringLiteral("Thumbnail generation timed out"))
+            : IpcMessage::makeError(id, IpcErrorCode::Unsupported,
+                                    QStringLiteral("No thumbnail available: %1")
+                                        .arg(generateError));
+        return std::nullopt;
+    }
+    thumbnail.png = std::move(png.value());
+    if (!m_thumbnailCache->store(key, thumbnail.png)) {
+        LOG_WARN(bsIpc, "Could not cache thumbnail under %s",
+                 qUtf8Printable(m_thumbnailCache->rootDir()));
+    }
+    return thumbnail;
+}
+
+QJsonObject QueryService::handleGetThumbnail(uint64_t id, const QJsonObject& params)
+{
+    QJsonObject error;
+    const auto thumbnail = documentThumbnail(id, params, &error);
+    if (!thumbnail.has_value()) {
+        return error;
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("itemId")] = static_cast<qint64>(thumbnail->itemId);
+    result[QStringLiteral("size")] = thumbnail->pixelSize;
+    result[QStringLiteral("mimeType")] = QStringLiteral("image/png");
+    result[QStringLiteral("data")] = QString::fromLatin1(thumbnail->png.toBase64());
+    result[QStringLiteral("cached")] = thumbnail->cached;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
@@ -0,0 +1,172 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+#include "core/vector/vector_index.h"
+#include "core/vector/vector_store.h"
+
+#include <QDir>
+#include <QJsonArray>
+
+#include <mutex>
+#include <shared_mutex>
+#include <unordered_set>
+
+namespace bs {
+
+namespace {
+
+// Embeddings `index` still stores that no item owns: live labels no
+// vector_map row of `generation` points at, and labels marked deleted but
+// never zeroed.
+std::vector<uint64_t> residualLabels(VectorIndex& index, VectorStore& store,
+                                     const QString& generation)
+{
+    std::unordered_set<uint64_t> mapped;
+    for (const auto& [itemId, label] : store.getAllMappings(generation.toStdString())) {
+        mapped.insert(label);
+    }
+    std::vector<uint64_t> out = index.unerasedDeletedLabels();
+    for (uint64_t label : index.liveLabels()) {
+        if (mapped.count(label) == 0) {
+            out.push_back(label);
+        }
+    }
+    return out;
+}
+
+} // namespace
+
+QueryService::VectorSweep QueryService::sweepVectorResidue(bool erase)
+{
+    VectorSweep sweep;
+    std::unique_lock<std::shared_mutex> lock(m_vectorIndexMutex);
+    const auto sweepIndex = [&](VectorIndex* index, const QString& generation,
+                                const QString& indexPath, const QString& metaPath) {
+        if (!index || !index->isAvailable() || !m_vectorStore) {
+            return;
+        }
+        int erased = 0;
+        if (erase) {
+            for (uint64_t label : residualLabels(*index, *m_vectorStore, generation)) {
+                if (index->eraseVector(label)) {
+                    ++erased;
+                }
+            }
+        }
+        if (erased > 0 && !indexPath.isEmpty()
+            && !index->save(indexPath.toStdString(), metaPath.toStdString())) {
+            sweep.saved = false;
+        }
+        sweep.erased += erased;
+        sweep.remaining +=
+            static_cast<int>(residualLabels(*index, *m_vectorStore, generation).size());
+    };
+    sweepIndex(m_vectorIndex.get(), m_activeVectorGeneration, m_vectorIndexPath,
+               m_vectorMetaPath);
+    sweepIndex(m_fastVectorIndex.get(), m_fastVectorGeneration, m_fastVectorIndexPath,
+               m_fastVectorMetaPath);
+    return sweep;
+}
+
+// Second half of a privacy exclusion, after the indexer's
+// addPrivacyExclusion has deleted the folder's items. Deleting an item
+// cascades its vector_map rows away but leaves its embeddings in the HNSW
+// indexes, so every vector no mapping points at is erased, whichever
+// folder it came from. `bspot purge` sends it after the indexer's
+// purgePath too.
+QJsonObject QueryService::handlePurgePrivacyPaths(uint64_t id, const QJsonObject& params)
+{
+    QStringList paths;
+    for (const QJsonValue& value : params.value(QStringLiteral("paths")).toArray()) {
+        const QString path = value.toString();
+        if (!QDir::isAbsolutePath(path)) {
+            return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                         QStringLiteral("'paths' must be absolute"));
+        }
+        paths.append(QDir::cleanPath(path));
+    }
+    if (paths.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing or empty 'paths' array"));
+    }
+    if (!ensureM2ModulesInitialized()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    // Cached responses, snippets, typo corrections and thumbnails can all
+    // quote purged documents.
+    m_queryCache.clear();
+    m_typoLexiconReady = false;
+    m_typoLexiconBuildAttempted = false;
+    if (!m_thumbnailCache) {
+        m_thumbnailCache = std::make_unique<ThumbnailCache>(
+            m_dataDir + QStringLiteral("/thumbnails"));
+    }
+    const int thumbnailsRemoved = m_thumbnailCache->clear();
+
+    const VectorSweep sweep = sweepVectorResidue(true);
+    const int erasedVectors = sweep.erased;
+    const int remainingVectors = sweep.remaining;
+    const bool vectorsSaved = sweep.saved;
+
+    bool verified = remainingVectors == 0 && vectorsSaved;
+    QJsonArray remaining;
+    for (const QString& path : paths) {
+        const SQLiteStore::PathFootprint footprint = m_store->footprintUnderPath(path);
+        verified = verified && footprint.empty();
+        QJsonObject entry;
+        entry[QStringLiteral("path")] = path;
+        entry[QStringLiteral("items")] = static_cast<qint64>(footprint.items);
+        entry[QStringLiteral("ftsRows")] = static_cast<qint64>(footprint.ftsRows);
+        entry[QStringLiteral("behaviorEvents")] = static_cast<qint64>(footprint.behaviorEvents);
+        remaining.append(entry);
+    }
+
+    LOG_INFO(bsIpc, "Privacy purge: %d vector(s) erased, %d thumbnail(s) removed, verified=%d",
+             erasedVectors, thumbnailsRemoved, verified ? 1 : 0);
+
+    QJsonObject result;
+    result[QStringLiteral("erasedVectors")] = erasedVectors;
+    result[QStringLiteral("remainingVectors")] = remainingVectors;
+    result[QStringLiteral("vectorsSaved")] = vectorsSaved;
+    result[QStringLiteral("thumbnailsRemoved")] = thumbnailsRemoved;
+    result[QStringLiteral("remaining")] = remaining;
+    result[QStringLiteral("verified")] = verified;
+    return IpcMessage::makeResponse(id, result);
+}
+
+// The query service's share of an index compaction: what the indexer's
+// VACUUM cannot reach. Sent by the app once the indexer reports one, and
+// by `bspot purge --verify` with dryRun to count without erasing.
+QJsonObject QueryService::handlePurgeOrphans(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureM2ModulesInitialized()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    const bool dryRun = params.value(QStringLiteral("dryRun")).toBool(false);
+    if (!dryRun) {
+        // Cached responses can quote documents deleted since they were built.
+        m_queryCache.clear();
+        m_typoLexiconReady = false;
+        m_typoLexiconBuildAttempted = false;
+    }
+    const VectorSweep sweep = sweepVectorResidue(!dryRun);
+    if (sweep.erased > 0 || sweep.remaining > 0) {
+        LOG_INFO(bsIpc, "Orphan sweep: %d vector(s) erased, %d remaining (dryRun=%d)",
+                 sweep.erased, sweep.remaining, dryRun ? 1 : 0);
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("dryRun")] = dryRun;
+    result[QStringLiteral("erasedVectors")] = sweep.erased;
+    result[QStringLiteral("remainingVectors")] = sweep.remaining;
+    result[QStringLiteral("vectorsSaved")] = sweep.saved;
+    result[QStringLiteral("verified")] = sweep.remaining == 0 && sweep.saved;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
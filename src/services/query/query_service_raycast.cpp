@@ -0,0 +1,114 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/query/raycast_view.h"
+
+#include <QDateTime>
+#include <QDir>
+#include <QJsonArray>
+#include <QTimer>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+// The first page trades depth for latency: a few hits under a tight
+// deadline, so the list paints while the full ranking runs.
+constexpr int kFirstPageLimit = 8;
+constexpr int kFirstPageBudgetMs = 60;
+constexpr int kDefaultLimit = 30;
+constexpr int kMaxLimit = 100;
+
+QJsonObject raycastPayload(const QString& query, const QJsonObject& searchResult, bool final)
+{
+    const QJsonArray results = searchResult.value(QStringLiteral("results")).toArray();
+    QJsonObject payload;
+    payload[QStringLiteral("query")] = query;
+    payload[QStringLiteral("final")] = final;
+    payload[QStringLiteral("sections")] = RaycastView::sections(results, query, QDir::homePath());
+    payload[QStringLiteral("resultCount")] = results.size();
+    payload[QStringLiteral("queryTime")] = searchResult.value(QStringLiteral("queryTime"));
+    if (searchResult.contains(QStringLiteral("totalMatches"))) {
+        payload[QStringLiteral("totalMatches")] = searchResult.value(QStringLiteral("totalMatches"));
+    }
+    if (searchResult.value(QStringLiteral("deadlineExceeded")).toBool()) {
+        payload[QStringLiteral("deadlineExceeded")] = true;
+    }
+    return payload;
+}
+
+} // namespace
+
+std::optional<HttpResponse> QueryService::startRaycastSearch(
+    const HttpRequest& request, quint64 streamId, const std::optional<ApiTokenScope>& scope)
+{
+    const QString query = request.queryValue(QStringLiteral("q")).trimmed();
+    int limit = kDefaultLimit;
+    if (!request.queryValue(QStringLiteral("limit")).isEmpty()) {
+        bool ok = false;
+        limit = request.queryValue(QStringLiteral("limit")).toInt(&ok);
+        if (!ok || limit < 1 || limit > kMaxLimit) {
+            return HttpResponse::error(
+                400, QStringLiteral("'limit' must be between 1 and %1").arg(kMaxLimit));
+        }
+    }
+
+    QJsonObject params;
+    params[QStringLiteral("query")] = query;
+    params[QStringLiteral("limit")] = limit;
+    if (scope.has_value()) {
+        params = scope->narrowSearch(params);
+    }
+    // Same filtering a scoped token gets through handleRequest(), and no
+    // messages for any token.
+    const auto visible = [scope](const QJsonObject& response) {
+        return withoutLocalOnly(scope.has_value() ? scopedResponse(response, scope.value())
+                                                  : response);
+    };
+
+    const int firstLimit = std::min(limit, kFirstPageLimit);
+    QJsonObject firstParams = params;
+    firstParams[QStringLiteral("limit")] = firstLimit;
+    firstParams[QStringLiteral("deadlineMs")] =
+        QDateTime::currentMSecsSinceEpoch() + kFirstPageBudgetMs;
+    const QJsonObject firstResponse = visible(handleSearch(0, firstParams));
+    auditRequest(IpcMessage::makeRequest(0, QStringLiteral("search"), params), firstResponse);
+    if (firstResponse.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return HttpResponse::fromIpc(firstResponse);
+    }
+    const QJsonObject firstResult = firstResponse.value(QStringLiteral("result")).toObject();
+
+    // Nothing more to rank: the first page already holds every hit.
+    const bool complete = !firstResult.value(QStringLiteral("deadlineExceeded")).toBool()
+        && (limit <= firstLimit
+            || firstResult.value(QStringLiteral("results")).toArray().size() < firstLimit);
+    m_httpServer->sendEvent(streamId,
+                            complete ? QStringLiteral("complete") : QStringLiteral("page"),
+                            raycastPayload(query, firstResult, complete));
+
+    // Deferred so the first page is written out before the full search
+    // holds the event loop; the stream can only be closed once accepted.
+    QTimer::singleShot(0, this, [this, streamId, query, params, complete, visible]() {
+        if (!m_httpServer) {
+            return;
+        }
+        if (!complete) {
+            const QJsonObject response = visible(handleSearch(0, params));
+            if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+                m_httpServer->sendEvent(streamId, QStringLiteral("error"),
+                                        response.value(QStringLiteral("error")).toObject());
+            } else {
+                m_httpServer->sendEvent(
+                    streamId, QStringLiteral("complete"),
+                    raycastPayload(query, response.value(QStringLiteral("result")).toObject(),
+                                   true));
+            }
+        }
+        m_httpServer->closeStream(streamId);
+    });
+    return std::nullopt;
+}
+
+} // namespace bs
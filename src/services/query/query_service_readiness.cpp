@@ -0,0 +1,100 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/ipc/socket_client.h"
+#include "core/shared/logging.h"
+#include "core/shared/service_readiness.h"
+
+#include <QDateTime>
+
+#include <algorithm>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultIndexerStallMs = 120000;
+
+int indexerStallThresholdMs()
+{
+    if (qEnvironmentVariableIsSet("BS_TEST_READINESS_STALL_MS")) {
+        bool ok = false;
+        const int parsed = qEnvironmentVariableIntValue("BS_TEST_READINESS_STALL_MS", &ok);
+        if (ok) {
+            return std::clamp(parsed, 100, 3600000);
+        }
+    }
+    return kDefaultIndexerStallMs;
+}
+
+} // namespace
+
+QJsonObject QueryService::handleGetReadiness(uint64_t id)
+{
+    return IpcMessage::makeResponse(id, readinessSnapshot());
+}
+
+QJsonObject QueryService::readinessSnapshot()
+{
+    const qint64 nowMs = QDateTime::currentMSecsSinceEpoch();
+    ReadinessInputs inputs;
+    inputs.stallThresholdMs = indexerStallThresholdMs();
+    inputs.indexOpen = ensureStoreOpen();
+    if (inputs.indexOpen) {
+        const IndexHealth health = m_store->getHealth();
+        inputs.indexedItems = health.totalIndexedItems;
+        inputs.criticalFailures = health.criticalFailures;
+        inputs.initialIndexCompleted =
+            m_store->getSetting(QStringLiteral("last_full_index_at")).has_value();
+    }
+
+    QJsonObject indexer;
+    SocketClient indexerClient;
+    if (indexerClient.connectToServer(ServiceBase::socketPath(QStringLiteral("indexer")), 75)) {
+        const auto response =
+            indexerClient.sendRequest(QStringLiteral("getQueueStatus"), {}, 150);
+        if (response.has_value()
+            && response->value(QStringLiteral("type")).toString() == QLatin1String("response")) {
+            const QJsonObject queue = response->value(QStringLiteral("result")).toObject();
+            const qint64 processed = queue.value(QStringLiteral("lastProgressReport")).toObject()
+                                         .value(QStringLiteral("scanned")).toInteger();
+            inputs.indexerReachable = true;
+            inputs.indexerPaused = queue.value(QStringLiteral("paused")).toBool(false);
+            inputs.indexerActive = queue.value(QStringLiteral("pending")).toInteger() > 0
+                || queue.value(QStringLiteral("processing")).toInteger() > 0
+                || queue.value(QStringLiteral("preparing")).toInteger() > 0
+                || queue.value(QStringLiteral("writing")).toInteger() > 0;
+
+            // Restart the stall clock on any progress, and whenever the queue
+            // is idle or paused so a resume doesn't look instantly stalled.
+            if (processed != m_readinessLastProcessed || !inputs.indexerActive
+                || inputs.indexerPaused) {
+                m_readinessLastProcessed = processed;
+                m_readinessProgressAtMs = nowMs;
+            }
+            inputs.msSinceIndexerProgress = nowMs - m_readinessProgressAtMs;
+
+            indexer[QStringLiteral("pending")] = queue.value(QStringLiteral("pending"));
+            indexer[QStringLiteral("processing")] = queue.value(QStringLiteral("processing"));
+            indexer[QStringLiteral("processed")] = processed;
+            indexer[QStringLiteral("paused")] = inputs.indexerPaused;
+            indexer[QStringLiteral("msSinceProgress")] = inputs.msSinceIndexerProgress;
+        }
+    }
+    indexer[QStringLiteral("reachable")] = inputs.indexerReachable;
+    indexer[QStringLiteral("active")] = inputs.indexerActive;
+
+    const ReadinessReport report = evaluateReadiness(inputs);
+    QJsonObject result;
+    result[QStringLiteral("state")] = readinessStateToString(report.state);
+    result[QStringLiteral("reason")] = report.reason;
+    result[QStringLiteral("ready")] = report.state == ReadinessState::Serving;
+    result[QStringLiteral("acceptingQueries")] = report.acceptingQueries;
+    result[QStringLiteral("indexedItems")] = static_cast<qint64>(inputs.indexedItems);
+    result[QStringLiteral("initialIndexCompleted")] = inputs.initialIndexCompleted;
+    result[QStringLiteral("uptimeMs")] = nowMs - m_startedAtMs;
+    result[QStringLiteral("indexer")] = indexer;
+    return result;
+}
+
+} // namespace bs
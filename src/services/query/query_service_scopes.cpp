@@ -0,0 +1,119 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QJsonDocument>
+#include <QJsonParseError>
+
+namespace bs {
+
+const SearchScopes& QueryService::searchScopes()
+{
+    if (m_searchScopesLoaded || !m_store.has_value()) {
+        return m_searchScopes;
+    }
+    m_searchScopesLoaded = true;
+    const QString raw = m_store->getSetting(QString::fromLatin1(SearchScopes::kSettingKey))
+                            .value_or(QString());
+    if (raw.isEmpty()) {
+        return m_searchScopes;
+    }
+    QJsonParseError parseError;
+    const QJsonDocument doc = QJsonDocument::fromJson(raw.toUtf8(), &parseError);
+    if (parseError.error != QJsonParseError::NoError || !doc.isArray()) {
+        LOG_WARN(bsIpc, "Ignoring unreadable %s setting: %s", SearchScopes::kSettingKey,
+                 qUtf8Printable(parseError.errorString()));
+        return m_searchScopes;
+    }
+    int skipped = 0;
+    m_searchScopes = SearchScopes::fromJson(doc.array(), &skipped);
+    if (skipped > 0) {
+        LOG_WARN(bsIpc, "Skipped %d invalid search scopes", skipped);
+    }
+    return m_searchScopes;
+}
+
+bool QueryService::saveSearchScopes(const SearchScopes& scopes)
+{
+    const QString json =
+        QString::fromUtf8(QJsonDocument(scopes.toJson()).toJson(QJsonDocument::Compact));
+    if (!m_store->setSetting(QString::fromLatin1(SearchScopes::kSettingKey), json)) {
+        return false;
+    }
+    m_searchScopes = scopes;
+    m_searchScopesLoaded = true;
+    // Cached results were filtered by the old definitions.
+    m_queryCache.clear();
+    return true;
+}
+
+QJsonObject QueryService::handleListSearchScopes(uint64_t id)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    QJsonObject result;
+    result[QStringLiteral("scopes")] = searchScopes().toJson();
+    result[QStringLiteral("maxScopes")] = SearchScopes::kMaxScopes;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleSetSearchScope(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    QString error;
+    const auto scope = SearchScope::fromJson(params, &error);
+    if (!scope.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    SearchScopes updated = searchScopes();
+    const bool replaced = updated.find(scope->id).has_value();
+    if (!updated.set(scope.value(), &error)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    if (!saveSearchScopes(updated)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to save search scopes"));
+    }
+
+    LOG_INFO(bsIpc, "Search scope '%s' %s with %d roots", qUtf8Printable(scope->id),
+             replaced ? "replaced" : "added", static_cast<int>(scope->roots.size()));
+    QJsonObject result = scope->toJson();
+    result[QStringLiteral("replaced")] = replaced;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleRemoveSearchScope(uint64_t id, const QJsonObject& params)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    const QString scopeId = params.value(QStringLiteral("id")).toString();
+    if (scopeId.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'id' parameter"));
+    }
+
+    SearchScopes updated = searchScopes();
+    if (!updated.remove(scopeId)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Unknown search scope '%1'").arg(scopeId));
+    }
+    if (!saveSearchScopes(updated)) {
+        return IpcMessage::makeError(id, IpcErrorCode::InternalError,
+                                     QStringLiteral("Failed to save search scopes"));
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("removed")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
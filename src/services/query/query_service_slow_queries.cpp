@@ -0,0 +1,47 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+
+#include <QJsonArray>
+
+namespace bs {
+
+namespace {
+
+constexpr int kMaxSlowQueryLimit = 1000;
+
+} // namespace
+
+// Admin only: entries carry query text.
+QJsonObject QueryService::handleGetSlowQueries(uint64_t id, const QJsonObject& params)
+{
+    const int limit = params.value(QStringLiteral("limit")).toInt(50);
+    if (limit < 1 || limit > kMaxSlowQueryLimit) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'limit' must be between 1 and %1").arg(kMaxSlowQueryLimit));
+    }
+
+    QJsonArray entries;
+    for (const SlowQueryLog::Entry& entry : m_slowQueryLog.entries(limit)) {
+        entries.append(entry.toJson());
+    }
+
+    QJsonObject result;
+    result[QStringLiteral("thresholdMs")] = m_slowQueryLog.thresholdMs();
+    result[QStringLiteral("recorded")] = static_cast<qint64>(m_slowQueryLog.recordedCount());
+    result[QStringLiteral("entries")] = entries;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleClearSlowQueries(uint64_t id)
+{
+    const qint64 removed = static_cast<qint64>(m_slowQueryLog.entries(kMaxSlowQueryLimit).size());
+    m_slowQueryLog.clear();
+
+    QJsonObject result;
+    result[QStringLiteral("removedEntries")] = removed;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
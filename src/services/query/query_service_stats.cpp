@@ -0,0 +1,86 @@
+#include "query_service.h"
+
+#include "core/ipc/message.h"
+#include "core/ipc/socket_client.h"
+
+#include <QJsonArray>
+
+namespace bs {
+
+namespace {
+
+constexpr int kDefaultBreakdownTop = 20;
+constexpr int kMaxBreakdownTop = 500;
+
+QJsonArray bucketsJson(const std::vector<SQLiteStore::SizeBucket>& buckets)
+{
+    QJsonArray out;
+    for (const SQLiteStore::SizeBucket& bucket : buckets) {
+        QJsonObject json;
+        json[QStringLiteral("name")] = bucket.name;
+        json[QStringLiteral("items")] = static_cast<qint64>(bucket.items);
+        json[QStringLiteral("bytes")] = static_cast<qint64>(bucket.bytes);
+        out.append(json);
+    }
+    return out;
+}
+
+// The indexer owns the roots; same short probe as getReadiness. Empty when
+// it does not answer, and files then count toward their parent directory.
+QStringList indexerRoots()
+{
+    QStringList roots;
+    SocketClient indexerClient;
+    if (indexerClient.connectToServer(ServiceBase::socketPath(QStringLiteral("indexer")), 75)) {
+        const auto response = indexerClient.sendRequest(QStringLiteral("getQueueStatus"), {}, 150);
+        if (response.has_value()
+            && response->value(QStringLiteral("type")).toString() == QLatin1String("response")) {
+            for (const QJsonValue& root : response->value(QStringLiteral("result")).toObject()
+                                              .value(QStringLiteral("roots")).toArray()) {
+                roots.append(root.toString());
+            }
+        }
+    }
+    return roots;
+}
+
+} // namespace
+
+// Admin only: directory names span every root, whatever a scoped token
+// may search.
+QJsonObject QueryService::handleGetIndexBreakdown(uint64_t id, const QJsonObject& params)
+{
+    const int top = params.value(QStringLiteral("top")).toInt(kDefaultBreakdownTop);
+    if (top < 1 || top > kMaxBreakdownTop) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'top' must be between 1 and %1").arg(kMaxBreakdownTop));
+    }
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    QStringList roots;
+    if (params.contains(QStringLiteral("roots"))) {
+        for (const QJsonValue& root : params.value(QStringLiteral("roots")).toArray()) {
+            roots.append(root.toString());
+        }
+    } else {
+        roots = indexerRoots();
+    }
+
+    const SQLiteStore::SizeBreakdown breakdown = m_store->sizeBreakdown(roots, top);
+    QJsonObject result;
+    result[QStringLiteral("totalBytes")] = static_cast<qint64>(breakdown.totalBytes);
+    result[QStringLiteral("fileBytes")] = static_cast<qint64>(breakdown.fileBytes);
+    result[QStringLiteral("measured")] = breakdown.measured;
+    result[QStringLiteral("roots")] = QJsonArray::fromStringList(roots);
+    result[QStringLiteral("tables")] = bucketsJson(breakdown.tables);
+    result[QStringLiteral("fields")] = bucketsJson(breakdown.fields);
+    result[QStringLiteral("fileTypes")] = bucketsJson(breakdown.fileTypes);
+    result[QStringLiteral("directories")] = bucketsJson(breakdown.directories);
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
@@ -0,0 +1,186 @@
+#include "query_service.h"
+
+#include "core/indexing/peer_sync.h"
+#include "core/ipc/message.h"
+
+#include <QDateTime>
+#include <QJsonArray>
+
+#include <algorithm>
+#include <limits>
+
+namespace bs {
+
+namespace {
+
+SyncedItem syncedItem(const SQLiteStore::ItemRow& row)
+{
+    SyncedItem item;
+    item.id = row.id;
+    item.path = row.path;
+    item.name = row.name;
+    item.kind = row.kind;
+    item.size = row.size;
+    item.createdAt = row.createdAt;
+    item.modifiedAt = row.modifiedAt;
+    item.indexedAt = row.indexedAt;
+    item.contentHash = row.contentHash;
+    return item;
+}
+
+} // namespace
+
+// The publishing side of PeerSync. A scoped token's roots narrow what is
+// published, digests included, so a subscriber only ever sees, and
+// compares, the files its token covers. Items carry the user's signals
+// on them, so a change to a tag, pin or open count changes the digest and
+// the segment is fetched again.
+std::vector<SyncedItem> QueryService::syncableItems(int64_t firstId, int64_t lastId)
+{
+    std::vector<SyncedItem> items;
+    const auto itemSignals = m_store->getItemSignalsInIdRange(firstId, lastId);
+    for (const SQLiteStore::ItemRow& row : m_store->getItemsInIdRange(firstId, lastId)) {
+        if (!PeerSync::isSyncable(row.path)
+            || (m_requestScope.has_value() && !m_requestScope->coversPath(row.path))) {
+            continue;
+        }
+        SyncedItem item = syncedItem(row);
+        if (const auto found = itemSignals.find(row.id); found != itemSignals.end()) {
+            item.metadata = MetadataMerge::fromSignals(found->second, row.indexedAt);
+        }
+        items.push_back(std::move(item));
+    }
+    return items;
+}
+
+QJsonObject QueryService::handleGetSyncSegments(uint64_t id)
+{
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    const std::vector<SyncSegment> segments =
+        PeerSync::segments(syncableItems(0, std::numeric_limits<int64_t>::max()));
+    int items = 0;
+    for (const SyncSegment& segment : segments) {
+        items += segment.count;
+    }
+    QJsonObject result;
+    result[QStringLiteral("segmentSpan")] = static_cast<qint64>(PeerSync::kSegmentSpan);
+    result[QStringLiteral("segments")] = PeerSync::segmentsToJson(segments);
+    result[QStringLiteral("itemCount")] = items;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleGetSyncSegment(uint64_t id, const QJsonObject& params)
+{
+    const qint64 first = params.value(QStringLiteral("first")).toInteger(-1);
+    if (first < 0 || first % PeerSync::kSegmentSpan != 0) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'first' must be a multiple of %1").arg(PeerSync::kSegmentSpan));
+    }
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+    std::vector<SyncedItem> items = syncableItems(first, first + PeerSync::kSegmentSpan - 1);
+    const std::vector<SyncSegment> segments = PeerSync::segments(items);
+
+    QJsonArray entries;
+    for (SyncedItem& item : items) {
+        item.text = m_store->getItemContent(item.id, PeerSync::kMaxTextChars);
+        entries.append(PeerSync::itemToJson(item));
+    }
+    QJsonObject result;
+    result[QStringLiteral("first")] = first;
+    // The digest of exactly these entries, so the subscriber records the
+    // state it applied even when the index moved on since getSyncSegments.
+    result[QStringLiteral("digest")] = segments.empty() ? QString() : segments.front().digest;
+    result[QStringLiteral("items")] = entries;
+    return IpcMessage::makeResponse(id, result);
+}
+
+// A delta page: the segments from `from` on that changed since the cursor,
+// up to about PeerSync::kDeltaPageItems entries, whole segments only. No
+// cursor, or one this index cannot serve (tombstones pruned past it, a
+// different index file, a clock moved back), is a reset: the subscriber
+// compares digests instead, then keeps the cursor returned here.
+QJsonObject QueryService::handleGetSyncDelta(uint64_t id, const QJsonObject& params)
+{
+    std::optional<SyncCursor> cursor;
+    const QString token = params.value(QStringLiteral("cursor")).toString();
+    if (!token.isEmpty()) {
+        cursor = PeerSync::cursorFromToken(token);
+        if (!cursor.has_value()) {
+            return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                         QStringLiteral("'cursor' is not a sync cursor"));
+        }
+    }
+    const qint64 from = params.value(QStringLiteral("from")).toInteger(0);
+    if (from < 0 || from % PeerSync::kSegmentSpan != 0) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::InvalidParams,
+            QStringLiteral("'from' must be a multiple of %1").arg(PeerSync::kSegmentSpan));
+    }
+    if (!ensureStoreOpen()) {
+        return IpcMessage::makeError(id, IpcErrorCode::ServiceUnavailable,
+                                     QStringLiteral("Database is not available"));
+    }
+
+    // Taken before anything is read, so a change made while the page is
+    // cut is in the next pass as well.
+    const double now = static_cast<double>(QDateTime::currentSecsSinceEpoch());
+    SyncCursor next;
+    next.indexedSince = std::max(0.0, now - PeerSync::kCursorOverlapSecs);
+    next.tombstoneSeq = m_store->lastTombstoneSeq();
+
+    QJsonObject result;
+    result[QStringLiteral("segmentSpan")] = static_cast<qint64>(PeerSync::kSegmentSpan);
+    result[QStringLiteral("cursor")] = PeerSync::cursorToken(next);
+
+    bool reset = !cursor.has_value() || cursor->tombstoneSeq < m_store->prunedTombstoneSeq()
+                 || cursor->tombstoneSeq > next.tombstoneSeq || cursor->indexedSince > now;
+    std::vector<int64_t> tombstoneIds;
+    if (!reset) {
+        const std::vector<SQLiteStore::Tombstone> tombstones =
+            m_store->getTombstonesAfter(cursor->tombstoneSeq, PeerSync::kMaxDeltaTombstones + 1);
+        reset = tombstones.size() > static_cast<size_t>(PeerSync::kMaxDeltaTombstones);
+        for (const SQLiteStore::Tombstone& tombstone : tombstones) {
+            tombstoneIds.push_back(tombstone.itemId);
+        }
+    }
+    result[QStringLiteral("reset")] = reset;
+    QJsonArray segments;
+    if (reset) {
+        result[QStringLiteral("segments")] = segments;
+        return IpcMessage::makeResponse(id, result);
+    }
+
+    // Deleted ids go out whatever the token's roots: an id names nothing,
+    // and a subscriber only removes the ones it holds.
+    const std::vector<int64_t> touched = PeerSync::touchedSegments(
+        m_store->getItemIdsIndexedSince(cursor->indexedSince), tombstoneIds);
+    auto it = std::lower_bound(touched.begin(), touched.end(), static_cast<int64_t>(from));
+    int entries = 0;
+    for (; it != touched.end() && entries < PeerSync::kDeltaPageItems; ++it) {
+        SegmentDelta delta = PeerSync::segmentDelta(
+            *it, syncableItems(*it, *it + PeerSync::kSegmentSpan - 1), cursor->indexedSince,
+            tombstoneIds);
+        if (delta.items.empty() && delta.tombstones.empty()) {
+            continue;  // only unpublished items changed
+        }
+        for (SyncedItem& item : delta.items) {
+            item.text = m_store->getItemContent(item.id, PeerSync::kMaxTextChars);
+        }
+        entries += static_cast<int>(delta.items.size());
+        segments.append(PeerSync::segmentDeltaToJson(delta));
+    }
+    result[QStringLiteral("segments")] = segments;
+    if (it != touched.end()) {
+        result[QStringLiteral("next")] = static_cast<qint64>(*it);
+    }
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs
@@ -0,0 +1,334 @@
+#include "query_service.h"
+
+#include "core/ipc/ipc_auth.h"
+#include "core/ipc/message.h"
+#include "core/shared/logging.h"
+
+#include <QDir>
+#include <QHash>
+#include <QJsonArray>
+
+namespace bs {
+
+namespace {
+
+// Method -> capability a scoped token needs to call it. Anything missing
+// here (admin methods, health, learning, live socket subscriptions whose
+// updates are broadcast) is not available to scoped tokens at all.
+const QHash<QString, QString>& methodCapabilities()
+{
+    static const QHash<QString, QString> kCapabilities = {
+        {QStringLiteral("ping"), QStringLiteral("search")},
+        {QStringLiteral("getReadiness"), QStringLiteral("search")},
+        {QStringLiteral("search"), QStringLiteral("search")},
+        {QStringLiteral("suggest"), QStringLiteral("search")},
+        {QStringLiteral("completePaths"), QStringLiteral("search")},
+        {QStringLiteral("exportMatches"), QStringLiteral("search")},
+        {QStringLiteral("findSimilar"), QStringLiteral("search")},
+        {QStringLiteral("getDocument"), QStringLiteral("search")},
+        {QStringLiteral("getAnswerSnippet"), QStringLiteral("content")},
+        {QStringLiteral("get_answer_snippet"), QStringLiteral("content")},
+        {QStringLiteral("getThumbnail"), QStringLiteral("content")},
+        {QStringLiteral("performAction"), QStringLiteral("actions")},
+        {QStringLiteral("recordFeedback"), QStringLiteral("actions")},
+        {QStringLiteral("getRecentActions"), QStringLiteral("actions")},
+        {QStringLiteral("repeatAction"), QStringLiteral("actions")},
+        {QStringLiteral("getMetrics"), QStringLiteral("metrics")},
+        {QStringLiteral("getQueryStats"), QStringLiteral("metrics")},
+        {QStringLiteral("getResourceUsage"), QStringLiteral("metrics")},
+        {QStringLiteral("getSyncSegments"), QStringLiteral("sync")},
+        {QStringLiteral("getSyncSegment"), QStringLiteral("sync")},
+        {QStringLiteral("getSyncDelta"), QStringLiteral("sync")},
+    };
+    return kCapabilities;
+}
+
+// Methods that name one document by itemId or path.
+bool targetsDocument(const QString& method)
+{
+    return method == QLatin1String("getDocument") || method == QLatin1String("getThumbnail")
+           || method == QLatin1String("findSimilar") || method == QLatin1String("performAction")
+           || method == QLatin1String("recordFeedback");
+}
+
+QJsonObject tokenSummary(const ApiToken& token)
+{
+    QJsonObject json = token.scope.toJson();
+    json[QStringLiteral("id")] = token.id;
+    json[QStringLiteral("name")] = token.name;
+    json[QStringLiteral("createdAt")] = token.createdAt;
+    return json;
+}
+
+// A loopback request must name the loopback listener in its Host header:
+// 127.0.0.1, localhost or [::1], on the port we are bound to. A DNS-rebound
+// page reaches the same socket but sends its own host name.
+bool isLoopbackHost(const QString& host, quint16 port, quint16 defaultPort)
+{
+    QString name = host.trimmed();
+    QString portText;
+    if (name.startsWith(QLatin1Char('['))) {
+        const qsizetype close = name.indexOf(QLatin1Char(']'));
+        if (close < 0) {
+            return false;
+        }
+        const QString rest = name.mid(close + 1);
+        if (!rest.isEmpty() && !rest.startsWith(QLatin1Char(':'))) {
+            return false;
+        }
+        portText = rest.mid(1);
+        name.truncate(close + 1);
+    } else if (const qsizetype colon = name.lastIndexOf(QLatin1Char(':')); colon >= 0) {
+        portText = name.mid(colon + 1);
+        name.truncate(colon);
+    }
+    if (name != QLatin1String("127.0.0.1") && name != QLatin1String("[::1]")
+        && name.compare(QLatin1String("localhost"), Qt::CaseInsensitive) != 0) {
+        return false;
+    }
+    if (portText.isEmpty()) {
+        return port == defaultPort;
+    }
+    bool ok = false;
+    const uint requested = portText.toUInt(&ok);
+    return ok && requested == port;
+}
+
+} // namespace
+
+std::optional<QJsonObject> QueryService::scopedTokenScope(const QByteArray& token) const
+{
+    const auto match = m_apiTokens.verify(token);
+    if (!match.has_value()) {
+        return std::nullopt;
+    }
+    QJsonObject auth = match->scope.toJson();
+    auth[QStringLiteral("tokenId")] = match->id;
+    auth[QStringLiteral("name")] = match->name;
+    return auth;
+}
+
+// The `auth` object only names the token: its scope is re-read here, so a
+// revoke or re-issue applies to the next request of an open connection.
+QJsonObject QueryService::handleScopedRequest(const QJsonObject& request)
+{
+    const QString method = request.value(QStringLiteral("method")).toString();
+    const uint64_t id = static_cast<uint64_t>(request.value(QStringLiteral("id")).toInteger());
+    const QString tokenId = request.value(QStringLiteral("auth")).toObject()
+                                .value(QStringLiteral("tokenId")).toString();
+    const auto token = m_apiTokens.find(tokenId);
+    if (!token.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::PermissionDenied,
+                                     QStringLiteral("API token was revoked"));
+    }
+
+    QJsonObject params = request.value(QStringLiteral("params")).toObject();
+    if (const auto denial = scopeDenial(id, method, params, token->scope)) {
+        return denial.value();
+    }
+    if (method == QLatin1String("search") || method == QLatin1String("exportMatches")) {
+        params = token->scope.narrowSearch(params);
+    }
+
+    QJsonObject unscoped = request;
+    unscoped.remove(QStringLiteral("auth"));
+    unscoped[QStringLiteral("params")] = params;
+    m_requestScope = token->scope;
+    const QJsonObject response = handleRequest(unscoped);
+    m_requestScope.reset();
+    return scopedResponse(response, token->scope);
+}
+
+std::optional<QJsonObject> QueryService::scopeDenial(uint64_t id, const QString& method,
+                                                     const QJsonObject& params,
+                                                     const ApiTokenScope& scope)
+{
+    const auto capability = methodCapabilities().constFind(method);
+    if (capability == methodCapabilities().cend()) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::PermissionDenied,
+            QStringLiteral("'%1' is not available to scoped API tokens").arg(method));
+    }
+    QString required = capability.value();
+    if (method == QLatin1String("getDocument")
+        && params.value(QStringLiteral("includeContent")).toBool(false)) {
+        required = QStringLiteral("content");
+    }
+    if (!scope.allows(required)) {
+        return IpcMessage::makeError(
+            id, IpcErrorCode::PermissionDenied,
+            QStringLiteral("This token lacks the '%1' capability").arg(required));
+    }
+
+    if (!targetsDocument(method)) {
+        return std::nullopt;
+    }
+    if (targetsLocalOnly(params)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    if (scope.roots.isEmpty()) {
+        return std::nullopt;
+    }
+    // Outside the roots reads exactly like "not indexed", so a token cannot
+    // probe for documents it may not see.
+    QString path;
+    if (params.contains(QStringLiteral("itemId"))) {
+        if (ensureStoreOpen()) {
+            if (const auto item = m_store->getItemById(static_cast<int64_t>(
+                    params.value(QStringLiteral("itemId")).toInteger()))) {
+                path = item->path;
+            }
+        }
+    } else {
+        path = params.value(QStringLiteral("path")).toString();
+    }
+    if (path.isEmpty() || !QDir::isAbsolutePath(path) || !scope.coversPath(path)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    return std::nullopt;
+}
+
+QJsonObject QueryService::scopedResponse(const QJsonObject& response, const ApiTokenScope& scope)
+{
+    if (response.value(QStringLiteral("type")).toString() == QLatin1String("error")) {
+        return response;
+    }
+    const uint64_t id = static_cast<uint64_t>(response.value(QStringLiteral("id")).toInteger());
+    const auto filtered = scope.filterResult(response.value(QStringLiteral("result")).toObject());
+    if (!filtered.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("Document is not indexed"));
+    }
+    return withoutLocalOnly(IpcMessage::makeResponse(id, filtered.value()));
+}
+
+// No Authorization header is accepted only while no API token exists, so
+// the loopback API keeps working for existing local scripts until a token
+// is issued. After that every route but /healthz and /readyz needs either
+// the admin token or an API token. From the network (server mode) only an
+// API token will do: the admin token is the session's, never the LAN's.
+// Browsers are kept out either way: a request carrying an Origin header is
+// refused, and a loopback one must name the loopback listener as its Host.
+std::optional<HttpResponse> QueryService::authorizeHttp(const HttpRequest& request,
+                                                        std::optional<QJsonObject>* auth) const
+{
+    auth->reset();
+    const bool remote = request.isRemote();
+    if (!request.header(QStringLiteral("origin")).isEmpty()) {
+        return HttpResponse::error(403, QStringLiteral("Cross-origin requests are not accepted"));
+    }
+    if (!remote && m_httpServer) {
+        const quint16 defaultPort = m_httpServer->isTls() ? 443 : 80;
+        if (!isLoopbackHost(request.header(QStringLiteral("host")),
+                            m_httpServer->serverPort(), defaultPort)) {
+            return HttpResponse::error(403, QStringLiteral("Host is not a loopback address"));
+        }
+    }
+    const QString header = request.header(QStringLiteral("authorization")).trimmed();
+    if (header.isEmpty()) {
+        if (m_apiTokens.isEmpty() && !remote) {
+            return std::nullopt;
+        }
+        return HttpResponse::error(401, QStringLiteral("Missing bearer token"));
+    }
+    if (!header.startsWith(QLatin1String("Bearer "), Qt::CaseInsensitive)) {
+        return HttpResponse::error(401, QStringLiteral("Expected 'Authorization: Bearer <token>'"));
+    }
+    const QByteArray token = header.mid(7).trimmed().toLatin1();
+    const QByteArray adminToken = IpcAuth::environmentToken();
+    if (!adminToken.isEmpty() && IpcAuth::tokensEqual(adminToken, token)) {
+        if (remote) {
+            return HttpResponse::error(
+                401, QStringLiteral("The admin token is not accepted over the network"));
+        }
+        return std::nullopt;
+    }
+    if (auto scope = scopedTokenScope(token)) {
+        *auth = std::move(scope);
+        return std::nullopt;
+    }
+    return HttpResponse::error(401, QStringLiteral("Invalid bearer token"));
+}
+
+std::optional<HttpResponse> QueryService::authorizeHttpDirect(
+    const HttpRequest& request, const QString& method, const QJsonObject& params,
+    std::optional<QJsonObject>* auth, std::optional<ApiTokenScope>* scope)
+{
+    std::optional<QJsonObject> tokenAuth;
+    if (auto denied = authorizeHttp(request, &tokenAuth)) {
+        return denied;
+    }
+    if (!tokenAuth.has_value()) {
+        return std::nullopt;
+    }
+    const auto token =
+        m_apiTokens.find(tokenAuth->value(QStringLiteral("tokenId")).toString());
+    if (!token.has_value()) {
+        return HttpResponse::error(401, QStringLiteral("API token was revoked"));
+    }
+    if (const auto denial = scopeDenial(0, method, params, token->scope)) {
+        return HttpResponse::fromIpc(denial.value());
+    }
+    if (auth) {
+        *auth = std::move(tokenAuth);
+    }
+    if (scope) {
+        *scope = token->scope;
+    }
+    return std::nullopt;
+}
+
+QJsonObject QueryService::handleCreateApiToken(uint64_t id, const QJsonObject& params)
+{
+    QString error;
+    const auto scope = ApiTokenScope::fromJson(params, &error);
+    if (!scope.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    ApiToken created;
+    const auto secret = m_apiTokens.create(params.value(QStringLiteral("name")).toString(),
+                                           scope.value(), &created, &error);
+    if (!secret.has_value()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams, error);
+    }
+    LOG_INFO(bsIpc, "Issued API token %s (%s)", qUtf8Printable(created.id),
+             qUtf8Printable(created.name));
+
+    QJsonObject result = tokenSummary(created);
+    result[QStringLiteral("token")] = secret.value();
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleListApiTokens(uint64_t id)
+{
+    QJsonArray tokens;
+    for (const ApiToken& token : m_apiTokens.list()) {
+        tokens.append(tokenSummary(token));
+    }
+    QJsonObject result;
+    result[QStringLiteral("tokens")] = tokens;
+    return IpcMessage::makeResponse(id, result);
+}
+
+QJsonObject QueryService::handleRevokeApiToken(uint64_t id, const QJsonObject& params)
+{
+    const QString tokenId = params.value(QStringLiteral("id")).toString().trimmed();
+    if (tokenId.isEmpty()) {
+        return IpcMessage::makeError(id, IpcErrorCode::InvalidParams,
+                                     QStringLiteral("Missing 'id' parameter"));
+    }
+    if (!m_apiTokens.revoke(tokenId)) {
+        return IpcMessage::makeError(id, IpcErrorCode::NotFound,
+                                     QStringLiteral("No API token with id '%1'").arg(tokenId));
+    }
+    LOG_INFO(bsIpc, "Revoked API token %s", qUtf8Printable(tokenId));
+
+    QJsonObject result;
+    result[QStringLiteral("id")] = tokenId;
+    result[QStringLiteral("revoked")] = true;
+    return IpcMessage::makeResponse(id, result);
+}
+
+} // namespace bs